@@ -18,6 +18,7 @@ import (
 	"github.com/crosslogic/control-plane/pkg/cache"
 	"github.com/crosslogic/control-plane/pkg/database"
 	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/crosslogic/control-plane/pkg/r2"
 	"go.uber.org/zap"
 )
 
@@ -51,7 +52,7 @@ func main() {
 		logger.Fatal("failed to connect to Redis", zap.Error(err))
 	}
 	defer redisCache.Close()
-	logger.Info("connected to Redis")
+	logger.Info("connected to Redis", zap.String("mode", redisCache.Mode()))
 
 	// Initialize event bus
 	eventBus := events.NewBus(logger)
@@ -69,6 +70,13 @@ func main() {
 	}
 	logger.Info("initialized notification service")
 
+	// Initialize model deprecation reminders (delivered via notification service)
+	deprecationReminder := notifications.NewDeprecationReminder(db, logger, eventBus)
+
+	// Initialize tenant usage alert rules (delivered via notification service)
+	usageAlerts := notifications.NewUsageAlerts(db, logger, eventBus)
+	budgets := billing.NewBudgets(db, logger, eventBus)
+
 	// Initialize billing engine when enabled
 	var billingEngine *billing.Engine
 	if cfg.Billing.Enabled {
@@ -82,10 +90,42 @@ func main() {
 	costTracker := billing.NewCostTracker(db, logger)
 	logger.Info("initialized cost tracker")
 
+	// Initialize token reconciliation of billed usage against vLLM counters
+	tokenReconciler := billing.NewTokenReconciler(db, logger)
+
+	// Plan limits and the Stripe prices that bill self-serve plans
+	plans := billing.NewPlanCatalog(map[string]string{
+		"starter": cfg.Billing.StripePriceStarter,
+		"pro":     cfg.Billing.StripePricePro,
+	})
+	if err := plans.SetChatLimits(cfg.ChatLimits.MaxMessages, cfg.ChatLimits.MaxMessageChars); err != nil {
+		logger.Fatal("invalid chat limits", zap.Error(err))
+	}
+
+	// Cross-check tenants against Stripe customers and subscriptions
+	var stripeCustomers billing.CustomerDirectory
+	if cfg.Billing.Enabled {
+		stripeCustomers = billing.NewStripeCustomers()
+	}
+	stripeReconciler := billing.NewStripeReconciler(db, logger, stripeCustomers, plans, cfg.Billing.ReconcileInterval, cfg.Billing.ReconcileAutoFix)
+
+	// Billing sandbox: accelerated invoice cycles, issued to Stripe test mode
+	// only when a test-mode key is configured
+	var sandboxInvoicer billing.SandboxInvoicer
+	if cfg.Billing.StripeSandboxSecretKey != "" {
+		stripeSandbox, err := billing.NewStripeSandbox(cfg.Billing.StripeSandboxSecretKey, logger)
+		if err != nil {
+			logger.Fatal("failed to initialize billing sandbox", zap.Error(err))
+		}
+		sandboxInvoicer = stripeSandbox
+	}
+	billingSandbox := billing.NewSandboxRunner(db, logger, sandboxInvoicer, cfg.Billing.SandboxCycle)
+
 	// Initialize webhook handler with event bus when billing is enabled
 	var webhookHandler *billing.WebhookHandler
 	if cfg.Billing.Enabled {
 		webhookHandler = billing.NewWebhookHandler(cfg.Billing.StripeWebhookSecret, db, redisCache, logger, eventBus)
+		webhookHandler.SetPlanCatalog(plans)
 		logger.Info("initialized webhook handler")
 	} else {
 		logger.Info("billing disabled; webhook handler not registered")
@@ -106,10 +146,28 @@ func main() {
 	if err != nil {
 		logger.Fatal("failed to initialize orchestrator", zap.Error(err))
 	}
+	nodeAPIURL := cfg.Server.ControlPlaneURL
+	if cfg.NodeAPI.Port != 0 {
+		if cfg.NodeAPI.URL != "" {
+			nodeAPIURL = cfg.NodeAPI.URL
+		}
+		orch.SetNodeAPI(nodeAPIURL, cfg.NodeAPI.Token)
+	}
+	if cfg.NodeHardening.Enabled {
+		if err := orch.SetHardening(cfg.NodeHardening.AllowedCIDRs); err != nil {
+			logger.Fatal("invalid node hardening configuration", zap.Error(err))
+		}
+		logger.Info("node hardening enabled", zap.Strings("allowed_cidrs", cfg.NodeHardening.AllowedCIDRs))
+	}
+	// Launch preflight checks look for model weights in the R2 model bucket
+	if presigner, err := r2.NewPresigner(cfg.R2.Endpoint, cfg.R2.Bucket, cfg.R2.AccessKey, cfg.R2.SecretKey); err == nil {
+		orch.SetModelStore(r2.NewObjects(presigner))
+	}
 	logger.Info("initialized SkyPilot orchestrator")
 
 	// Initialize Triple Safety Monitor
 	monitor := orchestrator.NewTripleSafetyMonitor(db, logger, orch, eventBus)
+	monitor.SetHeartbeatThresholds(cfg.Monitoring.StaleHeartbeatThreshold, cfg.Monitoring.DeregisterHeartbeatThreshold)
 	logger.Info("initialized triple safety monitor")
 
 	// Initialize State Reconciler with Triple Safety Monitor integration
@@ -137,9 +195,147 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Export Redis health metrics and stop calling Redis while it is down
+	redisCache.StartHealthCheck(ctx, cfg.Redis.HealthCheckInterval, logger)
+
+	// Export connection pool metrics, flag long-held connections and size the pool to load
+	db.StartPoolMonitor(ctx, logger)
+
 	// Initialize API gateway with event bus and credential service
 	gw := gateway.NewGateway(db, redisCache, logger, webhookHandler, orch, monitor, cfg.Security.AdminAPIToken, eventBus, credentialService)
+	gw.SetCatalogCacheTTLs(cfg.Redis.CatalogCacheTTL, cfg.Redis.RouteCacheTTL)
+	gw.SetResponseCompression(cfg.Compression.Enabled, cfg.Compression.MinBytes, cfg.Compression.Level)
+	gw.SetNodeConnectionPool(cfg.NodeProxy.MaxIdleConnsPerHost, cfg.NodeProxy.IdleConnTimeout, cfg.NodeProxy.PrewarmConns)
+	gw.SetNodeHeaderPolicy(cfg.NodeProxy.ForwardHeaders, cfg.NodeProxy.StripHeaders,
+		cfg.NodeProxy.TenantHeader, cfg.NodeProxy.RequestIDHeader, cfg.NodeProxy.TenantHashKey)
+	gw.SetAudioMaxFileSize(int64(cfg.Audio.MaxFileMB) << 20)
+	gw.SetImageLimits(cfg.Images.MaxImages, cfg.Images.MaxDimension, cfg.Images.MaxSteps)
+	if cfg.DegradedMode.Enabled {
+		gw.SetDegradedMode(cfg.DegradedMode.Saturation, cfg.DegradedMode.MaxTokens, cfg.DegradedMode.LowPriorityPlans)
+		logger.Info("degraded mode enabled",
+			zap.Float64("saturation", cfg.DegradedMode.Saturation),
+			zap.Int("max_tokens", cfg.DegradedMode.MaxTokens),
+		)
+	}
+	gw.SetAPIv1Sunset(cfg.API.V1Sunset)
+	gw.SetNodeDiscovery(gateway.NodeDiscoverySettings{
+		Secret:        cfg.NodeAPI.DiscoverySecret,
+		TokenTTL:      cfg.NodeAPI.DiscoveryTokenTTL,
+		NodeAPIURL:    nodeAPIURL,
+		PublicURL:     cfg.Server.ControlPlaneURL,
+		AlternateURLs: cfg.NodeAPI.DiscoveryAlternateURLs,
+	})
 	gw.StartHealthMetrics(ctx)
+	gw.StartCacheNamespaceMigration(ctx)
+	gw.StartJobs(ctx)
+	gw.StartMaintenance(ctx)
+	gw.StartModelPriceChanges(ctx)
+	gw.StartPromptExperiments(ctx)
+	if err := gw.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Fatal("invalid trusted proxies", zap.Error(err))
+	}
+	gw.SetKillSwitchTwoPerson(cfg.Security.KillSwitchTwoPerson)
+	gw.StartKillSwitches(ctx)
+	gw.StartDrainWatch(ctx)
+
+	// Self-serve plan upgrades bill through Stripe when billing is enabled
+	gw.Plans = plans
+	if cfg.Billing.Enabled {
+		gw.Subscriptions = billing.NewStripeSubscriptions(logger)
+	}
+	gw.BillingSandbox = billingSandbox
+	gw.StripeReconciler = stripeReconciler
+	gw.UsageAlerts = usageAlerts
+	gw.Budgets = budgets
+
+	// Optional HMAC request signing for high-security tenants
+	if cfg.Security.RequestSigningKey != "" {
+		gw.SigningSecrets, err = credentials.NewEncryptionService(cfg.Security.RequestSigningKey, "v1")
+		if err != nil {
+			logger.Fatal("failed to initialize request signing", zap.Error(err))
+		}
+	}
+
+	// Opt-in quality sampling needs a key to hash tenant and user identifiers
+	if cfg.QualitySampling.HashKey != "" {
+		gw.QualitySamples, err = gateway.NewQualitySampler(cfg.QualitySampling.Rate, cfg.QualitySampling.HashKey,
+			cfg.QualitySampling.MaxBytes, cfg.QualitySampling.Retention)
+		if err != nil {
+			logger.Fatal("failed to initialize quality sampling", zap.Error(err))
+		}
+		gw.StartQualitySampling(ctx)
+	} else {
+		logger.Info("quality sampling disabled (QUALITY_SAMPLE_HASH_KEY not set)")
+	}
+
+	// The public playground needs a model and a captcha to keep it for humans
+	if cfg.Playground.Model != "" && cfg.Playground.CaptchaSecret != "" {
+		gw.Playground, err = gateway.NewPlayground(cfg.Playground.Model, cfg.Playground.CaptchaSecret,
+			cfg.Playground.CaptchaVerifyURL, gateway.PlaygroundLimits{
+				MaxTokens:               cfg.Playground.MaxTokens,
+				RequestsPerMinute:       cfg.Playground.RequestsPerMinute,
+				RequestsPerDay:          cfg.Playground.RequestsPerDay,
+				GlobalRequestsPerMinute: cfg.Playground.GlobalRequestsPerMinute,
+			})
+		if err != nil {
+			logger.Fatal("failed to initialize playground", zap.Error(err))
+		}
+		logger.Info("public playground enabled", zap.String("model", cfg.Playground.Model))
+	} else {
+		logger.Info("public playground disabled (PLAYGROUND_MODEL or PLAYGROUND_CAPTCHA_SECRET not set)")
+	}
+
+	// Node crash forensics bundles are uploaded straight to R2 when it is configured
+	if presigner, err := r2.NewPresigner(cfg.R2.Endpoint, cfg.R2.CrashBucket, cfg.R2.AccessKey, cfg.R2.SecretKey); err == nil {
+		gw.CrashBundles = presigner
+	} else {
+		logger.Info("node crash bundles disabled", zap.Error(err))
+	}
+
+	// Tenant account exports are built in the background and stored in R2
+	if presigner, err := r2.NewPresigner(cfg.R2.Endpoint, cfg.R2.ExportBucket, cfg.R2.AccessKey, cfg.R2.SecretKey); err == nil {
+		gw.AccountExports = presigner
+		// Usage extracts share the export bucket under their own prefix
+		gw.UsageExports = presigner
+	} else {
+		logger.Info("account and usage exports disabled", zap.Error(err))
+	}
+
+	// Tenant files (batch and fine-tuning inputs) are stored in R2
+	if presigner, err := r2.NewPresigner(cfg.R2.Endpoint, cfg.R2.FilesBucket, cfg.R2.AccessKey, cfg.R2.SecretKey); err == nil {
+		gw.Files = presigner
+		gw.StartUploadExpiry(ctx)
+	} else {
+		logger.Info("files API disabled", zap.Error(err))
+	}
+
+	// Platform configuration snapshots are stored in R2
+	if presigner, err := r2.NewPresigner(cfg.R2.Endpoint, cfg.R2.ConfigBucket, cfg.R2.AccessKey, cfg.R2.SecretKey); err == nil {
+		gw.ConfigSnapshots = presigner
+	} else {
+		logger.Info("configuration snapshots disabled", zap.Error(err))
+	}
+
+	// Idle node launch logs are archived to R2 and served from there once
+	// they leave Redis
+	var nodeLogArchive *orchestrator.NodeLogArchive
+	if presigner, err := r2.NewPresigner(cfg.R2.Endpoint, cfg.R2.NodeLogBucket, cfg.R2.AccessKey, cfg.R2.SecretKey); err == nil {
+		nodeLogArchive = orchestrator.NewNodeLogArchive(db, redisCache, r2.NewObjects(presigner), logger, orchestrator.NodeLogRetention{
+			ArchiveAfter:    cfg.NodeLogs.ArchiveAfter,
+			Retention:       cfg.NodeLogs.Retention,
+			FailedRetention: cfg.NodeLogs.FailedRetention,
+		}, cfg.NodeLogs.ArchiveInterval)
+		gw.NodeLogArchive = nodeLogArchive
+	} else {
+		logger.Info("node log archival disabled", zap.Error(err))
+	}
+
+	// Stop routing to nodes with stale heartbeats before the monitor deregisters them
+	gw.LoadBalancer.SetStaleHeartbeatThreshold(cfg.Monitoring.StaleHeartbeatThreshold)
+
+	// Ramp newly registered nodes into routing, cordoning those that fail
+	gw.LoadBalancer.SetCanaryRamp(cfg.Monitoring.CanaryDuration, cfg.Monitoring.CanaryInitialShare,
+		cfg.Monitoring.CanaryMaxErrorRate, cfg.Monitoring.CanaryMinRequests, cfg.Monitoring.CanaryMaxLatencyFactor)
 
 	// Start queue depth monitoring for intelligent load balancing
 	gw.LoadBalancer.StartQueueMonitoring(ctx)
@@ -147,10 +343,32 @@ func main() {
 
 	// Initialize Deployment Controller
 	deploymentController := orchestrator.NewDeploymentController(db, logger, orch, gw.LoadBalancer)
+	deploymentController.SetDriftRemediation(cfg.Monitoring.DriftAutoRemediate, cfg.Monitoring.DriftRemediateAfter)
+	deploymentController.SetLaunchRetry(cfg.Monitoring.LaunchMaxAttempts, cfg.Monitoring.LaunchRetryBackoff, cfg.Monitoring.LaunchRetryMaxBackoff)
+	deploymentController.SetCapacityAlerts(cfg.Monitoring.CapacityAlertAfter)
+	deploymentController.SetAutoscaling(orchestrator.AutoscaleSettings{
+		TargetQueueDepth:  cfg.Monitoring.AutoscaleTargetQueueDepth,
+		TargetP95Latency:  cfg.Monitoring.AutoscaleTargetP95Latency,
+		ScaleUpCooldown:   cfg.Monitoring.AutoscaleScaleUpCooldown,
+		ScaleDownCooldown: cfg.Monitoring.AutoscaleScaleDownCooldown,
+	})
+	deploymentController.SubscribeFailover(eventBus)
 	logger.Info("initialized deployment controller")
 
+	// Initialize catalog sync for instance types and region availability
+	catalogSyncer := orchestrator.NewCatalogSyncer(db, logger, cfg.SkyPilot.CatalogURL, cfg.SkyPilot.CatalogSyncInterval, cfg.SkyPilot.CatalogSyncEnabled)
+	gw.CatalogSyncer = catalogSyncer
+	logger.Info("initialized catalog syncer")
+
+	// Compatibility runs smoke test vLLM releases on canary nodes before
+	// deployments may be pinned to them
+	gw.CompatTester = orchestrator.NewCompatTester(db, logger, orch)
+	gw.SetVLLMCompatGate(cfg.Runtime.CompatGate)
+	logger.Info("initialized vLLM compatibility tester", zap.Bool("gate", cfg.Runtime.CompatGate))
+
 	// Initialize Model Cache Warmer for R2/vLLM optimization
 	cacheWarmer := orchestrator.NewModelCacheWarmer(db, logger, orch)
+	deploymentController.SetCacheWarmer(cacheWarmer)
 	logger.Info("initialized model cache warmer")
 
 	// Start monitor and reconciler
@@ -167,16 +385,47 @@ func main() {
 		billingEngine.StartBackgroundJobs(ctx)
 	}
 
+	// Start scheduled catalog sync
+	catalogSyncer.Start(ctx)
+
 	// Start cost tracker aggregation loop (available even when billing disabled)
 	costTracker.Start(ctx)
 	logger.Info("started cost tracker")
 
+	// Start hourly token reconciliation
+	tokenReconciler.Start(ctx)
+
+	// Scheduled cross-check of tenants against Stripe
+	stripeReconciler.Start(ctx)
+
+	// Close due billing sandbox cycles (independent of production billing)
+	billingSandbox.Start(ctx)
+
 	// Start notification service
 	if err := notificationService.Start(ctx); err != nil {
 		logger.Fatal("failed to start notification service", zap.Error(err))
 	}
 	logger.Info("started notification service")
 
+	// Start model deprecation reminders
+	deprecationReminder.Start(ctx)
+	logger.Info("started model deprecation reminder")
+
+	// Start evaluating tenant usage alert rules
+	usageAlerts.Start(ctx)
+	logger.Info("started usage alert evaluator")
+
+	// Keep tenant spend budget state current for the gateway
+	budgets.Start(ctx)
+
+	if nodeLogArchive != nil {
+		nodeLogArchive.Start(ctx)
+	}
+
+	// Relay events committed to the outbox once their subscribers are registered
+	events.NewOutboxRelay(db, eventBus, logger).Start(ctx)
+	logger.Info("started event outbox relay")
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
@@ -186,6 +435,9 @@ func main() {
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	// Let open SSE streams hand clients a resume point instead of being cut off
+	server.RegisterOnShutdown(gw.DrainStreams)
+
 	// Start server in goroutine
 	go func() {
 		logger.Info("starting HTTP server",
@@ -196,6 +448,32 @@ func main() {
 		}
 	}()
 
+	// Node agent callbacks get their own listener when configured, so it can
+	// be firewalled to node networks
+	var nodeServer *http.Server
+	if cfg.NodeAPI.Port != 0 {
+		nodeServer = &http.Server{
+			Addr: fmt.Sprintf("%s:%d", cfg.NodeAPI.Host, cfg.NodeAPI.Port),
+			Handler: gw.NodeAPIHandler(gateway.NodeAPISettings{
+				Token:          cfg.NodeAPI.Token,
+				MaxBodyBytes:   cfg.NodeAPI.MaxBodyBytes,
+				MaxConcurrent:  cfg.NodeAPI.MaxConcurrent,
+				HandlerTimeout: cfg.NodeAPI.WriteTimeout,
+			}),
+			ReadTimeout:  cfg.NodeAPI.ReadTimeout,
+			WriteTimeout: cfg.NodeAPI.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+		}
+		go func() {
+			logger.Info("starting node API server",
+				zap.String("address", nodeServer.Addr),
+			)
+			if err := nodeServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatal("node API server failed", zap.Error(err))
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -215,6 +493,11 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		logger.Error("server forced to shutdown", zap.Error(err))
 	}
+	if nodeServer != nil {
+		if err := nodeServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("node API server forced to shutdown", zap.Error(err))
+		}
+	}
 
 	logger.Info("server exited")
 }
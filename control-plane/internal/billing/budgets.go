@@ -0,0 +1,440 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// Spend budgets.
+//
+// A budget caps what a tenant, or one of its environments, may spend in a
+// UTC day or calendar month. Spend is the cost metered into usage_records
+// since the period started. Every control plane replica refreshes the state
+// of all enabled budgets every budgetRefreshInterval and keeps the exceeded
+// ones in memory, so the gateway can check a request without a database
+// round trip. Spend recorded between refreshes can push a tenant slightly
+// past its limit before requests are rejected.
+
+var (
+	// ErrBudgetNotFound is returned when the tenant has no such budget
+	ErrBudgetNotFound = errors.New("budget not found")
+	// ErrBudgetExists is returned when the tenant already has a budget for
+	// the environment and period
+	ErrBudgetExists = errors.New("a budget for this environment and period already exists")
+	// ErrInvalidBudget wraps budget validation failures
+	ErrInvalidBudget = errors.New("invalid budget")
+)
+
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodMonthly = "monthly"
+
+	// budgetRefreshInterval is how often budget state is recomputed
+	budgetRefreshInterval = 10 * time.Second
+	// maxBudgetsPerTenant caps a tenant's budgets
+	maxBudgetsPerTenant = 50
+)
+
+// Budget is a spend cap for a tenant or one of its environments
+type Budget struct {
+	ID                uuid.UUID  `json:"id"`
+	TenantID          uuid.UUID  `json:"tenant_id"`
+	EnvironmentID     *uuid.UUID `json:"environment_id,omitempty"`
+	Period            string     `json:"period"`
+	LimitMicrodollars int64      `json:"limit_microdollars"`
+	Enabled           bool       `json:"enabled"`
+	ExceededAt        *time.Time `json:"exceeded_at,omitempty"`
+	UpdatedBy         *string    `json:"updated_by,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Validate checks the period and limit
+func (b *Budget) Validate() error {
+	if b.Period != BudgetPeriodDaily && b.Period != BudgetPeriodMonthly {
+		return fmt.Errorf("%w: period must be %s or %s", ErrInvalidBudget, BudgetPeriodDaily, BudgetPeriodMonthly)
+	}
+	if b.LimitMicrodollars <= 0 {
+		return fmt.Errorf("%w: limit_microdollars must be positive", ErrInvalidBudget)
+	}
+	return nil
+}
+
+// BudgetStatus is a budget with its spend and burn rate in the current
+// period. The burn rate is spend over the last hour; projections assume it
+// holds until the period ends.
+type BudgetStatus struct {
+	Budget
+	PeriodStart                 time.Time  `json:"period_start"`
+	PeriodEnd                   time.Time  `json:"period_end"`
+	SpentMicrodollars           int64      `json:"spent_microdollars"`
+	RemainingMicrodollars       int64      `json:"remaining_microdollars"`
+	PercentUsed                 float64    `json:"percent_used"`
+	AverageMicrodollarsPerHour  int64      `json:"average_microdollars_per_hour"`
+	BurnRateMicrodollarsPerHour int64      `json:"burn_rate_microdollars_per_hour"`
+	ProjectedMicrodollars       int64      `json:"projected_microdollars"`
+	ProjectedExhaustionAt       *time.Time `json:"projected_exhaustion_at,omitempty"`
+	Exceeded                    bool       `json:"exceeded"`
+}
+
+// budgetPeriod returns the UTC period containing now
+func budgetPeriod(period string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	if period == BudgetPeriodDaily {
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	}
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// newBudgetStatus computes a budget's state from its spend in the period
+// and over the last hour
+func newBudgetStatus(b Budget, spent, lastHour int64, now time.Time) BudgetStatus {
+	s := BudgetStatus{Budget: b, SpentMicrodollars: spent}
+	s.PeriodStart, s.PeriodEnd = budgetPeriod(b.Period, now)
+	s.RemainingMicrodollars = max(b.LimitMicrodollars-spent, 0)
+	s.PercentUsed = float64(spent) / float64(b.LimitMicrodollars) * 100
+	s.Exceeded = spent >= b.LimitMicrodollars
+
+	if elapsed := now.Sub(s.PeriodStart).Hours(); elapsed > 0 {
+		s.AverageMicrodollarsPerHour = int64(float64(spent) / elapsed)
+	}
+	// The last hour may reach back before the period started
+	window := min(time.Hour, now.Sub(s.PeriodStart))
+	if window > 0 {
+		s.BurnRateMicrodollarsPerHour = int64(float64(lastHour) / window.Hours())
+	}
+
+	left := s.PeriodEnd.Sub(now)
+	s.ProjectedMicrodollars = spent + int64(float64(s.BurnRateMicrodollarsPerHour)*left.Hours())
+	if !s.Exceeded && s.BurnRateMicrodollarsPerHour > 0 && s.ProjectedMicrodollars >= b.LimitMicrodollars {
+		hours := float64(s.RemainingMicrodollars) / float64(s.BurnRateMicrodollarsPerHour)
+		at := now.Add(time.Duration(hours * float64(time.Hour))).UTC()
+		s.ProjectedExhaustionAt = &at
+	}
+	return s
+}
+
+// budgetScope identifies what a budget covers; a tenant-wide budget has a
+// nil environment
+type budgetScope struct {
+	tenantID      uuid.UUID
+	environmentID uuid.UUID
+}
+
+// Budgets stores spend budgets and tracks which are exceeded
+type Budgets struct {
+	db       *database.Database
+	logger   *zap.Logger
+	bus      *events.Bus
+	interval time.Duration
+
+	mu       sync.RWMutex
+	exceeded map[budgetScope]BudgetStatus
+}
+
+// NewBudgets creates the spend budget store
+func NewBudgets(db *database.Database, logger *zap.Logger, bus *events.Bus) *Budgets {
+	return &Budgets{
+		db:       db,
+		logger:   logger,
+		bus:      bus,
+		interval: budgetRefreshInterval,
+		exceeded: make(map[budgetScope]BudgetStatus),
+	}
+}
+
+// Start loads budget state and keeps it current in the background
+func (b *Budgets) Start(ctx context.Context) {
+	b.logger.Info("starting spend budget enforcement")
+	if err := b.Refresh(ctx); err != nil {
+		b.logger.Error("failed to load spend budgets", zap.Error(err))
+	}
+	go func() {
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := b.Refresh(ctx); err != nil {
+					b.logger.Warn("failed to refresh spend budgets", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Exceeded returns the exceeded budget covering a request from the tenant's
+// environment, or nil when it may spend
+func (b *Budgets) Exceeded(tenantID, environmentID uuid.UUID) *BudgetStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.exceeded) == 0 {
+		return nil
+	}
+	for _, scope := range []budgetScope{{tenantID, environmentID}, {tenantID, uuid.Nil}} {
+		if s, ok := b.exceeded[scope]; ok {
+			return &s
+		}
+	}
+	return nil
+}
+
+// setExceeded replaces the exceeded budgets. Where a tenant or environment
+// has exceeded both a daily and a monthly budget, the one that resets last
+// is kept.
+func (b *Budgets) setExceeded(statuses []BudgetStatus) {
+	exceeded := make(map[budgetScope]BudgetStatus)
+	for _, s := range statuses {
+		if !s.Enabled || !s.Exceeded {
+			continue
+		}
+		scope := budgetScope{tenantID: s.TenantID}
+		if s.EnvironmentID != nil {
+			scope.environmentID = *s.EnvironmentID
+		}
+		if cur, ok := exceeded[scope]; ok && !s.PeriodEnd.After(cur.PeriodEnd) {
+			continue
+		}
+		exceeded[scope] = s
+	}
+
+	b.mu.Lock()
+	b.exceeded = exceeded
+	b.mu.Unlock()
+}
+
+// Refresh recomputes the spend of every enabled budget and marks budgets
+// that have newly been exceeded
+func (b *Budgets) Refresh(ctx context.Context) error {
+	now := time.Now()
+	statuses, err := b.loadStatuses(ctx, nil, now)
+	if err != nil {
+		return err
+	}
+	b.setExceeded(statuses)
+
+	for _, s := range statuses {
+		if s.Exceeded && (s.ExceededAt == nil || s.ExceededAt.Before(s.PeriodStart)) {
+			b.markExceeded(ctx, s)
+		}
+	}
+	return nil
+}
+
+// markExceeded records that a budget was exceeded this period. The update
+// is conditional so only one replica reports it.
+func (b *Budgets) markExceeded(ctx context.Context, s BudgetStatus) {
+	tag, err := b.db.Pool.Exec(ctx, `
+		UPDATE spend_budgets SET exceeded_at = NOW()
+		WHERE id = $1 AND (exceeded_at IS NULL OR exceeded_at < $2)
+	`, s.ID, s.PeriodStart)
+	if err != nil {
+		b.logger.Warn("failed to mark budget exceeded", zap.Error(err), zap.String("budget_id", s.ID.String()))
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		return
+	}
+
+	b.logger.Warn("tenant spend budget exceeded",
+		zap.String("budget_id", s.ID.String()),
+		zap.String("tenant_id", s.TenantID.String()),
+		zap.String("period", s.Period),
+		zap.Int64("limit_microdollars", s.LimitMicrodollars),
+		zap.Int64("spent_microdollars", s.SpentMicrodollars),
+	)
+	if b.bus == nil {
+		return
+	}
+	payload := map[string]interface{}{
+		"budget_id":          s.ID.String(),
+		"period":             s.Period,
+		"limit_microdollars": s.LimitMicrodollars,
+		"spent_microdollars": s.SpentMicrodollars,
+		"resets_at":          s.PeriodEnd.Format(time.RFC3339),
+	}
+	if s.EnvironmentID != nil {
+		payload["environment_id"] = s.EnvironmentID.String()
+	}
+	if err := b.bus.Publish(ctx, events.NewEvent(events.EventBudgetExceeded, s.TenantID.String(), payload)); err != nil {
+		b.logger.Warn("failed to publish budget exceeded event", zap.Error(err))
+	}
+}
+
+const budgetColumns = `b.id, b.tenant_id, b.environment_id, b.period, b.limit_microdollars, b.enabled,
+	b.exceeded_at, b.updated_by, b.created_at, b.updated_at`
+
+func scanBudget(row pgx.Row, extra ...any) (*Budget, error) {
+	var b Budget
+	dest := append([]any{&b.ID, &b.TenantID, &b.EnvironmentID, &b.Period, &b.LimitMicrodollars, &b.Enabled,
+		&b.ExceededAt, &b.UpdatedBy, &b.CreatedAt, &b.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// loadStatuses computes the state of a tenant's budgets, or of every
+// enabled budget when tenantID is nil
+func (b *Budgets) loadStatuses(ctx context.Context, tenantID *uuid.UUID, now time.Time) ([]BudgetStatus, error) {
+	dayStart, _ := budgetPeriod(BudgetPeriodDaily, now)
+	monthStart, _ := budgetPeriod(BudgetPeriodMonthly, now)
+
+	rows, err := b.db.Pool.Query(ctx, `
+		SELECT `+budgetColumns+`,
+		       COALESCE(SUM(ur.cost_microdollars), 0),
+		       COALESCE(SUM(ur.cost_microdollars) FILTER (WHERE ur.timestamp >= $3), 0)
+		FROM spend_budgets b
+		LEFT JOIN usage_records ur
+		       ON ur.tenant_id = b.tenant_id
+		      AND (b.environment_id IS NULL OR ur.environment_id = b.environment_id)
+		      AND ur.timestamp >= CASE b.period WHEN 'daily' THEN $1::timestamptz ELSE $2::timestamptz END
+		WHERE ($4::uuid IS NULL AND b.enabled) OR b.tenant_id = $4
+		GROUP BY b.id
+		ORDER BY b.created_at
+	`, dayStart, monthStart, now.Add(-time.Hour), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []BudgetStatus
+	for rows.Next() {
+		var spent, lastHour int64
+		budget, err := scanBudget(rows, &spent, &lastHour)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, newBudgetStatus(*budget, spent, lastHour, now))
+	}
+	return statuses, rows.Err()
+}
+
+// List returns a tenant's budgets with their current spend and burn rate
+func (b *Budgets) List(ctx context.Context, tenantID uuid.UUID) ([]BudgetStatus, error) {
+	statuses, err := b.loadStatuses(ctx, &tenantID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if statuses == nil {
+		statuses = []BudgetStatus{}
+	}
+	return statuses, nil
+}
+
+// Get returns one of a tenant's budgets with its current spend
+func (b *Budgets) Get(ctx context.Context, tenantID, id uuid.UUID) (*BudgetStatus, error) {
+	statuses, err := b.loadStatuses(ctx, &tenantID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range statuses {
+		if statuses[i].ID == id {
+			return &statuses[i], nil
+		}
+	}
+	return nil, ErrBudgetNotFound
+}
+
+// Create adds a budget for a tenant or one of its environments
+func (b *Budgets) Create(ctx context.Context, tenantID uuid.UUID, budget Budget, actor *string) (*BudgetStatus, error) {
+	if err := budget.Validate(); err != nil {
+		return nil, err
+	}
+	if budget.EnvironmentID != nil {
+		var exists bool
+		err := b.db.Pool.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM environments WHERE id = $1 AND tenant_id = $2)
+		`, *budget.EnvironmentID, tenantID).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: environment not found for this tenant", ErrInvalidBudget)
+		}
+	}
+	var count int
+	if err := b.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM spend_budgets WHERE tenant_id = $1`, tenantID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= maxBudgetsPerTenant {
+		return nil, fmt.Errorf("%w: at most %d budgets per tenant", ErrInvalidBudget, maxBudgetsPerTenant)
+	}
+
+	var id uuid.UUID
+	err := b.db.Pool.QueryRow(ctx, `
+		INSERT INTO spend_budgets (tenant_id, environment_id, period, limit_microdollars, enabled, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, tenantID, budget.EnvironmentID, budget.Period, budget.LimitMicrodollars, budget.Enabled, actor).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrBudgetExists
+		}
+		return nil, err
+	}
+	return b.changed(ctx, tenantID, id)
+}
+
+// Update changes a budget's limit or switches it on or off; nil leaves a
+// setting as it is
+func (b *Budgets) Update(ctx context.Context, tenantID, id uuid.UUID, limitMicrodollars *int64, enabled *bool, actor *string) (*BudgetStatus, error) {
+	if limitMicrodollars != nil && *limitMicrodollars <= 0 {
+		return nil, fmt.Errorf("%w: limit_microdollars must be positive", ErrInvalidBudget)
+	}
+	tag, err := b.db.Pool.Exec(ctx, `
+		UPDATE spend_budgets SET
+			limit_microdollars = COALESCE($3, limit_microdollars),
+			enabled = COALESCE($4, enabled),
+			updated_by = $5,
+			updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID, limitMicrodollars, enabled, actor)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrBudgetNotFound
+	}
+	return b.changed(ctx, tenantID, id)
+}
+
+// Delete removes a budget
+func (b *Budgets) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	tag, err := b.db.Pool.Exec(ctx, `DELETE FROM spend_budgets WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrBudgetNotFound
+	}
+	if err := b.Refresh(ctx); err != nil {
+		b.logger.Warn("failed to refresh spend budgets", zap.Error(err))
+	}
+	return nil
+}
+
+// changed applies a budget change on this replica at once and returns the
+// budget's state; other replicas pick it up on their next refresh
+func (b *Budgets) changed(ctx context.Context, tenantID, id uuid.UUID) (*BudgetStatus, error) {
+	if err := b.Refresh(ctx); err != nil {
+		b.logger.Warn("failed to refresh spend budgets", zap.Error(err))
+	}
+	return b.Get(ctx, tenantID, id)
+}
@@ -0,0 +1,106 @@
+package billing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestBudgetPeriod(t *testing.T) {
+	now := time.Date(2026, time.February, 14, 18, 30, 0, 0, time.FixedZone("PST", -8*3600))
+
+	start, end := budgetPeriod(BudgetPeriodDaily, now)
+	if !start.Equal(time.Date(2026, time.February, 15, 0, 0, 0, 0, time.UTC)) || end.Sub(start) != 24*time.Hour {
+		t.Errorf("daily period = %s - %s, want the UTC day", start, end)
+	}
+	start, end = budgetPeriod(BudgetPeriodMonthly, now)
+	if !start.Equal(time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)) ||
+		!end.Equal(time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("monthly period = %s - %s", start, end)
+	}
+}
+
+func TestBudgetValidate(t *testing.T) {
+	if err := (&Budget{Period: BudgetPeriodDaily, LimitMicrodollars: 1}).Validate(); err != nil {
+		t.Errorf("valid budget rejected: %v", err)
+	}
+	for name, b := range map[string]Budget{
+		"weekly":     {Period: "weekly", LimitMicrodollars: 1},
+		"zero limit": {Period: BudgetPeriodMonthly},
+	} {
+		if err := b.Validate(); !errors.Is(err, ErrInvalidBudget) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestNewBudgetStatus(t *testing.T) {
+	now := time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC)
+	budget := Budget{Period: BudgetPeriodDaily, LimitMicrodollars: 100_000_000}
+
+	// $30 spent over 12 hours, $5 of it in the last hour
+	s := newBudgetStatus(budget, 30_000_000, 5_000_000, now)
+	if s.Exceeded || s.RemainingMicrodollars != 70_000_000 || s.PercentUsed != 30 {
+		t.Errorf("status = %+v", s)
+	}
+	if s.AverageMicrodollarsPerHour != 2_500_000 || s.BurnRateMicrodollarsPerHour != 5_000_000 {
+		t.Errorf("rates = %d average, %d burn", s.AverageMicrodollarsPerHour, s.BurnRateMicrodollarsPerHour)
+	}
+	// 12 more hours at $5/hour reaches $90, under the limit
+	if s.ProjectedMicrodollars != 90_000_000 || s.ProjectedExhaustionAt != nil {
+		t.Errorf("projection = %d, exhaustion %v", s.ProjectedMicrodollars, s.ProjectedExhaustionAt)
+	}
+
+	// At $10/hour the remaining $70 lasts 7 hours
+	s = newBudgetStatus(budget, 30_000_000, 10_000_000, now)
+	if s.ProjectedExhaustionAt == nil || !s.ProjectedExhaustionAt.Equal(now.Add(7*time.Hour)) {
+		t.Errorf("exhaustion = %v, want %s", s.ProjectedExhaustionAt, now.Add(7*time.Hour))
+	}
+
+	// Shortly after midnight the burn rate covers only the period so far
+	s = newBudgetStatus(budget, 1_000_000, 1_000_000, time.Date(2026, time.March, 2, 0, 15, 0, 0, time.UTC))
+	if s.BurnRateMicrodollarsPerHour != 4_000_000 {
+		t.Errorf("burn rate = %d, want spend scaled from 15 minutes", s.BurnRateMicrodollarsPerHour)
+	}
+
+	s = newBudgetStatus(budget, 120_000_000, 0, now)
+	if !s.Exceeded || s.RemainingMicrodollars != 0 {
+		t.Errorf("over-limit status = %+v", s)
+	}
+}
+
+func TestBudgetsExceeded(t *testing.T) {
+	b := NewBudgets(nil, zap.NewNop(), nil)
+	tenant, env, otherEnv := uuid.New(), uuid.New(), uuid.New()
+	now := time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC)
+
+	envBudget := newBudgetStatus(Budget{ID: uuid.New(), TenantID: tenant, EnvironmentID: &env,
+		Period: BudgetPeriodDaily, LimitMicrodollars: 10, Enabled: true}, 10, 0, now)
+	disabled := newBudgetStatus(Budget{ID: uuid.New(), TenantID: tenant,
+		Period: BudgetPeriodDaily, LimitMicrodollars: 10}, 50, 0, now)
+	b.setExceeded([]BudgetStatus{envBudget, disabled})
+
+	if got := b.Exceeded(tenant, env); got == nil || got.ID != envBudget.ID {
+		t.Errorf("environment budget not enforced: %v", got)
+	}
+	if got := b.Exceeded(tenant, otherEnv); got != nil {
+		t.Errorf("environment budget applied to another environment, or disabled budget enforced: %v", got)
+	}
+
+	// A tenant-wide budget covers every environment, and the one that
+	// resets last is reported
+	daily := newBudgetStatus(Budget{ID: uuid.New(), TenantID: tenant,
+		Period: BudgetPeriodDaily, LimitMicrodollars: 10, Enabled: true}, 10, 0, now)
+	monthly := newBudgetStatus(Budget{ID: uuid.New(), TenantID: tenant,
+		Period: BudgetPeriodMonthly, LimitMicrodollars: 10, Enabled: true}, 10, 0, now)
+	b.setExceeded([]BudgetStatus{daily, monthly})
+	if got := b.Exceeded(tenant, otherEnv); got == nil || got.ID != monthly.ID {
+		t.Errorf("tenant budget = %v, want the monthly budget", got)
+	}
+	if got := b.Exceeded(uuid.New(), env); got != nil {
+		t.Errorf("budget applied to another tenant: %v", got)
+	}
+}
@@ -282,6 +282,8 @@ type UsageRecord struct {
 	RegionID         uuid.UUID
 	ModelID          uuid.UUID
 	Model            string
+	GPUType          string
+	StatusCode       int
 	PromptTokens     int
 	CompletionTokens int
 	TotalTokens      int
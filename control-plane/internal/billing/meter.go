@@ -24,13 +24,19 @@ func NewTokenMeter(db *database.Database, logger *zap.Logger) *TokenMeter {
 
 // Record records token usage
 func (tm *TokenMeter) Record(ctx context.Context, usage *UsageRecord, cost int64) error {
+	// model_id falls back to a lookup by name so breakdowns don't depend on
+	// callers resolving it; gpu_type and status_code are stored as reported
 	_, err := tm.db.Pool.Exec(ctx, `
 		INSERT INTO usage_records (
 			request_id, tenant_id, environment_id, api_key_id,
-			region_id, model_id, prompt_tokens, completion_tokens,
+			region_id, model_id, gpu_type, status_code,
+			prompt_tokens, completion_tokens,
 			total_tokens, latency_ms, cost_microdollars, billed
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, false
+			$1, $2, $3, $4, $5,
+			COALESCE(NULLIF($6, '00000000-0000-0000-0000-000000000000'::uuid), (SELECT id FROM models WHERE name = $14)),
+			NULLIF($7, ''), NULLIF($8, 0),
+			$9, $10, $11, $12, $13, false
 		)
 	`,
 		usage.RequestID,
@@ -39,11 +45,14 @@ func (tm *TokenMeter) Record(ctx context.Context, usage *UsageRecord, cost int64
 		usage.APIKeyID,
 		usage.RegionID,
 		usage.ModelID,
+		usage.GPUType,
+		usage.StatusCode,
 		usage.PromptTokens,
 		usage.CompletionTokens,
 		usage.TotalTokens,
 		usage.LatencyMs,
 		cost,
+		usage.Model,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert usage record: %w", err)
@@ -0,0 +1,220 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/customer"
+	"github.com/stripe/stripe-go/v76/subscription"
+	"go.uber.org/zap"
+)
+
+// Plan describes a subscription plan: its rank for upgrades, the limits
+// applied to the tenant's API keys, and the Stripe price that bills it.
+type Plan struct {
+	Name             string `json:"name"`
+	Rank             int    `json:"-"`
+	RequestsPerMin   int    `json:"requests_per_min"`
+	ConcurrencyLimit int    `json:"concurrency_limit"`
+	TokensPerMin     *int   `json:"tokens_per_min,omitempty"`
+	// CloudCredentials enables the bring-your-own-cloud credential endpoints
+	CloudCredentials bool `json:"cloud_credentials"`
+	// PrewarmReplicas caps the extra replicas the tenant's scheduled prewarms
+	// may hold at once; zero disables POST /v1/models/{model}/prewarm
+	PrewarmReplicas int `json:"prewarm_replicas"`
+	// RequestTimeoutSecs caps the deadline a request may ask for with
+	// X-Request-Timeout or a timeout field
+	RequestTimeoutSecs int `json:"request_timeout_seconds"`
+	// FileStorageBytes caps the total size of the tenant's uploaded files
+	FileStorageBytes int64 `json:"file_storage_bytes"`
+	// MaxChatMessages caps the messages of a chat request; zero is unlimited
+	MaxChatMessages int `json:"max_chat_messages"`
+	// MaxMessageChars caps the text of a single chat message, in characters;
+	// zero is unlimited
+	MaxMessageChars int `json:"max_message_chars"`
+	// SelfServe plans can be chosen via POST /v1/billing/upgrade; others need sales
+	SelfServe     bool   `json:"self_serve"`
+	StripePriceID string `json:"-"`
+}
+
+// defaultPlans are the built-in plans. Limits match api_keys column defaults
+// for the free plan.
+var defaultPlans = []Plan{
+	{Name: "free", Rank: 0, RequestsPerMin: 60, ConcurrencyLimit: 5, RequestTimeoutSecs: 60, FileStorageBytes: 1 << 30, MaxChatMessages: 50, MaxMessageChars: 32000},
+	{Name: "starter", Rank: 1, RequestsPerMin: 300, ConcurrencyLimit: 10, RequestTimeoutSecs: 120, FileStorageBytes: 10 << 30, MaxChatMessages: 100, MaxMessageChars: 64000, SelfServe: true},
+	{Name: "pro", Rank: 2, RequestsPerMin: 1000, ConcurrencyLimit: 50, CloudCredentials: true, PrewarmReplicas: 2, RequestTimeoutSecs: 300, FileStorageBytes: 100 << 30, MaxChatMessages: 500, MaxMessageChars: 200000, SelfServe: true},
+	{Name: "enterprise", Rank: 3, RequestsPerMin: 5000, ConcurrencyLimit: 200, CloudCredentials: true, PrewarmReplicas: 10, RequestTimeoutSecs: 600, FileStorageBytes: 1 << 40},
+}
+
+// PlanCatalog resolves plans by name and by Stripe price ID
+type PlanCatalog struct {
+	plans map[string]Plan
+}
+
+// NewPlanCatalog creates the plan catalog with Stripe price IDs keyed by
+// plan name (e.g. {"pro": "price_..."}). Plans without a price are free.
+func NewPlanCatalog(priceIDs map[string]string) *PlanCatalog {
+	c := &PlanCatalog{plans: make(map[string]Plan, len(defaultPlans))}
+	for _, p := range defaultPlans {
+		p.StripePriceID = priceIDs[p.Name]
+		c.plans[p.Name] = p
+	}
+	return c
+}
+
+// Get returns a plan by name. Legacy plan names (e.g. "serverless") resolve
+// to the free plan.
+func (c *PlanCatalog) Get(name string) Plan {
+	if p, ok := c.plans[name]; ok {
+		return p
+	}
+	return c.plans["free"]
+}
+
+// Lookup returns a plan by name, reporting whether it exists
+func (c *PlanCatalog) Lookup(name string) (Plan, bool) {
+	p, ok := c.plans[name]
+	return p, ok
+}
+
+// PlanForPrice returns the plan billed by a Stripe price ID
+func (c *PlanCatalog) PlanForPrice(priceID string) (Plan, bool) {
+	if priceID == "" {
+		return Plan{}, false
+	}
+	for _, p := range c.plans {
+		if p.StripePriceID == priceID {
+			return p, true
+		}
+	}
+	return Plan{}, false
+}
+
+// SetChatLimits overrides the plans' chat request limits, keyed by plan
+// name. Plans left out keep their built-in limits; zero lifts a limit.
+func (c *PlanCatalog) SetChatLimits(maxMessages, maxMessageChars map[string]int) error {
+	for _, limits := range []map[string]int{maxMessages, maxMessageChars} {
+		for name, limit := range limits {
+			if _, ok := c.plans[name]; !ok {
+				return fmt.Errorf("unknown plan %q", name)
+			}
+			if limit < 0 {
+				return fmt.Errorf("plan %s: chat limits can't be negative", name)
+			}
+		}
+	}
+	for name, limit := range maxMessages {
+		p := c.plans[name]
+		p.MaxChatMessages = limit
+		c.plans[name] = p
+	}
+	for name, limit := range maxMessageChars {
+		p := c.plans[name]
+		p.MaxMessageChars = limit
+		c.plans[name] = p
+	}
+	return nil
+}
+
+// SubscriptionChange is a request to move a tenant's Stripe subscription to a new price
+type SubscriptionChange struct {
+	TenantID       string
+	TenantName     string
+	TenantEmail    string
+	CustomerID     *string
+	SubscriptionID *string
+	PriceID        string
+}
+
+// SubscriptionResult identifies the Stripe objects after a plan change
+type SubscriptionResult struct {
+	CustomerID     string
+	SubscriptionID string
+	Status         string
+}
+
+// SubscriptionManager creates or updates tenant subscriptions
+type SubscriptionManager interface {
+	ChangePlan(ctx context.Context, change SubscriptionChange) (*SubscriptionResult, error)
+}
+
+// StripeSubscriptions manages subscriptions through the Stripe API. The API
+// key is set globally by NewEngine.
+type StripeSubscriptions struct {
+	logger *zap.Logger
+}
+
+// NewStripeSubscriptions creates a Stripe-backed subscription manager
+func NewStripeSubscriptions(logger *zap.Logger) *StripeSubscriptions {
+	return &StripeSubscriptions{logger: logger}
+}
+
+// ChangePlan moves the tenant onto the given price, creating the Stripe
+// customer and subscription if they don't exist yet. Existing subscriptions
+// are updated in place with prorations.
+func (s *StripeSubscriptions) ChangePlan(ctx context.Context, change SubscriptionChange) (*SubscriptionResult, error) {
+	customerID := ""
+	if change.CustomerID != nil {
+		customerID = *change.CustomerID
+	}
+	if customerID == "" {
+		cust, err := customer.New(&stripe.CustomerParams{
+			Params: stripe.Params{
+				Context:  ctx,
+				Metadata: map[string]string{"tenant_id": change.TenantID},
+			},
+			Email: stripe.String(change.TenantEmail),
+			Name:  stripe.String(change.TenantName),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stripe customer: %w", err)
+		}
+		customerID = cust.ID
+	}
+
+	if change.SubscriptionID != nil && *change.SubscriptionID != "" {
+		current, err := subscription.Get(*change.SubscriptionID, &stripe.SubscriptionParams{Params: stripe.Params{Context: ctx}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch stripe subscription: %w", err)
+		}
+		if len(current.Items.Data) == 0 {
+			return nil, fmt.Errorf("stripe subscription %s has no items", current.ID)
+		}
+
+		updated, err := subscription.Update(current.ID, &stripe.SubscriptionParams{
+			Params: stripe.Params{Context: ctx},
+			Items: []*stripe.SubscriptionItemsParams{{
+				ID:    stripe.String(current.Items.Data[0].ID),
+				Price: stripe.String(change.PriceID),
+			}},
+			ProrationBehavior: stripe.String("create_prorations"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update stripe subscription: %w", err)
+		}
+		return &SubscriptionResult{CustomerID: customerID, SubscriptionID: updated.ID, Status: string(updated.Status)}, nil
+	}
+
+	created, err := subscription.New(&stripe.SubscriptionParams{
+		Params: stripe.Params{
+			Context:  ctx,
+			Metadata: map[string]string{"tenant_id": change.TenantID},
+		},
+		Customer: stripe.String(customerID),
+		Items: []*stripe.SubscriptionItemsParams{{
+			Price: stripe.String(change.PriceID),
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stripe subscription: %w", err)
+	}
+
+	s.logger.Info("created stripe subscription",
+		zap.String("tenant_id", change.TenantID),
+		zap.String("customer_id", customerID),
+		zap.String("subscription_id", created.ID),
+	)
+
+	return &SubscriptionResult{CustomerID: customerID, SubscriptionID: created.ID, Status: string(created.Status)}, nil
+}
@@ -0,0 +1,48 @@
+package billing
+
+import "testing"
+
+func TestPlanCatalog(t *testing.T) {
+	plans := NewPlanCatalog(map[string]string{"pro": "price_pro"})
+
+	if got := plans.Get("serverless").Name; got != "free" {
+		t.Errorf("Get(legacy plan) = %q, want free", got)
+	}
+	if _, ok := plans.Lookup("serverless"); ok {
+		t.Error("Lookup(legacy plan) should not resolve")
+	}
+
+	plan, ok := plans.PlanForPrice("price_pro")
+	if !ok || plan.Name != "pro" {
+		t.Errorf("PlanForPrice(price_pro) = %q, %v, want pro", plan.Name, ok)
+	}
+	if _, ok := plans.PlanForPrice(""); ok {
+		t.Error("PlanForPrice(\"\") should not match plans without a price")
+	}
+	if _, ok := plans.PlanForPrice("price_unknown"); ok {
+		t.Error("PlanForPrice(unknown) should not match")
+	}
+}
+
+func TestPlanCatalogSetChatLimits(t *testing.T) {
+	plans := NewPlanCatalog(nil)
+	if err := plans.SetChatLimits(map[string]int{"free": 20}, map[string]int{"pro": 0}); err != nil {
+		t.Fatalf("SetChatLimits() error = %v", err)
+	}
+	if got := plans.Get("free"); got.MaxChatMessages != 20 || got.MaxMessageChars != 32000 {
+		t.Errorf("free limits = %d messages / %d chars, want 20 / 32000", got.MaxChatMessages, got.MaxMessageChars)
+	}
+	if got := plans.Get("pro").MaxMessageChars; got != 0 {
+		t.Errorf("pro MaxMessageChars = %d, want the limit lifted", got)
+	}
+
+	if err := plans.SetChatLimits(map[string]int{"serverless": 10}, nil); err == nil {
+		t.Error("SetChatLimits(unknown plan) should fail")
+	}
+	if err := plans.SetChatLimits(nil, map[string]int{"starter": -1}); err == nil {
+		t.Error("SetChatLimits(negative limit) should fail")
+	}
+	if got := plans.Get("starter").MaxMessageChars; got != 64000 {
+		t.Errorf("starter MaxMessageChars = %d, want a failed call to change nothing", got)
+	}
+}
@@ -0,0 +1,382 @@
+package billing
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// Token reconciliation compares the tokens we bill (usage_records) against the
+// tokens vLLM itself reports having processed, per node per hour.
+//
+// vLLM exports cumulative Prometheus counters (vllm:prompt_tokens_total and
+// vllm:generation_tokens_total). The reconciler snapshots them on every hour
+// boundary; the difference between two consecutive snapshots is what the node
+// processed in that hour, which is compared with the usage records written for
+// the same node and hour. Discrepancies above the threshold are flagged.
+const (
+	// reconciliationThresholdPercent is the discrepancy above which an hour is flagged
+	reconciliationThresholdPercent = 1.0
+	// snapshotTolerance is how far from the hour boundary a snapshot may be
+	// taken and still be used for reconciliation
+	snapshotTolerance = 2 * time.Minute
+)
+
+// vLLM Prometheus counter names
+const (
+	vllmPromptTokensMetric     = "vllm:prompt_tokens_total"
+	vllmGenerationTokensMetric = "vllm:generation_tokens_total"
+)
+
+// TokenCounters are vLLM's cumulative token counters at a point in time
+type TokenCounters struct {
+	PromptTokens     int64
+	GenerationTokens int64
+}
+
+// TokenReconciliation is the result of reconciling one node for one hour
+type TokenReconciliation struct {
+	NodeID                 string    `json:"node_id"`
+	Hour                   time.Time `json:"hour"`
+	BilledPromptTokens     int64     `json:"billed_prompt_tokens"`
+	BilledCompletionTokens int64     `json:"billed_completion_tokens"`
+	VLLMPromptTokens       int64     `json:"vllm_prompt_tokens"`
+	VLLMGenerationTokens   int64     `json:"vllm_generation_tokens"`
+	DiscrepancyPercent     float64   `json:"discrepancy_percent"`
+	Flagged                bool      `json:"flagged"`
+}
+
+// TokenReconciler snapshots vLLM token counters and reconciles them against
+// billed usage every hour
+type TokenReconciler struct {
+	db         *database.Database
+	logger     *zap.Logger
+	httpClient *http.Client
+}
+
+// NewTokenReconciler creates a new token reconciliation job
+func NewTokenReconciler(db *database.Database, logger *zap.Logger) *TokenReconciler {
+	return &TokenReconciler{
+		db:     db,
+		logger: logger,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+// Start begins the reconciliation loop
+func (tr *TokenReconciler) Start(ctx context.Context) {
+	tr.logger.Info("starting token reconciler")
+	go tr.reconciliationLoop(ctx)
+}
+
+// reconciliationLoop runs on every hour boundary. Unlike the other hourly
+// jobs it is aligned to the clock, since snapshots must bracket whole hours.
+func (tr *TokenReconciler) reconciliationLoop(ctx context.Context) {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Hour).Add(time.Hour)
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		hour := time.Now().Truncate(time.Hour)
+		tr.snapshotCounters(ctx, hour)
+		if err := tr.ReconcileHour(ctx, hour.Add(-time.Hour)); err != nil {
+			tr.logger.Error("failed to reconcile billed tokens", zap.Error(err))
+		}
+	}
+}
+
+// snapshotCounters records vLLM's token counters for every active node
+func (tr *TokenReconciler) snapshotCounters(ctx context.Context, hour time.Time) {
+	if time.Since(hour) > snapshotTolerance {
+		tr.logger.Warn("skipping token counter snapshot taken too far from the hour boundary",
+			zap.Time("hour", hour),
+		)
+		return
+	}
+
+	rows, err := tr.db.Pool.Query(ctx, `
+		SELECT id, endpoint_url FROM nodes
+		WHERE status = 'active' AND endpoint_url != ''
+	`)
+	if err != nil {
+		tr.logger.Error("failed to fetch active nodes for token snapshot", zap.Error(err))
+		return
+	}
+	defer rows.Close()
+
+	type node struct {
+		id       string
+		endpoint string
+	}
+	var nodes []node
+	for rows.Next() {
+		var n node
+		if err := rows.Scan(&n.id, &n.endpoint); err != nil {
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+
+	for _, n := range nodes {
+		counters, err := tr.fetchCounters(ctx, n.endpoint)
+		if err != nil {
+			tr.logger.Warn("failed to scrape vLLM token counters",
+				zap.String("node_id", n.id),
+				zap.String("endpoint", n.endpoint),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		_, err = tr.db.Pool.Exec(ctx, `
+			INSERT INTO node_token_snapshots (node_id, hour, prompt_tokens_total, generation_tokens_total)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (node_id, hour) DO NOTHING
+		`, n.id, hour, counters.PromptTokens, counters.GenerationTokens)
+		if err != nil {
+			tr.logger.Error("failed to store token snapshot",
+				zap.String("node_id", n.id),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// fetchCounters scrapes a node's Prometheus endpoint for token counters
+func (tr *TokenReconciler) fetchCounters(ctx context.Context, endpoint string) (*TokenCounters, error) {
+	metricsURL := endpoint + "/metrics"
+	if !strings.HasPrefix(endpoint, "http") {
+		metricsURL = "http://" + metricsURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metricsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := tr.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics endpoint returned HTTP %d", resp.StatusCode)
+	}
+
+	return parseTokenCounters(resp.Body)
+}
+
+// parseTokenCounters sums vLLM's token counters across all label sets in a
+// Prometheus text exposition
+func parseTokenCounters(r io.Reader) (*TokenCounters, error) {
+	counters := &TokenCounters{}
+	found := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := parseSampleLine(line)
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case vllmPromptTokensMetric:
+			counters.PromptTokens += int64(value)
+			found = true
+		case vllmGenerationTokensMetric:
+			counters.GenerationTokens += int64(value)
+			found = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no vLLM token counters found")
+	}
+	return counters, nil
+}
+
+// parseSampleLine splits `name{labels} value [timestamp]` into name and value
+func parseSampleLine(line string) (string, float64, bool) {
+	var name, rest string
+	if idx := strings.IndexByte(line, '{'); idx != -1 {
+		end := strings.LastIndexByte(line, '}')
+		if end < idx {
+			return "", 0, false
+		}
+		name, rest = line[:idx], line[end+1:]
+	} else {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return "", 0, false
+		}
+		name, rest = fields[0], strings.Join(fields[1:], " ")
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return name, value, true
+}
+
+// reconcile compares billed tokens with vLLM's counter deltas for one hour
+func reconcile(nodeID string, hour time.Time, billedPrompt, billedCompletion int64, start, end TokenCounters) (*TokenReconciliation, bool) {
+	vllmPrompt := end.PromptTokens - start.PromptTokens
+	vllmGeneration := end.GenerationTokens - start.GenerationTokens
+	if vllmPrompt < 0 || vllmGeneration < 0 {
+		// Counters reset (vLLM restarted mid-hour); the hour can't be reconciled
+		return nil, false
+	}
+
+	result := &TokenReconciliation{
+		NodeID:                 nodeID,
+		Hour:                   hour,
+		BilledPromptTokens:     billedPrompt,
+		BilledCompletionTokens: billedCompletion,
+		VLLMPromptTokens:       vllmPrompt,
+		VLLMGenerationTokens:   vllmGeneration,
+	}
+
+	billed := billedPrompt + billedCompletion
+	reported := vllmPrompt + vllmGeneration
+	switch {
+	case billed == reported:
+		result.DiscrepancyPercent = 0
+	case reported == 0:
+		result.DiscrepancyPercent = 100
+	default:
+		result.DiscrepancyPercent = math.Abs(float64(billed-reported)) / float64(reported) * 100
+	}
+	result.Flagged = result.DiscrepancyPercent > reconciliationThresholdPercent
+
+	return result, true
+}
+
+// ReconcileHour reconciles every node with snapshots bracketing the given hour
+func (tr *TokenReconciler) ReconcileHour(ctx context.Context, hour time.Time) error {
+	hour = hour.Truncate(time.Hour)
+
+	rows, err := tr.db.Pool.Query(ctx, `
+		SELECT s.node_id,
+		       s.prompt_tokens_total, s.generation_tokens_total,
+		       e.prompt_tokens_total, e.generation_tokens_total,
+		       COALESCE(SUM(ur.prompt_tokens), 0),
+		       COALESCE(SUM(ur.completion_tokens), 0)
+		FROM node_token_snapshots s
+		JOIN node_token_snapshots e ON e.node_id = s.node_id AND e.hour = $2
+		LEFT JOIN usage_records ur ON ur.node_id = s.node_id
+		     AND ur.timestamp >= $1 AND ur.timestamp < $2
+		WHERE s.hour = $1
+		GROUP BY s.node_id, s.prompt_tokens_total, s.generation_tokens_total,
+		         e.prompt_tokens_total, e.generation_tokens_total
+	`, hour, hour.Add(time.Hour))
+	if err != nil {
+		return fmt.Errorf("failed to load token snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*TokenReconciliation
+	for rows.Next() {
+		var nodeID string
+		var start, end TokenCounters
+		var billedPrompt, billedCompletion int64
+		if err := rows.Scan(&nodeID,
+			&start.PromptTokens, &start.GenerationTokens,
+			&end.PromptTokens, &end.GenerationTokens,
+			&billedPrompt, &billedCompletion,
+		); err != nil {
+			return fmt.Errorf("failed to scan token snapshots: %w", err)
+		}
+
+		result, ok := reconcile(nodeID, hour, billedPrompt, billedCompletion, start, end)
+		if !ok {
+			tr.logger.Info("skipping token reconciliation after counter reset",
+				zap.String("node_id", nodeID),
+				zap.Time("hour", hour),
+			)
+			continue
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	flagged := 0
+	for _, result := range results {
+		_, err := tr.db.Pool.Exec(ctx, `
+			INSERT INTO token_reconciliations (
+				node_id, hour, billed_prompt_tokens, billed_completion_tokens,
+				vllm_prompt_tokens, vllm_generation_tokens, discrepancy_percent, flagged
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (node_id, hour) DO UPDATE SET
+				billed_prompt_tokens = EXCLUDED.billed_prompt_tokens,
+				billed_completion_tokens = EXCLUDED.billed_completion_tokens,
+				vllm_prompt_tokens = EXCLUDED.vllm_prompt_tokens,
+				vllm_generation_tokens = EXCLUDED.vllm_generation_tokens,
+				discrepancy_percent = EXCLUDED.discrepancy_percent,
+				flagged = EXCLUDED.flagged
+		`, result.NodeID, result.Hour, result.BilledPromptTokens, result.BilledCompletionTokens,
+			result.VLLMPromptTokens, result.VLLMGenerationTokens, result.DiscrepancyPercent, result.Flagged)
+		if err != nil {
+			tr.logger.Error("failed to store token reconciliation",
+				zap.String("node_id", result.NodeID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		metrics.UpdateTokenDiscrepancy(result.NodeID, result.DiscrepancyPercent)
+
+		if result.Flagged {
+			flagged++
+			tr.logger.Warn("billed tokens do not match vLLM reported tokens",
+				zap.String("node_id", result.NodeID),
+				zap.Time("hour", hour),
+				zap.Int64("billed_tokens", result.BilledPromptTokens+result.BilledCompletionTokens),
+				zap.Int64("vllm_tokens", result.VLLMPromptTokens+result.VLLMGenerationTokens),
+				zap.Float64("discrepancy_percent", result.DiscrepancyPercent),
+			)
+		}
+	}
+
+	tr.logger.Info("reconciled billed tokens",
+		zap.Time("hour", hour),
+		zap.Int("nodes", len(results)),
+		zap.Int("flagged", flagged),
+	)
+	return nil
+}
@@ -0,0 +1,75 @@
+package billing
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTokenCounters(t *testing.T) {
+	exposition := `# HELP vllm:prompt_tokens_total Number of prefill tokens processed.
+# TYPE vllm:prompt_tokens_total counter
+vllm:prompt_tokens_total{model_name="llama-3-8b"} 1200.0
+vllm:prompt_tokens_total{model_name="llama-3-8b-lora"} 300.0
+# TYPE vllm:generation_tokens_total counter
+vllm:generation_tokens_total{model_name="llama-3-8b"} 4500.0 1700000000000
+vllm:num_requests_running{model_name="llama-3-8b"} 3.0
+`
+
+	counters, err := parseTokenCounters(strings.NewReader(exposition))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if counters.PromptTokens != 1500 {
+		t.Errorf("expected 1500 prompt tokens, got %d", counters.PromptTokens)
+	}
+	if counters.GenerationTokens != 4500 {
+		t.Errorf("expected 4500 generation tokens, got %d", counters.GenerationTokens)
+	}
+
+	if _, err := parseTokenCounters(strings.NewReader("up 1\n")); err == nil {
+		t.Error("expected error when no vLLM counters are present")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	hour := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	start := TokenCounters{PromptTokens: 1000, GenerationTokens: 2000}
+	end := TokenCounters{PromptTokens: 2000, GenerationTokens: 4000}
+
+	tests := []struct {
+		name        string
+		prompt      int64
+		completion  int64
+		wantFlagged bool
+	}{
+		{"exact match", 1000, 2000, false},
+		{"within threshold", 1000, 1990, false},
+		{"under billed", 900, 1800, true},
+		{"over billed", 1100, 2000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := reconcile("node-1", hour, tt.prompt, tt.completion, start, end)
+			if !ok {
+				t.Fatal("expected reconciliation result")
+			}
+			if result.VLLMPromptTokens != 1000 || result.VLLMGenerationTokens != 2000 {
+				t.Errorf("unexpected vLLM deltas: %+v", result)
+			}
+			if result.Flagged != tt.wantFlagged {
+				t.Errorf("expected flagged=%v, got %v (%.2f%%)", tt.wantFlagged, result.Flagged, result.DiscrepancyPercent)
+			}
+		})
+	}
+
+	if _, ok := reconcile("node-1", hour, 10, 10, end, start); ok {
+		t.Error("expected counter reset to skip reconciliation")
+	}
+
+	result, _ := reconcile("node-1", hour, 10, 0, start, start)
+	if !result.Flagged || result.DiscrepancyPercent != 100 {
+		t.Errorf("expected billed tokens with no vLLM activity to be flagged, got %+v", result)
+	}
+}
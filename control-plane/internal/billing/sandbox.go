@@ -0,0 +1,707 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/client"
+	"go.uber.org/zap"
+)
+
+// Billing sandbox.
+//
+// A tenant's sandbox runs the usage-to-invoice pipeline on accelerated
+// cycles: every cycle (minutes or hours instead of a month) the tenant's
+// usage for the window is priced, written as a sandbox invoice and, when a
+// Stripe test-mode key is configured, issued to a dedicated test-mode
+// customer. Pricing uses the model catalog unless the sandbox carries price
+// overrides, which is how proposed pricing changes are validated. Production
+// state is only read: usage stays unbilled for the real export and the
+// tenant's live Stripe customer is never used.
+
+var (
+	// ErrSandboxNotFound is returned when the tenant has no billing sandbox
+	ErrSandboxNotFound = errors.New("billing sandbox not found")
+	// ErrSandboxDisabled is returned when the tenant's sandbox is switched off
+	ErrSandboxDisabled = errors.New("billing sandbox is disabled")
+	// ErrInvalidSandboxSettings wraps sandbox settings validation failures
+	ErrInvalidSandboxSettings = errors.New("invalid billing sandbox settings")
+)
+
+const (
+	minSandboxCycle = time.Minute
+	maxSandboxCycle = 31 * 24 * time.Hour
+
+	// sandboxPollInterval is how often due cycles are closed
+	sandboxPollInterval = 30 * time.Second
+)
+
+// ModelPrice is a per-million-token price in dollars
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// SandboxSettings configures a tenant's billing sandbox
+type SandboxSettings struct {
+	CycleLength      time.Duration
+	IncludeLiveUsage bool
+	PriceOverrides   map[string]ModelPrice
+}
+
+// Validate checks the cycle length and override prices
+func (s SandboxSettings) Validate() error {
+	if s.CycleLength < minSandboxCycle || s.CycleLength > maxSandboxCycle {
+		return fmt.Errorf("%w: cycle must be between %s and %s", ErrInvalidSandboxSettings, minSandboxCycle, maxSandboxCycle)
+	}
+	for model, price := range s.PriceOverrides {
+		if strings.TrimSpace(model) == "" {
+			return fmt.Errorf("%w: price override model name is required", ErrInvalidSandboxSettings)
+		}
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			return fmt.Errorf("%w: price override for %s must not be negative", ErrInvalidSandboxSettings, model)
+		}
+	}
+	return nil
+}
+
+// Sandbox is a tenant's billing sandbox and its current cycle
+type Sandbox struct {
+	TenantID           uuid.UUID             `json:"tenant_id"`
+	Enabled            bool                  `json:"enabled"`
+	CycleSeconds       int                   `json:"cycle_seconds"`
+	IncludeLiveUsage   bool                  `json:"include_live_usage"`
+	PriceOverrides     map[string]ModelPrice `json:"price_overrides"`
+	CycleNumber        int                   `json:"cycle_number"`
+	CurrentPeriodStart time.Time             `json:"current_period_start"`
+	CurrentPeriodEnd   time.Time             `json:"current_period_end"`
+	StripeCustomerID   *string               `json:"stripe_customer_id,omitempty"`
+	CreatedAt          time.Time             `json:"created_at"`
+	UpdatedAt          time.Time             `json:"updated_at"`
+}
+
+// SandboxUsage is usage injected into a sandbox to simulate traffic
+type SandboxUsage struct {
+	Model            string `json:"model"`
+	Requests         int    `json:"requests"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+}
+
+// SandboxLineItem is one model's charges on a sandbox invoice.
+// RecordedMicrodollars is what production metering computed for the same
+// live usage, for comparison with AmountMicrodollars.
+type SandboxLineItem struct {
+	Model                string  `json:"model"`
+	Requests             int64   `json:"requests"`
+	PromptTokens         int64   `json:"prompt_tokens"`
+	CompletionTokens     int64   `json:"completion_tokens"`
+	InputPerMillion      float64 `json:"input_per_million"`
+	OutputPerMillion     float64 `json:"output_per_million"`
+	PriceOverridden      bool    `json:"price_overridden"`
+	AmountMicrodollars   int64   `json:"amount_microdollars"`
+	RecordedMicrodollars int64   `json:"recorded_microdollars"`
+}
+
+// SandboxInvoice is the result of closing one sandbox cycle
+type SandboxInvoice struct {
+	ID                   uuid.UUID         `json:"id"`
+	TenantID             uuid.UUID         `json:"tenant_id"`
+	CycleNumber          int               `json:"cycle_number"`
+	PeriodStart          time.Time         `json:"period_start"`
+	PeriodEnd            time.Time         `json:"period_end"`
+	LineItems            []SandboxLineItem `json:"line_items"`
+	TotalTokens          int64             `json:"total_tokens"`
+	TotalMicrodollars    int64             `json:"total_microdollars"`
+	Status               string            `json:"status"`
+	StripeInvoiceID      *string           `json:"stripe_invoice_id,omitempty"`
+	StripeAmountDueCents *int64            `json:"stripe_amount_due_cents,omitempty"`
+	StripeInvoiceURL     *string           `json:"stripe_invoice_url,omitempty"`
+	Error                *string           `json:"error,omitempty"`
+	CreatedAt            time.Time         `json:"created_at"`
+}
+
+// sandboxUsageGroup is usage for one model at one region multiplier, with
+// the model's catalog price
+type sandboxUsageGroup struct {
+	Model                string
+	Multiplier           float64
+	Requests             int64
+	PromptTokens         int64
+	CompletionTokens     int64
+	RecordedMicrodollars int64
+	Price                ModelPrice
+}
+
+// priceSandboxUsage prices usage groups into per-model line items using the
+// same formula as PricingCalculator, with overrides replacing catalog prices
+func priceSandboxUsage(groups []sandboxUsageGroup, overrides map[string]ModelPrice) ([]SandboxLineItem, int64, int64) {
+	byModel := make(map[string]*SandboxLineItem)
+	for _, g := range groups {
+		price, overridden := overrides[g.Model]
+		if !overridden {
+			price = g.Price
+		}
+		multiplier := g.Multiplier
+		if multiplier <= 0 {
+			multiplier = 1.0
+		}
+
+		item, ok := byModel[g.Model]
+		if !ok {
+			item = &SandboxLineItem{
+				Model:            g.Model,
+				InputPerMillion:  price.InputPerMillion,
+				OutputPerMillion: price.OutputPerMillion,
+				PriceOverridden:  overridden,
+			}
+			byModel[g.Model] = item
+		}
+
+		item.Requests += g.Requests
+		item.PromptTokens += g.PromptTokens
+		item.CompletionTokens += g.CompletionTokens
+		item.RecordedMicrodollars += g.RecordedMicrodollars
+		item.AmountMicrodollars += int64(float64(g.PromptTokens)*price.InputPerMillion*multiplier +
+			float64(g.CompletionTokens)*price.OutputPerMillion*multiplier)
+	}
+
+	items := make([]SandboxLineItem, 0, len(byModel))
+	var totalTokens, totalMicrodollars int64
+	for _, item := range byModel {
+		items = append(items, *item)
+		totalTokens += item.PromptTokens + item.CompletionTokens
+		totalMicrodollars += item.AmountMicrodollars
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Model < items[j].Model })
+
+	return items, totalTokens, totalMicrodollars
+}
+
+// microdollarsToCents rounds a microdollar amount to the nearest cent
+func microdollarsToCents(microdollars int64) int64 {
+	return (microdollars + 5000) / 10000
+}
+
+// SandboxInvoiceRequest asks a SandboxInvoicer to issue a sandbox invoice
+type SandboxInvoiceRequest struct {
+	TenantID   string
+	TenantName string
+	CustomerID string // empty on the first invoice
+	Invoice    *SandboxInvoice
+}
+
+// SandboxInvoiceResult identifies the issued test-mode invoice
+type SandboxInvoiceResult struct {
+	CustomerID     string
+	InvoiceID      string
+	AmountDueCents int64
+	URL            string
+}
+
+// SandboxInvoicer issues sandbox invoices to a billing provider's test mode
+type SandboxInvoicer interface {
+	IssueInvoice(ctx context.Context, req SandboxInvoiceRequest) (*SandboxInvoiceResult, error)
+}
+
+// StripeSandbox issues sandbox invoices in Stripe test mode. It uses its own
+// API client so the global live key set by NewEngine is never used.
+type StripeSandbox struct {
+	api    *client.API
+	logger *zap.Logger
+}
+
+// NewStripeSandbox creates a Stripe test-mode invoicer. Live keys are
+// rejected so the sandbox can never create real charges.
+func NewStripeSandbox(secretKey string, logger *zap.Logger) (*StripeSandbox, error) {
+	if !strings.HasPrefix(secretKey, "sk_test_") && !strings.HasPrefix(secretKey, "rk_test_") {
+		return nil, fmt.Errorf("billing sandbox requires a Stripe test-mode key")
+	}
+	return &StripeSandbox{api: client.New(secretKey, nil), logger: logger}, nil
+}
+
+// IssueInvoice creates (once) the tenant's test-mode customer, then a
+// finalized invoice with one item per model
+func (s *StripeSandbox) IssueInvoice(ctx context.Context, req SandboxInvoiceRequest) (*SandboxInvoiceResult, error) {
+	metadata := map[string]string{"tenant_id": req.TenantID, "billing_sandbox": "true"}
+
+	customerID := req.CustomerID
+	if customerID == "" {
+		cust, err := s.api.Customers.New(&stripe.CustomerParams{
+			Params: stripe.Params{Context: ctx, Metadata: metadata},
+			Name:   stripe.String("[sandbox] " + req.TenantName),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sandbox customer: %w", err)
+		}
+		customerID = cust.ID
+	}
+
+	inv := req.Invoice
+	created, err := s.api.Invoices.New(&stripe.InvoiceParams{
+		Params: stripe.Params{
+			Context:  ctx,
+			Metadata: map[string]string{"tenant_id": req.TenantID, "sandbox_invoice_id": inv.ID.String()},
+		},
+		Customer:                    stripe.String(customerID),
+		AutoAdvance:                 stripe.Bool(false),
+		CollectionMethod:            stripe.String(string(stripe.InvoiceCollectionMethodSendInvoice)),
+		DaysUntilDue:                stripe.Int64(30),
+		PendingInvoiceItemsBehavior: stripe.String("exclude"),
+		Description: stripe.String(fmt.Sprintf("Sandbox cycle %d (%s - %s)",
+			inv.CycleNumber, inv.PeriodStart.UTC().Format(time.RFC3339), inv.PeriodEnd.UTC().Format(time.RFC3339))),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox invoice: %w", err)
+	}
+
+	for _, item := range inv.LineItems {
+		_, err := s.api.InvoiceItems.New(&stripe.InvoiceItemParams{
+			Params:   stripe.Params{Context: ctx},
+			Customer: stripe.String(customerID),
+			Invoice:  stripe.String(created.ID),
+			Amount:   stripe.Int64(microdollarsToCents(item.AmountMicrodollars)),
+			Currency: stripe.String(string(stripe.CurrencyUSD)),
+			Description: stripe.String(fmt.Sprintf("%s: %d requests, %d prompt + %d completion tokens",
+				item.Model, item.Requests, item.PromptTokens, item.CompletionTokens)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add sandbox invoice item for %s: %w", item.Model, err)
+		}
+	}
+
+	finalized, err := s.api.Invoices.FinalizeInvoice(created.ID, &stripe.InvoiceFinalizeInvoiceParams{
+		Params: stripe.Params{Context: ctx},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize sandbox invoice: %w", err)
+	}
+
+	s.logger.Info("issued sandbox invoice",
+		zap.String("tenant_id", req.TenantID),
+		zap.String("customer_id", customerID),
+		zap.String("invoice_id", finalized.ID),
+		zap.Int64("amount_due_cents", finalized.AmountDue),
+	)
+
+	return &SandboxInvoiceResult{
+		CustomerID:     customerID,
+		InvoiceID:      finalized.ID,
+		AmountDueCents: finalized.AmountDue,
+		URL:            finalized.HostedInvoiceURL,
+	}, nil
+}
+
+// SandboxRunner manages tenant billing sandboxes and closes their cycles
+type SandboxRunner struct {
+	db           *database.Database
+	logger       *zap.Logger
+	invoicer     SandboxInvoicer
+	defaultCycle time.Duration
+}
+
+// NewSandboxRunner creates a sandbox runner. defaultCycle applies to sandboxes
+// enabled without a cycle length. Without an invoicer, cycles are still
+// priced into sandbox invoices but nothing is sent to Stripe.
+func NewSandboxRunner(db *database.Database, logger *zap.Logger, invoicer SandboxInvoicer, defaultCycle time.Duration) *SandboxRunner {
+	return &SandboxRunner{
+		db:           db,
+		logger:       logger,
+		invoicer:     invoicer,
+		defaultCycle: defaultCycle,
+	}
+}
+
+// Start begins closing due sandbox cycles in the background
+func (s *SandboxRunner) Start(ctx context.Context) {
+	s.logger.Info("starting billing sandbox runner", zap.Bool("stripe_test_mode", s.invoicer != nil))
+	go func() {
+		ticker := time.NewTicker(sandboxPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.closeDueCycles(ctx)
+			}
+		}
+	}()
+}
+
+// closeDueCycles closes every enabled sandbox whose cycle has ended
+func (s *SandboxRunner) closeDueCycles(ctx context.Context) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT tenant_id FROM billing_sandboxes
+		WHERE enabled AND current_period_end <= NOW()
+	`)
+	if err != nil {
+		s.logger.Error("failed to query due billing sandboxes", zap.Error(err))
+		return
+	}
+
+	var due []uuid.UUID
+	for rows.Next() {
+		var tenantID uuid.UUID
+		if err := rows.Scan(&tenantID); err != nil {
+			continue
+		}
+		due = append(due, tenantID)
+	}
+	rows.Close()
+
+	for _, tenantID := range due {
+		if _, err := s.CloseCycle(ctx, tenantID, false); err != nil {
+			s.logger.Error("failed to close billing sandbox cycle",
+				zap.String("tenant_id", tenantID.String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+const sandboxColumns = `tenant_id, enabled, cycle_seconds, include_live_usage, price_overrides,
+	cycle_number, current_period_start, current_period_end, stripe_customer_id, created_at, updated_at`
+
+func scanSandbox(row pgx.Row) (*Sandbox, error) {
+	var sb Sandbox
+	var overrides []byte
+	err := row.Scan(&sb.TenantID, &sb.Enabled, &sb.CycleSeconds, &sb.IncludeLiveUsage, &overrides,
+		&sb.CycleNumber, &sb.CurrentPeriodStart, &sb.CurrentPeriodEnd, &sb.StripeCustomerID, &sb.CreatedAt, &sb.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSandboxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(overrides, &sb.PriceOverrides); err != nil {
+		return nil, fmt.Errorf("invalid price overrides: %w", err)
+	}
+	if sb.PriceOverrides == nil {
+		sb.PriceOverrides = map[string]ModelPrice{}
+	}
+	return &sb, nil
+}
+
+// Get returns the tenant's sandbox
+func (s *SandboxRunner) Get(ctx context.Context, tenantID uuid.UUID) (*Sandbox, error) {
+	return scanSandbox(s.db.Pool.QueryRow(ctx, `SELECT `+sandboxColumns+` FROM billing_sandboxes WHERE tenant_id = $1`, tenantID))
+}
+
+// Enable creates or updates the tenant's sandbox. A new (or re-enabled)
+// sandbox starts its first cycle now; changing the cycle length of a running
+// sandbox reschedules the end of the current cycle.
+func (s *SandboxRunner) Enable(ctx context.Context, tenantID uuid.UUID, settings SandboxSettings) (*Sandbox, error) {
+	if settings.CycleLength == 0 {
+		settings.CycleLength = s.defaultCycle
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	overrides := settings.PriceOverrides
+	if overrides == nil {
+		overrides = map[string]ModelPrice{}
+	}
+	overridesJSON, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSandbox(s.db.Pool.QueryRow(ctx, `
+		INSERT INTO billing_sandboxes (
+			tenant_id, enabled, cycle_seconds, include_live_usage, price_overrides,
+			current_period_start, current_period_end
+		) VALUES ($1, true, $2, $3, $4, NOW(), NOW() + $2 * INTERVAL '1 second')
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			cycle_seconds = EXCLUDED.cycle_seconds,
+			include_live_usage = EXCLUDED.include_live_usage,
+			price_overrides = EXCLUDED.price_overrides,
+			current_period_start = CASE WHEN billing_sandboxes.enabled
+				THEN billing_sandboxes.current_period_start ELSE EXCLUDED.current_period_start END,
+			current_period_end = CASE WHEN billing_sandboxes.enabled
+				THEN billing_sandboxes.current_period_start + EXCLUDED.cycle_seconds * INTERVAL '1 second'
+				ELSE EXCLUDED.current_period_end END,
+			enabled = true
+		RETURNING `+sandboxColumns,
+		tenantID, int(settings.CycleLength/time.Second), settings.IncludeLiveUsage, overridesJSON,
+	))
+}
+
+// Disable stops the tenant's sandbox; its invoices are kept
+func (s *SandboxRunner) Disable(ctx context.Context, tenantID uuid.UUID) error {
+	tag, err := s.db.Pool.Exec(ctx, `UPDATE billing_sandboxes SET enabled = false WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSandboxNotFound
+	}
+	return nil
+}
+
+// RecordUsage injects synthetic usage into the sandbox's current cycle
+func (s *SandboxRunner) RecordUsage(ctx context.Context, tenantID uuid.UUID, usage []SandboxUsage) error {
+	sb, err := s.Get(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if !sb.Enabled {
+		return ErrSandboxDisabled
+	}
+
+	batch := &pgx.Batch{}
+	for _, u := range usage {
+		batch.Queue(`
+			INSERT INTO billing_sandbox_usage (tenant_id, model, requests, prompt_tokens, completion_tokens)
+			VALUES ($1, $2, $3, $4, $5)
+		`, tenantID, u.Model, u.Requests, u.PromptTokens, u.CompletionTokens)
+	}
+	return s.db.Pool.SendBatch(ctx, batch).Close()
+}
+
+// CloseCycle prices the sandbox's current cycle into an invoice and starts
+// the next cycle. Unless early is set, a cycle that hasn't ended yet is left
+// alone and nil is returned; early closes it now, to advance the simulation
+// without waiting. The invoice is issued to Stripe test mode afterwards.
+func (s *SandboxRunner) CloseCycle(ctx context.Context, tenantID uuid.UUID, early bool) (*SandboxInvoice, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	sb, err := scanSandbox(tx.QueryRow(ctx, `SELECT `+sandboxColumns+` FROM billing_sandboxes WHERE tenant_id = $1 FOR UPDATE`, tenantID))
+	if err != nil {
+		return nil, err
+	}
+	if !sb.Enabled {
+		return nil, ErrSandboxDisabled
+	}
+
+	periodEnd := sb.CurrentPeriodEnd
+	if now := time.Now(); now.Before(periodEnd) {
+		if !early {
+			return nil, nil
+		}
+		periodEnd = now
+	}
+
+	groups, err := s.loadCycleUsage(ctx, tx, sb, sb.CurrentPeriodStart, periodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sandbox usage: %w", err)
+	}
+	items, totalTokens, totalMicrodollars := priceSandboxUsage(groups, sb.PriceOverrides)
+	itemsJSON, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &SandboxInvoice{
+		TenantID:          tenantID,
+		CycleNumber:       sb.CycleNumber,
+		PeriodStart:       sb.CurrentPeriodStart,
+		PeriodEnd:         periodEnd,
+		LineItems:         items,
+		TotalTokens:       totalTokens,
+		TotalMicrodollars: totalMicrodollars,
+		Status:            "computed",
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO billing_sandbox_invoices (
+			tenant_id, cycle_number, period_start, period_end,
+			line_items, total_tokens, total_microdollars
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, tenantID, inv.CycleNumber, inv.PeriodStart, inv.PeriodEnd, itemsJSON, totalTokens, totalMicrodollars).Scan(&inv.ID, &inv.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store sandbox invoice: %w", err)
+	}
+
+	// Cycles are contiguous so no usage falls between two invoices
+	_, err = tx.Exec(ctx, `
+		UPDATE billing_sandboxes
+		SET cycle_number = cycle_number + 1,
+		    current_period_start = $2,
+		    current_period_end = $2 + cycle_seconds * INTERVAL '1 second'
+		WHERE tenant_id = $1
+	`, tenantID, periodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to advance sandbox cycle: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("closed billing sandbox cycle",
+		zap.String("tenant_id", tenantID.String()),
+		zap.Int("cycle_number", inv.CycleNumber),
+		zap.Int64("total_microdollars", totalMicrodollars),
+	)
+
+	if s.invoicer != nil {
+		s.issueInvoice(ctx, sb, inv)
+	}
+
+	return inv, nil
+}
+
+// loadCycleUsage returns the cycle's usage grouped by model and region
+// multiplier: the tenant's recorded usage (when included) and synthetic usage
+func (s *SandboxRunner) loadCycleUsage(ctx context.Context, tx pgx.Tx, sb *Sandbox, start, end time.Time) ([]sandboxUsageGroup, error) {
+	var groups []sandboxUsageGroup
+
+	scan := func(rows pgx.Rows) error {
+		defer rows.Close()
+		for rows.Next() {
+			var g sandboxUsageGroup
+			if err := rows.Scan(&g.Model, &g.Multiplier, &g.Requests, &g.PromptTokens, &g.CompletionTokens,
+				&g.RecordedMicrodollars, &g.Price.InputPerMillion, &g.Price.OutputPerMillion); err != nil {
+				return err
+			}
+			groups = append(groups, g)
+		}
+		return rows.Err()
+	}
+
+	if sb.IncludeLiveUsage {
+		rows, err := tx.Query(ctx, `
+			SELECT COALESCE(m.name, 'unknown'), COALESCE(rg.cost_multiplier, 1.0)::float8,
+			       COUNT(*), SUM(u.prompt_tokens), SUM(u.completion_tokens),
+			       COALESCE(SUM(u.cost_microdollars), 0),
+			       COALESCE(m.price_input_per_million, 0)::float8, COALESCE(m.price_output_per_million, 0)::float8
+			FROM usage_records u
+			LEFT JOIN models m ON m.id = u.model_id
+			LEFT JOIN regions rg ON rg.id = u.region_id
+			WHERE u.tenant_id = $1 AND u.timestamp >= $2 AND u.timestamp < $3
+			GROUP BY 1, 2, 7, 8
+		`, sb.TenantID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		if err := scan(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT s.model, 1.0::float8,
+		       SUM(s.requests), SUM(s.prompt_tokens), SUM(s.completion_tokens),
+		       0::bigint,
+		       COALESCE(m.price_input_per_million, 0)::float8, COALESCE(m.price_output_per_million, 0)::float8
+		FROM billing_sandbox_usage s
+		LEFT JOIN models m ON m.name = s.model
+		WHERE s.tenant_id = $1 AND s.recorded_at >= $2 AND s.recorded_at < $3
+		GROUP BY 1, 2, 7, 8
+	`, sb.TenantID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if err := scan(rows); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// issueInvoice sends a closed cycle's invoice to Stripe test mode and
+// records the outcome on the sandbox invoice
+func (s *SandboxRunner) issueInvoice(ctx context.Context, sb *Sandbox, inv *SandboxInvoice) {
+	var tenantName string
+	if err := s.db.Pool.QueryRow(ctx, `SELECT name FROM tenants WHERE id = $1`, sb.TenantID).Scan(&tenantName); err != nil {
+		tenantName = sb.TenantID.String()
+	}
+
+	req := SandboxInvoiceRequest{
+		TenantID:   sb.TenantID.String(),
+		TenantName: tenantName,
+		Invoice:    inv,
+	}
+	if sb.StripeCustomerID != nil {
+		req.CustomerID = *sb.StripeCustomerID
+	}
+
+	result, err := s.invoicer.IssueInvoice(ctx, req)
+	if err != nil {
+		msg := err.Error()
+		inv.Status = "failed"
+		inv.Error = &msg
+		if _, dbErr := s.db.Pool.Exec(ctx, `
+			UPDATE billing_sandbox_invoices SET status = 'failed', error = $2 WHERE id = $1
+		`, inv.ID, msg); dbErr != nil {
+			s.logger.Error("failed to record sandbox invoice failure", zap.Error(dbErr))
+		}
+		s.logger.Warn("failed to issue sandbox invoice",
+			zap.String("tenant_id", sb.TenantID.String()),
+			zap.String("invoice_id", inv.ID.String()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	inv.Status = "issued"
+	inv.StripeInvoiceID = &result.InvoiceID
+	inv.StripeAmountDueCents = &result.AmountDueCents
+	inv.StripeInvoiceURL = &result.URL
+
+	if _, err := s.db.Pool.Exec(ctx, `
+		UPDATE billing_sandbox_invoices
+		SET status = 'issued', stripe_invoice_id = $2, stripe_amount_due_cents = $3, stripe_invoice_url = NULLIF($4, '')
+		WHERE id = $1
+	`, inv.ID, result.InvoiceID, result.AmountDueCents, result.URL); err != nil {
+		s.logger.Error("failed to record issued sandbox invoice", zap.Error(err))
+	}
+
+	if req.CustomerID == "" {
+		if _, err := s.db.Pool.Exec(ctx, `
+			UPDATE billing_sandboxes SET stripe_customer_id = $2 WHERE tenant_id = $1
+		`, sb.TenantID, result.CustomerID); err != nil {
+			s.logger.Error("failed to record sandbox customer", zap.Error(err))
+		}
+	}
+}
+
+// ListInvoices returns the sandbox's most recent invoices first
+func (s *SandboxRunner) ListInvoices(ctx context.Context, tenantID uuid.UUID, limit int) ([]SandboxInvoice, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, tenant_id, cycle_number, period_start, period_end, line_items,
+		       total_tokens, total_microdollars, status, stripe_invoice_id,
+		       stripe_amount_due_cents, stripe_invoice_url, error, created_at
+		FROM billing_sandbox_invoices
+		WHERE tenant_id = $1
+		ORDER BY cycle_number DESC
+		LIMIT $2
+	`, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []SandboxInvoice{}
+	for rows.Next() {
+		var inv SandboxInvoice
+		var items []byte
+		if err := rows.Scan(&inv.ID, &inv.TenantID, &inv.CycleNumber, &inv.PeriodStart, &inv.PeriodEnd, &items,
+			&inv.TotalTokens, &inv.TotalMicrodollars, &inv.Status, &inv.StripeInvoiceID,
+			&inv.StripeAmountDueCents, &inv.StripeInvoiceURL, &inv.Error, &inv.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(items, &inv.LineItems); err != nil {
+			return nil, fmt.Errorf("invalid line items: %w", err)
+		}
+		invoices = append(invoices, inv)
+	}
+
+	return invoices, rows.Err()
+}
@@ -0,0 +1,90 @@
+package billing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestPriceSandboxUsage(t *testing.T) {
+	groups := []sandboxUsageGroup{
+		// Live usage in two regions, one at a 1.5x multiplier
+		{Model: "llama-3-8b", Multiplier: 1.0, Requests: 10, PromptTokens: 1000, CompletionTokens: 500, RecordedMicrodollars: 400, Price: ModelPrice{0.2, 0.4}},
+		{Model: "llama-3-8b", Multiplier: 1.5, Requests: 2, PromptTokens: 1000, CompletionTokens: 0, RecordedMicrodollars: 300, Price: ModelPrice{0.2, 0.4}},
+		// Synthetic usage with an overridden price
+		{Model: "mistral-7b", Multiplier: 1.0, Requests: 1, PromptTokens: 2000, CompletionTokens: 1000, Price: ModelPrice{0.1, 0.1}},
+	}
+	overrides := map[string]ModelPrice{"mistral-7b": {InputPerMillion: 0.5, OutputPerMillion: 1.0}}
+
+	items, totalTokens, total := priceSandboxUsage(groups, overrides)
+	if len(items) != 2 {
+		t.Fatalf("got %d line items, want 2", len(items))
+	}
+
+	llama := items[0]
+	if llama.Model != "llama-3-8b" || llama.PriceOverridden {
+		t.Errorf("first item = %+v, want catalog-priced llama-3-8b", llama)
+	}
+	// 1000*0.2 + 500*0.4 = 400, plus 1000*0.2*1.5 = 300
+	if llama.AmountMicrodollars != 700 || llama.RecordedMicrodollars != 700 {
+		t.Errorf("llama amount = %d (recorded %d), want 700", llama.AmountMicrodollars, llama.RecordedMicrodollars)
+	}
+	if llama.Requests != 12 || llama.PromptTokens != 2000 || llama.CompletionTokens != 500 {
+		t.Errorf("llama usage = %+v", llama)
+	}
+
+	mistral := items[1]
+	// 2000*0.5 + 1000*1.0 = 2000
+	if !mistral.PriceOverridden || mistral.AmountMicrodollars != 2000 {
+		t.Errorf("mistral = %+v, want overridden price totalling 2000", mistral)
+	}
+
+	if totalTokens != 5500 || total != 2700 {
+		t.Errorf("totals = %d tokens, %d microdollars; want 5500, 2700", totalTokens, total)
+	}
+}
+
+func TestMicrodollarsToCents(t *testing.T) {
+	tests := map[int64]int64{0: 0, 4999: 0, 5000: 1, 10000: 1, 1234567: 123}
+	for in, want := range tests {
+		if got := microdollarsToCents(in); got != want {
+			t.Errorf("microdollarsToCents(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestSandboxSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings SandboxSettings
+		wantErr  bool
+	}{
+		{"hourly cycle", SandboxSettings{CycleLength: time.Hour}, false},
+		{"cycle too short", SandboxSettings{CycleLength: time.Second}, true},
+		{"cycle too long", SandboxSettings{CycleLength: 60 * 24 * time.Hour}, true},
+		{"negative override", SandboxSettings{CycleLength: time.Hour, PriceOverrides: map[string]ModelPrice{"m": {InputPerMillion: -1}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSandboxSettings) {
+				t.Errorf("error %v does not wrap ErrInvalidSandboxSettings", err)
+			}
+		})
+	}
+}
+
+func TestNewStripeSandboxRejectsLiveKeys(t *testing.T) {
+	if _, err := NewStripeSandbox("sk_live_abc", zap.NewNop()); err == nil {
+		t.Error("expected live key to be rejected")
+	}
+	if _, err := NewStripeSandbox("sk_test_abc", zap.NewNop()); err != nil {
+		t.Errorf("test key rejected: %v", err)
+	}
+}
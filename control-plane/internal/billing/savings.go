@@ -0,0 +1,165 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+)
+
+// SavingsReport compares a tenant's actual GPU spend on spot instances with
+// what the same instance-hours would have cost on-demand at catalog prices
+type SavingsReport struct {
+	TenantID    uuid.UUID `json:"tenant_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+
+	SpotHours     float64 `json:"spot_hours"`
+	OnDemandHours float64 `json:"ondemand_hours"`
+
+	// SpotSpend is what the spot hours actually cost; SpotOnDemandEquivalent
+	// is what they would have cost on-demand
+	SpotSpend              float64 `json:"spot_spend_usd"`
+	SpotOnDemandEquivalent float64 `json:"spot_ondemand_equivalent_usd"`
+	OnDemandSpend          float64 `json:"ondemand_spend_usd"`
+	TotalSpend             float64 `json:"total_spend_usd"`
+
+	Savings        float64 `json:"savings_usd"`
+	SavingsPercent float64 `json:"savings_percent"`
+
+	InstanceTypes []InstanceSavings `json:"instance_types"`
+
+	// UnpricedHours are spot hours on instance types without a catalog
+	// on-demand price; they are excluded from the savings figures
+	UnpricedHours float64 `json:"unpriced_hours,omitempty"`
+}
+
+// InstanceSavings is the savings breakdown for one instance type
+type InstanceSavings struct {
+	Provider               string  `json:"provider"`
+	InstanceType           string  `json:"instance_type"`
+	GPUType                string  `json:"gpu_type,omitempty"`
+	SpotHours              float64 `json:"spot_hours"`
+	SpotSpend              float64 `json:"spot_spend_usd"`
+	SpotOnDemandEquivalent float64 `json:"spot_ondemand_equivalent_usd"`
+	Savings                float64 `json:"savings_usd"`
+}
+
+// nodeUsage is one tenant node's running time within a report period and
+// the prices that apply to it
+type nodeUsage struct {
+	Provider      string
+	InstanceType  string
+	GPUType       string
+	Spot          bool
+	Hours         float64
+	NodeSpotPrice *float64 // price the node actually ran at, when recorded
+	OnDemandPrice *float64 // catalog on-demand price per hour
+	CatalogSpot   *float64 // catalog spot price per hour
+}
+
+// summarizeSavings builds a savings report from node usage
+func summarizeSavings(report *SavingsReport, nodes []nodeUsage) {
+	byType := make(map[string]*InstanceSavings)
+
+	for _, n := range nodes {
+		if n.Hours <= 0 {
+			continue
+		}
+
+		if !n.Spot {
+			report.OnDemandHours += n.Hours
+			if n.OnDemandPrice != nil {
+				report.OnDemandSpend += n.Hours * *n.OnDemandPrice
+			}
+			continue
+		}
+
+		report.SpotHours += n.Hours
+
+		spotPrice := n.NodeSpotPrice
+		if spotPrice == nil {
+			spotPrice = n.CatalogSpot
+		}
+		if spotPrice == nil || n.OnDemandPrice == nil {
+			report.UnpricedHours += n.Hours
+			continue
+		}
+
+		spend := n.Hours * *spotPrice
+		equivalent := n.Hours * *n.OnDemandPrice
+		report.SpotSpend += spend
+		report.SpotOnDemandEquivalent += equivalent
+
+		key := n.Provider + "/" + n.InstanceType
+		line, ok := byType[key]
+		if !ok {
+			line = &InstanceSavings{Provider: n.Provider, InstanceType: n.InstanceType, GPUType: n.GPUType}
+			byType[key] = line
+		}
+		line.SpotHours += n.Hours
+		line.SpotSpend += spend
+		line.SpotOnDemandEquivalent += equivalent
+		line.Savings += equivalent - spend
+	}
+
+	report.TotalSpend = report.SpotSpend + report.OnDemandSpend
+	report.Savings = report.SpotOnDemandEquivalent - report.SpotSpend
+	if report.SpotOnDemandEquivalent > 0 {
+		report.SavingsPercent = report.Savings / report.SpotOnDemandEquivalent * 100
+	}
+
+	report.InstanceTypes = make([]InstanceSavings, 0, len(byType))
+	for _, line := range byType {
+		report.InstanceTypes = append(report.InstanceTypes, *line)
+	}
+	sort.Slice(report.InstanceTypes, func(i, j int) bool {
+		return report.InstanceTypes[i].Savings > report.InstanceTypes[j].Savings
+	})
+}
+
+// GetSavingsReport reports spot savings on the tenant's own nodes over
+// [start, end). Each node counts for the part of its lifetime inside the
+// period; dead nodes without a termination time end at their last update.
+func GetSavingsReport(ctx context.Context, db *database.Database, tenantID uuid.UUID, start, end time.Time) (*SavingsReport, error) {
+	rows, err := db.Pool.Query(ctx, `
+		WITH tenant_nodes AS (
+			SELECT n.provider, COALESCE(n.instance_type, '') AS instance_type,
+			       COALESCE(n.gpu_type, '') AS gpu_type, COALESCE(n.spot_instance, false) AS spot,
+			       n.spot_price, n.created_at,
+			       COALESCE(n.terminated_at, CASE WHEN n.status = 'dead' THEN n.updated_at END, NOW()) AS ended_at
+			FROM nodes n
+			WHERE n.tenant_id = $1
+		)
+		SELECT tn.provider, tn.instance_type, tn.gpu_type, tn.spot,
+		       EXTRACT(EPOCH FROM (LEAST(tn.ended_at, $3) - GREATEST(tn.created_at, $2))) / 3600.0,
+		       tn.spot_price::float8, it.price_per_hour::float8, it.spot_price_per_hour::float8
+		FROM tenant_nodes tn
+		LEFT JOIN instance_types it ON it.provider = tn.provider AND it.instance_type = tn.instance_type
+		WHERE tn.created_at < $3 AND tn.ended_at > $2
+	`, tenantID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tenant nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []nodeUsage
+	for rows.Next() {
+		var n nodeUsage
+		if err := rows.Scan(&n.Provider, &n.InstanceType, &n.GPUType, &n.Spot, &n.Hours,
+			&n.NodeSpotPrice, &n.OnDemandPrice, &n.CatalogSpot); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant node: %w", err)
+		}
+		nodes = append(nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &SavingsReport{TenantID: tenantID, PeriodStart: start, PeriodEnd: end}
+	summarizeSavings(report, nodes)
+	return report, nil
+}
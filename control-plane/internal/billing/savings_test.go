@@ -0,0 +1,46 @@
+package billing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummarizeSavings(t *testing.T) {
+	price := func(v float64) *float64 { return &v }
+
+	nodes := []nodeUsage{
+		// Spot node with its own recorded price
+		{Provider: "aws", InstanceType: "g5.xlarge", GPUType: "A10G", Spot: true, Hours: 10, NodeSpotPrice: price(0.40), OnDemandPrice: price(1.00), CatalogSpot: price(0.50)},
+		// Spot node falling back to the catalog spot price
+		{Provider: "aws", InstanceType: "g5.xlarge", GPUType: "A10G", Spot: true, Hours: 10, OnDemandPrice: price(1.00), CatalogSpot: price(0.50)},
+		// On-demand node
+		{Provider: "gcp", InstanceType: "a2-highgpu-1g", Spot: false, Hours: 5, OnDemandPrice: price(3.00)},
+		// Spot node on an instance type missing from the catalog
+		{Provider: "azure", InstanceType: "custom", Spot: true, Hours: 2},
+		// Outside the period
+		{Provider: "aws", InstanceType: "g5.xlarge", Spot: true, Hours: -1, OnDemandPrice: price(1.00), CatalogSpot: price(0.50)},
+	}
+
+	report := &SavingsReport{}
+	summarizeSavings(report, nodes)
+
+	approx := func(name string, got, want float64) {
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	approx("SpotHours", report.SpotHours, 22)
+	approx("OnDemandHours", report.OnDemandHours, 5)
+	approx("SpotSpend", report.SpotSpend, 9)                            // 10*0.40 + 10*0.50
+	approx("SpotOnDemandEquivalent", report.SpotOnDemandEquivalent, 20) // 20*1.00
+	approx("OnDemandSpend", report.OnDemandSpend, 15)
+	approx("TotalSpend", report.TotalSpend, 24)
+	approx("Savings", report.Savings, 11)
+	approx("SavingsPercent", report.SavingsPercent, 55)
+	approx("UnpricedHours", report.UnpricedHours, 2)
+
+	if len(report.InstanceTypes) != 1 || report.InstanceTypes[0].InstanceType != "g5.xlarge" {
+		t.Fatalf("InstanceTypes = %+v, want one g5.xlarge line", report.InstanceTypes)
+	}
+	approx("g5.xlarge savings", report.InstanceTypes[0].Savings, 11)
+}
@@ -0,0 +1,737 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/customer"
+	"github.com/stripe/stripe-go/v76/subscription"
+	"go.uber.org/zap"
+)
+
+// Stripe reconciliation.
+//
+// Webhooks keep tenants in step with Stripe, but deliveries can be lost or
+// processed out of order: a payment_failed handled after the subscription
+// recovered leaves the tenant suspended, a customer created before its
+// tenant row was written is never linked. A reconciliation run lists every
+// Stripe customer and subscription, compares them with the tenants table
+// and records a discrepancy report. Runs with auto-fix correct the safe
+// discrepancies the same way the webhook would have; each fix only applies
+// if the tenant still holds the value the run observed, so a webhook landing
+// mid-run always wins.
+
+const (
+	// DiscrepancyOrphanedCustomer is a Stripe customer no tenant points at
+	DiscrepancyOrphanedCustomer = "orphaned_customer"
+	// DiscrepancyMissingCustomer is a tenant that should be billed through
+	// Stripe but has no customer, or points at one Stripe doesn't have
+	DiscrepancyMissingCustomer = "missing_customer"
+	// DiscrepancyStatusMismatch is a tenant status that disagrees with its
+	// current subscription
+	DiscrepancyStatusMismatch = "status_mismatch"
+	// DiscrepancySubscriptionMismatch is a tenant tracking a subscription
+	// other than its customer's current one
+	DiscrepancySubscriptionMismatch = "subscription_mismatch"
+	// DiscrepancyPlanMismatch is a billing plan that disagrees with the
+	// price of the current subscription
+	DiscrepancyPlanMismatch = "plan_mismatch"
+)
+
+const (
+	// StripeReconciliationTriggerScheduled is triggered_by for runs of the
+	// background loop
+	StripeReconciliationTriggerScheduled = "scheduled"
+
+	// staleReconciliationRun is how long a run may stay pending or running
+	// before it is presumed abandoned by a crashed replica
+	staleReconciliationRun = 2 * time.Hour
+
+	// stripeListPageSize is the page size of customer and subscription lists
+	stripeListPageSize = 100
+)
+
+var (
+	// ErrReconciliationInProgress is returned when another run is pending or running
+	ErrReconciliationInProgress = errors.New("a stripe reconciliation is already in progress")
+	// ErrReconciliationRunNotFound is returned for unknown run IDs
+	ErrReconciliationRunNotFound = errors.New("stripe reconciliation run not found")
+)
+
+// StripeCustomerRecord is the part of a Stripe customer reconciliation uses
+type StripeCustomerRecord struct {
+	ID    string
+	Email string
+	// TenantID is the tenant_id metadata set when the customer was created
+	TenantID string
+	// Sandbox customers belong to billing sandboxes, not tenants
+	Sandbox bool
+	Created time.Time
+}
+
+// StripeSubscriptionRecord is the part of a Stripe subscription
+// reconciliation uses
+type StripeSubscriptionRecord struct {
+	ID         string
+	CustomerID string
+	Status     stripe.SubscriptionStatus
+	PriceID    string
+	Created    time.Time
+}
+
+// CustomerDirectory lists a billing provider's customers and subscriptions
+type CustomerDirectory interface {
+	ListCustomers(ctx context.Context) ([]StripeCustomerRecord, error)
+	ListSubscriptions(ctx context.Context) ([]StripeSubscriptionRecord, error)
+}
+
+// StripeCustomers lists customers and subscriptions through the Stripe API.
+// The API key is set globally by NewEngine.
+type StripeCustomers struct{}
+
+// NewStripeCustomers creates a Stripe-backed customer directory
+func NewStripeCustomers() *StripeCustomers {
+	return &StripeCustomers{}
+}
+
+// ListCustomers returns every customer that hasn't been deleted
+func (s *StripeCustomers) ListCustomers(ctx context.Context) ([]StripeCustomerRecord, error) {
+	params := &stripe.CustomerListParams{}
+	params.Context = ctx
+	params.Limit = stripe.Int64(stripeListPageSize)
+
+	var records []StripeCustomerRecord
+	iter := customer.List(params)
+	for iter.Next() {
+		c := iter.Customer()
+		records = append(records, StripeCustomerRecord{
+			ID:       c.ID,
+			Email:    c.Email,
+			TenantID: c.Metadata["tenant_id"],
+			Sandbox:  c.Metadata["billing_sandbox"] == "true",
+			Created:  time.Unix(c.Created, 0),
+		})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list stripe customers: %w", err)
+	}
+	return records, nil
+}
+
+// ListSubscriptions returns every subscription, canceled ones included
+func (s *StripeCustomers) ListSubscriptions(ctx context.Context) ([]StripeSubscriptionRecord, error) {
+	params := &stripe.SubscriptionListParams{Status: stripe.String("all")}
+	params.Context = ctx
+	params.Limit = stripe.Int64(stripeListPageSize)
+
+	var records []StripeSubscriptionRecord
+	iter := subscription.List(params)
+	for iter.Next() {
+		sub := iter.Subscription()
+		if sub.Customer == nil {
+			continue
+		}
+		record := StripeSubscriptionRecord{
+			ID:         sub.ID,
+			CustomerID: sub.Customer.ID,
+			Status:     sub.Status,
+			Created:    time.Unix(sub.Created, 0),
+		}
+		if sub.Items != nil && len(sub.Items.Data) > 0 && sub.Items.Data[0].Price != nil {
+			record.PriceID = sub.Items.Data[0].Price.ID
+		}
+		records = append(records, record)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list stripe subscriptions: %w", err)
+	}
+	return records, nil
+}
+
+// StripeDiscrepancy is one finding of a reconciliation run. Expected is the
+// value Stripe implies, Actual what the tenant had when compared.
+type StripeDiscrepancy struct {
+	ID             uuid.UUID  `json:"id"`
+	Type           string     `json:"type"`
+	TenantID       *uuid.UUID `json:"tenant_id,omitempty"`
+	CustomerID     string     `json:"stripe_customer_id,omitempty"`
+	SubscriptionID string     `json:"stripe_subscription_id,omitempty"`
+	Expected       string     `json:"expected,omitempty"`
+	Actual         string     `json:"actual,omitempty"`
+	Detail         string     `json:"detail"`
+	Fixable        bool       `json:"fixable"`
+	Fixed          bool       `json:"fixed"`
+	FixError       *string    `json:"fix_error,omitempty"`
+}
+
+// StripeReconciliationRun is one cross-check of tenants against Stripe
+type StripeReconciliationRun struct {
+	ID                   uuid.UUID           `json:"id"`
+	Status               string              `json:"status"`
+	AutoFix              bool                `json:"auto_fix"`
+	TriggeredBy          string              `json:"triggered_by"`
+	TenantsChecked       int                 `json:"tenants_checked"`
+	CustomersChecked     int                 `json:"customers_checked"`
+	SubscriptionsChecked int                 `json:"subscriptions_checked"`
+	DiscrepancyCount     int                 `json:"discrepancy_count"`
+	FixedCount           int                 `json:"fixed_count"`
+	Error                *string             `json:"error,omitempty"`
+	StartedAt            time.Time           `json:"started_at"`
+	CompletedAt          *time.Time          `json:"completed_at,omitempty"`
+	Discrepancies        []StripeDiscrepancy `json:"discrepancies,omitempty"`
+}
+
+// reconcileTenant is a tenant's billing state as reconciliation sees it
+type reconcileTenant struct {
+	ID             uuid.UUID
+	Email          string
+	Status         string
+	BillingPlan    string
+	CustomerID     string
+	SubscriptionID string
+	// AdminSuspended is set while an admin suspension is in force; the
+	// reconciler never lifts those
+	AdminSuspended bool
+}
+
+// currentSubscriptions picks each customer's current subscription: the
+// newest one still live, else the newest one
+func currentSubscriptions(subs []StripeSubscriptionRecord) map[string]StripeSubscriptionRecord {
+	current := make(map[string]StripeSubscriptionRecord)
+	for _, sub := range subs {
+		prev, ok := current[sub.CustomerID]
+		if !ok {
+			current[sub.CustomerID] = sub
+			continue
+		}
+		subLive, prevLive := subscriptionLive(sub.Status), subscriptionLive(prev.Status)
+		if (subLive && !prevLive) || (subLive == prevLive && sub.Created.After(prev.Created)) {
+			current[sub.CustomerID] = sub
+		}
+	}
+	return current
+}
+
+// subscriptionLive reports whether a subscription can still bill the customer
+func subscriptionLive(status stripe.SubscriptionStatus) bool {
+	return status != stripe.SubscriptionStatusCanceled && status != stripe.SubscriptionStatusIncompleteExpired
+}
+
+// findStripeDiscrepancies compares tenants with Stripe's customers and
+// subscriptions. Tenant checks run against the customer a fixable orphan
+// would link, so one run can both link a customer and correct the tenant.
+func findStripeDiscrepancies(tenants []reconcileTenant, customers []StripeCustomerRecord, subs []StripeSubscriptionRecord, plans *PlanCatalog) []StripeDiscrepancy {
+	var found []StripeDiscrepancy
+
+	customersByID := make(map[string]StripeCustomerRecord, len(customers))
+	for _, c := range customers {
+		customersByID[c.ID] = c
+	}
+	tenantsByID := make(map[string]*reconcileTenant, len(tenants))
+	tenantByCustomer := make(map[string]*reconcileTenant, len(tenants))
+	for i := range tenants {
+		t := &tenants[i]
+		tenantsByID[t.ID.String()] = t
+		if t.CustomerID != "" {
+			tenantByCustomer[t.CustomerID] = t
+		}
+	}
+	current := currentSubscriptions(subs)
+
+	// Customers no tenant points at. One is linked when its tenant_id
+	// metadata names a tenant without a customer; the oldest such customer
+	// wins so duplicates created by retried sign-ups stay reported.
+	linked := make(map[uuid.UUID]string)
+	sort.SliceStable(customers, func(i, j int) bool { return customers[i].Created.Before(customers[j].Created) })
+	for _, c := range customers {
+		if c.Sandbox || tenantByCustomer[c.ID] != nil {
+			continue
+		}
+		d := StripeDiscrepancy{
+			Type:       DiscrepancyOrphanedCustomer,
+			CustomerID: c.ID,
+			Detail:     "no tenant references this stripe customer",
+		}
+		if sub, ok := current[c.ID]; ok {
+			d.SubscriptionID = sub.ID
+		}
+		if t := tenantsByID[c.TenantID]; t != nil {
+			id := t.ID
+			d.TenantID = &id
+			d.Expected = c.ID
+			d.Actual = t.CustomerID
+			switch {
+			case t.Status == "deleted":
+				d.Detail = "customer metadata names a deleted tenant"
+			case t.CustomerID != "" && customersByID[t.CustomerID].ID != "":
+				d.Detail = fmt.Sprintf("customer metadata names a tenant billed through %s; duplicate customer", t.CustomerID)
+			case t.CustomerID != "":
+				d.Detail = fmt.Sprintf("customer metadata names a tenant whose customer %s is missing from stripe", t.CustomerID)
+			case linked[t.ID] != "":
+				d.Detail = fmt.Sprintf("duplicate customer for a tenant linked to %s", linked[t.ID])
+			default:
+				d.Detail = "customer metadata names a tenant without a customer; link it"
+				d.Fixable = true
+				linked[t.ID] = c.ID
+			}
+		}
+		found = append(found, d)
+	}
+
+	for i := range tenants {
+		t := tenants[i]
+		if t.Status == "deleted" {
+			continue
+		}
+		id := t.ID
+
+		if t.CustomerID == "" {
+			if cust, ok := linked[t.ID]; ok {
+				t.CustomerID = cust
+			} else {
+				if plans.Get(t.BillingPlan).StripePriceID != "" || t.SubscriptionID != "" {
+					found = append(found, StripeDiscrepancy{
+						Type:           DiscrepancyMissingCustomer,
+						TenantID:       &id,
+						SubscriptionID: t.SubscriptionID,
+						Detail:         fmt.Sprintf("tenant on plan %s has no stripe customer", t.BillingPlan),
+					})
+				}
+				continue
+			}
+		} else if _, ok := customersByID[t.CustomerID]; !ok {
+			found = append(found, StripeDiscrepancy{
+				Type:       DiscrepancyMissingCustomer,
+				TenantID:   &id,
+				CustomerID: t.CustomerID,
+				Actual:     t.CustomerID,
+				Detail:     "tenant references a stripe customer that does not exist or was deleted",
+			})
+			continue
+		}
+
+		sub, ok := current[t.CustomerID]
+		if !ok {
+			if t.SubscriptionID != "" {
+				found = append(found, StripeDiscrepancy{
+					Type:           DiscrepancySubscriptionMismatch,
+					TenantID:       &id,
+					CustomerID:     t.CustomerID,
+					SubscriptionID: t.SubscriptionID,
+					Actual:         t.SubscriptionID,
+					Detail:         "tenant tracks a subscription its stripe customer does not have",
+				})
+			}
+			continue
+		}
+
+		if t.SubscriptionID != sub.ID {
+			found = append(found, StripeDiscrepancy{
+				Type:           DiscrepancySubscriptionMismatch,
+				TenantID:       &id,
+				CustomerID:     t.CustomerID,
+				SubscriptionID: sub.ID,
+				Expected:       sub.ID,
+				Actual:         t.SubscriptionID,
+				Detail:         "tenant does not track its customer's current subscription",
+				Fixable:        true,
+			})
+		}
+
+		if want := mapSubscriptionStatus(sub.Status); t.Status != want {
+			d := StripeDiscrepancy{
+				Type:           DiscrepancyStatusMismatch,
+				TenantID:       &id,
+				CustomerID:     t.CustomerID,
+				SubscriptionID: sub.ID,
+				Expected:       want,
+				Actual:         t.Status,
+				Detail:         fmt.Sprintf("subscription is %s", sub.Status),
+				Fixable:        true,
+			}
+			if t.AdminSuspended && t.Status == "suspended" {
+				d.Detail += "; tenant was suspended by an admin"
+				d.Fixable = false
+			}
+			found = append(found, d)
+		}
+
+		if plan, ok := plans.PlanForPrice(sub.PriceID); ok && subscriptionLive(sub.Status) && t.BillingPlan != plan.Name {
+			found = append(found, StripeDiscrepancy{
+				Type:           DiscrepancyPlanMismatch,
+				TenantID:       &id,
+				CustomerID:     t.CustomerID,
+				SubscriptionID: sub.ID,
+				Expected:       plan.Name,
+				Actual:         t.BillingPlan,
+				Detail:         fmt.Sprintf("subscription is billed at price %s", sub.PriceID),
+				Fixable:        true,
+			})
+		}
+	}
+
+	return found
+}
+
+// StripeReconciler cross-checks tenants against Stripe on a schedule and on demand
+type StripeReconciler struct {
+	db        *database.Database
+	logger    *zap.Logger
+	directory CustomerDirectory
+	plans     *PlanCatalog
+	interval  time.Duration
+	autoFix   bool
+}
+
+// NewStripeReconciler creates a Stripe reconciler. Scheduled runs happen
+// every interval (zero disables them) and fix safe discrepancies when
+// autoFix is set.
+func NewStripeReconciler(db *database.Database, logger *zap.Logger, directory CustomerDirectory, plans *PlanCatalog, interval time.Duration, autoFix bool) *StripeReconciler {
+	return &StripeReconciler{
+		db:        db,
+		logger:    logger,
+		directory: directory,
+		plans:     plans,
+		interval:  interval,
+		autoFix:   autoFix,
+	}
+}
+
+// Start begins the scheduled reconciliation loop
+func (sr *StripeReconciler) Start(ctx context.Context) {
+	if sr.directory == nil || sr.interval <= 0 {
+		sr.logger.Info("scheduled stripe reconciliation disabled")
+		return
+	}
+	sr.logger.Info("starting stripe reconciler",
+		zap.Duration("interval", sr.interval),
+		zap.Bool("auto_fix", sr.autoFix),
+	)
+	go func() {
+		ticker := time.NewTicker(sr.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sr.runScheduled(ctx)
+			}
+		}
+	}()
+}
+
+// runScheduled performs one scheduled run, skipping it while another
+// replica's or an admin's run is in progress
+func (sr *StripeReconciler) runScheduled(ctx context.Context) {
+	run, err := sr.CreateRun(ctx, sr.db.Pool, sr.autoFix, StripeReconciliationTriggerScheduled)
+	if errors.Is(err, ErrReconciliationInProgress) {
+		sr.logger.Debug("skipping scheduled stripe reconciliation; a run is in progress")
+		return
+	}
+	if err != nil {
+		sr.logger.Error("failed to create stripe reconciliation run", zap.Error(err))
+		return
+	}
+	if err := sr.Execute(ctx, run.ID); err != nil {
+		sr.logger.Error("stripe reconciliation failed", zap.String("run_id", run.ID.String()), zap.Error(err))
+	}
+}
+
+// CreateRun records a pending run on q, which may be a transaction that
+// also enqueues its execution. Runs abandoned by a crashed replica are
+// failed first so they don't block new ones.
+func (sr *StripeReconciler) CreateRun(ctx context.Context, q database.Querier, autoFix bool, triggeredBy string) (*StripeReconciliationRun, error) {
+	if _, err := q.Exec(ctx, `
+		UPDATE stripe_reconciliation_runs
+		SET status = 'failed', error = 'abandoned', completed_at = NOW()
+		WHERE status IN ('pending', 'running') AND started_at < $1
+	`, time.Now().Add(-staleReconciliationRun)); err != nil {
+		return nil, fmt.Errorf("failed to expire abandoned runs: %w", err)
+	}
+
+	run := &StripeReconciliationRun{Status: "pending", AutoFix: autoFix, TriggeredBy: triggeredBy}
+	err := q.QueryRow(ctx, `
+		INSERT INTO stripe_reconciliation_runs (auto_fix, triggered_by)
+		VALUES ($1, $2)
+		RETURNING id, started_at
+	`, autoFix, triggeredBy).Scan(&run.ID, &run.StartedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrReconciliationInProgress
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+// Execute performs a pending run: it compares tenants with Stripe, applies
+// fixes when the run has auto-fix set and stores the discrepancy report.
+// A run that is no longer pending is left alone.
+func (sr *StripeReconciler) Execute(ctx context.Context, runID uuid.UUID) error {
+	if sr.directory == nil {
+		return sr.failRun(runID, errors.New("stripe billing is not configured"))
+	}
+
+	var autoFix bool
+	err := sr.db.Pool.QueryRow(ctx, `
+		UPDATE stripe_reconciliation_runs SET status = 'running'
+		WHERE id = $1 AND status = 'pending'
+		RETURNING auto_fix
+	`, runID).Scan(&autoFix)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	tenants, err := sr.loadTenants(ctx)
+	if err != nil {
+		return sr.failRun(runID, err)
+	}
+	customers, err := sr.directory.ListCustomers(ctx)
+	if err != nil {
+		return sr.failRun(runID, err)
+	}
+	subs, err := sr.directory.ListSubscriptions(ctx)
+	if err != nil {
+		return sr.failRun(runID, err)
+	}
+
+	found := findStripeDiscrepancies(tenants, customers, subs, sr.plans)
+	fixed := 0
+	if autoFix {
+		for i := range found {
+			if !found[i].Fixable {
+				continue
+			}
+			if err := sr.applyFix(ctx, &found[i]); err != nil {
+				msg := err.Error()
+				found[i].FixError = &msg
+				continue
+			}
+			found[i].Fixed = true
+			fixed++
+		}
+	}
+
+	if err := sr.saveReport(ctx, runID, found); err != nil {
+		return sr.failRun(runID, err)
+	}
+
+	_, err = sr.db.Pool.Exec(ctx, `
+		UPDATE stripe_reconciliation_runs
+		SET status = 'completed', tenants_checked = $2, customers_checked = $3,
+		    subscriptions_checked = $4, discrepancy_count = $5, fixed_count = $6,
+		    completed_at = NOW()
+		WHERE id = $1
+	`, runID, len(tenants), len(customers), len(subs), len(found), fixed)
+	if err != nil {
+		return err
+	}
+
+	sr.logger.Info("stripe reconciliation completed",
+		zap.String("run_id", runID.String()),
+		zap.Int("tenants", len(tenants)),
+		zap.Int("customers", len(customers)),
+		zap.Int("subscriptions", len(subs)),
+		zap.Int("discrepancies", len(found)),
+		zap.Int("fixed", fixed),
+	)
+	return nil
+}
+
+// failRun marks a run failed. It uses its own context so a run whose
+// context expired is still recorded.
+func (sr *StripeReconciler) failRun(runID uuid.UUID, runErr error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := sr.db.Pool.Exec(ctx, `
+		UPDATE stripe_reconciliation_runs
+		SET status = 'failed', error = $2, completed_at = NOW()
+		WHERE id = $1
+	`, runID, runErr.Error()); err != nil {
+		sr.logger.Error("failed to record stripe reconciliation failure", zap.Error(err))
+	}
+	return runErr
+}
+
+// loadTenants reads every tenant's billing state
+func (sr *StripeReconciler) loadTenants(ctx context.Context) ([]reconcileTenant, error) {
+	rows, err := sr.db.Pool.Query(ctx, `
+		SELECT id, email, status, billing_plan,
+		       COALESCE(stripe_customer_id, ''), COALESCE(stripe_subscription_id, ''),
+		       status = 'suspended'
+		           AND COALESCE(region_preferences->>'suspended_at', '') > COALESCE(region_preferences->>'activated_at', '')
+		FROM tenants
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []reconcileTenant
+	for rows.Next() {
+		var t reconcileTenant
+		if err := rows.Scan(&t.ID, &t.Email, &t.Status, &t.BillingPlan, &t.CustomerID, &t.SubscriptionID, &t.AdminSuspended); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, rows.Err()
+}
+
+// applyFix corrects one fixable discrepancy. The update is conditioned on
+// the tenant still holding the observed value and customer.
+func (sr *StripeReconciler) applyFix(ctx context.Context, d *StripeDiscrepancy) error {
+	var query string
+	args := []any{*d.TenantID, d.Expected, d.Actual, d.CustomerID}
+	switch d.Type {
+	case DiscrepancyOrphanedCustomer:
+		query = `UPDATE tenants SET stripe_customer_id = $2, updated_at = NOW()
+			WHERE id = $1 AND stripe_customer_id IS NULL`
+		args = args[:2]
+	case DiscrepancySubscriptionMismatch:
+		query = `UPDATE tenants SET stripe_subscription_id = $2, updated_at = NOW()
+			WHERE id = $1 AND COALESCE(stripe_subscription_id, '') = $3 AND stripe_customer_id = $4`
+	case DiscrepancyStatusMismatch:
+		query = `UPDATE tenants SET status = $2, updated_at = NOW()
+			WHERE id = $1 AND status = $3 AND stripe_customer_id = $4`
+	case DiscrepancyPlanMismatch:
+		query = `UPDATE tenants SET billing_plan = $2, updated_at = NOW()
+			WHERE id = $1 AND billing_plan = $3 AND stripe_customer_id = $4`
+	default:
+		return fmt.Errorf("%s discrepancies are not fixable", d.Type)
+	}
+
+	tag, err := sr.db.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New("tenant changed since it was compared")
+	}
+
+	sr.logger.Info("fixed stripe discrepancy",
+		zap.String("type", d.Type),
+		zap.String("tenant_id", d.TenantID.String()),
+		zap.String("customer_id", d.CustomerID),
+		zap.String("from", d.Actual),
+		zap.String("to", d.Expected),
+	)
+	return nil
+}
+
+// saveReport stores a run's discrepancies
+func (sr *StripeReconciler) saveReport(ctx context.Context, runID uuid.UUID, found []StripeDiscrepancy) error {
+	if len(found) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for i := range found {
+		d := &found[i]
+		d.ID = uuid.New()
+		batch.Queue(`
+			INSERT INTO stripe_reconciliation_discrepancies
+			    (id, run_id, type, tenant_id, stripe_customer_id, stripe_subscription_id,
+			     expected, actual, detail, fixable, fixed, fix_error)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, $12)
+		`, d.ID, runID, d.Type, d.TenantID, d.CustomerID, d.SubscriptionID,
+			d.Expected, d.Actual, d.Detail, d.Fixable, d.Fixed, d.FixError)
+	}
+	results := sr.db.Pool.SendBatch(ctx, batch)
+	defer results.Close()
+	for range found {
+		if _, err := results.Exec(); err != nil {
+			return fmt.Errorf("failed to store discrepancy: %w", err)
+		}
+	}
+	return nil
+}
+
+const stripeReconciliationRunColumns = `
+	id, status, auto_fix, triggered_by, tenants_checked, customers_checked,
+	subscriptions_checked, discrepancy_count, fixed_count, error, started_at, completed_at`
+
+func scanStripeReconciliationRun(row pgx.Row) (*StripeReconciliationRun, error) {
+	var run StripeReconciliationRun
+	err := row.Scan(&run.ID, &run.Status, &run.AutoFix, &run.TriggeredBy, &run.TenantsChecked,
+		&run.CustomersChecked, &run.SubscriptionsChecked, &run.DiscrepancyCount, &run.FixedCount,
+		&run.Error, &run.StartedAt, &run.CompletedAt)
+	return &run, err
+}
+
+// ListRuns returns the most recent runs, newest first, without their reports
+func (sr *StripeReconciler) ListRuns(ctx context.Context, limit int) ([]StripeReconciliationRun, error) {
+	rows, err := sr.db.Pool.Query(ctx, `SELECT `+stripeReconciliationRunColumns+`
+		FROM stripe_reconciliation_runs
+		ORDER BY started_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []StripeReconciliationRun{}
+	for rows.Next() {
+		run, err := scanStripeReconciliationRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, rows.Err()
+}
+
+// GetRun returns a run with its discrepancy report, optionally only the
+// discrepancies of one type
+func (sr *StripeReconciler) GetRun(ctx context.Context, runID uuid.UUID, discrepancyType string) (*StripeReconciliationRun, error) {
+	run, err := scanStripeReconciliationRun(sr.db.Pool.QueryRow(ctx, `SELECT `+stripeReconciliationRunColumns+`
+		FROM stripe_reconciliation_runs WHERE id = $1
+	`, runID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReconciliationRunNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	args := database.NewArgs()
+	where := database.NewWhere(args).Eq("run_id", runID)
+	if discrepancyType != "" {
+		where.Eq("type", discrepancyType)
+	}
+	rows, err := sr.db.Pool.Query(ctx, `
+		SELECT id, type, tenant_id, COALESCE(stripe_customer_id, ''), COALESCE(stripe_subscription_id, ''),
+		       COALESCE(expected, ''), COALESCE(actual, ''), detail, fixable, fixed, fix_error
+		FROM stripe_reconciliation_discrepancies
+		`+where.String()+`
+		ORDER BY type, created_at
+	`, args.Values()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	run.Discrepancies = []StripeDiscrepancy{}
+	for rows.Next() {
+		var d StripeDiscrepancy
+		if err := rows.Scan(&d.ID, &d.Type, &d.TenantID, &d.CustomerID, &d.SubscriptionID,
+			&d.Expected, &d.Actual, &d.Detail, &d.Fixable, &d.Fixed, &d.FixError); err != nil {
+			return nil, err
+		}
+		run.Discrepancies = append(run.Discrepancies, d)
+	}
+	return run, rows.Err()
+}
@@ -0,0 +1,113 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stripe/stripe-go/v76"
+)
+
+func TestCurrentSubscriptions(t *testing.T) {
+	base := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	current := currentSubscriptions([]StripeSubscriptionRecord{
+		{ID: "sub_old", CustomerID: "cus_a", Status: stripe.SubscriptionStatusActive, Created: base},
+		{ID: "sub_canceled", CustomerID: "cus_a", Status: stripe.SubscriptionStatusCanceled, Created: base.Add(time.Hour)},
+		{ID: "sub_new", CustomerID: "cus_a", Status: stripe.SubscriptionStatusPastDue, Created: base.Add(2 * time.Hour)},
+		{ID: "sub_b1", CustomerID: "cus_b", Status: stripe.SubscriptionStatusCanceled, Created: base},
+		{ID: "sub_b2", CustomerID: "cus_b", Status: stripe.SubscriptionStatusIncompleteExpired, Created: base.Add(time.Hour)},
+	})
+
+	if got := current["cus_a"].ID; got != "sub_new" {
+		t.Errorf("cus_a current = %s, want the newest live subscription", got)
+	}
+	if got := current["cus_b"].ID; got != "sub_b2" {
+		t.Errorf("cus_b current = %s, want the newest subscription when none is live", got)
+	}
+}
+
+func TestFindStripeDiscrepancies(t *testing.T) {
+	plans := NewPlanCatalog(map[string]string{"starter": "price_starter", "pro": "price_pro"})
+	base := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	inSync := reconcileTenant{ID: uuid.New(), Status: "active", BillingPlan: "pro", CustomerID: "cus_sync", SubscriptionID: "sub_sync"}
+	raced := reconcileTenant{ID: uuid.New(), Status: "suspended", BillingPlan: "starter", CustomerID: "cus_raced", SubscriptionID: "sub_raced_old"}
+	adminHeld := reconcileTenant{ID: uuid.New(), Status: "suspended", BillingPlan: "pro", CustomerID: "cus_held", SubscriptionID: "sub_held", AdminSuspended: true}
+	unlinked := reconcileTenant{ID: uuid.New(), Status: "active", BillingPlan: "pro"}
+	noCustomer := reconcileTenant{ID: uuid.New(), Status: "active", BillingPlan: "starter"}
+	free := reconcileTenant{ID: uuid.New(), Status: "active", BillingPlan: "free"}
+	gone := reconcileTenant{ID: uuid.New(), Status: "active", BillingPlan: "pro", CustomerID: "cus_deleted"}
+	deleted := reconcileTenant{ID: uuid.New(), Status: "deleted", BillingPlan: "pro", CustomerID: "cus_missing_too"}
+
+	customers := []StripeCustomerRecord{
+		{ID: "cus_sync", Created: base},
+		{ID: "cus_raced", Created: base},
+		{ID: "cus_held", Created: base},
+		{ID: "cus_unlinked", TenantID: unlinked.ID.String(), Created: base},
+		{ID: "cus_unlinked_dup", TenantID: unlinked.ID.String(), Created: base.Add(time.Hour)},
+		{ID: "cus_stray", Created: base},
+		{ID: "cus_sandbox", TenantID: inSync.ID.String(), Sandbox: true, Created: base},
+	}
+	subs := []StripeSubscriptionRecord{
+		{ID: "sub_sync", CustomerID: "cus_sync", Status: stripe.SubscriptionStatusActive, PriceID: "price_pro", Created: base},
+		{ID: "sub_raced_old", CustomerID: "cus_raced", Status: stripe.SubscriptionStatusCanceled, PriceID: "price_starter", Created: base},
+		{ID: "sub_raced", CustomerID: "cus_raced", Status: stripe.SubscriptionStatusActive, PriceID: "price_pro", Created: base.Add(time.Hour)},
+		{ID: "sub_held", CustomerID: "cus_held", Status: stripe.SubscriptionStatusActive, PriceID: "price_pro", Created: base},
+		{ID: "sub_unlinked", CustomerID: "cus_unlinked", Status: stripe.SubscriptionStatusActive, PriceID: "price_pro", Created: base},
+	}
+
+	found := findStripeDiscrepancies(
+		[]reconcileTenant{inSync, raced, adminHeld, unlinked, noCustomer, free, gone, deleted},
+		customers, subs, plans)
+
+	type key struct {
+		typ    string
+		tenant uuid.UUID
+		cust   string
+	}
+	got := make(map[key]StripeDiscrepancy)
+	for _, d := range found {
+		k := key{typ: d.Type, cust: d.CustomerID}
+		if d.TenantID != nil {
+			k.tenant = *d.TenantID
+		}
+		if _, dup := got[k]; dup {
+			t.Errorf("duplicate discrepancy %+v", k)
+		}
+		got[k] = d
+	}
+
+	want := []struct {
+		key      key
+		expected string
+		actual   string
+		fixable  bool
+	}{
+		{key{DiscrepancySubscriptionMismatch, raced.ID, "cus_raced"}, "sub_raced", "sub_raced_old", true},
+		{key{DiscrepancyStatusMismatch, raced.ID, "cus_raced"}, "active", "suspended", true},
+		{key{DiscrepancyPlanMismatch, raced.ID, "cus_raced"}, "pro", "starter", true},
+		{key{DiscrepancyStatusMismatch, adminHeld.ID, "cus_held"}, "active", "suspended", false},
+		{key{DiscrepancyOrphanedCustomer, unlinked.ID, "cus_unlinked"}, "cus_unlinked", "", true},
+		{key{DiscrepancyOrphanedCustomer, unlinked.ID, "cus_unlinked_dup"}, "cus_unlinked_dup", "", false},
+		// Checked against the customer the orphan fix links
+		{key{DiscrepancySubscriptionMismatch, unlinked.ID, "cus_unlinked"}, "sub_unlinked", "", true},
+		{key{DiscrepancyOrphanedCustomer, uuid.Nil, "cus_stray"}, "", "", false},
+		{key{DiscrepancyMissingCustomer, noCustomer.ID, ""}, "", "", false},
+		{key{DiscrepancyMissingCustomer, gone.ID, "cus_deleted"}, "", "cus_deleted", false},
+	}
+	for _, w := range want {
+		d, ok := got[w.key]
+		if !ok {
+			t.Errorf("missing discrepancy %+v", w.key)
+			continue
+		}
+		if d.Expected != w.expected || d.Actual != w.actual || d.Fixable != w.fixable {
+			t.Errorf("%+v: expected=%q actual=%q fixable=%v, want %q %q %v",
+				w.key, d.Expected, d.Actual, d.Fixable, w.expected, w.actual, w.fixable)
+		}
+		delete(got, w.key)
+	}
+	for k, d := range got {
+		t.Errorf("unexpected discrepancy %+v: %s", k, d.Detail)
+	}
+}
@@ -66,6 +66,9 @@ type WebhookHandler struct {
 	// In production, this should be backed by a distributed cache (Redis) or database table.
 	processedEvents map[string]time.Time
 
+	// plans maps Stripe price IDs back to plan names (optional)
+	plans *PlanCatalog
+
 	mu sync.Mutex
 }
 
@@ -108,6 +111,11 @@ func NewWebhookHandler(webhookSecret string, db *database.Database, cacheClient
 	}
 }
 
+// SetPlanCatalog sets the catalog used to map subscription prices to plans
+func (h *WebhookHandler) SetPlanCatalog(plans *PlanCatalog) {
+	h.plans = plans
+}
+
 // HandleWebhook processes incoming Stripe webhook events.
 //
 // This is the main entry point for all Stripe webhook events. It performs:
@@ -287,6 +295,24 @@ func (h *WebhookHandler) handlePaymentSucceeded(ctx context.Context, event strip
 		return fmt.Errorf("failed to update tenant status: %w", err)
 	}
 
+	// Record the event with the status change so it survives a crash after commit
+	evt := events.NewEvent(
+		events.EventPaymentSucceeded,
+		tenantID.String(),
+		map[string]interface{}{
+			"tenant_id":         tenantID.String(),
+			"tenant_name":       tenantName,
+			"amount":            paymentIntent.Amount,
+			"currency":          string(paymentIntent.Currency),
+			"amount_formatted":  fmt.Sprintf("$%.2f", float64(paymentIntent.Amount)/100),
+			"payment_method":    paymentIntent.PaymentMethod,
+			"stripe_payment_id": paymentIntent.ID,
+		},
+	)
+	if err := events.Enqueue(ctx, tx, evt); err != nil {
+		return err
+	}
+
 	// Commit transaction
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -300,29 +326,6 @@ func (h *WebhookHandler) handlePaymentSucceeded(ctx context.Context, event strip
 		zap.String("currency", string(paymentIntent.Currency)),
 	)
 
-	// Publish payment succeeded event
-	if h.eventBus != nil {
-		evt := events.NewEvent(
-			events.EventPaymentSucceeded,
-			tenantID.String(),
-			map[string]interface{}{
-				"tenant_id":         tenantID.String(),
-				"tenant_name":       tenantName,
-				"amount":            paymentIntent.Amount,
-				"currency":          string(paymentIntent.Currency),
-				"amount_formatted":  fmt.Sprintf("$%.2f", float64(paymentIntent.Amount)/100),
-				"payment_method":    paymentIntent.PaymentMethod,
-				"stripe_payment_id": paymentIntent.ID,
-			},
-		)
-		if err := h.eventBus.Publish(ctx, evt); err != nil {
-			h.logger.Error("failed to publish payment succeeded event",
-				zap.Error(err),
-				zap.String("payment_id", paymentIntent.ID),
-			)
-		}
-	}
-
 	return nil
 }
 
@@ -426,7 +429,8 @@ func (h *WebhookHandler) handlePaymentFailed(ctx context.Context, event stripe.E
 // 4. Log subscription change for audit trail
 //
 // Database updates:
-// - tenants.billing_plan = subscription.items[0].price.id
+// - tenants.billing_plan = plan billed by subscription.items[0].price.id (unchanged if unknown)
+// - tenants.stripe_subscription_id = subscription.id
 // - tenants.status = subscription.status (active, canceled, past_due, etc.)
 // - tenants.updated_at = NOW()
 //
@@ -465,6 +469,14 @@ func (h *WebhookHandler) handleSubscriptionUpdated(ctx context.Context, event st
 	// Map subscription status to tenant status
 	tenantStatus := mapSubscriptionStatus(subscription.Status)
 
+	// Map the price to a plan name; unknown prices leave the plan unchanged
+	var planName *string
+	if h.plans != nil {
+		if plan, ok := h.plans.PlanForPrice(priceID); ok {
+			planName = &plan.Name
+		}
+	}
+
 	// Start transaction
 	tx, err := h.db.Pool.Begin(ctx)
 	if err != nil {
@@ -475,14 +487,15 @@ func (h *WebhookHandler) handleSubscriptionUpdated(ctx context.Context, event st
 	// Update tenant billing plan and status
 	query := `
 		UPDATE tenants
-		SET billing_plan = $1, status = $2, updated_at = NOW()
+		SET billing_plan = COALESCE($1, billing_plan), status = $2,
+		    stripe_subscription_id = $4, updated_at = NOW()
 		WHERE stripe_customer_id = $3
 		RETURNING id, name
 	`
 
 	var tenantID uuid.UUID
 	var tenantName string
-	err = tx.QueryRow(ctx, query, priceID, tenantStatus, customerID).Scan(&tenantID, &tenantName)
+	err = tx.QueryRow(ctx, query, planName, tenantStatus, customerID, subscription.ID).Scan(&tenantID, &tenantName)
 	if err != nil {
 		return fmt.Errorf("failed to update tenant subscription: %w", err)
 	}
@@ -641,7 +654,14 @@ func (h *WebhookHandler) reserveEvent(ctx context.Context, eventID string) (bool
 	if h.cache != nil {
 		key := h.redisKeyForEvent(eventID)
 		acquired, err := h.cache.SetNX(ctx, key, "processing", webhookProcessingTTL)
-		return acquired, err
+		if err == nil || !h.cache.FailOpen(cache.FeatureIdempotency) {
+			return acquired, err
+		}
+		// Fall back to per-process deduplication while Redis is down
+		h.logger.Warn("webhook idempotency cache unavailable, deduplicating in memory",
+			zap.String("event_id", eventID),
+			zap.Error(err),
+		)
 	}
 
 	h.mu.Lock()
@@ -672,7 +692,6 @@ func (h *WebhookHandler) finalizeEvent(ctx context.Context, eventID string, succ
 				)
 			}
 		}
-		return
 	}
 
 	if !success {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,6 +19,19 @@ type Config struct {
 	Monitoring MonitoringConfig
 	R2         R2Config
 	SkyPilot   SkyPilotConfig
+
+	QualitySampling QualitySamplingConfig
+	Compression     CompressionConfig
+	NodeProxy       NodeProxyConfig
+	Playground      PlaygroundConfig
+	NodeAPI         NodeAPIConfig
+	API             APIConfig
+	NodeLogs        NodeLogsConfig
+	NodeHardening   NodeHardeningConfig
+	DegradedMode    DegradedModeConfig
+	ChatLimits      ChatLimitsConfig
+	Audio           AudioConfig
+	Images          ImagesConfig
 }
 
 // ServerConfig holds server configuration
@@ -27,7 +41,47 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	IdleTimeout     time.Duration
-	ControlPlaneURL string // Public HTTPS URL for node agent registration
+	ControlPlaneURL string   // Public HTTPS URL for node agent registration
+	TrustedProxies  []string // Load balancer networks whose X-Forwarded-For is believed
+}
+
+// APIConfig holds tenant API versioning policy
+type APIConfig struct {
+	V1Sunset time.Time // When deprecated v1 routes stop being served; zero while unannounced
+}
+
+// NodeLogsConfig holds the retention policy for node launch logs, which are
+// archived to R2 once idle
+type NodeLogsConfig struct {
+	ArchiveAfter    time.Duration // Idle time before a node's log is archived; at most 12h
+	ArchiveInterval time.Duration // How often idle logs are archived and expired archives deleted
+	Retention       time.Duration // How long archives are kept after their last line
+	FailedRetention time.Duration // Retention for launches that ended in failure
+}
+
+// NodeHardeningConfig holds the security hardening applied to GPU nodes
+// during setup
+type NodeHardeningConfig struct {
+	Enabled      bool     // Harden nodes at launch: key-only SSH, firewall, unattended security updates
+	AllowedCIDRs []string // Networks allowed to reach the inference port (control plane and mesh)
+}
+
+// NodeAPIConfig holds the internal listener serving node agent callbacks
+// (registration, heartbeats, drains, termination warnings, crash reports)
+type NodeAPIConfig struct {
+	Host          string
+	Port          int    // The listener is disabled when 0; nodes then use the public listener
+	URL           string // URL nodes reach the listener at; defaults to CONTROL_PLANE_URL
+	Token         string // Shared token node agents authenticate with
+	ReadTimeout   time.Duration
+	WriteTimeout  time.Duration
+	MaxBodyBytes  int64
+	MaxConcurrent int // Node requests handled at once; more wait in line
+
+	// Node registry: nodes look up control plane URLs and sibling nodes
+	DiscoverySecret        string        // Signs discovery tokens; defaults to NODE_API_TOKEN
+	DiscoveryTokenTTL      time.Duration // Lifetime of discovery tokens
+	DiscoveryAlternateURLs []string      // Other URLs nodes may reach the control plane at
 }
 
 // DatabaseConfig holds database configuration
@@ -38,18 +92,45 @@ type DatabaseConfig struct {
 	Password        string
 	Database        string
 	SSLMode         string
-	MaxOpenConns    int
-	MaxIdleConns    int
+	MaxOpenConns    int // Pool ceiling
+	MaxIdleConns    int // Connections the pool keeps open at minimum
 	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	PoolMonitor     PoolMonitorConfig
+}
+
+// PoolMonitorConfig holds connection pool monitoring and adaptive sizing
+type PoolMonitorConfig struct {
+	Interval      time.Duration // How often pool statistics are exported and sizing reviewed
+	Adaptive      bool          // Open spare connections under load and release idle ones when the database is struggling
+	SpareConns    int           // Idle connections kept ready while callers are waiting for connections
+	SlowPing      time.Duration // Ping latency above which the database counts as struggling
+	HoldWarnAfter time.Duration // Connections held longer than this are logged with the request holding them
 }
 
 // RedisConfig holds Redis configuration
 type RedisConfig struct {
+	// Mode is standalone, sentinel or cluster
+	Mode     string
 	Host     string
 	Port     int
 	Password string
 	DB       int
 	PoolSize int
+	// Addrs lists sentinel or cluster seed addresses (host:port)
+	Addrs []string
+	// MasterName is the Sentinel master set name
+	MasterName       string
+	SentinelPassword string
+	// HealthCheckInterval is how often the connection is pinged for health metrics
+	HealthCheckInterval time.Duration
+	// FailOpen lists the Redis-backed features that are skipped while Redis
+	// is down instead of failing requests: rate_limit, idempotency, admin_guard
+	FailOpen []string
+	// CatalogCacheTTL is how long the /v1/models listing is cached
+	CatalogCacheTTL time.Duration
+	// RouteCacheTTL is how long each model's routable nodes are cached in memory
+	RouteCacheTTL time.Duration
 }
 
 // BillingConfig holds billing configuration
@@ -59,6 +140,19 @@ type BillingConfig struct {
 	StripeWebhookSecret string
 	AggregationInterval time.Duration
 	ExportInterval      time.Duration
+
+	// Stripe price IDs for self-serve plans (POST /v1/billing/upgrade)
+	StripePriceStarter string
+	StripePricePro     string
+
+	// Billing sandbox: Stripe test-mode key and default accelerated cycle
+	StripeSandboxSecretKey string
+	SandboxCycle           time.Duration
+
+	// Stripe reconciliation: how often tenants are cross-checked against
+	// Stripe (0 disables scheduled runs) and whether safe fixes are applied
+	ReconcileInterval time.Duration
+	ReconcileAutoFix  bool
 }
 
 // SecurityConfig holds security configuration
@@ -69,12 +163,24 @@ type SecurityConfig struct {
 	TLSCertPath      string
 	TLSKeyPath       string
 	AdminAPIToken    string
+
+	// RequestSigningKey encrypts tenant request signing secrets at rest;
+	// request signing is unavailable when unset
+	RequestSigningKey string
+
+	// KillSwitchTwoPerson requires a second admin to confirm an emergency
+	// kill switch before it takes effect
+	KillSwitchTwoPerson bool
 }
 
 // RuntimeConfig holds runtime dependency versions
 type RuntimeConfig struct {
 	VLLMVersion  string
 	TorchVersion string
+
+	// CompatGate requires a passing compatibility run before deployments
+	// are pinned to a vLLM newer than VLLMVersion
+	CompatGate bool
 }
 
 // MonitoringConfig holds monitoring configuration
@@ -83,6 +189,106 @@ type MonitoringConfig struct {
 	PrometheusPort int
 	MetricsPath    string
 	LogLevel       string
+
+	// Stale node detection based on heartbeat gaps
+	StaleHeartbeatThreshold      time.Duration // Stop routing to nodes whose last heartbeat is older than this
+	DeregisterHeartbeatThreshold time.Duration // Deregister (mark dead) nodes silent for longer than this
+
+	// Runtime drift between deployment nodes and their spec
+	DriftAutoRemediate  bool          // Replace nodes that stay drifted instead of only reporting them
+	DriftRemediateAfter time.Duration // How long a node must stay drifted before it is replaced
+
+	// Retries of deployment launches that fail for transient reasons
+	LaunchMaxAttempts     int           // Attempts per node launch, including the first; 1 disables retries
+	LaunchRetryBackoff    time.Duration // Wait before the first retry, doubled for each later one
+	LaunchRetryMaxBackoff time.Duration // Upper bound of the retry wait, and how long a deployment waits after running out of attempts
+
+	// Capacity incidents when deployments serve below their minimum
+	CapacityAlertAfter time.Duration // How long serving nodes may stay below min_replicas before tenants and operators are notified
+
+	// Autoscaling of deployments on queue depth and latency; deployments can override each
+	AutoscaleTargetQueueDepth  int           // Requests waiting per serving node to scale up at
+	AutoscaleTargetP95Latency  time.Duration // p95 request latency to scale up at
+	AutoscaleScaleUpCooldown   time.Duration // Minimum time between scale ups
+	AutoscaleScaleDownCooldown time.Duration // Minimum time from any scaling to a scale down
+
+	// Traffic ramp of newly registered nodes
+	CanaryDuration         time.Duration // How long a new node's share of traffic takes to grow to full; 0 sends full traffic at once
+	CanaryInitialShare     float64       // Share of traffic a new node is offered at first
+	CanaryMaxErrorRate     float64       // Error rate above which a ramping node is cordoned
+	CanaryMinRequests      int           // Requests a ramping node serves before it is judged
+	CanaryMaxLatencyFactor float64       // Latency, relative to the model's other nodes, above which a ramping node is cordoned
+}
+
+// QualitySamplingConfig holds opt-in prompt/response sampling for offline
+// quality evaluation. Only tenants that opt in are sampled.
+type QualitySamplingConfig struct {
+	Rate      float64       // Fraction of an opted-in tenant's requests sampled (0.001 = 0.1%)
+	HashKey   string        // HMAC key for hashing identifiers; sampling is disabled when unset
+	MaxBytes  int           // Prompt and response text kept per sample, each
+	Retention time.Duration // Samples older than this are deleted
+}
+
+// CompressionConfig holds gzip compression of non-streaming gateway
+// responses
+type CompressionConfig struct {
+	Enabled  bool
+	MinBytes int // Responses smaller than this are sent uncompressed
+	Level    int // compress/gzip level, 1 (fastest) to 9 (smallest); -1 is the default
+}
+
+// PlaygroundConfig holds the public, anonymous playground used by the
+// marketing site's try-it-now page
+type PlaygroundConfig struct {
+	Model                   string // Model the playground serves; the playground is disabled when unset
+	CaptchaSecret           string // Captcha site secret; the playground is disabled when unset
+	CaptchaVerifyURL        string // siteverify endpoint of the captcha provider
+	MaxTokens               int    // Cap on max_tokens of a playground completion
+	RequestsPerMinute       int    // Per client IP
+	RequestsPerDay          int    // Per client IP
+	GlobalRequestsPerMinute int    // Across all playground clients
+}
+
+// NodeProxyConfig holds the gateway's connection pool to inference nodes
+// and the headers it sends them
+type NodeProxyConfig struct {
+	MaxIdleConnsPerHost int           // Idle connections kept open per node
+	IdleConnTimeout     time.Duration // How long an idle connection stays open
+	PrewarmConns        int           // Connections opened when a node becomes active; 0 disables
+
+	ForwardHeaders  []string // Client headers forwarded to nodes; empty forwards all not stripped
+	StripHeaders    []string // Client headers never forwarded, on top of credentials and hop-by-hop headers
+	TenantHeader    string   // Header carrying the hashed tenant ID; empty disables
+	RequestIDHeader string   // Header carrying the gateway request ID; empty disables
+	TenantHashKey   string   // Key for hashing tenant IDs; unkeyed SHA-256 when empty
+}
+
+// DegradedModeConfig caps max_tokens of low-priority requests while the
+// fleet is saturated, keeping latency down for everyone else
+type DegradedModeConfig struct {
+	Enabled          bool
+	Saturation       float64  // Share of the fleet's concurrency in use that triggers the cap
+	MaxTokens        int      // max_tokens cap for low-priority requests
+	LowPriorityPlans []string // Plans whose tenants' requests are always low priority
+}
+
+// ChatLimitsConfig overrides the plans' limits on chat requests, keyed by
+// plan name. Plans left out keep their built-in limits; zero lifts a limit.
+type ChatLimitsConfig struct {
+	MaxMessages     map[string]int // Most messages a chat request may carry
+	MaxMessageChars map[string]int // Most characters of text in a single message
+}
+
+// AudioConfig holds limits of the audio transcription endpoint
+type AudioConfig struct {
+	MaxFileMB int // Largest audio file accepted for transcription
+}
+
+// ImagesConfig holds limits of the image generation endpoint
+type ImagesConfig struct {
+	MaxImages    int // Most images a single request may ask for
+	MaxDimension int // Largest image side in pixels
+	MaxSteps     int // Most inference steps a single request may ask for
 }
 
 // R2Config holds Cloudflare R2 configuration for model storage
@@ -92,6 +298,12 @@ type R2Config struct {
 	AccessKey string // R2 Access Key ID
 	SecretKey string // R2 Secret Access Key
 	CDNDomain string // Optional: Custom CDN domain for cache
+
+	CrashBucket   string // Bucket for node crash forensics bundles (defaults to Bucket)
+	NodeLogBucket string // Bucket for archived node launch logs (defaults to Bucket)
+	ExportBucket  string // Bucket for tenant account export archives (defaults to Bucket)
+	FilesBucket   string // Bucket for tenant files uploaded through the Files API (defaults to Bucket)
+	ConfigBucket  string // Bucket for platform configuration snapshots (defaults to Bucket)
 }
 
 // SkyPilotConfig holds SkyPilot configuration
@@ -112,6 +324,31 @@ type SkyPilotConfig struct {
 
 	// Credential encryption key (for cloud credentials in DB)
 	CredentialEncryptionKey string    // Encryption key for storing cloud credentials securely
+
+	// Per-tenant workspaces (API Server mode only)
+	TenantWorkspaces    bool          // Isolate each tenant's clusters in its own SkyPilot workspace
+	WorkspacePrefix     string        // Prefix for tenant workspace names (e.g., "tenant-")
+	DefaultWorkspace    string        // Workspace for platform-owned clusters
+
+	// Credential profiles (API Server mode only)
+	CredentialProfiles  bool          // Register tenant credentials as named profiles instead of sending them on every launch
+
+	// Catalog sync (instance_types / region availability)
+	CatalogURL          string        // Base URL of the SkyPilot catalog (contains <cloud>/vms.csv)
+	CatalogSyncEnabled  bool          // Whether to run the scheduled catalog sync
+	CatalogSyncInterval time.Duration // How often to sync the catalog (default: nightly)
+
+	// Task templates
+	TemplateDir string // Directory of <runtime>[.<provider>].yaml.tmpl files replacing or adding to the built-in templates
+
+	// Remote command execution on nodes
+	ExecTimeout         time.Duration // Default timeout of a command run on a node
+	ExecMaxTimeout      time.Duration // Upper bound for a timeout requested via the admin API
+	ExecAllowedCommands []string      // Commands the admin API may run on nodes; "*" allows any
+
+	// Launch queue
+	LaunchConcurrency       int      // Concurrent launches per provider/region without its own limit; 0 is unlimited
+	LaunchConcurrencyLimits []string // "provider=N" or "provider/region=N" limits overriding LaunchConcurrency
 }
 
 // LoadConfig loads configuration from environment variables
@@ -124,6 +361,21 @@ func LoadConfig() (*Config, error) {
 			WriteTimeout:    getEnvAsDuration("SERVER_WRITE_TIMEOUT", "30s"),
 			IdleTimeout:     getEnvAsDuration("SERVER_IDLE_TIMEOUT", "120s"),
 			ControlPlaneURL: getEnv("CONTROL_PLANE_URL", "https://api.crosslogic.ai"),
+			TrustedProxies:  getEnvAsList("TRUSTED_PROXY_CIDRS", ""),
+		},
+		NodeAPI: NodeAPIConfig{
+			Host:          getEnv("NODE_API_HOST", "0.0.0.0"),
+			Port:          getEnvAsInt("NODE_API_PORT", 0),
+			URL:           getEnv("NODE_API_URL", ""),
+			Token:         getEnv("NODE_API_TOKEN", ""),
+			ReadTimeout:   getEnvAsDuration("NODE_API_READ_TIMEOUT", "10s"),
+			WriteTimeout:  getEnvAsDuration("NODE_API_WRITE_TIMEOUT", "10s"),
+			MaxBodyBytes:  int64(getEnvAsInt("NODE_API_MAX_BODY_BYTES", 1<<20)),
+			MaxConcurrent: getEnvAsInt("NODE_API_MAX_CONCURRENT", 200),
+
+			DiscoverySecret:        getEnv("NODE_DISCOVERY_SECRET", os.Getenv("NODE_API_TOKEN")),
+			DiscoveryTokenTTL:      getEnvAsDuration("NODE_DISCOVERY_TOKEN_TTL", "15m"),
+			DiscoveryAlternateURLs: getEnvAsList("NODE_DISCOVERY_ALTERNATE_URLS", ""),
 		},
 		Database: DatabaseConfig{
 			Host:            getEnv("DB_HOST", "localhost"),
@@ -135,6 +387,14 @@ func LoadConfig() (*Config, error) {
 			MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
 			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 5),
 			ConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", "5m"),
+			ConnMaxIdleTime: getEnvAsDuration("DB_CONN_MAX_IDLE_TIME", "30m"),
+			PoolMonitor: PoolMonitorConfig{
+				Interval:      getEnvAsDuration("DB_POOL_MONITOR_INTERVAL", "15s"),
+				Adaptive:      getEnvAsBool("DB_POOL_ADAPTIVE", true),
+				SpareConns:    getEnvAsInt("DB_POOL_SPARE_CONNS", 2),
+				SlowPing:      getEnvAsDuration("DB_POOL_SLOW_PING", "250ms"),
+				HoldWarnAfter: getEnvAsDuration("DB_POOL_HOLD_WARN_AFTER", "10s"),
+			},
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
@@ -142,6 +402,15 @@ func LoadConfig() (*Config, error) {
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getEnvAsInt("REDIS_DB", 0),
 			PoolSize: getEnvAsInt("REDIS_POOL_SIZE", 10),
+
+			Mode:                getEnv("REDIS_MODE", "standalone"),
+			Addrs:               getEnvAsList("REDIS_ADDRS", ""),
+			MasterName:          getEnv("REDIS_SENTINEL_MASTER", ""),
+			SentinelPassword:    getEnv("REDIS_SENTINEL_PASSWORD", ""),
+			HealthCheckInterval: getEnvAsDuration("REDIS_HEALTH_CHECK_INTERVAL", "5s"),
+			FailOpen:            getEnvAsList("REDIS_FAIL_OPEN", "admin_guard"),
+			CatalogCacheTTL:     getEnvAsDuration("MODEL_CATALOG_CACHE_TTL", "60s"),
+			RouteCacheTTL:       getEnvAsDuration("ROUTE_CACHE_TTL", "2s"),
 		},
 		Billing: BillingConfig{
 			Enabled:             getEnvAsBool("BILLING_ENABLED", true),
@@ -149,6 +418,14 @@ func LoadConfig() (*Config, error) {
 			StripeWebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
 			AggregationInterval: getEnvAsDuration("BILLING_AGGREGATION_INTERVAL", "1h"),
 			ExportInterval:      getEnvAsDuration("BILLING_EXPORT_INTERVAL", "5m"),
+			StripePriceStarter:  getEnv("STRIPE_PRICE_STARTER", ""),
+			StripePricePro:      getEnv("STRIPE_PRICE_PRO", ""),
+
+			StripeSandboxSecretKey: getEnv("STRIPE_SANDBOX_SECRET_KEY", ""),
+			SandboxCycle:           getEnvAsDuration("BILLING_SANDBOX_CYCLE", "1h"),
+
+			ReconcileInterval: getEnvAsDuration("STRIPE_RECONCILE_INTERVAL", "24h"),
+			ReconcileAutoFix:  getEnvAsBool("STRIPE_RECONCILE_AUTO_FIX", true),
 		},
 		Security: SecurityConfig{
 			APIKeyHashRounds: getEnvAsInt("API_KEY_HASH_ROUNDS", 12),
@@ -157,16 +434,44 @@ func LoadConfig() (*Config, error) {
 			TLSCertPath:      getEnv("TLS_CERT_PATH", ""),
 			TLSKeyPath:       getEnv("TLS_KEY_PATH", ""),
 			AdminAPIToken:    getEnv("ADMIN_API_TOKEN", ""),
+
+			RequestSigningKey: getEnv("REQUEST_SIGNING_ENCRYPTION_KEY", ""),
+
+			KillSwitchTwoPerson: getEnvAsBool("KILL_SWITCH_TWO_PERSON", true),
 		},
 		Runtime: RuntimeConfig{
 			VLLMVersion:  getEnv("VLLM_VERSION", "0.6.2"),
 			TorchVersion: getEnv("TORCH_VERSION", "2.4.0"),
+			CompatGate:   getEnvAsBool("VLLM_COMPAT_GATE", true),
 		},
 		Monitoring: MonitoringConfig{
 			Enabled:        getEnvAsBool("MONITORING_ENABLED", true),
 			PrometheusPort: getEnvAsInt("PROMETHEUS_PORT", 9090),
 			MetricsPath:    getEnv("METRICS_PATH", "/metrics"),
 			LogLevel:       getEnv("LOG_LEVEL", "info"),
+
+			StaleHeartbeatThreshold:      getEnvAsDuration("NODE_STALE_HEARTBEAT_THRESHOLD", "30s"),
+			DeregisterHeartbeatThreshold: getEnvAsDuration("NODE_DEREGISTER_HEARTBEAT_THRESHOLD", "5m"),
+
+			DriftAutoRemediate:  getEnvAsBool("NODE_DRIFT_AUTO_REMEDIATE", false),
+			DriftRemediateAfter: getEnvAsDuration("NODE_DRIFT_REMEDIATE_AFTER", "10m"),
+
+			LaunchMaxAttempts:     getEnvAsInt("DEPLOYMENT_LAUNCH_MAX_ATTEMPTS", 4),
+			LaunchRetryBackoff:    getEnvAsDuration("DEPLOYMENT_LAUNCH_RETRY_BACKOFF", "30s"),
+			LaunchRetryMaxBackoff: getEnvAsDuration("DEPLOYMENT_LAUNCH_RETRY_MAX_BACKOFF", "10m"),
+
+			CapacityAlertAfter: getEnvAsDuration("DEPLOYMENT_CAPACITY_ALERT_AFTER", "5m"),
+
+			AutoscaleTargetQueueDepth:  getEnvAsInt("DEPLOYMENT_AUTOSCALE_TARGET_QUEUE_DEPTH", 4),
+			AutoscaleTargetP95Latency:  getEnvAsDuration("DEPLOYMENT_AUTOSCALE_TARGET_P95_LATENCY", "20s"),
+			AutoscaleScaleUpCooldown:   getEnvAsDuration("DEPLOYMENT_AUTOSCALE_SCALE_UP_COOLDOWN", "3m"),
+			AutoscaleScaleDownCooldown: getEnvAsDuration("DEPLOYMENT_AUTOSCALE_SCALE_DOWN_COOLDOWN", "10m"),
+
+			CanaryDuration:         getEnvAsDuration("NODE_CANARY_DURATION", "5m"),
+			CanaryInitialShare:     getEnvAsFloat("NODE_CANARY_INITIAL_SHARE", 0.05),
+			CanaryMaxErrorRate:     getEnvAsFloat("NODE_CANARY_MAX_ERROR_RATE", 0.1),
+			CanaryMinRequests:      getEnvAsInt("NODE_CANARY_MIN_REQUESTS", 20),
+			CanaryMaxLatencyFactor: getEnvAsFloat("NODE_CANARY_MAX_LATENCY_FACTOR", 3),
 		},
 		R2: R2Config{
 			Endpoint:  getEnv("R2_ENDPOINT", ""),
@@ -174,6 +479,70 @@ func LoadConfig() (*Config, error) {
 			AccessKey: getEnv("R2_ACCESS_KEY", ""),
 			SecretKey: getEnv("R2_SECRET_KEY", ""),
 			CDNDomain: getEnv("R2_CDN_DOMAIN", ""),
+
+			CrashBucket:   getEnv("R2_CRASH_BUCKET", getEnv("R2_BUCKET", "crosslogic-models")),
+			NodeLogBucket: getEnv("R2_NODE_LOG_BUCKET", getEnv("R2_BUCKET", "crosslogic-models")),
+			ExportBucket:  getEnv("R2_EXPORT_BUCKET", getEnv("R2_BUCKET", "crosslogic-models")),
+			FilesBucket:   getEnv("R2_FILES_BUCKET", getEnv("R2_BUCKET", "crosslogic-models")),
+			ConfigBucket:  getEnv("R2_CONFIG_BUCKET", getEnv("R2_BUCKET", "crosslogic-models")),
+		},
+		NodeLogs: NodeLogsConfig{
+			ArchiveAfter:    getEnvAsDuration("NODE_LOG_ARCHIVE_AFTER", "1h"),
+			ArchiveInterval: getEnvAsDuration("NODE_LOG_ARCHIVE_INTERVAL", "10m"),
+			Retention:       getEnvAsDuration("NODE_LOG_RETENTION", "720h"),
+			FailedRetention: getEnvAsDuration("NODE_LOG_FAILED_RETENTION", "2160h"),
+		},
+		NodeHardening: NodeHardeningConfig{
+			Enabled:      getEnvAsBool("NODE_HARDENING_ENABLED", false),
+			AllowedCIDRs: getEnvAsList("NODE_HARDENING_ALLOWED_CIDRS", ""),
+		},
+		QualitySampling: QualitySamplingConfig{
+			Rate:      getEnvAsFloat("QUALITY_SAMPLE_RATE", 0.001),
+			HashKey:   getEnv("QUALITY_SAMPLE_HASH_KEY", ""),
+			MaxBytes:  getEnvAsInt("QUALITY_SAMPLE_MAX_BYTES", 32768),
+			Retention: getEnvAsDuration("QUALITY_SAMPLE_RETENTION", "720h"),
+		},
+		Compression: CompressionConfig{
+			Enabled:  getEnvAsBool("GATEWAY_COMPRESSION_ENABLED", true),
+			MinBytes: getEnvAsInt("GATEWAY_COMPRESSION_MIN_BYTES", 1024),
+			Level:    getEnvAsInt("GATEWAY_COMPRESSION_LEVEL", -1),
+		},
+		NodeProxy: NodeProxyConfig{
+			MaxIdleConnsPerHost: getEnvAsInt("NODE_PROXY_MAX_IDLE_CONNS_PER_HOST", 64),
+			IdleConnTimeout:     getEnvAsDuration("NODE_PROXY_IDLE_CONN_TIMEOUT", "90s"),
+			PrewarmConns:        getEnvAsInt("NODE_PROXY_PREWARM_CONNS", 4),
+			ForwardHeaders:      getEnvAsList("NODE_PROXY_FORWARD_HEADERS", ""),
+			StripHeaders:        getEnvAsList("NODE_PROXY_STRIP_HEADERS", "X-Signature,X-Signature-Timestamp,X-Signature-Nonce"),
+			TenantHeader:        getEnvOrEmpty("NODE_PROXY_TENANT_HEADER", "X-Tenant-Hash"),
+			RequestIDHeader:     getEnvOrEmpty("NODE_PROXY_REQUEST_ID_HEADER", "X-Request-ID"),
+			TenantHashKey:       getEnv("NODE_PROXY_TENANT_HASH_KEY", ""),
+		},
+		DegradedMode: DegradedModeConfig{
+			Enabled:          getEnvAsBool("DEGRADED_MODE_ENABLED", false),
+			Saturation:       getEnvAsFloat("DEGRADED_MODE_SATURATION", 0.9),
+			MaxTokens:        getEnvAsInt("DEGRADED_MODE_MAX_TOKENS", 256),
+			LowPriorityPlans: getEnvAsList("DEGRADED_MODE_LOW_PRIORITY_PLANS", "free"),
+		},
+		ChatLimits: ChatLimitsConfig{
+			MaxMessages:     getEnvAsIntMap("CHAT_MAX_MESSAGES"),
+			MaxMessageChars: getEnvAsIntMap("CHAT_MAX_MESSAGE_CHARS"),
+		},
+		Audio: AudioConfig{
+			MaxFileMB: getEnvAsInt("AUDIO_MAX_FILE_MB", 25),
+		},
+		Images: ImagesConfig{
+			MaxImages:    getEnvAsInt("IMAGE_MAX_N", 4),
+			MaxDimension: getEnvAsInt("IMAGE_MAX_DIMENSION", 1536),
+			MaxSteps:     getEnvAsInt("IMAGE_MAX_STEPS", 50),
+		},
+		Playground: PlaygroundConfig{
+			Model:                   getEnv("PLAYGROUND_MODEL", ""),
+			CaptchaSecret:           getEnv("PLAYGROUND_CAPTCHA_SECRET", ""),
+			CaptchaVerifyURL:        getEnv("PLAYGROUND_CAPTCHA_VERIFY_URL", "https://challenges.cloudflare.com/turnstile/v0/siteverify"),
+			MaxTokens:               getEnvAsInt("PLAYGROUND_MAX_TOKENS", 256),
+			RequestsPerMinute:       getEnvAsInt("PLAYGROUND_REQUESTS_PER_MINUTE", 5),
+			RequestsPerDay:          getEnvAsInt("PLAYGROUND_REQUESTS_PER_DAY", 50),
+			GlobalRequestsPerMinute: getEnvAsInt("PLAYGROUND_GLOBAL_REQUESTS_PER_MINUTE", 300),
 		},
 		SkyPilot: SkyPilotConfig{
 			APIServerURL:            getEnv("SKYPILOT_API_SERVER_URL", ""),
@@ -185,6 +554,19 @@ func LoadConfig() (*Config, error) {
 			MaxRetries:              getEnvAsInt("SKYPILOT_MAX_RETRIES", 3),
 			RetryBackoff:            getEnvAsDuration("SKYPILOT_RETRY_BACKOFF", "5s"),
 			CredentialEncryptionKey: getEnv("SKYPILOT_CREDENTIAL_ENCRYPTION_KEY", ""),
+			TenantWorkspaces:        getEnvAsBool("SKYPILOT_TENANT_WORKSPACES", false),
+			WorkspacePrefix:         getEnv("SKYPILOT_WORKSPACE_PREFIX", "tenant-"),
+			DefaultWorkspace:        getEnv("SKYPILOT_DEFAULT_WORKSPACE", "default"),
+			CredentialProfiles:      getEnvAsBool("SKYPILOT_CREDENTIAL_PROFILES", false),
+			CatalogURL:              getEnv("SKYPILOT_CATALOG_URL", "https://raw.githubusercontent.com/skypilot-org/skypilot-catalog/master/catalogs/v6"),
+			CatalogSyncEnabled:      getEnvAsBool("SKYPILOT_CATALOG_SYNC_ENABLED", false),
+			CatalogSyncInterval:     getEnvAsDuration("SKYPILOT_CATALOG_SYNC_INTERVAL", "24h"),
+			TemplateDir:             getEnv("SKYPILOT_TEMPLATE_DIR", ""),
+			ExecTimeout:             getEnvAsDuration("SKYPILOT_EXEC_TIMEOUT", "5m"),
+			ExecMaxTimeout:          getEnvAsDuration("SKYPILOT_EXEC_MAX_TIMEOUT", "30m"),
+			ExecAllowedCommands:     getEnvAsList("SKYPILOT_EXEC_ALLOWED_COMMANDS", "nvidia-smi,df,free,uptime,ps,ls,cat,tail,head,journalctl,systemctl status,curl -sf http://localhost:8000/"),
+			LaunchConcurrency:       getEnvAsInt("SKYPILOT_LAUNCH_CONCURRENCY", 4),
+			LaunchConcurrencyLimits: getEnvAsList("SKYPILOT_LAUNCH_CONCURRENCY_LIMITS", ""),
 		},
 	}
 
@@ -201,6 +583,43 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("ADMIN_API_TOKEN is required")
 	}
 
+	if sunset := getEnv("API_V1_SUNSET", ""); sunset != "" {
+		t, err := time.Parse("2006-01-02", sunset)
+		if err != nil {
+			return nil, fmt.Errorf("API_V1_SUNSET must be a date (YYYY-MM-DD): %w", err)
+		}
+		cfg.API.V1Sunset = t
+	}
+
+	if cfg.NodeAPI.Port != 0 {
+		if len(cfg.NodeAPI.Token) < 32 {
+			return nil, fmt.Errorf("NODE_API_TOKEN of at least 32 characters is required when NODE_API_PORT is set")
+		}
+		if cfg.NodeAPI.Port == cfg.Server.Port {
+			return nil, fmt.Errorf("NODE_API_PORT must differ from SERVER_PORT")
+		}
+	}
+	if os.Getenv("NODE_DISCOVERY_SECRET") != "" && len(cfg.NodeAPI.DiscoverySecret) < 32 {
+		return nil, fmt.Errorf("NODE_DISCOVERY_SECRET must be at least 32 characters")
+	}
+	if cfg.NodeAPI.DiscoveryTokenTTL < time.Minute {
+		return nil, fmt.Errorf("NODE_DISCOVERY_TOKEN_TTL must be at least 1m")
+	}
+
+	switch cfg.Redis.Mode {
+	case "standalone":
+	case "sentinel":
+		if cfg.Redis.MasterName == "" || len(cfg.Redis.Addrs) == 0 {
+			return nil, fmt.Errorf("REDIS_SENTINEL_MASTER and REDIS_ADDRS are required when REDIS_MODE is sentinel")
+		}
+	case "cluster":
+		if len(cfg.Redis.Addrs) == 0 {
+			return nil, fmt.Errorf("REDIS_ADDRS is required when REDIS_MODE is cluster")
+		}
+	default:
+		return nil, fmt.Errorf("REDIS_MODE must be standalone, sentinel or cluster")
+	}
+
 	// Validate SkyPilot API Server configuration when enabled
 	if cfg.SkyPilot.UseAPIServer {
 		if cfg.SkyPilot.APIServerURL == "" {
@@ -225,6 +644,14 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvOrEmpty is getEnv, except that a variable set to empty stays empty
+func getEnvOrEmpty(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return strings.TrimSpace(value)
+	}
+	return defaultValue
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -237,6 +664,18 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -249,6 +688,39 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	return value
 }
 
+// getEnvAsList parses a comma-separated list, dropping empty items
+func getEnvAsList(key string, defaultValue string) []string {
+	valueStr, ok := os.LookupEnv(key)
+	if !ok {
+		valueStr = defaultValue
+	}
+	var values []string
+	for _, item := range strings.Split(valueStr, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// getEnvAsIntMap parses comma-separated key=value items with integer values
+// (e.g. "free=20,pro=200"), dropping malformed items
+func getEnvAsIntMap(key string) map[string]int {
+	values := make(map[string]int)
+	for _, item := range getEnvAsList(key, "") {
+		name, valueStr, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(valueStr))
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+	return values
+}
+
 func getEnvAsDuration(key string, defaultValue string) time.Duration {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -158,6 +158,9 @@ func ValidateCredentialsStructure(provider string, credentials interface{}) erro
 		if aws.AccessKeyID == "" || aws.SecretAccessKey == "" {
 			return fmt.Errorf("AWS credentials must include access_key_id and secret_access_key")
 		}
+		if err := aws.validateSovereign(); err != nil {
+			return fmt.Errorf("invalid AWS credentials: %w", err)
+		}
 
 	case "azure":
 		var azure AzureCredentials
@@ -167,6 +170,9 @@ func ValidateCredentialsStructure(provider string, credentials interface{}) erro
 		if azure.ClientID == "" || azure.ClientSecret == "" || azure.TenantID == "" || azure.SubscriptionID == "" {
 			return fmt.Errorf("Azure credentials must include client_id, client_secret, tenant_id, and subscription_id")
 		}
+		if err := azure.validateSovereign(); err != nil {
+			return fmt.Errorf("invalid Azure credentials: %w", err)
+		}
 
 	case "gcp":
 		var gcp GCPCredentials
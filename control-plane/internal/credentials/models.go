@@ -68,6 +68,11 @@ type AWSCredentials struct {
 	Region          string  `json:"region,omitempty"`
 	RoleArn         *string `json:"role_arn,omitempty"`
 	SessionToken    *string `json:"session_token,omitempty"`
+	// Partition is aws, aws-us-gov, aws-cn, aws-iso or aws-iso-b; defaults to
+	// the region's partition
+	Partition   string  `json:"partition,omitempty"`
+	EndpointURL *string `json:"endpoint_url,omitempty"`
+	STSEndpoint *string `json:"sts_endpoint,omitempty"`
 }
 
 // AzureCredentials contains Azure-specific credentials
@@ -76,6 +81,11 @@ type AzureCredentials struct {
 	ClientSecret   string `json:"client_secret"`
 	TenantID       string `json:"tenant_id"`
 	SubscriptionID string `json:"subscription_id"`
+	// Cloud is AzurePublicCloud (default), AzureUSGovernment, AzureChinaCloud
+	// or custom
+	Cloud                   string  `json:"cloud,omitempty"`
+	AuthorityHost           *string `json:"authority_host,omitempty"`
+	ResourceManagerEndpoint *string `json:"resource_manager_endpoint,omitempty"`
 }
 
 // GCPCredentials contains GCP-specific credentials
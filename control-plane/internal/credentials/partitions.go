@@ -0,0 +1,191 @@
+package credentials
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Sovereign cloud support.
+//
+// AWS partitions (GovCloud, China, the isolated regions) and Azure national
+// clouds have their own API and login endpoints, and credentials from one
+// partition don't work in another. AWS credentials name their partition,
+// defaulting to the one their region belongs to, and Azure credentials name
+// their cloud; either can override the endpoints for private or air-gapped
+// deployments.
+
+// AWS partitions
+const (
+	AWSPartitionStandard = "aws"
+	AWSPartitionGovCloud = "aws-us-gov"
+	AWSPartitionChina    = "aws-cn"
+	AWSPartitionISO      = "aws-iso"
+	AWSPartitionISOB     = "aws-iso-b"
+)
+
+// awsPartitionRegionPrefixes maps region name prefixes to their partition,
+// longest prefix first
+var awsPartitionRegionPrefixes = []struct {
+	prefix    string
+	partition string
+}{
+	{"us-isob-", AWSPartitionISOB},
+	{"us-iso-", AWSPartitionISO},
+	{"us-gov-", AWSPartitionGovCloud},
+	{"cn-", AWSPartitionChina},
+}
+
+// awsPartitionsWithoutPublicEndpoints must be given an endpoint_url
+var awsPartitionsWithoutPublicEndpoints = map[string]bool{
+	AWSPartitionISO:  true,
+	AWSPartitionISOB: true,
+}
+
+// AWSPartitionForRegion returns the partition a region belongs to
+func AWSPartitionForRegion(region string) string {
+	for _, p := range awsPartitionRegionPrefixes {
+		if strings.HasPrefix(region, p.prefix) {
+			return p.partition
+		}
+	}
+	return AWSPartitionStandard
+}
+
+// IsValidAWSPartition checks if the partition is supported
+func IsValidAWSPartition(partition string) bool {
+	if partition == AWSPartitionStandard {
+		return true
+	}
+	for _, p := range awsPartitionRegionPrefixes {
+		if p.partition == partition {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveAWSPartition returns the explicit partition, or the one the region
+// belongs to
+func ResolveAWSPartition(partition, region string) string {
+	if partition != "" {
+		return partition
+	}
+	return AWSPartitionForRegion(region)
+}
+
+// CheckAWSRegion returns an error when region is outside partition
+func CheckAWSRegion(partition, region string) error {
+	if region == "" {
+		return nil
+	}
+	if regionPartition := AWSPartitionForRegion(region); regionPartition != partition {
+		return fmt.Errorf("region %s is in AWS partition %s, but the credentials are for %s", region, regionPartition, partition)
+	}
+	return nil
+}
+
+// validateSovereign checks the partition and endpoint overrides
+func (c AWSCredentials) validateSovereign() error {
+	if c.Partition != "" && !IsValidAWSPartition(c.Partition) {
+		return fmt.Errorf("unsupported AWS partition %q", c.Partition)
+	}
+	partition := ResolveAWSPartition(c.Partition, c.Region)
+	if err := CheckAWSRegion(partition, c.Region); err != nil {
+		return err
+	}
+	if awsPartitionsWithoutPublicEndpoints[partition] && c.EndpointURL == nil {
+		return fmt.Errorf("AWS partition %s has no public endpoints; endpoint_url is required", partition)
+	}
+	if err := validateEndpointURL("endpoint_url", c.EndpointURL); err != nil {
+		return err
+	}
+	return validateEndpointURL("sts_endpoint", c.STSEndpoint)
+}
+
+// AzureCloud is an Azure cloud environment and its endpoints
+type AzureCloud struct {
+	Name                    string
+	AuthorityHost           string
+	ResourceManagerEndpoint string
+}
+
+// Azure clouds
+const (
+	AzureCloudPublic       = "AzurePublicCloud"
+	AzureCloudUSGovernment = "AzureUSGovernment"
+	AzureCloudChina        = "AzureChinaCloud"
+	// AzureCloudCustom is an Azure Stack or private cloud; its endpoints must
+	// be given explicitly
+	AzureCloudCustom = "custom"
+)
+
+var azureClouds = map[string]AzureCloud{
+	AzureCloudPublic: {
+		Name:                    AzureCloudPublic,
+		AuthorityHost:           "https://login.microsoftonline.com",
+		ResourceManagerEndpoint: "https://management.azure.com",
+	},
+	AzureCloudUSGovernment: {
+		Name:                    AzureCloudUSGovernment,
+		AuthorityHost:           "https://login.microsoftonline.us",
+		ResourceManagerEndpoint: "https://management.usgovcloudapi.net",
+	},
+	AzureCloudChina: {
+		Name:                    AzureCloudChina,
+		AuthorityHost:           "https://login.chinacloudapi.cn",
+		ResourceManagerEndpoint: "https://management.chinacloudapi.cn",
+	},
+}
+
+// ResolveAzureCloud returns the cloud and endpoints Azure credentials use:
+// the named cloud (public by default) with any endpoint overrides applied
+func ResolveAzureCloud(cloud string, authorityHost, resourceManagerEndpoint *string) (AzureCloud, error) {
+	if cloud == "" {
+		cloud = AzureCloudPublic
+	}
+
+	resolved, ok := azureClouds[cloud]
+	switch {
+	case ok:
+	case cloud == AzureCloudCustom:
+		if authorityHost == nil || resourceManagerEndpoint == nil {
+			return AzureCloud{}, fmt.Errorf("custom Azure clouds require authority_host and resource_manager_endpoint")
+		}
+		resolved = AzureCloud{Name: AzureCloudCustom}
+	default:
+		return AzureCloud{}, fmt.Errorf("unsupported Azure cloud %q", cloud)
+	}
+
+	if authorityHost != nil {
+		resolved.AuthorityHost = *authorityHost
+	}
+	if resourceManagerEndpoint != nil {
+		resolved.ResourceManagerEndpoint = *resourceManagerEndpoint
+	}
+	return resolved, nil
+}
+
+// validateSovereign checks the cloud and endpoint overrides
+func (c AzureCredentials) validateSovereign() error {
+	if _, err := ResolveAzureCloud(c.Cloud, c.AuthorityHost, c.ResourceManagerEndpoint); err != nil {
+		return err
+	}
+	if err := validateEndpointURL("authority_host", c.AuthorityHost); err != nil {
+		return err
+	}
+	return validateEndpointURL("resource_manager_endpoint", c.ResourceManagerEndpoint)
+}
+
+// validateEndpointURL checks that an optional endpoint override is an
+// absolute https URL
+func validateEndpointURL(field string, endpoint *string) error {
+	if endpoint == nil {
+		return nil
+	}
+	u, err := url.Parse(*endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%s must be an https URL", field)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSPartitionForRegion(t *testing.T) {
+	assert.Equal(t, AWSPartitionStandard, AWSPartitionForRegion("us-east-1"))
+	assert.Equal(t, AWSPartitionGovCloud, AWSPartitionForRegion("us-gov-west-1"))
+	assert.Equal(t, AWSPartitionChina, AWSPartitionForRegion("cn-north-1"))
+	assert.Equal(t, AWSPartitionISO, AWSPartitionForRegion("us-iso-east-1"))
+	assert.Equal(t, AWSPartitionISOB, AWSPartitionForRegion("us-isob-east-1"))
+
+	assert.Equal(t, AWSPartitionGovCloud, ResolveAWSPartition("", "us-gov-east-1"))
+	assert.Equal(t, AWSPartitionChina, ResolveAWSPartition(AWSPartitionChina, ""))
+
+	assert.NoError(t, CheckAWSRegion(AWSPartitionGovCloud, "us-gov-west-1"))
+	assert.NoError(t, CheckAWSRegion(AWSPartitionGovCloud, ""))
+	assert.Error(t, CheckAWSRegion(AWSPartitionGovCloud, "us-east-1"))
+}
+
+func TestValidateSovereignCredentials(t *testing.T) {
+	base := AWSCredentials{AccessKeyID: "AKIA", SecretAccessKey: "secret"}
+	endpoint := "https://ec2.us-iso-east-1.c2s.ic.gov"
+	insecure := "http://sts.internal"
+
+	t.Run("GovCloud region infers partition", func(t *testing.T) {
+		creds := base
+		creds.Region = "us-gov-west-1"
+		assert.NoError(t, ValidateCredentialsStructure("aws", creds))
+	})
+
+	t.Run("partition must match region", func(t *testing.T) {
+		creds := base
+		creds.Region, creds.Partition = "us-east-1", AWSPartitionGovCloud
+		assert.Error(t, ValidateCredentialsStructure("aws", creds))
+	})
+
+	t.Run("unknown partition", func(t *testing.T) {
+		creds := base
+		creds.Partition = "aws-moon"
+		assert.Error(t, ValidateCredentialsStructure("aws", creds))
+	})
+
+	t.Run("isolated partitions need an endpoint", func(t *testing.T) {
+		creds := base
+		creds.Region = "us-iso-east-1"
+		assert.Error(t, ValidateCredentialsStructure("aws", creds))
+
+		creds.EndpointURL = &endpoint
+		assert.NoError(t, ValidateCredentialsStructure("aws", creds))
+	})
+
+	t.Run("endpoints must be https", func(t *testing.T) {
+		creds := base
+		creds.STSEndpoint = &insecure
+		assert.Error(t, ValidateCredentialsStructure("aws", creds))
+	})
+}
+
+func TestResolveAzureCloud(t *testing.T) {
+	cloud, err := ResolveAzureCloud("", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, AzureCloudPublic, cloud.Name)
+	assert.Equal(t, "https://management.azure.com", cloud.ResourceManagerEndpoint)
+
+	cloud, err = ResolveAzureCloud(AzureCloudUSGovernment, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://login.microsoftonline.us", cloud.AuthorityHost)
+
+	authority := "https://login.example.mil"
+	cloud, err = ResolveAzureCloud(AzureCloudUSGovernment, &authority, nil)
+	require.NoError(t, err)
+	assert.Equal(t, authority, cloud.AuthorityHost)
+	assert.Equal(t, "https://management.usgovcloudapi.net", cloud.ResourceManagerEndpoint)
+
+	_, err = ResolveAzureCloud(AzureCloudCustom, &authority, nil)
+	assert.Error(t, err)
+
+	_, err = ResolveAzureCloud("AzureGermanCloud", nil, nil)
+	assert.Error(t, err)
+}
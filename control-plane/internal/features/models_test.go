@@ -0,0 +1,85 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFlagEnabledFor(t *testing.T) {
+	tenant := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+
+	tests := []struct {
+		name string
+		flag *Flag
+		want bool
+	}{
+		{"nil flag", nil, false},
+		{"disabled", &Flag{Key: "x", RolloutPercent: 100}, false},
+		{"disabled ignores override", &Flag{Key: "x", Overrides: map[uuid.UUID]bool{tenant: true}}, false},
+		{"full rollout", &Flag{Key: "x", Enabled: true, RolloutPercent: 100}, true},
+		{"zero rollout", &Flag{Key: "x", Enabled: true}, false},
+		{"override on", &Flag{Key: "x", Enabled: true, Overrides: map[uuid.UUID]bool{tenant: true}}, true},
+		{"override off", &Flag{Key: "x", Enabled: true, RolloutPercent: 100, Overrides: map[uuid.UUID]bool{tenant: false}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flag.EnabledFor(tenant); got != tt.want {
+				t.Errorf("EnabledFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolloutBucket(t *testing.T) {
+	const tenants = 10000
+	ids := make([]uuid.UUID, tenants)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+
+	for _, percent := range []int{10, 50} {
+		flag := &Flag{Key: "batch_api", Enabled: true, RolloutPercent: percent}
+		on := 0
+		for _, id := range ids {
+			if flag.EnabledFor(id) {
+				on++
+			}
+		}
+		// Allow a few points of sampling error
+		if got := on * 100 / tenants; got < percent-3 || got > percent+3 {
+			t.Errorf("rollout %d%% enabled %d%% of tenants", percent, got)
+		}
+	}
+
+	// Raising a rollout only adds tenants
+	low := &Flag{Key: "batch_api", Enabled: true, RolloutPercent: 10}
+	high := &Flag{Key: "batch_api", Enabled: true, RolloutPercent: 50}
+	for _, id := range ids {
+		if low.EnabledFor(id) && !high.EnabledFor(id) {
+			t.Fatalf("tenant %s dropped out when rollout increased", id)
+		}
+	}
+}
+
+func TestFlagInputValidate(t *testing.T) {
+	tests := []struct {
+		input   FlagInput
+		wantErr bool
+	}{
+		{FlagInput{Key: "batch_api", RolloutPercent: 25}, false},
+		{FlagInput{Key: "v2.responses-api"}, false},
+		{FlagInput{Key: ""}, true},
+		{FlagInput{Key: "Batch"}, true},
+		{FlagInput{Key: "batch api"}, true},
+		{FlagInput{Key: "batch", RolloutPercent: 101}, true},
+		{FlagInput{Key: "batch", RolloutPercent: -1}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.input.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// flagCacheTTL bounds how stale a flag can be on another replica after a change
+const flagCacheTTL = 30 * time.Second
+
+// Service stores feature flags in Postgres and caches them in Redis
+type Service struct {
+	db     *database.Database
+	cache  *cache.Cache
+	logger *zap.Logger
+}
+
+// NewService creates a new feature flag service
+func NewService(db *database.Database, cache *cache.Cache, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		cache:  cache,
+		logger: logger,
+	}
+}
+
+// cachedFlag wraps a flag so unknown flags are cached too
+type cachedFlag struct {
+	Flag *Flag `json:"flag"`
+}
+
+func flagCacheKey(key string) string {
+	return fmt.Sprintf("feature_flag:%s", key)
+}
+
+// IsEnabled reports whether a flag is on for a tenant. Unknown flags and
+// lookup failures are treated as off so unfinished features stay hidden.
+func (s *Service) IsEnabled(ctx context.Context, key string, tenantID uuid.UUID) bool {
+	flag, err := s.lookup(ctx, key)
+	if err != nil {
+		s.logger.Warn("failed to evaluate feature flag",
+			zap.String("flag", key),
+			zap.Error(err),
+		)
+		return false
+	}
+	return flag.EnabledFor(tenantID)
+}
+
+// lookup returns a flag from Redis, falling back to Postgres. It returns a
+// nil flag for unknown keys.
+func (s *Service) lookup(ctx context.Context, key string) (*Flag, error) {
+	if cached, err := s.cache.Get(ctx, flagCacheKey(key)); err == nil {
+		var entry cachedFlag
+		if err := json.Unmarshal([]byte(cached), &entry); err == nil {
+			return entry.Flag, nil
+		}
+	}
+
+	flag, err := s.GetFlag(ctx, key)
+	if err != nil && !errors.Is(err, ErrFlagNotFound) {
+		return nil, err
+	}
+
+	entry, _ := json.Marshal(cachedFlag{Flag: flag})
+	if err := s.cache.Set(ctx, flagCacheKey(key), string(entry), flagCacheTTL); err != nil {
+		s.logger.Debug("failed to cache feature flag", zap.String("flag", key), zap.Error(err))
+	}
+	return flag, nil
+}
+
+// Invalidate drops a cached flag changed outside the service, for example by
+// a configuration restore
+func (s *Service) Invalidate(ctx context.Context, key string) {
+	s.invalidate(ctx, key)
+}
+
+// invalidate drops a cached flag after it changes
+func (s *Service) invalidate(ctx context.Context, key string) {
+	if err := s.cache.Delete(ctx, flagCacheKey(key)); err != nil {
+		s.logger.Warn("failed to invalidate feature flag cache",
+			zap.String("flag", key),
+			zap.Error(err),
+		)
+	}
+}
+
+// ListFlags returns all flags with their overrides
+func (s *Service) ListFlags(ctx context.Context) ([]*Flag, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT key, description, enabled, rollout_percent, created_at, updated_at
+		FROM feature_flags
+		ORDER BY key
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	var flags []*Flag
+	byKey := make(map[string]*Flag)
+	for rows.Next() {
+		flag := &Flag{Overrides: make(map[uuid.UUID]bool)}
+		if err := rows.Scan(&flag.Key, &flag.Description, &flag.Enabled, &flag.RolloutPercent, &flag.CreatedAt, &flag.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan feature flag: %w", err)
+		}
+		flags = append(flags, flag)
+		byKey[flag.Key] = flag
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	rows, err = s.db.Pool.Query(ctx, `SELECT flag_key, tenant_id, enabled FROM feature_flag_overrides`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flag overrides: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var tenantID uuid.UUID
+		var enabled bool
+		if err := rows.Scan(&key, &tenantID, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag override: %w", err)
+		}
+		if flag, ok := byKey[key]; ok {
+			flag.Overrides[tenantID] = enabled
+		}
+	}
+	return flags, rows.Err()
+}
+
+// GetFlag returns a flag with its overrides
+func (s *Service) GetFlag(ctx context.Context, key string) (*Flag, error) {
+	flag := &Flag{Overrides: make(map[uuid.UUID]bool)}
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT key, description, enabled, rollout_percent, created_at, updated_at
+		FROM feature_flags
+		WHERE key = $1
+	`, key).Scan(&flag.Key, &flag.Description, &flag.Enabled, &flag.RolloutPercent, &flag.CreatedAt, &flag.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrFlagNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feature flag: %w", err)
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT tenant_id, enabled FROM feature_flag_overrides WHERE flag_key = $1
+	`, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feature flag overrides: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tenantID uuid.UUID
+		var enabled bool
+		if err := rows.Scan(&tenantID, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag override: %w", err)
+		}
+		flag.Overrides[tenantID] = enabled
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get feature flag overrides: %w", err)
+	}
+
+	return flag, nil
+}
+
+// CreateFlag creates a new flag
+func (s *Service) CreateFlag(ctx context.Context, input FlagInput) (*Flag, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.Pool.Exec(ctx, `
+		INSERT INTO feature_flags (key, description, enabled, rollout_percent)
+		VALUES ($1, $2, $3, $4)
+	`, input.Key, input.Description, input.Enabled, input.RolloutPercent)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, ErrFlagExists
+		}
+		return nil, fmt.Errorf("failed to create feature flag: %w", err)
+	}
+
+	s.invalidate(ctx, input.Key)
+	s.logger.Info("created feature flag",
+		zap.String("flag", input.Key),
+		zap.Bool("enabled", input.Enabled),
+		zap.Int("rollout_percent", input.RolloutPercent),
+	)
+	return s.GetFlag(ctx, input.Key)
+}
+
+// UpdateFlag replaces a flag's description, kill switch and rollout
+func (s *Service) UpdateFlag(ctx context.Context, input FlagInput) (*Flag, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.db.Pool.Exec(ctx, `
+		UPDATE feature_flags
+		SET description = $2, enabled = $3, rollout_percent = $4, updated_at = NOW()
+		WHERE key = $1
+	`, input.Key, input.Description, input.Enabled, input.RolloutPercent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update feature flag: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrFlagNotFound
+	}
+
+	s.invalidate(ctx, input.Key)
+	s.logger.Info("updated feature flag",
+		zap.String("flag", input.Key),
+		zap.Bool("enabled", input.Enabled),
+		zap.Int("rollout_percent", input.RolloutPercent),
+	)
+	return s.GetFlag(ctx, input.Key)
+}
+
+// DeleteFlag deletes a flag and its overrides
+func (s *Service) DeleteFlag(ctx context.Context, key string) error {
+	tag, err := s.db.Pool.Exec(ctx, `DELETE FROM feature_flags WHERE key = $1`, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFlagNotFound
+	}
+
+	s.invalidate(ctx, key)
+	s.logger.Info("deleted feature flag", zap.String("flag", key))
+	return nil
+}
+
+// SetOverride forces a flag on or off for one tenant
+func (s *Service) SetOverride(ctx context.Context, key string, tenantID uuid.UUID, enabled bool) error {
+	tag, err := s.db.Pool.Exec(ctx, `
+		INSERT INTO feature_flag_overrides (flag_key, tenant_id, enabled)
+		SELECT key, $2, $3 FROM feature_flags WHERE key = $1
+		ON CONFLICT (flag_key, tenant_id) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = NOW()
+	`, key, tenantID, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to set feature flag override: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFlagNotFound
+	}
+
+	s.invalidate(ctx, key)
+	s.logger.Info("set feature flag override",
+		zap.String("flag", key),
+		zap.String("tenant_id", tenantID.String()),
+		zap.Bool("enabled", enabled),
+	)
+	return nil
+}
+
+// DeleteOverride removes a tenant override so the rollout applies again
+func (s *Service) DeleteOverride(ctx context.Context, key string, tenantID uuid.UUID) error {
+	_, err := s.db.Pool.Exec(ctx, `
+		DELETE FROM feature_flag_overrides WHERE flag_key = $1 AND tenant_id = $2
+	`, key, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag override: %w", err)
+	}
+
+	s.invalidate(ctx, key)
+	return nil
+}
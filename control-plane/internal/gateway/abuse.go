@@ -0,0 +1,646 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// API key abuse detection.
+//
+// Leaked keys usually show up as one of three patterns before the bill does:
+// traffic from many networks at once, a sudden jump in request rate, or a
+// flood of near-identical prompts (scraping or enumeration). The gateway
+// tracks these signals per key in Redis, off the request path. A rate spike
+// or scraping throttles the key for abuseThrottleDuration; traffic from many
+// networks, or any signal on a key that is already throttled, quarantines it
+// until an admin reviews the incident. Every action is recorded in
+// api_key_abuse_incidents and published as a tenant event.
+const (
+	// abuseWindow is the window network spread and prompt diversity are measured over
+	abuseWindow = 10 * time.Minute
+	// abuseNetworkThreshold is the number of distinct client networks in a
+	// window that quarantines a key
+	abuseNetworkThreshold = 20
+	// abuseRPMFloor is the minimum requests per minute considered a spike,
+	// so new and quiet keys are not flagged for ordinary bursts
+	abuseRPMFloor = 120
+	// abuseRPMSpikeFactor is how far above its baseline a key's rate must jump
+	abuseRPMSpikeFactor = 10
+	// abuseBaselineWeight is the EWMA weight of the latest active minute
+	abuseBaselineWeight = 0.1
+	// abuseScrapeMinPrompts is the prompt volume below which scraping is not evaluated
+	abuseScrapeMinPrompts = 500
+	// abuseScrapeMaxDiversity is the share of distinct prompt shapes at or
+	// below which traffic looks like scraping
+	abuseScrapeMaxDiversity = 0.02
+	// abuseThrottleDuration is how long a throttled key runs at reduced limits
+	abuseThrottleDuration = 30 * time.Minute
+	// abuseThrottleFactor scales a throttled key's rate and concurrency limits
+	abuseThrottleFactor = 0.1
+)
+
+// abuseSignal identifies the detector that flagged a key
+type abuseSignal string
+
+const (
+	abuseSignalNetworkSpread abuseSignal = "network_spread"
+	abuseSignalRateSpike     abuseSignal = "rate_spike"
+	abuseSignalScraping      abuseSignal = "scraping"
+)
+
+const (
+	abuseActionThrottle   = "throttle"
+	abuseActionQuarantine = "quarantine"
+)
+
+// ErrAPIKeyQuarantined is returned for keys quarantined by abuse detection
+var ErrAPIKeyQuarantined = errors.New("API key is quarantined")
+
+// abuseAction decides how to contain a flagged key. Network spread is the
+// strongest leak signal; anything else escalates only if the key is already
+// throttled.
+func abuseAction(signal abuseSignal, throttled bool) string {
+	if signal == abuseSignalNetworkSpread || throttled {
+		return abuseActionQuarantine
+	}
+	return abuseActionThrottle
+}
+
+// clientNetwork maps a client address to its /16 (IPv4) or /48 (IPv6)
+// network, a cheap stand-in for geography that ignores churn within one
+// provider's address block
+func clientNetwork(remoteAddr string) string {
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(16, 32)).String() + "/16"
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
+
+// rateSpikeThreshold returns the per-minute request count that counts as a
+// spike for a key with the given baseline
+func rateSpikeThreshold(baseline float64) int64 {
+	threshold := int64(math.Ceil(baseline * abuseRPMSpikeFactor))
+	if threshold < abuseRPMFloor {
+		return abuseRPMFloor
+	}
+	return threshold
+}
+
+// updateBaseline folds the last active minute into a key's request-rate baseline
+func updateBaseline(baseline float64, lastMinute int64) float64 {
+	return baseline*(1-abuseBaselineWeight) + float64(lastMinute)*abuseBaselineWeight
+}
+
+// looksLikeScraping reports whether a window's prompts are dominated by a
+// handful of templates
+func looksLikeScraping(prompts, shapes int64) bool {
+	if prompts < abuseScrapeMinPrompts {
+		return false
+	}
+	return float64(shapes)/float64(prompts) <= abuseScrapeMaxDiversity
+}
+
+// promptShape hashes the last user prompt of a chat or completion request
+// after folding case, whitespace and digits, so prompts that differ only by
+// an ID or page number share a shape. It returns "" when there is no prompt.
+func promptShape(body []byte) string {
+	var req struct {
+		Prompt   json.RawMessage `json:"prompt"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+
+	var text string
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			text = contentText(req.Messages[i].Content)
+			break
+		}
+	}
+	if text == "" && len(req.Prompt) > 0 {
+		text = contentText(req.Prompt)
+	}
+	if text == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	lastDigit, lastSpace := false, false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsDigit(r):
+			if !lastDigit {
+				b.WriteRune('0')
+			}
+			lastDigit, lastSpace = true, false
+		case unicode.IsSpace(r):
+			if !lastSpace {
+				b.WriteRune(' ')
+			}
+			lastDigit, lastSpace = false, true
+		default:
+			b.WriteRune(r)
+			lastDigit, lastSpace = false, false
+		}
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(strings.TrimSpace(b.String())))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// contentText extracts the text of a string or content-part array
+func contentText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// throttledAPIKey returns a copy of the key with its limits cut to
+// abuseThrottleFactor
+func throttledAPIKey(key *models.APIKey) *models.APIKey {
+	scale := func(limit int) int {
+		scaled := int(float64(limit) * abuseThrottleFactor)
+		if scaled < 1 {
+			return 1
+		}
+		return scaled
+	}
+
+	throttled := *key
+	throttled.RateLimitRequestsPerMin = scale(key.RateLimitRequestsPerMin)
+	throttled.ConcurrencyLimit = scale(key.ConcurrencyLimit)
+	if key.RateLimitTokensPerMin != nil {
+		tokens := scale(*key.RateLimitTokensPerMin)
+		throttled.RateLimitTokensPerMin = &tokens
+	}
+	return &throttled
+}
+
+func abuseThrottleKey(keyID uuid.UUID) string {
+	return fmt.Sprintf("abuse:throttle:%s", keyID)
+}
+
+// abuseMiddleware records key usage for abuse detection and applies reduced
+// limits to throttled keys. It must run after authMiddleware and before
+// rateLimitMiddleware.
+func (g *Gateway) abuseMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		keyInfo, ok := ctx.Value("api_key").(*models.APIKey)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		go g.observeKeyUsage(*keyInfo, r.RemoteAddr)
+
+		if g.isKeyThrottled(ctx, keyInfo.ID) {
+			ctx = context.WithValue(ctx, "api_key", throttledAPIKey(keyInfo))
+			r = r.WithContext(ctx)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isKeyThrottled reports whether abuse detection has throttled a key. Cache
+// errors fail open.
+func (g *Gateway) isKeyThrottled(ctx context.Context, keyID uuid.UUID) bool {
+	n, err := g.cache.Exists(ctx, abuseThrottleKey(keyID))
+	return err == nil && n > 0
+}
+
+// incrWithExpiry increments a counter, starting its TTL on first use
+func (g *Gateway) incrWithExpiry(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	count, err := g.cache.Incr(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		g.cache.Expire(ctx, key, ttl)
+	}
+	return count, nil
+}
+
+// observeKeyUsage tracks client network spread and request rate for a key.
+// It runs off the request path.
+func (g *Gateway) observeKeyUsage(key models.APIKey, remoteAddr string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	window := now.Truncate(abuseWindow).Unix()
+
+	if network := clientNetwork(remoteAddr); network != "" {
+		first, err := g.cache.SetNX(ctx, fmt.Sprintf("abuse:net:%s:%d:%s", key.ID, window, network), 1, abuseWindow)
+		if err == nil && first {
+			networks, err := g.incrWithExpiry(ctx, fmt.Sprintf("abuse:nets:%s:%d", key.ID, window), abuseWindow)
+			if err == nil && networks == abuseNetworkThreshold {
+				g.flagAPIKey(ctx, &key, abuseSignalNetworkSpread, map[string]interface{}{
+					"networks": networks,
+					"window":   abuseWindow.String(),
+				})
+			}
+		}
+	}
+
+	minute := now.Truncate(time.Minute).Unix()
+	count, err := g.incrWithExpiry(ctx, fmt.Sprintf("abuse:rpm:%s:%d", key.ID, minute), 2*time.Minute)
+	if err != nil {
+		return
+	}
+
+	baselineKey := fmt.Sprintf("abuse:baseline:%s", key.ID)
+	baseline := 0.0
+	if raw, err := g.cache.Get(ctx, baselineKey); err == nil {
+		baseline, _ = strconv.ParseFloat(raw, 64)
+	}
+
+	if count == 1 {
+		// First request of a new minute: fold the previous minute into the baseline
+		previous, _, _ := g.cache.GetInt64(ctx, fmt.Sprintf("abuse:rpm:%s:%d", key.ID, minute-60))
+		updated := updateBaseline(baseline, previous)
+		g.cache.Set(ctx, baselineKey, strconv.FormatFloat(updated, 'f', 2, 64), 24*time.Hour)
+		return
+	}
+
+	if count == rateSpikeThreshold(baseline) {
+		g.flagAPIKey(ctx, &key, abuseSignalRateSpike, map[string]interface{}{
+			"requests_per_min": count,
+			"baseline_per_min": math.Round(baseline*100) / 100,
+		})
+	}
+}
+
+// observePrompt tracks prompt diversity for the authenticated key so
+// scraping-like traffic can be detected
+func (g *Gateway) observePrompt(ctx context.Context, body []byte) {
+	keyInfo, ok := ctx.Value("api_key").(*models.APIKey)
+	if !ok {
+		return
+	}
+	shape := promptShape(body)
+	if shape == "" {
+		return
+	}
+	go g.recordPromptShape(*keyInfo, shape)
+}
+
+// recordPromptShape counts prompts and distinct prompt shapes per window
+func (g *Gateway) recordPromptShape(key models.APIKey, shape string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	window := time.Now().Truncate(abuseWindow).Unix()
+	prompts, err := g.incrWithExpiry(ctx, fmt.Sprintf("abuse:prompts:%s:%d", key.ID, window), abuseWindow)
+	if err != nil {
+		return
+	}
+
+	shapesKey := fmt.Sprintf("abuse:shapes:%s:%d", key.ID, window)
+	var shapes int64
+	first, err := g.cache.SetNX(ctx, fmt.Sprintf("abuse:shape:%s:%d:%s", key.ID, window, shape), 1, abuseWindow)
+	if err != nil {
+		return
+	}
+	if first {
+		shapes, err = g.incrWithExpiry(ctx, shapesKey, abuseWindow)
+	} else {
+		shapes, _, err = g.cache.GetInt64(ctx, shapesKey)
+	}
+	if err != nil {
+		return
+	}
+
+	// Evaluate every 100 prompts rather than on every request
+	if prompts%100 == 0 && looksLikeScraping(prompts, shapes) {
+		g.flagAPIKey(ctx, &key, abuseSignalScraping, map[string]interface{}{
+			"prompts":         prompts,
+			"distinct_shapes": shapes,
+			"window":          abuseWindow.String(),
+		})
+	}
+}
+
+// flagAPIKey throttles or quarantines a key, records the incident and
+// notifies the tenant. Each signal fires at most once per key per window.
+func (g *Gateway) flagAPIKey(ctx context.Context, key *models.APIKey, signal abuseSignal, details map[string]interface{}) {
+	first, err := g.cache.SetNX(ctx, fmt.Sprintf("abuse:flagged:%s:%s", key.ID, signal), 1, abuseWindow)
+	if err != nil || !first {
+		return
+	}
+
+	action := abuseAction(signal, g.isKeyThrottled(ctx, key.ID))
+	switch action {
+	case abuseActionQuarantine:
+		err = g.quarantineAPIKey(ctx, key.ID)
+	default:
+		err = g.cache.Set(ctx, abuseThrottleKey(key.ID), string(signal), abuseThrottleDuration)
+	}
+	if err != nil {
+		g.logger.Error("failed to contain flagged API key",
+			zap.Error(err),
+			zap.String("key_id", key.ID.String()),
+			zap.String("signal", string(signal)),
+		)
+		return
+	}
+
+	detailsJSON, _ := json.Marshal(details)
+	var incidentID uuid.UUID
+	err = g.db.Pool.QueryRow(ctx, `
+		INSERT INTO api_key_abuse_incidents (api_key_id, tenant_id, signal, action, details)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, key.ID, key.TenantID, string(signal), action, detailsJSON).Scan(&incidentID)
+	if err != nil {
+		g.logger.Error("failed to record abuse incident", zap.Error(err), zap.String("key_id", key.ID.String()))
+	}
+
+	apiKeyAbuseIncidents.WithLabelValues(string(signal), action).Inc()
+
+	g.logger.Warn("API key flagged by abuse detection",
+		zap.String("key_id", key.ID.String()),
+		zap.String("tenant_id", key.TenantID.String()),
+		zap.String("signal", string(signal)),
+		zap.String("action", action),
+		zap.Any("details", details),
+	)
+
+	eventType := events.EventAPIKeyThrottled
+	if action == abuseActionQuarantine {
+		eventType = events.EventAPIKeyQuarantined
+	}
+	payload := map[string]interface{}{
+		"key_id":      key.ID.String(),
+		"key_prefix":  key.KeyPrefix,
+		"signal":      string(signal),
+		"action":      action,
+		"incident_id": incidentID.String(),
+		"details":     details,
+	}
+	if key.Name != nil {
+		payload["key_name"] = *key.Name
+	}
+	if action == abuseActionThrottle {
+		payload["throttled_until"] = time.Now().Add(abuseThrottleDuration).UTC().Format(time.RFC3339)
+	}
+	if err := g.eventBus.Publish(ctx, events.NewEvent(eventType, key.TenantID.String(), payload)); err != nil {
+		g.logger.Warn("failed to publish abuse event", zap.Error(err))
+	}
+}
+
+// quarantineAPIKey blocks an active key until an admin releases it
+func (g *Gateway) quarantineAPIKey(ctx context.Context, keyID uuid.UUID) error {
+	var keyHash string
+	err := g.db.Pool.QueryRow(ctx, `
+		UPDATE api_keys SET status = 'quarantined'
+		WHERE id = $1 AND status = 'active'
+		RETURNING key_hash
+	`, keyID).Scan(&keyHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Already revoked, suspended or quarantined
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to quarantine API key: %w", err)
+	}
+
+	if err := g.cache.Delete(ctx, fmt.Sprintf("api_key:%s", keyHash), abuseThrottleKey(keyID)); err != nil {
+		g.logger.Warn("failed to invalidate quarantined API key", zap.Error(err), zap.String("key_id", keyID.String()))
+	}
+	return nil
+}
+
+// AbuseIncident is the admin view of an abuse detection incident
+type AbuseIncident struct {
+	ID         uuid.UUID       `json:"id"`
+	APIKeyID   uuid.UUID       `json:"api_key_id"`
+	KeyPrefix  string          `json:"key_prefix"`
+	KeyName    *string         `json:"key_name,omitempty"`
+	KeyStatus  string          `json:"key_status"`
+	TenantID   uuid.UUID       `json:"tenant_id"`
+	Signal     string          `json:"signal"`
+	Action     string          `json:"action"`
+	Details    json.RawMessage `json:"details"`
+	Status     string          `json:"status"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ReviewedAt *time.Time      `json:"reviewed_at,omitempty"`
+	ReviewNote *string         `json:"review_note,omitempty"`
+}
+
+// handleListAbuseIncidents lists abuse incidents, open ones by default
+// Admin API - GET /admin/abuse/incidents?status=open|released|confirmed|all
+func (g *Gateway) handleListAbuseIncidents(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "open"
+	}
+	switch status {
+	case "open", "released", "confirmed", "all":
+	default:
+		g.writeError(w, http.StatusBadRequest, "status must be one of open, released, confirmed, all")
+		return
+	}
+
+	rows, err := g.db.Pool.Query(r.Context(), `
+		SELECT i.id, i.api_key_id, k.key_prefix, k.name, k.status, i.tenant_id,
+		       i.signal, i.action, i.details, i.status, i.created_at, i.reviewed_at, i.review_note
+		FROM api_key_abuse_incidents i
+		JOIN api_keys k ON k.id = i.api_key_id
+		WHERE $1 = 'all' OR i.status = $1
+		ORDER BY i.created_at DESC
+		LIMIT 200
+	`, status)
+	if err != nil {
+		g.logger.Error("failed to list abuse incidents", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list abuse incidents")
+		return
+	}
+	defer rows.Close()
+
+	incidents := []AbuseIncident{}
+	for rows.Next() {
+		var inc AbuseIncident
+		if err := rows.Scan(&inc.ID, &inc.APIKeyID, &inc.KeyPrefix, &inc.KeyName, &inc.KeyStatus, &inc.TenantID,
+			&inc.Signal, &inc.Action, &inc.Details, &inc.Status, &inc.CreatedAt, &inc.ReviewedAt, &inc.ReviewNote,
+		); err != nil {
+			g.logger.Error("failed to scan abuse incident", zap.Error(err))
+			continue
+		}
+		incidents = append(incidents, inc)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{"data": incidents})
+}
+
+// handleReleaseAbuseIncident marks an incident as a false positive: the key is
+// reactivated, its throttle lifted and all of its open incidents closed
+// Admin API - POST /admin/abuse/incidents/{id}/release
+func (g *Gateway) handleReleaseAbuseIncident(w http.ResponseWriter, r *http.Request) {
+	g.resolveAbuseIncident(w, r, "released")
+}
+
+// handleConfirmAbuseIncident confirms abuse: the key is revoked and all of its
+// open incidents closed
+// Admin API - POST /admin/abuse/incidents/{id}/confirm
+func (g *Gateway) handleConfirmAbuseIncident(w http.ResponseWriter, r *http.Request) {
+	g.resolveAbuseIncident(w, r, "confirmed")
+}
+
+// resolveAbuseIncident applies an admin review decision to an open incident
+func (g *Gateway) resolveAbuseIncident(w http.ResponseWriter, r *http.Request, resolution string) {
+	ctx := r.Context()
+
+	incidentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid incident ID")
+		return
+	}
+
+	var req struct {
+		Note *string `json:"note"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			g.writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	var keyID, tenantID uuid.UUID
+	var status string
+	err = g.db.Pool.QueryRow(ctx, `
+		SELECT api_key_id, tenant_id, status FROM api_key_abuse_incidents WHERE id = $1
+	`, incidentID).Scan(&keyID, &tenantID, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "incident not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to load abuse incident", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load incident")
+		return
+	}
+	if status != "open" {
+		g.writeError(w, http.StatusConflict, fmt.Sprintf("incident is already %s", status))
+		return
+	}
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		g.writeError(w, http.StatusInternalServerError, "failed to resolve incident")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var keyHash string
+	if resolution == "released" {
+		err = tx.QueryRow(ctx, `
+			UPDATE api_keys SET status = CASE WHEN status = 'quarantined' THEN 'active' ELSE status END
+			WHERE id = $1
+			RETURNING key_hash
+		`, keyID).Scan(&keyHash)
+	} else {
+		err = tx.QueryRow(ctx, `
+			UPDATE api_keys SET status = 'revoked' WHERE id = $1 RETURNING key_hash
+		`, keyID).Scan(&keyHash)
+	}
+	if err != nil {
+		g.logger.Error("failed to update API key for abuse review", zap.Error(err), zap.String("key_id", keyID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to resolve incident")
+		return
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE api_key_abuse_incidents
+		SET status = $2, reviewed_at = NOW(), review_note = $3
+		WHERE api_key_id = $1 AND status = 'open'
+	`, keyID, resolution, req.Note)
+	if err != nil {
+		g.logger.Error("failed to resolve abuse incidents", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to resolve incident")
+		return
+	}
+
+	if resolution == "confirmed" {
+		evt := events.NewEvent(events.EventAPIKeyRevoked, tenantID.String(), map[string]interface{}{
+			"key_id": keyID.String(),
+			"reason": "abuse_confirmed",
+		})
+		if err := events.Enqueue(ctx, tx, evt); err != nil {
+			g.logger.Error("failed to record API key revoked event", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to resolve incident")
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		g.writeError(w, http.StatusInternalServerError, "failed to resolve incident")
+		return
+	}
+
+	if err := g.cache.Delete(ctx, fmt.Sprintf("api_key:%s", keyHash), abuseThrottleKey(keyID)); err != nil {
+		g.logger.Warn("failed to invalidate reviewed API key", zap.Error(err), zap.String("key_id", keyID.String()))
+	}
+
+	g.logger.Info("abuse incident reviewed",
+		zap.String("incident_id", incidentID.String()),
+		zap.String("key_id", keyID.String()),
+		zap.String("resolution", resolution),
+	)
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"incident_id":        incidentID,
+		"api_key_id":         keyID,
+		"status":             resolution,
+		"incidents_resolved": tag.RowsAffected(),
+	})
+}
@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/crosslogic/control-plane/pkg/models"
+)
+
+func TestAbuseAction(t *testing.T) {
+	tests := []struct {
+		signal    abuseSignal
+		throttled bool
+		want      string
+	}{
+		{abuseSignalRateSpike, false, abuseActionThrottle},
+		{abuseSignalScraping, false, abuseActionThrottle},
+		{abuseSignalNetworkSpread, false, abuseActionQuarantine},
+		{abuseSignalRateSpike, true, abuseActionQuarantine},
+		{abuseSignalScraping, true, abuseActionQuarantine},
+	}
+
+	for _, tt := range tests {
+		if got := abuseAction(tt.signal, tt.throttled); got != tt.want {
+			t.Errorf("abuseAction(%s, %v) = %s, want %s", tt.signal, tt.throttled, got, tt.want)
+		}
+	}
+}
+
+func TestClientNetwork(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.7:51234":           "203.0.0.0/16",
+		"203.0.200.9":                 "203.0.0.0/16",
+		"[2001:db8:1234:5678::1]:443": "2001:db8:1234::/48",
+		"not-an-ip":                   "",
+	}
+	for addr, want := range tests {
+		if got := clientNetwork(addr); got != want {
+			t.Errorf("clientNetwork(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
+
+func TestRateSpikeThreshold(t *testing.T) {
+	tests := []struct {
+		baseline float64
+		want     int64
+	}{
+		{0, abuseRPMFloor},
+		{5, abuseRPMFloor},
+		{50, 500},
+		{33.3, 333},
+	}
+	for _, tt := range tests {
+		if got := rateSpikeThreshold(tt.baseline); got != tt.want {
+			t.Errorf("rateSpikeThreshold(%v) = %d, want %d", tt.baseline, got, tt.want)
+		}
+	}
+
+	if got := updateBaseline(100, 200); got != 110 {
+		t.Errorf("updateBaseline(100, 200) = %v, want 110", got)
+	}
+}
+
+func TestLooksLikeScraping(t *testing.T) {
+	tests := []struct {
+		prompts, shapes int64
+		want            bool
+	}{
+		{100, 1, false}, // below minimum volume
+		{1000, 5, true},
+		{1000, 20, true},
+		{1000, 21, false},
+		{1000, 900, false},
+	}
+	for _, tt := range tests {
+		if got := looksLikeScraping(tt.prompts, tt.shapes); got != tt.want {
+			t.Errorf("looksLikeScraping(%d, %d) = %v, want %v", tt.prompts, tt.shapes, got, tt.want)
+		}
+	}
+}
+
+func TestPromptShape(t *testing.T) {
+	chat := func(prompt string) []byte {
+		return []byte(`{"model":"m","messages":[{"role":"system","content":"be terse"},{"role":"user","content":"` + prompt + `"}]}`)
+	}
+
+	a := promptShape(chat("Summarise product page 1234"))
+	b := promptShape(chat("summarise  product page 98"))
+	c := promptShape(chat("Write a haiku about autumn"))
+	if a == "" || a != b {
+		t.Errorf("prompts differing only by ID should share a shape: %q vs %q", a, b)
+	}
+	if a == c {
+		t.Error("different prompts should not share a shape")
+	}
+
+	parts := promptShape([]byte(`{"messages":[{"role":"user","content":[{"type":"text","text":"Summarise product page 7"}]}]}`))
+	if parts != a {
+		t.Errorf("content parts shape = %q, want %q", parts, a)
+	}
+
+	completion := promptShape([]byte(`{"model":"m","prompt":"Summarise product page 55"}`))
+	if completion != a {
+		t.Errorf("completion prompt shape = %q, want %q", completion, a)
+	}
+
+	if got := promptShape([]byte(`{"model":"m"}`)); got != "" {
+		t.Errorf("request without prompt shape = %q, want empty", got)
+	}
+}
+
+func TestThrottledAPIKey(t *testing.T) {
+	tokens := 100000
+	key := &models.APIKey{RateLimitRequestsPerMin: 1000, ConcurrencyLimit: 5, RateLimitTokensPerMin: &tokens}
+
+	got := throttledAPIKey(key)
+	if got.RateLimitRequestsPerMin != 100 || got.ConcurrencyLimit != 1 || *got.RateLimitTokensPerMin != 10000 {
+		t.Errorf("throttledAPIKey() limits = %d rpm, %d concurrency, %d tpm", got.RateLimitRequestsPerMin, got.ConcurrencyLimit, *got.RateLimitTokensPerMin)
+	}
+	if key.RateLimitRequestsPerMin != 1000 || *key.RateLimitTokensPerMin != 100000 {
+		t.Error("throttledAPIKey() modified the original key")
+	}
+}
@@ -0,0 +1,403 @@
+package gateway
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Access reviews list everything that grants access to the platform for
+// auditors: admin tokens, tenant API keys, cloud credentials, and the
+// commands run on nodes during the review period. Each section is a flat
+// table served as JSON, as a CSV, or with every section as a CSV in a zip.
+// Flags point reviewers at access that may no longer be needed.
+
+// accessReviewPeriod is the default review window, a quarter
+const accessReviewPeriod = 90 * 24 * time.Hour
+
+// Access review sections, in the order they are exported
+var accessReviewSections = []string{"admin_tokens", "api_keys", "credentials", "node_access"}
+
+// accessReviewTable is one section of an access review
+type accessReviewTable struct {
+	Header []string
+	Rows   [][]string
+}
+
+// records returns the table as JSON objects keyed by column
+func (t accessReviewTable) records() []map[string]string {
+	records := make([]map[string]string, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		record := make(map[string]string, len(t.Header))
+		for i, column := range t.Header {
+			record[column] = row[i]
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// writeCSV writes the table with formula-like cells neutralized, so
+// commands and names can't run as spreadsheet formulas when auditors open
+// the file
+func (t accessReviewTable) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Header); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		safe := make([]string, len(row))
+		for i, cell := range row {
+			safe[i] = csvSafe(cell)
+		}
+		if err := cw.Write(safe); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvSafe prefixes cells that spreadsheets would evaluate as formulas
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// reviewTime formats an optional timestamp for a review table
+func reviewTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// reviewFlags joins the flags that apply, separated by semicolons
+func reviewFlags(flags ...string) string {
+	var set []string
+	for _, flag := range flags {
+		if flag != "" {
+			set = append(set, flag)
+		}
+	}
+	return strings.Join(set, ";")
+}
+
+// flagIf returns flag when cond holds
+func flagIf(cond bool, flag string) string {
+	if cond {
+		return flag
+	}
+	return ""
+}
+
+// unusedSince reports whether access last used at lastUsed went unused
+// for the whole review period
+func unusedSince(lastUsed *time.Time, start time.Time) bool {
+	return lastUsed == nil || lastUsed.Before(start)
+}
+
+// parseAccessReviewPeriod reads start_date and end_date (RFC 3339),
+// defaulting to the last 90 days
+func parseAccessReviewPeriod(r *http.Request, now time.Time) (time.Time, time.Time, error) {
+	start, end := now.Add(-accessReviewPeriod), now
+	if v := r.URL.Query().Get("start_date"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return start, end, errors.New("start_date must be an RFC 3339 timestamp")
+		}
+		start = t
+	}
+	if v := r.URL.Query().Get("end_date"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return start, end, errors.New("end_date must be an RFC 3339 timestamp")
+		}
+		end = t
+	}
+	if !start.Before(end) {
+		return start, end, errors.New("start_date must be before end_date")
+	}
+	return start, end, nil
+}
+
+// handleGetAccessReview produces an access review for the period.
+// format=csv with a section returns that section as a CSV; format=csv
+// alone returns a zip with a CSV per section.
+// Admin API - GET /admin/access-review?start_date=&end_date=&format=csv&section=api_keys
+func (g *Gateway) handleGetAccessReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	now := time.Now().UTC()
+	actor, _ := ctx.Value("admin_token").(string)
+
+	start, end, err := parseAccessReviewPeriod(r, now)
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		g.writeError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+	sections := accessReviewSections
+	if section := r.URL.Query().Get("section"); section != "" {
+		if !slices.Contains(accessReviewSections, section) {
+			g.writeError(w, http.StatusBadRequest, "section must be one of "+strings.Join(accessReviewSections, ", "))
+			return
+		}
+		sections = []string{section}
+	}
+
+	tables := make(map[string]accessReviewTable, len(sections))
+	for _, section := range sections {
+		table, err := g.accessReviewSection(ctx, section, start, end)
+		if err != nil {
+			g.logger.Error("failed to build access review", zap.String("section", section), zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to build access review")
+			return
+		}
+		tables[section] = table
+	}
+
+	g.logger.Info("access review exported",
+		zap.String("actor", actor),
+		zap.Strings("sections", sections),
+		zap.Time("start", start),
+		zap.Time("end", end),
+	)
+
+	name := "access-review-" + end.Format("2006-01-02")
+	switch {
+	case format == "csv" && len(sections) == 1:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.csv"`, name, sections[0]))
+		if err := tables[sections[0]].writeCSV(w); err != nil {
+			g.logger.Warn("failed to write access review", zap.Error(err))
+		}
+	case format == "csv":
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, name))
+		if err := writeAccessReviewZip(w, name, sections, tables); err != nil {
+			g.logger.Warn("failed to write access review", zap.Error(err))
+		}
+	default:
+		resp := map[string]interface{}{
+			"period":       map[string]time.Time{"start": start, "end": end},
+			"generated_at": now,
+		}
+		summary := map[string]int{}
+		data := map[string]interface{}{}
+		for _, section := range sections {
+			summary[section] = len(tables[section].Rows)
+			data[section] = tables[section].records()
+		}
+		resp["summary"] = summary
+		resp["sections"] = data
+		g.writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// writeAccessReviewZip writes each section as name/<section>.csv
+func writeAccessReviewZip(w io.Writer, name string, sections []string, tables map[string]accessReviewTable) error {
+	zw := zip.NewWriter(w)
+	for _, section := range sections {
+		f, err := zw.Create(name + "/" + section + ".csv")
+		if err != nil {
+			return err
+		}
+		if err := tables[section].writeCSV(f); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// accessReviewSection builds one section of the review
+func (g *Gateway) accessReviewSection(ctx context.Context, section string, start, end time.Time) (accessReviewTable, error) {
+	switch section {
+	case "admin_tokens":
+		return g.reviewAdminTokens(ctx, start)
+	case "api_keys":
+		return g.reviewAPIKeys(ctx, start, end)
+	case "credentials":
+		return g.reviewCredentials(ctx, start)
+	case "node_access":
+		return g.reviewNodeAccess(ctx, start, end)
+	}
+	return accessReviewTable{}, fmt.Errorf("unknown access review section %q", section)
+}
+
+// reviewAdminTokens lists the active admin tokens, including the bootstrap
+// ADMIN_API_TOKEN, which can't be revoked without a redeploy
+func (g *Gateway) reviewAdminTokens(ctx context.Context, start time.Time) (accessReviewTable, error) {
+	table := accessReviewTable{Header: []string{"id", "name", "token_prefix", "created_at", "last_used_at", "flags"}}
+	if g.adminToken != "" {
+		table.Rows = append(table.Rows, []string{"", bootstrapAdminTokenName, "", "", "", "bootstrap"})
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id::text, name, token_prefix, created_at, last_used_at
+		FROM admin_tokens
+		WHERE revoked_at IS NULL
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return table, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, name, prefix string
+		var createdAt time.Time
+		var lastUsed *time.Time
+		if err := rows.Scan(&id, &name, &prefix, &createdAt, &lastUsed); err != nil {
+			return table, err
+		}
+		table.Rows = append(table.Rows, []string{
+			id, name, prefix, reviewTime(&createdAt), reviewTime(lastUsed),
+			reviewFlags(flagIf(unusedSince(lastUsed, start), "unused")),
+		})
+	}
+	return table, rows.Err()
+}
+
+// reviewAPIKeys lists tenant API keys that can still be used, with their
+// role as the key's scope
+func (g *Gateway) reviewAPIKeys(ctx context.Context, start, end time.Time) (accessReviewTable, error) {
+	table := accessReviewTable{Header: []string{
+		"id", "tenant_id", "tenant_name", "environment", "name", "key_prefix", "role", "status",
+		"created_at", "last_used_at", "expires_at", "flags",
+	}}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT k.id::text, k.tenant_id::text, t.name, COALESCE(e.name, ''), COALESCE(k.name, ''),
+		       k.key_prefix, k.role, k.status, k.created_at, k.last_used_at, k.expires_at
+		FROM api_keys k
+		JOIN tenants t ON t.id = k.tenant_id
+		LEFT JOIN environments e ON e.id = k.environment_id
+		WHERE k.status != 'revoked'
+		ORDER BY t.name, k.created_at
+	`)
+	if err != nil {
+		return table, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, tenantID, tenantName, environment, name, prefix, role, status string
+		var createdAt time.Time
+		var lastUsed, expiresAt *time.Time
+		if err := rows.Scan(&id, &tenantID, &tenantName, &environment, &name, &prefix, &role, &status,
+			&createdAt, &lastUsed, &expiresAt); err != nil {
+			return table, err
+		}
+		table.Rows = append(table.Rows, []string{
+			id, tenantID, tenantName, environment, name, prefix, role, status,
+			reviewTime(&createdAt), reviewTime(lastUsed), reviewTime(expiresAt),
+			reviewFlags(
+				flagIf(unusedSince(lastUsed, start), "unused"),
+				flagIf(expiresAt == nil, "no_expiry"),
+				flagIf(expiresAt != nil && expiresAt.Before(end), "expired"),
+				flagIf(role == "admin", "admin_role"),
+			),
+		})
+	}
+	return table, rows.Err()
+}
+
+// reviewCredentials lists the cloud credentials on record, without their
+// secrets
+func (g *Gateway) reviewCredentials(ctx context.Context, start time.Time) (accessReviewTable, error) {
+	table := accessReviewTable{Header: []string{
+		"id", "tenant_id", "tenant_name", "provider", "name", "is_default", "status",
+		"created_at", "last_used_at", "last_validated_at", "validation_error", "flags",
+	}}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT c.id::text, c.tenant_id::text, t.name, c.provider, c.name, c.is_default, c.status,
+		       c.created_at, c.last_used_at, c.last_validated_at, COALESCE(c.validation_error, '')
+		FROM cloud_credentials c
+		JOIN tenants t ON t.id = c.tenant_id
+		WHERE c.status != 'deleted' AND c.deleted_at IS NULL
+		ORDER BY t.name, c.created_at
+	`)
+	if err != nil {
+		return table, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, tenantID, tenantName, provider, name, status, validationError string
+		var isDefault bool
+		var createdAt time.Time
+		var lastUsed, lastValidated *time.Time
+		if err := rows.Scan(&id, &tenantID, &tenantName, &provider, &name, &isDefault, &status,
+			&createdAt, &lastUsed, &lastValidated, &validationError); err != nil {
+			return table, err
+		}
+		table.Rows = append(table.Rows, []string{
+			id, tenantID, tenantName, provider, name, strconv.FormatBool(isDefault), status,
+			reviewTime(&createdAt), reviewTime(lastUsed), reviewTime(lastValidated), validationError,
+			reviewFlags(
+				flagIf(unusedSince(lastUsed, start), "unused"),
+				flagIf(unusedSince(lastValidated, start), "not_validated"),
+				flagIf(validationError != "", "validation_failed"),
+			),
+		})
+	}
+	return table, rows.Err()
+}
+
+// reviewNodeAccess lists the commands run on nodes during the period
+func (g *Gateway) reviewNodeAccess(ctx context.Context, start, end time.Time) (accessReviewTable, error) {
+	table := accessReviewTable{Header: []string{
+		"id", "started_at", "finished_at", "cluster_name", "tenant_id", "source", "actor",
+		"command", "streamed", "exit_code", "error",
+	}}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id::text, started_at, finished_at, cluster_name, tenant_id::text, source,
+		       COALESCE(actor, ''), command, streamed, exit_code, COALESCE(error, '')
+		FROM node_exec_audit
+		WHERE started_at >= $1 AND started_at < $2
+		ORDER BY started_at
+	`, start, end)
+	if err != nil {
+		return table, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, cluster, tenantID, source, actor, command, execErr string
+		var startedAt time.Time
+		var finishedAt *time.Time
+		var streamed bool
+		var exitCode *int
+		if err := rows.Scan(&id, &startedAt, &finishedAt, &cluster, &tenantID, &source,
+			&actor, &command, &streamed, &exitCode, &execErr); err != nil {
+			return table, err
+		}
+		code := ""
+		if exitCode != nil {
+			code = strconv.Itoa(*exitCode)
+		}
+		table.Rows = append(table.Rows, []string{
+			id, reviewTime(&startedAt), reviewTime(finishedAt), cluster, tenantID, source, actor,
+			command, strconv.FormatBool(streamed), code, execErr,
+		})
+	}
+	return table, rows.Err()
+}
@@ -0,0 +1,624 @@
+package gateway
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/jobs"
+	"github.com/crosslogic/control-plane/pkg/r2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Tenants export their account data as a zip archive of JSON files. The
+// archive is built by a background job and uploaded to R2; the export
+// endpoint reports its progress and hands out a presigned download link
+// once it is ready. Secrets (key hashes, encrypted credentials) are never
+// exported.
+
+const (
+	// accountExportTTL is how long a completed export can be downloaded
+	accountExportTTL = 7 * 24 * time.Hour
+	// accountExportURLTTL is how long a download link stays valid
+	accountExportURLTTL = time.Hour
+	// accountExportFormat versions the archive layout for consumers
+	accountExportFormat = 1
+)
+
+// Account export statuses
+const (
+	exportPending   = "pending"
+	exportRunning   = "running"
+	exportCompleted = "completed"
+)
+
+// AccountExport is a tenant's export request and, once completed, its archive
+type AccountExport struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error,omitempty"`
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	RequestedAt time.Time  `json:"requested_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+
+	objectKey *string
+}
+
+// accountExportArgs are the arguments of an account.export job
+type accountExportArgs struct {
+	ExportID uuid.UUID `json:"export_id"`
+}
+
+// accountExportKey is where an export's archive is stored
+func accountExportKey(tenantID, exportID uuid.UUID) string {
+	return fmt.Sprintf("account-exports/%s/%s.zip", tenantID, exportID)
+}
+
+// accountExportFresh reports whether an export can be served as is: it is
+// still being built, or completed and not yet expired
+func accountExportFresh(e *AccountExport, now time.Time) bool {
+	switch e.Status {
+	case exportPending, exportRunning:
+		return true
+	case exportCompleted:
+		return e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+	}
+	return false
+}
+
+// handleGetAccountExport returns the tenant's latest account export,
+// starting one when there is none that can still be downloaded. It answers
+// 202 while the export is being built and 200 with a download link once it
+// is ready.
+// GET /v1/account/export
+func (g *Gateway) handleGetAccountExport(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.accountExportTenant(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := g.latestAccountExport(r.Context(), tenantID)
+	if err != nil {
+		g.logger.Error("failed to get account export", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to get account export")
+		return
+	}
+	if export == nil || !accountExportFresh(export, time.Now()) {
+		g.startAccountExport(w, r, tenantID)
+		return
+	}
+	g.writeAccountExport(w, export)
+}
+
+// handleCreateAccountExport starts a new account export, for when the
+// latest one predates changes to the account. An export already in
+// progress is returned instead of starting another.
+// POST /v1/account/export
+func (g *Gateway) handleCreateAccountExport(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.accountExportTenant(w, r)
+	if !ok {
+		return
+	}
+	g.startAccountExport(w, r, tenantID)
+}
+
+// accountExportTenant returns the authenticated tenant, answering the
+// request itself when exports are unavailable
+func (g *Gateway) accountExportTenant(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	if g.AccountExports == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "account exports are not configured")
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// startAccountExport queues an export for the tenant, or returns the one
+// already in progress
+func (g *Gateway) startAccountExport(w http.ResponseWriter, r *http.Request, tenantID uuid.UUID) {
+	ctx := r.Context()
+
+	export, err := g.createAccountExport(ctx, tenantID)
+	if err != nil {
+		g.logger.Error("failed to start account export", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to start account export")
+		return
+	}
+	g.writeAccountExport(w, export)
+}
+
+// writeAccountExport answers with an export, 202 until it has completed
+func (g *Gateway) writeAccountExport(w http.ResponseWriter, export *AccountExport) {
+	if export.Status != exportCompleted {
+		g.writeJSON(w, http.StatusAccepted, export)
+		return
+	}
+	if export.objectKey != nil {
+		export.DownloadURL = g.AccountExports.PresignGet(*export.objectKey, accountExportURLTTL)
+	}
+	g.writeJSON(w, http.StatusOK, export)
+}
+
+const accountExportColumns = `
+	id, status, error, size_bytes, requested_at, completed_at, expires_at, object_key
+`
+
+func scanAccountExport(row pgx.Row) (*AccountExport, error) {
+	var e AccountExport
+	err := row.Scan(&e.ID, &e.Status, &e.Error, &e.SizeBytes, &e.RequestedAt, &e.CompletedAt, &e.ExpiresAt, &e.objectKey)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// latestAccountExport returns the tenant's most recent export, nil if it
+// has never exported
+func (g *Gateway) latestAccountExport(ctx context.Context, tenantID uuid.UUID) (*AccountExport, error) {
+	export, err := scanAccountExport(g.db.Pool.QueryRow(ctx, `
+		SELECT `+accountExportColumns+`
+		FROM account_exports
+		WHERE tenant_id = $1
+		ORDER BY requested_at DESC
+		LIMIT 1
+	`, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return export, err
+}
+
+// createAccountExport records a pending export and queues the job that
+// builds it. The in-progress index allows one export at a time, so a
+// concurrent request gets the export already queued.
+func (g *Gateway) createAccountExport(ctx context.Context, tenantID uuid.UUID) (*AccountExport, error) {
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	export, err := scanAccountExport(tx.QueryRow(ctx, `
+		INSERT INTO account_exports (tenant_id)
+		VALUES ($1)
+		ON CONFLICT (tenant_id) WHERE status IN ('pending', 'running') DO NOTHING
+		RETURNING `+accountExportColumns, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return scanAccountExport(tx.QueryRow(ctx, `
+			SELECT `+accountExportColumns+`
+			FROM account_exports
+			WHERE tenant_id = $1 AND status IN ('pending', 'running')
+		`, tenantID))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := g.jobs.EnqueueTx(ctx, tx, jobAccountExport, accountExportArgs{ExportID: export.ID}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	g.logger.Info("account export requested",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("export_id", export.ID.String()),
+	)
+	return export, nil
+}
+
+// runAccountExport builds an export's archive, uploads it to R2 and
+// deletes the tenant's older archives
+func (g *Gateway) runAccountExport(ctx context.Context, job *jobs.Job) error {
+	var args accountExportArgs
+	if err := job.Decode(&args); err != nil {
+		return err
+	}
+	if g.AccountExports == nil {
+		return jobs.Permanent(errors.New("account exports are not configured"))
+	}
+
+	var tenantID uuid.UUID
+	err := g.db.Pool.QueryRow(ctx, `
+		UPDATE account_exports SET status = 'running'
+		WHERE id = $1 AND status IN ('pending', 'running')
+		RETURNING tenant_id
+	`, args.ExportID).Scan(&tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim account export: %w", err)
+	}
+
+	archive, err := g.buildAccountExport(ctx, tenantID, time.Now().UTC())
+	if err == nil {
+		objects := r2.NewObjects(g.AccountExports)
+		key := accountExportKey(tenantID, args.ExportID)
+		if err = objects.Put(ctx, key, archive, "application/zip"); err == nil {
+			err = g.completeAccountExport(ctx, objects, tenantID, args.ExportID, key, len(archive))
+		}
+	}
+	if err != nil {
+		if job.FinalAttempt() {
+			g.failAccountExport(ctx, args.ExportID, err)
+		}
+		return fmt.Errorf("failed to export account: %w", err)
+	}
+
+	g.logger.Info("account export completed",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("export_id", args.ExportID.String()),
+		zap.Int("size_bytes", len(archive)),
+	)
+	return nil
+}
+
+// completeAccountExport marks an export downloadable and deletes the
+// archives of the tenant's earlier exports. Failing to delete an old
+// archive doesn't fail the export; it is retried when the next one
+// completes.
+func (g *Gateway) completeAccountExport(ctx context.Context, objects *r2.Objects, tenantID, exportID uuid.UUID, key string, size int) error {
+	_, err := g.db.Pool.Exec(ctx, `
+		UPDATE account_exports
+		SET status = 'completed', object_key = $2, size_bytes = $3, error = NULL,
+		    completed_at = NOW(), expires_at = NOW() + $4 * INTERVAL '1 second'
+		WHERE id = $1
+	`, exportID, key, size, int64(accountExportTTL.Seconds()))
+	if err != nil {
+		return err
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, object_key FROM account_exports
+		WHERE tenant_id = $1 AND id != $2 AND object_key IS NOT NULL
+	`, tenantID, exportID)
+	if err != nil {
+		g.logger.Warn("failed to list earlier account exports", zap.Error(err))
+		return nil
+	}
+	old := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var id uuid.UUID
+		var oldKey string
+		if err := rows.Scan(&id, &oldKey); err != nil {
+			rows.Close()
+			g.logger.Warn("failed to scan earlier account export", zap.Error(err))
+			return nil
+		}
+		old[id] = oldKey
+	}
+	rows.Close()
+
+	for id, oldKey := range old {
+		if err := objects.Delete(ctx, oldKey); err != nil {
+			g.logger.Warn("failed to delete earlier account export",
+				zap.String("export_id", id.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		if _, err := g.db.Pool.Exec(ctx, `
+			UPDATE account_exports SET status = 'expired', object_key = NULL WHERE id = $1
+		`, id); err != nil {
+			g.logger.Warn("failed to expire earlier account export",
+				zap.String("export_id", id.String()),
+				zap.Error(err),
+			)
+		}
+	}
+	return nil
+}
+
+// failAccountExport records why an export could not be built
+func (g *Gateway) failAccountExport(ctx context.Context, exportID uuid.UUID, cause error) {
+	_, err := g.db.Pool.Exec(ctx, `
+		UPDATE account_exports SET status = 'failed', error = $2, completed_at = NOW()
+		WHERE id = $1
+	`, exportID, cause.Error())
+	if err != nil {
+		g.logger.Error("failed to record account export failure",
+			zap.String("export_id", exportID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+// accountExportFile is one JSON document of an export archive
+type accountExportFile struct {
+	name string
+	data interface{}
+}
+
+// accountExportManifest describes an archive's contents
+type accountExportManifest struct {
+	Format     int       `json:"format"`
+	TenantID   uuid.UUID `json:"tenant_id"`
+	ExportedAt time.Time `json:"exported_at"`
+	Files      []string  `json:"files"`
+}
+
+// writeAccountArchive zips the files as indented JSON, preceded by a
+// manifest listing them
+func writeAccountArchive(tenantID uuid.UUID, exportedAt time.Time, files []accountExportFile) ([]byte, error) {
+	manifest := accountExportManifest{Format: accountExportFormat, TenantID: tenantID, ExportedAt: exportedAt}
+	for _, f := range files {
+		manifest.Files = append(manifest.Files, f.name)
+	}
+	files = append([]accountExportFile{{name: "manifest.json", data: manifest}}, files...)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: exportedAt})
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// buildAccountExport gathers the tenant's account data into an archive
+func (g *Gateway) buildAccountExport(ctx context.Context, tenantID uuid.UUID, now time.Time) ([]byte, error) {
+	sections := []struct {
+		name    string
+		collect func(context.Context, uuid.UUID) (interface{}, error)
+	}{
+		{"account.json", g.exportAccount},
+		{"api_keys.json", g.exportAPIKeys},
+		{"credentials.json", g.exportCredentials},
+		{"instances.json", g.exportInstances},
+		{"usage_summary.json", g.exportUsageSummary},
+		{"invoices.json", g.exportInvoices},
+	}
+
+	files := make([]accountExportFile, 0, len(sections))
+	for _, section := range sections {
+		data, err := section.collect(ctx, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", section.name, err)
+		}
+		files = append(files, accountExportFile{name: section.name, data: data})
+	}
+	return writeAccountArchive(tenantID, now, files)
+}
+
+// exportAccount returns the tenant's profile
+func (g *Gateway) exportAccount(ctx context.Context, tenantID uuid.UUID) (interface{}, error) {
+	var account struct {
+		ID          uuid.UUID `json:"id"`
+		Name        string    `json:"name"`
+		Email       string    `json:"email"`
+		Status      string    `json:"status"`
+		BillingPlan string    `json:"billing_plan"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT id, name, email, status, billing_plan, created_at
+		FROM tenants
+		WHERE id = $1
+	`, tenantID).Scan(&account.ID, &account.Name, &account.Email, &account.Status, &account.BillingPlan, &account.CreatedAt)
+	return account, err
+}
+
+// exportedAPIKey is an API key's metadata; its hash is never exported
+type exportedAPIKey struct {
+	ID                uuid.UUID  `json:"id"`
+	EnvironmentID     uuid.UUID  `json:"environment_id"`
+	Name              *string    `json:"name"`
+	Prefix            string     `json:"prefix"`
+	Role              string     `json:"role"`
+	Status            string     `json:"status"`
+	RateLimitRequests *int       `json:"rate_limit_requests_per_min"`
+	RateLimitTokens   *int       `json:"rate_limit_tokens_per_min"`
+	ConcurrencyLimit  *int       `json:"concurrency_limit"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastUsedAt        *time.Time `json:"last_used_at"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+}
+
+// exportAPIKeys returns the metadata of all the tenant's API keys,
+// including revoked ones
+func (g *Gateway) exportAPIKeys(ctx context.Context, tenantID uuid.UUID) (interface{}, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, environment_id, name, key_prefix, role, status,
+		       rate_limit_requests_per_min, rate_limit_tokens_per_min, concurrency_limit,
+		       created_at, last_used_at, expires_at
+		FROM api_keys
+		WHERE tenant_id = $1
+		ORDER BY created_at
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []exportedAPIKey{}
+	for rows.Next() {
+		var k exportedAPIKey
+		if err := rows.Scan(&k.ID, &k.EnvironmentID, &k.Name, &k.Prefix, &k.Role, &k.Status,
+			&k.RateLimitRequests, &k.RateLimitTokens, &k.ConcurrencyLimit,
+			&k.CreatedAt, &k.LastUsedAt, &k.ExpiresAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// exportedCredential is a cloud credential's metadata; the encrypted
+// credentials are never exported
+type exportedCredential struct {
+	ID              uuid.UUID  `json:"id"`
+	EnvironmentID   *uuid.UUID `json:"environment_id"`
+	Provider        string     `json:"provider"`
+	Name            string     `json:"name"`
+	IsDefault       bool       `json:"is_default"`
+	Status          string     `json:"status"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	LastValidatedAt *time.Time `json:"last_validated_at"`
+	ValidationError *string    `json:"validation_error"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+}
+
+// exportCredentials returns the metadata of the tenant's cloud credentials
+func (g *Gateway) exportCredentials(ctx context.Context, tenantID uuid.UUID) (interface{}, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, environment_id, provider, name, is_default, status,
+		       last_used_at, last_validated_at, validation_error, created_at, deleted_at
+		FROM cloud_credentials
+		WHERE tenant_id = $1
+		ORDER BY created_at
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creds := []exportedCredential{}
+	for rows.Next() {
+		var c exportedCredential
+		if err := rows.Scan(&c.ID, &c.EnvironmentID, &c.Provider, &c.Name, &c.IsDefault, &c.Status,
+			&c.LastUsedAt, &c.LastValidatedAt, &c.ValidationError, &c.CreatedAt, &c.DeletedAt); err != nil {
+			return nil, err
+		}
+		creds = append(creds, c)
+	}
+	return creds, rows.Err()
+}
+
+// exportInstances returns the tenant's self-service instances, including
+// terminated ones
+func (g *Gateway) exportInstances(ctx context.Context, tenantID uuid.UUID) (interface{}, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id::text, COALESCE(cluster_name, ''), COALESCE(model_name, ''), provider,
+		       COALESCE(gpu_type, ''), status, COALESCE(endpoint_url, ''), COALESCE(spot_instance, false),
+		       created_at, updated_at, terminated_at
+		FROM nodes
+		WHERE tenant_id = $1
+		ORDER BY created_at
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	instances := []InstanceOutput{}
+	for rows.Next() {
+		var inst InstanceOutput
+		if err := rows.Scan(&inst.ID, &inst.ClusterName, &inst.Model, &inst.Provider,
+			&inst.GPU, &inst.Status, &inst.EndpointURL, &inst.SpotInstance,
+			&inst.CreatedAt, &inst.UpdatedAt, &inst.TerminatedAt); err != nil {
+			return nil, err
+		}
+		instances = append(instances, inst)
+	}
+	return instances, rows.Err()
+}
+
+// exportedUsageMonth is the tenant's usage of one model in one month
+type exportedUsageMonth struct {
+	Month            string `json:"month"`
+	Model            string `json:"model"`
+	Requests         int64  `json:"requests"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	TotalTokens      int64  `json:"total_tokens"`
+	CostMicrodollars int64  `json:"cost_microdollars"`
+}
+
+// exportUsageSummary returns the tenant's usage by month and model
+func (g *Gateway) exportUsageSummary(ctx context.Context, tenantID uuid.UUID) (interface{}, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT
+			TO_CHAR(DATE_TRUNC('month', ur.timestamp), 'YYYY-MM') AS month,
+			COALESCE(m.name, 'unknown'),
+			COUNT(*),
+			COALESCE(SUM(ur.prompt_tokens), 0),
+			COALESCE(SUM(ur.completion_tokens), 0),
+			COALESCE(SUM(ur.total_tokens), 0),
+			COALESCE(SUM(ur.cost_microdollars), 0)
+		FROM usage_records ur
+		LEFT JOIN models m ON m.id = ur.model_id
+		WHERE ur.tenant_id = $1
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []exportedUsageMonth{}
+	for rows.Next() {
+		var u exportedUsageMonth
+		if err := rows.Scan(&u.Month, &u.Model, &u.Requests, &u.PromptTokens,
+			&u.CompletionTokens, &u.TotalTokens, &u.CostMicrodollars); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// exportedInvoice is a Stripe invoice billed to the tenant
+type exportedInvoice struct {
+	StripeInvoiceID    string     `json:"stripe_invoice_id"`
+	EventType          string     `json:"event_type"`
+	AmountMicrodollars int64      `json:"amount_microdollars"`
+	Currency           *string    `json:"currency"`
+	Description        *string    `json:"description"`
+	PeriodStart        *time.Time `json:"period_start"`
+	PeriodEnd          *time.Time `json:"period_end"`
+	Status             string     `json:"status"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// exportInvoices returns the billing events tied to the tenant's invoices
+func (g *Gateway) exportInvoices(ctx context.Context, tenantID uuid.UUID) (interface{}, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT stripe_invoice_id, event_type, amount_microdollars, currency, description,
+		       period_start, period_end, status, created_at
+		FROM billing_events
+		WHERE tenant_id = $1 AND stripe_invoice_id IS NOT NULL
+		ORDER BY created_at
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []exportedInvoice{}
+	for rows.Next() {
+		var inv exportedInvoice
+		if err := rows.Scan(&inv.StripeInvoiceID, &inv.EventType, &inv.AmountMicrodollars, &inv.Currency,
+			&inv.Description, &inv.PeriodStart, &inv.PeriodEnd, &inv.Status, &inv.CreatedAt); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
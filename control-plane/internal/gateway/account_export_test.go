@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestWriteAccountArchive(t *testing.T) {
+	tenantID := uuid.New()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	data, err := writeAccountArchive(tenantID, now, []accountExportFile{
+		{name: "api_keys.json", data: []exportedAPIKey{{ID: uuid.New(), Prefix: "sk_live_ab", Status: "active"}}},
+		{name: "invoices.json", data: []exportedInvoice{}},
+	})
+	if err != nil {
+		t.Fatalf("writeAccountArchive: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("archive is not a zip: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if len(names) != 3 || names[0] != "manifest.json" || names[1] != "api_keys.json" || names[2] != "invoices.json" {
+		t.Fatalf("archive files = %v", names)
+	}
+
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	var manifest accountExportManifest
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	if manifest.TenantID != tenantID || manifest.Format != accountExportFormat || len(manifest.Files) != 2 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
+
+func TestAccountExportFresh(t *testing.T) {
+	now := time.Now()
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		export AccountExport
+		want   bool
+	}{
+		{"pending", AccountExport{Status: exportPending}, true},
+		{"running", AccountExport{Status: exportRunning}, true},
+		{"completed", AccountExport{Status: exportCompleted, ExpiresAt: &later}, true},
+		{"completed and expired", AccountExport{Status: exportCompleted, ExpiresAt: &earlier}, false},
+		{"failed", AccountExport{Status: "failed"}, false},
+		{"replaced", AccountExport{Status: "expired"}, false},
+	}
+	for _, tt := range tests {
+		if got := accountExportFresh(&tt.export, now); got != tt.want {
+			t.Errorf("%s: accountExportFresh = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
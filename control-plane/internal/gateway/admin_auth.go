@@ -0,0 +1,371 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// adminRequestsPerMinute caps admin API requests from one client address
+	adminRequestsPerMinute = 600
+
+	// adminFailureThreshold is how many bad admin tokens an address may send
+	// within adminFailureWindow before it is locked out
+	adminFailureThreshold = 5
+	adminFailureWindow    = time.Hour
+
+	// Lockouts start at adminLockoutBase and double with every further
+	// failure, up to adminLockoutMax
+	adminLockoutBase = time.Minute
+	adminLockoutMax  = 24 * time.Hour
+
+	// adminTokenCacheTTL bounds how long a revoked token keeps working on a
+	// replica whose cache entry survived the revocation
+	adminTokenCacheTTL = 30 * time.Second
+
+	// bootstrapAdminTokenName identifies the ADMIN_API_TOKEN secret in audit logs
+	bootstrapAdminTokenName = "bootstrap"
+)
+
+// adminLockoutDuration returns how long an address is locked out after the
+// given number of consecutive failures, or 0 below the threshold
+func adminLockoutDuration(failures int64) time.Duration {
+	if failures < adminFailureThreshold {
+		return 0
+	}
+	lockout := adminLockoutBase
+	for i := int64(adminFailureThreshold); i < failures; i++ {
+		lockout *= 2
+		if lockout >= adminLockoutMax {
+			return adminLockoutMax
+		}
+	}
+	return lockout
+}
+
+// clientHost strips the port from a request's remote address
+func clientHost(remoteAddr string) string {
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return h
+	}
+	return remoteAddr
+}
+
+// adminAuthGuard rate limits admin requests and locks out addresses that
+// guess admin tokens. State lives in Redis so limits hold across replicas.
+type adminAuthGuard struct {
+	cache *cache.Cache
+}
+
+func newAdminAuthGuard(cache *cache.Cache) *adminAuthGuard {
+	return &adminAuthGuard{cache: cache}
+}
+
+// allow counts a request from host and reports whether it is within the
+// per-minute limit
+func (ag *adminAuthGuard) allow(ctx context.Context, host string, now time.Time) (bool, error) {
+	key := fmt.Sprintf("admin_auth:rate:%s:%s", host, now.Format("2006-01-02T15:04"))
+	count, err := ag.cache.Incr(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		ag.cache.Expire(ctx, key, 65*time.Second)
+	}
+	return count <= adminRequestsPerMinute, nil
+}
+
+// lockedUntil returns when host's lockout ends, or the zero time if it is
+// not locked out
+func (ag *adminAuthGuard) lockedUntil(ctx context.Context, host string, now time.Time) (time.Time, error) {
+	until, ok, err := ag.cache.GetInt64(ctx, "admin_auth:lock:"+host)
+	if err != nil || !ok {
+		return time.Time{}, err
+	}
+	t := time.Unix(until, 0)
+	if !t.After(now) {
+		return time.Time{}, nil
+	}
+	return t, nil
+}
+
+// recordFailure counts a bad token from host and locks it out once the
+// threshold is reached. It returns the lockout applied, if any.
+func (ag *adminAuthGuard) recordFailure(ctx context.Context, host string, now time.Time) (time.Duration, error) {
+	key := "admin_auth:failures:" + host
+	failures, err := ag.cache.Incr(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	ag.cache.Expire(ctx, key, adminFailureWindow)
+
+	lockout := adminLockoutDuration(failures)
+	if lockout == 0 {
+		return 0, nil
+	}
+	until := now.Add(lockout)
+	if err := ag.cache.Set(ctx, "admin_auth:lock:"+host, strconv.FormatInt(until.Unix(), 10), lockout); err != nil {
+		return 0, err
+	}
+	return lockout, nil
+}
+
+// recordSuccess clears host's failure count
+func (ag *adminAuthGuard) recordSuccess(ctx context.Context, host string) {
+	ag.cache.Delete(ctx, "admin_auth:failures:"+host)
+}
+
+// adminTokenCacheKey is the cache entry holding a valid token's name
+func adminTokenCacheKey(tokenHash string) string {
+	return "admin_token:" + tokenHash
+}
+
+// resolveAdminToken returns the name of the admin token, or "" if it is not
+// a valid token. The ADMIN_API_TOKEN secret is always accepted as the
+// bootstrap token; named tokens live in admin_tokens.
+func (g *Gateway) resolveAdminToken(ctx context.Context, token string) (string, error) {
+	// Constant-time comparison to prevent timing attacks
+	if subtle.ConstantTimeCompare([]byte(token), []byte(g.adminToken)) == 1 {
+		return bootstrapAdminTokenName, nil
+	}
+
+	tokenHash := hashAPIKey(token)
+	if name, err := g.cache.Get(ctx, adminTokenCacheKey(tokenHash)); err == nil && name != "" {
+		return name, nil
+	}
+
+	var name string
+	err := g.db.Pool.QueryRow(ctx, `
+		UPDATE admin_tokens SET last_used_at = NOW()
+		WHERE token_hash = $1 AND revoked_at IS NULL
+		RETURNING name
+	`, tokenHash).Scan(&name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	g.cache.Set(ctx, adminTokenCacheKey(tokenHash), name, adminTokenCacheTTL)
+	return name, nil
+}
+
+// adminAuthMiddleware authenticates admin requests by X-Admin-Token, with
+// per-address rate limiting and exponential lockout after failed attempts
+func (g *Gateway) adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		host := clientIP(r)
+		now := time.Now()
+
+		// Redis outages fail open by default so operators keep access to
+		// the admin API; REDIS_FAIL_OPEN decides
+		allowed, err := g.adminGuard.allow(ctx, host, now)
+		if err != nil {
+			g.logger.Warn("admin rate limit check failed", zap.Error(err))
+			if !g.cache.FailOpen(cache.FeatureAdminGuard) {
+				g.writeError(w, http.StatusServiceUnavailable, "admin authentication temporarily unavailable")
+				return
+			}
+		} else if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(60-now.Second()))
+			g.writeError(w, http.StatusTooManyRequests, "too many admin requests")
+			return
+		}
+
+		until, err := g.adminGuard.lockedUntil(ctx, host, now)
+		if err != nil {
+			g.logger.Warn("admin lockout check failed", zap.Error(err))
+		} else if !until.IsZero() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(until.Sub(now).Seconds())+1))
+			g.writeError(w, http.StatusTooManyRequests, "too many failed admin authentication attempts")
+			return
+		}
+
+		adminToken := r.Header.Get("X-Admin-Token")
+		if adminToken == "" {
+			g.writeError(w, http.StatusUnauthorized, "missing admin token")
+			return
+		}
+
+		tokenName, err := g.resolveAdminToken(ctx, adminToken)
+		if err != nil {
+			g.logger.Error("failed to validate admin token", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to validate admin token")
+			return
+		}
+
+		if tokenName == "" {
+			lockout, err := g.adminGuard.recordFailure(ctx, host, now)
+			if err != nil {
+				g.logger.Warn("failed to record admin auth failure", zap.Error(err))
+			}
+			g.logger.Warn("invalid admin token attempt",
+				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("path", r.URL.Path),
+				zap.Duration("lockout", lockout),
+			)
+			g.writeError(w, http.StatusUnauthorized, "invalid admin token")
+			return
+		}
+		g.adminGuard.recordSuccess(ctx, host)
+
+		// Audit log for admin actions
+		g.logger.Info("admin action authenticated",
+			zap.String("request_id", middleware.GetReqID(ctx)),
+			zap.String("admin_token", tokenName),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+		)
+
+		ctx = context.WithValue(ctx, "admin_token", tokenName)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AdminToken is a named admin API token
+type AdminToken struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// generateAdminToken returns a new random admin token
+func generateAdminToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "clat_" + hex.EncodeToString(raw), nil
+}
+
+// handleListAdminTokens lists named admin tokens
+// Admin API - GET /admin/tokens
+func (g *Gateway) handleListAdminTokens(w http.ResponseWriter, r *http.Request) {
+	rows, err := g.db.Pool.Query(r.Context(), `
+		SELECT id, name, token_prefix, created_at, last_used_at, revoked_at
+		FROM admin_tokens
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		g.logger.Error("failed to list admin tokens", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list admin tokens")
+		return
+	}
+	defer rows.Close()
+
+	tokens := []AdminToken{}
+	for rows.Next() {
+		var t AdminToken
+		if err := rows.Scan(&t.ID, &t.Name, &t.TokenPrefix, &t.CreatedAt, &t.LastUsedAt, &t.RevokedAt); err != nil {
+			g.logger.Error("failed to scan admin token", zap.Error(err))
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{"data": tokens})
+}
+
+// handleCreateAdminToken issues a named admin token. The token is only
+// returned in this response.
+// Admin API - POST /admin/tokens
+func (g *Gateway) handleCreateAdminToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.Name == bootstrapAdminTokenName {
+		g.writeError(w, http.StatusBadRequest, "name is required and must not be \"bootstrap\"")
+		return
+	}
+
+	token, err := generateAdminToken()
+	if err != nil {
+		g.logger.Error("failed to generate admin token", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create admin token")
+		return
+	}
+
+	t := AdminToken{Name: req.Name, TokenPrefix: token[:13]}
+	err = g.db.Pool.QueryRow(r.Context(), `
+		INSERT INTO admin_tokens (name, token_hash, token_prefix)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, t.Name, hashAPIKey(token), t.TokenPrefix).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			g.writeError(w, http.StatusConflict, "an active admin token with this name already exists")
+			return
+		}
+		g.logger.Error("failed to create admin token", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create admin token")
+		return
+	}
+
+	g.logger.Info("admin token created", zap.String("token_id", t.ID), zap.String("name", t.Name))
+	g.writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":       token,
+		"admin_token": t,
+	})
+}
+
+// handleRevokeAdminToken revokes a named admin token
+// Admin API - DELETE /admin/tokens/{id}
+func (g *Gateway) handleRevokeAdminToken(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid token ID")
+		return
+	}
+
+	var tokenHash, name string
+	err = g.db.Pool.QueryRow(r.Context(), `
+		UPDATE admin_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING token_hash, name
+	`, tokenID).Scan(&tokenHash, &name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			g.writeError(w, http.StatusNotFound, "admin token not found or already revoked")
+			return
+		}
+		g.logger.Error("failed to revoke admin token", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to revoke admin token")
+		return
+	}
+
+	g.cache.Delete(r.Context(), adminTokenCacheKey(tokenHash))
+
+	g.logger.Info("admin token revoked", zap.String("token_id", tokenID.String()), zap.String("name", name))
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":     tokenID,
+		"status": "revoked",
+	})
+}
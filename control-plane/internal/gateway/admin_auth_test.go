@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAdminLockoutDuration(t *testing.T) {
+	tests := []struct {
+		failures int64
+		want     time.Duration
+	}{
+		{1, 0},
+		{adminFailureThreshold - 1, 0},
+		{adminFailureThreshold, time.Minute},
+		{adminFailureThreshold + 1, 2 * time.Minute},
+		{adminFailureThreshold + 3, 8 * time.Minute},
+		{adminFailureThreshold + 20, adminLockoutMax},
+	}
+
+	for _, tt := range tests {
+		if got := adminLockoutDuration(tt.failures); got != tt.want {
+			t.Errorf("adminLockoutDuration(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestAdminAuthGuardLockout(t *testing.T) {
+	cacheClient, cleanup := setupLimiterCache(t)
+	defer cleanup()
+
+	guard := newAdminAuthGuard(cacheClient)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	now := time.Now()
+
+	for i := 1; i < adminFailureThreshold; i++ {
+		if lockout, err := guard.recordFailure(ctx, "10.0.0.1", now); err != nil || lockout != 0 {
+			t.Fatalf("failure %d: lockout = %v, err = %v", i, lockout, err)
+		}
+	}
+	if until, _ := guard.lockedUntil(ctx, "10.0.0.1", now); !until.IsZero() {
+		t.Fatal("locked out below the threshold")
+	}
+
+	lockout, err := guard.recordFailure(ctx, "10.0.0.1", now)
+	if err != nil || lockout != adminLockoutBase {
+		t.Fatalf("lockout = %v, err = %v", lockout, err)
+	}
+	if until, _ := guard.lockedUntil(ctx, "10.0.0.1", now); until.IsZero() {
+		t.Error("not locked out at the threshold")
+	}
+	if until, _ := guard.lockedUntil(ctx, "10.0.0.2", now); !until.IsZero() {
+		t.Error("lockout leaked to another address")
+	}
+
+	// A success resets the failure count but not an active lockout
+	guard.recordSuccess(ctx, "10.0.0.1")
+	if lockout, _ := guard.recordFailure(ctx, "10.0.0.1", now); lockout != 0 {
+		t.Errorf("failure after success locked out for %v", lockout)
+	}
+}
+
+func TestAdminAuthGuardRateLimit(t *testing.T) {
+	cacheClient, cleanup := setupLimiterCache(t)
+	defer cleanup()
+
+	guard := newAdminAuthGuard(cacheClient)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	now := time.Now()
+
+	for i := 0; i < adminRequestsPerMinute; i++ {
+		if allowed, err := guard.allow(ctx, "10.0.0.1", now); err != nil || !allowed {
+			t.Fatalf("request %d rejected: %v", i, err)
+		}
+	}
+	if allowed, _ := guard.allow(ctx, "10.0.0.1", now); allowed {
+		t.Error("request over the limit allowed")
+	}
+	if allowed, _ := guard.allow(ctx, "10.0.0.2", now); !allowed {
+		t.Error("limit applied to another address")
+	}
+}
+
+func TestAdminAuthIgnoresSpoofedForwardedFor(t *testing.T) {
+	cacheClient, cleanup := setupLimiterCache(t)
+	defer cleanup()
+
+	g := &Gateway{cache: cacheClient, logger: zap.NewNop(), adminGuard: newAdminAuthGuard(cacheClient), adminToken: "secret"}
+	handler := g.realIP(g.adminAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	send := func(peer, forwardedFor, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin/nodes", nil)
+		req.RemoteAddr = peer + ":51234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	const attacker, admin = "203.0.113.7", "198.51.100.9"
+	for i := 0; i < adminFailureThreshold; i++ {
+		if _, err := g.adminGuard.recordFailure(ctx, attacker, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Rotating X-Forwarded-For doesn't escape the lockout
+	if code := send(attacker, "192.0.2.1", "secret"); code != http.StatusTooManyRequests {
+		t.Errorf("locked out peer with a spoofed X-Forwarded-For got %d, want 429", code)
+	}
+
+	// Another client claiming the locked address isn't locked out, and its
+	// success doesn't clear the attacker's failures
+	if code := send(admin, attacker, "secret"); code != http.StatusOK {
+		t.Errorf("client claiming a locked address got %d, want 200", code)
+	}
+	if lockout, _ := g.adminGuard.recordFailure(ctx, attacker, time.Now()); lockout != 2*adminLockoutBase {
+		t.Errorf("attacker's next failure locked out for %v, want the failure count kept", lockout)
+	}
+
+	// Nor can a client lock out the address it claims
+	for i := 0; i < adminFailureThreshold; i++ {
+		g.adminGuard.recordFailure(ctx, admin, time.Now())
+	}
+	if code := send("192.0.2.50", admin, "secret"); code != http.StatusOK {
+		t.Errorf("lockout moved to another peer: got %d, want 200", code)
+	}
+}
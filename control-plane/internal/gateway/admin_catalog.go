@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"go.uber.org/zap"
+)
+
+// CatalogSyncRun is a recorded catalog sync run
+type CatalogSyncRun struct {
+	ID                    string     `json:"id"`
+	Trigger               string     `json:"trigger"`
+	Status                string     `json:"status"`
+	InstanceTypesUpserted int        `json:"instance_types_upserted"`
+	AvailabilityUpserted  int        `json:"availability_upserted"`
+	InstanceTypesMissing  int        `json:"instance_types_missing"`
+	Error                 *string    `json:"error,omitempty"`
+	StartedAt             time.Time  `json:"started_at"`
+	FinishedAt            *time.Time `json:"finished_at,omitempty"`
+}
+
+// handleTriggerCatalogSync starts an instance type / region availability sync
+// Admin API - POST /admin/catalog/sync
+func (g *Gateway) handleTriggerCatalogSync(w http.ResponseWriter, r *http.Request) {
+	if g.CatalogSyncer == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "catalog sync not configured")
+		return
+	}
+
+	if err := g.CatalogSyncer.TriggerSync(r.Context()); err != nil {
+		if errors.Is(err, orchestrator.ErrCatalogSyncInProgress) {
+			g.writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		g.logger.Error("failed to trigger catalog sync", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to trigger catalog sync")
+		return
+	}
+
+	g.logger.Info("catalog sync triggered manually")
+
+	g.writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"status":  "started",
+		"message": "catalog sync started; see GET /admin/catalog/sync/runs for progress",
+	})
+}
+
+// handleListCatalogSyncRuns lists recent catalog sync runs
+// Admin API - GET /admin/catalog/sync/runs
+func (g *Gateway) handleListCatalogSyncRuns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, trigger, status, instance_types_upserted, availability_upserted,
+		       instance_types_missing, error, started_at, finished_at
+		FROM catalog_sync_runs
+		ORDER BY started_at DESC
+		LIMIT 20
+	`)
+	if err != nil {
+		g.logger.Error("failed to list catalog sync runs", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list catalog sync runs")
+		return
+	}
+	defer rows.Close()
+
+	runs := []CatalogSyncRun{}
+	for rows.Next() {
+		var run CatalogSyncRun
+		if err := rows.Scan(&run.ID, &run.Trigger, &run.Status, &run.InstanceTypesUpserted,
+			&run.AvailabilityUpserted, &run.InstanceTypesMissing, &run.Error,
+			&run.StartedAt, &run.FinishedAt); err != nil {
+			g.logger.Error("failed to scan catalog sync run", zap.Error(err))
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": runs,
+	})
+}
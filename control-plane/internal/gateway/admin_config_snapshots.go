@@ -0,0 +1,678 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/r2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Platform configuration snapshots.
+//
+// A snapshot captures the platform's configuration tables as JSON rows in a
+// versioned artifact stored in R2. Restoring upserts the rows by their
+// natural keys (model name, region code, ...) rather than IDs, so a
+// snapshot can be restored into another environment whose rows have
+// different IDs. Environment-specific columns such as IDs, timestamps and
+// tenant links are left out; tenant-owned deployments and launch profiles
+// are not captured. Restores never delete rows missing from the snapshot.
+// Columns the target's schema lacks are skipped and reported, so snapshots
+// survive schema drift between environments.
+const (
+	// configSnapshotFormat is the artifact format version
+	configSnapshotFormat = 1
+	// configSnapshotPrefix is where artifacts are stored in R2
+	configSnapshotPrefix = "config-snapshots/"
+)
+
+// configTable describes how a configuration table is captured and restored
+type configTable struct {
+	Name string
+	// Key is the natural key restores upsert on
+	Key []string
+	// KeyWhere is the predicate of a partial unique index on Key
+	KeyWhere string
+	// Omit lists environment-specific columns left out of snapshots
+	Omit []string
+	// Where selects the rows captured, from the table aliased t
+	Where string
+	// InsertOnly columns are set on new rows but never overwritten
+	InsertOnly []string
+}
+
+// configSnapshotTables are restored in order, so referenced rows come first
+var configSnapshotTables = []configTable{
+	{Name: "regions", Key: []string{"code"}, Omit: []string{"id", "created_at", "updated_at"}},
+	{Name: "instance_types", Key: []string{"provider", "instance_type"},
+		Omit: []string{"id", "created_at", "updated_at", "last_synced_at", "missing_since"}},
+	// Rows name their instance type instead of its serial ID; see
+	// resolveInstanceTypes
+	{Name: "region_instance_availability", Key: []string{"region_code", "instance_type_id"},
+		Omit: []string{"id", "instance_type_id", "created_at", "updated_at", "last_synced_at"}},
+	{Name: "models", Key: []string{"name"}, Omit: []string{"id", "created_at", "updated_at", "license_id"}},
+	{Name: "model_aliases", Key: []string{"alias"}, Omit: []string{"id", "created_at", "updated_at"}},
+	{Name: "deployments", Key: []string{"name"},
+		Omit:       []string{"id", "created_at", "updated_at", "current_replicas", "tenant_id"},
+		Where:      "t.tenant_id IS NULL AND t.status <> 'deleted'",
+		InsertOnly: []string{"status"}},
+	{Name: "launch_profiles", Key: []string{"name"}, KeyWhere: "tenant_id IS NULL",
+		Omit:  []string{"id", "tenant_id", "created_at", "updated_at"},
+		Where: "t.tenant_id IS NULL"},
+	{Name: "routing_overrides", Key: []string{"endpoint"}, Omit: []string{"updated_at"}},
+	{Name: "region_egress_routes", Key: []string{"provider", "source_region", "dest_region"},
+		Omit: []string{"updated_by", "updated_at"}},
+	{Name: "routing_egress_weights", Key: []string{"plan"}, Omit: []string{"updated_by", "updated_at"}},
+	{Name: "feature_flags", Key: []string{"key"}, Omit: []string{"created_at", "updated_at"}},
+}
+
+func configTableByName(name string) (configTable, bool) {
+	for _, table := range configSnapshotTables {
+		if table.Name == name {
+			return table, true
+		}
+	}
+	return configTable{}, false
+}
+
+// ConfigSnapshotArtifact is the snapshot stored in R2
+type ConfigSnapshotArtifact struct {
+	FormatVersion int                                 `json:"format_version"`
+	ID            uuid.UUID                           `json:"id"`
+	Label         *string                             `json:"label,omitempty"`
+	CreatedAt     time.Time                           `json:"created_at"`
+	CreatedBy     *string                             `json:"created_by,omitempty"`
+	Tables        map[string][]map[string]interface{} `json:"tables"`
+}
+
+// ConfigSnapshot is a stored snapshot's metadata
+type ConfigSnapshot struct {
+	ID            uuid.UUID      `json:"id"`
+	Label         *string        `json:"label,omitempty"`
+	FormatVersion int            `json:"format_version"`
+	ObjectKey     string         `json:"object_key"`
+	SizeBytes     int64          `json:"size_bytes"`
+	SHA256        string         `json:"sha256"`
+	RowCounts     map[string]int `json:"row_counts"`
+	CreatedBy     *string        `json:"created_by,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// ConfigRestoreRequest restores a snapshot by ID (from this environment),
+// by object key (from a bucket shared with another environment) or inline
+// (a downloaded artifact)
+type ConfigRestoreRequest struct {
+	SnapshotID *uuid.UUID              `json:"snapshot_id,omitempty"`
+	ObjectKey  string                  `json:"object_key,omitempty"`
+	Snapshot   *ConfigSnapshotArtifact `json:"snapshot,omitempty"`
+	// Tables restricts the restore to some tables
+	Tables []string `json:"tables,omitempty"`
+	DryRun bool     `json:"dry_run"`
+	// ActivateDeployments creates new deployments active instead of paused,
+	// which launches nodes for them
+	ActivateDeployments bool `json:"activate_deployments"`
+}
+
+// ConfigTableRestore is what a restore did to one table
+type ConfigTableRestore struct {
+	Inserted       int      `json:"inserted"`
+	Updated        int      `json:"updated"`
+	Skipped        int      `json:"skipped,omitempty"`
+	SkippedColumns []string `json:"skipped_columns,omitempty"`
+}
+
+// HandleCreateConfigSnapshot handles POST /api/v1/admin/config-snapshots
+func (g *Gateway) HandleCreateConfigSnapshot(w http.ResponseWriter, r *http.Request) {
+	if g.ConfigSnapshots == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "configuration snapshots are not configured")
+		return
+	}
+	ctx := r.Context()
+
+	var req struct {
+		Label *string `json:"label"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			g.writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	if req.Label != nil && len(*req.Label) > 255 {
+		g.writeError(w, http.StatusBadRequest, "label must be at most 255 characters")
+		return
+	}
+
+	artifact := ConfigSnapshotArtifact{
+		FormatVersion: configSnapshotFormat,
+		ID:            uuid.New(),
+		Label:         req.Label,
+		CreatedAt:     time.Now().UTC(),
+		CreatedBy:     adminActor(ctx),
+		Tables:        make(map[string][]map[string]interface{}, len(configSnapshotTables)),
+	}
+	snapshot := ConfigSnapshot{
+		ID:            artifact.ID,
+		Label:         artifact.Label,
+		FormatVersion: configSnapshotFormat,
+		ObjectKey:     configSnapshotPrefix + artifact.ID.String() + ".json",
+		RowCounts:     make(map[string]int, len(configSnapshotTables)),
+		CreatedBy:     artifact.CreatedBy,
+		CreatedAt:     artifact.CreatedAt,
+	}
+	for _, table := range configSnapshotTables {
+		rows, err := g.captureConfigTable(ctx, table)
+		if err != nil {
+			g.logger.Error("failed to capture configuration table", zap.Error(err), zap.String("table", table.Name))
+			g.writeError(w, http.StatusInternalServerError, "failed to capture configuration")
+			return
+		}
+		artifact.Tables[table.Name] = rows
+		snapshot.RowCounts[table.Name] = len(rows)
+	}
+
+	data, err := json.Marshal(artifact)
+	if err != nil {
+		g.logger.Error("failed to encode configuration snapshot", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to encode configuration snapshot")
+		return
+	}
+	sum := sha256.Sum256(data)
+	snapshot.SHA256 = hex.EncodeToString(sum[:])
+	snapshot.SizeBytes = int64(len(data))
+
+	if err := r2.NewObjects(g.ConfigSnapshots).Put(ctx, snapshot.ObjectKey, data, "application/json"); err != nil {
+		g.logger.Error("failed to store configuration snapshot", zap.Error(err))
+		g.writeError(w, http.StatusBadGateway, "failed to store configuration snapshot")
+		return
+	}
+	_, err = g.db.Pool.Exec(ctx, `
+		INSERT INTO config_snapshots (id, label, format_version, object_key, size_bytes, sha256, row_counts, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, snapshot.ID, snapshot.Label, snapshot.FormatVersion, snapshot.ObjectKey, snapshot.SizeBytes,
+		snapshot.SHA256, snapshot.RowCounts, snapshot.CreatedBy, snapshot.CreatedAt)
+	if err != nil {
+		g.logger.Error("failed to record configuration snapshot", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to record configuration snapshot")
+		return
+	}
+
+	g.logger.Info("configuration snapshot created",
+		zap.String("snapshot_id", snapshot.ID.String()),
+		zap.Int64("size_bytes", snapshot.SizeBytes),
+	)
+	g.writeJSON(w, http.StatusCreated, snapshot)
+}
+
+// captureConfigTable reads a table's rows as JSON objects without its
+// environment-specific columns, ordered by key so artifacts diff cleanly
+func (g *Gateway) captureConfigTable(ctx context.Context, table configTable) ([]map[string]interface{}, error) {
+	row := "to_jsonb(t) - $1::text[]"
+	from := table.Name + " t"
+	if table.Name == "region_instance_availability" {
+		row += " || jsonb_build_object('instance_type', jsonb_build_object('provider', i.provider, 'instance_type', i.instance_type))"
+		from += " JOIN instance_types i ON i.id = t.instance_type_id"
+	}
+	orderBy := make([]string, len(table.Key))
+	for i, column := range table.Key {
+		orderBy[i] = "t." + column
+	}
+	if table.Name == "region_instance_availability" {
+		orderBy = []string{"t.region_code", "i.provider", "i.instance_type"}
+	}
+	where := ""
+	if table.Where != "" {
+		where = " WHERE " + table.Where
+	}
+
+	var rows []map[string]interface{}
+	err := g.db.Pool.QueryRow(ctx, fmt.Sprintf(
+		`SELECT COALESCE(jsonb_agg(%s ORDER BY %s), '[]') FROM %s%s`,
+		row, strings.Join(orderBy, ", "), from, where,
+	), table.Omit).Scan(&rows)
+	return rows, err
+}
+
+// HandleListConfigSnapshots handles GET /api/v1/admin/config-snapshots
+func (g *Gateway) HandleListConfigSnapshots(w http.ResponseWriter, r *http.Request) {
+	rows, err := g.db.Pool.Query(r.Context(), `
+		SELECT id, label, format_version, object_key, size_bytes, sha256, row_counts, created_by, created_at
+		FROM config_snapshots
+		ORDER BY created_at DESC
+		LIMIT 100
+	`)
+	if err != nil {
+		g.logger.Error("failed to list configuration snapshots", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list configuration snapshots")
+		return
+	}
+	defer rows.Close()
+
+	snapshots := []ConfigSnapshot{}
+	for rows.Next() {
+		var s ConfigSnapshot
+		if err := rows.Scan(&s.ID, &s.Label, &s.FormatVersion, &s.ObjectKey, &s.SizeBytes, &s.SHA256, &s.RowCounts, &s.CreatedBy, &s.CreatedAt); err != nil {
+			g.logger.Error("failed to scan configuration snapshot", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to list configuration snapshots")
+			return
+		}
+		snapshots = append(snapshots, s)
+	}
+	if err := rows.Err(); err != nil {
+		g.logger.Error("failed to list configuration snapshots", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list configuration snapshots")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{"data": snapshots})
+}
+
+// loadConfigSnapshot returns a stored snapshot's metadata
+func (g *Gateway) loadConfigSnapshot(ctx context.Context, id uuid.UUID) (*ConfigSnapshot, error) {
+	var s ConfigSnapshot
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT id, label, format_version, object_key, size_bytes, sha256, row_counts, created_by, created_at
+		FROM config_snapshots WHERE id = $1
+	`, id).Scan(&s.ID, &s.Label, &s.FormatVersion, &s.ObjectKey, &s.SizeBytes, &s.SHA256, &s.RowCounts, &s.CreatedBy, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// HandleDownloadConfigSnapshot handles
+// GET /api/v1/admin/config-snapshots/{id}/download, returning the artifact
+// for restoring into an environment that can't read this bucket
+func (g *Gateway) HandleDownloadConfigSnapshot(w http.ResponseWriter, r *http.Request) {
+	if g.ConfigSnapshots == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "configuration snapshots are not configured")
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid snapshot id")
+		return
+	}
+	snapshot, err := g.loadConfigSnapshot(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to load configuration snapshot", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load configuration snapshot")
+		return
+	}
+	data, err := r2.NewObjects(g.ConfigSnapshots).Get(r.Context(), snapshot.ObjectKey)
+	if err != nil {
+		g.logger.Error("failed to read configuration snapshot", zap.Error(err), zap.String("object_key", snapshot.ObjectKey))
+		g.writeError(w, http.StatusBadGateway, "failed to read configuration snapshot")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="config-snapshot-%s.json"`, snapshot.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleRestoreConfigSnapshot handles POST /api/v1/admin/config-snapshots/restore.
+// The restore runs in one transaction; a dry run reports what it would do
+// and rolls back.
+func (g *Gateway) HandleRestoreConfigSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req ConfigRestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	tables, err := configRestoreTables(req.Tables)
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	artifact, status, err := g.configRestoreArtifact(ctx, &req)
+	if err != nil {
+		g.writeError(w, status, err.Error())
+		return
+	}
+	if artifact.FormatVersion != configSnapshotFormat {
+		g.writeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported snapshot format_version %d", artifact.FormatVersion))
+		return
+	}
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		g.logger.Error("failed to begin configuration restore", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to restore configuration")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	summary := make(map[string]*ConfigTableRestore, len(tables))
+	for _, table := range tables {
+		rows := artifact.Tables[table.Name]
+		if table.Name == "deployments" && !req.ActivateDeployments {
+			for _, row := range rows {
+				row["status"] = "paused"
+			}
+		}
+		result, err := restoreConfigTable(ctx, tx, table, rows)
+		if err != nil {
+			g.logger.Error("failed to restore configuration table", zap.Error(err), zap.String("table", table.Name))
+			g.writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("failed to restore %s: %v", table.Name, err))
+			return
+		}
+		summary[table.Name] = result
+	}
+
+	if !req.DryRun {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO config_snapshot_restores (snapshot_id, snapshot_label, summary, restored_by)
+			VALUES ($1, $2, $3, $4)
+		`, artifact.ID, artifact.Label, summary, adminActor(ctx))
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			g.logger.Error("failed to commit configuration restore", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to restore configuration")
+			return
+		}
+		g.configRestored(ctx, artifact, summary)
+		g.logger.Info("configuration snapshot restored",
+			zap.String("snapshot_id", artifact.ID.String()),
+			zap.Int("tables", len(summary)),
+		)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"dry_run":     req.DryRun,
+		"snapshot_id": artifact.ID,
+		"tables":      summary,
+	})
+}
+
+// configRestoreTables returns the tables to restore, in restore order
+func configRestoreTables(names []string) ([]configTable, error) {
+	if len(names) == 0 {
+		return configSnapshotTables, nil
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := configTableByName(name); !ok {
+			return nil, fmt.Errorf("unknown configuration table %q", name)
+		}
+		wanted[name] = true
+	}
+	var tables []configTable
+	for _, table := range configSnapshotTables {
+		if wanted[table.Name] {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// configRestoreArtifact loads the snapshot a restore request names
+func (g *Gateway) configRestoreArtifact(ctx context.Context, req *ConfigRestoreRequest) (*ConfigSnapshotArtifact, int, error) {
+	sources := 0
+	for _, set := range []bool{req.SnapshotID != nil, req.ObjectKey != "", req.Snapshot != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, http.StatusBadRequest, errors.New("exactly one of snapshot_id, object_key or snapshot is required")
+	}
+	if req.Snapshot != nil {
+		return req.Snapshot, 0, nil
+	}
+	if g.ConfigSnapshots == nil {
+		return nil, http.StatusServiceUnavailable, errors.New("configuration snapshots are not configured")
+	}
+
+	key, checksum := req.ObjectKey, ""
+	if req.SnapshotID != nil {
+		snapshot, err := g.loadConfigSnapshot(ctx, *req.SnapshotID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, http.StatusNotFound, errors.New("snapshot not found")
+		}
+		if err != nil {
+			g.logger.Error("failed to load configuration snapshot", zap.Error(err))
+			return nil, http.StatusInternalServerError, errors.New("failed to load configuration snapshot")
+		}
+		key, checksum = snapshot.ObjectKey, snapshot.SHA256
+	} else if !strings.HasPrefix(key, configSnapshotPrefix) || strings.Contains(key, "..") {
+		return nil, http.StatusBadRequest, fmt.Errorf("object_key must be under %s", configSnapshotPrefix)
+	}
+
+	data, err := r2.NewObjects(g.ConfigSnapshots).Get(ctx, key)
+	if err != nil {
+		g.logger.Error("failed to read configuration snapshot", zap.Error(err), zap.String("object_key", key))
+		return nil, http.StatusBadGateway, errors.New("failed to read configuration snapshot")
+	}
+	if checksum != "" {
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != checksum {
+			return nil, http.StatusConflict, errors.New("snapshot artifact does not match its recorded checksum")
+		}
+	}
+	var artifact ConfigSnapshotArtifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return nil, http.StatusUnprocessableEntity, errors.New("snapshot artifact is not valid JSON")
+	}
+	return &artifact, 0, nil
+}
+
+// restoreConfigTable upserts a table's snapshot rows by natural key
+func restoreConfigTable(ctx context.Context, tx pgx.Tx, table configTable, rows []map[string]interface{}) (*ConfigTableRestore, error) {
+	result := &ConfigTableRestore{}
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	if table.Name == "region_instance_availability" {
+		var err error
+		if rows, result.Skipped, err = resolveInstanceTypes(ctx, tx, rows); err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return result, nil
+		}
+	}
+
+	var columns []string
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(array_agg(column_name::text ORDER BY ordinal_position), '{}')
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+	`, table.Name).Scan(&columns)
+	if err != nil {
+		return nil, err
+	}
+	var insert []string
+	insert, result.SkippedColumns = configRestoreColumns(table, columns, rows)
+	for _, key := range table.Key {
+		if !slices.Contains(insert, key) {
+			return nil, fmt.Errorf("snapshot rows lack key column %s", key)
+		}
+	}
+
+	payload, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	queryRows, err := tx.Query(ctx, configUpsertSQL(table, insert, slices.Contains(columns, "updated_at")), payload)
+	if err != nil {
+		return nil, err
+	}
+	defer queryRows.Close()
+	for queryRows.Next() {
+		var inserted bool
+		if err := queryRows.Scan(&inserted); err != nil {
+			return nil, err
+		}
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+	return result, queryRows.Err()
+}
+
+// configRestoreColumns returns the snapshot columns the target table has,
+// and those it lacks
+func configRestoreColumns(table configTable, targetColumns []string, rows []map[string]interface{}) (insert, skipped []string) {
+	present := map[string]bool{}
+	for _, row := range rows {
+		for column := range row {
+			present[column] = true
+		}
+	}
+	for _, column := range table.Key {
+		present[column] = true
+	}
+	for _, column := range targetColumns {
+		if present[column] && (!slices.Contains(table.Omit, column) || slices.Contains(table.Key, column)) {
+			insert = append(insert, column)
+		}
+		delete(present, column)
+	}
+	for column := range present {
+		if !slices.Contains(table.Omit, column) {
+			skipped = append(skipped, column)
+		}
+	}
+	sort.Strings(skipped)
+	return insert, skipped
+}
+
+// configUpsertSQL builds the upsert of snapshot rows passed as a JSON
+// array in $1. It returns whether each row was inserted.
+func configUpsertSQL(table configTable, columns []string, touchUpdatedAt bool) string {
+	quoted := make([]string, len(columns))
+	var set []string
+	for i, column := range columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+		if !slices.Contains(table.Key, column) && !slices.Contains(table.InsertOnly, column) {
+			set = append(set, fmt.Sprintf("%s = EXCLUDED.%s", quoted[i], quoted[i]))
+		}
+	}
+	if touchUpdatedAt {
+		set = append(set, "updated_at = NOW()")
+	}
+	keys := make([]string, len(table.Key))
+	for i, column := range table.Key {
+		keys[i] = pgx.Identifier{column}.Sanitize()
+	}
+	conflict := "(" + strings.Join(keys, ", ") + ")"
+	if table.KeyWhere != "" {
+		conflict += " WHERE " + table.KeyWhere
+	}
+	// Touching an unchanged row keeps RETURNING reporting it as updated
+	if len(set) == 0 {
+		set = append(set, fmt.Sprintf("%s = EXCLUDED.%s", keys[0], keys[0]))
+	}
+
+	columnList := strings.Join(quoted, ", ")
+	return fmt.Sprintf(`INSERT INTO %s (%s)
+		SELECT %s FROM jsonb_populate_recordset(NULL::%s, $1::jsonb)
+		ON CONFLICT %s DO UPDATE SET %s
+		RETURNING (xmax = 0)`,
+		table.Name, columnList, columnList, table.Name, conflict, strings.Join(set, ", "))
+}
+
+// resolveInstanceTypes replaces the instance type named in availability
+// rows with its ID in this environment, dropping rows whose instance type
+// doesn't exist here
+func resolveInstanceTypes(ctx context.Context, q database.Querier, rows []map[string]interface{}) ([]map[string]interface{}, int, error) {
+	ids := map[[2]string]int{}
+	typeRows, err := q.Query(ctx, `SELECT id, provider, instance_type FROM instance_types`)
+	if err != nil {
+		return nil, 0, err
+	}
+	for typeRows.Next() {
+		var id int
+		var provider, instanceType string
+		if err := typeRows.Scan(&id, &provider, &instanceType); err != nil {
+			typeRows.Close()
+			return nil, 0, err
+		}
+		ids[[2]string{provider, instanceType}] = id
+	}
+	typeRows.Close()
+	if err := typeRows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	resolved := make([]map[string]interface{}, 0, len(rows))
+	skipped := 0
+	for _, row := range rows {
+		ref, _ := row["instance_type"].(map[string]interface{})
+		provider, _ := ref["provider"].(string)
+		instanceType, _ := ref["instance_type"].(string)
+		id, ok := ids[[2]string{provider, instanceType}]
+		if !ok {
+			skipped++
+			continue
+		}
+		row = copyConfigRow(row)
+		delete(row, "instance_type")
+		row["instance_type_id"] = id
+		resolved = append(resolved, row)
+	}
+	return resolved, skipped, nil
+}
+
+func copyConfigRow(row map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		copied[k] = v
+	}
+	return copied
+}
+
+// configRestored refreshes the caches of restored configuration
+func (g *Gateway) configRestored(ctx context.Context, artifact *ConfigSnapshotArtifact, summary map[string]*ConfigTableRestore) {
+	if _, ok := summary["models"]; ok {
+		for _, row := range artifact.Tables["models"] {
+			if name, ok := row["name"].(string); ok {
+				g.modelCapabilities.invalidate(name)
+			}
+		}
+	}
+	if summary["models"] != nil || summary["model_aliases"] != nil {
+		g.publishCatalogChanged(ctx, "", "restored")
+	}
+	if summary["routing_overrides"] != nil {
+		if err := g.LoadBalancer.LoadOverrides(ctx); err != nil {
+			g.logger.Warn("failed to reload routing overrides", zap.Error(err))
+		}
+	}
+	if summary["region_egress_routes"] != nil || summary["routing_egress_weights"] != nil {
+		g.reloadEgressRouting(ctx)
+	}
+	if summary["feature_flags"] != nil {
+		for _, row := range artifact.Tables["feature_flags"] {
+			if key, ok := row["key"].(string); ok {
+				g.features.Invalidate(ctx, key)
+			}
+		}
+	}
+}
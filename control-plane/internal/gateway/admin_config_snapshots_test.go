@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigRestoreTables(t *testing.T) {
+	all, err := configRestoreTables(nil)
+	if err != nil || len(all) != len(configSnapshotTables) {
+		t.Fatalf("all tables = %d, %v", len(all), err)
+	}
+
+	// Restore order is kept whatever order tables are asked for in
+	tables, err := configRestoreTables([]string{"feature_flags", "models"})
+	if err != nil || len(tables) != 2 || tables[0].Name != "models" || tables[1].Name != "feature_flags" {
+		t.Errorf("tables = %+v, %v", tables, err)
+	}
+
+	if _, err := configRestoreTables([]string{"tenants"}); err == nil {
+		t.Error("tenants accepted as a configuration table")
+	}
+}
+
+func TestConfigRestoreColumns(t *testing.T) {
+	table, _ := configTableByName("models")
+	target := []string{"id", "name", "family", "metadata", "created_at", "updated_at"}
+	rows := []map[string]interface{}{
+		{"name": "llama", "family": "Llama", "metadata": map[string]interface{}{}},
+		{"name": "qwen", "family": "Qwen", "supports_audio": true, "id": "ignored"},
+	}
+
+	insert, skipped := configRestoreColumns(table, target, rows)
+	if !reflect.DeepEqual(insert, []string{"name", "family", "metadata"}) {
+		t.Errorf("insert = %v", insert)
+	}
+	if !reflect.DeepEqual(skipped, []string{"supports_audio"}) {
+		t.Errorf("skipped = %v", skipped)
+	}
+}
+
+func TestConfigUpsertSQL(t *testing.T) {
+	deployments, _ := configTableByName("deployments")
+	sql := configUpsertSQL(deployments, []string{"name", "model_name", "status"}, true)
+	for _, want := range []string{
+		`INSERT INTO deployments ("name", "model_name", "status")`,
+		`jsonb_populate_recordset(NULL::deployments, $1::jsonb)`,
+		`ON CONFLICT ("name") DO UPDATE SET "model_name" = EXCLUDED."model_name", updated_at = NOW()`,
+		`RETURNING (xmax = 0)`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("upsert lacks %q:\n%s", want, sql)
+		}
+	}
+
+	profiles, _ := configTableByName("launch_profiles")
+	if sql := configUpsertSQL(profiles, []string{"name"}, false); !strings.Contains(sql, `ON CONFLICT ("name") WHERE tenant_id IS NULL DO UPDATE SET "name" = EXCLUDED."name"`) {
+		t.Errorf("partial key upsert:\n%s", sql)
+	}
+}
@@ -249,6 +249,8 @@ func (g *Gateway) handleUpdateCredential(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	g.syncCredentialProfile(ctx, credentialID)
+
 	g.logger.Info("credential updated",
 		zap.String("credential_id", credentialID.String()),
 		zap.String("tenant_id", tenantID.String()),
@@ -296,6 +298,8 @@ func (g *Gateway) handleDeleteCredential(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	g.deleteCredentialProfile(ctx, credentialID)
+
 	g.logger.Info("credential deleted",
 		zap.String("credential_id", credentialID.String()),
 		zap.String("tenant_id", tenantID.String()),
@@ -3,10 +3,14 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/crosslogic/control-plane/internal/nodes"
 	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/crosslogic/control-plane/internal/repository"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -26,11 +30,19 @@ func (g *Gateway) handleCreateDeployment(w http.ResponseWriter, r *http.Request)
 		InstanceType           string `json:"instance_type"`
 		UseSpot                bool   `json:"use_spot"`
 		LoadBalancingStrategy  string `json:"load_balancing_strategy"` // round-robin, least-latency, least-connections
+		// WarmStandby keeps an unrouted node ready to replace a failed one
+		WarmStandby            bool   `json:"warm_standby"`
+		// KeepStoppedNodes stops up to this many nodes at scale down for
+		// faster restarts instead of terminating them
+		KeepStoppedNodes       int    `json:"keep_stopped_nodes"`
+		// VLLMVersion and TorchVersion pin the runtime; empty uses the platform default
+		VLLMVersion            string `json:"vllm_version"`
+		TorchVersion           string `json:"torch_version"`
 		AutoScaling            *struct {
 			Enabled          bool `json:"enabled"`
 			MinNodes         int  `json:"min_nodes"`
 			MaxNodes         int  `json:"max_nodes"`
-			TargetLatencyMs  int  `json:"target_latency_ms"`
+			autoscaleTargets
 		} `json:"auto_scaling"`
 	}
 
@@ -49,10 +61,24 @@ func (g *Gateway) handleCreateDeployment(w http.ResponseWriter, r *http.Request)
 		req.NodeCount = 1
 	}
 
+	if req.KeepStoppedNodes < 0 {
+		g.writeError(w, http.StatusBadRequest, "keep_stopped_nodes must not be negative")
+		return
+	}
+
 	if req.LoadBalancingStrategy == "" {
 		req.LoadBalancingStrategy = "least-latency"
 	}
 
+	var targets autoscaleTargets
+	if req.AutoScaling != nil {
+		targets = req.AutoScaling.autoscaleTargets
+	}
+	if err := targets.validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Verify model exists
 	var modelID uuid.UUID
 	err := g.db.Pool.QueryRow(ctx, `
@@ -68,6 +94,12 @@ func (g *Gateway) handleCreateDeployment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Refuse runtimes that can't serve the model before anything launches
+	if err := g.checkDeploymentRuntime(ctx, req.ModelName, req.VLLMVersion, req.TorchVersion); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Create deployment record
 	deploymentID := uuid.New()
 	minReplicas := req.NodeCount
@@ -86,14 +118,30 @@ func (g *Gateway) handleCreateDeployment(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	_, err = g.db.Pool.Exec(ctx, `
+	// The deployment and its launch jobs commit together, so a crash can't
+	// leave a deployment that never launches
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		g.logger.Error("failed to begin transaction", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create deployment")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
 		INSERT INTO deployments (
 			id, name, model_id, min_replicas, max_replicas,
 			current_replicas, strategy, provider, region, gpu_type,
-			auto_scaling_enabled, status, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, 0, $6, $7, $8, $9, $10, 'launching', NOW(), NOW())
+			auto_scaling_enabled, warm_standby, vllm_version, torch_version,
+			keep_stopped_nodes, autoscale_target_queue_depth, autoscale_target_p95_latency_ms,
+			autoscale_scale_up_cooldown_seconds, autoscale_scale_down_cooldown_seconds,
+			status, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, 0, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), NULLIF($13, ''), $14,
+			NULLIF($15, 0), NULLIF($16, 0), NULLIF($17, 0), NULLIF($18, 0), 'launching', NOW(), NOW())
 	`, deploymentID, req.ModelName+"-deployment", modelID, minReplicas, maxReplicas,
-		req.LoadBalancingStrategy, req.Provider, req.Region, req.InstanceType, autoScalingEnabled)
+		req.LoadBalancingStrategy, req.Provider, req.Region, req.InstanceType, autoScalingEnabled, req.WarmStandby,
+		req.VLLMVersion, req.TorchVersion, req.KeepStoppedNodes,
+		targets.TargetQueueDepth, targets.TargetLatencyMs, targets.ScaleUpCooldownSeconds, targets.ScaleDownCooldownSeconds)
 
 	if err != nil {
 		g.logger.Error("failed to create deployment record",
@@ -104,86 +152,54 @@ func (g *Gateway) handleCreateDeployment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	g.logger.Info("deployment created, launching nodes",
-		zap.String("deployment_id", deploymentID.String()),
-		zap.String("model", req.ModelName),
-		zap.Int("node_count", req.NodeCount),
-	)
-
-	// Launch nodes asynchronously
-	go g.launchDeploymentNodes(context.Background(), deploymentID, req.ModelName, req.NodeCount,
-		req.Provider, req.Region, req.InstanceType, req.UseSpot)
-
-	g.writeJSON(w, http.StatusAccepted, map[string]interface{}{
-		"deployment_id":   deploymentID,
-		"status":          "launching",
-		"message":         "Deployment initiated. Launching " + string(rune(req.NodeCount)) + " nodes...",
-		"estimated_time":  "5-8 minutes",
-		"endpoint_url":    "https://api.crosslogic.ai/inference/" + req.ModelName,
-	})
-}
-
-// launchDeploymentNodes launches nodes for a deployment in the background
-func (g *Gateway) launchDeploymentNodes(ctx context.Context, deploymentID uuid.UUID,
-	modelName string, nodeCount int, provider, region, instanceType string, useSpot bool) {
-
-	ctx, cancel := context.WithTimeout(ctx, 20*time.Minute)
-	defer cancel()
-
-	successCount := 0
-	for i := 0; i < nodeCount; i++ {
-		nodeID := uuid.New().String()
+	// Spot clusters can't be stopped, so deployments that keep stopped
+	// nodes launch on-demand
+	useSpot := req.UseSpot && req.KeepStoppedNodes == 0
 
+	// Each node launches in its own job so failures retry independently
+	for i := 0; i < req.NodeCount; i++ {
 		nodeConfig := orchestrator.NodeConfig{
-			NodeID:   nodeID,
-			Provider: provider,
-			Region:   region,
-			Model:    modelName,
-			GPU:      instanceType,
-			UseSpot:  useSpot,
-			DiskSize: 256,
+			NodeID:       uuid.New().String(),
+			Provider:     req.Provider,
+			Region:       req.Region,
+			Model:        req.ModelName,
+			GPU:          req.InstanceType,
+			UseSpot:      useSpot,
+			DiskSize:     256,
+			DeploymentID: deploymentID.String(),
+			VLLMVersion:  req.VLLMVersion,
+			TorchVersion: req.TorchVersion,
+			RequestedAt:  time.Now(),
 		}
-
-		clusterName, err := g.orchestrator.LaunchNode(ctx, nodeConfig)
-		if err != nil {
-			g.logger.Error("failed to launch node for deployment",
+		if _, err := g.jobs.EnqueueTx(ctx, tx, jobLaunchDeployNode, nodeConfig); err != nil {
+			g.logger.Error("failed to queue node launch",
 				zap.Error(err),
 				zap.String("deployment_id", deploymentID.String()),
-				zap.Int("node_index", i),
 			)
-			continue
+			g.writeError(w, http.StatusInternalServerError, "failed to create deployment")
+			return
 		}
-
-		g.logger.Info("node launched for deployment",
-			zap.String("deployment_id", deploymentID.String()),
-			zap.String("cluster_name", clusterName),
-		)
-
-		successCount++
 	}
 
-	// Update deployment status
-	status := "active"
-	if successCount == 0 {
-		status = "failed"
-	} else if successCount < nodeCount {
-		status = "degraded"
+	if err := tx.Commit(ctx); err != nil {
+		g.logger.Error("failed to commit deployment", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create deployment")
+		return
 	}
 
-	_, err := g.db.Pool.Exec(ctx, `
-		UPDATE deployments SET
-			current_replicas = $1,
-			status = $2,
-			updated_at = NOW()
-		WHERE id = $3
-	`, successCount, status, deploymentID)
+	g.logger.Info("deployment created, launching nodes",
+		zap.String("deployment_id", deploymentID.String()),
+		zap.String("model", req.ModelName),
+		zap.Int("node_count", req.NodeCount),
+	)
 
-	if err != nil {
-		g.logger.Error("failed to update deployment status",
-			zap.Error(err),
-			zap.String("deployment_id", deploymentID.String()),
-		)
-	}
+	g.writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"deployment_id":   deploymentID,
+		"status":          "launching",
+		"message":         "Deployment initiated. Launching " + strconv.Itoa(req.NodeCount) + " nodes...",
+		"estimated_time":  "5-8 minutes",
+		"endpoint_url":    "https://api.crosslogic.ai/inference/" + req.ModelName,
+	})
 }
 
 // handleListDeployments lists all model deployments
@@ -194,85 +210,43 @@ func (g *Gateway) handleListDeployments(w http.ResponseWriter, r *http.Request)
 	modelFilter := r.URL.Query().Get("model")
 	statusFilter := r.URL.Query().Get("status")
 
-	query := `
-		SELECT d.id, d.name, m.name as model_name, d.status,
-		       d.current_replicas, d.min_replicas, d.max_replicas,
-		       d.strategy, d.created_at
-		FROM deployments d
-		INNER JOIN models m ON m.id = d.model_id
-		WHERE 1=1
-	`
-
-	args := []interface{}{}
-	if modelFilter != "" {
-		args = append(args, modelFilter)
-		query += " AND m.name = $" + string(rune(len(args)))
-	}
-	if statusFilter != "" {
-		args = append(args, statusFilter)
-		query += " AND d.status = $" + string(rune(len(args)))
-	}
-
-	query += " ORDER BY d.created_at DESC"
-
-	rows, err := g.db.Pool.Query(ctx, query, args...)
+	list, err := g.store.Deployments.List(ctx, repository.DeploymentFilter{
+		ModelName: modelFilter,
+		Status:    statusFilter,
+	})
 	if err != nil {
 		g.logger.Error("failed to query deployments", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to query deployments")
 		return
 	}
-	defer rows.Close()
 
 	var deployments []map[string]interface{}
-	for rows.Next() {
-		var id uuid.UUID
-		var name, modelName, status, strategy string
-		var currentReplicas, minReplicas, maxReplicas int
-		var createdAt time.Time
-
-		if err := rows.Scan(&id, &name, &modelName, &status, &currentReplicas,
-			&minReplicas, &maxReplicas, &strategy, &createdAt); err != nil {
-			g.logger.Warn("failed to scan deployment row", zap.Error(err))
-			continue
-		}
-
+	for _, d := range list {
 		// Get nodes for this deployment
 		var nodes []map[string]interface{}
-		nodeRows, err := g.db.Pool.Query(ctx, `
-			SELECT n.cluster_name, n.status, n.health_score
-			FROM nodes n
-			INNER JOIN models m ON m.id = n.model_id
-			WHERE m.name = $1
-			ORDER BY n.created_at DESC
-			LIMIT $2
-		`, modelName, maxReplicas)
-
-		if err == nil {
-			defer nodeRows.Close()
-			for nodeRows.Next() {
-				var clusterName, nodeStatus string
-				var healthScore float64
-				if err := nodeRows.Scan(&clusterName, &nodeStatus, &healthScore); err == nil {
-					nodes = append(nodes, map[string]interface{}{
-						"cluster_name": clusterName,
-						"status":       nodeStatus,
-						"health_score": healthScore,
-					})
-				}
-			}
+		nodeList, err := g.store.Nodes.ListByModel(ctx, d.ModelName, d.MaxReplicas)
+		if err != nil {
+			g.logger.Warn("failed to query deployment nodes", zap.Error(err))
+		}
+		for _, n := range nodeList {
+			nodes = append(nodes, map[string]interface{}{
+				"cluster_name": n.ClusterName,
+				"status":       n.Status,
+				"health_score": n.HealthScore,
+			})
 		}
 
 		deployments = append(deployments, map[string]interface{}{
-			"id":                        id,
-			"name":                      name,
-			"model_name":                modelName,
-			"status":                    status,
-			"node_count":                currentReplicas,
-			"min_replicas":              minReplicas,
-			"max_replicas":              maxReplicas,
-			"load_balancing_strategy":   strategy,
-			"created_at":                createdAt,
-			"nodes":                     nodes,
+			"id":                      d.ID,
+			"name":                    d.Name,
+			"model_name":              d.ModelName,
+			"status":                  d.Status,
+			"node_count":              d.CurrentReplicas,
+			"min_replicas":            d.MinReplicas,
+			"max_replicas":            d.MaxReplicas,
+			"load_balancing_strategy": d.Strategy,
+			"created_at":              d.CreatedAt,
+			"nodes":                   nodes,
 		})
 	}
 
@@ -294,18 +268,30 @@ func (g *Gateway) handleGetDeployment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var name, modelName, status, strategy, provider, region string
-	var currentReplicas, minReplicas, maxReplicas int
+	var currentReplicas, minReplicas, maxReplicas, keepStoppedNodes int
+	var warmStandby, autoScaling bool
+	var vllmVersion, torchVersion *string
+	var targets autoscaleTargets
+	var lastScaledUpAt, lastScaledDownAt *time.Time
 	var createdAt, updatedAt time.Time
 
 	err = g.db.Pool.QueryRow(ctx, `
 		SELECT d.name, m.name, d.status, d.current_replicas,
 		       d.min_replicas, d.max_replicas, d.strategy,
-		       d.provider, d.region, d.created_at, d.updated_at
+		       d.provider, d.region, d.warm_standby, d.vllm_version, d.torch_version,
+		       d.keep_stopped_nodes, d.auto_scaling_enabled,
+		       COALESCE(d.autoscale_target_queue_depth, 0), COALESCE(d.autoscale_target_p95_latency_ms, 0),
+		       COALESCE(d.autoscale_scale_up_cooldown_seconds, 0), COALESCE(d.autoscale_scale_down_cooldown_seconds, 0),
+		       d.last_scaled_up_at, d.last_scaled_down_at, d.created_at, d.updated_at
 		FROM deployments d
 		INNER JOIN models m ON m.id = d.model_id
 		WHERE d.id = $1
 	`, deploymentID).Scan(&name, &modelName, &status, &currentReplicas,
-		&minReplicas, &maxReplicas, &strategy, &provider, &region, &createdAt, &updatedAt)
+		&minReplicas, &maxReplicas, &strategy, &provider, &region, &warmStandby,
+		&vllmVersion, &torchVersion, &keepStoppedNodes, &autoScaling,
+		&targets.TargetQueueDepth, &targets.TargetLatencyMs,
+		&targets.ScaleUpCooldownSeconds, &targets.ScaleDownCooldownSeconds,
+		&lastScaledUpAt, &lastScaledDownAt, &createdAt, &updatedAt)
 
 	if err != nil {
 		g.logger.Error("deployment not found",
@@ -319,7 +305,7 @@ func (g *Gateway) handleGetDeployment(w http.ResponseWriter, r *http.Request) {
 	// Get nodes
 	nodeRows, err := g.db.Pool.Query(ctx, `
 		SELECT n.id, n.cluster_name, n.status, n.health_score,
-		       n.endpoint_url, n.created_at
+		       n.endpoint_url, n.standby, n.vllm_version, n.torch_version, n.created_at
 		FROM nodes n
 		INNER JOIN models m ON m.id = n.model_id
 		WHERE m.name = $1
@@ -333,16 +319,21 @@ func (g *Gateway) handleGetDeployment(w http.ResponseWriter, r *http.Request) {
 			var nodeID uuid.UUID
 			var clusterName, nodeStatus, endpointURL string
 			var healthScore float64
+			var standby bool
+			var nodeVLLM, nodeTorch *string
 			var nodeCreatedAt time.Time
 
 			if err := nodeRows.Scan(&nodeID, &clusterName, &nodeStatus, &healthScore,
-				&endpointURL, &nodeCreatedAt); err == nil {
+				&endpointURL, &standby, &nodeVLLM, &nodeTorch, &nodeCreatedAt); err == nil {
 				nodes = append(nodes, map[string]interface{}{
 					"id":            nodeID,
 					"cluster_name":  clusterName,
 					"status":        nodeStatus,
 					"health_score":  healthScore,
 					"endpoint_url":  endpointURL,
+					"standby":       standby,
+					"vllm_version":  nodeVLLM,
+					"torch_version": nodeTorch,
 					"created_at":    nodeCreatedAt,
 				})
 			}
@@ -353,6 +344,7 @@ func (g *Gateway) handleGetDeployment(w http.ResponseWriter, r *http.Request) {
 		"id":                      deploymentID,
 		"name":                    name,
 		"model_name":              modelName,
+		"drift":                   g.deploymentDrift(ctx, deploymentID, modelName),
 		"status":                  status,
 		"node_count":              currentReplicas,
 		"min_replicas":            minReplicas,
@@ -360,12 +352,261 @@ func (g *Gateway) handleGetDeployment(w http.ResponseWriter, r *http.Request) {
 		"load_balancing_strategy": strategy,
 		"provider":                provider,
 		"region":                  region,
+		"warm_standby":            warmStandby,
+		"keep_stopped_nodes":      keepStoppedNodes,
+		"auto_scaling": map[string]interface{}{
+			"enabled":                     autoScaling,
+			"target_queue_depth":          targets.TargetQueueDepth,
+			"target_latency_ms":           targets.TargetLatencyMs,
+			"scale_up_cooldown_seconds":   targets.ScaleUpCooldownSeconds,
+			"scale_down_cooldown_seconds": targets.ScaleDownCooldownSeconds,
+			"last_scaled_up_at":           lastScaledUpAt,
+			"last_scaled_down_at":         lastScaledDownAt,
+		},
+		"vllm_version":            vllmVersion,
+		"torch_version":           torchVersion,
 		"created_at":              createdAt,
 		"updated_at":              updatedAt,
 		"nodes":                   nodes,
 	})
 }
 
+// handleSetWarmStandby turns a deployment's warm standby on or off
+// Platform Admin Only - PUT /admin/deployments/{id}/warm-standby
+// The deployment controller launches the standby on its next pass; turning
+// it off leaves the standby running until the deployment scales down.
+func (g *Gateway) handleSetWarmStandby(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	deploymentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid deployment ID")
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		g.writeError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+
+	result, err := g.db.Pool.Exec(ctx, `
+		UPDATE deployments SET warm_standby = $2, updated_at = NOW()
+		WHERE id = $1
+	`, deploymentID, *req.Enabled)
+	if err != nil {
+		g.logger.Error("failed to update warm standby", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update deployment")
+		return
+	}
+	if result.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+
+	g.logger.Info("deployment warm standby updated",
+		zap.String("deployment_id", deploymentID.String()),
+		zap.Bool("enabled", *req.Enabled),
+	)
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deployment_id": deploymentID,
+		"warm_standby":  *req.Enabled,
+	})
+}
+
+// handleSetKeepStoppedNodes sets how many nodes a deployment stops at scale
+// down instead of terminating
+// Platform Admin Only - PUT /admin/deployments/{id}/stopped-nodes
+// Stopped nodes keep their disks and are restarted first at scale up. The
+// deployment controller terminates stopped nodes beyond the new number on
+// its next pass.
+func (g *Gateway) handleSetKeepStoppedNodes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	deploymentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid deployment ID")
+		return
+	}
+
+	var req struct {
+		Keep *int `json:"keep"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Keep == nil {
+		g.writeError(w, http.StatusBadRequest, "keep is required")
+		return
+	}
+	if *req.Keep < 0 {
+		g.writeError(w, http.StatusBadRequest, "keep must not be negative")
+		return
+	}
+
+	result, err := g.db.Pool.Exec(ctx, `
+		UPDATE deployments SET keep_stopped_nodes = $2, updated_at = NOW()
+		WHERE id = $1
+	`, deploymentID, *req.Keep)
+	if err != nil {
+		g.logger.Error("failed to update stopped nodes", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update deployment")
+		return
+	}
+	if result.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+
+	g.logger.Info("deployment stopped nodes updated",
+		zap.String("deployment_id", deploymentID.String()),
+		zap.Int("keep", *req.Keep),
+	)
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deployment_id":      deploymentID,
+		"keep_stopped_nodes": *req.Keep,
+	})
+}
+
+// autoscaleTargets are a deployment's autoscaling targets and cooldowns;
+// zero uses the platform default
+type autoscaleTargets struct {
+	TargetQueueDepth         int `json:"target_queue_depth"`
+	TargetLatencyMs          int `json:"target_latency_ms"` // p95
+	ScaleUpCooldownSeconds   int `json:"scale_up_cooldown_seconds"`
+	ScaleDownCooldownSeconds int `json:"scale_down_cooldown_seconds"`
+}
+
+// validate rejects negative targets and cooldowns
+func (t autoscaleTargets) validate() error {
+	if t.TargetQueueDepth < 0 || t.TargetLatencyMs < 0 || t.ScaleUpCooldownSeconds < 0 || t.ScaleDownCooldownSeconds < 0 {
+		return errors.New("autoscaling targets and cooldowns must not be negative")
+	}
+	return nil
+}
+
+// handleSetDeploymentAutoscaling turns a deployment's autoscaling on or off
+// and sets its bounds, targets and cooldowns
+// Platform Admin Only - PUT /admin/deployments/{id}/autoscaling
+// The deployment controller scales between min_nodes and max_nodes on the
+// deployment's queue depth and p95 latency from its next pass.
+func (g *Gateway) handleSetDeploymentAutoscaling(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	deploymentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid deployment ID")
+		return
+	}
+
+	var req struct {
+		Enabled  *bool `json:"enabled"`
+		MinNodes int   `json:"min_nodes"`
+		MaxNodes int   `json:"max_nodes"`
+		autoscaleTargets
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		g.writeError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+	if err := req.autoscaleTargets.validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.MinNodes < 0 || (req.MaxNodes > 0 && req.MaxNodes < req.MinNodes) {
+		g.writeError(w, http.StatusBadRequest, "max_nodes must be at least min_nodes")
+		return
+	}
+
+	// Bounds left at zero keep the deployment's current ones
+	result, err := g.db.Pool.Exec(ctx, `
+		UPDATE deployments SET
+			auto_scaling_enabled = $2,
+			min_replicas = COALESCE(NULLIF($3, 0), min_replicas),
+			max_replicas = GREATEST(COALESCE(NULLIF($4, 0), max_replicas), COALESCE(NULLIF($3, 0), min_replicas)),
+			autoscale_target_queue_depth = NULLIF($5, 0),
+			autoscale_target_p95_latency_ms = NULLIF($6, 0),
+			autoscale_scale_up_cooldown_seconds = NULLIF($7, 0),
+			autoscale_scale_down_cooldown_seconds = NULLIF($8, 0),
+			updated_at = NOW()
+		WHERE id = $1
+	`, deploymentID, *req.Enabled, req.MinNodes, req.MaxNodes,
+		req.TargetQueueDepth, req.TargetLatencyMs, req.ScaleUpCooldownSeconds, req.ScaleDownCooldownSeconds)
+	if err != nil {
+		g.logger.Error("failed to update deployment autoscaling", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update deployment")
+		return
+	}
+	if result.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+
+	g.logger.Info("deployment autoscaling updated",
+		zap.String("deployment_id", deploymentID.String()),
+		zap.Bool("enabled", *req.Enabled),
+		zap.Int("min_nodes", req.MinNodes),
+		zap.Int("max_nodes", req.MaxNodes),
+	)
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deployment_id":               deploymentID,
+		"enabled":                     *req.Enabled,
+		"target_queue_depth":          req.TargetQueueDepth,
+		"target_latency_ms":           req.TargetLatencyMs,
+		"scale_up_cooldown_seconds":   req.ScaleUpCooldownSeconds,
+		"scale_down_cooldown_seconds": req.ScaleDownCooldownSeconds,
+	})
+}
+
+// DeploymentNodeDrift is a deployment node whose reported runtime differs
+// from its spec
+type DeploymentNodeDrift struct {
+	NodeID      uuid.UUID     `json:"node_id"`
+	ClusterName string        `json:"cluster_name"`
+	Drift       []nodes.Drift `json:"drift"`
+	DriftSince  *time.Time    `json:"drift_since,omitempty"`
+	ReportedAt  *time.Time    `json:"reported_at,omitempty"`
+}
+
+// deploymentDrift summarizes how the deployment's nodes compare to its spec.
+// Nodes that haven't reported their runtime yet are counted as unreported.
+func (g *Gateway) deploymentDrift(ctx context.Context, deploymentID uuid.UUID, modelName string) map[string]interface{} {
+	runtimes, err := g.nodeRegistry.DeploymentRuntimes(ctx, deploymentID)
+	if err != nil {
+		g.logger.Warn("failed to load deployment node runtimes",
+			zap.Error(err),
+			zap.String("deployment_id", deploymentID.String()),
+		)
+		return map[string]interface{}{"error": "drift unavailable"}
+	}
+
+	drifted := []DeploymentNodeDrift{}
+	unreported := 0
+	for _, n := range runtimes {
+		if n.Report == nil {
+			unreported++
+			continue
+		}
+		if drift := n.Drift(modelName); len(drift) > 0 {
+			drifted = append(drifted, DeploymentNodeDrift{
+				NodeID:      n.NodeID,
+				ClusterName: n.ClusterName,
+				Drift:       drift,
+				DriftSince:  n.DriftSince,
+				ReportedAt:  n.ReportedAt,
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"checked_nodes":    len(runtimes) - unreported,
+		"unreported_nodes": unreported,
+		"drifted_nodes":    drifted,
+	}
+}
+
 // handleScaleDeployment scales a deployment up or down
 // Platform Admin Only - PUT /admin/deployments/{id}/scale
 func (g *Gateway) handleScaleDeployment(w http.ResponseWriter, r *http.Request) {
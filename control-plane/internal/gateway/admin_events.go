@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Admin event stream.
+//
+// GET /admin/events/stream delivers the event bus (node lifecycle,
+// deployment capacity, billing, safety monitor and abuse actions, ...) to
+// admin dashboards as Server-Sent Events, so they update live instead of
+// polling. Streams see the events published on the replica they are
+// connected to, and events are not stored, so a reconnecting dashboard
+// should reload its views once and then follow the stream.
+//
+// Query parameters:
+//   - types: comma-separated event types; "node.*" matches a whole category
+//   - tenant_id: comma-separated tenant IDs; only their events are sent
+const (
+	// adminEventBuffer is how many events a slow stream can fall behind by
+	// before events are dropped
+	adminEventBuffer = 256
+	// adminEventKeepalive keeps idle streams open through proxies
+	adminEventKeepalive = 15 * time.Second
+	// maxAdminEventStreams caps concurrent streams per replica
+	maxAdminEventStreams = 50
+)
+
+var adminEventTypePattern = regexp.MustCompile(`^[a-z_]+(\.([a-z_]+|\*))?$`)
+
+// adminEventFilter selects the events a stream receives
+type adminEventFilter struct {
+	types      map[events.EventType]bool
+	categories map[string]bool
+	tenants    map[string]bool
+}
+
+// parseAdminEventFilter reads the types and tenant_id query parameters
+func parseAdminEventFilter(types, tenants string) (*adminEventFilter, error) {
+	filter := &adminEventFilter{}
+	for _, item := range strings.Split(types, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !adminEventTypePattern.MatchString(item) {
+			return nil, fmt.Errorf("invalid event type %q", item)
+		}
+		if category, ok := strings.CutSuffix(item, ".*"); ok {
+			if filter.categories == nil {
+				filter.categories = map[string]bool{}
+			}
+			filter.categories[category] = true
+			continue
+		}
+		if filter.types == nil {
+			filter.types = map[events.EventType]bool{}
+		}
+		filter.types[events.EventType(item)] = true
+	}
+	for _, item := range strings.Split(tenants, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := uuid.Parse(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenant_id %q", item)
+		}
+		if filter.tenants == nil {
+			filter.tenants = map[string]bool{}
+		}
+		filter.tenants[id.String()] = true
+	}
+	return filter, nil
+}
+
+// matches reports whether an event passes the filter
+func (f *adminEventFilter) matches(event events.Event) bool {
+	if f.tenants != nil && !f.tenants[strings.ToLower(event.TenantID)] {
+		return false
+	}
+	if f.types == nil && f.categories == nil {
+		return true
+	}
+	if f.types[event.Type] {
+		return true
+	}
+	category, _, _ := strings.Cut(string(event.Type), ".")
+	return f.categories[category]
+}
+
+// adminStreamEvent is an event as sent to admin streams
+type adminStreamEvent struct {
+	ID        string                 `json:"id"`
+	Type      events.EventType       `json:"type"`
+	TenantID  string                 `json:"tenant_id,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Payload   map[string]interface{} `json:"payload"`
+}
+
+// handleAdminEventStream streams bus events to an admin dashboard
+// Platform Admin Only - GET /admin/events/stream
+func (g *Gateway) handleAdminEventStream(w http.ResponseWriter, r *http.Request) {
+	if g.eventBus == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "event bus is not configured")
+		return
+	}
+	filter, err := parseAdminEventFilter(r.URL.Query().Get("types"), r.URL.Query().Get("tenant_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		g.writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	if g.adminEventStreams.Add(1) > maxAdminEventStreams {
+		g.adminEventStreams.Add(-1)
+		g.writeError(w, http.StatusServiceUnavailable, "too many open event streams")
+		return
+	}
+	defer g.adminEventStreams.Add(-1)
+
+	// The bus never waits on a stream: a full buffer drops the event and
+	// the stream reports how many it missed
+	queue := make(chan events.Event, adminEventBuffer)
+	var dropped atomic.Int64
+	unsubscribe := g.eventBus.SubscribeAll(func(ctx context.Context, event events.Event) error {
+		if !filter.matches(event) {
+			return nil
+		}
+		select {
+		case queue <- event:
+		default:
+			dropped.Add(1)
+		}
+		return nil
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis)
+	flusher.Flush()
+
+	g.logger.Info("admin event stream opened", zap.String("types", r.URL.Query().Get("types")))
+
+	keepalive := time.NewTicker(adminEventKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-g.streamsDraining:
+			g.writeSSEEvent(w, "reconnect", map[string]interface{}{
+				"reason": "server shutting down",
+			})
+			flusher.Flush()
+			return
+
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+
+		case event := <-queue:
+			if missed := dropped.Swap(0); missed > 0 {
+				g.writeSSEEvent(w, "dropped", map[string]interface{}{"count": missed})
+			}
+			g.writeSSEEvent(w, string(event.Type), adminStreamEvent{
+				ID:        event.ID,
+				Type:      event.Type,
+				TenantID:  event.TenantID,
+				Timestamp: event.Timestamp,
+				Payload:   event.Payload,
+			})
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/google/uuid"
+)
+
+func TestAdminEventFilter(t *testing.T) {
+	tenant := uuid.New()
+	filter, err := parseAdminEventFilter("node.*, payment.failed", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		eventType events.EventType
+		want      bool
+	}{
+		{events.EventNodeDraining, true},
+		{events.EventNodeHealthChanged, true},
+		{events.EventPaymentFailed, true},
+		{events.EventPaymentSucceeded, false},
+		{events.EventDeploymentCapacityDegraded, false},
+	} {
+		if got := filter.matches(events.Event{Type: tc.eventType}); got != tc.want {
+			t.Errorf("matches(%s) = %v, want %v", tc.eventType, got, tc.want)
+		}
+	}
+
+	filter, err = parseAdminEventFilter("", tenant.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filter.matches(events.Event{Type: events.EventAPIKeyRevoked, TenantID: tenant.String()}) {
+		t.Error("tenant event filtered out")
+	}
+	if filter.matches(events.Event{Type: events.EventNodeDraining}) {
+		t.Error("system event passed a tenant filter")
+	}
+
+	if all, _ := parseAdminEventFilter("", ""); !all.matches(events.Event{Type: events.EventTenantCreated}) {
+		t.Error("unfiltered stream dropped an event")
+	}
+	for _, bad := range [][2]string{{"node.*.x", ""}, {"Node.Draining", ""}, {"*", ""}, {"", "acme"}} {
+		if _, err := parseAdminEventFilter(bad[0], bad[1]); err == nil {
+			t.Errorf("parseAdminEventFilter(%q, %q) accepted", bad[0], bad[1])
+		}
+	}
+}
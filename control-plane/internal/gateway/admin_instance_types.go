@@ -48,9 +48,9 @@ func (g *Gateway) handleCreateInstanceType(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Validate provider
-	validProviders := map[string]bool{"aws": true, "azure": true, "gcp": true, "oci": true}
+	validProviders := map[string]bool{"aws": true, "azure": true, "gcp": true, "oci": true, "lambda": true, "runpod": true, "nebius": true}
 	if !validProviders[req.Provider] {
-		g.writeError(w, http.StatusBadRequest, "invalid provider. Valid values: aws, azure, gcp, oci")
+		g.writeError(w, http.StatusBadRequest, "invalid provider. Valid values: aws, azure, gcp, oci, lambda, runpod, nebius")
 		return
 	}
 
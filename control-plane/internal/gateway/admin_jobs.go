@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/crosslogic/control-plane/internal/jobs"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// handleListJobs lists background jobs with per-kind state counts
+// Admin API - GET /admin/jobs?state=dead&kind=usage.record
+func (g *Gateway) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	state := r.URL.Query().Get("state")
+	switch state {
+	case "", jobs.StateAvailable, jobs.StateRunning, jobs.StateRetryable, jobs.StateCompleted, jobs.StateDead:
+	default:
+		g.writeError(w, http.StatusBadRequest, "state must be one of available, running, retryable, completed, dead")
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 50, 1, 200)
+	offset := parseIntParam(r, "offset", 0, 0, 999999)
+
+	list, err := g.jobs.List(ctx, jobs.Filter{State: state, Kind: r.URL.Query().Get("kind")}, limit, offset)
+	if err != nil {
+		g.logger.Error("failed to list jobs", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list jobs")
+		return
+	}
+
+	counts, err := g.jobs.Counts(ctx)
+	if err != nil {
+		g.logger.Error("failed to count jobs", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list jobs")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data":   list,
+		"counts": counts,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// handleRetryJob requeues a dead or retryable job with fresh attempts
+// Admin API - POST /admin/jobs/{id}/retry
+func (g *Gateway) handleRetryJob(w http.ResponseWriter, r *http.Request) {
+	g.changeJob(w, r, "retried", g.jobs.Retry)
+}
+
+// handleDiscardJob dead-letters a job that hasn't run yet
+// Admin API - POST /admin/jobs/{id}/discard
+func (g *Gateway) handleDiscardJob(w http.ResponseWriter, r *http.Request) {
+	g.changeJob(w, r, "discarded", g.jobs.Discard)
+}
+
+// changeJob applies an admin action to the job named in the URL
+func (g *Gateway) changeJob(w http.ResponseWriter, r *http.Request, status string, action func(ctx context.Context, id int64) error) {
+	jobID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid job ID")
+		return
+	}
+
+	if err := action(r.Context(), jobID); err != nil {
+		if errors.Is(err, jobs.ErrNotFound) {
+			g.writeError(w, http.StatusNotFound, "job not found or not in a state that allows this action")
+			return
+		}
+		g.logger.Error("failed to update job", zap.Error(err), zap.Int64("job_id", jobID))
+		g.writeError(w, http.StatusInternalServerError, "failed to update job")
+		return
+	}
+
+	g.logger.Info("admin updated job", zap.Int64("job_id", jobID), zap.String("status", status))
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":     jobID,
+		"status": status,
+	})
+}
@@ -0,0 +1,876 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Emergency kill switches.
+//
+// A kill switch stops routing inference for a tenant, a model, a provider or
+// everything, for incidents such as compromised keys or runaway costs, and
+// can also terminate the nodes in its scope. One admin requests a switch and
+// a second admin (a different admin token) confirms it; only then does it
+// take effect. Single-operator installs can turn confirmation off, in which
+// case a request takes effect at once. Requests that aren't confirmed within
+// killSwitchConfirmWindow expire.
+//
+// The replica handling a change applies it immediately; the others pick it
+// up within killSwitchRefreshInterval. Every step is written to
+// kill_switch_audit before it is acted on.
+const (
+	killScopeTenant   = "tenant"
+	killScopeModel    = "model"
+	killScopeProvider = "provider"
+	killScopeGlobal   = "global"
+
+	killStatusPending   = "pending"
+	killStatusActive    = "active"
+	killStatusCancelled = "cancelled"
+	killStatusExpired   = "expired"
+	killStatusReleased  = "released"
+
+	// killSwitchConfirmWindow is how long a request waits for confirmation
+	killSwitchConfirmWindow = 15 * time.Minute
+	// killSwitchRefreshInterval is how often replicas reload active switches
+	killSwitchRefreshInterval = 5 * time.Second
+	// killSwitchTerminateTimeout bounds terminating the nodes of one switch
+	killSwitchTerminateTimeout = 30 * time.Minute
+)
+
+var killSwitchProviderPattern = regexp.MustCompile(`^[a-z0-9-]{1,50}$`)
+
+// KillSwitch is an emergency routing halt
+type KillSwitch struct {
+	ID             uuid.UUID  `json:"id"`
+	Scope          string     `json:"scope"`
+	Target         *string    `json:"target,omitempty"`
+	TerminateNodes bool       `json:"terminate_nodes"`
+	Reason         string     `json:"reason"`
+	Status         string     `json:"status"`
+	RequestedBy    string     `json:"requested_by"`
+	RequestedAt    time.Time  `json:"requested_at"`
+	ConfirmBy      time.Time  `json:"confirm_by"`
+	ConfirmedBy    *string    `json:"confirmed_by,omitempty"`
+	ActivatedAt    *time.Time `json:"activated_at,omitempty"`
+	ReleasedBy     *string    `json:"released_by,omitempty"`
+	ReleasedAt     *time.Time `json:"released_at,omitempty"`
+
+	Audit []KillSwitchAuditEntry `json:"audit,omitempty"`
+}
+
+// KillSwitchAuditEntry is one recorded step of a kill switch
+type KillSwitchAuditEntry struct {
+	Action     string                 `json:"action"`
+	Actor      *string                `json:"actor,omitempty"`
+	RemoteAddr *string                `json:"remote_addr,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+const killSwitchColumns = `id, scope, target, terminate_nodes, reason, status, requested_by, requested_at,
+	confirm_by, confirmed_by, activated_at, released_by, released_at`
+
+func scanKillSwitch(row pgx.Row) (*KillSwitch, error) {
+	var ks KillSwitch
+	err := row.Scan(&ks.ID, &ks.Scope, &ks.Target, &ks.TerminateNodes, &ks.Reason, &ks.Status,
+		&ks.RequestedBy, &ks.RequestedAt, &ks.ConfirmBy, &ks.ConfirmedBy, &ks.ActivatedAt,
+		&ks.ReleasedBy, &ks.ReleasedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ks, nil
+}
+
+// killScopeCondition matches the rows of nodes or deployments in a switch's
+// scope, given the target as $1
+func killScopeCondition(scope string) string {
+	switch scope {
+	case killScopeTenant:
+		return "tenant_id::text = $1"
+	case killScopeModel:
+		return "model_name = $1"
+	case killScopeProvider:
+		return "provider = $1"
+	default:
+		return "$1::text IS NULL"
+	}
+}
+
+// killSwitchSet is the active switches, as checked on the request path
+type killSwitchSet struct {
+	mu      sync.RWMutex
+	global  bool
+	tenants map[uuid.UUID]bool
+	models  map[string]bool
+}
+
+// set replaces the active switches
+func (s *killSwitchSet) set(switches []KillSwitch) {
+	global := false
+	tenants := make(map[uuid.UUID]bool)
+	models := make(map[string]bool)
+	for _, ks := range switches {
+		switch ks.Scope {
+		case killScopeGlobal:
+			global = true
+		case killScopeTenant:
+			if id, err := uuid.Parse(*ks.Target); err == nil {
+				tenants[id] = true
+			}
+		case killScopeModel:
+			models[*ks.Target] = true
+		}
+	}
+
+	s.mu.Lock()
+	s.global, s.tenants, s.models = global, tenants, models
+	s.mu.Unlock()
+}
+
+// haltsTenant reports whether routing is stopped for a tenant
+func (s *killSwitchSet) haltsTenant(tenantID uuid.UUID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.global || s.tenants[tenantID]
+}
+
+// haltsModel reports whether routing is stopped for a model
+func (s *killSwitchSet) haltsModel(model string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.global || s.models[model]
+}
+
+// SetHaltedEndpoints replaces the endpoints taken out of routing by kill
+// switches
+func (lb *IntelligentLoadBalancer) SetHaltedEndpoints(halted map[string]bool) {
+	lb.mu.Lock()
+	lb.halted = halted
+	lb.mu.Unlock()
+}
+
+// haltedEndpoints returns the endpoints stopped by kill switches
+func (lb *IntelligentLoadBalancer) haltedEndpoints() []string {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	endpoints := make([]string, 0, len(lb.halted))
+	for endpoint := range lb.halted {
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+// withoutHalted drops endpoints stopped by a kill switch. Callers must hold
+// lb.mu.
+func (lb *IntelligentLoadBalancer) withoutHalted(endpoints []string) []string {
+	if len(lb.halted) == 0 {
+		return endpoints
+	}
+	var routable []string
+	for _, endpoint := range endpoints {
+		if !lb.halted[endpoint] {
+			routable = append(routable, endpoint)
+		}
+	}
+	return routable
+}
+
+// writeRoutingHalted answers a request stopped by a kill switch
+func (g *Gateway) writeRoutingHalted(w http.ResponseWriter, message string) {
+	g.writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+		"error": map[string]string{
+			"message": message,
+			"type":    "server_error",
+			"code":    "service_halted",
+		},
+	})
+}
+
+// enforceKillSwitches rejects inference from tenants whose routing is halted
+func (g *Gateway) enforceKillSwitches(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID); ok && g.killSwitches.haltsTenant(tenantID) {
+			g.writeRoutingHalted(w, "inference is temporarily halted for this account, contact support")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SetKillSwitchTwoPerson sets whether kill switches need a second admin to
+// confirm them (the default)
+func (g *Gateway) SetKillSwitchTwoPerson(required bool) {
+	g.killSwitchSingleAdmin = !required
+}
+
+// StartKillSwitches loads the active kill switches and keeps them current
+func (g *Gateway) StartKillSwitches(ctx context.Context) {
+	if err := g.LoadKillSwitches(ctx); err != nil {
+		g.logger.Error("failed to load kill switches", zap.Error(err))
+	}
+	go func() {
+		ticker := time.NewTicker(killSwitchRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := g.LoadKillSwitches(ctx); err != nil {
+					g.logger.Warn("failed to refresh kill switches", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// LoadKillSwitches expires unconfirmed requests, then reloads the active
+// switches and the endpoints they take out of routing
+func (g *Gateway) LoadKillSwitches(ctx context.Context) error {
+	rows, err := g.db.Pool.Query(ctx, `
+		WITH expired AS (
+			UPDATE kill_switches SET status = 'expired'
+			WHERE status = 'pending' AND confirm_by <= NOW()
+			RETURNING id
+		)
+		INSERT INTO kill_switch_audit (kill_switch_id, action)
+		SELECT id, 'expired' FROM expired
+		RETURNING kill_switch_id
+	`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err == nil {
+			g.logger.Warn("kill switch request expired unconfirmed", zap.String("kill_switch_id", id.String()))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = g.db.Pool.Query(ctx, `
+		SELECT `+killSwitchColumns+` FROM kill_switches WHERE status = 'active'
+	`)
+	if err != nil {
+		return err
+	}
+	var active []KillSwitch
+	for rows.Next() {
+		ks, err := scanKillSwitch(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		active = append(active, *ks)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	halted := make(map[string]bool)
+	for _, ks := range active {
+		endpoints, err := g.db.Pool.Query(ctx, `
+			SELECT endpoint_url FROM nodes
+			WHERE endpoint_url != '' AND terminated_at IS NULL AND `+killScopeCondition(ks.Scope), ks.Target)
+		if err != nil {
+			return err
+		}
+		for endpoints.Next() {
+			var endpoint string
+			if err := endpoints.Scan(&endpoint); err != nil {
+				endpoints.Close()
+				return err
+			}
+			halted[endpoint] = true
+		}
+		endpoints.Close()
+		if err := endpoints.Err(); err != nil {
+			return err
+		}
+	}
+
+	g.killSwitches.set(active)
+	g.LoadBalancer.SetHaltedEndpoints(halted)
+	return nil
+}
+
+// recordKillSwitchAudit appends a step to a switch's audit trail
+func recordKillSwitchAudit(ctx context.Context, q database.Querier, id uuid.UUID, action string, actor *string, remoteAddr string, details map[string]interface{}) error {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	var addr *string
+	if remoteAddr != "" {
+		addr = &remoteAddr
+	}
+	_, err = q.Exec(ctx, `
+		INSERT INTO kill_switch_audit (kill_switch_id, action, actor, remote_addr, details)
+		VALUES ($1, $2, $3, $4, $5)
+	`, id, action, actor, addr, encoded)
+	return err
+}
+
+// loadKillSwitch reads a switch with its audit trail, or nil if it doesn't
+// exist
+func (g *Gateway) loadKillSwitch(ctx context.Context, id uuid.UUID) (*KillSwitch, error) {
+	ks, err := scanKillSwitch(g.db.Pool.QueryRow(ctx, `
+		SELECT `+killSwitchColumns+` FROM kill_switches WHERE id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT action, actor, remote_addr, details, created_at
+		FROM kill_switch_audit
+		WHERE kill_switch_id = $1
+		ORDER BY id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ks.Audit = []KillSwitchAuditEntry{}
+	for rows.Next() {
+		var entry KillSwitchAuditEntry
+		var details []byte
+		if err := rows.Scan(&entry.Action, &entry.Actor, &entry.RemoteAddr, &details, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(details, &entry.Details); err != nil {
+			return nil, err
+		}
+		ks.Audit = append(ks.Audit, entry)
+	}
+	return ks, rows.Err()
+}
+
+// killSwitchRequest is the body of POST /admin/kill-switches
+type killSwitchRequest struct {
+	Scope          string  `json:"scope"`
+	Target         *string `json:"target"`
+	TerminateNodes bool    `json:"terminate_nodes"`
+	Reason         string  `json:"reason"`
+}
+
+// validate checks the scope and normalizes the target
+func (req *killSwitchRequest) validate() error {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return errors.New("reason is required")
+	}
+	if req.Target != nil {
+		target := strings.TrimSpace(*req.Target)
+		req.Target = &target
+		if target == "" {
+			req.Target = nil
+		}
+	}
+
+	switch req.Scope {
+	case killScopeGlobal:
+		if req.Target != nil {
+			return errors.New("a global kill switch takes no target")
+		}
+		return nil
+	case killScopeTenant, killScopeModel, killScopeProvider:
+		if req.Target == nil {
+			return fmt.Errorf("target is required for scope %s", req.Scope)
+		}
+	default:
+		return fmt.Errorf("scope must be one of %s, %s, %s or %s", killScopeTenant, killScopeModel, killScopeProvider, killScopeGlobal)
+	}
+
+	switch req.Scope {
+	case killScopeTenant:
+		id, err := uuid.Parse(*req.Target)
+		if err != nil {
+			return errors.New("target must be a tenant ID")
+		}
+		target := id.String()
+		req.Target = &target
+	case killScopeProvider:
+		if !killSwitchProviderPattern.MatchString(*req.Target) {
+			return errors.New("invalid provider")
+		}
+	}
+	return nil
+}
+
+// handleCreateKillSwitch requests a kill switch. It takes effect when a
+// second admin confirms it, or at once when confirmation is turned off.
+// Platform Admin Only - POST /admin/kill-switches
+func (g *Gateway) handleCreateKillSwitch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	actor := adminActor(ctx)
+	if actor == nil {
+		g.writeError(w, http.StatusForbidden, "kill switches need a named admin token")
+		return
+	}
+
+	var req killSwitchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Scope == killScopeTenant {
+		var exists bool
+		if err := g.db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM tenants WHERE id = $1)`, *req.Target).Scan(&exists); err != nil {
+			g.logger.Error("failed to look up tenant", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to create kill switch")
+			return
+		}
+		if !exists {
+			g.writeError(w, http.StatusNotFound, "tenant not found")
+			return
+		}
+	}
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		g.logger.Error("failed to begin transaction", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create kill switch")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	ks, err := scanKillSwitch(tx.QueryRow(ctx, `
+		INSERT INTO kill_switches (scope, target, terminate_nodes, reason, requested_by, confirm_by)
+		VALUES ($1, $2, $3, $4, $5, NOW() + make_interval(secs => $6))
+		RETURNING `+killSwitchColumns,
+		req.Scope, req.Target, req.TerminateNodes, req.Reason, *actor, killSwitchConfirmWindow.Seconds(),
+	))
+	if err != nil {
+		g.logger.Error("failed to create kill switch", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create kill switch")
+		return
+	}
+	details := map[string]interface{}{
+		"scope":           ks.Scope,
+		"target":          ks.Target,
+		"terminate_nodes": ks.TerminateNodes,
+		"reason":          ks.Reason,
+	}
+	if err := recordKillSwitchAudit(ctx, tx, ks.ID, "requested", actor, r.RemoteAddr, details); err != nil {
+		g.logger.Error("failed to audit kill switch", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create kill switch")
+		return
+	}
+	if g.killSwitchSingleAdmin {
+		if err := g.activateKillSwitch(ctx, tx, ks, *actor, r.RemoteAddr); err != nil {
+			g.logger.Error("failed to activate kill switch", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to activate kill switch")
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		g.logger.Error("failed to commit kill switch", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create kill switch")
+		return
+	}
+
+	g.logger.Warn("kill switch requested",
+		zap.String("kill_switch_id", ks.ID.String()),
+		zap.String("scope", ks.Scope),
+		zap.Stringp("target", ks.Target),
+		zap.Bool("terminate_nodes", ks.TerminateNodes),
+		zap.String("requested_by", *actor),
+	)
+	if ks.Status == killStatusActive {
+		g.killSwitchActivated(ctx, ks)
+	}
+	g.writeKillSwitch(w, ctx, ks.ID, http.StatusCreated)
+}
+
+// handleConfirmKillSwitch activates a pending kill switch. The confirming
+// admin must not be the one who requested it.
+// Platform Admin Only - POST /admin/kill-switches/{id}/confirm
+func (g *Gateway) handleConfirmKillSwitch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	actor := adminActor(ctx)
+	if actor == nil {
+		g.writeError(w, http.StatusForbidden, "kill switches need a named admin token")
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid kill switch ID")
+		return
+	}
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		g.logger.Error("failed to begin transaction", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to confirm kill switch")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	ks, err := scanKillSwitch(tx.QueryRow(ctx, `
+		SELECT `+killSwitchColumns+` FROM kill_switches WHERE id = $1 FOR UPDATE
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "kill switch not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to load kill switch", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to confirm kill switch")
+		return
+	}
+	if ks.Status != killStatusPending || !time.Now().Before(ks.ConfirmBy) {
+		g.writeError(w, http.StatusConflict, "kill switch is not awaiting confirmation")
+		return
+	}
+	if ks.RequestedBy == *actor {
+		g.writeError(w, http.StatusForbidden, "a kill switch must be confirmed by a different admin than the one who requested it")
+		return
+	}
+
+	if err := recordKillSwitchAudit(ctx, tx, ks.ID, "confirmed", actor, r.RemoteAddr, nil); err != nil {
+		g.logger.Error("failed to audit kill switch", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to confirm kill switch")
+		return
+	}
+	if err := g.activateKillSwitch(ctx, tx, ks, *actor, r.RemoteAddr); err != nil {
+		g.logger.Error("failed to activate kill switch", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to confirm kill switch")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		g.logger.Error("failed to commit kill switch", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to confirm kill switch")
+		return
+	}
+
+	g.killSwitchActivated(ctx, ks)
+	g.writeKillSwitch(w, ctx, ks.ID, http.StatusOK)
+}
+
+// activateKillSwitch marks a switch active inside tx
+func (g *Gateway) activateKillSwitch(ctx context.Context, tx pgx.Tx, ks *KillSwitch, confirmedBy, remoteAddr string) error {
+	err := tx.QueryRow(ctx, `
+		UPDATE kill_switches SET status = 'active', confirmed_by = $2, activated_at = NOW()
+		WHERE id = $1
+		RETURNING status, confirmed_by, activated_at
+	`, ks.ID, confirmedBy).Scan(&ks.Status, &ks.ConfirmedBy, &ks.ActivatedAt)
+	if err != nil {
+		return err
+	}
+	return recordKillSwitchAudit(ctx, tx, ks.ID, "activated", &confirmedBy, remoteAddr, nil)
+}
+
+// killSwitchActivated applies a switch that just became active: routing
+// stops on this replica at once, and the nodes in scope are terminated if
+// the switch asks for it
+func (g *Gateway) killSwitchActivated(ctx context.Context, ks *KillSwitch) {
+	if err := g.LoadKillSwitches(ctx); err != nil {
+		g.logger.Error("failed to apply kill switch; other replicas apply it on their next refresh", zap.Error(err))
+	}
+
+	g.logger.Warn("kill switch active",
+		zap.String("kill_switch_id", ks.ID.String()),
+		zap.String("scope", ks.Scope),
+		zap.Stringp("target", ks.Target),
+		zap.Stringp("confirmed_by", ks.ConfirmedBy),
+	)
+	g.publishKillSwitchEvent(ctx, events.EventKillSwitchActivated, ks)
+
+	if ks.TerminateNodes {
+		go g.terminateKillSwitchScope(ks)
+	}
+}
+
+// terminateKillSwitchScope pauses the deployments in a switch's scope so
+// they don't relaunch capacity, then terminates its nodes. Paused
+// deployments stay paused after the switch is released.
+func (g *Gateway) terminateKillSwitchScope(ks *KillSwitch) {
+	ctx, cancel := context.WithTimeout(context.Background(), killSwitchTerminateTimeout)
+	defer cancel()
+
+	details := map[string]interface{}{}
+	paused := []string{}
+	rows, err := g.db.Pool.Query(ctx, `
+		UPDATE deployments SET status = 'paused', updated_at = NOW()
+		WHERE status = 'active' AND `+killScopeCondition(ks.Scope)+`
+		RETURNING name
+	`, ks.Target)
+	if err == nil {
+		for rows.Next() {
+			var name string
+			if rows.Scan(&name) == nil {
+				paused = append(paused, name)
+			}
+		}
+		rows.Close()
+		err = rows.Err()
+	}
+	if err != nil {
+		g.logger.Error("failed to pause deployments for kill switch", zap.Error(err), zap.String("kill_switch_id", ks.ID.String()))
+		details["pause_error"] = err.Error()
+	}
+	details["deployments_paused"] = paused
+
+	var clusters []string
+	rows, err = g.db.Pool.Query(ctx, `
+		SELECT cluster_name FROM nodes
+		WHERE terminated_at IS NULL AND cluster_name IS NOT NULL AND `+killScopeCondition(ks.Scope), ks.Target)
+	if err == nil {
+		for rows.Next() {
+			var cluster string
+			if rows.Scan(&cluster) == nil {
+				clusters = append(clusters, cluster)
+			}
+		}
+		rows.Close()
+		err = rows.Err()
+	}
+	if err != nil {
+		g.logger.Error("failed to list nodes for kill switch", zap.Error(err), zap.String("kill_switch_id", ks.ID.String()))
+		details["list_error"] = err.Error()
+	}
+
+	terminated := []string{}
+	failed := map[string]string{}
+	for _, cluster := range clusters {
+		if g.orchestrator == nil {
+			failed[cluster] = "orchestrator is not configured"
+			continue
+		}
+		if err := g.orchestrator.TerminateNode(ctx, cluster); err != nil {
+			g.logger.Error("failed to terminate node for kill switch",
+				zap.Error(err),
+				zap.String("kill_switch_id", ks.ID.String()),
+				zap.String("cluster_name", cluster),
+			)
+			failed[cluster] = err.Error()
+			continue
+		}
+		terminated = append(terminated, cluster)
+	}
+	details["terminated"] = terminated
+	details["failed"] = failed
+
+	if err := recordKillSwitchAudit(ctx, g.db.Pool, ks.ID, "nodes_terminated", nil, "", details); err != nil {
+		g.logger.Error("failed to audit kill switch node termination", zap.Error(err), zap.String("kill_switch_id", ks.ID.String()))
+	}
+	g.logger.Warn("kill switch terminated nodes",
+		zap.String("kill_switch_id", ks.ID.String()),
+		zap.Int("terminated", len(terminated)),
+		zap.Int("failed", len(failed)),
+		zap.Int("deployments_paused", len(paused)),
+	)
+}
+
+// handleCancelKillSwitch withdraws a pending kill switch
+// Platform Admin Only - POST /admin/kill-switches/{id}/cancel
+func (g *Gateway) handleCancelKillSwitch(w http.ResponseWriter, r *http.Request) {
+	g.transitionKillSwitch(w, r, killStatusPending, killStatusCancelled, "cancelled")
+}
+
+// handleReleaseKillSwitch restores routing stopped by an active switch.
+// Terminated nodes and paused deployments are not brought back.
+// Platform Admin Only - POST /admin/kill-switches/{id}/release
+func (g *Gateway) handleReleaseKillSwitch(w http.ResponseWriter, r *http.Request) {
+	g.transitionKillSwitch(w, r, killStatusActive, killStatusReleased, "released")
+}
+
+// transitionKillSwitch moves a switch from one status to another and
+// records it
+func (g *Gateway) transitionKillSwitch(w http.ResponseWriter, r *http.Request, from, to, action string) {
+	ctx := r.Context()
+	actor := adminActor(ctx)
+	if actor == nil {
+		g.writeError(w, http.StatusForbidden, "kill switches need a named admin token")
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid kill switch ID")
+		return
+	}
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		g.logger.Error("failed to begin transaction", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update kill switch")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	ks, err := scanKillSwitch(tx.QueryRow(ctx, `
+		UPDATE kill_switches SET
+			status = $3,
+			released_by = CASE WHEN $3 = 'released' THEN $4 ELSE released_by END,
+			released_at = CASE WHEN $3 = 'released' THEN NOW() ELSE released_at END
+		WHERE id = $1 AND status = $2
+		RETURNING `+killSwitchColumns,
+		id, from, to, *actor,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := g.db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM kill_switches WHERE id = $1)`, id).Scan(&exists); err == nil && !exists {
+			g.writeError(w, http.StatusNotFound, "kill switch not found")
+			return
+		}
+		g.writeError(w, http.StatusConflict, fmt.Sprintf("kill switch is not %s", from))
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to update kill switch", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update kill switch")
+		return
+	}
+	if err := recordKillSwitchAudit(ctx, tx, ks.ID, action, actor, r.RemoteAddr, nil); err != nil {
+		g.logger.Error("failed to audit kill switch", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update kill switch")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		g.logger.Error("failed to commit kill switch", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update kill switch")
+		return
+	}
+
+	g.logger.Warn("kill switch "+action,
+		zap.String("kill_switch_id", ks.ID.String()),
+		zap.String("scope", ks.Scope),
+		zap.Stringp("target", ks.Target),
+		zap.String("by", *actor),
+	)
+	if to == killStatusReleased {
+		if err := g.LoadKillSwitches(ctx); err != nil {
+			g.logger.Error("failed to apply kill switch release; other replicas apply it on their next refresh", zap.Error(err))
+		}
+		g.publishKillSwitchEvent(ctx, events.EventKillSwitchReleased, ks)
+	}
+	g.writeKillSwitch(w, ctx, ks.ID, http.StatusOK)
+}
+
+// handleListKillSwitches lists kill switches, newest first
+// Platform Admin Only - GET /admin/kill-switches?status=active
+func (g *Gateway) handleListKillSwitches(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", killStatusPending, killStatusActive, killStatusCancelled, killStatusExpired, killStatusReleased:
+	default:
+		g.writeError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT `+killSwitchColumns+` FROM kill_switches
+		WHERE $1 = '' OR status = $1
+		ORDER BY requested_at DESC
+		LIMIT 200
+	`, status)
+	if err != nil {
+		g.logger.Error("failed to list kill switches", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list kill switches")
+		return
+	}
+	defer rows.Close()
+
+	switches := []KillSwitch{}
+	for rows.Next() {
+		ks, err := scanKillSwitch(rows)
+		if err != nil {
+			g.logger.Error("failed to scan kill switch", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to list kill switches")
+			return
+		}
+		switches = append(switches, *ks)
+	}
+	if err := rows.Err(); err != nil {
+		g.logger.Error("failed to list kill switches", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list kill switches")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data":                 switches,
+		"two_person_confirmed": !g.killSwitchSingleAdmin,
+	})
+}
+
+// handleGetKillSwitch returns a kill switch with its audit trail
+// Platform Admin Only - GET /admin/kill-switches/{id}
+func (g *Gateway) handleGetKillSwitch(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid kill switch ID")
+		return
+	}
+	g.writeKillSwitch(w, r.Context(), id, http.StatusOK)
+}
+
+// writeKillSwitch answers with a switch and its audit trail
+func (g *Gateway) writeKillSwitch(w http.ResponseWriter, ctx context.Context, id uuid.UUID, status int) {
+	ks, err := g.loadKillSwitch(ctx, id)
+	if err != nil {
+		g.logger.Error("failed to load kill switch", zap.Error(err), zap.String("kill_switch_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load kill switch")
+		return
+	}
+	if ks == nil {
+		g.writeError(w, http.StatusNotFound, "kill switch not found")
+		return
+	}
+	g.writeJSON(w, status, ks)
+}
+
+// publishKillSwitchEvent tells admin dashboards about a switch change
+func (g *Gateway) publishKillSwitchEvent(ctx context.Context, eventType events.EventType, ks *KillSwitch) {
+	if g.eventBus == nil {
+		return
+	}
+	tenantID := ""
+	if ks.Scope == killScopeTenant {
+		tenantID = *ks.Target
+	}
+	payload := map[string]interface{}{
+		"kill_switch_id":  ks.ID.String(),
+		"scope":           ks.Scope,
+		"terminate_nodes": ks.TerminateNodes,
+		"reason":          ks.Reason,
+	}
+	if ks.Target != nil {
+		payload["target"] = *ks.Target
+	}
+	if err := g.eventBus.Publish(ctx, events.NewEvent(eventType, tenantID, payload)); err != nil {
+		g.logger.Warn("failed to publish kill switch event", zap.Error(err))
+	}
+}
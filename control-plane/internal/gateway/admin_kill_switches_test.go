@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestKillSwitchRequestValidate(t *testing.T) {
+	tenant := uuid.New().String()
+	blank, acme, badProvider, region := "  ", "acme", "AWS East", "us-east"
+	for _, tc := range []struct {
+		name string
+		req  killSwitchRequest
+		ok   bool
+	}{
+		{"global", killSwitchRequest{Scope: killScopeGlobal, Reason: "runaway costs"}, true},
+		{"global with blank target", killSwitchRequest{Scope: killScopeGlobal, Target: &blank, Reason: "x"}, true},
+		{"tenant", killSwitchRequest{Scope: killScopeTenant, Target: &tenant, Reason: "leaked key"}, true},
+		{"no reason", killSwitchRequest{Scope: killScopeGlobal}, false},
+		{"global with target", killSwitchRequest{Scope: killScopeGlobal, Target: &tenant, Reason: "x"}, false},
+		{"model without target", killSwitchRequest{Scope: killScopeModel, Reason: "x"}, false},
+		{"bad tenant", killSwitchRequest{Scope: killScopeTenant, Target: &acme, Reason: "x"}, false},
+		{"bad provider", killSwitchRequest{Scope: killScopeProvider, Target: &badProvider, Reason: "x"}, false},
+		{"unknown scope", killSwitchRequest{Scope: "region", Target: &region, Reason: "x"}, false},
+	} {
+		if err := tc.req.validate(); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+}
+
+func TestKillSwitchSet(t *testing.T) {
+	tenant := uuid.New()
+	tenantTarget, model, provider := tenant.String(), "llama", "aws"
+	var set killSwitchSet
+	if set.haltsTenant(tenant) || set.haltsModel("llama") {
+		t.Fatal("empty set halts routing")
+	}
+
+	set.set([]KillSwitch{
+		{Scope: killScopeTenant, Target: &tenantTarget},
+		{Scope: killScopeModel, Target: &model},
+		{Scope: killScopeProvider, Target: &provider},
+	})
+	if !set.haltsTenant(tenant) || set.haltsTenant(uuid.New()) {
+		t.Error("tenant switch not scoped to its tenant")
+	}
+	if !set.haltsModel("llama") || set.haltsModel("mistral") {
+		t.Error("model switch not scoped to its model")
+	}
+
+	set.set([]KillSwitch{{Scope: killScopeGlobal}})
+	if !set.haltsTenant(uuid.New()) || !set.haltsModel("mistral") {
+		t.Error("global switch doesn't halt everything")
+	}
+
+	set.set(nil)
+	if set.haltsTenant(tenant) || set.haltsModel("llama") {
+		t.Error("released switches still halt routing")
+	}
+}
+
+func TestSelectEndpointSkipsHaltedEndpoints(t *testing.T) {
+	lb := &IntelligentLoadBalancer{
+		logger: zap.NewNop(),
+		stats:  map[string]*EndpointStats{},
+		overrides: map[string]RoutingOverride{
+			"http://aws": {Pinned: true, Weight: 1},
+		},
+	}
+	lb.SetRouteCacheTTL(time.Minute)
+	lb.storeRoutes("llama", []string{"http://aws", "http://gcp"}, time.Now())
+
+	lb.SetHaltedEndpoints(map[string]bool{"http://aws": true})
+	endpoint, err := lb.SelectEndpoint(context.Background(), "llama")
+	if err != nil || endpoint != "http://gcp" {
+		t.Fatalf("endpoint = %s, %v; want the halted node skipped even though pinned", endpoint, err)
+	}
+
+	lb.SetHaltedEndpoints(map[string]bool{"http://aws": true, "http://gcp": true})
+	if endpoint, err := lb.SelectEndpoint(context.Background(), "llama"); err != nil || endpoint != "" {
+		t.Fatalf("endpoint = %s, %v; want no endpoint", endpoint, err)
+	}
+}
@@ -0,0 +1,486 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxModelPriceChanges bounds one batch price update
+	maxModelPriceChanges = 500
+
+	// priceImpactWindow is the usage a price update's revenue impact is
+	// estimated from
+	priceImpactWindow = 30 * 24 * time.Hour
+
+	// priceEffectiveSkew is how far in the past effective_at may be, for
+	// clock skew; older times would reprice usage already billed
+	priceEffectiveSkew = time.Minute
+
+	// modelPriceInterval is how often scheduled price changes are applied
+	modelPriceInterval = time.Minute
+)
+
+// ModelPriceChange is one model's new token prices in a batch update. An
+// omitted price keeps the model's current one; an omitted effective_at
+// takes effect immediately.
+type ModelPriceChange struct {
+	ModelID     uuid.UUID  `json:"model_id"`
+	InputPrice  *float64   `json:"input_price,omitempty"`
+	OutputPrice *float64   `json:"output_price,omitempty"`
+	EffectiveAt *time.Time `json:"effective_at,omitempty"`
+}
+
+// ModelPriceUpdateRequest is the body of PATCH /api/v1/admin/models/prices
+type ModelPriceUpdateRequest struct {
+	Prices []ModelPriceChange `json:"prices"`
+	// DryRun validates the changes and reports their revenue impact
+	// without saving them
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// modelPriceError points at the change in a batch that failed validation
+type modelPriceError struct {
+	Index   int    `json:"index"`
+	ModelID string `json:"model_id,omitempty"`
+	Message string `json:"message"`
+}
+
+// ModelPriceImpact is a price change with the revenue it would have made
+// over the last 30 days of usage, at list prices before region multipliers
+type ModelPriceImpact struct {
+	ID                  *uuid.UUID `json:"id,omitempty"`
+	ModelID             uuid.UUID  `json:"model_id"`
+	Model               string     `json:"model"`
+	CurrentInputPrice   float64    `json:"current_input_price"`
+	CurrentOutputPrice  float64    `json:"current_output_price"`
+	InputPrice          float64    `json:"input_price"`
+	OutputPrice         float64    `json:"output_price"`
+	EffectiveAt         time.Time  `json:"effective_at"`
+	Status              string     `json:"status"` // applied or scheduled
+	PromptTokens        int64      `json:"prompt_tokens_30d"`
+	CompletionTokens    int64      `json:"completion_tokens_30d"`
+	CurrentRevenueUSD   float64    `json:"current_revenue_usd"`
+	ProjectedRevenueUSD float64    `json:"projected_revenue_usd"`
+	ChangeUSD           float64    `json:"change_usd"`
+	ChangePercent       *float64   `json:"change_percent,omitempty"`
+}
+
+// ModelPriceSummary totals the revenue impact of a batch
+type ModelPriceSummary struct {
+	Models              int      `json:"models"`
+	Immediate           int      `json:"immediate"`
+	Scheduled           int      `json:"scheduled"`
+	CurrentRevenueUSD   float64  `json:"current_revenue_usd"`
+	ProjectedRevenueUSD float64  `json:"projected_revenue_usd"`
+	ChangeUSD           float64  `json:"change_usd"`
+	ChangePercent       *float64 `json:"change_percent,omitempty"`
+}
+
+// currentModelPrice is a model's name and current token prices
+type currentModelPrice struct {
+	Name   string
+	Input  float64
+	Output float64
+}
+
+// modelUsageTotals is a model's token usage over the impact window
+type modelUsageTotals struct {
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// HandleUpdateModelPrices handles PATCH /api/v1/admin/models/prices. It
+// sets the token prices of many models at once, each effective now or at a
+// later time, and reports the revenue impact; with dry_run it only reports.
+func (g *Gateway) HandleUpdateModelPrices(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req ModelPriceUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	now := time.Now()
+	problems := validateModelPriceChanges(req.Prices, now)
+	if len(problems) > 0 {
+		g.writeModelPriceErrors(w, problems)
+		return
+	}
+
+	ids := make([]uuid.UUID, len(req.Prices))
+	for i, change := range req.Prices {
+		ids[i] = change.ModelID
+	}
+	current, err := g.currentModelPrices(ctx, ids)
+	if err != nil {
+		g.logger.Error("failed to load model prices", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load model prices")
+		return
+	}
+	for i, change := range req.Prices {
+		if _, ok := current[change.ModelID]; !ok {
+			problems = append(problems, modelPriceError{Index: i, ModelID: change.ModelID.String(), Message: "model not found"})
+		}
+	}
+	if len(problems) > 0 {
+		g.writeModelPriceErrors(w, problems)
+		return
+	}
+
+	usage, err := g.modelUsageSince(ctx, ids, now.Add(-priceImpactWindow))
+	if err != nil {
+		g.logger.Error("failed to load model usage", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to estimate revenue impact")
+		return
+	}
+	impacts, summary := modelPriceImpacts(req.Prices, current, usage, now)
+
+	if !req.DryRun {
+		if err := g.saveModelPriceChanges(ctx, impacts, adminActor(ctx)); err != nil {
+			g.logger.Error("failed to save model price changes", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to update model prices")
+			return
+		}
+		for _, impact := range impacts {
+			if impact.Status == "applied" {
+				g.modelPricesChanged(ctx, impact.Model)
+			}
+		}
+		g.logger.Info("model prices updated",
+			zap.Int("models", summary.Models),
+			zap.Int("scheduled", summary.Scheduled),
+		)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"dry_run": req.DryRun,
+		"data":    impacts,
+		"summary": summary,
+	})
+}
+
+func (g *Gateway) writeModelPriceErrors(w http.ResponseWriter, problems []modelPriceError) {
+	g.writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": "invalid price changes",
+			"type":    "invalid_request_error",
+			"details": problems,
+		},
+	})
+}
+
+// validateModelPriceChanges checks a batch without the database. Each model
+// may appear once, so the batch's revenue impact is unambiguous.
+func validateModelPriceChanges(changes []ModelPriceChange, now time.Time) []modelPriceError {
+	if len(changes) == 0 {
+		return []modelPriceError{{Index: -1, Message: "prices must list at least one change"}}
+	}
+	if len(changes) > maxModelPriceChanges {
+		return []modelPriceError{{Index: -1, Message: fmt.Sprintf("at most %d price changes per request", maxModelPriceChanges)}}
+	}
+
+	var problems []modelPriceError
+	seen := make(map[uuid.UUID]bool, len(changes))
+	for i, change := range changes {
+		fail := func(message string) {
+			problems = append(problems, modelPriceError{Index: i, ModelID: change.ModelID.String(), Message: message})
+		}
+		switch {
+		case change.ModelID == uuid.Nil:
+			fail("model_id is required")
+		case seen[change.ModelID]:
+			fail("model appears more than once")
+		case change.InputPrice == nil && change.OutputPrice == nil:
+			fail("input_price or output_price is required")
+		case change.InputPrice != nil && !validPrice(*change.InputPrice):
+			fail("input_price must be a non-negative number")
+		case change.OutputPrice != nil && !validPrice(*change.OutputPrice):
+			fail("output_price must be a non-negative number")
+		case change.EffectiveAt != nil && change.EffectiveAt.Before(now.Add(-priceEffectiveSkew)):
+			fail("effective_at must not be in the past")
+		}
+		seen[change.ModelID] = true
+	}
+	return problems
+}
+
+func validPrice(price float64) bool {
+	return price >= 0 && !math.IsInf(price, 0) && !math.IsNaN(price)
+}
+
+// modelPriceImpacts resolves each change against the model's current
+// prices and estimates its revenue impact from the model's recent usage
+func modelPriceImpacts(changes []ModelPriceChange, current map[uuid.UUID]currentModelPrice, usage map[uuid.UUID]modelUsageTotals, now time.Time) ([]ModelPriceImpact, ModelPriceSummary) {
+	impacts := make([]ModelPriceImpact, 0, len(changes))
+	var summary ModelPriceSummary
+	for _, change := range changes {
+		model := current[change.ModelID]
+		impact := ModelPriceImpact{
+			ModelID:            change.ModelID,
+			Model:              model.Name,
+			CurrentInputPrice:  model.Input,
+			CurrentOutputPrice: model.Output,
+			InputPrice:         model.Input,
+			OutputPrice:        model.Output,
+			EffectiveAt:        now,
+			Status:             "applied",
+			PromptTokens:       usage[change.ModelID].PromptTokens,
+			CompletionTokens:   usage[change.ModelID].CompletionTokens,
+		}
+		if change.InputPrice != nil {
+			impact.InputPrice = *change.InputPrice
+		}
+		if change.OutputPrice != nil {
+			impact.OutputPrice = *change.OutputPrice
+		}
+		if change.EffectiveAt != nil && change.EffectiveAt.After(now) {
+			impact.EffectiveAt = *change.EffectiveAt
+			impact.Status = "scheduled"
+			summary.Scheduled++
+		} else {
+			summary.Immediate++
+		}
+
+		current := tokenRevenueUSD(impact.PromptTokens, impact.CompletionTokens, impact.CurrentInputPrice, impact.CurrentOutputPrice)
+		projected := tokenRevenueUSD(impact.PromptTokens, impact.CompletionTokens, impact.InputPrice, impact.OutputPrice)
+		impact.CurrentRevenueUSD = roundCents(current)
+		impact.ProjectedRevenueUSD = roundCents(projected)
+		impact.ChangeUSD = roundCents(projected - current)
+		impact.ChangePercent = percentChange(current, projected)
+		impacts = append(impacts, impact)
+
+		summary.CurrentRevenueUSD += current
+		summary.ProjectedRevenueUSD += projected
+	}
+
+	summary.Models = len(impacts)
+	summary.ChangePercent = percentChange(summary.CurrentRevenueUSD, summary.ProjectedRevenueUSD)
+	summary.ChangeUSD = roundCents(summary.ProjectedRevenueUSD - summary.CurrentRevenueUSD)
+	summary.CurrentRevenueUSD = roundCents(summary.CurrentRevenueUSD)
+	summary.ProjectedRevenueUSD = roundCents(summary.ProjectedRevenueUSD)
+	return impacts, summary
+}
+
+// tokenRevenueUSD prices token counts at per-million prices
+func tokenRevenueUSD(promptTokens, completionTokens int64, input, output float64) float64 {
+	return (float64(promptTokens)*input + float64(completionTokens)*output) / 1_000_000
+}
+
+func roundCents(usd float64) float64 {
+	return math.Round(usd*100) / 100
+}
+
+// percentChange is the change from before to after in percent, nil when
+// there was nothing before to compare with
+func percentChange(before, after float64) *float64 {
+	if before == 0 {
+		return nil
+	}
+	percent := math.Round((after-before)/before*10000) / 100
+	return &percent
+}
+
+// currentModelPrices loads the names and token prices of models by ID
+func (g *Gateway) currentModelPrices(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]currentModelPrice, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, name, price_input_per_million::float8, price_output_per_million::float8
+		FROM models WHERE id = ANY($1)
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[uuid.UUID]currentModelPrice, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var price currentModelPrice
+		if err := rows.Scan(&id, &price.Name, &price.Input, &price.Output); err != nil {
+			return nil, err
+		}
+		prices[id] = price
+	}
+	return prices, rows.Err()
+}
+
+// modelUsageSince totals the tokens each model served since a time
+func (g *Gateway) modelUsageSince(ctx context.Context, ids []uuid.UUID, since time.Time) (map[uuid.UUID]modelUsageTotals, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT model_id, COALESCE(SUM(prompt_tokens), 0)::bigint, COALESCE(SUM(completion_tokens), 0)::bigint
+		FROM usage_records
+		WHERE model_id = ANY($1) AND timestamp >= $2
+		GROUP BY model_id
+	`, ids, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[uuid.UUID]modelUsageTotals, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var totals modelUsageTotals
+		if err := rows.Scan(&id, &totals.PromptTokens, &totals.CompletionTokens); err != nil {
+			return nil, err
+		}
+		usage[id] = totals
+	}
+	return usage, rows.Err()
+}
+
+// saveModelPriceChanges records a batch in the price history and applies
+// the changes already in effect, all or nothing. The IDs of the recorded
+// changes are set on impacts.
+func (g *Gateway) saveModelPriceChanges(ctx context.Context, impacts []ModelPriceImpact, actor *string) error {
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range impacts {
+		impact := &impacts[i]
+		var id uuid.UUID
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO model_price_changes (model_id, price_input_per_million, price_output_per_million, effective_at, created_by)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, impact.ModelID, impact.InputPrice, impact.OutputPrice, impact.EffectiveAt, actor).Scan(&id); err != nil {
+			return err
+		}
+		impact.ID = &id
+
+		if impact.Status == "applied" {
+			if _, err := applyModelPriceChange(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// applyModelPriceChange writes a recorded change's prices to its model,
+// keeping the prices it replaces, and returns the model's name
+func applyModelPriceChange(ctx context.Context, tx database.Querier, id uuid.UUID) (string, error) {
+	var model string
+	err := tx.QueryRow(ctx, `
+		UPDATE model_price_changes c
+		SET applied_at = NOW(),
+		    previous_input_per_million = m.price_input_per_million,
+		    previous_output_per_million = m.price_output_per_million
+		FROM models m
+		WHERE c.id = $1 AND m.id = c.model_id
+		RETURNING m.name
+	`, id).Scan(&model)
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE models m
+		SET price_input_per_million = c.price_input_per_million,
+		    price_output_per_million = c.price_output_per_million,
+		    updated_at = NOW()
+		FROM model_price_changes c
+		WHERE c.id = $1 AND m.id = c.model_id
+	`, id)
+	return model, err
+}
+
+// modelPricesChanged drops cached pricing of a model after its prices change
+func (g *Gateway) modelPricesChanged(ctx context.Context, model string) {
+	g.modelCapabilities.invalidate(model)
+	g.publishCatalogChanged(ctx, model, "updated")
+}
+
+// StartModelPriceChanges starts the loop applying scheduled price changes
+// as they come due
+func (g *Gateway) StartModelPriceChanges(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(modelPriceInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := g.applyDueModelPrices(ctx); err != nil {
+					g.logger.Error("failed to apply scheduled model prices", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// applyDueModelPrices applies the scheduled price changes now in effect,
+// oldest first. Rows are locked while applied, so replicas running this at
+// once apply each change only once.
+func (g *Gateway) applyDueModelPrices(ctx context.Context) error {
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM model_price_changes
+		WHERE applied_at IS NULL AND effective_at <= NOW()
+		ORDER BY effective_at
+		FOR UPDATE SKIP LOCKED
+	`)
+	if err != nil {
+		return err
+	}
+	var due []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	changed := make(map[string]bool)
+	for _, id := range due {
+		model, err := applyModelPriceChange(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		changed[model] = true
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	for model := range changed {
+		g.modelPricesChanged(ctx, model)
+	}
+	g.logger.Info("applied scheduled model prices", zap.Int("changes", len(due)))
+	return nil
+}
+
+// adminActor is the admin token name behind a request, if any
+func adminActor(ctx context.Context) *string {
+	if name, ok := ctx.Value("admin_token").(string); ok && name != "" {
+		return &name
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func priceRef(v float64) *float64 { return &v }
+
+func TestValidateModelPriceChanges(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	model := uuid.New()
+	past := now.Add(-time.Hour)
+
+	if problems := validateModelPriceChanges(nil, now); len(problems) != 1 || problems[0].Index != -1 {
+		t.Errorf("empty batch: %v", problems)
+	}
+
+	problems := validateModelPriceChanges([]ModelPriceChange{
+		{ModelID: model, InputPrice: priceRef(0.5)},
+		{ModelID: model, InputPrice: priceRef(0.6)},
+		{ModelID: uuid.New()},
+		{ModelID: uuid.New(), OutputPrice: priceRef(-1)},
+		{ModelID: uuid.New(), InputPrice: priceRef(1), EffectiveAt: &past},
+		{InputPrice: priceRef(1)},
+	}, now)
+	want := map[int]string{
+		1: "model appears more than once",
+		2: "input_price or output_price is required",
+		3: "output_price must be a non-negative number",
+		4: "effective_at must not be in the past",
+		5: "model_id is required",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %v", problems)
+	}
+	for _, problem := range problems {
+		if want[problem.Index] != problem.Message {
+			t.Errorf("change %d: %q, want %q", problem.Index, problem.Message, want[problem.Index])
+		}
+	}
+}
+
+func TestModelPriceImpacts(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(24 * time.Hour)
+	llama, qwen := uuid.New(), uuid.New()
+
+	current := map[uuid.UUID]currentModelPrice{
+		llama: {Name: "llama", Input: 1, Output: 2},
+		qwen:  {Name: "qwen", Input: 0.5, Output: 0.5},
+	}
+	usage := map[uuid.UUID]modelUsageTotals{
+		llama: {PromptTokens: 10_000_000, CompletionTokens: 5_000_000},
+	}
+
+	impacts, summary := modelPriceImpacts([]ModelPriceChange{
+		{ModelID: llama, OutputPrice: priceRef(3)},
+		{ModelID: qwen, InputPrice: priceRef(0.25), OutputPrice: priceRef(0.25), EffectiveAt: &later},
+	}, current, usage, now)
+
+	got := impacts[0]
+	if got.Status != "applied" || got.InputPrice != 1 || got.OutputPrice != 3 || !got.EffectiveAt.Equal(now) {
+		t.Errorf("llama change = %+v", got)
+	}
+	// 10M input at $1 + 5M output at $2 -> $20; output at $3 -> $25
+	if got.CurrentRevenueUSD != 20 || got.ProjectedRevenueUSD != 25 || got.ChangeUSD != 5 || *got.ChangePercent != 25 {
+		t.Errorf("llama impact = %+v", got)
+	}
+
+	if impacts[1].Status != "scheduled" || impacts[1].ChangePercent != nil || impacts[1].ProjectedRevenueUSD != 0 {
+		t.Errorf("unused qwen change = %+v", impacts[1])
+	}
+	if summary.Models != 2 || summary.Immediate != 1 || summary.Scheduled != 1 || summary.ChangeUSD != 5 {
+		t.Errorf("summary = %+v", summary)
+	}
+}
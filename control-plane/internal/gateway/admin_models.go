@@ -16,6 +16,7 @@ import (
 // Mock launch job tracker for demo purposes
 type LaunchJob struct {
 	JobID       string
+	NodeID      string
 	Status      string
 	Progress    int
 	Stage       string
@@ -150,6 +151,7 @@ func (g *Gateway) LaunchModelInstanceHandler(w http.ResponseWriter, r *http.Requ
 		// Create job tracker for UI status
 		job := &LaunchJob{
 			JobID:     jobID,
+			NodeID:    nodeID,
 			Status:    "in_progress",
 			Progress:  0,
 			Stage:     "validating",
@@ -328,9 +330,7 @@ func (g *Gateway) GetLaunchStatusHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	
-	// Return current job status
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	resp := map[string]interface{}{
 		"job_id":   job.JobID,
 		"status":   job.Status,
 		"stage":    job.Stage,
@@ -338,7 +338,20 @@ func (g *Gateway) GetLaunchStatusHandler(w http.ResponseWriter, r *http.Request)
 		"stages":   job.Stages,
 		"model":    job.ModelName,
 		"elapsed":  time.Since(job.StartTime).Seconds(),
-	})
+	}
+
+	// Launches waiting on the provider/region concurrency limit report where
+	// they are in the queue
+	if g.orchestrator != nil && job.NodeID != "" {
+		if position := g.orchestrator.LaunchQueue().Position(job.NodeID); position > 0 {
+			resp["stage"] = "queued"
+			resp["queue_position"] = position
+		}
+	}
+
+	// Return current job status
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
 // Helper functions
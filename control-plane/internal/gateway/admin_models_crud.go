@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/crosslogic/control-plane/internal/repository"
 	"github.com/crosslogic/control-plane/pkg/models"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -28,6 +29,13 @@ type ModelResponse struct {
 	PriceOutputPerMillion   float64                `json:"price_output_per_million"`
 	TokensPerSecondCapacity *int                   `json:"tokens_per_second_capacity,omitempty"`
 	Status                  string                 `json:"status"`
+	SupportsTools           bool                   `json:"supports_tools"`
+	SupportsVision          bool                   `json:"supports_vision"`
+	SupportsJSONMode        bool                   `json:"supports_json_mode"`
+	SupportsGuidedDecoding  bool                   `json:"supports_guided_decoding"`
+	MaxOutputTokens         *int                   `json:"max_output_tokens,omitempty"`
+	PricePerAudioMinute     *float64               `json:"price_per_audio_minute,omitempty"`
+	PricePerImage           *float64               `json:"price_per_image,omitempty"`
 	Metadata                map[string]interface{} `json:"metadata"`
 	CreatedAt               time.Time              `json:"created_at"`
 	UpdatedAt               time.Time              `json:"updated_at"`
@@ -45,6 +53,13 @@ type ModelCreateRequest struct {
 	PriceOutputPerMillion   float64                `json:"price_output_per_million"`
 	TokensPerSecondCapacity *int                   `json:"tokens_per_second_capacity,omitempty"`
 	Status                  string                 `json:"status,omitempty"`
+	SupportsTools           bool                   `json:"supports_tools,omitempty"`
+	SupportsVision          bool                   `json:"supports_vision,omitempty"`
+	SupportsJSONMode        *bool                  `json:"supports_json_mode,omitempty"`
+	SupportsGuidedDecoding  bool                   `json:"supports_guided_decoding,omitempty"`
+	MaxOutputTokens         *int                   `json:"max_output_tokens,omitempty"`
+	PricePerAudioMinute     *float64               `json:"price_per_audio_minute,omitempty"`
+	PricePerImage           *float64               `json:"price_per_image,omitempty"`
 	Metadata                map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -60,6 +75,13 @@ type ModelUpdateRequest struct {
 	PriceOutputPerMillion   float64                `json:"price_output_per_million"`
 	TokensPerSecondCapacity *int                   `json:"tokens_per_second_capacity,omitempty"`
 	Status                  string                 `json:"status"`
+	SupportsTools           bool                   `json:"supports_tools"`
+	SupportsVision          bool                   `json:"supports_vision"`
+	SupportsJSONMode        bool                   `json:"supports_json_mode"`
+	SupportsGuidedDecoding  bool                   `json:"supports_guided_decoding"`
+	MaxOutputTokens         *int                   `json:"max_output_tokens,omitempty"`
+	PricePerAudioMinute     *float64               `json:"price_per_audio_minute,omitempty"`
+	PricePerImage           *float64               `json:"price_per_image,omitempty"`
 	Metadata                map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -75,6 +97,13 @@ type ModelPatchRequest struct {
 	PriceOutputPerMillion   *float64                `json:"price_output_per_million,omitempty"`
 	TokensPerSecondCapacity *int                    `json:"tokens_per_second_capacity,omitempty"`
 	Status                  *string                 `json:"status,omitempty"`
+	SupportsTools           *bool                   `json:"supports_tools,omitempty"`
+	SupportsVision          *bool                   `json:"supports_vision,omitempty"`
+	SupportsJSONMode        *bool                   `json:"supports_json_mode,omitempty"`
+	SupportsGuidedDecoding  *bool                   `json:"supports_guided_decoding,omitempty"`
+	MaxOutputTokens         *int                    `json:"max_output_tokens,omitempty"`
+	PricePerAudioMinute     *float64                `json:"price_per_audio_minute,omitempty"`
+	PricePerImage           *float64                `json:"price_per_image,omitempty"`
 	Metadata                *map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -110,15 +139,7 @@ func (g *Gateway) HandleListModels(w http.ResponseWriter, r *http.Request) {
 		sortOrder = "asc"
 	}
 
-	// Validate sort fields
-	validSortFields := map[string]bool{
-		"name":             true,
-		"family":           true,
-		"created_at":       true,
-		"updated_at":       true,
-		"vram_required_gb": true,
-	}
-	if !validSortFields[sortBy] {
+	if !repository.ModelSortColumns[sortBy] {
 		g.writeError(w, http.StatusBadRequest, "invalid sort_by field")
 		return
 	}
@@ -128,104 +149,25 @@ func (g *Gateway) HandleListModels(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build query
-	queryBuilder := strings.Builder{}
-	queryBuilder.WriteString(`
-		SELECT id, name, family, size, type, context_length,
-		       vram_required_gb, price_input_per_million, price_output_per_million,
-		       tokens_per_second_capacity, status, metadata, created_at, updated_at
-		FROM models
-		WHERE 1=1
-	`)
-
-	countBuilder := strings.Builder{}
-	countBuilder.WriteString("SELECT COUNT(*) FROM models WHERE 1=1")
-
-	args := []interface{}{}
-	argIndex := 1
-
-	// Add filters
-	if family != "" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND family = $%d", argIndex))
-		countBuilder.WriteString(fmt.Sprintf(" AND family = $%d", argIndex))
-		args = append(args, family)
-		argIndex++
-	}
-
-	if modelType != "" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND type = $%d", argIndex))
-		countBuilder.WriteString(fmt.Sprintf(" AND type = $%d", argIndex))
-		args = append(args, modelType)
-		argIndex++
-	}
-
-	if status != "" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND status = $%d", argIndex))
-		countBuilder.WriteString(fmt.Sprintf(" AND status = $%d", argIndex))
-		args = append(args, status)
-		argIndex++
-	}
-
-	if minVRAM > 0 {
-		queryBuilder.WriteString(fmt.Sprintf(" AND vram_required_gb >= $%d", argIndex))
-		countBuilder.WriteString(fmt.Sprintf(" AND vram_required_gb >= $%d", argIndex))
-		args = append(args, minVRAM)
-		argIndex++
-	}
-
-	if maxVRAM > 0 {
-		queryBuilder.WriteString(fmt.Sprintf(" AND vram_required_gb <= $%d", argIndex))
-		countBuilder.WriteString(fmt.Sprintf(" AND vram_required_gb <= $%d", argIndex))
-		args = append(args, maxVRAM)
-		argIndex++
-	}
-
-	if search != "" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND name ILIKE $%d", argIndex))
-		countBuilder.WriteString(fmt.Sprintf(" AND name ILIKE $%d", argIndex))
-		args = append(args, "%"+search+"%")
-		argIndex++
-	}
-
-	// Add sorting
-	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY %s %s", sortBy, strings.ToUpper(sortOrder)))
-
-	// Add pagination
-	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1))
-	args = append(args, limit, offset)
-
-	// Get total count
-	var total int
-	err := g.db.Pool.QueryRow(ctx, countBuilder.String(), args[:len(args)-2]...).Scan(&total)
-	if err != nil {
-		g.logger.Error("failed to count models", zap.Error(err))
-		g.writeError(w, http.StatusInternalServerError, "failed to count models")
-		return
-	}
-
-	// Execute query
-	rows, err := g.db.Pool.Query(ctx, queryBuilder.String(), args...)
+	list, total, err := g.store.Models.List(ctx, repository.ModelFilter{
+		Family:     family,
+		Type:       modelType,
+		Status:     status,
+		MinVRAMGB:  minVRAM,
+		MaxVRAMGB:  maxVRAM,
+		Search:     search,
+		SortBy:     sortBy,
+		Descending: sortOrder == "desc",
+	}, repository.Page{Limit: limit, Offset: offset})
 	if err != nil {
 		g.logger.Error("failed to query models", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to query models")
 		return
 	}
-	defer rows.Close()
 
 	modelsList := []ModelResponse{}
-	for rows.Next() {
-		var m models.Model
-		var metadataJSON []byte
-
-		err := rows.Scan(
-			&m.ID, &m.Name, &m.Family, &m.Size, &m.Type, &m.ContextLength,
-			&m.VRAMRequiredGB, &m.PriceInputPerMillion, &m.PriceOutputPerMillion,
-			&m.TokensPerSecondCapacity, &m.Status, &metadataJSON, &m.CreatedAt, &m.UpdatedAt,
-		)
-		if err != nil {
-			g.logger.Error("failed to scan model", zap.Error(err))
-			continue
-		}
+	for _, m := range list {
+		metadataJSON := []byte(m.Metadata)
 
 		// Parse metadata JSON
 		var metadata map[string]interface{}
@@ -250,6 +192,13 @@ func (g *Gateway) HandleListModels(w http.ResponseWriter, r *http.Request) {
 			PriceOutputPerMillion:   m.PriceOutputPerMillion,
 			TokensPerSecondCapacity: m.TokensPerSecondCapacity,
 			Status:                  m.Status,
+			SupportsTools:           m.SupportsTools,
+			SupportsVision:          m.SupportsVision,
+			SupportsJSONMode:        m.SupportsJSONMode,
+			SupportsGuidedDecoding:  m.SupportsGuidedDecoding,
+			MaxOutputTokens:         m.MaxOutputTokens,
+			PricePerAudioMinute:     m.PricePerAudioMinute,
+			PricePerImage:           m.PricePerImage,
 			Metadata:                metadata,
 			CreatedAt:               m.CreatedAt,
 			UpdatedAt:               m.UpdatedAt,
@@ -289,7 +238,8 @@ func (g *Gateway) HandleGetModel(w http.ResponseWriter, r *http.Request) {
 	query := `
 		SELECT id, name, family, size, type, context_length,
 		       vram_required_gb, price_input_per_million, price_output_per_million,
-		       tokens_per_second_capacity, status, metadata, created_at, updated_at
+		       tokens_per_second_capacity, status, supports_tools, supports_vision, supports_json_mode,
+		       supports_guided_decoding, max_output_tokens, price_per_audio_minute::float8, price_per_image::float8, metadata, created_at, updated_at
 		FROM models
 		WHERE id = $1
 	`
@@ -297,7 +247,8 @@ func (g *Gateway) HandleGetModel(w http.ResponseWriter, r *http.Request) {
 	err = g.db.Pool.QueryRow(ctx, query, modelID).Scan(
 		&m.ID, &m.Name, &m.Family, &m.Size, &m.Type, &m.ContextLength,
 		&m.VRAMRequiredGB, &m.PriceInputPerMillion, &m.PriceOutputPerMillion,
-		&m.TokensPerSecondCapacity, &m.Status, &metadataJSON, &m.CreatedAt, &m.UpdatedAt,
+		&m.TokensPerSecondCapacity, &m.Status, &m.SupportsTools, &m.SupportsVision, &m.SupportsJSONMode,
+		&m.SupportsGuidedDecoding, &m.MaxOutputTokens, &m.PricePerAudioMinute, &m.PricePerImage, &metadataJSON, &m.CreatedAt, &m.UpdatedAt,
 	)
 
 	if err != nil {
@@ -333,6 +284,13 @@ func (g *Gateway) HandleGetModel(w http.ResponseWriter, r *http.Request) {
 		PriceOutputPerMillion:   m.PriceOutputPerMillion,
 		TokensPerSecondCapacity: m.TokensPerSecondCapacity,
 		Status:                  m.Status,
+		SupportsTools:           m.SupportsTools,
+		SupportsVision:          m.SupportsVision,
+		SupportsJSONMode:        m.SupportsJSONMode,
+		SupportsGuidedDecoding:  m.SupportsGuidedDecoding,
+		MaxOutputTokens:         m.MaxOutputTokens,
+		PricePerAudioMinute:     m.PricePerAudioMinute,
+		PricePerImage:           m.PricePerImage,
 		Metadata:                metadata,
 		CreatedAt:               m.CreatedAt,
 		UpdatedAt:               m.UpdatedAt,
@@ -363,6 +321,12 @@ func (g *Gateway) HandleCreateModel(w http.ResponseWriter, r *http.Request) {
 		req.Status = "active"
 	}
 
+	// vLLM serves response_format JSON mode out of the box
+	if req.SupportsJSONMode == nil {
+		supportsJSONMode := true
+		req.SupportsJSONMode = &supportsJSONMode
+	}
+
 	// Serialize metadata
 	var metadataJSON []byte
 	var err error
@@ -381,8 +345,9 @@ func (g *Gateway) HandleCreateModel(w http.ResponseWriter, r *http.Request) {
 		INSERT INTO models (
 			name, family, size, type, context_length, vram_required_gb,
 			price_input_per_million, price_output_per_million, tokens_per_second_capacity,
-			status, metadata
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			status, supports_tools, supports_vision, supports_json_mode,
+			supports_guided_decoding, max_output_tokens, metadata, price_per_audio_minute, price_per_image
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -392,7 +357,8 @@ func (g *Gateway) HandleCreateModel(w http.ResponseWriter, r *http.Request) {
 	err = g.db.Pool.QueryRow(ctx, query,
 		req.Name, req.Family, req.Size, req.Type, req.ContextLength, req.VRAMRequiredGB,
 		req.PriceInputPerMillion, req.PriceOutputPerMillion, req.TokensPerSecondCapacity,
-		req.Status, metadataJSON,
+		req.Status, req.SupportsTools, req.SupportsVision, *req.SupportsJSONMode,
+		req.SupportsGuidedDecoding, req.MaxOutputTokens, metadataJSON, req.PricePerAudioMinute, req.PricePerImage,
 	).Scan(&modelID, &createdAt, &updatedAt)
 
 	if err != nil {
@@ -405,6 +371,8 @@ func (g *Gateway) HandleCreateModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	g.publishCatalogChanged(ctx, req.Name, "created")
+
 	g.logger.Info("model created successfully",
 		zap.String("model_id", modelID.String()),
 		zap.String("name", req.Name),
@@ -423,6 +391,13 @@ func (g *Gateway) HandleCreateModel(w http.ResponseWriter, r *http.Request) {
 		PriceOutputPerMillion:   req.PriceOutputPerMillion,
 		TokensPerSecondCapacity: req.TokensPerSecondCapacity,
 		Status:                  req.Status,
+		SupportsTools:           req.SupportsTools,
+		SupportsVision:          req.SupportsVision,
+		SupportsJSONMode:        *req.SupportsJSONMode,
+		SupportsGuidedDecoding:  req.SupportsGuidedDecoding,
+		MaxOutputTokens:         req.MaxOutputTokens,
+		PricePerAudioMinute:     req.PricePerAudioMinute,
+		PricePerImage:           req.PricePerImage,
 		Metadata:                req.Metadata,
 		CreatedAt:               createdAt,
 		UpdatedAt:               updatedAt,
@@ -473,17 +448,21 @@ func (g *Gateway) HandleUpdateModel(w http.ResponseWriter, r *http.Request) {
 		UPDATE models SET
 			name = $1, family = $2, size = $3, type = $4, context_length = $5,
 			vram_required_gb = $6, price_input_per_million = $7, price_output_per_million = $8,
-			tokens_per_second_capacity = $9, status = $10, metadata = $11, updated_at = NOW()
-		WHERE id = $12
-		RETURNING updated_at
+			tokens_per_second_capacity = $9, status = $10, supports_tools = $11, supports_vision = $12,
+			supports_json_mode = $13, supports_guided_decoding = $14, max_output_tokens = $15,
+			metadata = $16, price_per_audio_minute = $18, price_per_image = $19, updated_at = NOW()
+		WHERE id = $17
+		RETURNING name, updated_at
 	`
 
+	var modelName string
 	var updatedAt time.Time
 	err = g.db.Pool.QueryRow(ctx, query,
 		req.Name, req.Family, req.Size, req.Type, req.ContextLength, req.VRAMRequiredGB,
 		req.PriceInputPerMillion, req.PriceOutputPerMillion, req.TokensPerSecondCapacity,
-		req.Status, metadataJSON, modelID,
-	).Scan(&updatedAt)
+		req.Status, req.SupportsTools, req.SupportsVision, req.SupportsJSONMode,
+		req.SupportsGuidedDecoding, req.MaxOutputTokens, metadataJSON, modelID, req.PricePerAudioMinute, req.PricePerImage,
+	).Scan(&modelName, &updatedAt)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -495,6 +474,10 @@ func (g *Gateway) HandleUpdateModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	g.modelCapabilities.invalidate(modelName)
+
+	g.publishCatalogChanged(ctx, modelName, "updated")
+
 	g.logger.Info("model updated successfully", zap.String("model_id", modelID.String()))
 
 	// Return updated model (fetch it to get created_at)
@@ -585,6 +568,48 @@ func (g *Gateway) HandlePatchModel(w http.ResponseWriter, r *http.Request) {
 		argIndex++
 	}
 
+	if req.SupportsTools != nil {
+		updates = append(updates, fmt.Sprintf("supports_tools = $%d", argIndex))
+		args = append(args, *req.SupportsTools)
+		argIndex++
+	}
+
+	if req.SupportsVision != nil {
+		updates = append(updates, fmt.Sprintf("supports_vision = $%d", argIndex))
+		args = append(args, *req.SupportsVision)
+		argIndex++
+	}
+
+	if req.SupportsJSONMode != nil {
+		updates = append(updates, fmt.Sprintf("supports_json_mode = $%d", argIndex))
+		args = append(args, *req.SupportsJSONMode)
+		argIndex++
+	}
+
+	if req.SupportsGuidedDecoding != nil {
+		updates = append(updates, fmt.Sprintf("supports_guided_decoding = $%d", argIndex))
+		args = append(args, *req.SupportsGuidedDecoding)
+		argIndex++
+	}
+
+	if req.MaxOutputTokens != nil {
+		updates = append(updates, fmt.Sprintf("max_output_tokens = $%d", argIndex))
+		args = append(args, *req.MaxOutputTokens)
+		argIndex++
+	}
+
+	if req.PricePerAudioMinute != nil {
+		updates = append(updates, fmt.Sprintf("price_per_audio_minute = $%d", argIndex))
+		args = append(args, *req.PricePerAudioMinute)
+		argIndex++
+	}
+
+	if req.PricePerImage != nil {
+		updates = append(updates, fmt.Sprintf("price_per_image = $%d", argIndex))
+		args = append(args, *req.PricePerImage)
+		argIndex++
+	}
+
 	if req.Metadata != nil {
 		metadataJSON, err := json.Marshal(*req.Metadata)
 		if err != nil {
@@ -609,13 +634,14 @@ func (g *Gateway) HandlePatchModel(w http.ResponseWriter, r *http.Request) {
 
 	// Execute update
 	query := fmt.Sprintf(
-		"UPDATE models SET %s WHERE id = $%d RETURNING updated_at",
+		"UPDATE models SET %s WHERE id = $%d RETURNING name, updated_at",
 		strings.Join(updates, ", "),
 		argIndex,
 	)
 
+	var modelName string
 	var updatedAt time.Time
-	err = g.db.Pool.QueryRow(ctx, query, args...).Scan(&updatedAt)
+	err = g.db.Pool.QueryRow(ctx, query, args...).Scan(&modelName, &updatedAt)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -627,6 +653,10 @@ func (g *Gateway) HandlePatchModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	g.modelCapabilities.invalidate(modelName)
+
+	g.publishCatalogChanged(ctx, modelName, "updated")
+
 	g.logger.Info("model patched successfully", zap.String("model_id", modelID.String()))
 
 	// Return updated model
@@ -674,6 +704,8 @@ func (g *Gateway) HandleDeleteModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	g.publishCatalogChanged(ctx, modelID.String(), "deleted")
+
 	g.logger.Info("model deleted successfully", zap.String("model_id", modelID.String()))
 
 	w.WriteHeader(http.StatusNoContent)
@@ -697,7 +729,8 @@ func (g *Gateway) HandleSearchModels(w http.ResponseWriter, r *http.Request) {
 	queryBuilder.WriteString(`
 		SELECT id, name, family, size, type, context_length,
 		       vram_required_gb, price_input_per_million, price_output_per_million,
-		       tokens_per_second_capacity, status, metadata, created_at, updated_at
+		       tokens_per_second_capacity, status, supports_tools, supports_vision, supports_json_mode,
+		       supports_guided_decoding, max_output_tokens, price_per_audio_minute::float8, price_per_image::float8, metadata, created_at, updated_at
 		FROM models
 		WHERE 1=1
 	`)
@@ -799,7 +832,8 @@ func (g *Gateway) HandleSearchModels(w http.ResponseWriter, r *http.Request) {
 		err := rows.Scan(
 			&m.ID, &m.Name, &m.Family, &m.Size, &m.Type, &m.ContextLength,
 			&m.VRAMRequiredGB, &m.PriceInputPerMillion, &m.PriceOutputPerMillion,
-			&m.TokensPerSecondCapacity, &m.Status, &metadataJSON, &m.CreatedAt, &m.UpdatedAt,
+			&m.TokensPerSecondCapacity, &m.Status, &m.SupportsTools, &m.SupportsVision, &m.SupportsJSONMode,
+			&m.SupportsGuidedDecoding, &m.MaxOutputTokens, &m.PricePerAudioMinute, &m.PricePerImage, &metadataJSON, &m.CreatedAt, &m.UpdatedAt,
 		)
 		if err != nil {
 			g.logger.Error("failed to scan model", zap.Error(err))
@@ -827,6 +861,13 @@ func (g *Gateway) HandleSearchModels(w http.ResponseWriter, r *http.Request) {
 			PriceOutputPerMillion:   m.PriceOutputPerMillion,
 			TokensPerSecondCapacity: m.TokensPerSecondCapacity,
 			Status:                  m.Status,
+			SupportsTools:           m.SupportsTools,
+			SupportsVision:          m.SupportsVision,
+			SupportsJSONMode:        m.SupportsJSONMode,
+			SupportsGuidedDecoding:  m.SupportsGuidedDecoding,
+			MaxOutputTokens:         m.MaxOutputTokens,
+			PricePerAudioMinute:     m.PricePerAudioMinute,
+			PricePerImage:           m.PricePerImage,
 			Metadata:                metadata,
 			CreatedAt:               m.CreatedAt,
 			UpdatedAt:               m.UpdatedAt,
@@ -849,6 +890,11 @@ func (g *Gateway) HandleSearchModels(w http.ResponseWriter, r *http.Request) {
 
 // Validation functions
 
+// validModelType reports whether t is a type the models table accepts
+func validModelType(t string) bool {
+	return t == "completion" || t == "chat" || t == "embedding" || t == "audio" || t == "image"
+}
+
 func validateModelCreate(req *ModelCreateRequest) error {
 	if req.Name == "" {
 		return fmt.Errorf("name is required")
@@ -859,8 +905,8 @@ func validateModelCreate(req *ModelCreateRequest) error {
 	if req.Type == "" {
 		return fmt.Errorf("type is required")
 	}
-	if req.Type != "completion" && req.Type != "chat" && req.Type != "embedding" {
-		return fmt.Errorf("type must be 'completion', 'chat', or 'embedding'")
+	if !validModelType(req.Type) {
+		return fmt.Errorf("type must be 'completion', 'chat', 'embedding', 'audio', or 'image'")
 	}
 	if req.ContextLength <= 0 {
 		return fmt.Errorf("context_length must be positive")
@@ -874,6 +920,15 @@ func validateModelCreate(req *ModelCreateRequest) error {
 	if req.PriceOutputPerMillion < 0 {
 		return fmt.Errorf("price_output_per_million must be non-negative")
 	}
+	if req.MaxOutputTokens != nil && (*req.MaxOutputTokens <= 0 || *req.MaxOutputTokens > req.ContextLength) {
+		return fmt.Errorf("max_output_tokens must be positive and at most context_length")
+	}
+	if req.PricePerAudioMinute != nil && *req.PricePerAudioMinute < 0 {
+		return fmt.Errorf("price_per_audio_minute must be non-negative")
+	}
+	if req.PricePerImage != nil && *req.PricePerImage < 0 {
+		return fmt.Errorf("price_per_image must be non-negative")
+	}
 	if req.Status != "" && req.Status != "active" && req.Status != "deprecated" && req.Status != "beta" {
 		return fmt.Errorf("status must be 'active', 'deprecated', or 'beta'")
 	}
@@ -890,8 +945,8 @@ func validateModelUpdate(req *ModelUpdateRequest) error {
 	if req.Type == "" {
 		return fmt.Errorf("type is required")
 	}
-	if req.Type != "completion" && req.Type != "chat" && req.Type != "embedding" {
-		return fmt.Errorf("type must be 'completion', 'chat', or 'embedding'")
+	if !validModelType(req.Type) {
+		return fmt.Errorf("type must be 'completion', 'chat', 'embedding', 'audio', or 'image'")
 	}
 	if req.ContextLength <= 0 {
 		return fmt.Errorf("context_length must be positive")
@@ -905,6 +960,15 @@ func validateModelUpdate(req *ModelUpdateRequest) error {
 	if req.PriceOutputPerMillion < 0 {
 		return fmt.Errorf("price_output_per_million must be non-negative")
 	}
+	if req.MaxOutputTokens != nil && (*req.MaxOutputTokens <= 0 || *req.MaxOutputTokens > req.ContextLength) {
+		return fmt.Errorf("max_output_tokens must be positive and at most context_length")
+	}
+	if req.PricePerAudioMinute != nil && *req.PricePerAudioMinute < 0 {
+		return fmt.Errorf("price_per_audio_minute must be non-negative")
+	}
+	if req.PricePerImage != nil && *req.PricePerImage < 0 {
+		return fmt.Errorf("price_per_image must be non-negative")
+	}
 	if req.Status != "active" && req.Status != "deprecated" && req.Status != "beta" {
 		return fmt.Errorf("status must be 'active', 'deprecated', or 'beta'")
 	}
@@ -0,0 +1,234 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// NodeExecRequest is the body for running a command on a node
+type NodeExecRequest struct {
+	Command        string `json:"command"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"` // default: SKYPILOT_EXEC_TIMEOUT
+}
+
+// NodeExecAuditRecord is an audited command run on a tenant-owned node
+type NodeExecAuditRecord struct {
+	ID             string     `json:"id"`
+	NodeID         *string    `json:"node_id,omitempty"`
+	TenantID       string     `json:"tenant_id"`
+	ClusterName    string     `json:"cluster_name"`
+	Command        string     `json:"command"`
+	Source         string     `json:"source"`
+	Actor          *string    `json:"actor,omitempty"`
+	Streamed       bool       `json:"streamed"`
+	TimeoutSeconds int        `json:"timeout_seconds"`
+	ExitCode       *int       `json:"exit_code,omitempty"`
+	Error          *string    `json:"error,omitempty"`
+	OutputBytes    int64      `json:"output_bytes"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// parseNodeExecRequest reads the exec request body into an orchestrator
+// request attributed to the calling admin token
+func (g *Gateway) parseNodeExecRequest(w http.ResponseWriter, r *http.Request) (orchestrator.ExecRequest, bool) {
+	var req NodeExecRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return orchestrator.ExecRequest{}, false
+	}
+	if req.Command == "" {
+		g.writeError(w, http.StatusBadRequest, "command is required")
+		return orchestrator.ExecRequest{}, false
+	}
+	if req.TimeoutSeconds < 0 {
+		g.writeError(w, http.StatusBadRequest, "timeout_seconds must be positive")
+		return orchestrator.ExecRequest{}, false
+	}
+
+	actor, _ := r.Context().Value("admin_token").(string)
+	return orchestrator.ExecRequest{
+		ClusterName: chi.URLParam(r, "cluster_name"),
+		Command:     req.Command,
+		Timeout:     time.Duration(req.TimeoutSeconds) * time.Second,
+		Source:      orchestrator.ExecSourceAdmin,
+		Actor:       actor,
+	}, true
+}
+
+// writeExecRejection responds to a command refused before it ran and
+// reports whether err was one
+func (g *Gateway) writeExecRejection(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, orchestrator.ErrExecCommandNotAllowed):
+		g.writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, orchestrator.ErrExecTimeoutTooLong):
+		g.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// handleExecNodeCommand runs an allow-listed command on a node and returns
+// its output once it finishes
+// Admin API - POST /admin/nodes/{cluster_name}/exec
+func (g *Gateway) handleExecNodeCommand(w http.ResponseWriter, r *http.Request) {
+	if g.orchestrator == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "orchestrator not configured")
+		return
+	}
+	req, ok := g.parseNodeExecRequest(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := g.orchestrator.Exec(r.Context(), req, nil)
+	if err != nil && result == nil {
+		if g.writeExecRejection(w, err) {
+			return
+		}
+		g.logger.Error("failed to exec command on node", zap.Error(err), zap.String("cluster_name", req.ClusterName))
+		g.writeError(w, http.StatusBadGateway, "failed to run command: "+err.Error())
+		return
+	}
+
+	resp := map[string]interface{}{
+		"cluster_name": req.ClusterName,
+		"output":       result.Output,
+		"exit_code":    result.ExitCode,
+		"output_bytes": result.OutputBytes,
+		"duration_ms":  result.Duration.Milliseconds(),
+	}
+	if err != nil {
+		resp["error"] = err.Error()
+	}
+	g.writeJSON(w, http.StatusOK, resp)
+}
+
+// handleStreamNodeCommand runs an allow-listed command on a node and
+// streams its output as Server-Sent Events: "output" events as it is
+// produced, then one "exit" or "error" event. Disconnecting stops the
+// command.
+// Admin API - POST /admin/nodes/{cluster_name}/exec/stream
+func (g *Gateway) handleStreamNodeCommand(w http.ResponseWriter, r *http.Request) {
+	if g.orchestrator == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "orchestrator not configured")
+		return
+	}
+	req, ok := g.parseNodeExecRequest(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		g.writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	// Headers are sent with the first event, so a command refused before
+	// running still gets a plain JSON error
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+		w.WriteHeader(http.StatusOK)
+	}
+
+	ctx := r.Context()
+	result, err := g.orchestrator.Exec(ctx, req, func(out orchestrator.ExecOutput) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start()
+		g.writeSSEEvent(w, "output", out)
+		flusher.Flush()
+		return nil
+	})
+	if err != nil && result == nil && !started {
+		if g.writeExecRejection(w, err) {
+			return
+		}
+	}
+
+	start()
+	if result == nil {
+		g.logger.Error("failed to exec command on node", zap.Error(err), zap.String("cluster_name", req.ClusterName))
+		g.writeSSEEvent(w, "error", map[string]string{"message": err.Error()})
+		flusher.Flush()
+		return
+	}
+
+	exit := map[string]interface{}{
+		"exit_code":    result.ExitCode,
+		"output_bytes": result.OutputBytes,
+		"duration_ms":  result.Duration.Milliseconds(),
+	}
+	if err != nil {
+		exit["error"] = err.Error()
+	}
+	g.writeSSEEvent(w, "exit", exit)
+	flusher.Flush()
+}
+
+// handleListExecAudit lists commands run on tenant-owned nodes, newest first
+// Admin API - GET /admin/exec-audit?tenant_id=&cluster_name=&limit=100
+func (g *Gateway) handleListExecAudit(w http.ResponseWriter, r *http.Request) {
+	var tenantID *uuid.UUID
+	if raw := r.URL.Query().Get("tenant_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			g.writeError(w, http.StatusBadRequest, "invalid tenant_id")
+			return
+		}
+		tenantID = &id
+	}
+	clusterName := r.URL.Query().Get("cluster_name")
+	limit := parseIntParam(r, "limit", 100, 1, 1000)
+
+	rows, err := g.db.Pool.Query(r.Context(), `
+		SELECT id, node_id, tenant_id, cluster_name, command, source, actor, streamed,
+		       timeout_seconds, exit_code, error, output_bytes, started_at, finished_at
+		FROM node_exec_audit
+		WHERE ($1::uuid IS NULL OR tenant_id = $1)
+		  AND ($2 = '' OR cluster_name = $2)
+		ORDER BY started_at DESC
+		LIMIT $3
+	`, tenantID, clusterName, limit)
+	if err != nil {
+		g.logger.Error("failed to list exec audit", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list exec audit")
+		return
+	}
+	defer rows.Close()
+
+	records := []NodeExecAuditRecord{}
+	for rows.Next() {
+		var rec NodeExecAuditRecord
+		if err := rows.Scan(&rec.ID, &rec.NodeID, &rec.TenantID, &rec.ClusterName, &rec.Command,
+			&rec.Source, &rec.Actor, &rec.Streamed, &rec.TimeoutSeconds, &rec.ExitCode,
+			&rec.Error, &rec.OutputBytes, &rec.StartedAt, &rec.FinishedAt); err != nil {
+			g.logger.Error("failed to scan exec audit record", zap.Error(err))
+			continue
+		}
+		records = append(records, rec)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": records,
+	})
+}
@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func TestParseNodeExecRequest(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+
+	newRequest := func(body string) *http.Request {
+		r := httptest.NewRequest("POST", "/admin/nodes/cic-aws-1/exec", strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("cluster_name", "cic-aws-1")
+		ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+		ctx = context.WithValue(ctx, "admin_token", "ops-oncall")
+		return r.WithContext(ctx)
+	}
+
+	w := httptest.NewRecorder()
+	req, ok := g.parseNodeExecRequest(w, newRequest(`{"command":"nvidia-smi","timeout_seconds":90}`))
+	if !ok {
+		t.Fatalf("request rejected: %s", w.Body.String())
+	}
+	if req.ClusterName != "cic-aws-1" || req.Timeout != 90*time.Second ||
+		req.Source != orchestrator.ExecSourceAdmin || req.Actor != "ops-oncall" {
+		t.Errorf("parsed %+v", req)
+	}
+
+	for _, body := range []string{`{}`, `{"command":"ls","timeout_seconds":-1}`, `not json`} {
+		w := httptest.NewRecorder()
+		if _, ok := g.parseNodeExecRequest(w, newRequest(body)); ok || w.Code != http.StatusBadRequest {
+			t.Errorf("%s: ok = %v, status = %d; want rejected with 400", body, ok, w.Code)
+		}
+	}
+}
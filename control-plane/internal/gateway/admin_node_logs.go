@@ -13,6 +13,16 @@ import (
 	"go.uber.org/zap"
 )
 
+// nodeLogStore returns a log store that reads archived logs once Redis no
+// longer has them
+func (g *Gateway) nodeLogStore() *orchestrator.NodeLogStore {
+	store := orchestrator.NewNodeLogStore(g.cache, g.db, g.logger)
+	if g.NodeLogArchive != nil {
+		store.SetArchive(g.NodeLogArchive)
+	}
+	return store
+}
+
 // handleStreamNodeLogs streams node launch logs in real-time via Server-Sent Events (SSE)
 // Platform Admin Only - GET /admin/nodes/{id}/logs/stream
 //
@@ -20,6 +30,12 @@ import (
 //   - follow (bool): Keep connection open and stream new logs (default: true)
 //   - tail (int): Number of recent lines to send initially (default: 100)
 //   - since (timestamp): Only return logs after this timestamp (RFC3339 format)
+//   - last_event_id (int): Resume after this log entry, like the Last-Event-ID header
+//   - stream_id (string): Client-chosen ID; the server remembers how far the
+//     stream got so a reconnect with the same ID resumes without Last-Event-ID
+//
+// Log events carry an id (the entry's seq) so clients can resume after a
+// control-plane restart.
 //
 // Response Format (SSE):
 //   - event: log
@@ -30,6 +46,8 @@ import (
 //     data: {"error": "Failed to provision", "details": "...", "phase": "provisioning"}
 //   - event: done
 //     data: {"status": "active", "endpoint": "http://10.0.0.1:8000", "message": "Node ready"}
+//   - event: reconnect
+//     data: {"reason": "server shutting down", "last_event_id": 41}
 func (g *Gateway) handleStreamNodeLogs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -100,7 +118,28 @@ func (g *Gateway) handleStreamNodeLogs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Initialize log store
-	logStore := orchestrator.NewNodeLogStore(g.cache, g.logger)
+	logStore := g.nodeLogStore()
+
+	// Resume behind the last event the client saw, e.g. after a restart
+	positionScope := "node_logs:" + nodeID
+	lastSent := int64(-1)
+	var after *int64
+	if lastID, ok := g.sseResumePosition(ctx, r, positionScope); ok {
+		after = &lastID
+		lastSent = lastID
+
+		// A launch that already finished has nothing left to follow
+		if last, err := logStore.LastLog(ctx, nodeID); err == nil && last != nil &&
+			(last.Phase == orchestrator.PhaseActive || last.Phase == orchestrator.PhaseFailed) {
+			follow = false
+		}
+	}
+
+	sendLog := func(entry orchestrator.NodeLogEntry) {
+		g.writeSSEEventWithID(w, entry.Seq, "log", entry)
+		lastSent = entry.Seq
+		g.saveSSEPosition(ctx, r, positionScope, entry.Seq)
+	}
 
 	// Create context with timeout for the stream
 	streamCtx, cancel := context.WithTimeout(ctx, 30*time.Minute)
@@ -117,7 +156,7 @@ func (g *Gateway) handleStreamNodeLogs(w http.ResponseWriter, r *http.Request) {
 	// Start streaming logs
 	if follow {
 		// Stream mode: Send existing logs + follow new ones
-		logChan, errChan := logStore.StreamLogs(streamCtx, nodeID, tail, since)
+		logChan, errChan := logStore.StreamLogs(streamCtx, nodeID, tail, since, after)
 
 		// Track if we've seen a terminal state
 		terminated := false
@@ -127,6 +166,11 @@ func (g *Gateway) handleStreamNodeLogs(w http.ResponseWriter, r *http.Request) {
 			case <-streamCtx.Done():
 				return
 
+			case <-g.streamsDraining:
+				g.writeSSEReconnect(w, lastSent)
+				flusher.Flush()
+				return
+
 			case err := <-errChan:
 				if err != nil {
 					g.logger.Error("error streaming logs",
@@ -147,7 +191,7 @@ func (g *Gateway) handleStreamNodeLogs(w http.ResponseWriter, r *http.Request) {
 				}
 
 				// Send log entry as SSE event
-				g.writeSSEEvent(w, "log", entry)
+				sendLog(entry)
 				flusher.Flush()
 
 				// Check for status updates
@@ -175,7 +219,7 @@ func (g *Gateway) handleStreamNodeLogs(w http.ResponseWriter, r *http.Request) {
 					// Query for endpoint URL
 					var endpoint string
 					if err := g.db.Pool.QueryRow(streamCtx, `
-						SELECT COALESCE(endpoint_url, endpoint, '') FROM nodes WHERE id = $1
+						SELECT endpoint_url FROM nodes WHERE id = $1
 					`, nodeID).Scan(&endpoint); err != nil {
 						g.logger.Warn("failed to get endpoint URL", zap.Error(err))
 						endpoint = ""
@@ -210,7 +254,12 @@ func (g *Gateway) handleStreamNodeLogs(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 		// Non-follow mode: Send existing logs and close
-		logs, err := logStore.GetLogs(streamCtx, nodeID, tail, since)
+		var logs []orchestrator.NodeLogEntry
+		if after != nil {
+			logs, err = logStore.GetLogsAfter(streamCtx, nodeID, *after)
+		} else {
+			logs, err = logStore.GetLogs(streamCtx, nodeID, tail, since)
+		}
 		if err != nil {
 			g.logger.Error("failed to get logs",
 				zap.String("node_id", nodeID),
@@ -226,7 +275,7 @@ func (g *Gateway) handleStreamNodeLogs(w http.ResponseWriter, r *http.Request) {
 
 		// Send all logs
 		for _, entry := range logs {
-			g.writeSSEEvent(w, "log", entry)
+			sendLog(entry)
 			flusher.Flush()
 		}
 
@@ -258,6 +307,8 @@ func (g *Gateway) writeSSEEvent(w http.ResponseWriter, event string, data interf
 //   - tail (int): Number of recent lines to return (default: 100)
 //   - since (timestamp): Only return logs after this timestamp (RFC3339 format)
 //
+// Logs that have left Redis are served from their R2 archive.
+//
 // Response: JSON array of log entries
 func (g *Gateway) handleGetNodeLogs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -303,7 +354,7 @@ func (g *Gateway) handleGetNodeLogs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get logs from store
-	logStore := orchestrator.NewNodeLogStore(g.cache, g.logger)
+	logStore := g.nodeLogStore()
 	logs, err := logStore.GetLogs(ctx, nodeID, tail, since)
 	if err != nil {
 		g.logger.Error("failed to retrieve logs",
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/crosslogic/control-plane/internal/repository"
 	"go.uber.org/zap"
 )
 
@@ -310,85 +311,27 @@ func (g *Gateway) handleListTenants(w http.ResponseWriter, r *http.Request) {
 	limit := 50
 	offset := 0
 
-	query := `
-		SELECT
-			t.id,
-			t.name,
-			t.email,
-			t.status,
-			t.billing_plan,
-			t.created_at,
-			COUNT(DISTINCT ak.id) as api_keys_count
-		FROM tenants t
-		LEFT JOIN api_keys ak ON ak.tenant_id = t.id AND ak.status = 'active'
-		WHERE 1=1
-	`
-
-	args := []interface{}{}
-	argNum := 1
-	if statusFilter != "" {
-		args = append(args, statusFilter)
-		query += " AND t.status = $" + string(rune('0'+argNum))
-		argNum++
-	}
-
-	query += `
-		GROUP BY t.id, t.name, t.email, t.status, t.billing_plan, t.created_at
-		ORDER BY t.created_at DESC
-		LIMIT $` + string(rune('0'+argNum)) + ` OFFSET $` + string(rune('0'+argNum+1))
-
-	args = append(args, limit, offset)
-
-	rows, err := g.db.Pool.Query(ctx, query, args...)
+	list, total, err := g.store.Tenants.List(ctx, repository.TenantFilter{Status: statusFilter}, repository.Page{Limit: limit, Offset: offset})
 	if err != nil {
 		g.logger.Error("failed to query tenants", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to query tenants")
 		return
 	}
-	defer rows.Close()
 
 	var tenants []map[string]interface{}
-	for rows.Next() {
-		var id string
-		var name, email, status, billingPlan string
-		var createdAt time.Time
-		var apiKeysCount int
-
-		if err := rows.Scan(&id, &name, &email, &status, &billingPlan, &createdAt, &apiKeysCount); err != nil {
-			g.logger.Warn("failed to scan tenant row", zap.Error(err))
-			continue
-		}
-
-		// Get usage stats for tenant
-		var totalSpendMicrodollars int64
-		g.db.Pool.QueryRow(ctx, `
-			SELECT COALESCE(SUM(cost_microdollars), 0)
-			FROM usage_records
-			WHERE tenant_id = $1
-		`, id).Scan(&totalSpendMicrodollars)
-
+	for _, t := range list {
 		tenants = append(tenants, map[string]interface{}{
-			"id":              id,
-			"name":            name,
-			"email":           email,
-			"status":          status,
-			"tier":            billingPlan,
-			"created_at":      createdAt,
-			"total_spend_usd": float64(totalSpendMicrodollars) / 1_000_000.0,
-			"api_keys_count":  apiKeysCount,
+			"id":              t.ID.String(),
+			"name":            t.Name,
+			"email":           t.Email,
+			"status":          t.Status,
+			"tier":            t.BillingPlan,
+			"created_at":      t.CreatedAt,
+			"total_spend_usd": float64(t.TotalSpendMicrodollars) / 1_000_000.0,
+			"api_keys_count":  t.ActiveAPIKeys,
 		})
 	}
 
-	// Get total count
-	var total int
-	countQuery := "SELECT COUNT(*) FROM tenants WHERE 1=1"
-	if statusFilter != "" {
-		countQuery += " AND status = $1"
-		g.db.Pool.QueryRow(ctx, countQuery, statusFilter).Scan(&total)
-	} else {
-		g.db.Pool.QueryRow(ctx, countQuery).Scan(&total)
-	}
-
 	g.writeJSON(w, http.StatusOK, map[string]interface{}{
 		"data": tenants,
 		"pagination": map[string]interface{}{
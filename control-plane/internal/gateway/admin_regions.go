@@ -3,8 +3,10 @@ package gateway
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/crosslogic/control-plane/internal/repository"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -38,9 +40,9 @@ func (g *Gateway) handleCreateRegion(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate provider
-	validProviders := map[string]bool{"aws": true, "azure": true, "gcp": true, "oci": true}
+	validProviders := map[string]bool{"aws": true, "azure": true, "gcp": true, "oci": true, "lambda": true, "runpod": true, "nebius": true}
 	if !validProviders[req.Provider] {
-		g.writeError(w, http.StatusBadRequest, "invalid provider. Valid values: aws, azure, gcp, oci")
+		g.writeError(w, http.StatusBadRequest, "invalid provider. Valid values: aws, azure, gcp, oci, lambda, runpod, nebius")
 		return
 	}
 
@@ -146,57 +148,26 @@ func (g *Gateway) handleUpdateRegion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build update query dynamically
-	updates := []string{}
-	args := []interface{}{}
-	argNum := 1
-
-	if req.Name != nil {
-		updates = append(updates, "name = $"+string(rune('0'+argNum)))
-		args = append(args, *req.Name)
-		argNum++
+	update := repository.RegionUpdate{
+		Name:           req.Name,
+		CostMultiplier: req.PricingMultiplier,
 	}
-
 	if req.Available != nil {
 		status := "offline"
 		if *req.Available {
 			status = "active"
 		}
-		updates = append(updates, "status = $"+string(rune('0'+argNum)))
-		args = append(args, status)
-		argNum++
+		update.Status = &status
 	}
-
-	if req.PricingMultiplier != nil {
-		updates = append(updates, "cost_multiplier = $"+string(rune('0'+argNum)))
-		args = append(args, *req.PricingMultiplier)
-		argNum++
-	}
-
 	if req.Metadata != nil {
-		metadataJSON, _ := json.Marshal(req.Metadata)
-		updates = append(updates, "metadata = $"+string(rune('0'+argNum)))
-		args = append(args, metadataJSON)
-		argNum++
+		update.Metadata, _ = json.Marshal(req.Metadata)
 	}
 
-	if len(updates) == 0 {
+	err = g.store.Regions.Update(ctx, regionID, update)
+	if errors.Is(err, repository.ErrNoChanges) {
 		g.writeError(w, http.StatusBadRequest, "no fields to update")
 		return
 	}
-
-	// Add updated_at
-	updates = append(updates, "updated_at = NOW()")
-
-	// Add WHERE clause
-	args = append(args, regionID)
-	query := "UPDATE regions SET " + updates[0]
-	for i := 1; i < len(updates); i++ {
-		query += ", " + updates[i]
-	}
-	query += " WHERE id = $" + string(rune('0'+argNum))
-
-	_, err = g.db.Pool.Exec(ctx, query, args...)
 	if err != nil {
 		g.logger.Error("failed to update region", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to update region")
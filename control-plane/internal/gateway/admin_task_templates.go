@@ -0,0 +1,252 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// TaskTemplateUploadRequest is the body for uploading a task template
+// override. An empty provider applies to every provider.
+type TaskTemplateUploadRequest struct {
+	Provider string `json:"provider"`
+	Runtime  string `json:"runtime"`
+	Body     string `json:"body"`
+	Notes    string `json:"notes,omitempty"`
+}
+
+// taskTemplates returns the orchestrator's template registry, or writes an
+// error when there is no orchestrator
+func (g *Gateway) taskTemplates(w http.ResponseWriter) *orchestrator.TemplateRegistry {
+	if g.orchestrator == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "orchestrator not configured")
+		return nil
+	}
+	return g.orchestrator.Templates()
+}
+
+// templateScope reads the runtime from the URL and the provider from the
+// provider query parameter ("" or "*" for any provider)
+func templateScope(r *http.Request) (provider, runtime string) {
+	provider = strings.ToLower(strings.TrimSpace(r.URL.Query().Get("provider")))
+	if provider == "*" {
+		provider = ""
+	}
+	return provider, chi.URLParam(r, "runtime")
+}
+
+// writeTaskTemplateError maps template registry errors to responses
+func (g *Gateway) writeTaskTemplateError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, orchestrator.ErrInvalidTaskTemplate):
+		g.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, orchestrator.ErrTaskTemplateNotFound):
+		g.writeError(w, http.StatusNotFound, err.Error())
+	default:
+		g.logger.Error("failed to "+action, zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to "+action)
+	}
+}
+
+// handleListTaskTemplates lists the template files and the stored overrides
+// Admin API - GET /admin/skypilot/templates
+func (g *Gateway) handleListTaskTemplates(w http.ResponseWriter, r *http.Request) {
+	templates := g.taskTemplates(w)
+	if templates == nil {
+		return
+	}
+
+	overrides, err := templates.Versions(r.Context(), "", "")
+	if err != nil {
+		g.writeTaskTemplateError(w, err, "list task templates")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"files":     templates.Files(),
+		"overrides": overrides,
+	})
+}
+
+// handleUploadTaskTemplate validates a template and makes it the active
+// override for its provider and runtime
+// Admin API - POST /admin/skypilot/templates
+func (g *Gateway) handleUploadTaskTemplate(w http.ResponseWriter, r *http.Request) {
+	templates := g.taskTemplates(w)
+	if templates == nil {
+		return
+	}
+
+	var req TaskTemplateUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Runtime == "" {
+		req.Runtime = orchestrator.DefaultRuntime
+	}
+	if req.Provider == "*" {
+		req.Provider = ""
+	}
+
+	version, err := templates.Upload(r.Context(), strings.ToLower(req.Provider), req.Runtime, req.Body, req.Notes)
+	if err != nil {
+		g.writeTaskTemplateError(w, err, "upload task template")
+		return
+	}
+
+	g.logger.Info("task template override uploaded",
+		zap.String("ref", version.Ref),
+		zap.String("sha256", version.SHA256),
+	)
+	version.Body = ""
+	g.writeJSON(w, http.StatusCreated, version)
+}
+
+// handleValidateTaskTemplate checks a template without storing it
+// Admin API - POST /admin/skypilot/templates/validate
+func (g *Gateway) handleValidateTaskTemplate(w http.ResponseWriter, r *http.Request) {
+	var req TaskTemplateUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if _, err := orchestrator.ValidateTaskTemplate(req.Body); err != nil {
+		g.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"valid": false,
+			"error": err.Error(),
+		})
+		return
+	}
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{"valid": true})
+}
+
+// handleResolveTaskTemplate reports which template a launch would use
+// Admin API - GET /admin/skypilot/templates/{runtime}/resolve?provider=aws
+func (g *Gateway) handleResolveTaskTemplate(w http.ResponseWriter, r *http.Request) {
+	templates := g.taskTemplates(w)
+	if templates == nil {
+		return
+	}
+
+	provider, runtime := templateScope(r)
+	template, err := templates.Resolve(r.Context(), provider, runtime)
+	if err != nil {
+		g.writeTaskTemplateError(w, err, "resolve task template")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, template)
+}
+
+// handleListTaskTemplateVersions lists the override versions of a runtime
+// for one provider, with how many nodes each launched
+// Admin API - GET /admin/skypilot/templates/{runtime}/versions?provider=aws
+func (g *Gateway) handleListTaskTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	templates := g.taskTemplates(w)
+	if templates == nil {
+		return
+	}
+
+	provider, runtime := templateScope(r)
+	versions, err := templates.Versions(r.Context(), provider, runtime)
+	if err != nil {
+		g.writeTaskTemplateError(w, err, "list task template versions")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": versions,
+	})
+}
+
+// templateVersionParam reads the {version} URL parameter
+func templateVersionParam(r *http.Request) (int, bool) {
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	return version, err == nil && version > 0
+}
+
+// handleGetTaskTemplateVersion returns one override version with its body
+// Admin API - GET /admin/skypilot/templates/{runtime}/versions/{version}?provider=aws
+func (g *Gateway) handleGetTaskTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	templates := g.taskTemplates(w)
+	if templates == nil {
+		return
+	}
+
+	version, ok := templateVersionParam(r)
+	if !ok {
+		g.writeError(w, http.StatusBadRequest, "invalid version")
+		return
+	}
+
+	provider, runtime := templateScope(r)
+	v, err := templates.Version(r.Context(), provider, runtime, version)
+	if err != nil {
+		g.writeTaskTemplateError(w, err, "get task template version")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, v)
+}
+
+// handleActivateTaskTemplateVersion makes a stored version the active
+// override, e.g. to roll back a bad upload
+// Admin API - POST /admin/skypilot/templates/{runtime}/versions/{version}/activate?provider=aws
+func (g *Gateway) handleActivateTaskTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	templates := g.taskTemplates(w)
+	if templates == nil {
+		return
+	}
+
+	version, ok := templateVersionParam(r)
+	if !ok {
+		g.writeError(w, http.StatusBadRequest, "invalid version")
+		return
+	}
+
+	provider, runtime := templateScope(r)
+	if err := templates.Activate(r.Context(), provider, runtime, version); err != nil {
+		g.writeTaskTemplateError(w, err, "activate task template version")
+		return
+	}
+
+	g.logger.Info("task template override activated",
+		zap.String("provider", provider),
+		zap.String("runtime", runtime),
+		zap.Int("version", version),
+	)
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"provider": provider,
+		"runtime":  runtime,
+		"version":  version,
+		"active":   true,
+	})
+}
+
+// handleDeactivateTaskTemplate drops the active override so launches fall
+// back to the template files
+// Admin API - DELETE /admin/skypilot/templates/{runtime}/override?provider=aws
+func (g *Gateway) handleDeactivateTaskTemplate(w http.ResponseWriter, r *http.Request) {
+	templates := g.taskTemplates(w)
+	if templates == nil {
+		return
+	}
+
+	provider, runtime := templateScope(r)
+	if err := templates.Deactivate(r.Context(), provider, runtime); err != nil {
+		g.writeTaskTemplateError(w, err, "deactivate task template override")
+		return
+	}
+
+	g.logger.Info("task template override deactivated",
+		zap.String("provider", provider),
+		zap.String("runtime", runtime),
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"go.uber.org/zap"
+)
+
+func TestHandleValidateTaskTemplate(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+
+	tests := []struct {
+		name, body string
+		wantValid  bool
+	}{
+		{"built-in template", orchestrator.SkyPilotTaskTemplate, true},
+		{"no run section", "resources:\n  accelerators: {{.GPU}}:1\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, _ := json.Marshal(TaskTemplateUploadRequest{Runtime: "vllm", Body: tt.body})
+			w := httptest.NewRecorder()
+			g.handleValidateTaskTemplate(w, httptest.NewRequest("POST", "/admin/skypilot/templates/validate", strings.NewReader(string(payload))))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", w.Code)
+			}
+			var resp struct {
+				Valid bool   `json:"valid"`
+				Error string `json:"error"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Valid != tt.wantValid {
+				t.Errorf("valid = %v (%s), want %v", resp.Valid, resp.Error, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestTaskTemplatesWithoutOrchestrator(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+	w := httptest.NewRecorder()
+	g.handleListTaskTemplates(w, httptest.NewRequest("GET", "/admin/skypilot/templates", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
+
+func TestTemplateScope(t *testing.T) {
+	for query, want := range map[string]string{
+		"":              "",
+		"?provider=*":   "",
+		"?provider=AWS": "aws",
+	} {
+		provider, _ := templateScope(httptest.NewRequest("GET", "/admin/skypilot/templates/vllm/versions"+query, nil))
+		if provider != want {
+			t.Errorf("templateScope(%q) provider = %q, want %q", query, provider, want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/crosslogic/control-plane/internal/repository"
 	"github.com/crosslogic/control-plane/pkg/events"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -81,6 +82,21 @@ func (g *Gateway) handleDeleteTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Record the event with the deletion so it survives a crash after commit
+	evt := events.NewEvent(
+		events.EventTenantDeleted,
+		tenantID.String(),
+		map[string]interface{}{
+			"previous_status": currentStatus,
+			"deleted_at":      time.Now(),
+		},
+	)
+	if err := events.Enqueue(ctx, tx, evt); err != nil {
+		g.logger.Error("failed to record tenant deleted event", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to delete tenant")
+		return
+	}
+
 	// Commit transaction
 	if err = tx.Commit(ctx); err != nil {
 		g.logger.Error("failed to commit transaction", zap.Error(err))
@@ -88,21 +104,6 @@ func (g *Gateway) handleDeleteTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Publish event
-	if g.eventBus != nil {
-		evt := events.NewEvent(
-			events.EventTenantDeleted,
-			tenantID.String(),
-			map[string]interface{}{
-				"previous_status": currentStatus,
-				"deleted_at":      time.Now(),
-			},
-		)
-		if err := g.eventBus.Publish(ctx, evt); err != nil {
-			g.logger.Error("failed to publish tenant deleted event", zap.Error(err))
-		}
-	}
-
 	g.logger.Info("tenant deleted",
 		zap.String("tenant_id", tenantID.String()),
 		zap.String("previous_status", currentStatus),
@@ -213,6 +214,21 @@ func (g *Gateway) handleSuspendTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	evt := events.NewEvent(
+		events.EventTenantSuspended,
+		tenantID.String(),
+		map[string]interface{}{
+			"reason":          req.Reason,
+			"notes":           req.Notes,
+			"previous_status": currentStatus,
+		},
+	)
+	if err := events.Enqueue(ctx, tx, evt); err != nil {
+		g.logger.Error("failed to record tenant suspended event", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to suspend tenant")
+		return
+	}
+
 	// Commit transaction
 	if err = tx.Commit(ctx); err != nil {
 		g.logger.Error("failed to commit transaction", zap.Error(err))
@@ -220,22 +236,6 @@ func (g *Gateway) handleSuspendTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: Publish event when EventTenantSuspended is added to events package
-	// if g.eventBus != nil {
-	// 	evt := events.NewEvent(
-	// 		events.EventTenantSuspended,
-	// 		tenantID.String(),
-	// 		map[string]interface{}{
-	// 			"reason":          req.Reason,
-	// 			"notes":           req.Notes,
-	// 			"previous_status": currentStatus,
-	// 		},
-	// 	)
-	// 	if err := g.eventBus.Publish(ctx, evt); err != nil {
-	// 		g.logger.Error("failed to publish tenant suspended event", zap.Error(err))
-	// 	}
-	// }
-
 	g.logger.Info("tenant suspended",
 		zap.String("tenant_id", tenantID.String()),
 		zap.String("reason", req.Reason),
@@ -340,6 +340,20 @@ func (g *Gateway) handleActivateTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	evt := events.NewEvent(
+		events.EventTenantActivated,
+		tenantID.String(),
+		map[string]interface{}{
+			"notes":           req.Notes,
+			"previous_status": currentStatus,
+		},
+	)
+	if err := events.Enqueue(ctx, tx, evt); err != nil {
+		g.logger.Error("failed to record tenant activated event", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to activate tenant")
+		return
+	}
+
 	// Commit transaction
 	if err = tx.Commit(ctx); err != nil {
 		g.logger.Error("failed to commit transaction", zap.Error(err))
@@ -347,21 +361,6 @@ func (g *Gateway) handleActivateTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: Publish event when EventTenantActivated is added to events package
-	// if g.eventBus != nil {
-	// 	evt := events.NewEvent(
-	// 		events.EventTenantActivated,
-	// 		tenantID.String(),
-	// 		map[string]interface{}{
-	// 			"notes":           req.Notes,
-	// 			"previous_status": currentStatus,
-	// 		},
-	// 	)
-	// 	if err := g.eventBus.Publish(ctx, evt); err != nil {
-	// 		g.logger.Error("failed to publish tenant activated event", zap.Error(err))
-	// 	}
-	// }
-
 	g.logger.Info("tenant activated",
 		zap.String("tenant_id", tenantID.String()),
 		zap.String("previous_status", currentStatus),
@@ -403,80 +402,36 @@ func (g *Gateway) handleGetTenantAPIKeys(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	// Build query
-	query := `
-		SELECT
-			id, key_prefix, name, role, status,
-			rate_limit_requests_per_min, concurrency_limit,
-			created_at, last_used_at, expires_at
-		FROM api_keys
-		WHERE tenant_id = $1
-	`
-	args := []interface{}{tenantID}
-	argNum := 2
-
-	if statusFilter != "" {
-		query += " AND status = $" + string(rune('0'+argNum))
-		args = append(args, statusFilter)
-		argNum++
-	}
-
-	query += " ORDER BY created_at DESC LIMIT $" + string(rune('0'+argNum)) + " OFFSET $" + string(rune('0'+argNum+1))
-	args = append(args, limit, offset)
-
-	rows, err := g.db.Pool.Query(ctx, query, args...)
+	keys, total, err := g.store.APIKeys.ListForTenant(ctx, tenantID, repository.APIKeyFilter{Status: statusFilter}, repository.Page{Limit: limit, Offset: offset})
 	if err != nil {
 		g.logger.Error("failed to query API keys", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to query API keys")
 		return
 	}
-	defer rows.Close()
 
 	var apiKeys []map[string]interface{}
-	for rows.Next() {
-		var id uuid.UUID
-		var keyPrefix, name, role, status string
-		var rateLimitRPM, concurrencyLimit int
-		var createdAt time.Time
-		var lastUsedAt, expiresAt *time.Time
-
-		if err := rows.Scan(&id, &keyPrefix, &name, &role, &status,
-			&rateLimitRPM, &concurrencyLimit, &createdAt, &lastUsedAt, &expiresAt); err != nil {
-			g.logger.Warn("failed to scan API key row", zap.Error(err))
-			continue
-		}
-
+	for _, k := range keys {
 		keyData := map[string]interface{}{
-			"id":                  id,
-			"key_prefix":          keyPrefix + "...",
-			"name":                name,
-			"role":                role,
-			"status":              status,
-			"rate_limit_rpm":      rateLimitRPM,
-			"concurrency_limit":   concurrencyLimit,
-			"created_at":          createdAt,
+			"id":                k.ID,
+			"key_prefix":        k.KeyPrefix + "...",
+			"name":              k.Name,
+			"role":              k.Role,
+			"status":            k.Status,
+			"rate_limit_rpm":    k.RateLimitRequestsPerMin,
+			"concurrency_limit": k.ConcurrencyLimit,
+			"created_at":        k.CreatedAt,
 		}
 
-		if lastUsedAt != nil {
-			keyData["last_used_at"] = *lastUsedAt
+		if k.LastUsedAt != nil {
+			keyData["last_used_at"] = *k.LastUsedAt
 		}
-		if expiresAt != nil {
-			keyData["expires_at"] = *expiresAt
+		if k.ExpiresAt != nil {
+			keyData["expires_at"] = *k.ExpiresAt
 		}
 
 		apiKeys = append(apiKeys, keyData)
 	}
 
-	// Get total count
-	var total int
-	countQuery := "SELECT COUNT(*) FROM api_keys WHERE tenant_id = $1"
-	countArgs := []interface{}{tenantID}
-	if statusFilter != "" {
-		countQuery += " AND status = $2"
-		countArgs = append(countArgs, statusFilter)
-	}
-	g.db.Pool.QueryRow(ctx, countQuery, countArgs...).Scan(&total)
-
 	g.writeJSON(w, http.StatusOK, map[string]interface{}{
 		"data": apiKeys,
 		"pagination": map[string]interface{}{
@@ -590,200 +545,73 @@ func (g *Gateway) handleGetTenantDetailedUsage(w http.ResponseWriter, r *http.Re
 		groupBy = "model"
 	}
 
-	// Validate group_by
-	validGroupBy := map[string]string{
-		"model":   "m.id, m.name",
-		"api_key": "ak.id, ak.name, ak.key_prefix",
-		"region":  "r.id, r.name, r.code",
-		"hour":    "DATE_TRUNC('hour', ur.timestamp)",
-		"day":     "DATE_TRUNC('day', ur.timestamp)",
-	}
-
-	groupClause, ok := validGroupBy[groupBy]
-	if !ok {
+	if !repository.ValidUsageGroupBy(groupBy) {
 		g.writeError(w, http.StatusBadRequest, "invalid group_by parameter")
 		return
 	}
 
-	// Build dynamic query
-	var selectClause, joinClause string
-	switch groupBy {
-	case "model":
-		selectClause = "m.id as model_id, m.name as model_name, m.family"
-		joinClause = "INNER JOIN models m ON m.id = ur.model_id"
-	case "api_key":
-		selectClause = "ak.id as api_key_id, ak.name as api_key_name, ak.key_prefix"
-		joinClause = "INNER JOIN api_keys ak ON ak.id = ur.api_key_id"
-	case "region":
-		selectClause = "r.id as region_id, r.name as region_name, r.code as region_code"
-		joinClause = "LEFT JOIN regions r ON r.id = ur.region_id"
-	case "hour", "day":
-		selectClause = "DATE_TRUNC('" + groupBy + "', ur.timestamp) as period"
-		joinClause = ""
-	}
-
-	query := `
-		SELECT
-			` + selectClause + `,
-			COALESCE(SUM(ur.prompt_tokens), 0) as prompt_tokens,
-			COALESCE(SUM(ur.completion_tokens), 0) as completion_tokens,
-			COALESCE(SUM(ur.total_tokens), 0) as total_tokens,
-			COALESCE(SUM(ur.cached_tokens), 0) as cached_tokens,
-			COALESCE(COUNT(*), 0) as total_requests,
-			COALESCE(AVG(ur.latency_ms), 0) as avg_latency_ms,
-			COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY ur.latency_ms), 0) as p95_latency_ms,
-			COALESCE(SUM(ur.cost_microdollars), 0) as total_cost_microdollars
-		FROM usage_records ur
-		` + joinClause + `
-		WHERE ur.tenant_id = $1
-		  AND ur.timestamp >= $2
-		  AND ur.timestamp <= $3
-	`
-
-	args := []interface{}{tenantID, startDate, endDate}
-	argNum := 4
-
-	// Add filters
-	if modelFilter != "" {
-		modelID, err := uuid.Parse(modelFilter)
-		if err == nil {
-			query += " AND ur.model_id = $" + string(rune('0'+argNum))
-			args = append(args, modelID)
-			argNum++
-		}
+	filter := repository.UsageFilter{TenantID: tenantID, Start: startDate, End: endDate}
+	if id, err := uuid.Parse(modelFilter); err == nil {
+		filter.ModelID = &id
 	}
-	if apiKeyFilter != "" {
-		apiKeyID, err := uuid.Parse(apiKeyFilter)
-		if err == nil {
-			query += " AND ur.api_key_id = $" + string(rune('0'+argNum))
-			args = append(args, apiKeyID)
-			argNum++
-		}
+	if id, err := uuid.Parse(apiKeyFilter); err == nil {
+		filter.APIKeyID = &id
 	}
-	if regionFilter != "" {
-		regionID, err := uuid.Parse(regionFilter)
-		if err == nil {
-			query += " AND ur.region_id = $" + string(rune('0'+argNum))
-			args = append(args, regionID)
-			argNum++
-		}
+	if id, err := uuid.Parse(regionFilter); err == nil {
+		filter.RegionID = &id
 	}
 
-	query += " GROUP BY " + groupClause + " ORDER BY total_tokens DESC LIMIT 1000"
-
-	rows, err := g.db.Pool.Query(ctx, query, args...)
+	rows, err := g.store.Usage.Breakdown(ctx, groupBy, filter)
 	if err != nil {
 		g.logger.Error("failed to query detailed usage", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to query usage")
 		return
 	}
-	defer rows.Close()
 
 	var data []map[string]interface{}
-	for rows.Next() {
-		// Use a flexible scanner based on group_by
-		var promptTokens, completionTokens, totalTokens, cachedTokens, totalRequests, totalCostMicro int64
-		var avgLatency, p95Latency float64
+	for _, row := range rows {
+		entry := map[string]interface{}{
+			"prompt_tokens":     row.PromptTokens,
+			"completion_tokens": row.CompletionTokens,
+			"total_tokens":      row.TotalTokens,
+			"cached_tokens":     row.CachedTokens,
+			"total_requests":    row.Requests,
+			"avg_latency_ms":    row.AvgLatencyMs,
+			"p95_latency_ms":    row.P95LatencyMs,
+			"total_cost_usd":    float64(row.CostMicrodollars) / 1_000_000.0,
+		}
 
 		switch groupBy {
-		case "model":
-			var modelID uuid.UUID
-			var modelName, family string
-			if err := rows.Scan(&modelID, &modelName, &family,
-				&promptTokens, &completionTokens, &totalTokens, &cachedTokens,
-				&totalRequests, &avgLatency, &p95Latency, &totalCostMicro); err != nil {
-				g.logger.Warn("failed to scan row", zap.Error(err))
-				continue
-			}
+		case repository.UsageByModel:
+			entry["model_id"] = row.ID
+			entry["model_name"] = row.Name
+			entry["family"] = row.Detail
 			cacheHitRate := 0.0
-			if totalTokens > 0 {
-				cacheHitRate = float64(cachedTokens) / float64(totalTokens) * 100
+			if row.TotalTokens > 0 {
+				cacheHitRate = float64(row.CachedTokens) / float64(row.TotalTokens) * 100
 			}
-			data = append(data, map[string]interface{}{
-				"model_id":            modelID,
-				"model_name":          modelName,
-				"family":              family,
-				"prompt_tokens":       promptTokens,
-				"completion_tokens":   completionTokens,
-				"total_tokens":        totalTokens,
-				"cached_tokens":       cachedTokens,
-				"cache_hit_rate_pct":  cacheHitRate,
-				"total_requests":      totalRequests,
-				"avg_latency_ms":      avgLatency,
-				"p95_latency_ms":      p95Latency,
-				"total_cost_usd":      float64(totalCostMicro) / 1_000_000.0,
-			})
-		case "api_key":
-			var apiKeyID uuid.UUID
-			var apiKeyName, keyPrefix string
-			if err := rows.Scan(&apiKeyID, &apiKeyName, &keyPrefix,
-				&promptTokens, &completionTokens, &totalTokens, &cachedTokens,
-				&totalRequests, &avgLatency, &p95Latency, &totalCostMicro); err != nil {
-				g.logger.Warn("failed to scan row", zap.Error(err))
-				continue
+			entry["cache_hit_rate_pct"] = cacheHitRate
+		case repository.UsageByAPIKey:
+			entry["api_key_id"] = row.ID
+			entry["api_key_name"] = row.Name
+			if row.Detail != nil {
+				entry["key_prefix"] = *row.Detail + "..."
 			}
-			data = append(data, map[string]interface{}{
-				"api_key_id":        apiKeyID,
-				"api_key_name":      apiKeyName,
-				"key_prefix":        keyPrefix + "...",
-				"prompt_tokens":     promptTokens,
-				"completion_tokens": completionTokens,
-				"total_tokens":      totalTokens,
-				"cached_tokens":     cachedTokens,
-				"total_requests":    totalRequests,
-				"avg_latency_ms":    avgLatency,
-				"p95_latency_ms":    p95Latency,
-				"total_cost_usd":    float64(totalCostMicro) / 1_000_000.0,
-			})
-		case "region":
-			var regionID *uuid.UUID
-			var regionName, regionCode *string
-			if err := rows.Scan(&regionID, &regionName, &regionCode,
-				&promptTokens, &completionTokens, &totalTokens, &cachedTokens,
-				&totalRequests, &avgLatency, &p95Latency, &totalCostMicro); err != nil {
-				g.logger.Warn("failed to scan row", zap.Error(err))
-				continue
+		case repository.UsageByRegion:
+			if row.ID != nil {
+				entry["region_id"] = *row.ID
 			}
-			regionData := map[string]interface{}{
-				"prompt_tokens":     promptTokens,
-				"completion_tokens": completionTokens,
-				"total_tokens":      totalTokens,
-				"cached_tokens":     cachedTokens,
-				"total_requests":    totalRequests,
-				"avg_latency_ms":    avgLatency,
-				"p95_latency_ms":    p95Latency,
-				"total_cost_usd":    float64(totalCostMicro) / 1_000_000.0,
+			if row.Name != nil {
+				entry["region_name"] = *row.Name
 			}
-			if regionID != nil {
-				regionData["region_id"] = *regionID
+			if row.Detail != nil {
+				entry["region_code"] = *row.Detail
 			}
-			if regionName != nil {
-				regionData["region_name"] = *regionName
-			}
-			if regionCode != nil {
-				regionData["region_code"] = *regionCode
-			}
-			data = append(data, regionData)
-		case "hour", "day":
-			var period time.Time
-			if err := rows.Scan(&period,
-				&promptTokens, &completionTokens, &totalTokens, &cachedTokens,
-				&totalRequests, &avgLatency, &p95Latency, &totalCostMicro); err != nil {
-				g.logger.Warn("failed to scan row", zap.Error(err))
-				continue
-			}
-			data = append(data, map[string]interface{}{
-				"period":            period,
-				"prompt_tokens":     promptTokens,
-				"completion_tokens": completionTokens,
-				"total_tokens":      totalTokens,
-				"cached_tokens":     cachedTokens,
-				"total_requests":    totalRequests,
-				"avg_latency_ms":    avgLatency,
-				"p95_latency_ms":    p95Latency,
-				"total_cost_usd":    float64(totalCostMicro) / 1_000_000.0,
-			})
+		default:
+			entry["period"] = row.Period
 		}
+
+		data = append(data, entry)
 	}
 
 	g.writeJSON(w, http.StatusOK, map[string]interface{}{
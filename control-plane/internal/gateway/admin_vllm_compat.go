@@ -0,0 +1,205 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/crosslogic/control-plane/internal/jobs"
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxCompatRuns is the most compatibility runs listed at once
+const maxCompatRuns = 100
+
+// compatRunArgs are the arguments of a runtime.compat_run job
+type compatRunArgs struct {
+	RunID uuid.UUID `json:"run_id"`
+}
+
+// SetVLLMCompatGate sets whether deployments may only be pinned to a vLLM
+// newer than the platform default once it passed a compatibility run (the
+// default)
+func (g *Gateway) SetVLLMCompatGate(required bool) {
+	g.vllmCompatUngated = !required
+}
+
+// checkVLLMCompatGate returns an error for the client when a deployment of
+// a model family is pinned to a vLLM newer than the platform default that
+// has not passed a compatibility run for the family
+func (g *Gateway) checkVLLMCompatGate(ctx context.Context, family, vllmVersion string) error {
+	if g.vllmCompatUngated || g.CompatTester == nil || g.orchestrator == nil || vllmVersion == "" {
+		return nil
+	}
+	if orchestrator.CompareVersions(vllmVersion, g.orchestrator.DefaultVLLMVersion()) <= 0 {
+		return nil
+	}
+
+	gate, err := g.CompatTester.Gate(ctx, vllmVersion, family)
+	if err != nil {
+		g.logger.Error("failed to check vLLM compatibility gate", zap.String("vllm_version", vllmVersion), zap.Error(err))
+		return errors.New("failed to check vLLM compatibility")
+	}
+	if !gate.Allowed {
+		return fmt.Errorf("%s; run POST /admin/runtime/compat-runs first", gate.Reason)
+	}
+	return nil
+}
+
+// compatTesterAvailable checks compatibility runs are available
+func (g *Gateway) compatTesterAvailable(w http.ResponseWriter) bool {
+	if g.CompatTester == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "vLLM compatibility runs are not configured")
+		return false
+	}
+	return true
+}
+
+// handleCreateCompatRun queues a compatibility run of a vLLM release
+// Platform Admin Only - POST /admin/runtime/compat-runs
+// The run launches a canary node per model family, all active families
+// unless some are listed, and runs the smoke suite against each.
+func (g *Gateway) handleCreateCompatRun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if !g.compatTesterAvailable(w) {
+		return
+	}
+
+	var req struct {
+		VLLMVersion  string   `json:"vllm_version"`
+		TorchVersion string   `json:"torch_version"`
+		Families     []string `json:"families"`
+		Provider     string   `json:"provider"`
+		Region       string   `json:"region"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	run, err := g.CompatTester.Create(ctx, orchestrator.CompatRun{
+		VLLMVersion:  req.VLLMVersion,
+		TorchVersion: req.TorchVersion,
+		Families:     req.Families,
+		Provider:     req.Provider,
+		Region:       req.Region,
+		RequestedBy:  adminActor(ctx),
+	})
+	if err != nil {
+		if g.writeNodeConfigError(w, err) {
+			return
+		}
+		g.logger.Error("failed to create compatibility run", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create compatibility run")
+		return
+	}
+	if _, err := g.jobs.Enqueue(ctx, jobCompatRun, compatRunArgs{RunID: run.ID}); err != nil {
+		g.logger.Error("failed to enqueue compatibility run", zap.String("run_id", run.ID.String()), zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to start compatibility run")
+		return
+	}
+
+	g.logger.Info("vLLM compatibility run queued",
+		zap.String("run_id", run.ID.String()),
+		zap.String("vllm_version", run.VLLMVersion),
+		zap.Strings("families", run.Families),
+	)
+	g.writeJSON(w, http.StatusAccepted, run)
+}
+
+// handleListCompatRuns lists recent compatibility runs, optionally of one
+// vLLM release
+// Platform Admin Only - GET /admin/runtime/compat-runs
+func (g *Gateway) handleListCompatRuns(w http.ResponseWriter, r *http.Request) {
+	if !g.compatTesterAvailable(w) {
+		return
+	}
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxCompatRuns {
+			g.writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxCompatRuns))
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := g.CompatTester.List(r.Context(), r.URL.Query().Get("vllm_version"), limit)
+	if err != nil {
+		g.logger.Error("failed to list compatibility runs", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list compatibility runs")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": runs,
+	})
+}
+
+// handleGetCompatRun returns a compatibility run with its pass/fail matrix
+// Platform Admin Only - GET /admin/runtime/compat-runs/{id}
+func (g *Gateway) handleGetCompatRun(w http.ResponseWriter, r *http.Request) {
+	if !g.compatTesterAvailable(w) {
+		return
+	}
+	runID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	run, err := g.CompatTester.Get(r.Context(), runID)
+	if errors.Is(err, orchestrator.ErrCompatRunNotFound) {
+		g.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to load compatibility run", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load compatibility run")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, run)
+}
+
+// handleGetCompatGate reports whether a vLLM release may be adopted,
+// platform wide or for one model family
+// Platform Admin Only - GET /admin/runtime/compat-gate
+func (g *Gateway) handleGetCompatGate(w http.ResponseWriter, r *http.Request) {
+	if !g.compatTesterAvailable(w) {
+		return
+	}
+	vllmVersion := r.URL.Query().Get("vllm_version")
+	if !orchestrator.ValidRuntimeVersion(vllmVersion) {
+		g.writeError(w, http.StatusBadRequest, "vllm_version must be a release version such as 0.6.3")
+		return
+	}
+
+	gate, err := g.CompatTester.Gate(r.Context(), vllmVersion, r.URL.Query().Get("family"))
+	if err != nil {
+		g.logger.Error("failed to check vLLM compatibility gate", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to check vLLM compatibility")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, gate)
+}
+
+// runCompatRun runs a queued compatibility run
+func (g *Gateway) runCompatRun(ctx context.Context, job *jobs.Job) error {
+	var args compatRunArgs
+	if err := job.Decode(&args); err != nil {
+		return err
+	}
+	if g.CompatTester == nil {
+		return jobs.Permanent(errors.New("vLLM compatibility runs are not configured"))
+	}
+	return g.CompatTester.Run(ctx, args.RunID)
+}
@@ -0,0 +1,798 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Anthropic Messages API compatibility. POST /v1/messages accepts an
+// Anthropic-style request, translates it to a chat completion for the serving
+// node and translates the result (or its SSE stream) back, so clients built
+// on the Anthropic SDK only need a new base URL and API key.
+
+// anthropicMessagesRequest is the subset of the Messages API we translate
+type anthropicMessagesRequest struct {
+	Model         string               `json:"model"`
+	MaxTokens     int                  `json:"max_tokens"`
+	System        json.RawMessage      `json:"system,omitempty"`
+	Messages      []anthropicMessage   `json:"messages"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	TopK          *int                 `json:"top_k,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Metadata      *struct {
+		UserID string `json:"user_id"`
+	} `json:"metadata,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role string `json:"role"`
+	// Content is a string or an array of content blocks
+	Content json.RawMessage `json:"content"`
+}
+
+type anthropicContentBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text,omitempty"`
+	Source *struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+		Data      string `json:"data"`
+		URL       string `json:"url"`
+	} `json:"source,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"` // auto, any, tool, none
+	Name string `json:"name,omitempty"`
+}
+
+// Validate checks the fields the Messages API requires
+func (r *anthropicMessagesRequest) Validate() error {
+	if r.Model == "" {
+		return fmt.Errorf("model: Field required")
+	}
+	if r.MaxTokens < 1 {
+		return fmt.Errorf("max_tokens: Field required")
+	}
+	if len(r.Messages) == 0 {
+		return fmt.Errorf("messages: Field required")
+	}
+	return nil
+}
+
+// Chat completion shapes produced from and consumed for the translation
+
+type chatToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatMessage struct {
+	Role       string         `json:"role"`
+	Content    interface{}    `json:"content"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+type chatContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+type chatTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+type chatRequestFromAnthropic struct {
+	Model         string        `json:"model"`
+	Messages      []chatMessage `json:"messages"`
+	MaxTokens     int           `json:"max_tokens"`
+	Temperature   *float64      `json:"temperature,omitempty"`
+	TopP          *float64      `json:"top_p,omitempty"`
+	TopK          *int          `json:"top_k,omitempty"`
+	Stop          []string      `json:"stop,omitempty"`
+	Stream        bool          `json:"stream,omitempty"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+	Tools      []chatTool  `json:"tools,omitempty"`
+	ToolChoice interface{} `json:"tool_choice,omitempty"`
+	User       string      `json:"user,omitempty"`
+}
+
+// parseAnthropicContent decodes content given as a string or block array
+func parseAnthropicContent(raw json.RawMessage) ([]anthropicContentBlock, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	if raw[0] == '"' {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+		return []anthropicContentBlock{{Type: "text", Text: text}}, nil
+	}
+	var blocks []anthropicContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// joinAnthropicText concatenates the text blocks of content
+func joinAnthropicText(raw json.RawMessage) (string, error) {
+	blocks, err := parseAnthropicContent(raw)
+	if err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, b := range blocks {
+		if b.Type == "text" {
+			texts = append(texts, b.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// chatContent renders text and image parts as a chat message content value:
+// a plain string when there are only text parts, otherwise a part array
+func chatContent(parts []chatContentPart) interface{} {
+	var texts []string
+	for _, p := range parts {
+		if p.Type != "text" {
+			return parts
+		}
+		texts = append(texts, p.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// translateAnthropicRequest converts a Messages request to a chat completion
+// request body
+func translateAnthropicRequest(req *anthropicMessagesRequest) ([]byte, error) {
+	out := chatRequestFromAnthropic{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		TopK:        req.TopK,
+		Stop:        req.StopSequences,
+		Stream:      req.Stream,
+	}
+	if req.Stream {
+		// Usage arrives in the final chunk, which message_delta reports
+		out.StreamOptions = &struct {
+			IncludeUsage bool `json:"include_usage"`
+		}{IncludeUsage: true}
+	}
+	if req.Metadata != nil {
+		out.User = req.Metadata.UserID
+	}
+
+	if len(req.System) > 0 {
+		system, err := joinAnthropicText(req.System)
+		if err != nil {
+			return nil, fmt.Errorf("system: %w", err)
+		}
+		if system != "" {
+			out.Messages = append(out.Messages, chatMessage{Role: "system", Content: system})
+		}
+	}
+
+	for i, msg := range req.Messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			return nil, fmt.Errorf("messages.%d.role: must be user or assistant", i)
+		}
+		blocks, err := parseAnthropicContent(msg.Content)
+		if err != nil {
+			return nil, fmt.Errorf("messages.%d.content: %w", i, err)
+		}
+
+		var parts []chatContentPart
+		var toolCalls []chatToolCall
+		var toolResults []chatMessage
+		for _, b := range blocks {
+			switch b.Type {
+			case "text":
+				parts = append(parts, chatContentPart{Type: "text", Text: b.Text})
+			case "image":
+				if b.Source == nil {
+					return nil, fmt.Errorf("messages.%d.content: image source is required", i)
+				}
+				url := b.Source.URL
+				if b.Source.Type == "base64" {
+					url = "data:" + b.Source.MediaType + ";base64," + b.Source.Data
+				}
+				part := chatContentPart{Type: "image_url"}
+				part.ImageURL = &struct {
+					URL string `json:"url"`
+				}{URL: url}
+				parts = append(parts, part)
+			case "tool_use":
+				call := chatToolCall{ID: b.ID, Type: "function"}
+				call.Function.Name = b.Name
+				call.Function.Arguments = "{}"
+				if len(b.Input) > 0 {
+					call.Function.Arguments = string(b.Input)
+				}
+				toolCalls = append(toolCalls, call)
+			case "tool_result":
+				result, err := joinAnthropicText(b.Content)
+				if err != nil {
+					return nil, fmt.Errorf("messages.%d.content: tool_result: %w", i, err)
+				}
+				if b.IsError {
+					result = "Error: " + result
+				}
+				toolResults = append(toolResults, chatMessage{Role: "tool", ToolCallID: b.ToolUseID, Content: result})
+			case "thinking", "redacted_thinking":
+				// Reasoning from another model's turn has no chat equivalent
+			default:
+				return nil, fmt.Errorf("messages.%d.content: unsupported content block type %q", i, b.Type)
+			}
+		}
+
+		// Tool results must directly follow the assistant turn that called them
+		out.Messages = append(out.Messages, toolResults...)
+
+		switch {
+		case msg.Role == "assistant" && len(toolCalls) > 0:
+			var content interface{}
+			if len(parts) > 0 {
+				content = chatContent(parts)
+			}
+			out.Messages = append(out.Messages, chatMessage{Role: "assistant", Content: content, ToolCalls: toolCalls})
+		case len(parts) > 0:
+			out.Messages = append(out.Messages, chatMessage{Role: msg.Role, Content: chatContent(parts)})
+		case len(toolResults) == 0:
+			out.Messages = append(out.Messages, chatMessage{Role: msg.Role, Content: ""})
+		}
+	}
+
+	for _, t := range req.Tools {
+		tool := chatTool{Type: "function"}
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.InputSchema
+		out.Tools = append(out.Tools, tool)
+	}
+
+	if req.ToolChoice != nil {
+		switch req.ToolChoice.Type {
+		case "auto", "none":
+			out.ToolChoice = req.ToolChoice.Type
+		case "any":
+			out.ToolChoice = "required"
+		case "tool":
+			out.ToolChoice = map[string]interface{}{
+				"type":     "function",
+				"function": map[string]string{"name": req.ToolChoice.Name},
+			}
+		default:
+			return nil, fmt.Errorf("tool_choice.type: unsupported value %q", req.ToolChoice.Type)
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// anthropicStopReason maps a chat finish reason to a Messages stop reason.
+// vLLM reports the matched stop string in stop_reason, which tells a stop
+// sequence apart from the end of the turn.
+func anthropicStopReason(finishReason string, matched json.RawMessage, stopSequences []string) (string, *string) {
+	switch finishReason {
+	case "length":
+		return "max_tokens", nil
+	case "tool_calls", "function_call":
+		return "tool_use", nil
+	case "content_filter":
+		return "refusal", nil
+	}
+	var seq string
+	if len(matched) > 0 && json.Unmarshal(matched, &seq) == nil {
+		for _, s := range stopSequences {
+			if s == seq {
+				return "stop_sequence", &seq
+			}
+		}
+	}
+	return "end_turn", nil
+}
+
+// anthropicMessageID derives a message ID from a chat completion ID
+func anthropicMessageID(chatID string) string {
+	return "msg_" + strings.TrimPrefix(chatID, "chatcmpl-")
+}
+
+// anthropicToolInput returns tool call arguments as a JSON object
+func anthropicToolInput(arguments string) json.RawMessage {
+	trimmed := strings.TrimSpace(arguments)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	return json.RawMessage("{}")
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicOutputBlock struct {
+	Type  string          `json:"type"`
+	Text  *string         `json:"text,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+type anthropicMessageResponse struct {
+	ID           string                 `json:"id"`
+	Type         string                 `json:"type"`
+	Role         string                 `json:"role"`
+	Model        string                 `json:"model"`
+	Content      []anthropicOutputBlock `json:"content"`
+	StopReason   *string                `json:"stop_reason"`
+	StopSequence *string                `json:"stop_sequence"`
+	Usage        anthropicUsage         `json:"usage"`
+}
+
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type chatCompletionResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content   *string        `json:"content"`
+			ToolCalls []chatToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string          `json:"finish_reason"`
+		StopReason   json.RawMessage `json:"stop_reason"`
+	} `json:"choices"`
+	Usage chatUsage `json:"usage"`
+}
+
+// translateChatResponse converts a chat completion into a Messages response
+func translateChatResponse(resp *chatCompletionResponse, stopSequences []string) anthropicMessageResponse {
+	out := anthropicMessageResponse{
+		ID:      anthropicMessageID(resp.ID),
+		Type:    "message",
+		Role:    "assistant",
+		Model:   resp.Model,
+		Content: []anthropicOutputBlock{},
+		Usage: anthropicUsage{
+			InputTokens:  resp.Usage.PromptTokens,
+			OutputTokens: resp.Usage.CompletionTokens,
+		},
+	}
+	if len(resp.Choices) == 0 {
+		stop := "end_turn"
+		out.StopReason = &stop
+		return out
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message.Content != nil && *choice.Message.Content != "" {
+		text := *choice.Message.Content
+		out.Content = append(out.Content, anthropicOutputBlock{Type: "text", Text: &text})
+	}
+	for _, call := range choice.Message.ToolCalls {
+		out.Content = append(out.Content, anthropicOutputBlock{
+			Type:  "tool_use",
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: anthropicToolInput(call.Function.Arguments),
+		})
+	}
+	stop, seq := anthropicStopReason(choice.FinishReason, choice.StopReason, stopSequences)
+	out.StopReason = &stop
+	out.StopSequence = seq
+	return out
+}
+
+// anthropicEvent is one Messages API server-sent event
+type anthropicEvent struct {
+	Name string
+	Data interface{}
+}
+
+type chatCompletionChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   *string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string         `json:"finish_reason"`
+		StopReason   json.RawMessage `json:"stop_reason"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+	// Error is set when the model server fails mid-stream
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicStreamTranslator turns chat completion chunks into Messages API
+// events. Anthropic content blocks are strictly sequential, so the block in
+// progress is closed whenever output switches between text and a tool call.
+type anthropicStreamTranslator struct {
+	model         string
+	stopSequences []string
+
+	started    bool
+	blocks     int  // content blocks opened so far
+	open       bool // whether block blocks-1 is still open
+	openTool   int  // chat tool call index of the open block, or -1 for text
+	stopReason string
+	stopSeq    *string
+	usage      anthropicUsage
+}
+
+func newAnthropicStreamTranslator(model string, stopSequences []string) *anthropicStreamTranslator {
+	return &anthropicStreamTranslator{model: model, stopSequences: stopSequences, openTool: -1}
+}
+
+func (t *anthropicStreamTranslator) start(id, model string) []anthropicEvent {
+	t.started = true
+	if model == "" {
+		model = t.model
+	}
+	return []anthropicEvent{
+		{Name: "message_start", Data: map[string]interface{}{
+			"type": "message_start",
+			"message": anthropicMessageResponse{
+				ID:      anthropicMessageID(id),
+				Type:    "message",
+				Role:    "assistant",
+				Model:   model,
+				Content: []anthropicOutputBlock{},
+			},
+		}},
+		{Name: "ping", Data: map[string]string{"type": "ping"}},
+	}
+}
+
+func (t *anthropicStreamTranslator) closeBlock() []anthropicEvent {
+	if !t.open {
+		return nil
+	}
+	t.open = false
+	return []anthropicEvent{{Name: "content_block_stop", Data: map[string]interface{}{
+		"type":  "content_block_stop",
+		"index": t.blocks - 1,
+	}}}
+}
+
+func (t *anthropicStreamTranslator) openBlock(block anthropicOutputBlock, tool int) []anthropicEvent {
+	events := t.closeBlock()
+	t.open = true
+	t.openTool = tool
+	t.blocks++
+	return append(events, anthropicEvent{Name: "content_block_start", Data: map[string]interface{}{
+		"type":          "content_block_start",
+		"index":         t.blocks - 1,
+		"content_block": block,
+	}})
+}
+
+// Chunk translates one chat completion chunk
+func (t *anthropicStreamTranslator) Chunk(c *chatCompletionChunk) []anthropicEvent {
+	var events []anthropicEvent
+	if !t.started {
+		events = append(events, t.start(c.ID, c.Model)...)
+	}
+	if c.Usage != nil {
+		t.usage = anthropicUsage{InputTokens: c.Usage.PromptTokens, OutputTokens: c.Usage.CompletionTokens}
+	}
+	if len(c.Choices) == 0 {
+		return events
+	}
+
+	choice := c.Choices[0]
+	if text := choice.Delta.Content; text != nil && *text != "" {
+		if !t.open || t.openTool != -1 {
+			empty := ""
+			events = append(events, t.openBlock(anthropicOutputBlock{Type: "text", Text: &empty}, -1)...)
+		}
+		events = append(events, anthropicEvent{Name: "content_block_delta", Data: map[string]interface{}{
+			"type":  "content_block_delta",
+			"index": t.blocks - 1,
+			"delta": map[string]string{"type": "text_delta", "text": *text},
+		}})
+	}
+
+	for _, call := range choice.Delta.ToolCalls {
+		if !t.open || t.openTool != call.Index || call.ID != "" {
+			events = append(events, t.openBlock(anthropicOutputBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Function.Name,
+				Input: json.RawMessage("{}"),
+			}, call.Index)...)
+		}
+		if call.Function.Arguments != "" {
+			events = append(events, anthropicEvent{Name: "content_block_delta", Data: map[string]interface{}{
+				"type":  "content_block_delta",
+				"index": t.blocks - 1,
+				"delta": map[string]string{"type": "input_json_delta", "partial_json": call.Function.Arguments},
+			}})
+		}
+	}
+
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		events = append(events, t.closeBlock()...)
+		t.stopReason, t.stopSeq = anthropicStopReason(*choice.FinishReason, choice.StopReason, t.stopSequences)
+	}
+	return events
+}
+
+// Finish closes the message once the chat stream has ended
+func (t *anthropicStreamTranslator) Finish() []anthropicEvent {
+	var events []anthropicEvent
+	if !t.started {
+		events = append(events, t.start("", "")...)
+	}
+	events = append(events, t.closeBlock()...)
+	if t.stopReason == "" {
+		t.stopReason = "end_turn"
+	}
+	return append(events,
+		anthropicEvent{Name: "message_delta", Data: map[string]interface{}{
+			"type":  "message_delta",
+			"delta": map[string]interface{}{"stop_reason": t.stopReason, "stop_sequence": t.stopSeq},
+			"usage": t.usage,
+		}},
+		anthropicEvent{Name: "message_stop", Data: map[string]string{"type": "message_stop"}},
+	)
+}
+
+// anthropicErrorType maps an HTTP status to a Messages API error type
+func anthropicErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable, 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// anthropicErrorBody renders an error in the Messages API shape
+func anthropicErrorBody(status int, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "error",
+		"error": map[string]string{
+			"type":    anthropicErrorType(status),
+			"message": message,
+		},
+	}
+}
+
+// chatErrorMessage extracts the message from an OpenAI-style error body
+func chatErrorMessage(body []byte) string {
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		if parsed.Error.Message != "" {
+			return parsed.Error.Message
+		}
+		if parsed.Message != "" {
+			return parsed.Message
+		}
+	}
+	if text := strings.TrimSpace(string(body)); text != "" {
+		return text
+	}
+	return "upstream error"
+}
+
+// anthropicErrorWriter holds back error responses written by the shared chat
+// pipeline so they can be rewritten in the Messages API shape. Headers such
+// as Retry-After pass straight through.
+type anthropicErrorWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (e *anthropicErrorWriter) WriteHeader(status int) {
+	e.status = status
+}
+
+func (e *anthropicErrorWriter) Write(b []byte) (int, error) {
+	return e.body.Write(b)
+}
+
+// flush writes the held-back error, if any, in the Messages API shape
+func (e *anthropicErrorWriter) flush(g *Gateway) {
+	if e.status == 0 {
+		return
+	}
+	e.ResponseWriter.Header().Del("Content-Length")
+	g.writeJSON(e.ResponseWriter, e.status, anthropicErrorBody(e.status, chatErrorMessage(e.body.Bytes())))
+}
+
+// writeAnthropicError writes an error in the Messages API shape
+func (g *Gateway) writeAnthropicError(w http.ResponseWriter, status int, message string) {
+	g.writeJSON(w, status, anthropicErrorBody(status, message))
+}
+
+// handleAnthropicMessages serves the Anthropic Messages API on top of chat
+// completions
+// Tenant API - POST /v1/messages
+func (g *Gateway) handleAnthropicMessages(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		g.writeAnthropicError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	r.Body.Close()
+
+	var req anthropicMessagesRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		g.writeAnthropicError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		g.writeAnthropicError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chatBody, err := translateAnthropicRequest(&req)
+	if err != nil {
+		g.writeAnthropicError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The serving node only speaks chat completions
+	chatReq := r.Clone(r.Context())
+	chatReq.URL.Path = "/v1/chat/completions"
+	chatReq.Header.Del("Content-Length")
+	chatReq.Header.Set("Content-Type", "application/json")
+
+	ew := &anthropicErrorWriter{ResponseWriter: w}
+	resp := g.forwardChatCompletion(ew, chatReq, chatBody)
+	if resp == nil {
+		ew.flush(g)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		upstream, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		g.writeAnthropicError(w, resp.StatusCode, chatErrorMessage(upstream))
+		return
+	}
+
+	if req.Stream {
+		g.streamAnthropicMessages(w, resp.Body, &req)
+		return
+	}
+
+	var chatResp chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		g.logger.Error("failed to decode chat completion for messages API", zap.Error(err))
+		g.writeAnthropicError(w, http.StatusBadGateway, "invalid response from model server")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, translateChatResponse(&chatResp, req.StopSequences))
+}
+
+// streamAnthropicMessages relays a chat completion SSE stream as Messages API
+// events
+func (g *Gateway) streamAnthropicMessages(w http.ResponseWriter, upstream io.Reader, req *anthropicMessagesRequest) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	send := func(events []anthropicEvent) {
+		for _, evt := range events {
+			data, err := json.Marshal(evt.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Name, data)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	translator := newAnthropicStreamTranslator(req.Model, req.StopSequences)
+	scanner := bufio.NewScanner(upstream)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			g.logger.Warn("skipping undecodable chat completion chunk", zap.Error(err))
+			continue
+		}
+		if chunk.Error != nil {
+			send([]anthropicEvent{{Name: "error", Data: anthropicErrorBody(http.StatusInternalServerError, chunk.Error.Message)}})
+			return
+		}
+		send(translator.Chunk(&chunk))
+	}
+	if err := scanner.Err(); err != nil {
+		g.logger.Warn("chat completion stream ended early", zap.Error(err))
+		send([]anthropicEvent{{Name: "error", Data: anthropicErrorBody(http.StatusBadGateway, "model server stream interrupted")}})
+		return
+	}
+
+	send(translator.Finish())
+}
@@ -0,0 +1,210 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTranslateAnthropicRequest(t *testing.T) {
+	body := `{
+		"model": "llama-3-8b",
+		"max_tokens": 256,
+		"system": [{"type": "text", "text": "Be brief."}],
+		"stop_sequences": ["END"],
+		"metadata": {"user_id": "u-1"},
+		"tools": [{"name": "get_weather", "description": "Weather", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "any"},
+		"messages": [
+			{"role": "user", "content": "What's the weather in Paris?"},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "18C"}]},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "AAA"}},
+				{"type": "text", "text": "And this?"}
+			]}
+		]
+	}`
+	var req anthropicMessagesRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := translateAnthropicRequest(&req)
+	if err != nil {
+		t.Fatalf("translateAnthropicRequest() error = %v", err)
+	}
+
+	var got struct {
+		Model      string            `json:"model"`
+		MaxTokens  int               `json:"max_tokens"`
+		Stop       []string          `json:"stop"`
+		User       string            `json:"user"`
+		ToolChoice string            `json:"tool_choice"`
+		Tools      []json.RawMessage `json:"tools"`
+		Messages   []struct {
+			Role       string          `json:"role"`
+			Content    json.RawMessage `json:"content"`
+			ToolCalls  []chatToolCall  `json:"tool_calls"`
+			ToolCallID string          `json:"tool_call_id"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Model != "llama-3-8b" || got.MaxTokens != 256 || got.User != "u-1" || got.ToolChoice != "required" {
+		t.Errorf("translated fields = %+v", got)
+	}
+	if len(got.Stop) != 1 || got.Stop[0] != "END" || len(got.Tools) != 1 {
+		t.Errorf("stop = %v, tools = %d", got.Stop, len(got.Tools))
+	}
+
+	roles := make([]string, len(got.Messages))
+	for i, m := range got.Messages {
+		roles[i] = m.Role
+	}
+	if want := "system,user,assistant,tool,user"; strings.Join(roles, ",") != want {
+		t.Fatalf("roles = %v, want %s", roles, want)
+	}
+
+	assistant := got.Messages[2]
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].Function.Arguments != `{"city": "Paris"}` {
+		t.Errorf("tool calls = %+v", assistant.ToolCalls)
+	}
+	if tool := got.Messages[3]; tool.ToolCallID != "toolu_1" || string(tool.Content) != `"18C"` {
+		t.Errorf("tool result = %s (%s)", tool.Content, tool.ToolCallID)
+	}
+	if !strings.Contains(string(got.Messages[4].Content), "data:image/png;base64,AAA") {
+		t.Errorf("image content = %s", got.Messages[4].Content)
+	}
+}
+
+func TestTranslateAnthropicRequestRejectsUnknownBlocks(t *testing.T) {
+	req := anthropicMessagesRequest{
+		Model:     "m",
+		MaxTokens: 1,
+		Messages:  []anthropicMessage{{Role: "user", Content: json.RawMessage(`[{"type": "document"}]`)}},
+	}
+	if _, err := translateAnthropicRequest(&req); err == nil {
+		t.Error("translateAnthropicRequest() accepted an unsupported block")
+	}
+}
+
+func TestTranslateChatResponse(t *testing.T) {
+	body := `{
+		"id": "chatcmpl-abc",
+		"model": "llama-3-8b",
+		"choices": [{
+			"message": {"content": "Checking.", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]},
+			"finish_reason": "tool_calls"
+		}],
+		"usage": {"prompt_tokens": 12, "completion_tokens": 7}
+	}`
+	var resp chatCompletionResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	got := translateChatResponse(&resp, nil)
+	if got.ID != "msg_abc" || got.Type != "message" || *got.StopReason != "tool_use" {
+		t.Errorf("response = %+v", got)
+	}
+	if len(got.Content) != 2 || got.Content[0].Type != "text" || got.Content[1].Type != "tool_use" || string(got.Content[1].Input) != `{"city":"Paris"}` {
+		t.Errorf("content = %+v", got.Content)
+	}
+	if got.Usage.InputTokens != 12 || got.Usage.OutputTokens != 7 {
+		t.Errorf("usage = %+v", got.Usage)
+	}
+}
+
+func TestAnthropicStopReason(t *testing.T) {
+	tests := []struct {
+		finish  string
+		matched string
+		want    string
+		wantSeq bool
+	}{
+		{"stop", "", "end_turn", false},
+		{"stop", `"END"`, "stop_sequence", true},
+		{"stop", `"OTHER"`, "end_turn", false},
+		{"stop", `128009`, "end_turn", false},
+		{"length", "", "max_tokens", false},
+		{"tool_calls", "", "tool_use", false},
+	}
+
+	for _, tt := range tests {
+		var matched json.RawMessage
+		if tt.matched != "" {
+			matched = json.RawMessage(tt.matched)
+		}
+		got, seq := anthropicStopReason(tt.finish, matched, []string{"END"})
+		if got != tt.want || (seq != nil) != tt.wantSeq {
+			t.Errorf("anthropicStopReason(%q, %s) = %q, %v", tt.finish, tt.matched, got, seq)
+		}
+	}
+}
+
+func TestAnthropicStreamTranslator(t *testing.T) {
+	chunks := []string{
+		`{"id":"chatcmpl-1","model":"m","choices":[{"delta":{"role":"assistant","content":""}}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"delta":{"content":"Hi"}}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"f","arguments":""}}]}}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"a\":1}"}}]}}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":3}}`,
+	}
+
+	translator := newAnthropicStreamTranslator("m", nil)
+	var names []string
+	var events []anthropicEvent
+	for _, c := range chunks {
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(c), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, translator.Chunk(&chunk)...)
+	}
+	events = append(events, translator.Finish()...)
+	for _, e := range events {
+		names = append(names, e.Name)
+	}
+
+	want := []string{
+		"message_start", "ping",
+		"content_block_start", "content_block_delta",
+		"content_block_stop", "content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v\nwant     %v", names, want)
+	}
+
+	delta, _ := json.Marshal(events[8].Data)
+	if !strings.Contains(string(delta), `"stop_reason":"tool_use"`) || !strings.Contains(string(delta), `"output_tokens":3`) {
+		t.Errorf("message_delta = %s", delta)
+	}
+	toolStart, _ := json.Marshal(events[5].Data)
+	if !strings.Contains(string(toolStart), `"index":1`) || !strings.Contains(string(toolStart), `"input":{}`) {
+		t.Errorf("tool block start = %s", toolStart)
+	}
+}
+
+func TestAnthropicErrorType(t *testing.T) {
+	tests := map[int]string{
+		http.StatusBadRequest:          "invalid_request_error",
+		http.StatusUnauthorized:        "authentication_error",
+		http.StatusTooManyRequests:     "rate_limit_error",
+		http.StatusServiceUnavailable:  "overloaded_error",
+		http.StatusInternalServerError: "api_error",
+	}
+	for status, want := range tests {
+		if got := anthropicErrorType(status); got != want {
+			t.Errorf("anthropicErrorType(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -86,7 +86,7 @@ func (g *Gateway) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Use Authenticator to create the key (handles hashing and storage)
-	apiKey, err := g.authenticator.CreateAPIKey(ctx, req.TenantID, envID, req.Name)
+	apiKey, err := g.authenticator.CreateAPIKey(ctx, req.TenantID, envID, req.Name, g.tenantPlan(ctx, req.TenantID))
 	if err != nil {
 		g.logger.Error("failed to create api key", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to create api key")
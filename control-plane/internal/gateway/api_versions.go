@@ -0,0 +1,235 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// API versioning.
+//
+// The tenant API is served under /v1 and /v2. Both namespaces share the
+// middleware stack and most handlers; setupTenantRoutes receives the version
+// it is wiring so a route can get a version-specific handler or exist in one
+// namespace only. v2 answers errors in a richer envelope carrying a stable
+// code and the request ID. v1 routes slated for removal answer with
+// Deprecation, Sunset and successor Link headers (RFC 8594) so SDKs can
+// migrate before they go away.
+
+// apiVersion is a version of the tenant API
+type apiVersion int
+
+const (
+	apiV1 apiVersion = 1
+	apiV2 apiVersion = 2
+)
+
+// apiVersions are the served tenant API versions, oldest first
+var apiVersions = []apiVersion{apiV1, apiV2}
+
+func (v apiVersion) String() string {
+	return fmt.Sprintf("v%d", int(v))
+}
+
+// prefix is the path namespace the version is served under
+func (v apiVersion) prefix() string {
+	return "/" + v.String()
+}
+
+type apiVersionKey struct{}
+
+// apiVersionFromContext returns the API version a request was routed to
+func apiVersionFromContext(ctx context.Context) apiVersion {
+	if v, ok := ctx.Value(apiVersionKey{}).(apiVersion); ok {
+		return v
+	}
+	return apiV1
+}
+
+// apiVersionMiddleware tags requests with the version they were routed to.
+// It runs ahead of authentication so every v2 error, including auth and
+// rate limit rejections, uses the v2 envelope.
+func (g *Gateway) apiVersionMiddleware(version apiVersion) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("API-Version", version.String())
+			r = r.WithContext(context.WithValue(r.Context(), apiVersionKey{}, version))
+			if version < apiV2 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &v2ErrorWriter{ResponseWriter: w, requestID: middleware.GetReqID(r.Context())}
+			next.ServeHTTP(ew, r)
+			ew.finish(g)
+		})
+	}
+}
+
+// routeDeprecation describes a v1 route slated for removal
+type routeDeprecation struct {
+	// Since is when the route was deprecated
+	Since time.Time
+	// Successor is the path that replaces it
+	Successor string
+}
+
+// v1UsageByDate is superseded by the fixed-granularity usage series
+// (/usage/by-hour, /usage/by-day, /usage/by-month)
+var v1UsageByDate = routeDeprecation{
+	Since:     time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC),
+	Successor: "/v2/usage/by-day",
+}
+
+// SetAPIv1Sunset sets when deprecated v1 routes stop being served. The
+// zero time leaves the date unannounced.
+func (g *Gateway) SetAPIv1Sunset(sunset time.Time) {
+	g.apiV1Sunset = sunset
+}
+
+// deprecatedRoute marks responses from a route slated for removal
+func (g *Gateway) deprecatedRoute(d routeDeprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+			if !g.apiV1Sunset.IsZero() {
+				h.Set("Sunset", g.apiV1Sunset.UTC().Format(http.TimeFormat))
+			}
+			if d.Successor != "" {
+				h.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", d.Successor))
+			}
+			route := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			deprecatedAPIRequests.WithLabelValues(r.Method, route).Inc()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// nodePath maps a tenant API path to the node's path. Nodes only speak the
+// OpenAI-compatible /v1 API, whichever version the client called.
+func nodePath(path string) string {
+	if rest, ok := strings.CutPrefix(path, apiV2.prefix()+"/"); ok {
+		return apiV1.prefix() + "/" + rest
+	}
+	return path
+}
+
+// v2ErrorCode returns the stable error code for a status
+func v2ErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return "invalid_request"
+	case http.StatusUnauthorized:
+		return "unauthenticated"
+	case http.StatusPaymentRequired:
+		return "payment_required"
+	case http.StatusForbidden:
+		return "permission_denied"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusGone:
+		return "gone"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	case http.StatusGatewayTimeout:
+		return "timeout"
+	default:
+		return "internal_error"
+	}
+}
+
+// v2ErrorBody renders an error in the v2 envelope. v1 fields keep their
+// meaning; any extra fields of the original error are carried over.
+func v2ErrorBody(status int, original []byte, requestID string) map[string]interface{} {
+	fields := map[string]interface{}{}
+	var parsed struct {
+		Error map[string]interface{} `json:"error"`
+	}
+	if json.Unmarshal(original, &parsed) == nil && parsed.Error != nil {
+		fields = parsed.Error
+	}
+	if _, ok := fields["message"].(string); !ok {
+		fields["message"] = chatErrorMessage(original)
+	}
+	if _, ok := fields["type"]; !ok {
+		fields["type"] = "invalid_request_error"
+	}
+	fields["code"] = v2ErrorCode(status)
+	if requestID != "" {
+		fields["request_id"] = requestID
+	}
+	return map[string]interface{}{"error": fields}
+}
+
+// v2ErrorWriter holds back JSON error responses so they can be rewritten in
+// the v2 envelope. Everything else, including streams, passes straight
+// through.
+type v2ErrorWriter struct {
+	http.ResponseWriter
+	requestID string
+	status    int
+	holding   bool
+	body      bytes.Buffer
+}
+
+func (e *v2ErrorWriter) WriteHeader(status int) {
+	if e.status != 0 {
+		return
+	}
+	e.status = status
+	if status >= http.StatusBadRequest && strings.HasPrefix(e.Header().Get("Content-Type"), "application/json") {
+		e.holding = true
+		return
+	}
+	e.ResponseWriter.WriteHeader(status)
+}
+
+func (e *v2ErrorWriter) Write(b []byte) (int, error) {
+	if e.status == 0 {
+		e.WriteHeader(http.StatusOK)
+	}
+	if e.holding {
+		return e.body.Write(b)
+	}
+	return e.ResponseWriter.Write(b)
+}
+
+func (e *v2ErrorWriter) Flush() {
+	if e.holding {
+		return
+	}
+	if f, ok := e.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (e *v2ErrorWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// finish writes the held-back error, if any, in the v2 envelope
+func (e *v2ErrorWriter) finish(g *Gateway) {
+	if !e.holding {
+		return
+	}
+	e.ResponseWriter.Header().Del("Content-Length")
+	g.writeJSON(e.ResponseWriter, e.status, v2ErrorBody(e.status, e.body.Bytes(), e.requestID))
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+func TestAPIv2ErrorEnvelope(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+	handler := middleware.RequestID(g.apiVersionMiddleware(apiV2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiVersionFromContext(r.Context()) != apiV2 {
+			t.Error("request not tagged with v2")
+		}
+		g.writeError(w, http.StatusNotFound, "model not found")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/models/missing", nil)
+	req.Header.Set("X-Request-Id", "req-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if got := rec.Header().Get("API-Version"); got != "v2" {
+		t.Errorf("API-Version = %q, want v2", got)
+	}
+	var body struct {
+		Error map[string]string `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+	}
+	want := map[string]string{
+		"message":    "model not found",
+		"type":       "invalid_request_error",
+		"code":       "not_found",
+		"request_id": "req-123",
+	}
+	for k, v := range want {
+		if body.Error[k] != v {
+			t.Errorf("error.%s = %q, want %q", k, body.Error[k], v)
+		}
+	}
+}
+
+func TestAPIv2PassesThroughSuccessAndStreams(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+	handler := g.apiVersionMiddleware(apiV2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: one\n\n"))
+		w.(http.Flusher).Flush()
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v2/chat/completions", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "data: one\n\n" {
+		t.Errorf("got %d %q, want the stream untouched", rec.Code, rec.Body.String())
+	}
+	if !rec.Flushed {
+		t.Error("flush not passed through")
+	}
+}
+
+func TestAPIv1ErrorsUnchanged(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+	handler := g.apiVersionMiddleware(apiV1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.writeError(w, http.StatusBadRequest, "bad")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/usage", nil))
+
+	var body struct {
+		Error map[string]string `json:"error"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if _, ok := body.Error["code"]; ok {
+		t.Errorf("v1 error gained a code: %s", rec.Body.String())
+	}
+}
+
+func TestDeprecatedRouteHeaders(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+	r := chi.NewRouter()
+	r.With(g.deprecatedRoute(v1UsageByDate)).Get("/v1/usage/by-date", func(w http.ResponseWriter, r *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/usage/by-date", nil))
+	if got := rec.Header().Get("Deprecation"); got != "@1792108800" {
+		t.Errorf("Deprecation = %q", got)
+	}
+	if got := rec.Header().Get("Sunset"); got != "" {
+		t.Errorf("Sunset = %q before a date is announced", got)
+	}
+	if got := rec.Header().Get("Link"); got != `</v2/usage/by-day>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+
+	g.SetAPIv1Sunset(time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/usage/by-date", nil))
+	if got := rec.Header().Get("Sunset"); got != "Thu, 01 Apr 2027 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+}
+
+func TestNodePath(t *testing.T) {
+	tests := map[string]string{
+		"/v2/chat/completions": "/v1/chat/completions",
+		"/v1/embeddings":       "/v1/embeddings",
+		"/v2":                  "/v2",
+	}
+	for in, want := range tests {
+		if got := nodePath(in); got != want {
+			t.Errorf("nodePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultAudioMaxFileBytes matches OpenAI's transcription upload limit
+	defaultAudioMaxFileBytes = 25 << 20
+
+	// audioFormOverhead is allowed on top of the file for the other form
+	// fields and multipart boundaries
+	audioFormOverhead = 1 << 20
+
+	// wavHeaderBytes is how much of an upload is kept to read a WAV header
+	wavHeaderBytes = 4096
+)
+
+var audioSecondsTranscribed = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gateway_audio_seconds_transcribed_total",
+		Help: "Seconds of audio transcribed and billed",
+	},
+	[]string{"model"},
+)
+
+// SetAudioMaxFileSize sets the largest audio file accepted for transcription
+func (g *Gateway) SetAudioMaxFileSize(maxBytes int64) {
+	if maxBytes > 0 {
+		g.audioMaxFileBytes = maxBytes
+	}
+}
+
+// transcriptionForm is what the gateway reads of a transcription upload;
+// the form itself is forwarded to the node unchanged
+type transcriptionForm struct {
+	Model    string
+	FileSize int64
+	// WAVSeconds is the duration read from a WAV header, 0 for other formats
+	WAVSeconds float64
+}
+
+// parseTranscriptionForm reads the model and audio file of a
+// multipart/form-data transcription request
+func parseTranscriptionForm(contentType string, body []byte) (transcriptionForm, error) {
+	var form transcriptionForm
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return form, errors.New("request must be multipart/form-data")
+	}
+
+	hasFile := false
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return form, errors.New("invalid multipart body")
+		}
+
+		switch part.FormName() {
+		case "model":
+			value, err := io.ReadAll(io.LimitReader(part, 256))
+			if err != nil {
+				return form, errors.New("invalid multipart body")
+			}
+			form.Model = strings.TrimSpace(string(value))
+		case "file":
+			header := make([]byte, wavHeaderBytes)
+			n, err := io.ReadFull(part, header)
+			if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+				return form, errors.New("invalid multipart body")
+			}
+			rest, err := io.Copy(io.Discard, part)
+			if err != nil {
+				return form, errors.New("invalid multipart body")
+			}
+			hasFile = true
+			form.FileSize = int64(n) + rest
+			form.WAVSeconds = wavDuration(header[:n], form.FileSize)
+		}
+		part.Close()
+	}
+
+	if form.Model == "" {
+		return form, errors.New("model is required")
+	}
+	if !hasFile || form.FileSize == 0 {
+		return form, errors.New("file is required")
+	}
+	return form, nil
+}
+
+// wavDuration returns the length in seconds of a WAV file from its header,
+// or 0 when the header isn't a readable WAV. fileSize bounds the data chunk,
+// which streamed WAVs leave unset.
+func wavDuration(header []byte, fileSize int64) float64 {
+	if len(header) < 12 || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return 0
+	}
+
+	var byteRate uint32
+	for offset := 12; offset+8 <= len(header); {
+		id := string(header[offset : offset+4])
+		size := binary.LittleEndian.Uint32(header[offset+4 : offset+8])
+		data := offset + 8
+
+		switch id {
+		case "fmt ":
+			if data+12 > len(header) {
+				return 0
+			}
+			byteRate = binary.LittleEndian.Uint32(header[data+8 : data+12])
+		case "data":
+			if byteRate == 0 {
+				return 0
+			}
+			dataSize := int64(size)
+			if remaining := fileSize - int64(data); dataSize > remaining {
+				dataSize = remaining
+			}
+			return float64(dataSize) / float64(byteRate)
+		}
+		// Chunks are padded to an even size
+		offset = data + int(size) + int(size%2)
+	}
+	return 0
+}
+
+// transcriptionSeconds returns the audio duration a node reported: the
+// usage seconds of OpenAI's current format, or verbose_json's duration.
+// It returns 0 for text, srt and vtt responses.
+func transcriptionSeconds(body []byte) float64 {
+	var resp struct {
+		Duration *float64 `json:"duration"`
+		Usage    *struct {
+			Type    string  `json:"type"`
+			Seconds float64 `json:"seconds"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0
+	}
+	if resp.Usage != nil && resp.Usage.Type == "duration" && resp.Usage.Seconds > 0 {
+		return resp.Usage.Seconds
+	}
+	if resp.Duration != nil && *resp.Duration > 0 {
+		return *resp.Duration
+	}
+	return 0
+}
+
+// audioCostMicrodollars prices audio by the started second
+func audioCostMicrodollars(seconds, pricePerMinute float64) int64 {
+	return int64(math.Round(math.Ceil(seconds) / 60 * pricePerMinute * 1e6))
+}
+
+// audioModel is an audio model's ID and per-minute price
+type audioModel struct {
+	ID             uuid.UUID
+	PricePerMinute float64
+}
+
+// getAudioModel looks up an audio model, returning nil when the model
+// doesn't exist or isn't an audio model
+func (g *Gateway) getAudioModel(ctx context.Context, name string) (*audioModel, error) {
+	var m audioModel
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT id, COALESCE(price_per_audio_minute, 0)::float8
+		FROM models WHERE name = $1 AND type = 'audio'
+	`, name).Scan(&m.ID, &m.PricePerMinute)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// handleAudioTranscriptions proxies an OpenAI-compatible transcription
+// upload to a node of the audio workload class and bills the audio's
+// duration
+// Tenant API - POST /v1/audio/transcriptions
+func (g *Gateway) handleAudioTranscriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	maxBytes := g.audioMaxFileBytes
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes+audioFormOverhead))
+	r.Body.Close()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		g.writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("audio file exceeds the %d MB limit", maxBytes>>20))
+		return
+	}
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	form, err := parseTranscriptionForm(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if form.FileSize > maxBytes {
+		g.writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("audio file exceeds the %d MB limit", maxBytes>>20))
+		return
+	}
+
+	// Apply deprecation headers and enforce sunset cutoff
+	if !g.enforceModelLifecycle(w, r, form.Model) {
+		return
+	}
+
+	// Gated models need the tenant to have accepted their license
+	if !g.enforceModelLicense(w, r, form.Model) {
+		return
+	}
+
+	model, err := g.getAudioModel(ctx, form.Model)
+	if err != nil {
+		g.logger.Error("failed to get audio model", zap.Error(err), zap.String("model", form.Model))
+		g.writeError(w, http.StatusInternalServerError, "failed to get model")
+		return
+	}
+	if model == nil {
+		g.writeError(w, http.StatusBadRequest, fmt.Sprintf("model '%s' does not support audio transcription", form.Model))
+		return
+	}
+
+	// Shed traffic for models over their fleet-wide error budget
+	if !g.allowModelRequest(w, r, form.Model) {
+		return
+	}
+
+	g.logger.Info("transcription request",
+		zap.String("model", form.Model),
+		zap.Int64("file_bytes", form.FileSize),
+	)
+
+	// Route by the environment's region and the tenant's plan
+	r = r.WithContext(g.withRoutingOrigin(ctx))
+	ctx = r.Context()
+
+	// Select best audio endpoint
+	endpoint, err := g.LoadBalancer.SelectWorkloadEndpoint(ctx, form.Model, nodes.WorkloadAudio)
+	if errors.Is(err, ErrNodesAtCapacity) {
+		g.writeNodesAtCapacity(w, form.Model)
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to select endpoint", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to select endpoint")
+		return
+	}
+	if endpoint == "" {
+		g.writeError(w, http.StatusServiceUnavailable, "no healthy audio nodes for model")
+		return
+	}
+
+	// The form is forwarded as uploaded
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+
+	start := time.Now()
+	resp, err := g.proxyRequest(endpoint, r)
+	duration := time.Since(start)
+
+	// Record stats; an expired client deadline isn't the node's failure
+	isError := (err != nil && !deadlineExceeded(r)) || (resp != nil && resp.StatusCode >= 500)
+	g.LoadBalancer.RecordRequest(endpoint, duration, isError)
+	g.recordModelOutcome(ctx, form.Model, isError)
+	g.trackNodeRequest(r, endpoint, form.Model, start, resp, err)
+
+	if err != nil {
+		g.writeProxyError(w, r, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		g.logger.Error("failed to read transcription", zap.Error(err))
+		g.writeError(w, http.StatusBadGateway, "failed to read node response")
+		return
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		seconds := transcriptionSeconds(respBody)
+		if seconds == 0 {
+			seconds = form.WAVSeconds
+		}
+		g.recordAudioUsage(r, endpoint, form.Model, model, seconds, resp.StatusCode, duration)
+	}
+
+	for key, values := range resp.Header {
+		if key == "Content-Length" {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(respBody)
+}
+
+// recordAudioUsage bills a transcription by its duration. The duration is
+// unknown for plain text responses to non-WAV uploads; those are recorded
+// unbilled and flagged for reconciliation.
+func (g *Gateway) recordAudioUsage(r *http.Request, endpoint, modelName string, model *audioModel, seconds float64, status int, latency time.Duration) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return
+	}
+	envID, _ := ctx.Value("environment_id").(uuid.UUID)
+
+	metadata := map[string]interface{}{
+		"workload_class": nodes.WorkloadAudio,
+		"audio_seconds":  seconds,
+	}
+	if seconds <= 0 {
+		metadata["audio_seconds_unknown"] = true
+		g.logger.Warn("transcription duration unknown, usage recorded unbilled",
+			zap.String("model", modelName),
+			zap.String("tenant_id", tenantID.String()),
+		)
+	}
+	encoded, _ := json.Marshal(metadata)
+
+	requestID := middleware.GetReqID(ctx)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	cost := audioCostMicrodollars(seconds, model.PricePerMinute)
+	latencyMs := int(latency.Milliseconds())
+	usage := models.UsageRecord{
+		ID:               uuid.New(),
+		RequestID:        &requestID,
+		Timestamp:        time.Now(),
+		TenantID:         tenantID,
+		EnvironmentID:    envID,
+		ModelID:          &model.ID,
+		StatusCode:       &status,
+		LatencyMs:        &latencyMs,
+		CostMicrodollars: &cost,
+		Metadata:         string(encoded),
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok {
+		usage.APIKeyID = &keyInfo.ID
+	}
+	if nodeID, err := uuid.Parse(g.LoadBalancer.getNodeIDForEndpoint(endpoint)); err == nil {
+		usage.NodeID = &nodeID
+	}
+
+	audioSecondsTranscribed.WithLabelValues(modelName).Add(math.Ceil(seconds))
+	g.recordUsage(ctx, usage)
+}
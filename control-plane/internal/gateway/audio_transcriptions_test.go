@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"mime/multipart"
+	"testing"
+)
+
+// testWAV builds a 16 kHz mono 16-bit WAV of the given length
+func testWAV(seconds int) []byte {
+	const sampleRate, blockAlign = 16000, 2
+	dataSize := uint32(seconds * sampleRate * blockAlign)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, 36+dataSize)
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, uint16(1))                     // PCM
+	binary.Write(&b, binary.LittleEndian, uint16(1))                     // channels
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate))            // sample rate
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate*blockAlign)) // byte rate
+	binary.Write(&b, binary.LittleEndian, uint16(blockAlign))            // block align
+	binary.Write(&b, binary.LittleEndian, uint16(16))                    // bits per sample
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, dataSize)
+	b.Write(make([]byte, dataSize))
+	return b.Bytes()
+}
+
+func transcriptionBody(t *testing.T, model string, file []byte) ([]byte, string) {
+	t.Helper()
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	if model != "" {
+		mw.WriteField("model", model)
+	}
+	if file != nil {
+		fw, err := mw.CreateFormFile("file", "audio.wav")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(file)
+	}
+	mw.WriteField("response_format", "json")
+	mw.Close()
+	return b.Bytes(), mw.FormDataContentType()
+}
+
+func TestParseTranscriptionForm(t *testing.T) {
+	wav := testWAV(3)
+	body, contentType := transcriptionBody(t, "whisper-large-v3", wav)
+
+	form, err := parseTranscriptionForm(contentType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form.Model != "whisper-large-v3" || form.FileSize != int64(len(wav)) {
+		t.Errorf("form = %+v, want model whisper-large-v3 and %d bytes", form, len(wav))
+	}
+	if math.Abs(form.WAVSeconds-3) > 0.001 {
+		t.Errorf("WAVSeconds = %v, want 3", form.WAVSeconds)
+	}
+
+	// Other formats have no duration until the node reports it
+	body, contentType = transcriptionBody(t, "whisper-large-v3", []byte("ID3 mp3 data"))
+	if form, err := parseTranscriptionForm(contentType, body); err != nil || form.WAVSeconds != 0 {
+		t.Errorf("mp3 upload = %+v, %v", form, err)
+	}
+
+	body, contentType = transcriptionBody(t, "", wav)
+	if _, err := parseTranscriptionForm(contentType, body); err == nil {
+		t.Error("expected an error without a model")
+	}
+	body, contentType = transcriptionBody(t, "whisper-large-v3", nil)
+	if _, err := parseTranscriptionForm(contentType, body); err == nil {
+		t.Error("expected an error without a file")
+	}
+	if _, err := parseTranscriptionForm("application/json", []byte(`{"model":"m"}`)); err == nil {
+		t.Error("expected an error for a JSON body")
+	}
+}
+
+func TestWAVDurationTruncated(t *testing.T) {
+	wav := testWAV(10)
+	// A streamed WAV's data size can exceed what was uploaded
+	if got := wavDuration(wav[:wavHeaderBytes], int64(len(wav)/2)); got >= 5 {
+		t.Errorf("duration = %v, want under 5s for half a 10s file", got)
+	}
+	if got := wavDuration([]byte("not a wav file"), 14); got != 0 {
+		t.Errorf("duration of non-WAV = %v, want 0", got)
+	}
+}
+
+func TestTranscriptionSeconds(t *testing.T) {
+	tests := []struct {
+		body string
+		want float64
+	}{
+		{`{"text":"hi","usage":{"type":"duration","seconds":12}}`, 12},
+		{`{"task":"transcribe","duration":8.5,"text":"hi"}`, 8.5},
+		{`{"text":"hi","usage":{"type":"tokens","input_tokens":10}}`, 0},
+		{`hello world`, 0},
+	}
+	for _, tt := range tests {
+		if got := transcriptionSeconds([]byte(tt.body)); got != tt.want {
+			t.Errorf("transcriptionSeconds(%s) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestAudioCostMicrodollars(t *testing.T) {
+	// $0.006 per minute, billed by the started second
+	if got := audioCostMicrodollars(60, 0.006); got != 6000 {
+		t.Errorf("cost of a minute = %d, want 6000", got)
+	}
+	if got := audioCostMicrodollars(0.2, 0.006); got != 100 {
+		t.Errorf("cost of 0.2s = %d, want 100", got)
+	}
+	if got := audioCostMicrodollars(0, 0.006); got != 0 {
+		t.Errorf("cost of unknown duration = %d, want 0", got)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/crosslogic/control-plane/internal/billing"
+	"github.com/crosslogic/control-plane/internal/jobs"
 	"github.com/crosslogic/control-plane/pkg/cache"
 	"github.com/crosslogic/control-plane/pkg/database"
 	"github.com/crosslogic/control-plane/pkg/models"
@@ -19,14 +21,16 @@ import (
 type Authenticator struct {
 	db     *database.Database
 	cache  *cache.Cache
+	jobs   *jobs.Queue
 	logger *zap.Logger
 }
 
 // NewAuthenticator creates a new authenticator
-func NewAuthenticator(db *database.Database, cache *cache.Cache, logger *zap.Logger) *Authenticator {
+func NewAuthenticator(db *database.Database, cache *cache.Cache, queue *jobs.Queue, logger *zap.Logger) *Authenticator {
 	return &Authenticator{
 		db:     db,
 		cache:  cache,
+		jobs:   queue,
 		logger: logger,
 	}
 }
@@ -89,6 +93,9 @@ func (a *Authenticator) ValidateAPIKey(ctx context.Context, apiKey string) (*mod
 	}
 
 	// Validate status
+	if keyInfo.Status == "quarantined" {
+		return nil, ErrAPIKeyQuarantined
+	}
 	if keyInfo.Status != "active" {
 		return nil, fmt.Errorf("API key is not active")
 	}
@@ -118,28 +125,28 @@ func (a *Authenticator) ValidateAPIKey(ctx context.Context, apiKey string) (*mod
 		return nil, fmt.Errorf("environment is not active")
 	}
 
+	// Shared environment budget, enforced by the rate limiter
+	budget, err := loadEnvironmentBudget(ctx, a.db.Pool, keyInfo.EnvironmentID, keyInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo.Budget = budget
+
 	// Cache the key info for 60 seconds
 	keyJSON, _ := json.Marshal(keyInfo)
 	a.cache.Set(ctx, cacheKey, string(keyJSON), 60*time.Second)
 
-	// Update last used timestamp (async)
-	go a.updateLastUsed(keyInfo.ID)
+	// Update last used timestamp in the background
+	a.touchLastUsed(ctx, keyInfo.ID)
 
 	return &keyInfo, nil
 }
 
-// updateLastUsed updates the last_used_at timestamp for an API key
-func (a *Authenticator) updateLastUsed(keyID uuid.UUID) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	_, err := a.db.Pool.Exec(ctx, `
-		UPDATE api_keys
-		SET last_used_at = CURRENT_TIMESTAMP
-		WHERE id = $1
-	`, keyID)
-	if err != nil {
-		a.logger.Warn("failed to update last_used_at", zap.Error(err), zap.String("key_id", keyID.String()))
+// touchLastUsed queues an update of the key's last_used_at timestamp
+func (a *Authenticator) touchLastUsed(ctx context.Context, keyID uuid.UUID) {
+	args := touchAPIKeyArgs{KeyID: keyID, UsedAt: time.Now()}
+	if _, err := a.jobs.Enqueue(ctx, jobTouchAPIKey, args); err != nil {
+		a.logger.Warn("failed to queue last_used_at update", zap.Error(err), zap.String("key_id", keyID.String()))
 	}
 }
 
@@ -158,8 +165,9 @@ func GenerateAPIKey(env string) string {
 	return fmt.Sprintf("clsk_%s_%s", env, randomPart)
 }
 
-// CreateAPIKey creates a new API key in the database
-func (a *Authenticator) CreateAPIKey(ctx context.Context, tenantID, environmentID uuid.UUID, name string) (string, error) {
+// CreateAPIKey creates a new API key in the database with the limits of its
+// environment's rate limit template, within the tenant's plan
+func (a *Authenticator) CreateAPIKey(ctx context.Context, tenantID, environmentID uuid.UUID, name string, plan billing.Plan) (string, error) {
 	// Generate new API key
 	apiKey := GenerateAPIKey("live")
 	keyHash := hashAPIKey(apiKey)
@@ -170,10 +178,17 @@ func (a *Authenticator) CreateAPIKey(ctx context.Context, tenantID, environmentI
 	err := a.db.Pool.QueryRow(ctx, `
 		INSERT INTO api_keys (
 			key_hash, key_prefix, tenant_id, environment_id,
-			name, role, status
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			name, role, status,
+			rate_limit_requests_per_min, concurrency_limit, rate_limit_tokens_per_min
+		)
+		SELECT $1, $2, $3, e.id, $5, $6, $7,
+			LEAST(t.requests_per_min, $8::int), LEAST(t.concurrency_limit, $9::int), LEAST(t.tokens_per_min, $10::int)
+		FROM environments e
+		LEFT JOIN rate_limit_templates t ON t.id = e.rate_limit_template_id
+		WHERE e.id = $4
 		RETURNING id
-	`, keyHash, keyPrefix, tenantID, environmentID, name, "developer", "active").Scan(&keyID)
+	`, keyHash, keyPrefix, tenantID, environmentID, name, "developer", "active",
+		plan.RequestsPerMin, plan.ConcurrencyLimit, plan.TokensPerMin).Scan(&keyID)
 	if err != nil {
 		return "", fmt.Errorf("failed to create API key: %w", err)
 	}
@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// lowPriorityMaxQueueDepth is the number of requests waiting on the selected
+// node above which `priority: low` requests are deferred with 503 + Retry-After
+// instead of joining the queue.
+const lowPriorityMaxQueueDepth = 4
+
+// QueueStatus returns the current queue depth for an endpoint and an estimate
+// of how long a newly queued request would wait before being scheduled.
+func (lb *IntelligentLoadBalancer) QueueStatus(endpoint string) (int64, time.Duration) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	stats, ok := lb.stats[endpoint]
+	if !ok {
+		return 0, 0
+	}
+	return stats.QueueDepth, estimateQueueWait(stats.QueueDepth, stats.ActiveRequests, stats.Latency)
+}
+
+// estimateQueueWait approximates the time until a waiting request is scheduled:
+// running requests complete roughly every latency/running, and each waiting
+// request ahead needs one of those slots.
+func estimateQueueWait(waiting, running int64, latency time.Duration) time.Duration {
+	if waiting <= 0 || latency <= 0 {
+		return 0
+	}
+	if running < 1 {
+		running = 1
+	}
+	return time.Duration(int64(latency) * waiting / running)
+}
+
+// parseRequestPriority reports whether the client asked for low-priority
+// handling, via the X-Priority header or a string `"priority": "low"` body
+// field. A string priority is removed from the body before proxying because
+// vLLM's own `priority` parameter is an integer; integer values are passed
+// through untouched.
+func parseRequestPriority(r *http.Request, body []byte) (bool, []byte) {
+	lowPriority := strings.EqualFold(r.Header.Get("X-Priority"), "low")
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return lowPriority, body
+	}
+	raw, ok := fields["priority"]
+	if !ok {
+		return lowPriority, body
+	}
+
+	var priority string
+	if err := json.Unmarshal(raw, &priority); err != nil {
+		return lowPriority, body
+	}
+	if strings.EqualFold(priority, "low") {
+		lowPriority = true
+	}
+
+	delete(fields, "priority")
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return lowPriority, body
+	}
+	return lowPriority, stripped
+}
+
+// applyBackpressure sets X-Queue-Depth and X-Estimated-Wait-Ms for the
+// selected endpoint so clients can back off early. Low-priority requests are
+// rejected with 503 and Retry-After when the node is already queueing.
+// It returns false when the request has been answered.
+func (g *Gateway) applyBackpressure(w http.ResponseWriter, endpoint string, lowPriority bool) bool {
+	depth, wait := g.LoadBalancer.QueueStatus(endpoint)
+
+	w.Header().Set("X-Queue-Depth", strconv.FormatInt(depth, 10))
+	w.Header().Set("X-Estimated-Wait-Ms", strconv.FormatInt(wait.Milliseconds(), 10))
+
+	if !lowPriority || depth < lowPriorityMaxQueueDepth {
+		return true
+	}
+
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+	g.logger.Info("deferred low priority request",
+		zap.String("endpoint", endpoint),
+		zap.Int64("queue_depth", depth),
+		zap.Duration("estimated_wait", wait),
+	)
+
+	g.writeError(w, http.StatusServiceUnavailable, "server is busy; low priority request deferred, retry after the indicated delay")
+	return false
+}
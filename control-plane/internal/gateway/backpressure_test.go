@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEstimateQueueWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		waiting int64
+		running int64
+		latency time.Duration
+		want    time.Duration
+	}{
+		{"empty queue", 0, 8, time.Second, 0},
+		{"no latency sample", 4, 2, 0, 0},
+		{"idle node", 3, 0, time.Second, 3 * time.Second},
+		{"spread across running", 8, 4, time.Second, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateQueueWait(tt.waiting, tt.running, tt.latency); got != tt.want {
+				t.Errorf("estimateQueueWait() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRequestPriority(t *testing.T) {
+	t.Run("string priority is stripped", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		low, body := parseRequestPriority(r, []byte(`{"model":"m","priority":"low"}`))
+		if !low {
+			t.Fatal("expected low priority")
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			t.Fatalf("invalid body: %v", err)
+		}
+		if _, ok := fields["priority"]; ok {
+			t.Error("string priority should be removed before proxying")
+		}
+		if fields["model"] != "m" {
+			t.Errorf("model = %v, want m", fields["model"])
+		}
+	})
+
+	t.Run("integer priority passes through", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		in := []byte(`{"model":"m","priority":5}`)
+		low, body := parseRequestPriority(r, in)
+		if low {
+			t.Error("integer priority should not be treated as low")
+		}
+		if string(body) != string(in) {
+			t.Errorf("body changed: %s", body)
+		}
+	})
+
+	t.Run("header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		r.Header.Set("X-Priority", "LOW")
+		if low, _ := parseRequestPriority(r, []byte(`{"model":"m"}`)); !low {
+			t.Error("expected X-Priority header to request low priority")
+		}
+	})
+}
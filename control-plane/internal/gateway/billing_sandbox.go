@@ -0,0 +1,243 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxSandboxUsageEntries caps one synthetic usage injection
+const maxSandboxUsageEntries = 1000
+
+// billingSandboxRequest is the body of PUT /admin/tenants/{id}/billing-sandbox
+type billingSandboxRequest struct {
+	Cycle            string                        `json:"cycle"` // e.g. "1h"; empty uses BILLING_SANDBOX_CYCLE
+	IncludeLiveUsage *bool                         `json:"include_live_usage"`
+	PriceOverrides   map[string]billing.ModelPrice `json:"price_overrides"`
+}
+
+func (req *billingSandboxRequest) settings() (billing.SandboxSettings, error) {
+	settings := billing.SandboxSettings{
+		IncludeLiveUsage: true,
+		PriceOverrides:   req.PriceOverrides,
+	}
+	if req.IncludeLiveUsage != nil {
+		settings.IncludeLiveUsage = *req.IncludeLiveUsage
+	}
+	if req.Cycle != "" {
+		cycle, err := time.ParseDuration(req.Cycle)
+		if err != nil {
+			return settings, fmt.Errorf("invalid cycle %q", req.Cycle)
+		}
+		settings.CycleLength = cycle
+	}
+	return settings, nil
+}
+
+// sandboxUsageRequest is the body of POST /admin/tenants/{id}/billing-sandbox/usage
+type sandboxUsageRequest struct {
+	Usage []billing.SandboxUsage `json:"usage"`
+}
+
+func (req *sandboxUsageRequest) validate() error {
+	if len(req.Usage) == 0 {
+		return fmt.Errorf("usage is required")
+	}
+	if len(req.Usage) > maxSandboxUsageEntries {
+		return fmt.Errorf("at most %d usage entries per request", maxSandboxUsageEntries)
+	}
+	for i := range req.Usage {
+		u := &req.Usage[i]
+		u.Model = strings.TrimSpace(u.Model)
+		if u.Model == "" {
+			return fmt.Errorf("usage[%d]: model is required", i)
+		}
+		if u.Requests == 0 {
+			u.Requests = 1
+		}
+		if u.Requests < 0 || u.PromptTokens < 0 || u.CompletionTokens < 0 {
+			return fmt.Errorf("usage[%d]: requests and tokens must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// sandboxTenantID parses the tenant ID route parameter and checks the
+// sandbox runner is available
+func (g *Gateway) sandboxTenantID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	if g.BillingSandbox == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "billing sandbox is not configured")
+		return uuid.Nil, false
+	}
+	tenantID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid tenant ID")
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// writeSandboxError maps sandbox errors to responses
+func (g *Gateway) writeSandboxError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, billing.ErrSandboxNotFound):
+		g.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, billing.ErrSandboxDisabled):
+		g.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, billing.ErrInvalidSandboxSettings):
+		g.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		g.logger.Error("billing sandbox request failed", zap.String("action", action), zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to "+action)
+	}
+}
+
+// handleGetBillingSandbox returns a tenant's billing sandbox
+// GET /admin/tenants/{id}/billing-sandbox
+func (g *Gateway) handleGetBillingSandbox(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.sandboxTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	sb, err := g.BillingSandbox.Get(r.Context(), tenantID)
+	if err != nil {
+		g.writeSandboxError(w, err, "get billing sandbox")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, sb)
+}
+
+// handleEnableBillingSandbox creates or updates a tenant's billing sandbox
+// PUT /admin/tenants/{id}/billing-sandbox
+func (g *Gateway) handleEnableBillingSandbox(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.sandboxTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	var req billingSandboxRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	settings, err := req.settings()
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sb, err := g.BillingSandbox.Enable(r.Context(), tenantID, settings)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			g.writeError(w, http.StatusNotFound, "tenant not found")
+			return
+		}
+		g.writeSandboxError(w, err, "enable billing sandbox")
+		return
+	}
+
+	g.logger.Info("billing sandbox enabled",
+		zap.String("tenant_id", tenantID.String()),
+		zap.Int("cycle_seconds", sb.CycleSeconds),
+		zap.Int("price_overrides", len(sb.PriceOverrides)),
+	)
+
+	g.writeJSON(w, http.StatusOK, sb)
+}
+
+// handleDisableBillingSandbox stops a tenant's billing sandbox
+// DELETE /admin/tenants/{id}/billing-sandbox
+func (g *Gateway) handleDisableBillingSandbox(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.sandboxTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := g.BillingSandbox.Disable(r.Context(), tenantID); err != nil {
+		g.writeSandboxError(w, err, "disable billing sandbox")
+		return
+	}
+
+	g.logger.Info("billing sandbox disabled", zap.String("tenant_id", tenantID.String()))
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "disabled",
+	})
+}
+
+// handleRecordSandboxUsage injects synthetic usage into the current cycle
+// POST /admin/tenants/{id}/billing-sandbox/usage
+func (g *Gateway) handleRecordSandboxUsage(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.sandboxTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	var req sandboxUsageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := g.BillingSandbox.RecordUsage(r.Context(), tenantID, req.Usage); err != nil {
+		g.writeSandboxError(w, err, "record sandbox usage")
+		return
+	}
+
+	g.writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"recorded": len(req.Usage),
+	})
+}
+
+// handleAdvanceBillingSandbox closes the current cycle now and returns its
+// invoice, so a simulation doesn't have to wait for the cycle to end
+// POST /admin/tenants/{id}/billing-sandbox/advance
+func (g *Gateway) handleAdvanceBillingSandbox(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.sandboxTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	invoice, err := g.BillingSandbox.CloseCycle(r.Context(), tenantID, true)
+	if err != nil {
+		g.writeSandboxError(w, err, "advance billing sandbox")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, invoice)
+}
+
+// handleListSandboxInvoices lists a tenant's sandbox invoices, newest first
+// GET /admin/tenants/{id}/billing-sandbox/invoices
+func (g *Gateway) handleListSandboxInvoices(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.sandboxTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 24, 1, 200)
+	invoices, err := g.BillingSandbox.ListInvoices(r.Context(), tenantID, limit)
+	if err != nil {
+		g.writeSandboxError(w, err, "list sandbox invoices")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"data":   invoices,
+	})
+}
@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+)
+
+func TestBillingSandboxRequestSettings(t *testing.T) {
+	req := billingSandboxRequest{Cycle: "15m"}
+	settings, err := req.settings()
+	if err != nil {
+		t.Fatalf("settings() error = %v", err)
+	}
+	if settings.CycleLength != 15*time.Minute || !settings.IncludeLiveUsage {
+		t.Errorf("settings = %+v, want 15m cycle including live usage", settings)
+	}
+
+	off := false
+	req = billingSandboxRequest{IncludeLiveUsage: &off}
+	settings, err = req.settings()
+	if err != nil {
+		t.Fatalf("settings() error = %v", err)
+	}
+	if settings.CycleLength != 0 || settings.IncludeLiveUsage {
+		t.Errorf("settings = %+v, want default cycle without live usage", settings)
+	}
+
+	req = billingSandboxRequest{Cycle: "monthly"}
+	if _, err := req.settings(); err == nil {
+		t.Error("expected invalid cycle to be rejected")
+	}
+}
+
+func TestSandboxUsageRequestValidate(t *testing.T) {
+	req := sandboxUsageRequest{Usage: []billing.SandboxUsage{{Model: " llama-3-8b ", PromptTokens: 100}}}
+	if err := req.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+	if req.Usage[0].Model != "llama-3-8b" || req.Usage[0].Requests != 1 {
+		t.Errorf("usage = %+v, want trimmed model and one request", req.Usage[0])
+	}
+
+	invalid := []sandboxUsageRequest{
+		{},
+		{Usage: []billing.SandboxUsage{{Model: ""}}},
+		{Usage: []billing.SandboxUsage{{Model: "m", CompletionTokens: -1}}},
+		{Usage: make([]billing.SandboxUsage, maxSandboxUsageEntries+1)},
+	}
+	for i, req := range invalid {
+		if err := req.validate(); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+}
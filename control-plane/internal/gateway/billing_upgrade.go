@@ -0,0 +1,256 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// upgradeError is a plan upgrade rejection with an OpenAI-style error code
+type upgradeError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *upgradeError) Error() string { return e.message }
+
+// validatePlanUpgrade checks that the target plan can be self-served from the
+// tenant's current plan. Only upgrades are self-serve; downgrades and
+// enterprise go through sales.
+func validatePlanUpgrade(plans *billing.PlanCatalog, currentPlan, targetPlan string) (billing.Plan, error) {
+	target, ok := plans.Lookup(targetPlan)
+	if !ok {
+		return billing.Plan{}, &upgradeError{http.StatusBadRequest, "invalid_plan", fmt.Sprintf("unknown plan '%s'", targetPlan)}
+	}
+	if !target.SelfServe {
+		return billing.Plan{}, &upgradeError{http.StatusBadRequest, "plan_not_self_serve", fmt.Sprintf("the %s plan is not available for self-serve upgrade; please contact sales", target.Name)}
+	}
+
+	current := plans.Get(currentPlan)
+	if target.Name == currentPlan {
+		return billing.Plan{}, &upgradeError{http.StatusConflict, "plan_unchanged", fmt.Sprintf("tenant is already on the %s plan", target.Name)}
+	}
+	if target.Rank <= current.Rank {
+		return billing.Plan{}, &upgradeError{http.StatusBadRequest, "plan_downgrade_not_supported", fmt.Sprintf("cannot change from %s to %s; downgrades require contacting support", current.Name, target.Name)}
+	}
+	return target, nil
+}
+
+// tenantPlanCacheTTL bounds how long the request path caches a tenant's
+// plan; changes made outside applyPlan (webhooks, reconciliation) apply
+// within it
+const tenantPlanCacheTTL = 60 * time.Second
+
+func tenantPlanCacheKey(tenantID uuid.UUID) string {
+	return cache.TenantKey(tenantID, "billing_plan")
+}
+
+// lookupTenantPlan returns the tenant's current plan for the request path,
+// cached for tenantPlanCacheTTL. Unlike tenantPlan it fails when the plan
+// can't be read, so limits aren't enforced against the free plan instead.
+func (g *Gateway) lookupTenantPlan(ctx context.Context, tenantID uuid.UUID) (billing.Plan, error) {
+	key := tenantPlanCacheKey(tenantID)
+	if g.cache != nil {
+		if name, err := g.cache.Get(ctx, key); err == nil && name != "" {
+			return g.Plans.Get(name), nil
+		}
+	}
+
+	var planName string
+	if err := g.db.Pool.QueryRow(ctx, `SELECT billing_plan FROM tenants WHERE id = $1`, tenantID).Scan(&planName); err != nil {
+		return billing.Plan{}, fmt.Errorf("failed to load tenant plan: %w", err)
+	}
+	if g.cache != nil {
+		if err := g.cache.Set(ctx, key, planName, tenantPlanCacheTTL); err != nil {
+			g.logger.Debug("failed to cache tenant plan", zap.Error(err))
+		}
+	}
+	return g.Plans.Get(planName), nil
+}
+
+// tenantPlan returns the tenant's current plan, falling back to the free plan
+// if it cannot be read
+func (g *Gateway) tenantPlan(ctx context.Context, tenantID uuid.UUID) billing.Plan {
+	var planName string
+	if err := g.db.Pool.QueryRow(ctx, `SELECT billing_plan FROM tenants WHERE id = $1`, tenantID).Scan(&planName); err != nil {
+		g.logger.Warn("failed to load tenant plan", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+	}
+	return g.Plans.Get(planName)
+}
+
+// handleUpgradePlan moves the tenant onto a higher plan, creating or updating
+// its Stripe subscription, and applies the new plan's limits immediately
+// Tenant API - POST /v1/billing/upgrade
+func (g *Gateway) handleUpgradePlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok && keyInfo.Role == "read-only" {
+		g.writeError(w, http.StatusForbidden, "read-only API keys cannot change the billing plan")
+		return
+	}
+
+	var req struct {
+		Plan string `json:"plan"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Plan == "" {
+		g.writeError(w, http.StatusBadRequest, "plan is required")
+		return
+	}
+
+	var name, email, currentPlan string
+	var customerID, subscriptionID *string
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT name, email, billing_plan, stripe_customer_id, stripe_subscription_id
+		FROM tenants
+		WHERE id = $1 AND status = 'active' AND deleted_at IS NULL
+	`, tenantID).Scan(&name, &email, &currentPlan, &customerID, &subscriptionID)
+	if err != nil {
+		g.logger.Error("failed to load tenant for plan upgrade", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load tenant")
+		return
+	}
+
+	target, err := validatePlanUpgrade(g.Plans, currentPlan, req.Plan)
+	if err != nil {
+		upErr := err.(*upgradeError)
+		g.writeJSON(w, upErr.status, map[string]interface{}{
+			"error": map[string]string{
+				"message": upErr.message,
+				"type":    "invalid_request_error",
+				"code":    upErr.code,
+			},
+		})
+		return
+	}
+
+	// Bill the new plan before granting it
+	if target.StripePriceID != "" {
+		if g.Subscriptions == nil {
+			g.writeError(w, http.StatusServiceUnavailable, "billing is not configured")
+			return
+		}
+		result, err := g.Subscriptions.ChangePlan(ctx, billing.SubscriptionChange{
+			TenantID:       tenantID.String(),
+			TenantName:     name,
+			TenantEmail:    email,
+			CustomerID:     customerID,
+			SubscriptionID: subscriptionID,
+			PriceID:        target.StripePriceID,
+		})
+		if err != nil {
+			g.logger.Error("failed to change stripe subscription",
+				zap.Error(err),
+				zap.String("tenant_id", tenantID.String()),
+				zap.String("plan", target.Name),
+			)
+			g.writeError(w, http.StatusBadGateway, "failed to update subscription with payment provider")
+			return
+		}
+		customerID = &result.CustomerID
+		subscriptionID = &result.SubscriptionID
+	}
+
+	payload := map[string]interface{}{
+		"tenant_name":   name,
+		"previous_plan": currentPlan,
+		"plan":          target.Name,
+	}
+	if subscriptionID != nil {
+		payload["stripe_subscription_id"] = *subscriptionID
+	}
+	evt := events.NewEvent(events.EventTenantPlanChanged, tenantID.String(), payload)
+
+	if err := g.applyPlan(ctx, tenantID, target, customerID, subscriptionID, evt); err != nil {
+		g.logger.Error("failed to apply plan",
+			zap.Error(err),
+			zap.String("tenant_id", tenantID.String()),
+			zap.String("plan", target.Name),
+		)
+		g.writeError(w, http.StatusInternalServerError, "failed to apply plan")
+		return
+	}
+
+	g.logger.Info("tenant plan upgraded",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("previous_plan", currentPlan),
+		zap.String("plan", target.Name),
+	)
+
+	response := map[string]interface{}{
+		"tenant_id":     tenantID,
+		"previous_plan": currentPlan,
+		"plan":          target.Name,
+		"limits": map[string]interface{}{
+			"requests_per_min":  target.RequestsPerMin,
+			"concurrency_limit": target.ConcurrencyLimit,
+			"tokens_per_min":    target.TokensPerMin,
+		},
+		"features": map[string]bool{
+			"cloud_credentials": target.CloudCredentials,
+		},
+	}
+	if subscriptionID != nil {
+		response["stripe_subscription_id"] = *subscriptionID
+	}
+	g.writeJSON(w, http.StatusOK, response)
+}
+
+// applyPlan records the tenant's plan and rewrites the limits on all of its
+// API keys, then drops the cached keys so the limits apply on the next request.
+// Tier-gated features read billing_plan per request and follow automatically.
+// evt is written to the event outbox in the same transaction.
+func (g *Gateway) applyPlan(ctx context.Context, tenantID uuid.UUID, plan billing.Plan, customerID, subscriptionID *string, evt events.Event) error {
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE tenants
+		SET billing_plan = $2, stripe_customer_id = $3, stripe_subscription_id = $4, updated_at = NOW()
+		WHERE id = $1
+	`, tenantID, plan.Name, customerID, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to update tenant plan: %w", err)
+	}
+
+	cacheKeys, err := applyKeyLimits(ctx, tx, tenantID, plan)
+	if err != nil {
+		return err
+	}
+
+	if err := events.Enqueue(ctx, tx, evt); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	cacheKeys = append(cacheKeys, tenantPlanCacheKey(tenantID))
+	if err := g.cache.Delete(ctx, cacheKeys...); err != nil {
+		// Cached keys and plans expire within 60s; the new limits apply then
+		g.logger.Warn("failed to invalidate cached API keys", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+	}
+	return nil
+}
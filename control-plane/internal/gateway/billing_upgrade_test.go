@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+)
+
+func TestValidatePlanUpgrade(t *testing.T) {
+	plans := billing.NewPlanCatalog(nil)
+
+	tests := []struct {
+		name     string
+		current  string
+		target   string
+		wantCode string
+	}{
+		{"free to pro", "free", "pro", ""},
+		{"legacy plan to starter", "serverless", "starter", ""},
+		{"starter to pro", "starter", "pro", ""},
+		{"unknown plan", "free", "platinum", "invalid_plan"},
+		{"enterprise is sales only", "pro", "enterprise", "plan_not_self_serve"},
+		{"same plan", "pro", "pro", "plan_unchanged"},
+		{"downgrade", "pro", "starter", "plan_downgrade_not_supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := validatePlanUpgrade(plans, tt.current, tt.target)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("validatePlanUpgrade() error = %v", err)
+				}
+				if plan.Name != tt.target {
+					t.Errorf("plan = %q, want %q", plan.Name, tt.target)
+				}
+				return
+			}
+
+			var upErr *upgradeError
+			if !errors.As(err, &upErr) {
+				t.Fatalf("validatePlanUpgrade() error = %v, want upgradeError", err)
+			}
+			if upErr.code != tt.wantCode {
+				t.Errorf("code = %q, want %q", upErr.code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// tenantNamespaceMigration names the move of tenant-owned keys into
+	// tenant namespaces
+	tenantNamespaceMigration = "tenant_namespaces_v1"
+	// migrationMarkerRetention outlives every migrated key's TTL, after which
+	// no legacy keys can be left
+	migrationMarkerRetention = 30 * 24 * time.Hour
+)
+
+// legacyTenantKeys are the long-lived keys written before tenant namespaces.
+// Minute windows and read-through caches expire on their own and are not
+// moved.
+var legacyTenantKeys = []struct {
+	pattern string
+	// parts is how many ":" separated parts a matching key has
+	parts int
+	// idPart is the part holding the ID of the row owning the key
+	idPart int
+	// table maps that ID to its tenant
+	table string
+}{
+	{pattern: "ratelimit:key:*:concurrency", parts: 4, idPart: 2, table: "api_keys"},
+	{pattern: "tokens:key:*:day:*", parts: 5, idPart: 2, table: "api_keys"},
+	{pattern: "tokens:env:*:day:*", parts: 5, idPart: 2, table: "environments"},
+	{pattern: "node_logs:*", parts: 2, idPart: 1, table: "nodes"},
+}
+
+// StartCacheNamespaceMigration moves keys written before tenant namespaces
+// into their tenant's namespace, so concurrency counters, daily token
+// quotas and launch logs carry over. It runs once per Redis deployment.
+func (g *Gateway) StartCacheNamespaceMigration(ctx context.Context) {
+	go func() {
+		ran, err := g.cache.RunOnce(ctx, tenantNamespaceMigration, migrationMarkerRetention, g.migrateTenantKeys)
+		if err != nil {
+			g.logger.Error("cache namespace migration failed", zap.Error(err))
+			return
+		}
+		if ran {
+			g.logger.Info("cache namespace migration complete")
+		}
+	}()
+}
+
+// migrateTenantKeys moves each legacy key to the same key under its owning
+// tenant. Keys of rows without a tenant, such as platform nodes, stay where
+// they are.
+func (g *Gateway) migrateTenantKeys(ctx context.Context) error {
+	owners := make(map[string]uuid.UUID)
+	tenantOf := func(table, id string) (uuid.UUID, error) {
+		if tenantID, ok := owners[table+":"+id]; ok {
+			return tenantID, nil
+		}
+		var tenantID *uuid.UUID
+		err := g.db.Pool.QueryRow(ctx,
+			fmt.Sprintf(`SELECT tenant_id FROM %s WHERE id::text = $1`, table), id,
+		).Scan(&tenantID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, err
+		}
+		owner := uuid.Nil
+		if tenantID != nil {
+			owner = *tenantID
+		}
+		owners[table+":"+id] = owner
+		return owner, nil
+	}
+
+	for _, legacy := range legacyTenantKeys {
+		var moved, skipped int
+		err := g.cache.ScanKeys(ctx, legacy.pattern, func(key string) error {
+			parts := strings.Split(key, ":")
+			if len(parts) != legacy.parts {
+				return nil
+			}
+			tenantID, err := tenantOf(legacy.table, parts[legacy.idPart])
+			if err != nil {
+				return err
+			}
+			if tenantID == uuid.Nil {
+				skipped++
+				return nil
+			}
+			ok, err := g.cache.MoveKey(ctx, key, cache.TenantKey(tenantID, parts...))
+			if err != nil {
+				return fmt.Errorf("failed to move %q: %w", key, err)
+			}
+			if ok {
+				moved++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		g.logger.Info("moved legacy cache keys into tenant namespaces",
+			zap.String("pattern", legacy.pattern),
+			zap.Int("moved", moved),
+			zap.Int("kept", skipped),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Read-through caching for the inference hot path.
+//
+// The /v1/models catalog is cached in Redis, shared by every gateway
+// replica, with a short in-memory copy in front of it. The load balancer
+// caches each model's routable endpoints in memory. Admin mutations publish
+// events on the bus and the gateway drops the affected entries; replicas that
+// don't see an event catch up when their in-memory copy expires.
+const (
+	modelCatalogCacheKey = "catalog:models"
+
+	// maxLocalCacheTTL bounds how long a replica serves its in-memory copy
+	// after another replica changed the catalog
+	maxLocalCacheTTL = 5 * time.Second
+
+	defaultModelCatalogCacheTTL = 60 * time.Second
+	defaultRouteCacheTTL        = 2 * time.Second
+)
+
+// readThroughCache caches one value in memory and in Redis, loading it on a
+// miss in both
+type readThroughCache struct {
+	name     string
+	key      string
+	ttl      time.Duration
+	localTTL time.Duration
+	redis    *cache.Cache
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	value   []byte
+	expires time.Time
+}
+
+func newReadThroughCache(name, key string, ttl time.Duration, redis *cache.Cache, logger *zap.Logger) *readThroughCache {
+	c := &readThroughCache{name: name, key: key, redis: redis, logger: logger}
+	c.setTTL(ttl)
+	return c
+}
+
+// setTTL changes how long values are cached; zero or less disables caching
+func (c *readThroughCache) setTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+	c.localTTL = ttl
+	if c.localTTL > maxLocalCacheTTL {
+		c.localTTL = maxLocalCacheTTL
+	}
+	c.value = nil
+}
+
+// get returns the cached value, loading and caching it on a miss. Redis
+// errors fall through to load.
+func (c *readThroughCache) get(ctx context.Context, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	ttl, localTTL := c.ttl, c.localTTL
+	if c.value != nil && time.Now().Before(c.expires) {
+		value := c.value
+		c.mu.Unlock()
+		readCacheLookups.WithLabelValues(c.name, "memory").Inc()
+		return value, nil
+	}
+	c.mu.Unlock()
+
+	if ttl <= 0 {
+		return load(ctx)
+	}
+
+	if c.redis != nil {
+		if cached, err := c.redis.Get(ctx, c.key); err == nil && cached != "" {
+			readCacheLookups.WithLabelValues(c.name, "redis").Inc()
+			c.store([]byte(cached), localTTL)
+			return []byte(cached), nil
+		}
+	}
+
+	readCacheLookups.WithLabelValues(c.name, "miss").Inc()
+	value, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.redis != nil {
+		if err := c.redis.Set(ctx, c.key, value, ttl); err != nil {
+			c.logger.Debug("failed to cache value in redis", zap.String("cache", c.name), zap.Error(err))
+		}
+	}
+	c.store(value, localTTL)
+	return value, nil
+}
+
+func (c *readThroughCache) store(value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value = value
+	c.expires = time.Now().Add(ttl)
+}
+
+// invalidate drops the cached value here and in Redis
+func (c *readThroughCache) invalidate(ctx context.Context) {
+	c.mu.Lock()
+	c.value = nil
+	c.mu.Unlock()
+
+	if c.redis != nil {
+		if err := c.redis.Delete(ctx, c.key); err != nil {
+			c.logger.Warn("failed to invalidate cached value", zap.String("cache", c.name), zap.Error(err))
+		}
+	}
+}
+
+// SetCatalogCacheTTLs configures how long the model catalog and each model's
+// routes are cached. Zero disables the cache.
+func (g *Gateway) SetCatalogCacheTTLs(catalogTTL, routeTTL time.Duration) {
+	g.modelCatalog.setTTL(catalogTTL)
+	g.LoadBalancer.SetRouteCacheTTL(routeTTL)
+}
+
+// subscribeCacheInvalidation drops cached catalog and routes when models or
+// nodes change
+func (g *Gateway) subscribeCacheInvalidation() {
+	if g.eventBus == nil {
+		return
+	}
+
+	g.eventBus.Subscribe(events.EventModelCatalogChanged, func(ctx context.Context, event events.Event) error {
+		g.modelCatalog.invalidate(ctx)
+		// A renamed or retired model changes which nodes serve it
+		g.LoadBalancer.InvalidateRoutes()
+		return nil
+	})
+
+	for _, eventType := range []events.EventType{
+		events.EventNodeRegistered,
+		events.EventNodeLaunched,
+		events.EventNodeTerminated,
+		events.EventNodeDraining,
+		events.EventNodeHealthChanged,
+		events.EventNodeStandbyPromoted,
+	} {
+		g.eventBus.Subscribe(eventType, func(ctx context.Context, event events.Event) error {
+			g.LoadBalancer.InvalidateRoutes()
+			return nil
+		})
+	}
+}
+
+// publishCatalogChanged tells the gateway caches that models or aliases
+// changed
+func (g *Gateway) publishCatalogChanged(ctx context.Context, model, change string) {
+	if g.eventBus == nil {
+		g.modelCatalog.invalidate(ctx)
+		return
+	}
+	if err := g.eventBus.Publish(ctx, events.NewEvent(events.EventModelCatalogChanged, "", map[string]interface{}{
+		"model":  model,
+		"change": change,
+	})); err != nil {
+		g.logger.Warn("failed to publish catalog change", zap.Error(err), zap.String("model", model))
+	}
+}
+
+// loadModelCatalog builds the /v1/models data array from Postgres
+func (g *Gateway) loadModelCatalog(ctx context.Context) ([]byte, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, name, family, type, context_length, status,
+		       supports_tools, supports_vision, supports_json_mode,
+		       supports_guided_decoding, max_output_tokens
+		FROM models
+		WHERE status = 'active'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	created := time.Now().Unix()
+	modelsList := []map[string]interface{}{}
+	for rows.Next() {
+		var m models.Model
+		if err := rows.Scan(&m.ID, &m.Name, &m.Family, &m.Type, &m.ContextLength, &m.Status,
+			&m.SupportsTools, &m.SupportsVision, &m.SupportsJSONMode, &m.SupportsGuidedDecoding, &m.MaxOutputTokens,
+		); err != nil {
+			continue
+		}
+
+		capabilities := &ModelCapabilities{
+			Model:                  m.Name,
+			SupportsTools:          m.SupportsTools,
+			SupportsVision:         m.SupportsVision,
+			SupportsJSONMode:       m.SupportsJSONMode,
+			SupportsGuidedDecoding: m.SupportsGuidedDecoding,
+			MaxOutputTokens:        m.MaxOutputTokens,
+		}
+		modelsList = append(modelsList, map[string]interface{}{
+			"id":                m.Name,
+			"object":            "model",
+			"created":           created,
+			"owned_by":          "crosslogic",
+			"context_length":    m.ContextLength,
+			"max_output_tokens": m.MaxOutputTokens,
+			"capabilities":      capabilities.publicCapabilities(),
+		})
+	}
+	rows.Close()
+
+	// Expose stable aliases alongside concrete models
+	aliases, err := g.listModelAliases(ctx)
+	if err != nil {
+		g.logger.Warn("failed to list model aliases", zap.Error(err))
+	}
+	for _, a := range aliases {
+		modelsList = append(modelsList, map[string]interface{}{
+			"id":        a.Alias,
+			"object":    "model",
+			"created":   a.CreatedAt.Unix(),
+			"owned_by":  "crosslogic",
+			"alias_for": a.TargetModel,
+		})
+	}
+
+	return json.Marshal(modelsList)
+}
+
+// routeCacheEntry is a model's routable endpoints as of a point in time
+type routeCacheEntry struct {
+	endpoints []string
+	expires   time.Time
+}
+
+// SetRouteCacheTTL configures how long each model's routable endpoints are
+// cached. Zero disables the cache.
+func (lb *IntelligentLoadBalancer) SetRouteCacheTTL(ttl time.Duration) {
+	lb.routesMu.Lock()
+	defer lb.routesMu.Unlock()
+	lb.routeCacheTTL = ttl
+	lb.routes = make(map[string]routeCacheEntry)
+}
+
+// InvalidateRoutes drops every cached route so the next request per model
+// reads the nodes table
+func (lb *IntelligentLoadBalancer) InvalidateRoutes() {
+	lb.routesMu.Lock()
+	defer lb.routesMu.Unlock()
+	lb.routes = make(map[string]routeCacheEntry)
+}
+
+// cachedRoutes returns a model's cached endpoints, if fresh
+func (lb *IntelligentLoadBalancer) cachedRoutes(modelName string, now time.Time) ([]string, bool) {
+	lb.routesMu.Lock()
+	defer lb.routesMu.Unlock()
+
+	entry, ok := lb.routes[modelName]
+	if !ok || !now.Before(entry.expires) {
+		readCacheLookups.WithLabelValues("routes", "miss").Inc()
+		return nil, false
+	}
+	readCacheLookups.WithLabelValues("routes", "memory").Inc()
+	return entry.endpoints, true
+}
+
+// storeRoutes caches a model's endpoints
+func (lb *IntelligentLoadBalancer) storeRoutes(modelName string, endpoints []string, now time.Time) {
+	lb.routesMu.Lock()
+	defer lb.routesMu.Unlock()
+
+	if lb.routeCacheTTL <= 0 {
+		return
+	}
+	if lb.routes == nil {
+		lb.routes = make(map[string]routeCacheEntry)
+	}
+	lb.routes[modelName] = routeCacheEntry{endpoints: endpoints, expires: now.Add(lb.routeCacheTTL)}
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestReadThroughCache(t *testing.T) {
+	c, cleanup := setupLimiterCache(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	loads := 0
+	load := func(ctx context.Context) ([]byte, error) {
+		loads++
+		return []byte(`["llama"]`), nil
+	}
+
+	first := newReadThroughCache("test", "catalog:test", time.Minute, c, zap.NewNop())
+	for i := 0; i < 3; i++ {
+		if got, err := first.get(ctx, load); err != nil || string(got) != `["llama"]` {
+			t.Fatalf("get() = %s, %v", got, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+
+	// Another replica is served from Redis
+	second := newReadThroughCache("test", "catalog:test", time.Minute, c, zap.NewNop())
+	if _, err := second.get(ctx, load); err != nil || loads != 1 {
+		t.Errorf("second replica loads = %d, want 1 (%v)", loads, err)
+	}
+
+	// Invalidation clears both tiers
+	first.invalidate(ctx)
+	if _, err := first.get(ctx, load); err != nil || loads != 2 {
+		t.Errorf("loads after invalidate = %d, want 2 (%v)", loads, err)
+	}
+}
+
+func TestReadThroughCacheDisabled(t *testing.T) {
+	loads := 0
+	load := func(ctx context.Context) ([]byte, error) {
+		loads++
+		return []byte("x"), nil
+	}
+
+	c := newReadThroughCache("test", "catalog:test", 0, nil, zap.NewNop())
+	c.get(context.Background(), load)
+	c.get(context.Background(), load)
+	if loads != 2 {
+		t.Errorf("loads = %d, want 2 with caching disabled", loads)
+	}
+	if c.localTTL != 0 {
+		t.Errorf("localTTL = %s, want 0", c.localTTL)
+	}
+
+	c.setTTL(time.Hour)
+	if c.localTTL != maxLocalCacheTTL {
+		t.Errorf("localTTL = %s, want capped at %s", c.localTTL, maxLocalCacheTTL)
+	}
+}
+
+func TestRouteCache(t *testing.T) {
+	lb := &IntelligentLoadBalancer{}
+	lb.SetRouteCacheTTL(time.Second)
+	now := time.Now()
+
+	lb.storeRoutes("llama", []string{"http://a"}, now)
+	if got, ok := lb.cachedRoutes("llama", now.Add(500*time.Millisecond)); !ok || !reflect.DeepEqual(got, []string{"http://a"}) {
+		t.Errorf("cachedRoutes() = %v, %v", got, ok)
+	}
+	if _, ok := lb.cachedRoutes("llama", now.Add(2*time.Second)); ok {
+		t.Error("expired routes served")
+	}
+
+	lb.InvalidateRoutes()
+	if _, ok := lb.cachedRoutes("llama", now); ok {
+		t.Error("invalidated routes served")
+	}
+
+	lb.SetRouteCacheTTL(0)
+	lb.storeRoutes("llama", []string{"http://a"}, now)
+	if _, ok := lb.cachedRoutes("llama", now); ok {
+		t.Error("routes cached with caching disabled")
+	}
+}
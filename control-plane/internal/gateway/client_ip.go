@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// SetTrustedProxies sets the networks of the load balancers in front of the
+// gateway. X-Forwarded-For is only believed from them; other clients are
+// known by their TCP peer address whatever headers they send. A bare IP is
+// taken as a single-host network.
+func (g *Gateway) SetTrustedProxies(cidrs []string) error {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if ip := net.ParseIP(cidr); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	g.trustedProxies = nets
+	return nil
+}
+
+// trustedProxy reports whether host is one of the trusted proxies
+func (g *Gateway) trustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range g.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedClient returns the client address a trusted proxy reports: the
+// nearest address in X-Forwarded-For that isn't itself a trusted proxy.
+// Entries further left were written by the client and are ignored. It
+// returns "" when the header carries no usable address.
+func (g *Gateway) forwardedClient(header string) string {
+	hops := strings.Split(header, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			return ""
+		}
+		if !g.trustedProxy(hop) {
+			return hop
+		}
+	}
+	return ""
+}
+
+// realIP sets RemoteAddr to the client's address for handlers, logs and
+// per-address limits. The TCP peer is kept on the context as peer_addr;
+// RemoteAddr only differs from it when the peer is a trusted proxy
+// reporting the client in X-Forwarded-For. Unlike chi's RealIP, headers
+// from anyone else can't choose the address a request is counted against.
+func (g *Gateway) realIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer := r.RemoteAddr
+		ctx := context.WithValue(r.Context(), "peer_addr", peer)
+		if g.trustedProxy(clientHost(peer)) {
+			if client := g.forwardedClient(r.Header.Get("X-Forwarded-For")); client != "" {
+				r.RemoteAddr = client
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientIP is the address per-client limits and blocks apply to: the TCP
+// peer, or the client a trusted proxy forwarded the request for
+func clientIP(r *http.Request) string {
+	return clientHost(r.RemoteAddr)
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	g := &Gateway{}
+	if err := g.SetTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		peer         string
+		forwardedFor string
+		want         string
+	}{
+		{"untrusted peer", "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:4000", "198.51.100.1", "198.51.100.1"},
+		{"client-written hops are ignored", "10.1.2.3:4000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"chain of trusted proxies", "10.1.2.3:4000", "198.51.100.1, 192.0.2.10, 10.9.9.9", "198.51.100.1"},
+		{"garbage hop", "10.1.2.3:4000", "198.51.100.1, not-an-ip", "10.1.2.3"},
+		{"no header", "10.1.2.3:4000", "", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.peer
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			var got, peer string
+			g.realIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+				peer, _ = r.Context().Value("peer_addr").(string)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+			if peer != tt.peer {
+				t.Errorf("peer_addr = %q, want %q", peer, tt.peer)
+			}
+		})
+	}
+
+	if err := g.SetTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected an invalid network to be rejected")
+	}
+}
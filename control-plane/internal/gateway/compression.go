@@ -0,0 +1,314 @@
+package gateway
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// defaultCompressionMinBytes leaves small responses uncompressed, where
+	// gzip's framing outweighs the savings
+	defaultCompressionMinBytes = 1024
+
+	encodingGzip = "gzip"
+)
+
+// localEncodings are the encodings the gateway compresses with itself, in
+// order of preference. Other encodings such as zstd reach clients only when
+// a node's response is relayed as is (see proxyRequestPassthrough).
+var localEncodings = []string{encodingGzip}
+
+// compressionSettings configures response compression
+type compressionSettings struct {
+	enabled  bool
+	minBytes int
+	level    int
+}
+
+func defaultCompressionSettings() compressionSettings {
+	return compressionSettings{
+		enabled:  true,
+		minBytes: defaultCompressionMinBytes,
+		level:    gzip.DefaultCompression,
+	}
+}
+
+// SetResponseCompression configures gzip compression of non-streaming
+// responses. Responses smaller than minBytes are sent as is; level is a
+// compress/gzip level.
+func (g *Gateway) SetResponseCompression(enabled bool, minBytes, level int) {
+	if minBytes < 0 {
+		minBytes = 0
+	}
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	g.compression = compressionSettings{enabled: enabled, minBytes: minBytes, level: level}
+}
+
+// compressionMiddleware compresses responses for clients that accept gzip.
+// SSE streams, responses that are already encoded and responses with
+// trailers are left alone, so streaming stays incremental and node
+// responses relayed with their own encoding pass through untouched.
+func (g *Gateway) compressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		settings := g.compression
+		if !settings.enabled || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), localEncodings)
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, settings: settings}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks the supported encoding the Accept-Encoding header
+// ranks highest, preferring earlier supported encodings on ties. It returns
+// "" when the client accepts none of them.
+func negotiateEncoding(header string, supported []string) string {
+	if header == "" {
+		return ""
+	}
+
+	quality := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		quality[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supported {
+		q, ok := quality[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressibleResponse reports whether a response with these headers is
+// worth compressing and safe to compress
+func compressibleResponse(h http.Header, status int) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Trailer") != "" {
+		return false
+	}
+	if strings.Contains(strings.ToLower(h.Get("Cache-Control")), "no-transform") {
+		return false
+	}
+
+	contentType := strings.ToLower(h.Get("Content-Type"))
+	switch {
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		return false
+	case strings.HasPrefix(contentType, "text/"),
+		strings.Contains(contentType, "json"),
+		strings.Contains(contentType, "xml"),
+		strings.Contains(contentType, "yaml"),
+		strings.Contains(contentType, "javascript"):
+		return true
+	}
+	return false
+}
+
+var gzipWriterPools sync.Map // level -> *sync.Pool
+
+func getGzipWriter(dst io.Writer, level int) *gzip.Writer {
+	pool, _ := gzipWriterPools.LoadOrStore(level, &sync.Pool{})
+	if gz, ok := pool.(*sync.Pool).Get().(*gzip.Writer); ok {
+		gz.Reset(dst)
+		return gz
+	}
+	gz, err := gzip.NewWriterLevel(dst, level)
+	if err != nil {
+		gz = gzip.NewWriter(dst)
+	}
+	return gz
+}
+
+func putGzipWriter(gz *gzip.Writer, level int) {
+	pool, _ := gzipWriterPools.LoadOrStore(level, &sync.Pool{})
+	pool.(*sync.Pool).Put(gz)
+}
+
+// compressWriter buffers the start of a compressible response until it
+// reaches the minimum size, then switches to compressing it. Responses that
+// end or flush below the minimum are sent as is.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	settings compressionSettings
+
+	status      int
+	wroteHeader bool
+	// decided is set once the response is known to be compressed or sent
+	// as is
+	decided  bool
+	compress bool
+	buf      []byte
+	gz       *gzip.Writer
+	// in and out count the bytes before and after compression
+	in  int64
+	out *countingWriter
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+
+	h := w.Header()
+	if !compressibleResponse(h, status) {
+		w.passthrough()
+		return
+	}
+	if cl, err := strconv.Atoi(h.Get("Content-Length")); err == nil && cl < w.settings.minBytes {
+		w.passthrough()
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.compress {
+			w.in += int64(len(p))
+			return w.gz.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.settings.minBytes {
+		if err := w.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what is buffered. A response flushed before reaching the
+// minimum size is being streamed, so it is sent as is.
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.passthrough()
+	}
+	if w.compress {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// passthrough sends the response uncompressed, writing out anything
+// buffered
+func (w *compressWriter) passthrough() {
+	w.decided = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *compressWriter) startCompression() error {
+	w.decided = true
+	w.compress = true
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", w.encoding)
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.out = &countingWriter{w: w.ResponseWriter}
+	w.gz = getGzipWriter(w.out, w.settings.level)
+
+	buf := w.buf
+	w.buf = nil
+	w.in += int64(len(buf))
+	_, err := w.gz.Write(buf)
+	return err
+}
+
+// close finishes the response: a compressed stream is terminated, and a
+// buffered response below the minimum size is sent as is
+func (w *compressWriter) close() {
+	if !w.wroteHeader {
+		return
+	}
+	if !w.decided {
+		w.passthrough()
+		return
+	}
+	if !w.compress {
+		return
+	}
+
+	w.gz.Close()
+	putGzipWriter(w.gz, w.settings.level)
+	responseCompressionBytes.WithLabelValues(w.encoding, "uncompressed").Add(float64(w.in))
+	responseCompressionBytes.WithLabelValues(w.encoding, "compressed").Add(float64(w.out.n))
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
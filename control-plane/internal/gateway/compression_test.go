@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "gzip"},
+		{"zstd, gzip;q=0.5", "gzip"},
+		{"br;q=1.0, *;q=0.1", "gzip"},
+		{"gzip;q=0", ""},
+		{"*;q=0", ""},
+		{"identity", ""},
+		{"GZIP;Q=0.8", "gzip"},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header, localEncodings); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+// serveCompressed runs handler behind the compression middleware
+func serveCompressed(t *testing.T, acceptEncoding string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	g := &Gateway{compression: defaultCompressionSettings()}
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	g.compressionMiddleware(handler).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := `{"data":[` + strings.Repeat(`0.0123456789,`, 500) + `0]}`
+	jsonHandler := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			io.WriteString(w, body)
+		}
+	}
+
+	// Large JSON is gzipped
+	rec := serveCompressed(t, "gzip, deflate", jsonHandler(large))
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("headers = %v", rec.Header())
+	}
+	if rec.Body.Len() >= len(large) {
+		t.Errorf("compressed %d bytes to %d", len(large), rec.Body.Len())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != large {
+		t.Error("decompressed body differs")
+	}
+
+	// Small responses and clients without gzip get the body as is
+	for _, tc := range []struct{ accept, body string }{{"gzip", `{"ok":true}`}, {"", large}} {
+		rec = serveCompressed(t, tc.accept, jsonHandler(tc.body))
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != tc.body {
+			t.Errorf("accept %q, %d bytes: encoded as %q", tc.accept, len(tc.body), rec.Header().Get("Content-Encoding"))
+		}
+	}
+
+	// SSE streams and already encoded node responses pass through
+	rec = serveCompressed(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, strings.Repeat("data: {}\n\n", 500))
+		w.(http.Flusher).Flush()
+	})
+	if rec.Header().Get("Content-Encoding") != "" || !rec.Flushed {
+		t.Errorf("SSE stream encoded as %q, flushed %v", rec.Header().Get("Content-Encoding"), rec.Flushed)
+	}
+	rec = serveCompressed(t, "zstd, gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "zstd")
+		io.WriteString(w, large)
+	})
+	if rec.Header().Get("Content-Encoding") != "zstd" || rec.Body.String() != large {
+		t.Errorf("node-encoded response re-encoded: %v", rec.Header())
+	}
+}
+
+func TestProxyRequestAcceptEncoding(t *testing.T) {
+	var got []string
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Accept-Encoding"))
+	}))
+	defer node.Close()
+
+	g := &Gateway{nodeClient: newNodeClient(defaultNodePoolSettings())}
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", nil)
+	req.Header.Set("Accept-Encoding", "zstd")
+
+	for _, proxy := range []func(string, *http.Request) (*http.Response, error){g.proxyRequest, g.proxyRequestPassthrough} {
+		resp, err := proxy(node.URL, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	// The transport negotiates gzip itself unless the client's header is kept
+	if len(got) != 2 || got[0] != "gzip" || got[1] != "zstd" {
+		t.Errorf("node saw Accept-Encoding %q", got)
+	}
+}
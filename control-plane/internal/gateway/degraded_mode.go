@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// maxTokensCappedHeader tells the client its max_tokens was lowered to the
+// value given
+const maxTokensCappedHeader = "X-Max-Tokens-Capped"
+
+var maxTokensCapped = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gateway_max_tokens_capped_total",
+		Help: "Low priority requests whose max_tokens was capped while the fleet was saturated",
+	},
+	[]string{"model"},
+)
+
+// degradedMode caps max_tokens of low-priority requests once the share of
+// the fleet's concurrency in use reaches saturation
+type degradedMode struct {
+	saturation       float64
+	maxTokens        int
+	lowPriorityPlans map[string]bool
+}
+
+// SetDegradedMode enables max_tokens capping for low-priority requests
+// while the fleet is saturated. Requests are low priority when the client
+// asks for it or the tenant is on one of lowPriorityPlans.
+func (g *Gateway) SetDegradedMode(saturation float64, maxTokens int, lowPriorityPlans []string) {
+	if saturation <= 0 || maxTokens <= 0 {
+		g.degraded = nil
+		return
+	}
+	plans := make(map[string]bool, len(lowPriorityPlans))
+	for _, plan := range lowPriorityPlans {
+		plans[strings.ToLower(plan)] = true
+	}
+	g.degraded = &degradedMode{saturation: saturation, maxTokens: maxTokens, lowPriorityPlans: plans}
+}
+
+// FleetUtilization returns the share of the concurrency of nodes with a
+// known limit that is in use, or 0 when no limit is known yet
+func (lb *IntelligentLoadBalancer) FleetUtilization() float64 {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	now := time.Now()
+	var load, capacity int64
+	for endpoint, limit := range lb.concurrencyLimits {
+		load += lb.endpointLoad(endpoint, now)
+		capacity += int64(limit)
+	}
+	if capacity == 0 {
+		return 0
+	}
+	return float64(load) / float64(capacity)
+}
+
+// applyDegradedMaxTokens caps max_tokens of a low-priority request while the
+// fleet is saturated and sets X-Max-Tokens-Capped when it does. The body is
+// returned unchanged otherwise.
+func (g *Gateway) applyDegradedMaxTokens(w http.ResponseWriter, r *http.Request, model string, body []byte, lowPriority bool) []byte {
+	mode := g.degraded
+	if mode == nil {
+		return body
+	}
+	utilization := g.LoadBalancer.FleetUtilization()
+	if utilization < mode.saturation {
+		return body
+	}
+	if !lowPriority {
+		tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID)
+		if !ok || !mode.lowPriorityPlans[strings.ToLower(g.tenantPlan(r.Context(), tenantID).Name)] {
+			return body
+		}
+	}
+
+	capped, ok := capMaxTokens(body, mode.maxTokens)
+	if !ok {
+		return body
+	}
+
+	maxTokensCapped.WithLabelValues(model).Inc()
+	w.Header().Set(maxTokensCappedHeader, strconv.Itoa(mode.maxTokens))
+	g.logger.Debug("capped max_tokens of low priority request",
+		zap.String("model", model),
+		zap.Float64("fleet_utilization", utilization),
+		zap.Int("max_tokens", mode.maxTokens),
+	)
+	return capped
+}
+
+// capMaxTokens lowers the request's max_tokens, and max_completion_tokens
+// when sent, to limit. Requests already asking for no more than limit are
+// left alone and report false.
+func capMaxTokens(body []byte, limit int) ([]byte, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body, false
+	}
+
+	requested := fields["max_tokens"]
+	if raw, ok := fields["max_completion_tokens"]; ok && !isJSONNull(raw) {
+		requested = raw
+	}
+	if len(requested) > 0 && !isJSONNull(requested) {
+		var n int
+		if err := json.Unmarshal(requested, &n); err == nil && n > 0 && n <= limit {
+			return body, false
+		}
+	}
+
+	encoded := json.RawMessage(strconv.Itoa(limit))
+	fields["max_tokens"] = encoded
+	if _, ok := fields["max_completion_tokens"]; ok {
+		fields["max_completion_tokens"] = encoded
+	}
+	capped, err := json.Marshal(fields)
+	if err != nil {
+		return body, false
+	}
+	return capped, true
+}
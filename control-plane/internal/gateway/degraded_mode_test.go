@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestCapMaxTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		capped bool
+		want   map[string]int
+	}{
+		{"omitted", `{"model":"m"}`, true, map[string]int{"max_tokens": 256}},
+		{"null", `{"max_tokens":null}`, true, map[string]int{"max_tokens": 256}},
+		{"above limit", `{"max_tokens":4096}`, true, map[string]int{"max_tokens": 256}},
+		{"within limit", `{"max_tokens":100}`, false, nil},
+		{"max_completion_tokens above limit", `{"max_completion_tokens":1000}`, true, map[string]int{"max_tokens": 256, "max_completion_tokens": 256}},
+		{"max_completion_tokens within limit", `{"max_tokens":4096,"max_completion_tokens":64}`, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, capped := capMaxTokens([]byte(tt.body), 256)
+			if capped != tt.capped {
+				t.Fatalf("capped = %v, want %v", capped, tt.capped)
+			}
+			if !capped {
+				if string(got) != tt.body {
+					t.Errorf("body changed to %s", got)
+				}
+				return
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(got, &fields); err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				if string(fields[name]) != strconv.Itoa(want) {
+					t.Errorf("%s = %s, want %d", name, fields[name], want)
+				}
+			}
+		})
+	}
+
+	if _, capped := capMaxTokens([]byte("not json"), 256); capped {
+		t.Error("expected invalid JSON to be left alone")
+	}
+}
+
+func TestApplyDegradedMaxTokens(t *testing.T) {
+	lb := &IntelligentLoadBalancer{concurrencyLimits: map[string]int{"http://a": 2, "http://b": 2}}
+	g := &Gateway{LoadBalancer: lb, logger: zap.NewNop()}
+	g.SetDegradedMode(0.75, 128, []string{"free"})
+	body := []byte(`{"model":"m","max_tokens":1024}`)
+
+	// Below saturation nothing is capped
+	lb.beginRequest("http://a")
+	lb.beginRequest("http://a")
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	if got := g.applyDegradedMaxTokens(w, r, "m", body, true); string(got) != string(body) {
+		t.Errorf("capped below saturation: %s", got)
+	}
+	if got := lb.FleetUtilization(); got != 0.5 {
+		t.Errorf("FleetUtilization = %v, want 0.5", got)
+	}
+
+	// Saturated: low priority requests are capped
+	lb.beginRequest("http://b")
+	got := g.applyDegradedMaxTokens(w, r, "m", body, true)
+	if !strings.Contains(string(got), `"max_tokens":128`) {
+		t.Errorf("expected max_tokens capped to 128, got %s", got)
+	}
+	if h := w.Header().Get(maxTokensCappedHeader); h != "128" {
+		t.Errorf("%s = %q, want 128", maxTokensCappedHeader, h)
+	}
+
+	// Requests without a tenant aren't treated as low priority by plan
+	w = httptest.NewRecorder()
+	if got := g.applyDegradedMaxTokens(w, r, "m", body, false); string(got) != string(body) {
+		t.Errorf("capped a normal priority request: %s", got)
+	}
+	if h := w.Header().Get(maxTokensCappedHeader); h != "" {
+		t.Errorf("unexpected %s header %q", maxTokensCappedHeader, h)
+	}
+
+	// Disabled
+	g.SetDegradedMode(0, 128, nil)
+	if got := g.applyDegradedMaxTokens(w, r, "m", body, true); string(got) != string(body) {
+		t.Errorf("capped while disabled: %s", got)
+	}
+}
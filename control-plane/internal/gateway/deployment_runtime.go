@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// checkDeploymentRuntime validates a deployment's vLLM and torch pins and
+// that the vLLM it will run, pinned or the platform default, can serve the
+// model, and that a pin newer than the platform default passed a
+// compatibility run for the model's family. The returned error is meant for
+// the client.
+func (g *Gateway) checkDeploymentRuntime(ctx context.Context, modelName, vllmVersion, torchVersion string) error {
+	if vllmVersion != "" && !orchestrator.ValidRuntimeVersion(vllmVersion) {
+		return fmt.Errorf("vllm_version %q is not a release version such as 0.6.3", vllmVersion)
+	}
+	if torchVersion != "" && !orchestrator.ValidRuntimeVersion(torchVersion) {
+		return fmt.Errorf("torch_version %q is not a release version such as 2.4.0", torchVersion)
+	}
+
+	if vllmVersion == "" && g.orchestrator != nil {
+		vllmVersion = g.orchestrator.DefaultVLLMVersion()
+	}
+	var registered, family string
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(min_vllm_version, ''), family FROM models WHERE name = $1
+	`, modelName).Scan(&registered, &family)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("failed to load model requirements")
+	}
+	if err := orchestrator.CheckVLLMCompatibility(modelName, vllmVersion, orchestrator.MinVLLMVersion(modelName, registered)); err != nil {
+		return err
+	}
+	return g.checkVLLMCompatGate(ctx, family, vllmVersion)
+}
+
+// handleSetDeploymentRuntime pins or unpins a deployment's vLLM and torch
+// versions
+// Platform Admin Only - PUT /admin/deployments/{id}/runtime
+// Pins apply to nodes launched afterwards; running nodes keep their versions
+// until replaced. Empty versions return to the platform defaults.
+func (g *Gateway) handleSetDeploymentRuntime(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	deploymentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid deployment ID")
+		return
+	}
+
+	var req struct {
+		VLLMVersion  string `json:"vllm_version"`
+		TorchVersion string `json:"torch_version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var modelName string
+	err = g.db.Pool.QueryRow(ctx, `
+		SELECT m.name FROM deployments d
+		INNER JOIN models m ON m.id = d.model_id
+		WHERE d.id = $1
+	`, deploymentID).Scan(&modelName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "deployment not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to load deployment", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update deployment")
+		return
+	}
+
+	if err := g.checkDeploymentRuntime(ctx, modelName, req.VLLMVersion, req.TorchVersion); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, err = g.db.Pool.Exec(ctx, `
+		UPDATE deployments
+		SET vllm_version = NULLIF($2, ''), torch_version = NULLIF($3, ''), updated_at = NOW()
+		WHERE id = $1
+	`, deploymentID, req.VLLMVersion, req.TorchVersion)
+	if err != nil {
+		g.logger.Error("failed to update deployment runtime", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update deployment")
+		return
+	}
+
+	g.logger.Info("deployment runtime versions updated",
+		zap.String("deployment_id", deploymentID.String()),
+		zap.String("vllm_version", req.VLLMVersion),
+		zap.String("torch_version", req.TorchVersion),
+	)
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deployment_id": deploymentID,
+		"vllm_version":  req.VLLMVersion,
+		"torch_version": req.TorchVersion,
+	})
+}
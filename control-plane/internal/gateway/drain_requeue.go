@@ -0,0 +1,261 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// A drain is noticed by every gateway replica: each one polls for nodes
+// that are draining, whether an admin, a scale-down or a node callback
+// started it, and for nodes halted by a kill switch. The first time it sees
+// a node in either state it withdraws its own requests still queued there.
+const (
+	// maxDrainRequeues is how many times one request may be moved off
+	// draining nodes before it stays where it is
+	maxDrainRequeues = 2
+
+	// drainWatchInterval is how often replicas look for newly draining nodes
+	drainWatchInterval = 2 * time.Second
+)
+
+// errRequeuedForDrain cancels a request withdrawn from a draining node's
+// queue so it can be sent to another node
+var errRequeuedForDrain = errors.New("node draining; request re-queued")
+
+var drainRequeues = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gateway_drain_requeued_requests_total",
+		Help: "Queued non-streaming requests moved off draining nodes, by outcome (requeued, no_alternative)",
+	},
+	[]string{"model", "outcome"},
+)
+
+// queuedRequest is a non-streaming request waiting on a node's response
+type queuedRequest struct {
+	sent   time.Time
+	cancel context.CancelCauseFunc
+}
+
+// queuedRequests tracks this gateway's non-streaming requests per node that
+// have not been answered yet, so a drain can withdraw the ones still
+// waiting in the node's queue
+type queuedRequests struct {
+	mu         sync.Mutex
+	byEndpoint map[string]map[*queuedRequest]struct{}
+	// draining are the endpoints whose drain has already been handled
+	draining map[string]bool
+}
+
+func newQueuedRequests() *queuedRequests {
+	return &queuedRequests{
+		byEndpoint: make(map[string]map[*queuedRequest]struct{}),
+		draining:   make(map[string]bool),
+	}
+}
+
+// track records a request sent to endpoint until the returned func is
+// called
+func (q *queuedRequests) track(endpoint string, cancel context.CancelCauseFunc) func() {
+	if q == nil {
+		return func() {}
+	}
+	req := &queuedRequest{sent: time.Now(), cancel: cancel}
+
+	q.mu.Lock()
+	if q.byEndpoint[endpoint] == nil {
+		q.byEndpoint[endpoint] = make(map[*queuedRequest]struct{})
+	}
+	q.byEndpoint[endpoint][req] = struct{}{}
+	q.mu.Unlock()
+
+	return func() {
+		q.mu.Lock()
+		delete(q.byEndpoint[endpoint], req)
+		if len(q.byEndpoint[endpoint]) == 0 {
+			delete(q.byEndpoint, endpoint)
+		}
+		q.mu.Unlock()
+	}
+}
+
+// withdraw cancels the most recently sent n requests to endpoint and
+// returns how many it cancelled. vLLM schedules first come first served,
+// so the newest requests are the ones still waiting to start.
+func (q *queuedRequests) withdraw(endpoint string, n int) int {
+	if q == nil || n <= 0 {
+		return 0
+	}
+
+	q.mu.Lock()
+	pending := make([]*queuedRequest, 0, len(q.byEndpoint[endpoint]))
+	for req := range q.byEndpoint[endpoint] {
+		pending = append(pending, req)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].sent.After(pending[j].sent) })
+	if len(pending) > n {
+		pending = pending[:n]
+	}
+	for _, req := range pending {
+		delete(q.byEndpoint[endpoint], req)
+	}
+	if len(q.byEndpoint[endpoint]) == 0 {
+		delete(q.byEndpoint, endpoint)
+	}
+	q.mu.Unlock()
+
+	for _, req := range pending {
+		req.cancel(errRequeuedForDrain)
+	}
+	return len(pending)
+}
+
+// newlyDraining takes the endpoints draining now and returns those whose
+// drain hasn't been handled yet. Endpoints that are no longer draining are
+// forgotten, so a node drained again is handled again.
+func (q *queuedRequests) newlyDraining(current map[string]bool) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var fresh []string
+	for endpoint := range current {
+		if !q.draining[endpoint] {
+			fresh = append(fresh, endpoint)
+		}
+	}
+	sort.Strings(fresh)
+	q.draining = current
+	return fresh
+}
+
+// markDraining records that endpoint's drain was handled where it started
+func (q *queuedRequests) markDraining(endpoint string) {
+	q.mu.Lock()
+	q.draining[endpoint] = true
+	q.mu.Unlock()
+}
+
+// StartDrainWatch starts requeueing this replica's requests off nodes that
+// start draining or are halted by a kill switch
+func (g *Gateway) StartDrainWatch(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(drainWatchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := g.requeueFromDrainingNodes(ctx); err != nil {
+					g.logger.Warn("failed to check for draining nodes", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// requeueFromDrainingNodes reads the draining and halted endpoints and
+// requeues the requests waiting on those that weren't draining last time
+func (g *Gateway) requeueFromDrainingNodes(ctx context.Context) error {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT endpoint_url FROM nodes
+		WHERE status = 'draining' AND endpoint_url != ''
+	`)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]bool)
+	for rows.Next() {
+		var endpoint string
+		if err := rows.Scan(&endpoint); err != nil {
+			rows.Close()
+			return err
+		}
+		current[endpoint] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if g.LoadBalancer != nil {
+		for _, endpoint := range g.LoadBalancer.haltedEndpoints() {
+			current[endpoint] = true
+		}
+	}
+
+	for _, endpoint := range g.queuedRequests.newlyDraining(current) {
+		g.requeueDrainingRequests(endpoint)
+	}
+	return nil
+}
+
+// requeueDrainingRequests withdraws this gateway's requests still queued on
+// a node that is being drained. The node's waiting count is polled, so the
+// requests withdrawn are an approximation; requests it has started finish
+// there as a graceful drain expects.
+func (g *Gateway) requeueDrainingRequests(endpoint string) {
+	if endpoint == "" || g.LoadBalancer == nil {
+		return
+	}
+	waiting, _ := g.LoadBalancer.QueueStatus(endpoint)
+	if n := g.queuedRequests.withdraw(endpoint, int(waiting)); n > 0 {
+		g.logger.Info("re-queueing requests waiting on draining node",
+			zap.String("endpoint", endpoint),
+			zap.Int("requests", n),
+		)
+	}
+}
+
+// proxyQueuedRequest proxies a non-streaming request to endpoint. If the
+// node is drained while the request waits in its queue, the request is sent
+// to another node serving the model instead of failing. It returns the
+// endpoint that answered.
+func (g *Gateway) proxyQueuedRequest(r *http.Request, endpoint, model string, body []byte) (string, *http.Response, error) {
+	for requeues := 0; ; requeues++ {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if requeues >= maxDrainRequeues {
+			resp, err := g.proxyRequest(endpoint, r)
+			return endpoint, resp, err
+		}
+
+		ctx, cancel := context.WithCancelCause(r.Context())
+		untrack := g.queuedRequests.track(endpoint, cancel)
+		resp, err := g.proxyRequest(endpoint, r.WithContext(ctx))
+		untrack()
+		if err == nil {
+			// The attempt's context must outlive reading the response
+			resp.Body = &inflightBody{ReadCloser: resp.Body, done: func() { cancel(nil) }}
+			return endpoint, resp, nil
+		}
+		cancel(nil)
+		if !errors.Is(context.Cause(ctx), errRequeuedForDrain) || r.Context().Err() != nil {
+			return endpoint, nil, err
+		}
+
+		// Resend to another node, or to the draining node when it is the
+		// only one left, where the request still completes
+		next, selectErr := g.LoadBalancer.SelectEndpoint(r.Context(), model)
+		if selectErr != nil || next == "" || next == endpoint {
+			drainRequeues.WithLabelValues(model, "no_alternative").Inc()
+			requeues = maxDrainRequeues - 1
+			continue
+		}
+		drainRequeues.WithLabelValues(model, "requeued").Inc()
+		g.logger.Info("re-queued request from draining node",
+			zap.String("model", model),
+			zap.String("from", endpoint),
+			zap.String("to", next),
+		)
+		endpoint = next
+	}
+}
@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestQueuedRequestsWithdrawNewest(t *testing.T) {
+	q := newQueuedRequests()
+	var cancelled []string
+	cancelFor := func(name string) context.CancelCauseFunc {
+		return func(cause error) { cancelled = append(cancelled, name) }
+	}
+
+	q.track("http://a", cancelFor("oldest"))
+	time.Sleep(time.Millisecond)
+	untrack := q.track("http://a", cancelFor("answered"))
+	time.Sleep(time.Millisecond)
+	q.track("http://a", cancelFor("newest"))
+	q.track("http://b", cancelFor("other node"))
+	untrack()
+
+	if n := q.withdraw("http://a", 1); n != 1 || len(cancelled) != 1 || cancelled[0] != "newest" {
+		t.Fatalf("withdraw = %d, cancelled %v; want the newest request", n, cancelled)
+	}
+	if n := q.withdraw("http://a", 5); n != 1 || cancelled[1] != "oldest" {
+		t.Errorf("withdraw = %d, cancelled %v", n, cancelled)
+	}
+	if n := q.withdraw("http://a", 5); n != 0 {
+		t.Errorf("withdraw from an empty node = %d", n)
+	}
+}
+
+func TestProxyQueuedRequestMovesOffDrainingNode(t *testing.T) {
+	draining := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer draining.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer healthy.Close()
+
+	lb := &IntelligentLoadBalancer{logger: zap.NewNop()}
+	lb.SetRouteCacheTTL(time.Minute)
+	lb.storeRoutes("llama", []string{healthy.URL}, time.Now())
+	g := &Gateway{
+		logger:         zap.NewNop(),
+		LoadBalancer:   lb,
+		nodeClient:     newNodeClient(defaultNodePoolSettings()),
+		queuedRequests: newQueuedRequests(),
+	}
+
+	go func() {
+		for g.queuedRequests.withdraw(draining.URL, 1) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	endpoint, resp, err := g.proxyQueuedRequest(req, draining.URL, "llama", []byte(`{"model":"llama"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if endpoint != healthy.URL || string(body) != `{"model":"llama"}` {
+		t.Errorf("answered by %s with %q, want the healthy node echoing the request", endpoint, body)
+	}
+}
+
+func TestQueuedRequestsNewlyDraining(t *testing.T) {
+	q := newQueuedRequests()
+
+	if got := q.newlyDraining(map[string]bool{"http://a": true, "http://b": true}); len(got) != 2 || got[0] != "http://a" || got[1] != "http://b" {
+		t.Fatalf("first poll = %v, want both nodes", got)
+	}
+	if got := q.newlyDraining(map[string]bool{"http://a": true, "http://b": true}); len(got) != 0 {
+		t.Errorf("second poll = %v, want nothing new", got)
+	}
+
+	// A drain handled by the replica that started it isn't handled again
+	q.markDraining("http://c")
+	if got := q.newlyDraining(map[string]bool{"http://a": true, "http://c": true}); len(got) != 0 {
+		t.Errorf("poll after a local drain = %v, want nothing new", got)
+	}
+
+	// A node that left draining is handled again when drained again
+	if got := q.newlyDraining(map[string]bool{"http://a": true, "http://b": true}); len(got) != 1 || got[0] != "http://b" {
+		t.Errorf("poll after b was drained again = %v, want [http://b]", got)
+	}
+}
@@ -0,0 +1,486 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Egress-aware routing.
+//
+// When several regions serve a model, the routing score of each endpoint is
+// scaled down by the estimated egress cost and network latency from the
+// node's region to the client's region, so traffic stays close to clients
+// unless a remote node is much better. The client's region is the region of
+// the environment the API key belongs to. How much cost and latency count is
+// set per tenant plan; plans without weights route as before.
+//
+// An endpoint's egress factor is
+//
+//	1 / (1 + cost_weight * cost_per_gb / egressCostReference
+//	       + latency_weight * latency_ms / egressLatencyReference)
+//
+// so a route costing egressCostReference with a cost weight of 1 halves the
+// endpoint's score.
+const (
+	// egressCostReference is a typical internet egress price in USD per GB
+	egressCostReference = 0.09
+	// egressLatencyReference is a typical cross-region round trip
+	egressLatencyReference = 100.0
+	// maxEgressWeight bounds plan weights
+	maxEgressWeight = 10.0
+	// egressWildcard matches any provider or region in a route
+	egressWildcard = "*"
+	// routingOriginCacheTTL bounds how long an environment's region and
+	// tenant plan are cached for routing
+	routingOriginCacheTTL = 60 * time.Second
+)
+
+var (
+	egressRegionPattern = regexp.MustCompile(`^([a-z0-9][a-z0-9-]{0,49}|\*)$`)
+	egressPlanPattern   = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+)
+
+// RoutingOrigin is where a request comes from, for egress-aware routing.
+// It is stored on the request context as "routing_origin".
+type RoutingOrigin struct {
+	Region string `json:"region"`
+	Plan   string `json:"plan"`
+}
+
+// EgressRoute is the estimated egress cost and latency from nodes of a
+// provider in one region to clients in another. "*" matches any provider or
+// region; the most specific route applies.
+type EgressRoute struct {
+	Provider        string    `json:"provider"`
+	SourceRegion    string    `json:"source_region"`
+	DestRegion      string    `json:"dest_region"`
+	EgressCostPerGB float64   `json:"egress_cost_per_gb"`
+	LatencyMs       int       `json:"latency_ms"`
+	UpdatedBy       *string   `json:"updated_by,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// EgressWeights sets how much egress cost and latency count in routing for
+// a plan
+type EgressWeights struct {
+	Plan          string    `json:"plan"`
+	CostWeight    float64   `json:"cost_weight"`
+	LatencyWeight float64   `json:"latency_weight"`
+	UpdatedBy     *string   `json:"updated_by,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type egressRouteKey struct {
+	provider, source, dest string
+}
+
+// endpointLocation is the provider and region of the node behind an endpoint
+type endpointLocation struct {
+	Provider string
+	Region   string
+}
+
+// egressTable holds the routes and weights endpoint selection reads
+type egressTable struct {
+	routes  map[egressRouteKey]EgressRoute
+	weights map[string]EgressWeights
+}
+
+// route returns the most specific route from a node location to a client
+// region
+func (t *egressTable) route(from endpointLocation, dest string) (EgressRoute, bool) {
+	keys := []egressRouteKey{
+		{from.Provider, from.Region, dest},
+		{egressWildcard, from.Region, dest},
+	}
+	// Catch-all routes describe leaving a region, not serving inside it
+	if from.Region != dest {
+		keys = append(keys,
+			egressRouteKey{from.Provider, from.Region, egressWildcard},
+			egressRouteKey{egressWildcard, from.Region, egressWildcard},
+			egressRouteKey{from.Provider, egressWildcard, egressWildcard},
+			egressRouteKey{egressWildcard, egressWildcard, egressWildcard},
+		)
+	}
+	for _, key := range keys {
+		if route, ok := t.routes[key]; ok {
+			return route, true
+		}
+	}
+	return EgressRoute{}, false
+}
+
+// factor returns the multiplier egress applies to an endpoint's routing
+// score for a request origin
+func (t *egressTable) factor(origin *RoutingOrigin, from endpointLocation) float64 {
+	if origin == nil || origin.Region == "" || from.Region == "" {
+		return 1
+	}
+	weights, ok := t.weights[origin.Plan]
+	if !ok || (weights.CostWeight == 0 && weights.LatencyWeight == 0) {
+		return 1
+	}
+	route, ok := t.route(from, origin.Region)
+	if !ok {
+		return 1
+	}
+	penalty := weights.CostWeight*route.EgressCostPerGB/egressCostReference +
+		weights.LatencyWeight*float64(route.LatencyMs)/egressLatencyReference
+	return 1 / (1 + penalty)
+}
+
+// LoadEgressRouting reloads egress routes, plan weights and the location of
+// every routable endpoint
+func (lb *IntelligentLoadBalancer) LoadEgressRouting(ctx context.Context) error {
+	table := &egressTable{
+		routes:  make(map[egressRouteKey]EgressRoute),
+		weights: make(map[string]EgressWeights),
+	}
+
+	routes, err := lb.db.Pool.Query(ctx, `
+		SELECT provider, source_region, dest_region, egress_cost_per_gb::float8, latency_ms, updated_by, updated_at
+		FROM region_egress_routes
+	`)
+	if err != nil {
+		return err
+	}
+	for routes.Next() {
+		var route EgressRoute
+		if err := routes.Scan(&route.Provider, &route.SourceRegion, &route.DestRegion,
+			&route.EgressCostPerGB, &route.LatencyMs, &route.UpdatedBy, &route.UpdatedAt); err != nil {
+			routes.Close()
+			return err
+		}
+		table.routes[egressRouteKey{route.Provider, route.SourceRegion, route.DestRegion}] = route
+	}
+	routes.Close()
+	if err := routes.Err(); err != nil {
+		return err
+	}
+
+	weights, err := lb.db.Pool.Query(ctx, `
+		SELECT plan, cost_weight::float8, latency_weight::float8, updated_by, updated_at
+		FROM routing_egress_weights
+	`)
+	if err != nil {
+		return err
+	}
+	for weights.Next() {
+		var w EgressWeights
+		if err := weights.Scan(&w.Plan, &w.CostWeight, &w.LatencyWeight, &w.UpdatedBy, &w.UpdatedAt); err != nil {
+			weights.Close()
+			return err
+		}
+		table.weights[w.Plan] = w
+	}
+	weights.Close()
+	if err := weights.Err(); err != nil {
+		return err
+	}
+
+	nodes, err := lb.db.Pool.Query(ctx, `
+		SELECT n.endpoint_url, n.provider, COALESCE(r.code, '')
+		FROM nodes n
+		LEFT JOIN regions r ON r.id = n.region_id
+		WHERE n.status = 'active' AND n.endpoint_url != ''
+	`)
+	if err != nil {
+		return err
+	}
+	defer nodes.Close()
+	locations := make(map[string]endpointLocation)
+	for nodes.Next() {
+		var endpoint string
+		var loc endpointLocation
+		if err := nodes.Scan(&endpoint, &loc.Provider, &loc.Region); err != nil {
+			return err
+		}
+		locations[endpoint] = loc
+	}
+	if err := nodes.Err(); err != nil {
+		return err
+	}
+
+	lb.mu.Lock()
+	lb.egress = table
+	lb.endpointLocations = locations
+	lb.mu.Unlock()
+	return nil
+}
+
+// egressFactor returns the egress multiplier for routing a request from
+// origin to an endpoint. Callers must hold lb.mu.
+func (lb *IntelligentLoadBalancer) egressFactor(origin *RoutingOrigin, endpoint string) float64 {
+	if lb.egress == nil {
+		return 1
+	}
+	loc, ok := lb.endpointLocations[endpoint]
+	if !ok {
+		return 1
+	}
+	return lb.egress.factor(origin, loc)
+}
+
+func routingOriginCacheKey(tenantID, envID uuid.UUID) string {
+	return cache.TenantKey(tenantID, "routing_origin", envID.String())
+}
+
+// withRoutingOrigin stores the request's environment region and tenant plan
+// on the context for endpoint selection. Origins that can't be resolved
+// route without egress scoring.
+func (g *Gateway) withRoutingOrigin(ctx context.Context) context.Context {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return ctx
+	}
+	envID, ok := ctx.Value("environment_id").(uuid.UUID)
+	if !ok {
+		return ctx
+	}
+
+	key := routingOriginCacheKey(tenantID, envID)
+	if cached, err := g.cache.Get(ctx, key); err == nil {
+		var origin RoutingOrigin
+		if err := json.Unmarshal([]byte(cached), &origin); err == nil {
+			return context.WithValue(ctx, "routing_origin", &origin)
+		}
+	}
+
+	var origin RoutingOrigin
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT e.region, t.billing_plan
+		FROM environments e
+		JOIN tenants t ON t.id = e.tenant_id
+		WHERE e.id = $1 AND e.tenant_id = $2 AND e.status = 'active'
+	`, envID, tenantID).Scan(&origin.Region, &origin.Plan)
+	if err != nil {
+		g.logger.Error("failed to get environment",
+			zap.Error(err),
+			zap.String("env_id", envID.String()),
+		)
+		// Continue without region preference
+		return ctx
+	}
+
+	encoded, _ := json.Marshal(origin)
+	if err := g.cache.Set(ctx, key, string(encoded), routingOriginCacheTTL); err != nil {
+		g.logger.Debug("failed to cache routing origin", zap.Error(err))
+	}
+	return context.WithValue(ctx, "routing_origin", &origin)
+}
+
+// handleGetEgressRouting lists egress routes and plan weights
+// Platform Admin Only - GET /admin/routing/egress
+func (g *Gateway) handleGetEgressRouting(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := g.LoadBalancer.LoadEgressRouting(ctx); err != nil {
+		g.logger.Error("failed to load egress routing", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load egress routing")
+		return
+	}
+
+	g.LoadBalancer.mu.RLock()
+	routes := make([]EgressRoute, 0, len(g.LoadBalancer.egress.routes))
+	for _, route := range g.LoadBalancer.egress.routes {
+		routes = append(routes, route)
+	}
+	weights := make([]EgressWeights, 0, len(g.LoadBalancer.egress.weights))
+	for _, w := range g.LoadBalancer.egress.weights {
+		weights = append(weights, w)
+	}
+	g.LoadBalancer.mu.RUnlock()
+	sort.Slice(routes, func(i, j int) bool {
+		a, b := routes[i], routes[j]
+		if a.SourceRegion != b.SourceRegion {
+			return a.SourceRegion < b.SourceRegion
+		}
+		if a.DestRegion != b.DestRegion {
+			return a.DestRegion < b.DestRegion
+		}
+		return a.Provider < b.Provider
+	})
+	sort.Slice(weights, func(i, j int) bool { return weights[i].Plan < weights[j].Plan })
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"routes":            routes,
+		"weights":           weights,
+		"cost_reference":    egressCostReference,
+		"latency_reference": egressLatencyReference,
+	})
+}
+
+// egressRouteRequest is the body of PUT /admin/routing/egress/routes
+type egressRouteRequest struct {
+	Provider        string  `json:"provider"`
+	SourceRegion    string  `json:"source_region"`
+	DestRegion      string  `json:"dest_region"`
+	EgressCostPerGB float64 `json:"egress_cost_per_gb"`
+	LatencyMs       int     `json:"latency_ms"`
+}
+
+// validate fills in defaults and checks the route
+func (req *egressRouteRequest) validate() error {
+	if req.Provider == "" {
+		req.Provider = egressWildcard
+	}
+	for field, value := range map[string]string{
+		"provider":      req.Provider,
+		"source_region": req.SourceRegion,
+		"dest_region":   req.DestRegion,
+	} {
+		if !egressRegionPattern.MatchString(value) {
+			return fmt.Errorf("%s must be a code or \"*\"", field)
+		}
+	}
+	if req.EgressCostPerGB < 0 || req.EgressCostPerGB > 100 {
+		return fmt.Errorf("egress_cost_per_gb must be between 0 and 100")
+	}
+	if req.LatencyMs < 0 || req.LatencyMs > 60000 {
+		return fmt.Errorf("latency_ms must be between 0 and 60000")
+	}
+	return nil
+}
+
+// handlePutEgressRoute creates or replaces an egress route
+// Platform Admin Only - PUT /admin/routing/egress/routes
+func (g *Gateway) handlePutEgressRoute(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req egressRouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	route := EgressRoute{
+		Provider:        req.Provider,
+		SourceRegion:    req.SourceRegion,
+		DestRegion:      req.DestRegion,
+		EgressCostPerGB: req.EgressCostPerGB,
+		LatencyMs:       req.LatencyMs,
+	}
+	err := g.db.Pool.QueryRow(ctx, `
+		INSERT INTO region_egress_routes (provider, source_region, dest_region, egress_cost_per_gb, latency_ms, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (provider, source_region, dest_region) DO UPDATE SET
+			egress_cost_per_gb = EXCLUDED.egress_cost_per_gb,
+			latency_ms = EXCLUDED.latency_ms,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING updated_by, updated_at
+	`, route.Provider, route.SourceRegion, route.DestRegion, route.EgressCostPerGB, route.LatencyMs, adminActor(ctx)).
+		Scan(&route.UpdatedBy, &route.UpdatedAt)
+	if err != nil {
+		g.logger.Error("failed to save egress route", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to save egress route")
+		return
+	}
+	g.reloadEgressRouting(ctx)
+
+	g.logger.Info("egress route updated",
+		zap.String("provider", route.Provider),
+		zap.String("source_region", route.SourceRegion),
+		zap.String("dest_region", route.DestRegion),
+		zap.Float64("egress_cost_per_gb", route.EgressCostPerGB),
+		zap.Int("latency_ms", route.LatencyMs),
+	)
+	g.writeJSON(w, http.StatusOK, route)
+}
+
+// handleDeleteEgressRoute removes an egress route
+// Platform Admin Only - DELETE /admin/routing/egress/routes/{provider}/{source}/{dest}
+func (g *Gateway) handleDeleteEgressRoute(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	result, err := g.db.Pool.Exec(ctx, `
+		DELETE FROM region_egress_routes
+		WHERE provider = $1 AND source_region = $2 AND dest_region = $3
+	`, chi.URLParam(r, "provider"), chi.URLParam(r, "source"), chi.URLParam(r, "dest"))
+	if err != nil {
+		g.logger.Error("failed to delete egress route", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to delete egress route")
+		return
+	}
+	if result.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "egress route not found")
+		return
+	}
+	g.reloadEgressRouting(ctx)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePutEgressWeights sets a plan's egress weights; zero weights make
+// the plan ignore egress
+// Platform Admin Only - PUT /admin/routing/egress/weights/{plan}
+func (g *Gateway) handlePutEgressWeights(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Legacy billing plans aren't in the plan catalog, so any plan name is
+	// accepted
+	plan := chi.URLParam(r, "plan")
+	if !egressPlanPattern.MatchString(plan) {
+		g.writeError(w, http.StatusBadRequest, "invalid plan")
+		return
+	}
+
+	var req struct {
+		CostWeight    float64 `json:"cost_weight"`
+		LatencyWeight float64 `json:"latency_weight"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.CostWeight < 0 || req.CostWeight > maxEgressWeight || req.LatencyWeight < 0 || req.LatencyWeight > maxEgressWeight {
+		g.writeError(w, http.StatusBadRequest, fmt.Sprintf("weights must be between 0 and %.0f", maxEgressWeight))
+		return
+	}
+
+	weights := EgressWeights{Plan: plan, CostWeight: req.CostWeight, LatencyWeight: req.LatencyWeight}
+	err := g.db.Pool.QueryRow(ctx, `
+		INSERT INTO routing_egress_weights (plan, cost_weight, latency_weight, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (plan) DO UPDATE SET
+			cost_weight = EXCLUDED.cost_weight,
+			latency_weight = EXCLUDED.latency_weight,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING updated_by, updated_at
+	`, plan, req.CostWeight, req.LatencyWeight, adminActor(ctx)).Scan(&weights.UpdatedBy, &weights.UpdatedAt)
+	if err != nil {
+		g.logger.Error("failed to save egress weights", zap.Error(err), zap.String("plan", plan))
+		g.writeError(w, http.StatusInternalServerError, "failed to save egress weights")
+		return
+	}
+	g.reloadEgressRouting(ctx)
+
+	g.logger.Info("egress weights updated",
+		zap.String("plan", plan),
+		zap.Float64("cost_weight", weights.CostWeight),
+		zap.Float64("latency_weight", weights.LatencyWeight),
+	)
+	g.writeJSON(w, http.StatusOK, weights)
+}
+
+// reloadEgressRouting applies a change on this replica right away; other
+// replicas pick it up on their next queue monitoring pass
+func (g *Gateway) reloadEgressRouting(ctx context.Context) {
+	if err := g.LoadBalancer.LoadEgressRouting(ctx); err != nil {
+		g.logger.Warn("failed to reload egress routing", zap.Error(err))
+	}
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func testEgressTable() *egressTable {
+	return &egressTable{
+		routes: map[egressRouteKey]EgressRoute{
+			{"aws", "us-east", "eu-west"}: {EgressCostPerGB: 0.09, LatencyMs: 80},
+			{"*", "us-east", "eu-west"}:   {EgressCostPerGB: 0.12, LatencyMs: 90},
+			{"*", "*", "*"}:               {EgressCostPerGB: 0.18, LatencyMs: 200},
+		},
+		weights: map[string]EgressWeights{
+			"free":       {Plan: "free", CostWeight: 1},
+			"enterprise": {Plan: "enterprise", LatencyWeight: 1},
+			"starter":    {Plan: "starter"},
+		},
+	}
+}
+
+func TestEgressRouteLookup(t *testing.T) {
+	table := testEgressTable()
+	for _, tc := range []struct {
+		from endpointLocation
+		dest string
+		want float64
+		ok   bool
+	}{
+		{endpointLocation{"aws", "us-east"}, "eu-west", 0.09, true},
+		{endpointLocation{"gcp", "us-east"}, "eu-west", 0.12, true},
+		{endpointLocation{"gcp", "in-mumbai"}, "eu-west", 0.18, true},
+		// Catch-all routes don't price serving within the client's region
+		{endpointLocation{"gcp", "eu-west"}, "eu-west", 0, false},
+	} {
+		route, ok := table.route(tc.from, tc.dest)
+		if ok != tc.ok || route.EgressCostPerGB != tc.want {
+			t.Errorf("route(%v, %s) = %v, %v; want %v, %v", tc.from, tc.dest, route.EgressCostPerGB, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestEgressFactor(t *testing.T) {
+	table := testEgressTable()
+	awsEast := endpointLocation{"aws", "us-east"}
+	for _, tc := range []struct {
+		name   string
+		origin *RoutingOrigin
+		want   float64
+	}{
+		{"no origin", nil, 1},
+		{"plan without weights", &RoutingOrigin{Region: "eu-west", Plan: "pro"}, 1},
+		{"zero weights", &RoutingOrigin{Region: "eu-west", Plan: "starter"}, 1},
+		{"cost weighted", &RoutingOrigin{Region: "eu-west", Plan: "free"}, 0.5},
+		{"latency weighted", &RoutingOrigin{Region: "eu-west", Plan: "enterprise"}, 1 / 1.8},
+		{"same region", &RoutingOrigin{Region: "us-east", Plan: "free"}, 1},
+	} {
+		if got := table.factor(tc.origin, awsEast); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: factor = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSelectEndpointPrefersClientRegion(t *testing.T) {
+	lb := &IntelligentLoadBalancer{
+		logger: zap.NewNop(),
+		stats: map[string]*EndpointStats{
+			// The remote node is somewhat faster
+			"http://east": {Latency: 40 * time.Millisecond, RequestCount: 10},
+			"http://eu":   {Latency: 60 * time.Millisecond, RequestCount: 10},
+		},
+		egress: testEgressTable(),
+		endpointLocations: map[string]endpointLocation{
+			"http://east": {"aws", "us-east"},
+			"http://eu":   {"aws", "eu-west"},
+		},
+	}
+	lb.SetRouteCacheTTL(time.Minute)
+	lb.storeRoutes("llama", []string{"http://east", "http://eu"}, time.Now())
+
+	endpoint, err := lb.SelectEndpoint(context.Background(), "llama")
+	if err != nil || endpoint != "http://east" {
+		t.Fatalf("without origin: %s, %v; want the fastest node", endpoint, err)
+	}
+
+	ctx := context.WithValue(context.Background(), "routing_origin", &RoutingOrigin{Region: "eu-west", Plan: "free"})
+	endpoint, err = lb.SelectEndpoint(ctx, "llama")
+	if err != nil || endpoint != "http://eu" {
+		t.Fatalf("from eu-west: %s, %v; want the node in the client's region", endpoint, err)
+	}
+}
@@ -0,0 +1,510 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Environment budgets.
+//
+// Tenants give an environment a token and/or spend budget per UTC day or
+// month that every API key in it draws from, and may cap single keys with
+// sub-limits inside it. The budget is loaded with the key on authentication
+// (and cached with it), checked by the rate limiter before inference and
+// drawn from once a response's usage is known. Totals live in Redis and
+// start counting when a budget is first set.
+
+// environmentBudgetRequest is the body of environment budget updates
+type environmentBudgetRequest struct {
+	Period            string `json:"period"`
+	LimitTokens       *int64 `json:"limit_tokens"`
+	LimitMicrodollars *int64 `json:"limit_microdollars"`
+}
+
+func (req *environmentBudgetRequest) validate() error {
+	if req.Period == "" {
+		req.Period = "monthly"
+	}
+	if req.Period != "daily" && req.Period != "monthly" {
+		return fmt.Errorf("period must be daily or monthly")
+	}
+	return validateBudgetLimits(req.LimitTokens, req.LimitMicrodollars)
+}
+
+// keyBudgetRequest is the body of API key sub-limit updates
+type keyBudgetRequest struct {
+	LimitTokens       *int64 `json:"limit_tokens"`
+	LimitMicrodollars *int64 `json:"limit_microdollars"`
+}
+
+// validateBudgetLimits requires at least one limit and only positive ones
+func validateBudgetLimits(tokens, microdollars *int64) error {
+	if tokens == nil && microdollars == nil {
+		return fmt.Errorf("limit_tokens or limit_microdollars is required")
+	}
+	if tokens != nil && *tokens <= 0 {
+		return fmt.Errorf("limit_tokens must be positive")
+	}
+	if microdollars != nil && *microdollars <= 0 {
+		return fmt.Errorf("limit_microdollars must be positive")
+	}
+	return nil
+}
+
+// EnvironmentBudgetStatus is an environment budget with its use this period
+type EnvironmentBudgetStatus struct {
+	EnvironmentID     uuid.UUID              `json:"environment_id"`
+	Period            string                 `json:"period"`
+	LimitTokens       *int64                 `json:"limit_tokens,omitempty"`
+	LimitMicrodollars *int64                 `json:"limit_microdollars,omitempty"`
+	UsedTokens        int64                  `json:"used_tokens"`
+	UsedMicrodollars  int64                  `json:"used_microdollars"`
+	ResetsAt          time.Time              `json:"resets_at"`
+	KeyLimits         []KeyBudgetLimitStatus `json:"key_limits"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+}
+
+// KeyBudgetLimitStatus is an API key's sub-limit with its use this period
+type KeyBudgetLimitStatus struct {
+	APIKeyID          uuid.UUID `json:"api_key_id"`
+	LimitTokens       *int64    `json:"limit_tokens,omitempty"`
+	LimitMicrodollars *int64    `json:"limit_microdollars,omitempty"`
+	UsedTokens        int64     `json:"used_tokens"`
+	UsedMicrodollars  int64     `json:"used_microdollars"`
+}
+
+// loadEnvironmentBudget reads an environment's budget with the key's
+// sub-limits, nil when the environment has no budget
+func loadEnvironmentBudget(ctx context.Context, q database.Querier, environmentID, keyID uuid.UUID) (*models.EnvironmentBudget, error) {
+	budget := models.EnvironmentBudget{EnvironmentID: environmentID}
+	err := q.QueryRow(ctx, `
+		SELECT b.period, b.limit_tokens, b.limit_microdollars, kl.limit_tokens, kl.limit_microdollars
+		FROM environment_budgets b
+		LEFT JOIN environment_budget_key_limits kl ON kl.environment_id = b.environment_id AND kl.api_key_id = $2
+		WHERE b.environment_id = $1
+	`, environmentID, keyID).Scan(&budget.Period, &budget.LimitTokens, &budget.LimitMicrodollars,
+		&budget.KeyLimitTokens, &budget.KeyLimitMicrodollars)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment budget: %w", err)
+	}
+	return &budget, nil
+}
+
+// enforceEnvironmentBudgets rejects inference once the key's environment
+// budget or the key's sub-limit is used up. Like rate limits it lets
+// requests through when Redis is down if rate limiting fails open.
+func (g *Gateway) enforceEnvironmentBudgets(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyInfo, ok := r.Context().Value("api_key").(*models.APIKey)
+		if !ok || keyInfo.Budget == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		exceeded, err := g.rateLimiter.CheckBudget(r.Context(), keyInfo)
+		if err != nil {
+			if g.cache.FailOpen(cache.FeatureRateLimit) {
+				g.logger.Warn("environment budget check failed, allowing request", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+			g.logger.Error("environment budget check failed", zap.Error(err))
+			g.writeError(w, http.StatusServiceUnavailable, "budget check failed")
+			return
+		}
+		if exceeded == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limit := fmt.Sprintf("%d tokens", exceeded.Limit)
+		if exceeded.Resource == "spend" {
+			limit = fmt.Sprintf("$%.2f", float64(exceeded.Limit)/1e6)
+		}
+		g.writeJSON(w, http.StatusPaymentRequired, map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": fmt.Sprintf("%s %s budget of %s exceeded; it resets at %s",
+					keyInfo.Budget.Period, exceeded.Scope, limit, exceeded.ResetsAt.Format(time.RFC3339)),
+				"type":           "insufficient_quota",
+				"code":           exceeded.Scope + "_budget_exceeded",
+				"environment_id": keyInfo.EnvironmentID.String(),
+				"resets_at":      exceeded.ResetsAt.Format(time.RFC3339),
+			},
+		})
+	})
+}
+
+// drawEnvironmentBudget draws a completed request's usage from the key's
+// environment budget. Spend is priced like usage cost previews and only
+// when a spend limit applies. It is best effort and runs after the request
+// context may have been canceled.
+func (g *Gateway) drawEnvironmentBudget(ctx context.Context, model string, promptTokens, completionTokens int, microdollars *int64) {
+	keyInfo, ok := ctx.Value("api_key").(*models.APIKey)
+	if !ok || keyInfo.Budget == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+
+	var spend int64
+	switch {
+	case microdollars != nil:
+		spend = *microdollars
+	case keyInfo.Budget.LimitsSpend() && model != "":
+		if capabilities, err := g.getModelCapabilities(ctx, model); err == nil && capabilities != nil {
+			cost := computeUsageCost(capabilities.Pricing, g.regionCostMultiplier(ctx), promptTokens, completionTokens)
+			spend = int64(math.Round(cost.Total * 1_000_000))
+		}
+	}
+
+	if err := g.rateLimiter.RecordBudgetUsage(ctx, keyInfo, int64(promptTokens+completionTokens), spend); err != nil {
+		g.logger.Warn("failed to draw from environment budget",
+			zap.Error(err),
+			zap.String("environment_id", keyInfo.EnvironmentID.String()),
+		)
+	}
+}
+
+// tenantEnvironmentID parses the environment ID route parameter and checks
+// the environment belongs to the tenant. Writes need a key that isn't
+// read-only.
+func (g *Gateway) tenantEnvironmentID(w http.ResponseWriter, r *http.Request, write bool) (uuid.UUID, uuid.UUID, bool) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return uuid.Nil, uuid.Nil, false
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); write && ok && keyInfo.Role == "read-only" {
+		g.writeError(w, http.StatusForbidden, "read-only API keys cannot change budgets")
+		return uuid.Nil, uuid.Nil, false
+	}
+	environmentID, err := uuid.Parse(chi.URLParam(r, "environment_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid environment ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	var exists bool
+	err = g.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM environments WHERE id = $1 AND tenant_id = $2)
+	`, environmentID, tenantID).Scan(&exists)
+	if err != nil {
+		g.logger.Error("failed to look up environment", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to look up environment")
+		return uuid.Nil, uuid.Nil, false
+	}
+	if !exists {
+		g.writeError(w, http.StatusNotFound, "environment not found")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return tenantID, environmentID, true
+}
+
+// environmentBudgetStatus reads an environment's budget, its key sub-limits
+// and what each drew this period, nil when the environment has no budget
+func (g *Gateway) environmentBudgetStatus(ctx context.Context, tenantID, environmentID uuid.UUID) (*EnvironmentBudgetStatus, error) {
+	status := EnvironmentBudgetStatus{EnvironmentID: environmentID, KeyLimits: []KeyBudgetLimitStatus{}}
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT period, limit_tokens, limit_microdollars, updated_at
+		FROM environment_budgets
+		WHERE environment_id = $1 AND tenant_id = $2
+	`, environmentID, tenantID).Scan(&status.Period, &status.LimitTokens, &status.LimitMicrodollars, &status.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment budget: %w", err)
+	}
+	_, status.ResetsAt = budgetWindow(status.Period, time.Now())
+
+	counters := &models.APIKey{TenantID: tenantID, EnvironmentID: environmentID}
+	status.UsedTokens, status.UsedMicrodollars, err = g.rateLimiter.BudgetUsage(ctx, counters, status.Period, "environment")
+	if err != nil {
+		g.logger.Warn("failed to read environment budget usage", zap.Error(err))
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT api_key_id, limit_tokens, limit_microdollars
+		FROM environment_budget_key_limits
+		WHERE environment_id = $1
+		ORDER BY created_at
+	`, environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key budget limits: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kl KeyBudgetLimitStatus
+		if err := rows.Scan(&kl.APIKeyID, &kl.LimitTokens, &kl.LimitMicrodollars); err != nil {
+			return nil, fmt.Errorf("failed to scan key budget limit: %w", err)
+		}
+		counters.ID = kl.APIKeyID
+		kl.UsedTokens, kl.UsedMicrodollars, err = g.rateLimiter.BudgetUsage(ctx, counters, status.Period, "key")
+		if err != nil {
+			g.logger.Warn("failed to read key budget usage", zap.Error(err))
+		}
+		status.KeyLimits = append(status.KeyLimits, kl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load key budget limits: %w", err)
+	}
+	return &status, nil
+}
+
+// invalidateEnvironmentKeys drops the cached API keys of an environment so
+// budget changes apply on their next request
+func (g *Gateway) invalidateEnvironmentKeys(ctx context.Context, environmentID uuid.UUID) {
+	rows, err := g.db.Pool.Query(ctx, `SELECT key_hash FROM api_keys WHERE environment_id = $1`, environmentID)
+	if err != nil {
+		g.logger.Warn("failed to list environment API keys", zap.Error(err))
+		return
+	}
+	defer rows.Close()
+
+	var cacheKeys []string
+	for rows.Next() {
+		var keyHash string
+		if err := rows.Scan(&keyHash); err == nil {
+			cacheKeys = append(cacheKeys, fmt.Sprintf("api_key:%s", keyHash))
+		}
+	}
+	if len(cacheKeys) == 0 {
+		return
+	}
+	if err := g.cache.Delete(ctx, cacheKeys...); err != nil {
+		g.logger.Warn("failed to invalidate cached API keys", zap.Error(err))
+	}
+}
+
+// handleGetEnvironmentBudget returns an environment's budget and its use
+// Tenant API - GET /v1/environments/{environment_id}/budget
+func (g *Gateway) handleGetEnvironmentBudget(w http.ResponseWriter, r *http.Request) {
+	tenantID, environmentID, ok := g.tenantEnvironmentID(w, r, false)
+	if !ok {
+		return
+	}
+
+	status, err := g.environmentBudgetStatus(r.Context(), tenantID, environmentID)
+	if err != nil {
+		g.logger.Error("failed to load environment budget", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load environment budget")
+		return
+	}
+	if status == nil {
+		g.writeError(w, http.StatusNotFound, "environment has no budget")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, status)
+}
+
+// handlePutEnvironmentBudget sets an environment's shared budget
+// Tenant API - PUT /v1/environments/{environment_id}/budget
+func (g *Gateway) handlePutEnvironmentBudget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, environmentID, ok := g.tenantEnvironmentID(w, r, true)
+	if !ok {
+		return
+	}
+
+	var req environmentBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var updatedBy *string
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok {
+		actor := "api_key:" + keyInfo.ID.String()
+		updatedBy = &actor
+	}
+	_, err := g.db.Pool.Exec(ctx, `
+		INSERT INTO environment_budgets (environment_id, tenant_id, period, limit_tokens, limit_microdollars, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (environment_id) DO UPDATE SET
+			period = EXCLUDED.period,
+			limit_tokens = EXCLUDED.limit_tokens,
+			limit_microdollars = EXCLUDED.limit_microdollars,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+	`, environmentID, tenantID, req.Period, req.LimitTokens, req.LimitMicrodollars, updatedBy)
+	if err != nil {
+		g.logger.Error("failed to save environment budget", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to save environment budget")
+		return
+	}
+	g.invalidateEnvironmentKeys(ctx, environmentID)
+
+	g.logger.Info("environment budget set",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("environment_id", environmentID.String()),
+		zap.String("period", req.Period),
+	)
+
+	status, err := g.environmentBudgetStatus(ctx, tenantID, environmentID)
+	if err != nil || status == nil {
+		g.writeJSON(w, http.StatusOK, map[string]interface{}{"environment_id": environmentID, "period": req.Period})
+		return
+	}
+	g.writeJSON(w, http.StatusOK, status)
+}
+
+// handleDeleteEnvironmentBudget removes an environment's budget and its key
+// sub-limits
+// Tenant API - DELETE /v1/environments/{environment_id}/budget
+func (g *Gateway) handleDeleteEnvironmentBudget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	_, environmentID, ok := g.tenantEnvironmentID(w, r, true)
+	if !ok {
+		return
+	}
+
+	tag, err := g.db.Pool.Exec(ctx, `DELETE FROM environment_budgets WHERE environment_id = $1`, environmentID)
+	if err != nil {
+		g.logger.Error("failed to delete environment budget", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to delete environment budget")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "environment has no budget")
+		return
+	}
+	g.invalidateEnvironmentKeys(ctx, environmentID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tenantAPIKeyEnvironment parses the key ID route parameter and returns the
+// key's environment after checking the key belongs to the tenant
+func (g *Gateway) tenantAPIKeyEnvironment(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return uuid.Nil, uuid.Nil, false
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok && keyInfo.Role == "read-only" {
+		g.writeError(w, http.StatusForbidden, "read-only API keys cannot change budgets")
+		return uuid.Nil, uuid.Nil, false
+	}
+	keyID, err := uuid.Parse(chi.URLParam(r, "key_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid key ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	var keyTenantID, environmentID uuid.UUID
+	err = g.db.Pool.QueryRow(ctx, `
+		SELECT tenant_id, environment_id FROM api_keys WHERE id = $1
+	`, keyID).Scan(&keyTenantID, &environmentID)
+	if err != nil {
+		g.writeError(w, http.StatusNotFound, "API key not found")
+		return uuid.Nil, uuid.Nil, false
+	}
+	if keyTenantID != tenantID {
+		g.logger.Warn("attempt to change budget of key from different tenant",
+			zap.String("tenant_id", tenantID.String()),
+			zap.String("key_id", keyID.String()),
+		)
+		g.writeError(w, http.StatusForbidden, "access denied")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return keyID, environmentID, true
+}
+
+// handlePutKeyBudget sets an API key's sub-limits within its environment's
+// budget
+// Tenant API - PUT /v1/api-keys/{key_id}/budget
+func (g *Gateway) handlePutKeyBudget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	keyID, environmentID, ok := g.tenantAPIKeyEnvironment(w, r)
+	if !ok {
+		return
+	}
+
+	var req keyBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := validateBudgetLimits(req.LimitTokens, req.LimitMicrodollars); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var updatedBy *string
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok {
+		actor := "api_key:" + keyInfo.ID.String()
+		updatedBy = &actor
+	}
+	tag, err := g.db.Pool.Exec(ctx, `
+		INSERT INTO environment_budget_key_limits (api_key_id, environment_id, limit_tokens, limit_microdollars, updated_by)
+		SELECT $1, environment_id, $3, $4, $5 FROM environment_budgets WHERE environment_id = $2
+		ON CONFLICT (api_key_id) DO UPDATE SET
+			limit_tokens = EXCLUDED.limit_tokens,
+			limit_microdollars = EXCLUDED.limit_microdollars,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+	`, keyID, environmentID, req.LimitTokens, req.LimitMicrodollars, updatedBy)
+	if err != nil {
+		g.logger.Error("failed to save key budget limit", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to save key budget limit")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		g.writeError(w, http.StatusConflict, "the key's environment has no budget; set one first")
+		return
+	}
+	g.invalidateEnvironmentKeys(ctx, environmentID)
+
+	g.writeJSON(w, http.StatusOK, KeyBudgetLimitStatus{
+		APIKeyID:          keyID,
+		LimitTokens:       req.LimitTokens,
+		LimitMicrodollars: req.LimitMicrodollars,
+	})
+}
+
+// handleDeleteKeyBudget removes an API key's sub-limits; the key keeps
+// drawing on the shared budget
+// Tenant API - DELETE /v1/api-keys/{key_id}/budget
+func (g *Gateway) handleDeleteKeyBudget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	keyID, environmentID, ok := g.tenantAPIKeyEnvironment(w, r)
+	if !ok {
+		return
+	}
+
+	tag, err := g.db.Pool.Exec(ctx, `DELETE FROM environment_budget_key_limits WHERE api_key_id = $1`, keyID)
+	if err != nil {
+		g.logger.Error("failed to delete key budget limit", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to delete key budget limit")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "API key has no budget limit")
+		return
+	}
+	g.invalidateEnvironmentKeys(ctx, environmentID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
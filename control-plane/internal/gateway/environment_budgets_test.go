@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestCheckBudget_SharedAcrossKeys(t *testing.T) {
+	c, cleanup := setupLimiterCache(t)
+	defer cleanup()
+	rl := NewRateLimiter(c, zap.NewNop())
+	ctx := context.Background()
+
+	tenantID, envID := uuid.New(), uuid.New()
+	budget := &models.EnvironmentBudget{EnvironmentID: envID, Period: "monthly", LimitTokens: int64Ptr(1000)}
+	first := &models.APIKey{ID: uuid.New(), TenantID: tenantID, EnvironmentID: envID, Budget: budget}
+	second := &models.APIKey{ID: uuid.New(), TenantID: tenantID, EnvironmentID: envID, Budget: budget}
+
+	if err := rl.RecordBudgetUsage(ctx, first, 600, 0); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if exceeded, err := rl.CheckBudget(ctx, second); err != nil || exceeded != nil {
+		t.Fatalf("within budget: exceeded=%+v err=%v", exceeded, err)
+	}
+
+	if err := rl.RecordBudgetUsage(ctx, second, 400, 0); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	exceeded, err := rl.CheckBudget(ctx, first)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if exceeded == nil || exceeded.Scope != "environment" || exceeded.Resource != "tokens" || exceeded.Used != 1000 {
+		t.Fatalf("expected environment token budget exceeded, got %+v", exceeded)
+	}
+}
+
+func TestCheckBudget_KeySubLimit(t *testing.T) {
+	c, cleanup := setupLimiterCache(t)
+	defer cleanup()
+	rl := NewRateLimiter(c, zap.NewNop())
+	ctx := context.Background()
+
+	tenantID, envID := uuid.New(), uuid.New()
+	limited := &models.APIKey{ID: uuid.New(), TenantID: tenantID, EnvironmentID: envID, Budget: &models.EnvironmentBudget{
+		EnvironmentID:        envID,
+		Period:               "daily",
+		LimitMicrodollars:    int64Ptr(10_000_000),
+		KeyLimitMicrodollars: int64Ptr(2_000_000),
+	}}
+	other := &models.APIKey{ID: uuid.New(), TenantID: tenantID, EnvironmentID: envID, Budget: &models.EnvironmentBudget{
+		EnvironmentID:     envID,
+		Period:            "daily",
+		LimitMicrodollars: int64Ptr(10_000_000),
+	}}
+
+	if err := rl.RecordBudgetUsage(ctx, limited, 100, 2_500_000); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	exceeded, err := rl.CheckBudget(ctx, limited)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if exceeded == nil || exceeded.Scope != "key" || exceeded.Resource != "spend" {
+		t.Fatalf("expected key spend sub-limit exceeded, got %+v", exceeded)
+	}
+	if exceeded, err := rl.CheckBudget(ctx, other); err != nil || exceeded != nil {
+		t.Fatalf("other key should stay within the shared budget: exceeded=%+v err=%v", exceeded, err)
+	}
+
+	tokens, spend, err := rl.BudgetUsage(ctx, other, "daily", "environment")
+	if err != nil || tokens != 100 || spend != 2_500_000 {
+		t.Errorf("environment usage = %d tokens, %d microdollars, %v", tokens, spend, err)
+	}
+}
+
+func TestCheckBudget_NoBudget(t *testing.T) {
+	c, cleanup := setupLimiterCache(t)
+	defer cleanup()
+	rl := NewRateLimiter(c, zap.NewNop())
+
+	key := &models.APIKey{ID: uuid.New(), TenantID: uuid.New(), EnvironmentID: uuid.New()}
+	if err := rl.RecordBudgetUsage(context.Background(), key, 1_000_000, 0); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if exceeded, err := rl.CheckBudget(context.Background(), key); err != nil || exceeded != nil {
+		t.Fatalf("key without budget was limited: exceeded=%+v err=%v", exceeded, err)
+	}
+}
+
+func TestBudgetWindow(t *testing.T) {
+	now := time.Date(2026, 2, 14, 18, 30, 0, 0, time.UTC)
+
+	window, resetsAt := budgetWindow("daily", now)
+	if window != "2026-02-14" || !resetsAt.Equal(time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("daily window = %s, resets %s", window, resetsAt)
+	}
+
+	window, resetsAt = budgetWindow("monthly", now)
+	if window != "2026-02" || !resetsAt.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("monthly window = %s, resets %s", window, resetsAt)
+	}
+}
+
+func TestEnvironmentBudgetRequestValidate(t *testing.T) {
+	req := environmentBudgetRequest{LimitTokens: int64Ptr(5000)}
+	if err := req.validate(); err != nil || req.Period != "monthly" {
+		t.Errorf("default period: period=%q err=%v", req.Period, err)
+	}
+
+	invalid := []environmentBudgetRequest{
+		{Period: "monthly"},
+		{Period: "weekly", LimitTokens: int64Ptr(5000)},
+		{Period: "daily", LimitMicrodollars: int64Ptr(0)},
+		{Period: "daily", LimitTokens: int64Ptr(-1)},
+	}
+	for _, req := range invalid {
+		if err := req.validate(); err == nil {
+			t.Errorf("accepted %+v", req)
+		}
+	}
+}
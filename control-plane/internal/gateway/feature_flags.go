@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/crosslogic/control-plane/internal/features"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// featureEnabled reports whether a feature flag is on for the authenticated
+// tenant. Handlers use it to branch on partially rolled out behaviour.
+func (g *Gateway) featureEnabled(ctx context.Context, key string) bool {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return false
+	}
+	return g.features.IsEnabled(ctx, key, tenantID)
+}
+
+// RequireFeature is middleware that hides a route unless the feature flag is
+// on for the tenant. It must run after authMiddleware.
+func (g *Gateway) RequireFeature(key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !g.featureEnabled(r.Context(), key) {
+				g.writeJSON(w, http.StatusForbidden, map[string]interface{}{
+					"error": map[string]string{
+						"message": "This feature is not enabled for your account.",
+						"type":    "feature_not_enabled",
+						"code":    key,
+					},
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// featureFlagRequest is the body for creating or updating a flag
+type featureFlagRequest struct {
+	Key            string  `json:"key"`
+	Description    *string `json:"description,omitempty"`
+	Enabled        bool    `json:"enabled"`
+	RolloutPercent int     `json:"rollout_percent"`
+}
+
+// writeFeatureFlagError maps feature service errors to HTTP responses
+func (g *Gateway) writeFeatureFlagError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, features.ErrFlagNotFound):
+		g.writeError(w, http.StatusNotFound, "feature flag not found")
+	case errors.Is(err, features.ErrFlagExists):
+		g.writeError(w, http.StatusConflict, "feature flag already exists")
+	default:
+		g.logger.Error("failed to "+action+" feature flag", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to "+action+" feature flag")
+	}
+}
+
+// handleListFeatureFlags lists all feature flags
+// GET /api/v1/admin/feature-flags
+func (g *Gateway) handleListFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := g.features.ListFlags(r.Context())
+	if err != nil {
+		g.writeFeatureFlagError(w, err, "list")
+		return
+	}
+	if flags == nil {
+		flags = []*features.Flag{}
+	}
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{"data": flags})
+}
+
+// handleGetFeatureFlag returns one feature flag with its overrides
+// GET /api/v1/admin/feature-flags/{key}
+func (g *Gateway) handleGetFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	flag, err := g.features.GetFlag(r.Context(), chi.URLParam(r, "key"))
+	if err != nil {
+		g.writeFeatureFlagError(w, err, "get")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, flag)
+}
+
+// handleCreateFeatureFlag creates a feature flag
+// POST /api/v1/admin/feature-flags
+func (g *Gateway) handleCreateFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	var req featureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	input := features.FlagInput(req)
+	if err := input.Validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	flag, err := g.features.CreateFlag(r.Context(), input)
+	if err != nil {
+		g.writeFeatureFlagError(w, err, "create")
+		return
+	}
+	g.writeJSON(w, http.StatusCreated, flag)
+}
+
+// handleUpdateFeatureFlag replaces a flag's description, kill switch and rollout
+// PUT /api/v1/admin/feature-flags/{key}
+func (g *Gateway) handleUpdateFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	var req featureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	input := features.FlagInput(req)
+	input.Key = chi.URLParam(r, "key")
+	if err := input.Validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	flag, err := g.features.UpdateFlag(r.Context(), input)
+	if err != nil {
+		g.writeFeatureFlagError(w, err, "update")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, flag)
+}
+
+// handleDeleteFeatureFlag deletes a flag and its overrides
+// DELETE /api/v1/admin/feature-flags/{key}
+func (g *Gateway) handleDeleteFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	if err := g.features.DeleteFlag(r.Context(), chi.URLParam(r, "key")); err != nil {
+		g.writeFeatureFlagError(w, err, "delete")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSetFeatureFlagOverride forces a flag on or off for one tenant
+// PUT /api/v1/admin/feature-flags/{key}/overrides/{tenant_id}
+func (g *Gateway) handleSetFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(chi.URLParam(r, "tenant_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid tenant_id")
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Enabled == nil {
+		g.writeError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+
+	key := chi.URLParam(r, "key")
+	if err := g.features.SetOverride(r.Context(), key, tenantID, *req.Enabled); err != nil {
+		g.writeFeatureFlagError(w, err, "update")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"key":       key,
+		"tenant_id": tenantID,
+		"enabled":   *req.Enabled,
+	})
+}
+
+// handleDeleteFeatureFlagOverride removes a tenant override so the rollout applies again
+// DELETE /api/v1/admin/feature-flags/{key}/overrides/{tenant_id}
+func (g *Gateway) handleDeleteFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := uuid.Parse(chi.URLParam(r, "tenant_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid tenant_id")
+		return
+	}
+
+	if err := g.features.DeleteOverride(r.Context(), chi.URLParam(r, "key"), tenantID); err != nil {
+		g.writeFeatureFlagError(w, err, "update")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,614 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/crosslogic/control-plane/pkg/r2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Tenants upload JSONL inputs for batch and fine-tuning jobs through the
+// OpenAI-compatible Files API. Every line is checked against the file's
+// purpose before the file is stored in R2, so jobs never start on input
+// they would reject halfway. Files count against the plan's storage quota
+// until deleted.
+
+const (
+	// filesMaxBytes is the largest file, matching OpenAI's batch input limit
+	filesMaxBytes = 200 << 20
+
+	// filesMaxLines is the most requests or examples a file may hold
+	filesMaxLines = 50_000
+
+	// filesFormOverhead is allowed on top of the file for the other form
+	// fields and multipart boundaries
+	filesFormOverhead = 1 << 20
+
+	// filesListMaxLimit is the most files one list request returns
+	filesListMaxLimit = 10_000
+)
+
+// File purposes
+const (
+	filePurposeBatch    = "batch"
+	filePurposeFineTune = "fine-tune"
+)
+
+// fileContentTypes are the media types a JSONL upload may declare. Many
+// clients send application/octet-stream for .jsonl files, so it is
+// accepted too; the content is validated either way.
+var fileContentTypes = map[string]bool{
+	"application/jsonl":        true,
+	"application/x-jsonlines":  true,
+	"application/x-ndjson":     true,
+	"application/json":         true,
+	"text/jsonl":               true,
+	"application/octet-stream": true,
+}
+
+// batchFileURLs are the endpoints batch input lines may call
+var batchFileURLs = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/embeddings":       true,
+}
+
+// errFileQuota is returned when a file would take the tenant over its
+// plan's file storage quota
+var errFileQuota = errors.New("file storage quota exceeded")
+
+// File is an uploaded file as the OpenAI Files API describes it
+type File struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+
+	objectKey string
+}
+
+const fileColumns = `id, filename, purpose, bytes, object_key, created_at`
+
+func scanFile(row pgx.Row) (*File, error) {
+	f := &File{Object: "file", Status: "processed"}
+	var createdAt time.Time
+	if err := row.Scan(&f.ID, &f.Filename, &f.Purpose, &f.Bytes, &f.objectKey, &createdAt); err != nil {
+		return nil, err
+	}
+	f.CreatedAt = createdAt.Unix()
+	return f, nil
+}
+
+// newObjectID returns an OpenAI-style ID such as file-3f2a...
+func newObjectID(prefix string) string {
+	id := uuid.New()
+	return prefix + hex.EncodeToString(id[:])
+}
+
+// tenantFileKey is where a file's contents are stored
+func tenantFileKey(tenantID uuid.UUID, fileID string) string {
+	return fmt.Sprintf("files/%s/%s.jsonl", tenantID, fileID)
+}
+
+// fileContentError points at the first line of a file that failed
+// validation
+type fileContentError struct {
+	Line    int
+	Message string
+}
+
+func (e *fileContentError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// validFilePurpose reports whether files can be uploaded for purpose
+func validFilePurpose(purpose string) bool {
+	return purpose == filePurposeBatch || purpose == filePurposeFineTune
+}
+
+// validateFileUpload checks the name and declared media type of an upload
+func validateFileUpload(filename, contentType string) error {
+	if filename == "" || len(filename) > 255 {
+		return errors.New("filename is required and must be at most 255 characters")
+	}
+	if !strings.EqualFold(path.Ext(filename), ".jsonl") {
+		return errors.New("only .jsonl files are supported")
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !fileContentTypes[mediaType] {
+		return fmt.Errorf("unsupported content type %q; upload JSONL as application/jsonl", contentType)
+	}
+	return nil
+}
+
+// validateFileContent checks that every line of a file is a JSON object of
+// the shape its purpose needs, returning the number of lines
+func validateFileContent(purpose string, data []byte) (int, error) {
+	if !utf8.Valid(data) {
+		return 0, &fileContentError{Message: "file must be UTF-8 encoded JSONL"}
+	}
+
+	customIDs := make(map[string]bool)
+	lines := 0
+	for i, raw := range bytes.Split(data, []byte("\n")) {
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			continue
+		}
+		lines++
+		if lines > filesMaxLines {
+			return 0, &fileContentError{Message: fmt.Sprintf("file has more than %d lines", filesMaxLines)}
+		}
+		if raw[0] != '{' || !json.Valid(raw) {
+			return 0, &fileContentError{Line: i + 1, Message: "each line must be a JSON object"}
+		}
+
+		var err error
+		switch purpose {
+		case filePurposeBatch:
+			err = validateBatchLine(raw, customIDs)
+		case filePurposeFineTune:
+			err = validateFineTuneLine(raw)
+		}
+		if err != nil {
+			return 0, &fileContentError{Line: i + 1, Message: err.Error()}
+		}
+	}
+	if lines == 0 {
+		return 0, &fileContentError{Message: "file has no JSON lines"}
+	}
+	return lines, nil
+}
+
+// validateBatchLine checks a batch request line: a unique custom_id and a
+// POST to a supported endpoint with a JSON object body
+func validateBatchLine(raw []byte, customIDs map[string]bool) error {
+	var line struct {
+		CustomID string          `json:"custom_id"`
+		Method   string          `json:"method"`
+		URL      string          `json:"url"`
+		Body     json.RawMessage `json:"body"`
+	}
+	if err := json.Unmarshal(raw, &line); err != nil {
+		return errors.New("custom_id, method and url must be strings")
+	}
+	switch {
+	case line.CustomID == "":
+		return errors.New("custom_id is required")
+	case customIDs[line.CustomID]:
+		return fmt.Errorf("custom_id %q is used more than once", line.CustomID)
+	case line.Method != http.MethodPost:
+		return errors.New(`method must be "POST"`)
+	case !batchFileURLs[line.URL]:
+		return fmt.Errorf("url %q is not supported in batches", line.URL)
+	case len(line.Body) == 0 || line.Body[0] != '{':
+		return errors.New("body must be a JSON object")
+	}
+	customIDs[line.CustomID] = true
+	return nil
+}
+
+// validateFineTuneLine checks a training example: a chat conversation, or a
+// prompt and completion pair
+func validateFineTuneLine(raw []byte) error {
+	var line struct {
+		Messages []struct {
+			Role string `json:"role"`
+		} `json:"messages"`
+		Prompt     *string `json:"prompt"`
+		Completion *string `json:"completion"`
+	}
+	if err := json.Unmarshal(raw, &line); err != nil {
+		return errors.New("messages must be a list of objects with a role")
+	}
+	if line.Prompt != nil && line.Completion != nil {
+		return nil
+	}
+	if len(line.Messages) == 0 {
+		return errors.New("each example needs messages, or a prompt and completion")
+	}
+	for _, message := range line.Messages {
+		if message.Role == "" {
+			return errors.New("every message needs a role")
+		}
+	}
+	return nil
+}
+
+// filesTenant returns the authenticated tenant, answering the request
+// itself when the Files API is unavailable or, for writes, the API key is
+// read-only
+func (g *Gateway) filesTenant(w http.ResponseWriter, r *http.Request, write bool) (uuid.UUID, bool) {
+	tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	if g.Files == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "file storage is not configured")
+		return uuid.Nil, false
+	}
+	if keyInfo, ok := r.Context().Value("api_key").(*models.APIKey); write && ok && keyInfo.Role == "read-only" {
+		g.writeError(w, http.StatusForbidden, "read-only API keys cannot change files")
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// handleCreateFile uploads a file from a multipart form with purpose and
+// file fields
+// POST /v1/files
+func (g *Gateway) handleCreateFile(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.filesTenant(w, r, true)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, filesMaxBytes+filesFormOverhead)
+	purpose, filename, data, err := parseFileForm(r.Header.Get("Content-Type"), r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			g.writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds the %d MB limit", filesMaxBytes>>20))
+			return
+		}
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	file, err := g.storeFile(r.Context(), tenantID, filename, purpose, data)
+	if err != nil {
+		g.writeStoreFileError(w, tenantID, err)
+		return
+	}
+	g.writeJSON(w, http.StatusOK, file)
+}
+
+// parseFileForm reads the purpose and file of a multipart/form-data upload
+func parseFileForm(contentType string, body io.Reader) (purpose, filename string, data []byte, err error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", "", nil, errors.New("request must be multipart/form-data")
+	}
+
+	hasFile := false
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", nil, fileFormError(err)
+		}
+
+		switch part.FormName() {
+		case "purpose":
+			value, err := io.ReadAll(io.LimitReader(part, 64))
+			if err != nil {
+				return "", "", nil, fileFormError(err)
+			}
+			purpose = strings.TrimSpace(string(value))
+		case "file":
+			filename = part.FileName()
+			if err := validateFileUpload(filename, part.Header.Get("Content-Type")); err != nil {
+				return "", "", nil, err
+			}
+			if data, err = io.ReadAll(part); err != nil {
+				return "", "", nil, fileFormError(err)
+			}
+			hasFile = true
+		}
+		part.Close()
+	}
+
+	if !validFilePurpose(purpose) {
+		return "", "", nil, errors.New(`purpose must be "batch" or "fine-tune"`)
+	}
+	if !hasFile || len(data) == 0 {
+		return "", "", nil, errors.New("file is required")
+	}
+	if len(data) > filesMaxBytes {
+		return "", "", nil, &http.MaxBytesError{Limit: filesMaxBytes}
+	}
+	return purpose, filename, data, nil
+}
+
+// fileFormError keeps a body size error recognizable and reports any other
+// read failure as a malformed form
+func fileFormError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	return errors.New("invalid multipart body")
+}
+
+// storeFile validates a file's content and stores it for the tenant,
+// checking the plan's storage quota. A tenant's files are stored one at a
+// time so concurrent uploads can't overrun the quota together.
+func (g *Gateway) storeFile(ctx context.Context, tenantID uuid.UUID, filename, purpose string, data []byte) (*File, error) {
+	lines, err := validateFileContent(purpose, data)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('files:' || $1::text))`, tenantID); err != nil {
+		return nil, err
+	}
+	if err := g.checkFileQuota(ctx, tx, tenantID, int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	fileID := newObjectID("file-")
+	key := tenantFileKey(tenantID, fileID)
+	objects := r2.NewObjects(g.Files)
+	if err := objects.Put(ctx, key, data, "application/jsonl"); err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	file, err := scanFile(tx.QueryRow(ctx, `
+		INSERT INTO files (id, tenant_id, filename, purpose, bytes, line_count, object_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+fileColumns,
+		fileID, tenantID, filename, purpose, len(data), lines, key,
+	))
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		if deleteErr := objects.Delete(context.WithoutCancel(ctx), key); deleteErr != nil {
+			g.logger.Warn("failed to remove unrecorded file", zap.Error(deleteErr), zap.String("key", key))
+		}
+		return nil, err
+	}
+
+	g.logger.Info("file uploaded",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("file_id", file.ID),
+		zap.String("purpose", purpose),
+		zap.Int64("bytes", file.Bytes),
+	)
+	return file, nil
+}
+
+// checkFileQuota returns errFileQuota when adding size bytes would take the
+// tenant over its plan's file storage quota
+func (g *Gateway) checkFileQuota(ctx context.Context, q database.Querier, tenantID uuid.UUID, size int64) error {
+	var used int64
+	if err := q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(bytes), 0)::bigint FROM files WHERE tenant_id = $1 AND deleted_at IS NULL
+	`, tenantID).Scan(&used); err != nil {
+		return err
+	}
+	if limit := g.tenantPlan(ctx, tenantID).FileStorageBytes; used+size > limit {
+		return fmt.Errorf("%w: %d of %d bytes used", errFileQuota, used, limit)
+	}
+	return nil
+}
+
+// writeStoreFileError answers a failed storeFile
+func (g *Gateway) writeStoreFileError(w http.ResponseWriter, tenantID uuid.UUID, err error) {
+	var contentErr *fileContentError
+	switch {
+	case errors.As(err, &contentErr):
+		g.writeError(w, http.StatusBadRequest, "invalid file: "+contentErr.Error())
+	case errors.Is(err, errFileQuota):
+		g.writeJSON(w, http.StatusRequestEntityTooLarge, map[string]interface{}{
+			"error": map[string]string{
+				"message": err.Error() + "; delete files you no longer need",
+				"type":    "file_quota_exceeded",
+			},
+		})
+	default:
+		g.logger.Error("failed to store file", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to store file")
+	}
+}
+
+// handleListFiles lists the tenant's files, newest first unless
+// order=asc, optionally for one purpose, paging with limit and after
+// GET /v1/files
+func (g *Gateway) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.filesTenant(w, r, false)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	purpose := query.Get("purpose")
+	if purpose != "" && !validFilePurpose(purpose) {
+		g.writeError(w, http.StatusBadRequest, `purpose must be "batch" or "fine-tune"`)
+		return
+	}
+	limit := filesListMaxLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > filesListMaxLimit {
+			g.writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", filesListMaxLimit))
+			return
+		}
+		limit = n
+	}
+	order, cmp := "DESC", "<"
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		order, cmp = "ASC", ">"
+	default:
+		g.writeError(w, http.StatusBadRequest, `order must be "asc" or "desc"`)
+		return
+	}
+
+	rows, err := g.db.Pool.Query(r.Context(), fmt.Sprintf(`
+		SELECT %s FROM files
+		WHERE tenant_id = $1 AND deleted_at IS NULL
+		  AND ($2 = '' OR purpose = $2)
+		  AND ($3 = '' OR (created_at, id) %s (SELECT created_at, id FROM files WHERE id = $3 AND tenant_id = $1))
+		ORDER BY created_at %s, id %s
+		LIMIT $4
+	`, fileColumns, cmp, order, order), tenantID, purpose, query.Get("after"), limit+1)
+	if err != nil {
+		g.logger.Error("failed to list files", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to list files")
+		return
+	}
+	defer rows.Close()
+
+	files := []*File{}
+	for rows.Next() {
+		file, err := scanFile(rows)
+		if err != nil {
+			g.logger.Error("failed to scan file", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to list files")
+			return
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		g.logger.Error("failed to list files", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list files")
+		return
+	}
+
+	hasMore := len(files) > limit
+	if hasMore {
+		files = files[:limit]
+	}
+	response := map[string]interface{}{
+		"object":   "list",
+		"data":     files,
+		"has_more": hasMore,
+	}
+	if len(files) > 0 {
+		response["first_id"] = files[0].ID
+		response["last_id"] = files[len(files)-1].ID
+	}
+	g.writeJSON(w, http.StatusOK, response)
+}
+
+// tenantFile loads one of the tenant's files, answering 404 itself
+func (g *Gateway) tenantFile(w http.ResponseWriter, r *http.Request, tenantID uuid.UUID) (*File, bool) {
+	file, err := scanFile(g.db.Pool.QueryRow(r.Context(), `
+		SELECT `+fileColumns+` FROM files
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+	`, chi.URLParam(r, "file_id"), tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "file not found")
+		return nil, false
+	}
+	if err != nil {
+		g.logger.Error("failed to get file", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to get file")
+		return nil, false
+	}
+	return file, true
+}
+
+// handleGetFile returns a file's metadata
+// GET /v1/files/{file_id}
+func (g *Gateway) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.filesTenant(w, r, false)
+	if !ok {
+		return
+	}
+	if file, ok := g.tenantFile(w, r, tenantID); ok {
+		g.writeJSON(w, http.StatusOK, file)
+	}
+}
+
+// handleGetFileContent returns a file's contents
+// GET /v1/files/{file_id}/content
+func (g *Gateway) handleGetFileContent(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.filesTenant(w, r, false)
+	if !ok {
+		return
+	}
+	file, ok := g.tenantFile(w, r, tenantID)
+	if !ok {
+		return
+	}
+
+	data, err := r2.NewObjects(g.Files).Get(r.Context(), file.objectKey)
+	if err != nil {
+		g.logger.Error("failed to read file content",
+			zap.Error(err),
+			zap.String("tenant_id", tenantID.String()),
+			zap.String("file_id", file.ID),
+		)
+		g.writeError(w, http.StatusInternalServerError, "failed to read file content")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// handleDeleteFile deletes a file and its contents, freeing its quota
+// DELETE /v1/files/{file_id}
+func (g *Gateway) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.filesTenant(w, r, true)
+	if !ok {
+		return
+	}
+	fileID := chi.URLParam(r, "file_id")
+
+	var key string
+	err := g.db.Pool.QueryRow(r.Context(), `
+		UPDATE files SET deleted_at = NOW()
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+		RETURNING object_key
+	`, fileID, tenantID).Scan(&key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to delete file", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to delete file")
+		return
+	}
+
+	if err := r2.NewObjects(g.Files).Delete(r.Context(), key); err != nil {
+		g.logger.Warn("failed to remove deleted file content", zap.Error(err), zap.String("key", key))
+	}
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      fileID,
+		"object":  "file",
+		"deleted": true,
+	})
+}
@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestValidateFileUpload(t *testing.T) {
+	if err := validateFileUpload("requests.jsonl", "application/octet-stream"); err != nil {
+		t.Errorf("octet-stream .jsonl upload rejected: %v", err)
+	}
+	for _, tc := range []struct{ filename, contentType string }{
+		{"requests.csv", "application/jsonl"},
+		{"requests.jsonl", "text/html"},
+		{"", "application/jsonl"},
+	} {
+		if err := validateFileUpload(tc.filename, tc.contentType); err == nil {
+			t.Errorf("validateFileUpload(%q, %q) accepted", tc.filename, tc.contentType)
+		}
+	}
+}
+
+func TestValidateFileContent(t *testing.T) {
+	batch := `{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"llama"}}
+{"custom_id":"b","method":"POST","url":"/v1/embeddings","body":{"input":"hi"}}
+`
+	if lines, err := validateFileContent(filePurposeBatch, []byte(batch)); err != nil || lines != 2 {
+		t.Errorf("valid batch file: %d lines, %v", lines, err)
+	}
+
+	fineTune := `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}
+{"prompt":"2+2=","completion":"4"}`
+	if _, err := validateFileContent(filePurposeFineTune, []byte(fineTune)); err != nil {
+		t.Errorf("valid fine-tune file: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name, purpose, content, want string
+	}{
+		{"empty", filePurposeBatch, "\n\n", "no JSON lines"},
+		{"not an object", filePurposeBatch, `[1,2]`, "line 1: each line must be a JSON object"},
+		{"duplicate custom_id", filePurposeBatch, `{"custom_id":"a","method":"POST","url":"/v1/completions","body":{}}` + "\n" + `{"custom_id":"a","method":"POST","url":"/v1/completions","body":{}}`, `line 2: custom_id "a" is used more than once`},
+		{"unsupported url", filePurposeBatch, `{"custom_id":"a","method":"POST","url":"/v1/files","body":{}}`, "not supported in batches"},
+		{"GET", filePurposeBatch, `{"custom_id":"a","method":"GET","url":"/v1/completions","body":{}}`, `method must be "POST"`},
+		{"no messages", filePurposeFineTune, `{"text":"hi"}`, "needs messages"},
+		{"message without role", filePurposeFineTune, `{"messages":[{"content":"hi"}]}`, "needs a role"},
+		{"not UTF-8", filePurposeFineTune, "{\"prompt\":\"\xff\"}", "UTF-8"},
+	} {
+		_, err := validateFileContent(tc.purpose, []byte(tc.content))
+		var contentErr *fileContentError
+		if !errors.As(err, &contentErr) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: error %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestParseFileForm(t *testing.T) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("purpose", "batch")
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="input.jsonl"`)
+	header.Set("Content-Type", "application/jsonl")
+	part, _ := form.CreatePart(header)
+	part.Write([]byte(`{"custom_id":"a"}`))
+	form.Close()
+
+	purpose, filename, data, err := parseFileForm(form.FormDataContentType(), &body)
+	if err != nil || purpose != "batch" || filename != "input.jsonl" || string(data) != `{"custom_id":"a"}` {
+		t.Errorf("parseFileForm = %q, %q, %q, %v", purpose, filename, data, err)
+	}
+
+	if _, _, _, err := parseFileForm("application/json", strings.NewReader("{}")); err == nil {
+		t.Error("non-multipart upload accepted")
+	}
+}
+
+func TestOrderUploadParts(t *testing.T) {
+	parts := map[string]*UploadPart{
+		"part_a": {ID: "part_a", bytes: 10},
+		"part_b": {ID: "part_b", bytes: 5},
+	}
+
+	ordered, err := orderUploadParts([]string{"part_b", "part_a"}, parts, 15)
+	if err != nil || len(ordered) != 2 || ordered[0].ID != "part_b" {
+		t.Fatalf("orderUploadParts = %v, %v", ordered, err)
+	}
+
+	for _, tc := range []struct {
+		ids      []string
+		declared int64
+	}{
+		{nil, 15},
+		{[]string{"part_a", "part_a"}, 20},
+		{[]string{"part_a", "part_c"}, 15},
+		{[]string{"part_a"}, 15},
+	} {
+		if _, err := orderUploadParts(tc.ids, parts, tc.declared); err == nil {
+			t.Errorf("orderUploadParts(%v, %d) accepted", tc.ids, tc.declared)
+		}
+	}
+}
+
+func TestCreateUploadRequestValidate(t *testing.T) {
+	req := CreateUploadRequest{Filename: "train.jsonl", Purpose: "fine-tune", Bytes: 1024, MimeType: "application/jsonl"}
+	if err := req.Validate(); err != nil {
+		t.Errorf("valid upload rejected: %v", err)
+	}
+
+	tooBig := req
+	tooBig.Bytes = filesMaxBytes + 1
+	octetStream := req
+	octetStream.MimeType = "application/octet-stream"
+	badPurpose := req
+	badPurpose.Purpose = "assistants"
+	for _, bad := range []CreateUploadRequest{tooBig, octetStream, badPurpose} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("upload %+v accepted", bad)
+		}
+	}
+}
@@ -3,25 +3,35 @@ package gateway
 import (
 	"bytes"
 	"context"
-	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/crosslogic/control-plane/internal/billing"
 	"github.com/crosslogic/control-plane/internal/credentials"
+	"github.com/crosslogic/control-plane/internal/features"
+	"github.com/crosslogic/control-plane/internal/jobs"
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/crosslogic/control-plane/internal/notifications"
 	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/crosslogic/control-plane/internal/repository"
 	"github.com/crosslogic/control-plane/pkg/cache"
 	"github.com/crosslogic/control-plane/pkg/database"
 	"github.com/crosslogic/control-plane/pkg/events"
 	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/crosslogic/control-plane/pkg/r2"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
@@ -43,15 +53,114 @@ type Gateway struct {
 	credentialService *credentials.Service
 	// LoadBalancer handles intelligent request routing
 	LoadBalancer *IntelligentLoadBalancer
+	// CatalogSyncer syncs instance types and region availability (optional)
+	CatalogSyncer *orchestrator.CatalogSyncer
+	// modelLifecycle caches model deprecation state for the inference path
+	modelLifecycle *modelLifecycleCache
+	// modelAliases caches alias -> target model resolutions
+	modelAliases *modelAliasCache
+	// modelCatalog caches the /v1/models listing in memory and Redis
+	modelCatalog *readThroughCache
+	// modelBreakers sheds traffic for models over their fleet-wide error budget
+	modelBreakers *modelBreakerSet
+	// modelCapabilities caches per-model feature flags such as guided decoding
+	modelCapabilities *modelCapabilitiesCache
+	// modelLicenses caches the license gating each model
+	modelLicenses *modelLicenseCache
+	// features gates new capabilities per tenant
+	features *features.Service
+	// nodeRegistry writes node rows for self-registration and tenant instances
+	nodeRegistry *nodes.Registry
+	// launches records the cold start checkpoints the gateway observes
+	launches    *nodes.LaunchTracker
+	firstTokens *firstTokenChecks
+	// queuedRequests tracks unanswered non-streaming requests so a drain
+	// can move the ones still queued to another node
+	queuedRequests *queuedRequests
+	// store provides typed queries for the admin and tenant APIs
+	store *repository.Store
+	// jobs runs background work such as usage inserts and node launches
+	jobs *jobs.Queue
+	// adminGuard rate limits admin requests and locks out token guessing
+	adminGuard *adminAuthGuard
+	// trustedProxies are the load balancers whose X-Forwarded-For is believed
+	trustedProxies []*net.IPNet
+	// streamsDraining is closed on shutdown so SSE streams end with a resume point
+	streamsDraining chan struct{}
+	drainOnce       sync.Once
+	// adminEventStreams counts open admin event streams
+	adminEventStreams atomic.Int32
+	// Plans defines plan limits and their Stripe prices
+	Plans *billing.PlanCatalog
+	// Subscriptions changes tenant Stripe subscriptions (nil when billing is disabled)
+	Subscriptions billing.SubscriptionManager
+	// SigningSecrets encrypts tenant request signing secrets (nil disables request signing)
+	SigningSecrets *credentials.EncryptionService
+	// CrashBundles presigns node crash bundle uploads to R2 (nil keeps reports without bundles)
+	CrashBundles *r2.Presigner
+	// AccountExports stores tenant account export archives in R2 (nil disables exports)
+	AccountExports *r2.Presigner
+	// UsageExports stores tenant usage record extracts in R2 (nil disables usage exports)
+	UsageExports *r2.Presigner
+	// Files stores tenant files for batch and fine-tuning inputs in R2 (nil disables the Files API)
+	Files *r2.Presigner
+	// ConfigSnapshots stores platform configuration snapshots in R2 (nil disables snapshots)
+	ConfigSnapshots *r2.Presigner
+	// nodeDiscovery issues node registry tokens (nil disables the registry)
+	nodeDiscovery *nodeDiscovery
+	// promptCounts holds this replica's unsaved prompt variant outcomes
+	promptCounts promptVariantCounts
+	// killSwitches are the active emergency halts; killSwitchSingleAdmin
+	// lets one admin activate a switch without a second confirming it
+	killSwitches          killSwitchSet
+	killSwitchSingleAdmin bool
+
+	// NodeLogArchive serves node logs Redis no longer holds (nil serves Redis only)
+	NodeLogArchive *orchestrator.NodeLogArchive
+	// BillingSandbox runs per-tenant billing simulations (nil disables the sandbox endpoints)
+	BillingSandbox *billing.SandboxRunner
+	// StripeReconciler cross-checks tenants against Stripe (nil disables the reconciliation endpoints)
+	StripeReconciler *billing.StripeReconciler
+	// Budgets enforces tenant spend caps (nil disables enforcement and the budget endpoints)
+	Budgets *billing.Budgets
+	// UsageAlerts stores tenant usage alert rules (nil disables the alert endpoints)
+	UsageAlerts *notifications.UsageAlerts
+	// QualitySamples stores anonymized samples for opted-in tenants (nil disables quality sampling)
+	QualitySamples *QualitySampler
+	// CompatTester runs vLLM compatibility runs (nil disables the runs and
+	// the gate); vllmCompatUngated lets deployments pin untested releases
+	CompatTester      *orchestrator.CompatTester
+	vllmCompatUngated bool
+	// Playground serves anonymous completions for the try-it-now page (nil disables the playground)
+	Playground *Playground
+	// apiV1Sunset is when deprecated v1 routes stop being served (zero when unannounced)
+	apiV1Sunset time.Time
+	// compression configures gzip compression of non-streaming responses
+	compression compressionSettings
+	// nodeClient is shared by all requests proxied to nodes so their
+	// connections are pooled; nodePool configures it
+	nodeClient *http.Client
+	nodePool   nodePoolSettings
+	// nodeHeaders decides the headers sent to nodes
+	nodeHeaders nodeHeaderPolicy
+	// degraded caps max_tokens of low-priority requests while the fleet is
+	// saturated (nil disables it)
+	degraded *degradedMode
+	// audioMaxFileBytes is the largest audio file accepted for transcription
+	audioMaxFileBytes int64
+	// images bounds image generation requests
+	images imageLimits
 }
 
 // NewGateway creates a new API gateway
 func NewGateway(db *database.Database, cache *cache.Cache, logger *zap.Logger, webhookHandler *billing.WebhookHandler, orch *orchestrator.SkyPilotOrchestrator, monitor *orchestrator.TripleSafetyMonitor, adminToken string, eventBus *events.Bus, credentialService *credentials.Service) *Gateway {
+	jobQueue := jobs.NewQueue(db, logger)
+
 	g := &Gateway{
 		db:                db,
 		cache:             cache,
 		logger:            logger,
-		authenticator:     NewAuthenticator(db, cache, logger),
+		authenticator:     NewAuthenticator(db, cache, jobQueue, logger),
 		rateLimiter:       NewRateLimiter(cache, logger),
 		router:            chi.NewRouter(),
 		webhookHandler:    webhookHandler,
@@ -61,8 +170,34 @@ func NewGateway(db *database.Database, cache *cache.Cache, logger *zap.Logger, w
 		eventBus:          eventBus,
 		credentialService: credentialService,
 		LoadBalancer:      NewIntelligentLoadBalancer(db, logger),
-	}
-
+		modelLifecycle:    newModelLifecycleCache(),
+		modelAliases:      newModelAliasCache(),
+		modelCatalog:      newReadThroughCache("model_catalog", modelCatalogCacheKey, defaultModelCatalogCacheTTL, cache, logger),
+		modelBreakers:     newModelBreakerSet(),
+		modelCapabilities: newModelCapabilitiesCache(),
+		modelLicenses:     newModelLicenseCache(),
+		features:          features.NewService(db, cache, logger),
+		nodeRegistry:      nodes.NewRegistry(db),
+		launches:          nodes.NewLaunchTracker(db),
+		firstTokens:       newFirstTokenChecks(),
+		queuedRequests:    newQueuedRequests(),
+		store:             repository.NewStore(db.Pool),
+		jobs:              jobQueue,
+		adminGuard:        newAdminAuthGuard(cache),
+		streamsDraining:   make(chan struct{}),
+		Plans:             billing.NewPlanCatalog(nil),
+		compression:       defaultCompressionSettings(),
+		nodeHeaders:       defaultNodeHeaderPolicy(),
+		audioMaxFileBytes: defaultAudioMaxFileBytes,
+		images:            defaultImageLimits(),
+		nodeClient:        newNodeClient(defaultNodePoolSettings()),
+		nodePool:          defaultNodePoolSettings(),
+	}
+
+	g.registerJobs()
+	g.subscribeCacheInvalidation()
+	g.subscribeNodePrewarm()
+	g.subscribeIncidents()
 	g.setupRoutes()
 	return g
 }
@@ -79,19 +214,20 @@ func (g *Gateway) setupRoutes() {
 
 	// Standard middleware
 	g.router.Use(middleware.RequestID)
-	g.router.Use(middleware.RealIP)
+	g.router.Use(g.realIP) // Client address, from X-Forwarded-For of trusted proxies only
 	g.router.Use(g.requestIDResponseMiddleware) // Add request ID to responses
 	g.router.Use(g.loggerMiddleware)
 	g.router.Use(g.metricsMiddleware) // Add metrics middleware
 	g.router.Use(middleware.Recoverer)
-	g.router.Use(middleware.Timeout(60 * time.Second))
+	g.router.Use(defaultTimeout(60 * time.Second)) // Lifted for inference, which has deadlines of its own
+	g.router.Use(g.compressionMiddleware) // gzip non-streaming responses
 
 	// CORS - Updated with rate limit headers exposed
 	g.router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "https://*.crosslogic.ai"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Admin-Token"},
-		ExposedHeaders:   []string{"Link", "X-Request-ID", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Admin-Token", "X-Priority", "OpenAI-Organization", "OpenAI-Project", "X-Api-Key", "Anthropic-Version", "Anthropic-Beta", "Last-Event-ID", "X-Captcha-Token", "X-Request-Timeout", "If-None-Match"},
+		ExposedHeaders:   []string{"API-Version", "Link", "Deprecation", "Sunset", "X-Model-Alias", "X-Resolved-Model", "X-Queue-Depth", "X-Estimated-Wait-Ms", "X-Max-Tokens-Capped", "X-Request-ID", "OpenAI-Organization", "OpenAI-Project", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", "X-Request-Timeout", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -104,6 +240,13 @@ func (g *Gateway) setupRoutes() {
 	g.router.Get("/health", g.handleHealth)
 	g.router.Get("/ready", g.handleReady)
 
+	// Platform status and scheduled maintenance
+	g.router.Get("/status", g.handleStatus)
+
+	// Anonymous playground for the try-it-now page (captcha and per-IP limits)
+	g.router.Get("/playground", g.handlePlaygroundInfo)
+	g.router.Post("/playground/chat/completions", g.handlePlaygroundChat)
+
 	// API documentation
 	g.router.Get("/api-docs", g.handleSwaggerUI)
 	g.router.Get("/api/v1/admin/openapi.yaml", g.handleOpenAPISpec)
@@ -117,6 +260,9 @@ func (g *Gateway) setupRoutes() {
 		})
 	}
 
+	// Node registry (discovery token auth)
+	g.router.Get("/registry/discovery", g.handleNodeDiscovery)
+
 	// === PLATFORM ADMIN APIs (X-Admin-Token auth) ===
 	g.router.Group(func(r chi.Router) {
 		r.Use(g.adminAuthMiddleware)
@@ -125,37 +271,136 @@ func (g *Gateway) setupRoutes() {
 		r.Get("/api/v1/admin/models", g.HandleListModels)
 		r.Post("/api/v1/admin/models", g.HandleCreateModel)
 		r.Get("/api/v1/admin/models/search", g.HandleSearchModels)
+		r.Get("/api/v1/admin/models/circuit-breakers", g.HandleListModelBreakers)
+		r.Patch("/api/v1/admin/models/prices", g.HandleUpdateModelPrices)
 		r.Get("/api/v1/admin/models/{id}", g.HandleGetModel)
 		r.Put("/api/v1/admin/models/{id}", g.HandleUpdateModel)
 		r.Patch("/api/v1/admin/models/{id}", g.HandlePatchModel)
 		r.Delete("/api/v1/admin/models/{id}", g.HandleDeleteModel)
+		r.Post("/api/v1/admin/models/{id}/deprecate", g.HandleDeprecateModel)
+		r.Get("/api/v1/admin/models/{id}/sampling-defaults", g.HandleGetSamplingDefaults)
+		r.Put("/api/v1/admin/models/{id}/sampling-defaults", g.HandleSetSamplingDefaults)
+		r.Put("/api/v1/admin/models/{id}/license", g.HandleSetModelLicense)
+		r.Get("/api/v1/admin/models/{id}/benchmarks", g.HandleListModelBenchmarks)
+		r.Put("/api/v1/admin/models/{id}/benchmarks/{gpu_type}", g.HandleSetModelBenchmark)
+
+		// Admin - Configuration snapshots
+		r.Get("/api/v1/admin/config-snapshots", g.HandleListConfigSnapshots)
+		r.Post("/api/v1/admin/config-snapshots", g.HandleCreateConfigSnapshot)
+		r.Post("/api/v1/admin/config-snapshots/restore", g.HandleRestoreConfigSnapshot)
+		r.Get("/api/v1/admin/config-snapshots/{id}/download", g.HandleDownloadConfigSnapshot)
+
+		// Admin - Model Licenses
+		r.Get("/api/v1/admin/licenses", g.HandleListModelLicenses)
+		r.Post("/api/v1/admin/licenses", g.HandleCreateModelLicense)
+		r.Get("/api/v1/admin/licenses/{id}/acceptances", g.HandleListLicenseAcceptances)
+
+		// Admin - Model Aliases
+		r.Get("/api/v1/admin/model-aliases", g.HandleListModelAliases)
+		r.Post("/api/v1/admin/model-aliases", g.HandleSaveModelAlias)
+		r.Put("/api/v1/admin/model-aliases/{alias}", g.HandleUpdateModelAlias)
+		r.Delete("/api/v1/admin/model-aliases/{alias}", g.HandleDeleteModelAlias)
+
+		// Admin - Feature flags
+		r.Get("/api/v1/admin/feature-flags", g.handleListFeatureFlags)
+		r.Post("/api/v1/admin/feature-flags", g.handleCreateFeatureFlag)
+		r.Get("/api/v1/admin/feature-flags/{key}", g.handleGetFeatureFlag)
+		r.Put("/api/v1/admin/feature-flags/{key}", g.handleUpdateFeatureFlag)
+		r.Delete("/api/v1/admin/feature-flags/{key}", g.handleDeleteFeatureFlag)
+		r.Put("/api/v1/admin/feature-flags/{key}/overrides/{tenant_id}", g.handleSetFeatureFlagOverride)
+		r.Delete("/api/v1/admin/feature-flags/{key}/overrides/{tenant_id}", g.handleDeleteFeatureFlagOverride)
 
 		// Admin - Nodes
 		r.Get("/admin/nodes", g.handleListNodes)
 		r.Post("/admin/nodes/launch", g.handleLaunchNode)
+		r.Post("/admin/nodes/preflight", g.handleLaunchPreflight)
+		r.Get("/admin/nodes/launch-queue", g.handleGetLaunchQueue)
+		r.Get("/admin/nodes/compliance", g.handleListNodeCompliance)
 		r.Post("/admin/nodes/register", g.handleRegisterNode)
 		r.Get("/admin/nodes/{cluster_name}", g.handleNodeStatus)
 		r.Post("/admin/nodes/{cluster_name}/terminate", g.handleTerminateNode)
 		r.Get("/admin/nodes/{cluster_name}/status", g.handleNodeStatus)
+		r.Post("/admin/nodes/{cluster_name}/exec", g.handleExecNodeCommand)
+		r.Post("/admin/nodes/{cluster_name}/exec/stream", g.handleStreamNodeCommand)
+		r.Get("/admin/exec-audit", g.handleListExecAudit)
+		r.Get("/admin/events/stream", g.handleAdminEventStream)
 		r.Post("/admin/nodes/{node_id}/heartbeat", g.handleHeartbeat)
 		r.Post("/admin/nodes/{node_id}/drain", g.handleDrainNode)
 		r.Post("/admin/nodes/{node_id}/termination-warning", g.handleTerminationWarning)
+		r.Post("/admin/nodes/{node_id}/discovery-token", g.handleIssueDiscoveryToken)
 
 		// Admin - Node Logs (Real-time streaming)
 		r.Get("/admin/nodes/{id}/logs", g.handleGetNodeLogs)
 		r.Get("/admin/nodes/{id}/logs/stream", g.handleStreamNodeLogs)
+		r.Get("/admin/nodes/{id}/recent-requests", g.handleGetNodeRecentRequests)
+		r.Get("/admin/nodes/{id}/launch-timings", g.handleGetNodeLaunchTiming)
+		r.Get("/admin/nodes/{id}/timeline", g.handleGetNodeTimeline)
+		r.Post("/admin/nodes/{id}/crash-reports", g.handleCreateCrashReport)
+		r.Get("/admin/nodes/{id}/crash-reports", g.handleListCrashReports)
+		r.Get("/admin/nodes/{id}/crash-reports/{report_id}", g.handleGetCrashReport)
+		r.Post("/admin/nodes/{id}/crash-reports/{report_id}/uploaded", g.handleCrashBundleUploaded)
 
 		// Admin - Deployments
 		r.Post("/admin/deployments", g.handleCreateDeployment)
 		r.Get("/admin/deployments", g.handleListDeployments)
 		r.Get("/admin/deployments/{id}", g.handleGetDeployment)
 		r.Put("/admin/deployments/{id}/scale", g.handleScaleDeployment)
+		r.Put("/admin/deployments/{id}/warm-standby", g.handleSetWarmStandby)
+		r.Put("/admin/deployments/{id}/stopped-nodes", g.handleSetKeepStoppedNodes)
+		r.Put("/admin/deployments/{id}/autoscaling", g.handleSetDeploymentAutoscaling)
+		r.Put("/admin/deployments/{id}/runtime", g.handleSetDeploymentRuntime)
 		r.Delete("/admin/deployments/{id}", g.handleDeleteDeployment)
+		r.Post("/admin/runtime/compat-runs", g.handleCreateCompatRun)
+		r.Get("/admin/runtime/compat-runs", g.handleListCompatRuns)
+		r.Get("/admin/runtime/compat-runs/{id}", g.handleGetCompatRun)
+		r.Get("/admin/runtime/compat-gate", g.handleGetCompatGate)
+
+		// Admin - Admin tokens
+		r.Get("/admin/tokens", g.handleListAdminTokens)
+		r.Post("/admin/tokens", g.handleCreateAdminToken)
+		r.Delete("/admin/tokens/{id}", g.handleRevokeAdminToken)
+
+		// Admin - Access reviews (SOC 2 evidence)
+		r.Get("/admin/access-review", g.handleGetAccessReview)
+
+		// Admin - Maintenance windows
+		r.Get("/admin/maintenance", g.handleListMaintenanceWindows)
+		r.Post("/admin/maintenance", g.handleCreateMaintenanceWindow)
+		r.Get("/admin/maintenance/{id}", g.handleGetMaintenanceWindow)
+		r.Post("/admin/maintenance/{id}/cancel", g.handleCancelMaintenanceWindow)
+
+		// Admin - Background jobs
+		r.Get("/admin/jobs", g.handleListJobs)
+		r.Post("/admin/jobs/{id}/retry", g.handleRetryJob)
+		r.Post("/admin/jobs/{id}/discard", g.handleDiscardJob)
 
 		// Admin - Routing
 		r.Get("/admin/routes", g.handleListRoutes)
 		r.Get("/admin/routes/{model_id}", g.handleGetRoute)
 		r.Put("/admin/routes/{model_id}", g.handleUpdateRoute)
+		r.Get("/admin/routing/state", g.handleGetRoutingState)
+		r.Post("/admin/routing/pin", g.handlePinEndpoint)
+		r.Post("/admin/routing/unpin", g.handleUnpinEndpoint)
+		r.Post("/admin/routing/weight", g.handleWeightEndpoint)
+		r.Post("/admin/routing/experiments", g.handleCreateRoutingExperiment)
+		r.Get("/admin/routing/experiments", g.handleListRoutingExperiments)
+		r.Get("/admin/routing/experiments/{id}", g.handleGetRoutingExperiment)
+		r.Post("/admin/routing/experiments/{id}/stop", g.handleStopRoutingExperiment)
+		r.Get("/admin/routing/egress", g.handleGetEgressRouting)
+		r.Put("/admin/routing/egress/routes", g.handlePutEgressRoute)
+		r.Delete("/admin/routing/egress/routes/{provider}/{source}/{dest}", g.handleDeleteEgressRoute)
+		r.Put("/admin/routing/egress/weights/{plan}", g.handlePutEgressWeights)
+		r.Post("/admin/kill-switches", g.handleCreateKillSwitch)
+		r.Get("/admin/kill-switches", g.handleListKillSwitches)
+		r.Get("/admin/kill-switches/{id}", g.handleGetKillSwitch)
+		r.Post("/admin/kill-switches/{id}/confirm", g.handleConfirmKillSwitch)
+		r.Post("/admin/kill-switches/{id}/cancel", g.handleCancelKillSwitch)
+		r.Post("/admin/kill-switches/{id}/release", g.handleReleaseKillSwitch)
+
+		// Admin - API key abuse review
+		r.Get("/admin/abuse/incidents", g.handleListAbuseIncidents)
+		r.Post("/admin/abuse/incidents/{id}/release", g.handleReleaseAbuseIncident)
+		r.Post("/admin/abuse/incidents/{id}/confirm", g.handleConfirmAbuseIncident)
 
 		// Admin - Tenants
 		r.Post("/admin/tenants", g.handleCreateTenant)
@@ -164,16 +409,49 @@ func (g *Gateway) setupRoutes() {
 		r.Get("/admin/tenants/{tenant_id}", g.handleGetTenant)
 		r.Put("/admin/tenants/{id}", g.handleUpdateTenant)
 		r.Get("/admin/tenants/{id}/usage", g.handleGetTenantUsageAdmin)
+		r.Post("/admin/tenants/{id}/usage/export", g.handleCreateTenantUsageExport)
+		r.Get("/admin/tenants/{id}/usage/export", g.handleListTenantUsageExports)
+		r.Get("/admin/tenants/{id}/usage/export/{export_id}", g.handleGetTenantUsageExport)
+
+		// Billing sandbox (accelerated invoice cycles against Stripe test mode)
+		r.Get("/admin/tenants/{id}/billing-sandbox", g.handleGetBillingSandbox)
+		r.Put("/admin/tenants/{id}/billing-sandbox", g.handleEnableBillingSandbox)
+		r.Delete("/admin/tenants/{id}/billing-sandbox", g.handleDisableBillingSandbox)
+		r.Post("/admin/tenants/{id}/billing-sandbox/usage", g.handleRecordSandboxUsage)
+		r.Post("/admin/tenants/{id}/billing-sandbox/advance", g.handleAdvanceBillingSandbox)
+		r.Get("/admin/tenants/{id}/billing-sandbox/invoices", g.handleListSandboxInvoices)
+
+		// Stripe reconciliation (tenants vs. Stripe customers and subscriptions)
+		r.Post("/admin/billing/reconciliations", g.handleCreateStripeReconciliation)
+		r.Get("/admin/billing/reconciliations", g.handleListStripeReconciliations)
+		r.Get("/admin/billing/reconciliations/{id}", g.handleGetStripeReconciliation)
+
+		// Spend budgets (daily and monthly caps enforced on inference)
+		r.Get("/admin/tenants/{id}/budgets", g.handleListTenantBudgetsAdmin)
+		r.Post("/admin/tenants/{id}/budgets", g.handleCreateTenantBudget)
+		r.Put("/admin/tenants/{id}/budgets/{budget_id}", g.handleUpdateTenantBudget)
+		r.Delete("/admin/tenants/{id}/budgets/{budget_id}", g.handleDeleteTenantBudget)
+
+		// Admin - Quality samples (anonymized prompt/response pairs for evaluation)
+		r.Get("/admin/quality-samples", g.handleDownloadQualitySamples)
 
 		// Admin - Platform
 		r.Get("/admin/platform/health", g.handlePlatformHealth)
 		r.Get("/admin/platform/metrics", g.handlePlatformMetrics)
+		r.Get("/admin/platform/launch-metrics", g.handleLaunchMetrics)
 
 		// Admin - API Keys (admin view - all keys for a tenant)
 		r.Get("/admin/api-keys/{tenant_id}", g.handleListAPIKeys)
 		r.Post("/admin/api-keys", g.handleCreateAPIKey)
 		r.Delete("/admin/api-keys/{key_id}", g.handleRevokeAPIKey)
 
+		// Admin - Rate limit templates (limits for environments by name and their keys)
+		r.Get("/admin/rate-limit-templates", g.handleListRateLimitTemplates)
+		r.Post("/admin/rate-limit-templates", g.handleCreateRateLimitTemplate)
+		r.Put("/admin/rate-limit-templates/{id}", g.handleUpdateRateLimitTemplate)
+		r.Delete("/admin/rate-limit-templates/{id}", g.handleDeleteRateLimitTemplate)
+		r.Post("/admin/rate-limit-templates/{id}/apply", g.handleApplyRateLimitTemplate)
+
 		// Admin - Credentials
 		r.Post("/admin/credentials", g.handleCreateCredential)
 		r.Get("/admin/credentials", g.handleListCredentials)
@@ -185,6 +463,7 @@ func (g *Gateway) setupRoutes() {
 
 		// Admin - Model/Instance management (UI-driven, legacy)
 		r.Get("/admin/models/r2", g.ListR2ModelsHandler)
+		r.Post("/admin/models/{id}/prewarm", g.HandleCreateModelPrewarm)
 		r.Post("/admin/instances/launch", g.LaunchModelInstanceHandler)
 		r.Get("/admin/instances/status", g.GetLaunchStatusHandler)
 		r.Get("/admin/regions", g.ListRegionsHandler)
@@ -195,60 +474,159 @@ func (g *Gateway) setupRoutes() {
 	})
 
 	// === TENANT (CUSTOMER) APIs (Bearer token auth) ===
-	g.router.Group(func(r chi.Router) {
-		r.Use(g.authMiddleware)
-		r.Use(g.rateLimitMiddleware)
-
-		// Tenant - API Keys (self-service)
-		r.Post("/v1/api-keys", g.handleCreateTenantAPIKey)
-		r.Get("/v1/api-keys", g.handleListTenantAPIKeys)
-		r.Delete("/v1/api-keys/{key_id}", g.handleRevokeTenantAPIKey)
-
-		// Tenant - Endpoints (discovery)
-		r.Get("/v1/endpoints", g.handleListTenantEndpoints)
-		r.Get("/v1/endpoints/{model_id}", g.handleGetTenantEndpoint)
-
-		// Tenant - Inference (OpenAI-compatible)
-		r.Post("/v1/chat/completions", g.handleChatCompletions)
-		r.Post("/v1/completions", g.handleCompletions)
-		r.Post("/v1/embeddings", g.handleEmbeddings)
-		r.Get("/v1/models", g.handleListModels)
-		r.Get("/v1/models/{model}", g.handleGetModel)
-
-		// Tenant - Usage & Billing
-		r.Get("/v1/usage", g.handleGetUsage)
-		r.Get("/v1/usage/by-model", g.handleGetUsageByModel)
-		r.Get("/v1/usage/by-key", g.handleGetUsageByKey)
-		r.Get("/v1/usage/by-date", g.handleGetUsageByDate)
-
-		// Tenant - Metrics
-		r.Get("/v1/metrics/latency", g.handleGetLatencyMetrics)
-		r.Get("/v1/metrics/tokens", g.handleGetTokenMetrics)
-
-		// === SELF-SERVICE FEATURES (PRO & ENTERPRISE ONLY) ===
-		r.Group(func(proRouter chi.Router) {
-			proRouter.Use(g.RequireProOrEnterprise)
-
-			// Tenant - Cloud Credentials (self-service)
-			proRouter.Post("/v1/credentials", g.handleCreateTenantCredential)
-			proRouter.Get("/v1/credentials", g.handleListTenantCredentials)
-			proRouter.Get("/v1/credentials/{id}", g.handleGetTenantCredential)
-			proRouter.Put("/v1/credentials/{id}", g.handleUpdateTenantCredential)
-			proRouter.Delete("/v1/credentials/{id}", g.handleDeleteTenantCredential)
-			proRouter.Post("/v1/credentials/{id}/validate", g.handleValidateTenantCredential)
-			proRouter.Post("/v1/credentials/{id}/default", g.handleSetDefaultTenantCredential)
-
-			// Tenant - vLLM Instances (self-service)
-			proRouter.Post("/v1/instances", g.handleLaunchTenantInstance)
-			proRouter.Get("/v1/instances", g.handleListTenantInstances)
-			proRouter.Get("/v1/instances/{id}", g.handleGetTenantInstance)
-			proRouter.Delete("/v1/instances/{id}", g.handleTerminateTenantInstance)
-			proRouter.Get("/v1/instances/{id}/logs/stream", g.handleStreamTenantInstanceLogs)
+	for _, version := range apiVersions {
+		version := version
+		g.router.Route(version.prefix(), func(r chi.Router) {
+			r.Use(g.apiVersionMiddleware(version))
+			r.Use(g.authMiddleware)
+			r.Use(g.openAIHeadersMiddleware)
+			r.Use(g.requestSigningMiddleware)
+			r.Use(g.abuseMiddleware)
+			r.Use(g.rateLimitMiddleware)
+
+			g.setupTenantRoutes(r, version)
 		})
+	}
+}
 
-		// === EXTENDED TENANT ROUTES ===
-		g.setupExtendedTenantRoutes(r)
+// setupTenantRoutes registers the tenant API for one version, relative to
+// the version's namespace
+func (g *Gateway) setupTenantRoutes(r chi.Router, version apiVersion) {
+	// Tenant - API Keys (self-service)
+	r.Post("/api-keys", g.handleCreateTenantAPIKey)
+	r.Get("/api-keys", g.handleListTenantAPIKeys)
+	r.Delete("/api-keys/{key_id}", g.handleRevokeTenantAPIKey)
+	r.Put("/api-keys/{key_id}/budget", g.handlePutKeyBudget)
+	r.Delete("/api-keys/{key_id}/budget", g.handleDeleteKeyBudget)
+
+	// Tenant - Environment budgets shared by the environment's keys
+	r.Get("/environments/{environment_id}/budget", g.handleGetEnvironmentBudget)
+	r.Put("/environments/{environment_id}/budget", g.handlePutEnvironmentBudget)
+	r.Delete("/environments/{environment_id}/budget", g.handleDeleteEnvironmentBudget)
+
+	// Tenant - Endpoints (discovery)
+	r.Get("/endpoints", g.handleListTenantEndpoints)
+	r.Get("/endpoints/{model_id}", g.handleGetTenantEndpoint)
+
+	// Tenant - Inference (OpenAI-compatible), with client-requested deadlines
+	inference := r.With(g.enforceKillSwitches, g.enforceSpendBudgets, g.enforceEnvironmentBudgets, g.requestDeadline)
+	inference.Post("/chat/completions", g.handleChatCompletions)
+	inference.Post("/completions", g.handleCompletions)
+	inference.Post("/embeddings", g.handleEmbeddings)
+	inference.Post("/audio/transcriptions", g.handleAudioTranscriptions)
+	inference.Post("/images/generations", g.handleImageGenerations)
+
+	// Tenant - Inference (Anthropic Messages-compatible)
+	inference.Post("/messages", g.handleAnthropicMessages)
+	r.Get("/models", g.handleListModels)
+	r.Get("/models/{model}", g.handleGetModel)
+	r.Post("/models/{model}/prewarm", g.handleCreateTenantPrewarm)
+
+	// Tenant - Model licenses (gated models)
+	r.Get("/licenses", g.handleListTenantLicenses)
+	r.Post("/licenses/{id}/accept", g.handleAcceptLicense)
+
+	// Tenant - Usage & Billing
+	r.Get("/usage", g.handleGetUsage)
+	r.Get("/usage/by-model", g.handleGetUsageByModel)
+	r.Get("/usage/by-key", g.handleGetUsageByKey)
+	r.Post("/usage/export", g.handleCreateUsageExport)
+	r.Get("/usage/export", g.handleListUsageExports)
+	r.Get("/usage/export/{export_id}", g.handleGetUsageExport)
+	if version == apiV1 {
+		r.With(g.deprecatedRoute(v1UsageByDate)).Get("/usage/by-date", g.handleGetUsageByDate)
+	}
+	r.Get("/reports/savings", g.handleGetSavingsReport)
+	r.Post("/billing/upgrade", g.handleUpgradePlan)
+
+	// Tenant - Account data export
+	r.Get("/account/export", g.handleGetAccountExport)
+	r.Post("/account/export", g.handleCreateAccountExport)
+
+	// Tenant - Files and multipart uploads (batch and fine-tuning inputs)
+	r.Post("/files", g.handleCreateFile)
+	r.Get("/files", g.handleListFiles)
+	r.Get("/files/{file_id}", g.handleGetFile)
+	r.Get("/files/{file_id}/content", g.handleGetFileContent)
+	r.Delete("/files/{file_id}", g.handleDeleteFile)
+	r.Post("/uploads", g.handleCreateUpload)
+	r.Post("/uploads/{upload_id}/parts", g.handleAddUploadPart)
+	r.Post("/uploads/{upload_id}/complete", g.handleCompleteUpload)
+	r.Post("/uploads/{upload_id}/cancel", g.handleCancelUpload)
+
+	// Tenant - Incident history
+	r.Get("/incidents", g.handleListIncidents)
+
+	// Tenant - Usage alert rules
+	r.Get("/alerts", g.handleListUsageAlerts)
+	r.Post("/alerts", g.handleCreateUsageAlert)
+	r.Get("/alerts/{id}", g.handleGetUsageAlert)
+	r.Put("/alerts/{id}", g.handleUpdateUsageAlert)
+	r.Delete("/alerts/{id}", g.handleDeleteUsageAlert)
+
+	// Tenant - Spend budgets (set by admins) with current burn rate
+	r.Get("/budgets", g.handleListBudgets)
+
+	// Tenant - OpenAI organization/project header mapping
+	r.Get("/openai-mapping", g.handleGetOpenAIMapping)
+	r.Put("/openai-mapping/organization", g.handleSetOpenAIOrganization)
+	r.Put("/openai-mapping/projects/{environment_id}", g.handleSetOpenAIProject)
+
+	// Tenant - Request signing
+	r.Get("/security/request-signing", g.handleGetRequestSigning)
+	r.Put("/security/request-signing", g.handleUpdateRequestSigning)
+	r.Delete("/security/request-signing", g.handleDeleteRequestSigning)
+	r.Post("/security/request-signing/rotate", g.handleRotateSigningSecret)
+
+	// Quality sampling opt-in
+	r.Get("/privacy/quality-sampling", g.handleGetQualitySampling)
+	r.Put("/privacy/quality-sampling", g.handleUpdateQualitySampling)
+
+	// Tenant - Output post-processing policies
+	r.Get("/output-policies", g.handleListOutputPolicies)
+	r.Post("/output-policies", g.handleSaveOutputPolicy)
+	r.Post("/output-policies/preview", g.handlePreviewOutputPolicy)
+	r.Delete("/output-policies/{id}", g.handleDeleteOutputPolicy)
+
+	// Tenant - Prompt A/B experiments
+	r.Get("/prompt-experiments", g.handleListPromptExperiments)
+	r.Post("/prompt-experiments", g.handleCreatePromptExperiment)
+	r.Get("/prompt-experiments/{id}", g.handleGetPromptExperiment)
+	r.Post("/prompt-experiments/{id}/stop", g.handleStopPromptExperiment)
+
+	// Tenant - Metrics
+	r.Get("/metrics/latency", g.handleGetLatencyMetrics)
+	r.Get("/metrics/tokens", g.handleGetTokenMetrics)
+
+	// === SELF-SERVICE FEATURES (PRO & ENTERPRISE ONLY) ===
+	r.Group(func(proRouter chi.Router) {
+		proRouter.Use(g.RequireProOrEnterprise)
+
+		// Tenant - Cloud Credentials (self-service)
+		proRouter.Post("/credentials", g.handleCreateTenantCredential)
+		proRouter.Get("/credentials", g.handleListTenantCredentials)
+		proRouter.Get("/credentials/{id}", g.handleGetTenantCredential)
+		proRouter.Put("/credentials/{id}", g.handleUpdateTenantCredential)
+		proRouter.Delete("/credentials/{id}", g.handleDeleteTenantCredential)
+		proRouter.Post("/credentials/{id}/validate", g.handleValidateTenantCredential)
+		proRouter.Post("/credentials/{id}/default", g.handleSetDefaultTenantCredential)
+
+		// Tenant - vLLM Instances (self-service)
+		proRouter.Post("/instances", g.handleLaunchTenantInstance)
+		proRouter.Get("/instances", g.handleListTenantInstances)
+		proRouter.Get("/instances/{id}", g.handleGetTenantInstance)
+		proRouter.Delete("/instances/{id}", g.handleTerminateTenantInstance)
+		proRouter.Get("/instances/{id}/logs/stream", g.handleStreamTenantInstanceLogs)
+
+		// Tenant - Launch Profiles (named NodeConfig defaults)
+		proRouter.Get("/launch-profiles", g.handleListTenantLaunchProfiles)
+		proRouter.Post("/launch-profiles", g.handleSaveTenantLaunchProfile)
+		proRouter.Get("/launch-profiles/{name}", g.handleGetTenantLaunchProfile)
+		proRouter.Delete("/launch-profiles/{name}", g.handleDeleteTenantLaunchProfile)
 	})
+
+	// === EXTENDED TENANT ROUTES ===
+	g.setupExtendedTenantRoutes(r)
 }
 
 func (g *Gateway) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
@@ -260,6 +638,10 @@ func (g *Gateway) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		HealthScore float64 `json:"health_score"`
+		// Runtime is what vLLM is running with, for drift detection
+		Runtime *nodes.RuntimeReport `json:"runtime,omitempty"`
+		// Compliance is the node's security hardening state
+		Compliance *nodes.ComplianceReport `json:"compliance,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		g.writeError(w, http.StatusBadRequest, "invalid request body")
@@ -272,6 +654,22 @@ func (g *Gateway) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A missed runtime report only delays drift detection
+	if req.Runtime != nil {
+		if id, err := uuid.Parse(nodeID); err == nil {
+			if err := g.nodeRegistry.RecordRuntime(r.Context(), id, *req.Runtime); err != nil {
+				g.logger.Warn("failed to record node runtime", zap.Error(err), zap.String("node_id", nodeID))
+			}
+		}
+	}
+	if req.Compliance != nil {
+		if id, err := uuid.Parse(nodeID); err == nil {
+			if err := g.nodeRegistry.RecordCompliance(r.Context(), id, *req.Compliance); err != nil {
+				g.logger.Warn("failed to record node compliance", zap.Error(err), zap.String("node_id", nodeID))
+			}
+		}
+	}
+
 	g.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -285,8 +683,8 @@ func (g *Gateway) handleTerminationWarning(w http.ResponseWriter, r *http.Reques
 	g.logger.Warn("received spot termination warning", zap.String("node_id", nodeID))
 
 	// Mark node as terminating
-	query := `UPDATE nodes SET status = 'terminating', status_message = 'spot_termination_warning' WHERE id = $1`
-	_, err := g.db.Pool.Exec(r.Context(), query, nodeID)
+	query := `UPDATE nodes SET status = 'terminating', status_message = 'spot_termination_warning', status_source = $2 WHERE id = $1`
+	_, err := g.db.Pool.Exec(r.Context(), query, nodeID, nodes.SourceNode)
 	if err != nil {
 		g.logger.Error("failed to update node status", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to process warning")
@@ -306,17 +704,26 @@ func (g *Gateway) handleTerminationWarning(w http.ResponseWriter, r *http.Reques
 
 func (g *Gateway) handleRegisterNode(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		ClusterName   string  `json:"cluster_name"`
-		Provider      string  `json:"provider"`
-		Region        string  `json:"region"`
-		InstanceType  string  `json:"instance_type"`
-		GPUType       string  `json:"gpu_type"`
-		VRAMTotalGB   int     `json:"vram_total_gb"`
-		ModelName     string  `json:"model_name"`
-		EndpointURL   string  `json:"endpoint_url"`
-		InternalIP    string  `json:"internal_ip"`
-		SpotInstance  bool    `json:"spot_instance"`
-		SpotPrice     float64 `json:"spot_price"`
+		NodeID       string   `json:"node_id"`
+		ClusterName  string   `json:"cluster_name"`
+		DeploymentID string   `json:"deployment_id"`
+		Provider     string   `json:"provider"`
+		Region       string   `json:"region"`
+		InstanceType string   `json:"instance_type"`
+		GPUType      string   `json:"gpu_type"`
+		GPUCount     int      `json:"gpu_count"`
+		VRAMTotalGB  int      `json:"vram_total_gb"`
+		ModelName    string   `json:"model_name"`
+		EndpointURL  string   `json:"endpoint_url"`
+		InternalIP   string   `json:"internal_ip"`
+		SpotInstance bool     `json:"spot_instance"`
+		SpotPrice    *float64 `json:"spot_price"`
+		// WorkloadClass is text, audio or image; defaults to the model's
+		WorkloadClass string `json:"workload_class"`
+
+		// Cold start checkpoints from the node's clock
+		SetupStartedAt *time.Time `json:"setup_started_at"`
+		VLLMStartedAt  *time.Time `json:"vllm_started_at"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -324,81 +731,116 @@ func (g *Gateway) handleRegisterNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate required fields
-	if req.ClusterName == "" || req.Provider == "" || req.EndpointURL == "" {
-		g.writeError(w, http.StatusBadRequest, "cluster_name, provider, and endpoint_url are required")
+	// Self-registration always means vLLM is serving
+	reg := nodes.Registration{
+		ClusterName:  req.ClusterName,
+		Provider:     req.Provider,
+		Region:       req.Region,
+		InstanceType: req.InstanceType,
+		GPUType:      req.GPUType,
+		GPUCount:     req.GPUCount,
+		VRAMTotalGB:  req.VRAMTotalGB,
+		ModelName:    req.ModelName,
+		EndpointURL:  req.EndpointURL,
+		InternalIP:   req.InternalIP,
+		SpotInstance: req.SpotInstance,
+		SpotPrice:    req.SpotPrice,
+		Status:       nodes.StatusActive,
+		Source:       nodes.SourceNode,
+
+		WorkloadClass: req.WorkloadClass,
+	}
+	if req.NodeID != "" {
+		id, err := uuid.Parse(req.NodeID)
+		if err != nil {
+			g.writeError(w, http.StatusBadRequest, "invalid node_id")
+			return
+		}
+		reg.ID = id
+	}
+	if req.DeploymentID != "" {
+		id, err := uuid.Parse(req.DeploymentID)
+		if err != nil {
+			g.writeError(w, http.StatusBadRequest, "invalid deployment_id")
+			return
+		}
+		reg.DeploymentID = &id
+	}
+
+	if err := reg.Normalize().Validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	g.logger.Info("registering node",
+		zap.String("node_id", req.NodeID),
 		zap.String("cluster_name", req.ClusterName),
 		zap.String("provider", req.Provider),
 		zap.String("endpoint", req.EndpointURL),
 	)
 
-	// Check if node already exists
-	var existingID string
-	checkQuery := `SELECT id FROM nodes WHERE cluster_name = $1`
-	err := g.db.Pool.QueryRow(r.Context(), checkQuery, req.ClusterName).Scan(&existingID)
-
-	if err == nil {
-		// Node exists, update it
-		updateQuery := `
-			UPDATE nodes SET
-				endpoint_url = $1,
-				internal_ip = $2,
-				status = 'active',
-				health_score = 100.0,
-				last_heartbeat_at = NOW(),
-				updated_at = NOW()
-			WHERE cluster_name = $3
-			RETURNING id
-		`
-		var nodeID string
-		err = g.db.Pool.QueryRow(r.Context(), updateQuery,
-			req.EndpointURL, req.InternalIP, req.ClusterName,
-		).Scan(&nodeID)
+	nodeID, created, err := g.nodeRegistry.Register(r.Context(), reg)
+	if err != nil {
+		g.logger.Error("failed to register node", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to register node")
+		return
+	}
 
-		if err != nil {
-			g.logger.Error("failed to update node", zap.Error(err))
-			g.writeError(w, http.StatusInternalServerError, "failed to update node")
-			return
+	g.recordLaunchHealthy(r.Context(), nodeID, reg.Normalize().EndpointURL, req.SetupStartedAt, req.VLLMStartedAt)
+
+	// Later launches tune vLLM from the reported memory; flag nodes whose
+	// launch assumed a different size
+	if req.VRAMTotalGB > 0 && req.GPUCount > 0 {
+		reported := req.VRAMTotalGB / req.GPUCount
+		if tuned, err := g.nodeRegistry.TunedVRAMPerGPU(r.Context(), nodeID); err == nil && tuned > 0 && tuned != reported {
+			g.logger.Warn("node GPU memory differs from what its vLLM settings were tuned for",
+				zap.String("node_id", nodeID.String()),
+				zap.String("gpu_type", req.GPUType),
+				zap.Int("tuned_vram_per_gpu_gb", tuned),
+				zap.Int("reported_vram_per_gpu_gb", reported),
+			)
 		}
+	}
 
-		g.writeJSON(w, http.StatusOK, map[string]interface{}{
-			"status":  "updated",
-			"node_id": nodeID,
-		})
-		return
+	// Ramp the node into routing instead of sending it full traffic at once
+	if err := g.LoadBalancer.StartCanary(r.Context(), nodeID, reg.Normalize().EndpointURL); err != nil {
+		g.logger.Warn("failed to start node traffic ramp", zap.Error(err), zap.String("node_id", nodeID.String()))
 	}
 
-	// Create new node
-	insertQuery := `
-		INSERT INTO nodes (
-			cluster_name, provider, instance_type, gpu_type, vram_total_gb,
-			model_name, endpoint_url, internal_ip, spot_instance, spot_price,
-			status, health_score, last_heartbeat_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'active', 100.0, NOW())
-		RETURNING id
-	`
+	// Models colocated on the node were started before its agent, each on
+	// its own port; they are routed as nodes of their own
+	colocated, err := g.nodeRegistry.ActivateColocated(r.Context(), nodeID, reg.Normalize().EndpointURL)
+	if err != nil {
+		g.logger.Warn("failed to activate colocated models", zap.Error(err), zap.String("node_id", nodeID.String()))
+	}
+	for _, c := range colocated {
+		if err := g.LoadBalancer.StartCanary(r.Context(), c.ID, c.EndpointURL); err != nil {
+			g.logger.Warn("failed to start node traffic ramp", zap.Error(err), zap.String("node_id", c.ID.String()))
+		}
+	}
 
-	var nodeID string
-	err = g.db.Pool.QueryRow(r.Context(), insertQuery,
-		req.ClusterName, req.Provider, req.InstanceType, req.GPUType, req.VRAMTotalGB,
-		req.ModelName, req.EndpointURL, req.InternalIP, req.SpotInstance, req.SpotPrice,
-	).Scan(&nodeID)
+	if g.eventBus != nil {
+		g.eventBus.Publish(r.Context(), events.NewEvent(events.EventNodeRegistered, "", map[string]interface{}{
+			"node_id":  nodeID.String(),
+			"model":    reg.ModelName,
+			"endpoint": reg.EndpointURL,
+			"created":  created,
+		}))
+	}
 
-	if err != nil {
-		g.logger.Error("failed to register node", zap.Error(err))
-		g.writeError(w, http.StatusInternalServerError, "failed to register node")
+	if !created {
+		g.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status":  "updated",
+			"node_id": nodeID.String(),
+		})
 		return
 	}
 
-	g.logger.Info("node registered successfully", zap.String("node_id", nodeID))
+	g.logger.Info("node registered successfully", zap.String("node_id", nodeID.String()))
 
 	g.writeJSON(w, http.StatusCreated, map[string]interface{}{
 		"status":  "registered",
-		"node_id": nodeID,
+		"node_id": nodeID.String(),
 	})
 }
 
@@ -412,9 +854,11 @@ func (g *Gateway) handleDrainNode(w http.ResponseWriter, r *http.Request) {
 	g.logger.Info("draining node", zap.String("node_id", nodeID))
 
 	// Mark node as draining
-	query := `UPDATE nodes SET status = 'draining', status_message = 'graceful_drain_initiated' WHERE id = $1`
-	_, err := g.db.Pool.Exec(r.Context(), query, nodeID)
-	if err != nil {
+	query := `UPDATE nodes SET status = 'draining', status_message = 'graceful_drain_initiated', status_source = $2 WHERE id = $1
+		RETURNING COALESCE(endpoint_url, '')`
+	var endpoint string
+	err := g.db.Pool.QueryRow(r.Context(), query, nodeID, nodeCallbackSource(r.Context())).Scan(&endpoint)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		g.logger.Error("failed to update node status", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to drain node")
 		return
@@ -427,6 +871,13 @@ func (g *Gateway) handleDrainNode(w http.ResponseWriter, r *http.Request) {
 		}))
 	}
 
+	// Move requests still waiting in the node's queue to other nodes now;
+	// other replicas see the drain when they next poll for it
+	if endpoint != "" {
+		g.queuedRequests.markDraining(endpoint)
+	}
+	g.requeueDrainingRequests(endpoint)
+
 	g.writeJSON(w, http.StatusOK, map[string]string{"status": "draining"})
 }
 
@@ -475,10 +926,15 @@ func (g *Gateway) loggerMiddleware(next http.Handler) http.Handler {
 		start := time.Now()
 
 		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		// Name the request on connections it holds, for long hold warnings
+		r = r.WithContext(database.WithCaller(r.Context(), r.Method+" "+r.URL.Path))
 		next.ServeHTTP(ww, r)
 
 		// Anonymize API key in logs for security
 		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			authHeader = r.Header.Get("X-Api-Key")
+		}
 		anonymizedAuth := ""
 		if authHeader != "" {
 			anonymizedAuth = AnonymizeAPIKey(strings.TrimPrefix(authHeader, "Bearer "))
@@ -509,8 +965,12 @@ func (g *Gateway) requestIDResponseMiddleware(next http.Handler) http.Handler {
 
 func (g *Gateway) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Extract API key from Authorization header
+		// Extract API key from Authorization header, or x-api-key as sent by
+		// Anthropic clients
 		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			authHeader = r.Header.Get("X-Api-Key")
+		}
 		if authHeader == "" {
 			g.writeError(w, http.StatusUnauthorized, "missing authorization header")
 			return
@@ -523,6 +983,15 @@ func (g *Gateway) authMiddleware(next http.Handler) http.Handler {
 		// Validate API key
 		ctx := r.Context()
 		keyInfo, err := g.authenticator.ValidateAPIKey(ctx, apiKey)
+		if errors.Is(err, ErrAPIKeyQuarantined) {
+			g.writeJSON(w, http.StatusForbidden, map[string]interface{}{
+				"error": map[string]string{
+					"message": "This API key has been quarantined after unusual activity. Check your email or contact support to have it reviewed.",
+					"type":    "key_quarantined",
+				},
+			})
+			return
+		}
 		if err != nil {
 			g.logger.Warn("authentication failed", zap.Error(err))
 			g.writeError(w, http.StatusUnauthorized, "invalid API key")
@@ -552,8 +1021,13 @@ func (g *Gateway) rateLimitMiddleware(next http.Handler) http.Handler {
 		// Check rate limits with info for headers
 		allowed, rateLimitInfo, err := g.rateLimiter.CheckRateLimitWithInfo(ctx, keyInfo)
 		if err != nil {
+			if g.cache.FailOpen(cache.FeatureRateLimit) {
+				g.logger.Warn("rate limit check failed, allowing request", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
 			g.logger.Error("rate limit check failed", zap.Error(err))
-			g.writeError(w, http.StatusInternalServerError, "rate limit check failed")
+			g.writeError(w, http.StatusServiceUnavailable, "rate limit check failed")
 			return
 		}
 
@@ -569,16 +1043,16 @@ func (g *Gateway) rateLimitMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		defer func(keyID string) {
+		defer func(key *models.APIKey) {
 			releaseCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 			defer cancel()
-			if err := g.rateLimiter.DecrementConcurrency(releaseCtx, keyID); err != nil {
+			if err := g.rateLimiter.DecrementConcurrency(releaseCtx, key); err != nil {
 				g.logger.Debug("failed to decrement concurrency",
-					zap.String("key_id", keyID),
+					zap.String("key_id", key.ID.String()),
 					zap.Error(err),
 				)
 			}
-		}(keyInfo.ID.String())
+		}(keyInfo)
 
 		next.ServeHTTP(w, r)
 	})
@@ -601,36 +1075,6 @@ func (g *Gateway) writeRateLimitError(w http.ResponseWriter, info *RateLimitInfo
 	})
 }
 
-func (g *Gateway) adminAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		adminToken := r.Header.Get("X-Admin-Token")
-		if adminToken == "" {
-			g.writeError(w, http.StatusUnauthorized, "missing admin token")
-			return
-		}
-
-		// Constant-time comparison to prevent timing attacks
-		if subtle.ConstantTimeCompare([]byte(adminToken), []byte(g.adminToken)) != 1 {
-			g.logger.Warn("invalid admin token attempt",
-				zap.String("remote_addr", r.RemoteAddr),
-				zap.String("path", r.URL.Path),
-			)
-			g.writeError(w, http.StatusUnauthorized, "invalid admin token")
-			return
-		}
-
-		// Audit log for admin actions
-		g.logger.Info("admin action authenticated",
-			zap.String("request_id", middleware.GetReqID(r.Context())),
-			zap.String("remote_addr", r.RemoteAddr),
-			zap.String("method", r.Method),
-			zap.String("path", r.URL.Path),
-		)
-
-		next.ServeHTTP(w, r)
-	})
-}
-
 // Handler implementations
 
 func (g *Gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -649,9 +1093,13 @@ func (g *Gateway) handleReady(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check cache
+	// Redis outages degrade Redis-backed features rather than the whole
+	// pod, so an unavailable cache is reported but doesn't fail readiness
 	if err := g.cache.Health(ctx); err != nil {
-		g.writeError(w, http.StatusServiceUnavailable, "cache not ready")
+		g.writeJSON(w, http.StatusOK, map[string]string{
+			"status": "degraded",
+			"cache":  "unavailable",
+		})
 		return
 	}
 
@@ -661,8 +1109,6 @@ func (g *Gateway) handleReady(w http.ResponseWriter, r *http.Request) {
 }
 
 func (g *Gateway) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
 	// Read request body for parsing and forwarding
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -671,17 +1117,79 @@ func (g *Gateway) handleChatCompletions(w http.ResponseWriter, r *http.Request)
 	}
 	r.Body.Close()
 
+	// A stream's node request is cancelled when the client goes away
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	resp := g.forwardChatCompletion(w, r, body)
+	if resp == nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	g.relayProxiedResponse(w, resp, cancel)
+}
+
+// forwardChatCompletion validates a chat completion request, applies model
+// policies and proxies it to a serving node. It returns the node's response
+// for the caller to relay and close, or nil after writing an error to w.
+func (g *Gateway) forwardChatCompletion(w http.ResponseWriter, r *http.Request, body []byte) *http.Response {
+	ctx := r.Context()
+
 	// Parse request for validation and routing
 	var req ChatCompletionRequest
 	if err := json.Unmarshal(body, &req); err != nil {
 		g.writeError(w, http.StatusBadRequest, "invalid request body")
-		return
+		return nil
 	}
 
 	// Validate request
 	if err := req.Validate(); err != nil {
 		g.writeError(w, http.StatusBadRequest, err.Error())
-		return
+		return nil
+	}
+
+	// Resolve stable model aliases to their versioned target
+	r, req.Model, body = g.applyModelAlias(w, r, req.Model, body)
+
+	// Client-requested priority (`priority: low` or X-Priority: low)
+	lowPriority, body := parseRequestPriority(r, body)
+
+	// Enforce the plan's limits on messages, or cut to a sliding window
+	body, ok := g.applyRequestShaping(w, r, &req, body)
+	if !ok {
+		return nil
+	}
+
+	// Apply deprecation headers and enforce sunset cutoff
+	if !g.enforceModelLifecycle(w, r, req.Model) {
+		return nil
+	}
+
+	// Gated models need the tenant to have accepted their license
+	if !g.enforceModelLicense(w, r, req.Model) {
+		return nil
+	}
+
+	// Reject parameters the model does not support (tools, vision, JSON mode, guided decoding)
+	if !g.enforceModelCapabilities(w, r, req.Model, body) {
+		return nil
+	}
+
+	// Fill in the model's sampling defaults for parameters the client omitted
+	body = g.applyModelSamplingDefaults(ctx, req.Model, body)
+
+	// Assign the request to a variant of the tenant's prompt experiment
+	r, body = g.applyPromptExperiment(w, r, req.Model, body)
+	ctx = r.Context()
+
+	// Track prompt diversity for abuse detection
+	g.observePrompt(ctx, body)
+
+	// Shed traffic for models over their fleet-wide error budget
+	if !g.allowModelRequest(w, r, req.Model) {
+		return nil
 	}
 
 	// Get tenant/env info from context
@@ -695,65 +1203,72 @@ func (g *Gateway) handleChatCompletions(w http.ResponseWriter, r *http.Request)
 		zap.Bool("streaming", req.Stream),
 	)
 
-	// Get environment details for region preference
-	var envRegion string
-	err = g.db.Pool.QueryRow(ctx, `
-		SELECT region FROM environments
-		WHERE id = $1 AND tenant_id = $2 AND status = 'active'
-	`, envID, tenantID).Scan(&envRegion)
-	if err != nil {
-		g.logger.Error("failed to get environment",
-			zap.Error(err),
-			zap.String("env_id", envID.String()),
-		)
-		// Continue without region preference
-	}
+	// Route by the environment's region and the tenant's plan, also when
+	// the request is moved off a draining node
+	r = r.WithContext(g.withRoutingOrigin(ctx))
+	ctx = r.Context()
 
 	// Select best endpoint
 	endpoint, err := g.LoadBalancer.SelectEndpoint(ctx, req.Model)
+	if errors.Is(err, ErrNodesAtCapacity) {
+		g.writeNodesAtCapacity(w, req.Model)
+		return nil
+	}
 	if err != nil {
 		g.logger.Error("failed to select endpoint", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to select endpoint")
-		return
+		return nil
 	}
 
 	if endpoint == "" {
 		g.writeError(w, http.StatusServiceUnavailable, "no healthy nodes for model")
-		return
+		return nil
 	}
 
-	// Proxy request to endpoint
-	// Re-create body reader for proxying
-	r.Body = io.NopCloser(bytes.NewBuffer(body))
+	// Signal queue backpressure and defer low priority work on busy nodes
+	if !g.applyBackpressure(w, endpoint, lowPriority) {
+		return nil
+	}
+
+	// Cap max_tokens of low priority work while the fleet is saturated
+	body = g.applyDegradedMaxTokens(w, r, req.Model, body, lowPriority)
 
+	// Proxy request to endpoint; non-streaming requests are moved off the
+	// node if it is drained while they wait in its queue
 	start := time.Now()
-	resp, err := g.proxyRequest(endpoint, r)
+	var resp *http.Response
+	if req.Stream {
+		r.Body = io.NopCloser(bytes.NewBuffer(body))
+		resp, err = g.proxyRequest(endpoint, r)
+	} else {
+		endpoint, resp, err = g.proxyQueuedRequest(r, endpoint, req.Model, body)
+	}
 	duration := time.Since(start)
 
-	// Record stats
-	isError := err != nil || (resp != nil && resp.StatusCode >= 500)
+	// Record stats; an expired client deadline isn't the node's failure
+	isError := (err != nil && !deadlineExceeded(r)) || (resp != nil && resp.StatusCode >= 500)
 	g.LoadBalancer.RecordRequest(endpoint, duration, isError)
+	g.recordModelOutcome(ctx, req.Model, isError)
+	g.trackNodeRequest(r, endpoint, req.Model, start, resp, err)
+	g.trackPromptVariant(r, start, resp, err)
 
 	if err != nil {
-		g.logger.Error("failed to proxy request", zap.Error(err))
-		g.writeError(w, http.StatusBadGateway, "failed to proxy request")
-		return
+		g.writeProxyError(w, r, err)
+		return nil
 	}
-	defer resp.Body.Close()
 
-	// Copy response headers
-	for key, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(key, value)
-		}
-	}
-
-	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, resp.Body)
+	// Apply the tenant's output post-processing rules
+	g.applyOutputPolicy(ctx, resp, req.Model, systemPromptText(req.Messages), true)
+	g.sampleForQuality(r, resp, endpoint, req.Model, body, true, start)
+	g.applyUsageCost(r, resp, req.Model)
+	return resp
 }
 
 func (g *Gateway) handleCompletions(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	// A stream's node request is cancelled when the client goes away
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	r = r.WithContext(ctx)
 
 	// Read request body for parsing and forwarding
 	body, err := io.ReadAll(r.Body)
@@ -780,6 +1295,38 @@ func (g *Gateway) handleCompletions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Resolve stable model aliases to their versioned target
+	r, req.Model, body = g.applyModelAlias(w, r, req.Model, body)
+
+	// Client-requested priority (`priority: low` or X-Priority: low)
+	lowPriority, body := parseRequestPriority(r, body)
+
+	// Apply deprecation headers and enforce sunset cutoff
+	if !g.enforceModelLifecycle(w, r, req.Model) {
+		return
+	}
+
+	// Gated models need the tenant to have accepted their license
+	if !g.enforceModelLicense(w, r, req.Model) {
+		return
+	}
+
+	// Reject parameters the model does not support (tools, vision, JSON mode, guided decoding)
+	if !g.enforceModelCapabilities(w, r, req.Model, body) {
+		return
+	}
+
+	// Fill in the model's sampling defaults for parameters the client omitted
+	body = g.applyModelSamplingDefaults(ctx, req.Model, body)
+
+	// Track prompt diversity for abuse detection
+	g.observePrompt(ctx, body)
+
+	// Shed traffic for models over their fleet-wide error budget
+	if !g.allowModelRequest(w, r, req.Model) {
+		return
+	}
+
 	// Get tenant/env info from context
 	tenantID := ctx.Value("tenant_id").(uuid.UUID)
 	envID := ctx.Value("environment_id").(uuid.UUID)
@@ -791,8 +1338,16 @@ func (g *Gateway) handleCompletions(w http.ResponseWriter, r *http.Request) {
 		zap.Bool("streaming", req.Stream),
 	)
 
+	// Route by the environment's region and the tenant's plan
+	r = r.WithContext(g.withRoutingOrigin(ctx))
+	ctx = r.Context()
+
 	// Select best endpoint
 	endpoint, err := g.LoadBalancer.SelectEndpoint(ctx, req.Model)
+	if errors.Is(err, ErrNodesAtCapacity) {
+		g.writeNodesAtCapacity(w, req.Model)
+		return
+	}
 	if err != nil {
 		g.logger.Error("failed to select endpoint", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to select endpoint")
@@ -804,34 +1359,44 @@ func (g *Gateway) handleCompletions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Proxy request to endpoint
-	// Re-create body reader for proxying
-	r.Body = io.NopCloser(bytes.NewBuffer(body))
+	// Signal queue backpressure and defer low priority work on busy nodes
+	if !g.applyBackpressure(w, endpoint, lowPriority) {
+		return
+	}
+
+	// Cap max_tokens of low priority work while the fleet is saturated
+	body = g.applyDegradedMaxTokens(w, r, req.Model, body, lowPriority)
 
+	// Proxy request to endpoint; non-streaming requests are moved off the
+	// node if it is drained while they wait in its queue
 	start := time.Now()
-	resp, err := g.proxyRequest(endpoint, r)
+	var resp *http.Response
+	if req.Stream {
+		r.Body = io.NopCloser(bytes.NewBuffer(body))
+		resp, err = g.proxyRequest(endpoint, r)
+	} else {
+		endpoint, resp, err = g.proxyQueuedRequest(r, endpoint, req.Model, body)
+	}
 	duration := time.Since(start)
 
-	// Record stats
-	isError := err != nil || (resp != nil && resp.StatusCode >= 500)
+	// Record stats; an expired client deadline isn't the node's failure
+	isError := (err != nil && !deadlineExceeded(r)) || (resp != nil && resp.StatusCode >= 500)
 	g.LoadBalancer.RecordRequest(endpoint, duration, isError)
+	g.recordModelOutcome(ctx, req.Model, isError)
+	g.trackNodeRequest(r, endpoint, req.Model, start, resp, err)
 
 	if err != nil {
-		g.logger.Error("failed to proxy request", zap.Error(err))
-		g.writeError(w, http.StatusBadGateway, "failed to proxy request")
+		g.writeProxyError(w, r, err)
 		return
 	}
 	defer resp.Body.Close()
 
-	// Copy response headers
-	for key, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(key, value)
-		}
-	}
+	// Apply the tenant's output post-processing rules
+	g.applyOutputPolicy(ctx, resp, req.Model, "", false)
+	g.sampleForQuality(r, resp, endpoint, req.Model, body, false, start)
+	g.applyUsageCost(r, resp, req.Model)
 
-	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, resp.Body)
+	g.relayProxiedResponse(w, resp, cancel)
 }
 
 func (g *Gateway) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
@@ -858,12 +1423,41 @@ func (g *Gateway) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Resolve stable model aliases to their versioned target
+	r, req.Model, body = g.applyModelAlias(w, r, req.Model, body)
+
+	// Client-requested priority (`priority: low` or X-Priority: low)
+	lowPriority, body := parseRequestPriority(r, body)
+
+	// Apply deprecation headers and enforce sunset cutoff
+	if !g.enforceModelLifecycle(w, r, req.Model) {
+		return
+	}
+
+	// Gated models need the tenant to have accepted their license
+	if !g.enforceModelLicense(w, r, req.Model) {
+		return
+	}
+
+	// Shed traffic for models over their fleet-wide error budget
+	if !g.allowModelRequest(w, r, req.Model) {
+		return
+	}
+
 	g.logger.Info("embedding request",
 		zap.String("model", req.Model),
 	)
 
+	// Route by the environment's region and the tenant's plan
+	r = r.WithContext(g.withRoutingOrigin(ctx))
+	ctx = r.Context()
+
 	// Select best endpoint
 	endpoint, err := g.LoadBalancer.SelectEndpoint(ctx, req.Model)
+	if errors.Is(err, ErrNodesAtCapacity) {
+		g.writeNodesAtCapacity(w, req.Model)
+		return
+	}
 	if err != nil {
 		g.logger.Error("failed to select endpoint", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to select endpoint")
@@ -875,21 +1469,27 @@ func (g *Gateway) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Signal queue backpressure and defer low priority work on busy nodes
+	if !g.applyBackpressure(w, endpoint, lowPriority) {
+		return
+	}
+
 	// Proxy request to endpoint
 	// Re-create body reader for proxying
 	r.Body = io.NopCloser(bytes.NewBuffer(body))
 
 	start := time.Now()
-	resp, err := g.proxyRequest(endpoint, r)
+	resp, err := g.proxyRequestPassthrough(endpoint, r)
 	duration := time.Since(start)
 
-	// Record stats
-	isError := err != nil || (resp != nil && resp.StatusCode >= 500)
+	// Record stats; an expired client deadline isn't the node's failure
+	isError := (err != nil && !deadlineExceeded(r)) || (resp != nil && resp.StatusCode >= 500)
 	g.LoadBalancer.RecordRequest(endpoint, duration, isError)
+	g.recordModelOutcome(ctx, req.Model, isError)
+	g.trackNodeRequest(r, endpoint, req.Model, start, resp, err)
 
 	if err != nil {
-		g.logger.Error("failed to proxy request", zap.Error(err))
-		g.writeError(w, http.StatusBadGateway, "failed to proxy request")
+		g.writeProxyError(w, r, err)
 		return
 	}
 	defer resp.Body.Close()
@@ -906,81 +1506,53 @@ func (g *Gateway) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
 }
 
 func (g *Gateway) handleListModels(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	// Query models from database
-	rows, err := g.db.Pool.Query(ctx, `
-		SELECT id, name, family, type, context_length, status
-		FROM models
-		WHERE status = 'active'
-		ORDER BY name
-	`)
+	data, err := g.modelCatalog.get(r.Context(), g.loadModelCatalog)
 	if err != nil {
 		g.logger.Error("failed to query models", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, "failed to query models")
 		return
 	}
-	defer rows.Close()
-
-	var modelsList []map[string]interface{}
-	for rows.Next() {
-		var m models.Model
-		if err := rows.Scan(&m.ID, &m.Name, &m.Family, &m.Type, &m.ContextLength, &m.Status); err != nil {
-			continue
-		}
-
-		modelsList = append(modelsList, map[string]interface{}{
-			"id":       m.Name,
-			"object":   "model",
-			"created":  time.Now().Unix(),
-			"owned_by": "crosslogic",
-		})
-	}
 
 	g.writeJSON(w, http.StatusOK, map[string]interface{}{
 		"object": "list",
-		"data":   modelsList,
+		"data":   json.RawMessage(data),
 	})
 }
 
 func (g *Gateway) handleGetModel(w http.ResponseWriter, r *http.Request) {
 	modelName := chi.URLParam(r, "model")
 
-	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"id":       modelName,
 		"object":   "model",
 		"created":  time.Now().Unix(),
 		"owned_by": "crosslogic",
-	})
-}
-
-func (g *Gateway) handleListNodes(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	}
 
-	rows, err := g.db.Pool.Query(ctx, `
-		SELECT id, provider, status, endpoint_url, health_score, last_heartbeat_at
-		FROM nodes
-		WHERE status IN ('active', 'draining')
-		ORDER BY created_at DESC
-		LIMIT 100
-	`)
+	capabilities, err := g.getModelCapabilities(r.Context(), modelName)
 	if err != nil {
-		g.logger.Error("failed to query nodes", zap.Error(err))
-		g.writeError(w, http.StatusInternalServerError, "failed to query nodes")
-		return
+		g.logger.Warn("failed to load model capabilities",
+			zap.Error(err),
+			zap.String("model", modelName),
+		)
+	}
+	if capabilities != nil {
+		response["max_output_tokens"] = capabilities.MaxOutputTokens
+		response["capabilities"] = capabilities.publicCapabilities()
 	}
-	defer rows.Close()
 
-	var nodes []models.Node
-	for rows.Next() {
-		var n models.Node
-		if err := rows.Scan(&n.ID, &n.Provider, &n.Status, &n.EndpointURL, &n.HealthScore, &n.LastHeartbeatAt); err != nil {
-			continue
+	// Tell clients up front that the model needs a license accepted
+	if license, err := g.getGatingLicense(r.Context(), modelName); err == nil && license != nil {
+		response["license"] = map[string]interface{}{
+			"id":                  license.ID,
+			"name":                license.Name,
+			"version":             license.Version,
+			"url":                 license.URL,
+			"requires_acceptance": true,
 		}
-		nodes = append(nodes, n)
 	}
 
-	g.writeJSON(w, http.StatusOK, nodes)
+	g.writeJSON(w, http.StatusOK, response)
 }
 
 func (g *Gateway) handleGetTenantUsageAdmin(w http.ResponseWriter, r *http.Request) {
@@ -1033,6 +1605,9 @@ func (g *Gateway) handleLaunchNode(w http.ResponseWriter, r *http.Request) {
 	// Launch node
 	clusterName, err := g.orchestrator.LaunchNode(ctx, req)
 	if err != nil {
+		if g.writeNodeConfigError(w, err) {
+			return
+		}
 		g.logger.Error("failed to launch node", zap.Error(err))
 		g.writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to launch node: %v", err))
 		return
@@ -1045,6 +1620,90 @@ func (g *Gateway) handleLaunchNode(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleLaunchPreflight runs a launch's checks without launching: the
+// configuration, credentials, catalog availability, disk size, provider
+// quota and the model's presence in R2
+// Admin API - POST /admin/nodes/preflight
+func (g *Gateway) handleLaunchPreflight(w http.ResponseWriter, r *http.Request) {
+	if g.orchestrator == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "orchestrator not configured")
+		return
+	}
+
+	var req orchestrator.NodeConfig
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	report, err := g.orchestrator.Preflight(r.Context(), req)
+	if err != nil {
+		g.logger.Error("failed to run launch preflight", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to run preflight: %v", err))
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, report)
+}
+
+// handleGetLaunchQueue returns running launches per provider and region and
+// the launches waiting for a slot, in the order they will start
+func (g *Gateway) handleGetLaunchQueue(w http.ResponseWriter, r *http.Request) {
+	if g.orchestrator == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "orchestrator not configured")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, g.orchestrator.LaunchQueue().Status())
+}
+
+// handleListNodeCompliance lists the security hardening compliance of live
+// nodes as reported by their agents, optionally only those in one status
+// (compliant, noncompliant, unknown, not_required)
+// GET /admin/nodes/compliance?status=noncompliant
+func (g *Gateway) handleListNodeCompliance(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", nodes.ComplianceCompliant, nodes.ComplianceNoncompliant, nodes.ComplianceUnknown, nodes.ComplianceNotRequired:
+	default:
+		g.writeError(w, http.StatusBadRequest, "status must be compliant, noncompliant, unknown or not_required")
+		return
+	}
+
+	list, err := g.nodeRegistry.ListCompliance(r.Context(), status)
+	if err != nil {
+		g.logger.Error("failed to list node compliance", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list node compliance")
+		return
+	}
+
+	summary := map[string]int{}
+	for _, n := range list {
+		summary[n.Compliance]++
+	}
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data":    list,
+		"summary": summary,
+	})
+}
+
+// writeNodeConfigError responds with the field errors of an invalid launch
+// configuration and reports whether err was one
+func (g *Gateway) writeNodeConfigError(w http.ResponseWriter, err error) bool {
+	var cfgErr *orchestrator.ConfigError
+	if !errors.As(err, &cfgErr) {
+		return false
+	}
+
+	g.writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": cfgErr.Error(),
+			"type":    "invalid_request_error",
+			"fields":  cfgErr.Fields,
+		},
+	})
+	return true
+}
+
 // handleTerminateNode terminates a GPU node
 func (g *Gateway) handleTerminateNode(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -1111,30 +1770,25 @@ func (g *Gateway) writeError(w http.ResponseWriter, statusCode int, message stri
 
 // recordUsage records token usage for billing
 func (g *Gateway) recordUsage(ctx context.Context, usage models.UsageRecord) {
-	// Store usage record asynchronously
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		_, err := g.db.Pool.Exec(ctx, `
-			INSERT INTO usage_records (
-				id, request_id, timestamp, tenant_id, environment_id,
-				api_key_id, node_id, prompt_tokens, completion_tokens,
-				total_tokens, latency_ms
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		`,
-			usage.ID, usage.RequestID, usage.Timestamp,
-			usage.TenantID, usage.EnvironmentID, usage.APIKeyID,
-			usage.NodeID, usage.PromptTokens, usage.CompletionTokens,
-			usage.TotalTokens, usage.LatencyMs,
+	// Token responses draw from environment budgets as they close; usage
+	// recorded here (audio, images) carries no token counts in the body
+	g.drawEnvironmentBudget(ctx, "", usage.PromptTokens, usage.CompletionTokens, usage.CostMicrodollars)
+
+	g.queueUsage(ctx, usage)
+}
+
+// queueUsage stores a usage record without drawing from budgets
+func (g *Gateway) queueUsage(ctx context.Context, usage models.UsageRecord) {
+	// Record alias resolution for traceability when the request used one
+	usage.Metadata = usageMetadataWithAlias(ctx, usage.Metadata)
+
+	// Stored by a background job so a database blip doesn't lose billed usage
+	if _, err := g.jobs.Enqueue(ctx, jobRecordUsage, usage); err != nil {
+		g.logger.Error("failed to queue usage record",
+			zap.Error(err),
+			zap.String("request_id", *usage.RequestID),
 		)
-		if err != nil {
-			g.logger.Error("failed to record usage",
-				zap.Error(err),
-				zap.String("request_id", *usage.RequestID),
-			)
-		}
-	}()
+	}
 }
 
 // Helper functions
@@ -1183,8 +1837,9 @@ type ChatCompletionRequest struct {
 }
 
 type ChatCompletionMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is a string or, for multimodal messages, an array of content parts
+	Content json.RawMessage `json:"content"`
 }
 
 func (r *ChatCompletionRequest) Validate() error {
@@ -1236,12 +1891,25 @@ func (r *EmbeddingRequest) Validate() error {
 	return nil
 }
 
+// proxyRequest forwards r to a node. The client's Accept-Encoding is not
+// forwarded: the transport negotiates gzip with the node itself and hands
+// back a decoded body the gateway can inspect.
 func (g *Gateway) proxyRequest(endpoint string, r *http.Request) (*http.Response, error) {
+	return g.forwardToNode(endpoint, r, false)
+}
+
+// proxyRequestPassthrough forwards r to a node along with the client's
+// Accept-Encoding, for responses relayed byte for byte. The node may then
+// answer in any encoding the client accepts, such as zstd, and the response
+// reaches the client without being decoded and compressed again. The node
+// request log can't read token counts from an encoded response.
+func (g *Gateway) proxyRequestPassthrough(endpoint string, r *http.Request) (*http.Response, error) {
+	return g.forwardToNode(endpoint, r, true)
+}
+
+func (g *Gateway) forwardToNode(endpoint string, r *http.Request, keepEncoding bool) (*http.Response, error) {
 	// Construct target URL
-	targetURL := endpoint + r.URL.Path
-	if !strings.HasPrefix(endpoint, "http") {
-		targetURL = "http://" + endpoint + r.URL.Path
-	}
+	targetURL := nodeURL(endpoint, nodePath(r.URL.Path))
 
 	// Create new request
 	proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL, r.Body)
@@ -1249,19 +1917,28 @@ func (g *Gateway) proxyRequest(endpoint string, r *http.Request) (*http.Response
 		return nil, fmt.Errorf("failed to create proxy request: %w", err)
 	}
 
-	// Copy headers
-	for k, v := range r.Header {
-		proxyReq.Header[k] = v
+	// Copy the headers nodes may see. Hop-by-hop headers describe the
+	// client's connection, and a client's "Connection: close" would
+	// otherwise close the pooled node connection.
+	g.nodeHeaders.apply(r, proxyReq.Header)
+	if !keepEncoding {
+		proxyReq.Header.Del("Accept-Encoding")
 	}
 
-	// Execute request
-	client := &http.Client{
-		Timeout: 10 * time.Minute, // Long timeout for LLM generation
+	// Count the request against the node's concurrency limit until its
+	// response has been read
+	done := func() {}
+	if g.LoadBalancer != nil {
+		done = g.LoadBalancer.beginRequest(endpoint)
 	}
-	resp, err := client.Do(proxyReq)
+
+	// Execute request on the shared pooled client
+	resp, err := g.nodeClient.Do(proxyReq)
 	if err != nil {
+		done()
 		return nil, fmt.Errorf("proxy request failed: %w", err)
 	}
+	resp.Body = &inflightBody{ReadCloser: resp.Body, done: done}
 
 	return resp, nil
 }
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp/syntax"
+)
+
+// Guided decoding.
+//
+// vLLM accepts guided_json, guided_regex and guided_choice as extra body
+// parameters to constrain generation to a JSON schema, regular expression or
+// fixed set of choices. The gateway forwards the request body unchanged, so
+// these already reach vLLM; what the gateway adds is validation up front, and
+// (see model_capabilities.go) a per-model capability flag, so a bad schema or
+// a model deployed without a guided decoding backend fails fast with a clear
+// error instead of a vLLM 500.
+const (
+	// maxGuidedJSONBytes bounds the size of a guided_json schema
+	maxGuidedJSONBytes = 64 * 1024
+	// maxGuidedRegexLength bounds the length of a guided_regex pattern
+	maxGuidedRegexLength = 4096
+	// maxGuidedChoices bounds the number of guided_choice options
+	maxGuidedChoices = 256
+)
+
+// GuidedDecodingParams are the vLLM guided decoding extra body parameters
+type GuidedDecodingParams struct {
+	GuidedJSON   json.RawMessage `json:"guided_json,omitempty"`
+	GuidedRegex  *string         `json:"guided_regex,omitempty"`
+	GuidedChoice []string        `json:"guided_choice,omitempty"`
+}
+
+// parseGuidedDecoding extracts guided decoding parameters from a request body.
+// It returns nil when the request does not use guided decoding.
+func parseGuidedDecoding(body []byte) (*GuidedDecodingParams, error) {
+	var params GuidedDecodingParams
+	if err := json.Unmarshal(body, &params); err != nil {
+		return nil, fmt.Errorf("invalid guided decoding parameters")
+	}
+	if isJSONNull(params.GuidedJSON) {
+		params.GuidedJSON = nil
+	}
+	if params.GuidedJSON == nil && params.GuidedRegex == nil && params.GuidedChoice == nil {
+		return nil, nil
+	}
+	return &params, nil
+}
+
+// Validate checks that exactly one constraint is set and that it is well formed
+func (p *GuidedDecodingParams) Validate() error {
+	set := 0
+	if p.GuidedJSON != nil {
+		set++
+	}
+	if p.GuidedRegex != nil {
+		set++
+	}
+	if p.GuidedChoice != nil {
+		set++
+	}
+	if set > 1 {
+		return fmt.Errorf("only one of guided_json, guided_regex or guided_choice may be set")
+	}
+
+	switch {
+	case p.GuidedJSON != nil:
+		return validateGuidedJSON(p.GuidedJSON)
+	case p.GuidedRegex != nil:
+		return validateGuidedRegex(*p.GuidedRegex)
+	default:
+		return validateGuidedChoice(p.GuidedChoice)
+	}
+}
+
+// validateGuidedJSON accepts a JSON schema object, or a string containing one
+// (vLLM accepts both forms)
+func validateGuidedJSON(raw json.RawMessage) error {
+	if len(raw) > maxGuidedJSONBytes {
+		return fmt.Errorf("guided_json must be at most %d bytes", maxGuidedJSONBytes)
+	}
+
+	schema := []byte(raw)
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err == nil {
+		schema = []byte(encoded)
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(schema, &obj); err != nil {
+		return fmt.Errorf("guided_json must be a JSON schema object")
+	}
+	if len(obj) == 0 {
+		return fmt.Errorf("guided_json schema must not be empty")
+	}
+	return nil
+}
+
+func validateGuidedRegex(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("guided_regex must not be empty")
+	}
+	if len(pattern) > maxGuidedRegexLength {
+		return fmt.Errorf("guided_regex must be at most %d characters", maxGuidedRegexLength)
+	}
+	if _, err := syntax.Parse(pattern, syntax.Perl); err != nil {
+		return fmt.Errorf("guided_regex is not a valid regular expression: %v", err)
+	}
+	return nil
+}
+
+func validateGuidedChoice(choices []string) error {
+	if len(choices) == 0 {
+		return fmt.Errorf("guided_choice must contain at least one option")
+	}
+	if len(choices) > maxGuidedChoices {
+		return fmt.Errorf("guided_choice must contain at most %d options", maxGuidedChoices)
+	}
+	for _, choice := range choices {
+		if choice == "" {
+			return fmt.Errorf("guided_choice options must not be empty")
+		}
+	}
+	return nil
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return raw != nil && bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
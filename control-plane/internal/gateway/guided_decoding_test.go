@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGuidedDecoding(t *testing.T) {
+	params, err := parseGuidedDecoding([]byte(`{"model":"m","messages":[]}`))
+	if err != nil || params != nil {
+		t.Fatalf("expected no guided decoding, got %+v, %v", params, err)
+	}
+
+	params, err = parseGuidedDecoding([]byte(`{"model":"m","guided_json":null}`))
+	if err != nil || params != nil {
+		t.Fatalf("expected null guided_json to be ignored, got %+v, %v", params, err)
+	}
+
+	params, err = parseGuidedDecoding([]byte(`{"model":"m","guided_choice":["yes","no"]}`))
+	if err != nil || params == nil || len(params.GuidedChoice) != 2 {
+		t.Fatalf("expected guided_choice to be parsed, got %+v, %v", params, err)
+	}
+}
+
+func TestGuidedDecodingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"schema object", `{"guided_json":{"type":"object","properties":{"a":{"type":"string"}}}}`, ""},
+		{"schema string", `{"guided_json":"{\"type\":\"object\"}"}`, ""},
+		{"schema not object", `{"guided_json":[1,2]}`, "JSON schema object"},
+		{"empty schema", `{"guided_json":{}}`, "must not be empty"},
+		{"valid regex", `{"guided_regex":"\\d{3}-\\d{4}"}`, ""},
+		{"invalid regex", `{"guided_regex":"(abc"}`, "not a valid regular expression"},
+		{"empty regex", `{"guided_regex":""}`, "must not be empty"},
+		{"choices", `{"guided_choice":["positive","negative"]}`, ""},
+		{"no choices", `{"guided_choice":[]}`, "at least one option"},
+		{"empty choice", `{"guided_choice":["a",""]}`, "must not be empty"},
+		{"multiple constraints", `{"guided_regex":"a+","guided_choice":["a"]}`, "only one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := parseGuidedDecoding([]byte(tt.body))
+			if err != nil || params == nil {
+				t.Fatalf("failed to parse: %+v, %v", params, err)
+			}
+			err = params.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultImageSize = "1024x1024"
+
+	// imageSizeStep is the multiple image sides must be of; diffusion models
+	// work on latents downsampled by 8 and most checkpoints need 64
+	imageSizeStep = 64
+
+	// imageMinDimension is the smallest side accepted
+	imageMinDimension = 256
+
+	// imageBasePixels is the image size price_per_image is quoted for
+	imageBasePixels = 1024 * 1024
+)
+
+var imagesGenerated = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gateway_images_generated_total",
+		Help: "Images generated and billed",
+	},
+	[]string{"model"},
+)
+
+// imageLimits bounds what a single image generation request may ask for
+type imageLimits struct {
+	maxImages    int
+	maxDimension int
+	maxSteps     int
+}
+
+func defaultImageLimits() imageLimits {
+	return imageLimits{maxImages: 4, maxDimension: 1536, maxSteps: 50}
+}
+
+// SetImageLimits sets the most images, the largest side and the most
+// inference steps a single image generation request may ask for
+func (g *Gateway) SetImageLimits(maxImages, maxDimension, maxSteps int) {
+	if maxImages > 0 {
+		g.images.maxImages = maxImages
+	}
+	if maxDimension >= imageMinDimension {
+		g.images.maxDimension = maxDimension
+	}
+	if maxSteps > 0 {
+		g.images.maxSteps = maxSteps
+	}
+}
+
+// imageRequest is what the gateway reads of an image generation request;
+// the body itself is forwarded to the node unchanged
+type imageRequest struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	N              *int   `json:"n"`
+	Size           string `json:"size"`
+	Steps          *int   `json:"steps"`
+	ResponseFormat string `json:"response_format"`
+}
+
+// validatedImageRequest is an image request with its defaults applied
+type validatedImageRequest struct {
+	Model  string
+	Images int
+	Width  int
+	Height int
+	Steps  int
+}
+
+// parseImageSize reads a WIDTHxHEIGHT size
+func parseImageSize(size string) (int, int, bool) {
+	w, h, ok := strings.Cut(strings.ToLower(size), "x")
+	if !ok {
+		return 0, 0, false
+	}
+	width, err := strconv.Atoi(w)
+	if err != nil {
+		return 0, 0, false
+	}
+	height, err := strconv.Atoi(h)
+	if err != nil {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// validateImageRequest checks an image generation request against limits
+func validateImageRequest(body []byte, limits imageLimits) (validatedImageRequest, error) {
+	var req imageRequest
+	var v validatedImageRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return v, errors.New("invalid request body")
+	}
+	if req.Model == "" {
+		return v, errors.New("model is required")
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return v, errors.New("prompt is required")
+	}
+	v.Model = req.Model
+
+	v.Images = 1
+	if req.N != nil {
+		if *req.N < 1 || *req.N > limits.maxImages {
+			return v, fmt.Errorf("n must be between 1 and %d", limits.maxImages)
+		}
+		v.Images = *req.N
+	}
+
+	size := req.Size
+	if size == "" {
+		size = defaultImageSize
+	}
+	width, height, ok := parseImageSize(size)
+	if !ok {
+		return v, errors.New("size must be WIDTHxHEIGHT, e.g. 1024x1024")
+	}
+	for _, side := range []int{width, height} {
+		if side < imageMinDimension || side > limits.maxDimension || side%imageSizeStep != 0 {
+			return v, fmt.Errorf("size sides must be multiples of %d between %d and %d",
+				imageSizeStep, imageMinDimension, limits.maxDimension)
+		}
+	}
+	v.Width, v.Height = width, height
+
+	if req.Steps != nil {
+		if *req.Steps < 1 || *req.Steps > limits.maxSteps {
+			return v, fmt.Errorf("steps must be between 1 and %d", limits.maxSteps)
+		}
+		v.Steps = *req.Steps
+	}
+
+	if req.ResponseFormat != "" && req.ResponseFormat != "b64_json" {
+		return v, errors.New("response_format must be b64_json")
+	}
+	return v, nil
+}
+
+// generatedImages counts the images in a node's response
+func generatedImages(body []byte) int {
+	var resp struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0
+	}
+	return len(resp.Data)
+}
+
+// imageCostMicrodollars prices images by pixel count relative to 1024x1024
+func imageCostMicrodollars(images, width, height int, pricePerImage float64) int64 {
+	scale := float64(width*height) / imageBasePixels
+	return int64(math.Round(float64(images) * scale * pricePerImage * 1e6))
+}
+
+// imageModel is an image model's ID and per-image price
+type imageModel struct {
+	ID            uuid.UUID
+	PricePerImage float64
+}
+
+// getImageModel looks up an image model, returning nil when the model
+// doesn't exist or isn't an image model
+func (g *Gateway) getImageModel(ctx context.Context, name string) (*imageModel, error) {
+	var m imageModel
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT id, COALESCE(price_per_image, 0)::float8
+		FROM models WHERE name = $1 AND type = 'image'
+	`, name).Scan(&m.ID, &m.PricePerImage)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// handleImageGenerations proxies an OpenAI-compatible image generation
+// request to a node of the image workload class and bills each image
+// Tenant API - POST /v1/images/generations
+func (g *Gateway) handleImageGenerations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	req, err := validateImageRequest(body, g.images)
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Apply deprecation headers and enforce sunset cutoff
+	if !g.enforceModelLifecycle(w, r, req.Model) {
+		return
+	}
+
+	// Gated models need the tenant to have accepted their license
+	if !g.enforceModelLicense(w, r, req.Model) {
+		return
+	}
+
+	model, err := g.getImageModel(ctx, req.Model)
+	if err != nil {
+		g.logger.Error("failed to get image model", zap.Error(err), zap.String("model", req.Model))
+		g.writeError(w, http.StatusInternalServerError, "failed to get model")
+		return
+	}
+	if model == nil {
+		g.writeError(w, http.StatusBadRequest, fmt.Sprintf("model '%s' does not support image generation", req.Model))
+		return
+	}
+
+	// Shed traffic for models over their fleet-wide error budget
+	if !g.allowModelRequest(w, r, req.Model) {
+		return
+	}
+
+	g.logger.Info("image generation request",
+		zap.String("model", req.Model),
+		zap.Int("n", req.Images),
+		zap.Int("width", req.Width),
+		zap.Int("height", req.Height),
+	)
+
+	// Route by the environment's region and the tenant's plan
+	r = r.WithContext(g.withRoutingOrigin(ctx))
+	ctx = r.Context()
+
+	// Select best image endpoint
+	endpoint, err := g.LoadBalancer.SelectWorkloadEndpoint(ctx, req.Model, nodes.WorkloadImage)
+	if errors.Is(err, ErrNodesAtCapacity) {
+		g.writeNodesAtCapacity(w, req.Model)
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to select endpoint", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to select endpoint")
+		return
+	}
+	if endpoint == "" {
+		g.writeError(w, http.StatusServiceUnavailable, "no healthy image nodes for model")
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+
+	start := time.Now()
+	resp, err := g.proxyRequest(endpoint, r)
+	duration := time.Since(start)
+
+	// Record stats; an expired client deadline isn't the node's failure
+	isError := (err != nil && !deadlineExceeded(r)) || (resp != nil && resp.StatusCode >= 500)
+	g.LoadBalancer.RecordRequest(endpoint, duration, isError)
+	g.recordModelOutcome(ctx, req.Model, isError)
+	g.trackNodeRequest(r, endpoint, req.Model, start, resp, err)
+
+	if err != nil {
+		g.writeProxyError(w, r, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		g.logger.Error("failed to read generated images", zap.Error(err))
+		g.writeError(w, http.StatusBadGateway, "failed to read node response")
+		return
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		g.recordImageUsage(r, endpoint, model, req, generatedImages(respBody), resp.StatusCode, duration)
+	}
+
+	for key, values := range resp.Header {
+		if key == "Content-Length" {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(respBody)
+}
+
+// recordImageUsage bills the images a node returned
+func (g *Gateway) recordImageUsage(r *http.Request, endpoint string, model *imageModel, req validatedImageRequest, images, status int, latency time.Duration) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return
+	}
+	envID, _ := ctx.Value("environment_id").(uuid.UUID)
+
+	metadata := map[string]interface{}{
+		"workload_class": nodes.WorkloadImage,
+		"images":         images,
+		"size":           fmt.Sprintf("%dx%d", req.Width, req.Height),
+	}
+	if req.Steps > 0 {
+		metadata["steps"] = req.Steps
+	}
+	encoded, _ := json.Marshal(metadata)
+
+	requestID := middleware.GetReqID(ctx)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	cost := imageCostMicrodollars(images, req.Width, req.Height, model.PricePerImage)
+	latencyMs := int(latency.Milliseconds())
+	usage := models.UsageRecord{
+		ID:               uuid.New(),
+		RequestID:        &requestID,
+		Timestamp:        time.Now(),
+		TenantID:         tenantID,
+		EnvironmentID:    envID,
+		ModelID:          &model.ID,
+		StatusCode:       &status,
+		LatencyMs:        &latencyMs,
+		CostMicrodollars: &cost,
+		Metadata:         string(encoded),
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok {
+		usage.APIKeyID = &keyInfo.ID
+	}
+	if nodeID, err := uuid.Parse(g.LoadBalancer.getNodeIDForEndpoint(endpoint)); err == nil {
+		usage.NodeID = &nodeID
+	}
+
+	imagesGenerated.WithLabelValues(req.Model).Add(float64(images))
+	g.recordUsage(ctx, usage)
+}
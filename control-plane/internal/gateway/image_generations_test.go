@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateImageRequest(t *testing.T) {
+	limits := defaultImageLimits()
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"defaults", `{"model":"sdxl","prompt":"a cat"}`, ""},
+		{"full", `{"model":"sdxl","prompt":"a cat","n":4,"size":"1536x768","steps":50,"response_format":"b64_json"}`, ""},
+		{"missing model", `{"prompt":"a cat"}`, "model is required"},
+		{"missing prompt", `{"model":"sdxl","prompt":"  "}`, "prompt is required"},
+		{"zero images", `{"model":"sdxl","prompt":"a cat","n":0}`, "n must be between 1 and 4"},
+		{"too many images", `{"model":"sdxl","prompt":"a cat","n":5}`, "n must be between 1 and 4"},
+		{"bad size", `{"model":"sdxl","prompt":"a cat","size":"large"}`, "size must be WIDTHxHEIGHT"},
+		{"unaligned size", `{"model":"sdxl","prompt":"a cat","size":"1000x1024"}`, "multiples of 64"},
+		{"too small", `{"model":"sdxl","prompt":"a cat","size":"128x128"}`, "multiples of 64"},
+		{"too large", `{"model":"sdxl","prompt":"a cat","size":"2048x2048"}`, "multiples of 64"},
+		{"too many steps", `{"model":"sdxl","prompt":"a cat","steps":51}`, "steps must be between 1 and 50"},
+		{"url response", `{"model":"sdxl","prompt":"a cat","response_format":"url"}`, "response_format must be b64_json"},
+		{"invalid json", `{`, "invalid request body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateImageRequest([]byte(tt.body), limits)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateImageRequestDefaults(t *testing.T) {
+	req, err := validateImageRequest([]byte(`{"model":"sdxl","prompt":"a cat"}`), defaultImageLimits())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Images != 1 || req.Width != 1024 || req.Height != 1024 || req.Steps != 0 {
+		t.Fatalf("unexpected defaults: %+v", req)
+	}
+}
+
+func TestGeneratedImages(t *testing.T) {
+	if n := generatedImages([]byte(`{"created":1,"data":[{"b64_json":"a"},{"b64_json":"b"}]}`)); n != 2 {
+		t.Fatalf("generatedImages = %d, want 2", n)
+	}
+	if n := generatedImages([]byte(`not json`)); n != 0 {
+		t.Fatalf("generatedImages = %d, want 0", n)
+	}
+}
+
+func TestImageCostMicrodollars(t *testing.T) {
+	tests := []struct {
+		images, width, height int
+		price                 float64
+		want                  int64
+	}{
+		{1, 1024, 1024, 0.04, 40000},
+		{2, 1024, 1024, 0.04, 80000},
+		{1, 512, 512, 0.04, 10000},
+		{1, 1536, 1024, 0.04, 60000},
+		{3, 1024, 1024, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := imageCostMicrodollars(tt.images, tt.width, tt.height, tt.price); got != tt.want {
+			t.Errorf("imageCostMicrodollars(%d, %d, %d, %v) = %d, want %d",
+				tt.images, tt.width, tt.height, tt.price, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Incident types reported to tenants
+const (
+	incidentOutage             = "outage"
+	incidentElevatedErrors     = "elevated_errors"
+	incidentMaintenance        = "maintenance"
+	incidentDeploymentCapacity = "deployment_capacity"
+)
+
+// Incident states reported to tenants
+const (
+	incidentOngoing  = "ongoing"
+	incidentResolved = "resolved"
+)
+
+const (
+	// defaultIncidentLookback is how far back incidents are listed without since
+	defaultIncidentLookback = 90 * 24 * time.Hour
+
+	// incidentUsageWindow is how long before an incident a tenant must have
+	// used its model or region to be considered affected
+	incidentUsageWindow = 7 * 24 * time.Hour
+
+	maxIncidents = 200
+)
+
+// TenantIncident is an incident that affected the calling tenant
+type TenantIncident struct {
+	ID           uuid.UUID  `json:"id"`
+	Type         string     `json:"type"`
+	Title        string     `json:"title"`
+	Description  *string    `json:"description,omitempty"`
+	Model        *string    `json:"model,omitempty"`
+	Region       *string    `json:"region,omitempty"`
+	DeploymentID *uuid.UUID `json:"deployment_id,omitempty"`
+	Status       string     `json:"status"`
+	StartedAt    time.Time  `json:"started_at"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+}
+
+// incidentStatus returns whether an incident resolving at resolvedAt is
+// still ongoing at now
+func incidentStatus(resolvedAt *time.Time, now time.Time) string {
+	if resolvedAt == nil || resolvedAt.After(now) {
+		return incidentOngoing
+	}
+	return incidentResolved
+}
+
+// modelIncidentTitle describes a model incident for tenants
+func modelIncidentTitle(kind, model string) string {
+	if kind == incidentOutage {
+		return "Outage of " + model
+	}
+	return "Elevated error rates on " + model
+}
+
+// sortIncidents orders incidents newest first, ongoing ones ahead of
+// resolved ones starting at the same time, and keeps at most limit
+func sortIncidents(incidents []TenantIncident, limit int) []TenantIncident {
+	sort.SliceStable(incidents, func(i, j int) bool {
+		if !incidents[i].StartedAt.Equal(incidents[j].StartedAt) {
+			return incidents[i].StartedAt.After(incidents[j].StartedAt)
+		}
+		return incidents[i].Status == incidentOngoing && incidents[j].Status != incidentOngoing
+	})
+	if len(incidents) > limit {
+		incidents = incidents[:limit]
+	}
+	return incidents
+}
+
+// subscribeIncidents records model incidents from monitor and circuit
+// breaker events. Open incidents are unique per model and kind, so every
+// replica handling the same event is harmless.
+func (g *Gateway) subscribeIncidents() {
+	if g.eventBus == nil {
+		return
+	}
+
+	g.eventBus.Subscribe(events.EventModelCircuitOpened, func(ctx context.Context, event events.Event) error {
+		if model, _ := event.Payload["model"].(string); model != "" {
+			g.openModelIncident(ctx, model, incidentElevatedErrors)
+		}
+		return nil
+	})
+	g.eventBus.Subscribe(events.EventModelCircuitClosed, func(ctx context.Context, event events.Event) error {
+		if model, _ := event.Payload["model"].(string); model != "" {
+			g.resolveModelIncident(ctx, model, incidentElevatedErrors)
+		}
+		return nil
+	})
+	g.eventBus.Subscribe(events.EventNodeHealthChanged, func(ctx context.Context, event events.Event) error {
+		status, _ := event.Payload["status"].(string)
+		g.trackModelOutage(ctx, event.Payload["node_id"], status)
+		return nil
+	})
+	g.eventBus.Subscribe(events.EventNodeRegistered, func(ctx context.Context, event events.Event) error {
+		g.trackModelOutage(ctx, event.Payload["node_id"], "active")
+		return nil
+	})
+}
+
+// trackModelOutage opens an outage when a node's model is left without an
+// active node, and resolves it when a node of the model becomes active
+func (g *Gateway) trackModelOutage(ctx context.Context, nodeID interface{}, status string) {
+	id, _ := nodeID.(string)
+	if id == "" || g.db == nil {
+		return
+	}
+
+	var model string
+	var active int
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT n.model_name,
+			(SELECT COUNT(*) FROM nodes a
+			 WHERE a.model_name = n.model_name AND a.status = 'active' AND NOT a.standby)
+		FROM nodes n WHERE n.id::text = $1 AND COALESCE(n.model_name, '') <> ''
+	`, id).Scan(&model, &active)
+	if err != nil {
+		return
+	}
+
+	if status == "active" || active > 0 {
+		g.resolveModelIncident(ctx, model, incidentOutage)
+		return
+	}
+	g.openModelIncident(ctx, model, incidentOutage)
+}
+
+// openModelIncident opens an incident unless one of the kind is open
+func (g *Gateway) openModelIncident(ctx context.Context, model, kind string) {
+	tag, err := g.db.Pool.Exec(ctx, `
+		INSERT INTO model_incidents (model_name, kind) VALUES ($1, $2)
+		ON CONFLICT (model_name, kind) WHERE resolved_at IS NULL DO NOTHING
+	`, model, kind)
+	if err != nil {
+		g.logger.Error("failed to open model incident", zap.Error(err), zap.String("model", model), zap.String("kind", kind))
+		return
+	}
+	if tag.RowsAffected() > 0 {
+		g.logger.Warn("model incident opened", zap.String("model", model), zap.String("kind", kind))
+	}
+}
+
+// resolveModelIncident resolves the model's open incident of the kind
+func (g *Gateway) resolveModelIncident(ctx context.Context, model, kind string) {
+	tag, err := g.db.Pool.Exec(ctx, `
+		UPDATE model_incidents SET resolved_at = NOW()
+		WHERE model_name = $1 AND kind = $2 AND resolved_at IS NULL
+	`, model, kind)
+	if err != nil {
+		g.logger.Error("failed to resolve model incident", zap.Error(err), zap.String("model", model), zap.String("kind", kind))
+		return
+	}
+	if tag.RowsAffected() > 0 {
+		g.logger.Info("model incident resolved", zap.String("model", model), zap.String("kind", kind))
+	}
+}
+
+// handleListIncidents lists past and ongoing incidents that affected the
+// tenant: outages and error budget breaches of models it used around the
+// time, maintenance of the platform or of models and regions it used, and
+// capacity incidents of its dedicated deployments
+// Tenant API - GET /v1/incidents
+func (g *Gateway) handleListIncidents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+
+	now := time.Now()
+	since := now.Add(-defaultIncidentLookback)
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			g.writeError(w, http.StatusBadRequest, "invalid 'since' timestamp format (expected RFC3339)")
+			return
+		}
+		since = parsed
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxIncidents {
+			g.writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxIncidents))
+			return
+		}
+		limit = parsed
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != incidentOngoing && status != incidentResolved {
+		g.writeError(w, http.StatusBadRequest, "status must be 'ongoing' or 'resolved'")
+		return
+	}
+
+	incidents, err := g.tenantIncidents(ctx, tenantID, since, now)
+	if err != nil {
+		g.logger.Error("failed to list incidents", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to list incidents")
+		return
+	}
+
+	if status != "" {
+		filtered := incidents[:0]
+		for _, incident := range incidents {
+			if incident.Status == status {
+				filtered = append(filtered, incident)
+			}
+		}
+		incidents = filtered
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"data":   sortIncidents(incidents, limit),
+		"since":  since,
+	})
+}
+
+// tenantIncidents collects the incidents that affected a tenant and were
+// ongoing at or after since
+func (g *Gateway) tenantIncidents(ctx context.Context, tenantID uuid.UUID, since, now time.Time) ([]TenantIncident, error) {
+	incidents := []TenantIncident{}
+	usageWindow := incidentUsageWindow.Seconds()
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT mi.id, mi.kind, mi.model_name, mi.started_at, mi.resolved_at
+		FROM model_incidents mi
+		WHERE COALESCE(mi.resolved_at, NOW()) >= $2
+		  AND EXISTS (
+			SELECT 1 FROM usage_records ur
+			JOIN models m ON m.id = ur.model_id
+			WHERE ur.tenant_id = $1 AND m.name = mi.model_name
+			  AND ur.timestamp >= mi.started_at - make_interval(secs => $3::float8)
+			  AND ur.timestamp <= COALESCE(mi.resolved_at, NOW())
+		  )
+	`, tenantID, since, usageWindow)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var inc TenantIncident
+		var model string
+		if err := rows.Scan(&inc.ID, &inc.Type, &model, &inc.StartedAt, &inc.ResolvedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		inc.Model = &model
+		inc.Title = modelIncidentTitle(inc.Type, model)
+		inc.Status = incidentStatus(inc.ResolvedAt, now)
+		incidents = append(incidents, inc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Maintenance counts once it has started; cancelled windows never happened
+	rows, err = g.db.Pool.Query(ctx, `
+		SELECT mw.id, mw.title, mw.description, mw.scope, mw.target, mw.starts_at, mw.ends_at
+		FROM maintenance_windows mw
+		WHERE mw.status = 'scheduled' AND mw.starts_at <= NOW() AND mw.ends_at >= $2
+		  AND (
+			mw.scope = 'platform'
+			OR (mw.scope = 'model' AND EXISTS (
+				SELECT 1 FROM usage_records ur
+				JOIN models m ON m.id = ur.model_id
+				WHERE ur.tenant_id = $1 AND m.name = mw.target
+				  AND ur.timestamp >= mw.starts_at - make_interval(secs => $3::float8)
+				  AND ur.timestamp <= mw.ends_at
+			))
+			OR (mw.scope = 'region' AND EXISTS (
+				SELECT 1 FROM usage_records ur
+				JOIN regions rg ON rg.id = ur.region_id
+				WHERE ur.tenant_id = $1 AND rg.code = mw.target
+				  AND ur.timestamp >= mw.starts_at - make_interval(secs => $3::float8)
+				  AND ur.timestamp <= mw.ends_at
+			))
+		  )
+	`, tenantID, since, usageWindow)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var inc TenantIncident
+		var scope string
+		var target *string
+		var endsAt time.Time
+		if err := rows.Scan(&inc.ID, &inc.Title, &inc.Description, &scope, &target, &inc.StartedAt, &endsAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		inc.Type = incidentMaintenance
+		switch scope {
+		case maintenanceScopeModel:
+			inc.Model = target
+		case maintenanceScopeRegion:
+			inc.Region = target
+		}
+		inc.ResolvedAt = &endsAt
+		inc.Status = incidentStatus(inc.ResolvedAt, now)
+		incidents = append(incidents, inc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Capacity dips shorter than the alert delay were never announced
+	rows, err = g.db.Pool.Query(ctx, `
+		SELECT ci.id, d.id, d.name, d.model_name, ci.started_at, ci.resolved_at
+		FROM deployment_capacity_incidents ci
+		JOIN deployments d ON d.id = ci.deployment_id
+		WHERE ci.tenant_id = $1 AND ci.notified_at IS NOT NULL
+		  AND COALESCE(ci.resolved_at, NOW()) >= $2
+	`, tenantID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var inc TenantIncident
+		var deploymentID uuid.UUID
+		var deploymentName, model string
+		if err := rows.Scan(&inc.ID, &deploymentID, &deploymentName, &model, &inc.StartedAt, &inc.ResolvedAt); err != nil {
+			return nil, err
+		}
+		inc.Type = incidentDeploymentCapacity
+		inc.Title = "Reduced capacity on deployment " + deploymentName
+		inc.Model = &model
+		inc.DeploymentID = &deploymentID
+		inc.Status = incidentStatus(inc.ResolvedAt, now)
+		incidents = append(incidents, inc)
+	}
+	return incidents, rows.Err()
+}
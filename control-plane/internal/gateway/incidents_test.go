@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIncidentStatus(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	if got := incidentStatus(nil, now); got != incidentOngoing {
+		t.Errorf("unresolved incident status = %q, want ongoing", got)
+	}
+	if got := incidentStatus(&past, now); got != incidentResolved {
+		t.Errorf("resolved incident status = %q, want resolved", got)
+	}
+	// Maintenance windows resolve at their scheduled end
+	if got := incidentStatus(&future, now); got != incidentOngoing {
+		t.Errorf("active maintenance status = %q, want ongoing", got)
+	}
+}
+
+func TestSortIncidents(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	incidents := []TenantIncident{
+		{Title: "old", Status: incidentResolved, StartedAt: base.Add(-48 * time.Hour)},
+		{Title: "resolved", Status: incidentResolved, StartedAt: base},
+		{Title: "ongoing", Status: incidentOngoing, StartedAt: base},
+		{Title: "newest", Status: incidentResolved, StartedAt: base.Add(time.Hour)},
+	}
+
+	sorted := sortIncidents(incidents, 3)
+	want := []string{"newest", "ongoing", "resolved"}
+	if len(sorted) != len(want) {
+		t.Fatalf("got %d incidents, want %d", len(sorted), len(want))
+	}
+	for i, title := range want {
+		if sorted[i].Title != title {
+			t.Errorf("incident %d = %q, want %q", i, sorted[i].Title, title)
+		}
+	}
+}
+
+func TestModelIncidentTitle(t *testing.T) {
+	if got := modelIncidentTitle(incidentOutage, "llama-3-8b"); got != "Outage of llama-3-8b" {
+		t.Errorf("outage title = %q", got)
+	}
+	if got := modelIncidentTitle(incidentElevatedErrors, "llama-3-8b"); got != "Elevated error rates on llama-3-8b" {
+		t.Errorf("elevated errors title = %q", got)
+	}
+}
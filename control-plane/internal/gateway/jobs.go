@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/jobs"
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Background job kinds run by the gateway
+const (
+	jobRecordUsage      = "usage.record"
+	jobTouchAPIKey      = "api_key.touch"
+	jobLaunchDeployNode = "deployment.launch_node"
+	jobAccountExport    = "account.export"
+	jobUsageExport      = "usage.export"
+	jobCompatRun        = "runtime.compat_run"
+	jobStripeReconcile  = "billing.stripe_reconcile"
+)
+
+// touchAPIKeyArgs are the arguments of an api_key.touch job
+type touchAPIKeyArgs struct {
+	KeyID  uuid.UUID `json:"key_id"`
+	UsedAt time.Time `json:"used_at"`
+}
+
+// registerJobs installs the gateway's job handlers on the queue
+func (g *Gateway) registerJobs() {
+	// Usage rows are billed, so keep retrying through database blips
+	g.jobs.Register(jobRecordUsage, g.runRecordUsage, jobs.RetryPolicy{
+		MaxAttempts: 10,
+		Timeout:     10 * time.Second,
+	})
+	g.jobs.Register(jobTouchAPIKey, g.runTouchAPIKey, jobs.RetryPolicy{
+		MaxAttempts: 3,
+		Timeout:     5 * time.Second,
+	})
+	// A retry reuses the node ID, so it relaunches the same cluster
+	g.jobs.Register(jobLaunchDeployNode, g.runLaunchDeploymentNode, jobs.RetryPolicy{
+		MaxAttempts: 3,
+		Timeout:     20 * time.Minute,
+		BaseDelay:   time.Minute,
+	})
+	g.jobs.Register(jobAccountExport, g.runAccountExport, jobs.RetryPolicy{
+		MaxAttempts: 3,
+		Timeout:     10 * time.Minute,
+		BaseDelay:   30 * time.Second,
+	})
+	// Extracts of a year of usage take a while to stream and upload
+	g.jobs.Register(jobUsageExport, g.runUsageExport, jobs.RetryPolicy{
+		MaxAttempts: 3,
+		Timeout:     30 * time.Minute,
+		BaseDelay:   30 * time.Second,
+	})
+	// A run starts over when picked up again, so one attempt launches one
+	// set of canaries
+	g.jobs.Register(jobCompatRun, g.runCompatRun, jobs.RetryPolicy{
+		MaxAttempts: 1,
+		Timeout:     2 * time.Hour,
+	})
+	// A failed run is recorded in its report; admins start a new one
+	g.jobs.Register(jobStripeReconcile, g.runStripeReconcile, jobs.RetryPolicy{
+		MaxAttempts: 1,
+		Timeout:     30 * time.Minute,
+	})
+}
+
+// StartJobs starts the background job workers
+func (g *Gateway) StartJobs(ctx context.Context) {
+	g.jobs.Start(ctx)
+}
+
+// runRecordUsage inserts a usage record. Model, region and GPU type are
+// snapshotted from the serving node when not supplied, so later node changes
+// don't rewrite history. The node's vLLM and torch versions are always
+// snapshotted, to tie regressions to a runtime.
+func (g *Gateway) runRecordUsage(ctx context.Context, job *jobs.Job) error {
+	var usage models.UsageRecord
+	if err := job.Decode(&usage); err != nil {
+		return err
+	}
+
+	// A retry after a lost acknowledgement must not double bill
+	_, err := g.db.Pool.Exec(ctx, `
+		INSERT INTO usage_records (
+			id, request_id, timestamp, tenant_id, environment_id,
+			api_key_id, node_id, model_id, region_id, gpu_type,
+			status_code, prompt_tokens, completion_tokens,
+			total_tokens, latency_ms, metadata, cost_microdollars,
+			vllm_version, torch_version
+		)
+		SELECT $1, $2, $3, $4, $5, $6, $7,
+			COALESCE($8, n.model_id),
+			COALESCE($9, n.region_id),
+			COALESCE($10, n.gpu_type),
+			$11, $12, $13, $14, $15, $16, $17,
+			n.vllm_version, n.torch_version
+		FROM (SELECT 1) AS one
+		LEFT JOIN nodes n ON n.id = $7
+		ON CONFLICT DO NOTHING
+	`,
+		usage.ID, usage.RequestID, usage.Timestamp,
+		usage.TenantID, usage.EnvironmentID, usage.APIKeyID,
+		usage.NodeID, usage.ModelID, usage.RegionID, usage.GPUType,
+		usage.StatusCode, usage.PromptTokens, usage.CompletionTokens,
+		usage.TotalTokens, usage.LatencyMs, usage.Metadata, usage.CostMicrodollars,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// runTouchAPIKey updates the last_used_at timestamp of an API key. Jobs may
+// run out of order, so an older timestamp never overwrites a newer one.
+func (g *Gateway) runTouchAPIKey(ctx context.Context, job *jobs.Job) error {
+	var args touchAPIKeyArgs
+	if err := job.Decode(&args); err != nil {
+		return err
+	}
+
+	_, err := g.db.Pool.Exec(ctx, `
+		UPDATE api_keys
+		SET last_used_at = GREATEST(COALESCE(last_used_at, 'epoch'), $2)
+		WHERE id = $1
+	`, args.KeyID, args.UsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update last_used_at: %w", err)
+	}
+	return nil
+}
+
+// runLaunchDeploymentNode launches one node of a deployment and counts it
+// toward the deployment's replicas once it is up
+func (g *Gateway) runLaunchDeploymentNode(ctx context.Context, job *jobs.Job) error {
+	var cfg orchestrator.NodeConfig
+	if err := job.Decode(&cfg); err != nil {
+		return err
+	}
+
+	clusterName, err := g.orchestrator.LaunchNode(ctx, cfg)
+	if err != nil {
+		// An invalid configuration fails the same way on every retry
+		var cfgErr *orchestrator.ConfigError
+		if errors.As(err, &cfgErr) {
+			g.logger.Error("invalid node configuration for deployment",
+				zap.Error(err),
+				zap.String("deployment_id", cfg.DeploymentID),
+				zap.String("node_id", cfg.NodeID),
+			)
+			return jobs.Permanent(err)
+		}
+		if job.FinalAttempt() {
+			g.logger.Error("giving up launching node for deployment",
+				zap.Error(err),
+				zap.String("deployment_id", cfg.DeploymentID),
+				zap.String("node_id", cfg.NodeID),
+			)
+		}
+		return fmt.Errorf("failed to launch node: %w", err)
+	}
+
+	g.logger.Info("node launched for deployment",
+		zap.String("deployment_id", cfg.DeploymentID),
+		zap.String("cluster_name", clusterName),
+	)
+
+	_, err = g.db.Pool.Exec(ctx, `
+		UPDATE deployments SET
+			current_replicas = current_replicas + 1,
+			status = 'active',
+			updated_at = NOW()
+		WHERE id = $1
+	`, cfg.DeploymentID)
+	if err != nil {
+		// The node is running; the deployment controller reconciles the count
+		g.logger.Error("failed to update deployment status",
+			zap.Error(err),
+			zap.String("deployment_id", cfg.DeploymentID),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultLaunchSLO is the cold start target when the request sets none
+	defaultLaunchSLO = 5 * time.Minute
+
+	// maxLaunchMetricsLaunches caps how many launches one report aggregates
+	maxLaunchMetricsLaunches = 5000
+
+	// slowestLaunchesShown is how many of the slowest launches a report lists
+	slowestLaunchesShown = 10
+
+	// firstTokenRecheck is how long an endpoint is skipped after checking it
+	// for a launch waiting on its first response
+	firstTokenRecheck = time.Minute
+)
+
+// firstTokenChecks remembers which endpoints were recently checked for a
+// pending first response, so the inference path doesn't query per request
+type firstTokenChecks struct {
+	mu      sync.Mutex
+	checked map[string]time.Time
+}
+
+func newFirstTokenChecks() *firstTokenChecks {
+	return &firstTokenChecks{checked: make(map[string]time.Time)}
+}
+
+// due reports whether endpoint should be checked now and marks it checked
+func (c *firstTokenChecks) due(endpoint string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if last, ok := c.checked[endpoint]; ok && now.Sub(last) < firstTokenRecheck {
+		return false
+	}
+	c.checked[endpoint] = now
+	return true
+}
+
+// reset makes the next response from endpoint check for a pending launch
+func (c *firstTokenChecks) reset(endpoint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.checked, endpoint)
+}
+
+// observeFirstToken records a node's first successful response for its
+// launch metrics. It is best effort and never delays the request.
+func (g *Gateway) observeFirstToken(endpoint string, status int) {
+	if g.launches == nil || status >= http.StatusBadRequest || !g.firstTokens.due(endpoint, time.Now()) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		recorded, err := g.launches.FirstToken(ctx, endpoint)
+		if err != nil {
+			g.logger.Debug("failed to record first token", zap.Error(err), zap.String("endpoint", endpoint))
+			return
+		}
+		if recorded {
+			g.logger.Info("node served its first response", zap.String("endpoint", endpoint))
+		}
+	}()
+}
+
+// recordLaunchHealthy records a launched node registering with vLLM healthy
+func (g *Gateway) recordLaunchHealthy(ctx context.Context, nodeID uuid.UUID, endpoint string, setupStartedAt, vllmStartedAt *time.Time) {
+	if err := g.launches.VLLMHealthy(ctx, nodeID, setupStartedAt, vllmStartedAt); err != nil {
+		g.logger.Warn("failed to record launch timing", zap.Error(err), zap.String("node_id", nodeID.String()))
+	}
+	g.firstTokens.reset(endpoint)
+}
+
+// parseLaunchSLO reads the cold start target from the slo query parameter
+func parseLaunchSLO(r *http.Request) (time.Duration, bool) {
+	raw := r.URL.Query().Get("slo")
+	if raw == "" {
+		return defaultLaunchSLO, true
+	}
+	slo, err := time.ParseDuration(raw)
+	if err != nil || slo <= 0 {
+		return 0, false
+	}
+	return slo, true
+}
+
+// launchTimingView is a launch with its measured phases in seconds
+type launchTimingView struct {
+	nodes.LaunchTiming
+	Durations   map[string]float64 `json:"durations_seconds"`
+	SLOBreached bool               `json:"slo_breached"`
+}
+
+func newLaunchTimingView(t nodes.LaunchTiming, slo time.Duration, now time.Time) launchTimingView {
+	durations := t.Durations()
+	view := launchTimingView{
+		LaunchTiming: t,
+		Durations:    make(map[string]float64, len(durations)),
+	}
+	for name, d := range durations {
+		view.Durations[name] = d.Seconds()
+	}
+
+	switch {
+	case t.VLLMHealthyAt != nil:
+		view.SLOBreached = t.VLLMHealthyAt.Sub(t.RequestedAt) > slo
+	case t.FailedAt != nil:
+		view.SLOBreached = true
+	default:
+		view.SLOBreached = now.Sub(t.RequestedAt) > slo
+	}
+	return view
+}
+
+// slowestLaunches returns the launches with the longest cold start, with
+// launches still pending measured up to now. Failed launches are left out.
+func slowestLaunches(timings []nodes.LaunchTiming, slo time.Duration, now time.Time, n int) []launchTimingView {
+	coldStart := func(t *nodes.LaunchTiming) time.Duration {
+		if t.VLLMHealthyAt != nil {
+			return t.VLLMHealthyAt.Sub(t.RequestedAt)
+		}
+		return now.Sub(t.RequestedAt)
+	}
+
+	sorted := make([]nodes.LaunchTiming, 0, len(timings))
+	for _, t := range timings {
+		if t.FailedAt == nil {
+			sorted = append(sorted, t)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return coldStart(&sorted[i]) > coldStart(&sorted[j])
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+
+	views := make([]launchTimingView, 0, len(sorted))
+	for _, t := range sorted {
+		views = append(views, newLaunchTimingView(t, slo, now))
+	}
+	return views
+}
+
+// handleLaunchMetrics reports where launch cold start time goes, per phase
+// and per provider/region/GPU, against the launch SLO
+// Platform Admin Only - GET /admin/platform/launch-metrics?period=7d&provider=aws&region=&gpu_type=&slo=5m
+func (g *Gateway) handleLaunchMetrics(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "7d"
+	}
+	slo, ok := parseLaunchSLO(r)
+	if !ok {
+		g.writeError(w, http.StatusBadRequest, "invalid slo: use a duration such as 5m")
+		return
+	}
+
+	startDate := calculateStartDate(period)
+	timings, err := g.launches.List(r.Context(), nodes.LaunchFilter{
+		Since:    startDate,
+		Provider: r.URL.Query().Get("provider"),
+		Region:   r.URL.Query().Get("region"),
+		GPUType:  r.URL.Query().Get("gpu_type"),
+		Limit:    maxLaunchMetricsLaunches,
+	})
+	if err != nil {
+		g.logger.Error("failed to list launch timings", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load launch metrics")
+		return
+	}
+
+	now := time.Now()
+	metrics := nodes.SummarizeLaunches(timings, slo, now)
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"period":     period,
+		"start_date": startDate.Format(time.RFC3339),
+		"end_date":   now.Format(time.RFC3339),
+		"phases":     nodes.LaunchPhases,
+		"overall":    metrics.Overall,
+		"groups":     metrics.Groups,
+		"slowest":    slowestLaunches(timings, slo, now, slowestLaunchesShown),
+		"truncated":  len(timings) == maxLaunchMetricsLaunches,
+	})
+}
+
+// handleGetNodeLaunchTiming returns the cold start checkpoints of one node
+// Platform Admin Only - GET /admin/nodes/{id}/launch-timings?slo=5m
+func (g *Gateway) handleGetNodeLaunchTiming(w http.ResponseWriter, r *http.Request) {
+	nodeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid node ID")
+		return
+	}
+	slo, ok := parseLaunchSLO(r)
+	if !ok {
+		g.writeError(w, http.StatusBadRequest, "invalid slo: use a duration such as 5m")
+		return
+	}
+
+	timing, err := g.launches.Get(r.Context(), nodeID)
+	if err != nil {
+		if errors.Is(err, nodes.ErrLaunchTimingNotFound) {
+			g.writeError(w, http.StatusNotFound, "no launch recorded for node")
+			return
+		}
+		g.logger.Error("failed to get launch timing", zap.Error(err), zap.String("node_id", nodeID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to get launch timing")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, newLaunchTimingView(*timing, slo, time.Now()))
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/google/uuid"
+)
+
+func TestFirstTokenChecks(t *testing.T) {
+	c := newFirstTokenChecks()
+	now := time.Now()
+	endpoint := "http://10.0.0.4:8000"
+
+	if !c.due(endpoint, now) {
+		t.Fatal("first check should be due")
+	}
+	if c.due(endpoint, now.Add(time.Second)) {
+		t.Error("check within the recheck interval should be skipped")
+	}
+	if !c.due("http://10.0.0.5:8000", now) {
+		t.Error("other endpoints are tracked separately")
+	}
+	if !c.due(endpoint, now.Add(firstTokenRecheck)) {
+		t.Error("check after the recheck interval should be due")
+	}
+
+	c.reset(endpoint)
+	if !c.due(endpoint, now.Add(firstTokenRecheck+time.Second)) {
+		t.Error("check after reset should be due")
+	}
+}
+
+func TestParseLaunchSLO(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", defaultLaunchSLO, true},
+		{"?slo=3m", 3 * time.Minute, true},
+		{"?slo=90s", 90 * time.Second, true},
+		{"?slo=0s", 0, false},
+		{"?slo=soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/admin/platform/launch-metrics"+tt.query, nil)
+		got, ok := parseLaunchSLO(r)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseLaunchSLO(%q) = %v, %v; want %v, %v", tt.query, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestSlowestLaunches(t *testing.T) {
+	now := time.Now()
+	launch := func(requestedAgo, coldStart time.Duration) nodes.LaunchTiming {
+		l := nodes.LaunchTiming{NodeID: uuid.New(), RequestedAt: now.Add(-requestedAgo)}
+		if coldStart > 0 {
+			healthy := l.RequestedAt.Add(coldStart)
+			l.VLLMHealthyAt = &healthy
+		}
+		return l
+	}
+
+	fast := launch(time.Hour, 2*time.Minute)
+	slow := launch(time.Hour, 9*time.Minute)
+	pending := launch(6*time.Minute, 0)
+	failed := launch(time.Hour, 0)
+	failed.FailedAt = &now
+
+	got := slowestLaunches([]nodes.LaunchTiming{fast, failed, pending, slow}, 5*time.Minute, now, 2)
+	if len(got) != 2 {
+		t.Fatalf("slowestLaunches() returned %d launches, want 2", len(got))
+	}
+	if got[0].NodeID != slow.NodeID || got[1].NodeID != pending.NodeID {
+		t.Errorf("slowestLaunches() order = %s, %s; want slow then pending", got[0].NodeID, got[1].NodeID)
+	}
+	if !got[0].SLOBreached || !got[1].SLOBreached {
+		t.Error("launches past the target should be marked as breaching the SLO")
+	}
+	if got[0].Durations[nodes.LaunchColdStart] != 540 {
+		t.Errorf("cold start = %v, want 540", got[0].Durations[nodes.LaunchColdStart])
+	}
+}
@@ -0,0 +1,362 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// LaunchProfile is a named set of NodeConfig defaults that can be referenced
+// by name in POST /v1/instances. Profiles with a nil TenantID are platform
+// defaults managed by admins and visible to every tenant.
+type LaunchProfile struct {
+	ID                string     `json:"id"`
+	TenantID          *uuid.UUID `json:"tenant_id,omitempty"`
+	Name              string     `json:"name"`
+	Description       *string    `json:"description,omitempty"`
+	Provider          *string    `json:"provider,omitempty"`
+	Region            *string    `json:"region,omitempty"`
+	GPU               *string    `json:"gpu,omitempty"`
+	GPUCount          *int       `json:"gpu_count,omitempty"`
+	UseSpot           *bool      `json:"use_spot,omitempty"`
+	DiskSize          *int       `json:"disk_size,omitempty"`
+	VLLMArgs          *string    `json:"vllm_args,omitempty"`
+	IdleMinutesToStop *int       `json:"idle_minutes_to_autostop,omitempty"`
+	Scope             string     `json:"scope"` // "tenant" or "platform"
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// LaunchProfileRequest is the body for creating or replacing a launch profile
+type LaunchProfileRequest struct {
+	Name              string  `json:"name"`
+	Description       *string `json:"description,omitempty"`
+	Provider          *string `json:"provider,omitempty"`
+	Region            *string `json:"region,omitempty"`
+	GPU               *string `json:"gpu,omitempty"`
+	GPUCount          *int    `json:"gpu_count,omitempty"`
+	UseSpot           *bool   `json:"use_spot,omitempty"`
+	DiskSize          *int    `json:"disk_size,omitempty"`
+	VLLMArgs          *string `json:"vllm_args,omitempty"`
+	IdleMinutesToStop *int    `json:"idle_minutes_to_autostop,omitempty"`
+}
+
+// ApplyTo pre-fills any launch request fields the caller left unset.
+// Values explicitly provided in the request always win over the profile.
+func (p *LaunchProfile) ApplyTo(req *LaunchInstanceRequest) {
+	if req.Provider == "" && req.CredentialID == nil && p.Provider != nil {
+		req.Provider = *p.Provider
+	}
+	if req.Region == "" && p.Region != nil {
+		req.Region = *p.Region
+	}
+	if req.GPU == "" && p.GPU != nil {
+		req.GPU = *p.GPU
+	}
+	if req.GPUCount == 0 && p.GPUCount != nil {
+		req.GPUCount = *p.GPUCount
+	}
+	if req.UseSpot == nil && p.UseSpot != nil {
+		useSpot := *p.UseSpot
+		req.UseSpot = &useSpot
+	}
+	if req.DiskSize == nil && p.DiskSize != nil {
+		diskSize := *p.DiskSize
+		req.DiskSize = &diskSize
+	}
+	if req.VLLMArgs == "" && p.VLLMArgs != nil {
+		req.VLLMArgs = *p.VLLMArgs
+	}
+	if req.IdleMinutesToStop == 0 && p.IdleMinutesToStop != nil {
+		req.IdleMinutesToStop = *p.IdleMinutesToStop
+	}
+}
+
+func (req *LaunchProfileRequest) validate() error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(req.Name) > 100 {
+		return fmt.Errorf("name must be at most 100 characters")
+	}
+	if req.GPUCount != nil && *req.GPUCount <= 0 {
+		return fmt.Errorf("gpu_count must be positive")
+	}
+	if req.DiskSize != nil && *req.DiskSize <= 0 {
+		return fmt.Errorf("disk_size must be positive")
+	}
+	return nil
+}
+
+const launchProfileColumns = `
+	id, tenant_id, name, description, provider, region, gpu, gpu_count,
+	use_spot, disk_size, vllm_args, idle_minutes_to_autostop, created_at, updated_at
+`
+
+func scanLaunchProfile(row pgx.Row) (*LaunchProfile, error) {
+	var p LaunchProfile
+	var id uuid.UUID
+	err := row.Scan(
+		&id, &p.TenantID, &p.Name, &p.Description, &p.Provider, &p.Region, &p.GPU, &p.GPUCount,
+		&p.UseSpot, &p.DiskSize, &p.VLLMArgs, &p.IdleMinutesToStop, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	p.ID = id.String()
+	p.Scope = "platform"
+	if p.TenantID != nil {
+		p.Scope = "tenant"
+	}
+	return &p, nil
+}
+
+// resolveLaunchProfile looks up a profile by name for a tenant. A tenant-owned
+// profile shadows a platform default with the same name.
+func (g *Gateway) resolveLaunchProfile(ctx context.Context, tenantID uuid.UUID, name string) (*LaunchProfile, error) {
+	query := `SELECT ` + launchProfileColumns + `
+		FROM launch_profiles
+		WHERE name = $1 AND (tenant_id = $2 OR tenant_id IS NULL)
+		ORDER BY tenant_id NULLS LAST
+		LIMIT 1
+	`
+	return scanLaunchProfile(g.db.Pool.QueryRow(ctx, query, name, tenantID))
+}
+
+// listLaunchProfiles returns platform profiles, plus the tenant's own when tenantID is set
+func (g *Gateway) listLaunchProfiles(ctx context.Context, tenantID *uuid.UUID) ([]*LaunchProfile, error) {
+	query := `SELECT ` + launchProfileColumns + ` FROM launch_profiles WHERE tenant_id IS NULL ORDER BY name`
+	args := []interface{}{}
+	if tenantID != nil {
+		query = `SELECT ` + launchProfileColumns + `
+			FROM launch_profiles
+			WHERE tenant_id = $1 OR tenant_id IS NULL
+			ORDER BY tenant_id NULLS LAST, name
+		`
+		args = append(args, *tenantID)
+	}
+
+	rows, err := g.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := []*LaunchProfile{}
+	for rows.Next() {
+		p, err := scanLaunchProfile(rows)
+		if err != nil {
+			g.logger.Warn("failed to scan launch profile", zap.Error(err))
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// upsertLaunchProfile creates or replaces a profile owned by tenantID (nil = platform)
+func (g *Gateway) upsertLaunchProfile(ctx context.Context, tenantID *uuid.UUID, req LaunchProfileRequest) (*LaunchProfile, error) {
+	conflict := `ON CONFLICT (tenant_id, name) WHERE tenant_id IS NOT NULL`
+	if tenantID == nil {
+		conflict = `ON CONFLICT (name) WHERE tenant_id IS NULL`
+	}
+
+	query := `
+		INSERT INTO launch_profiles (
+			tenant_id, name, description, provider, region, gpu, gpu_count,
+			use_spot, disk_size, vllm_args, idle_minutes_to_autostop
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		` + conflict + ` DO UPDATE SET
+			description = EXCLUDED.description,
+			provider = EXCLUDED.provider,
+			region = EXCLUDED.region,
+			gpu = EXCLUDED.gpu,
+			gpu_count = EXCLUDED.gpu_count,
+			use_spot = EXCLUDED.use_spot,
+			disk_size = EXCLUDED.disk_size,
+			vllm_args = EXCLUDED.vllm_args,
+			idle_minutes_to_autostop = EXCLUDED.idle_minutes_to_autostop,
+			updated_at = NOW()
+		RETURNING ` + launchProfileColumns
+
+	return scanLaunchProfile(g.db.Pool.QueryRow(ctx, query,
+		tenantID, req.Name, req.Description, req.Provider, req.Region, req.GPU, req.GPUCount,
+		req.UseSpot, req.DiskSize, req.VLLMArgs, req.IdleMinutesToStop,
+	))
+}
+
+// deleteLaunchProfile removes a profile owned by tenantID (nil = platform)
+func (g *Gateway) deleteLaunchProfile(ctx context.Context, tenantID *uuid.UUID, name string) (bool, error) {
+	query := `DELETE FROM launch_profiles WHERE name = $1 AND tenant_id IS NULL`
+	args := []interface{}{name}
+	if tenantID != nil {
+		query = `DELETE FROM launch_profiles WHERE name = $1 AND tenant_id = $2`
+		args = append(args, *tenantID)
+	}
+
+	result, err := g.db.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// === Tenant handlers ===
+
+// handleListTenantLaunchProfiles lists the tenant's profiles and platform defaults
+// GET /v1/launch-profiles
+func (g *Gateway) handleListTenantLaunchProfiles(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	profiles, err := g.listLaunchProfiles(r.Context(), &tenantID)
+	if err != nil {
+		g.logger.Error("failed to list launch profiles",
+			zap.Error(err),
+			zap.String("tenant_id", tenantID.String()),
+		)
+		g.writeError(w, http.StatusInternalServerError, "failed to list launch profiles")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": profiles,
+	})
+}
+
+// handleGetTenantLaunchProfile resolves a profile by name for the tenant
+// GET /v1/launch-profiles/{name}
+func (g *Gateway) handleGetTenantLaunchProfile(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	profile, err := g.resolveLaunchProfile(r.Context(), tenantID, chi.URLParam(r, "name"))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			g.writeError(w, http.StatusNotFound, "launch profile not found")
+			return
+		}
+		g.logger.Error("failed to get launch profile", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to get launch profile")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, profile)
+}
+
+// handleSaveTenantLaunchProfile creates or replaces a tenant-owned profile
+// POST /v1/launch-profiles
+func (g *Gateway) handleSaveTenantLaunchProfile(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	g.saveLaunchProfile(w, r, &tenantID)
+}
+
+// handleDeleteTenantLaunchProfile deletes a tenant-owned profile
+// DELETE /v1/launch-profiles/{name}
+func (g *Gateway) handleDeleteTenantLaunchProfile(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	g.removeLaunchProfile(w, r, &tenantID)
+}
+
+// === Admin handlers (platform-default profiles) ===
+
+// handleListPlatformLaunchProfiles lists platform-default profiles
+// Admin API - GET /admin/launch-profiles
+func (g *Gateway) handleListPlatformLaunchProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := g.listLaunchProfiles(r.Context(), nil)
+	if err != nil {
+		g.logger.Error("failed to list platform launch profiles", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list launch profiles")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": profiles,
+	})
+}
+
+// handleSavePlatformLaunchProfile creates or replaces a platform-default profile
+// Admin API - POST /admin/launch-profiles
+func (g *Gateway) handleSavePlatformLaunchProfile(w http.ResponseWriter, r *http.Request) {
+	g.saveLaunchProfile(w, r, nil)
+}
+
+// handleDeletePlatformLaunchProfile deletes a platform-default profile
+// Admin API - DELETE /admin/launch-profiles/{name}
+func (g *Gateway) handleDeletePlatformLaunchProfile(w http.ResponseWriter, r *http.Request) {
+	g.removeLaunchProfile(w, r, nil)
+}
+
+func (g *Gateway) saveLaunchProfile(w http.ResponseWriter, r *http.Request, tenantID *uuid.UUID) {
+	var req LaunchProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	profile, err := g.upsertLaunchProfile(r.Context(), tenantID, req)
+	if err != nil {
+		g.logger.Error("failed to save launch profile",
+			zap.Error(err),
+			zap.String("name", req.Name),
+		)
+		g.writeError(w, http.StatusInternalServerError, "failed to save launch profile")
+		return
+	}
+
+	g.logger.Info("launch profile saved",
+		zap.String("profile_id", profile.ID),
+		zap.String("name", profile.Name),
+		zap.String("scope", profile.Scope),
+	)
+
+	g.writeJSON(w, http.StatusOK, profile)
+}
+
+func (g *Gateway) removeLaunchProfile(w http.ResponseWriter, r *http.Request, tenantID *uuid.UUID) {
+	name := chi.URLParam(r, "name")
+
+	deleted, err := g.deleteLaunchProfile(r.Context(), tenantID, name)
+	if err != nil {
+		g.logger.Error("failed to delete launch profile", zap.Error(err), zap.String("name", name))
+		g.writeError(w, http.StatusInternalServerError, "failed to delete launch profile")
+		return
+	}
+	if !deleted {
+		g.writeError(w, http.StatusNotFound, "launch profile not found")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  "deleted",
+		"message": "launch profile deleted successfully",
+	})
+}
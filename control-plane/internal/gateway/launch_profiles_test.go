@@ -0,0 +1,50 @@
+package gateway
+
+import "testing"
+
+func TestLaunchProfileApplyToFillsUnsetFields(t *testing.T) {
+	provider, region, gpu := "aws", "us-east-1", "A100"
+	gpuCount, diskSize := 2, 512
+	useSpot := false
+
+	profile := &LaunchProfile{
+		Provider: &provider,
+		Region:   &region,
+		GPU:      &gpu,
+		GPUCount: &gpuCount,
+		UseSpot:  &useSpot,
+		DiskSize: &diskSize,
+	}
+
+	req := LaunchInstanceRequest{Model: "meta-llama/Llama-3-8b-instruct"}
+	profile.ApplyTo(&req)
+
+	if req.Provider != "aws" || req.Region != "us-east-1" || req.GPU != "A100" {
+		t.Fatalf("expected profile placement fields, got %+v", req)
+	}
+	if req.GPUCount != 2 {
+		t.Errorf("expected gpu_count 2, got %d", req.GPUCount)
+	}
+	if req.UseSpot == nil || *req.UseSpot {
+		t.Errorf("expected use_spot=false from profile")
+	}
+	if req.DiskSize == nil || *req.DiskSize != 512 {
+		t.Errorf("expected disk_size 512 from profile")
+	}
+}
+
+func TestLaunchProfileApplyToKeepsExplicitValues(t *testing.T) {
+	provider, region := "aws", "us-east-1"
+	profile := &LaunchProfile{Provider: &provider, Region: &region}
+
+	credentialID := "7b0c1f6e-5a7f-4c1e-9d7e-1f2a3b4c5d6e"
+	req := LaunchInstanceRequest{Region: "eu-west-1", CredentialID: &credentialID}
+	profile.ApplyTo(&req)
+
+	if req.Region != "eu-west-1" {
+		t.Errorf("explicit region should win, got %s", req.Region)
+	}
+	if req.Provider != "" {
+		t.Errorf("provider must come from credential when credential_id is set, got %s", req.Provider)
+	}
+}
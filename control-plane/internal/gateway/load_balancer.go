@@ -3,12 +3,15 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"math/rand"
 	"net/http"
 	"sync"
 	"time"
 
+	nodepkg "github.com/crosslogic/control-plane/internal/nodes"
 	"github.com/crosslogic/control-plane/pkg/database"
 	pkgmetrics "github.com/crosslogic/control-plane/pkg/metrics"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -20,8 +23,13 @@ type EndpointStats struct {
 	QueueDepth   int64 // Number of requests waiting in vLLM queue
 	ActiveRequests int64 // Number of requests currently being processed
 	LastUpdated  time.Time
+	QueueUpdated time.Time // When QueueDepth was last polled
 }
 
+// queueDepthMaxAge is how old a polled queue depth may be and still count
+// towards a model's queue; polling runs every 5 seconds
+const queueDepthMaxAge = 30 * time.Second
+
 // VLLMMetrics represents metrics from vLLM's metrics endpoint
 type VLLMMetrics struct {
 	NumRequestsRunning int64 `json:"num_requests_running"`
@@ -36,6 +44,46 @@ type IntelligentLoadBalancer struct {
 	mu         sync.RWMutex
 	httpClient *http.Client
 	stopChan   chan struct{}
+
+	// staleHeartbeatThreshold excludes nodes whose last heartbeat is older
+	// than this from routing, before the monitor has marked them unhealthy
+	staleHeartbeatThreshold time.Duration
+
+	// routeQuerier reads routable nodes in place of the pool when set, so
+	// routing can run against a fake database
+	routeQuerier database.Querier
+
+	// overrides are operator pin/weight overrides keyed by endpoint URL
+	overrides map[string]RoutingOverride
+
+	// experiments are live routing experiments keyed by model, and
+	// endpointClasses the node class and price of each active endpoint
+	experiments     map[string]*liveExperiment
+	endpointClasses map[string]endpointClass
+	random          func() float64
+
+	// routes caches each model's routable endpoints for routeCacheTTL
+	routesMu      sync.Mutex
+	routes        map[string]routeCacheEntry
+	routeCacheTTL time.Duration
+
+	// concurrencyLimits caps the requests each endpoint works on at once,
+	// and inflight counts this gateway's requests to each endpoint
+	concurrencyLimits map[string]int
+	inflight          map[string]int64
+
+	// canaries are the endpoints being ramped into routing, and canary how
+	// they are ramped
+	canaries map[string]*canaryState
+	canary   canarySettings
+
+	// egress prices routes between node and client regions, and
+	// endpointLocations places each endpoint (nil until first loaded)
+	egress            *egressTable
+	endpointLocations map[string]endpointLocation
+
+	// halted are the endpoints taken out of routing by active kill switches
+	halted map[string]bool
 }
 
 // NewIntelligentLoadBalancer creates a new load balancer.
@@ -52,10 +100,27 @@ func NewIntelligentLoadBalancer(db *database.Database, logger *zap.Logger) *Inte
 				IdleConnTimeout:     30 * time.Second,
 			},
 		},
-		stopChan: make(chan struct{}),
+		stopChan:                make(chan struct{}),
+		staleHeartbeatThreshold: 30 * time.Second,
+		overrides:               make(map[string]RoutingOverride),
+		experiments:             make(map[string]*liveExperiment),
+		endpointClasses:         make(map[string]endpointClass),
+		random:                  rand.Float64,
+		routes:                  make(map[string]routeCacheEntry),
+		routeCacheTTL:           defaultRouteCacheTTL,
+		concurrencyLimits:       make(map[string]int),
+		inflight:                make(map[string]int64),
+		canaries:                make(map[string]*canaryState),
+		canary:                  defaultCanarySettings(),
 	}
 }
 
+// SetStaleHeartbeatThreshold configures how long a node may go without a
+// heartbeat before it stops receiving routed traffic. Zero disables the check.
+func (lb *IntelligentLoadBalancer) SetStaleHeartbeatThreshold(threshold time.Duration) {
+	lb.staleHeartbeatThreshold = threshold
+}
+
 // StartQueueMonitoring begins background queue depth monitoring
 func (lb *IntelligentLoadBalancer) StartQueueMonitoring(ctx context.Context) {
 	lb.logger.Info("starting queue depth monitoring")
@@ -86,8 +151,25 @@ func (lb *IntelligentLoadBalancer) queueMonitoringLoop(ctx context.Context) {
 
 // updateAllQueueDepths updates queue depth for all active nodes
 func (lb *IntelligentLoadBalancer) updateAllQueueDepths(ctx context.Context) {
+	// Pick up routing overrides made on other gateway replicas
+	if err := lb.LoadOverrides(ctx); err != nil {
+		lb.logger.Warn("failed to refresh routing overrides", zap.Error(err))
+	}
+	if err := lb.LoadExperiments(ctx); err != nil {
+		lb.logger.Warn("failed to refresh routing experiments", zap.Error(err))
+	}
+	if err := lb.LoadConcurrencyLimits(ctx); err != nil {
+		lb.logger.Warn("failed to refresh node concurrency limits", zap.Error(err))
+	}
+	if err := lb.LoadEgressRouting(ctx); err != nil {
+		lb.logger.Warn("failed to refresh egress routing", zap.Error(err))
+	}
+
+	// Admit or cordon nodes at the end of their traffic ramp
+	lb.evaluateCanaries(ctx)
+
 	// Get all active nodes
-	query := `SELECT endpoint FROM nodes WHERE status = 'active' AND endpoint != ''`
+	query := `SELECT endpoint_url FROM nodes WHERE status = 'active' AND endpoint_url != ''`
 	rows, err := lb.db.Pool.Query(ctx, query)
 	if err != nil {
 		lb.logger.Error("failed to fetch active nodes for queue monitoring", zap.Error(err))
@@ -161,6 +243,7 @@ func (lb *IntelligentLoadBalancer) updateQueueDepth(endpoint string) {
 	stats.QueueDepth = metrics.NumRequestsWaiting
 	stats.ActiveRequests = metrics.NumRequestsRunning
 	stats.LastUpdated = time.Now()
+	stats.QueueUpdated = stats.LastUpdated
 
 	// Update Prometheus metrics
 	// Get model name for this endpoint
@@ -179,7 +262,7 @@ func (lb *IntelligentLoadBalancer) getModelNameForEndpoint(endpoint string) stri
 
 	var modelName string
 	err := lb.db.Pool.QueryRow(ctx,
-		"SELECT model_name FROM nodes WHERE endpoint = $1 LIMIT 1",
+		"SELECT model_name FROM nodes WHERE endpoint_url = $1 LIMIT 1",
 		endpoint,
 	).Scan(&modelName)
 
@@ -196,7 +279,7 @@ func (lb *IntelligentLoadBalancer) getNodeIDForEndpoint(endpoint string) string
 
 	var nodeID string
 	err := lb.db.Pool.QueryRow(ctx,
-		"SELECT id FROM nodes WHERE endpoint = $1 LIMIT 1",
+		"SELECT id FROM nodes WHERE endpoint_url = $1 LIMIT 1",
 		endpoint,
 	).Scan(&nodeID)
 
@@ -237,25 +320,72 @@ func (lb *IntelligentLoadBalancer) GetAverageLatency(ctx context.Context, modelN
 	return time.Duration(int64(totalLatency) / int64(count)), nil
 }
 
+// GetQueueDepth returns the requests waiting across a model's healthy
+// nodes, and how many of the nodes have a recently polled queue depth
+func (lb *IntelligentLoadBalancer) GetQueueDepth(ctx context.Context, modelName string) (int64, int, error) {
+	nodes, err := lb.getHealthyNodes(ctx, modelName)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	var waiting int64
+	var reporting int
+	cutoff := time.Now().Add(-queueDepthMaxAge)
+	for _, node := range nodes {
+		if stats, ok := lb.stats[node]; ok && stats.QueueUpdated.After(cutoff) {
+			waiting += stats.QueueDepth
+			reporting++
+		}
+	}
+	return waiting, reporting, nil
+}
+
 // SelectEndpoint chooses the best available endpoint for a model.
 //
 // Strategy: Weighted Score (Latency + Reliability + Queue Depth)
 // - Filters for healthy nodes serving the model
 // - Prefers nodes with lower latency, error rates, and queue depth
 // - Weights: 40% Latency, 30% Queue Depth, 30% Reliability
+// - Scales scores down by the egress cost and latency from the node's
+//   region to the request's routing origin (see egress_routing.go)
+// - Skips nodes at their concurrency limit, returning ErrNodesAtCapacity
+//   when no node has room
 func (lb *IntelligentLoadBalancer) SelectEndpoint(ctx context.Context, modelName string) (string, error) {
+	return lb.SelectWorkloadEndpoint(ctx, modelName, nodepkg.WorkloadText)
+}
+
+// SelectWorkloadEndpoint chooses the best endpoint for a model among the
+// nodes of a workload class, as SelectEndpoint does for text
+func (lb *IntelligentLoadBalancer) SelectWorkloadEndpoint(ctx context.Context, modelName, workload string) (string, error) {
 	// Get active nodes for model
-	nodes, err := lb.getHealthyNodes(ctx, modelName)
+	nodes, err := lb.getWorkloadNodes(ctx, modelName, workload)
 	if err != nil {
 		return "", err
 	}
 
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	// Drop nodes stopped by a kill switch, even if pinned
+	nodes = lb.withoutHalted(nodes)
+	// Apply operator pins and drains
+	nodes = lb.applyRoutingOverrides(nodes)
+	// Route by the model's node class experiment, if any
+	nodes = lb.applyExperiment(modelName, nodes)
+	// Offer newly registered nodes a growing share of traffic
+	nodes = lb.applyCanaryRamp(nodes, time.Now())
 	if len(nodes) == 0 {
 		return "", nil // No nodes available
 	}
+	// Leave nodes at their practical limit alone rather than queue on them
+	if nodes = lb.belowConcurrencyLimit(nodes); len(nodes) == 0 {
+		return "", ErrNodesAtCapacity
+	}
 
-	lb.mu.RLock()
-	defer lb.mu.RUnlock()
+	origin, _ := ctx.Value("routing_origin").(*RoutingOrigin)
 
 	// Calculate scores
 	type nodeScore struct {
@@ -273,7 +403,7 @@ func (lb *IntelligentLoadBalancer) SelectEndpoint(ctx context.Context, modelName
 			// No stats yet, give it a high default score to encourage exploration
 			scores = append(scores, nodeScore{
 				node:       node,
-				score:      2.0,
+				score:      2.0 * lb.endpointWeight(node) * lb.egressFactor(origin, node),
 				queueDepth: 0,
 				latencyMs:  0,
 				errorRate:  0,
@@ -308,6 +438,12 @@ func (lb *IntelligentLoadBalancer) SelectEndpoint(ctx context.Context, modelName
 		// 30% Reliability - Prefer nodes with fewer errors
 		finalScore := (latencyScore * 0.4) + (queueScore * 0.3) + (errorScore * 0.3)
 
+		// Operator weight steers traffic towards or away from this node
+		finalScore *= lb.endpointWeight(node)
+
+		// Remote regions pay for egress and network latency
+		finalScore *= lb.egressFactor(origin, node)
+
 		scores = append(scores, nodeScore{
 			node:       node,
 			score:      finalScore,
@@ -366,26 +502,74 @@ func (lb *IntelligentLoadBalancer) RecordRequest(endpoint string, latency time.D
 		stats.ErrorCount++
 	}
 	stats.LastUpdated = time.Now()
+
+	lb.recordCanaryRequest(endpoint, latency, isError)
 }
 
+// getHealthyNodes returns the text endpoints serving a model, from the
+// route cache when it is fresh. The returned slice must not be modified.
 func (lb *IntelligentLoadBalancer) getHealthyNodes(ctx context.Context, modelName string) ([]string, error) {
+	return lb.getWorkloadNodes(ctx, modelName, nodepkg.WorkloadText)
+}
+
+// getWorkloadNodes returns the endpoints of a workload class serving a
+// model, from the route cache when it is fresh
+func (lb *IntelligentLoadBalancer) getWorkloadNodes(ctx context.Context, modelName, workload string) ([]string, error) {
+	routeKey := modelName
+	if workload != nodepkg.WorkloadText {
+		routeKey = workload + ":" + modelName
+	}
+
+	now := time.Now()
+	if endpoints, ok := lb.cachedRoutes(routeKey, now); ok {
+		return endpoints, nil
+	}
+
+	// Nodes that have heartbeated before but have since gone quiet are
+	// skipped, judged by the database clock that stamped the heartbeat;
+	// nodes that never sent one are left to the monitor. Warm standbys stay
+	// out of routing until promoted. Nodes still in their traffic ramp come
+	// with the time it started.
 	query := `
-		SELECT endpoint FROM nodes
-		WHERE model_name = $1 AND status = 'active' AND endpoint != ''
+		SELECT n.endpoint_url, n.id, a.started_at, n.last_heartbeat_at, NOW() FROM nodes n
+		LEFT JOIN node_admissions a ON a.node_id = n.id AND a.finished_at IS NULL
+		WHERE n.model_name = $1 AND n.status = 'active' AND n.endpoint_url != '' AND NOT n.standby
+		  AND n.workload_class = $2
 	`
-	rows, err := lb.db.Pool.Query(ctx, query, modelName)
+	q := lb.routeQuerier
+	if q == nil {
+		q = lb.db.Pool
+	}
+	rows, err := q.Query(ctx, query, modelName, workload)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var endpoints []string
+	ramps := make(map[string]*rampStart)
 	for rows.Next() {
 		var endpoint string
-		if err := rows.Scan(&endpoint); err != nil {
+		var nodeID uuid.UUID
+		var rampStarted, lastHeartbeat *time.Time
+		var dbNow time.Time
+		if err := rows.Scan(&endpoint, &nodeID, &rampStarted, &lastHeartbeat, &dbNow); err != nil {
+			continue
+		}
+		if nodepkg.HeartbeatStale(lastHeartbeat, lb.staleHeartbeatThreshold, dbNow) {
 			continue
 		}
 		endpoints = append(endpoints, endpoint)
+		ramps[endpoint] = nil
+		if rampStarted != nil {
+			ramps[endpoint] = &rampStart{nodeID: nodeID, started: *rampStarted}
+		}
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lb.noteCanaries(ramps)
+	lb.storeRoutes(routeKey, endpoints, now)
 	return endpoints, nil
 }
@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	nodepkg "github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/crosslogic/control-plane/pkg/testutil"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestRoutingSkipsStaleHeartbeats(t *testing.T) {
+	dbNow := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		at := dbNow.Add(-d)
+		return &at
+	}
+	columns := []string{"endpoint_url", "id", "started_at", "last_heartbeat_at", "now"}
+	route := func(endpoint string, lastHeartbeat *time.Time) []any {
+		return []any{endpoint, uuid.New(), nil, lastHeartbeat, dbNow}
+	}
+
+	db := testutil.NewFakeDB()
+	db.Expect("FROM nodes n").Returns(columns,
+		route("http://fresh", ago(5*time.Second)),
+		route("http://stale", ago(2*time.Minute)),
+		route("http://starting", nil),
+	).Once()
+	db.Expect("FROM nodes n").Returns(columns,
+		route("http://stale", ago(2*time.Minute)),
+	).Once()
+	// The stale node heartbeats again
+	db.Expect("FROM nodes n").Returns(columns,
+		route("http://stale", ago(time.Second)),
+	).Once()
+
+	lb := NewIntelligentLoadBalancer(nil, zap.NewNop())
+	lb.routeQuerier = db
+	lb.SetRouteCacheTTL(0)
+	ctx := context.Background()
+
+	endpoints, err := lb.getWorkloadNodes(ctx, "llama", nodepkg.WorkloadText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"http://fresh", "http://starting"}; !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("routable endpoints = %v, want %v", endpoints, want)
+	}
+
+	// With only the stale node left there is nothing to route to
+	if endpoint, err := lb.SelectEndpoint(ctx, "llama"); err != nil || endpoint != "" {
+		t.Errorf("SelectEndpoint() = %q, %v; want no endpoint", endpoint, err)
+	}
+
+	if endpoint, err := lb.SelectEndpoint(ctx, "llama"); err != nil || endpoint != "http://stale" {
+		t.Errorf("SelectEndpoint() after a fresh heartbeat = %q, %v; want http://stale", endpoint, err)
+	}
+}
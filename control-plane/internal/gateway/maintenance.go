@@ -0,0 +1,743 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Maintenance window scopes
+const (
+	maintenanceScopePlatform = "platform"
+	maintenanceScopeModel    = "model"
+	maintenanceScopeRegion   = "region"
+)
+
+// Maintenance window states as reported by the API. Only scheduled and
+// cancelled are stored; the rest follow from the window's times.
+const (
+	maintenanceUpcoming  = "upcoming"
+	maintenanceActive    = "active"
+	maintenanceCompleted = "completed"
+	maintenanceCancelled = "cancelled"
+)
+
+// Notice milestones. Tenants get one notice when a window is scheduled, a
+// reminder a day before it starts and a notice if it is cancelled.
+const (
+	maintenanceNoticeScheduled = "scheduled"
+	maintenanceNoticeReminder  = "24h"
+	maintenanceNoticeCancelled = "cancelled"
+)
+
+const (
+	// maintenanceInterval is how often notices and pre-scaling are checked
+	maintenanceInterval = 5 * time.Minute
+
+	// maintenanceReminderLead is how long before the start the reminder goes out
+	maintenanceReminderLead = 24 * time.Hour
+
+	// maintenancePrescaleLead is how long before a region window starts its
+	// replacement capacity is launched; nodes take 5-8 minutes to come up
+	maintenancePrescaleLead = time.Hour
+
+	// maintenanceUsageWindow is how far back usage is checked to decide
+	// whether a tenant depends on a model or region
+	maintenanceUsageWindow = 7 * 24 * time.Hour
+)
+
+// MaintenanceWindow is a scheduled period of maintenance on the platform, a
+// model or a region
+type MaintenanceWindow struct {
+	ID               uuid.UUID   `json:"id"`
+	Title            string      `json:"title"`
+	Description      *string     `json:"description,omitempty"`
+	Scope            string      `json:"scope"`
+	Target           *string     `json:"target,omitempty"`
+	StartsAt         time.Time   `json:"starts_at"`
+	EndsAt           time.Time   `json:"ends_at"`
+	Prescale         bool        `json:"prescale"`
+	Status           string      `json:"status"`
+	State            string      `json:"state"`
+	CreatedBy        *string     `json:"created_by,omitempty"`
+	PrescaledAt      *time.Time  `json:"prescaled_at,omitempty"`
+	PrescaledNodeIDs []uuid.UUID `json:"prescaled_node_ids"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// MaintenanceNotice is the public view of a maintenance window
+type MaintenanceNotice struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description,omitempty"`
+	Scope       string    `json:"scope"`
+	Target      *string   `json:"target,omitempty"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	State       string    `json:"state"`
+}
+
+// maintenanceState returns the reported state of a window at now
+func maintenanceState(status string, startsAt, endsAt, now time.Time) string {
+	switch {
+	case status == "cancelled":
+		return maintenanceCancelled
+	case now.Before(startsAt):
+		return maintenanceUpcoming
+	case now.Before(endsAt):
+		return maintenanceActive
+	default:
+		return maintenanceCompleted
+	}
+}
+
+// dueMaintenanceNotice returns the notice that should have reached tenants
+// by now for a scheduled window, or "" once the window has started
+func dueMaintenanceNotice(now, startsAt time.Time) string {
+	if !now.Before(startsAt) {
+		return ""
+	}
+	if startsAt.Sub(now) <= maintenanceReminderLead {
+		return maintenanceNoticeReminder
+	}
+	return maintenanceNoticeScheduled
+}
+
+// prescaleDue reports whether a window's replacement capacity should be
+// launched now
+func (w *MaintenanceWindow) prescaleDue(now time.Time) bool {
+	return w.Prescale && w.PrescaledAt == nil && w.Status == "scheduled" &&
+		!now.Before(w.StartsAt.Add(-maintenancePrescaleLead)) && now.Before(w.EndsAt)
+}
+
+func (w *MaintenanceWindow) notice() MaintenanceNotice {
+	return MaintenanceNotice{
+		ID:          w.ID,
+		Title:       w.Title,
+		Description: w.Description,
+		Scope:       w.Scope,
+		Target:      w.Target,
+		StartsAt:    w.StartsAt,
+		EndsAt:      w.EndsAt,
+		State:       w.State,
+	}
+}
+
+// maintenanceWindowRequest is the body of POST /admin/maintenance
+type maintenanceWindowRequest struct {
+	Title       string    `json:"title"`
+	Description *string   `json:"description"`
+	Scope       string    `json:"scope"`
+	Target      string    `json:"target"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	Prescale    bool      `json:"prescale"`
+}
+
+// validate checks the request's fields; the target's existence is checked
+// against the database separately
+func (req *maintenanceWindowRequest) validate(now time.Time) error {
+	req.Title = strings.TrimSpace(req.Title)
+	req.Target = strings.TrimSpace(req.Target)
+
+	if req.Title == "" {
+		return errors.New("title is required")
+	}
+	switch req.Scope {
+	case maintenanceScopePlatform:
+		if req.Target != "" {
+			return errors.New("target must be empty for platform maintenance")
+		}
+	case maintenanceScopeModel, maintenanceScopeRegion:
+		if req.Target == "" {
+			return fmt.Errorf("target is required for %s maintenance", req.Scope)
+		}
+	default:
+		return errors.New("scope must be one of platform, model, region")
+	}
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() {
+		return errors.New("starts_at and ends_at are required")
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if !req.EndsAt.After(now) {
+		return errors.New("ends_at must be in the future")
+	}
+	// Only a region has other regions to move its traffic to
+	if req.Prescale && req.Scope != maintenanceScopeRegion {
+		return errors.New("prescale is only supported for region maintenance")
+	}
+	return nil
+}
+
+const maintenanceWindowColumns = `
+	id, title, description, scope, target, starts_at, ends_at, prescale,
+	status, created_by, prescaled_at, prescaled_node_ids, created_at, updated_at
+`
+
+func scanMaintenanceWindow(row pgx.Row, now time.Time) (*MaintenanceWindow, error) {
+	var w MaintenanceWindow
+	err := row.Scan(&w.ID, &w.Title, &w.Description, &w.Scope, &w.Target, &w.StartsAt, &w.EndsAt, &w.Prescale,
+		&w.Status, &w.CreatedBy, &w.PrescaledAt, &w.PrescaledNodeIDs, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	w.State = maintenanceState(w.Status, w.StartsAt, w.EndsAt, now)
+	return &w, nil
+}
+
+// queryMaintenanceWindows returns the windows matching the query, in start order
+func (g *Gateway) queryMaintenanceWindows(ctx context.Context, where string, args ...any) ([]*MaintenanceWindow, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT `+maintenanceWindowColumns+`
+		FROM maintenance_windows`+where+`
+		ORDER BY starts_at, created_at
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	windows := []*MaintenanceWindow{}
+	for rows.Next() {
+		w, err := scanMaintenanceWindow(rows, now)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// upcomingMaintenance returns scheduled windows that haven't ended
+func (g *Gateway) upcomingMaintenance(ctx context.Context) ([]*MaintenanceWindow, error) {
+	return g.queryMaintenanceWindows(ctx, ` WHERE status = 'scheduled' AND ends_at > NOW()`)
+}
+
+// handleCreateMaintenanceWindow schedules a maintenance window. Affected
+// tenants are notified by the maintenance loop.
+// Admin API - POST /admin/maintenance
+func (g *Gateway) handleCreateMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req maintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.validate(time.Now()); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var target *string
+	if req.Target != "" {
+		var exists bool
+		query := `SELECT EXISTS(SELECT 1 FROM models WHERE name = $1)`
+		if req.Scope == maintenanceScopeRegion {
+			query = `SELECT EXISTS(SELECT 1 FROM regions WHERE code = $1)`
+		}
+		if err := g.db.Pool.QueryRow(ctx, query, req.Target).Scan(&exists); err != nil {
+			g.logger.Error("failed to look up maintenance target", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to create maintenance window")
+			return
+		}
+		if !exists {
+			g.writeError(w, http.StatusBadRequest, fmt.Sprintf("%s %q not found", req.Scope, req.Target))
+			return
+		}
+		target = &req.Target
+	}
+
+	var createdBy *string
+	if name, ok := ctx.Value("admin_token").(string); ok && name != "" {
+		createdBy = &name
+	}
+
+	window, err := scanMaintenanceWindow(g.db.Pool.QueryRow(ctx, `
+		INSERT INTO maintenance_windows (title, description, scope, target, starts_at, ends_at, prescale, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+maintenanceWindowColumns,
+		req.Title, req.Description, req.Scope, target, req.StartsAt, req.EndsAt, req.Prescale, createdBy,
+	), time.Now())
+	if err != nil {
+		g.logger.Error("failed to create maintenance window", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create maintenance window")
+		return
+	}
+
+	g.logger.Info("maintenance window scheduled",
+		zap.String("window_id", window.ID.String()),
+		zap.String("scope", window.Scope),
+		zap.String("target", req.Target),
+		zap.Time("starts_at", window.StartsAt),
+	)
+
+	// Send the first notices now rather than on the next tick
+	go g.processMaintenance(context.Background())
+
+	g.writeJSON(w, http.StatusCreated, window)
+}
+
+// handleListMaintenanceWindows lists maintenance windows, newest first
+// Admin API - GET /admin/maintenance?state=upcoming&limit=50
+func (g *Gateway) handleListMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	args := database.NewArgs()
+	where := database.NewWhere(args)
+	switch state := r.URL.Query().Get("state"); state {
+	case "":
+	case maintenanceUpcoming:
+		where.Raw("status = 'scheduled' AND starts_at > NOW()")
+	case maintenanceActive:
+		where.Raw("status = 'scheduled' AND starts_at <= NOW() AND ends_at > NOW()")
+	case maintenanceCompleted:
+		where.Raw("status = 'scheduled' AND ends_at <= NOW()")
+	case maintenanceCancelled:
+		where.Raw("status = 'cancelled'")
+	default:
+		g.writeError(w, http.StatusBadRequest, "state must be one of upcoming, active, completed, cancelled")
+		return
+	}
+	if scope := r.URL.Query().Get("scope"); scope != "" {
+		where.Eq("scope", scope)
+	}
+
+	limit := parseIntParam(r, "limit", 50, 1, 200)
+	offset := parseIntParam(r, "offset", 0, 0, 999999)
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT `+maintenanceWindowColumns+`
+		FROM maintenance_windows`+where.String()+`
+		ORDER BY starts_at DESC
+		LIMIT `+args.Add(limit)+` OFFSET `+args.Add(offset),
+		args.Values()...)
+	if err != nil {
+		g.logger.Error("failed to list maintenance windows", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list maintenance windows")
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	windows := []*MaintenanceWindow{}
+	for rows.Next() {
+		window, err := scanMaintenanceWindow(rows, now)
+		if err != nil {
+			g.logger.Error("failed to scan maintenance window", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to list maintenance windows")
+			return
+		}
+		windows = append(windows, window)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data":   windows,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// handleGetMaintenanceWindow returns one maintenance window with the number
+// of tenants notified at each milestone
+// Admin API - GET /admin/maintenance/{id}
+func (g *Gateway) handleGetMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid maintenance window ID")
+		return
+	}
+
+	window, err := scanMaintenanceWindow(g.db.Pool.QueryRow(ctx, `
+		SELECT `+maintenanceWindowColumns+` FROM maintenance_windows WHERE id = $1
+	`, id), time.Now())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			g.writeError(w, http.StatusNotFound, "maintenance window not found")
+			return
+		}
+		g.logger.Error("failed to load maintenance window", zap.Error(err), zap.String("window_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load maintenance window")
+		return
+	}
+
+	notified := map[string]int{}
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT milestone, COUNT(*) FROM maintenance_notifications
+		WHERE window_id = $1
+		GROUP BY milestone
+	`, id)
+	if err != nil {
+		g.logger.Error("failed to count maintenance notices", zap.Error(err), zap.String("window_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load maintenance window")
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var milestone string
+		var count int
+		if err := rows.Scan(&milestone, &count); err != nil {
+			continue
+		}
+		notified[milestone] = count
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"window":           window,
+		"tenants_notified": notified,
+	})
+}
+
+// handleCancelMaintenanceWindow cancels a window that hasn't ended. Tenants
+// that were told about it receive a cancellation notice. Nodes launched by
+// pre-scaling keep running; they are listed on the window for operators.
+// Admin API - POST /admin/maintenance/{id}/cancel
+func (g *Gateway) handleCancelMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid maintenance window ID")
+		return
+	}
+
+	window, err := scanMaintenanceWindow(g.db.Pool.QueryRow(ctx, `
+		UPDATE maintenance_windows
+		SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND status = 'scheduled' AND ends_at > NOW()
+		RETURNING `+maintenanceWindowColumns,
+		id,
+	), time.Now())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			g.writeError(w, http.StatusNotFound, "maintenance window not found or already over")
+			return
+		}
+		g.logger.Error("failed to cancel maintenance window", zap.Error(err), zap.String("window_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to cancel maintenance window")
+		return
+	}
+
+	g.logger.Info("maintenance window cancelled", zap.String("window_id", id.String()))
+
+	go g.processMaintenance(context.Background())
+
+	g.writeJSON(w, http.StatusOK, window)
+}
+
+// handleStatus reports platform status and any current or upcoming
+// maintenance
+// Public API - GET /status
+func (g *Gateway) handleStatus(w http.ResponseWriter, r *http.Request) {
+	windows, err := g.upcomingMaintenance(r.Context())
+	if err != nil {
+		g.logger.Error("failed to load maintenance windows", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load status")
+		return
+	}
+
+	status := "operational"
+	active := []MaintenanceNotice{}
+	upcoming := []MaintenanceNotice{}
+	for _, window := range windows {
+		if window.State == maintenanceActive {
+			active = append(active, window.notice())
+			status = "maintenance"
+		} else {
+			upcoming = append(upcoming, window.notice())
+		}
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": status,
+		"maintenance": map[string]interface{}{
+			"active":   active,
+			"upcoming": upcoming,
+		},
+	})
+}
+
+// StartMaintenance starts the loop that notifies tenants of maintenance and
+// pre-scales capacity ahead of region maintenance
+func (g *Gateway) StartMaintenance(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(maintenanceInterval)
+		defer ticker.Stop()
+
+		g.processMaintenance(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				g.processMaintenance(ctx)
+			}
+		}
+	}()
+}
+
+// processMaintenance sends due notices and pre-scales due windows. Notices
+// are recorded per tenant and milestone, and pre-scaling claims its window,
+// so running on several replicas or twice in a row is harmless.
+func (g *Gateway) processMaintenance(ctx context.Context) {
+	windows, err := g.upcomingMaintenance(ctx)
+	if err != nil {
+		g.logger.Error("failed to load maintenance windows", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for _, window := range windows {
+		if milestone := dueMaintenanceNotice(now, window.StartsAt); milestone != "" {
+			if err := g.notifyMaintenance(ctx, window, milestone); err != nil {
+				g.logger.Error("failed to send maintenance notices",
+					zap.String("window_id", window.ID.String()),
+					zap.String("milestone", milestone),
+					zap.Error(err),
+				)
+			}
+		}
+		if window.prescaleDue(now) {
+			if err := g.prescaleForMaintenance(ctx, window); err != nil {
+				g.logger.Error("failed to pre-scale for maintenance",
+					zap.String("window_id", window.ID.String()),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+
+	cancelled, err := g.queryMaintenanceWindows(ctx, ` WHERE status = 'cancelled' AND ends_at > NOW()`)
+	if err != nil {
+		g.logger.Error("failed to load cancelled maintenance windows", zap.Error(err))
+		return
+	}
+	for _, window := range cancelled {
+		if err := g.notifyMaintenance(ctx, window, maintenanceNoticeCancelled); err != nil {
+			g.logger.Error("failed to send maintenance cancellation notices",
+				zap.String("window_id", window.ID.String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// affectedTenantsQuery returns a query selecting the tenants affected by a
+// window: every active tenant for platform maintenance, otherwise those that
+// used the model or region recently. Cancellations go to tenants that were
+// told about the window. args already holds the window ID as $1.
+func affectedTenantsQuery(window *MaintenanceWindow, milestone string, since time.Time, args *database.Args) string {
+	if milestone == maintenanceNoticeCancelled {
+		return `
+			SELECT DISTINCT tenant_id FROM maintenance_notifications
+			WHERE window_id = $1 AND milestone <> ` + args.Add(maintenanceNoticeCancelled)
+	}
+
+	switch window.Scope {
+	case maintenanceScopeModel:
+		return `
+			SELECT DISTINCT ur.tenant_id
+			FROM usage_records ur
+			JOIN models m ON m.id = ur.model_id
+			JOIN tenants t ON t.id = ur.tenant_id
+			WHERE m.name = ` + args.Add(*window.Target) + ` AND ur.timestamp > ` + args.Add(since) + ` AND t.status = 'active'`
+	case maintenanceScopeRegion:
+		return `
+			SELECT DISTINCT ur.tenant_id
+			FROM usage_records ur
+			JOIN regions rg ON rg.id = ur.region_id
+			JOIN tenants t ON t.id = ur.tenant_id
+			WHERE rg.code = ` + args.Add(*window.Target) + ` AND ur.timestamp > ` + args.Add(since) + ` AND t.status = 'active'`
+	default:
+		return `SELECT id FROM tenants WHERE status = 'active'`
+	}
+}
+
+// notifyMaintenance records and publishes a notice for every affected tenant
+// that hasn't received this milestone yet
+func (g *Gateway) notifyMaintenance(ctx context.Context, window *MaintenanceWindow, milestone string) error {
+	args := database.NewArgs(window.ID)
+	milestoneParam := args.Add(milestone)
+	affected := affectedTenantsQuery(window, milestone, time.Now().Add(-maintenanceUsageWindow), args)
+	rows, err := g.db.Pool.Query(ctx, `
+		INSERT INTO maintenance_notifications (window_id, tenant_id, milestone)
+		SELECT $1::uuid, affected.tenant_id, `+milestoneParam+`
+		FROM (`+affected+`) AS affected(tenant_id)
+		ON CONFLICT (window_id, tenant_id, milestone) DO NOTHING
+		RETURNING tenant_id
+	`, args.Values()...)
+	if err != nil {
+		return fmt.Errorf("failed to record notices: %w", err)
+	}
+
+	var tenantIDs []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			continue
+		}
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to record notices: %w", err)
+	}
+
+	eventType := events.EventMaintenanceScheduled
+	if milestone == maintenanceNoticeCancelled {
+		eventType = events.EventMaintenanceCancelled
+	}
+	for _, tenantID := range tenantIDs {
+		payload := map[string]interface{}{
+			"window_id": window.ID.String(),
+			"title":     window.Title,
+			"scope":     window.Scope,
+			"milestone": milestone,
+			"starts_at": window.StartsAt.UTC().Format(time.RFC3339),
+			"ends_at":   window.EndsAt.UTC().Format(time.RFC3339),
+		}
+		if window.Target != nil {
+			payload["target"] = *window.Target
+		}
+		if window.Description != nil {
+			payload["description"] = *window.Description
+		}
+
+		if err := g.eventBus.Publish(ctx, events.NewEvent(eventType, tenantID, payload)); err != nil {
+			g.logger.Error("failed to publish maintenance notice",
+				zap.String("tenant_id", tenantID),
+				zap.String("window_id", window.ID.String()),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if len(tenantIDs) > 0 {
+		g.logger.Info("sent maintenance notices",
+			zap.String("window_id", window.ID.String()),
+			zap.String("milestone", milestone),
+			zap.Int("tenants", len(tenantIDs)),
+		)
+	}
+	return nil
+}
+
+// prescaleForMaintenance launches, for each deployment node in the region
+// under maintenance, a replacement node in another region so the
+// deployment keeps its capacity during the window. The replacement goes to
+// the region where the deployment already has the most active nodes, or
+// failing that any active region offering the node's cloud provider.
+func (g *Gateway) prescaleForMaintenance(ctx context.Context, window *MaintenanceWindow) error {
+	region := *window.Target
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Claiming the window holds its row lock until commit, so only one
+	// replica launches capacity
+	tag, err := tx.Exec(ctx, `
+		UPDATE maintenance_windows SET prescaled_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND prescaled_at IS NULL AND status = 'scheduled'
+	`, window.ID)
+	if err != nil {
+		return fmt.Errorf("failed to claim window: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT n.deployment_id::text, n.provider, COALESCE(n.gpu_type, ''), m.name, COALESCE(n.spot_instance, false)
+		FROM nodes n
+		JOIN regions rg ON rg.id = n.region_id
+		JOIN deployments d ON d.id = n.deployment_id
+		JOIN models m ON m.id = d.model_id
+		WHERE rg.code = $1 AND n.status IN ('initializing', 'active') AND d.status IN ('launching', 'active')
+	`, region)
+	if err != nil {
+		return fmt.Errorf("failed to load nodes in region: %w", err)
+	}
+	var launches []orchestrator.NodeConfig
+	for rows.Next() {
+		var cfg orchestrator.NodeConfig
+		if err := rows.Scan(&cfg.DeploymentID, &cfg.Provider, &cfg.GPU, &cfg.Model, &cfg.UseSpot); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan node: %w", err)
+		}
+		launches = append(launches, cfg)
+	}
+	rows.Close()
+
+	var nodeIDs []uuid.UUID
+	for _, cfg := range launches {
+		err := tx.QueryRow(ctx, `
+			SELECT rg.code
+			FROM regions rg
+			LEFT JOIN nodes n ON n.region_id = rg.id AND n.deployment_id = $1 AND n.status = 'active'
+			WHERE rg.status = 'active' AND rg.code <> $2 AND rg.cloud_providers ? $3
+			GROUP BY rg.code
+			ORDER BY COUNT(n.id) DESC, rg.code
+			LIMIT 1
+		`, cfg.DeploymentID, region, cfg.Provider).Scan(&cfg.Region)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				g.logger.Warn("no region available to pre-scale deployment",
+					zap.String("window_id", window.ID.String()),
+					zap.String("deployment_id", cfg.DeploymentID),
+					zap.String("provider", cfg.Provider),
+				)
+				continue
+			}
+			return fmt.Errorf("failed to choose region: %w", err)
+		}
+
+		nodeID := uuid.New()
+		cfg.NodeID = nodeID.String()
+		cfg.DiskSize = 256
+		cfg.RequestedAt = time.Now()
+		if _, err := g.jobs.EnqueueTx(ctx, tx, jobLaunchDeployNode, cfg); err != nil {
+			return fmt.Errorf("failed to queue node launch: %w", err)
+		}
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+
+	if len(nodeIDs) > 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE maintenance_windows SET prescaled_node_ids = $2 WHERE id = $1
+		`, window.ID, nodeIDs); err != nil {
+			return fmt.Errorf("failed to record pre-scaled nodes: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit pre-scaling: %w", err)
+	}
+
+	g.logger.Info("pre-scaled capacity for region maintenance",
+		zap.String("window_id", window.ID.String()),
+		zap.String("region", region),
+		zap.Int("nodes", len(nodeIDs)),
+	)
+	return nil
+}
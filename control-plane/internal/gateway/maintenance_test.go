@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+)
+
+func TestMaintenanceState(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		status   string
+		startsAt time.Time
+		endsAt   time.Time
+		want     string
+	}{
+		{"upcoming", "scheduled", now.Add(time.Hour), now.Add(2 * time.Hour), maintenanceUpcoming},
+		{"active", "scheduled", now.Add(-time.Hour), now.Add(time.Hour), maintenanceActive},
+		{"completed", "scheduled", now.Add(-2 * time.Hour), now.Add(-time.Hour), maintenanceCompleted},
+		{"cancelled", "cancelled", now.Add(-time.Hour), now.Add(time.Hour), maintenanceCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maintenanceState(tt.status, tt.startsAt, tt.endsAt, now); got != tt.want {
+				t.Errorf("maintenanceState() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDueMaintenanceNotice(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		startsAt time.Time
+		want     string
+	}{
+		{"next week", now.Add(7 * 24 * time.Hour), maintenanceNoticeScheduled},
+		{"within a day", now.Add(3 * time.Hour), maintenanceNoticeReminder},
+		{"already started", now.Add(-time.Minute), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dueMaintenanceNotice(now, tt.startsAt); got != tt.want {
+				t.Errorf("dueMaintenanceNotice() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaintenancePrescaleDue(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	window := func(startsIn time.Duration) *MaintenanceWindow {
+		return &MaintenanceWindow{
+			Prescale: true,
+			Status:   "scheduled",
+			StartsAt: now.Add(startsIn),
+			EndsAt:   now.Add(startsIn + 2*time.Hour),
+		}
+	}
+
+	if window(3 * time.Hour).prescaleDue(now) {
+		t.Error("prescaleDue() = true three hours before the window")
+	}
+	if !window(30 * time.Minute).prescaleDue(now) {
+		t.Error("prescaleDue() = false within the lead time")
+	}
+	if !window(-time.Hour).prescaleDue(now) {
+		t.Error("prescaleDue() = false during the window")
+	}
+
+	done := window(30 * time.Minute)
+	done.PrescaledAt = &now
+	if done.prescaleDue(now) {
+		t.Error("prescaleDue() = true after pre-scaling")
+	}
+	off := window(30 * time.Minute)
+	off.Prescale = false
+	if off.prescaleDue(now) {
+		t.Error("prescaleDue() = true without prescale")
+	}
+}
+
+func TestMaintenanceWindowRequestValidate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := func() maintenanceWindowRequest {
+		return maintenanceWindowRequest{
+			Title:    "Network upgrade",
+			Scope:    maintenanceScopeRegion,
+			Target:   "us-east",
+			StartsAt: now.Add(time.Hour),
+			EndsAt:   now.Add(3 * time.Hour),
+			Prescale: true,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *maintenanceWindowRequest)
+		wantErr string
+	}{
+		{"valid", func(r *maintenanceWindowRequest) {}, ""},
+		{"missing title", func(r *maintenanceWindowRequest) { r.Title = "  " }, "title is required"},
+		{"unknown scope", func(r *maintenanceWindowRequest) { r.Scope = "node" }, "scope must be"},
+		{"missing target", func(r *maintenanceWindowRequest) { r.Target = "" }, "target is required"},
+		{"platform with target", func(r *maintenanceWindowRequest) { r.Scope = maintenanceScopePlatform; r.Prescale = false }, "target must be empty"},
+		{"ends before start", func(r *maintenanceWindowRequest) { r.EndsAt = r.StartsAt }, "ends_at must be after"},
+		{"already over", func(r *maintenanceWindowRequest) {
+			r.StartsAt = now.Add(-2 * time.Hour)
+			r.EndsAt = now.Add(-time.Hour)
+		}, "in the future"},
+		{"prescale model", func(r *maintenanceWindowRequest) { r.Scope = maintenanceScopeModel }, "prescale is only supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(&req)
+			err := req.validate(now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAffectedTenantsQuery(t *testing.T) {
+	target := "llama-3-8b"
+	window := &MaintenanceWindow{ID: uuid.New(), Scope: maintenanceScopeModel, Target: &target}
+	since := time.Now()
+
+	args := database.NewArgs(window.ID)
+	query := affectedTenantsQuery(window, maintenanceNoticeScheduled, since, args)
+	if !strings.Contains(query, "m.name = $2") || !strings.Contains(query, "ur.timestamp > $3") {
+		t.Errorf("model query = %s", query)
+	}
+	if args.Len() != 3 {
+		t.Errorf("model query args = %d, want 3", args.Len())
+	}
+
+	// Platform maintenance reaches every tenant without extra parameters
+	window.Scope = maintenanceScopePlatform
+	args = database.NewArgs(window.ID)
+	affectedTenantsQuery(window, maintenanceNoticeReminder, since, args)
+	if args.Len() != 1 {
+		t.Errorf("platform query args = %d, want 1", args.Len())
+	}
+
+	// Cancellations only go to tenants that were notified
+	args = database.NewArgs(window.ID)
+	query = affectedTenantsQuery(window, maintenanceNoticeCancelled, since, args)
+	if !strings.Contains(query, "FROM maintenance_notifications") {
+		t.Errorf("cancellation query = %s", query)
+	}
+}
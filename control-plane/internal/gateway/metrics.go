@@ -45,6 +45,46 @@ var (
 		[]string{"node_id", "error_type"},
 	)
 
+	apiKeyAbuseIncidents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_key_abuse_incidents_total",
+			Help: "API keys throttled or quarantined by abuse detection",
+		},
+		[]string{"signal", "action"},
+	)
+
+	readCacheLookups = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_read_cache_lookups_total",
+			Help: "Catalog and route cache lookups by where they were answered (memory, redis, miss)",
+		},
+		[]string{"cache", "result"},
+	)
+
+	responseCompressionBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_response_compression_bytes_total",
+			Help: "Bytes of compressed responses before and after compression (stage: uncompressed, compressed)",
+		},
+		[]string{"encoding", "stage"},
+	)
+
+	playgroundRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_playground_requests_total",
+			Help: "Public playground requests by outcome (served, invalid, rate_limited, blocked, captcha_failed, unavailable, upstream_error)",
+		},
+		[]string{"outcome"},
+	)
+
+	deprecatedAPIRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_deprecated_api_requests_total",
+			Help: "Requests to API routes slated for removal, by route",
+		},
+		[]string{"method", "route"},
+	)
+
 	dependencyUp = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "dependency_up",
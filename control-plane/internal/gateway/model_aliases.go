@@ -0,0 +1,323 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// modelAliasCacheTTL bounds how long an alias resolution is cached in-process.
+// Repointing an alias takes effect on other gateway replicas within this window.
+const modelAliasCacheTTL = 30 * time.Second
+
+// ModelAlias maps a stable model name to a versioned target model
+type ModelAlias struct {
+	Alias       string    `json:"alias"`
+	TargetModel string    `json:"target_model"`
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ModelAliasRequest is the request body for creating or repointing an alias
+type ModelAliasRequest struct {
+	Alias       string  `json:"alias"`
+	TargetModel string  `json:"target_model"`
+	Description *string `json:"description,omitempty"`
+}
+
+type cachedModelAlias struct {
+	target    string
+	expiresAt time.Time
+}
+
+// modelAliasCache caches alias -> target lookups for the inference path.
+// An empty target records that a name is not an alias.
+type modelAliasCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedModelAlias
+}
+
+func newModelAliasCache() *modelAliasCache {
+	return &modelAliasCache{entries: make(map[string]cachedModelAlias)}
+}
+
+func (c *modelAliasCache) get(name string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[name]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.target, true
+}
+
+func (c *modelAliasCache) set(name, target string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[name] = cachedModelAlias{target: target, expiresAt: time.Now().Add(modelAliasCacheTTL)}
+}
+
+func (c *modelAliasCache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, name)
+}
+
+// resolveModelAlias returns the target model for an alias, or "" if the
+// requested name is not an alias
+func (g *Gateway) resolveModelAlias(ctx context.Context, name string) (string, error) {
+	if target, ok := g.modelAliases.get(name); ok {
+		return target, nil
+	}
+
+	var target string
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT target_model FROM model_aliases WHERE alias = $1
+	`, name).Scan(&target)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.modelAliases.set(name, "")
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	g.modelAliases.set(name, target)
+	return target, nil
+}
+
+// applyModelAlias resolves an aliased model name in an inference request.
+// When the model is an alias, the request body is rewritten to the target so
+// upstream vLLM sees the served model name, the resolution is stored on the
+// request context for usage recording, and X-Model-Alias/X-Resolved-Model
+// response headers are set. It returns the (possibly rewritten) request.
+func (g *Gateway) applyModelAlias(w http.ResponseWriter, r *http.Request, model string, body []byte) (*http.Request, string, []byte) {
+	target, err := g.resolveModelAlias(r.Context(), model)
+	if err != nil {
+		// Fail open: treat the name as a concrete model
+		g.logger.Warn("failed to resolve model alias",
+			zap.Error(err),
+			zap.String("model", model),
+		)
+		return r, model, body
+	}
+	if target == "" || target == model {
+		return r, model, body
+	}
+
+	rewritten, err := rewriteRequestModel(body, target)
+	if err != nil {
+		g.logger.Warn("failed to rewrite aliased request body",
+			zap.Error(err),
+			zap.String("alias", model),
+		)
+		return r, model, body
+	}
+
+	w.Header().Set("X-Model-Alias", model)
+	w.Header().Set("X-Resolved-Model", target)
+
+	ctx := context.WithValue(r.Context(), "model_alias", model)
+	return r.WithContext(ctx), target, rewritten
+}
+
+// rewriteRequestModel replaces the "model" field of a JSON request body while
+// leaving every other field untouched
+func rewriteRequestModel(body []byte, model string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+	fields["model"] = encoded
+
+	return json.Marshal(fields)
+}
+
+// listModelAliases returns all configured aliases ordered by name
+func (g *Gateway) listModelAliases(ctx context.Context) ([]ModelAlias, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT alias, target_model, description, created_at, updated_at
+		FROM model_aliases
+		ORDER BY alias
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := []ModelAlias{}
+	for rows.Next() {
+		var a ModelAlias
+		if err := rows.Scan(&a.Alias, &a.TargetModel, &a.Description, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// HandleListModelAliases lists all model aliases
+// Admin API - GET /api/v1/admin/model-aliases
+func (g *Gateway) HandleListModelAliases(w http.ResponseWriter, r *http.Request) {
+	aliases, err := g.listModelAliases(r.Context())
+	if err != nil {
+		g.logger.Error("failed to list model aliases", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list model aliases")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": aliases,
+	})
+}
+
+// HandleSaveModelAlias creates an alias or repoints an existing one
+// Admin API - POST /api/v1/admin/model-aliases
+func (g *Gateway) HandleSaveModelAlias(w http.ResponseWriter, r *http.Request) {
+	var req ModelAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	g.saveModelAlias(w, r, req)
+}
+
+// HandleUpdateModelAlias repoints an existing alias to a new target
+// Admin API - PUT /api/v1/admin/model-aliases/{alias}
+func (g *Gateway) HandleUpdateModelAlias(w http.ResponseWriter, r *http.Request) {
+	var req ModelAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Alias = chi.URLParam(r, "alias")
+	g.saveModelAlias(w, r, req)
+}
+
+// saveModelAlias validates and upserts an alias
+func (g *Gateway) saveModelAlias(w http.ResponseWriter, r *http.Request, req ModelAliasRequest) {
+	ctx := r.Context()
+
+	req.Alias = strings.TrimSpace(req.Alias)
+	req.TargetModel = strings.TrimSpace(req.TargetModel)
+	if req.Alias == "" || req.TargetModel == "" {
+		g.writeError(w, http.StatusBadRequest, "alias and target_model are required")
+		return
+	}
+	if req.Alias == req.TargetModel {
+		g.writeError(w, http.StatusBadRequest, "alias cannot point to itself")
+		return
+	}
+
+	// Aliases must not shadow real models, and must point at one
+	var aliasIsModel, targetExists bool
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT
+			EXISTS(SELECT 1 FROM models WHERE name = $1),
+			EXISTS(SELECT 1 FROM models WHERE name = $2)
+	`, req.Alias, req.TargetModel).Scan(&aliasIsModel, &targetExists)
+	if err != nil {
+		g.logger.Error("failed to validate model alias", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to save model alias")
+		return
+	}
+	if aliasIsModel {
+		g.writeError(w, http.StatusBadRequest, fmt.Sprintf("alias %q conflicts with an existing model name", req.Alias))
+		return
+	}
+	if !targetExists {
+		g.writeError(w, http.StatusBadRequest, fmt.Sprintf("target model %q not found", req.TargetModel))
+		return
+	}
+
+	var alias ModelAlias
+	err = g.db.Pool.QueryRow(ctx, `
+		INSERT INTO model_aliases (alias, target_model, description)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (alias) DO UPDATE
+		SET target_model = EXCLUDED.target_model,
+		    description = COALESCE(EXCLUDED.description, model_aliases.description)
+		RETURNING alias, target_model, description, created_at, updated_at
+	`, req.Alias, req.TargetModel, req.Description).Scan(
+		&alias.Alias, &alias.TargetModel, &alias.Description, &alias.CreatedAt, &alias.UpdatedAt,
+	)
+	if err != nil {
+		g.logger.Error("failed to save model alias", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to save model alias")
+		return
+	}
+
+	g.modelAliases.invalidate(alias.Alias)
+	g.publishCatalogChanged(r.Context(), alias.Alias, "alias_saved")
+
+	g.logger.Info("model alias saved",
+		zap.String("alias", alias.Alias),
+		zap.String("target_model", alias.TargetModel),
+	)
+
+	g.writeJSON(w, http.StatusOK, alias)
+}
+
+// HandleDeleteModelAlias removes an alias
+// Admin API - DELETE /api/v1/admin/model-aliases/{alias}
+func (g *Gateway) HandleDeleteModelAlias(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "alias")
+
+	result, err := g.db.Pool.Exec(r.Context(), `DELETE FROM model_aliases WHERE alias = $1`, name)
+	if err != nil {
+		g.logger.Error("failed to delete model alias", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to delete model alias")
+		return
+	}
+	if result.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "model alias not found")
+		return
+	}
+
+	g.modelAliases.invalidate(name)
+	g.publishCatalogChanged(r.Context(), name, "alias_deleted")
+
+	g.logger.Info("model alias deleted", zap.String("alias", name))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// usageMetadataWithAlias merges the alias and prompt experiment variant
+// stored on the request context (if any) into a usage record's JSON metadata
+func usageMetadataWithAlias(ctx context.Context, metadata string) string {
+	fields := map[string]interface{}{}
+	if metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &fields); err != nil {
+			return metadata
+		}
+	}
+
+	if alias, ok := ctx.Value("model_alias").(string); ok && alias != "" {
+		fields["model_alias"] = alias
+	}
+	if assignment, ok := ctx.Value("prompt_variant").(*promptAssignment); ok {
+		fields["prompt_experiment_id"] = assignment.ExperimentID.String()
+		fields["prompt_variant"] = assignment.Variant
+	}
+
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return metadata
+	}
+	return string(encoded)
+}
@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestRewriteRequestModelPreservesOtherFields(t *testing.T) {
+	body := []byte(`{"model":"llama-3-small","messages":[{"role":"user","content":"hi"}],"temperature":0.2}`)
+
+	rewritten, err := rewriteRequestModel(body, "meta-llama/Llama-3-8b-instruct@v2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(rewritten, &fields); err != nil {
+		t.Fatalf("rewritten body is not valid JSON: %v", err)
+	}
+	if fields["model"] != "meta-llama/Llama-3-8b-instruct@v2" {
+		t.Errorf("expected model to be rewritten, got %v", fields["model"])
+	}
+	if fields["temperature"] != 0.2 {
+		t.Errorf("expected temperature to be preserved, got %v", fields["temperature"])
+	}
+	if msgs, ok := fields["messages"].([]interface{}); !ok || len(msgs) != 1 {
+		t.Errorf("expected messages to be preserved, got %v", fields["messages"])
+	}
+}
+
+func TestUsageMetadataWithAlias(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "model_alias", "llama-3-small")
+
+	metadata := usageMetadataWithAlias(ctx, `{"streaming":true}`)
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(metadata), &fields); err != nil {
+		t.Fatalf("metadata is not valid JSON: %v", err)
+	}
+	if fields["model_alias"] != "llama-3-small" {
+		t.Errorf("expected model_alias in metadata, got %v", fields["model_alias"])
+	}
+	if fields["streaming"] != true {
+		t.Errorf("expected existing metadata to be preserved")
+	}
+
+	if got := usageMetadataWithAlias(context.Background(), ""); got != "{}" {
+		t.Errorf("expected empty metadata object without alias, got %s", got)
+	}
+}
@@ -0,0 +1,255 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"go.uber.org/zap"
+)
+
+// Model-level circuit breaking.
+//
+// Node breakers protect against a single bad node; they do nothing when a
+// model is sick across the fleet (bad weights push, tokenizer bug, upstream
+// OOM loop). In that case client retries just move load onto the remaining
+// healthy nodes and melt them too. The model breaker tracks the fleet-wide
+// error rate per model over a sliding window and, once it exceeds the error
+// budget, sheds a fraction of that model's traffic with a clear error until
+// the rate recovers.
+const (
+	// modelBreakerWindow is the sliding window the error rate is computed over
+	modelBreakerWindow = time.Minute
+	// modelBreakerBuckets is the number of buckets the window is split into
+	modelBreakerBuckets = 6
+	// modelBreakerMinRequests avoids tripping on a handful of failures
+	modelBreakerMinRequests = 20
+	// modelBreakerErrorRate is the error budget; above it the breaker opens
+	modelBreakerErrorRate = 0.5
+	// modelBreakerShedFraction is the share of traffic rejected while open
+	modelBreakerShedFraction = 0.5
+	// modelBreakerCooldown is the minimum time a breaker stays open
+	modelBreakerCooldown = 30 * time.Second
+)
+
+type modelBreakerBucket struct {
+	start  time.Time
+	total  int64
+	errors int64
+}
+
+// modelBreaker tracks the error rate of one model
+type modelBreaker struct {
+	buckets  [modelBreakerBuckets]modelBreakerBucket
+	open     bool
+	openedAt time.Time
+}
+
+// ModelBreakerStatus is the admin view of a model breaker
+type ModelBreakerStatus struct {
+	Model     string     `json:"model"`
+	State     string     `json:"state"` // "closed" or "open"
+	Requests  int64      `json:"requests"`
+	Errors    int64      `json:"errors"`
+	ErrorRate float64    `json:"error_rate"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+}
+
+func (b *modelBreaker) bucket(now time.Time) *modelBreakerBucket {
+	width := modelBreakerWindow / modelBreakerBuckets
+	start := now.Truncate(width)
+	idx := int(start.UnixNano()/int64(width)) % modelBreakerBuckets
+	bucket := &b.buckets[idx]
+	if !bucket.start.Equal(start) {
+		*bucket = modelBreakerBucket{start: start}
+	}
+	return bucket
+}
+
+// counts returns request and error totals within the window
+func (b *modelBreaker) counts(now time.Time) (int64, int64) {
+	var total, errs int64
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) < modelBreakerWindow {
+			total += bucket.total
+			errs += bucket.errors
+		}
+	}
+	return total, errs
+}
+
+// record adds an outcome and returns whether the breaker changed state
+// (true, true = opened; true, false = closed)
+func (b *modelBreaker) record(now time.Time, isError bool) (changed bool, open bool) {
+	bucket := b.bucket(now)
+	bucket.total++
+	if isError {
+		bucket.errors++
+	}
+	return b.evaluate(now)
+}
+
+// evaluate opens or closes the breaker based on the current window
+func (b *modelBreaker) evaluate(now time.Time) (changed bool, open bool) {
+	total, errs := b.counts(now)
+	tripped := total >= modelBreakerMinRequests && float64(errs)/float64(total) > modelBreakerErrorRate
+
+	switch {
+	case !b.open && tripped:
+		b.open = true
+		b.openedAt = now
+		return true, true
+	case b.open && !tripped && now.Sub(b.openedAt) >= modelBreakerCooldown:
+		b.open = false
+		return true, false
+	}
+	return false, b.open
+}
+
+// modelBreakerSet holds a breaker per model name
+type modelBreakerSet struct {
+	mu       sync.Mutex
+	breakers map[string]*modelBreaker
+	shed     func() bool
+}
+
+func newModelBreakerSet() *modelBreakerSet {
+	return &modelBreakerSet{
+		breakers: make(map[string]*modelBreaker),
+		shed: func() bool {
+			return rand.Float64() < modelBreakerShedFraction
+		},
+	}
+}
+
+func (s *modelBreakerSet) get(model string) *modelBreaker {
+	b, ok := s.breakers[model]
+	if !ok {
+		b = &modelBreaker{}
+		s.breakers[model] = b
+	}
+	return b
+}
+
+// allow reports whether a request for model should be admitted. It also
+// returns a state change when an open breaker's cooldown elapsed.
+func (s *modelBreakerSet) allow(model string, now time.Time) (allowed, changed, open bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.breakers[model]
+	if !ok {
+		return true, false, false
+	}
+	changed, open = b.evaluate(now)
+	if !open {
+		return true, changed, false
+	}
+	return !s.shed(), changed, true
+}
+
+func (s *modelBreakerSet) record(model string, now time.Time, isError bool) (changed, open bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(model).record(now, isError)
+}
+
+func (s *modelBreakerSet) status(now time.Time) []ModelBreakerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]ModelBreakerStatus, 0, len(s.breakers))
+	for model, b := range s.breakers {
+		total, errs := b.counts(now)
+		st := ModelBreakerStatus{Model: model, State: "closed", Requests: total, Errors: errs}
+		if total > 0 {
+			st.ErrorRate = float64(errs) / float64(total)
+		}
+		if b.open {
+			openedAt := b.openedAt
+			st.State = "open"
+			st.OpenedAt = &openedAt
+		}
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Model < statuses[j].Model })
+	return statuses
+}
+
+// allowModelRequest applies the model breaker to an inference request. When the
+// model is over its error budget a share of requests is rejected with 503 and
+// Retry-After, and models halted by a kill switch are rejected outright; it
+// returns false when the request has been answered.
+func (g *Gateway) allowModelRequest(w http.ResponseWriter, r *http.Request, model string) bool {
+	if g.killSwitches.haltsModel(model) {
+		g.writeRoutingHalted(w, fmt.Sprintf("model %s is temporarily halted", model))
+		return false
+	}
+	allowed, changed, open := g.modelBreakers.allow(model, time.Now())
+	if changed {
+		g.publishModelBreakerChange(r.Context(), model, open)
+	}
+	if allowed {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(modelBreakerCooldown.Seconds())))
+	g.writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+		"error": map[string]string{
+			"message": "model " + model + " is experiencing elevated error rates; part of its traffic is being shed, retry later",
+			"type":    "server_error",
+			"code":    "model_overloaded",
+		},
+	})
+	return false
+}
+
+// recordModelOutcome feeds a proxied request's outcome into the model breaker
+func (g *Gateway) recordModelOutcome(ctx context.Context, model string, isError bool) {
+	if changed, open := g.modelBreakers.record(model, time.Now(), isError); changed {
+		g.publishModelBreakerChange(ctx, model, open)
+	}
+}
+
+// publishModelBreakerChange logs and notifies operators of a breaker transition
+func (g *Gateway) publishModelBreakerChange(ctx context.Context, model string, open bool) {
+	eventType := events.EventModelCircuitClosed
+	if open {
+		eventType = events.EventModelCircuitOpened
+		g.logger.Warn("model circuit breaker opened",
+			zap.String("model", model),
+			zap.Float64("error_rate_threshold", modelBreakerErrorRate),
+			zap.Float64("shed_fraction", modelBreakerShedFraction),
+		)
+	} else {
+		g.logger.Info("model circuit breaker closed", zap.String("model", model))
+	}
+
+	if g.eventBus == nil {
+		return
+	}
+
+	evt := events.NewEvent(eventType, "", map[string]interface{}{
+		"model":                model,
+		"error_rate_threshold": fmt.Sprintf("%.0f%%", modelBreakerErrorRate*100),
+		"shed_fraction":        fmt.Sprintf("%.0f%%", modelBreakerShedFraction*100),
+		"window":               modelBreakerWindow.String(),
+	})
+	if err := g.eventBus.Publish(ctx, evt); err != nil {
+		g.logger.Error("failed to publish model circuit event", zap.Error(err))
+	}
+}
+
+// HandleListModelBreakers returns the state of every model circuit breaker
+// Admin API - GET /api/v1/admin/models/circuit-breakers
+func (g *Gateway) HandleListModelBreakers(w http.ResponseWriter, r *http.Request) {
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": g.modelBreakers.status(time.Now()),
+	})
+}
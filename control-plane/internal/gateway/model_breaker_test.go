@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestModelBreakerOpensOverErrorBudget(t *testing.T) {
+	s := newModelBreakerSet()
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < modelBreakerMinRequests-1; i++ {
+		if changed, _ := s.record("m", now, true); changed {
+			t.Fatalf("breaker opened before reaching the minimum request count")
+		}
+	}
+
+	changed, open := s.record("m", now, true)
+	if !changed || !open {
+		t.Fatalf("expected breaker to open, got changed=%v open=%v", changed, open)
+	}
+
+	s.shed = func() bool { return true }
+	if allowed, _, _ := s.allow("m", now); allowed {
+		t.Error("expected request to be shed while breaker is open")
+	}
+	if allowed, _, _ := s.allow("other", now); !allowed {
+		t.Error("expected unrelated model to be unaffected")
+	}
+}
+
+func TestModelBreakerStaysClosedWithinBudget(t *testing.T) {
+	s := newModelBreakerSet()
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < modelBreakerMinRequests*2; i++ {
+		if changed, _ := s.record("m", now, i%4 == 0); changed {
+			t.Fatalf("breaker changed state at a 25%% error rate")
+		}
+	}
+	if allowed, _, open := s.allow("m", now); !allowed || open {
+		t.Errorf("expected closed breaker, got allowed=%v open=%v", allowed, open)
+	}
+}
+
+func TestModelBreakerClosesAfterCooldownAndRecovery(t *testing.T) {
+	s := newModelBreakerSet()
+	s.shed = func() bool { return true }
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < modelBreakerMinRequests; i++ {
+		s.record("m", now, true)
+	}
+
+	// Still open before the cooldown elapses
+	if _, changed, open := s.allow("m", now.Add(modelBreakerCooldown/2)); changed || !open {
+		t.Fatalf("expected breaker to stay open during cooldown")
+	}
+
+	// Errors age out of the window and the cooldown has passed
+	later := now.Add(modelBreakerWindow + modelBreakerCooldown)
+	allowed, changed, open := s.allow("m", later)
+	if !allowed || !changed || open {
+		t.Errorf("expected breaker to close, got allowed=%v changed=%v open=%v", allowed, changed, open)
+	}
+}
+
+func TestModelBreakerStatus(t *testing.T) {
+	s := newModelBreakerSet()
+	now := time.Unix(1700000000, 0)
+
+	s.record("b", now, false)
+	for i := 0; i < modelBreakerMinRequests; i++ {
+		s.record("a", now, true)
+	}
+
+	statuses := s.status(now)
+	if len(statuses) != 2 || statuses[0].Model != "a" || statuses[1].Model != "b" {
+		t.Fatalf("expected statuses sorted by model, got %+v", statuses)
+	}
+	if statuses[0].State != "open" || statuses[0].OpenedAt == nil || statuses[0].ErrorRate != 1 {
+		t.Errorf("unexpected status for open breaker: %+v", statuses[0])
+	}
+	if statuses[1].State != "closed" || statuses[1].Requests != 1 {
+		t.Errorf("unexpected status for closed breaker: %+v", statuses[1])
+	}
+}
@@ -0,0 +1,261 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// modelCapabilitiesCacheTTL bounds how long capability flags are cached
+// in-process before being re-read from the database.
+const modelCapabilitiesCacheTTL = 60 * time.Second
+
+// ModelCapabilities are the per-model feature flags and limits the gateway
+// enforces before a request reaches vLLM
+type ModelCapabilities struct {
+	Model                  string `json:"model"`
+	SupportsTools          bool   `json:"supports_tools"`
+	SupportsVision         bool   `json:"supports_vision"`
+	SupportsJSONMode       bool   `json:"supports_json_mode"`
+	SupportsGuidedDecoding bool   `json:"supports_guided_decoding"`
+	MaxOutputTokens        *int   `json:"max_output_tokens,omitempty"`
+	// SamplingDefaults are merged into requests that omit them
+	SamplingDefaults *SamplingDefaults `json:"-"`
+	// Pricing is the model's list price, used for usage cost previews
+	Pricing ModelPricing `json:"-"`
+}
+
+// publicCapabilities is the capability block exposed on /v1/models
+func (c *ModelCapabilities) publicCapabilities() map[string]interface{} {
+	return map[string]interface{}{
+		"tools":           c.SupportsTools,
+		"vision":          c.SupportsVision,
+		"json_mode":       c.SupportsJSONMode,
+		"guided_decoding": c.SupportsGuidedDecoding,
+	}
+}
+
+type cachedModelCapabilities struct {
+	capabilities *ModelCapabilities
+	expiresAt    time.Time
+}
+
+// modelCapabilitiesCache keeps capability lookups off Postgres on the hot path
+type modelCapabilitiesCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedModelCapabilities
+}
+
+func newModelCapabilitiesCache() *modelCapabilitiesCache {
+	return &modelCapabilitiesCache{entries: make(map[string]cachedModelCapabilities)}
+}
+
+func (c *modelCapabilitiesCache) get(model string) (*ModelCapabilities, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[model]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.capabilities, true
+}
+
+func (c *modelCapabilitiesCache) set(model string, capabilities *ModelCapabilities) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[model] = cachedModelCapabilities{capabilities: capabilities, expiresAt: time.Now().Add(modelCapabilitiesCacheTTL)}
+}
+
+func (c *modelCapabilitiesCache) invalidate(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, model)
+}
+
+// getModelCapabilities returns the capability flags for a model by name.
+// Unknown models return nil.
+func (g *Gateway) getModelCapabilities(ctx context.Context, modelName string) (*ModelCapabilities, error) {
+	if capabilities, ok := g.modelCapabilities.get(modelName); ok {
+		return capabilities, nil
+	}
+
+	capabilities := &ModelCapabilities{Model: modelName}
+	var metadata []byte
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT supports_tools, supports_vision, supports_json_mode,
+		       supports_guided_decoding, max_output_tokens, metadata,
+		       price_input_per_million::float8, price_output_per_million::float8
+		FROM models
+		WHERE name = $1
+	`, modelName).Scan(
+		&capabilities.SupportsTools, &capabilities.SupportsVision, &capabilities.SupportsJSONMode,
+		&capabilities.SupportsGuidedDecoding, &capabilities.MaxOutputTokens, &metadata,
+		&capabilities.Pricing.InputPerMillion, &capabilities.Pricing.OutputPerMillion,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.modelCapabilities.set(modelName, nil)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	capabilities.SamplingDefaults = parseSamplingDefaults(metadata)
+
+	g.modelCapabilities.set(modelName, capabilities)
+	return capabilities, nil
+}
+
+// requestFeatures are the capability-relevant parts of an inference request
+type requestFeatures struct {
+	Tools     bool
+	Vision    bool
+	JSONMode  bool
+	Guided    *GuidedDecodingParams
+	MaxTokens *int
+}
+
+// parseRequestFeatures inspects a chat or completion request body for
+// parameters that need a model capability
+func parseRequestFeatures(body []byte) (*requestFeatures, error) {
+	var raw struct {
+		Tools          []json.RawMessage `json:"tools"`
+		Functions      []json.RawMessage `json:"functions"`
+		ToolChoice     json.RawMessage   `json:"tool_choice"`
+		ResponseFormat *struct {
+			Type string `json:"type"`
+		} `json:"response_format"`
+		MaxTokens           *int `json:"max_tokens"`
+		MaxCompletionTokens *int `json:"max_completion_tokens"`
+		Messages            []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("invalid request body")
+	}
+
+	guided, err := parseGuidedDecoding(body)
+	if err != nil {
+		return nil, err
+	}
+
+	features := &requestFeatures{
+		Tools:     len(raw.Tools) > 0 || len(raw.Functions) > 0 || usesToolChoice(raw.ToolChoice),
+		JSONMode:  raw.ResponseFormat != nil && (raw.ResponseFormat.Type == "json_object" || raw.ResponseFormat.Type == "json_schema"),
+		Guided:    guided,
+		MaxTokens: raw.MaxTokens,
+	}
+	if raw.MaxCompletionTokens != nil {
+		features.MaxTokens = raw.MaxCompletionTokens
+	}
+	for _, msg := range raw.Messages {
+		if hasImageContent(msg.Content) {
+			features.Vision = true
+			break
+		}
+	}
+	return features, nil
+}
+
+// usesToolChoice reports whether tool_choice asks for a tool call
+func usesToolChoice(raw json.RawMessage) bool {
+	if len(raw) == 0 || isJSONNull(raw) {
+		return false
+	}
+	var choice string
+	if err := json.Unmarshal(raw, &choice); err == nil {
+		return choice != "" && choice != "none"
+	}
+	return true
+}
+
+// hasImageContent reports whether message content contains image parts
+func hasImageContent(raw json.RawMessage) bool {
+	var parts []struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return false
+	}
+	for _, part := range parts {
+		if part.Type == "image_url" || part.Type == "input_image" {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error describing the first requested feature the model
+// does not support
+func (c *ModelCapabilities) Check(features *requestFeatures) error {
+	switch {
+	case features.Tools && !c.SupportsTools:
+		return fmt.Errorf("model '%s' does not support tools or function calling", c.Model)
+	case features.Vision && !c.SupportsVision:
+		return fmt.Errorf("model '%s' does not support image inputs", c.Model)
+	case features.JSONMode && !c.SupportsJSONMode:
+		return fmt.Errorf("model '%s' does not support response_format JSON mode", c.Model)
+	case features.Guided != nil && !c.SupportsGuidedDecoding:
+		return fmt.Errorf("model '%s' does not support guided decoding (guided_json, guided_regex, guided_choice)", c.Model)
+	case features.MaxTokens != nil && c.MaxOutputTokens != nil && *features.MaxTokens > *c.MaxOutputTokens:
+		return fmt.Errorf("max_tokens %d exceeds the maximum output of %d tokens for model '%s'", *features.MaxTokens, *c.MaxOutputTokens, c.Model)
+	}
+	return nil
+}
+
+// enforceModelCapabilities validates guided decoding parameters and rejects
+// parameters the model does not support. It returns false when the request
+// has already been answered.
+func (g *Gateway) enforceModelCapabilities(w http.ResponseWriter, r *http.Request, modelName string, body []byte) bool {
+	features, err := parseRequestFeatures(body)
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	if features.Guided != nil {
+		if err := features.Guided.Validate(); err != nil {
+			g.writeInvalidRequest(w, err.Error(), "invalid_guided_decoding")
+			return false
+		}
+	}
+
+	capabilities, err := g.getModelCapabilities(r.Context(), modelName)
+	if err != nil {
+		// Fail open: vLLM still rejects parameters it cannot serve
+		g.logger.Warn("failed to load model capabilities",
+			zap.Error(err),
+			zap.String("model", modelName),
+		)
+		return true
+	}
+	if capabilities == nil {
+		// Unknown models are not in the registry; leave them to vLLM
+		return true
+	}
+
+	if err := capabilities.Check(features); err != nil {
+		g.writeInvalidRequest(w, err.Error(), "unsupported_parameter")
+		return false
+	}
+	return true
+}
+
+// writeInvalidRequest writes an OpenAI-style invalid_request_error with a code
+func (g *Gateway) writeInvalidRequest(w http.ResponseWriter, message, code string) {
+	g.writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error": map[string]string{
+			"message": message,
+			"type":    "invalid_request_error",
+			"code":    code,
+		},
+	})
+}
@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRequestFeatures(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want requestFeatures
+	}{
+		{"plain chat", `{"model":"m","messages":[{"role":"user","content":"hi"}]}`, requestFeatures{}},
+		{"tools", `{"model":"m","tools":[{"type":"function","function":{"name":"f"}}]}`, requestFeatures{Tools: true}},
+		{"legacy functions", `{"model":"m","functions":[{"name":"f"}]}`, requestFeatures{Tools: true}},
+		{"tool_choice none", `{"model":"m","tool_choice":"none"}`, requestFeatures{}},
+		{"json mode", `{"model":"m","response_format":{"type":"json_object"}}`, requestFeatures{JSONMode: true}},
+		{"text response format", `{"model":"m","response_format":{"type":"text"}}`, requestFeatures{}},
+		{"vision", `{"model":"m","messages":[{"role":"user","content":[{"type":"text","text":"what is this"},{"type":"image_url","image_url":{"url":"https://x/y.png"}}]}]}`, requestFeatures{Vision: true}},
+		{"text parts only", `{"model":"m","messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}]}`, requestFeatures{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRequestFeatures([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Tools != tt.want.Tools || got.Vision != tt.want.Vision || got.JSONMode != tt.want.JSONMode {
+				t.Errorf("parseRequestFeatures() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	got, err := parseRequestFeatures([]byte(`{"model":"m","max_tokens":100,"max_completion_tokens":200}`))
+	if err != nil || got.MaxTokens == nil || *got.MaxTokens != 200 {
+		t.Errorf("expected max_completion_tokens to take precedence, got %+v, %v", got, err)
+	}
+}
+
+func TestModelCapabilitiesCheck(t *testing.T) {
+	maxOutput := 1024
+	caps := &ModelCapabilities{Model: "m", SupportsJSONMode: true, MaxOutputTokens: &maxOutput}
+
+	if err := caps.Check(&requestFeatures{JSONMode: true}); err != nil {
+		t.Errorf("unexpected error for supported feature: %v", err)
+	}
+
+	tooMany := 4096
+	tests := []struct {
+		name     string
+		features requestFeatures
+		wantErr  string
+	}{
+		{"tools", requestFeatures{Tools: true}, "tools or function calling"},
+		{"vision", requestFeatures{Vision: true}, "image inputs"},
+		{"guided", requestFeatures{Guided: &GuidedDecodingParams{GuidedChoice: []string{"a"}}}, "guided decoding"},
+		{"max tokens", requestFeatures{MaxTokens: &tooMany}, "exceeds the maximum output of 1024"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := caps.Check(&tt.features)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
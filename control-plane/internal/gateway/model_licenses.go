@@ -0,0 +1,519 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// modelLicenseCacheTTL bounds how long a model's license is cached
+	// in-process; attaching a license takes effect on other replicas within it
+	modelLicenseCacheTTL = 60 * time.Second
+
+	// licenseAcceptanceCacheTTL bounds how long a tenant's acceptance of a
+	// license is cached in Redis
+	licenseAcceptanceCacheTTL = 60 * time.Second
+)
+
+// ModelLicense is the license governing use of one or more models
+type ModelLicense struct {
+	ID                 uuid.UUID `json:"id"`
+	Name               string    `json:"name"`
+	Version            string    `json:"version"`
+	URL                string    `json:"url"`
+	Summary            *string   `json:"summary,omitempty"`
+	RequiresAcceptance bool      `json:"requires_acceptance"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+const modelLicenseColumns = `id, name, version, url, summary, requires_acceptance, created_at, updated_at`
+
+func scanModelLicense(row pgx.Row) (*ModelLicense, error) {
+	var l ModelLicense
+	if err := row.Scan(&l.ID, &l.Name, &l.Version, &l.URL, &l.Summary, &l.RequiresAcceptance, &l.CreatedAt, &l.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+type cachedModelLicense struct {
+	license   *ModelLicense
+	expiresAt time.Time
+}
+
+// modelLicenseCache caches model -> gating license lookups for the inference
+// path. A nil license records that the model is not gated.
+type modelLicenseCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedModelLicense
+}
+
+func newModelLicenseCache() *modelLicenseCache {
+	return &modelLicenseCache{entries: make(map[string]cachedModelLicense)}
+}
+
+func (c *modelLicenseCache) get(model string) (*ModelLicense, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[model]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.license, true
+}
+
+func (c *modelLicenseCache) set(model string, license *ModelLicense) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[model] = cachedModelLicense{license: license, expiresAt: time.Now().Add(modelLicenseCacheTTL)}
+}
+
+// invalidateAll drops every entry; a license change can affect many models
+func (c *modelLicenseCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cachedModelLicense)
+}
+
+// getGatingLicense returns the license a tenant must accept before using a
+// model, or nil if the model is not gated
+func (g *Gateway) getGatingLicense(ctx context.Context, modelName string) (*ModelLicense, error) {
+	if license, ok := g.modelLicenses.get(modelName); ok {
+		return license, nil
+	}
+
+	license, err := scanModelLicense(g.db.Pool.QueryRow(ctx, `
+		SELECT l.id, l.name, l.version, l.url, l.summary, l.requires_acceptance, l.created_at, l.updated_at
+		FROM models m
+		JOIN model_licenses l ON l.id = m.license_id
+		WHERE m.name = $1 AND l.requires_acceptance
+	`, modelName))
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.modelLicenses.set(modelName, nil)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	g.modelLicenses.set(modelName, license)
+	return license, nil
+}
+
+func licenseAcceptanceCacheKey(tenantID, licenseID uuid.UUID) string {
+	return cache.TenantKey(tenantID, "license_accepted", licenseID.String())
+}
+
+// licenseAccepted reports whether the tenant has accepted the license
+func (g *Gateway) licenseAccepted(ctx context.Context, tenantID, licenseID uuid.UUID) (bool, error) {
+	key := licenseAcceptanceCacheKey(tenantID, licenseID)
+	if cached, err := g.cache.Get(ctx, key); err == nil {
+		return cached == "1", nil
+	}
+
+	var accepted bool
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM tenant_license_acceptances WHERE tenant_id = $1 AND license_id = $2)
+	`, tenantID, licenseID).Scan(&accepted)
+	if err != nil {
+		return false, err
+	}
+
+	value := "0"
+	if accepted {
+		value = "1"
+	}
+	if err := g.cache.Set(ctx, key, value, licenseAcceptanceCacheTTL); err != nil {
+		g.logger.Debug("failed to cache license acceptance", zap.Error(err))
+	}
+	return accepted, nil
+}
+
+// enforceModelLicense rejects requests to a gated model from tenants that
+// have not accepted its license. Unlike the other model checks it fails
+// closed: serving a gated model without acceptance is a licensing breach.
+// It returns false when the request has already been answered.
+func (g *Gateway) enforceModelLicense(w http.ResponseWriter, r *http.Request, modelName string) bool {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return true
+	}
+
+	license, err := g.getGatingLicense(ctx, modelName)
+	if err == nil && license != nil {
+		var accepted bool
+		accepted, err = g.licenseAccepted(ctx, tenantID, license.ID)
+		if err == nil && !accepted {
+			g.writeJSON(w, http.StatusForbidden, map[string]interface{}{
+				"error": licenseRequiredError(modelName, license),
+			})
+			return false
+		}
+	}
+	if err != nil {
+		g.logger.Error("failed to check model license acceptance",
+			zap.Error(err),
+			zap.String("model", modelName),
+			zap.String("tenant_id", tenantID.String()),
+		)
+		g.writeError(w, http.StatusServiceUnavailable, "unable to verify model license acceptance, please retry")
+		return false
+	}
+	return true
+}
+
+// licenseRequiredError is the error body for a gated model whose license the
+// tenant has not accepted, with what it needs to accept it
+func licenseRequiredError(modelName string, license *ModelLicense) map[string]interface{} {
+	return map[string]interface{}{
+		"message": fmt.Sprintf("The model '%s' requires accepting the %s (version %s). Review it at %s and accept it with POST /v1/licenses/%s/accept",
+			modelName, license.Name, license.Version, license.URL, license.ID),
+		"type": "permission_error",
+		"code": "license_not_accepted",
+		"license": map[string]interface{}{
+			"id":      license.ID,
+			"name":    license.Name,
+			"version": license.Version,
+			"url":     license.URL,
+		},
+	}
+}
+
+// TenantLicense is a license covering active models, with the tenant's
+// acceptance
+type TenantLicense struct {
+	ModelLicense
+	Models     []string   `json:"models"`
+	Accepted   bool       `json:"accepted"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+// handleListTenantLicenses lists the licenses of active models and whether
+// the tenant has accepted each
+// Tenant API - GET /v1/licenses
+func (g *Gateway) handleListTenantLicenses(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT l.id, l.name, l.version, l.url, l.summary, l.requires_acceptance, l.created_at, l.updated_at,
+		       ARRAY_AGG(m.name ORDER BY m.name), a.accepted_at
+		FROM model_licenses l
+		JOIN models m ON m.license_id = l.id AND m.status = 'active'
+		LEFT JOIN tenant_license_acceptances a ON a.license_id = l.id AND a.tenant_id = $1
+		GROUP BY l.id, a.accepted_at
+		ORDER BY l.name, l.version
+	`, tenantID)
+	if err != nil {
+		g.logger.Error("failed to list licenses", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to list licenses")
+		return
+	}
+	defer rows.Close()
+
+	licenses := []TenantLicense{}
+	for rows.Next() {
+		var l TenantLicense
+		if err := rows.Scan(&l.ID, &l.Name, &l.Version, &l.URL, &l.Summary, &l.RequiresAcceptance,
+			&l.CreatedAt, &l.UpdatedAt, &l.Models, &l.AcceptedAt,
+		); err != nil {
+			g.logger.Error("failed to scan license", zap.Error(err))
+			continue
+		}
+		l.Accepted = l.AcceptedAt != nil
+		licenses = append(licenses, l)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": licenses,
+	})
+}
+
+// handleAcceptLicense records the tenant's acceptance of a license. The API
+// key and client address are kept as the audit record; accepting again
+// keeps the original record.
+// Tenant API - POST /v1/licenses/{id}/accept
+func (g *Gateway) handleAcceptLicense(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+	var keyID *uuid.UUID
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok {
+		if keyInfo.Role == "read-only" {
+			g.writeError(w, http.StatusForbidden, "read-only API keys cannot accept licenses")
+			return
+		}
+		keyID = &keyInfo.ID
+	}
+
+	licenseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid license ID")
+		return
+	}
+
+	license, err := scanModelLicense(g.db.Pool.QueryRow(ctx, `
+		SELECT `+modelLicenseColumns+` FROM model_licenses WHERE id = $1
+	`, licenseID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			g.writeError(w, http.StatusNotFound, "license not found")
+			return
+		}
+		g.logger.Error("failed to load license", zap.Error(err), zap.String("license_id", licenseID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to accept license")
+		return
+	}
+
+	var acceptedAt time.Time
+	err = g.db.Pool.QueryRow(ctx, `
+		INSERT INTO tenant_license_acceptances (tenant_id, license_id, accepted_by_key_id, accepted_from)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, license_id) DO UPDATE SET tenant_id = EXCLUDED.tenant_id
+		RETURNING accepted_at
+	`, tenantID, licenseID, keyID, r.RemoteAddr).Scan(&acceptedAt)
+	if err != nil {
+		g.logger.Error("failed to record license acceptance", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to accept license")
+		return
+	}
+
+	if err := g.cache.Delete(ctx, licenseAcceptanceCacheKey(tenantID, licenseID)); err != nil {
+		g.logger.Warn("failed to invalidate license acceptance cache", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+	}
+
+	g.logger.Info("tenant accepted model license",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("license", license.Name),
+		zap.String("version", license.Version),
+	)
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"license":     license,
+		"accepted":    true,
+		"accepted_at": acceptedAt,
+	})
+}
+
+// ModelLicenseRequest is the request body for creating a license
+type ModelLicenseRequest struct {
+	Name               string  `json:"name"`
+	Version            string  `json:"version"`
+	URL                string  `json:"url"`
+	Summary            *string `json:"summary,omitempty"`
+	RequiresAcceptance *bool   `json:"requires_acceptance,omitempty"`
+}
+
+// HandleListModelLicenses lists licenses with the models they cover and how
+// many tenants have accepted each
+// Admin API - GET /api/v1/admin/licenses
+func (g *Gateway) HandleListModelLicenses(w http.ResponseWriter, r *http.Request) {
+	rows, err := g.db.Pool.Query(r.Context(), `
+		SELECT l.id, l.name, l.version, l.url, l.summary, l.requires_acceptance, l.created_at, l.updated_at,
+		       COALESCE((SELECT ARRAY_AGG(m.name ORDER BY m.name) FROM models m WHERE m.license_id = l.id), '{}'),
+		       (SELECT COUNT(*) FROM tenant_license_acceptances a WHERE a.license_id = l.id)
+		FROM model_licenses l
+		ORDER BY l.name, l.version
+	`)
+	if err != nil {
+		g.logger.Error("failed to list model licenses", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list model licenses")
+		return
+	}
+	defer rows.Close()
+
+	type licenseSummary struct {
+		ModelLicense
+		Models      []string `json:"models"`
+		Acceptances int      `json:"acceptances"`
+	}
+	licenses := []licenseSummary{}
+	for rows.Next() {
+		var l licenseSummary
+		if err := rows.Scan(&l.ID, &l.Name, &l.Version, &l.URL, &l.Summary, &l.RequiresAcceptance,
+			&l.CreatedAt, &l.UpdatedAt, &l.Models, &l.Acceptances,
+		); err != nil {
+			g.logger.Error("failed to scan model license", zap.Error(err))
+			continue
+		}
+		licenses = append(licenses, l)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": licenses,
+	})
+}
+
+// HandleCreateModelLicense creates a license. New terms for an existing
+// license are a new version, so tenants accept them again.
+// Admin API - POST /api/v1/admin/licenses
+func (g *Gateway) HandleCreateModelLicense(w http.ResponseWriter, r *http.Request) {
+	var req ModelLicenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Version = strings.TrimSpace(req.Version)
+	req.URL = strings.TrimSpace(req.URL)
+	if req.Name == "" || req.URL == "" {
+		g.writeError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+	if !strings.HasPrefix(req.URL, "https://") && !strings.HasPrefix(req.URL, "http://") {
+		g.writeError(w, http.StatusBadRequest, "url must be an http(s) URL")
+		return
+	}
+	if req.Version == "" {
+		req.Version = "1"
+	}
+	requiresAcceptance := true
+	if req.RequiresAcceptance != nil {
+		requiresAcceptance = *req.RequiresAcceptance
+	}
+
+	license, err := scanModelLicense(g.db.Pool.QueryRow(r.Context(), `
+		INSERT INTO model_licenses (name, version, url, summary, requires_acceptance)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name, version) DO NOTHING
+		RETURNING `+modelLicenseColumns,
+		req.Name, req.Version, req.URL, req.Summary, requiresAcceptance,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusConflict, fmt.Sprintf("license %q version %q already exists", req.Name, req.Version))
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to create model license", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create model license")
+		return
+	}
+
+	g.logger.Info("model license created",
+		zap.String("license_id", license.ID.String()),
+		zap.String("name", license.Name),
+		zap.String("version", license.Version),
+	)
+	g.writeJSON(w, http.StatusCreated, license)
+}
+
+// HandleListLicenseAcceptances lists the tenants that accepted a license
+// Admin API - GET /api/v1/admin/licenses/{id}/acceptances
+func (g *Gateway) HandleListLicenseAcceptances(w http.ResponseWriter, r *http.Request) {
+	licenseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid license ID")
+		return
+	}
+
+	rows, err := g.db.Pool.Query(r.Context(), `
+		SELECT a.tenant_id, t.name, a.accepted_by_key_id, a.accepted_from, a.accepted_at
+		FROM tenant_license_acceptances a
+		JOIN tenants t ON t.id = a.tenant_id
+		WHERE a.license_id = $1
+		ORDER BY a.accepted_at DESC
+	`, licenseID)
+	if err != nil {
+		g.logger.Error("failed to list license acceptances", zap.Error(err), zap.String("license_id", licenseID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to list license acceptances")
+		return
+	}
+	defer rows.Close()
+
+	type acceptance struct {
+		TenantID        uuid.UUID  `json:"tenant_id"`
+		TenantName      string     `json:"tenant_name"`
+		AcceptedByKeyID *uuid.UUID `json:"accepted_by_key_id,omitempty"`
+		AcceptedFrom    *string    `json:"accepted_from,omitempty"`
+		AcceptedAt      time.Time  `json:"accepted_at"`
+	}
+	acceptances := []acceptance{}
+	for rows.Next() {
+		var a acceptance
+		if err := rows.Scan(&a.TenantID, &a.TenantName, &a.AcceptedByKeyID, &a.AcceptedFrom, &a.AcceptedAt); err != nil {
+			g.logger.Error("failed to scan license acceptance", zap.Error(err))
+			continue
+		}
+		acceptances = append(acceptances, a)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"license_id": licenseID,
+		"data":       acceptances,
+	})
+}
+
+// HandleSetModelLicense attaches a license to a model, or detaches it when
+// license_id is null. Tenants that have not accepted the license lose
+// access to the model within the cache TTL.
+// Admin API - PUT /api/v1/admin/models/{id}/license
+func (g *Gateway) HandleSetModelLicense(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	modelID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid model ID")
+		return
+	}
+
+	var req struct {
+		LicenseID *uuid.UUID `json:"license_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var modelName string
+	err = g.db.Pool.QueryRow(ctx, `
+		UPDATE models SET license_id = $2 WHERE id = $1 RETURNING name
+	`, modelID, req.LicenseID).Scan(&modelName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "model not found")
+		return
+	}
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			g.writeError(w, http.StatusBadRequest, "license_id not found")
+			return
+		}
+		g.logger.Error("failed to set model license", zap.Error(err), zap.String("model_id", modelID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to set model license")
+		return
+	}
+
+	g.modelLicenses.invalidateAll()
+
+	g.logger.Info("model license updated",
+		zap.String("model", modelName),
+		zap.Any("license_id", req.LicenseID),
+	)
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"model_id":   modelID,
+		"model":      modelName,
+		"license_id": req.LicenseID,
+	})
+}
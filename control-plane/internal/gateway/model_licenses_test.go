@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestModelLicenseCache(t *testing.T) {
+	c := newModelLicenseCache()
+	if _, ok := c.get("llama-3-8b"); ok {
+		t.Fatal("empty cache returned an entry")
+	}
+
+	license := &ModelLicense{ID: uuid.New(), Name: "Llama 3 Community License"}
+	c.set("llama-3-8b", license)
+	c.set("mistral-7b", nil)
+
+	if got, ok := c.get("llama-3-8b"); !ok || got != license {
+		t.Errorf("get(llama-3-8b) = %v, %v", got, ok)
+	}
+	if got, ok := c.get("mistral-7b"); !ok || got != nil {
+		t.Errorf("get(mistral-7b) = %v, %v; want cached ungated model", got, ok)
+	}
+
+	c.invalidateAll()
+	if _, ok := c.get("llama-3-8b"); ok {
+		t.Error("invalidateAll() kept an entry")
+	}
+}
+
+func TestLicenseRequiredError(t *testing.T) {
+	license := &ModelLicense{
+		ID:      uuid.MustParse("6f1c2a44-0d7e-4d3b-9a51-3c8e2b7f9d10"),
+		Name:    "Llama 3.1 Community License",
+		Version: "3.1",
+		URL:     "https://llama.meta.com/llama3_1/license/",
+	}
+
+	body := licenseRequiredError("llama-3.1-8b", license)
+	if body["code"] != "license_not_accepted" || body["type"] != "permission_error" {
+		t.Errorf("licenseRequiredError() = %v", body)
+	}
+	message, _ := body["message"].(string)
+	for _, want := range []string{"llama-3.1-8b", "Llama 3.1 Community License", "version 3.1", license.URL, "/v1/licenses/" + license.ID.String() + "/accept"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message %q missing %q", message, want)
+		}
+	}
+}
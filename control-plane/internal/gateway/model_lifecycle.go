@@ -0,0 +1,253 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// modelLifecycleCacheTTL bounds how long a model's deprecation state is cached
+// in-process before being re-read from the database.
+const modelLifecycleCacheTTL = 60 * time.Second
+
+// ModelLifecycle describes the deprecation state of a model
+type ModelLifecycle struct {
+	Model            string     `json:"model"`
+	Status           string     `json:"status"`
+	DeprecatedAt     *time.Time `json:"deprecated_at,omitempty"`
+	SunsetAt         *time.Time `json:"sunset_at,omitempty"`
+	ReplacementModel *string    `json:"replacement_model,omitempty"`
+	Notice           *string    `json:"notice,omitempty"`
+}
+
+// IsDeprecated reports whether the model has been marked deprecated
+func (m *ModelLifecycle) IsDeprecated() bool {
+	return m != nil && (m.Status == "deprecated" || m.DeprecatedAt != nil)
+}
+
+// IsDecommissioned reports whether the model's sunset date has passed
+func (m *ModelLifecycle) IsDecommissioned(now time.Time) bool {
+	return m != nil && m.SunsetAt != nil && !now.Before(*m.SunsetAt)
+}
+
+// SetHeaders writes the Deprecation, Sunset and successor Link headers
+// (RFC 9745 / RFC 8594) for a deprecated model.
+func (m *ModelLifecycle) SetHeaders(h http.Header) {
+	if !m.IsDeprecated() {
+		return
+	}
+
+	if m.DeprecatedAt != nil {
+		h.Set("Deprecation", fmt.Sprintf("@%d", m.DeprecatedAt.Unix()))
+	} else {
+		h.Set("Deprecation", "true")
+	}
+	if m.SunsetAt != nil {
+		h.Set("Sunset", m.SunsetAt.UTC().Format(http.TimeFormat))
+	}
+	if m.ReplacementModel != nil && *m.ReplacementModel != "" {
+		h.Add("Link", fmt.Sprintf(`</v1/models/%s>; rel="successor-version"`, *m.ReplacementModel))
+	}
+}
+
+type cachedModelLifecycle struct {
+	lifecycle *ModelLifecycle
+	expiresAt time.Time
+}
+
+// modelLifecycleCache is a small in-process TTL cache so the hot inference
+// path does not hit Postgres for every request.
+type modelLifecycleCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedModelLifecycle
+}
+
+func newModelLifecycleCache() *modelLifecycleCache {
+	return &modelLifecycleCache{entries: make(map[string]cachedModelLifecycle)}
+}
+
+func (c *modelLifecycleCache) get(model string) (*ModelLifecycle, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[model]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.lifecycle, true
+}
+
+func (c *modelLifecycleCache) set(model string, lifecycle *ModelLifecycle) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[model] = cachedModelLifecycle{lifecycle: lifecycle, expiresAt: time.Now().Add(modelLifecycleCacheTTL)}
+}
+
+func (c *modelLifecycleCache) invalidate(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, model)
+}
+
+// getModelLifecycle returns the lifecycle state for a model by name.
+// Unknown models return nil so routing behaves exactly as before.
+func (g *Gateway) getModelLifecycle(ctx context.Context, modelName string) (*ModelLifecycle, error) {
+	if lifecycle, ok := g.modelLifecycle.get(modelName); ok {
+		return lifecycle, nil
+	}
+
+	lifecycle := &ModelLifecycle{Model: modelName}
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT status, deprecated_at, sunset_at, replacement_model, deprecation_notice
+		FROM models
+		WHERE name = $1
+	`, modelName).Scan(&lifecycle.Status, &lifecycle.DeprecatedAt, &lifecycle.SunsetAt, &lifecycle.ReplacementModel, &lifecycle.Notice)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.modelLifecycle.set(modelName, nil)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	g.modelLifecycle.set(modelName, lifecycle)
+	return lifecycle, nil
+}
+
+// enforceModelLifecycle adds deprecation headers for deprecated models and
+// rejects requests to models past their sunset date. It returns false when
+// the request has already been answered.
+func (g *Gateway) enforceModelLifecycle(w http.ResponseWriter, r *http.Request, modelName string) bool {
+	lifecycle, err := g.getModelLifecycle(r.Context(), modelName)
+	if err != nil {
+		// Fail open: lifecycle lookups must never take down inference
+		g.logger.Warn("failed to load model lifecycle",
+			zap.Error(err),
+			zap.String("model", modelName),
+		)
+		return true
+	}
+	if lifecycle == nil {
+		return true
+	}
+
+	lifecycle.SetHeaders(w.Header())
+
+	if lifecycle.IsDecommissioned(time.Now()) {
+		g.writeModelDecommissioned(w, lifecycle)
+		return false
+	}
+	return true
+}
+
+// writeModelDecommissioned writes the model_decommissioned error with a
+// suggested replacement when one is configured
+func (g *Gateway) writeModelDecommissioned(w http.ResponseWriter, lifecycle *ModelLifecycle) {
+	message := fmt.Sprintf("The model '%s' was decommissioned on %s", lifecycle.Model, lifecycle.SunsetAt.UTC().Format("2006-01-02"))
+	errBody := map[string]interface{}{
+		"message": message,
+		"type":    "invalid_request_error",
+		"code":    "model_decommissioned",
+	}
+	if lifecycle.ReplacementModel != nil && *lifecycle.ReplacementModel != "" {
+		errBody["message"] = fmt.Sprintf("%s; use '%s' instead", message, *lifecycle.ReplacementModel)
+		errBody["replacement_model"] = *lifecycle.ReplacementModel
+	}
+
+	g.writeJSON(w, http.StatusGone, map[string]interface{}{
+		"error": errBody,
+	})
+}
+
+// ModelDeprecateRequest is the request body for deprecating a model
+type ModelDeprecateRequest struct {
+	SunsetAt         time.Time `json:"sunset_at"`
+	ReplacementModel *string   `json:"replacement_model,omitempty"`
+	Notice           *string   `json:"notice,omitempty"`
+}
+
+// HandleDeprecateModel marks a model deprecated and schedules its sunset
+// Admin API - POST /api/v1/admin/models/{id}/deprecate
+func (g *Gateway) HandleDeprecateModel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	modelID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid model ID")
+		return
+	}
+
+	var req ModelDeprecateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.SunsetAt.IsZero() {
+		g.writeError(w, http.StatusBadRequest, "sunset_at is required")
+		return
+	}
+	if !req.SunsetAt.After(time.Now()) {
+		g.writeError(w, http.StatusBadRequest, "sunset_at must be in the future")
+		return
+	}
+
+	if req.ReplacementModel != nil && *req.ReplacementModel != "" {
+		var exists bool
+		err := g.db.Pool.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM models WHERE name = $1 AND status = 'active' AND id <> $2)
+		`, *req.ReplacementModel, modelID).Scan(&exists)
+		if err != nil {
+			g.logger.Error("failed to check replacement model", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to deprecate model")
+			return
+		}
+		if !exists {
+			g.writeError(w, http.StatusBadRequest, "replacement_model must be an active model")
+			return
+		}
+	}
+
+	var lifecycle ModelLifecycle
+	err = g.db.Pool.QueryRow(ctx, `
+		UPDATE models
+		SET status = 'deprecated',
+		    deprecated_at = COALESCE(deprecated_at, NOW()),
+		    sunset_at = $2,
+		    replacement_model = $3,
+		    deprecation_notice = $4
+		WHERE id = $1
+		RETURNING name, status, deprecated_at, sunset_at, replacement_model, deprecation_notice
+	`, modelID, req.SunsetAt, req.ReplacementModel, req.Notice).Scan(
+		&lifecycle.Model, &lifecycle.Status, &lifecycle.DeprecatedAt,
+		&lifecycle.SunsetAt, &lifecycle.ReplacementModel, &lifecycle.Notice,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "model not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to deprecate model", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to deprecate model")
+		return
+	}
+
+	g.modelLifecycle.invalidate(lifecycle.Model)
+	g.publishCatalogChanged(ctx, lifecycle.Model, "deprecated")
+
+	g.logger.Info("model deprecated",
+		zap.String("model_id", modelID.String()),
+		zap.String("model", lifecycle.Model),
+		zap.Time("sunset_at", req.SunsetAt),
+	)
+
+	g.writeJSON(w, http.StatusOK, lifecycle)
+}
@@ -0,0 +1,286 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// prewarmMaxWindow is the longest a prewarm may hold extra replicas
+	prewarmMaxWindow = 24 * time.Hour
+
+	// prewarmMaxAhead is how far ahead a prewarm may be scheduled
+	prewarmMaxAhead = 30 * 24 * time.Hour
+
+	// prewarmMaxReplicas caps the extra replicas of a single prewarm
+	prewarmMaxReplicas = 20
+)
+
+// ModelPrewarm is a request for extra replicas of a model's deployment over
+// a window. The deployment controller launches them PrewarmLead before the
+// window, warms the model cache once they are up and scales them back when
+// the window ends.
+type ModelPrewarm struct {
+	ID           uuid.UUID  `json:"id"`
+	ModelName    string     `json:"model"`
+	DeploymentID uuid.UUID  `json:"deployment_id"`
+	TenantID     *uuid.UUID `json:"tenant_id,omitempty"`
+	Replicas     int        `json:"replicas"`
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       time.Time  `json:"ends_at"`
+	LaunchAt     time.Time  `json:"launch_at"`
+	Status       string     `json:"status"`
+	CreatedBy    *string    `json:"created_by,omitempty"`
+	WarmedAt     *time.Time `json:"warmed_at,omitempty"`
+	ScaledBackAt *time.Time `json:"scaled_back_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+const modelPrewarmColumns = `
+	id, model_name, deployment_id, tenant_id, replicas, starts_at, ends_at,
+	status, created_by, warmed_at, scaled_back_at, created_at
+`
+
+func scanModelPrewarm(row pgx.Row) (*ModelPrewarm, error) {
+	var p ModelPrewarm
+	err := row.Scan(&p.ID, &p.ModelName, &p.DeploymentID, &p.TenantID, &p.Replicas, &p.StartsAt, &p.EndsAt,
+		&p.Status, &p.CreatedBy, &p.WarmedAt, &p.ScaledBackAt, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.LaunchAt = p.StartsAt.Add(-orchestrator.PrewarmLead)
+	return &p, nil
+}
+
+// prewarmRequest is the body of POST /v1/models/{model}/prewarm and
+// POST /admin/models/{id}/prewarm
+type prewarmRequest struct {
+	Replicas     int        `json:"replicas"`
+	StartsAt     time.Time  `json:"starts_at"` // defaults to now
+	EndsAt       time.Time  `json:"ends_at"`
+	DeploymentID *uuid.UUID `json:"deployment_id"` // admin only; defaults to the shared deployment
+}
+
+// validate checks the request's fields and fills in the default start
+func (req *prewarmRequest) validate(now time.Time) error {
+	if req.Replicas < 1 || req.Replicas > prewarmMaxReplicas {
+		return fmt.Errorf("replicas must be between 1 and %d", prewarmMaxReplicas)
+	}
+	if req.StartsAt.IsZero() {
+		req.StartsAt = now
+	}
+	if req.EndsAt.IsZero() {
+		return errors.New("ends_at is required")
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if !req.EndsAt.After(now) {
+		return errors.New("ends_at must be in the future")
+	}
+	if req.EndsAt.Sub(req.StartsAt) > prewarmMaxWindow {
+		return fmt.Errorf("a prewarm window may last at most %s", prewarmMaxWindow)
+	}
+	if req.StartsAt.Sub(now) > prewarmMaxAhead {
+		return errors.New("starts_at may be at most 30 days ahead")
+	}
+	return nil
+}
+
+// insertModelPrewarm schedules a prewarm on a deployment
+func (g *Gateway) insertModelPrewarm(ctx context.Context, modelName string, deploymentID uuid.UUID, tenantID *uuid.UUID, req prewarmRequest, createdBy *string) (*ModelPrewarm, error) {
+	return scanModelPrewarm(g.db.Pool.QueryRow(ctx, `
+		INSERT INTO model_prewarms (model_name, deployment_id, tenant_id, replicas, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+modelPrewarmColumns,
+		modelName, deploymentID, tenantID, req.Replicas, req.StartsAt, req.EndsAt, createdBy,
+	))
+}
+
+// handleCreateTenantPrewarm schedules extra replicas of a model ahead of a
+// tenant's launch event. The tenant's dedicated deployment of the model is
+// prewarmed if it has one, the shared deployment otherwise. Extra replicas
+// held by the tenant's prewarms at once are capped by its plan.
+// Tenant API - POST /v1/models/{model}/prewarm
+func (g *Gateway) handleCreateTenantPrewarm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok && keyInfo.Role == "read-only" {
+		g.writeError(w, http.StatusForbidden, "read-only API keys cannot prewarm models")
+		return
+	}
+
+	var req prewarmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.DeploymentID != nil {
+		g.writeError(w, http.StatusBadRequest, "deployment_id cannot be set by tenants")
+		return
+	}
+	now := time.Now()
+	if err := req.validate(now); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	plan := g.tenantPlan(ctx, tenantID)
+	if plan.PrewarmReplicas == 0 {
+		g.writeError(w, http.StatusForbidden, fmt.Sprintf("model prewarming is not available on the %s plan", plan.Name))
+		return
+	}
+
+	modelName := chi.URLParam(r, "model")
+	if target, err := g.resolveModelAlias(ctx, modelName); err != nil {
+		g.logger.Warn("failed to resolve model alias", zap.Error(err), zap.String("model", modelName))
+	} else if target != "" {
+		modelName = target
+	}
+
+	var deploymentID uuid.UUID
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT id FROM deployments
+		WHERE model_name = $1 AND status = 'active' AND (tenant_id = $2 OR tenant_id IS NULL)
+		ORDER BY tenant_id IS NULL, created_at
+		LIMIT 1
+	`, modelName, tenantID).Scan(&deploymentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, fmt.Sprintf("no active deployment serves model %q", modelName))
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to look up deployment for prewarm", zap.Error(err), zap.String("model", modelName))
+		g.writeError(w, http.StatusInternalServerError, "failed to schedule prewarm")
+		return
+	}
+
+	// Prewarms that overlap in time share the quota; counting every
+	// unfinished prewarm keeps the check simple and errs on the safe side
+	var held int
+	err = g.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(replicas), 0) FROM model_prewarms
+		WHERE tenant_id = $1 AND status = 'scheduled' AND ends_at > $2
+	`, tenantID, now).Scan(&held)
+	if err != nil {
+		g.logger.Error("failed to count tenant prewarms", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to schedule prewarm")
+		return
+	}
+	if held+req.Replicas > plan.PrewarmReplicas {
+		g.writeError(w, http.StatusForbidden, fmt.Sprintf(
+			"prewarm quota exceeded: %d of %d replicas already scheduled on the %s plan",
+			held, plan.PrewarmReplicas, plan.Name))
+		return
+	}
+
+	prewarm, err := g.insertModelPrewarm(ctx, modelName, deploymentID, &tenantID, req, nil)
+	if err != nil {
+		g.logger.Error("failed to create prewarm", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to schedule prewarm")
+		return
+	}
+
+	g.logger.Info("model prewarm scheduled",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("prewarm_id", prewarm.ID.String()),
+		zap.String("model", modelName),
+		zap.Int("replicas", prewarm.Replicas),
+		zap.Time("starts_at", prewarm.StartsAt),
+	)
+	g.writeJSON(w, http.StatusCreated, prewarm)
+}
+
+// HandleCreateModelPrewarm schedules extra replicas of a model's shared
+// deployment, or of the given deployment, outside any tenant quota
+// Admin API - POST /admin/models/{id}/prewarm
+func (g *Gateway) HandleCreateModelPrewarm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	modelID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid model ID")
+		return
+	}
+
+	var req prewarmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.validate(time.Now()); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var modelName string
+	if err := g.db.Pool.QueryRow(ctx, `SELECT name FROM models WHERE id = $1`, modelID).Scan(&modelName); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			g.writeError(w, http.StatusNotFound, "model not found")
+			return
+		}
+		g.logger.Error("failed to load model for prewarm", zap.Error(err), zap.String("model_id", modelID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to schedule prewarm")
+		return
+	}
+
+	var deploymentID uuid.UUID
+	if req.DeploymentID != nil {
+		err = g.db.Pool.QueryRow(ctx, `
+			SELECT id FROM deployments WHERE id = $1 AND model_name = $2 AND status = 'active'
+		`, *req.DeploymentID, modelName).Scan(&deploymentID)
+	} else {
+		err = g.db.Pool.QueryRow(ctx, `
+			SELECT id FROM deployments
+			WHERE model_name = $1 AND status = 'active' AND tenant_id IS NULL
+			ORDER BY created_at
+			LIMIT 1
+		`, modelName).Scan(&deploymentID)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, fmt.Sprintf("no active deployment of model %q found", modelName))
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to look up deployment for prewarm", zap.Error(err), zap.String("model", modelName))
+		g.writeError(w, http.StatusInternalServerError, "failed to schedule prewarm")
+		return
+	}
+
+	var createdBy *string
+	if name, ok := ctx.Value("admin_token").(string); ok && name != "" {
+		createdBy = &name
+	}
+
+	prewarm, err := g.insertModelPrewarm(ctx, modelName, deploymentID, nil, req, createdBy)
+	if err != nil {
+		g.logger.Error("failed to create prewarm", zap.Error(err), zap.String("model", modelName))
+		g.writeError(w, http.StatusInternalServerError, "failed to schedule prewarm")
+		return
+	}
+
+	g.logger.Info("model prewarm scheduled by admin",
+		zap.String("prewarm_id", prewarm.ID.String()),
+		zap.String("model", modelName),
+		zap.String("deployment_id", deploymentID.String()),
+		zap.Int("replicas", prewarm.Replicas),
+		zap.Time("starts_at", prewarm.StartsAt),
+	)
+	g.writeJSON(w, http.StatusCreated, prewarm)
+}
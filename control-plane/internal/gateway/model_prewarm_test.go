@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrewarmRequestValidate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		req     prewarmRequest
+		wantErr string
+	}{
+		{"valid", prewarmRequest{Replicas: 2, StartsAt: now.Add(time.Hour), EndsAt: now.Add(3 * time.Hour)}, ""},
+		{"starts now by default", prewarmRequest{Replicas: 1, EndsAt: now.Add(time.Hour)}, ""},
+		{"no replicas", prewarmRequest{EndsAt: now.Add(time.Hour)}, "replicas must be"},
+		{"too many replicas", prewarmRequest{Replicas: prewarmMaxReplicas + 1, EndsAt: now.Add(time.Hour)}, "replicas must be"},
+		{"missing end", prewarmRequest{Replicas: 1}, "ends_at is required"},
+		{"ends before start", prewarmRequest{Replicas: 1, StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(time.Hour)}, "after starts_at"},
+		{"already over", prewarmRequest{Replicas: 1, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)}, "in the future"},
+		{"window too long", prewarmRequest{Replicas: 1, EndsAt: now.Add(25 * time.Hour)}, "at most"},
+		{"too far ahead", prewarmRequest{Replicas: 1, StartsAt: now.Add(31 * 24 * time.Hour), EndsAt: now.Add(31*24*time.Hour + time.Hour)}, "30 days"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			err := req.validate(now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate() = %v, want nil", err)
+				}
+				if req.StartsAt.IsZero() {
+					t.Error("validate() left starts_at unset")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// Internal node listener.
+//
+// Node agents register, heartbeat, look up the node registry and report
+// drains, termination warnings and crashes. Those callbacks are also served
+// on a second listener (NODE_API_PORT) that can be firewalled to node
+// networks. It authenticates nodes with a shared node token instead of
+// admin tokens, serves nothing but the node callbacks and has its own
+// timeouts and limits, so a misbehaving fleet can't starve the public API
+// and vice versa. The routes keep their public paths so agents only need a
+// different base URL; the admin-token routes stay on the public listener for
+// operators and older agents.
+
+// nodeAPIActor identifies node agent callbacks in audit fields
+const nodeAPIActor = "node-agent"
+
+// NodeAPISettings configures the internal node listener
+type NodeAPISettings struct {
+	Token          string
+	MaxBodyBytes   int64
+	MaxConcurrent  int
+	HandlerTimeout time.Duration
+}
+
+// NodeAPIHandler returns the handler for the internal node listener
+func (g *Gateway) NodeAPIHandler(settings NodeAPISettings) http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(g.requestIDResponseMiddleware)
+	r.Use(g.loggerMiddleware)
+	r.Use(middleware.Recoverer)
+	if settings.HandlerTimeout > 0 {
+		r.Use(middleware.Timeout(settings.HandlerTimeout))
+	}
+	r.Use(RequestSizeLimitMiddleware(settings.MaxBodyBytes))
+	if settings.MaxConcurrent > 0 {
+		r.Use(middleware.Throttle(settings.MaxConcurrent))
+	}
+
+	// Load balancer health checks
+	r.Get("/health", g.handleHealth)
+	r.Get("/registry/discovery", g.handleNodeDiscovery)
+
+	r.Group(func(r chi.Router) {
+		r.Use(g.nodeAuthMiddleware(settings.Token))
+
+		r.Post("/admin/nodes/register", g.handleRegisterNode)
+		r.Post("/admin/nodes/{node_id}/heartbeat", g.handleHeartbeat)
+		r.Post("/admin/nodes/{node_id}/drain", g.handleDrainNode)
+		r.Post("/admin/nodes/{node_id}/termination-warning", g.handleTerminationWarning)
+		r.Post("/admin/nodes/{node_id}/discovery-token", g.handleIssueDiscoveryToken)
+		r.Post("/admin/nodes/{id}/crash-reports", g.handleCreateCrashReport)
+		r.Post("/admin/nodes/{id}/crash-reports/{report_id}/uploaded", g.handleCrashBundleUploaded)
+	})
+	return r
+}
+
+// nodeAuthMiddleware accepts requests carrying the node token as a bearer
+// token or in X-Node-Token
+func (g *Gateway) nodeAuthMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented := r.Header.Get("X-Node-Token")
+			if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				presented = bearer
+			}
+			if presented == "" {
+				g.writeError(w, http.StatusUnauthorized, "missing node token")
+				return
+			}
+			if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				g.logger.Warn("invalid node token",
+					zap.String("remote_addr", r.RemoteAddr),
+					zap.String("path", r.URL.Path),
+				)
+				g.writeError(w, http.StatusUnauthorized, "invalid node token")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), "admin_token", nodeAPIActor)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
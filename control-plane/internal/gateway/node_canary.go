@@ -0,0 +1,363 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Newly registered nodes are ramped into routing rather than receiving a
+// full share of traffic at once. During the ramp a node is offered to each
+// request with a probability that grows linearly from the initial share to
+// 1, and its error rate and latency are compared with the model's other
+// nodes. A node that fails is cordoned with a zero-weight routing override;
+// one that lasts the ramp is admitted. Ramps are recorded in
+// node_admissions so every gateway replica ramps the same nodes.
+
+const (
+	canaryAdmitted = "admitted"
+	canaryCordoned = "cordoned"
+)
+
+var nodeCanaries = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gateway_node_canaries_total",
+		Help: "Node traffic ramps by outcome",
+	},
+	[]string{"outcome"},
+)
+
+// canarySettings configures the ramp of new nodes; a zero duration turns
+// ramping off
+type canarySettings struct {
+	duration     time.Duration
+	initialShare float64
+	// A node is cordoned once it has served minRequests with an error rate
+	// above maxErrorRate, or latency above maxLatencyFactor times the
+	// average of the model's admitted nodes
+	maxErrorRate     float64
+	minRequests      int64
+	maxLatencyFactor float64
+}
+
+func defaultCanarySettings() canarySettings {
+	return canarySettings{
+		duration:         5 * time.Minute,
+		initialShare:     0.05,
+		maxErrorRate:     0.1,
+		minRequests:      20,
+		maxLatencyFactor: 3,
+	}
+}
+
+// canaryState is a ramping node's progress as seen by this gateway
+type canaryState struct {
+	nodeID   uuid.UUID
+	started  time.Time
+	peers    []string // the model's other endpoints when last routed
+	requests int64
+	errors   int64
+	latency  time.Duration // moving average, as in EndpointStats
+}
+
+// SetCanaryRamp configures how newly registered nodes are ramped into
+// routing. A zero duration sends them full traffic at once.
+func (lb *IntelligentLoadBalancer) SetCanaryRamp(duration time.Duration, initialShare, maxErrorRate float64, minRequests int, maxLatencyFactor float64) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	settings := defaultCanarySettings()
+	settings.duration = duration
+	if initialShare > 0 && initialShare <= 1 {
+		settings.initialShare = initialShare
+	}
+	if maxErrorRate > 0 {
+		settings.maxErrorRate = maxErrorRate
+	}
+	if minRequests > 0 {
+		settings.minRequests = int64(minRequests)
+	}
+	if maxLatencyFactor > 0 {
+		settings.maxLatencyFactor = maxLatencyFactor
+	}
+	lb.canary = settings
+}
+
+// canaryShare is the share of traffic a node ramping since started is
+// offered at now
+func canaryShare(started, now time.Time, settings canarySettings) float64 {
+	if settings.duration <= 0 {
+		return 1
+	}
+	progress := float64(now.Sub(started)) / float64(settings.duration)
+	if progress >= 1 {
+		return 1
+	}
+	if progress < 0 {
+		progress = 0
+	}
+	return settings.initialShare + (1-settings.initialShare)*progress
+}
+
+// StartCanary begins ramping a node that has just registered. A node that
+// registers again, such as after its server restarted, is ramped again.
+func (lb *IntelligentLoadBalancer) StartCanary(ctx context.Context, nodeID uuid.UUID, endpoint string) error {
+	lb.mu.RLock()
+	enabled := lb.canary.duration > 0
+	lb.mu.RUnlock()
+	if !enabled || endpoint == "" {
+		return nil
+	}
+
+	var started time.Time
+	err := lb.db.Pool.QueryRow(ctx, `
+		INSERT INTO node_admissions (node_id, endpoint, started_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (node_id) DO UPDATE
+		SET endpoint = EXCLUDED.endpoint, started_at = NOW(), finished_at = NULL, outcome = NULL, reason = NULL
+		RETURNING started_at
+	`, nodeID, endpoint).Scan(&started)
+	if err != nil {
+		return err
+	}
+
+	lb.mu.Lock()
+	if lb.canaries == nil {
+		lb.canaries = make(map[string]*canaryState)
+	}
+	lb.canaries[endpoint] = &canaryState{nodeID: nodeID, started: started}
+	lb.mu.Unlock()
+	lb.InvalidateRoutes()
+	return nil
+}
+
+// noteCanaries syncs ramp state with the ramps of a model's routable
+// endpoints, keyed by endpoint with a nil start for endpoints not ramping
+func (lb *IntelligentLoadBalancer) noteCanaries(ramps map[string]*rampStart) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if lb.canaries == nil {
+		lb.canaries = make(map[string]*canaryState)
+	}
+
+	var endpoints []string
+	for endpoint := range ramps {
+		endpoints = append(endpoints, endpoint)
+	}
+	for endpoint, ramp := range ramps {
+		if ramp == nil {
+			delete(lb.canaries, endpoint)
+			continue
+		}
+		c, ok := lb.canaries[endpoint]
+		if !ok || !c.started.Equal(ramp.started) {
+			c = &canaryState{nodeID: ramp.nodeID, started: ramp.started}
+			lb.canaries[endpoint] = c
+		}
+		c.peers = c.peers[:0]
+		for _, peer := range endpoints {
+			if peer != endpoint {
+				c.peers = append(c.peers, peer)
+			}
+		}
+	}
+}
+
+// rampStart is when a routable node's current ramp started
+type rampStart struct {
+	nodeID  uuid.UUID
+	started time.Time
+}
+
+// applyCanaryRamp offers each ramping endpoint with probability equal to
+// its current share. Ramping endpoints are kept when they are all the
+// model has. Callers must hold lb.mu.
+func (lb *IntelligentLoadBalancer) applyCanaryRamp(endpoints []string, now time.Time) []string {
+	if len(lb.canaries) == 0 {
+		return endpoints
+	}
+	offered := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		c, ok := lb.canaries[endpoint]
+		if ok && lb.random() >= canaryShare(c.started, now, lb.canary) {
+			continue
+		}
+		offered = append(offered, endpoint)
+	}
+	if len(offered) == 0 {
+		return endpoints
+	}
+	return offered
+}
+
+// recordCanaryRequest counts a request served by a ramping endpoint.
+// Callers must hold lb.mu for writing.
+func (lb *IntelligentLoadBalancer) recordCanaryRequest(endpoint string, latency time.Duration, isError bool) {
+	c, ok := lb.canaries[endpoint]
+	if !ok {
+		return
+	}
+	if c.latency == 0 {
+		c.latency = latency
+	} else {
+		c.latency = time.Duration(float64(c.latency)*0.8 + float64(latency)*0.2)
+	}
+	c.requests++
+	if isError {
+		c.errors++
+	}
+}
+
+// judgeCanary decides a ramp's outcome: cordoned with a reason when the
+// node errs or lags too much, admitted once the ramp is over, or "" while
+// it is still ramping. baseline is the average latency of the model's
+// admitted nodes, zero when unknown.
+func judgeCanary(c *canaryState, baseline time.Duration, now time.Time, settings canarySettings) (string, string) {
+	if c.requests >= settings.minRequests {
+		errorRate := float64(c.errors) / float64(c.requests)
+		if errorRate > settings.maxErrorRate {
+			return canaryCordoned, fmt.Sprintf("error rate %.0f%% over %d requests exceeds %.0f%%",
+				errorRate*100, c.requests, settings.maxErrorRate*100)
+		}
+		if baseline > 0 && float64(c.latency) > float64(baseline)*settings.maxLatencyFactor {
+			return canaryCordoned, fmt.Sprintf("latency %s is over %.1fx the model's %s",
+				c.latency.Round(time.Millisecond), settings.maxLatencyFactor, baseline.Round(time.Millisecond))
+		}
+	}
+	if now.Sub(c.started) >= settings.duration {
+		return canaryAdmitted, ""
+	}
+	return "", ""
+}
+
+// canaryVerdict is a ramp that has ended
+type canaryVerdict struct {
+	endpoint string
+	nodeID   uuid.UUID
+	outcome  string
+	reason   string
+}
+
+// evaluateCanaries ends the ramps of nodes that failed or completed them
+func (lb *IntelligentLoadBalancer) evaluateCanaries(ctx context.Context) {
+	now := time.Now()
+
+	lb.mu.Lock()
+	var verdicts []canaryVerdict
+	for endpoint, c := range lb.canaries {
+		outcome, reason := judgeCanary(c, lb.peerLatency(c.peers), now, lb.canary)
+		if outcome == "" {
+			continue
+		}
+		verdicts = append(verdicts, canaryVerdict{endpoint: endpoint, nodeID: c.nodeID, outcome: outcome, reason: reason})
+		delete(lb.canaries, endpoint)
+	}
+	lb.mu.Unlock()
+
+	for _, v := range verdicts {
+		if err := lb.finishCanary(ctx, v); err != nil {
+			lb.logger.Error("failed to record node ramp outcome",
+				zap.String("endpoint", v.endpoint),
+				zap.String("outcome", v.outcome),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// peerLatency is the average latency of the admitted endpoints among
+// peers, zero when none has served requests. Callers must hold lb.mu.
+func (lb *IntelligentLoadBalancer) peerLatency(peers []string) time.Duration {
+	var total time.Duration
+	var n int
+	for _, peer := range peers {
+		if _, ramping := lb.canaries[peer]; ramping {
+			continue
+		}
+		if stats, ok := lb.stats[peer]; ok && stats.RequestCount > 0 && stats.Latency > 0 {
+			total += stats.Latency
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return total / time.Duration(n)
+}
+
+// finishCanary records a ramp's outcome, cordoning failed nodes. Another
+// gateway replica may have ended the ramp first, in which case nothing
+// changes.
+func (lb *IntelligentLoadBalancer) finishCanary(ctx context.Context, v canaryVerdict) error {
+	tx, err := lb.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE node_admissions
+		SET finished_at = NOW(), outcome = $2, reason = NULLIF($3, '')
+		WHERE node_id = $1 AND finished_at IS NULL
+	`, v.nodeID, v.outcome, v.reason)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	var override *RoutingOverride
+	if v.outcome == canaryCordoned {
+		reason := "failed traffic ramp: " + v.reason
+		override = &RoutingOverride{Endpoint: v.endpoint, Weight: 0, Reason: &reason}
+		err := tx.QueryRow(ctx, `
+			INSERT INTO routing_overrides (endpoint, pinned, weight, reason, updated_at)
+			VALUES ($1, false, 0, $2, NOW())
+			ON CONFLICT (endpoint) DO UPDATE
+			SET pinned = false, weight = 0, reason = EXCLUDED.reason, updated_at = NOW()
+			RETURNING updated_at
+		`, v.endpoint, reason).Scan(&override.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	nodeCanaries.WithLabelValues(v.outcome).Inc()
+	if override != nil {
+		lb.mu.Lock()
+		lb.overrides[v.endpoint] = *override
+		lb.mu.Unlock()
+		lb.logger.Warn("cordoned node that failed its traffic ramp",
+			zap.String("node_id", v.nodeID.String()),
+			zap.String("endpoint", v.endpoint),
+			zap.String("reason", v.reason),
+		)
+		return nil
+	}
+	lb.logger.Info("node admitted after traffic ramp",
+		zap.String("node_id", v.nodeID.String()),
+		zap.String("endpoint", v.endpoint),
+	)
+	return nil
+}
+
+// CanaryShare returns the share of traffic a ramping endpoint is offered,
+// and false when it isn't ramping
+func (lb *IntelligentLoadBalancer) CanaryShare(endpoint string) (float64, bool) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	c, ok := lb.canaries[endpoint]
+	if !ok {
+		return 0, false
+	}
+	return canaryShare(c.started, time.Now(), lb.canary), true
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCanaryShare(t *testing.T) {
+	settings := defaultCanarySettings()
+	start := time.Now()
+
+	tests := []struct {
+		elapsed time.Duration
+		want    float64
+	}{
+		{0, 0.05},
+		{settings.duration / 2, 0.525},
+		{settings.duration, 1},
+		{2 * settings.duration, 1},
+	}
+	for _, tt := range tests {
+		if got := canaryShare(start, start.Add(tt.elapsed), settings); got < tt.want-1e-9 || got > tt.want+1e-9 {
+			t.Errorf("canaryShare after %s = %v, want %v", tt.elapsed, got, tt.want)
+		}
+	}
+
+	settings.duration = 0
+	if got := canaryShare(start, start, settings); got != 1 {
+		t.Errorf("canaryShare with ramping off = %v, want 1", got)
+	}
+}
+
+func TestApplyCanaryRamp(t *testing.T) {
+	now := time.Now()
+	lb := &IntelligentLoadBalancer{
+		canaries: map[string]*canaryState{"http://new": {started: now}},
+		canary:   defaultCanarySettings(),
+	}
+	endpoints := []string{"http://old", "http://new"}
+
+	// At the start of the ramp the new node is offered 5% of the time
+	lb.random = func() float64 { return 0.5 }
+	if got := lb.applyCanaryRamp(endpoints, now); !reflect.DeepEqual(got, []string{"http://old"}) {
+		t.Errorf("ramp = %v, want the new node held back", got)
+	}
+	lb.random = func() float64 { return 0.01 }
+	if got := lb.applyCanaryRamp(endpoints, now); !reflect.DeepEqual(got, endpoints) {
+		t.Errorf("ramp = %v, want the new node offered", got)
+	}
+
+	// A model whose only node is ramping still gets served
+	lb.random = func() float64 { return 0.5 }
+	if got := lb.applyCanaryRamp([]string{"http://new"}, now); !reflect.DeepEqual(got, []string{"http://new"}) {
+		t.Errorf("ramp = %v, want the only node kept", got)
+	}
+}
+
+func TestJudgeCanary(t *testing.T) {
+	settings := defaultCanarySettings()
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		canary   canaryState
+		baseline time.Duration
+		want     string
+		reason   string
+	}{
+		{"ramping", canaryState{started: now, requests: 30, errors: 1, latency: 100 * time.Millisecond}, 80 * time.Millisecond, "", ""},
+		{"too few requests to judge", canaryState{started: now, requests: 5, errors: 5}, 0, "", ""},
+		{"errors", canaryState{started: now, requests: 20, errors: 5}, 0, canaryCordoned, "error rate 25%"},
+		{"slow", canaryState{started: now, requests: 20, latency: 400 * time.Millisecond}, 100 * time.Millisecond, canaryCordoned, "latency 400ms"},
+		{"no baseline", canaryState{started: now, requests: 20, latency: 400 * time.Millisecond}, 0, "", ""},
+		{"ramp over", canaryState{started: now.Add(-settings.duration), requests: 20, latency: 100 * time.Millisecond}, 100 * time.Millisecond, canaryAdmitted, ""},
+		{"failed at the end", canaryState{started: now.Add(-settings.duration), requests: 20, errors: 10}, 0, canaryCordoned, "error rate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := judgeCanary(&tt.canary, tt.baseline, now, settings)
+			if got != tt.want {
+				t.Fatalf("outcome = %q, want %q (%s)", got, tt.want, reason)
+			}
+			if !strings.Contains(reason, tt.reason) {
+				t.Errorf("reason = %q, want it to mention %q", reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestRecordCanaryRequest(t *testing.T) {
+	lb := &IntelligentLoadBalancer{
+		stats:    make(map[string]*EndpointStats),
+		canaries: map[string]*canaryState{"http://new": {started: time.Now()}},
+	}
+	lb.RecordRequest("http://new", 100*time.Millisecond, false)
+	lb.RecordRequest("http://new", 200*time.Millisecond, true)
+	lb.RecordRequest("http://old", 50*time.Millisecond, true)
+
+	c := lb.canaries["http://new"]
+	if c.requests != 2 || c.errors != 1 {
+		t.Errorf("canary counted %d requests and %d errors, want 2 and 1", c.requests, c.errors)
+	}
+	if c.latency != 120*time.Millisecond {
+		t.Errorf("canary latency = %s, want 120ms", c.latency)
+	}
+}
+
+func TestPeerLatencySkipsRampingPeers(t *testing.T) {
+	lb := &IntelligentLoadBalancer{
+		stats: map[string]*EndpointStats{
+			"http://a":    {Latency: 100 * time.Millisecond, RequestCount: 10},
+			"http://b":    {Latency: 300 * time.Millisecond, RequestCount: 10},
+			"http://new":  {Latency: 900 * time.Millisecond, RequestCount: 10},
+			"http://idle": {},
+		},
+		canaries: map[string]*canaryState{"http://new": {}},
+	}
+	if got := lb.peerLatency([]string{"http://a", "http://b", "http://new", "http://idle"}); got != 200*time.Millisecond {
+		t.Errorf("peerLatency = %s, want 200ms", got)
+	}
+	if got := lb.peerLatency(nil); got != 0 {
+		t.Errorf("peerLatency without peers = %s, want 0", got)
+	}
+}
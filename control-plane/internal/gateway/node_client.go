@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"go.uber.org/zap"
+)
+
+const (
+	// nodeRequestTimeout bounds a proxied request, long enough for the
+	// longest generations
+	nodeRequestTimeout = 10 * time.Minute
+
+	defaultNodeMaxIdleConnsPerHost = 64
+	defaultNodeIdleConnTimeout     = 90 * time.Second
+	defaultNodePrewarmConns        = 4
+
+	// nodePrewarmTimeout bounds the health requests that open a new node's
+	// connections
+	nodePrewarmTimeout = 10 * time.Second
+)
+
+// hopByHopHeaders are not forwarded to nodes (RFC 9110 section 7.6.1)
+var hopByHopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Connection", "Proxy-Authorization",
+	"Te", "Transfer-Encoding", "Upgrade",
+}
+
+// nodePoolSettings configures the connection pool to inference nodes
+type nodePoolSettings struct {
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	// prewarmConns is how many connections are opened to a node when it
+	// becomes active; zero turns pre-warming off
+	prewarmConns int
+}
+
+func defaultNodePoolSettings() nodePoolSettings {
+	return nodePoolSettings{
+		maxIdleConnsPerHost: defaultNodeMaxIdleConnsPerHost,
+		idleConnTimeout:     defaultNodeIdleConnTimeout,
+		prewarmConns:        defaultNodePrewarmConns,
+	}
+}
+
+// newNodeClient returns the client shared by every request proxied to a
+// node, so connections are kept alive and reused across requests. Nodes
+// behind TLS negotiate HTTP/2 when they support it; plain HTTP nodes (vLLM
+// itself) use pooled HTTP/1.1 connections.
+func newNodeClient(settings nodePoolSettings) *http.Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          0, // bounded per host
+		MaxIdleConnsPerHost:   settings.maxIdleConnsPerHost,
+		IdleConnTimeout:       settings.idleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{Timeout: nodeRequestTimeout, Transport: transport}
+}
+
+// SetNodeConnectionPool configures the pool of connections to inference
+// nodes: idle connections kept per node, how long they stay open, and how
+// many are opened ahead of traffic when a node becomes active.
+func (g *Gateway) SetNodeConnectionPool(maxIdleConnsPerHost int, idleConnTimeout time.Duration, prewarmConns int) {
+	settings := defaultNodePoolSettings()
+	if maxIdleConnsPerHost > 0 {
+		settings.maxIdleConnsPerHost = maxIdleConnsPerHost
+	}
+	if idleConnTimeout > 0 {
+		settings.idleConnTimeout = idleConnTimeout
+	}
+	settings.prewarmConns = prewarmConns
+	if settings.prewarmConns < 0 {
+		settings.prewarmConns = 0
+	}
+	if settings.prewarmConns > settings.maxIdleConnsPerHost {
+		settings.prewarmConns = settings.maxIdleConnsPerHost
+	}
+
+	old := g.nodeClient
+	g.nodePool = settings
+	g.nodeClient = newNodeClient(settings)
+	if old != nil {
+		old.CloseIdleConnections()
+	}
+}
+
+// nodeURL is the URL of path on a node endpoint, which may lack a scheme
+func nodeURL(endpoint, path string) string {
+	if !strings.HasPrefix(endpoint, "http") {
+		return "http://" + endpoint + path
+	}
+	return endpoint + path
+}
+
+// subscribeNodePrewarm opens connections to nodes as they become active,
+// so their first requests don't pay for connection setup
+func (g *Gateway) subscribeNodePrewarm() {
+	if g.eventBus == nil {
+		return
+	}
+
+	g.eventBus.Subscribe(events.EventNodeRegistered, func(ctx context.Context, event events.Event) error {
+		endpoint, _ := event.Payload["endpoint"].(string)
+		if endpoint == "" {
+			endpoint = g.nodeEndpoint(ctx, event.Payload["node_id"])
+		}
+		g.prewarmNode(ctx, endpoint)
+		return nil
+	})
+	g.eventBus.Subscribe(events.EventNodeHealthChanged, func(ctx context.Context, event events.Event) error {
+		if status, _ := event.Payload["status"].(string); status != "active" {
+			return nil
+		}
+		g.prewarmNode(ctx, g.nodeEndpoint(ctx, event.Payload["node_id"]))
+		return nil
+	})
+	g.eventBus.Subscribe(events.EventNodeStandbyPromoted, func(ctx context.Context, event events.Event) error {
+		g.prewarmNode(ctx, g.nodeEndpoint(ctx, event.Payload["node_id"]))
+		return nil
+	})
+}
+
+// nodeEndpoint looks up a node's endpoint URL, "" when unknown
+func (g *Gateway) nodeEndpoint(ctx context.Context, nodeID interface{}) string {
+	id, _ := nodeID.(string)
+	if id == "" || g.db == nil {
+		return ""
+	}
+	var endpoint string
+	if err := g.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(endpoint_url, '') FROM nodes WHERE id::text = $1
+	`, id).Scan(&endpoint); err != nil {
+		return ""
+	}
+	return endpoint
+}
+
+// prewarmNode opens connections to a node with concurrent health requests.
+// The connections go back to the pool for the node's first requests. It is
+// best effort: a node that isn't reachable yet is simply not pre-warmed.
+func (g *Gateway) prewarmNode(ctx context.Context, endpoint string) {
+	conns := g.nodePool.prewarmConns
+	if endpoint == "" || conns <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nodePrewarmTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var warmed int
+	var lastErr error
+	for i := 0; i < conns; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := g.pingNode(ctx, endpoint)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				lastErr = err
+				return
+			}
+			warmed++
+		}()
+	}
+	wg.Wait()
+
+	if lastErr != nil {
+		g.logger.Debug("node connection pre-warm incomplete",
+			zap.String("endpoint", endpoint),
+			zap.Int("warmed", warmed),
+			zap.Error(lastErr),
+		)
+		return
+	}
+	g.logger.Debug("pre-warmed node connections", zap.String("endpoint", endpoint), zap.Int("connections", warmed))
+}
+
+// pingNode requests the node's health endpoint and reads the response to
+// the end so the connection is returned to the pool
+func (g *Gateway) pingNode(ctx context.Context, endpoint string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, nodeURL(endpoint, "/health"), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := g.nodeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
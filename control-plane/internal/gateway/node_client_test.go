@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// countingNode is a node that counts the connections opened to it
+func countingNode(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var conns atomic.Int32
+	node := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	node.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	node.Start()
+	t.Cleanup(node.Close)
+	return node, &conns
+}
+
+func TestProxyRequestReusesConnections(t *testing.T) {
+	node, conns := countingNode(t)
+	g := &Gateway{nodeClient: newNodeClient(defaultNodePoolSettings())}
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		// A client closing its own connection must not close the node's
+		req.Header.Set("Connection", "close")
+		resp, err := g.proxyRequest(node.URL, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// An unread body may close the connection instead of pooling it
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if got := conns.Load(); got != 1 {
+		t.Errorf("opened %d connections for 3 requests, want 1", got)
+	}
+}
+
+func TestPrewarmNode(t *testing.T) {
+	node, conns := countingNode(t)
+	g := &Gateway{logger: zap.NewNop()}
+	g.SetNodeConnectionPool(8, 0, 2)
+
+	g.prewarmNode(context.Background(), node.URL)
+	warmed := conns.Load()
+	if warmed < 1 {
+		t.Fatal("pre-warm opened no connections")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	resp, err := g.proxyRequest(node.URL, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if got := conns.Load(); got != warmed {
+		t.Errorf("first request opened a connection (%d -> %d)", warmed, got)
+	}
+
+	// Pre-warming off
+	g.SetNodeConnectionPool(8, 0, 0)
+	g.prewarmNode(context.Background(), node.URL)
+	if got := conns.Load(); got != warmed {
+		t.Errorf("disabled pre-warm opened connections (%d -> %d)", warmed, got)
+	}
+}
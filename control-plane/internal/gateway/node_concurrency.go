@@ -0,0 +1,428 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ErrNodesAtCapacity is returned by SelectEndpoint when every node serving
+// the model already has as many requests in flight as it can serve well
+var ErrNodesAtCapacity = errors.New("all nodes for model are at their concurrency limit")
+
+const (
+	// polledLoadMaxAge is how long a node's polled running and waiting
+	// counts are trusted for its concurrency limit
+	polledLoadMaxAge = 15 * time.Second
+
+	// nodesAtCapacityRetryAfter is the Retry-After sent when every node is
+	// at its limit
+	nodesAtCapacityRetryAfter = "1"
+)
+
+var requestsAtCapacity = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gateway_requests_at_capacity_total",
+		Help: "Requests turned away because every node serving the model was at its concurrency limit",
+	},
+	[]string{"model"},
+)
+
+// LoadConcurrencyLimits recomputes the concurrency limit of every routable
+// node from the model's benchmarks and tokens per second capacity, capped by
+// the max_num_seqs the node's vLLM runs with. Image nodes don't run vLLM and
+// get nodes.ImageConcurrencyLimit.
+func (lb *IntelligentLoadBalancer) LoadConcurrencyLimits(ctx context.Context) error {
+	benchmarks, err := lb.loadBenchmarks(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows, err := lb.db.Pool.Query(ctx, `
+		SELECT n.endpoint_url, n.model_name, COALESCE(n.gpu_type, ''),
+		       COALESCE(n.reported_runtime->'vllm_args', n.desired_runtime->'vllm_args'),
+		       COALESCE(m.tokens_per_second_capacity, 0), n.workload_class
+		FROM nodes n
+		LEFT JOIN models m ON m.name = n.model_name
+		WHERE n.status = 'active' AND n.endpoint_url != '' AND n.model_name IS NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	limits := make(map[string]int)
+	for rows.Next() {
+		var endpoint, model, gpuType, workload string
+		var rawArgs []byte
+		var capacity int
+		if err := rows.Scan(&endpoint, &model, &gpuType, &rawArgs, &capacity, &workload); err != nil {
+			return err
+		}
+		if workload == nodes.WorkloadImage {
+			limits[endpoint] = nodes.ImageConcurrencyLimit
+			continue
+		}
+		var args []string
+		if len(rawArgs) > 0 {
+			if err := json.Unmarshal(rawArgs, &args); err != nil {
+				lb.logger.Debug("ignoring unreadable vLLM args", zap.String("endpoint", endpoint), zap.Error(err))
+			}
+		}
+		limits[endpoint] = nodes.ConcurrencyLimit(nodes.MaxNumSeqs(args), benchmarks[benchmarkKey(model, gpuType)], capacity)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	lb.mu.Lock()
+	lb.concurrencyLimits = limits
+	lb.mu.Unlock()
+	return nil
+}
+
+func benchmarkKey(model, gpuType string) string {
+	return model + "|" + strings.ToLower(gpuType)
+}
+
+// loadBenchmarks reads every benchmark curve keyed by model and GPU type
+func (lb *IntelligentLoadBalancer) loadBenchmarks(ctx context.Context) (map[string][]nodes.BenchmarkPoint, error) {
+	rows, err := lb.db.Pool.Query(ctx, `
+		SELECT model_name, gpu_type, concurrency, tokens_per_second::float8 FROM model_benchmarks
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	benchmarks := make(map[string][]nodes.BenchmarkPoint)
+	for rows.Next() {
+		var model, gpuType string
+		var p nodes.BenchmarkPoint
+		if err := rows.Scan(&model, &gpuType, &p.Concurrency, &p.TokensPerSecond); err != nil {
+			return nil, err
+		}
+		key := benchmarkKey(model, gpuType)
+		benchmarks[key] = append(benchmarks[key], p)
+	}
+	return benchmarks, rows.Err()
+}
+
+// beginRequest counts a request in flight on endpoint until the returned
+// func is called
+func (lb *IntelligentLoadBalancer) beginRequest(endpoint string) func() {
+	lb.mu.Lock()
+	if lb.inflight == nil {
+		lb.inflight = make(map[string]int64)
+	}
+	lb.inflight[endpoint]++
+	lb.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			lb.mu.Lock()
+			if lb.inflight[endpoint]--; lb.inflight[endpoint] <= 0 {
+				delete(lb.inflight, endpoint)
+			}
+			lb.mu.Unlock()
+		})
+	}
+}
+
+// endpointLoad is the requests a node is working on: this gateway's own
+// requests in flight, or the node's polled running and waiting counts when
+// they are fresh and higher, since they include other gateway replicas.
+// Callers must hold lb.mu.
+func (lb *IntelligentLoadBalancer) endpointLoad(endpoint string, now time.Time) int64 {
+	load := lb.inflight[endpoint]
+	if stats, ok := lb.stats[endpoint]; ok && now.Sub(stats.LastUpdated) < polledLoadMaxAge {
+		if polled := stats.ActiveRequests + stats.QueueDepth; polled > load {
+			load = polled
+		}
+	}
+	return load
+}
+
+// belowConcurrencyLimit drops the endpoints at their concurrency limit.
+// Endpoints without a known limit are kept. Callers must hold lb.mu.
+func (lb *IntelligentLoadBalancer) belowConcurrencyLimit(endpoints []string) []string {
+	now := time.Now()
+	open := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		limit, ok := lb.concurrencyLimits[endpoint]
+		if ok && lb.endpointLoad(endpoint, now) >= int64(limit) {
+			continue
+		}
+		open = append(open, endpoint)
+	}
+	return open
+}
+
+// EndpointConcurrency returns an endpoint's current load and concurrency
+// limit; limit is 0 when it is not known yet
+func (lb *IntelligentLoadBalancer) EndpointConcurrency(endpoint string) (load int64, limit int) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return lb.endpointLoad(endpoint, time.Now()), lb.concurrencyLimits[endpoint]
+}
+
+// writeNodesAtCapacity answers a request no node has room for
+func (g *Gateway) writeNodesAtCapacity(w http.ResponseWriter, model string) {
+	requestsAtCapacity.WithLabelValues(model).Inc()
+	w.Header().Set("Retry-After", nodesAtCapacityRetryAfter)
+	g.writeError(w, http.StatusServiceUnavailable, "all nodes serving the model are at capacity, retry after the indicated delay")
+}
+
+// inflightBody ends the request's in-flight count when its body is closed
+type inflightBody struct {
+	io.ReadCloser
+	done func()
+}
+
+func (b *inflightBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+// ModelBenchmark is a model's throughput curve on one GPU type
+type ModelBenchmark struct {
+	GPUType string                 `json:"gpu_type"`
+	Points  []ModelBenchmarkResult `json:"points"`
+	// Knee is the concurrency nodes of this GPU type are limited to, before
+	// their max_num_seqs cap
+	Knee int `json:"knee"`
+}
+
+// ModelBenchmarkResult is the throughput measured at one concurrency
+type ModelBenchmarkResult struct {
+	Concurrency     int       `json:"concurrency"`
+	TokensPerSecond float64   `json:"tokens_per_second"`
+	P95LatencyMs    *int      `json:"p95_latency_ms,omitempty"`
+	RecordedBy      *string   `json:"recorded_by,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func benchmarkPoints(results []ModelBenchmarkResult) []nodes.BenchmarkPoint {
+	points := make([]nodes.BenchmarkPoint, len(results))
+	for i, r := range results {
+		points[i] = nodes.BenchmarkPoint{Concurrency: r.Concurrency, TokensPerSecond: r.TokensPerSecond}
+	}
+	return points
+}
+
+// validateBenchmarkResults checks a benchmark run before it replaces a curve
+func validateBenchmarkResults(results []ModelBenchmarkResult) error {
+	if len(results) == 0 {
+		return errors.New("results are required")
+	}
+	seen := make(map[int]bool, len(results))
+	for _, r := range results {
+		if r.Concurrency <= 0 {
+			return errors.New("concurrency must be positive")
+		}
+		if r.TokensPerSecond < 0 {
+			return errors.New("tokens_per_second must not be negative")
+		}
+		if r.P95LatencyMs != nil && *r.P95LatencyMs < 0 {
+			return errors.New("p95_latency_ms must not be negative")
+		}
+		if seen[r.Concurrency] {
+			return fmt.Errorf("concurrency %d is listed twice", r.Concurrency)
+		}
+		seen[r.Concurrency] = true
+	}
+	return nil
+}
+
+// modelNameByID resolves a model ID from the URL, answering the request
+// when it can't
+func (g *Gateway) modelNameByID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	modelID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid model ID")
+		return "", false
+	}
+
+	var name string
+	err = g.db.Pool.QueryRow(r.Context(), `SELECT name FROM models WHERE id = $1`, modelID).Scan(&name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "model not found")
+		return "", false
+	}
+	if err != nil {
+		g.logger.Error("failed to load model", zap.Error(err), zap.String("model_id", modelID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load model")
+		return "", false
+	}
+	return name, true
+}
+
+// HandleListModelBenchmarks returns a model's benchmark curves with the
+// concurrency each one limits nodes to
+// Admin API - GET /api/v1/admin/models/{id}/benchmarks
+func (g *Gateway) HandleListModelBenchmarks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	modelName, ok := g.modelNameByID(w, r)
+	if !ok {
+		return
+	}
+
+	var capacity *int
+	if err := g.db.Pool.QueryRow(ctx, `SELECT tokens_per_second_capacity FROM models WHERE name = $1`, modelName).Scan(&capacity); err != nil {
+		g.logger.Error("failed to load model capacity", zap.Error(err), zap.String("model", modelName))
+		g.writeError(w, http.StatusInternalServerError, "failed to list benchmarks")
+		return
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT gpu_type, concurrency, tokens_per_second::float8, p95_latency_ms, recorded_by, created_at
+		FROM model_benchmarks
+		WHERE model_name = $1
+		ORDER BY gpu_type, concurrency
+	`, modelName)
+	if err != nil {
+		g.logger.Error("failed to list model benchmarks", zap.Error(err), zap.String("model", modelName))
+		g.writeError(w, http.StatusInternalServerError, "failed to list benchmarks")
+		return
+	}
+	defer rows.Close()
+
+	benchmarks := []ModelBenchmark{}
+	for rows.Next() {
+		var gpuType string
+		var res ModelBenchmarkResult
+		if err := rows.Scan(&gpuType, &res.Concurrency, &res.TokensPerSecond, &res.P95LatencyMs, &res.RecordedBy, &res.CreatedAt); err != nil {
+			g.logger.Error("failed to scan model benchmark", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to list benchmarks")
+			return
+		}
+		if n := len(benchmarks); n == 0 || benchmarks[n-1].GPUType != gpuType {
+			benchmarks = append(benchmarks, ModelBenchmark{GPUType: gpuType})
+		}
+		b := &benchmarks[len(benchmarks)-1]
+		b.Points = append(b.Points, res)
+	}
+	if err := rows.Err(); err != nil {
+		g.logger.Error("failed to list model benchmarks", zap.Error(err), zap.String("model", modelName))
+		g.writeError(w, http.StatusInternalServerError, "failed to list benchmarks")
+		return
+	}
+	for i := range benchmarks {
+		benchmarks[i].Knee = nodes.BenchmarkKnee(benchmarkPoints(benchmarks[i].Points))
+	}
+
+	// What nodes on GPU types without a benchmark are limited to
+	fallback := nodes.ConcurrencyLimit(nodes.DefaultMaxNumSeqs, nil, 0)
+	if capacity != nil {
+		fallback = nodes.ConcurrencyLimit(nodes.DefaultMaxNumSeqs, nil, *capacity)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"model":                      modelName,
+		"tokens_per_second_capacity": capacity,
+		"fallback_concurrency":       fallback,
+		"benchmarks":                 benchmarks,
+	})
+}
+
+// HandleSetModelBenchmark replaces a model's benchmark curve on a GPU type
+// with the results of a new run. Node limits pick it up on the load
+// balancer's next refresh.
+// Admin API - PUT /api/v1/admin/models/{id}/benchmarks/{gpu_type}
+func (g *Gateway) HandleSetModelBenchmark(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	gpuType := strings.TrimSpace(chi.URLParam(r, "gpu_type"))
+	if gpuType == "" {
+		g.writeError(w, http.StatusBadRequest, "gpu_type is required")
+		return
+	}
+
+	var req struct {
+		Results []ModelBenchmarkResult `json:"results"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := validateBenchmarkResults(req.Results); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	modelName, ok := g.modelNameByID(w, r)
+	if !ok {
+		return
+	}
+
+	var recordedBy *string
+	if name, ok := ctx.Value("admin_token").(string); ok && name != "" {
+		recordedBy = &name
+	}
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		g.logger.Error("failed to begin transaction", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to record benchmark")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM model_benchmarks WHERE model_name = $1 AND LOWER(gpu_type) = LOWER($2)
+	`, modelName, gpuType); err != nil {
+		g.logger.Error("failed to clear model benchmark", zap.Error(err), zap.String("model", modelName))
+		g.writeError(w, http.StatusInternalServerError, "failed to record benchmark")
+		return
+	}
+	for i := range req.Results {
+		res := &req.Results[i]
+		res.RecordedBy = recordedBy
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO model_benchmarks (model_name, gpu_type, concurrency, tokens_per_second, p95_latency_ms, recorded_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING created_at
+		`, modelName, gpuType, res.Concurrency, res.TokensPerSecond, res.P95LatencyMs, recordedBy).Scan(&res.CreatedAt); err != nil {
+			g.logger.Error("failed to insert model benchmark", zap.Error(err), zap.String("model", modelName))
+			g.writeError(w, http.StatusInternalServerError, "failed to record benchmark")
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		g.logger.Error("failed to commit model benchmark", zap.Error(err), zap.String("model", modelName))
+		g.writeError(w, http.StatusInternalServerError, "failed to record benchmark")
+		return
+	}
+
+	sort.Slice(req.Results, func(i, j int) bool { return req.Results[i].Concurrency < req.Results[j].Concurrency })
+	knee := nodes.BenchmarkKnee(benchmarkPoints(req.Results))
+
+	g.logger.Info("model benchmark recorded",
+		zap.String("model", modelName),
+		zap.String("gpu_type", gpuType),
+		zap.Int("points", len(req.Results)),
+		zap.Int("knee", knee),
+	)
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"model":    modelName,
+		"gpu_type": gpuType,
+		"points":   req.Results,
+		"knee":     knee,
+	})
+}
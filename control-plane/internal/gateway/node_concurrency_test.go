@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBelowConcurrencyLimit(t *testing.T) {
+	lb := &IntelligentLoadBalancer{
+		concurrencyLimits: map[string]int{"http://a": 2, "http://b": 2, "http://c": 4},
+		stats: map[string]*EndpointStats{
+			// Polled load counts other gateways' requests
+			"http://b": {ActiveRequests: 1, QueueDepth: 1, LastUpdated: time.Now()},
+			// Stale polls are ignored
+			"http://c": {ActiveRequests: 10, LastUpdated: time.Now().Add(-time.Minute)},
+		},
+	}
+	done := lb.beginRequest("http://a")
+	lb.beginRequest("http://a")
+
+	endpoints := []string{"http://a", "http://b", "http://c", "http://unknown"}
+	if got, want := lb.belowConcurrencyLimit(endpoints), []string{"http://c", "http://unknown"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("belowConcurrencyLimit = %v, want %v", got, want)
+	}
+
+	// Ending a request twice only frees one slot
+	done()
+	done()
+	if load, limit := lb.EndpointConcurrency("http://a"); load != 1 || limit != 2 {
+		t.Errorf("EndpointConcurrency = %d/%d, want 1/2", load, limit)
+	}
+	if got := lb.belowConcurrencyLimit(endpoints); len(got) != 3 || got[0] != "http://a" {
+		t.Errorf("expected http://a to have room again, got %v", got)
+	}
+}
+
+func TestValidateBenchmarkResults(t *testing.T) {
+	negative := -1
+	tests := []struct {
+		name    string
+		results []ModelBenchmarkResult
+		wantErr bool
+	}{
+		{"valid", []ModelBenchmarkResult{{Concurrency: 1, TokensPerSecond: 80}, {Concurrency: 8, TokensPerSecond: 500}}, false},
+		{"empty", nil, true},
+		{"zero concurrency", []ModelBenchmarkResult{{Concurrency: 0, TokensPerSecond: 80}}, true},
+		{"negative throughput", []ModelBenchmarkResult{{Concurrency: 1, TokensPerSecond: -1}}, true},
+		{"negative latency", []ModelBenchmarkResult{{Concurrency: 1, TokensPerSecond: 1, P95LatencyMs: &negative}}, true},
+		{"duplicate", []ModelBenchmarkResult{{Concurrency: 4, TokensPerSecond: 1}, {Concurrency: 4, TokensPerSecond: 2}}, true},
+	}
+	for _, tt := range tests {
+		if err := validateBenchmarkResults(tt.results); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
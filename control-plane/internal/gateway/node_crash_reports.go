@@ -0,0 +1,369 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Node crash forensics. When vLLM dies the node agent files a crash report
+// with the tail of vllm.log, an nvidia-smi snapshot and its last vLLM
+// metrics, then uploads the full bundle to R2 with a presigned URL. The
+// gateway adds the requests the node was serving from its recent request
+// buffer, since those are what the agent can't see.
+const (
+	// crashLogTailMax and crashGPUSnapshotMax bound what is kept in the
+	// database; the full files are in the bundle
+	crashLogTailMax     = 64 * 1024
+	crashGPUSnapshotMax = 16 * 1024
+
+	// crashRequestWindow is how far before the crash requests are attributed to it
+	crashRequestWindow = 5 * time.Minute
+
+	// crashBundleMaxSize is the largest bundle a node may upload
+	crashBundleMaxSize = 256 * 1024 * 1024
+
+	crashUploadURLTTL   = 30 * time.Minute
+	crashDownloadURLTTL = 15 * time.Minute
+)
+
+var (
+	crashReasons = map[string]bool{"oom": true, "process_exited": true, "unhealthy": true}
+
+	sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// NodeCrashReport is a crash captured by a node agent
+type NodeCrashReport struct {
+	ID               uuid.UUID       `json:"id"`
+	NodeID           uuid.UUID       `json:"node_id"`
+	Reason           string          `json:"reason"`
+	OOM              bool            `json:"oom"`
+	DetectedAt       time.Time       `json:"detected_at"`
+	Summary          json.RawMessage `json:"summary,omitempty"`
+	RecentRequests   json.RawMessage `json:"recent_requests,omitempty"`
+	BundleKey        *string         `json:"bundle_key,omitempty"`
+	BundleSize       *int64          `json:"bundle_size,omitempty"`
+	BundleSHA256     *string         `json:"bundle_sha256,omitempty"`
+	BundleUploadedAt *time.Time      `json:"bundle_uploaded_at,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	DownloadURL      string          `json:"download_url,omitempty"`
+}
+
+// crashReportRequest is what the node agent sends when vLLM crashes
+type crashReportRequest struct {
+	Reason       string             `json:"reason"`
+	OOM          bool               `json:"oom"`
+	DetectedAt   *time.Time         `json:"detected_at"`
+	LogTail      string             `json:"log_tail"`
+	GPUSnapshot  string             `json:"gpu_snapshot"`
+	VLLMMetrics  map[string]float64 `json:"vllm_metrics"`
+	BundleSize   int64              `json:"bundle_size"`
+	BundleSHA256 string             `json:"bundle_sha256"`
+}
+
+// validate checks the report and trims the excerpts kept in the database
+func (req *crashReportRequest) validate(now time.Time) error {
+	if !crashReasons[req.Reason] {
+		return errors.New("reason must be one of oom, process_exited, unhealthy")
+	}
+	if req.Reason == "oom" {
+		req.OOM = true
+	}
+	if req.DetectedAt == nil || req.DetectedAt.After(now.Add(time.Minute)) {
+		req.DetectedAt = &now
+	}
+	if req.BundleSize < 0 || req.BundleSize > crashBundleMaxSize {
+		return fmt.Errorf("bundle_size must be between 0 and %d bytes", crashBundleMaxSize)
+	}
+	if req.BundleSize > 0 && !sha256Pattern.MatchString(req.BundleSHA256) {
+		return errors.New("bundle_sha256 must be a lowercase hex SHA-256 digest")
+	}
+	req.LogTail = tailString(req.LogTail, crashLogTailMax)
+	req.GPUSnapshot = tailString(req.GPUSnapshot, crashGPUSnapshotMax)
+	return nil
+}
+
+// tailString returns at most max bytes from the end of s, starting on a
+// character boundary
+func tailString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[len(s)-max:]
+	for len(s) > 0 && !utf8.RuneStart(s[0]) {
+		s = s[1:]
+	}
+	return s
+}
+
+// crashRequests picks the requests from a node's recent request buffer that
+// started shortly before the crash, newest first
+func crashRequests(raw []string, detectedAt time.Time) []NodeRequest {
+	since := detectedAt.Add(-crashRequestWindow)
+	requests := []NodeRequest{}
+	for _, item := range raw {
+		var req NodeRequest
+		if err := json.Unmarshal([]byte(item), &req); err != nil {
+			continue
+		}
+		if req.Timestamp.Before(since) || req.Timestamp.After(detectedAt) {
+			continue
+		}
+		requests = append(requests, req)
+	}
+	return requests
+}
+
+// crashBundleKey is where a report's bundle is stored in R2
+func crashBundleKey(nodeID, reportID uuid.UUID) string {
+	return fmt.Sprintf("crash-reports/%s/%s.tar.gz", nodeID, reportID)
+}
+
+// handleCreateCrashReport records a crash reported by a node agent and links
+// it to the node. When R2 is configured and the agent has a bundle, the
+// response carries a presigned URL to upload it to.
+// Admin API - POST /admin/nodes/{id}/crash-reports
+func (g *Gateway) handleCreateCrashReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	nodeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid node ID")
+		return
+	}
+
+	var req crashReportRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.validate(time.Now().UTC()); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var endpoint *string
+	err = g.db.Pool.QueryRow(ctx, `
+		SELECT endpoint_url FROM nodes WHERE id = $1
+	`, nodeID).Scan(&endpoint)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			g.writeError(w, http.StatusNotFound, "node not found")
+			return
+		}
+		g.logger.Error("failed to load node", zap.Error(err), zap.String("node_id", nodeID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load node")
+		return
+	}
+
+	// The buffer is best effort; a report without requests is still useful
+	requests := []NodeRequest{}
+	if endpoint != nil && *endpoint != "" {
+		raw, err := g.cache.Range(ctx, nodeRecentRequestsKey(*endpoint), 0, nodeRecentRequestsMax-1)
+		if err != nil {
+			g.logger.Warn("failed to read node requests for crash report", zap.Error(err), zap.String("node_id", nodeID.String()))
+		} else {
+			requests = crashRequests(raw, *req.DetectedAt)
+		}
+	}
+
+	summary, _ := json.Marshal(map[string]interface{}{
+		"log_tail":     req.LogTail,
+		"gpu_snapshot": req.GPUSnapshot,
+		"vllm_metrics": req.VLLMMetrics,
+	})
+	recent, _ := json.Marshal(requests)
+
+	reportID := uuid.New()
+	var bundleKey *string
+	var bundleSize *int64
+	var bundleSHA *string
+	if g.CrashBundles != nil && req.BundleSize > 0 {
+		key := crashBundleKey(nodeID, reportID)
+		bundleKey, bundleSize, bundleSHA = &key, &req.BundleSize, &req.BundleSHA256
+	}
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		g.logger.Error("failed to begin transaction", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to record crash report")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO node_crash_reports (id, node_id, reason, oom, detected_at, summary, recent_requests,
+		                                bundle_key, bundle_size, bundle_sha256)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, reportID, nodeID, req.Reason, req.OOM, *req.DetectedAt, summary, recent, bundleKey, bundleSize, bundleSHA)
+	if err != nil {
+		g.logger.Error("failed to insert crash report", zap.Error(err), zap.String("node_id", nodeID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to record crash report")
+		return
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE nodes
+		SET last_crash_report_id = $2, last_crash_at = $3, status_message = $4, updated_at = NOW()
+		WHERE id = $1
+	`, nodeID, reportID, *req.DetectedAt, "vllm_crash_"+req.Reason)
+	if err != nil {
+		g.logger.Error("failed to link crash report to node", zap.Error(err), zap.String("node_id", nodeID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to record crash report")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		g.logger.Error("failed to commit crash report", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to record crash report")
+		return
+	}
+
+	g.logger.Warn("node reported vLLM crash",
+		zap.String("node_id", nodeID.String()),
+		zap.String("report_id", reportID.String()),
+		zap.String("reason", req.Reason),
+		zap.Int("recent_requests", len(requests)),
+	)
+
+	resp := map[string]interface{}{
+		"id":              reportID,
+		"node_id":         nodeID,
+		"recent_requests": len(requests),
+	}
+	if bundleKey != nil {
+		resp["bundle_key"] = *bundleKey
+		resp["upload_url"] = g.CrashBundles.PresignPut(*bundleKey, crashUploadURLTTL)
+		resp["upload_expires_at"] = time.Now().UTC().Add(crashUploadURLTTL)
+	}
+	g.writeJSON(w, http.StatusCreated, resp)
+}
+
+// handleCrashBundleUploaded marks a report's bundle as uploaded
+// Admin API - POST /admin/nodes/{id}/crash-reports/{report_id}/uploaded
+func (g *Gateway) handleCrashBundleUploaded(w http.ResponseWriter, r *http.Request) {
+	nodeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid node ID")
+		return
+	}
+	reportID, err := uuid.Parse(chi.URLParam(r, "report_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid crash report ID")
+		return
+	}
+
+	tag, err := g.db.Pool.Exec(r.Context(), `
+		UPDATE node_crash_reports SET bundle_uploaded_at = NOW()
+		WHERE id = $1 AND node_id = $2 AND bundle_key IS NOT NULL
+	`, reportID, nodeID)
+	if err != nil {
+		g.logger.Error("failed to mark crash bundle uploaded", zap.Error(err), zap.String("report_id", reportID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to update crash report")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "crash report not found or has no bundle")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]string{"status": "uploaded"})
+}
+
+// handleListCrashReports lists a node's crash reports, newest first. The log
+// and request excerpts are left out; fetch a single report for those.
+// Admin API - GET /admin/nodes/{id}/crash-reports?limit=50&offset=0
+func (g *Gateway) handleListCrashReports(w http.ResponseWriter, r *http.Request) {
+	nodeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid node ID")
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 50, 1, 200)
+	offset := parseIntParam(r, "offset", 0, 0, 999999)
+
+	rows, err := g.db.Pool.Query(r.Context(), `
+		SELECT id, node_id, reason, oom, detected_at, bundle_key, bundle_size, bundle_sha256,
+		       bundle_uploaded_at, created_at
+		FROM node_crash_reports
+		WHERE node_id = $1
+		ORDER BY detected_at DESC
+		LIMIT $2 OFFSET $3
+	`, nodeID, limit, offset)
+	if err != nil {
+		g.logger.Error("failed to list crash reports", zap.Error(err), zap.String("node_id", nodeID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to list crash reports")
+		return
+	}
+	defer rows.Close()
+
+	reports := []NodeCrashReport{}
+	for rows.Next() {
+		var rep NodeCrashReport
+		if err := rows.Scan(&rep.ID, &rep.NodeID, &rep.Reason, &rep.OOM, &rep.DetectedAt, &rep.BundleKey,
+			&rep.BundleSize, &rep.BundleSHA256, &rep.BundleUploadedAt, &rep.CreatedAt,
+		); err != nil {
+			g.logger.Error("failed to scan crash report", zap.Error(err))
+			continue
+		}
+		reports = append(reports, rep)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id": nodeID,
+		"data":    reports,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// handleGetCrashReport returns one crash report with a short-lived download
+// URL for its bundle
+// Admin API - GET /admin/nodes/{id}/crash-reports/{report_id}
+func (g *Gateway) handleGetCrashReport(w http.ResponseWriter, r *http.Request) {
+	nodeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid node ID")
+		return
+	}
+	reportID, err := uuid.Parse(chi.URLParam(r, "report_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid crash report ID")
+		return
+	}
+
+	var rep NodeCrashReport
+	err = g.db.Pool.QueryRow(r.Context(), `
+		SELECT id, node_id, reason, oom, detected_at, summary, recent_requests, bundle_key, bundle_size,
+		       bundle_sha256, bundle_uploaded_at, created_at
+		FROM node_crash_reports
+		WHERE id = $1 AND node_id = $2
+	`, reportID, nodeID).Scan(&rep.ID, &rep.NodeID, &rep.Reason, &rep.OOM, &rep.DetectedAt, &rep.Summary,
+		&rep.RecentRequests, &rep.BundleKey, &rep.BundleSize, &rep.BundleSHA256, &rep.BundleUploadedAt, &rep.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			g.writeError(w, http.StatusNotFound, "crash report not found")
+			return
+		}
+		g.logger.Error("failed to load crash report", zap.Error(err), zap.String("report_id", reportID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load crash report")
+		return
+	}
+
+	if g.CrashBundles != nil && rep.BundleKey != nil && rep.BundleUploadedAt != nil {
+		rep.DownloadURL = g.CrashBundles.PresignGet(*rep.BundleKey, crashDownloadURLTTL)
+	}
+
+	g.writeJSON(w, http.StatusOK, rep)
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestCrashReportRequestValidate(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	digest := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name    string
+		req     crashReportRequest
+		wantErr bool
+	}{
+		{name: "oom", req: crashReportRequest{Reason: "oom"}},
+		{name: "with bundle", req: crashReportRequest{Reason: "process_exited", BundleSize: 1024, BundleSHA256: digest}},
+		{name: "unknown reason", req: crashReportRequest{Reason: "segfault"}, wantErr: true},
+		{name: "bundle without digest", req: crashReportRequest{Reason: "oom", BundleSize: 1024}, wantErr: true},
+		{name: "bundle too large", req: crashReportRequest{Reason: "oom", BundleSize: crashBundleMaxSize + 1, BundleSHA256: digest}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.validate(now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	req := crashReportRequest{Reason: "oom", LogTail: strings.Repeat("x", crashLogTailMax+10)}
+	if err := req.validate(now); err != nil {
+		t.Fatal(err)
+	}
+	if !req.OOM || !req.DetectedAt.Equal(now) || len(req.LogTail) != crashLogTailMax {
+		t.Errorf("validate() = %+v", req)
+	}
+}
+
+func TestTailString(t *testing.T) {
+	s := "héllo wörld"
+	for max := 0; max <= len(s); max++ {
+		got := tailString(s, max)
+		if len(got) > max || !utf8.ValidString(got) || !strings.HasSuffix(s, got) {
+			t.Errorf("tailString(%q, %d) = %q", s, max, got)
+		}
+	}
+}
+
+func TestCrashRequests(t *testing.T) {
+	crash := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(id string, at time.Time) string {
+		data, _ := json.Marshal(NodeRequest{RequestID: id, Timestamp: at})
+		return string(data)
+	}
+	raw := []string{
+		entry("after", crash.Add(time.Second)),
+		entry("inflight", crash.Add(-10*time.Second)),
+		"not json",
+		entry("earlier", crash.Add(-4*time.Minute)),
+		entry("stale", crash.Add(-time.Hour)),
+	}
+
+	got := crashRequests(raw, crash)
+	if len(got) != 2 || got[0].RequestID != "inflight" || got[1].RequestID != "earlier" {
+		t.Errorf("crashRequests() = %+v", got)
+	}
+}
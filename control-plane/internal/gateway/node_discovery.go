@@ -0,0 +1,260 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Node discovery.
+//
+// Nodes used to learn the control plane's address only from the
+// CONTROL_PLANE_URL baked into their environment at launch, which breaks
+// every running node when the control plane moves domains, and had no way
+// to find the other nodes of a multi-node deployment. A node now exchanges
+// its node token for a short-lived discovery token and looks itself up in
+// the registry, which answers with the URLs it should call back to and its
+// sibling nodes. Discovery tokens are scoped to one node and only read the
+// registry, so they can be handed to serving workers on the node without
+// sharing the node token.
+//
+// Tokens are "nd1.{node id}.{unix expiry}.{hex HMAC-SHA256}" signed with the
+// discovery secret, so any replica can verify them without shared state.
+const (
+	discoveryTokenPrefix = "nd1"
+	// defaultDiscoveryTokenTTL applies when no TTL is configured
+	defaultDiscoveryTokenTTL = 15 * time.Minute
+)
+
+var errInvalidDiscoveryToken = errors.New("invalid discovery token")
+
+// NodeDiscoverySettings configures the node registry
+type NodeDiscoverySettings struct {
+	Secret   string
+	TokenTTL time.Duration
+	// NodeAPIURL is where nodes should send callbacks
+	NodeAPIURL string
+	// PublicURL is the public API
+	PublicURL string
+	// AlternateURLs also reach the node API, for example the previous
+	// domain during a migration; nodes fall back to them
+	AlternateURLs []string
+}
+
+// nodeDiscovery issues and verifies discovery tokens
+type nodeDiscovery struct {
+	settings NodeDiscoverySettings
+	now      func() time.Time
+}
+
+// SetNodeDiscovery enables the node registry. It stays disabled (503)
+// without a secret.
+func (g *Gateway) SetNodeDiscovery(settings NodeDiscoverySettings) {
+	if settings.Secret == "" {
+		g.nodeDiscovery = nil
+		return
+	}
+	if settings.TokenTTL <= 0 {
+		settings.TokenTTL = defaultDiscoveryTokenTTL
+	}
+	g.nodeDiscovery = &nodeDiscovery{settings: settings, now: time.Now}
+}
+
+// issue signs a token for a node
+func (d *nodeDiscovery) issue(nodeID uuid.UUID) (string, time.Time) {
+	expiresAt := d.now().Add(d.settings.TokenTTL).Truncate(time.Second)
+	payload := discoveryTokenPrefix + "." + nodeID.String() + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + d.sign(payload), expiresAt
+}
+
+// verify returns the node a token was issued to
+func (d *nodeDiscovery) verify(token string) (uuid.UUID, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 || parts[0] != discoveryTokenPrefix {
+		return uuid.Nil, errInvalidDiscoveryToken
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(d.sign(payload))) {
+		return uuid.Nil, errInvalidDiscoveryToken
+	}
+	nodeID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return uuid.Nil, errInvalidDiscoveryToken
+	}
+	expiry, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || !d.now().Before(time.Unix(expiry, 0)) {
+		return uuid.Nil, errInvalidDiscoveryToken
+	}
+	return nodeID, nil
+}
+
+func (d *nodeDiscovery) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(d.settings.Secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// refreshAfter is how long nodes should wait before refreshing, leaving
+// time to retry before the token expires
+func (d *nodeDiscovery) refreshAfter() time.Duration {
+	return d.settings.TokenTTL / 2
+}
+
+// DiscoveryToken is a short-lived registry token for one node
+type DiscoveryToken struct {
+	Token               string    `json:"token"`
+	ExpiresAt           time.Time `json:"expires_at"`
+	RefreshAfterSeconds int       `json:"refresh_after_seconds"`
+}
+
+// DiscoveryControlPlane lists the URLs a node can reach the control plane at
+type DiscoveryControlPlane struct {
+	NodeAPIURL    string   `json:"node_api_url"`
+	PublicURL     string   `json:"public_url"`
+	AlternateURLs []string `json:"alternate_urls"`
+}
+
+// DiscoverySibling is another node serving the same deployment
+type DiscoverySibling struct {
+	NodeID          uuid.UUID  `json:"node_id"`
+	EndpointURL     string     `json:"endpoint_url"`
+	InternalIP      string     `json:"internal_ip,omitempty"`
+	Status          string     `json:"status"`
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at,omitempty"`
+}
+
+// DiscoveryResponse is a node's view of the registry
+type DiscoveryResponse struct {
+	NodeID              uuid.UUID             `json:"node_id"`
+	DeploymentID        *uuid.UUID            `json:"deployment_id"`
+	ControlPlane        DiscoveryControlPlane `json:"control_plane"`
+	Siblings            []DiscoverySibling    `json:"siblings"`
+	RefreshAfterSeconds int                   `json:"refresh_after_seconds"`
+}
+
+// handleIssueDiscoveryToken exchanges node credentials for a discovery token
+func (g *Gateway) handleIssueDiscoveryToken(w http.ResponseWriter, r *http.Request) {
+	if g.nodeDiscovery == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "node discovery is not configured")
+		return
+	}
+	nodeID, err := uuid.Parse(chi.URLParam(r, "node_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid node_id")
+		return
+	}
+
+	var exists bool
+	err = g.db.Pool.QueryRow(r.Context(), `
+		SELECT EXISTS (SELECT 1 FROM nodes WHERE id = $1 AND terminated_at IS NULL)
+	`, nodeID).Scan(&exists)
+	if err != nil {
+		g.logger.Error("failed to look up node for discovery token", zap.Error(err), zap.String("node_id", nodeID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to issue discovery token")
+		return
+	}
+	if !exists {
+		g.writeError(w, http.StatusNotFound, "node not found")
+		return
+	}
+
+	token, expiresAt := g.nodeDiscovery.issue(nodeID)
+	g.writeJSON(w, http.StatusOK, DiscoveryToken{
+		Token:               token,
+		ExpiresAt:           expiresAt,
+		RefreshAfterSeconds: int(g.nodeDiscovery.refreshAfter().Seconds()),
+	})
+}
+
+// handleNodeDiscovery returns the control plane endpoints and sibling nodes
+// for the node a discovery token was issued to
+func (g *Gateway) handleNodeDiscovery(w http.ResponseWriter, r *http.Request) {
+	if g.nodeDiscovery == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "node discovery is not configured")
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		g.writeError(w, http.StatusUnauthorized, "missing discovery token")
+		return
+	}
+	nodeID, err := g.nodeDiscovery.verify(token)
+	if err != nil {
+		g.writeError(w, http.StatusUnauthorized, "invalid or expired discovery token")
+		return
+	}
+
+	ctx := r.Context()
+	var deploymentID *uuid.UUID
+	err = g.db.Pool.QueryRow(ctx, `
+		SELECT deployment_id FROM nodes WHERE id = $1 AND terminated_at IS NULL
+	`, nodeID).Scan(&deploymentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "node not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to look up node for discovery", zap.Error(err), zap.String("node_id", nodeID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to look up node")
+		return
+	}
+
+	settings := g.nodeDiscovery.settings
+	resp := DiscoveryResponse{
+		NodeID:       nodeID,
+		DeploymentID: deploymentID,
+		ControlPlane: DiscoveryControlPlane{
+			NodeAPIURL:    settings.NodeAPIURL,
+			PublicURL:     settings.PublicURL,
+			AlternateURLs: settings.AlternateURLs,
+		},
+		Siblings:            []DiscoverySibling{},
+		RefreshAfterSeconds: int(g.nodeDiscovery.refreshAfter().Seconds()),
+	}
+	if resp.ControlPlane.AlternateURLs == nil {
+		resp.ControlPlane.AlternateURLs = []string{}
+	}
+
+	// Standalone nodes have no siblings
+	if deploymentID != nil {
+		rows, err := g.db.Pool.Query(ctx, `
+			SELECT id, endpoint_url, COALESCE(internal_ip, ''), status, last_heartbeat_at
+			FROM nodes
+			WHERE deployment_id = $1 AND id <> $2 AND terminated_at IS NULL
+			  AND status IN ('initializing', 'active', 'draining')
+			ORDER BY created_at
+		`, *deploymentID, nodeID)
+		if err != nil {
+			g.logger.Error("failed to list sibling nodes", zap.Error(err), zap.String("node_id", nodeID.String()))
+			g.writeError(w, http.StatusInternalServerError, "failed to list sibling nodes")
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sibling DiscoverySibling
+			if err := rows.Scan(&sibling.NodeID, &sibling.EndpointURL, &sibling.InternalIP, &sibling.Status, &sibling.LastHeartbeatAt); err != nil {
+				g.logger.Error("failed to scan sibling node", zap.Error(err))
+				g.writeError(w, http.StatusInternalServerError, "failed to list sibling nodes")
+				return
+			}
+			resp.Siblings = append(resp.Siblings, sibling)
+		}
+		if err := rows.Err(); err != nil {
+			g.logger.Error("failed to list sibling nodes", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to list sibling nodes")
+			return
+		}
+	}
+
+	g.writeJSON(w, http.StatusOK, resp)
+}
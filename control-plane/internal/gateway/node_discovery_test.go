@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDiscoveryTokens(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := &nodeDiscovery{
+		settings: NodeDiscoverySettings{Secret: strings.Repeat("s", 32), TokenTTL: 15 * time.Minute},
+		now:      func() time.Time { return now },
+	}
+	node := uuid.New()
+
+	token, expiresAt := d.issue(node)
+	if !expiresAt.Equal(now.Add(15 * time.Minute)) {
+		t.Errorf("expires_at = %v", expiresAt)
+	}
+	if got, err := d.verify(token); err != nil || got != node {
+		t.Fatalf("verify = %v, %v", got, err)
+	}
+
+	parts := strings.Split(token, ".")
+	otherNode := strings.Join([]string{parts[0], uuid.NewString(), parts[2], parts[3]}, ".")
+	otherSecret := &nodeDiscovery{settings: NodeDiscoverySettings{Secret: strings.Repeat("x", 32)}, now: d.now}
+	foreign, _ := otherSecret.issue(node)
+	for name, bad := range map[string]string{
+		"empty":        "",
+		"other node":   otherNode,
+		"wrong prefix": "nd2" + strings.TrimPrefix(token, discoveryTokenPrefix),
+		"truncated":    strings.Join(parts[:3], "."),
+		"other secret": foreign,
+		"node token":   strings.Repeat("s", 32),
+	} {
+		if _, err := d.verify(bad); err == nil {
+			t.Errorf("%s token accepted", name)
+		}
+	}
+
+	now = now.Add(15 * time.Minute)
+	if _, err := d.verify(token); err == nil {
+		t.Error("expired token accepted")
+	}
+}
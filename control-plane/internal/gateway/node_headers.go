@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// credentialHeaders carry client or platform credentials. They are never
+// forwarded to nodes, whatever the configured policy says.
+var credentialHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie",
+	"X-Api-Key", "X-Admin-Token", "X-Node-Token", "X-Captcha-Token",
+}
+
+// neverForwarded is credentialHeaders and hopByHopHeaders in canonical form
+var neverForwarded = func() map[string]bool {
+	set := make(map[string]bool)
+	for _, list := range [][]string{credentialHeaders, hopByHopHeaders} {
+		for _, h := range list {
+			set[http.CanonicalHeaderKey(h)] = true
+		}
+	}
+	return set
+}()
+
+// defaultStrippedNodeHeaders are stripped unless NODE_PROXY_STRIP_HEADERS
+// says otherwise: request signatures only mean something to the gateway
+var defaultStrippedNodeHeaders = []string{
+	signatureHeader, signatureTimestampHeader, signatureNonceHeader,
+}
+
+// nodeHeaderPolicy decides which client headers reach nodes and which
+// platform headers the gateway adds to node requests
+type nodeHeaderPolicy struct {
+	// forward, when set, is the only client headers forwarded; otherwise
+	// every header not stripped is
+	forward map[string]bool
+	strip   map[string]bool
+	// tenantHeader carries a hash of the tenant ID, so nodes can tell
+	// tenants apart in their logs without learning who they are
+	tenantHeader  string
+	tenantHashKey []byte
+	// requestIDHeader carries the gateway's request ID
+	requestIDHeader string
+}
+
+func defaultNodeHeaderPolicy() nodeHeaderPolicy {
+	return newNodeHeaderPolicy(nil, defaultStrippedNodeHeaders, "X-Tenant-Hash", "X-Request-ID", "")
+}
+
+func newNodeHeaderPolicy(forward, strip []string, tenantHeader, requestIDHeader, tenantHashKey string) nodeHeaderPolicy {
+	p := nodeHeaderPolicy{
+		strip:           make(map[string]bool),
+		tenantHeader:    http.CanonicalHeaderKey(tenantHeader),
+		requestIDHeader: http.CanonicalHeaderKey(requestIDHeader),
+	}
+	if tenantHashKey != "" {
+		p.tenantHashKey = []byte(tenantHashKey)
+	}
+	if len(forward) > 0 {
+		p.forward = make(map[string]bool, len(forward))
+		for _, h := range forward {
+			p.forward[http.CanonicalHeaderKey(h)] = true
+		}
+	}
+	for _, h := range strip {
+		p.strip[http.CanonicalHeaderKey(h)] = true
+	}
+	// Clients can't supply the headers the gateway injects
+	for _, h := range []string{p.tenantHeader, p.requestIDHeader} {
+		if h != "" {
+			p.strip[h] = true
+		}
+	}
+	return p
+}
+
+// SetNodeHeaderPolicy configures the headers sent to nodes. forward, when
+// not empty, limits the client headers forwarded; strip removes more.
+// Credentials and hop-by-hop headers are always stripped. An empty
+// tenantHeader or requestIDHeader turns that injected header off; without
+// tenantHashKey the tenant ID is hashed unkeyed.
+func (g *Gateway) SetNodeHeaderPolicy(forward, strip []string, tenantHeader, requestIDHeader, tenantHashKey string) {
+	g.nodeHeaders = newNodeHeaderPolicy(forward, strip, tenantHeader, requestIDHeader, tenantHashKey)
+}
+
+// forwards reports whether a client header is sent to nodes
+func (p nodeHeaderPolicy) forwards(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	if neverForwarded[name] || p.strip[name] {
+		return false
+	}
+	return p.forward == nil || p.forward[name]
+}
+
+// tenantHash is the tenant ID as nodes see it
+func (p nodeHeaderPolicy) tenantHash(tenantID uuid.UUID) string {
+	if p.tenantHashKey == nil {
+		sum := sha256.Sum256([]byte(tenantID.String()))
+		return hex.EncodeToString(sum[:16])
+	}
+	mac := hmac.New(sha256.New, p.tenantHashKey)
+	mac.Write([]byte(tenantID.String()))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// apply writes the headers of r that nodes may see, and the platform
+// headers, to dst
+func (p nodeHeaderPolicy) apply(r *http.Request, dst http.Header) {
+	for k, v := range r.Header {
+		if p.forwards(k) {
+			dst[k] = v
+		}
+	}
+
+	if p.requestIDHeader != "" {
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			dst.Set(p.requestIDHeader, id)
+		}
+	}
+	if p.tenantHeader != "" {
+		if tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID); ok {
+			dst.Set(p.tenantHeader, p.tenantHash(tenantID))
+		}
+	}
+}
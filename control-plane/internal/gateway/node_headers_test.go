@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+func nodeHeaderRequest(tenantID uuid.UUID, reqID string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	r.Header.Set("Authorization", "Bearer sk-tenant")
+	r.Header.Set("Cookie", "session=abc")
+	r.Header.Set("X-Api-Key", "sk-tenant")
+	r.Header.Set("Connection", "keep-alive")
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Custom", "value")
+	r.Header.Set("X-Signature", "sig")
+	r.Header.Set("X-Tenant-Hash", "spoofed")
+	ctx := context.WithValue(r.Context(), "tenant_id", tenantID)
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, reqID)
+	return r.WithContext(ctx)
+}
+
+func TestNodeHeaderPolicy_NeverForwardsCredentials(t *testing.T) {
+	policies := map[string]nodeHeaderPolicy{
+		"zero":      {},
+		"default":   defaultNodeHeaderPolicy(),
+		"allowlist": newNodeHeaderPolicy([]string{"authorization", "cookie", "x-api-key", "connection"}, nil, "", "", ""),
+	}
+	for name, p := range policies {
+		dst := http.Header{}
+		p.apply(nodeHeaderRequest(uuid.New(), "req-1"), dst)
+		for _, h := range []string{"Authorization", "Cookie", "X-Api-Key", "Connection"} {
+			if v := dst.Get(h); v != "" {
+				t.Errorf("%s policy forwarded %s: %q", name, h, v)
+			}
+		}
+	}
+}
+
+func TestNodeHeaderPolicy_Default(t *testing.T) {
+	tenantID := uuid.New()
+	dst := http.Header{}
+	defaultNodeHeaderPolicy().apply(nodeHeaderRequest(tenantID, "req-1"), dst)
+
+	if dst.Get("Content-Type") != "application/json" || dst.Get("X-Custom") != "value" {
+		t.Errorf("expected client headers to be forwarded, got %v", dst)
+	}
+	if dst.Get("X-Signature") != "" {
+		t.Error("expected signature header to be stripped")
+	}
+	if got := dst.Get("X-Request-ID"); got != "req-1" {
+		t.Errorf("X-Request-ID = %q, want req-1", got)
+	}
+	if got := dst.Get("X-Tenant-Hash"); got == "spoofed" || got == "" || got == tenantID.String() {
+		t.Errorf("X-Tenant-Hash = %q, want the gateway's hash", got)
+	}
+}
+
+func TestNodeHeaderPolicy_Allowlist(t *testing.T) {
+	p := newNodeHeaderPolicy([]string{"content-type"}, nil, "", "", "")
+	dst := http.Header{}
+	p.apply(nodeHeaderRequest(uuid.New(), "req-1"), dst)
+
+	if len(dst) != 1 || dst.Get("Content-Type") != "application/json" {
+		t.Errorf("expected only Content-Type, got %v", dst)
+	}
+}
+
+func TestNodeHeaderPolicy_TenantHash(t *testing.T) {
+	tenantID := uuid.New()
+	unkeyed := newNodeHeaderPolicy(nil, nil, "X-Tenant-Hash", "", "")
+	keyed := newNodeHeaderPolicy(nil, nil, "X-Tenant-Hash", "", "secret")
+	otherKey := newNodeHeaderPolicy(nil, nil, "X-Tenant-Hash", "", "other")
+
+	if unkeyed.tenantHash(tenantID) != unkeyed.tenantHash(tenantID) {
+		t.Error("expected tenant hash to be stable")
+	}
+	if unkeyed.tenantHash(tenantID) == unkeyed.tenantHash(uuid.New()) {
+		t.Error("expected tenants to hash differently")
+	}
+	if keyed.tenantHash(tenantID) == unkeyed.tenantHash(tenantID) || keyed.tenantHash(tenantID) == otherKey.tenantHash(tenantID) {
+		t.Error("expected the key to change the hash")
+	}
+	if got := len(keyed.tenantHash(tenantID)); got != 32 {
+		t.Errorf("hash length = %d, want 32", got)
+	}
+}
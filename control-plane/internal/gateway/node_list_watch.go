@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Node list change detection.
+//
+// GET /admin/nodes returns a weak ETag over the node state (id, provider,
+// status, endpoint and health score). Dashboards send it back in
+// If-None-Match and get 304 Not Modified while nothing changed. Heartbeat
+// timestamps are left out so a fleet that only keeps heartbeating reads as
+// unchanged.
+//
+// With ?wait=30s (or wait=30) and a matching If-None-Match the request is
+// held until the node state changes or the wait runs out, which answers
+// 304. Changes are picked up from node.* events on this replica and by
+// re-reading the list every nodeListPollInterval for changes made
+// elsewhere.
+const (
+	// maxNodeListWait caps long-polls below the router's request timeout
+	maxNodeListWait = 50 * time.Second
+	// nodeListPollInterval is how often a long-poll re-reads the list
+	nodeListPollInterval = 2 * time.Second
+)
+
+// listAdminNodes reads the active and draining nodes shown to admins
+func (g *Gateway) listAdminNodes(ctx context.Context) ([]models.Node, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, provider, status, endpoint_url, health_score, last_heartbeat_at
+		FROM nodes
+		WHERE status IN ('active', 'draining')
+		ORDER BY created_at DESC
+		LIMIT 100
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []models.Node
+	for rows.Next() {
+		var n models.Node
+		if err := rows.Scan(&n.ID, &n.Provider, &n.Status, &n.EndpointURL, &n.HealthScore, &n.LastHeartbeatAt); err != nil {
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+// nodeListETag is the weak ETag of a node list's state
+func nodeListETag(nodes []models.Node) string {
+	h := sha256.New()
+	for _, n := range nodes {
+		fmt.Fprintf(h, "%s|%s|%s|%s|%.2f\n", n.ID, n.Provider, n.Status, n.EndpointURL, n.HealthScore)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header names the ETag,
+// using the weak comparison If-None-Match calls for
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNodeListWait reads the wait parameter as a duration ("30s") or
+// whole seconds ("30"), capped at maxNodeListWait
+func parseNodeListWait(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid wait %q: use a duration such as 30s", value)
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+	if wait < 0 {
+		return 0, fmt.Errorf("wait must not be negative")
+	}
+	if wait > maxNodeListWait {
+		wait = maxNodeListWait
+	}
+	return wait, nil
+}
+
+// waitForNodeListChange re-reads the node list until its ETag differs from
+// etag or the wait runs out, and returns the last list read
+func (g *Gateway) waitForNodeListChange(ctx context.Context, etag string, wait time.Duration) ([]models.Node, string, error) {
+	wake := make(chan struct{}, 1)
+	if g.eventBus != nil {
+		unsubscribe := g.eventBus.SubscribeAll(func(ctx context.Context, event events.Event) error {
+			if strings.HasPrefix(string(event.Type), "node.") {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+			return nil
+		})
+		defer unsubscribe()
+	}
+
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	poll := time.NewTicker(nodeListPollInterval)
+	defer poll.Stop()
+
+	var nodes []models.Node
+	for {
+		select {
+		case <-ctx.Done():
+			return nodes, etag, ctx.Err()
+		case <-g.streamsDraining:
+			return nodes, etag, nil
+		case <-deadline.C:
+			return nodes, etag, nil
+		case <-wake:
+		case <-poll.C:
+		}
+
+		current, err := g.listAdminNodes(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		nodes = current
+		if next := nodeListETag(current); next != etag {
+			return current, next, nil
+		}
+	}
+}
+
+// handleListNodes lists active and draining nodes, answering 304 while
+// the caller's ETag is current and long-polling when asked to wait
+// Admin API - GET /admin/nodes
+func (g *Gateway) handleListNodes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	wait, err := parseNodeListWait(r.URL.Query().Get("wait"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	nodes, err := g.listAdminNodes(ctx)
+	if err != nil {
+		g.logger.Error("failed to query nodes", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to query nodes")
+		return
+	}
+	etag := nodeListETag(nodes)
+
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if wait > 0 && etagMatches(ifNoneMatch, etag) {
+		// Held requests outlive the server's write timeout otherwise
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + 10*time.Second))
+
+		changed, changedETag, err := g.waitForNodeListChange(ctx, etag, wait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			g.logger.Error("failed to query nodes", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to query nodes")
+			return
+		}
+		if changedETag != etag {
+			nodes, etag = changed, changedETag
+		}
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, nodes)
+}
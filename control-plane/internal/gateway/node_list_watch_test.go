@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/google/uuid"
+)
+
+func TestNodeListETag(t *testing.T) {
+	seen := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	list := []models.Node{{ID: uuid.New(), Provider: "aws", Status: "active", EndpointURL: "http://10.0.0.1:8000", HealthScore: 98, LastHeartbeatAt: &seen}}
+	etag := nodeListETag(list)
+
+	later := seen.Add(10 * time.Second)
+	list[0].LastHeartbeatAt = &later
+	if got := nodeListETag(list); got != etag {
+		t.Errorf("heartbeat changed the ETag: %s != %s", got, etag)
+	}
+
+	list[0].Status = "draining"
+	if got := nodeListETag(list); got == etag {
+		t.Error("status change kept the ETag")
+	}
+
+	if got := nodeListETag(nil); got == etag || got[:3] != `W/"` {
+		t.Errorf("empty list ETag = %s", got)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	cases := map[string]bool{
+		"":                false,
+		`W/"abc"`:         true,
+		`"abc"`:           true,
+		`"x", W/"abc"`:    true,
+		`*`:               true,
+		`W/"abd"`:         false,
+		`"abc-different"`: false,
+	}
+	for header, want := range cases {
+		if got := etagMatches(header, etag); got != want {
+			t.Errorf("etagMatches(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestParseNodeListWait(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":    0,
+		"30s": 30 * time.Second,
+		"15":  15 * time.Second,
+		"5m":  maxNodeListWait,
+	}
+	for value, want := range cases {
+		got, err := parseNodeListWait(value)
+		if err != nil || got != want {
+			t.Errorf("parseNodeListWait(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"soon", "-5s"} {
+		if _, err := parseNodeListWait(value); err == nil {
+			t.Errorf("parseNodeListWait(%q) accepted", value)
+		}
+	}
+}
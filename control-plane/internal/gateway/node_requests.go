@@ -0,0 +1,294 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Each node keeps a short Redis list of the requests it served most recently,
+// so operators can see what a misbehaving node was doing without searching
+// the logs
+const (
+	nodeRecentRequestsMax = 200
+	nodeRecentRequestsTTL = 24 * time.Hour
+
+	// nodeResponseTailSize is how much of the response we keep to find usage
+	nodeResponseTailSize = 4096
+)
+
+var (
+	promptTokensPattern     = regexp.MustCompile(`"prompt_tokens"\s*:\s*(\d+)`)
+	completionTokensPattern = regexp.MustCompile(`"completion_tokens"\s*:\s*(\d+)`)
+)
+
+// NodeRequest summarizes a request proxied to a node
+type NodeRequest struct {
+	RequestID        string     `json:"request_id"`
+	Timestamp        time.Time  `json:"timestamp"`
+	Method           string     `json:"method"`
+	Path             string     `json:"path"`
+	Model            string     `json:"model"`
+	TenantID         *uuid.UUID `json:"tenant_id,omitempty"`
+	Status           int        `json:"status"`
+	LatencyMs        int64      `json:"latency_ms"`
+	PromptTokens     *int       `json:"prompt_tokens,omitempty"`
+	CompletionTokens *int       `json:"completion_tokens,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
+
+func nodeRecentRequestsKey(endpoint string) string {
+	return "node_requests:" + endpoint
+}
+
+// trackNodeRequest records a proxied request in the node's recent request
+// buffer. Failed proxies are recorded right away; otherwise the entry is
+// written when the response body is closed, so latency covers the whole
+// response and token counts can be read from its usage block.
+// A successful response also counts as the node's first token for its
+// launch metrics, and the finished request counts towards any routing
+// experiment on its model.
+func (g *Gateway) trackNodeRequest(r *http.Request, endpoint, model string, start time.Time, resp *http.Response, proxyErr error) {
+	entry := NodeRequest{
+		RequestID: middleware.GetReqID(r.Context()),
+		Timestamp: start.UTC(),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Model:     model,
+	}
+	if tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID); ok {
+		entry.TenantID = &tenantID
+	}
+
+	if proxyErr != nil || resp == nil {
+		entry.Status = http.StatusBadGateway
+		entry.LatencyMs = time.Since(start).Milliseconds()
+		if proxyErr != nil {
+			entry.Error = proxyErr.Error()
+		}
+		g.pushNodeRequest(endpoint, &entry)
+		g.recordNodeUsage(r, endpoint, &entry)
+		if g.LoadBalancer != nil {
+			g.LoadBalancer.RecordExperimentOutcome(model, endpoint, time.Since(start), 0, true)
+		}
+		return
+	}
+
+	entry.Status = resp.StatusCode
+	g.observeFirstToken(endpoint, resp.StatusCode)
+	resp.Body = &nodeResponseBody{
+		ReadCloser: resp.Body,
+		onClose: func(tail []byte, readErr error) {
+			entry.LatencyMs = time.Since(start).Milliseconds()
+			entry.PromptTokens = lastTokenCount(promptTokensPattern, tail)
+			entry.CompletionTokens = lastTokenCount(completionTokensPattern, tail)
+			if readErr != nil {
+				entry.Error = readErr.Error()
+			}
+			g.pushNodeRequest(endpoint, &entry)
+
+			if resp.StatusCode < 400 && entry.PromptTokens != nil {
+				completionTokens := 0
+				if entry.CompletionTokens != nil {
+					completionTokens = *entry.CompletionTokens
+				}
+				g.drawEnvironmentBudget(r.Context(), model, *entry.PromptTokens, completionTokens, nil)
+			}
+			g.recordNodeUsage(r, endpoint, &entry)
+
+			if g.LoadBalancer != nil {
+				tokens := 0
+				if entry.CompletionTokens != nil {
+					tokens = *entry.CompletionTokens
+				}
+				g.LoadBalancer.RecordExperimentOutcome(model, endpoint, time.Since(start), tokens, readErr != nil || resp.StatusCode >= 500)
+			}
+		},
+	}
+}
+
+// recordNodeUsage stores a tenant's proxied chat, completion or embedding
+// request as a usage record. The serving node is resolved from its endpoint
+// so the usage job can snapshot its model, region and GPU type; budgets are
+// drawn separately as the response closes.
+func (g *Gateway) recordNodeUsage(r *http.Request, endpoint string, entry *NodeRequest) {
+	if entry.TenantID == nil || g.jobs == nil {
+		return
+	}
+	nodeID := ""
+	if g.LoadBalancer != nil {
+		nodeID = g.LoadBalancer.getNodeIDForEndpoint(endpoint)
+	}
+	ctx := context.WithoutCancel(r.Context())
+	g.queueUsage(ctx, nodeUsageRecord(ctx, entry, nodeID))
+}
+
+// nodeUsageRecord builds the usage record of a proxied request: its tokens,
+// latency and the status returned to the client, which is 502 when the node
+// couldn't be reached
+func nodeUsageRecord(ctx context.Context, entry *NodeRequest, nodeID string) models.UsageRecord {
+	requestID := entry.RequestID
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	envID, _ := ctx.Value("environment_id").(uuid.UUID)
+	status := entry.Status
+	latencyMs := int(entry.LatencyMs)
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"workload_class": nodes.WorkloadText,
+	})
+
+	usage := models.UsageRecord{
+		ID:            uuid.New(),
+		RequestID:     &requestID,
+		Timestamp:     time.Now(),
+		TenantID:      *entry.TenantID,
+		EnvironmentID: envID,
+		StatusCode:    &status,
+		LatencyMs:     &latencyMs,
+		Metadata:      string(metadata),
+	}
+	if entry.PromptTokens != nil {
+		usage.PromptTokens = *entry.PromptTokens
+	}
+	if entry.CompletionTokens != nil {
+		usage.CompletionTokens = *entry.CompletionTokens
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok {
+		usage.APIKeyID = &keyInfo.ID
+	}
+	if id, err := uuid.Parse(nodeID); err == nil {
+		usage.NodeID = &id
+	}
+	return usage
+}
+
+// pushNodeRequest appends entry to the node's buffer. It is best effort and
+// never fails the request.
+func (g *Gateway) pushNodeRequest(endpoint string, entry *NodeRequest) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := g.cache.PushCapped(ctx, nodeRecentRequestsKey(endpoint), data, nodeRecentRequestsMax, nodeRecentRequestsTTL); err != nil {
+		g.logger.Debug("failed to record node request", zap.Error(err), zap.String("endpoint", endpoint))
+	}
+}
+
+// lastTokenCount returns the last count matched by pattern in data, which is
+// the final usage block of a streamed response
+func lastTokenCount(pattern *regexp.Regexp, data []byte) *int {
+	matches := pattern.FindAllSubmatch(data, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	n, err := strconv.Atoi(string(matches[len(matches)-1][1]))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// nodeResponseBody keeps the tail of a response body as it is read and
+// reports it once on Close
+type nodeResponseBody struct {
+	io.ReadCloser
+	tail    []byte
+	readErr error
+	closed  bool
+	onClose func(tail []byte, readErr error)
+}
+
+func (b *nodeResponseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.tail = append(b.tail, p[:n]...)
+		if len(b.tail) > nodeResponseTailSize {
+			b.tail = b.tail[len(b.tail)-nodeResponseTailSize:]
+		}
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.readErr = err
+	}
+	return n, err
+}
+
+func (b *nodeResponseBody) Close() error {
+	err := b.ReadCloser.Close()
+	if !b.closed {
+		b.closed = true
+		b.onClose(b.tail, b.readErr)
+	}
+	return err
+}
+
+// handleGetNodeRecentRequests returns the requests a node served most
+// recently, newest first
+// Admin API - GET /admin/nodes/{id}/recent-requests?limit=50
+func (g *Gateway) handleGetNodeRecentRequests(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	nodeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid node ID")
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 50, 1, nodeRecentRequestsMax)
+
+	var endpoint *string
+	err = g.db.Pool.QueryRow(ctx, `
+		SELECT endpoint_url FROM nodes WHERE id = $1
+	`, nodeID).Scan(&endpoint)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			g.writeError(w, http.StatusNotFound, "node not found")
+			return
+		}
+		g.logger.Error("failed to load node", zap.Error(err), zap.String("node_id", nodeID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load node")
+		return
+	}
+
+	requests := []NodeRequest{}
+	if endpoint != nil && *endpoint != "" {
+		raw, err := g.cache.Range(ctx, nodeRecentRequestsKey(*endpoint), 0, int64(limit)-1)
+		if err != nil {
+			g.logger.Error("failed to read node requests", zap.Error(err), zap.String("node_id", nodeID.String()))
+			g.writeError(w, http.StatusInternalServerError, "failed to read recent requests")
+			return
+		}
+		for _, item := range raw {
+			var req NodeRequest
+			if err := json.Unmarshal([]byte(item), &req); err != nil {
+				continue
+			}
+			requests = append(requests, req)
+		}
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id":  nodeID,
+		"endpoint": endpoint,
+		"data":     requests,
+		"limit":    limit,
+	})
+}
@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestTrackNodeRequest(t *testing.T) {
+	c, cleanup := setupLimiterCache(t)
+	defer cleanup()
+	g := &Gateway{cache: c, logger: zap.NewNop()}
+
+	endpoint := "http://10.0.0.1:8000"
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	stream := "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\": 34}}\n\n" +
+		"data: [DONE]\n\n"
+	resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(stream))}
+	g.trackNodeRequest(r, endpoint, "llama", time.Now(), resp, nil)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	resp.Body.Close()
+
+	g.trackNodeRequest(r, endpoint, "llama", time.Now(), nil, errors.New("connection refused"))
+
+	raw, err := c.Range(context.Background(), nodeRecentRequestsKey(endpoint), 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 2 {
+		t.Fatalf("recorded %d requests, want 2", len(raw))
+	}
+
+	var failed, served NodeRequest
+	json.Unmarshal([]byte(raw[0]), &failed)
+	json.Unmarshal([]byte(raw[1]), &served)
+
+	if failed.Status != http.StatusBadGateway || failed.Error != "connection refused" {
+		t.Errorf("failed request = %+v", failed)
+	}
+	if served.Status != http.StatusOK || served.Model != "llama" || served.Path != "/v1/chat/completions" {
+		t.Errorf("served request = %+v", served)
+	}
+	if served.PromptTokens == nil || *served.PromptTokens != 12 || served.CompletionTokens == nil || *served.CompletionTokens != 34 {
+		t.Errorf("token counts = %v, %v", served.PromptTokens, served.CompletionTokens)
+	}
+}
+
+func TestNodeUsageRecord(t *testing.T) {
+	tenantID, envID, keyID, nodeID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	ctx := context.WithValue(context.Background(), "environment_id", envID)
+	ctx = context.WithValue(ctx, "api_key", &models.APIKey{ID: keyID})
+
+	prompt, completion := 12, 34
+	served := nodeUsageRecord(ctx, &NodeRequest{
+		RequestID:        "req-1",
+		TenantID:         &tenantID,
+		Status:           http.StatusOK,
+		LatencyMs:        250,
+		PromptTokens:     &prompt,
+		CompletionTokens: &completion,
+	}, nodeID.String())
+
+	if served.TenantID != tenantID || served.EnvironmentID != envID || served.APIKeyID == nil || *served.APIKeyID != keyID {
+		t.Errorf("served usage ids = %+v", served)
+	}
+	if served.RequestID == nil || *served.RequestID != "req-1" {
+		t.Errorf("request ID = %v, want req-1", served.RequestID)
+	}
+	if served.StatusCode == nil || *served.StatusCode != http.StatusOK {
+		t.Errorf("status code = %v, want 200", served.StatusCode)
+	}
+	// The usage job snapshots model, region and GPU type from the node
+	if served.NodeID == nil || *served.NodeID != nodeID {
+		t.Errorf("node ID = %v, want %s", served.NodeID, nodeID)
+	}
+	if served.PromptTokens != 12 || served.CompletionTokens != 34 || served.TotalTokens != 46 {
+		t.Errorf("tokens = %d + %d = %d", served.PromptTokens, served.CompletionTokens, served.TotalTokens)
+	}
+	if served.LatencyMs == nil || *served.LatencyMs != 250 {
+		t.Errorf("latency = %v, want 250", served.LatencyMs)
+	}
+	if !strings.Contains(served.Metadata, `"workload_class":"text"`) {
+		t.Errorf("metadata = %s", served.Metadata)
+	}
+
+	// A node that couldn't be reached is recorded as the 502 the client got
+	failed := nodeUsageRecord(context.Background(), &NodeRequest{
+		TenantID: &tenantID,
+		Status:   http.StatusBadGateway,
+		Error:    "connection refused",
+	}, "")
+	if failed.StatusCode == nil || *failed.StatusCode != http.StatusBadGateway {
+		t.Errorf("status code = %v, want 502", failed.StatusCode)
+	}
+	if failed.NodeID != nil || failed.APIKeyID != nil || failed.TotalTokens != 0 {
+		t.Errorf("failed usage = %+v", failed)
+	}
+	if failed.RequestID == nil || *failed.RequestID == "" {
+		t.Error("failed usage has no request ID")
+	}
+}
+
+func TestPushNodeRequestCapsBuffer(t *testing.T) {
+	c, cleanup := setupLimiterCache(t)
+	defer cleanup()
+	g := &Gateway{cache: c, logger: zap.NewNop()}
+
+	for i := 0; i < nodeRecentRequestsMax+10; i++ {
+		g.pushNodeRequest("node", &NodeRequest{Status: i})
+	}
+
+	raw, err := c.Range(context.Background(), nodeRecentRequestsKey("node"), 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != nodeRecentRequestsMax {
+		t.Fatalf("buffer holds %d entries, want %d", len(raw), nodeRecentRequestsMax)
+	}
+	var newest NodeRequest
+	json.Unmarshal([]byte(raw[0]), &newest)
+	if newest.Status != nodeRecentRequestsMax+9 {
+		t.Errorf("newest entry = %+v", newest)
+	}
+}
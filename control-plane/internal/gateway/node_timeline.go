@@ -0,0 +1,272 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Timeline event kinds
+const (
+	timelineStatus = "status"
+	timelineLaunch = "launch"
+	timelineCrash  = "crash"
+	timelineLog    = "log"
+)
+
+// nodeTimelineLogTail bounds the launch log entries scanned for highlights
+const nodeTimelineLogTail = 1000
+
+// NodeTimelineEvent is one entry of a node's timeline
+type NodeTimelineEvent struct {
+	At      time.Time `json:"at"`
+	Kind    string    `json:"kind"`
+	Source  string    `json:"source,omitempty"`
+	Message string    `json:"message"`
+	// FromStatus and Status are set on status changes
+	FromStatus string `json:"from_status,omitempty"`
+	Status     string `json:"status,omitempty"`
+	// Level and Phase are set on log highlights
+	Level string `json:"level,omitempty"`
+	Phase string `json:"phase,omitempty"`
+	// CrashReportID links a crash to its report
+	CrashReportID *uuid.UUID `json:"crash_report_id,omitempty"`
+}
+
+// NodeCost is what a node's instance has cost so far
+type NodeCost struct {
+	PricePerHour  float64 `json:"price_per_hour"`
+	Spot          bool    `json:"spot"`
+	BillableHours float64 `json:"billable_hours"`
+	AccruedUSD    float64 `json:"accrued_usd"`
+}
+
+// NodeTimeline is a node's history for post-incident reviews
+type NodeTimeline struct {
+	NodeID       uuid.UUID           `json:"node_id"`
+	ClusterName  string              `json:"cluster_name,omitempty"`
+	Status       string              `json:"status"`
+	LaunchedAt   time.Time           `json:"launched_at"`
+	TerminatedAt *time.Time          `json:"terminated_at,omitempty"`
+	Cost         NodeCost            `json:"cost"`
+	Events       []NodeTimelineEvent `json:"events"`
+}
+
+// nodeCrash is the part of a crash report shown on the timeline
+type nodeCrash struct {
+	ID         uuid.UUID
+	Reason     string
+	DetectedAt time.Time
+}
+
+// launchTimelineEvents turns launch checkpoints into timeline events
+func launchTimelineEvents(t *nodes.LaunchTiming) []NodeTimelineEvent {
+	if t == nil {
+		return nil
+	}
+	checkpoints := []struct {
+		at      *time.Time
+		message string
+	}{
+		{&t.RequestedAt, "launch requested"},
+		{t.SetupStartedAt, "setup started"},
+		{t.InstanceReadyAt, "instance ready"},
+		{t.VLLMStartedAt, "vLLM started"},
+		{t.VLLMHealthyAt, "vLLM healthy"},
+		{t.FirstTokenAt, "first token served"},
+	}
+
+	var events []NodeTimelineEvent
+	for _, c := range checkpoints {
+		if c.at != nil && !c.at.IsZero() {
+			events = append(events, NodeTimelineEvent{At: *c.at, Kind: timelineLaunch, Message: c.message})
+		}
+	}
+	if t.FailedAt != nil {
+		message := "launch failed"
+		if t.Error != "" {
+			message += ": " + t.Error
+		}
+		events = append(events, NodeTimelineEvent{At: *t.FailedAt, Kind: timelineLaunch, Message: message})
+	}
+	return events
+}
+
+// logHighlights picks the launch log entries worth a place on the timeline:
+// warnings, errors and the first entry of each phase
+func logHighlights(logs []orchestrator.NodeLogEntry) []NodeTimelineEvent {
+	var events []NodeTimelineEvent
+	var phase orchestrator.NodeLogPhase
+	for _, entry := range logs {
+		phaseChanged := entry.Phase != phase
+		phase = entry.Phase
+		if entry.Level != orchestrator.LogLevelWarn && entry.Level != orchestrator.LogLevelError && !phaseChanged {
+			continue
+		}
+		events = append(events, NodeTimelineEvent{
+			At:      entry.Timestamp,
+			Kind:    timelineLog,
+			Message: entry.Message,
+			Level:   string(entry.Level),
+			Phase:   string(entry.Phase),
+		})
+	}
+	return events
+}
+
+// buildNodeTimelineEvents merges a node's history into one list, oldest first
+func buildNodeTimelineEvents(transitions []nodes.StatusTransition, launch *nodes.LaunchTiming, crashes []nodeCrash, logs []orchestrator.NodeLogEntry) []NodeTimelineEvent {
+	events := []NodeTimelineEvent{}
+	for _, t := range transitions {
+		message := t.Reason
+		if message == "" {
+			message = "status changed to " + t.ToStatus
+		}
+		events = append(events, NodeTimelineEvent{
+			At:         t.OccurredAt,
+			Kind:       timelineStatus,
+			Source:     t.Source,
+			Message:    message,
+			FromStatus: t.FromStatus,
+			Status:     t.ToStatus,
+		})
+	}
+	events = append(events, launchTimelineEvents(launch)...)
+	for _, c := range crashes {
+		id := c.ID
+		events = append(events, NodeTimelineEvent{
+			At:            c.DetectedAt,
+			Kind:          timelineCrash,
+			Source:        nodes.SourceNode,
+			Message:       "vLLM crashed: " + c.Reason,
+			CrashReportID: &id,
+		})
+	}
+	events = append(events, logHighlights(logs)...)
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	return events
+}
+
+// nodeCost prices the node's billable time at its hourly price
+func nodeCost(pricePerHour float64, spot bool, transitions []nodes.StatusTransition, launched, until time.Time) NodeCost {
+	hours := nodes.BillableDuration(transitions, launched, until).Hours()
+	return NodeCost{
+		PricePerHour:  pricePerHour,
+		Spot:          spot,
+		BillableHours: hours,
+		AccruedUSD:    hours * pricePerHour,
+	}
+}
+
+// nodeCallbackSource attributes a node callback to the node agent when it
+// came in on the node listener, otherwise to the admin who made it
+func nodeCallbackSource(ctx context.Context) string {
+	if actor, _ := ctx.Value("admin_token").(string); actor == nodeAPIActor {
+		return nodes.SourceNode
+	}
+	return nodes.SourceAdmin
+}
+
+// handleGetNodeTimeline returns a node's status changes, launch checkpoints,
+// crashes and launch log highlights in order, with the cost it has accrued.
+// Launch logs are read from the archive once Redis has dropped them; without
+// archival, timelines older than a day have no log highlights.
+// Admin API - GET /admin/nodes/{id}/timeline
+func (g *Gateway) handleGetNodeTimeline(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	nodeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid node ID")
+		return
+	}
+
+	timeline := NodeTimeline{NodeID: nodeID}
+	var pricePerHour float64
+	var spot bool
+	err = g.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(n.cluster_name, ''), n.status, n.created_at, n.terminated_at, COALESCE(n.spot_instance, false),
+		       COALESCE(CASE WHEN n.spot_instance THEN COALESCE(n.spot_price, it.spot_price_per_hour) END,
+		                it.price_per_hour, 0)::float8
+		FROM nodes n
+		LEFT JOIN LATERAL (
+			SELECT price_per_hour, spot_price_per_hour FROM instance_types
+			WHERE provider = n.provider AND instance_type = n.instance_type
+			LIMIT 1
+		) it ON true
+		WHERE n.id = $1
+	`, nodeID).Scan(&timeline.ClusterName, &timeline.Status, &timeline.LaunchedAt, &timeline.TerminatedAt,
+		&spot, &pricePerHour)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "node not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to get node", zap.Error(err), zap.String("node_id", nodeID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to get node timeline")
+		return
+	}
+
+	transitions, err := g.nodeRegistry.Transitions(ctx, nodeID)
+	if err != nil {
+		g.logger.Error("failed to get node status transitions", zap.Error(err), zap.String("node_id", nodeID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to get node timeline")
+		return
+	}
+
+	// The rest is best effort: a timeline without them still tells the story
+	launch, err := g.launches.Get(ctx, nodeID)
+	if err != nil {
+		if !errors.Is(err, nodes.ErrLaunchTimingNotFound) {
+			g.logger.Warn("failed to get launch timing", zap.Error(err), zap.String("node_id", nodeID.String()))
+		}
+		launch = nil
+	}
+	crashes, err := g.nodeCrashes(ctx, nodeID)
+	if err != nil {
+		g.logger.Warn("failed to list crash reports", zap.Error(err), zap.String("node_id", nodeID.String()))
+	}
+	logs, err := g.nodeLogStore().GetLogs(ctx, nodeID.String(), nodeTimelineLogTail, nil)
+	if err != nil {
+		g.logger.Warn("failed to get node logs", zap.Error(err), zap.String("node_id", nodeID.String()))
+	}
+
+	until := time.Now()
+	if timeline.TerminatedAt != nil {
+		until = *timeline.TerminatedAt
+	}
+	timeline.Cost = nodeCost(pricePerHour, spot, transitions, timeline.LaunchedAt, until)
+	timeline.Events = buildNodeTimelineEvents(transitions, launch, crashes, logs)
+
+	g.writeJSON(w, http.StatusOK, timeline)
+}
+
+// nodeCrashes returns a node's crash reports
+func (g *Gateway) nodeCrashes(ctx context.Context, nodeID uuid.UUID) ([]nodeCrash, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, reason, detected_at FROM node_crash_reports WHERE node_id = $1 ORDER BY detected_at
+	`, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var crashes []nodeCrash
+	for rows.Next() {
+		var c nodeCrash
+		if err := rows.Scan(&c.ID, &c.Reason, &c.DetectedAt); err != nil {
+			return nil, err
+		}
+		crashes = append(crashes, c)
+	}
+	return crashes, rows.Err()
+}
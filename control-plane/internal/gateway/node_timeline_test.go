@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/google/uuid"
+)
+
+func TestBuildNodeTimelineEvents(t *testing.T) {
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return base.Add(time.Duration(m) * time.Minute) }
+	healthy := at(6)
+
+	transitions := []nodes.StatusTransition{
+		{ToStatus: nodes.StatusInitializing, Source: nodes.SourceOrchestrator, OccurredAt: at(1)},
+		{FromStatus: nodes.StatusInitializing, ToStatus: nodes.StatusActive, Source: nodes.SourceNode, OccurredAt: at(7)},
+		{FromStatus: nodes.StatusActive, ToStatus: "dead", Source: nodes.SourceMonitor, Reason: "no heartbeat", OccurredAt: at(30)},
+	}
+	launch := &nodes.LaunchTiming{RequestedAt: at(0), VLLMHealthyAt: &healthy}
+	crashes := []nodeCrash{{ID: uuid.New(), Reason: "oom", DetectedAt: at(29)}}
+	logs := []orchestrator.NodeLogEntry{
+		{Timestamp: at(2), Level: orchestrator.LogLevelInfo, Phase: orchestrator.PhaseProvisioning, Message: "provisioning"},
+		{Timestamp: at(3), Level: orchestrator.LogLevelInfo, Phase: orchestrator.PhaseProvisioning, Message: "still provisioning"},
+		{Timestamp: at(4), Level: orchestrator.LogLevelWarn, Phase: orchestrator.PhaseProvisioning, Message: "capacity retry"},
+		{Timestamp: at(5), Level: orchestrator.LogLevelInfo, Phase: orchestrator.PhaseModelLoading, Message: "loading model"},
+	}
+
+	events := buildNodeTimelineEvents(transitions, launch, crashes, logs)
+
+	want := []string{
+		"launch requested",
+		"status changed to initializing",
+		"provisioning",
+		"capacity retry",
+		"loading model",
+		"vLLM healthy",
+		"status changed to active",
+		"vLLM crashed: oom",
+		"no heartbeat",
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, msg := range want {
+		if events[i].Message != msg {
+			t.Errorf("event %d = %q, want %q", i, events[i].Message, msg)
+		}
+	}
+	if last := events[len(events)-1]; last.Source != nodes.SourceMonitor || last.Status != "dead" || last.FromStatus != nodes.StatusActive {
+		t.Errorf("last event = %+v", last)
+	}
+}
+
+func TestNodeCost(t *testing.T) {
+	launched := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	cost := nodeCost(2.5, true, nil, launched, launched.Add(4*time.Hour))
+	if cost.BillableHours != 4 || cost.AccruedUSD != 10 || !cost.Spot {
+		t.Errorf("nodeCost() = %+v", cost)
+	}
+}
+
+func TestNodeCallbackSource(t *testing.T) {
+	if got := nodeCallbackSource(context.WithValue(context.Background(), "admin_token", nodeAPIActor)); got != nodes.SourceNode {
+		t.Errorf("node listener source = %q", got)
+	}
+	if got := nodeCallbackSource(context.WithValue(context.Background(), "admin_token", "ops@example.com")); got != nodes.SourceAdmin {
+		t.Errorf("admin source = %q", got)
+	}
+}
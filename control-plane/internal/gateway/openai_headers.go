@@ -0,0 +1,337 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// OpenAI SDKs send these headers when an organization or project is
+// configured. We map the organization to the tenant and the project to one of
+// its environments, so migrated clients keep their per-project segregation.
+const (
+	openAIOrganizationHeader = "OpenAI-Organization"
+	openAIProjectHeader      = "OpenAI-Project"
+
+	// openAIMappingCacheTTL bounds how long a changed mapping takes to apply
+	openAIMappingCacheTTL = 30 * time.Second
+)
+
+// openAIIdentifierPattern matches organization and project IDs we accept as
+// aliases, e.g. "org-abc123" or "proj_abc123"
+var openAIIdentifierPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,99}$`)
+
+// validOpenAIIdentifier reports whether id can be used as an organization or
+// project alias
+func validOpenAIIdentifier(id string) bool {
+	return openAIIdentifierPattern.MatchString(id)
+}
+
+// writeOpenAIHeaderError writes the error OpenAI returns for a header that
+// doesn't match the API key
+func (g *Gateway) writeOpenAIHeaderError(w http.ResponseWriter, message, code string) {
+	g.writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    "invalid_request_error",
+			"code":    code,
+		},
+	})
+}
+
+// openAIHeadersMiddleware validates OpenAI-Organization against the key's
+// tenant and scopes the request to the environment named by OpenAI-Project.
+// Both headers are echoed back like the OpenAI API does.
+func (g *Gateway) openAIHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		org := strings.TrimSpace(r.Header.Get(openAIOrganizationHeader))
+		project := strings.TrimSpace(r.Header.Get(openAIProjectHeader))
+		if org == "" && project == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+		if !ok {
+			g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+			return
+		}
+
+		if org != "" {
+			matched, err := g.matchOpenAIOrganization(ctx, tenantID, org)
+			if err != nil {
+				g.logger.Error("failed to resolve OpenAI organization", zap.Error(err))
+				g.writeError(w, http.StatusInternalServerError, "failed to resolve organization")
+				return
+			}
+			if !matched {
+				g.writeOpenAIHeaderError(w, "OpenAI-Organization header should match organization for API key", "mismatched_organization")
+				return
+			}
+			w.Header().Set(openAIOrganizationHeader, org)
+		}
+
+		if project != "" {
+			envID, err := g.resolveOpenAIProject(ctx, tenantID, project)
+			if err != nil {
+				g.logger.Error("failed to resolve OpenAI project", zap.Error(err))
+				g.writeError(w, http.StatusInternalServerError, "failed to resolve project")
+				return
+			}
+			if envID == uuid.Nil {
+				g.writeOpenAIHeaderError(w, "OpenAI-Project header should match a project of the organization for API key", "mismatched_project")
+				return
+			}
+			w.Header().Set(openAIProjectHeader, project)
+
+			// Usage and routing follow the project's environment
+			ctx = context.WithValue(ctx, "environment_id", envID)
+			ctx = context.WithValue(ctx, "openai_project", envID)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// matchOpenAIOrganization reports whether org names the tenant, either by
+// tenant ID or by its configured OpenAI organization ID
+func (g *Gateway) matchOpenAIOrganization(ctx context.Context, tenantID uuid.UUID, org string) (bool, error) {
+	cacheKey := cache.TenantKey(tenantID, "openai_org", org)
+	if v, err := g.cache.Get(ctx, cacheKey); err == nil && v != "" {
+		return v == "1", nil
+	}
+
+	var matched bool
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM tenants
+			WHERE id = $1 AND (id::text = $2 OR openai_organization_id = $2)
+		)
+	`, tenantID, org).Scan(&matched)
+	if err != nil {
+		return false, err
+	}
+
+	v := "0"
+	if matched {
+		v = "1"
+	}
+	g.cache.Set(ctx, cacheKey, v, openAIMappingCacheTTL)
+	return matched, nil
+}
+
+// resolveOpenAIProject returns the active environment of the tenant that
+// project names by environment ID, OpenAI project ID or environment name, in
+// that order of precedence. It returns uuid.Nil when nothing matches.
+func (g *Gateway) resolveOpenAIProject(ctx context.Context, tenantID uuid.UUID, project string) (uuid.UUID, error) {
+	cacheKey := cache.TenantKey(tenantID, "openai_project", project)
+	if v, err := g.cache.Get(ctx, cacheKey); err == nil && v != "" {
+		if id, err := uuid.Parse(v); err == nil {
+			return id, nil
+		}
+	}
+
+	var envID uuid.UUID
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT id FROM environments
+		WHERE tenant_id = $1 AND status = 'active'
+		  AND (id::text = $2 OR openai_project_id = $2 OR name = $2)
+		ORDER BY (id::text = $2) DESC, (openai_project_id IS NOT DISTINCT FROM $2) DESC
+		LIMIT 1
+	`, tenantID, project).Scan(&envID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+
+	g.cache.Set(ctx, cacheKey, envID.String(), openAIMappingCacheTTL)
+	return envID, nil
+}
+
+// OpenAIProjectMapping links an environment to an OpenAI project ID
+type OpenAIProjectMapping struct {
+	EnvironmentID   uuid.UUID `json:"environment_id"`
+	Name            string    `json:"name"`
+	Status          string    `json:"status"`
+	OpenAIProjectID *string   `json:"openai_project_id"`
+}
+
+// handleGetOpenAIMapping returns the tenant's OpenAI organization and project
+// mappings
+// Tenant API - GET /v1/openai-mapping
+func (g *Gateway) handleGetOpenAIMapping(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+
+	var orgID *string
+	if err := g.db.Pool.QueryRow(ctx, `
+		SELECT openai_organization_id FROM tenants WHERE id = $1
+	`, tenantID).Scan(&orgID); err != nil {
+		g.logger.Error("failed to load OpenAI organization mapping", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load mapping")
+		return
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, name, status, openai_project_id
+		FROM environments
+		WHERE tenant_id = $1
+		ORDER BY name
+	`, tenantID)
+	if err != nil {
+		g.logger.Error("failed to load OpenAI project mappings", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load mapping")
+		return
+	}
+	defer rows.Close()
+
+	projects := []OpenAIProjectMapping{}
+	for rows.Next() {
+		var p OpenAIProjectMapping
+		if err := rows.Scan(&p.EnvironmentID, &p.Name, &p.Status, &p.OpenAIProjectID); err != nil {
+			g.logger.Error("failed to scan project mapping", zap.Error(err))
+			continue
+		}
+		projects = append(projects, p)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tenant_id":              tenantID,
+		"openai_organization_id": orgID,
+		"projects":               projects,
+	})
+}
+
+// decodeOpenAIIdentifier reads {"id": "..."} from the body. An empty ID
+// clears the mapping and is returned as nil.
+func (g *Gateway) decodeOpenAIIdentifier(w http.ResponseWriter, r *http.Request) (*string, bool) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return nil, false
+	}
+	req.ID = strings.TrimSpace(req.ID)
+	if req.ID == "" {
+		return nil, true
+	}
+	if !validOpenAIIdentifier(req.ID) {
+		g.writeError(w, http.StatusBadRequest, "id must be 1-100 letters, digits, '-' or '_'")
+		return nil, false
+	}
+	return &req.ID, true
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint error
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign key error
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// handleSetOpenAIOrganization maps an OpenAI organization ID to the tenant
+// Tenant API - PUT /v1/openai-mapping/organization
+func (g *Gateway) handleSetOpenAIOrganization(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+
+	orgID, ok := g.decodeOpenAIIdentifier(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := g.db.Pool.Exec(ctx, `
+		UPDATE tenants SET openai_organization_id = $2, updated_at = NOW() WHERE id = $1
+	`, tenantID, orgID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			g.writeError(w, http.StatusConflict, "organization ID is already mapped to another tenant")
+			return
+		}
+		g.logger.Error("failed to set OpenAI organization mapping", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update mapping")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tenant_id":              tenantID,
+		"openai_organization_id": orgID,
+	})
+}
+
+// handleSetOpenAIProject maps an OpenAI project ID to one of the tenant's
+// environments
+// Tenant API - PUT /v1/openai-mapping/projects/{environment_id}
+func (g *Gateway) handleSetOpenAIProject(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+
+	envID, err := uuid.Parse(chi.URLParam(r, "environment_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid environment ID")
+		return
+	}
+
+	projectID, ok := g.decodeOpenAIIdentifier(w, r)
+	if !ok {
+		return
+	}
+
+	tag, err := g.db.Pool.Exec(ctx, `
+		UPDATE environments SET openai_project_id = $3, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+	`, envID, tenantID, projectID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			g.writeError(w, http.StatusConflict, "project ID is already mapped to another environment")
+			return
+		}
+		g.logger.Error("failed to set OpenAI project mapping", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update mapping")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "environment not found")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"environment_id":    envID,
+		"openai_project_id": projectID,
+	})
+}
@@ -0,0 +1,25 @@
+package gateway
+
+import "testing"
+
+func TestValidOpenAIIdentifier(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"org-AbC123", true},
+		{"proj_abc123", true},
+		{"production", true},
+		{"", false},
+		{"-leading-dash", false},
+		{"has space", false},
+		{"org'; DROP TABLE tenants", false},
+		{string(make([]byte, 101)), false},
+	}
+
+	for _, tt := range tests {
+		if got := validOpenAIIdentifier(tt.id); got != tt.want {
+			t.Errorf("validOpenAIIdentifier(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// outputPoliciesCacheTTL bounds how long a tenant's policies are cached
+const outputPoliciesCacheTTL = 60 * time.Second
+
+// OutputPolicy is a tenant's post-processing rules for inference output.
+// A nil APIKeyID or Model matches every key or model; when several
+// policies match a request the most specific one applies, with the API key
+// counting for more than the model.
+type OutputPolicy struct {
+	ID        uuid.UUID    `json:"id"`
+	APIKeyID  *uuid.UUID   `json:"api_key_id,omitempty"`
+	Model     *string      `json:"model,omitempty"`
+	Rules     []OutputRule `json:"rules"`
+	Enabled   bool         `json:"enabled"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// matchScore returns how specifically the policy matches a request, or -1
+// if it doesn't apply
+func (p *OutputPolicy) matchScore(keyID *uuid.UUID, model string) int {
+	if !p.Enabled {
+		return -1
+	}
+	score := 0
+	if p.APIKeyID != nil {
+		if keyID == nil || *p.APIKeyID != *keyID {
+			return -1
+		}
+		score += 2
+	}
+	if p.Model != nil {
+		if *p.Model != model {
+			return -1
+		}
+		score++
+	}
+	return score
+}
+
+// selectOutputPolicy returns the most specific policy matching a request
+func selectOutputPolicy(policies []OutputPolicy, keyID *uuid.UUID, model string) *OutputPolicy {
+	var best *OutputPolicy
+	bestScore := -1
+	for i := range policies {
+		if score := policies[i].matchScore(keyID, model); score > bestScore {
+			best, bestScore = &policies[i], score
+		}
+	}
+	return best
+}
+
+const outputPolicyColumns = `id, api_key_id, model, rules, enabled, created_at, updated_at`
+
+func scanOutputPolicy(row pgx.Row) (*OutputPolicy, error) {
+	var p OutputPolicy
+	var rules []byte
+	if err := row.Scan(&p.ID, &p.APIKeyID, &p.Model, &rules, &p.Enabled, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rules, &p.Rules); err != nil {
+		return nil, fmt.Errorf("invalid rules for output policy %s: %w", p.ID, err)
+	}
+	return &p, nil
+}
+
+func outputPoliciesCacheKey(tenantID uuid.UUID) string {
+	return cache.TenantKey(tenantID, "output_policies")
+}
+
+// listOutputPolicies returns the tenant's policies from the database
+func (g *Gateway) listOutputPolicies(ctx context.Context, tenantID uuid.UUID) ([]OutputPolicy, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT `+outputPolicyColumns+`
+		FROM output_policies
+		WHERE tenant_id = $1
+		ORDER BY created_at
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []OutputPolicy{}
+	for rows.Next() {
+		p, err := scanOutputPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, *p)
+	}
+	return policies, rows.Err()
+}
+
+// loadOutputPolicies returns the tenant's policies, from cache when possible
+func (g *Gateway) loadOutputPolicies(ctx context.Context, tenantID uuid.UUID) ([]OutputPolicy, error) {
+	if cached, err := g.cache.Get(ctx, outputPoliciesCacheKey(tenantID)); err == nil {
+		var policies []OutputPolicy
+		if err := json.Unmarshal([]byte(cached), &policies); err == nil {
+			return policies, nil
+		}
+	}
+
+	policies, err := g.listOutputPolicies(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, _ := json.Marshal(policies)
+	if err := g.cache.Set(ctx, outputPoliciesCacheKey(tenantID), string(encoded), outputPoliciesCacheTTL); err != nil {
+		g.logger.Debug("failed to cache output policies", zap.Error(err))
+	}
+	return policies, nil
+}
+
+// invalidateOutputPolicies drops cached policies after a change
+func (g *Gateway) invalidateOutputPolicies(ctx context.Context, tenantID uuid.UUID) {
+	if err := g.cache.Delete(ctx, outputPoliciesCacheKey(tenantID)); err != nil {
+		g.logger.Warn("failed to invalidate output policy cache", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+	}
+}
+
+// systemPromptText returns the text of a chat request's system messages
+func systemPromptText(messages []ChatCompletionMessage) string {
+	var texts []string
+	for _, m := range messages {
+		if m.Role == "system" || m.Role == "developer" {
+			if text := contentText(m.Content); text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// applyOutputPolicy wraps a successful response so the tenant's output
+// policy for this key and model is applied as it is relayed. Policies that
+// can't be loaded are skipped rather than failing the request.
+func (g *Gateway) applyOutputPolicy(ctx context.Context, resp *http.Response, model, systemPrompt string, chat bool) {
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return
+	}
+
+	policies, err := g.loadOutputPolicies(ctx, tenantID)
+	if err != nil {
+		g.logger.Warn("failed to load output policies", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		return
+	}
+	var keyID *uuid.UUID
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok {
+		keyID = &keyInfo.ID
+	}
+	policy := selectOutputPolicy(policies, keyID, model)
+	if policy == nil || len(policy.Rules) == 0 {
+		return
+	}
+
+	stream := strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	resp.Body = newOutputPolicyBody(resp.Body, newOutputRewriter(policy.Rules, systemPrompt, chat), stream)
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+}
+
+// outputPolicyRequest is the body of POST /v1/output-policies
+type outputPolicyRequest struct {
+	APIKeyID *uuid.UUID   `json:"api_key_id"`
+	Model    *string      `json:"model"`
+	Rules    []OutputRule `json:"rules"`
+	Enabled  *bool        `json:"enabled"`
+}
+
+// requireOutputPolicyWriter returns the tenant ID for requests allowed to
+// change output policies
+func (g *Gateway) requireOutputPolicyWriter(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return uuid.Nil, false
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok && keyInfo.Role == "read-only" {
+		g.writeError(w, http.StatusForbidden, "read-only API keys cannot change output policies")
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// handleListOutputPolicies lists the tenant's output policies
+// Tenant API - GET /v1/output-policies
+func (g *Gateway) handleListOutputPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+
+	policies, err := g.listOutputPolicies(ctx, tenantID)
+	if err != nil {
+		g.logger.Error("failed to list output policies", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to list output policies")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": policies,
+	})
+}
+
+// handleSaveOutputPolicy creates or replaces the policy for an API key
+// and/or model; omit both for a tenant-wide policy
+// Tenant API - POST /v1/output-policies
+func (g *Gateway) handleSaveOutputPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := g.requireOutputPolicyWriter(w, r)
+	if !ok {
+		return
+	}
+
+	var req outputPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Model != nil {
+		if model := strings.TrimSpace(*req.Model); model == "" {
+			req.Model = nil
+		} else {
+			req.Model = &model
+		}
+	}
+	if err := validateOutputRules(req.Rules); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	if req.APIKeyID != nil {
+		var exists bool
+		err := g.db.Pool.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM api_keys WHERE id = $1 AND tenant_id = $2)
+		`, *req.APIKeyID, tenantID).Scan(&exists)
+		if err != nil {
+			g.logger.Error("failed to look up API key", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to save output policy")
+			return
+		}
+		if !exists {
+			g.writeError(w, http.StatusBadRequest, "api_key_id not found")
+			return
+		}
+	}
+
+	rules, _ := json.Marshal(req.Rules)
+	policy, err := scanOutputPolicy(g.db.Pool.QueryRow(ctx, `
+		INSERT INTO output_policies (tenant_id, api_key_id, model, rules, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, COALESCE(api_key_id, '00000000-0000-0000-0000-000000000000'::uuid), COALESCE(model, ''))
+		DO UPDATE SET rules = EXCLUDED.rules, enabled = EXCLUDED.enabled, updated_at = NOW()
+		RETURNING `+outputPolicyColumns,
+		tenantID, req.APIKeyID, req.Model, rules, enabled,
+	))
+	if err != nil {
+		g.logger.Error("failed to save output policy", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to save output policy")
+		return
+	}
+	g.invalidateOutputPolicies(ctx, tenantID)
+
+	g.logger.Info("output policy saved",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("policy_id", policy.ID.String()),
+		zap.Int("rules", len(policy.Rules)),
+	)
+	g.writeJSON(w, http.StatusOK, policy)
+}
+
+// handleDeleteOutputPolicy removes an output policy
+// Tenant API - DELETE /v1/output-policies/{id}
+func (g *Gateway) handleDeleteOutputPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := g.requireOutputPolicyWriter(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid output policy ID")
+		return
+	}
+
+	result, err := g.db.Pool.Exec(ctx, `
+		DELETE FROM output_policies WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID)
+	if err != nil {
+		g.logger.Error("failed to delete output policy", zap.Error(err), zap.String("policy_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to delete output policy")
+		return
+	}
+	if result.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "output policy not found")
+		return
+	}
+	g.invalidateOutputPolicies(ctx, tenantID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePreviewOutputPolicy runs rules over sample text, so tenants can
+// check a policy before saving it
+// Tenant API - POST /v1/output-policies/preview
+func (g *Gateway) handlePreviewOutputPolicy(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Rules        []OutputRule `json:"rules"`
+		Text         string       `json:"text"`
+		SystemPrompt string       `json:"system_prompt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := validateOutputRules(req.Rules); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chain, err := newOutputChain(req.Rules, req.SystemPrompt)
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	text := chain.Write(req.Text) + chain.Flush()
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"text":      text,
+		"truncated": chain.Truncated(),
+	})
+}
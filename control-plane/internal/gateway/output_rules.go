@@ -0,0 +1,516 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Output rule types. Rules run in the order they are listed in a policy.
+const (
+	// outputRuleStopSequences ends the output at the first of its sequences
+	outputRuleStopSequences = "stop_sequences"
+
+	// outputRuleStripSystemEcho removes the system prompt when the model
+	// repeats it at the start of its output
+	outputRuleStripSystemEcho = "strip_system_echo"
+
+	// outputRuleMaxLines ends the output after a number of lines
+	outputRuleMaxLines = "max_lines"
+
+	// outputRuleRedact replaces matches of a regular expression
+	outputRuleRedact = "redact"
+)
+
+const (
+	maxOutputRules         = 10
+	maxOutputStopSequences = 8
+	maxOutputStopLength    = 100
+	maxOutputPatternLength = 500
+	maxOutputLines         = 10000
+
+	// defaultRedaction replaces redacted matches unless a rule sets its own
+	defaultRedaction = "[REDACTED]"
+
+	// redactHoldLimit bounds how much streamed text redaction holds back
+	// while waiting for a line to end
+	redactHoldLimit = 1024
+)
+
+// OutputRule is one post-processing step applied to generated text
+type OutputRule struct {
+	Type        string   `json:"type"`
+	Sequences   []string `json:"sequences,omitempty"`
+	MaxLines    int      `json:"max_lines,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Replacement *string  `json:"replacement,omitempty"`
+}
+
+// validateOutputRules checks a policy's rules
+func validateOutputRules(rules []OutputRule) error {
+	if len(rules) == 0 {
+		return errors.New("rules must not be empty")
+	}
+	if len(rules) > maxOutputRules {
+		return fmt.Errorf("at most %d rules are allowed", maxOutputRules)
+	}
+	for i, rule := range rules {
+		switch rule.Type {
+		case outputRuleStopSequences:
+			if len(rule.Sequences) == 0 || len(rule.Sequences) > maxOutputStopSequences {
+				return fmt.Errorf("rules[%d]: sequences must have 1 to %d entries", i, maxOutputStopSequences)
+			}
+			for _, seq := range rule.Sequences {
+				if seq == "" || len(seq) > maxOutputStopLength {
+					return fmt.Errorf("rules[%d]: sequences must be 1 to %d bytes", i, maxOutputStopLength)
+				}
+			}
+		case outputRuleStripSystemEcho:
+		case outputRuleMaxLines:
+			if rule.MaxLines < 1 || rule.MaxLines > maxOutputLines {
+				return fmt.Errorf("rules[%d]: max_lines must be between 1 and %d", i, maxOutputLines)
+			}
+		case outputRuleRedact:
+			if rule.Pattern == "" || len(rule.Pattern) > maxOutputPatternLength {
+				return fmt.Errorf("rules[%d]: pattern must be 1 to %d bytes", i, maxOutputPatternLength)
+			}
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return fmt.Errorf("rules[%d]: invalid pattern: %v", i, err)
+			}
+		default:
+			return fmt.Errorf("rules[%d]: type must be one of stop_sequences, strip_system_echo, max_lines, redact", i)
+		}
+	}
+	return nil
+}
+
+// outputStage is one rule applied incrementally to streamed text. Write
+// returns the text that is safe to emit so far; Flush returns whatever is
+// still held back once the output is complete.
+type outputStage interface {
+	Write(s string) string
+	Flush() string
+	Stopped() bool
+}
+
+// outputChain runs a policy's stages over one choice of a response
+type outputChain struct {
+	stages  []outputStage
+	flushed bool
+}
+
+// newOutputChain builds the stages for rules. systemPrompt is what
+// strip_system_echo removes; without one the rule does nothing.
+func newOutputChain(rules []OutputRule, systemPrompt string) (*outputChain, error) {
+	c := &outputChain{}
+	for _, rule := range rules {
+		switch rule.Type {
+		case outputRuleStopSequences:
+			c.stages = append(c.stages, newStopStage(rule.Sequences))
+		case outputRuleStripSystemEcho:
+			if echo := strings.TrimSpace(systemPrompt); echo != "" {
+				c.stages = append(c.stages, &echoStage{echo: echo})
+			}
+		case outputRuleMaxLines:
+			c.stages = append(c.stages, &linesStage{max: rule.MaxLines})
+		case outputRuleRedact:
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			replacement := defaultRedaction
+			if rule.Replacement != nil {
+				replacement = *rule.Replacement
+			}
+			c.stages = append(c.stages, &redactStage{pattern: pattern, replacement: replacement})
+		}
+	}
+	return c, nil
+}
+
+// Write passes text through every stage. Once a stage stops the output,
+// text still held by the stages after it is released, since it came before
+// the stop point.
+func (c *outputChain) Write(s string) string {
+	if c.flushed {
+		return ""
+	}
+	for _, stage := range c.stages {
+		s = stage.Write(s)
+	}
+	if c.Truncated() {
+		return s + c.Flush()
+	}
+	return s
+}
+
+// Flush releases held-back text at the end of the output
+func (c *outputChain) Flush() string {
+	if c.flushed {
+		return ""
+	}
+	c.flushed = true
+	s := ""
+	for _, stage := range c.stages {
+		s = stage.Write(s) + stage.Flush()
+	}
+	return s
+}
+
+// Truncated reports whether a rule ended the output early
+func (c *outputChain) Truncated() bool {
+	for _, stage := range c.stages {
+		if stage.Stopped() {
+			return true
+		}
+	}
+	return false
+}
+
+// runeStart moves i back to the start of the UTF-8 sequence containing it,
+// so held-back text is never split inside a character
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// stopStage ends the output at the first stop sequence. It holds back
+// enough text to match a sequence split across chunks.
+type stopStage struct {
+	sequences []string
+	hold      int
+	pending   string
+	stopped   bool
+}
+
+func newStopStage(sequences []string) *stopStage {
+	hold := 0
+	for _, seq := range sequences {
+		if len(seq)-1 > hold {
+			hold = len(seq) - 1
+		}
+	}
+	return &stopStage{sequences: sequences, hold: hold}
+}
+
+func (s *stopStage) Write(text string) string {
+	if s.stopped {
+		return ""
+	}
+	s.pending += text
+
+	stop := -1
+	for _, seq := range s.sequences {
+		if i := strings.Index(s.pending, seq); i >= 0 && (stop < 0 || i < stop) {
+			stop = i
+		}
+	}
+	if stop >= 0 {
+		out := s.pending[:stop]
+		s.pending = ""
+		s.stopped = true
+		return out
+	}
+
+	if len(s.pending) <= s.hold {
+		return ""
+	}
+	cut := runeStart(s.pending, len(s.pending)-s.hold)
+	out := s.pending[:cut]
+	s.pending = s.pending[cut:]
+	return out
+}
+
+func (s *stopStage) Flush() string {
+	out := s.pending
+	s.pending = ""
+	return out
+}
+
+func (s *stopStage) Stopped() bool { return s.stopped }
+
+// echoStage strips the system prompt from the start of the output. It holds
+// text back only while the output could still be an echo.
+type echoStage struct {
+	echo    string
+	pending string
+	decided bool
+	// trimming drops whitespace after a stripped echo until text follows
+	trimming bool
+}
+
+func (s *echoStage) Write(text string) string {
+	if s.decided {
+		if s.trimming {
+			text = strings.TrimLeft(text, " \t\r\n")
+			s.trimming = text == ""
+		}
+		return text
+	}
+	s.pending += text
+
+	trimmed := strings.TrimLeft(s.pending, " \t\r\n")
+	if len(trimmed) < len(s.echo) && strings.HasPrefix(s.echo, trimmed) {
+		return ""
+	}
+
+	s.decided = true
+	out := s.pending
+	s.pending = ""
+	if strings.HasPrefix(trimmed, s.echo) {
+		out = strings.TrimLeft(trimmed[len(s.echo):], " \t\r\n")
+		s.trimming = out == ""
+	}
+	return out
+}
+
+func (s *echoStage) Flush() string {
+	s.decided = true
+	out := s.pending
+	s.pending = ""
+	return out
+}
+
+func (s *echoStage) Stopped() bool { return false }
+
+// linesStage ends the output after max lines
+type linesStage struct {
+	max     int
+	lines   int
+	stopped bool
+}
+
+func (s *linesStage) Write(text string) string {
+	if s.stopped {
+		return ""
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\n' {
+			continue
+		}
+		s.lines++
+		if s.lines >= s.max {
+			s.stopped = true
+			return text[:i]
+		}
+	}
+	return text
+}
+
+func (s *linesStage) Flush() string { return "" }
+
+func (s *linesStage) Stopped() bool { return s.stopped }
+
+// redactStage replaces pattern matches. Streamed text is redacted a line at
+// a time, so a match can't be split across chunks; long lines are released
+// at the last whitespace once redactHoldLimit is reached.
+type redactStage struct {
+	pattern     *regexp.Regexp
+	replacement string
+	pending     string
+}
+
+func (s *redactStage) Write(text string) string {
+	s.pending += text
+
+	cut := strings.LastIndexByte(s.pending, '\n') + 1
+	if cut == 0 && len(s.pending) > redactHoldLimit {
+		cut = strings.LastIndexAny(s.pending, " \t\r") + 1
+		if cut == 0 {
+			cut = len(s.pending)
+		}
+	}
+	if cut == 0 {
+		return ""
+	}
+
+	out := s.pending[:cut]
+	s.pending = s.pending[cut:]
+	return s.pattern.ReplaceAllLiteralString(out, s.replacement)
+}
+
+func (s *redactStage) Flush() string {
+	out := s.pending
+	s.pending = ""
+	return s.pattern.ReplaceAllLiteralString(out, s.replacement)
+}
+
+func (s *redactStage) Stopped() bool { return false }
+
+// outputRewriter applies a policy to the choices of chat or text completion
+// responses, keeping a chain per choice index for streams
+type outputRewriter struct {
+	rules        []OutputRule
+	systemPrompt string
+	chat         bool
+	chains       map[int]*outputChain
+}
+
+func newOutputRewriter(rules []OutputRule, systemPrompt string, chat bool) *outputRewriter {
+	return &outputRewriter{
+		rules:        rules,
+		systemPrompt: systemPrompt,
+		chat:         chat,
+		chains:       map[int]*outputChain{},
+	}
+}
+
+func (o *outputRewriter) chain(index int) (*outputChain, error) {
+	if c, ok := o.chains[index]; ok {
+		return c, nil
+	}
+	c, err := newOutputChain(o.rules, o.systemPrompt)
+	if err != nil {
+		return nil, err
+	}
+	o.chains[index] = c
+	return c, nil
+}
+
+// choiceText returns the object holding a choice's generated text and the
+// field name: message.content or delta.content for chat, text otherwise
+func (o *outputRewriter) choiceText(choice map[string]interface{}, stream bool) (map[string]interface{}, string) {
+	if !o.chat {
+		return choice, "text"
+	}
+	field := "message"
+	if stream {
+		field = "delta"
+	}
+	container, _ := choice[field].(map[string]interface{})
+	return container, "content"
+}
+
+// rewrite applies the rules to a response object. In a stream each call is
+// one chunk and held-back text is released when a choice finishes.
+func (o *outputRewriter) rewrite(data []byte, stream bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	choices, _ := obj["choices"].([]interface{})
+	for i, raw := range choices {
+		choice, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		index := i
+		if n, ok := choice["index"].(json.Number); ok {
+			if v, err := n.Int64(); err == nil {
+				index = int(v)
+			}
+		}
+		c, err := o.chain(index)
+		if err != nil {
+			return nil, err
+		}
+
+		container, field := o.choiceText(choice, stream)
+		text, hasText := "", false
+		if container != nil {
+			text, hasText = container[field].(string)
+		}
+
+		out := c.Write(text)
+		finished := !stream || choice["finish_reason"] != nil
+		if finished {
+			out += c.Flush()
+			if c.Truncated() {
+				choice["finish_reason"] = "stop"
+			}
+		}
+		if hasText || (container != nil && out != "") {
+			container[field] = out
+		}
+	}
+
+	return json.Marshal(obj)
+}
+
+// rewriteSSELine rewrites one line of a streamed response. Lines that are
+// not data chunks, and chunks that fail to parse, pass through unchanged.
+func (o *outputRewriter) rewriteSSELine(line []byte) []byte {
+	trimmed := bytes.TrimRight(line, "\r\n")
+	payload, ok := bytes.CutPrefix(trimmed, []byte("data:"))
+	if !ok {
+		return line
+	}
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || bytes.Equal(payload, []byte("[DONE]")) {
+		return line
+	}
+
+	rewritten, err := o.rewrite(payload, true)
+	if err != nil {
+		return line
+	}
+	out := make([]byte, 0, len(rewritten)+8)
+	out = append(out, "data: "...)
+	out = append(out, rewritten...)
+	return append(out, '\n')
+}
+
+// outputPolicyBody applies an outputRewriter to a response body as it is
+// read: line by line for event streams, whole for JSON responses
+type outputPolicyBody struct {
+	src      io.ReadCloser
+	reader   *bufio.Reader
+	rewriter *outputRewriter
+	stream   bool
+	buf      bytes.Buffer
+	err      error
+}
+
+func newOutputPolicyBody(src io.ReadCloser, rewriter *outputRewriter, stream bool) *outputPolicyBody {
+	return &outputPolicyBody{
+		src:      src,
+		reader:   bufio.NewReader(src),
+		rewriter: rewriter,
+		stream:   stream,
+	}
+}
+
+func (b *outputPolicyBody) Read(p []byte) (int, error) {
+	for b.buf.Len() == 0 && b.err == nil {
+		if b.stream {
+			line, err := b.reader.ReadBytes('\n')
+			if len(line) > 0 {
+				b.buf.Write(b.rewriter.rewriteSSELine(line))
+			}
+			b.err = err
+			continue
+		}
+
+		data, err := io.ReadAll(b.reader)
+		if err != nil {
+			b.err = err
+			b.buf.Write(data)
+			break
+		}
+		if rewritten, rerr := b.rewriter.rewrite(data, false); rerr == nil {
+			data = rewritten
+		}
+		b.buf.Write(data)
+		b.err = io.EOF
+	}
+
+	if b.buf.Len() > 0 {
+		return b.buf.Read(p)
+	}
+	return 0, b.err
+}
+
+func (b *outputPolicyBody) Close() error {
+	return b.src.Close()
+}
@@ -0,0 +1,245 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// runChain feeds text to a chain in chunks of size n
+func runChain(t *testing.T, rules []OutputRule, system, text string, n int) (string, bool) {
+	t.Helper()
+	c, err := newOutputChain(rules, system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	for len(text) > 0 {
+		k := n
+		if k > len(text) {
+			k = len(text)
+		}
+		out.WriteString(c.Write(text[:k]))
+		text = text[k:]
+	}
+	out.WriteString(c.Flush())
+	return out.String(), c.Truncated()
+}
+
+func TestOutputChain(t *testing.T) {
+	redact := OutputRule{Type: outputRuleRedact, Pattern: `\b\d{3}-\d{2}-\d{4}\b`}
+	blank := ""
+
+	tests := []struct {
+		name          string
+		rules         []OutputRule
+		system        string
+		text          string
+		want          string
+		wantTruncated bool
+	}{
+		{
+			name:          "stop sequence",
+			rules:         []OutputRule{{Type: outputRuleStopSequences, Sequences: []string{"###", "END"}}},
+			text:          "first part### second part",
+			want:          "first part",
+			wantTruncated: true,
+		},
+		{
+			name:  "no stop sequence",
+			rules: []OutputRule{{Type: outputRuleStopSequences, Sequences: []string{"###"}}},
+			text:  "nothing to stop ##",
+			want:  "nothing to stop ##",
+		},
+		{
+			name:   "system echo",
+			rules:  []OutputRule{{Type: outputRuleStripSystemEcho}},
+			system: "You are a helpful assistant.",
+			text:   "  You are a helpful assistant.\n\nHello!",
+			want:   "Hello!",
+		},
+		{
+			name:   "no echo",
+			rules:  []OutputRule{{Type: outputRuleStripSystemEcho}},
+			system: "You are a helpful assistant.",
+			text:   "You are welcome.",
+			want:   "You are welcome.",
+		},
+		{
+			name:          "max lines",
+			rules:         []OutputRule{{Type: outputRuleMaxLines, MaxLines: 2}},
+			text:          "one\ntwo\nthree\nfour",
+			want:          "one\ntwo",
+			wantTruncated: true,
+		},
+		{
+			name:  "redact",
+			rules: []OutputRule{redact},
+			text:  "SSN 123-45-6789 on file\nand 987-65-4321",
+			want:  "SSN [REDACTED] on file\nand [REDACTED]",
+		},
+		{
+			name:  "redact with empty replacement",
+			rules: []OutputRule{{Type: outputRuleRedact, Pattern: `secret`, Replacement: &blank}},
+			text:  "a secret b",
+			want:  "a  b",
+		},
+		{
+			name: "rules in order",
+			rules: []OutputRule{
+				{Type: outputRuleStripSystemEcho},
+				redact,
+				{Type: outputRuleMaxLines, MaxLines: 1},
+			},
+			system:        "Be brief.",
+			text:          "Be brief. 123-45-6789\nsecond line",
+			want:          "[REDACTED]",
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		// Chunk sizes cover whole responses and token-sized stream deltas
+		for _, n := range []int{1, 3, 1 << 20} {
+			got, truncated := runChain(t, tt.rules, tt.system, tt.text, n)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("%s (chunks of %d) = %q, %v; want %q, %v", tt.name, n, got, truncated, tt.want, tt.wantTruncated)
+			}
+		}
+	}
+}
+
+func TestOutputChainKeepsRunesWhole(t *testing.T) {
+	rules := []OutputRule{{Type: outputRuleStopSequences, Sequences: []string{"STOP"}}}
+	c, err := newOutputChain(rules, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []string
+	for _, chunk := range []string{"héllo wörld ", "日本語", "STOP"} {
+		parts = append(parts, c.Write(chunk))
+	}
+	parts = append(parts, c.Flush())
+	for _, p := range parts {
+		if !utf8.ValidString(p) {
+			t.Errorf("chunk %q is not valid UTF-8", p)
+		}
+	}
+	if got := strings.Join(parts, ""); got != "héllo wörld 日本語" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestValidateOutputRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []OutputRule
+		wantErr bool
+	}{
+		{"valid", []OutputRule{{Type: outputRuleMaxLines, MaxLines: 5}, {Type: outputRuleStripSystemEcho}}, false},
+		{"empty", nil, true},
+		{"unknown type", []OutputRule{{Type: "uppercase"}}, true},
+		{"no sequences", []OutputRule{{Type: outputRuleStopSequences}}, true},
+		{"zero lines", []OutputRule{{Type: outputRuleMaxLines}}, true},
+		{"bad pattern", []OutputRule{{Type: outputRuleRedact, Pattern: "("}}, true},
+	}
+	for _, tt := range tests {
+		if err := validateOutputRules(tt.rules); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateOutputRules() error = %v", tt.name, err)
+		}
+	}
+}
+
+func TestSelectOutputPolicy(t *testing.T) {
+	keyID := uuid.New()
+	otherKey := uuid.New()
+	model := "llama-3-8b"
+	other := "mistral-7b"
+
+	policies := []OutputPolicy{
+		{ID: uuid.New(), Enabled: true},
+		{ID: uuid.New(), Model: &model, Enabled: true},
+		{ID: uuid.New(), APIKeyID: &keyID, Enabled: true},
+		{ID: uuid.New(), APIKeyID: &keyID, Model: &model, Enabled: false},
+		{ID: uuid.New(), APIKeyID: &otherKey, Model: &other, Enabled: true},
+	}
+
+	if got := selectOutputPolicy(policies, &keyID, model); got != &policies[2] {
+		t.Errorf("key policy not preferred over model policy: %+v", got)
+	}
+	if got := selectOutputPolicy(policies, nil, model); got != &policies[1] {
+		t.Errorf("model policy not selected: %+v", got)
+	}
+	if got := selectOutputPolicy(policies, nil, other); got != &policies[0] {
+		t.Errorf("tenant-wide policy not selected: %+v", got)
+	}
+	if got := selectOutputPolicy(policies[1:2], nil, other); got != nil {
+		t.Errorf("selectOutputPolicy() = %+v, want nil", got)
+	}
+}
+
+func TestOutputPolicyBodyStream(t *testing.T) {
+	rules := []OutputRule{{Type: outputRuleStopSequences, Sequences: []string{"STOP"}}}
+	stream := strings.Join([]string{
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello ST"}}]}`,
+		``,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"OP ignored"}}]}`,
+		``,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"length"}],"usage":{"completion_tokens":12}}`,
+		``,
+		`data: [DONE]`,
+		``,
+	}, "\n")
+
+	body := newOutputPolicyBody(io.NopCloser(strings.NewReader(stream)), newOutputRewriter(rules, "", true), true)
+	out, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var content strings.Builder
+	var finish string
+	for _, line := range strings.Split(string(out), "\n") {
+		payload, ok := strings.CutPrefix(line, "data: ")
+		if !ok || payload == "[DONE]" {
+			continue
+		}
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", payload, err)
+		}
+		for _, c := range chunk.Choices {
+			if c.Delta.Content != nil {
+				content.WriteString(*c.Delta.Content)
+			}
+			if c.FinishReason != nil {
+				finish = *c.FinishReason
+			}
+		}
+	}
+
+	if content.String() != "Hello " || finish != "stop" {
+		t.Errorf("content = %q, finish_reason = %q", content.String(), finish)
+	}
+	if !strings.Contains(string(out), `"completion_tokens":12`) || !strings.HasSuffix(string(out), "data: [DONE]\n") {
+		t.Errorf("stream = %s", out)
+	}
+}
+
+func TestOutputPolicyBodyJSON(t *testing.T) {
+	rules := []OutputRule{{Type: outputRuleRedact, Pattern: `sk-[a-z0-9]+`}}
+	resp := `{"id":"cmpl-1","choices":[{"index":0,"text":"key sk-abc123 here","finish_reason":"stop"}],"usage":{"total_tokens":9}}`
+
+	body := newOutputPolicyBody(io.NopCloser(strings.NewReader(resp)), newOutputRewriter(rules, "", false), false)
+	out, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"text":"key [REDACTED] here"`) || !strings.Contains(string(out), `"total_tokens":9`) {
+		t.Errorf("response = %s", out)
+	}
+}
@@ -0,0 +1,391 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"go.uber.org/zap"
+)
+
+// Public playground.
+//
+// The marketing site's try-it-now page sends chat completions to
+// POST /playground/chat/completions without an API key. Requests are pinned
+// to one small model, limited to a few short text messages and a low
+// max_tokens, need a fresh captcha token and are rate limited per client IP,
+// per client network and platform-wide. They are served by the model's
+// shared nodes at low priority but never attributed to a tenant: nothing is
+// billed, metered against a quota or recorded as usage.
+const (
+	// playgroundMaxBodyBytes caps the request body
+	playgroundMaxBodyBytes = 16 * 1024
+	// playgroundMaxMessages caps the messages of one conversation
+	playgroundMaxMessages = 10
+	// playgroundNetworkFactor scales the per-IP limits into limits for the
+	// client's /16 or /48 network, so rotating addresses doesn't help much
+	playgroundNetworkFactor = 10
+	// playgroundCaptchaFailureLimit is how many failed captchas from one IP
+	// within playgroundBlockDuration block it
+	playgroundCaptchaFailureLimit = 10
+	// playgroundBlockDuration is how long a blocked IP is refused
+	playgroundBlockDuration = time.Hour
+	// playgroundCaptchaTimeout bounds the call to the captcha provider
+	playgroundCaptchaTimeout = 5 * time.Second
+)
+
+// Playground outcomes, as counted in gateway_playground_requests_total
+const (
+	playgroundServed        = "served"
+	playgroundInvalid       = "invalid"
+	playgroundRateLimited   = "rate_limited"
+	playgroundBlocked       = "blocked"
+	playgroundCaptchaFailed = "captcha_failed"
+	playgroundUnavailable   = "unavailable"
+	playgroundUpstreamError = "upstream_error"
+)
+
+// PlaygroundLimits are the quotas of the public playground
+type PlaygroundLimits struct {
+	MaxTokens               int // Cap on max_tokens of a completion
+	RequestsPerMinute       int // Per client IP
+	RequestsPerDay          int // Per client IP
+	GlobalRequestsPerMinute int // Across all clients
+}
+
+// Playground serves anonymous chat completions on one model
+type Playground struct {
+	model         string
+	limits        PlaygroundLimits
+	captchaSecret string
+	captchaURL    string
+	client        *http.Client
+}
+
+// NewPlayground creates the public playground for model. Every request must
+// carry a captcha token, verified against captchaURL with captchaSecret.
+func NewPlayground(model, captchaSecret, captchaURL string, limits PlaygroundLimits) (*Playground, error) {
+	if model == "" {
+		return nil, errors.New("playground model is not set")
+	}
+	if captchaSecret == "" {
+		return nil, errors.New("playground captcha secret is not set")
+	}
+	if _, err := url.ParseRequestURI(captchaURL); err != nil {
+		return nil, fmt.Errorf("invalid playground captcha verify URL: %w", err)
+	}
+	if limits.MaxTokens <= 0 || limits.RequestsPerMinute <= 0 || limits.RequestsPerDay <= 0 || limits.GlobalRequestsPerMinute <= 0 {
+		return nil, errors.New("playground limits must be positive")
+	}
+	return &Playground{
+		model:         model,
+		limits:        limits,
+		captchaSecret: captchaSecret,
+		captchaURL:    captchaURL,
+		client:        &http.Client{Timeout: playgroundCaptchaTimeout},
+	}, nil
+}
+
+// playgroundRequest is the body of POST /playground/chat/completions. Only
+// these fields reach the node; tools, logprobs, n and the like are dropped.
+type playgroundRequest struct {
+	Model       string                  `json:"model"`
+	Messages    []ChatCompletionMessage `json:"messages"`
+	Temperature *float64                `json:"temperature,omitempty"`
+	MaxTokens   *int                    `json:"max_tokens,omitempty"`
+	Stream      bool                    `json:"stream,omitempty"`
+}
+
+// nodeBody validates a playground request and returns the body sent to the
+// node, pinned to the playground model with max_tokens capped
+func (p *Playground) nodeBody(body []byte) ([]byte, error) {
+	var req playgroundRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, errors.New("invalid request body")
+	}
+	if req.Model != "" && req.Model != p.model {
+		return nil, fmt.Errorf("the playground only serves %s", p.model)
+	}
+	if len(req.Messages) == 0 {
+		return nil, errors.New("messages are required")
+	}
+	if len(req.Messages) > playgroundMaxMessages {
+		return nil, fmt.Errorf("the playground accepts at most %d messages", playgroundMaxMessages)
+	}
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system", "user", "assistant":
+		default:
+			return nil, fmt.Errorf("unsupported message role %q", m.Role)
+		}
+		// Text only: no images or other content parts
+		var text string
+		if err := json.Unmarshal(m.Content, &text); err != nil {
+			return nil, errors.New("message content must be a string")
+		}
+	}
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
+		return nil, errors.New("temperature must be between 0 and 2")
+	}
+
+	maxTokens := p.limits.MaxTokens
+	if req.MaxTokens != nil && *req.MaxTokens > 0 && *req.MaxTokens < maxTokens {
+		maxTokens = *req.MaxTokens
+	}
+	req.Model = p.model
+	req.MaxTokens = &maxTokens
+	return json.Marshal(req)
+}
+
+// playgroundCounter increments a rate limit counter and reports whether it
+// is still within limit
+func playgroundCounter(ctx context.Context, c *cache.Cache, key string, window time.Duration, limit int64) (bool, error) {
+	count, err := c.Incr(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		c.Expire(ctx, key, window+5*time.Second)
+	}
+	return count <= limit, nil
+}
+
+// allow applies the playground's rate limits to a client. It returns the
+// outcome when the request is refused and how long until it may retry.
+func (p *Playground) allow(ctx context.Context, c *cache.Cache, ip string, now time.Time) (string, time.Duration, error) {
+	blocked, err := c.Exists(ctx, cache.PlatformKey(cache.NamespacePlayground, "blocked", ip))
+	if err != nil {
+		return "", 0, err
+	}
+	if blocked > 0 {
+		return playgroundBlocked, playgroundBlockDuration, nil
+	}
+
+	minute := now.Format(minuteFormat)
+	day := now.Format(dayFormat)
+	network := clientNetwork(ip)
+	untilMinute := now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+	untilDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()).Sub(now)
+
+	checks := []struct {
+		key    string
+		window time.Duration
+		limit  int
+		retry  time.Duration
+	}{
+		{cache.PlatformKey(cache.NamespacePlayground, "global", "minute", minute), time.Minute, p.limits.GlobalRequestsPerMinute, untilMinute},
+		{cache.PlatformKey(cache.NamespacePlayground, "ip", ip, "minute", minute), time.Minute, p.limits.RequestsPerMinute, untilMinute},
+		{cache.PlatformKey(cache.NamespacePlayground, "ip", ip, "day", day), 24 * time.Hour, p.limits.RequestsPerDay, untilDay},
+		{cache.PlatformKey(cache.NamespacePlayground, "network", network, "minute", minute), time.Minute, p.limits.RequestsPerMinute * playgroundNetworkFactor, untilMinute},
+	}
+	for _, check := range checks {
+		ok, err := playgroundCounter(ctx, c, check.key, check.window, int64(check.limit))
+		if err != nil {
+			return "", 0, err
+		}
+		if !ok {
+			return playgroundRateLimited, check.retry, nil
+		}
+	}
+	return "", 0, nil
+}
+
+// recordCaptchaFailure counts a failed captcha and blocks the IP once it
+// fails too often
+func (p *Playground) recordCaptchaFailure(ctx context.Context, c *cache.Cache, ip string) (bool, error) {
+	key := cache.PlatformKey(cache.NamespacePlayground, "captcha_failures", ip)
+	ok, err := playgroundCounter(ctx, c, key, playgroundBlockDuration, playgroundCaptchaFailureLimit)
+	if err != nil || ok {
+		return false, err
+	}
+	if err := c.Set(ctx, cache.PlatformKey(cache.NamespacePlayground, "blocked", ip), "1", playgroundBlockDuration); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifyCaptcha checks a captcha token with the provider's siteverify
+// endpoint (Cloudflare Turnstile and hCaptcha share its shape)
+func (p *Playground) verifyCaptcha(ctx context.Context, token, ip string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	form := url.Values{
+		"secret":   {p.captchaSecret},
+		"response": {token},
+		"remoteip": {ip},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.captchaURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("captcha verification failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("captcha verification returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return false, fmt.Errorf("invalid captcha verification response: %w", err)
+	}
+	return result.Success, nil
+}
+
+// handlePlaygroundInfo describes the playground for the try-it-now page
+// Public API - GET /playground
+func (g *Gateway) handlePlaygroundInfo(w http.ResponseWriter, r *http.Request) {
+	if g.Playground == nil {
+		g.writeError(w, http.StatusNotFound, "the playground is not enabled")
+		return
+	}
+	p := g.Playground
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"model":               p.model,
+		"max_tokens":          p.limits.MaxTokens,
+		"max_messages":        playgroundMaxMessages,
+		"requests_per_minute": p.limits.RequestsPerMinute,
+		"requests_per_day":    p.limits.RequestsPerDay,
+	})
+}
+
+// handlePlaygroundChat serves an anonymous chat completion on the playground
+// model. The captcha token is sent in the X-Captcha-Token header.
+// Public API - POST /playground/chat/completions
+func (g *Gateway) handlePlaygroundChat(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	p := g.Playground
+	if p == nil {
+		g.writeError(w, http.StatusNotFound, "the playground is not enabled")
+		return
+	}
+	// Quotas and blocks apply to the TCP peer, or the client a trusted
+	// proxy forwarded the request for; other clients can't pick their
+	// address with X-Forwarded-For
+	ip := clientIP(r)
+
+	// Rate limits come before the captcha so floods don't reach the provider
+	outcome, retry, err := p.allow(ctx, g.cache, ip, time.Now())
+	if err != nil {
+		// The playground is public: without counters it stays closed
+		g.logger.Warn("playground rate limit check failed", zap.Error(err))
+		playgroundRequests.WithLabelValues(playgroundUnavailable).Inc()
+		g.writeError(w, http.StatusServiceUnavailable, "the playground is temporarily unavailable")
+		return
+	}
+	if outcome != "" {
+		playgroundRequests.WithLabelValues(outcome).Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(int(retry.Round(time.Second).Seconds())))
+		if outcome == playgroundBlocked {
+			g.writeError(w, http.StatusForbidden, "too many failed captchas; try again later")
+			return
+		}
+		g.writeError(w, http.StatusTooManyRequests, "playground rate limit exceeded; sign up for an API key for higher limits")
+		return
+	}
+
+	ok, err := p.verifyCaptcha(ctx, r.Header.Get("X-Captcha-Token"), ip)
+	if err != nil {
+		g.logger.Warn("playground captcha verification failed", zap.Error(err))
+		playgroundRequests.WithLabelValues(playgroundUnavailable).Inc()
+		g.writeError(w, http.StatusServiceUnavailable, "captcha verification is temporarily unavailable")
+		return
+	}
+	if !ok {
+		playgroundRequests.WithLabelValues(playgroundCaptchaFailed).Inc()
+		if blocked, err := p.recordCaptchaFailure(ctx, g.cache, ip); err != nil {
+			g.logger.Warn("failed to record playground captcha failure", zap.Error(err))
+		} else if blocked {
+			g.logger.Warn("playground client blocked after repeated captcha failures", zap.String("ip", ip))
+		}
+		g.writeError(w, http.StatusForbidden, "captcha verification failed")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, playgroundMaxBodyBytes+1))
+	r.Body.Close()
+	if err != nil {
+		playgroundRequests.WithLabelValues(playgroundInvalid).Inc()
+		g.writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	if len(body) > playgroundMaxBodyBytes {
+		playgroundRequests.WithLabelValues(playgroundInvalid).Inc()
+		g.writeError(w, http.StatusRequestEntityTooLarge, "the playground accepts requests up to 16KB")
+		return
+	}
+	body, err = p.nodeBody(body)
+	if err != nil {
+		playgroundRequests.WithLabelValues(playgroundInvalid).Inc()
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !g.allowModelRequest(w, r, p.model) {
+		playgroundRequests.WithLabelValues(playgroundUnavailable).Inc()
+		return
+	}
+	endpoint, err := g.LoadBalancer.SelectEndpoint(ctx, p.model)
+	if err != nil || endpoint == "" {
+		playgroundRequests.WithLabelValues(playgroundUnavailable).Inc()
+		g.writeError(w, http.StatusServiceUnavailable, "the playground is temporarily unavailable")
+		return
+	}
+	// Playground traffic yields to tenants on busy nodes
+	if !g.applyBackpressure(w, endpoint, true) {
+		playgroundRequests.WithLabelValues(playgroundUnavailable).Inc()
+		return
+	}
+
+	// A stream's node request is cancelled when the client goes away
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Only the sanitized body is sent: the client's headers and cookies
+	// never reach the node
+	nodeReq := r.Clone(ctx)
+	nodeReq.URL.Path = "/v1/chat/completions"
+	nodeReq.Header = http.Header{"Content-Type": {"application/json"}}
+	nodeReq.Body = io.NopCloser(bytes.NewReader(body))
+	nodeReq.ContentLength = int64(len(body))
+
+	start := time.Now()
+	resp, err := g.proxyRequest(endpoint, nodeReq)
+	duration := time.Since(start)
+
+	isError := err != nil || (resp != nil && resp.StatusCode >= 500)
+	g.LoadBalancer.RecordRequest(endpoint, duration, isError)
+	g.recordModelOutcome(ctx, p.model, isError)
+	g.trackNodeRequest(nodeReq, endpoint, p.model, start, resp, err)
+
+	if err != nil {
+		g.logger.Warn("failed to proxy playground request", zap.Error(err))
+		playgroundRequests.WithLabelValues(playgroundUpstreamError).Inc()
+		g.writeError(w, http.StatusBadGateway, "the playground is temporarily unavailable")
+		return
+	}
+	defer resp.Body.Close()
+
+	if isError {
+		playgroundRequests.WithLabelValues(playgroundUpstreamError).Inc()
+	} else {
+		playgroundRequests.WithLabelValues(playgroundServed).Inc()
+	}
+	g.relayProxiedResponse(w, resp, cancel)
+}
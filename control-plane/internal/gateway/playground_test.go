@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"go.uber.org/zap"
+)
+
+func testPlayground(t *testing.T, captchaURL string) *Playground {
+	t.Helper()
+	p, err := NewPlayground("llama-3.2-1b", "secret", captchaURL, PlaygroundLimits{
+		MaxTokens:               256,
+		RequestsPerMinute:       5,
+		RequestsPerDay:          50,
+		GlobalRequestsPerMinute: 300,
+	})
+	if err != nil {
+		t.Fatalf("NewPlayground() error = %v", err)
+	}
+	return p
+}
+
+func TestNewPlaygroundValidation(t *testing.T) {
+	limits := PlaygroundLimits{MaxTokens: 256, RequestsPerMinute: 5, RequestsPerDay: 50, GlobalRequestsPerMinute: 300}
+
+	if _, err := NewPlayground("", "secret", "https://captcha.example/verify", limits); err == nil {
+		t.Error("expected error without a model")
+	}
+	if _, err := NewPlayground("m", "", "https://captcha.example/verify", limits); err == nil {
+		t.Error("expected error without a captcha secret")
+	}
+	if _, err := NewPlayground("m", "secret", "not a url", limits); err == nil {
+		t.Error("expected error for an invalid verify URL")
+	}
+	limits.RequestsPerDay = 0
+	if _, err := NewPlayground("m", "secret", "https://captcha.example/verify", limits); err == nil {
+		t.Error("expected error for a zero limit")
+	}
+}
+
+func TestPlaygroundNodeBody(t *testing.T) {
+	p := testPlayground(t, "https://captcha.example/verify")
+
+	tests := []struct {
+		name          string
+		body          string
+		wantErr       string
+		wantMaxTokens int
+	}{
+		{"defaults to the cap", `{"messages":[{"role":"user","content":"hi"}]}`, "", 256},
+		{"lower max_tokens kept", `{"model":"llama-3.2-1b","max_tokens":50,"messages":[{"role":"user","content":"hi"}]}`, "", 50},
+		{"higher max_tokens capped", `{"max_tokens":4096,"messages":[{"role":"user","content":"hi"}]}`, "", 256},
+		{"other model", `{"model":"llama-3.1-405b","messages":[{"role":"user","content":"hi"}]}`, "only serves", 0},
+		{"no messages", `{"messages":[]}`, "messages are required", 0},
+		{"content parts", `{"messages":[{"role":"user","content":[{"type":"image_url"}]}]}`, "must be a string", 0},
+		{"tool role", `{"messages":[{"role":"tool","content":"x"}]}`, "unsupported message role", 0},
+		{"temperature", `{"temperature":5,"messages":[{"role":"user","content":"hi"}]}`, "temperature", 0},
+		{"malformed", `{`, "invalid request body", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.nodeBody([]byte(tt.body))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("nodeBody() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("nodeBody() error = %v", err)
+			}
+			var req map[string]interface{}
+			if err := json.Unmarshal(got, &req); err != nil {
+				t.Fatalf("nodeBody() returned invalid JSON: %v", err)
+			}
+			if req["model"] != "llama-3.2-1b" {
+				t.Errorf("model = %v, want llama-3.2-1b", req["model"])
+			}
+			if req["max_tokens"] != float64(tt.wantMaxTokens) {
+				t.Errorf("max_tokens = %v, want %d", req["max_tokens"], tt.wantMaxTokens)
+			}
+		})
+	}
+}
+
+func TestPlaygroundNodeBodyDropsOtherFields(t *testing.T) {
+	p := testPlayground(t, "https://captcha.example/verify")
+
+	got, err := p.nodeBody([]byte(`{"messages":[{"role":"user","content":"hi"}],"n":20,"tools":[{"type":"function"}],"user":"x"}`))
+	if err != nil {
+		t.Fatalf("nodeBody() error = %v", err)
+	}
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(got, &req); err != nil {
+		t.Fatalf("nodeBody() returned invalid JSON: %v", err)
+	}
+	for _, field := range []string{"n", "tools", "user"} {
+		if _, ok := req[field]; ok {
+			t.Errorf("nodeBody() kept %s: %s", field, got)
+		}
+	}
+}
+
+func TestPlaygroundVerifyCaptcha(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm() error = %v", err)
+		}
+		if r.PostForm.Get("secret") != "secret" || r.PostForm.Get("remoteip") != "203.0.113.7" {
+			t.Errorf("unexpected form %v", r.PostForm)
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"success": r.PostForm.Get("response") == "good"})
+	}))
+	defer server.Close()
+	p := testPlayground(t, server.URL)
+
+	for token, want := range map[string]bool{"good": true, "bad": false} {
+		ok, err := p.verifyCaptcha(context.Background(), token, "203.0.113.7")
+		if err != nil {
+			t.Fatalf("verifyCaptcha(%q) error = %v", token, err)
+		}
+		if ok != want {
+			t.Errorf("verifyCaptcha(%q) = %v, want %v", token, ok, want)
+		}
+	}
+
+	if ok, err := p.verifyCaptcha(context.Background(), "", "203.0.113.7"); ok || err != nil {
+		t.Errorf("verifyCaptcha(\"\") = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestPlaygroundIgnoresSpoofedForwardedFor(t *testing.T) {
+	cacheClient, cleanup := setupLimiterCache(t)
+	defer cleanup()
+
+	g := &Gateway{cache: cacheClient, logger: zap.NewNop(), Playground: testPlayground(t, "https://captcha.example/verify")}
+	ctx := context.Background()
+	if err := cacheClient.Set(ctx, cache.PlatformKey(cache.NamespacePlayground, "blocked", "203.0.113.7"), "1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, forwardedFor := range []string{"198.51.100.1", "198.51.100.2, 10.0.0.1"} {
+		req := httptest.NewRequest(http.MethodPost, "/playground/chat/completions", strings.NewReader(`{"messages":[]}`))
+		req.RemoteAddr = "203.0.113.7:51234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		g.realIP(http.HandlerFunc(g.handlePlaygroundChat)).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "too many failed captchas") {
+			t.Errorf("blocked peer claiming %s got %d %s, want the block to hold", forwardedFor, rec.Code, rec.Body.String())
+		}
+	}
+}
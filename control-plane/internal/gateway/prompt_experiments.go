@@ -0,0 +1,722 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Prompt experiments.
+//
+// A tenant registers system prompt variants for a model (or for all its
+// models) with the percentage of traffic each should get. The gateway draws
+// a variant for every chat completion, rewrites the request's system prompt
+// from the variant's template and tags the request with the variant, so
+// prompts can be A/B tested without client changes. A variant with an empty
+// template leaves requests unchanged and serves as the control.
+//
+// Templates replace the client's system messages; "{{original}}" in a
+// template is replaced with the text of those messages, so a variant can
+// wrap the client's prompt instead of discarding it.
+//
+// Each replica counts the outcomes of the requests it served per variant
+// and adds them to the variant rows every promptExperimentFlushInterval.
+const (
+	// promptExperimentsCacheTTL bounds how long a tenant's running
+	// experiments are cached
+	promptExperimentsCacheTTL = 30 * time.Second
+	// promptExperimentFlushInterval is how often variant counts are saved
+	promptExperimentFlushInterval = 30 * time.Second
+	// maxPromptVariants caps the variants of one experiment
+	maxPromptVariants = 10
+	// maxPromptTemplateBytes caps a variant's system prompt template
+	maxPromptTemplateBytes = 32 * 1024
+	// promptOriginalPlaceholder is replaced with the client's system prompt
+	promptOriginalPlaceholder = "{{original}}"
+)
+
+var promptVariantNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// PromptVariant is one system prompt an experiment assigns requests to
+type PromptVariant struct {
+	ID           uuid.UUID             `json:"id"`
+	Name         string                `json:"name"`
+	SystemPrompt string                `json:"system_prompt"`
+	Percent      int                   `json:"percent"`
+	Metrics      *PromptVariantMetrics `json:"metrics,omitempty"`
+}
+
+// PromptVariantMetrics aggregates the responses a variant produced. Token
+// averages cover the responses that reported usage; streaming responses
+// only do when the client asks for it.
+type PromptVariantMetrics struct {
+	Requests            int64   `json:"requests"`
+	Errors              int64   `json:"errors"`
+	ErrorRate           float64 `json:"error_rate"`
+	AvgLatencyMs        float64 `json:"avg_latency_ms"`
+	UsageRequests       int64   `json:"usage_requests"`
+	AvgPromptTokens     float64 `json:"avg_prompt_tokens"`
+	AvgCompletionTokens float64 `json:"avg_completion_tokens"`
+}
+
+// PromptExperiment splits a tenant's chat traffic across prompt variants.
+// A nil Model applies to every model without an experiment of its own.
+type PromptExperiment struct {
+	ID        uuid.UUID       `json:"id"`
+	Name      string          `json:"name"`
+	Model     *string         `json:"model,omitempty"`
+	Status    string          `json:"status"`
+	Variants  []PromptVariant `json:"variants"`
+	CreatedAt time.Time       `json:"created_at"`
+	StoppedAt *time.Time      `json:"stopped_at,omitempty"`
+}
+
+// validatePromptVariants checks variant names, templates and that the
+// percentages split all traffic
+func validatePromptVariants(variants []PromptVariant) error {
+	if len(variants) < 2 {
+		return errors.New("an experiment needs at least 2 variants")
+	}
+	if len(variants) > maxPromptVariants {
+		return fmt.Errorf("an experiment can have at most %d variants", maxPromptVariants)
+	}
+	names := map[string]bool{}
+	total := 0
+	for _, v := range variants {
+		if !promptVariantNamePattern.MatchString(v.Name) {
+			return fmt.Errorf("invalid variant name %q", v.Name)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("duplicate variant name %q", v.Name)
+		}
+		names[v.Name] = true
+		if len(v.SystemPrompt) > maxPromptTemplateBytes {
+			return fmt.Errorf("system_prompt of variant %q exceeds %d bytes", v.Name, maxPromptTemplateBytes)
+		}
+		if v.Percent < 0 || v.Percent > 100 {
+			return fmt.Errorf("percent of variant %q must be between 0 and 100", v.Name)
+		}
+		total += v.Percent
+	}
+	if total != 100 {
+		return fmt.Errorf("variant percentages must add up to 100, got %d", total)
+	}
+	return nil
+}
+
+// selectPromptExperiment returns the running experiment for a model,
+// preferring one for the model itself over a tenant-wide one
+func selectPromptExperiment(experiments []PromptExperiment, model string) *PromptExperiment {
+	var fallback *PromptExperiment
+	for i := range experiments {
+		e := &experiments[i]
+		if e.Model == nil {
+			fallback = e
+		} else if *e.Model == model {
+			return e
+		}
+	}
+	return fallback
+}
+
+// pickPromptVariant returns the variant a draw in [0, 100) falls into
+func pickPromptVariant(variants []PromptVariant, draw int) *PromptVariant {
+	for i := range variants {
+		if draw < variants[i].Percent {
+			return &variants[i]
+		}
+		draw -= variants[i].Percent
+	}
+	return nil
+}
+
+// renderPromptVariant replaces the system messages of a chat request body
+// with the variant template. An empty template leaves the body unchanged.
+func renderPromptVariant(body []byte, template string) ([]byte, error) {
+	if template == "" {
+		return body, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	var messages []map[string]json.RawMessage
+	if err := json.Unmarshal(fields["messages"], &messages); err != nil {
+		return nil, err
+	}
+
+	var original []string
+	kept := make([]map[string]json.RawMessage, 0, len(messages)+1)
+	kept = append(kept, nil)
+	for _, m := range messages {
+		var role string
+		json.Unmarshal(m["role"], &role)
+		if role == "system" {
+			if text := contentText(m["content"]); text != "" {
+				original = append(original, text)
+			}
+			continue
+		}
+		kept = append(kept, m)
+	}
+
+	prompt := strings.ReplaceAll(template, promptOriginalPlaceholder, strings.Join(original, "\n"))
+	content, _ := json.Marshal(prompt)
+	kept[0] = map[string]json.RawMessage{
+		"role":    json.RawMessage(`"system"`),
+		"content": content,
+	}
+
+	encoded, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	fields["messages"] = encoded
+	return json.Marshal(fields)
+}
+
+// promptAssignment is the variant a request was assigned to, stored on the
+// request context as "prompt_variant"
+type promptAssignment struct {
+	ExperimentID uuid.UUID
+	VariantID    uuid.UUID
+	Variant      string
+}
+
+// applyPromptExperiment assigns a chat request to a variant of the tenant's
+// running experiment for the model and rewrites its system prompt.
+// Experiments that can't be loaded or applied leave the request unchanged.
+func (g *Gateway) applyPromptExperiment(w http.ResponseWriter, r *http.Request, model string, body []byte) (*http.Request, []byte) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		return r, body
+	}
+
+	experiments, err := g.loadRunningPromptExperiments(ctx, tenantID)
+	if err != nil {
+		g.logger.Warn("failed to load prompt experiments", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		return r, body
+	}
+	experiment := selectPromptExperiment(experiments, model)
+	if experiment == nil {
+		return r, body
+	}
+	variant := pickPromptVariant(experiment.Variants, rand.Intn(100))
+	if variant == nil {
+		return r, body
+	}
+
+	rendered, err := renderPromptVariant(body, variant.SystemPrompt)
+	if err != nil {
+		g.logger.Warn("failed to apply prompt variant",
+			zap.Error(err),
+			zap.String("experiment_id", experiment.ID.String()),
+			zap.String("variant", variant.Name),
+		)
+		return r, body
+	}
+
+	w.Header().Set("X-Prompt-Experiment", experiment.ID.String())
+	w.Header().Set("X-Prompt-Variant", variant.Name)
+
+	ctx = context.WithValue(ctx, "prompt_variant", &promptAssignment{
+		ExperimentID: experiment.ID,
+		VariantID:    variant.ID,
+		Variant:      variant.Name,
+	})
+	return r.WithContext(ctx), rendered
+}
+
+// trackPromptVariant counts the outcome of a request assigned to a prompt
+// variant once its response has been relayed
+func (g *Gateway) trackPromptVariant(r *http.Request, start time.Time, resp *http.Response, proxyErr error) {
+	assignment, ok := r.Context().Value("prompt_variant").(*promptAssignment)
+	if !ok {
+		return
+	}
+	if proxyErr != nil || resp == nil {
+		g.promptCounts.add(assignment.VariantID, time.Since(start), nil, nil, true)
+		return
+	}
+
+	resp.Body = &nodeResponseBody{
+		ReadCloser: resp.Body,
+		onClose: func(tail []byte, readErr error) {
+			g.promptCounts.add(assignment.VariantID, time.Since(start),
+				lastTokenCount(promptTokensPattern, tail),
+				lastTokenCount(completionTokensPattern, tail),
+				readErr != nil || resp.StatusCode >= 400,
+			)
+		},
+	}
+}
+
+// promptVariantCount is the outcome of requests one replica served for a
+// variant since the last flush
+type promptVariantCount struct {
+	Requests         int64
+	Errors           int64
+	LatencyMsSum     int64
+	UsageRequests    int64
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+func (c *promptVariantCount) addCount(o *promptVariantCount) {
+	c.Requests += o.Requests
+	c.Errors += o.Errors
+	c.LatencyMsSum += o.LatencyMsSum
+	c.UsageRequests += o.UsageRequests
+	c.PromptTokens += o.PromptTokens
+	c.CompletionTokens += o.CompletionTokens
+}
+
+// metrics turns totals into averages
+func (c *promptVariantCount) metrics() *PromptVariantMetrics {
+	m := &PromptVariantMetrics{
+		Requests:      c.Requests,
+		Errors:        c.Errors,
+		UsageRequests: c.UsageRequests,
+	}
+	if c.Requests > 0 {
+		m.ErrorRate = float64(c.Errors) / float64(c.Requests)
+		m.AvgLatencyMs = float64(c.LatencyMsSum) / float64(c.Requests)
+	}
+	if c.UsageRequests > 0 {
+		m.AvgPromptTokens = float64(c.PromptTokens) / float64(c.UsageRequests)
+		m.AvgCompletionTokens = float64(c.CompletionTokens) / float64(c.UsageRequests)
+	}
+	return m
+}
+
+// promptVariantCounts holds this replica's unflushed variant counts
+type promptVariantCounts struct {
+	mu      sync.Mutex
+	pending map[uuid.UUID]*promptVariantCount
+}
+
+func (p *promptVariantCounts) add(variantID uuid.UUID, latency time.Duration, promptTokens, completionTokens *int, isError bool) {
+	count := &promptVariantCount{Requests: 1, LatencyMsSum: latency.Milliseconds()}
+	if isError {
+		count.Errors = 1
+	}
+	if promptTokens != nil || completionTokens != nil {
+		count.UsageRequests = 1
+		count.PromptTokens = int64(derefInt(promptTokens))
+		count.CompletionTokens = int64(derefInt(completionTokens))
+	}
+	p.merge(map[uuid.UUID]*promptVariantCount{variantID: count})
+}
+
+// merge adds counts to the pending ones
+func (p *promptVariantCounts) merge(counts map[uuid.UUID]*promptVariantCount) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pending == nil {
+		p.pending = make(map[uuid.UUID]*promptVariantCount)
+	}
+	for id, count := range counts {
+		if existing, ok := p.pending[id]; ok {
+			existing.addCount(count)
+		} else {
+			p.pending[id] = count
+		}
+	}
+}
+
+// take removes and returns the pending counts
+func (p *promptVariantCounts) take() map[uuid.UUID]*promptVariantCount {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pending := p.pending
+	p.pending = nil
+	return pending
+}
+
+// StartPromptExperiments periodically saves this replica's prompt variant
+// counts
+func (g *Gateway) StartPromptExperiments(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(promptExperimentFlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				// Save what was counted since the last tick
+				flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				g.flushPromptVariantCounts(flushCtx)
+				cancel()
+				return
+			case <-ticker.C:
+				g.flushPromptVariantCounts(ctx)
+			}
+		}
+	}()
+}
+
+// flushPromptVariantCounts adds the counts recorded since the last flush to
+// the variant rows. Counts that fail to save are kept for the next flush.
+func (g *Gateway) flushPromptVariantCounts(ctx context.Context) {
+	failed := map[uuid.UUID]*promptVariantCount{}
+	for id, count := range g.promptCounts.take() {
+		_, err := g.db.Pool.Exec(ctx, `
+			UPDATE prompt_variants SET
+				requests = requests + $2,
+				errors = errors + $3,
+				latency_ms_sum = latency_ms_sum + $4,
+				usage_requests = usage_requests + $5,
+				prompt_tokens = prompt_tokens + $6,
+				completion_tokens = completion_tokens + $7,
+				updated_at = NOW()
+			WHERE id = $1
+		`, id, count.Requests, count.Errors, count.LatencyMsSum, count.UsageRequests, count.PromptTokens, count.CompletionTokens)
+		if err != nil {
+			g.logger.Warn("failed to save prompt variant counts", zap.Error(err), zap.String("variant_id", id.String()))
+			failed[id] = count
+		}
+	}
+	if len(failed) > 0 {
+		g.promptCounts.merge(failed)
+	}
+}
+
+func promptExperimentsCacheKey(tenantID uuid.UUID) string {
+	return cache.TenantKey(tenantID, "prompt_experiments")
+}
+
+// listPromptExperiments returns the tenant's experiments with their
+// variants, newest first. id narrows the list to one experiment.
+func (g *Gateway) listPromptExperiments(ctx context.Context, tenantID uuid.UUID, id *uuid.UUID, runningOnly bool) ([]PromptExperiment, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, name, model, status, created_at, stopped_at
+		FROM prompt_experiments
+		WHERE tenant_id = $1
+		  AND ($2::uuid IS NULL OR id = $2)
+		  AND (NOT $3 OR status = 'running')
+		ORDER BY created_at DESC
+	`, tenantID, id, runningOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	experiments := []PromptExperiment{}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var e PromptExperiment
+		if err := rows.Scan(&e.ID, &e.Name, &e.Model, &e.Status, &e.CreatedAt, &e.StoppedAt); err != nil {
+			return nil, err
+		}
+		e.Variants = []PromptVariant{}
+		experiments = append(experiments, e)
+		ids = append(ids, e.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return experiments, nil
+	}
+
+	index := make(map[uuid.UUID]int, len(experiments))
+	for i, e := range experiments {
+		index[e.ID] = i
+	}
+	variantRows, err := g.db.Pool.Query(ctx, `
+		SELECT id, experiment_id, name, system_prompt, percent,
+			requests, errors, latency_ms_sum, usage_requests, prompt_tokens, completion_tokens
+		FROM prompt_variants
+		WHERE experiment_id = ANY($1)
+		ORDER BY name
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer variantRows.Close()
+	for variantRows.Next() {
+		var v PromptVariant
+		var experimentID uuid.UUID
+		var count promptVariantCount
+		if err := variantRows.Scan(&v.ID, &experimentID, &v.Name, &v.SystemPrompt, &v.Percent,
+			&count.Requests, &count.Errors, &count.LatencyMsSum, &count.UsageRequests, &count.PromptTokens, &count.CompletionTokens); err != nil {
+			return nil, err
+		}
+		v.Metrics = count.metrics()
+		i := index[experimentID]
+		experiments[i].Variants = append(experiments[i].Variants, v)
+	}
+	return experiments, variantRows.Err()
+}
+
+// loadRunningPromptExperiments returns the tenant's running experiments,
+// from cache when possible
+func (g *Gateway) loadRunningPromptExperiments(ctx context.Context, tenantID uuid.UUID) ([]PromptExperiment, error) {
+	if cached, err := g.cache.Get(ctx, promptExperimentsCacheKey(tenantID)); err == nil {
+		var experiments []PromptExperiment
+		if err := json.Unmarshal([]byte(cached), &experiments); err == nil {
+			return experiments, nil
+		}
+	}
+
+	experiments, err := g.listPromptExperiments(ctx, tenantID, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	// Metrics aren't needed to assign requests
+	for i := range experiments {
+		for j := range experiments[i].Variants {
+			experiments[i].Variants[j].Metrics = nil
+		}
+	}
+
+	encoded, _ := json.Marshal(experiments)
+	if err := g.cache.Set(ctx, promptExperimentsCacheKey(tenantID), string(encoded), promptExperimentsCacheTTL); err != nil {
+		g.logger.Debug("failed to cache prompt experiments", zap.Error(err))
+	}
+	return experiments, nil
+}
+
+// invalidatePromptExperiments drops cached experiments after a change
+func (g *Gateway) invalidatePromptExperiments(ctx context.Context, tenantID uuid.UUID) {
+	if err := g.cache.Delete(ctx, promptExperimentsCacheKey(tenantID)); err != nil {
+		g.logger.Warn("failed to invalidate prompt experiment cache", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+	}
+}
+
+// requirePromptExperimentWriter returns the tenant ID for requests allowed
+// to change prompt experiments
+func (g *Gateway) requirePromptExperimentWriter(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return uuid.Nil, false
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok && keyInfo.Role == "read-only" {
+		g.writeError(w, http.StatusForbidden, "read-only API keys cannot change prompt experiments")
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// getPromptExperiment returns one of the tenant's experiments with current
+// metrics, or nil if it doesn't exist
+func (g *Gateway) getPromptExperiment(ctx context.Context, tenantID, id uuid.UUID) (*PromptExperiment, error) {
+	// Include what this replica counted since its last flush
+	g.flushPromptVariantCounts(ctx)
+
+	experiments, err := g.listPromptExperiments(ctx, tenantID, &id, false)
+	if err != nil || len(experiments) == 0 {
+		return nil, err
+	}
+	return &experiments[0], nil
+}
+
+// createPromptExperimentRequest is the body of POST /v1/prompt-experiments
+type createPromptExperimentRequest struct {
+	Name     string          `json:"name"`
+	Model    *string         `json:"model"`
+	Variants []PromptVariant `json:"variants"`
+}
+
+// handleCreatePromptExperiment starts an experiment; omit model to cover
+// every model without an experiment of its own
+// Tenant API - POST /v1/prompt-experiments
+func (g *Gateway) handleCreatePromptExperiment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := g.requirePromptExperimentWriter(w, r)
+	if !ok {
+		return
+	}
+
+	var req createPromptExperimentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 255 {
+		g.writeError(w, http.StatusBadRequest, "name is required and must be at most 255 characters")
+		return
+	}
+	if req.Model != nil {
+		if model := strings.TrimSpace(*req.Model); model == "" {
+			req.Model = nil
+		} else {
+			req.Model = &model
+		}
+	}
+	if err := validatePromptVariants(req.Variants); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		g.logger.Error("failed to begin transaction", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create prompt experiment")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var id uuid.UUID
+	err = tx.QueryRow(ctx, `
+		INSERT INTO prompt_experiments (tenant_id, name, model)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, tenantID, req.Name, req.Model).Scan(&id)
+	if isUniqueViolation(err) {
+		g.writeError(w, http.StatusConflict, "a prompt experiment is already running for this model; stop it first")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to create prompt experiment", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to create prompt experiment")
+		return
+	}
+	for _, v := range req.Variants {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO prompt_variants (experiment_id, name, system_prompt, percent)
+			VALUES ($1, $2, $3, $4)
+		`, id, v.Name, v.SystemPrompt, v.Percent)
+		if err != nil {
+			g.logger.Error("failed to create prompt variant", zap.Error(err), zap.String("experiment_id", id.String()))
+			g.writeError(w, http.StatusInternalServerError, "failed to create prompt experiment")
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		g.logger.Error("failed to commit prompt experiment", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create prompt experiment")
+		return
+	}
+	g.invalidatePromptExperiments(ctx, tenantID)
+
+	experiment, err := g.getPromptExperiment(ctx, tenantID, id)
+	if err != nil || experiment == nil {
+		g.logger.Error("failed to load prompt experiment", zap.Error(err), zap.String("experiment_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load prompt experiment")
+		return
+	}
+
+	g.logger.Info("prompt experiment started",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("experiment_id", id.String()),
+		zap.Int("variants", len(req.Variants)),
+	)
+	g.writeJSON(w, http.StatusCreated, experiment)
+}
+
+// handleListPromptExperiments lists the tenant's experiments with variant
+// metrics, newest first
+// Tenant API - GET /v1/prompt-experiments
+func (g *Gateway) handleListPromptExperiments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+
+	g.flushPromptVariantCounts(ctx)
+	experiments, err := g.listPromptExperiments(ctx, tenantID, nil, false)
+	if err != nil {
+		g.logger.Error("failed to list prompt experiments", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to list prompt experiments")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": experiments,
+	})
+}
+
+// handleGetPromptExperiment returns an experiment with variant metrics.
+// Counts from other replicas can lag by up to promptExperimentFlushInterval.
+// Tenant API - GET /v1/prompt-experiments/{id}
+func (g *Gateway) handleGetPromptExperiment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid prompt experiment ID")
+		return
+	}
+
+	experiment, err := g.getPromptExperiment(ctx, tenantID, id)
+	if err != nil {
+		g.logger.Error("failed to load prompt experiment", zap.Error(err), zap.String("experiment_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load prompt experiment")
+		return
+	}
+	if experiment == nil {
+		g.writeError(w, http.StatusNotFound, "prompt experiment not found")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, experiment)
+}
+
+// handleStopPromptExperiment stops assigning requests to an experiment's
+// variants; its metrics stay available
+// Tenant API - POST /v1/prompt-experiments/{id}/stop
+func (g *Gateway) handleStopPromptExperiment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := g.requirePromptExperimentWriter(w, r)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid prompt experiment ID")
+		return
+	}
+
+	result, err := g.db.Pool.Exec(ctx, `
+		UPDATE prompt_experiments
+		SET status = 'stopped', stopped_at = COALESCE(stopped_at, NOW())
+		WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID)
+	if err != nil {
+		g.logger.Error("failed to stop prompt experiment", zap.Error(err), zap.String("experiment_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to stop prompt experiment")
+		return
+	}
+	if result.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "prompt experiment not found")
+		return
+	}
+	g.invalidatePromptExperiments(ctx, tenantID)
+
+	experiment, err := g.getPromptExperiment(ctx, tenantID, id)
+	if err != nil || experiment == nil {
+		g.logger.Error("failed to load prompt experiment", zap.Error(err), zap.String("experiment_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load prompt experiment")
+		return
+	}
+
+	g.logger.Info("prompt experiment stopped",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("experiment_id", id.String()),
+	)
+	g.writeJSON(w, http.StatusOK, experiment)
+}
@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestValidatePromptVariants(t *testing.T) {
+	valid := []PromptVariant{
+		{Name: "control", Percent: 50},
+		{Name: "concise", SystemPrompt: "Be concise. {{original}}", Percent: 50},
+	}
+	if err := validatePromptVariants(valid); err != nil {
+		t.Fatalf("valid variants rejected: %v", err)
+	}
+
+	for name, variants := range map[string][]PromptVariant{
+		"single":    {{Name: "a", Percent: 100}},
+		"under 100": {{Name: "a", Percent: 40}, {Name: "b", Percent: 40}},
+		"over 100":  {{Name: "a", Percent: 60}, {Name: "b", Percent: 60}},
+		"negative":  {{Name: "a", Percent: 110}, {Name: "b", Percent: -10}},
+		"duplicate": {{Name: "a", Percent: 50}, {Name: "a", Percent: 50}},
+		"bad name":  {{Name: "a b", Percent: 50}, {Name: "c", Percent: 50}},
+		"too long":  {{Name: "a", Percent: 50}, {Name: "b", SystemPrompt: strings.Repeat("x", maxPromptTemplateBytes+1), Percent: 50}},
+	} {
+		if err := validatePromptVariants(variants); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestPickPromptVariant(t *testing.T) {
+	variants := []PromptVariant{
+		{Name: "a", Percent: 20},
+		{Name: "off", Percent: 0},
+		{Name: "b", Percent: 80},
+	}
+	for draw, want := range map[int]string{0: "a", 19: "a", 20: "b", 99: "b"} {
+		if got := pickPromptVariant(variants, draw); got == nil || got.Name != want {
+			t.Errorf("draw %d: got %v, want %s", draw, got, want)
+		}
+	}
+}
+
+func TestSelectPromptExperiment(t *testing.T) {
+	model := "llama-3-8b"
+	experiments := []PromptExperiment{
+		{Name: "all"},
+		{Name: "llama", Model: &model},
+	}
+	if got := selectPromptExperiment(experiments, model); got == nil || got.Name != "llama" {
+		t.Errorf("model experiment not preferred: %v", got)
+	}
+	if got := selectPromptExperiment(experiments, "mistral-7b"); got == nil || got.Name != "all" {
+		t.Errorf("tenant-wide experiment not used: %v", got)
+	}
+	if got := selectPromptExperiment(experiments[1:], "mistral-7b"); got != nil {
+		t.Errorf("unrelated experiment selected: %v", got)
+	}
+}
+
+func TestRenderPromptVariant(t *testing.T) {
+	body := []byte(`{"model":"m","temperature":0.2,"messages":[{"role":"system","content":"You are helpful."},{"role":"user","content":"hi","name":"u1"}]}`)
+
+	unchanged, err := renderPromptVariant(body, "")
+	if err != nil || string(unchanged) != string(body) {
+		t.Fatalf("control variant changed the body: %s, %v", unchanged, err)
+	}
+
+	rendered, err := renderPromptVariant(body, "Answer briefly. {{original}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req struct {
+		Temperature float64 `json:"temperature"`
+		Messages    []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+			Name    string `json:"name"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(rendered, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Temperature != 0.2 {
+		t.Errorf("temperature = %v, other fields must be kept", req.Temperature)
+	}
+	if len(req.Messages) != 2 {
+		t.Fatalf("messages = %+v, want the system message replaced", req.Messages)
+	}
+	if req.Messages[0].Role != "system" || req.Messages[0].Content != "Answer briefly. You are helpful." {
+		t.Errorf("system message = %+v", req.Messages[0])
+	}
+	if req.Messages[1].Role != "user" || req.Messages[1].Name != "u1" {
+		t.Errorf("user message = %+v", req.Messages[1])
+	}
+
+	// Requests without a system prompt get one
+	rendered, err = renderPromptVariant([]byte(`{"messages":[{"role":"user","content":"hi"}]}`), "Be terse.{{original}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(rendered), `{"content":"Be terse.","role":"system"}`) {
+		t.Errorf("rendered = %s", rendered)
+	}
+}
+
+func TestPromptVariantCounts(t *testing.T) {
+	var counts promptVariantCounts
+	id := uuid.New()
+	prompt, completion := 10, 30
+	counts.add(id, 100*time.Millisecond, &prompt, &completion, false)
+	counts.add(id, 300*time.Millisecond, nil, nil, true)
+
+	pending := counts.take()
+	if counts.take() != nil {
+		t.Error("take left counts pending")
+	}
+	m := pending[id].metrics()
+	if m.Requests != 2 || m.Errors != 1 || m.ErrorRate != 0.5 || m.AvgLatencyMs != 200 {
+		t.Errorf("metrics = %+v", m)
+	}
+	if m.UsageRequests != 1 || m.AvgPromptTokens != 10 || m.AvgCompletionTokens != 30 {
+		t.Errorf("token metrics = %+v, want averages over responses with usage", m)
+	}
+
+	counts.merge(pending)
+	if got := counts.take()[id].Requests; got != 2 {
+		t.Errorf("requests after merge = %d", got)
+	}
+}
+
+func TestUsageMetadataWithPromptVariant(t *testing.T) {
+	experimentID := uuid.New()
+	ctx := context.WithValue(context.Background(), "prompt_variant", &promptAssignment{
+		ExperimentID: experimentID,
+		VariantID:    uuid.New(),
+		Variant:      "concise",
+	})
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(usageMetadataWithAlias(ctx, "")), &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["prompt_variant"] != "concise" || fields["prompt_experiment_id"] != experimentID.String() {
+		t.Errorf("metadata = %v", fields)
+	}
+}
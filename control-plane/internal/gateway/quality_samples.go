@@ -0,0 +1,700 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Quality sampling.
+//
+// Tenants can opt in to having a small random fraction of their chat and
+// text completions stored for offline quality evaluation. A sample keeps the
+// prompt, the response text, latency, token counts and the model and vLLM
+// version that served it. Identifiers are HMAC-SHA256 hashed and personal
+// data patterns are masked in the text, so samples can be shared with
+// evaluators without exposing tenants or their users. The response is
+// captured as it is relayed and the sample stored once it has been sent.
+const (
+	// qualitySamplingCacheTTL bounds how long a tenant's opt-in is cached
+	qualitySamplingCacheTTL = 60 * time.Second
+	// qualitySamplePruneInterval is how often expired samples are deleted
+	qualitySamplePruneInterval = time.Hour
+	// qualitySampleStoreTimeout bounds the insert made after a response ends
+	qualitySampleStoreTimeout = 5 * time.Second
+	// qualitySampleDownloadMax caps the samples returned by one download
+	qualitySampleDownloadMax = 100000
+	// qualitySampleFlushEvery is how many samples are written between flushes
+	qualitySampleFlushEvery = 100
+
+	qualityEndpointChat        = "chat.completions"
+	qualityEndpointCompletions = "completions"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	ipv4Pattern  = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	// digitRunPattern finds phone, card and account numbers; runs with fewer
+	// than anonymizeMinDigits digits such as dates are kept
+	digitRunPattern = regexp.MustCompile(`\+?\d[\d ().-]{6,}\d`)
+)
+
+// anonymizeMinDigits is the fewest digits a masked number can have
+const anonymizeMinDigits = 9
+
+// anonymizeText masks emails, IPv4 addresses and long numbers
+func anonymizeText(text string) string {
+	text = emailPattern.ReplaceAllString(text, "<email>")
+	text = ipv4Pattern.ReplaceAllString(text, "<ip>")
+	return digitRunPattern.ReplaceAllStringFunc(text, func(match string) string {
+		digits := 0
+		for _, r := range match {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < anonymizeMinDigits {
+			return match
+		}
+		return "<number>"
+	})
+}
+
+// truncateUTF8 cuts text to at most max bytes without splitting a character
+func truncateUTF8(text string, max int) (string, bool) {
+	if len(text) <= max {
+		return text, false
+	}
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max], true
+}
+
+// QualitySampler decides which requests are sampled and anonymizes them
+type QualitySampler struct {
+	rate      float64
+	hashKey   []byte
+	maxBytes  int
+	retention time.Duration
+	random    func() float64
+}
+
+// NewQualitySampler creates a sampler drawing rate of opted-in requests and
+// keeping up to maxBytes of prompt and of response text per sample
+func NewQualitySampler(rate float64, hashKey string, maxBytes int, retention time.Duration) (*QualitySampler, error) {
+	if hashKey == "" {
+		return nil, errors.New("quality sampling hash key is not set")
+	}
+	if rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("quality sample rate must be between 0 and 1, got %g", rate)
+	}
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("quality sample max bytes must be positive, got %d", maxBytes)
+	}
+	return &QualitySampler{
+		rate:      rate,
+		hashKey:   []byte(hashKey),
+		maxBytes:  maxBytes,
+		retention: retention,
+		random:    rand.Float64,
+	}, nil
+}
+
+// hash returns the keyed hash of an identifier, or nil for an empty one
+func (s *QualitySampler) hash(value string) *string {
+	if value == "" {
+		return nil
+	}
+	mac := hmac.New(sha256.New, s.hashKey)
+	mac.Write([]byte(value))
+	sum := hex.EncodeToString(mac.Sum(nil))
+	return &sum
+}
+
+// draw reports whether a request is sampled
+func (s *QualitySampler) draw() bool {
+	return s.rate > 0 && s.random() < s.rate
+}
+
+// qualitySampleMessage is an anonymized chat message
+type qualitySampleMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// prompt anonymizes a request's prompt and cuts it to maxBytes: the chat
+// messages in order, or the completion prompt
+func (s *QualitySampler) prompt(body []byte, chat bool) (json.RawMessage, bool, error) {
+	if !chat {
+		var req CompletionRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, false, err
+		}
+		text, truncated := truncateUTF8(anonymizeText(req.Prompt), s.maxBytes)
+		encoded, err := json.Marshal(text)
+		return encoded, truncated, err
+	}
+
+	var req ChatCompletionRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, false, err
+	}
+	messages := []qualitySampleMessage{}
+	budget, truncated := s.maxBytes, false
+	for _, m := range req.Messages {
+		if budget <= 0 {
+			truncated = true
+			break
+		}
+		content, cut := truncateUTF8(anonymizeText(contentText(m.Content)), budget)
+		truncated = truncated || cut
+		budget -= len(content)
+		messages = append(messages, qualitySampleMessage{Role: m.Role, Content: content})
+	}
+	encoded, err := json.Marshal(messages)
+	return encoded, truncated, err
+}
+
+// QualitySample is a stored prompt/response pair
+type QualitySample struct {
+	ID                uuid.UUID       `json:"id"`
+	SampledAt         time.Time       `json:"sampled_at"`
+	TenantHash        string          `json:"tenant_hash"`
+	APIKeyHash        *string         `json:"api_key_hash,omitempty"`
+	UserHash          *string         `json:"user_hash,omitempty"`
+	RequestHash       *string         `json:"request_hash,omitempty"`
+	Endpoint          string          `json:"endpoint"`
+	Model             string          `json:"model"`
+	ModelAlias        *string         `json:"model_alias,omitempty"`
+	VLLMVersion       *string         `json:"vllm_version,omitempty"`
+	TaskTemplate      *string         `json:"task_template,omitempty"`
+	Streamed          bool            `json:"streamed"`
+	Status            int             `json:"status"`
+	LatencyMs         int64           `json:"latency_ms"`
+	PromptTokens      *int            `json:"prompt_tokens,omitempty"`
+	CompletionTokens  *int            `json:"completion_tokens,omitempty"`
+	Prompt            json.RawMessage `json:"prompt"`
+	Response          string          `json:"response"`
+	PromptTruncated   bool            `json:"prompt_truncated"`
+	ResponseTruncated bool            `json:"response_truncated"`
+}
+
+// qualityResponseCapture collects the generated text of the first choice as
+// a response is relayed: from each data line of an event stream, or from the
+// whole body of a JSON response
+type qualityResponseCapture struct {
+	chat, stream bool
+	limit        int
+	pending      []byte
+	raw          []byte
+	text         strings.Builder
+	truncated    bool
+}
+
+// qualityChoices is the part of a completion object holding generated text
+type qualityChoices struct {
+	Choices []struct {
+		Index   int    `json:"index"`
+		Text    string `json:"text"`
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+func (c *qualityResponseCapture) Write(p []byte) {
+	if !c.stream {
+		// JSON escaping makes the body larger than its text
+		if len(c.raw)+len(p) > 2*c.limit+16384 {
+			c.truncated = true
+			return
+		}
+		c.raw = append(c.raw, p...)
+		return
+	}
+
+	c.pending = append(c.pending, p...)
+	for {
+		i := bytes.IndexByte(c.pending, '\n')
+		if i < 0 {
+			return
+		}
+		c.line(c.pending[:i])
+		c.pending = c.pending[i+1:]
+	}
+}
+
+// line reads the text delta of one event stream line
+func (c *qualityResponseCapture) line(line []byte) {
+	payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !ok {
+		return
+	}
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || bytes.Equal(payload, []byte("[DONE]")) {
+		return
+	}
+	var chunk qualityChoices
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return
+	}
+	c.appendChoice(chunk)
+}
+
+func (c *qualityResponseCapture) appendChoice(obj qualityChoices) {
+	for _, choice := range obj.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		text := choice.Text
+		if c.chat {
+			text = choice.Message.Content + choice.Delta.Content
+		}
+		if room := c.limit - c.text.Len(); len(text) > room {
+			text, _ = truncateUTF8(text, room)
+			c.truncated = true
+		}
+		c.text.WriteString(text)
+	}
+}
+
+// Text returns the captured response text and whether it was cut short
+func (c *qualityResponseCapture) Text() (string, bool) {
+	if !c.stream && !c.truncated {
+		var obj qualityChoices
+		if err := json.Unmarshal(c.raw, &obj); err == nil {
+			c.appendChoice(obj)
+		}
+	}
+	return c.text.String(), c.truncated
+}
+
+// qualitySampleBody passes a response through a capture and calls onClose
+// once with the last bytes read, for the usage block
+type qualitySampleBody struct {
+	io.ReadCloser
+	capture *qualityResponseCapture
+	tail    []byte
+	closed  bool
+	onClose func(capture *qualityResponseCapture, tail []byte)
+}
+
+func (b *qualitySampleBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.capture.Write(p[:n])
+		b.tail = append(b.tail, p[:n]...)
+		if len(b.tail) > nodeResponseTailSize {
+			b.tail = b.tail[len(b.tail)-nodeResponseTailSize:]
+		}
+	}
+	return n, err
+}
+
+func (b *qualitySampleBody) Close() error {
+	err := b.ReadCloser.Close()
+	if !b.closed {
+		b.closed = true
+		b.onClose(b.capture, b.tail)
+	}
+	return err
+}
+
+// sampleForQuality draws the request for quality sampling and, when drawn
+// for a tenant that opted in, wraps the response so the pair is stored once
+// the response has been relayed. Sampling never fails the request.
+func (g *Gateway) sampleForQuality(r *http.Request, resp *http.Response, endpoint, model string, body []byte, chat bool, start time.Time) {
+	s := g.QualitySamples
+	if s == nil || resp.StatusCode != http.StatusOK {
+		return
+	}
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok || !s.draw() {
+		return
+	}
+
+	enabled, err := g.qualitySamplingEnabled(ctx, tenantID)
+	if err != nil {
+		g.logger.Warn("failed to load quality sampling opt-in", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		return
+	}
+	if !enabled {
+		return
+	}
+
+	prompt, promptTruncated, err := s.prompt(body, chat)
+	if err != nil {
+		return
+	}
+	var meta struct {
+		User string `json:"user"`
+	}
+	_ = json.Unmarshal(body, &meta)
+
+	sample := &QualitySample{
+		SampledAt:       start.UTC(),
+		TenantHash:      *s.hash(tenantID.String()),
+		UserHash:        s.hash(meta.User),
+		RequestHash:     s.hash(middleware.GetReqID(ctx)),
+		Endpoint:        qualityEndpointCompletions,
+		Model:           model,
+		Streamed:        strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"),
+		Status:          resp.StatusCode,
+		Prompt:          prompt,
+		PromptTruncated: promptTruncated,
+	}
+	if chat {
+		sample.Endpoint = qualityEndpointChat
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok {
+		sample.APIKeyHash = s.hash(keyInfo.ID.String())
+	}
+	if alias, ok := ctx.Value("model_alias").(string); ok && alias != "" {
+		sample.ModelAlias = &alias
+	}
+
+	resp.Body = &qualitySampleBody{
+		ReadCloser: resp.Body,
+		capture:    &qualityResponseCapture{chat: chat, stream: sample.Streamed, limit: s.maxBytes},
+		onClose: func(capture *qualityResponseCapture, tail []byte) {
+			sample.LatencyMs = time.Since(start).Milliseconds()
+			text, truncated := capture.Text()
+			sample.Response = anonymizeText(text)
+			sample.ResponseTruncated = truncated
+			sample.PromptTokens = lastTokenCount(promptTokensPattern, tail)
+			sample.CompletionTokens = lastTokenCount(completionTokensPattern, tail)
+			go g.storeQualitySample(sample, endpoint)
+		},
+	}
+}
+
+// storeQualitySample inserts a sample with the vLLM version and task
+// template of the node that served it
+func (g *Gateway) storeQualitySample(sample *QualitySample, endpoint string) {
+	ctx, cancel := context.WithTimeout(context.Background(), qualitySampleStoreTimeout)
+	defer cancel()
+
+	_, err := g.db.Pool.Exec(ctx, `
+		INSERT INTO quality_samples (
+			sampled_at, tenant_hash, api_key_hash, user_hash, request_hash,
+			endpoint, model, model_alias, vllm_version, task_template,
+			streamed, status, latency_ms, prompt_tokens, completion_tokens,
+			prompt, response, prompt_truncated, response_truncated
+		)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8,
+		       n.reported_runtime->>'vllm_version', n.task_template,
+		       $10, $11, $12, $13, $14, $15, $16, $17, $18
+		FROM (SELECT 1) one
+		LEFT JOIN LATERAL (
+			SELECT reported_runtime, task_template FROM nodes WHERE endpoint_url = $9 LIMIT 1
+		) n ON true
+	`, sample.SampledAt, sample.TenantHash, sample.APIKeyHash, sample.UserHash, sample.RequestHash,
+		sample.Endpoint, sample.Model, sample.ModelAlias, endpoint,
+		sample.Streamed, sample.Status, sample.LatencyMs, sample.PromptTokens, sample.CompletionTokens,
+		sample.Prompt, sample.Response, sample.PromptTruncated, sample.ResponseTruncated)
+	if err != nil {
+		g.logger.Warn("failed to store quality sample", zap.Error(err), zap.String("model", sample.Model))
+	}
+}
+
+func qualitySamplingCacheKey(tenantID uuid.UUID) string {
+	return cache.TenantKey(tenantID, "quality_sampling")
+}
+
+// qualitySamplingEnabled reports whether the tenant opted in, from cache
+// when possible
+func (g *Gateway) qualitySamplingEnabled(ctx context.Context, tenantID uuid.UUID) (bool, error) {
+	if cached, err := g.cache.Get(ctx, qualitySamplingCacheKey(tenantID)); err == nil {
+		return cached == "1", nil
+	}
+
+	var enabled bool
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT enabled FROM tenant_quality_sampling WHERE tenant_id = $1
+	`, tenantID).Scan(&enabled)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	if err := g.cache.Set(ctx, qualitySamplingCacheKey(tenantID), value, qualitySamplingCacheTTL); err != nil {
+		g.logger.Debug("failed to cache quality sampling opt-in", zap.Error(err))
+	}
+	return enabled, nil
+}
+
+// StartQualitySampling deletes samples older than the retention period
+// every hour. It does nothing when sampling is disabled.
+func (g *Gateway) StartQualitySampling(ctx context.Context) {
+	if g.QualitySamples == nil || g.QualitySamples.retention <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(qualitySamplePruneInterval)
+		defer ticker.Stop()
+
+		for {
+			g.pruneQualitySamples(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (g *Gateway) pruneQualitySamples(ctx context.Context) {
+	tag, err := g.db.Pool.Exec(ctx, `
+		DELETE FROM quality_samples WHERE sampled_at < NOW() - make_interval(secs => $1)
+	`, g.QualitySamples.retention.Seconds())
+	if err != nil {
+		g.logger.Error("failed to prune quality samples", zap.Error(err))
+		return
+	}
+	if tag.RowsAffected() > 0 {
+		g.logger.Info("pruned expired quality samples", zap.Int64("deleted", tag.RowsAffected()))
+	}
+}
+
+// qualitySamplingStatus describes a tenant's opt-in
+func (g *Gateway) qualitySamplingStatus(ctx context.Context, tenantID uuid.UUID) (map[string]interface{}, error) {
+	var enabled bool
+	var optedInAt, updatedAt *time.Time
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT enabled, opted_in_at, updated_at FROM tenant_quality_sampling WHERE tenant_id = $1
+	`, tenantID).Scan(&enabled, &optedInAt, &updatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	status := map[string]interface{}{
+		"enabled":   enabled,
+		"available": g.QualitySamples != nil,
+	}
+	if g.QualitySamples != nil {
+		status["sample_rate"] = g.QualitySamples.rate
+		status["retention_days"] = int(g.QualitySamples.retention.Hours() / 24)
+	}
+	if enabled && optedInAt != nil {
+		status["opted_in_at"] = optedInAt
+	}
+	if updatedAt != nil {
+		status["updated_at"] = updatedAt
+	}
+	return status, nil
+}
+
+// handleGetQualitySampling returns whether the tenant opted in to quality
+// sampling
+// Tenant API - GET /v1/privacy/quality-sampling
+func (g *Gateway) handleGetQualitySampling(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+
+	status, err := g.qualitySamplingStatus(ctx, tenantID)
+	if err != nil {
+		g.logger.Error("failed to load quality sampling opt-in", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load quality sampling settings")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, status)
+}
+
+// handleUpdateQualitySampling opts the tenant in or out of quality
+// sampling. Opting out deletes the tenant's stored samples.
+// Tenant API - PUT /v1/privacy/quality-sampling
+func (g *Gateway) handleUpdateQualitySampling(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+	keyInfo, _ := ctx.Value("api_key").(*models.APIKey)
+	if keyInfo != nil && keyInfo.Role == "read-only" {
+		g.writeError(w, http.StatusForbidden, "read-only API keys cannot change quality sampling")
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Enabled == nil {
+		g.writeError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+	if *req.Enabled && g.QualitySamples == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "quality sampling is not available")
+		return
+	}
+
+	var keyID *uuid.UUID
+	if keyInfo != nil {
+		keyID = &keyInfo.ID
+	}
+	_, err := g.db.Pool.Exec(ctx, `
+		INSERT INTO tenant_quality_sampling (tenant_id, enabled, changed_by_api_key_id, opted_in_at, updated_at)
+		VALUES ($1, $2, $3, CASE WHEN $2 THEN NOW() END, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			changed_by_api_key_id = EXCLUDED.changed_by_api_key_id,
+			opted_in_at = CASE
+				WHEN NOT EXCLUDED.enabled THEN NULL
+				ELSE COALESCE(tenant_quality_sampling.opted_in_at, NOW())
+			END,
+			updated_at = NOW()
+	`, tenantID, *req.Enabled, keyID)
+	if err != nil {
+		g.logger.Error("failed to update quality sampling opt-in", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to update quality sampling")
+		return
+	}
+	if err := g.cache.Delete(ctx, qualitySamplingCacheKey(tenantID)); err != nil {
+		g.logger.Warn("failed to invalidate quality sampling cache", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+	}
+
+	var deleted int64
+	if !*req.Enabled && g.QualitySamples != nil {
+		tag, err := g.db.Pool.Exec(ctx, `
+			DELETE FROM quality_samples WHERE tenant_hash = $1
+		`, *g.QualitySamples.hash(tenantID.String()))
+		if err != nil {
+			g.logger.Error("failed to delete quality samples", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+			g.writeError(w, http.StatusInternalServerError, "opted out, but failed to delete stored samples; retry to delete them")
+			return
+		}
+		deleted = tag.RowsAffected()
+	}
+
+	g.logger.Info("quality sampling opt-in changed",
+		zap.String("tenant_id", tenantID.String()),
+		zap.Bool("enabled", *req.Enabled),
+		zap.Int64("deleted_samples", deleted),
+	)
+
+	status, err := g.qualitySamplingStatus(ctx, tenantID)
+	if err != nil {
+		status = map[string]interface{}{"enabled": *req.Enabled}
+	}
+	if !*req.Enabled {
+		status["deleted_samples"] = deleted
+	}
+	g.writeJSON(w, http.StatusOK, status)
+}
+
+// parseSampleRange reads the required from and to RFC 3339 query parameters
+func parseSampleRange(r *http.Request) (time.Time, time.Time, error) {
+	from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("from must be an RFC 3339 time")
+	}
+	to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("to must be an RFC 3339 time")
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, errors.New("to must be after from")
+	}
+	return from, to, nil
+}
+
+// handleDownloadQualitySamples streams the samples taken in a time range as
+// newline-delimited JSON, oldest first
+// Admin API - GET /admin/quality-samples?from=&to=&model=&limit=10000
+func (g *Gateway) handleDownloadQualitySamples(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseSampleRange(r)
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	model := r.URL.Query().Get("model")
+	limit := parseIntParam(r, "limit", 10000, 1, qualitySampleDownloadMax)
+
+	rows, err := g.db.Pool.Query(r.Context(), `
+		SELECT id, sampled_at, tenant_hash, api_key_hash, user_hash, request_hash,
+		       endpoint, model, model_alias, vllm_version, task_template,
+		       streamed, status, latency_ms, prompt_tokens, completion_tokens,
+		       prompt, response, prompt_truncated, response_truncated
+		FROM quality_samples
+		WHERE sampled_at >= $1 AND sampled_at < $2
+		  AND ($3 = '' OR model = $3)
+		ORDER BY sampled_at
+		LIMIT $4
+	`, from, to, model, limit)
+	if err != nil {
+		g.logger.Error("failed to query quality samples", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to query quality samples")
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="quality-samples-%s-%s.jsonl"`,
+		from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z")))
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+	count := 0
+	for rows.Next() {
+		var s QualitySample
+		if err := rows.Scan(&s.ID, &s.SampledAt, &s.TenantHash, &s.APIKeyHash, &s.UserHash, &s.RequestHash,
+			&s.Endpoint, &s.Model, &s.ModelAlias, &s.VLLMVersion, &s.TaskTemplate,
+			&s.Streamed, &s.Status, &s.LatencyMs, &s.PromptTokens, &s.CompletionTokens,
+			&s.Prompt, &s.Response, &s.PromptTruncated, &s.ResponseTruncated); err != nil {
+			g.logger.Error("failed to scan quality sample", zap.Error(err))
+			continue
+		}
+		if err := enc.Encode(s); err != nil {
+			return
+		}
+		if count++; count%qualitySampleFlushEvery == 0 {
+			out.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		g.logger.Error("quality sample download interrupted", zap.Error(err))
+	}
+	out.Flush()
+}
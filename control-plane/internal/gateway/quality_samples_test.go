@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAnonymizeText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"mail jane.doe@example.com now", "mail <email> now"},
+		{"server 10.0.12.7 is down", "server <ip> is down"},
+		{"call +1 (415) 555-0132", "call <number>"},
+		{"card 4111 1111 1111 1111", "card <number>"},
+		{"due 2024-05-01, 3 items", "due 2024-05-01, 3 items"},
+	}
+	for _, tt := range tests {
+		if got := anonymizeText(tt.in); got != tt.want {
+			t.Errorf("anonymizeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	if got, cut := truncateUTF8("héllo", 2); got != "h" || !cut {
+		t.Errorf("truncateUTF8() = %q, %v, want \"h\", true", got, cut)
+	}
+	if got, cut := truncateUTF8("hi", 5); got != "hi" || cut {
+		t.Errorf("truncateUTF8() = %q, %v, want \"hi\", false", got, cut)
+	}
+}
+
+func TestNewQualitySampler(t *testing.T) {
+	if _, err := NewQualitySampler(0.001, "", 1024, time.Hour); err == nil {
+		t.Error("accepted an empty hash key")
+	}
+	if _, err := NewQualitySampler(1.5, "key", 1024, time.Hour); err == nil {
+		t.Error("accepted a rate above 1")
+	}
+
+	s, err := NewQualitySampler(0.25, "key", 1024, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.random = func() float64 { return 0.2 }
+	if !s.draw() {
+		t.Error("draw below the rate not sampled")
+	}
+	s.random = func() float64 { return 0.3 }
+	if s.draw() {
+		t.Error("draw above the rate sampled")
+	}
+
+	if s.hash("") != nil {
+		t.Error("hashed an empty identifier")
+	}
+	a, b := s.hash("tenant"), s.hash("tenant")
+	if a == nil || *a != *b || len(*a) != 64 || *a == "tenant" {
+		t.Errorf("hash() = %v, want a stable 64 character digest", a)
+	}
+	other, _ := NewQualitySampler(0.25, "other", 1024, time.Hour)
+	if *other.hash("tenant") == *a {
+		t.Error("hash does not depend on the key")
+	}
+}
+
+func TestQualitySamplerPrompt(t *testing.T) {
+	s, _ := NewQualitySampler(1, "key", 12, time.Hour)
+
+	body := []byte(`{"model":"m","messages":[{"role":"system","content":"be nice"},{"role":"user","content":"mail a@b.io"}]}`)
+	raw, truncated, err := s.prompt(body, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []qualitySampleMessage
+	if err := json.Unmarshal(raw, &messages); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].Content != "be nice" || messages[1].Content != "mail " || !truncated {
+		t.Errorf("prompt() = %s, %v", raw, truncated)
+	}
+
+	raw, truncated, err = s.prompt([]byte(`{"model":"m","prompt":"hello"}`), false)
+	if err != nil || string(raw) != `"hello"` || truncated {
+		t.Errorf("prompt() = %s, %v, %v", raw, truncated, err)
+	}
+}
+
+func TestQualityResponseCapture(t *testing.T) {
+	stream := &qualityResponseCapture{chat: true, stream: true, limit: 100}
+	stream.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\nda"))
+	stream.Write([]byte("ta: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"}},{\"index\":1,\"delta\":{\"content\":\"x\"}}]}\n\n"))
+	stream.Write([]byte("data: [DONE]\n\n"))
+	if text, cut := stream.Text(); text != "Hello" || cut {
+		t.Errorf("stream Text() = %q, %v, want \"Hello\", false", text, cut)
+	}
+
+	body := &qualityResponseCapture{limit: 3}
+	body.Write([]byte(`{"choices":[{"index":0,"text":"Hello"}]}`))
+	if text, cut := body.Text(); text != "Hel" || !cut {
+		t.Errorf("completion Text() = %q, %v, want \"Hel\", true", text, cut)
+	}
+}
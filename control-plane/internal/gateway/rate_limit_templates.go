@@ -0,0 +1,387 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Rate limit templates give environments limits by name: a new environment
+// takes the template named like it (dev, staging, production, ...), and API
+// keys created in it get the template's limits. Key limits a template leaves
+// unset follow the tenant's plan, and those it sets are capped by the plan,
+// so a template never lifts a tenant above what it pays for. Plan changes
+// recompute key limits the same way.
+
+// rateLimitTemplate is a named set of environment and key limits
+type rateLimitTemplate struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	// RequestsPerMin, TokensPerMin and ConcurrencyLimit are per-key limits;
+	// nil follows the plan
+	RequestsPerMin   *int `json:"requests_per_min"`
+	TokensPerMin     *int `json:"tokens_per_min"`
+	ConcurrencyLimit *int `json:"concurrency_limit"`
+	// TokensPerDay is the environment's daily token quota; nil keeps the
+	// default
+	TokensPerDay *int64    `json:"tokens_per_day"`
+	CreatedBy    *string   `json:"created_by,omitempty"`
+	UpdatedBy    *string   `json:"updated_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// validate checks a template's name and limits
+func (t *rateLimitTemplate) validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" || len(t.Name) > 100 {
+		return errors.New("name must be 1 to 100 characters")
+	}
+	for field, limit := range map[string]*int{
+		"requests_per_min":  t.RequestsPerMin,
+		"tokens_per_min":    t.TokensPerMin,
+		"concurrency_limit": t.ConcurrencyLimit,
+	} {
+		if limit != nil && *limit <= 0 {
+			return fmt.Errorf("%s must be positive, or null to follow the plan", field)
+		}
+	}
+	if t.TokensPerDay != nil && *t.TokensPerDay <= 0 {
+		return errors.New("tokens_per_day must be positive, or null to keep the default")
+	}
+	return nil
+}
+
+const rateLimitTemplateColumns = `
+	id, name, COALESCE(description, ''), requests_per_min, tokens_per_min, concurrency_limit,
+	tokens_per_day, created_by, updated_by, created_at, updated_at
+`
+
+// scanRateLimitTemplate scans a row of rateLimitTemplateColumns
+func scanRateLimitTemplate(row pgx.Row) (*rateLimitTemplate, error) {
+	var t rateLimitTemplate
+	err := row.Scan(&t.ID, &t.Name, &t.Description, &t.RequestsPerMin, &t.TokensPerMin, &t.ConcurrencyLimit,
+		&t.TokensPerDay, &t.CreatedBy, &t.UpdatedBy, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// createEnvironment creates an environment with the limits of the template
+// named like it, if any
+func (g *Gateway) createEnvironment(ctx context.Context, tenantID uuid.UUID, name, region string) (uuid.UUID, error) {
+	var envID uuid.UUID
+	err := g.db.Pool.QueryRow(ctx, `
+		INSERT INTO environments (tenant_id, name, region, status, rate_limit_template_id, created_at, updated_at)
+		VALUES ($1, $2, $3, 'active', (SELECT id FROM rate_limit_templates WHERE name = $2), NOW(), NOW())
+		RETURNING id
+	`, tenantID, name, region).Scan(&envID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create environment: %w", err)
+	}
+
+	_, err = g.db.Pool.Exec(ctx, `
+		UPDATE environments e SET quota_tokens_per_day = t.tokens_per_day
+		FROM rate_limit_templates t
+		WHERE e.id = $1 AND t.id = e.rate_limit_template_id AND t.tokens_per_day IS NOT NULL
+	`, envID)
+	if err != nil {
+		return envID, fmt.Errorf("failed to apply rate limit template: %w", err)
+	}
+	return envID, nil
+}
+
+// applyKeyLimits sets the limits of a tenant's API keys from their
+// environments' templates within plan, returning the cache keys of the
+// updated keys. Postgres' LEAST ignores NULLs, so an unset template limit
+// takes the plan's and an unlimited plan the template's.
+func applyKeyLimits(ctx context.Context, q database.Querier, tenantID uuid.UUID, plan billing.Plan) ([]string, error) {
+	rows, err := q.Query(ctx, `
+		UPDATE api_keys k
+		SET rate_limit_requests_per_min = LEAST(t.requests_per_min, $2::int),
+		    concurrency_limit = LEAST(t.concurrency_limit, $3::int),
+		    rate_limit_tokens_per_min = LEAST(t.tokens_per_min, $4::int)
+		FROM environments e
+		LEFT JOIN rate_limit_templates t ON t.id = e.rate_limit_template_id
+		WHERE k.tenant_id = $1 AND e.id = k.environment_id
+		RETURNING k.key_hash
+	`, tenantID, plan.RequestsPerMin, plan.ConcurrencyLimit, plan.TokensPerMin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update API key limits: %w", err)
+	}
+	defer rows.Close()
+
+	var cacheKeys []string
+	for rows.Next() {
+		var keyHash string
+		if err := rows.Scan(&keyHash); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		cacheKeys = append(cacheKeys, fmt.Sprintf("api_key:%s", keyHash))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to update API key limits: %w", err)
+	}
+	return cacheKeys, nil
+}
+
+// rateLimitTemplateID parses the template ID route parameter
+func (g *Gateway) rateLimitTemplateID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid template ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// decodeRateLimitTemplate reads and validates a template request body
+func (g *Gateway) decodeRateLimitTemplate(w http.ResponseWriter, r *http.Request) (*rateLimitTemplate, bool) {
+	var t rateLimitTemplate
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return nil, false
+	}
+	if err := t.validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return &t, true
+}
+
+// handleListRateLimitTemplates lists the rate limit templates
+// Platform Admin Only - GET /admin/rate-limit-templates
+func (g *Gateway) handleListRateLimitTemplates(w http.ResponseWriter, r *http.Request) {
+	rows, err := g.db.Pool.Query(r.Context(), `SELECT `+rateLimitTemplateColumns+` FROM rate_limit_templates ORDER BY name`)
+	if err != nil {
+		g.logger.Error("failed to list rate limit templates", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list rate limit templates")
+		return
+	}
+	defer rows.Close()
+
+	templates := []rateLimitTemplate{}
+	for rows.Next() {
+		t, err := scanRateLimitTemplate(rows)
+		if err != nil {
+			g.logger.Error("failed to scan rate limit template", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to list rate limit templates")
+			return
+		}
+		templates = append(templates, *t)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": templates,
+	})
+}
+
+// handleCreateRateLimitTemplate adds a template for environments of a name
+// Platform Admin Only - POST /admin/rate-limit-templates
+// Environments created afterwards with the template's name take it; use the
+// apply endpoint for existing ones.
+func (g *Gateway) handleCreateRateLimitTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, ok := g.decodeRateLimitTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	t, err := scanRateLimitTemplate(g.db.Pool.QueryRow(ctx, `
+		INSERT INTO rate_limit_templates (name, description, requests_per_min, tokens_per_min, concurrency_limit, tokens_per_day, created_by, updated_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $7)
+		RETURNING `+rateLimitTemplateColumns,
+		req.Name, req.Description, req.RequestsPerMin, req.TokensPerMin, req.ConcurrencyLimit, req.TokensPerDay, adminActor(ctx)))
+	if isUniqueViolation(err) {
+		g.writeError(w, http.StatusConflict, fmt.Sprintf("a template named %q already exists", req.Name))
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to create rate limit template", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to create rate limit template")
+		return
+	}
+
+	g.logger.Info("rate limit template created", zap.String("template_id", t.ID.String()), zap.String("name", t.Name))
+	g.writeJSON(w, http.StatusCreated, t)
+}
+
+// handleUpdateRateLimitTemplate replaces a template's name and limits
+// Platform Admin Only - PUT /admin/rate-limit-templates/{id}
+// Existing environments and keys keep their limits until the template is
+// applied or the tenant's plan changes.
+func (g *Gateway) handleUpdateRateLimitTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, ok := g.rateLimitTemplateID(w, r)
+	if !ok {
+		return
+	}
+	req, ok := g.decodeRateLimitTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	t, err := scanRateLimitTemplate(g.db.Pool.QueryRow(ctx, `
+		UPDATE rate_limit_templates
+		SET name = $2, description = NULLIF($3, ''), requests_per_min = $4, tokens_per_min = $5,
+		    concurrency_limit = $6, tokens_per_day = $7, updated_by = $8, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+rateLimitTemplateColumns,
+		id, req.Name, req.Description, req.RequestsPerMin, req.TokensPerMin, req.ConcurrencyLimit, req.TokensPerDay, adminActor(ctx)))
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		g.writeError(w, http.StatusNotFound, "rate limit template not found")
+		return
+	case isUniqueViolation(err):
+		g.writeError(w, http.StatusConflict, fmt.Sprintf("a template named %q already exists", req.Name))
+		return
+	case err != nil:
+		g.logger.Error("failed to update rate limit template", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update rate limit template")
+		return
+	}
+
+	g.logger.Info("rate limit template updated", zap.String("template_id", t.ID.String()), zap.String("name", t.Name))
+	g.writeJSON(w, http.StatusOK, t)
+}
+
+// handleDeleteRateLimitTemplate removes a template
+// Platform Admin Only - DELETE /admin/rate-limit-templates/{id}
+// Its environments keep their limits; their keys follow the plan from the
+// tenant's next plan change.
+func (g *Gateway) handleDeleteRateLimitTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := g.rateLimitTemplateID(w, r)
+	if !ok {
+		return
+	}
+
+	tag, err := g.db.Pool.Exec(r.Context(), `DELETE FROM rate_limit_templates WHERE id = $1`, id)
+	if err != nil {
+		g.logger.Error("failed to delete rate limit template", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to delete rate limit template")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "rate limit template not found")
+		return
+	}
+
+	g.logger.Info("rate limit template deleted", zap.String("template_id", id.String()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleApplyRateLimitTemplate applies a template to every environment named
+// like it and recomputes the limits of their tenants' API keys, replacing
+// limits set on keys by hand
+// Platform Admin Only - POST /admin/rate-limit-templates/{id}/apply
+func (g *Gateway) handleApplyRateLimitTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, ok := g.rateLimitTemplateID(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		g.logger.Error("failed to begin transaction", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to apply rate limit template")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE environments e
+		SET rate_limit_template_id = t.id,
+		    quota_tokens_per_day = COALESCE(t.tokens_per_day, e.quota_tokens_per_day),
+		    updated_at = NOW()
+		FROM rate_limit_templates t
+		WHERE t.id = $1 AND e.name = t.name
+		RETURNING e.tenant_id
+	`, id)
+	if err != nil {
+		g.logger.Error("failed to apply rate limit template", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to apply rate limit template")
+		return
+	}
+	var environments int
+	tenants := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var tenantID uuid.UUID
+		if err := rows.Scan(&tenantID); err != nil {
+			rows.Close()
+			g.logger.Error("failed to scan environment", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to apply rate limit template")
+			return
+		}
+		environments++
+		tenants[tenantID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		g.logger.Error("failed to apply rate limit template", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to apply rate limit template")
+		return
+	}
+	if environments == 0 {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM rate_limit_templates WHERE id = $1)`, id).Scan(&exists); err == nil && !exists {
+			g.writeError(w, http.StatusNotFound, "rate limit template not found")
+			return
+		}
+	}
+
+	var cacheKeys []string
+	for tenantID := range tenants {
+		var planName string
+		if err := tx.QueryRow(ctx, `SELECT billing_plan FROM tenants WHERE id = $1`, tenantID).Scan(&planName); err != nil {
+			g.logger.Error("failed to load tenant plan", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+			g.writeError(w, http.StatusInternalServerError, "failed to apply rate limit template")
+			return
+		}
+		keys, err := applyKeyLimits(ctx, tx, tenantID, g.Plans.Get(planName))
+		if err != nil {
+			g.logger.Error("failed to apply rate limit template", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+			g.writeError(w, http.StatusInternalServerError, "failed to apply rate limit template")
+			return
+		}
+		cacheKeys = append(cacheKeys, keys...)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		g.logger.Error("failed to commit rate limit template", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to apply rate limit template")
+		return
+	}
+	if len(cacheKeys) > 0 {
+		if err := g.cache.Delete(ctx, cacheKeys...); err != nil {
+			// Cached keys expire within 60s; the new limits apply then
+			g.logger.Warn("failed to invalidate cached API keys", zap.Error(err))
+		}
+	}
+
+	g.logger.Info("rate limit template applied",
+		zap.String("template_id", id.String()),
+		zap.Int("environments", environments),
+		zap.Int("tenants", len(tenants)),
+		zap.Int("api_keys", len(cacheKeys)),
+	)
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"template_id":  id,
+		"environments": environments,
+		"tenants":      len(tenants),
+		"api_keys":     len(cacheKeys),
+	})
+}
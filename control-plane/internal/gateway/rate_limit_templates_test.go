@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRateLimitTemplateValidate(t *testing.T) {
+	positive, zero := 60, 0
+	var negativeDay int64 = -1
+
+	tests := []struct {
+		name    string
+		tmpl    rateLimitTemplate
+		wantErr string
+	}{
+		{"plan based", rateLimitTemplate{Name: " production "}, ""},
+		{"limits set", rateLimitTemplate{Name: "dev", RequestsPerMin: &positive, ConcurrencyLimit: &positive}, ""},
+		{"no name", rateLimitTemplate{Name: "  "}, "name"},
+		{"long name", rateLimitTemplate{Name: strings.Repeat("x", 101)}, "name"},
+		{"zero limit", rateLimitTemplate{Name: "dev", TokensPerMin: &zero}, "tokens_per_min"},
+		{"negative quota", rateLimitTemplate{Name: "dev", TokensPerDay: &negativeDay}, "tokens_per_day"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tmpl.validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error = %v, want one about %s", err, tt.wantErr)
+			}
+		})
+	}
+
+	tmpl := rateLimitTemplate{Name: " staging "}
+	if err := tmpl.validate(); err != nil || tmpl.Name != "staging" {
+		t.Errorf("name = %q (%v), want it trimmed", tmpl.Name, err)
+	}
+}
+
+func TestRateLimitTemplateRequestsRejected(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{"malformed body", g.handleCreateRateLimitTemplate, `{"name":`},
+		{"invalid limit", g.handleCreateRateLimitTemplate, `{"name":"dev","requests_per_min":-5}`},
+		{"invalid template ID", g.handleUpdateRateLimitTemplate, `{"name":"dev"}`},
+		{"apply invalid template ID", g.handleApplyRateLimitTemplate, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, httptest.NewRequest("POST", "/admin/rate-limit-templates", strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/crosslogic/control-plane/pkg/cache"
 	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -23,7 +24,15 @@ type RateLimitInfo struct {
 	RetryAfter int64
 }
 
-// RateLimiter handles rate limiting
+// Window formats in counter keys
+const (
+	minuteFormat = "2006-01-02T15:04"
+	dayFormat    = "2006-01-02"
+	monthFormat  = "2006-01"
+)
+
+// RateLimiter handles rate limiting. Its counters live in the namespace of
+// the tenant owning the API key.
 type RateLimiter struct {
 	cache  *cache.Cache
 	logger *zap.Logger
@@ -55,7 +64,7 @@ func (rl *RateLimiter) CheckRateLimit(ctx context.Context, key *models.APIKey) (
 	}
 
 	// Layer 2: Environment level
-	allowed, err = rl.checkEnvironmentRateLimit(ctx, key.EnvironmentID, now)
+	allowed, err = rl.checkEnvironmentRateLimit(ctx, key, now)
 	if err != nil {
 		return false, err
 	}
@@ -84,7 +93,7 @@ func (rl *RateLimiter) CheckRateLimit(ctx context.Context, key *models.APIKey) (
 // checkKeyRateLimit checks rate limit for an API key
 func (rl *RateLimiter) checkKeyRateLimit(ctx context.Context, key *models.APIKey, now time.Time) (bool, error) {
 	// Per-minute request limit
-	minuteKey := fmt.Sprintf("ratelimit:key:%s:minute:%s", key.ID.String(), now.Format("2006-01-02T15:04"))
+	minuteKey := requestsPerMinuteKey(key, now)
 
 	count, err := rl.cache.Incr(ctx, minuteKey)
 	if err != nil {
@@ -106,7 +115,7 @@ func (rl *RateLimiter) checkKeyRateLimit(ctx context.Context, key *models.APIKey
 	}
 
 	// Per-second concurrency limit
-	concurrencyKey := fmt.Sprintf("ratelimit:key:%s:concurrency", key.ID.String())
+	concurrencyKey := concurrencyKey(key)
 	concurrent, err := rl.cache.Incr(ctx, concurrencyKey)
 	if err != nil {
 		return false, err
@@ -129,9 +138,9 @@ func (rl *RateLimiter) checkKeyRateLimit(ctx context.Context, key *models.APIKey
 	return true, nil
 }
 
-// checkEnvironmentRateLimit checks rate limit for an environment
-func (rl *RateLimiter) checkEnvironmentRateLimit(ctx context.Context, envID interface{}, now time.Time) (bool, error) {
-	minuteKey := fmt.Sprintf("ratelimit:env:%v:minute:%s", envID, now.Format("2006-01-02T15:04"))
+// checkEnvironmentRateLimit checks rate limit for the key's environment
+func (rl *RateLimiter) checkEnvironmentRateLimit(ctx context.Context, key *models.APIKey, now time.Time) (bool, error) {
+	minuteKey := cache.TenantKey(key.TenantID, "ratelimit", "env", key.EnvironmentID.String(), "minute", now.Format(minuteFormat))
 
 	count, err := rl.cache.Incr(ctx, minuteKey)
 	if err != nil {
@@ -149,8 +158,8 @@ func (rl *RateLimiter) checkEnvironmentRateLimit(ctx context.Context, envID inte
 }
 
 // checkTenantRateLimit checks rate limit for a tenant
-func (rl *RateLimiter) checkTenantRateLimit(ctx context.Context, tenantID interface{}, now time.Time) (bool, error) {
-	minuteKey := fmt.Sprintf("ratelimit:tenant:%v:minute:%s", tenantID, now.Format("2006-01-02T15:04"))
+func (rl *RateLimiter) checkTenantRateLimit(ctx context.Context, tenantID uuid.UUID, now time.Time) (bool, error) {
+	minuteKey := cache.TenantKey(tenantID, "ratelimit", "tenant", "minute", now.Format(minuteFormat))
 
 	count, err := rl.cache.Incr(ctx, minuteKey)
 	if err != nil {
@@ -172,7 +181,7 @@ func (rl *RateLimiter) RecordTokenUsage(ctx context.Context, key *models.APIKey,
 	now := time.Now()
 
 	// Per-minute token counter
-	minuteKey := fmt.Sprintf("tokens:key:%s:minute:%s", key.ID.String(), now.Format("2006-01-02T15:04"))
+	minuteKey := tokensPerMinuteKey(key, now)
 	_, err := rl.cache.IncrBy(ctx, minuteKey, int64(tokens))
 	if err != nil {
 		return err
@@ -180,7 +189,7 @@ func (rl *RateLimiter) RecordTokenUsage(ctx context.Context, key *models.APIKey,
 	rl.cache.Expire(ctx, minuteKey, 65*time.Second)
 
 	// Per-day token counter
-	dayKey := fmt.Sprintf("tokens:key:%s:day:%s", key.ID.String(), now.Format("2006-01-02"))
+	dayKey := cache.TenantKey(key.TenantID, "tokens", "key", key.ID.String(), "day", now.Format(dayFormat))
 	_, err = rl.cache.IncrBy(ctx, dayKey, int64(tokens))
 	if err != nil {
 		return err
@@ -196,7 +205,7 @@ func (rl *RateLimiter) CheckTokenQuota(ctx context.Context, key *models.APIKey,
 
 	// Check per-minute quota (if set)
 	if key.RateLimitTokensPerMin != nil && *key.RateLimitTokensPerMin > 0 {
-		minuteKey := fmt.Sprintf("tokens:key:%s:minute:%s", key.ID.String(), now.Format("2006-01-02T15:04"))
+		minuteKey := tokensPerMinuteKey(key, now)
 		count, err := rl.cache.Get(ctx, minuteKey)
 		if err == nil {
 			// Count exists
@@ -210,7 +219,7 @@ func (rl *RateLimiter) CheckTokenQuota(ctx context.Context, key *models.APIKey,
 
 	// Check per-day quota (environment level)
 	if envQuota > 0 {
-		dayKey := fmt.Sprintf("tokens:env:%s:day:%s", key.EnvironmentID.String(), now.Format("2006-01-02"))
+		dayKey := cache.TenantKey(key.TenantID, "tokens", "env", key.EnvironmentID.String(), "day", now.Format(dayFormat))
 		count, err := rl.cache.Get(ctx, dayKey)
 		if err == nil {
 			var tokenCount int64
@@ -225,9 +234,8 @@ func (rl *RateLimiter) CheckTokenQuota(ctx context.Context, key *models.APIKey,
 }
 
 // DecrementConcurrency decrements the concurrency counter
-func (rl *RateLimiter) DecrementConcurrency(ctx context.Context, keyID string) error {
-	concurrencyKey := fmt.Sprintf("ratelimit:key:%s:concurrency", keyID)
-	_, err := rl.cache.IncrBy(ctx, concurrencyKey, -1)
+func (rl *RateLimiter) DecrementConcurrency(ctx context.Context, key *models.APIKey) error {
+	_, err := rl.cache.IncrBy(ctx, concurrencyKey(key), -1)
 	return err
 }
 
@@ -239,7 +247,7 @@ func (rl *RateLimiter) CheckRateLimitWithInfo(ctx context.Context, key *models.A
 	resetAt := now.Truncate(time.Minute).Add(time.Minute).Unix()
 
 	// Get current count and limit for the key
-	minuteKey := fmt.Sprintf("ratelimit:key:%s:minute:%s", key.ID.String(), now.Format("2006-01-02T15:04"))
+	minuteKey := requestsPerMinuteKey(key, now)
 
 	// Get current count before increment
 	currentCountStr, _ := rl.cache.Get(ctx, minuteKey)
@@ -289,6 +297,131 @@ func (rl *RateLimiter) CheckRateLimitWithInfo(ctx context.Context, key *models.A
 	return true, info, nil
 }
 
+// BudgetExceeded describes the environment budget limit a key ran into
+type BudgetExceeded struct {
+	// Scope is "environment" for the shared budget, "key" for a sub-limit
+	Scope string
+	// Resource is "tokens" or "spend"; spend is in microdollars
+	Resource string
+	Limit    int64
+	Used     int64
+	ResetsAt time.Time
+}
+
+// CheckBudget checks the key's shared environment budget and its sub-limits,
+// returning the first limit used up or nil while within budget. Only the
+// totals recorded so far count, so a request in flight may overshoot.
+func (rl *RateLimiter) CheckBudget(ctx context.Context, key *models.APIKey) (*BudgetExceeded, error) {
+	b := key.Budget
+	if b == nil {
+		return nil, nil
+	}
+	window, resetsAt := budgetWindow(b.Period, time.Now())
+
+	limits := []struct {
+		scope, resource string
+		limit           *int64
+	}{
+		{"key", "tokens", b.KeyLimitTokens},
+		{"key", "spend", b.KeyLimitMicrodollars},
+		{"environment", "tokens", b.LimitTokens},
+		{"environment", "spend", b.LimitMicrodollars},
+	}
+	for _, l := range limits {
+		if l.limit == nil {
+			continue
+		}
+		used, _, err := rl.cache.GetInt64(ctx, budgetCounterKey(key, l.scope, l.resource, window))
+		if err != nil {
+			return nil, err
+		}
+		if used >= *l.limit {
+			return &BudgetExceeded{
+				Scope:    l.scope,
+				Resource: l.resource,
+				Limit:    *l.limit,
+				Used:     used,
+				ResetsAt: resetsAt,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// RecordBudgetUsage draws tokens and spend from the key's environment
+// budget. Key totals are kept even without a sub-limit so one set later in
+// the period sees the usage so far.
+func (rl *RateLimiter) RecordBudgetUsage(ctx context.Context, key *models.APIKey, tokens, microdollars int64) error {
+	if key.Budget == nil {
+		return nil
+	}
+	window, resetsAt := budgetWindow(key.Budget.Period, time.Now())
+	ttl := time.Until(resetsAt) + time.Hour
+
+	for _, scope := range []string{"environment", "key"} {
+		for resource, amount := range map[string]int64{"tokens": tokens, "spend": microdollars} {
+			if amount <= 0 {
+				continue
+			}
+			counter := budgetCounterKey(key, scope, resource, window)
+			if _, err := rl.cache.IncrBy(ctx, counter, amount); err != nil {
+				return err
+			}
+			rl.cache.Expire(ctx, counter, ttl)
+		}
+	}
+	return nil
+}
+
+// BudgetUsage reads what an environment ("environment" scope) or a key
+// ("key" scope) drew from its environment budget in the current period
+func (rl *RateLimiter) BudgetUsage(ctx context.Context, key *models.APIKey, period, scope string) (tokens, microdollars int64, err error) {
+	window, _ := budgetWindow(period, time.Now())
+	if tokens, _, err = rl.cache.GetInt64(ctx, budgetCounterKey(key, scope, "tokens", window)); err != nil {
+		return 0, 0, err
+	}
+	if microdollars, _, err = rl.cache.GetInt64(ctx, budgetCounterKey(key, scope, "spend", window)); err != nil {
+		return 0, 0, err
+	}
+	return tokens, microdollars, nil
+}
+
+// budgetWindow returns the counter window of the budget period containing
+// now and when that period ends. Periods are UTC days or calendar months.
+func budgetWindow(period string, now time.Time) (string, time.Time) {
+	now = now.UTC()
+	if period == "daily" {
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		return now.Format(dayFormat), start.AddDate(0, 0, 1)
+	}
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return now.Format(monthFormat), start.AddDate(0, 1, 0)
+}
+
+// budgetCounterKey totals a resource drawn from an environment budget by
+// the key's environment or by the key itself
+func budgetCounterKey(key *models.APIKey, scope, resource, window string) string {
+	if scope == "key" {
+		return cache.TenantKey(key.TenantID, "budget", "key", key.ID.String(), resource, window)
+	}
+	return cache.TenantKey(key.TenantID, "budget", "env", key.EnvironmentID.String(), resource, window)
+}
+
+// requestsPerMinuteKey counts the key's requests in the minute of now
+func requestsPerMinuteKey(key *models.APIKey, now time.Time) string {
+	return cache.TenantKey(key.TenantID, "ratelimit", "key", key.ID.String(), "minute", now.Format(minuteFormat))
+}
+
+// concurrencyKey counts the key's requests in flight
+func concurrencyKey(key *models.APIKey) string {
+	return cache.TenantKey(key.TenantID, "ratelimit", "key", key.ID.String(), "concurrency")
+}
+
+// tokensPerMinuteKey counts the key's tokens in the minute of now
+func tokensPerMinuteKey(key *models.APIKey, now time.Time) string {
+	return cache.TenantKey(key.TenantID, "tokens", "key", key.ID.String(), "minute", now.Format(minuteFormat))
+}
+
 // GetRateLimitHeaders returns HTTP headers for rate limit information
 func (info *RateLimitInfo) GetRateLimitHeaders() map[string]string {
 	if info == nil {
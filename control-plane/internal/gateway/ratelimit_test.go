@@ -74,7 +74,7 @@ func TestRateLimiterConcurrencyWindow(t *testing.T) {
 		t.Fatal("concurrency limit should reject third simultaneous request")
 	}
 
-	if err := rl.DecrementConcurrency(context.Background(), apiKey.ID.String()); err != nil {
+	if err := rl.DecrementConcurrency(context.Background(), apiKey); err != nil {
 		t.Fatalf("failed to decrement concurrency: %v", err)
 	}
 
@@ -0,0 +1,233 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// requestTimeoutHeader carries the deadline a client asks for, in seconds.
+// The gateway answers with the deadline it applied once clamped.
+const requestTimeoutHeader = "X-Request-Timeout"
+
+var requestDeadlinesExceeded = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "gateway_request_deadlines_exceeded_total",
+		Help: "Inference requests that ran past the deadline their client asked for",
+	},
+)
+
+// parseRequestTimeout reads the deadline a client asks for from the
+// X-Request-Timeout header or a `timeout` body field, in seconds (the header
+// also takes durations like "30s"). The body field is removed so nodes
+// don't see it; the header wins when both are sent. It returns zero when no
+// deadline was asked for.
+func parseRequestTimeout(header string, body []byte) (time.Duration, []byte, error) {
+	var timeout time.Duration
+	if header = strings.TrimSpace(header); header != "" {
+		d, err := parseTimeoutValue(header)
+		if err != nil {
+			return 0, body, fmt.Errorf("%s must be a positive number of seconds", requestTimeoutHeader)
+		}
+		timeout = d
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return timeout, body, nil
+	}
+	raw, ok := fields["timeout"]
+	if !ok {
+		return timeout, body, nil
+	}
+	if timeout == 0 && !isJSONNull(raw) {
+		var seconds float64
+		if err := json.Unmarshal(raw, &seconds); err != nil || seconds <= 0 {
+			return 0, body, errors.New("timeout must be a positive number of seconds")
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+
+	delete(fields, "timeout")
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return timeout, body, nil
+	}
+	return timeout, stripped, nil
+}
+
+// parseTimeoutValue reads seconds ("30", "2.5") or a duration ("30s", "1m")
+func parseTimeoutValue(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+			return 0, errors.New("timeout must be positive")
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("timeout must be positive")
+	}
+	return d, nil
+}
+
+// clampRequestTimeout limits a requested deadline to the plan's maximum,
+// or to the node request timeout when the plan sets none
+func clampRequestTimeout(requested time.Duration, planMaxSecs int) time.Duration {
+	limit := nodeRequestTimeout
+	if planMaxSecs > 0 && time.Duration(planMaxSecs)*time.Second < limit {
+		limit = time.Duration(planMaxSecs) * time.Second
+	}
+	if requested > limit {
+		return limit
+	}
+	return requested
+}
+
+// formatTimeoutSeconds renders a deadline in seconds for the response header
+func formatTimeoutSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// defaultTimeout bounds every request to timeout, cancelling its context
+// and answering 504 if nothing was written yet, like chi's Timeout. Unlike
+// a context deadline the bound can be lifted, which requestDeadline does
+// for inference requests: their deadlines go up to the plan's maximum.
+func defaultTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithCancelCause(r.Context())
+			timer := time.AfterFunc(timeout, func() { cancel(context.DeadlineExceeded) })
+			defer func() {
+				timer.Stop()
+				if context.Cause(ctx) == context.DeadlineExceeded {
+					w.WriteHeader(http.StatusGatewayTimeout)
+				}
+				cancel(nil)
+			}()
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, "default_timeout", timer)))
+		})
+	}
+}
+
+// liftDefaultTimeout stops defaultTimeout from cancelling the request. It
+// reports false when the timeout already expired.
+func liftDefaultTimeout(ctx context.Context) bool {
+	timer, ok := ctx.Value("default_timeout").(*time.Timer)
+	if !ok {
+		return true
+	}
+	return timer.Stop() || ctx.Err() == nil
+}
+
+// requestDeadline applies the deadline an inference request asks for to its
+// context, so the request to the node is cancelled when it expires. The
+// default timeout of other requests is lifted: without a deadline, requests
+// are bounded only by the node request timeout.
+func (g *Gateway) requestDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !liftDefaultTimeout(r.Context()) {
+			return
+		}
+		var body []byte
+		if isJSONRequest(r) {
+			var err error
+			if body, err = io.ReadAll(r.Body); err != nil {
+				g.writeError(w, http.StatusBadRequest, "failed to read request body")
+				return
+			}
+			r.Body.Close()
+		}
+
+		timeout, body, err := parseRequestTimeout(r.Header.Get(requestTimeoutHeader), body)
+		if err != nil {
+			g.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+		}
+		if timeout == 0 {
+			// Responses outlive the server's write timeout otherwise
+			_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(nodeRequestTimeout + 10*time.Second))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		timeout = clampRequestTimeout(timeout, g.tenantRequestTimeoutLimit(r.Context()))
+		w.Header().Set(requestTimeoutHeader, formatTimeoutSeconds(timeout))
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second))
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// isJSONRequest reports whether the request body is JSON
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err != nil || mediaType == "application/json"
+}
+
+// tenantRequestTimeoutLimit is the longest deadline, in seconds, the
+// tenant's plan allows; zero when unknown, including when the plan can't
+// be read, so paying tenants aren't cut to the free plan's limit
+func (g *Gateway) tenantRequestTimeoutLimit(ctx context.Context) int {
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok || g.Plans == nil || g.db == nil {
+		return 0
+	}
+	plan, err := g.lookupTenantPlan(ctx, tenantID)
+	if err != nil {
+		g.logger.Warn("failed to load plan deadline limit", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		return 0
+	}
+	return plan.RequestTimeoutSecs
+}
+
+// deadlineExceeded reports whether the request ran past the deadline its
+// client asked for. Those failures are the client's choice and are not
+// held against the node or model.
+func deadlineExceeded(r *http.Request) bool {
+	return errors.Is(r.Context().Err(), context.DeadlineExceeded)
+}
+
+// writeProxyError answers a request whose proxying to a node failed, with
+// a deadline_exceeded error when the client's deadline expired first
+func (g *Gateway) writeProxyError(w http.ResponseWriter, r *http.Request, err error) {
+	if deadlineExceeded(r) {
+		requestDeadlinesExceeded.Inc()
+		g.logger.Info("request deadline exceeded",
+			zap.String("path", r.URL.Path),
+			zap.String("timeout", w.Header().Get(requestTimeoutHeader)),
+		)
+		g.writeJSON(w, http.StatusGatewayTimeout, map[string]interface{}{
+			"error": map[string]string{
+				"message": "The request did not complete within its deadline.",
+				"type":    "deadline_exceeded",
+			},
+		})
+		return
+	}
+	g.logger.Error("failed to proxy request", zap.Error(err))
+	g.writeError(w, http.StatusBadGateway, "failed to proxy request")
+}
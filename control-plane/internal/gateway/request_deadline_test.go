@@ -0,0 +1,196 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+func TestParseRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		body     string
+		want     time.Duration
+		wantBody string
+		wantErr  bool
+	}{
+		{"none", "", `{"model":"m"}`, 0, `{"model":"m"}`, false},
+		{"header seconds", "30", `{"model":"m"}`, 30 * time.Second, `{"model":"m"}`, false},
+		{"header fraction", "2.5", `{"model":"m"}`, 2500 * time.Millisecond, `{"model":"m"}`, false},
+		{"header duration", "1m", `{"model":"m"}`, time.Minute, `{"model":"m"}`, false},
+		{"body field", "", `{"model":"m","timeout":45}`, 45 * time.Second, `{"model":"m"}`, false},
+		{"header wins", "10", `{"model":"m","timeout":45}`, 10 * time.Second, `{"model":"m"}`, false},
+		{"null body field", "", `{"model":"m","timeout":null}`, 0, `{"model":"m"}`, false},
+		{"bad header", "soon", `{"model":"m"}`, 0, "", true},
+		{"zero header", "0", `{"model":"m"}`, 0, "", true},
+		{"negative body field", "", `{"model":"m","timeout":-1}`, 0, "", true},
+		{"string body field", "", `{"model":"m","timeout":"30"}`, 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, body, err := parseRequestTimeout(tt.header, []byte(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("timeout = %v, want %v", got, tt.want)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %s, want %s", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestClampRequestTimeout(t *testing.T) {
+	if got := clampRequestTimeout(5*time.Minute, 60); got != time.Minute {
+		t.Errorf("clamped to plan = %v, want 1m", got)
+	}
+	if got := clampRequestTimeout(30*time.Second, 60); got != 30*time.Second {
+		t.Errorf("within plan = %v, want 30s", got)
+	}
+	if got := clampRequestTimeout(time.Hour, 0); got != nodeRequestTimeout {
+		t.Errorf("no plan limit = %v, want %v", got, nodeRequestTimeout)
+	}
+}
+
+func TestRequestDeadlineMiddleware(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+
+	var gotBody string
+	var gotDeadline bool
+	handler := g.requestDeadline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		_, gotDeadline = r.Context().Deadline()
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"m","timeout":20}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !gotDeadline {
+		t.Error("expected the request context to have a deadline")
+	}
+	if gotBody != `{"model":"m"}` {
+		t.Errorf("body = %s, want timeout removed", gotBody)
+	}
+	if got := rec.Header().Get(requestTimeoutHeader); got != "20" {
+		t.Errorf("%s = %q, want 20", requestTimeoutHeader, got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"m"}`))
+	req.Header.Set(requestTimeoutHeader, "never")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 for an invalid timeout", rec.Code)
+	}
+}
+
+func TestWriteProxyErrorDeadlineExceeded(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+
+	handler := g.requestDeadline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		g.writeProxyError(w, r, r.Context().Err())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"m"}`))
+	req.Header.Set(requestTimeoutHeader, "10ms")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"deadline_exceeded"`) {
+		t.Errorf("body = %s, want a deadline_exceeded error", rec.Body.String())
+	}
+}
+
+func TestRequestDeadlineOutlastsDefaultTimeout(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+	// Stands in for the gateway's 60s default, which the deadline exceeds
+	const defaultLimit = 20 * time.Millisecond
+
+	var remaining time.Duration
+	var ctxErr error
+	inference := defaultTimeout(defaultLimit)(g.requestDeadline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(3 * defaultLimit)
+		deadline, _ := r.Context().Deadline()
+		remaining = time.Until(deadline)
+		ctxErr = r.Context().Err()
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"m"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(requestTimeoutHeader, "300")
+	rec := httptest.NewRecorder()
+	inference.ServeHTTP(rec, req)
+
+	if ctxErr != nil {
+		t.Fatalf("request cancelled at the default timeout: %v", ctxErr)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+	if remaining < 4*time.Minute {
+		t.Errorf("deadline %v away, want the 300s asked for", remaining)
+	}
+	if got := rec.Header().Get(requestTimeoutHeader); got != "300" {
+		t.Errorf("%s = %q, want 300", requestTimeoutHeader, got)
+	}
+
+	// Other requests still time out
+	other := defaultTimeout(defaultLimit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	rec = httptest.NewRecorder()
+	other.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/usage", nil))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504 past the default timeout", rec.Code)
+	}
+}
+
+func TestTenantRequestTimeoutLimitPlanLookup(t *testing.T) {
+	cacheClient, cleanup := setupLimiterCache(t)
+	defer cleanup()
+
+	// Nothing listens on the port, so plan lookups that reach the database fail
+	pool, err := pgxpool.New(context.Background(), "postgres://crosslogic@127.0.0.1:1/crosslogic?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	g := &Gateway{db: &database.Database{Pool: pool}, cache: cacheClient, logger: zap.NewNop(), Plans: billing.NewPlanCatalog(nil)}
+
+	pro := uuid.New()
+	cacheClient.Set(context.Background(), tenantPlanCacheKey(pro), "pro", tenantPlanCacheTTL)
+	if got := g.tenantRequestTimeoutLimit(context.WithValue(context.Background(), "tenant_id", pro)); got != 300 {
+		t.Errorf("cached pro plan limit = %d, want 300", got)
+	}
+	if got := g.tenantRequestTimeoutLimit(context.WithValue(context.Background(), "tenant_id", uuid.New())); got != 0 {
+		t.Errorf("limit after a failed plan lookup = %d, want 0 rather than the free plan's", got)
+	}
+}
@@ -0,0 +1,296 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const (
+	// messageTruncationHeader selects what happens to chat requests over
+	// the plan's message limits; the `message_truncation` body field does
+	// the same
+	messageTruncationHeader = "X-Message-Truncation"
+
+	// messagesTruncatedHeader tells the client how many of its oldest
+	// messages were dropped
+	messagesTruncatedHeader = "X-Messages-Truncated"
+
+	// truncationSlidingWindow drops the oldest messages to fit the limits;
+	// truncationDisabled, the default, rejects the request instead
+	truncationSlidingWindow = "sliding_window"
+	truncationDisabled      = "disabled"
+)
+
+var chatRequestsShaped = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gateway_chat_requests_shaped_total",
+		Help: "Chat requests over their plan's message limits, by plan and whether they were truncated or rejected",
+	},
+	// Labelled by plan rather than model: shaping runs before the model is
+	// validated, and client-chosen names would be unbounded
+	[]string{"plan", "action"},
+)
+
+// messageLimits are the plan's limits on a chat request's messages; zero
+// is unlimited
+type messageLimits struct {
+	MaxMessages     int
+	MaxMessageChars int
+}
+
+// messageLimitError is a chat request over its plan's message limits
+type messageLimitError struct {
+	Code    string
+	Message string
+}
+
+func (e *messageLimitError) Error() string {
+	return e.Message
+}
+
+// parseMessageTruncation reads the truncation mode a client asks for from
+// the X-Message-Truncation header or a `message_truncation` body field,
+// reporting whether it is a sliding window. The body field is removed so
+// nodes don't see it; the header wins when both are sent.
+func parseMessageTruncation(header string, body []byte) (bool, []byte, error) {
+	mode := strings.ToLower(strings.TrimSpace(header))
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err == nil {
+		if raw, ok := fields["message_truncation"]; ok {
+			if mode == "" && !isJSONNull(raw) {
+				var value string
+				if err := json.Unmarshal(raw, &value); err != nil {
+					return false, body, fmt.Errorf("message_truncation must be %q or %q", truncationSlidingWindow, truncationDisabled)
+				}
+				mode = strings.ToLower(strings.TrimSpace(value))
+			}
+			delete(fields, "message_truncation")
+			if stripped, err := json.Marshal(fields); err == nil {
+				body = stripped
+			}
+		}
+	}
+
+	switch mode {
+	case "", truncationDisabled:
+		return false, body, nil
+	case truncationSlidingWindow:
+		return true, body, nil
+	default:
+		return false, body, fmt.Errorf("message_truncation must be %q or %q", truncationSlidingWindow, truncationDisabled)
+	}
+}
+
+// isSystemMessage reports whether the message sets up the conversation
+// rather than taking part in it
+func isSystemMessage(m ChatCompletionMessage) bool {
+	return m.Role == "system" || m.Role == "developer"
+}
+
+// messageChars is the length of a message's text in characters; images
+// and other non-text parts don't count
+func messageChars(m ChatCompletionMessage) int {
+	return utf8.RuneCountInString(contentText(m.Content))
+}
+
+// windowMessages checks the messages against the limits and returns the
+// indexes of those to send, or nil when all of them are. Without a sliding
+// window a request over the limits is rejected. With one, system messages
+// are kept and the conversation is cut to its newest messages that fit:
+// the window closes at the message limit or at the first message too long,
+// and never opens on tool results whose call was dropped. Requests whose
+// system messages or latest message don't fit are still rejected.
+func windowMessages(messages []ChatCompletionMessage, limits messageLimits, slidingWindow bool) ([]int, *messageLimitError) {
+	tooLong := func(i int) *messageLimitError {
+		if limits.MaxMessageChars <= 0 {
+			return nil
+		}
+		if n := messageChars(messages[i]); n > limits.MaxMessageChars {
+			return &messageLimitError{
+				Code:    "message_too_long",
+				Message: fmt.Sprintf("messages[%d] is %d characters long, over your plan's limit of %d", i, n, limits.MaxMessageChars),
+			}
+		}
+		return nil
+	}
+
+	if !slidingWindow {
+		if limits.MaxMessages > 0 && len(messages) > limits.MaxMessages {
+			return nil, &messageLimitError{
+				Code: "too_many_messages",
+				Message: fmt.Sprintf("request has %d messages, over your plan's limit of %d; drop older messages or send %s: %s",
+					len(messages), limits.MaxMessages, messageTruncationHeader, truncationSlidingWindow),
+			}
+		}
+		for i := range messages {
+			if err := tooLong(i); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	var system []int
+	for i, m := range messages {
+		if !isSystemMessage(m) {
+			continue
+		}
+		if err := tooLong(i); err != nil {
+			return nil, err
+		}
+		system = append(system, i)
+	}
+	room := len(messages)
+	if limits.MaxMessages > 0 {
+		room = limits.MaxMessages - len(system)
+	}
+	if room < 1 {
+		return nil, &messageLimitError{
+			Code:    "too_many_messages",
+			Message: fmt.Sprintf("request has %d system messages, leaving no room under your plan's limit of %d messages", len(system), limits.MaxMessages),
+		}
+	}
+
+	// Walk back from the newest message; window holds indexes newest first
+	var window []int
+	for i := len(messages) - 1; i >= 0 && len(window) < room; i-- {
+		if isSystemMessage(messages[i]) {
+			continue
+		}
+		if err := tooLong(i); err != nil {
+			if len(window) == 0 {
+				return nil, err
+			}
+			break
+		}
+		window = append(window, i)
+	}
+	if len(system)+len(window) == len(messages) {
+		return nil, nil
+	}
+	for len(window) > 0 && messages[window[len(window)-1]].Role == "tool" {
+		window = window[:len(window)-1]
+	}
+	if len(window) == 0 {
+		return nil, &messageLimitError{
+			Code:    "too_many_messages",
+			Message: "the latest messages are tool results that don't fit your plan's message limit with their tool call",
+		}
+	}
+
+	kept := make([]int, 0, len(system)+len(window))
+	w := len(window) - 1
+	for _, i := range system {
+		for w >= 0 && window[w] < i {
+			kept = append(kept, window[w])
+			w--
+		}
+		kept = append(kept, i)
+	}
+	for ; w >= 0; w-- {
+		kept = append(kept, window[w])
+	}
+	return kept, nil
+}
+
+// keepMessages rewrites the request body to carry only the messages at
+// kept, leaving every other field and message field as sent
+func keepMessages(body []byte, kept []int) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	var messages []json.RawMessage
+	if err := json.Unmarshal(fields["messages"], &messages); err != nil {
+		return nil, err
+	}
+
+	window := make([]json.RawMessage, 0, len(kept))
+	for _, i := range kept {
+		if i >= len(messages) {
+			return nil, fmt.Errorf("message %d out of range", i)
+		}
+		window = append(window, messages[i])
+	}
+	encoded, err := json.Marshal(window)
+	if err != nil {
+		return nil, err
+	}
+	fields["messages"] = encoded
+	return json.Marshal(fields)
+}
+
+// applyRequestShaping enforces the tenant plan's limits on the number and
+// length of a chat request's messages. Requests over them are rejected, or
+// cut to their newest messages when the client asks for a sliding window,
+// in which case X-Messages-Truncated reports how many were dropped. The
+// returned body replaces the request's; false means the response was
+// written.
+func (g *Gateway) applyRequestShaping(w http.ResponseWriter, r *http.Request, req *ChatCompletionRequest, body []byte) ([]byte, bool) {
+	slidingWindow, body, err := parseMessageTruncation(r.Header.Get(messageTruncationHeader), body)
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return body, false
+	}
+
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok || g.Plans == nil || g.db == nil {
+		return body, true
+	}
+	plan, err := g.lookupTenantPlan(ctx, tenantID)
+	if err != nil {
+		// Shaping protects latency; it isn't worth failing the request over,
+		// nor holding the tenant to another plan's limits
+		g.logger.Warn("skipping request shaping", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		return body, true
+	}
+	limits := messageLimits{MaxMessages: plan.MaxChatMessages, MaxMessageChars: plan.MaxMessageChars}
+	if limits.MaxMessages <= 0 && limits.MaxMessageChars <= 0 {
+		return body, true
+	}
+
+	kept, limitErr := windowMessages(req.Messages, limits, slidingWindow)
+	if limitErr != nil {
+		chatRequestsShaped.WithLabelValues(plan.Name, "rejected").Inc()
+		g.writeInvalidRequest(w, limitErr.Message, limitErr.Code)
+		return body, false
+	}
+	if kept == nil {
+		return body, true
+	}
+
+	truncated, err := keepMessages(body, kept)
+	if err != nil {
+		// The body already parsed as a chat request; send it as is
+		g.logger.Warn("failed to truncate chat messages", zap.Error(err))
+		return body, true
+	}
+
+	dropped := len(req.Messages) - len(kept)
+	window := make([]ChatCompletionMessage, 0, len(kept))
+	for _, i := range kept {
+		window = append(window, req.Messages[i])
+	}
+	req.Messages = window
+
+	chatRequestsShaped.WithLabelValues(plan.Name, "truncated").Inc()
+	w.Header().Set(messagesTruncatedHeader, strconv.Itoa(dropped))
+	g.logger.Debug("truncated chat messages to a sliding window",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("plan", plan.Name),
+		zap.String("model", req.Model),
+		zap.Int("dropped", dropped),
+	)
+	return truncated, true
+}
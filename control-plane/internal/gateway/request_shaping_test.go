@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+func TestParseMessageTruncation(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		body    string
+		sliding bool
+		wantErr bool
+	}{
+		{"default", "", `{"model":"m"}`, false, false},
+		{"header", "Sliding_Window", `{"model":"m"}`, true, false},
+		{"body field", "", `{"model":"m","message_truncation":"sliding_window"}`, true, false},
+		{"header wins", "disabled", `{"model":"m","message_truncation":"sliding_window"}`, false, false},
+		{"null field", "", `{"model":"m","message_truncation":null}`, false, false},
+		{"unknown mode", "", `{"model":"m","message_truncation":"auto"}`, false, true},
+		{"not a string", "", `{"model":"m","message_truncation":true}`, false, true},
+		{"unknown header", "oldest", `{"model":"m"}`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sliding, body, err := parseMessageTruncation(tt.header, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if sliding != tt.sliding {
+				t.Errorf("sliding window = %v, want %v", sliding, tt.sliding)
+			}
+			if strings.Contains(string(body), "message_truncation") {
+				t.Errorf("body still carries message_truncation: %s", body)
+			}
+		})
+	}
+}
+
+func TestWindowMessages(t *testing.T) {
+	msg := func(role, content string) ChatCompletionMessage {
+		encoded, _ := json.Marshal(content)
+		return ChatCompletionMessage{Role: role, Content: encoded}
+	}
+	conversation := []ChatCompletionMessage{
+		msg("system", "be brief"),
+		msg("user", "one"),
+		msg("assistant", "two"),
+		msg("user", "three"),
+		msg("assistant", ""),
+		msg("tool", "four"),
+		msg("user", "five"),
+	}
+
+	tests := []struct {
+		name     string
+		messages []ChatCompletionMessage
+		limits   messageLimits
+		sliding  bool
+		want     []int
+		code     string
+	}{
+		{"within limits", conversation, messageLimits{MaxMessages: 7, MaxMessageChars: 8}, false, nil, ""},
+		{"too many", conversation, messageLimits{MaxMessages: 6}, false, nil, "too_many_messages"},
+		{"too long", conversation, messageLimits{MaxMessageChars: 5}, false, nil, "message_too_long"},
+		{"window keeps system messages", conversation, messageLimits{MaxMessages: 5}, true, []int{0, 3, 4, 5, 6}, ""},
+		{"window doesn't open on tool results", conversation, messageLimits{MaxMessages: 3}, true, []int{0, 6}, ""},
+		{"window closes at a long message", []ChatCompletionMessage{
+			msg("user", "a long question"), msg("assistant", "ok"), msg("user", "next"),
+		}, messageLimits{MaxMessageChars: 8}, true, []int{1, 2}, ""},
+		{"window fits everything", conversation, messageLimits{MaxMessages: 7}, true, nil, ""},
+		{"latest message too long", []ChatCompletionMessage{
+			msg("user", "hi"), msg("user", "a long question"),
+		}, messageLimits{MaxMessageChars: 8}, true, nil, "message_too_long"},
+		{"system messages leave no room", []ChatCompletionMessage{
+			msg("system", "a"), msg("developer", "b"), msg("user", "c"),
+		}, messageLimits{MaxMessages: 2}, true, nil, "too_many_messages"},
+		{"only tool results fit", []ChatCompletionMessage{
+			msg("user", "a"), msg("assistant", ""), msg("tool", "b"),
+		}, messageLimits{MaxMessages: 1}, true, nil, "too_many_messages"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := windowMessages(tt.messages, tt.limits, tt.sliding)
+			if tt.code != "" {
+				if err == nil || err.Code != tt.code {
+					t.Fatalf("error = %v, want code %s", err, tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("windowMessages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepMessages(t *testing.T) {
+	body := `{"model":"m","stream":true,"messages":[{"role":"system","content":"s"},{"role":"user","content":"a"},{"role":"assistant","content":null,"tool_calls":[{"id":"1"}]},{"role":"tool","tool_call_id":"1","content":"b"}]}`
+	got, err := keepMessages([]byte(body), []int{0, 2, 3})
+	if err != nil {
+		t.Fatalf("keepMessages() error = %v", err)
+	}
+
+	var req struct {
+		Model    string            `json:"model"`
+		Stream   bool              `json:"stream"`
+		Messages []json.RawMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(got, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Model != "m" || !req.Stream || len(req.Messages) != 3 {
+		t.Fatalf("keepMessages() = %s", got)
+	}
+	if !strings.Contains(string(req.Messages[1]), `"tool_calls"`) || !strings.Contains(string(req.Messages[2]), `"tool_call_id"`) {
+		t.Errorf("message fields were lost: %s", got)
+	}
+
+	if _, err := keepMessages([]byte(body), []int{4}); err == nil {
+		t.Error("expected an out of range index to fail")
+	}
+}
+
+func TestApplyRequestShapingPlanLookup(t *testing.T) {
+	cacheClient, cleanup := setupLimiterCache(t)
+	defer cleanup()
+
+	// Nothing listens on the port, so plan lookups that reach the database fail
+	pool, err := pgxpool.New(context.Background(), "postgres://crosslogic@127.0.0.1:1/crosslogic?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	g := &Gateway{db: &database.Database{Pool: pool}, cache: cacheClient, logger: zap.NewNop(), Plans: billing.NewPlanCatalog(nil)}
+
+	messages := make([]string, 60)
+	for i := range messages {
+		messages[i] = `{"role":"user","content":"hi"}`
+	}
+	body := []byte(`{"model":"m","messages":[` + strings.Join(messages, ",") + `]}`)
+	shape := func(tenantID uuid.UUID) (int, bool) {
+		var req ChatCompletionRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		r = r.WithContext(context.WithValue(r.Context(), "tenant_id", tenantID))
+		rec := httptest.NewRecorder()
+		_, ok := g.applyRequestShaping(rec, r, &req, body)
+		return rec.Code, ok
+	}
+
+	// A failed lookup skips shaping rather than applying free-plan limits
+	if code, ok := shape(uuid.New()); !ok {
+		t.Errorf("plan lookup failure rejected the request with %d", code)
+	}
+
+	// Cached plans are enforced without the database
+	free, enterprise := uuid.New(), uuid.New()
+	ctx := context.Background()
+	cacheClient.Set(ctx, tenantPlanCacheKey(free), "free", tenantPlanCacheTTL)
+	cacheClient.Set(ctx, tenantPlanCacheKey(enterprise), "enterprise", tenantPlanCacheTTL)
+	if code, ok := shape(free); ok || code != http.StatusBadRequest {
+		t.Errorf("free plan over its message limit: ok = %v, status %d, want 400", ok, code)
+	}
+	if _, ok := shape(enterprise); !ok {
+		t.Error("enterprise plan without message limits was rejected")
+	}
+}
@@ -0,0 +1,452 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Request signing.
+//
+// High-security tenants can require every request to carry an HMAC-SHA256
+// signature made with a signing secret that is separate from the bearer key,
+// so a leaked API key alone is not enough to call the API. Clients send:
+//
+//	X-Signature-Timestamp: <unix seconds>
+//	X-Signature-Nonce:     <unique random string, 16-128 chars>
+//	X-Signature:           v1=<hex HMAC-SHA256(secret, canonical request)>
+//
+// where the canonical request is "v1\n{timestamp}\n{nonce}\n{METHOD}\n{path
+// and query}\n{hex SHA-256 of body}". Timestamps must be within
+// requestSigningTolerance of server time and nonces are remembered in Redis
+// for twice that, so captured requests cannot be replayed.
+const (
+	signatureTimestampHeader = "X-Signature-Timestamp"
+	signatureNonceHeader     = "X-Signature-Nonce"
+	signatureHeader          = "X-Signature"
+	signatureVersion         = "v1"
+
+	// requestSigningTolerance is the allowed clock skew between client and server
+	requestSigningTolerance = 5 * time.Minute
+	// requestSigningRotationGrace keeps the previous secret valid after a rotation
+	requestSigningRotationGrace = 24 * time.Hour
+	// requestSigningCacheTTL bounds how long signing settings are cached
+	requestSigningCacheTTL = 60 * time.Second
+)
+
+// signatureError is a rejected signature with an OpenAI-style error code
+type signatureError struct {
+	code    string
+	message string
+}
+
+func (e *signatureError) Error() string { return e.message }
+
+// canonicalSigningString builds the string a request signature covers
+func canonicalSigningString(timestamp, nonce, method, requestURI string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		signatureVersion,
+		timestamp,
+		nonce,
+		strings.ToUpper(method),
+		requestURI,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// computeRequestSignature returns the hex HMAC-SHA256 of the canonical request
+func computeRequestSignature(secret, timestamp, nonce, method, requestURI string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonicalSigningString(timestamp, nonce, method, requestURI, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyRequestSignature checks the signature headers against any of the
+// tenant's valid secrets. Nonce replay is checked separately.
+func verifyRequestSignature(header http.Header, method, requestURI string, body []byte, secrets []string, now time.Time) error {
+	timestamp := header.Get(signatureTimestampHeader)
+	nonce := header.Get(signatureNonceHeader)
+	signature := header.Get(signatureHeader)
+	if timestamp == "" || nonce == "" || signature == "" {
+		return &signatureError{"missing_signature", fmt.Sprintf("this tenant requires signed requests; send %s, %s and %s", signatureTimestampHeader, signatureNonceHeader, signatureHeader)}
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return &signatureError{"invalid_signature", fmt.Sprintf("%s must be a unix timestamp in seconds", signatureTimestampHeader)}
+	}
+	skew := now.Sub(time.Unix(ts, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > requestSigningTolerance {
+		return &signatureError{"signature_expired", fmt.Sprintf("request timestamp is more than %s from server time", requestSigningTolerance)}
+	}
+
+	if len(nonce) < 16 || len(nonce) > 128 {
+		return &signatureError{"invalid_signature", fmt.Sprintf("%s must be 16-128 characters", signatureNonceHeader)}
+	}
+
+	provided, ok := strings.CutPrefix(signature, signatureVersion+"=")
+	if !ok {
+		return &signatureError{"invalid_signature", fmt.Sprintf("%s must have the form %s=<hex>", signatureHeader, signatureVersion)}
+	}
+	providedMAC, err := hex.DecodeString(provided)
+	if err != nil {
+		return &signatureError{"invalid_signature", fmt.Sprintf("%s must have the form %s=<hex>", signatureHeader, signatureVersion)}
+	}
+
+	for _, secret := range secrets {
+		expected, _ := hex.DecodeString(computeRequestSignature(secret, timestamp, nonce, method, requestURI, body))
+		if hmac.Equal(providedMAC, expected) {
+			return nil
+		}
+	}
+	return &signatureError{"invalid_signature", "request signature does not match"}
+}
+
+// requestSigningSettings is a tenant's signing configuration as cached in
+// Redis. Secrets stay encrypted in the cache.
+type requestSigningSettings struct {
+	Configured      bool       `json:"configured"`
+	Required        bool       `json:"required"`
+	Secret          []byte     `json:"secret,omitempty"`
+	PreviousSecret  []byte     `json:"previous_secret,omitempty"`
+	PreviousExpires *time.Time `json:"previous_expires_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	RotatedAt       *time.Time `json:"rotated_at,omitempty"`
+}
+
+func requestSigningCacheKey(tenantID uuid.UUID) string {
+	return cache.TenantKey(tenantID, "request_signing")
+}
+
+// loadRequestSigning returns the tenant's signing settings, from cache when possible
+func (g *Gateway) loadRequestSigning(ctx context.Context, tenantID uuid.UUID) (*requestSigningSettings, error) {
+	if cached, err := g.cache.Get(ctx, requestSigningCacheKey(tenantID)); err == nil {
+		var settings requestSigningSettings
+		if err := json.Unmarshal([]byte(cached), &settings); err == nil {
+			return &settings, nil
+		}
+	}
+
+	settings := &requestSigningSettings{}
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT required, secret_encrypted, previous_secret_encrypted, previous_expires_at, created_at, rotated_at
+		FROM tenant_request_signing
+		WHERE tenant_id = $1
+	`, tenantID).Scan(&settings.Required, &settings.Secret, &settings.PreviousSecret,
+		&settings.PreviousExpires, &settings.CreatedAt, &settings.RotatedAt)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return nil, err
+	default:
+		settings.Configured = true
+	}
+
+	encoded, _ := json.Marshal(settings)
+	if err := g.cache.Set(ctx, requestSigningCacheKey(tenantID), string(encoded), requestSigningCacheTTL); err != nil {
+		g.logger.Debug("failed to cache request signing settings", zap.Error(err))
+	}
+	return settings, nil
+}
+
+// signingSecrets decrypts the secrets currently valid for verification
+func (g *Gateway) signingSecrets(settings *requestSigningSettings, now time.Time) ([]string, error) {
+	if g.SigningSecrets == nil {
+		return nil, fmt.Errorf("request signing encryption key is not configured")
+	}
+
+	var secrets []string
+	var secret string
+	if err := g.SigningSecrets.Decrypt(settings.Secret, &secret); err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing secret: %w", err)
+	}
+	secrets = append(secrets, secret)
+
+	if len(settings.PreviousSecret) > 0 && settings.PreviousExpires != nil && now.Before(*settings.PreviousExpires) {
+		var previous string
+		if err := g.SigningSecrets.Decrypt(settings.PreviousSecret, &previous); err == nil {
+			secrets = append(secrets, previous)
+		}
+	}
+	return secrets, nil
+}
+
+// requestSigningMiddleware verifies request signatures for tenants that
+// require them. Tenants with a secret that do not yet require signing get
+// their signatures verified when present, so clients can be tested before
+// enforcement is switched on. It must run after authMiddleware.
+func (g *Gateway) requestSigningMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		settings, err := g.loadRequestSigning(ctx, tenantID)
+		if err != nil {
+			g.logger.Error("failed to load request signing settings", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+			g.writeError(w, http.StatusInternalServerError, "failed to verify request signature")
+			return
+		}
+
+		signed := r.Header.Get(signatureHeader) != ""
+		if !settings.Configured || (!settings.Required && !signed) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		secrets, err := g.signingSecrets(settings, time.Now())
+		if err != nil {
+			g.logger.Error("failed to load signing secrets", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+			g.writeError(w, http.StatusInternalServerError, "failed to verify request signature")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			g.writeError(w, http.StatusBadRequest, "failed to read request body")
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if err := verifyRequestSignature(r.Header, r.Method, r.URL.RequestURI(), body, secrets, time.Now()); err != nil {
+			g.writeSignatureError(w, err.(*signatureError))
+			return
+		}
+
+		// Reject replays of a captured request within the tolerance window
+		nonceKey := cache.TenantKey(tenantID, "request_signing_nonce", r.Header.Get(signatureNonceHeader))
+		fresh, err := g.cache.SetNX(ctx, nonceKey, 1, 2*requestSigningTolerance)
+		if err != nil {
+			// Fail closed: without nonce tracking a replay cannot be ruled out
+			g.logger.Error("failed to record request nonce", zap.Error(err))
+			g.writeError(w, http.StatusServiceUnavailable, "failed to verify request signature")
+			return
+		}
+		if !fresh {
+			g.writeSignatureError(w, &signatureError{"replayed_request", "request nonce has already been used"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeSignatureError writes a 401 for a rejected request signature
+func (g *Gateway) writeSignatureError(w http.ResponseWriter, err *signatureError) {
+	g.writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+		"error": map[string]string{
+			"message": err.message,
+			"type":    "authentication_error",
+			"code":    err.code,
+		},
+	})
+}
+
+// requestSigningStatus is the tenant view of its signing configuration
+func requestSigningStatus(settings *requestSigningSettings) map[string]interface{} {
+	status := map[string]interface{}{
+		"enabled":  settings.Configured,
+		"required": settings.Required,
+	}
+	if settings.Configured {
+		status["created_at"] = settings.CreatedAt
+		status["rotated_at"] = settings.RotatedAt
+		if settings.PreviousExpires != nil && time.Now().Before(*settings.PreviousExpires) {
+			status["previous_secret_expires_at"] = settings.PreviousExpires
+		}
+	}
+	return status
+}
+
+// requireSigningAdmin rejects read-only keys from changing signing settings.
+// It returns false when the request has been answered.
+func (g *Gateway) requireSigningAdmin(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return uuid.Nil, false
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); ok && keyInfo.Role == "read-only" {
+		g.writeError(w, http.StatusForbidden, "read-only API keys cannot change request signing")
+		return uuid.Nil, false
+	}
+	if g.SigningSecrets == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "request signing is not configured")
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// handleGetRequestSigning returns the tenant's request signing status
+// Tenant API - GET /v1/security/request-signing
+func (g *Gateway) handleGetRequestSigning(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+
+	settings, err := g.loadRequestSigning(ctx, tenantID)
+	if err != nil {
+		g.logger.Error("failed to load request signing settings", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to load request signing settings")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, requestSigningStatus(settings))
+}
+
+// handleRotateSigningSecret creates the tenant's signing secret, or replaces
+// it while keeping the previous one valid for requestSigningRotationGrace.
+// The secret is only returned by this call.
+// Tenant API - POST /v1/security/request-signing/rotate
+func (g *Gateway) handleRotateSigningSecret(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := g.requireSigningAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		g.writeError(w, http.StatusInternalServerError, "failed to generate signing secret")
+		return
+	}
+	secret := "clsig_" + hex.EncodeToString(raw)
+
+	encrypted, err := g.SigningSecrets.Encrypt(secret)
+	if err != nil {
+		g.logger.Error("failed to encrypt signing secret", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to store signing secret")
+		return
+	}
+
+	_, err = g.db.Pool.Exec(ctx, `
+		INSERT INTO tenant_request_signing (tenant_id, secret_encrypted, key_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			previous_secret_encrypted = tenant_request_signing.secret_encrypted,
+			previous_expires_at = NOW() + $4::interval,
+			secret_encrypted = EXCLUDED.secret_encrypted,
+			key_id = EXCLUDED.key_id,
+			rotated_at = NOW()
+	`, tenantID, encrypted, g.SigningSecrets.GetKeyID(), fmt.Sprintf("%d seconds", int(requestSigningRotationGrace.Seconds())))
+	if err != nil {
+		g.logger.Error("failed to store signing secret", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to store signing secret")
+		return
+	}
+	g.invalidateRequestSigning(ctx, tenantID)
+
+	g.logger.Info("request signing secret rotated", zap.String("tenant_id", tenantID.String()))
+
+	settings, err := g.loadRequestSigning(ctx, tenantID)
+	if err != nil {
+		settings = &requestSigningSettings{Configured: true}
+	}
+	response := requestSigningStatus(settings)
+	response["secret"] = secret
+	g.writeJSON(w, http.StatusOK, response)
+}
+
+// handleUpdateRequestSigning switches enforcement on or off
+// Tenant API - PUT /v1/security/request-signing
+func (g *Gateway) handleUpdateRequestSigning(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := g.requireSigningAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Required *bool `json:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Required == nil {
+		g.writeError(w, http.StatusBadRequest, "required is required")
+		return
+	}
+
+	tag, err := g.db.Pool.Exec(ctx, `
+		UPDATE tenant_request_signing SET required = $2 WHERE tenant_id = $1
+	`, tenantID, *req.Required)
+	if err != nil {
+		g.logger.Error("failed to update request signing", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update request signing")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		g.writeError(w, http.StatusConflict, "create a signing secret with POST /v1/security/request-signing/rotate first")
+		return
+	}
+	g.invalidateRequestSigning(ctx, tenantID)
+
+	g.logger.Info("request signing enforcement changed",
+		zap.String("tenant_id", tenantID.String()),
+		zap.Bool("required", *req.Required),
+	)
+
+	settings, err := g.loadRequestSigning(ctx, tenantID)
+	if err != nil {
+		g.writeError(w, http.StatusInternalServerError, "failed to load request signing settings")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, requestSigningStatus(settings))
+}
+
+// handleDeleteRequestSigning removes the tenant's signing secret and enforcement
+// Tenant API - DELETE /v1/security/request-signing
+func (g *Gateway) handleDeleteRequestSigning(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID, ok := g.requireSigningAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := g.db.Pool.Exec(ctx, `DELETE FROM tenant_request_signing WHERE tenant_id = $1`, tenantID); err != nil {
+		g.logger.Error("failed to delete request signing", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to disable request signing")
+		return
+	}
+	g.invalidateRequestSigning(ctx, tenantID)
+
+	g.logger.Info("request signing disabled", zap.String("tenant_id", tenantID.String()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// invalidateRequestSigning drops cached signing settings after a change
+func (g *Gateway) invalidateRequestSigning(ctx context.Context, tenantID uuid.UUID) {
+	if err := g.cache.Delete(ctx, requestSigningCacheKey(tenantID)); err != nil {
+		g.logger.Warn("failed to invalidate request signing cache", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+	}
+}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyRequestSignature(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"model":"llama-3-8b","messages":[]}`)
+	uri := "/v1/chat/completions"
+	nonce := "3f9a1c7e5b2d4a60"
+
+	signed := func(secret string, ts time.Time, nonce string) http.Header {
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+		h := http.Header{}
+		h.Set(signatureTimestampHeader, timestamp)
+		h.Set(signatureNonceHeader, nonce)
+		h.Set(signatureHeader, "v1="+computeRequestSignature(secret, timestamp, nonce, http.MethodPost, uri, body))
+		return h
+	}
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		body     []byte
+		secrets  []string
+		wantCode string
+	}{
+		{"valid", signed("current", now, nonce), body, []string{"current"}, ""},
+		{"previous secret during rotation", signed("previous", now, nonce), body, []string{"current", "previous"}, ""},
+		{"within skew", signed("current", now.Add(-4*time.Minute), nonce), body, []string{"current"}, ""},
+		{"missing headers", http.Header{}, body, []string{"current"}, "missing_signature"},
+		{"wrong secret", signed("other", now, nonce), body, []string{"current"}, "invalid_signature"},
+		{"tampered body", signed("current", now, nonce), []byte(`{"model":"gpt-4"}`), []string{"current"}, "invalid_signature"},
+		{"expired", signed("current", now.Add(-6*time.Minute), nonce), body, []string{"current"}, "signature_expired"},
+		{"future", signed("current", now.Add(6*time.Minute), nonce), body, []string{"current"}, "signature_expired"},
+		{"short nonce", signed("current", now, "abc"), body, []string{"current"}, "invalid_signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyRequestSignature(tt.header, http.MethodPost, uri, tt.body, tt.secrets, now)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("verifyRequestSignature() error = %v", err)
+				}
+				return
+			}
+			var sigErr *signatureError
+			if !errors.As(err, &sigErr) || sigErr.code != tt.wantCode {
+				t.Errorf("verifyRequestSignature() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestVerifyRequestSignatureMalformed(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	h := http.Header{}
+	h.Set(signatureTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	h.Set(signatureNonceHeader, "3f9a1c7e5b2d4a60")
+
+	for _, signature := range []string{"deadbeef", "v1=not-hex", "v2=deadbeef"} {
+		h.Set(signatureHeader, signature)
+		err := verifyRequestSignature(h, http.MethodGet, "/v1/models", nil, []string{"secret"}, now)
+		var sigErr *signatureError
+		if !errors.As(err, &sigErr) || sigErr.code != "invalid_signature" {
+			t.Errorf("signature %q: error = %v, want invalid_signature", signature, err)
+		}
+	}
+}
+
+func TestCanonicalSigningString(t *testing.T) {
+	got := canonicalSigningString("1700000000", "nonce", "post", "/v1/models?limit=5", nil)
+	want := "v1\n1700000000\nnonce\nPOST\n/v1/models?limit=5\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if got != want {
+		t.Errorf("canonicalSigningString() = %q, want %q", got, want)
+	}
+}
@@ -25,20 +25,39 @@ func (g *Gateway) setupExtendedRoutes(r chi.Router) {
 	r.Delete("/admin/instance-types/{id}", g.handleDeleteInstanceType)
 	r.Post("/admin/instance-types/{id}/regions", g.handleAssociateInstanceTypeRegions)
 	r.Get("/admin/instance-types/{id}/pricing", g.handleGetInstanceTypePricing)
+
+	// === ADMIN CATALOG SYNC ===
+	r.Post("/admin/catalog/sync", g.handleTriggerCatalogSync)
+	r.Get("/admin/catalog/sync/runs", g.handleListCatalogSyncRuns)
+
+	// === ADMIN SKYPILOT TASK TEMPLATES ===
+	r.Get("/admin/skypilot/templates", g.handleListTaskTemplates)
+	r.Post("/admin/skypilot/templates", g.handleUploadTaskTemplate)
+	r.Post("/admin/skypilot/templates/validate", g.handleValidateTaskTemplate)
+	r.Get("/admin/skypilot/templates/{runtime}/resolve", g.handleResolveTaskTemplate)
+	r.Get("/admin/skypilot/templates/{runtime}/versions", g.handleListTaskTemplateVersions)
+	r.Get("/admin/skypilot/templates/{runtime}/versions/{version}", g.handleGetTaskTemplateVersion)
+	r.Post("/admin/skypilot/templates/{runtime}/versions/{version}/activate", g.handleActivateTaskTemplateVersion)
+	r.Delete("/admin/skypilot/templates/{runtime}/override", g.handleDeactivateTaskTemplate)
+
+	// === ADMIN LAUNCH PROFILES (platform defaults) ===
+	r.Get("/admin/launch-profiles", g.handleListPlatformLaunchProfiles)
+	r.Post("/admin/launch-profiles", g.handleSavePlatformLaunchProfile)
+	r.Delete("/admin/launch-profiles/{name}", g.handleDeletePlatformLaunchProfile)
 }
 
 // setupExtendedTenantRoutes registers all new tenant API routes
-// Call this from setupRoutes() to add the new tenant handlers
+// Call this from setupTenantRoutes() to add the new tenant handlers
 func (g *Gateway) setupExtendedTenantRoutes(r chi.Router) {
 	// === TENANT USAGE (Extended) ===
-	r.Get("/v1/usage/detailed", g.handleGetUsageDetailed)
-	r.Get("/v1/usage/by-hour", g.handleGetUsageByHour)
-	r.Get("/v1/usage/by-day", g.handleGetUsageByDay)
-	r.Get("/v1/usage/by-week", g.handleGetUsageByWeek)
-	r.Get("/v1/usage/by-month", g.handleGetUsageByMonth)
+	r.Get("/usage/detailed", g.handleGetUsageDetailed)
+	r.Get("/usage/by-hour", g.handleGetUsageByHour)
+	r.Get("/usage/by-day", g.handleGetUsageByDay)
+	r.Get("/usage/by-week", g.handleGetUsageByWeek)
+	r.Get("/usage/by-month", g.handleGetUsageByMonth)
 
 	// === TENANT METRICS (Extended) ===
-	r.Get("/v1/metrics/performance", g.handleGetPerformanceMetrics)
-	r.Get("/v1/metrics/throughput", g.handleGetThroughputMetrics)
-	r.Get("/v1/metrics/by-model", g.handleGetModelMetrics)
+	r.Get("/metrics/performance", g.handleGetPerformanceMetrics)
+	r.Get("/metrics/throughput", g.handleGetThroughputMetrics)
+	r.Get("/metrics/by-model", g.handleGetModelMetrics)
 }
@@ -0,0 +1,710 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// Routing experiments.
+//
+// When a model is served by more than one node class (GPU type, spot or
+// on-demand), an operator can start an experiment to find the cheapest class
+// that still meets a latency SLO. It is an epsilon-greedy bandit: a small
+// exploration fraction of the model's requests goes to the class with the
+// fewest samples, and the rest to the cheapest class known to meet the SLO,
+// or to the normal scoring while none is known. Once every class has enough
+// samples the experiment converges and the model's traffic stays on the
+// winner until the experiment is stopped.
+//
+// Cost is the node's hourly price for the time a request held it, so it ranks
+// classes by price and speed at equal load rather than giving billed cost.
+// Counters are kept per replica and added to Postgres on each refresh, so
+// every gateway replica routes on the same totals.
+const (
+	defaultExperimentSLOTarget  = 0.95
+	defaultExperimentExplore    = 0.1
+	defaultExperimentMinSamples = 200
+
+	experimentStatusRunning   = "running"
+	experimentStatusConverged = "converged"
+	experimentStatusStopped   = "stopped"
+)
+
+// endpointClass is the node class and price of an endpoint
+type endpointClass struct {
+	Model        string
+	Class        string
+	PricePerHour float64
+}
+
+// nodeClassKey names a node class, e.g. "A100:spot"
+func nodeClassKey(gpuType string, spot bool) string {
+	if gpuType == "" {
+		gpuType = "unknown"
+	}
+	if spot {
+		return gpuType + ":spot"
+	}
+	return gpuType + ":on-demand"
+}
+
+// ExperimentArm is what an experiment measured for one node class
+type ExperimentArm struct {
+	NodeClass       string  `json:"node_class"`
+	Requests        int64   `json:"requests"`
+	Errors          int64   `json:"errors"`
+	SLOViolations   int64   `json:"slo_violations"`
+	LatencyMsSum    int64   `json:"-"`
+	Tokens          int64   `json:"tokens"`
+	CostUSD         float64 `json:"cost_usd"`
+	MeanLatencyMs   float64 `json:"mean_latency_ms"`
+	SLOAttainment   float64 `json:"slo_attainment"`
+	CostPer1KTokens float64 `json:"cost_per_1k_tokens"`
+	MeetsSLO        bool    `json:"meets_slo"`
+}
+
+// add accumulates another arm's counters
+func (a *ExperimentArm) add(o *ExperimentArm) {
+	a.Requests += o.Requests
+	a.Errors += o.Errors
+	a.SLOViolations += o.SLOViolations
+	a.LatencyMsSum += o.LatencyMsSum
+	a.Tokens += o.Tokens
+	a.CostUSD += o.CostUSD
+}
+
+// summarize fills in the derived fields for an experiment's SLO
+func (a *ExperimentArm) summarize(exp *RoutingExperiment) {
+	if a.Requests == 0 {
+		return
+	}
+	a.MeanLatencyMs = float64(a.LatencyMsSum) / float64(a.Requests)
+	a.SLOAttainment = 1 - float64(a.SLOViolations)/float64(a.Requests)
+	if a.Tokens > 0 {
+		a.CostPer1KTokens = a.CostUSD / float64(a.Tokens) * 1000
+	}
+	a.MeetsSLO = a.Requests >= int64(exp.MinSamples) && a.Tokens > 0 && a.SLOAttainment >= exp.SLOTarget
+}
+
+// RoutingExperiment is a bandit experiment over one model's node classes
+type RoutingExperiment struct {
+	ID              uuid.UUID       `json:"id"`
+	Model           string          `json:"model"`
+	Status          string          `json:"status"`
+	LatencySLOMs    int             `json:"latency_slo_ms"`
+	SLOTarget       float64         `json:"slo_target"`
+	ExploreFraction float64         `json:"explore_fraction"`
+	MinSamples      int             `json:"min_samples"`
+	WinnerClass     *string         `json:"winner_class,omitempty"`
+	Reason          *string         `json:"reason,omitempty"`
+	StartedAt       time.Time       `json:"started_at"`
+	ConvergedAt     *time.Time      `json:"converged_at,omitempty"`
+	StoppedAt       *time.Time      `json:"stopped_at,omitempty"`
+	BestClass       *string         `json:"best_class,omitempty"`
+	Arms            []ExperimentArm `json:"arms,omitempty"`
+}
+
+// liveExperiment is a running or converged experiment as the load balancer
+// sees it: totals from Postgres and this replica's unflushed counts
+type liveExperiment struct {
+	RoutingExperiment
+	totals  map[string]*ExperimentArm
+	pending map[string]*ExperimentArm
+}
+
+// bestClass returns the cheapest class meeting the SLO among those in
+// classes, or "" when none is known yet
+func (e *liveExperiment) bestClass(classes map[string]bool) string {
+	best, bestCost := "", 0.0
+	for class := range classes {
+		arm, ok := e.totals[class]
+		if !ok {
+			continue
+		}
+		a := *arm
+		a.summarize(&e.RoutingExperiment)
+		if !a.MeetsSLO {
+			continue
+		}
+		if best == "" || a.CostPer1KTokens < bestCost || (a.CostPer1KTokens == bestCost && class < best) {
+			best, bestCost = class, a.CostPer1KTokens
+		}
+	}
+	return best
+}
+
+// leastSampled returns the class in classes with the fewest requests
+func (e *liveExperiment) leastSampled(classes map[string]bool) string {
+	least, fewest := "", int64(-1)
+	for class := range classes {
+		var n int64
+		if arm, ok := e.totals[class]; ok {
+			n = arm.Requests
+		}
+		if p, ok := e.pending[class]; ok {
+			n += p.Requests
+		}
+		if fewest < 0 || n < fewest || (n == fewest && class < least) {
+			least, fewest = class, n
+		}
+	}
+	return least
+}
+
+// converged reports whether every class has enough samples and one of them
+// meets the SLO, and returns that class
+func (e *liveExperiment) converged(classes map[string]bool) (string, bool) {
+	if len(classes) == 0 {
+		return "", false
+	}
+	for class := range classes {
+		arm, ok := e.totals[class]
+		if !ok || arm.Requests < int64(e.MinSamples) {
+			return "", false
+		}
+	}
+	best := e.bestClass(classes)
+	return best, best != ""
+}
+
+// applyExperiment narrows a model's endpoints to the node class its
+// experiment picks for this request. Callers must hold lb.mu.
+func (lb *IntelligentLoadBalancer) applyExperiment(modelName string, endpoints []string) []string {
+	exp, ok := lb.experiments[modelName]
+	if !ok || len(endpoints) == 0 {
+		return endpoints
+	}
+
+	classes := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		if c, ok := lb.endpointClasses[endpoint]; ok {
+			classes[c.Class] = true
+		}
+	}
+
+	var class string
+	switch {
+	case exp.Status == experimentStatusConverged && exp.WinnerClass != nil:
+		class = *exp.WinnerClass
+	case len(classes) < 2:
+		return endpoints
+	case lb.random != nil && lb.random() < exp.ExploreFraction:
+		class = exp.leastSampled(classes)
+	default:
+		class = exp.bestClass(classes)
+	}
+	if class == "" || !classes[class] {
+		return endpoints
+	}
+
+	var chosen []string
+	for _, endpoint := range endpoints {
+		if lb.endpointClasses[endpoint].Class == class {
+			chosen = append(chosen, endpoint)
+		}
+	}
+	return chosen
+}
+
+// RecordExperimentOutcome counts a finished request towards the experiment
+// on its model, if any
+func (lb *IntelligentLoadBalancer) RecordExperimentOutcome(modelName, endpoint string, latency time.Duration, tokens int, isError bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	exp, ok := lb.experiments[modelName]
+	if !ok {
+		return
+	}
+	c, ok := lb.endpointClasses[endpoint]
+	if !ok {
+		return
+	}
+
+	arm, ok := exp.pending[c.Class]
+	if !ok {
+		arm = &ExperimentArm{NodeClass: c.Class}
+		exp.pending[c.Class] = arm
+	}
+	arm.Requests++
+	if isError {
+		arm.Errors++
+	}
+	if isError || latency > time.Duration(exp.LatencySLOMs)*time.Millisecond {
+		arm.SLOViolations++
+	}
+	arm.LatencyMsSum += latency.Milliseconds()
+	if tokens > 0 {
+		arm.Tokens += int64(tokens)
+	}
+	arm.CostUSD += c.PricePerHour * latency.Hours()
+}
+
+// LoadExperiments adds this replica's counts to Postgres, reloads the live
+// experiments and node classes, and converges experiments that have found
+// their winner
+func (lb *IntelligentLoadBalancer) LoadExperiments(ctx context.Context) error {
+	lb.flushExperimentCounts(ctx)
+
+	experiments := make(map[string]*liveExperiment)
+	rows, err := lb.db.Pool.Query(ctx, `
+		SELECT id, model_name, status, latency_slo_ms, slo_target, explore_fraction, min_samples,
+		       winner_class, reason, started_at, converged_at
+		FROM routing_experiments
+		WHERE status IN ('running', 'converged')
+	`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		e := &liveExperiment{totals: make(map[string]*ExperimentArm), pending: make(map[string]*ExperimentArm)}
+		if err := rows.Scan(&e.ID, &e.Model, &e.Status, &e.LatencySLOMs, &e.SLOTarget, &e.ExploreFraction,
+			&e.MinSamples, &e.WinnerClass, &e.Reason, &e.StartedAt, &e.ConvergedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		experiments[e.Model] = e
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	classes := make(map[string]endpointClass)
+	if len(experiments) > 0 {
+		byID := make(map[uuid.UUID]*liveExperiment, len(experiments))
+		ids := make([]uuid.UUID, 0, len(experiments))
+		for _, e := range experiments {
+			byID[e.ID] = e
+			ids = append(ids, e.ID)
+		}
+		if err := lb.loadExperimentArms(ctx, ids, byID); err != nil {
+			return err
+		}
+		if classes, err = lb.loadEndpointClasses(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range experiments {
+		if e.Status != experimentStatusRunning {
+			continue
+		}
+		modelClasses := make(map[string]bool)
+		for _, c := range classes {
+			if c.Model == e.Model {
+				modelClasses[c.Class] = true
+			}
+		}
+		winner, ok := e.converged(modelClasses)
+		if !ok {
+			continue
+		}
+		tag, err := lb.db.Pool.Exec(ctx, `
+			UPDATE routing_experiments
+			SET status = 'converged', winner_class = $2, converged_at = NOW()
+			WHERE id = $1 AND status = 'running'
+		`, e.ID, winner)
+		if err != nil {
+			lb.logger.Warn("failed to converge routing experiment", zap.Error(err), zap.String("experiment_id", e.ID.String()))
+			continue
+		}
+		if tag.RowsAffected() > 0 {
+			lb.logger.Info("routing experiment converged",
+				zap.String("experiment_id", e.ID.String()),
+				zap.String("model", e.Model),
+				zap.String("winner_class", winner),
+			)
+		}
+		e.Status = experimentStatusConverged
+		e.WinnerClass = &winner
+	}
+
+	lb.mu.Lock()
+	for model, e := range experiments {
+		// Keep counts recorded since the flush
+		if old, ok := lb.experiments[model]; ok && old.ID == e.ID {
+			e.pending = old.pending
+		}
+	}
+	lb.experiments = experiments
+	lb.endpointClasses = classes
+	lb.mu.Unlock()
+	return nil
+}
+
+// flushExperimentCounts adds the counts recorded since the last flush to
+// the experiment arms. Counts that fail to save are kept for the next flush.
+func (lb *IntelligentLoadBalancer) flushExperimentCounts(ctx context.Context) {
+	type pendingArms struct {
+		id   uuid.UUID
+		arms map[string]*ExperimentArm
+	}
+	var flush []pendingArms
+
+	lb.mu.Lock()
+	for _, e := range lb.experiments {
+		if len(e.pending) > 0 {
+			flush = append(flush, pendingArms{id: e.ID, arms: e.pending})
+			e.pending = make(map[string]*ExperimentArm)
+		}
+	}
+	lb.mu.Unlock()
+
+	for _, p := range flush {
+		for class, arm := range p.arms {
+			_, err := lb.db.Pool.Exec(ctx, `
+				INSERT INTO routing_experiment_arms
+					(experiment_id, node_class, requests, errors, slo_violations, latency_ms_sum, tokens, cost_usd, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+				ON CONFLICT (experiment_id, node_class) DO UPDATE SET
+					requests = routing_experiment_arms.requests + EXCLUDED.requests,
+					errors = routing_experiment_arms.errors + EXCLUDED.errors,
+					slo_violations = routing_experiment_arms.slo_violations + EXCLUDED.slo_violations,
+					latency_ms_sum = routing_experiment_arms.latency_ms_sum + EXCLUDED.latency_ms_sum,
+					tokens = routing_experiment_arms.tokens + EXCLUDED.tokens,
+					cost_usd = routing_experiment_arms.cost_usd + EXCLUDED.cost_usd,
+					updated_at = NOW()
+			`, p.id, class, arm.Requests, arm.Errors, arm.SLOViolations, arm.LatencyMsSum, arm.Tokens, arm.CostUSD)
+			if err == nil {
+				continue
+			}
+			lb.logger.Warn("failed to save routing experiment counts", zap.Error(err), zap.String("experiment_id", p.id.String()))
+
+			lb.mu.Lock()
+			for _, e := range lb.experiments {
+				if e.ID != p.id {
+					continue
+				}
+				if existing, ok := e.pending[class]; ok {
+					existing.add(arm)
+				} else {
+					e.pending[class] = arm
+				}
+			}
+			lb.mu.Unlock()
+		}
+	}
+}
+
+// loadExperimentArms reads the arm totals of the given experiments
+func (lb *IntelligentLoadBalancer) loadExperimentArms(ctx context.Context, ids []uuid.UUID, byID map[uuid.UUID]*liveExperiment) error {
+	rows, err := lb.db.Pool.Query(ctx, `
+		SELECT experiment_id, node_class, requests, errors, slo_violations, latency_ms_sum, tokens, cost_usd
+		FROM routing_experiment_arms
+		WHERE experiment_id = ANY($1)
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		arm := &ExperimentArm{}
+		if err := rows.Scan(&id, &arm.NodeClass, &arm.Requests, &arm.Errors, &arm.SLOViolations,
+			&arm.LatencyMsSum, &arm.Tokens, &arm.CostUSD); err != nil {
+			return err
+		}
+		if e, ok := byID[id]; ok {
+			e.totals[arm.NodeClass] = arm
+		}
+	}
+	return rows.Err()
+}
+
+// loadEndpointClasses reads the node class and hourly price of every active
+// endpoint. Spot nodes use their recorded spot price when there is one.
+func (lb *IntelligentLoadBalancer) loadEndpointClasses(ctx context.Context) (map[string]endpointClass, error) {
+	rows, err := lb.db.Pool.Query(ctx, `
+		SELECT n.endpoint_url, n.model_name, COALESCE(n.gpu_type, ''), COALESCE(n.spot_instance, false),
+		       COALESCE(CASE WHEN n.spot_instance THEN COALESCE(n.spot_price, it.spot_price_per_hour) END,
+		                it.price_per_hour, 0)::float8
+		FROM nodes n
+		LEFT JOIN LATERAL (
+			SELECT price_per_hour, spot_price_per_hour FROM instance_types
+			WHERE provider = n.provider AND instance_type = n.instance_type
+			LIMIT 1
+		) it ON true
+		WHERE n.status = 'active' AND n.endpoint_url != '' AND NOT n.standby AND n.model_name IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classes := make(map[string]endpointClass)
+	for rows.Next() {
+		var endpoint, gpuType string
+		var spot bool
+		var c endpointClass
+		if err := rows.Scan(&endpoint, &c.Model, &gpuType, &spot, &c.PricePerHour); err != nil {
+			return nil, err
+		}
+		c.Class = nodeClassKey(gpuType, spot)
+		classes[endpoint] = c
+	}
+	return classes, rows.Err()
+}
+
+// loadRoutingExperiment reads an experiment with its arms and, while it is
+// live, the class it currently prefers
+func (g *Gateway) loadRoutingExperiment(ctx context.Context, id uuid.UUID) (*RoutingExperiment, error) {
+	e := &liveExperiment{totals: make(map[string]*ExperimentArm)}
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT id, model_name, status, latency_slo_ms, slo_target, explore_fraction, min_samples,
+		       winner_class, reason, started_at, converged_at, stopped_at
+		FROM routing_experiments WHERE id = $1
+	`, id).Scan(&e.ID, &e.Model, &e.Status, &e.LatencySLOMs, &e.SLOTarget, &e.ExploreFraction,
+		&e.MinSamples, &e.WinnerClass, &e.Reason, &e.StartedAt, &e.ConvergedAt, &e.StoppedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.LoadBalancer.loadExperimentArms(ctx, []uuid.UUID{id}, map[uuid.UUID]*liveExperiment{id: e}); err != nil {
+		return nil, err
+	}
+
+	all := make(map[string]bool, len(e.totals))
+	e.Arms = []ExperimentArm{}
+	for class, arm := range e.totals {
+		all[class] = true
+		a := *arm
+		a.summarize(&e.RoutingExperiment)
+		e.Arms = append(e.Arms, a)
+	}
+	sortExperimentArms(e.Arms)
+	if best := e.bestClass(all); best != "" {
+		e.BestClass = &best
+	}
+	return &e.RoutingExperiment, nil
+}
+
+// sortExperimentArms orders arms cheapest first, with unmeasured arms last
+func sortExperimentArms(arms []ExperimentArm) {
+	sort.Slice(arms, func(i, j int) bool {
+		a, b := arms[i], arms[j]
+		if (a.Tokens > 0) != (b.Tokens > 0) {
+			return a.Tokens > 0
+		}
+		if a.CostPer1KTokens != b.CostPer1KTokens {
+			return a.CostPer1KTokens < b.CostPer1KTokens
+		}
+		return a.NodeClass < b.NodeClass
+	})
+}
+
+// handleCreateRoutingExperiment starts a bandit experiment over a model's
+// node classes
+// Platform Admin Only - POST /admin/routing/experiments
+func (g *Gateway) handleCreateRoutingExperiment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		Model           string   `json:"model"`
+		LatencySLOMs    int      `json:"latency_slo_ms"`
+		SLOTarget       *float64 `json:"slo_target,omitempty"`
+		ExploreFraction *float64 `json:"explore_fraction,omitempty"`
+		MinSamples      *int     `json:"min_samples,omitempty"`
+		Reason          *string  `json:"reason,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Model == "" {
+		g.writeError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+	if req.LatencySLOMs <= 0 {
+		g.writeError(w, http.StatusBadRequest, "latency_slo_ms must be positive")
+		return
+	}
+
+	sloTarget, explore, minSamples := defaultExperimentSLOTarget, defaultExperimentExplore, defaultExperimentMinSamples
+	if req.SLOTarget != nil {
+		sloTarget = *req.SLOTarget
+	}
+	if req.ExploreFraction != nil {
+		explore = *req.ExploreFraction
+	}
+	if req.MinSamples != nil {
+		minSamples = *req.MinSamples
+	}
+	if sloTarget <= 0 || sloTarget > 1 {
+		g.writeError(w, http.StatusBadRequest, "slo_target must be in (0, 1]")
+		return
+	}
+	if explore <= 0 || explore > 1 {
+		g.writeError(w, http.StatusBadRequest, "explore_fraction must be in (0, 1]")
+		return
+	}
+	if minSamples <= 0 {
+		g.writeError(w, http.StatusBadRequest, "min_samples must be positive")
+		return
+	}
+
+	var classCount int
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT (COALESCE(gpu_type, ''), COALESCE(spot_instance, false)))
+		FROM nodes WHERE model_name = $1 AND status = 'active' AND endpoint_url != '' AND NOT standby
+	`, req.Model).Scan(&classCount)
+	if err != nil {
+		g.logger.Error("failed to count node classes", zap.Error(err), zap.String("model", req.Model))
+		g.writeError(w, http.StatusInternalServerError, "failed to create routing experiment")
+		return
+	}
+	if classCount < 2 {
+		g.writeError(w, http.StatusBadRequest, fmt.Sprintf("model %s is served by %d node class(es); an experiment needs at least 2", req.Model, classCount))
+		return
+	}
+
+	var id uuid.UUID
+	err = g.db.Pool.QueryRow(ctx, `
+		INSERT INTO routing_experiments (model_name, latency_slo_ms, slo_target, explore_fraction, min_samples, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, req.Model, req.LatencySLOMs, sloTarget, explore, minSamples, req.Reason).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			g.writeError(w, http.StatusConflict, "model already has a running or converged experiment; stop it first")
+			return
+		}
+		g.logger.Error("failed to create routing experiment", zap.Error(err), zap.String("model", req.Model))
+		g.writeError(w, http.StatusInternalServerError, "failed to create routing experiment")
+		return
+	}
+
+	if err := g.LoadBalancer.LoadExperiments(ctx); err != nil {
+		g.logger.Warn("failed to reload routing experiments", zap.Error(err))
+	}
+
+	g.logger.Info("routing experiment started",
+		zap.String("experiment_id", id.String()),
+		zap.String("model", req.Model),
+		zap.Int("latency_slo_ms", req.LatencySLOMs),
+		zap.Float64("explore_fraction", explore),
+	)
+
+	exp, err := g.loadRoutingExperiment(ctx, id)
+	if err != nil {
+		g.writeJSON(w, http.StatusCreated, map[string]interface{}{"id": id})
+		return
+	}
+	g.writeJSON(w, http.StatusCreated, exp)
+}
+
+// handleListRoutingExperiments lists routing experiments, newest first
+// Platform Admin Only - GET /admin/routing/experiments?model=&status=&limit=50
+func (g *Gateway) handleListRoutingExperiments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	model := r.URL.Query().Get("model")
+	status := r.URL.Query().Get("status")
+	limit := parseIntParam(r, "limit", 50, 1, 500)
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, model_name, status, latency_slo_ms, slo_target, explore_fraction, min_samples,
+		       winner_class, reason, started_at, converged_at, stopped_at
+		FROM routing_experiments
+		WHERE ($1 = '' OR model_name = $1) AND ($2 = '' OR status = $2)
+		ORDER BY started_at DESC
+		LIMIT $3
+	`, model, status, limit)
+	if err != nil {
+		g.logger.Error("failed to query routing experiments", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list routing experiments")
+		return
+	}
+	defer rows.Close()
+
+	experiments := []RoutingExperiment{}
+	for rows.Next() {
+		var e RoutingExperiment
+		if err := rows.Scan(&e.ID, &e.Model, &e.Status, &e.LatencySLOMs, &e.SLOTarget, &e.ExploreFraction,
+			&e.MinSamples, &e.WinnerClass, &e.Reason, &e.StartedAt, &e.ConvergedAt, &e.StoppedAt); err != nil {
+			g.logger.Warn("failed to scan routing experiment", zap.Error(err))
+			continue
+		}
+		experiments = append(experiments, e)
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"experiments": experiments,
+	})
+}
+
+// handleGetRoutingExperiment returns an experiment with the cost and latency
+// measured for each node class
+// Platform Admin Only - GET /admin/routing/experiments/{id}
+func (g *Gateway) handleGetRoutingExperiment(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid experiment ID")
+		return
+	}
+
+	exp, err := g.loadRoutingExperiment(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			g.writeError(w, http.StatusNotFound, "experiment not found")
+			return
+		}
+		g.logger.Error("failed to load routing experiment", zap.Error(err), zap.String("experiment_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to load routing experiment")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, exp)
+}
+
+// handleStopRoutingExperiment ends an experiment and returns its model to
+// normal routing. The measurements are kept.
+// Platform Admin Only - POST /admin/routing/experiments/{id}/stop
+func (g *Gateway) handleStopRoutingExperiment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid experiment ID")
+		return
+	}
+
+	// Save this replica's counts before the experiment leaves the live set
+	g.LoadBalancer.flushExperimentCounts(ctx)
+
+	tag, err := g.db.Pool.Exec(ctx, `
+		UPDATE routing_experiments SET status = 'stopped', stopped_at = NOW()
+		WHERE id = $1 AND status IN ('running', 'converged')
+	`, id)
+	if err != nil {
+		g.logger.Error("failed to stop routing experiment", zap.Error(err), zap.String("experiment_id", id.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to stop routing experiment")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		g.writeError(w, http.StatusNotFound, "no running or converged experiment with that ID")
+		return
+	}
+
+	if err := g.LoadBalancer.LoadExperiments(ctx); err != nil {
+		g.logger.Warn("failed to reload routing experiments", zap.Error(err))
+	}
+	g.logger.Info("routing experiment stopped", zap.String("experiment_id", id.String()))
+
+	exp, err := g.loadRoutingExperiment(ctx, id)
+	if err != nil {
+		g.writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "status": experimentStatusStopped})
+		return
+	}
+	g.writeJSON(w, http.StatusOK, exp)
+}
@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func experimentBalancer(exp *liveExperiment, random float64) *IntelligentLoadBalancer {
+	return &IntelligentLoadBalancer{
+		experiments: map[string]*liveExperiment{"llama": exp},
+		endpointClasses: map[string]endpointClass{
+			"http://a100": {Model: "llama", Class: "A100:on-demand", PricePerHour: 4},
+			"http://l4":   {Model: "llama", Class: "L4:spot", PricePerHour: 0.5},
+			"http://l4-2": {Model: "llama", Class: "L4:spot", PricePerHour: 0.5},
+		},
+		random: func() float64 { return random },
+	}
+}
+
+func newLiveExperiment() *liveExperiment {
+	return &liveExperiment{
+		RoutingExperiment: RoutingExperiment{
+			Model: "llama", Status: experimentStatusRunning, LatencySLOMs: 1000,
+			SLOTarget: 0.9, ExploreFraction: 0.1, MinSamples: 10,
+		},
+		totals:  make(map[string]*ExperimentArm),
+		pending: make(map[string]*ExperimentArm),
+	}
+}
+
+func TestApplyExperiment(t *testing.T) {
+	endpoints := []string{"http://a100", "http://l4", "http://l4-2"}
+
+	// No class is known to meet the SLO yet: exploit falls back to normal routing
+	exp := newLiveExperiment()
+	if got := experimentBalancer(exp, 0.5).applyExperiment("llama", endpoints); !reflect.DeepEqual(got, endpoints) {
+		t.Errorf("exploit without data = %v, want all endpoints", got)
+	}
+
+	// Exploration goes to the least sampled class
+	exp.totals["L4:spot"] = &ExperimentArm{NodeClass: "L4:spot", Requests: 50, Tokens: 5000, CostUSD: 0.01}
+	if got := experimentBalancer(exp, 0.05).applyExperiment("llama", endpoints); !reflect.DeepEqual(got, []string{"http://a100"}) {
+		t.Errorf("explore = %v, want the A100 class", got)
+	}
+
+	// Exploitation goes to the cheapest class meeting the SLO
+	exp.totals["A100:on-demand"] = &ExperimentArm{NodeClass: "A100:on-demand", Requests: 50, Tokens: 5000, CostUSD: 0.05}
+	if got := experimentBalancer(exp, 0.5).applyExperiment("llama", endpoints); !reflect.DeepEqual(got, []string{"http://l4", "http://l4-2"}) {
+		t.Errorf("exploit = %v, want the L4 class", got)
+	}
+
+	// A cheap class that misses the SLO loses
+	exp.totals["L4:spot"].SLOViolations = 20
+	if got := experimentBalancer(exp, 0.5).applyExperiment("llama", endpoints); !reflect.DeepEqual(got, []string{"http://a100"}) {
+		t.Errorf("exploit with slow L4 = %v, want the A100 class", got)
+	}
+
+	// Other models are untouched
+	if got := experimentBalancer(exp, 0.05).applyExperiment("mistral", endpoints); !reflect.DeepEqual(got, endpoints) {
+		t.Errorf("other model = %v, want all endpoints", got)
+	}
+}
+
+func TestConvergedExperimentRoutesToWinner(t *testing.T) {
+	exp := newLiveExperiment()
+	winner := "A100:on-demand"
+	exp.Status, exp.WinnerClass = experimentStatusConverged, &winner
+
+	lb := experimentBalancer(exp, 0.01)
+	if got := lb.applyExperiment("llama", []string{"http://a100", "http://l4"}); !reflect.DeepEqual(got, []string{"http://a100"}) {
+		t.Errorf("converged = %v, want the winner only", got)
+	}
+	// The winner has no eligible nodes: fall back rather than fail
+	if got := lb.applyExperiment("llama", []string{"http://l4"}); !reflect.DeepEqual(got, []string{"http://l4"}) {
+		t.Errorf("converged without winner nodes = %v, want the remaining endpoints", got)
+	}
+}
+
+func TestExperimentConverged(t *testing.T) {
+	exp := newLiveExperiment()
+	classes := map[string]bool{"A100:on-demand": true, "L4:spot": true}
+
+	exp.totals["L4:spot"] = &ExperimentArm{Requests: 50, Tokens: 5000, CostUSD: 0.01}
+	exp.totals["A100:on-demand"] = &ExperimentArm{Requests: 5, Tokens: 500, CostUSD: 0.01}
+	if _, ok := exp.converged(classes); ok {
+		t.Error("converged before every class had enough samples")
+	}
+
+	exp.totals["A100:on-demand"].Requests = 50
+	if winner, ok := exp.converged(classes); !ok || winner != "L4:spot" {
+		t.Errorf("converged() = %q, %v, want L4:spot", winner, ok)
+	}
+}
+
+func TestRecordExperimentOutcome(t *testing.T) {
+	exp := newLiveExperiment()
+	lb := experimentBalancer(exp, 0)
+
+	lb.RecordExperimentOutcome("llama", "http://a100", 1800*time.Millisecond, 100, false)
+	lb.RecordExperimentOutcome("llama", "http://a100", 200*time.Millisecond, 0, true)
+	lb.RecordExperimentOutcome("llama", "http://unknown", time.Second, 10, false)
+
+	arm := exp.pending["A100:on-demand"]
+	if arm == nil || arm.Requests != 2 || arm.Errors != 1 || arm.SLOViolations != 2 || arm.Tokens != 100 || arm.LatencyMsSum != 2000 {
+		t.Fatalf("pending arm = %+v", arm)
+	}
+	// $4/hour for two seconds
+	if want := 4.0 * 2 / 3600; arm.CostUSD < want*0.999 || arm.CostUSD > want*1.001 {
+		t.Errorf("CostUSD = %v, want %v", arm.CostUSD, want)
+	}
+	if len(exp.pending) != 1 {
+		t.Errorf("unknown endpoint recorded: %+v", exp.pending)
+	}
+}
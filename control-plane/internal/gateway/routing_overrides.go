@@ -0,0 +1,380 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// maxRoutingWeight bounds operator weights so one node can't absorb all traffic
+// by accident
+const maxRoutingWeight = 10.0
+
+// RoutingOverride is an operator override for a single endpoint, used for
+// emergency traffic steering. Pinned endpoints receive all of their model's
+// traffic while any of them is eligible; the weight multiplies the endpoint's
+// routing score, and a weight of 0 removes it from rotation.
+type RoutingOverride struct {
+	Endpoint  string    `json:"endpoint"`
+	Pinned    bool      `json:"pinned"`
+	Weight    float64   `json:"weight"`
+	Reason    *string   `json:"reason,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// isDefault reports whether the override no longer changes routing
+func (o RoutingOverride) isDefault() bool {
+	return !o.Pinned && o.Weight == 1
+}
+
+// LoadOverrides reloads routing overrides from the database. Overrides are
+// shared through Postgres so every gateway replica steers traffic the same way.
+func (lb *IntelligentLoadBalancer) LoadOverrides(ctx context.Context) error {
+	rows, err := lb.db.Pool.Query(ctx, `
+		SELECT endpoint, pinned, weight, reason, updated_at
+		FROM routing_overrides
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]RoutingOverride)
+	for rows.Next() {
+		var o RoutingOverride
+		if err := rows.Scan(&o.Endpoint, &o.Pinned, &o.Weight, &o.Reason, &o.UpdatedAt); err != nil {
+			return err
+		}
+		overrides[o.Endpoint] = o
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	lb.mu.Lock()
+	lb.overrides = overrides
+	lb.mu.Unlock()
+	return nil
+}
+
+// applyRoutingOverrides drops zero-weight endpoints and, when any eligible
+// endpoint is pinned, restricts routing to the pinned ones. Callers must hold lb.mu.
+func (lb *IntelligentLoadBalancer) applyRoutingOverrides(endpoints []string) []string {
+	var eligible, pinned []string
+	for _, endpoint := range endpoints {
+		o, ok := lb.overrides[endpoint]
+		if ok && o.Weight <= 0 {
+			continue
+		}
+		eligible = append(eligible, endpoint)
+		if ok && o.Pinned {
+			pinned = append(pinned, endpoint)
+		}
+	}
+	if len(pinned) > 0 {
+		return pinned
+	}
+	return eligible
+}
+
+// endpointWeight returns the operator weight for an endpoint (default 1).
+// Callers must hold lb.mu.
+func (lb *IntelligentLoadBalancer) endpointWeight(endpoint string) float64 {
+	if o, ok := lb.overrides[endpoint]; ok {
+		return o.Weight
+	}
+	return 1
+}
+
+// EligibleEndpoints returns the endpoints SelectEndpoint would choose between
+func (lb *IntelligentLoadBalancer) EligibleEndpoints(ctx context.Context, modelName string) ([]string, error) {
+	nodes, err := lb.getHealthyNodes(ctx, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return lb.applyRoutingOverrides(nodes), nil
+}
+
+// EndpointSnapshot returns a copy of the live stats and override for an endpoint
+func (lb *IntelligentLoadBalancer) EndpointSnapshot(endpoint string) (EndpointStats, *RoutingOverride) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	var stats EndpointStats
+	if s, ok := lb.stats[endpoint]; ok {
+		stats = *s
+	}
+	var override *RoutingOverride
+	if o, ok := lb.overrides[endpoint]; ok {
+		override = &o
+	}
+	return stats, override
+}
+
+// RoutingEndpointState is the live routing view of a single endpoint
+type RoutingEndpointState struct {
+	Endpoint        string     `json:"endpoint"`
+	NodeID          uuid.UUID  `json:"node_id"`
+	Status          string     `json:"status"`
+	HealthScore     float64    `json:"health_score"`
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at,omitempty"`
+	Eligible        bool       `json:"eligible"`
+	QueueDepth      int64      `json:"queue_depth"`
+	ActiveRequests  int64      `json:"active_requests"`
+	InFlight        int64      `json:"in_flight"`
+	MaxConcurrent   int        `json:"max_concurrent,omitempty"`
+	LatencyMs       int64      `json:"latency_ms"`
+	RequestCount    int64      `json:"request_count"`
+	ErrorCount      int64      `json:"error_count"`
+	ErrorRate       float64    `json:"error_rate"`
+	Pinned          bool       `json:"pinned"`
+	Weight          float64    `json:"weight"`
+	OverrideReason  *string    `json:"override_reason,omitempty"`
+	// RampShare is the share of traffic offered to a node still ramping
+	// into routing after it registered
+	RampShare *float64 `json:"ramp_share,omitempty"`
+}
+
+// RoutingModelState is the live routing table for one model
+type RoutingModelState struct {
+	Model        string                 `json:"model"`
+	BreakerState string                 `json:"breaker_state"`
+	Breaker      *ModelBreakerStatus    `json:"breaker,omitempty"`
+	Endpoints    []RoutingEndpointState `json:"endpoints"`
+}
+
+// handleGetRoutingState returns the load balancer's live routing table
+// Platform Admin Only - GET /admin/routing/state
+func (g *Gateway) handleGetRoutingState(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, model_name, endpoint_url, status, COALESCE(health_score, 0), last_heartbeat_at
+		FROM nodes
+		WHERE model_name IS NOT NULL AND endpoint_url != ''
+		  AND status IN ('active', 'draining', 'unhealthy')
+		ORDER BY model_name, endpoint_url
+	`)
+	if err != nil {
+		g.logger.Error("failed to query routing nodes", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to query routing state")
+		return
+	}
+	defer rows.Close()
+
+	var order []string
+	byModel := make(map[string]*RoutingModelState)
+	for rows.Next() {
+		var model string
+		var ep RoutingEndpointState
+		if err := rows.Scan(&ep.NodeID, &model, &ep.Endpoint, &ep.Status, &ep.HealthScore, &ep.LastHeartbeatAt); err != nil {
+			g.logger.Warn("failed to scan routing node", zap.Error(err))
+			continue
+		}
+
+		state, ok := byModel[model]
+		if !ok {
+			state = &RoutingModelState{Model: model, BreakerState: "closed", Endpoints: []RoutingEndpointState{}}
+			byModel[model] = state
+			order = append(order, model)
+		}
+		state.Endpoints = append(state.Endpoints, ep)
+	}
+	rows.Close()
+
+	breakers := make(map[string]ModelBreakerStatus)
+	for _, b := range g.modelBreakers.status(time.Now()) {
+		breakers[b.Model] = b
+	}
+
+	states := make([]RoutingModelState, 0, len(order))
+	for _, model := range order {
+		state := byModel[model]
+
+		if b, ok := breakers[model]; ok {
+			state.BreakerState = b.State
+			state.Breaker = &b
+		}
+
+		eligible, err := g.LoadBalancer.EligibleEndpoints(ctx, model)
+		if err != nil {
+			g.logger.Warn("failed to resolve eligible endpoints", zap.String("model", model), zap.Error(err))
+		}
+		eligibleSet := make(map[string]bool, len(eligible))
+		for _, endpoint := range eligible {
+			eligibleSet[endpoint] = true
+		}
+
+		for i := range state.Endpoints {
+			ep := &state.Endpoints[i]
+			stats, override := g.LoadBalancer.EndpointSnapshot(ep.Endpoint)
+
+			ep.Eligible = eligibleSet[ep.Endpoint]
+			ep.QueueDepth = stats.QueueDepth
+			ep.ActiveRequests = stats.ActiveRequests
+			ep.InFlight, ep.MaxConcurrent = g.LoadBalancer.EndpointConcurrency(ep.Endpoint)
+			ep.LatencyMs = stats.Latency.Milliseconds()
+			ep.RequestCount = stats.RequestCount
+			ep.ErrorCount = stats.ErrorCount
+			if stats.RequestCount > 0 {
+				ep.ErrorRate = float64(stats.ErrorCount) / float64(stats.RequestCount)
+			}
+			ep.Weight = 1
+			if override != nil {
+				ep.Pinned = override.Pinned
+				ep.Weight = override.Weight
+				ep.OverrideReason = override.Reason
+			}
+			if share, ok := g.LoadBalancer.CanaryShare(ep.Endpoint); ok {
+				ep.RampShare = &share
+			}
+		}
+		states = append(states, *state)
+	}
+
+	sort.Slice(states, func(i, j int) bool { return states[i].Model < states[j].Model })
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"models":       states,
+		"generated_at": time.Now(),
+	})
+}
+
+// routingOverrideRequest is the body for the pin/unpin/weight operations
+type routingOverrideRequest struct {
+	Endpoint string   `json:"endpoint"`
+	Weight   *float64 `json:"weight,omitempty"`
+	Reason   *string  `json:"reason,omitempty"`
+}
+
+// handlePinEndpoint routes all of a model's traffic to the endpoint (and any
+// other pinned endpoints) while it is eligible
+// Platform Admin Only - POST /admin/routing/pin
+func (g *Gateway) handlePinEndpoint(w http.ResponseWriter, r *http.Request) {
+	g.updateRoutingOverride(w, r, func(o *RoutingOverride, req *routingOverrideRequest) error {
+		o.Pinned = true
+		if o.Weight <= 0 {
+			o.Weight = 1
+		}
+		return nil
+	})
+}
+
+// handleUnpinEndpoint removes a pin
+// Platform Admin Only - POST /admin/routing/unpin
+func (g *Gateway) handleUnpinEndpoint(w http.ResponseWriter, r *http.Request) {
+	g.updateRoutingOverride(w, r, func(o *RoutingOverride, req *routingOverrideRequest) error {
+		o.Pinned = false
+		return nil
+	})
+}
+
+// handleWeightEndpoint sets the routing weight of an endpoint; 0 drains it
+// from rotation and 1 restores the default
+// Platform Admin Only - POST /admin/routing/weight
+func (g *Gateway) handleWeightEndpoint(w http.ResponseWriter, r *http.Request) {
+	g.updateRoutingOverride(w, r, func(o *RoutingOverride, req *routingOverrideRequest) error {
+		if req.Weight == nil {
+			return fmt.Errorf("weight is required")
+		}
+		if *req.Weight < 0 || *req.Weight > maxRoutingWeight {
+			return fmt.Errorf("weight must be between 0 and %.0f", maxRoutingWeight)
+		}
+		if *req.Weight == 0 && o.Pinned {
+			return fmt.Errorf("unpin the endpoint before setting its weight to 0")
+		}
+		o.Weight = *req.Weight
+		return nil
+	})
+}
+
+// updateRoutingOverride loads the current override for the requested
+// endpoint, applies the change and persists it. Overrides that no longer
+// change routing are deleted.
+func (g *Gateway) updateRoutingOverride(w http.ResponseWriter, r *http.Request, apply func(*RoutingOverride, *routingOverrideRequest) error) {
+	ctx := r.Context()
+
+	var req routingOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Endpoint == "" {
+		g.writeError(w, http.StatusBadRequest, "endpoint is required")
+		return
+	}
+
+	var exists bool
+	err := g.db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM nodes WHERE endpoint_url = $1)`, req.Endpoint).Scan(&exists)
+	if err != nil {
+		g.logger.Error("failed to look up endpoint", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update routing override")
+		return
+	}
+	if !exists {
+		g.writeError(w, http.StatusNotFound, "endpoint not found")
+		return
+	}
+
+	override := RoutingOverride{Endpoint: req.Endpoint, Weight: 1}
+	err = g.db.Pool.QueryRow(ctx, `
+		SELECT pinned, weight, reason FROM routing_overrides WHERE endpoint = $1
+	`, req.Endpoint).Scan(&override.Pinned, &override.Weight, &override.Reason)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		g.logger.Error("failed to load routing override", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update routing override")
+		return
+	}
+
+	if err := apply(&override, &req); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Reason != nil {
+		override.Reason = req.Reason
+	}
+
+	if override.isDefault() {
+		_, err = g.db.Pool.Exec(ctx, `DELETE FROM routing_overrides WHERE endpoint = $1`, req.Endpoint)
+		override.UpdatedAt = time.Now()
+	} else {
+		err = g.db.Pool.QueryRow(ctx, `
+			INSERT INTO routing_overrides (endpoint, pinned, weight, reason, updated_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			ON CONFLICT (endpoint) DO UPDATE SET
+				pinned = EXCLUDED.pinned,
+				weight = EXCLUDED.weight,
+				reason = EXCLUDED.reason,
+				updated_at = NOW()
+			RETURNING updated_at
+		`, override.Endpoint, override.Pinned, override.Weight, override.Reason).Scan(&override.UpdatedAt)
+	}
+	if err != nil {
+		g.logger.Error("failed to save routing override", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to update routing override")
+		return
+	}
+
+	if err := g.LoadBalancer.LoadOverrides(ctx); err != nil {
+		g.logger.Warn("failed to reload routing overrides", zap.Error(err))
+	}
+
+	g.logger.Info("routing override updated",
+		zap.String("endpoint", override.Endpoint),
+		zap.Bool("pinned", override.Pinned),
+		zap.Float64("weight", override.Weight),
+	)
+
+	g.writeJSON(w, http.StatusOK, override)
+}
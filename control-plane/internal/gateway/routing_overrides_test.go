@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyRoutingOverrides(t *testing.T) {
+	endpoints := []string{"http://a", "http://b", "http://c"}
+
+	tests := []struct {
+		name      string
+		overrides map[string]RoutingOverride
+		want      []string
+	}{
+		{"no overrides", nil, endpoints},
+		{
+			"zero weight drains endpoint",
+			map[string]RoutingOverride{"http://b": {Endpoint: "http://b", Weight: 0}},
+			[]string{"http://a", "http://c"},
+		},
+		{
+			"pinned endpoint takes all traffic",
+			map[string]RoutingOverride{"http://c": {Endpoint: "http://c", Pinned: true, Weight: 1}},
+			[]string{"http://c"},
+		},
+		{
+			"pin on an ineligible endpoint is ignored",
+			map[string]RoutingOverride{"http://z": {Endpoint: "http://z", Pinned: true, Weight: 1}},
+			endpoints,
+		},
+		{
+			"all drained",
+			map[string]RoutingOverride{
+				"http://a": {Endpoint: "http://a"},
+				"http://b": {Endpoint: "http://b"},
+				"http://c": {Endpoint: "http://c"},
+			},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := &IntelligentLoadBalancer{overrides: tt.overrides}
+			if got := lb.applyRoutingOverrides(endpoints); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyRoutingOverrides() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointWeight(t *testing.T) {
+	lb := &IntelligentLoadBalancer{overrides: map[string]RoutingOverride{
+		"http://a": {Endpoint: "http://a", Weight: 2.5},
+	}}
+
+	if got := lb.endpointWeight("http://a"); got != 2.5 {
+		t.Errorf("endpointWeight(a) = %v, want 2.5", got)
+	}
+	if got := lb.endpointWeight("http://b"); got != 1 {
+		t.Errorf("endpointWeight(b) = %v, want 1", got)
+	}
+}
+
+func TestRoutingOverrideIsDefault(t *testing.T) {
+	if !(RoutingOverride{Weight: 1}).isDefault() {
+		t.Error("unpinned weight 1 override should be default")
+	}
+	if (RoutingOverride{Weight: 1, Pinned: true}).isDefault() {
+		t.Error("pinned override should not be default")
+	}
+	if (RoutingOverride{Weight: 0}).isDefault() {
+		t.Error("drained override should not be default")
+	}
+}
@@ -0,0 +1,221 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// samplingDefaultsMetadataKey is the models.metadata key holding sampling defaults
+const samplingDefaultsMetadataKey = "sampling_defaults"
+
+// SamplingDefaults are per-model sampling parameters merged into requests
+// that omit them
+type SamplingDefaults struct {
+	Temperature       *float64 `json:"temperature,omitempty"`
+	TopP              *float64 `json:"top_p,omitempty"`
+	RepetitionPenalty *float64 `json:"repetition_penalty,omitempty"`
+}
+
+// IsEmpty reports whether no default is set
+func (d *SamplingDefaults) IsEmpty() bool {
+	return d == nil || (d.Temperature == nil && d.TopP == nil && d.RepetitionPenalty == nil)
+}
+
+// Validate checks the defaults against the ranges vLLM accepts
+func (d *SamplingDefaults) Validate() error {
+	if d.Temperature != nil && (*d.Temperature < 0 || *d.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if d.TopP != nil && (*d.TopP <= 0 || *d.TopP > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1")
+	}
+	if d.RepetitionPenalty != nil && (*d.RepetitionPenalty <= 0 || *d.RepetitionPenalty > 2) {
+		return fmt.Errorf("repetition_penalty must be greater than 0 and at most 2")
+	}
+	return nil
+}
+
+// parseSamplingDefaults reads sampling defaults from model metadata. Malformed
+// or missing defaults return nil.
+func parseSamplingDefaults(metadata []byte) *SamplingDefaults {
+	if len(metadata) == 0 {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(metadata, &raw); err != nil {
+		return nil
+	}
+	entry, ok := raw[samplingDefaultsMetadataKey]
+	if !ok {
+		return nil
+	}
+	var defaults SamplingDefaults
+	if err := json.Unmarshal(entry, &defaults); err != nil || defaults.IsEmpty() {
+		return nil
+	}
+	return &defaults
+}
+
+// applySamplingDefaults merges defaults into a request body for every
+// parameter the client omitted or sent as null. The body is returned
+// unchanged when nothing is merged.
+func applySamplingDefaults(body []byte, defaults *SamplingDefaults) []byte {
+	if defaults.IsEmpty() {
+		return body
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+
+	merged := false
+	merge := func(name string, value *float64) {
+		if value == nil {
+			return
+		}
+		if raw, ok := fields[name]; ok && !isJSONNull(raw) {
+			return
+		}
+		encoded, err := json.Marshal(*value)
+		if err != nil {
+			return
+		}
+		fields[name] = encoded
+		merged = true
+	}
+	merge("temperature", defaults.Temperature)
+	merge("top_p", defaults.TopP)
+	merge("repetition_penalty", defaults.RepetitionPenalty)
+
+	if !merged {
+		return body
+	}
+	updated, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return updated
+}
+
+// applyModelSamplingDefaults fills in the model's sampling defaults for
+// parameters the request omits. Lookup failures forward the request as sent.
+func (g *Gateway) applyModelSamplingDefaults(ctx context.Context, modelName string, body []byte) []byte {
+	capabilities, err := g.getModelCapabilities(ctx, modelName)
+	if err != nil {
+		g.logger.Warn("failed to load model sampling defaults",
+			zap.Error(err),
+			zap.String("model", modelName),
+		)
+		return body
+	}
+	if capabilities == nil {
+		return body
+	}
+	return applySamplingDefaults(body, capabilities.SamplingDefaults)
+}
+
+// HandleGetSamplingDefaults returns a model's sampling defaults
+// Admin API - GET /api/v1/admin/models/{id}/sampling-defaults
+func (g *Gateway) HandleGetSamplingDefaults(w http.ResponseWriter, r *http.Request) {
+	modelID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid model ID")
+		return
+	}
+
+	var name string
+	var metadata []byte
+	err = g.db.Pool.QueryRow(r.Context(), `
+		SELECT name, metadata FROM models WHERE id = $1
+	`, modelID).Scan(&name, &metadata)
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "model not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to get sampling defaults", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to get sampling defaults")
+		return
+	}
+
+	defaults := parseSamplingDefaults(metadata)
+	if defaults == nil {
+		defaults = &SamplingDefaults{}
+	}
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"model":             name,
+		"sampling_defaults": defaults,
+	})
+}
+
+// HandleSetSamplingDefaults replaces a model's sampling defaults. They apply
+// to new requests without a redeploy; an empty body clears them.
+// Admin API - PUT /api/v1/admin/models/{id}/sampling-defaults
+func (g *Gateway) HandleSetSamplingDefaults(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	modelID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid model ID")
+		return
+	}
+
+	var defaults SamplingDefaults
+	if err := json.NewDecoder(r.Body).Decode(&defaults); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := defaults.Validate(); err != nil {
+		g.writeInvalidRequest(w, err.Error(), "invalid_sampling_defaults")
+		return
+	}
+
+	var name string
+	if defaults.IsEmpty() {
+		err = g.db.Pool.QueryRow(ctx, `
+			UPDATE models
+			SET metadata = COALESCE(metadata, '{}'::jsonb) - $2, updated_at = NOW()
+			WHERE id = $1
+			RETURNING name
+		`, modelID, samplingDefaultsMetadataKey).Scan(&name)
+	} else {
+		encoded, _ := json.Marshal(defaults)
+		err = g.db.Pool.QueryRow(ctx, `
+			UPDATE models
+			SET metadata = jsonb_set(COALESCE(metadata, '{}'::jsonb), ARRAY[$2::text], $3::jsonb), updated_at = NOW()
+			WHERE id = $1
+			RETURNING name
+		`, modelID, samplingDefaultsMetadataKey, encoded).Scan(&name)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "model not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to set sampling defaults", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to set sampling defaults")
+		return
+	}
+
+	// Other replicas pick the change up when their capability cache expires
+	g.modelCapabilities.invalidate(name)
+
+	g.logger.Info("model sampling defaults updated",
+		zap.String("model", name),
+		zap.Any("sampling_defaults", defaults),
+	)
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"model":             name,
+		"sampling_defaults": defaults,
+	})
+}
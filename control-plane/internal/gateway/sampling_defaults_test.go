@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestParseSamplingDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata string
+		want     *SamplingDefaults
+	}{
+		{"empty", "", nil},
+		{"no defaults", `{"source": "hf"}`, nil},
+		{"empty defaults", `{"sampling_defaults": {}}`, nil},
+		{"malformed", `{"sampling_defaults": "hot"}`, nil},
+		{"set", `{"sampling_defaults": {"temperature": 0.6, "top_p": 0.9}}`, &SamplingDefaults{Temperature: floatPtr(0.6), TopP: floatPtr(0.9)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSamplingDefaults([]byte(tt.metadata))
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("parseSamplingDefaults() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestApplySamplingDefaults(t *testing.T) {
+	defaults := &SamplingDefaults{Temperature: floatPtr(0.6), TopP: floatPtr(0.9), RepetitionPenalty: floatPtr(1.1)}
+
+	tests := []struct {
+		name     string
+		body     string
+		defaults *SamplingDefaults
+		want     map[string]interface{}
+	}{
+		{
+			"fills omitted",
+			`{"model": "m"}`,
+			defaults,
+			map[string]interface{}{"temperature": 0.6, "top_p": 0.9, "repetition_penalty": 1.1},
+		},
+		{
+			"keeps client values",
+			`{"model": "m", "temperature": 0, "top_p": 0.5}`,
+			defaults,
+			map[string]interface{}{"temperature": 0.0, "top_p": 0.5, "repetition_penalty": 1.1},
+		},
+		{
+			"replaces null",
+			`{"model": "m", "temperature": null}`,
+			&SamplingDefaults{Temperature: floatPtr(0.6)},
+			map[string]interface{}{"temperature": 0.6},
+		},
+		{
+			"no defaults",
+			`{"model": "m"}`,
+			nil,
+			map[string]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			if err := json.Unmarshal(applySamplingDefaults([]byte(tt.body), tt.defaults), &got); err != nil {
+				t.Fatalf("invalid body: %v", err)
+			}
+			if got["model"] != "m" {
+				t.Errorf("model = %v, want m", got["model"])
+			}
+			for _, field := range []string{"temperature", "top_p", "repetition_penalty"} {
+				want, wantOK := tt.want[field]
+				value, ok := got[field]
+				if ok != wantOK || value != want {
+					t.Errorf("%s = %v (present %v), want %v (present %v)", field, value, ok, want, wantOK)
+				}
+			}
+		})
+	}
+}
+
+func TestSamplingDefaultsValidate(t *testing.T) {
+	tests := []struct {
+		defaults SamplingDefaults
+		wantErr  bool
+	}{
+		{SamplingDefaults{}, false},
+		{SamplingDefaults{Temperature: floatPtr(0), TopP: floatPtr(1), RepetitionPenalty: floatPtr(1.2)}, false},
+		{SamplingDefaults{Temperature: floatPtr(2.5)}, true},
+		{SamplingDefaults{TopP: floatPtr(0)}, true},
+		{SamplingDefaults{RepetitionPenalty: floatPtr(-1)}, true},
+	}
+
+	for i, tt := range tests {
+		if err := tt.defaults.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("case %d: Validate() error = %v, wantErr %v", i, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// handleGetSavingsReport compares the tenant's spot GPU spend with what the
+// same instance-hours would have cost on-demand at catalog prices
+// GET /v1/reports/savings?start_date=...&end_date=... (RFC3339, default last 30 days)
+func (g *Gateway) handleGetSavingsReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+
+	startDate, endDate := parseDateRange(r)
+	if !endDate.After(startDate) {
+		g.writeError(w, http.StatusBadRequest, "end_date must be after start_date")
+		return
+	}
+
+	report, err := billing.GetSavingsReport(ctx, g.db, tenantID, startDate, endDate)
+	if err != nil {
+		g.logger.Error("failed to build savings report",
+			zap.Error(err),
+			zap.String("tenant_id", tenantID.String()),
+		)
+		g.writeError(w, http.StatusInternalServerError, "failed to build savings report")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, report)
+}
@@ -107,7 +107,7 @@ func SecurityMiddleware(config SecurityConfig) func(http.Handler) http.Handler {
 
 			// Prevent caching of sensitive data
 			// Only apply to API responses, not static assets
-			if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/v1/") || strings.HasPrefix(r.URL.Path, "/admin/") {
+			if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/v1/") || strings.HasPrefix(r.URL.Path, "/v2/") || strings.HasPrefix(r.URL.Path, "/admin/") {
 				w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, proxy-revalidate")
 				w.Header().Set("Pragma", "no-cache")
 				w.Header().Set("Expires", "0")
@@ -127,7 +127,7 @@ func APISecurityMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Ensure JSON content type for API responses
-			if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/v1/") || strings.HasPrefix(r.URL.Path, "/admin/") {
+			if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/v1/") || strings.HasPrefix(r.URL.Path, "/v2/") || strings.HasPrefix(r.URL.Path, "/admin/") {
 				// Check Content-Type for POST/PUT/PATCH requests
 				if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" {
 					contentType := r.Header.Get("Content-Type")
@@ -0,0 +1,236 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// enforceSpendBudgets rejects inference with 402 once the tenant, or the
+// environment the request is made in, has spent its budget for the period
+func (g *Gateway) enforceSpendBudgets(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.Budgets == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		environmentID, _ := r.Context().Value("environment_id").(uuid.UUID)
+
+		budget := g.Budgets.Exceeded(tenantID, environmentID)
+		if budget == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scope := "account"
+		if budget.EnvironmentID != nil {
+			scope = "environment"
+		}
+		g.writeJSON(w, http.StatusPaymentRequired, map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": fmt.Sprintf("%s %s spend budget of $%.2f exceeded; it resets at %s",
+					budget.Period, scope, float64(budget.LimitMicrodollars)/1e6, budget.PeriodEnd.Format(time.RFC3339)),
+				"type":      "insufficient_quota",
+				"code":      "budget_exceeded",
+				"budget_id": budget.ID.String(),
+				"resets_at": budget.PeriodEnd.Format(time.RFC3339),
+			},
+		})
+	})
+}
+
+// budgetRequest is the body of budget create and update requests
+type budgetRequest struct {
+	EnvironmentID     *uuid.UUID `json:"environment_id"`
+	Period            string     `json:"period"`
+	LimitMicrodollars *int64     `json:"limit_microdollars"`
+	Enabled           *bool      `json:"enabled"`
+}
+
+// budgetsTenantID parses the tenant ID route parameter and checks budgets
+// are available
+func (g *Gateway) budgetsTenantID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	if g.Budgets == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "spend budgets are not configured")
+		return uuid.Nil, false
+	}
+	tenantID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid tenant ID")
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// writeBudgetError maps budget errors to responses
+func (g *Gateway) writeBudgetError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, billing.ErrBudgetNotFound):
+		g.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, billing.ErrBudgetExists):
+		g.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, billing.ErrInvalidBudget):
+		g.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		g.logger.Error("spend budget request failed", zap.String("action", action), zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to "+action)
+	}
+}
+
+// handleListTenantBudgetsAdmin lists a tenant's budgets with their current
+// spend and burn rate
+// GET /admin/tenants/{id}/budgets
+func (g *Gateway) handleListTenantBudgetsAdmin(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.budgetsTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	budgets, err := g.Budgets.List(r.Context(), tenantID)
+	if err != nil {
+		g.writeBudgetError(w, err, "list budgets")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": budgets,
+	})
+}
+
+// handleCreateTenantBudget adds a daily or monthly spend cap for a tenant
+// or one of its environments
+// POST /admin/tenants/{id}/budgets
+func (g *Gateway) handleCreateTenantBudget(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.budgetsTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	var req budgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.LimitMicrodollars == nil {
+		g.writeError(w, http.StatusBadRequest, "limit_microdollars is required")
+		return
+	}
+	budget := billing.Budget{
+		EnvironmentID:     req.EnvironmentID,
+		Period:            req.Period,
+		LimitMicrodollars: *req.LimitMicrodollars,
+		Enabled:           req.Enabled == nil || *req.Enabled,
+	}
+
+	status, err := g.Budgets.Create(r.Context(), tenantID, budget, adminActor(r.Context()))
+	if err != nil {
+		g.writeBudgetError(w, err, "create budget")
+		return
+	}
+
+	g.logger.Info("spend budget created",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("budget_id", status.ID.String()),
+		zap.String("period", status.Period),
+		zap.Int64("limit_microdollars", status.LimitMicrodollars),
+	)
+	g.writeJSON(w, http.StatusCreated, status)
+}
+
+// handleUpdateTenantBudget changes a budget's limit or switches it on or off
+// PUT /admin/tenants/{id}/budgets/{budget_id}
+func (g *Gateway) handleUpdateTenantBudget(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.budgetsTenantID(w, r)
+	if !ok {
+		return
+	}
+	budgetID, err := uuid.Parse(chi.URLParam(r, "budget_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid budget ID")
+		return
+	}
+
+	var req budgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.EnvironmentID != nil || req.Period != "" {
+		g.writeError(w, http.StatusBadRequest, "a budget's environment and period cannot be changed; create a new budget instead")
+		return
+	}
+
+	status, err := g.Budgets.Update(r.Context(), tenantID, budgetID, req.LimitMicrodollars, req.Enabled, adminActor(r.Context()))
+	if err != nil {
+		g.writeBudgetError(w, err, "update budget")
+		return
+	}
+
+	g.logger.Info("spend budget updated",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("budget_id", budgetID.String()),
+		zap.Int64("limit_microdollars", status.LimitMicrodollars),
+		zap.Bool("enabled", status.Enabled),
+	)
+	g.writeJSON(w, http.StatusOK, status)
+}
+
+// handleDeleteTenantBudget removes a budget
+// DELETE /admin/tenants/{id}/budgets/{budget_id}
+func (g *Gateway) handleDeleteTenantBudget(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.budgetsTenantID(w, r)
+	if !ok {
+		return
+	}
+	budgetID, err := uuid.Parse(chi.URLParam(r, "budget_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid budget ID")
+		return
+	}
+
+	if err := g.Budgets.Delete(r.Context(), tenantID, budgetID); err != nil {
+		g.writeBudgetError(w, err, "delete budget")
+		return
+	}
+
+	g.logger.Info("spend budget deleted",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("budget_id", budgetID.String()),
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListBudgets shows the tenant its budgets, spend and burn rate
+// Tenant API - GET /v1/budgets
+func (g *Gateway) handleListBudgets(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
+	if g.Budgets == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "spend budgets are not configured")
+		return
+	}
+
+	budgets, err := g.Budgets.List(r.Context(), tenantID)
+	if err != nil {
+		g.writeBudgetError(w, err, "list budgets")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": budgets,
+	})
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SSE streams carry event IDs so clients can reconnect with Last-Event-ID
+// after a control-plane restart and pick up where they left off. Clients that
+// can't send the header (curl, CLI tools) pass a stream_id instead and we
+// remember how far that stream got in Redis.
+const (
+	// sseRetryMillis is the reconnect delay we advise clients to use
+	sseRetryMillis = 2000
+
+	// ssePositionTTL bounds how long a stream_id can be resumed
+	ssePositionTTL = time.Hour
+)
+
+var sseStreamIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// DrainStreams asks open SSE streams to tell their clients to reconnect and
+// then close, so a restarting pod doesn't hold up shutdown or drop streams
+// without a resume point. Safe to call more than once.
+func (g *Gateway) DrainStreams() {
+	g.drainOnce.Do(func() {
+		close(g.streamsDraining)
+	})
+}
+
+// sseStreamID returns the client-chosen stream_id query parameter, if valid
+func sseStreamID(r *http.Request) string {
+	id := r.URL.Query().Get("stream_id")
+	if !sseStreamIDPattern.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+func ssePositionKey(scope, streamID string) string {
+	return "sse_position:" + scope + ":" + streamID
+}
+
+// sseResumePosition returns the last event ID the client has seen, from the
+// Last-Event-ID header, the last_event_id query parameter or the position
+// saved for its stream_id, in that order
+func (g *Gateway) sseResumePosition(ctx context.Context, r *http.Request, scope string) (int64, bool) {
+	raw := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 0 {
+			return 0, false
+		}
+		return id, true
+	}
+
+	streamID := sseStreamID(r)
+	if streamID == "" {
+		return 0, false
+	}
+	id, ok, err := g.cache.GetInt64(ctx, ssePositionKey(scope, streamID))
+	if err != nil {
+		g.logger.Warn("failed to load SSE stream position", zap.Error(err), zap.String("stream_id", streamID))
+		return 0, false
+	}
+	return id, ok
+}
+
+// saveSSEPosition records the last event sent on the request's stream_id
+func (g *Gateway) saveSSEPosition(ctx context.Context, r *http.Request, scope string, id int64) {
+	streamID := sseStreamID(r)
+	if streamID == "" {
+		return
+	}
+	if err := g.cache.Set(ctx, ssePositionKey(scope, streamID), id, ssePositionTTL); err != nil {
+		g.logger.Warn("failed to save SSE stream position", zap.Error(err), zap.String("stream_id", streamID))
+	}
+}
+
+// writeSSEEventWithID writes a Server-Sent Event that clients can resume from
+func (g *Gateway) writeSSEEventWithID(w http.ResponseWriter, id int64, event string, data interface{}) {
+	fmt.Fprintf(w, "id: %d\n", id)
+	g.writeSSEEvent(w, event, data)
+}
+
+// writeSSEReconnect tells the client the stream is ending because this pod is
+// shutting down and that it should reconnect to resume
+func (g *Gateway) writeSSEReconnect(w http.ResponseWriter, lastEventID int64) {
+	fmt.Fprintf(w, "retry: %d\n", sseRetryMillis)
+	g.writeSSEEvent(w, "reconnect", map[string]interface{}{
+		"reason":        "server shutting down",
+		"last_event_id": lastEventID,
+	})
+}
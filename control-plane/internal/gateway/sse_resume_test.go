@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestSSEResumePosition(t *testing.T) {
+	c, cleanup := setupLimiterCache(t)
+	defer cleanup()
+	g := &Gateway{cache: c, logger: zap.NewNop()}
+	ctx := context.Background()
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/nodes/n1/logs/stream?stream_id=tab-1", nil)
+	if _, ok := g.sseResumePosition(ctx, r, "node_logs:n1"); ok {
+		t.Fatal("new stream reported a resume position")
+	}
+
+	g.saveSSEPosition(ctx, r, "node_logs:n1", 7)
+	if id, ok := g.sseResumePosition(ctx, r, "node_logs:n1"); !ok || id != 7 {
+		t.Errorf("stream_id position = %d, %v, want 7", id, ok)
+	}
+
+	// Last-Event-ID takes precedence over the saved position
+	r.Header.Set("Last-Event-ID", "3")
+	if id, ok := g.sseResumePosition(ctx, r, "node_logs:n1"); !ok || id != 3 {
+		t.Errorf("Last-Event-ID position = %d, %v, want 3", id, ok)
+	}
+
+	r.Header.Set("Last-Event-ID", "garbage")
+	if _, ok := g.sseResumePosition(ctx, r, "node_logs:n1"); ok {
+		t.Error("invalid Last-Event-ID was accepted")
+	}
+}
+
+func TestNodeLogStoreResume(t *testing.T) {
+	c, cleanup := setupLimiterCache(t)
+	defer cleanup()
+	store := orchestrator.NewNodeLogStore(c, nil, zap.NewNop())
+	ctx := context.Background()
+
+	for _, msg := range []string{"queued", "provisioning", "ready"} {
+		if err := store.AppendLog(ctx, "n1", orchestrator.NodeLogEntry{Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logs, err := store.GetLogsAfter(ctx, "n1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[0].Seq != 1 || logs[0].Message != "provisioning" || logs[1].Seq != 2 {
+		t.Errorf("GetLogsAfter(0) = %+v", logs)
+	}
+
+	last, err := store.LastLog(ctx, "n1")
+	if err != nil || last == nil || last.Message != "ready" || last.Seq != 2 {
+		t.Errorf("LastLog() = %+v, %v", last, err)
+	}
+
+	// A tenant's node logs are kept in the tenant's namespace
+	tenantID := uuid.New()
+	store.BindTenant("n2", tenantID.String())
+	if err := store.AppendLog(ctx, "n2", orchestrator.NodeLogEntry{Message: "queued"}); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]int64{
+		orchestrator.NodeLogKey(tenantID, "n2"): 1,
+		orchestrator.NodeLogKey(uuid.Nil, "n2"): 0,
+		orchestrator.NodeLogKey(uuid.Nil, "n1"): 1,
+	} {
+		if n, _ := c.Exists(ctx, key); n != want {
+			t.Errorf("Exists(%q) = %d, want %d", key, n, want)
+		}
+	}
+}
+
+func TestDrainStreams(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop(), streamsDraining: make(chan struct{})}
+	g.DrainStreams()
+	g.DrainStreams()
+
+	select {
+	case <-g.streamsDraining:
+	default:
+		t.Fatal("streams were not drained")
+	}
+
+	w := httptest.NewRecorder()
+	g.writeSSEReconnect(w, 41)
+	if body := w.Body.String(); !strings.HasPrefix(body, "retry: 2000\nevent: reconnect\n") || !strings.Contains(body, `"last_event_id":41`) {
+		t.Errorf("reconnect event = %q", body)
+	}
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// streamChunkSize is the most read from a node's event stream before it is
+// written and flushed to the client
+const streamChunkSize = 4096
+
+// isEventStream reports whether a node answered with server-sent events
+func isEventStream(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// relayProxiedResponse relays a node's response to the client: event
+// streams as each chunk arrives, anything else as writeProxiedResponse does.
+// cancel cancels the node request.
+func (g *Gateway) relayProxiedResponse(w http.ResponseWriter, resp *http.Response, cancel context.CancelFunc) {
+	if isEventStream(resp) {
+		g.streamProxiedResponse(w, resp, cancel)
+		return
+	}
+	writeProxiedResponse(w, resp)
+}
+
+// streamProxiedResponse relays a node's event stream to the client, flushing
+// every chunk as it arrives rather than when a buffer fills, so the first
+// token reaches the client as soon as the node sends it. Once the client
+// stops accepting writes the node request is cancelled, so the node stops
+// generating for nobody.
+func (g *Gateway) streamProxiedResponse(w http.ResponseWriter, resp *http.Response, cancel context.CancelFunc) {
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	for key := range resp.Trailer {
+		w.Header().Add("Trailer", key)
+	}
+	// The stream's length is unknown, and proxies must not buffer it
+	w.Header().Del("Content-Length")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+
+	w.WriteHeader(resp.StatusCode)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	buf := make([]byte, streamChunkSize)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				g.logger.Debug("client went away during stream", zap.Error(werr))
+				cancel()
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// A cancelled request ends the stream without it being the
+			// node's fault
+			if !errors.Is(err, context.Canceled) {
+				g.logger.Warn("node stream interrupted", zap.Error(err))
+			}
+			return
+		}
+	}
+
+	for key, values := range resp.Trailer {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+}
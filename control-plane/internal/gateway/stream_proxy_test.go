@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sseResponse is a node's event stream reading from body
+func sseResponse(body io.ReadCloser) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/event-stream"}, "Content-Length": {"100"}},
+		Body:       body,
+	}
+}
+
+// readLine reads a line from the stream, failing the test if none arrives
+// in time
+func readLine(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := reader.ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(2 * time.Second):
+		t.Fatal("chunk was not flushed to the client")
+		return ""
+	}
+}
+
+func TestStreamProxiedResponseFlushesChunks(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+	nodeBody, node := io.Pipe()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.streamProxiedResponse(w, sseResponse(nodeBody), func() {})
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("X-Accel-Buffering"); got != "no" {
+		t.Errorf("X-Accel-Buffering = %q, want no", got)
+	}
+	if resp.ContentLength != -1 {
+		t.Errorf("ContentLength = %d, want unknown", resp.ContentLength)
+	}
+
+	// Each chunk must reach the client before the node sends the next
+	reader := bufio.NewReader(resp.Body)
+	for _, chunk := range []string{"data: {\"n\":1}\n", "data: {\"n\":2}\n", "data: [DONE]\n"} {
+		go node.Write([]byte(chunk))
+		if got := readLine(t, reader); got != chunk {
+			t.Errorf("got %q, want %q", got, chunk)
+		}
+	}
+	node.Close()
+	if rest, _ := io.ReadAll(reader); len(rest) != 0 {
+		t.Errorf("unexpected trailing data %q", rest)
+	}
+}
+
+// endlessStream is a node stream that keeps sending chunks until closed
+type endlessStream struct {
+	closed chan struct{}
+}
+
+func (s *endlessStream) Read(p []byte) (int, error) {
+	select {
+	case <-s.closed:
+		return 0, io.ErrClosedPipe
+	case <-time.After(5 * time.Millisecond):
+		return copy(p, "data: {\"choices\":[]}\n\n"), nil
+	}
+}
+
+func (s *endlessStream) Close() error { return nil }
+
+func TestStreamProxiedResponseCancelsOnDisconnect(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+	stream := &endlessStream{closed: make(chan struct{})}
+	cancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.streamProxiedResponse(w, sseResponse(stream), func() {
+			close(cancelled)
+			close(stream.closed)
+		})
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := readLine(t, bufio.NewReader(resp.Body)); !strings.HasPrefix(line, "data:") {
+		t.Fatalf("got %q, want a data line", line)
+	}
+	resp.Body.Close()
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		close(stream.closed)
+		t.Fatal("node request not cancelled after the client went away")
+	}
+}
+
+func TestRelayProxiedResponseKeepsJSON(t *testing.T) {
+	g := &Gateway{logger: zap.NewNop()}
+	rec := httptest.NewRecorder()
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"id":"x"}`)),
+	}
+
+	g.relayProxiedResponse(rec, resp, func() { t.Error("cancelled a non-streaming response") })
+	if rec.Body.String() != `{"id":"x"}` || rec.Header().Get("X-Accel-Buffering") != "" {
+		t.Errorf("got %q with headers %v", rec.Body.String(), rec.Header())
+	}
+}
@@ -0,0 +1,175 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+	"github.com/crosslogic/control-plane/internal/jobs"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxStripeReconciliationRuns caps the runs listed per request
+const maxStripeReconciliationRuns = 100
+
+// stripeReconciliationArgs are the arguments of a billing.stripe_reconcile job
+type stripeReconciliationArgs struct {
+	RunID uuid.UUID `json:"run_id"`
+}
+
+// stripeReconcilerAvailable answers the request itself when the reconciler
+// isn't wired up
+func (g *Gateway) stripeReconcilerAvailable(w http.ResponseWriter) bool {
+	if g.StripeReconciler == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "stripe reconciliation is not configured")
+		return false
+	}
+	return true
+}
+
+// handleCreateStripeReconciliation queues a cross-check of tenants against
+// Stripe customers and subscriptions
+// Platform Admin Only - POST /admin/billing/reconciliations
+// With auto_fix, discrepancies safe to correct (tenant status, subscription
+// and plan left behind by webhook races, unlinked customers) are fixed.
+func (g *Gateway) handleCreateStripeReconciliation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if !g.stripeReconcilerAvailable(w) {
+		return
+	}
+
+	var req struct {
+		AutoFix bool `json:"auto_fix"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	triggeredBy := "admin"
+	if actor := adminActor(ctx); actor != nil {
+		triggeredBy = *actor
+	}
+
+	run, err := g.createStripeReconciliation(ctx, req.AutoFix, triggeredBy)
+	if errors.Is(err, billing.ErrReconciliationInProgress) {
+		g.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to start stripe reconciliation", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to start stripe reconciliation")
+		return
+	}
+
+	g.logger.Info("stripe reconciliation queued",
+		zap.String("run_id", run.ID.String()),
+		zap.Bool("auto_fix", run.AutoFix),
+		zap.String("triggered_by", triggeredBy),
+	)
+	g.writeJSON(w, http.StatusAccepted, run)
+}
+
+// createStripeReconciliation records a pending run and queues its job
+func (g *Gateway) createStripeReconciliation(ctx context.Context, autoFix bool, triggeredBy string) (*billing.StripeReconciliationRun, error) {
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	run, err := g.StripeReconciler.CreateRun(ctx, tx, autoFix, triggeredBy)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := g.jobs.EnqueueTx(ctx, tx, jobStripeReconcile, stripeReconciliationArgs{RunID: run.ID}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// handleListStripeReconciliations lists recent reconciliation runs
+// Platform Admin Only - GET /admin/billing/reconciliations
+func (g *Gateway) handleListStripeReconciliations(w http.ResponseWriter, r *http.Request) {
+	if !g.stripeReconcilerAvailable(w) {
+		return
+	}
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxStripeReconciliationRuns {
+			g.writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxStripeReconciliationRuns))
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := g.StripeReconciler.ListRuns(r.Context(), limit)
+	if err != nil {
+		g.logger.Error("failed to list stripe reconciliations", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list stripe reconciliations")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": runs,
+	})
+}
+
+// handleGetStripeReconciliation returns a run with its discrepancy report,
+// optionally filtered to one discrepancy type
+// Platform Admin Only - GET /admin/billing/reconciliations/{id}
+func (g *Gateway) handleGetStripeReconciliation(w http.ResponseWriter, r *http.Request) {
+	if !g.stripeReconcilerAvailable(w) {
+		return
+	}
+	runID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	discrepancyType := r.URL.Query().Get("type")
+	switch discrepancyType {
+	case "", billing.DiscrepancyOrphanedCustomer, billing.DiscrepancyMissingCustomer,
+		billing.DiscrepancyStatusMismatch, billing.DiscrepancySubscriptionMismatch, billing.DiscrepancyPlanMismatch:
+	default:
+		g.writeError(w, http.StatusBadRequest, "unknown discrepancy type "+strconv.Quote(discrepancyType))
+		return
+	}
+
+	run, err := g.StripeReconciler.GetRun(r.Context(), runID, discrepancyType)
+	if errors.Is(err, billing.ErrReconciliationRunNotFound) {
+		g.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to get stripe reconciliation", zap.String("run_id", runID.String()), zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to get stripe reconciliation")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, run)
+}
+
+// runStripeReconcile executes a queued reconciliation run
+func (g *Gateway) runStripeReconcile(ctx context.Context, job *jobs.Job) error {
+	var args stripeReconciliationArgs
+	if err := job.Decode(&args); err != nil {
+		return err
+	}
+	if g.StripeReconciler == nil {
+		return jobs.Permanent(errors.New("stripe reconciliation is not configured"))
+	}
+	return g.StripeReconciler.Execute(ctx, args.RunID)
+}
@@ -55,7 +55,7 @@ func (g *Gateway) handleCreateTenantAPIKey(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Create API key using Authenticator
-	apiKey, err := g.authenticator.CreateAPIKey(ctx, tenantID, envID, req.Name)
+	apiKey, err := g.authenticator.CreateAPIKey(ctx, tenantID, envID, req.Name, g.tenantPlan(ctx, tenantID))
 	if err != nil {
 		g.logger.Error("failed to create api key",
 			zap.Error(err),
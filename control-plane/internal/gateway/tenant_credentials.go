@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
@@ -202,6 +203,8 @@ func (g *Gateway) handleUpdateTenantCredential(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	g.syncCredentialProfile(ctx, credentialID)
+
 	g.logger.Info("tenant credential updated",
 		zap.String("credential_id", credentialID.String()),
 		zap.String("tenant_id", tenantID.String()),
@@ -244,6 +247,8 @@ func (g *Gateway) handleDeleteTenantCredential(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	g.deleteCredentialProfile(ctx, credentialID)
+
 	g.logger.Info("tenant credential deleted",
 		zap.String("credential_id", credentialID.String()),
 		zap.String("tenant_id", tenantID.String()),
@@ -357,3 +362,31 @@ func (g *Gateway) handleSetDefaultTenantCredential(w http.ResponseWriter, r *htt
 		"message": "credential set as default",
 	})
 }
+
+// syncCredentialProfile rotates the credential's SkyPilot profile after an
+// update. Failures are logged; the next launch re-syncs a stale profile.
+func (g *Gateway) syncCredentialProfile(ctx context.Context, credentialID uuid.UUID) {
+	if g.orchestrator == nil {
+		return
+	}
+	if err := g.orchestrator.SyncCredentialProfile(ctx, credentialID); err != nil {
+		g.logger.Warn("failed to sync credential profile",
+			zap.Error(err),
+			zap.String("credential_id", credentialID.String()),
+		)
+	}
+}
+
+// deleteCredentialProfile removes the credential's SkyPilot profile after the
+// credential is deleted
+func (g *Gateway) deleteCredentialProfile(ctx context.Context, credentialID uuid.UUID) {
+	if g.orchestrator == nil {
+		return
+	}
+	if err := g.orchestrator.DeleteCredentialProfile(ctx, credentialID); err != nil {
+		g.logger.Warn("failed to delete credential profile",
+			zap.Error(err),
+			zap.String("credential_id", credentialID.String()),
+		)
+	}
+}
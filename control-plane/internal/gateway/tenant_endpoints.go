@@ -1,110 +1,247 @@
 package gateway
 
 import (
+	"context"
 	"net/http"
+	"time"
 
+	"github.com/crosslogic/control-plane/pkg/database"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
-// handleListTenantEndpoints lists available inference endpoints for the tenant
-// Tenant API - GET /v1/endpoints
-// Returns models that have active healthy nodes
-func (g *Gateway) handleListTenantEndpoints(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+// Endpoint availability as reported to tenants
+const (
+	endpointAvailable   = "available"
+	endpointDegraded    = "degraded"
+	endpointUnavailable = "unavailable"
+)
 
-	// Extract tenant_id from context (for future tenant-specific filtering if needed)
-	_, ok := ctx.Value("tenant_id").(uuid.UUID)
-	if !ok {
-		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
-		return
+// EndpointRegion is the serving state of a model in one region
+type EndpointRegion struct {
+	Region       string `json:"region"`
+	Name         string `json:"name,omitempty"`
+	Status       string `json:"status"`
+	HealthyNodes int    `json:"healthy_nodes"`
+	TotalNodes   int    `json:"total_nodes"`
+}
+
+// TenantEndpoint describes a model the tenant can send traffic to and
+// whether it is live right now
+type TenantEndpoint struct {
+	ModelID          uuid.UUID        `json:"model_id"`
+	ModelName        string           `json:"model_name"`
+	Family           string           `json:"family"`
+	Type             string           `json:"type"`
+	ContextLength    int              `json:"context_length"`
+	PriceInput       float64          `json:"price_input_per_million"`
+	PriceOutput      float64          `json:"price_output_per_million"`
+	Status           string           `json:"status"`
+	HealthyNodes     int              `json:"healthy_nodes"`
+	TotalNodes       int              `json:"total_nodes"`
+	CapacityTPS      int              `json:"capacity_tps"`
+	AvgLatencyMs     *float64         `json:"avg_latency_ms,omitempty"`
+	Regions          []EndpointRegion `json:"regions"`
+	Deprecated       bool             `json:"deprecated"`
+	SunsetAt         *time.Time       `json:"sunset_at,omitempty"`
+	ReplacementModel *string          `json:"replacement_model,omitempty"`
+	Description      string           `json:"description"`
+
+	tokensPerSecond *int
+}
+
+// regionEndpointStatus reports a region's availability from its node counts
+// and the region's operational status
+func regionEndpointStatus(healthy, total int, regionStatus string) string {
+	switch {
+	case healthy == 0 || regionStatus == "offline" || regionStatus == "maintenance":
+		return endpointUnavailable
+	case healthy < total || regionStatus == "degraded":
+		return endpointDegraded
+	default:
+		return endpointAvailable
 	}
+}
 
-	// Get optional type filter
-	modelType := r.URL.Query().Get("type")
+// modelEndpointStatus combines the regions of a model. A model whose circuit
+// breaker is open is shedding traffic and never reported as available.
+func modelEndpointStatus(regions []EndpointRegion, breakerOpen bool) string {
+	available, live := 0, 0
+	for _, region := range regions {
+		switch region.Status {
+		case endpointAvailable:
+			available++
+			live++
+		case endpointDegraded:
+			live++
+		}
+	}
 
-	// Query models that have active nodes
-	query := `
-		SELECT DISTINCT m.id, m.name, m.family, m.type, m.context_length,
-		       m.price_input_per_million, m.price_output_per_million,
-		       m.tokens_per_second_capacity, m.status
-		FROM models m
-		INNER JOIN nodes n ON n.model_id = m.id
-		WHERE m.status = 'active'
-		  AND n.status = 'active'
-		  AND n.health_score >= 50.0
-	`
+	switch {
+	case live == 0:
+		return endpointUnavailable
+	case breakerOpen || available < len(regions):
+		return endpointDegraded
+	default:
+		return endpointAvailable
+	}
+}
 
-	args := []interface{}{}
+// loadTenantEndpoints returns the deployed models visible to the tenant with
+// per-region health. Platform nodes serve every tenant; tenant-owned
+// instances only count for their owner. A nil modelID loads every model.
+func (g *Gateway) loadTenantEndpoints(ctx context.Context, tenantID uuid.UUID, modelID *uuid.UUID, modelType string) ([]*TenantEndpoint, error) {
+	args := database.NewArgs()
+	where := database.NewWhere(args).
+		Raw("m.status IN ('active', 'beta', 'deprecated')").
+		Raw("(m.sunset_at IS NULL OR m.sunset_at > NOW())").
+		Raw("(n.tenant_id IS NULL OR n.tenant_id = " + args.Add(tenantID) + ")")
+	if modelID != nil {
+		where.Eq("m.id", *modelID)
+	}
 	if modelType != "" {
-		query += " AND m.type = $1"
-		args = append(args, modelType)
+		where.Eq("m.type", modelType)
 	}
 
-	query += " ORDER BY m.family, m.name"
-
-	rows, err := g.db.Pool.Query(ctx, query, args...)
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT m.id, m.name, m.family, m.type, m.context_length,
+		       m.price_input_per_million, m.price_output_per_million,
+		       m.tokens_per_second_capacity, m.status, m.sunset_at, m.replacement_model,
+		       COALESCE(r.code, ''), COALESCE(r.name, ''), COALESCE(r.status, 'active'),
+		       COUNT(*) FILTER (WHERE n.status = 'active' AND n.health_score >= 50.0),
+		       COUNT(*)
+		FROM models m
+		INNER JOIN nodes n ON n.model_id = m.id AND n.status != 'dead'
+		LEFT JOIN regions r ON r.id = n.region_id
+		`+where.String()+`
+		GROUP BY m.id, r.code, r.name, r.status
+		ORDER BY m.family, m.name, r.code
+	`, args.Values()...)
 	if err != nil {
-		g.logger.Error("failed to query endpoints", zap.Error(err))
-		g.writeError(w, http.StatusInternalServerError, "failed to query endpoints")
-		return
+		return nil, err
 	}
 	defer rows.Close()
 
-	var endpoints []map[string]interface{}
+	var endpoints []*TenantEndpoint
+	byModel := make(map[uuid.UUID]*TenantEndpoint)
 	for rows.Next() {
-		var modelID uuid.UUID
-		var name, family, mType, status string
-		var contextLength int
-		var priceInput, priceOutput float64
-		var tps *int
-
-		if err := rows.Scan(&modelID, &name, &family, &mType, &contextLength,
-			&priceInput, &priceOutput, &tps, &status); err != nil {
-			g.logger.Warn("failed to scan endpoint row", zap.Error(err))
-			continue
+		var e TenantEndpoint
+		var modelStatus, regionStatus string
+		var region EndpointRegion
+		if err := rows.Scan(&e.ModelID, &e.ModelName, &e.Family, &e.Type, &e.ContextLength,
+			&e.PriceInput, &e.PriceOutput, &e.tokensPerSecond, &modelStatus, &e.SunsetAt, &e.ReplacementModel,
+			&region.Region, &region.Name, &regionStatus, &region.HealthyNodes, &region.TotalNodes); err != nil {
+			return nil, err
 		}
 
-		// Get node stats for this model
-		var healthyNodes int
-		var avgLatency *float64
-		err = g.db.Pool.QueryRow(ctx, `
-			SELECT COUNT(*), AVG(ur.latency_ms)
-			FROM nodes n
-			LEFT JOIN usage_records ur ON ur.node_id = n.id
-			  AND ur.timestamp > NOW() - INTERVAL '1 hour'
-			WHERE n.model_id = $1 AND n.status = 'active'
-		`, modelID).Scan(&healthyNodes, &avgLatency)
-
-		capacityTPS := 0
-		if tps != nil && healthyNodes > 0 {
-			capacityTPS = *tps * healthyNodes
+		endpoint, ok := byModel[e.ModelID]
+		if !ok {
+			e.Deprecated = modelStatus == "deprecated"
+			e.Description = generateModelDescription(e.ModelName, e.Family)
+			e.Regions = []EndpointRegion{}
+			endpoint = &e
+			byModel[e.ModelID] = endpoint
+			endpoints = append(endpoints, endpoint)
 		}
 
-		endpoint := map[string]interface{}{
-			"model_id":                  modelID,
-			"model_name":                name,
-			"family":                    family,
-			"type":                      mType,
-			"context_length":            contextLength,
-			"price_input_per_million":   priceInput,
-			"price_output_per_million":  priceOutput,
-			"status":                    "available",
-			"healthy_nodes":             healthyNodes,
-			"capacity_tps":              capacityTPS,
-			"description":               generateModelDescription(name, family),
+		if region.Region == "" {
+			region.Region = "unknown"
 		}
+		region.Status = regionEndpointStatus(region.HealthyNodes, region.TotalNodes, regionStatus)
+		endpoint.Regions = append(endpoint.Regions, region)
+		endpoint.HealthyNodes += region.HealthyNodes
+		endpoint.TotalNodes += region.TotalNodes
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return endpoints, nil
+	}
 
-		if avgLatency != nil {
-			endpoint["avg_latency_ms"] = *avgLatency
+	// Recent latency for every listed model in one query
+	ids := make([]uuid.UUID, 0, len(endpoints))
+	for _, e := range endpoints {
+		ids = append(ids, e.ModelID)
+	}
+	latencyRows, err := g.db.Pool.Query(ctx, `
+		SELECT model_id, AVG(latency_ms)
+		FROM usage_records
+		WHERE model_id = ANY($1) AND timestamp > NOW() - INTERVAL '1 hour'
+		GROUP BY model_id
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer latencyRows.Close()
+	for latencyRows.Next() {
+		var id uuid.UUID
+		var avg *float64
+		if err := latencyRows.Scan(&id, &avg); err != nil {
+			return nil, err
+		}
+		if e, ok := byModel[id]; ok {
+			e.AvgLatencyMs = avg
+		}
+	}
+	if err := latencyRows.Err(); err != nil {
+		return nil, err
+	}
+
+	openBreakers := make(map[string]bool)
+	for _, b := range g.modelBreakers.status(time.Now()) {
+		openBreakers[b.Model] = b.State == "open"
+	}
+	for _, e := range endpoints {
+		e.Status = modelEndpointStatus(e.Regions, openBreakers[e.ModelName])
+		if e.tokensPerSecond != nil {
+			e.CapacityTPS = *e.tokensPerSecond * e.HealthyNodes
 		}
+	}
+
+	return endpoints, nil
+}
+
+// handleListTenantEndpoints lists the models the tenant can send traffic to,
+// with serving regions, live availability, context length and pricing
+// Tenant API - GET /v1/endpoints?type=chat&status=available
+//
+// status is available, degraded (some regions or nodes are unhealthy, or the
+// model is shedding load) or unavailable (deployed but no healthy nodes).
+func (g *Gateway) handleListTenantEndpoints(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return
+	}
 
-		endpoints = append(endpoints, endpoint)
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", endpointAvailable, endpointDegraded, endpointUnavailable:
+	default:
+		g.writeError(w, http.StatusBadRequest, "status must be one of available, degraded, unavailable")
+		return
+	}
+
+	endpoints, err := g.loadTenantEndpoints(ctx, tenantID, nil, r.URL.Query().Get("type"))
+	if err != nil {
+		g.logger.Error("failed to query endpoints", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to query endpoints")
+		return
+	}
+
+	data := make([]*TenantEndpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		if status == "" || e.Status == status {
+			data = append(data, e)
+		}
 	}
 
 	g.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"data": endpoints,
+		"data": data,
 	})
 }
 
@@ -114,7 +251,7 @@ func (g *Gateway) handleGetTenantEndpoint(w http.ResponseWriter, r *http.Request
 	ctx := r.Context()
 
 	// Extract tenant_id from context
-	_, ok := ctx.Value("tenant_id").(uuid.UUID)
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
 	if !ok {
 		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
 		return
@@ -226,6 +363,14 @@ func (g *Gateway) handleGetTenantEndpoint(w http.ResponseWriter, r *http.Request
 		endpoint["p99_latency_ms"] = *p99
 	}
 
+	// Serving regions and live availability as listed by GET /v1/endpoints
+	if live, err := g.loadTenantEndpoints(ctx, tenantID, &modelID, ""); err != nil {
+		g.logger.Error("failed to load endpoint regions", zap.Error(err))
+	} else if len(live) == 1 {
+		endpoint["status"] = live[0].Status
+		endpoint["regions"] = live[0].Regions
+	}
+
 	g.writeJSON(w, http.StatusOK, endpoint)
 }
 
@@ -0,0 +1,44 @@
+package gateway
+
+import "testing"
+
+func TestRegionEndpointStatus(t *testing.T) {
+	tests := []struct {
+		healthy, total int
+		regionStatus   string
+		want           string
+	}{
+		{2, 2, "active", endpointAvailable},
+		{1, 2, "active", endpointDegraded},
+		{2, 2, "degraded", endpointDegraded},
+		{0, 2, "active", endpointUnavailable},
+		{2, 2, "maintenance", endpointUnavailable},
+	}
+	for _, tt := range tests {
+		if got := regionEndpointStatus(tt.healthy, tt.total, tt.regionStatus); got != tt.want {
+			t.Errorf("regionEndpointStatus(%d, %d, %q) = %q, want %q", tt.healthy, tt.total, tt.regionStatus, got, tt.want)
+		}
+	}
+}
+
+func TestModelEndpointStatus(t *testing.T) {
+	up := EndpointRegion{Status: endpointAvailable}
+	down := EndpointRegion{Status: endpointUnavailable}
+
+	tests := []struct {
+		name        string
+		regions     []EndpointRegion
+		breakerOpen bool
+		want        string
+	}{
+		{"all regions up", []EndpointRegion{up, up}, false, endpointAvailable},
+		{"one region down", []EndpointRegion{up, down}, false, endpointDegraded},
+		{"breaker open", []EndpointRegion{up}, true, endpointDegraded},
+		{"no live region", []EndpointRegion{down}, false, endpointUnavailable},
+	}
+	for _, tt := range tests {
+		if got := modelEndpointStatus(tt.regions, tt.breakerOpen); got != tt.want {
+			t.Errorf("%s: modelEndpointStatus() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/crosslogic/control-plane/internal/nodes"
 	"github.com/crosslogic/control-plane/internal/orchestrator"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
@@ -26,6 +28,7 @@ type LaunchInstanceRequest struct {
 	UseSpot            *bool   `json:"use_spot,omitempty"`            // Optional - defaults to true
 	DiskSize           *int    `json:"disk_size,omitempty"`           // Optional - defaults to 256GB
 	VLLMArgs           string  `json:"vllm_args,omitempty"`           // Optional additional vLLM arguments
+	Profile            string  `json:"profile,omitempty"`             // Optional launch profile name used to pre-fill unset fields
 }
 
 // InstanceOutput represents a vLLM instance for tenant viewing
@@ -62,6 +65,25 @@ func (g *Gateway) handleLaunchTenantInstance(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Pre-fill unset fields from the named launch profile
+	if req.Profile != "" {
+		profile, err := g.resolveLaunchProfile(ctx, tenantID, req.Profile)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				g.writeError(w, http.StatusBadRequest, "launch profile not found: "+req.Profile)
+				return
+			}
+			g.logger.Error("failed to resolve launch profile",
+				zap.Error(err),
+				zap.String("tenant_id", tenantID.String()),
+				zap.String("profile", req.Profile),
+			)
+			g.writeError(w, http.StatusInternalServerError, "failed to resolve launch profile")
+			return
+		}
+		profile.ApplyTo(&req)
+	}
+
 	// Validate required fields
 	if req.Model == "" {
 		g.writeError(w, http.StatusBadRequest, "model is required")
@@ -144,11 +166,15 @@ func (g *Gateway) handleLaunchTenantInstance(w http.ResponseWriter, r *http.Requ
 		zap.String("provider", provider),
 		zap.String("region", req.Region),
 		zap.String("gpu", req.GPU),
+		zap.String("profile", req.Profile),
 	)
 
 	// Launch node using orchestrator
 	clusterName, err := g.orchestrator.LaunchNode(ctx, nodeConfig)
 	if err != nil {
+		if g.writeNodeConfigError(w, err) {
+			return
+		}
 		g.logger.Error("failed to launch tenant instance",
 			zap.Error(err),
 			zap.String("tenant_id", tenantID.String()),
@@ -477,24 +503,16 @@ func (g *Gateway) handleStreamTenantInstanceLogs(w http.ResponseWriter, r *http.
 
 // registerTenantInstance registers a tenant-owned instance in the database
 func (g *Gateway) registerTenantInstance(ctx context.Context, tenantID, instanceID uuid.UUID, clusterName string, config orchestrator.NodeConfig) error {
-	query := `
-		INSERT INTO nodes (
-			id, tenant_id, cluster_name, provider, gpu_type,
-			model_name, status, spot_instance, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, 'launching', $7, NOW(), NOW())
-		ON CONFLICT (id) DO UPDATE
-		SET cluster_name = $3, status = 'launching', updated_at = NOW()
-	`
-
-	_, err := g.db.Pool.Exec(ctx, query,
-		instanceID,
-		tenantID,
-		clusterName,
-		config.Provider,
-		config.GPU,
-		config.Model,
-		config.UseSpot,
-	)
-
+	_, _, err := g.nodeRegistry.Register(ctx, nodes.Registration{
+		ID:           instanceID,
+		ClusterName:  clusterName,
+		TenantID:     &tenantID,
+		Provider:     config.Provider,
+		Region:       config.Region,
+		GPUType:      config.GPU,
+		ModelName:    config.Model,
+		SpotInstance: config.UseSpot,
+		Status:       nodes.StatusInitializing,
+	})
 	return err
 }
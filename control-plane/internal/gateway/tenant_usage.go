@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -87,14 +88,94 @@ func (g *Gateway) handleGetUsage(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"start_date":      startDate,
 		"end_date":        endDate,
 		"total_tokens":    totalTokens,
 		"total_requests":  totalRequests,
 		"total_cost_usd":  float64(totalCostMicrodollars) / 1_000_000.0,
 		"by_model":        byModel,
-	})
+	}
+
+	// Optional per-environment breakdown with each environment's budget
+	if r.URL.Query().Get("group_by") == "environment" {
+		byEnvironment, err := g.usageByEnvironment(ctx, tenantID, startDate, endDate)
+		if err != nil {
+			g.logger.Error("failed to query usage by environment", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to query usage")
+			return
+		}
+		response["by_environment"] = byEnvironment
+	}
+
+	g.writeJSON(w, http.StatusOK, response)
+}
+
+// usageByEnvironment sums usage per environment of the tenant, attaching the
+// environment budget and its use this period where one is set
+func (g *Gateway) usageByEnvironment(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]map[string]interface{}, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT
+			e.id,
+			e.name,
+			COALESCE(SUM(ur.total_tokens), 0) as tokens,
+			COUNT(ur.id) as requests,
+			COALESCE(SUM(ur.cost_microdollars), 0) as cost_microdollars,
+			b.environment_id IS NOT NULL as has_budget
+		FROM environments e
+		LEFT JOIN usage_records ur ON ur.environment_id = e.id
+		  AND ur.timestamp >= $2
+		  AND ur.timestamp <= $3
+		LEFT JOIN environment_budgets b ON b.environment_id = e.id
+		WHERE e.tenant_id = $1
+		GROUP BY e.id, e.name, b.environment_id
+		ORDER BY tokens DESC
+	`, tenantID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	type environmentUsage struct {
+		id                          uuid.UUID
+		name                        string
+		tokens, requests, costMicro int64
+		hasBudget                   bool
+	}
+	var usage []environmentUsage
+	for rows.Next() {
+		var u environmentUsage
+		if err := rows.Scan(&u.id, &u.name, &u.tokens, &u.requests, &u.costMicro, &u.hasBudget); err != nil {
+			g.logger.Warn("failed to scan usage row", zap.Error(err))
+			continue
+		}
+		usage = append(usage, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byEnvironment := make([]map[string]interface{}, 0, len(usage))
+	for _, u := range usage {
+		entry := map[string]interface{}{
+			"environment_id":   u.id,
+			"environment_name": u.name,
+			"tokens":           u.tokens,
+			"requests":         u.requests,
+			"cost_usd":         float64(u.costMicro) / 1_000_000.0,
+		}
+		if u.hasBudget {
+			budget, err := g.environmentBudgetStatus(ctx, tenantID, u.id)
+			if err != nil {
+				return nil, err
+			}
+			if budget != nil {
+				entry["budget"] = budget
+			}
+		}
+		byEnvironment = append(byEnvironment, entry)
+	}
+	return byEnvironment, nil
 }
 
 // handleGetUsageByModel returns usage breakdown by model
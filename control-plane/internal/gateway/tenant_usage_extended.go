@@ -25,7 +25,8 @@ func (g *Gateway) handleGetUsageDetailed(w http.ResponseWriter, r *http.Request)
 	startDate, endDate := parseDateRange(r)
 	modelFilter := r.URL.Query().Get("model_id")
 	apiKeyFilter := r.URL.Query().Get("api_key_id")
-	groupBy := r.URL.Query().Get("group_by") // model, api_key, region, hour, day
+	projectFilter := r.URL.Query().Get("project") // environment ID, OpenAI project ID or name
+	groupBy := r.URL.Query().Get("group_by") // model, api_key, region, gpu_type, project, hour, day
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
 
@@ -50,16 +51,18 @@ func (g *Gateway) handleGetUsageDetailed(w http.ResponseWriter, r *http.Request)
 
 	// Validate group_by
 	validGroupBy := map[string]string{
-		"model":   "m.id, m.name",
-		"api_key": "ak.id, ak.name, ak.key_prefix",
-		"region":  "r.id, r.name, r.code",
-		"hour":    "DATE_TRUNC('hour', ur.timestamp)",
-		"day":     "DATE_TRUNC('day', ur.timestamp)",
+		"model":    "m.id, m.name",
+		"api_key":  "ak.id, ak.name, ak.key_prefix",
+		"region":   "r.id, r.name, r.code",
+		"gpu_type": "ur.gpu_type",
+		"project":  "e.id, e.name, e.openai_project_id",
+		"hour":     "DATE_TRUNC('hour', ur.timestamp)",
+		"day":      "DATE_TRUNC('day', ur.timestamp)",
 	}
 
 	groupClause, ok := validGroupBy[groupBy]
 	if !ok {
-		g.writeError(w, http.StatusBadRequest, "invalid group_by parameter. Valid values: model, api_key, region, hour, day")
+		g.writeError(w, http.StatusBadRequest, "invalid group_by parameter. Valid values: model, api_key, region, gpu_type, project, hour, day")
 		return
 	}
 
@@ -75,6 +78,12 @@ func (g *Gateway) handleGetUsageDetailed(w http.ResponseWriter, r *http.Request)
 	case "region":
 		selectClause = "r.id as region_id, r.name as region_name, r.code as region_code"
 		joinClause = "LEFT JOIN regions r ON r.id = ur.region_id"
+	case "gpu_type":
+		selectClause = "ur.gpu_type"
+		joinClause = ""
+	case "project":
+		selectClause = "e.id as environment_id, e.name as environment_name, e.openai_project_id"
+		joinClause = "INNER JOIN environments e ON e.id = ur.environment_id"
 	case "hour", "day":
 		selectClause = "DATE_TRUNC('" + groupBy + "', ur.timestamp) as period"
 		joinClause = ""
@@ -119,6 +128,19 @@ func (g *Gateway) handleGetUsageDetailed(w http.ResponseWriter, r *http.Request)
 			argNum++
 		}
 	}
+	if projectFilter != "" {
+		query += fmt.Sprintf(` AND ur.environment_id IN (
+			SELECT id FROM environments
+			WHERE tenant_id = $1 AND (id::text = $%d OR openai_project_id = $%d OR name = $%d)
+		)`, argNum, argNum, argNum)
+		args = append(args, projectFilter)
+		argNum++
+	} else if envID, ok := ctx.Value("openai_project").(uuid.UUID); ok {
+		// Clients that send OpenAI-Project only see that project's usage
+		query += fmt.Sprintf(" AND ur.environment_id = $%d", argNum)
+		args = append(args, envID)
+		argNum++
+	}
 
 	query += " GROUP BY " + groupClause + " ORDER BY total_tokens DESC"
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argNum, argNum+1)
@@ -223,6 +245,53 @@ func (g *Gateway) handleGetUsageDetailed(w http.ResponseWriter, r *http.Request)
 				regionData["region_code"] = *regionCode
 			}
 			data = append(data, regionData)
+		case "gpu_type":
+			var gpuType *string
+			if err := rows.Scan(&gpuType,
+				&promptTokens, &completionTokens, &totalTokens, &cachedTokens,
+				&totalRequests, &avgLatency, &minLatency, &maxLatency, &totalCostMicro); err != nil {
+				g.logger.Warn("failed to scan row", zap.Error(err))
+				continue
+			}
+			gpuData := map[string]interface{}{
+				"prompt_tokens":     promptTokens,
+				"completion_tokens": completionTokens,
+				"total_tokens":      totalTokens,
+				"cached_tokens":     cachedTokens,
+				"total_requests":    totalRequests,
+				"avg_latency_ms":    avgLatency,
+				"min_latency_ms":    minLatency,
+				"max_latency_ms":    maxLatency,
+				"total_cost_usd":    float64(totalCostMicro) / 1_000_000.0,
+			}
+			if gpuType != nil {
+				gpuData["gpu_type"] = *gpuType
+			}
+			data = append(data, gpuData)
+		case "project":
+			var envID uuid.UUID
+			var envName string
+			var projectID *string
+			if err := rows.Scan(&envID, &envName, &projectID,
+				&promptTokens, &completionTokens, &totalTokens, &cachedTokens,
+				&totalRequests, &avgLatency, &minLatency, &maxLatency, &totalCostMicro); err != nil {
+				g.logger.Warn("failed to scan row", zap.Error(err))
+				continue
+			}
+			data = append(data, map[string]interface{}{
+				"environment_id":    envID,
+				"environment_name":  envName,
+				"openai_project_id": projectID,
+				"prompt_tokens":     promptTokens,
+				"completion_tokens": completionTokens,
+				"total_tokens":      totalTokens,
+				"cached_tokens":     cachedTokens,
+				"total_requests":    totalRequests,
+				"avg_latency_ms":    avgLatency,
+				"min_latency_ms":    minLatency,
+				"max_latency_ms":    maxLatency,
+				"total_cost_usd":    float64(totalCostMicro) / 1_000_000.0,
+			})
 		case "hour", "day":
 			var period time.Time
 			if err := rows.Scan(&period,
@@ -42,11 +42,9 @@ func (g *Gateway) handleCreateTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create default environment for the tenant
-	_, err = g.db.Pool.Exec(ctx, `
-		INSERT INTO environments (tenant_id, name, region, status, created_at, updated_at)
-		VALUES ($1, 'production', 'us-east', 'active', NOW(), NOW())
-	`, tenantID)
+	// Create default environment for the tenant, with the production
+	// rate limit template
+	_, err = g.createEnvironment(ctx, tenantID, "production", "us-east")
 
 	if err != nil {
 		g.logger.Error("failed to create default environment", zap.Error(err))
@@ -141,10 +139,7 @@ func (g *Gateway) handleResolveTenant(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create default environment
-	_, err = g.db.Pool.Exec(ctx, `
-		INSERT INTO environments (tenant_id, name, region, status, created_at, updated_at)
-		VALUES ($1, 'production', 'us-east', 'active', NOW(), NOW())
-	`, tenantID)
+	_, err = g.createEnvironment(ctx, tenantID, "production", "us-east")
 
 	if err != nil {
 		g.logger.Error("failed to create default environment", zap.Error(err))
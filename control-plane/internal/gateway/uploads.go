@@ -0,0 +1,531 @@
+package gateway
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/r2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// The Uploads API sends a file in parts: create an upload declaring the
+// file's size, add parts, then complete it with the parts in order to get a
+// file. Parts are kept in R2 until the upload completes, is cancelled or
+// expires.
+
+const (
+	// uploadPartMaxBytes is the largest part, as in OpenAI's Uploads API
+	uploadPartMaxBytes = 64 << 20
+
+	// uploadTTL is how long an upload accepts parts before it expires
+	uploadTTL = time.Hour
+
+	// uploadExpiryInterval is how often expired uploads' parts are removed
+	uploadExpiryInterval = 10 * time.Minute
+)
+
+// Upload statuses
+const (
+	uploadPending   = "pending"
+	uploadCompleted = "completed"
+	uploadCancelled = "cancelled"
+	uploadExpired   = "expired"
+)
+
+// uploadMimeTypes are the media types an upload may declare for its file
+var uploadMimeTypes = map[string]bool{
+	"application/jsonl":       true,
+	"application/x-jsonlines": true,
+	"application/x-ndjson":    true,
+	"application/json":        true,
+	"text/jsonl":              true,
+}
+
+// Upload is a file being sent in parts, as the OpenAI Uploads API describes it
+type Upload struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+	ExpiresAt int64  `json:"expires_at"`
+	File      *File  `json:"file"`
+}
+
+// UploadPart is one part added to an upload
+type UploadPart struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	CreatedAt int64  `json:"created_at"`
+	UploadID  string `json:"upload_id"`
+
+	bytes     int64
+	objectKey string
+}
+
+// CreateUploadRequest is the body of POST /v1/uploads
+type CreateUploadRequest struct {
+	Filename string `json:"filename"`
+	Purpose  string `json:"purpose"`
+	Bytes    int64  `json:"bytes"`
+	MimeType string `json:"mime_type"`
+}
+
+// Validate checks an upload request before any parts are sent
+func (req *CreateUploadRequest) Validate() error {
+	if !validFilePurpose(req.Purpose) {
+		return errors.New(`purpose must be "batch" or "fine-tune"`)
+	}
+	if req.Bytes <= 0 || req.Bytes > filesMaxBytes {
+		return fmt.Errorf("bytes must be between 1 and %d", filesMaxBytes)
+	}
+	mediaType, _, err := mime.ParseMediaType(req.MimeType)
+	if err != nil || !uploadMimeTypes[mediaType] {
+		return fmt.Errorf("unsupported mime_type %q; upload JSONL as application/jsonl", req.MimeType)
+	}
+	return validateFileUpload(req.Filename, req.MimeType)
+}
+
+// CompleteUploadRequest is the body of POST /v1/uploads/{upload_id}/complete
+type CompleteUploadRequest struct {
+	PartIDs []string `json:"part_ids"`
+	// MD5 optionally checks the assembled file's hex MD5 checksum
+	MD5 string `json:"md5,omitempty"`
+}
+
+const uploadColumns = `id, filename, purpose, bytes, status, created_at, expires_at`
+
+func scanUpload(row pgx.Row) (*Upload, error) {
+	u := &Upload{Object: "upload"}
+	var createdAt, expiresAt time.Time
+	if err := row.Scan(&u.ID, &u.Filename, &u.Purpose, &u.Bytes, &u.Status, &createdAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	// Uploads past their expiry are expired before the sweep gets to them
+	if u.Status == uploadPending && time.Now().After(expiresAt) {
+		u.Status = uploadExpired
+	}
+	u.CreatedAt = createdAt.Unix()
+	u.ExpiresAt = expiresAt.Unix()
+	return u, nil
+}
+
+// uploadPartKey is where a part is kept until its upload completes
+func uploadPartKey(tenantID uuid.UUID, uploadID, partID string) string {
+	return fmt.Sprintf("uploads/%s/%s/%s", tenantID, uploadID, partID)
+}
+
+// handleCreateUpload starts an upload. The declared size is checked
+// against the storage quota now so a large file isn't sent only to be
+// refused.
+// POST /v1/uploads
+func (g *Gateway) handleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.filesTenant(w, r, true)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	var req CreateUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := g.checkFileQuota(ctx, g.db.Pool, tenantID, req.Bytes); err != nil {
+		g.writeStoreFileError(w, tenantID, err)
+		return
+	}
+
+	upload, err := scanUpload(g.db.Pool.QueryRow(ctx, `
+		INSERT INTO uploads (id, tenant_id, filename, purpose, bytes, mime_type, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+uploadColumns,
+		newObjectID("upload_"), tenantID, req.Filename, req.Purpose, req.Bytes, req.MimeType, time.Now().Add(uploadTTL),
+	))
+	if err != nil {
+		g.logger.Error("failed to create upload", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to create upload")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, upload)
+}
+
+// pendingUpload loads one of the tenant's uploads that still accepts
+// changes, answering the request itself otherwise
+func (g *Gateway) pendingUpload(w http.ResponseWriter, r *http.Request, tenantID uuid.UUID) (*Upload, bool) {
+	upload, err := scanUpload(g.db.Pool.QueryRow(r.Context(), `
+		SELECT `+uploadColumns+` FROM uploads WHERE id = $1 AND tenant_id = $2
+	`, chi.URLParam(r, "upload_id"), tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "upload not found")
+		return nil, false
+	}
+	if err != nil {
+		g.logger.Error("failed to get upload", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to get upload")
+		return nil, false
+	}
+	if upload.Status != uploadPending {
+		g.writeError(w, http.StatusConflict, fmt.Sprintf("upload is %s", upload.Status))
+		return nil, false
+	}
+	return upload, true
+}
+
+// handleAddUploadPart adds a part from the data field of a multipart form.
+// Parts may be added in any order; the order is given on completion.
+// POST /v1/uploads/{upload_id}/parts
+func (g *Gateway) handleAddUploadPart(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.filesTenant(w, r, true)
+	if !ok {
+		return
+	}
+	upload, ok := g.pendingUpload(w, r, tenantID)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	r.Body = http.MaxBytesReader(w, r.Body, uploadPartMaxBytes+filesFormOverhead)
+	data, err := parseUploadPartForm(r.Header.Get("Content-Type"), r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			g.writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("parts may be at most %d MB", uploadPartMaxBytes>>20))
+			return
+		}
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var received int64
+	if err := g.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(bytes), 0)::bigint FROM upload_parts WHERE upload_id = $1
+	`, upload.ID).Scan(&received); err != nil {
+		g.logger.Error("failed to sum upload parts", zap.Error(err), zap.String("upload_id", upload.ID))
+		g.writeError(w, http.StatusInternalServerError, "failed to add upload part")
+		return
+	}
+	if received+int64(len(data)) > upload.Bytes {
+		g.writeError(w, http.StatusBadRequest, fmt.Sprintf("parts would exceed the upload's declared %d bytes", upload.Bytes))
+		return
+	}
+
+	part := &UploadPart{ID: newObjectID("part_"), Object: "upload.part", UploadID: upload.ID, bytes: int64(len(data))}
+	part.objectKey = uploadPartKey(tenantID, upload.ID, part.ID)
+	objects := r2.NewObjects(g.Files)
+	if err := objects.Put(ctx, part.objectKey, data, "application/octet-stream"); err != nil {
+		g.logger.Error("failed to store upload part", zap.Error(err), zap.String("upload_id", upload.ID))
+		g.writeError(w, http.StatusInternalServerError, "failed to add upload part")
+		return
+	}
+
+	var createdAt time.Time
+	err = g.db.Pool.QueryRow(ctx, `
+		INSERT INTO upload_parts (id, upload_id, bytes, object_key)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`, part.ID, upload.ID, part.bytes, part.objectKey).Scan(&createdAt)
+	if err != nil {
+		objects.Delete(context.WithoutCancel(ctx), part.objectKey)
+		g.logger.Error("failed to record upload part", zap.Error(err), zap.String("upload_id", upload.ID))
+		g.writeError(w, http.StatusInternalServerError, "failed to add upload part")
+		return
+	}
+	part.CreatedAt = createdAt.Unix()
+	g.writeJSON(w, http.StatusOK, part)
+}
+
+// parseUploadPartForm reads the data field of a multipart/form-data part
+func parseUploadPartForm(contentType string, body io.Reader) ([]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return nil, errors.New("request must be multipart/form-data")
+	}
+
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fileFormError(err)
+		}
+		if part.FormName() == "data" {
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return nil, fileFormError(err)
+			}
+			if len(data) == 0 {
+				return nil, errors.New("data is empty")
+			}
+			return data, nil
+		}
+		part.Close()
+	}
+	return nil, errors.New("data is required")
+}
+
+// handleCompleteUpload assembles the listed parts in order into a file,
+// which must match the declared size and, when given, the MD5 checksum
+// POST /v1/uploads/{upload_id}/complete
+func (g *Gateway) handleCompleteUpload(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.filesTenant(w, r, true)
+	if !ok {
+		return
+	}
+	upload, ok := g.pendingUpload(w, r, tenantID)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	var req CompleteUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	parts, err := g.uploadParts(ctx, upload.ID)
+	if err != nil {
+		g.logger.Error("failed to list upload parts", zap.Error(err), zap.String("upload_id", upload.ID))
+		g.writeError(w, http.StatusInternalServerError, "failed to complete upload")
+		return
+	}
+	ordered, err := orderUploadParts(req.PartIDs, parts, upload.Bytes)
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	objects := r2.NewObjects(g.Files)
+	data := make([]byte, 0, upload.Bytes)
+	for _, part := range ordered {
+		chunk, err := objects.Get(ctx, part.objectKey)
+		if err != nil {
+			g.logger.Error("failed to read upload part", zap.Error(err), zap.String("part_id", part.ID))
+			g.writeError(w, http.StatusInternalServerError, "failed to complete upload")
+			return
+		}
+		data = append(data, chunk...)
+	}
+	if req.MD5 != "" {
+		sum := md5.Sum(data)
+		if !strings.EqualFold(req.MD5, hex.EncodeToString(sum[:])) {
+			g.writeError(w, http.StatusBadRequest, "md5 does not match the uploaded parts")
+			return
+		}
+	}
+
+	file, err := g.storeFile(ctx, tenantID, upload.Filename, upload.Purpose, data)
+	if err != nil {
+		g.writeStoreFileError(w, tenantID, err)
+		return
+	}
+
+	tag, err := g.db.Pool.Exec(ctx, `
+		UPDATE uploads SET status = $2, file_id = $3 WHERE id = $1 AND status = $4
+	`, upload.ID, uploadCompleted, file.ID, uploadPending)
+	if err != nil || tag.RowsAffected() == 0 {
+		// Cancelled or completed meanwhile; the file stays for the tenant
+		// to use or delete
+		g.logger.Warn("upload changed while completing", zap.Error(err), zap.String("upload_id", upload.ID))
+	}
+	g.removeUploadParts(ctx, upload.ID, parts)
+
+	upload.Status = uploadCompleted
+	upload.File = file
+	g.writeJSON(w, http.StatusOK, upload)
+}
+
+// orderUploadParts returns the parts in the order listed, checking every
+// listed part belongs to the upload and that together they make up the
+// declared size
+func orderUploadParts(partIDs []string, parts map[string]*UploadPart, declared int64) ([]*UploadPart, error) {
+	if len(partIDs) == 0 {
+		return nil, errors.New("part_ids is required")
+	}
+
+	ordered := make([]*UploadPart, 0, len(partIDs))
+	seen := make(map[string]bool, len(partIDs))
+	var total int64
+	for _, id := range partIDs {
+		part, ok := parts[id]
+		if !ok {
+			return nil, fmt.Errorf("part %q is not part of this upload", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("part %q is listed more than once", id)
+		}
+		seen[id] = true
+		total += part.bytes
+		ordered = append(ordered, part)
+	}
+	if total != declared {
+		return nil, fmt.Errorf("parts total %d bytes, but the upload declared %d", total, declared)
+	}
+	return ordered, nil
+}
+
+// uploadParts loads an upload's parts by ID
+func (g *Gateway) uploadParts(ctx context.Context, uploadID string) (map[string]*UploadPart, error) {
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, bytes, object_key, created_at FROM upload_parts WHERE upload_id = $1
+	`, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parts := make(map[string]*UploadPart)
+	for rows.Next() {
+		part := &UploadPart{Object: "upload.part", UploadID: uploadID}
+		var createdAt time.Time
+		if err := rows.Scan(&part.ID, &part.bytes, &part.objectKey, &createdAt); err != nil {
+			return nil, err
+		}
+		part.CreatedAt = createdAt.Unix()
+		parts[part.ID] = part
+	}
+	return parts, rows.Err()
+}
+
+// removeUploadParts deletes an upload's part objects and rows once they are
+// no longer needed
+func (g *Gateway) removeUploadParts(ctx context.Context, uploadID string, parts map[string]*UploadPart) {
+	objects := r2.NewObjects(g.Files)
+	for _, part := range parts {
+		if err := objects.Delete(ctx, part.objectKey); err != nil {
+			g.logger.Warn("failed to remove upload part", zap.Error(err), zap.String("key", part.objectKey))
+			return
+		}
+	}
+	if _, err := g.db.Pool.Exec(ctx, `DELETE FROM upload_parts WHERE upload_id = $1`, uploadID); err != nil {
+		g.logger.Warn("failed to remove upload part rows", zap.Error(err), zap.String("upload_id", uploadID))
+	}
+}
+
+// handleCancelUpload cancels an upload and removes its parts
+// POST /v1/uploads/{upload_id}/cancel
+func (g *Gateway) handleCancelUpload(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.filesTenant(w, r, true)
+	if !ok {
+		return
+	}
+	upload, ok := g.pendingUpload(w, r, tenantID)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	tag, err := g.db.Pool.Exec(ctx, `
+		UPDATE uploads SET status = $2 WHERE id = $1 AND status = $3
+	`, upload.ID, uploadCancelled, uploadPending)
+	if err != nil {
+		g.logger.Error("failed to cancel upload", zap.Error(err), zap.String("upload_id", upload.ID))
+		g.writeError(w, http.StatusInternalServerError, "failed to cancel upload")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		g.writeError(w, http.StatusConflict, "upload is no longer pending")
+		return
+	}
+
+	if parts, err := g.uploadParts(ctx, upload.ID); err == nil {
+		g.removeUploadParts(ctx, upload.ID, parts)
+	} else {
+		g.logger.Warn("failed to list cancelled upload parts", zap.Error(err), zap.String("upload_id", upload.ID))
+	}
+
+	upload.Status = uploadCancelled
+	g.writeJSON(w, http.StatusOK, upload)
+}
+
+// StartUploadExpiry starts the loop that expires uploads left pending past
+// their deadline and removes leftover parts. It does nothing without file
+// storage.
+func (g *Gateway) StartUploadExpiry(ctx context.Context) {
+	if g.Files == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(uploadExpiryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := g.expireUploads(ctx); err != nil {
+					g.logger.Error("failed to expire uploads", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// expireUploads marks pending uploads past their deadline expired and
+// removes the parts left by uploads that are no longer pending, including
+// any a completion or cancellation failed to remove
+func (g *Gateway) expireUploads(ctx context.Context) error {
+	tag, err := g.db.Pool.Exec(ctx, `
+		UPDATE uploads SET status = $1 WHERE status = $2 AND expires_at < NOW()
+	`, uploadExpired, uploadPending)
+	if err != nil {
+		return err
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		g.logger.Info("expired uploads", zap.Int64("uploads", n))
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT DISTINCT p.upload_id FROM upload_parts p
+		JOIN uploads u ON u.id = p.upload_id
+		WHERE u.status != $1
+	`, uploadPending)
+	if err != nil {
+		return err
+	}
+	var finished []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		finished = append(finished, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range finished {
+		parts, err := g.uploadParts(ctx, id)
+		if err != nil {
+			return err
+		}
+		g.removeUploadParts(ctx, id, parts)
+	}
+	return nil
+}
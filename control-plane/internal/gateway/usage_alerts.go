@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/crosslogic/control-plane/internal/notifications"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// usageAlertRequest is the body of POST /v1/alerts and PUT /v1/alerts/{id}
+type usageAlertRequest struct {
+	Name          string  `json:"name"`
+	Metric        string  `json:"metric"`
+	Model         *string `json:"model"`
+	Operator      string  `json:"operator"` // ">" (default) or "<"
+	Threshold     float64 `json:"threshold"`
+	WindowMinutes int     `json:"window_minutes"`
+	Enabled       *bool   `json:"enabled"`
+}
+
+func (req *usageAlertRequest) rule() notifications.UsageAlertRule {
+	rule := notifications.UsageAlertRule{
+		Name:          req.Name,
+		Metric:        req.Metric,
+		Model:         req.Model,
+		Operator:      req.Operator,
+		Threshold:     req.Threshold,
+		WindowMinutes: req.WindowMinutes,
+		Enabled:       true,
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return rule
+}
+
+// usageAlertsTenantID returns the tenant ID for usage alert requests; writes
+// are refused to read-only keys. It returns false when the request has been
+// answered.
+func (g *Gateway) usageAlertsTenantID(w http.ResponseWriter, r *http.Request, write bool) (uuid.UUID, bool) {
+	ctx := r.Context()
+	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return uuid.Nil, false
+	}
+	if g.UsageAlerts == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "usage alerts are not configured")
+		return uuid.Nil, false
+	}
+	if keyInfo, ok := ctx.Value("api_key").(*models.APIKey); write && ok && keyInfo.Role == "read-only" {
+		g.writeError(w, http.StatusForbidden, "read-only API keys cannot change usage alerts")
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// writeUsageAlertError maps usage alert errors to responses
+func (g *Gateway) writeUsageAlertError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, notifications.ErrUsageAlertNotFound):
+		g.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, notifications.ErrInvalidUsageAlert):
+		g.writeError(w, http.StatusBadRequest, err.Error())
+	case isUniqueViolation(err):
+		g.writeError(w, http.StatusConflict, "a usage alert with this name already exists")
+	default:
+		g.logger.Error("usage alert request failed", zap.String("action", action), zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to "+action)
+	}
+}
+
+// handleListUsageAlerts lists the tenant's alert rules with their state
+// Tenant API - GET /v1/alerts
+func (g *Gateway) handleListUsageAlerts(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.usageAlertsTenantID(w, r, false)
+	if !ok {
+		return
+	}
+
+	rules, err := g.UsageAlerts.List(r.Context(), tenantID)
+	if err != nil {
+		g.writeUsageAlertError(w, err, "list usage alerts")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": rules,
+	})
+}
+
+// handleCreateUsageAlert adds an alert rule
+// Tenant API - POST /v1/alerts
+func (g *Gateway) handleCreateUsageAlert(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.usageAlertsTenantID(w, r, true)
+	if !ok {
+		return
+	}
+
+	var req usageAlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	rule, err := g.UsageAlerts.Create(r.Context(), tenantID, req.rule())
+	if err != nil {
+		g.writeUsageAlertError(w, err, "create usage alert")
+		return
+	}
+
+	g.logger.Info("usage alert created",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("rule_id", rule.ID.String()),
+		zap.String("metric", rule.Metric),
+	)
+	g.writeJSON(w, http.StatusCreated, rule)
+}
+
+// handleGetUsageAlert returns one alert rule
+// Tenant API - GET /v1/alerts/{id}
+func (g *Gateway) handleGetUsageAlert(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.usageAlertsTenantID(w, r, false)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid usage alert ID")
+		return
+	}
+
+	rule, err := g.UsageAlerts.Get(r.Context(), tenantID, id)
+	if err != nil {
+		g.writeUsageAlertError(w, err, "get usage alert")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, rule)
+}
+
+// handleUpdateUsageAlert replaces an alert rule's definition
+// Tenant API - PUT /v1/alerts/{id}
+func (g *Gateway) handleUpdateUsageAlert(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.usageAlertsTenantID(w, r, true)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid usage alert ID")
+		return
+	}
+
+	var req usageAlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	rule, err := g.UsageAlerts.Update(r.Context(), tenantID, id, req.rule())
+	if err != nil {
+		g.writeUsageAlertError(w, err, "update usage alert")
+		return
+	}
+
+	g.writeJSON(w, http.StatusOK, rule)
+}
+
+// handleDeleteUsageAlert removes an alert rule
+// Tenant API - DELETE /v1/alerts/{id}
+func (g *Gateway) handleDeleteUsageAlert(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.usageAlertsTenantID(w, r, true)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid usage alert ID")
+		return
+	}
+
+	if err := g.UsageAlerts.Delete(r.Context(), tenantID, id); err != nil {
+		g.writeUsageAlertError(w, err, "delete usage alert")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
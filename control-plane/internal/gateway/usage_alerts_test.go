@@ -0,0 +1,16 @@
+package gateway
+
+import "testing"
+
+func TestUsageAlertRequestRule(t *testing.T) {
+	req := usageAlertRequest{Name: "Daily spend", Metric: "spend_usd", Threshold: 50, WindowMinutes: 1440}
+	if rule := req.rule(); !rule.Enabled || rule.Threshold != 50 || rule.WindowMinutes != 1440 {
+		t.Errorf("rule = %+v, want enabled rule with request fields", rule)
+	}
+
+	off := false
+	req.Enabled = &off
+	if rule := req.rule(); rule.Enabled {
+		t.Error("enabled = false ignored")
+	}
+}
@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Clients that send X-Include-Usage-Cost: true get the cost of each
+// completion back with the response, priced the way billing prices usage:
+// model list price per million tokens times the region's cost multiplier.
+// Non-streaming responses carry it in headers and in usage.cost; streamed
+// responses carry it in trailers once the final usage chunk has been sent,
+// which vLLM only does when the request sets stream_options.include_usage.
+const (
+	usageCostRequestHeader = "X-Include-Usage-Cost"
+
+	usageCostHeader       = "X-Usage-Cost"
+	usageCostInputHeader  = "X-Usage-Cost-Input"
+	usageCostOutputHeader = "X-Usage-Cost-Output"
+)
+
+// usageCostHeaders are the response headers, or trailers, carrying the cost
+var usageCostHeaders = []string{usageCostHeader, usageCostInputHeader, usageCostOutputHeader}
+
+// ModelPricing is a model's list price in USD
+type ModelPricing struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// UsageCost is the cost of one request in USD
+type UsageCost struct {
+	Input    float64 `json:"input"`
+	Output   float64 `json:"output"`
+	Total    float64 `json:"total"`
+	Currency string  `json:"currency"`
+}
+
+// computeUsageCost prices token counts, rounding each part down to whole
+// microdollars as billing does
+func computeUsageCost(pricing ModelPricing, multiplier float64, promptTokens, completionTokens int) UsageCost {
+	input := int64(float64(promptTokens) * pricing.InputPerMillion * multiplier)
+	output := int64(float64(completionTokens) * pricing.OutputPerMillion * multiplier)
+	return UsageCost{
+		Input:    float64(input) / 1_000_000,
+		Output:   float64(output) / 1_000_000,
+		Total:    float64(input+output) / 1_000_000,
+		Currency: "USD",
+	}
+}
+
+// set writes the cost into h
+func (c UsageCost) set(h http.Header) {
+	h.Set(usageCostHeader, formatUSD(c.Total))
+	h.Set(usageCostInputHeader, formatUSD(c.Input))
+	h.Set(usageCostOutputHeader, formatUSD(c.Output))
+}
+
+func formatUSD(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+// wantsUsageCost reports whether the client asked for cost previews
+func wantsUsageCost(r *http.Request) bool {
+	v, err := strconv.ParseBool(strings.TrimSpace(r.Header.Get(usageCostRequestHeader)))
+	return err == nil && v
+}
+
+// applyUsageCost adds the cost of a successful completion to its response
+// when the client asked for it. It is best effort: a model without pricing or
+// a response without usage is passed through unchanged.
+func (g *Gateway) applyUsageCost(r *http.Request, resp *http.Response, model string) {
+	if !wantsUsageCost(r) || resp.StatusCode != http.StatusOK {
+		return
+	}
+	ctx := r.Context()
+
+	capabilities, err := g.getModelCapabilities(ctx, model)
+	if err != nil || capabilities == nil {
+		if err != nil {
+			g.logger.Warn("failed to load model pricing", zap.Error(err), zap.String("model", model))
+		}
+		return
+	}
+	pricing := capabilities.Pricing
+	multiplier := g.regionCostMultiplier(ctx)
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		resp.Trailer = http.Header{}
+		for _, h := range usageCostHeaders {
+			resp.Trailer[h] = nil
+		}
+		resp.Body = &usageCostStreamBody{
+			ReadCloser: resp.Body,
+			onEOF: func(tail []byte) {
+				prompt := lastTokenCount(promptTokensPattern, tail)
+				completion := lastTokenCount(completionTokensPattern, tail)
+				if prompt == nil && completion == nil {
+					return
+				}
+				computeUsageCost(pricing, multiplier, derefInt(prompt), derefInt(completion)).set(resp.Trailer)
+			},
+		}
+		return
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		resp.Body = io.NopCloser(errReader{err})
+		return
+	}
+	if cost, out, ok := injectUsageCost(body, pricing, multiplier); ok {
+		cost.set(resp.Header)
+		body = out
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+}
+
+// injectUsageCost prices the usage block of a completion response and adds
+// it as usage.cost. ok is false when the response has no usage.
+func injectUsageCost(body []byte, pricing ModelPricing, multiplier float64) (UsageCost, []byte, bool) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil || doc["usage"] == nil {
+		return UsageCost{}, body, false
+	}
+	var usage map[string]json.RawMessage
+	if err := json.Unmarshal(doc["usage"], &usage); err != nil || usage == nil {
+		return UsageCost{}, body, false
+	}
+	var prompt, completion int
+	json.Unmarshal(usage["prompt_tokens"], &prompt)
+	json.Unmarshal(usage["completion_tokens"], &completion)
+
+	cost := computeUsageCost(pricing, multiplier, prompt, completion)
+	usage["cost"], _ = json.Marshal(cost)
+	doc["usage"], _ = json.Marshal(usage)
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return UsageCost{}, body, false
+	}
+	return cost, out, true
+}
+
+// regionCostMultiplier is the cost multiplier of the region serving the
+// request's environment, 1 when it has none
+func (g *Gateway) regionCostMultiplier(ctx context.Context) float64 {
+	envID, ok := ctx.Value("environment_id").(uuid.UUID)
+	if !ok {
+		return 1
+	}
+	var multiplier float64
+	err := g.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(r.cost_multiplier, 1.0)::float8
+		FROM environments e
+		LEFT JOIN regions r ON r.code = e.region
+		WHERE e.id = $1
+	`, envID).Scan(&multiplier)
+	if err != nil || multiplier <= 0 {
+		return 1
+	}
+	return multiplier
+}
+
+// writeProxiedResponse relays a node's response, including any trailers
+// set while its body was read
+func writeProxiedResponse(w http.ResponseWriter, resp *http.Response) {
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	for key := range resp.Trailer {
+		w.Header().Add("Trailer", key)
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+
+	for key, values := range resp.Trailer {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+}
+
+// usageCostStreamBody keeps the tail of a streamed response and hands it
+// over at EOF, before the handler writes trailers
+type usageCostStreamBody struct {
+	io.ReadCloser
+	tail  []byte
+	done  bool
+	onEOF func(tail []byte)
+}
+
+func (b *usageCostStreamBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.tail = append(b.tail, p[:n]...)
+		if len(b.tail) > nodeResponseTailSize {
+			b.tail = b.tail[len(b.tail)-nodeResponseTailSize:]
+		}
+	}
+	if errors.Is(err, io.EOF) && !b.done {
+		b.done = true
+		b.onEOF(b.tail)
+	}
+	return n, err
+}
+
+// errReader fails every read with err
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func derefInt(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestComputeUsageCost(t *testing.T) {
+	pricing := ModelPricing{InputPerMillion: 0.15, OutputPerMillion: 0.60}
+
+	cost := computeUsageCost(pricing, 1.5, 1000, 500)
+	if cost.Input != 0.000225 || cost.Output != 0.00045 || cost.Total != 0.000675 || cost.Currency != "USD" {
+		t.Errorf("cost = %+v", cost)
+	}
+
+	h := http.Header{}
+	cost.set(h)
+	if got := h.Get(usageCostHeader); got != "0.000675" {
+		t.Errorf("%s = %q", usageCostHeader, got)
+	}
+}
+
+func TestInjectUsageCost(t *testing.T) {
+	body := []byte(`{"id":"cmpl-1","usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}`)
+	cost, out, ok := injectUsageCost(body, ModelPricing{InputPerMillion: 1, OutputPerMillion: 2}, 1)
+	if !ok || cost.Total != 0.002 {
+		t.Fatalf("injectUsageCost() = %+v, %v", cost, ok)
+	}
+
+	var resp struct {
+		ID    string `json:"id"`
+		Usage struct {
+			TotalTokens int       `json:"total_tokens"`
+			Cost        UsageCost `json:"cost"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != "cmpl-1" || resp.Usage.TotalTokens != 1500 || resp.Usage.Cost != cost {
+		t.Errorf("response = %s", out)
+	}
+
+	if _, out, ok := injectUsageCost([]byte(`{"id":"cmpl-2"}`), ModelPricing{}, 1); ok || string(out) != `{"id":"cmpl-2"}` {
+		t.Errorf("response without usage changed: %s", out)
+	}
+}
+
+func TestStreamedUsageCostTrailers(t *testing.T) {
+	stream := "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":10,\"completion_tokens\":20}}\n\n" +
+		"data: [DONE]\n\n"
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(stream)),
+	}
+	resp.Trailer = http.Header{usageCostHeader: nil}
+	resp.Body = &usageCostStreamBody{
+		ReadCloser: resp.Body,
+		onEOF: func(tail []byte) {
+			computeUsageCost(ModelPricing{InputPerMillion: 1, OutputPerMillion: 1}, 1,
+				derefInt(lastTokenCount(promptTokensPattern, tail)),
+				derefInt(lastTokenCount(completionTokensPattern, tail))).set(resp.Trailer)
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	writeProxiedResponse(rec, resp)
+	result := rec.Result()
+	io.ReadAll(result.Body)
+	if got := result.Trailer.Get(usageCostHeader); got != "0.000030" {
+		t.Errorf("trailer %s = %q, want 0.000030", usageCostHeader, got)
+	}
+	if rec.Body.String() != stream {
+		t.Error("stream body altered")
+	}
+}
@@ -0,0 +1,704 @@
+package gateway
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/jobs"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/crosslogic/control-plane/pkg/parquet"
+	"github.com/crosslogic/control-plane/pkg/r2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Usage exports extract a tenant's usage_records rows as CSV or Parquet so
+// finance teams can reconcile bills line by line. A request records the
+// filters and queues a job; the job streams the rows into a temporary file,
+// uploads it to R2 and the export endpoints hand out a presigned download
+// link once it is ready. Tenants export their own usage, admins any
+// tenant's.
+
+const (
+	// usageExportTTL is how long a completed export can be downloaded
+	usageExportTTL = 7 * 24 * time.Hour
+	// usageExportURLTTL is how long a download link stays valid
+	usageExportURLTTL = time.Hour
+	// maxUsageExportRange caps the date range of one export
+	maxUsageExportRange = 366 * 24 * time.Hour
+	// maxUsageExportsInProgress caps a tenant's queued and running exports
+	maxUsageExportsInProgress = 3
+)
+
+// usageExportContentTypes are the artifact content types by format
+var usageExportContentTypes = map[string]string{
+	"csv":     "text/csv",
+	"parquet": "application/vnd.apache.parquet",
+}
+
+// UsageExport is a usage extract request and, once completed, its artifact
+type UsageExport struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     time.Time  `json:"end_date"`
+	Model       *string    `json:"model,omitempty"`
+	APIKeyID    *uuid.UUID `json:"api_key_id,omitempty"`
+	RequestedBy *string    `json:"requested_by,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	RowCount    *int64     `json:"row_count,omitempty"`
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	RequestedAt time.Time  `json:"requested_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+
+	tenantID  uuid.UUID
+	objectKey *string
+}
+
+// usageExportRequest is the body of export requests
+type usageExportRequest struct {
+	Format    string     `json:"format"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	Model     *string    `json:"model"`
+	APIKeyID  *uuid.UUID `json:"api_key_id"`
+}
+
+// validate fills in defaults (CSV, the last 30 days) and checks the range
+func (req *usageExportRequest) validate(now time.Time) error {
+	if req.Format == "" {
+		req.Format = "csv"
+	}
+	if _, ok := usageExportContentTypes[req.Format]; !ok {
+		return fmt.Errorf("format must be csv or parquet")
+	}
+	if req.EndDate == nil {
+		req.EndDate = &now
+	}
+	if req.StartDate == nil {
+		start := req.EndDate.AddDate(0, 0, -30)
+		req.StartDate = &start
+	}
+	if !req.EndDate.After(*req.StartDate) {
+		return fmt.Errorf("end_date must be after start_date")
+	}
+	if req.EndDate.Sub(*req.StartDate) > maxUsageExportRange {
+		return fmt.Errorf("date range must not exceed 366 days")
+	}
+	if req.Model != nil && *req.Model == "" {
+		req.Model = nil
+	}
+	return nil
+}
+
+// usageExportArgs are the arguments of a usage.export job
+type usageExportArgs struct {
+	ExportID uuid.UUID `json:"export_id"`
+}
+
+// usageExportKey is where an export's artifact is stored
+func usageExportKey(tenantID, exportID uuid.UUID, format string) string {
+	return fmt.Sprintf("usage-exports/%s/%s.%s", tenantID, exportID, format)
+}
+
+// usageExportColumns are the exported columns, in file order
+var usageExportColumns = []parquet.Column{
+	{Name: "id", Type: parquet.String},
+	{Name: "request_id", Type: parquet.String, Optional: true},
+	{Name: "timestamp", Type: parquet.Timestamp},
+	{Name: "environment_id", Type: parquet.String},
+	{Name: "api_key_id", Type: parquet.String, Optional: true},
+	{Name: "model", Type: parquet.String, Optional: true},
+	{Name: "prompt_tokens", Type: parquet.Int64},
+	{Name: "completion_tokens", Type: parquet.Int64},
+	{Name: "total_tokens", Type: parquet.Int64},
+	{Name: "cached_tokens", Type: parquet.Int64, Optional: true},
+	{Name: "latency_ms", Type: parquet.Int64, Optional: true},
+	{Name: "status_code", Type: parquet.Int64, Optional: true},
+	{Name: "cost_microdollars", Type: parquet.Int64, Optional: true},
+	{Name: "billed", Type: parquet.Boolean},
+}
+
+// usageExportWriter writes rows of usageExportColumns values
+type usageExportWriter interface {
+	Write(values ...interface{}) error
+	Close() error
+}
+
+// newUsageExportWriter starts a file of the given format on w
+func newUsageExportWriter(w io.Writer, format string) (usageExportWriter, error) {
+	if format == "parquet" {
+		return parquet.NewWriter(w, usageExportColumns)
+	}
+	cw := &csvExportWriter{w: csv.NewWriter(w)}
+	header := make([]string, len(usageExportColumns))
+	for i, c := range usageExportColumns {
+		header[i] = c.Name
+	}
+	if err := cw.w.Write(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// csvExportWriter writes rows as CSV; nulls are empty fields and timestamps
+// RFC 3339 in UTC
+type csvExportWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (c *csvExportWriter) Write(values ...interface{}) error {
+	c.record = c.record[:0]
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			c.record = append(c.record, "")
+		case string:
+			c.record = append(c.record, v)
+		case int64:
+			c.record = append(c.record, strconv.FormatInt(v, 10))
+		case bool:
+			c.record = append(c.record, strconv.FormatBool(v))
+		case time.Time:
+			c.record = append(c.record, v.UTC().Format(time.RFC3339Nano))
+		default:
+			return fmt.Errorf("unexpected CSV value %T", v)
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvExportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// exportUUID, exportString and exportInt turn nullable columns into export
+// values, nil when NULL
+func exportUUID(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.String()
+}
+
+func exportString(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func exportInt(n *int64) interface{} {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
+
+// handleCreateUsageExport starts a usage export for the tenant
+// Tenant API - POST /v1/usage/export
+func (g *Gateway) handleCreateUsageExport(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.usageExportTenant(w, r)
+	if !ok {
+		return
+	}
+	requestedBy := ""
+	if keyInfo, ok := r.Context().Value("api_key").(*models.APIKey); ok {
+		requestedBy = "api_key:" + keyInfo.ID.String()
+	}
+	g.startUsageExport(w, r, tenantID, requestedBy)
+}
+
+// handleListUsageExports lists the tenant's recent usage exports
+// Tenant API - GET /v1/usage/export
+func (g *Gateway) handleListUsageExports(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.usageExportTenant(w, r)
+	if !ok {
+		return
+	}
+	g.listUsageExports(w, r, tenantID)
+}
+
+// handleGetUsageExport returns one of the tenant's usage exports, with a
+// download link once it has completed
+// Tenant API - GET /v1/usage/export/{export_id}
+func (g *Gateway) handleGetUsageExport(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.usageExportTenant(w, r)
+	if !ok {
+		return
+	}
+	g.getUsageExport(w, r, tenantID)
+}
+
+// handleCreateTenantUsageExport starts a usage export of any tenant
+// Admin API - POST /admin/tenants/{id}/usage/export
+func (g *Gateway) handleCreateTenantUsageExport(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.adminUsageExportTenant(w, r)
+	if !ok {
+		return
+	}
+	requestedBy := ""
+	if actor := adminActor(r.Context()); actor != nil {
+		requestedBy = *actor
+	}
+	g.startUsageExport(w, r, tenantID, requestedBy)
+}
+
+// handleListTenantUsageExports lists a tenant's recent usage exports
+// Admin API - GET /admin/tenants/{id}/usage/export
+func (g *Gateway) handleListTenantUsageExports(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.adminUsageExportTenant(w, r)
+	if !ok {
+		return
+	}
+	g.listUsageExports(w, r, tenantID)
+}
+
+// handleGetTenantUsageExport returns one of a tenant's usage exports
+// Admin API - GET /admin/tenants/{id}/usage/export/{export_id}
+func (g *Gateway) handleGetTenantUsageExport(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := g.adminUsageExportTenant(w, r)
+	if !ok {
+		return
+	}
+	g.getUsageExport(w, r, tenantID)
+}
+
+// usageExportTenant returns the authenticated tenant, answering the
+// request itself when exports are unavailable
+func (g *Gateway) usageExportTenant(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	tenantID, ok := r.Context().Value("tenant_id").(uuid.UUID)
+	if !ok {
+		g.writeError(w, http.StatusUnauthorized, "tenant ID not found in context")
+		return uuid.Nil, false
+	}
+	if g.UsageExports == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "usage exports are not configured")
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// adminUsageExportTenant parses the tenant ID route parameter and checks
+// the tenant exists
+func (g *Gateway) adminUsageExportTenant(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	if g.UsageExports == nil {
+		g.writeError(w, http.StatusServiceUnavailable, "usage exports are not configured")
+		return uuid.Nil, false
+	}
+	tenantID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid tenant ID")
+		return uuid.Nil, false
+	}
+	var exists bool
+	if err := g.db.Pool.QueryRow(r.Context(), `
+		SELECT EXISTS (SELECT 1 FROM tenants WHERE id = $1)
+	`, tenantID).Scan(&exists); err != nil {
+		g.logger.Error("failed to look up tenant", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to look up tenant")
+		return uuid.Nil, false
+	}
+	if !exists {
+		g.writeError(w, http.StatusNotFound, "tenant not found")
+		return uuid.Nil, false
+	}
+	return tenantID, true
+}
+
+// startUsageExport validates an export request, records it and queues the
+// job that writes it
+func (g *Gateway) startUsageExport(w http.ResponseWriter, r *http.Request, tenantID uuid.UUID, requestedBy string) {
+	ctx := r.Context()
+
+	var req usageExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		g.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.validate(time.Now().UTC()); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.APIKeyID != nil {
+		var keyTenantID uuid.UUID
+		err := g.db.Pool.QueryRow(ctx, `SELECT tenant_id FROM api_keys WHERE id = $1`, *req.APIKeyID).Scan(&keyTenantID)
+		if err != nil || keyTenantID != tenantID {
+			g.writeError(w, http.StatusBadRequest, "api_key_id does not belong to the tenant")
+			return
+		}
+	}
+
+	export, err := g.createUsageExport(ctx, tenantID, &req, requestedBy)
+	if errors.Is(err, errTooManyUsageExports) {
+		g.writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to start usage export", zap.Error(err), zap.String("tenant_id", tenantID.String()))
+		g.writeError(w, http.StatusInternalServerError, "failed to start usage export")
+		return
+	}
+	g.writeJSON(w, http.StatusAccepted, export)
+}
+
+// errTooManyUsageExports rejects exports beyond maxUsageExportsInProgress
+var errTooManyUsageExports = fmt.Errorf("at most %d usage exports may be in progress; wait for one to finish", maxUsageExportsInProgress)
+
+// listUsageExports answers with a tenant's 50 most recent exports
+func (g *Gateway) listUsageExports(w http.ResponseWriter, r *http.Request, tenantID uuid.UUID) {
+	rows, err := g.db.Pool.Query(r.Context(), `
+		SELECT `+usageExportSelect+`
+		FROM usage_exports
+		WHERE tenant_id = $1
+		ORDER BY requested_at DESC
+		LIMIT 50
+	`, tenantID)
+	if err != nil {
+		g.logger.Error("failed to list usage exports", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list usage exports")
+		return
+	}
+	defer rows.Close()
+
+	exports := []*UsageExport{}
+	for rows.Next() {
+		export, err := scanUsageExport(rows)
+		if err != nil {
+			g.logger.Error("failed to scan usage export", zap.Error(err))
+			g.writeError(w, http.StatusInternalServerError, "failed to list usage exports")
+			return
+		}
+		exports = append(exports, export)
+	}
+	if err := rows.Err(); err != nil {
+		g.logger.Error("failed to list usage exports", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to list usage exports")
+		return
+	}
+	g.writeJSON(w, http.StatusOK, map[string]interface{}{"data": exports})
+}
+
+// getUsageExport answers with one of a tenant's exports, 202 until it has
+// completed and with a download link while the artifact is kept
+func (g *Gateway) getUsageExport(w http.ResponseWriter, r *http.Request, tenantID uuid.UUID) {
+	exportID, err := uuid.Parse(chi.URLParam(r, "export_id"))
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid export ID")
+		return
+	}
+
+	export, err := scanUsageExport(g.db.Pool.QueryRow(r.Context(), `
+		SELECT `+usageExportSelect+`
+		FROM usage_exports
+		WHERE id = $1 AND tenant_id = $2
+	`, exportID, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		g.writeError(w, http.StatusNotFound, "usage export not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to get usage export", zap.Error(err))
+		g.writeError(w, http.StatusInternalServerError, "failed to get usage export")
+		return
+	}
+
+	switch export.Status {
+	case exportPending, exportRunning:
+		g.writeJSON(w, http.StatusAccepted, export)
+		return
+	case exportCompleted:
+		if export.objectKey != nil && export.ExpiresAt != nil && time.Now().Before(*export.ExpiresAt) {
+			export.DownloadURL = g.UsageExports.PresignGet(*export.objectKey, usageExportURLTTL)
+		}
+	}
+	g.writeJSON(w, http.StatusOK, export)
+}
+
+const usageExportSelect = `
+	id, tenant_id, status, format, start_date, end_date, model, api_key_id, requested_by,
+	error, row_count, size_bytes, requested_at, completed_at, expires_at, object_key
+`
+
+func scanUsageExport(row pgx.Row) (*UsageExport, error) {
+	var e UsageExport
+	err := row.Scan(&e.ID, &e.tenantID, &e.Status, &e.Format, &e.StartDate, &e.EndDate, &e.Model, &e.APIKeyID,
+		&e.RequestedBy, &e.Error, &e.RowCount, &e.SizeBytes, &e.RequestedAt, &e.CompletedAt, &e.ExpiresAt, &e.objectKey)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// createUsageExport records a pending export and queues its job, unless
+// the tenant already has maxUsageExportsInProgress exports in progress
+func (g *Gateway) createUsageExport(ctx context.Context, tenantID uuid.UUID, req *usageExportRequest, requestedBy string) (*UsageExport, error) {
+	tx, err := g.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize a tenant's export requests so the in-progress cap holds
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('usage_export:' || $1::text))`, tenantID); err != nil {
+		return nil, err
+	}
+	var inProgress int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM usage_exports WHERE tenant_id = $1 AND status IN ('pending', 'running')
+	`, tenantID).Scan(&inProgress); err != nil {
+		return nil, err
+	}
+	if inProgress >= maxUsageExportsInProgress {
+		return nil, errTooManyUsageExports
+	}
+
+	var by *string
+	if requestedBy != "" {
+		by = &requestedBy
+	}
+	export, err := scanUsageExport(tx.QueryRow(ctx, `
+		INSERT INTO usage_exports (tenant_id, format, start_date, end_date, model, api_key_id, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+usageExportSelect,
+		tenantID, req.Format, *req.StartDate, *req.EndDate, req.Model, req.APIKeyID, by))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := g.jobs.EnqueueTx(ctx, tx, jobUsageExport, usageExportArgs{ExportID: export.ID}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	g.logger.Info("usage export requested",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("export_id", export.ID.String()),
+		zap.String("format", export.Format),
+	)
+	return export, nil
+}
+
+// runUsageExport writes an export's rows to a temporary file and uploads
+// it to R2
+func (g *Gateway) runUsageExport(ctx context.Context, job *jobs.Job) error {
+	var args usageExportArgs
+	if err := job.Decode(&args); err != nil {
+		return err
+	}
+	if g.UsageExports == nil {
+		return jobs.Permanent(errors.New("usage exports are not configured"))
+	}
+
+	export, err := scanUsageExport(g.db.Pool.QueryRow(ctx, `
+		UPDATE usage_exports SET status = 'running'
+		WHERE id = $1 AND status IN ('pending', 'running')
+		RETURNING `+usageExportSelect, args.ExportID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim usage export: %w", err)
+	}
+
+	rowCount, size, err := g.writeUsageExportArtifact(ctx, export)
+	if err == nil {
+		err = g.completeUsageExport(ctx, export, rowCount, size)
+	}
+	if err != nil {
+		if job.FinalAttempt() {
+			g.failUsageExport(ctx, export.ID, err)
+		}
+		return fmt.Errorf("failed to export usage: %w", err)
+	}
+
+	g.logger.Info("usage export completed",
+		zap.String("tenant_id", export.tenantID.String()),
+		zap.String("export_id", export.ID.String()),
+		zap.Int64("rows", rowCount),
+		zap.Int64("size_bytes", size),
+	)
+	return nil
+}
+
+// writeUsageExportArtifact streams the export's rows into a temporary file
+// and uploads it, returning the row count and file size
+func (g *Gateway) writeUsageExportArtifact(ctx context.Context, export *UsageExport) (int64, int64, error) {
+	file, err := os.CreateTemp("", "usage-export-*."+export.Format)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	rowCount, err := g.writeUsageRows(ctx, export, file)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+
+	key := usageExportKey(export.tenantID, export.ID, export.Format)
+	if err := r2.NewObjects(g.UsageExports).PutReader(ctx, key, file, size, usageExportContentTypes[export.Format]); err != nil {
+		return 0, 0, err
+	}
+	return rowCount, size, nil
+}
+
+// writeUsageRows writes the usage records matching the export's filters to
+// w in the export's format
+func (g *Gateway) writeUsageRows(ctx context.Context, export *UsageExport, w io.Writer) (int64, error) {
+	args := database.NewArgs()
+	where := database.NewWhere(args).
+		Eq("ur.tenant_id", export.tenantID).
+		Raw("ur.timestamp >= " + args.Add(export.StartDate)).
+		Raw("ur.timestamp < " + args.Add(export.EndDate))
+	if export.Model != nil {
+		where.Eq("m.name", *export.Model)
+	}
+	if export.APIKeyID != nil {
+		where.Eq("ur.api_key_id", *export.APIKeyID)
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT ur.id, ur.request_id, ur.timestamp, ur.environment_id, ur.api_key_id, m.name,
+		       ur.prompt_tokens, ur.completion_tokens, ur.total_tokens, ur.cached_tokens,
+		       ur.latency_ms, ur.status_code, ur.cost_microdollars, ur.billed
+		FROM usage_records ur
+		LEFT JOIN models m ON m.id = ur.model_id`+where.String()+`
+		ORDER BY ur.timestamp, ur.id
+	`, args.Values()...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query usage records: %w", err)
+	}
+	defer rows.Close()
+
+	out, err := newUsageExportWriter(w, export.Format)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	for rows.Next() {
+		var (
+			id, environmentID                                     uuid.UUID
+			apiKeyID                                              *uuid.UUID
+			requestID, model                                      *string
+			timestamp                                             time.Time
+			promptTokens, completionTokens, totalTokens           int64
+			cachedTokens, latencyMs, statusCode, costMicrodollars *int64
+			billed                                                bool
+		)
+		if err := rows.Scan(&id, &requestID, &timestamp, &environmentID, &apiKeyID, &model,
+			&promptTokens, &completionTokens, &totalTokens, &cachedTokens,
+			&latencyMs, &statusCode, &costMicrodollars, &billed); err != nil {
+			return 0, fmt.Errorf("failed to scan usage record: %w", err)
+		}
+		err := out.Write(id.String(), exportString(requestID), timestamp, environmentID.String(),
+			exportUUID(apiKeyID), exportString(model), promptTokens, completionTokens, totalTokens,
+			exportInt(cachedTokens), exportInt(latencyMs), exportInt(statusCode), exportInt(costMicrodollars), billed)
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read usage records: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// completeUsageExport marks an export downloadable and deletes the
+// artifacts of the tenant's expired exports. Failing to delete one doesn't
+// fail the export; it is retried when the next one completes.
+func (g *Gateway) completeUsageExport(ctx context.Context, export *UsageExport, rowCount, size int64) error {
+	key := usageExportKey(export.tenantID, export.ID, export.Format)
+	_, err := g.db.Pool.Exec(ctx, `
+		UPDATE usage_exports
+		SET status = 'completed', object_key = $2, size_bytes = $3, row_count = $4, error = NULL,
+		    completed_at = NOW(), expires_at = NOW() + $5 * INTERVAL '1 second'
+		WHERE id = $1
+	`, export.ID, key, size, rowCount, int64(usageExportTTL.Seconds()))
+	if err != nil {
+		return err
+	}
+
+	rows, err := g.db.Pool.Query(ctx, `
+		SELECT id, object_key FROM usage_exports
+		WHERE tenant_id = $1 AND status = 'completed' AND expires_at < NOW() AND object_key IS NOT NULL
+	`, export.tenantID)
+	if err != nil {
+		g.logger.Warn("failed to list expired usage exports", zap.Error(err))
+		return nil
+	}
+	expired := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var id uuid.UUID
+		var oldKey string
+		if err := rows.Scan(&id, &oldKey); err != nil {
+			rows.Close()
+			g.logger.Warn("failed to scan expired usage export", zap.Error(err))
+			return nil
+		}
+		expired[id] = oldKey
+	}
+	rows.Close()
+
+	objects := r2.NewObjects(g.UsageExports)
+	for id, oldKey := range expired {
+		if err := objects.Delete(ctx, oldKey); err != nil {
+			g.logger.Warn("failed to delete expired usage export",
+				zap.String("export_id", id.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		if _, err := g.db.Pool.Exec(ctx, `
+			UPDATE usage_exports SET status = 'expired', object_key = NULL WHERE id = $1
+		`, id); err != nil {
+			g.logger.Warn("failed to expire usage export",
+				zap.String("export_id", id.String()),
+				zap.Error(err),
+			)
+		}
+	}
+	return nil
+}
+
+// failUsageExport records why an export could not be written
+func (g *Gateway) failUsageExport(ctx context.Context, exportID uuid.UUID, cause error) {
+	_, err := g.db.Pool.Exec(ctx, `
+		UPDATE usage_exports SET status = 'failed', error = $2, completed_at = NOW()
+		WHERE id = $1
+	`, exportID, cause.Error())
+	if err != nil {
+		g.logger.Error("failed to record usage export failure",
+			zap.String("export_id", exportID.String()),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageExportRequestValidate(t *testing.T) {
+	now := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	req := usageExportRequest{}
+	if err := req.validate(now); err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	if req.Format != "csv" || !req.EndDate.Equal(now) || !req.StartDate.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("defaults = %s %s..%s", req.Format, req.StartDate, req.EndDate)
+	}
+
+	start, end := now.AddDate(-2, 0, 0), now
+	reversed := now.Add(-time.Hour)
+	empty := ""
+	for name, req := range map[string]usageExportRequest{
+		"format":     {Format: "xlsx"},
+		"too long":   {StartDate: &start, EndDate: &end},
+		"reversed":   {StartDate: &end, EndDate: &reversed},
+		"zero range": {StartDate: &end, EndDate: &end},
+	} {
+		if err := req.validate(now); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+
+	req = usageExportRequest{Format: "parquet", Model: &empty}
+	if err := req.validate(now); err != nil || req.Model != nil {
+		t.Errorf("empty model filter kept: %v %v", req.Model, err)
+	}
+}
+
+func TestUsageExportWriterCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newUsageExportWriter(&buf, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := w.Write("u1", nil, ts, "env", nil, "llama, 8b", int64(10), int64(5), int64(15), nil, int64(120), int64(200), int64(42), true); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "id,request_id,timestamp,") || !strings.HasSuffix(lines[0], ",cost_microdollars,billed") {
+		t.Errorf("header = %q", lines[0])
+	}
+	want := `u1,,2026-03-01T12:00:00Z,env,,"llama, 8b",10,5,15,,120,200,42,true`
+	if lines[1] != want {
+		t.Errorf("row = %q, want %q", lines[1], want)
+	}
+}
+
+func TestUsageExportWriterParquet(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newUsageExportWriter(&buf, "parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := w.Write("u1", "req", ts, "env", "key", nil, int64(10), int64(5), int64(15), nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Error("parquet export is not framed by PAR1")
+	}
+}
@@ -0,0 +1,133 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+)
+
+// ErrNotFound is returned when a job doesn't exist or is not in a state the
+// requested action applies to
+var ErrNotFound = errors.New("job not found")
+
+// Record is a job row as shown on the admin dashboard
+type Record struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	State       string          `json:"state"`
+	Args        json.RawMessage `json:"args"`
+	Attempt     int             `json:"attempt"`
+	MaxAttempts int             `json:"max_attempts"`
+	Errors      json.RawMessage `json:"errors"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ScheduledAt time.Time       `json:"scheduled_at"`
+	AttemptedAt *time.Time      `json:"attempted_at,omitempty"`
+	FinalizedAt *time.Time      `json:"finalized_at,omitempty"`
+}
+
+// Filter narrows List results
+type Filter struct {
+	State string
+	Kind  string
+}
+
+// Count is the number of jobs of one kind in one state
+type Count struct {
+	Kind  string `json:"kind"`
+	State string `json:"state"`
+	Count int    `json:"count"`
+}
+
+// List returns jobs newest first
+func (q *Queue) List(ctx context.Context, f Filter, limit, offset int) ([]Record, error) {
+	args := database.NewArgs()
+	where := database.NewWhere(args)
+	if f.State != "" {
+		where.Eq("state", f.State)
+	}
+	if f.Kind != "" {
+		where.Eq("kind", f.Kind)
+	}
+	query := `
+		SELECT id, kind, state, args, attempt, max_attempts, errors,
+		       created_at, scheduled_at, attempted_at, finalized_at
+		FROM jobs` + where.String() + `
+		ORDER BY id DESC
+		LIMIT ` + args.Add(limit) + ` OFFSET ` + args.Add(offset)
+
+	rows, err := q.db.Pool.Query(ctx, query, args.Values()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.ID, &r.Kind, &r.State, &r.Args, &r.Attempt, &r.MaxAttempts, &r.Errors,
+			&r.CreatedAt, &r.ScheduledAt, &r.AttemptedAt, &r.FinalizedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Counts returns the number of jobs per kind and state
+func (q *Queue) Counts(ctx context.Context) ([]Count, error) {
+	rows, err := q.db.Pool.Query(ctx, `
+		SELECT kind, state, COUNT(*) FROM jobs GROUP BY kind, state ORDER BY kind, state
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []Count{}
+	for rows.Next() {
+		var c Count
+		if err := rows.Scan(&c.Kind, &c.State, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan job count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// Retry puts a dead or retryable job back in the queue with a fresh set of
+// attempts
+func (q *Queue) Retry(ctx context.Context, id int64) error {
+	tag, err := q.db.Pool.Exec(ctx, `
+		UPDATE jobs
+		SET state = 'available', attempt = 0, scheduled_at = NOW(), finalized_at = NULL
+		WHERE id = $1 AND state IN ('dead', 'retryable')
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to retry job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Discard dead-letters a job that hasn't started yet
+func (q *Queue) Discard(ctx context.Context, id int64) error {
+	tag, err := q.db.Pool.Exec(ctx, `
+		UPDATE jobs
+		SET state = 'dead', finalized_at = NOW(),
+		    errors = errors || jsonb_build_array(jsonb_build_object('attempt', attempt, 'error', 'discarded by admin', 'at', NOW()))
+		WHERE id = $1 AND state IN ('available', 'retryable')
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to discard job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+// Package jobs is a small Postgres-backed background job queue.
+//
+// Work that used to run in fire-and-forget goroutines is enqueued as a row in
+// the jobs table and executed by workers on any control plane replica. Failed
+// jobs are retried with backoff according to their kind's RetryPolicy and
+// moved to the dead state once attempts are exhausted, where an operator can
+// inspect and retry them through the admin API.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Job states
+const (
+	StateAvailable = "available"
+	StateRunning   = "running"
+	StateRetryable = "retryable"
+	StateCompleted = "completed"
+	StateDead      = "dead"
+)
+
+const (
+	// defaultWorkers is how many jobs a queue runs concurrently
+	defaultWorkers = 4
+
+	// pollInterval is how long an idle worker waits before looking again
+	pollInterval = 1 * time.Second
+
+	// leaseGrace is added to a job's timeout to form its lease; a running job
+	// whose lease expired belonged to a replica that died and is rescued
+	leaseGrace = 1 * time.Minute
+
+	// completedRetention is how long completed jobs are kept for the dashboard
+	completedRetention = 7 * 24 * time.Hour
+)
+
+// Job is a claimed unit of work passed to a Handler
+type Job struct {
+	ID          int64
+	Kind        string
+	Args        json.RawMessage
+	Attempt     int
+	MaxAttempts int
+}
+
+// Decode unmarshals the job arguments into v
+func (j *Job) Decode(v any) error {
+	if err := json.Unmarshal(j.Args, v); err != nil {
+		return Permanent(fmt.Errorf("invalid %s job args: %w", j.Kind, err))
+	}
+	return nil
+}
+
+// FinalAttempt reports whether a failure of this attempt dead-letters the job
+func (j *Job) FinalAttempt() bool {
+	return j.Attempt >= j.MaxAttempts
+}
+
+// Handler executes a job. Returning an error schedules a retry.
+type Handler func(ctx context.Context, job *Job) error
+
+// RetryPolicy controls how a job kind is retried
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts before the job is dead-lettered
+	MaxAttempts int
+	// Timeout bounds a single attempt
+	Timeout time.Duration
+	// BaseDelay is the wait before the first retry; it doubles per attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between retries
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy suits short database writes
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	Timeout:     30 * time.Second,
+	BaseDelay:   5 * time.Second,
+	MaxDelay:    30 * time.Minute,
+}
+
+// withDefaults fills unset fields from DefaultRetryPolicy
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = DefaultRetryPolicy.Timeout
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	return p
+}
+
+// Delay returns the wait before retrying after the given failed attempt
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not worth retrying; the job is dead-lettered
+// immediately
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+type kind struct {
+	handler Handler
+	policy  RetryPolicy
+}
+
+// Queue enqueues jobs and runs registered handlers
+type Queue struct {
+	db      *database.Database
+	logger  *zap.Logger
+	workers int
+
+	mu    sync.RWMutex
+	kinds map[string]kind
+}
+
+// NewQueue creates a queue backed by the jobs table
+func NewQueue(db *database.Database, logger *zap.Logger) *Queue {
+	return &Queue{
+		db:      db,
+		logger:  logger,
+		workers: defaultWorkers,
+		kinds:   make(map[string]kind),
+	}
+}
+
+// Register installs the handler for a job kind. Register every kind before
+// Start; jobs of kinds this replica doesn't know are left for other replicas.
+func (q *Queue) Register(name string, handler Handler, policy RetryPolicy) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.kinds[name] = kind{handler: handler, policy: policy.withDefaults()}
+}
+
+func (q *Queue) lookup(name string) (kind, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	k, ok := q.kinds[name]
+	return k, ok
+}
+
+func (q *Queue) kindNames() []string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	names := make([]string, 0, len(q.kinds))
+	for name := range q.kinds {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Enqueue schedules a job to run as soon as a worker is free
+func (q *Queue) Enqueue(ctx context.Context, kind string, args any) (int64, error) {
+	return q.EnqueueTx(ctx, q.db.Pool, kind, args)
+}
+
+// EnqueueTx schedules a job through tx, so it only runs if tx commits
+func (q *Queue) EnqueueTx(ctx context.Context, tx database.Querier, kind string, args any) (int64, error) {
+	k, ok := q.lookup(kind)
+	if !ok {
+		return 0, fmt.Errorf("unknown job kind %q", kind)
+	}
+
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal %s job args: %w", kind, err)
+	}
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO jobs (kind, args, max_attempts, timeout_seconds)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, kind, payload, k.policy.MaxAttempts, int(k.policy.Timeout.Seconds())).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue %s job: %w", kind, err)
+	}
+	return id, nil
+}
+
+// Start runs the workers and the maintenance loop until ctx is cancelled
+func (q *Queue) Start(ctx context.Context) {
+	q.logger.Info("starting job queue",
+		zap.Int("workers", q.workers),
+		zap.Strings("kinds", q.kindNames()),
+	)
+	for i := 0; i < q.workers; i++ {
+		go q.workLoop(ctx)
+	}
+	go q.maintenanceLoop(ctx)
+}
+
+func (q *Queue) workLoop(ctx context.Context) {
+	for {
+		worked, err := q.work(ctx)
+		if err != nil && ctx.Err() == nil {
+			q.logger.Error("failed to claim job", zap.Error(err))
+		}
+		if worked {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// work claims and runs one job. It reports whether a job was found.
+func (q *Queue) work(ctx context.Context) (bool, error) {
+	var job Job
+	err := q.db.Pool.QueryRow(ctx, `
+		UPDATE jobs
+		SET state = 'running',
+		    attempt = attempt + 1,
+		    attempted_at = NOW(),
+		    locked_until = NOW() + make_interval(secs => timeout_seconds + $2)
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE state IN ('available', 'retryable')
+			  AND scheduled_at <= NOW()
+			  AND kind = ANY($1)
+			ORDER BY scheduled_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, args, attempt, max_attempts
+	`, q.kindNames(), leaseGrace.Seconds()).Scan(&job.ID, &job.Kind, &job.Args, &job.Attempt, &job.MaxAttempts)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	q.run(ctx, &job)
+	return true, nil
+}
+
+func (q *Queue) run(ctx context.Context, job *Job) {
+	k, ok := q.lookup(job.Kind)
+	if !ok {
+		// Claimed kinds are always registered; guard against a bad Register
+		q.fail(ctx, job, RetryPolicy{}.withDefaults(), Permanent(fmt.Errorf("no handler for job kind %q", job.Kind)))
+		return
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, k.policy.Timeout)
+	err := q.call(runCtx, k.handler, job)
+	cancel()
+
+	if err != nil {
+		q.fail(ctx, job, k.policy, err)
+		return
+	}
+
+	if _, err := q.db.Pool.Exec(ctx, `
+		UPDATE jobs SET state = 'completed', finalized_at = NOW(), locked_until = NULL WHERE id = $1
+	`, job.ID); err != nil {
+		// The lease expires and the job is rescued for another attempt
+		q.logger.Warn("failed to mark job completed", zap.Int64("job_id", job.ID), zap.String("kind", job.Kind), zap.Error(err))
+	}
+}
+
+// call runs the handler, turning a panic into a job failure
+func (q *Queue) call(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+			q.logger.Error("job panicked",
+				zap.Int64("job_id", job.ID),
+				zap.String("kind", job.Kind),
+				zap.Any("panic", r),
+				zap.ByteString("stack", debug.Stack()),
+			)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// fail schedules a retry, or dead-letters the job when attempts are exhausted
+// or the error is permanent
+func (q *Queue) fail(ctx context.Context, job *Job, policy RetryPolicy, jobErr error) {
+	dead := job.FinalAttempt() || IsPermanent(jobErr)
+	state := StateRetryable
+	if dead {
+		state = StateDead
+	}
+
+	_, err := q.db.Pool.Exec(ctx, `
+		UPDATE jobs
+		SET state = $2,
+		    scheduled_at = NOW() + make_interval(secs => $3),
+		    finalized_at = CASE WHEN $2 = 'dead' THEN NOW() END,
+		    locked_until = NULL,
+		    errors = errors || jsonb_build_array(jsonb_build_object('attempt', attempt, 'error', $4::text, 'at', NOW()))
+		WHERE id = $1
+	`, job.ID, state, policy.Delay(job.Attempt).Seconds(), jobErr.Error())
+	if err != nil {
+		q.logger.Warn("failed to record job failure", zap.Int64("job_id", job.ID), zap.Error(err))
+	}
+
+	log := q.logger.Warn
+	if dead {
+		log = q.logger.Error
+	}
+	log("job failed",
+		zap.Int64("job_id", job.ID),
+		zap.String("kind", job.Kind),
+		zap.Int("attempt", job.Attempt),
+		zap.Int("max_attempts", job.MaxAttempts),
+		zap.Bool("dead", dead),
+		zap.Error(jobErr),
+	)
+}
+
+func (q *Queue) maintenanceLoop(ctx context.Context) {
+	rescue := time.NewTicker(time.Minute)
+	defer rescue.Stop()
+
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-rescue.C:
+			q.rescueStuck(ctx)
+		case <-cleanup.C:
+			if _, err := q.db.Pool.Exec(ctx, `
+				DELETE FROM jobs WHERE state = 'completed' AND finalized_at < NOW() - make_interval(secs => $1)
+			`, completedRetention.Seconds()); err != nil {
+				q.logger.Warn("failed to prune completed jobs", zap.Error(err))
+			}
+		}
+	}
+}
+
+// rescueStuck returns jobs whose worker died mid-run to the queue
+func (q *Queue) rescueStuck(ctx context.Context) {
+	tag, err := q.db.Pool.Exec(ctx, `
+		UPDATE jobs
+		SET state = CASE WHEN attempt >= max_attempts THEN 'dead' ELSE 'retryable' END,
+		    finalized_at = CASE WHEN attempt >= max_attempts THEN NOW() END,
+		    scheduled_at = NOW(),
+		    locked_until = NULL,
+		    errors = errors || jsonb_build_array(jsonb_build_object('attempt', attempt, 'error', 'worker lease expired', 'at', NOW()))
+		WHERE state = 'running' AND locked_until < NOW()
+	`)
+	if err != nil {
+		q.logger.Warn("failed to rescue stuck jobs", zap.Error(err))
+		return
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		q.logger.Warn("rescued jobs with expired leases", zap.Int64("count", n))
+	}
+}
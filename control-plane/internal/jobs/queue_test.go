@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 5 * time.Second, MaxDelay: time.Minute}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 5 * time.Second},
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{5, time.Minute},
+		{50, time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Delay(tt.attempt); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	got := RetryPolicy{MaxAttempts: 3}.withDefaults()
+	if got.MaxAttempts != 3 {
+		t.Errorf("MaxAttempts = %d, want 3", got.MaxAttempts)
+	}
+	if got.Timeout != DefaultRetryPolicy.Timeout || got.BaseDelay != DefaultRetryPolicy.BaseDelay || got.MaxDelay != DefaultRetryPolicy.MaxDelay {
+		t.Errorf("withDefaults() = %+v", got)
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	base := errors.New("bad args")
+	if IsPermanent(base) {
+		t.Error("plain error reported permanent")
+	}
+	wrapped := fmt.Errorf("launch: %w", Permanent(base))
+	if !IsPermanent(wrapped) || !errors.Is(wrapped, base) {
+		t.Errorf("IsPermanent(%v) = false or lost the cause", wrapped)
+	}
+	if Permanent(nil) != nil {
+		t.Error("Permanent(nil) != nil")
+	}
+}
+
+func TestJobDecode(t *testing.T) {
+	job := &Job{Kind: "api_key.touch", Args: []byte(`{"key_id":"abc"}`), Attempt: 2, MaxAttempts: 2}
+	var args struct {
+		KeyID string `json:"key_id"`
+	}
+	if err := job.Decode(&args); err != nil || args.KeyID != "abc" {
+		t.Fatalf("Decode() = %v, %+v", err, args)
+	}
+	if !job.FinalAttempt() {
+		t.Error("FinalAttempt() = false on the last attempt")
+	}
+
+	job.Args = []byte(`not json`)
+	if err := job.Decode(&args); !IsPermanent(err) {
+		t.Errorf("Decode() of bad args error = %v, want permanent", err)
+	}
+}
@@ -0,0 +1,86 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// ColocatedNode is a model served beside a node's primary model, from its
+// own vLLM instance on the host's machine
+type ColocatedNode struct {
+	ID          uuid.UUID
+	ModelName   string
+	Port        int
+	EndpointURL string
+}
+
+// ColocatedNodeID is the row ID of a model colocated on a host node. It is
+// derived from both so relaunching the host reuses the model's row.
+func ColocatedNodeID(hostID uuid.UUID, model string) uuid.UUID {
+	return uuid.NewSHA1(hostID, []byte(model))
+}
+
+// ColocatedEndpoint is the endpoint of the vLLM instance listening on port
+// on the same machine as hostEndpoint
+func ColocatedEndpoint(hostEndpoint string, port int) (string, error) {
+	u, err := url.Parse(hostEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid host endpoint: %w", err)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("host endpoint %q has no host", hostEndpoint)
+	}
+	u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(port))
+	return u.String(), nil
+}
+
+// ActivateColocated puts the models colocated on a host into routing once
+// the host's agent registers as serving, with endpoints on the host's
+// address. From then on the models follow the host's status. Terminated
+// models are left alone.
+func (r *Registry) ActivateColocated(ctx context.Context, hostID uuid.UUID, hostEndpoint string) ([]ColocatedNode, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, COALESCE(model_name, ''), serving_port FROM nodes
+		WHERE host_node_id = $1 AND serving_port IS NOT NULL AND status != 'terminated'
+		ORDER BY serving_port
+	`, hostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query colocated models: %w", err)
+	}
+	var colocated []ColocatedNode
+	for rows.Next() {
+		var n ColocatedNode
+		if err := rows.Scan(&n.ID, &n.ModelName, &n.Port); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan colocated model: %w", err)
+		}
+		colocated = append(colocated, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query colocated models: %w", err)
+	}
+
+	for i := range colocated {
+		n := &colocated[i]
+		if n.EndpointURL, err = ColocatedEndpoint(hostEndpoint, n.Port); err != nil {
+			return nil, err
+		}
+		if _, err := r.db.Pool.Exec(ctx, `
+			UPDATE nodes n
+			SET endpoint_url = $2, endpoint = $2, status = 'active', status_source = $3,
+			    health_score = 100.0, last_heartbeat_at = COALESCE(h.last_heartbeat_at, NOW()),
+			    terminated_at = NULL, updated_at = NOW()
+			FROM nodes h
+			WHERE n.id = $1 AND h.id = n.host_node_id
+		`, n.ID, n.EndpointURL, SourceNode); err != nil {
+			return nil, fmt.Errorf("failed to activate colocated model %s: %w", n.ModelName, err)
+		}
+	}
+	return colocated, nil
+}
@@ -0,0 +1,44 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestColocatedEndpoint(t *testing.T) {
+	tests := []struct {
+		host    string
+		port    int
+		want    string
+		wantErr bool
+	}{
+		{"http://10.0.0.4:8000", 8001, "http://10.0.0.4:8001", false},
+		{"https://node-1.mesh.internal", 8002, "https://node-1.mesh.internal:8002", false},
+		{"http://[fd00::4]:8000", 8001, "http://[fd00::4]:8001", false},
+		{"http://10.0.0.4:8000/v1", 8003, "http://10.0.0.4:8003/v1", false},
+		{"10.0.0.4", 8001, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, err := ColocatedEndpoint(tt.host, tt.port)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ColocatedEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ColocatedEndpoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColocatedNodeID(t *testing.T) {
+	host := uuid.New()
+	a := ColocatedNodeID(host, "Qwen/Qwen2.5-0.5B-Instruct")
+	if a != ColocatedNodeID(host, "Qwen/Qwen2.5-0.5B-Instruct") {
+		t.Error("ColocatedNodeID() differs for the same host and model")
+	}
+	if a == ColocatedNodeID(host, "Qwen/Qwen2.5-1.5B-Instruct") || a == ColocatedNodeID(uuid.New(), "Qwen/Qwen2.5-0.5B-Instruct") {
+		t.Error("ColocatedNodeID() collides across models or hosts")
+	}
+}
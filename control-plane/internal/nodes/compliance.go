@@ -0,0 +1,148 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HardeningBaseline is the security hardening applied to nodes at setup:
+// key-only SSH, a firewall limiting the inference port to the control
+// plane and mesh networks, and unattended security updates
+const HardeningBaseline = "baseline"
+
+// Compliance statuses of a node
+const (
+	ComplianceCompliant    = "compliant"
+	ComplianceNoncompliant = "noncompliant"
+	// ComplianceUnknown is a hardened node that hasn't reported yet
+	ComplianceUnknown = "unknown"
+	// ComplianceNotRequired is a node launched without hardening
+	ComplianceNotRequired = "not_required"
+)
+
+// Compliance checks reported by node agents
+const (
+	CheckPasswordSSHDisabled     = "password_ssh_disabled"
+	CheckFirewallActive          = "firewall_active"
+	CheckInferencePortRestricted = "inference_port_restricted"
+	CheckAutoUpdatesEnabled      = "auto_updates_enabled"
+)
+
+// ComplianceReport is the hardening state the node agent observes, sent
+// with heartbeats
+type ComplianceReport struct {
+	PasswordSSHDisabled     bool      `json:"password_ssh_disabled"`
+	FirewallActive          bool      `json:"firewall_active"`
+	InferencePortRestricted bool      `json:"inference_port_restricted"`
+	AutoUpdatesEnabled      bool      `json:"auto_updates_enabled"`
+	CheckedAt               time.Time `json:"checked_at"`
+}
+
+// Failing lists the checks the report fails
+func (r ComplianceReport) Failing() []string {
+	var failing []string
+	if !r.PasswordSSHDisabled {
+		failing = append(failing, CheckPasswordSSHDisabled)
+	}
+	if !r.FirewallActive {
+		failing = append(failing, CheckFirewallActive)
+	}
+	if !r.InferencePortRestricted {
+		failing = append(failing, CheckInferencePortRestricted)
+	}
+	if !r.AutoUpdatesEnabled {
+		failing = append(failing, CheckAutoUpdatesEnabled)
+	}
+	return failing
+}
+
+// ComplianceStatus judges a node launched with profile against its latest
+// report, which is nil until the agent has reported
+func ComplianceStatus(profile string, report *ComplianceReport) (string, []string) {
+	if profile == "" {
+		return ComplianceNotRequired, nil
+	}
+	if report == nil {
+		return ComplianceUnknown, nil
+	}
+	if failing := report.Failing(); len(failing) > 0 {
+		return ComplianceNoncompliant, failing
+	}
+	return ComplianceCompliant, nil
+}
+
+// NodeCompliance is a live node's hardening and its latest compliance report
+type NodeCompliance struct {
+	NodeID           uuid.UUID         `json:"node_id"`
+	ClusterName      string            `json:"cluster_name"`
+	Provider         string            `json:"provider"`
+	Region           string            `json:"region,omitempty"`
+	Status           string            `json:"status"`
+	HardeningProfile string            `json:"hardening_profile,omitempty"`
+	Compliance       string            `json:"compliance"`
+	Failing          []string          `json:"failing,omitempty"`
+	Report           *ComplianceReport `json:"report,omitempty"`
+	ReportedAt       *time.Time        `json:"reported_at,omitempty"`
+}
+
+// RecordCompliance stores the compliance report a node agent sent
+func (r *Registry) RecordCompliance(ctx context.Context, nodeID uuid.UUID, report ComplianceReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE nodes SET compliance_report = $2, compliance_reported_at = NOW()
+		WHERE id = $1
+	`, nodeID, data)
+	if err != nil {
+		return fmt.Errorf("failed to record node compliance: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("node not found: %s", nodeID)
+	}
+	return nil
+}
+
+// ListCompliance lists the compliance of live nodes, hardened nodes first.
+// A non-empty status keeps only nodes in that compliance status.
+func (r *Registry) ListCompliance(ctx context.Context, status string) ([]NodeCompliance, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT n.id, COALESCE(n.cluster_name, ''), n.provider, COALESCE(rg.code, ''), n.status,
+		       COALESCE(n.hardening_profile, ''), n.compliance_report, n.compliance_reported_at
+		FROM nodes n
+		LEFT JOIN regions rg ON rg.id = n.region_id
+		WHERE n.status IN ('initializing', 'active', 'ready', 'draining', 'unhealthy')
+		ORDER BY n.hardening_profile IS NULL, n.created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node compliance: %w", err)
+	}
+	defer rows.Close()
+
+	list := []NodeCompliance{}
+	for rows.Next() {
+		var n NodeCompliance
+		var report []byte
+		if err := rows.Scan(&n.NodeID, &n.ClusterName, &n.Provider, &n.Region, &n.Status,
+			&n.HardeningProfile, &report, &n.ReportedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan node compliance: %w", err)
+		}
+		if len(report) > 0 {
+			n.Report = &ComplianceReport{}
+			if err := json.Unmarshal(report, n.Report); err != nil {
+				return nil, fmt.Errorf("invalid compliance report for node %s: %w", n.NodeID, err)
+			}
+		}
+		n.Compliance, n.Failing = ComplianceStatus(n.HardeningProfile, n.Report)
+		if status != "" && n.Compliance != status {
+			continue
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,42 @@
+package nodes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComplianceStatus(t *testing.T) {
+	passing := &ComplianceReport{
+		PasswordSSHDisabled:     true,
+		FirewallActive:          true,
+		InferencePortRestricted: true,
+		AutoUpdatesEnabled:      true,
+	}
+	openPort := *passing
+	openPort.InferencePortRestricted = false
+	openPort.AutoUpdatesEnabled = false
+
+	tests := []struct {
+		name        string
+		profile     string
+		report      *ComplianceReport
+		want        string
+		wantFailing []string
+	}{
+		{"not hardened", "", &openPort, ComplianceNotRequired, nil},
+		{"not reported yet", HardeningBaseline, nil, ComplianceUnknown, nil},
+		{"compliant", HardeningBaseline, passing, ComplianceCompliant, nil},
+		{"drifted", HardeningBaseline, &openPort, ComplianceNoncompliant, []string{CheckInferencePortRestricted, CheckAutoUpdatesEnabled}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, failing := ComplianceStatus(tt.profile, tt.report)
+			if got != tt.want {
+				t.Errorf("status = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(failing, tt.wantFailing) {
+				t.Errorf("failing = %v, want %v", failing, tt.wantFailing)
+			}
+		})
+	}
+}
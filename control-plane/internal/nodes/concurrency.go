@@ -0,0 +1,88 @@
+package nodes
+
+import (
+	"sort"
+	"strconv"
+)
+
+const (
+	// DefaultMaxNumSeqs is vLLM's --max-num-seqs when a node doesn't set it
+	DefaultMaxNumSeqs = 256
+
+	// BenchmarkKneeFraction is the share of peak throughput at which a
+	// benchmark's curve counts as flat
+	BenchmarkKneeFraction = 0.9
+
+	// MinRequestTokensPerSecond is the generation speed a single request
+	// should keep. Without benchmarks, a node's tokens_per_second_capacity
+	// is shared out at this rate.
+	MinRequestTokensPerSecond = 20
+
+	// ImageConcurrencyLimit is how many requests an image node takes at
+	// once. Diffusion runtimes generate one batch at a time, so a few
+	// queued requests keep the GPU busy without long waits.
+	ImageConcurrencyLimit = 4
+)
+
+// BenchmarkPoint is a model's aggregate throughput at one concurrency
+type BenchmarkPoint struct {
+	Concurrency     int     `json:"concurrency"`
+	TokensPerSecond float64 `json:"tokens_per_second"`
+}
+
+// MaxNumSeqs returns the --max-num-seqs in vLLM arguments, or
+// DefaultMaxNumSeqs when they don't set a valid one
+func MaxNumSeqs(args []string) int {
+	if value, ok := parseFlags(args)["--max-num-seqs"]; ok {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultMaxNumSeqs
+}
+
+// BenchmarkKnee returns the lowest benchmarked concurrency reaching
+// BenchmarkKneeFraction of peak throughput, or 0 without usable points.
+// Beyond it more concurrent requests add latency, not throughput.
+func BenchmarkKnee(points []BenchmarkPoint) int {
+	var peak float64
+	for _, p := range points {
+		if p.Concurrency > 0 && p.TokensPerSecond > peak {
+			peak = p.TokensPerSecond
+		}
+	}
+	if peak <= 0 {
+		return 0
+	}
+
+	sorted := append([]BenchmarkPoint(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Concurrency < sorted[j].Concurrency })
+	for _, p := range sorted {
+		if p.Concurrency > 0 && p.TokensPerSecond >= peak*BenchmarkKneeFraction {
+			return p.Concurrency
+		}
+	}
+	return 0
+}
+
+// ConcurrencyLimit returns how many requests a node should serve at once.
+// Benchmarks take precedence over the model's tokens per second capacity;
+// either is capped by the node's vLLM max_num_seqs, which is the limit when
+// neither is known.
+func ConcurrencyLimit(maxNumSeqs int, benchmarks []BenchmarkPoint, tokensPerSecondCapacity int) int {
+	if maxNumSeqs <= 0 {
+		maxNumSeqs = DefaultMaxNumSeqs
+	}
+
+	limit := BenchmarkKnee(benchmarks)
+	if limit == 0 && tokensPerSecondCapacity > 0 {
+		limit = tokensPerSecondCapacity / MinRequestTokensPerSecond
+		if limit < 1 {
+			limit = 1
+		}
+	}
+	if limit == 0 || limit > maxNumSeqs {
+		return maxNumSeqs
+	}
+	return limit
+}
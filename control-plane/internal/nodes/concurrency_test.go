@@ -0,0 +1,65 @@
+package nodes
+
+import "testing"
+
+func TestMaxNumSeqs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, DefaultMaxNumSeqs},
+		{[]string{"--max-num-seqs", "64"}, 64},
+		{[]string{"--model", "m", "--max-num-seqs=32"}, 32},
+		{[]string{"--max-num-seqs", "0"}, DefaultMaxNumSeqs},
+		{[]string{"--max-num-seqs", "many"}, DefaultMaxNumSeqs},
+	}
+	for _, tt := range tests {
+		if got := MaxNumSeqs(tt.args); got != tt.want {
+			t.Errorf("MaxNumSeqs(%v) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestBenchmarkKnee(t *testing.T) {
+	curve := []BenchmarkPoint{
+		{Concurrency: 64, TokensPerSecond: 2050},
+		{Concurrency: 1, TokensPerSecond: 90},
+		{Concurrency: 8, TokensPerSecond: 700},
+		{Concurrency: 16, TokensPerSecond: 1300},
+		{Concurrency: 32, TokensPerSecond: 1900},
+	}
+	if got := BenchmarkKnee(curve); got != 32 {
+		t.Errorf("BenchmarkKnee = %d, want 32", got)
+	}
+	if got := BenchmarkKnee(nil); got != 0 {
+		t.Errorf("BenchmarkKnee(nil) = %d, want 0", got)
+	}
+	if got := BenchmarkKnee([]BenchmarkPoint{{Concurrency: 4}}); got != 0 {
+		t.Errorf("BenchmarkKnee without throughput = %d, want 0", got)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	curve := []BenchmarkPoint{{Concurrency: 8, TokensPerSecond: 500}, {Concurrency: 24, TokensPerSecond: 1000}}
+
+	tests := []struct {
+		name       string
+		maxNumSeqs int
+		benchmarks []BenchmarkPoint
+		capacity   int
+		want       int
+	}{
+		{"benchmark", 256, curve, 5000, 24},
+		{"benchmark capped by max_num_seqs", 16, curve, 0, 16},
+		{"capacity", 256, nil, 1000, 1000 / MinRequestTokensPerSecond},
+		{"small capacity", 256, nil, 5, 1},
+		{"capacity capped by max_num_seqs", 32, nil, 10000, 32},
+		{"max_num_seqs only", 48, nil, 0, 48},
+		{"nothing known", 0, nil, 0, DefaultMaxNumSeqs},
+	}
+	for _, tt := range tests {
+		if got := ConcurrencyLimit(tt.maxNumSeqs, tt.benchmarks, tt.capacity); got != tt.want {
+			t.Errorf("%s: ConcurrencyLimit = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package nodes
+
+import "time"
+
+// HeartbeatStale reports whether a node whose last heartbeat was at last has
+// gone quiet for longer than threshold as of now. Nodes that never sent a
+// heartbeat are still starting and aren't stale; a zero threshold disables
+// the check. now should come from the database clock that stamped last.
+func HeartbeatStale(last *time.Time, threshold time.Duration, now time.Time) bool {
+	if last == nil || threshold <= 0 {
+		return false
+	}
+	return now.Sub(*last) > threshold
+}
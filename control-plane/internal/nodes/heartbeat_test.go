@@ -0,0 +1,33 @@
+package nodes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeartbeatStale(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+
+	tests := []struct {
+		name      string
+		last      *time.Time
+		threshold time.Duration
+		want      bool
+	}{
+		{"fresh", ago(10 * time.Second), 30 * time.Second, false},
+		{"at the threshold", ago(30 * time.Second), 30 * time.Second, false},
+		{"past the threshold", ago(31 * time.Second), 30 * time.Second, true},
+		{"never heartbeated", nil, 30 * time.Second, false},
+		{"check disabled", ago(time.Hour), 0, false},
+		{"clock behind the database", ago(-time.Second), 30 * time.Second, false},
+	}
+	for _, tt := range tests {
+		if got := HeartbeatStale(tt.last, tt.threshold, now); got != tt.want {
+			t.Errorf("%s: HeartbeatStale = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrLaunchTimingNotFound is returned when a node has no recorded launch
+var ErrLaunchTimingNotFound = errors.New("launch timing not found")
+
+// Cold start phases, in pipeline order. Each is the time between two
+// checkpoints of a launch; a phase is only measured when both are known.
+const (
+	// LaunchPhaseProvisioning runs from the launch request until SkyPilot
+	// starts the setup script on the instance
+	LaunchPhaseProvisioning = "provisioning"
+	// LaunchPhaseSetup is the setup script: Python, vLLM and node agent installs
+	LaunchPhaseSetup = "setup"
+	// LaunchPhaseJobStart runs from setup completing until vLLM is started
+	LaunchPhaseJobStart = "job_start"
+	// LaunchPhaseModelLoad runs from vLLM starting until it passes its health check
+	LaunchPhaseModelLoad = "model_load"
+	// LaunchPhaseFirstToken runs from the node serving until its first response
+	LaunchPhaseFirstToken = "first_token"
+
+	// LaunchColdStart is the whole launch, from request until vLLM is healthy
+	LaunchColdStart = "cold_start"
+	// LaunchTimeToFirstToken is the request until the first response served
+	LaunchTimeToFirstToken = "time_to_first_token"
+)
+
+// LaunchPhases lists the cold start phases in pipeline order
+var LaunchPhases = []string{
+	LaunchPhaseProvisioning,
+	LaunchPhaseSetup,
+	LaunchPhaseJobStart,
+	LaunchPhaseModelLoad,
+	LaunchPhaseFirstToken,
+}
+
+// LaunchTiming is the cold start checkpoints recorded for one node launch
+type LaunchTiming struct {
+	NodeID          uuid.UUID  `json:"node_id"`
+	ClusterName     string     `json:"cluster_name,omitempty"`
+	Provider        string     `json:"provider"`
+	Region          string     `json:"region"`
+	GPUType         string     `json:"gpu_type"`
+	GPUCount        int        `json:"gpu_count"`
+	ModelName       string     `json:"model_name,omitempty"`
+	SpotInstance    bool       `json:"spot_instance"`
+	RequestedAt     time.Time  `json:"requested_at"`
+	SetupStartedAt  *time.Time `json:"setup_started_at,omitempty"`
+	InstanceReadyAt *time.Time `json:"instance_ready_at,omitempty"`
+	VLLMStartedAt   *time.Time `json:"vllm_started_at,omitempty"`
+	VLLMHealthyAt   *time.Time `json:"vllm_healthy_at,omitempty"`
+	FirstTokenAt    *time.Time `json:"first_token_at,omitempty"`
+	FailedAt        *time.Time `json:"failed_at,omitempty"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// Durations returns the measured phases and totals of the launch
+func (t *LaunchTiming) Durations() map[string]time.Duration {
+	requested := &t.RequestedAt
+	spans := []struct {
+		name       string
+		start, end *time.Time
+	}{
+		{LaunchPhaseProvisioning, requested, t.SetupStartedAt},
+		{LaunchPhaseSetup, t.SetupStartedAt, t.InstanceReadyAt},
+		{LaunchPhaseJobStart, t.InstanceReadyAt, t.VLLMStartedAt},
+		{LaunchPhaseModelLoad, t.VLLMStartedAt, t.VLLMHealthyAt},
+		{LaunchPhaseFirstToken, t.VLLMHealthyAt, t.FirstTokenAt},
+		{LaunchColdStart, requested, t.VLLMHealthyAt},
+		{LaunchTimeToFirstToken, requested, t.FirstTokenAt},
+	}
+
+	durations := make(map[string]time.Duration, len(spans))
+	for _, s := range spans {
+		if s.start == nil || s.end == nil || s.end.Before(*s.start) {
+			continue
+		}
+		durations[s.name] = s.end.Sub(*s.start)
+	}
+	return durations
+}
+
+// PhaseStats summarizes one phase across launches, in seconds
+type PhaseStats struct {
+	Count int     `json:"count"`
+	Avg   float64 `json:"avg_seconds"`
+	P50   float64 `json:"p50_seconds"`
+	P90   float64 `json:"p90_seconds"`
+	Max   float64 `json:"max_seconds"`
+}
+
+// LaunchSLO reports how many launches became healthy within the target.
+// Failed launches and launches still pending past the target count as
+// breaches; pending launches inside the target are not counted yet.
+type LaunchSLO struct {
+	TargetSeconds float64  `json:"target_seconds"`
+	Met           int      `json:"met"`
+	Breached      int      `json:"breached"`
+	Attainment    *float64 `json:"attainment_percent,omitempty"`
+}
+
+// LaunchSummary aggregates launches for one provider/region/GPU, or the
+// whole fleet when the group fields are empty
+type LaunchSummary struct {
+	Provider string                `json:"provider,omitempty"`
+	Region   string                `json:"region,omitempty"`
+	GPUType  string                `json:"gpu_type,omitempty"`
+	Launches int                   `json:"launches"`
+	Healthy  int                   `json:"healthy"`
+	Failed   int                   `json:"failed"`
+	Pending  int                   `json:"pending"`
+	Phases   map[string]PhaseStats `json:"phases"`
+	SLO      LaunchSLO             `json:"slo"`
+}
+
+// LaunchMetrics is the fleet-wide launch summary and its per-group breakdown
+type LaunchMetrics struct {
+	Overall LaunchSummary   `json:"overall"`
+	Groups  []LaunchSummary `json:"groups"`
+}
+
+// SummarizeLaunches aggregates launch timings per provider, region and GPU
+// type and checks each launch's cold start against the SLO target
+func SummarizeLaunches(timings []LaunchTiming, slo time.Duration, now time.Time) LaunchMetrics {
+	type groupKey struct{ provider, region, gpu string }
+
+	overall := newLaunchAccumulator()
+	groups := make(map[groupKey]*launchAccumulator)
+
+	for i := range timings {
+		t := &timings[i]
+		key := groupKey{t.Provider, t.Region, t.GPUType}
+		acc, ok := groups[key]
+		if !ok {
+			acc = newLaunchAccumulator()
+			groups[key] = acc
+		}
+		overall.add(t, slo, now)
+		acc.add(t, slo, now)
+	}
+
+	metrics := LaunchMetrics{
+		Overall: overall.summary(slo),
+		Groups:  make([]LaunchSummary, 0, len(groups)),
+	}
+	for key, acc := range groups {
+		s := acc.summary(slo)
+		s.Provider, s.Region, s.GPUType = key.provider, key.region, key.gpu
+		metrics.Groups = append(metrics.Groups, s)
+	}
+	sort.Slice(metrics.Groups, func(i, j int) bool {
+		a, b := metrics.Groups[i], metrics.Groups[j]
+		if a.Launches != b.Launches {
+			return a.Launches > b.Launches
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.GPUType < b.GPUType
+	})
+
+	return metrics
+}
+
+type launchAccumulator struct {
+	launches, healthy, failed, pending int
+	met, breached                      int
+	durations                          map[string][]float64
+}
+
+func newLaunchAccumulator() *launchAccumulator {
+	return &launchAccumulator{durations: make(map[string][]float64)}
+}
+
+func (a *launchAccumulator) add(t *LaunchTiming, slo time.Duration, now time.Time) {
+	a.launches++
+	for name, d := range t.Durations() {
+		a.durations[name] = append(a.durations[name], d.Seconds())
+	}
+
+	switch {
+	case t.VLLMHealthyAt != nil:
+		a.healthy++
+		if t.VLLMHealthyAt.Sub(t.RequestedAt) <= slo {
+			a.met++
+		} else {
+			a.breached++
+		}
+	case t.FailedAt != nil:
+		a.failed++
+		a.breached++
+	default:
+		a.pending++
+		if now.Sub(t.RequestedAt) > slo {
+			a.breached++
+		}
+	}
+}
+
+func (a *launchAccumulator) summary(slo time.Duration) LaunchSummary {
+	s := LaunchSummary{
+		Launches: a.launches,
+		Healthy:  a.healthy,
+		Failed:   a.failed,
+		Pending:  a.pending,
+		Phases:   make(map[string]PhaseStats, len(a.durations)),
+		SLO: LaunchSLO{
+			TargetSeconds: slo.Seconds(),
+			Met:           a.met,
+			Breached:      a.breached,
+		},
+	}
+	if total := a.met + a.breached; total > 0 {
+		attainment := float64(a.met) / float64(total) * 100
+		s.SLO.Attainment = &attainment
+	}
+	for name, values := range a.durations {
+		s.Phases[name] = phaseStats(values)
+	}
+	return s
+}
+
+// phaseStats computes the distribution of durations in seconds
+func phaseStats(values []float64) PhaseStats {
+	if len(values) == 0 {
+		return PhaseStats{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	return PhaseStats{
+		Count: len(sorted),
+		Avg:   roundSeconds(sum / float64(len(sorted))),
+		P50:   roundSeconds(percentile(sorted, 0.5)),
+		P90:   roundSeconds(percentile(sorted, 0.9)),
+		Max:   roundSeconds(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile interpolates the p-th percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func roundSeconds(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// LaunchTracker records cold start checkpoints. The orchestrator records the
+// launch itself; the gateway records when the node registers as healthy and
+// when it serves its first response. Recording is keyed by node ID so a
+// retried launch of the same node keeps its original request time.
+type LaunchTracker struct {
+	db *database.Database
+}
+
+// NewLaunchTracker creates a new launch tracker
+func NewLaunchTracker(db *database.Database) *LaunchTracker {
+	return &LaunchTracker{db: db}
+}
+
+// Requested records that a launch was accepted. A retry clears the outcome
+// of the previous attempt but keeps the earliest request time.
+func (l *LaunchTracker) Requested(ctx context.Context, t LaunchTiming) error {
+	_, err := l.db.Pool.Exec(ctx, `
+		INSERT INTO node_launch_timings (
+			node_id, cluster_name, provider, region, gpu_type, gpu_count,
+			model_name, spot_instance, requested_at
+		) VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, NULLIF($7, ''), $8, $9)
+		ON CONFLICT (node_id) DO UPDATE SET
+			cluster_name = EXCLUDED.cluster_name,
+			requested_at = LEAST(node_launch_timings.requested_at, EXCLUDED.requested_at),
+			instance_ready_at = NULL,
+			failed_at = NULL,
+			error = NULL
+	`, t.NodeID, t.ClusterName, t.Provider, t.Region, t.GPUType, t.GPUCount,
+		t.ModelName, t.SpotInstance, t.RequestedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record launch request: %w", err)
+	}
+	return nil
+}
+
+// InstanceReady records that SkyPilot finished provisioning and setup
+func (l *LaunchTracker) InstanceReady(ctx context.Context, nodeID uuid.UUID) error {
+	_, err := l.db.Pool.Exec(ctx, `
+		UPDATE node_launch_timings SET instance_ready_at = NOW() WHERE node_id = $1
+	`, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to record instance ready: %w", err)
+	}
+	return nil
+}
+
+// Failed records that a launch attempt failed
+func (l *LaunchTracker) Failed(ctx context.Context, nodeID uuid.UUID, launchErr error) error {
+	_, err := l.db.Pool.Exec(ctx, `
+		UPDATE node_launch_timings SET failed_at = NOW(), error = $2 WHERE node_id = $1
+	`, nodeID, launchErr.Error())
+	if err != nil {
+		return fmt.Errorf("failed to record launch failure: %w", err)
+	}
+	return nil
+}
+
+// VLLMHealthy records the node registering after vLLM passed its health
+// check, along with the setup and vLLM start times reported by the node.
+// Node-reported times outside the launch window (clock skew) are dropped.
+// Only the first registration counts; agent restarts don't move it.
+func (l *LaunchTracker) VLLMHealthy(ctx context.Context, nodeID uuid.UUID, setupStartedAt, vllmStartedAt *time.Time) error {
+	_, err := l.db.Pool.Exec(ctx, `
+		UPDATE node_launch_timings SET
+			vllm_healthy_at = NOW(),
+			setup_started_at = CASE WHEN $2 BETWEEN requested_at AND NOW() THEN $2 END,
+			vllm_started_at = CASE WHEN $3 BETWEEN requested_at AND NOW() THEN $3 END,
+			failed_at = NULL,
+			error = NULL
+		WHERE node_id = $1 AND vllm_healthy_at IS NULL
+	`, nodeID, setupStartedAt, vllmStartedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record vLLM healthy: %w", err)
+	}
+	return nil
+}
+
+// FirstToken records the first successful response from the node serving
+// at endpoint. It reports whether a launch was waiting for one.
+func (l *LaunchTracker) FirstToken(ctx context.Context, endpoint string) (bool, error) {
+	tag, err := l.db.Pool.Exec(ctx, `
+		UPDATE node_launch_timings t SET first_token_at = NOW()
+		FROM nodes n
+		WHERE n.id = t.node_id
+		  AND n.endpoint_url = $1
+		  AND n.status != 'dead'
+		  AND t.vllm_healthy_at IS NOT NULL
+		  AND t.first_token_at IS NULL
+	`, endpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to record first token: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// LaunchFilter selects launches to list
+type LaunchFilter struct {
+	Since    time.Time
+	Provider string
+	Region   string
+	GPUType  string
+	Limit    int
+}
+
+// List returns launches requested since the filter time, newest first
+func (l *LaunchTracker) List(ctx context.Context, f LaunchFilter) ([]LaunchTiming, error) {
+	rows, err := l.db.Pool.Query(ctx, `
+		SELECT `+launchTimingColumns+`
+		FROM node_launch_timings
+		WHERE requested_at >= $1
+		  AND ($2 = '' OR provider = $2)
+		  AND ($3 = '' OR region = $3)
+		  AND ($4 = '' OR gpu_type = $4)
+		ORDER BY requested_at DESC
+		LIMIT $5
+	`, f.Since, f.Provider, f.Region, f.GPUType, f.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query launch timings: %w", err)
+	}
+	defer rows.Close()
+
+	timings := []LaunchTiming{}
+	for rows.Next() {
+		t, err := scanLaunchTiming(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan launch timing: %w", err)
+		}
+		timings = append(timings, *t)
+	}
+	return timings, rows.Err()
+}
+
+// Get returns the launch timing of one node
+func (l *LaunchTracker) Get(ctx context.Context, nodeID uuid.UUID) (*LaunchTiming, error) {
+	t, err := scanLaunchTiming(l.db.Pool.QueryRow(ctx, `
+		SELECT `+launchTimingColumns+` FROM node_launch_timings WHERE node_id = $1
+	`, nodeID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLaunchTimingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query launch timing: %w", err)
+	}
+	return t, nil
+}
+
+const launchTimingColumns = `node_id, COALESCE(cluster_name, ''), provider, region, gpu_type, gpu_count,
+		       COALESCE(model_name, ''), spot_instance, requested_at, setup_started_at,
+		       instance_ready_at, vllm_started_at, vllm_healthy_at, first_token_at,
+		       failed_at, COALESCE(error, '')`
+
+func scanLaunchTiming(row pgx.Row) (*LaunchTiming, error) {
+	var t LaunchTiming
+	err := row.Scan(&t.NodeID, &t.ClusterName, &t.Provider, &t.Region, &t.GPUType, &t.GPUCount,
+		&t.ModelName, &t.SpotInstance, &t.RequestedAt, &t.SetupStartedAt,
+		&t.InstanceReadyAt, &t.VLLMStartedAt, &t.VLLMHealthyAt, &t.FirstTokenAt,
+		&t.FailedAt, &t.Error)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
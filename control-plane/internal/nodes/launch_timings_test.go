@@ -0,0 +1,120 @@
+package nodes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLaunchTimingDurations(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(secs int) *time.Time {
+		v := base.Add(time.Duration(secs) * time.Second)
+		return &v
+	}
+
+	timing := LaunchTiming{
+		NodeID:          uuid.New(),
+		RequestedAt:     base,
+		SetupStartedAt:  at(70),
+		InstanceReadyAt: at(190),
+		VLLMStartedAt:   at(200),
+		VLLMHealthyAt:   at(260),
+		FirstTokenAt:    at(275),
+	}
+
+	want := map[string]time.Duration{
+		LaunchPhaseProvisioning: 70 * time.Second,
+		LaunchPhaseSetup:        120 * time.Second,
+		LaunchPhaseJobStart:     10 * time.Second,
+		LaunchPhaseModelLoad:    60 * time.Second,
+		LaunchPhaseFirstToken:   15 * time.Second,
+		LaunchColdStart:         260 * time.Second,
+		LaunchTimeToFirstToken:  275 * time.Second,
+	}
+	got := timing.Durations()
+	if len(got) != len(want) {
+		t.Fatalf("Durations() = %v, want %v", got, want)
+	}
+	for name, d := range want {
+		if got[name] != d {
+			t.Errorf("Durations()[%s] = %v, want %v", name, got[name], d)
+		}
+	}
+
+	// A node that didn't report its own checkpoints only has the phases the
+	// control plane observed
+	timing.SetupStartedAt = nil
+	timing.VLLMStartedAt = nil
+	got = timing.Durations()
+	for _, name := range []string{LaunchPhaseProvisioning, LaunchPhaseSetup, LaunchPhaseJobStart, LaunchPhaseModelLoad} {
+		if _, ok := got[name]; ok {
+			t.Errorf("Durations() has %s without its checkpoints", name)
+		}
+	}
+	if got[LaunchColdStart] != 260*time.Second {
+		t.Errorf("Durations()[cold_start] = %v, want 260s", got[LaunchColdStart])
+	}
+}
+
+func TestSummarizeLaunches(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	launch := func(provider, gpu string, coldStart time.Duration, requestedAgo time.Duration) LaunchTiming {
+		l := LaunchTiming{Provider: provider, Region: "us-east-1", GPUType: gpu, RequestedAt: now.Add(-requestedAgo)}
+		if coldStart > 0 {
+			healthy := l.RequestedAt.Add(coldStart)
+			l.VLLMHealthyAt = &healthy
+		}
+		return l
+	}
+
+	failedAt := now.Add(-time.Hour)
+	failed := launch("aws", "A10G", 0, 2*time.Hour)
+	failed.FailedAt = &failedAt
+
+	timings := []LaunchTiming{
+		launch("aws", "A10G", 3*time.Minute, time.Hour),
+		launch("aws", "A10G", 4*time.Minute, time.Hour),
+		launch("aws", "A10G", 7*time.Minute, time.Hour),
+		failed,
+		launch("gcp", "L4", 2*time.Minute, time.Hour),
+		launch("gcp", "L4", 0, time.Minute),    // pending inside the target
+		launch("gcp", "L4", 0, 20*time.Minute), // pending past the target
+	}
+
+	metrics := SummarizeLaunches(timings, 5*time.Minute, now)
+
+	overall := metrics.Overall
+	if overall.Launches != 7 || overall.Healthy != 4 || overall.Failed != 1 || overall.Pending != 2 {
+		t.Errorf("overall counts = %+v", overall)
+	}
+	if overall.SLO.Met != 3 || overall.SLO.Breached != 3 {
+		t.Errorf("overall SLO = met %d, breached %d; want 3, 3", overall.SLO.Met, overall.SLO.Breached)
+	}
+	if overall.SLO.Attainment == nil || *overall.SLO.Attainment != 50 {
+		t.Errorf("overall SLO attainment = %v, want 50", overall.SLO.Attainment)
+	}
+
+	if len(metrics.Groups) != 2 {
+		t.Fatalf("groups = %d, want 2", len(metrics.Groups))
+	}
+	aws := metrics.Groups[0]
+	if aws.Provider != "aws" || aws.GPUType != "A10G" || aws.Launches != 4 {
+		t.Fatalf("first group = %+v, want aws A10G with 4 launches", aws)
+	}
+	cold := aws.Phases[LaunchColdStart]
+	if cold.Count != 3 || cold.P50 != 240 || cold.Max != 420 || cold.Avg != 280 {
+		t.Errorf("aws cold start stats = %+v", cold)
+	}
+	if cold.P90 != 384 {
+		t.Errorf("aws cold start p90 = %v, want 384", cold.P90)
+	}
+}
+
+func TestSummarizeLaunchesEmpty(t *testing.T) {
+	metrics := SummarizeLaunches(nil, 5*time.Minute, time.Now())
+	if metrics.Overall.Launches != 0 || metrics.Overall.SLO.Attainment != nil || len(metrics.Groups) != 0 {
+		t.Errorf("SummarizeLaunches(nil) = %+v", metrics)
+	}
+}
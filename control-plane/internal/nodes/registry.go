@@ -0,0 +1,293 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Node statuses written at registration time
+const (
+	StatusInitializing = "initializing"
+	StatusActive       = "active"
+)
+
+// Workload classes a node can serve. Requests are only routed to nodes of
+// their class.
+const (
+	WorkloadText  = "text"
+	WorkloadAudio = "audio"
+	WorkloadImage = "image"
+)
+
+// Registration is the single column set written for a node, whichever path
+// registers it: the orchestrator after launch, a tenant instance launch, or
+// the node agent calling back once vLLM is serving.
+type Registration struct {
+	// ID identifies the node. When zero, an existing row with the same
+	// cluster name is reused, otherwise a new ID is generated.
+	ID             uuid.UUID
+	ClusterName    string
+	NodeIDExternal string
+	TenantID       *uuid.UUID
+	DeploymentID   *uuid.UUID
+	Provider       string
+	// Region is a region code resolved to region_id when RegionID is unset
+	Region       string
+	RegionID     *uuid.UUID
+	InstanceType string
+	GPUType      string
+	GPUCount     int
+	// VRAMTotalGB is the GPU memory across all GPUs, as the node agent
+	// detected it
+	VRAMTotalGB int
+	ModelName   string
+	ModelID     *uuid.UUID
+	// EndpointURL is empty until the node is serving
+	EndpointURL  string
+	InternalIP   string
+	SpotInstance bool
+	SpotPrice    *float64
+	// Status defaults to active when an endpoint is known, else initializing
+	Status string
+	// TaskTemplate is the ref of the SkyPilot task template that launched
+	// the node
+	TaskTemplate string
+	// Runtime is how the node was launched to serve, compared against what
+	// its agent reports to detect drift
+	Runtime *RuntimeSpec
+	// Standby launches the node as its deployment's warm standby, kept out
+	// of routing until promoted
+	Standby bool
+	// VLLMVersion and TorchVersion are the versions the node was launched
+	// with. The agent's reported vLLM version replaces the launch's.
+	VLLMVersion  string
+	TorchVersion string
+	// Source is who is registering the node, recorded with the status it
+	// writes; defaults to the orchestrator
+	Source string
+	// WorkloadClass is the traffic the node serves. When empty a new node
+	// takes it from its model's type and an existing node keeps its own.
+	WorkloadClass string
+	// HardeningProfile is the security hardening the node was set up with,
+	// empty when it was not hardened
+	HardeningProfile string
+	// Tuning is the vLLM memory settings the node was launched with
+	Tuning *VLLMTuning
+	// HostNodeID is set for a model colocated on another node, served from
+	// ServingPort on the host
+	HostNodeID  *uuid.UUID
+	ServingPort int
+}
+
+// Normalize trims input and fills in the default status
+func (r Registration) Normalize() Registration {
+	r.ClusterName = strings.TrimSpace(r.ClusterName)
+	r.Provider = strings.ToLower(strings.TrimSpace(r.Provider))
+	r.Region = strings.TrimSpace(r.Region)
+	r.EndpointURL = strings.TrimRight(strings.TrimSpace(r.EndpointURL), "/")
+	if r.Status == "" {
+		r.Status = StatusInitializing
+		if r.EndpointURL != "" {
+			r.Status = StatusActive
+		}
+	}
+	if r.Source == "" {
+		r.Source = SourceOrchestrator
+	}
+	r.WorkloadClass = strings.ToLower(strings.TrimSpace(r.WorkloadClass))
+	return r
+}
+
+// Validate checks that a registration can be written
+func (r Registration) Validate() error {
+	if r.ID == uuid.Nil && r.ClusterName == "" {
+		return errors.New("node id or cluster_name is required")
+	}
+	if r.Provider == "" {
+		return errors.New("provider is required")
+	}
+	if r.Status == StatusActive && r.EndpointURL == "" {
+		return errors.New("endpoint_url is required for active nodes")
+	}
+	switch r.WorkloadClass {
+	case "", WorkloadText, WorkloadAudio, WorkloadImage:
+	default:
+		return fmt.Errorf("unknown workload_class %q", r.WorkloadClass)
+	}
+	return nil
+}
+
+// Registry writes node rows. Every registration path goes through it so the
+// load balancer, scheduler and admin views all see the same columns.
+type Registry struct {
+	db *database.Database
+}
+
+// NewRegistry creates a new node registry
+func NewRegistry(db *database.Database) *Registry {
+	return &Registry{db: db}
+}
+
+// Register inserts a node or updates the existing row with the same ID or
+// cluster name. Fields left empty keep their stored values, so a node agent
+// calling back does not clear the tenant or deployment set at launch, and a
+// late launch registration does not demote a node that is already active.
+// It returns the node ID and whether a new row was created.
+func (r *Registry) Register(ctx context.Context, reg Registration) (uuid.UUID, bool, error) {
+	reg = reg.Normalize()
+	if err := reg.Validate(); err != nil {
+		return uuid.Nil, false, err
+	}
+
+	id, err := r.resolveID(ctx, reg)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	var vramTotal, gpuCount *int
+	if reg.VRAMTotalGB > 0 {
+		vramTotal = &reg.VRAMTotalGB
+	}
+	if reg.GPUCount > 0 {
+		gpuCount = &reg.GPUCount
+	}
+	var servingPort *int
+	if reg.ServingPort > 0 {
+		servingPort = &reg.ServingPort
+	}
+	var memoryUtilization *float64
+	var maxModelLen, maxNumSeqs, tunedVRAM *int
+	if t := reg.Tuning; t != nil {
+		memoryUtilization, maxModelLen, maxNumSeqs = &t.GPUMemoryUtilization, &t.MaxModelLen, &t.MaxNumSeqs
+		if t.VRAMPerGPUGB > 0 {
+			tunedVRAM = &t.VRAMPerGPUGB
+		}
+	}
+
+	var runtime []byte
+	if reg.Runtime != nil {
+		if runtime, err = json.Marshal(reg.Runtime); err != nil {
+			return uuid.Nil, false, fmt.Errorf("invalid runtime spec: %w", err)
+		}
+	}
+
+	var nodeID uuid.UUID
+	var created bool
+	// A new node's first status is recorded here; later changes, including
+	// the update when the node already exists, by the status trigger
+	err = r.db.Pool.QueryRow(ctx, `
+		WITH upsert AS (
+			INSERT INTO nodes (
+				id, cluster_name, node_id_external, tenant_id, deployment_id,
+				provider, region_id, instance_type, gpu_type, vram_total_gb,
+				model_name, model_id, endpoint_url, endpoint, internal_ip,
+				spot_instance, spot_price, status, health_score, last_heartbeat_at,
+				task_template, desired_runtime, standby, vllm_version, torch_version,
+				workload_class, hardening_profile, gpu_count,
+				vllm_gpu_memory_utilization, vllm_max_model_len, vllm_max_num_seqs, vllm_tuned_vram_gb,
+				host_node_id, serving_port
+			) VALUES (
+				$1, NULLIF($2, ''), NULLIF($3, ''), $4, $5,
+				$6, COALESCE($7, (SELECT id FROM regions WHERE code = NULLIF($8, ''))),
+				NULLIF($9, ''), NULLIF($10, ''), $11,
+				NULLIF($12, ''), COALESCE($13, (SELECT id FROM models WHERE name = NULLIF($12, ''))),
+				$14, $14, NULLIF($15, ''),
+				$16, $17, $18, 100.0,
+				CASE WHEN $18 = 'active' THEN NOW() END,
+				NULLIF($19, ''), $20, $21, NULLIF($22, ''), NULLIF($23, ''),
+				COALESCE(NULLIF($25, ''),
+					(SELECT CASE WHEN type IN ('audio', 'image') THEN type END FROM models WHERE name = NULLIF($12, '')),
+					'text'),
+				NULLIF($26, ''), $27,
+				$28, $29, $30, $31,
+				$32, $33
+			)
+			ON CONFLICT (id) DO UPDATE SET
+				cluster_name = COALESCE(EXCLUDED.cluster_name, nodes.cluster_name),
+				node_id_external = COALESCE(EXCLUDED.node_id_external, nodes.node_id_external),
+				tenant_id = COALESCE(EXCLUDED.tenant_id, nodes.tenant_id),
+				deployment_id = COALESCE(EXCLUDED.deployment_id, nodes.deployment_id),
+				provider = EXCLUDED.provider,
+				region_id = COALESCE(EXCLUDED.region_id, nodes.region_id),
+				instance_type = COALESCE(EXCLUDED.instance_type, nodes.instance_type),
+				gpu_type = COALESCE(EXCLUDED.gpu_type, nodes.gpu_type),
+				vram_total_gb = COALESCE(EXCLUDED.vram_total_gb, nodes.vram_total_gb),
+				model_name = COALESCE(EXCLUDED.model_name, nodes.model_name),
+				model_id = COALESCE(EXCLUDED.model_id, nodes.model_id),
+				endpoint_url = COALESCE(NULLIF(EXCLUDED.endpoint_url, ''), nodes.endpoint_url),
+				endpoint = COALESCE(NULLIF(EXCLUDED.endpoint, ''), nodes.endpoint),
+				internal_ip = COALESCE(EXCLUDED.internal_ip, nodes.internal_ip),
+				spot_instance = EXCLUDED.spot_instance OR nodes.spot_instance,
+				spot_price = COALESCE(EXCLUDED.spot_price, nodes.spot_price),
+				status = CASE
+					WHEN EXCLUDED.status = 'initializing' AND nodes.status = 'active' THEN nodes.status
+					ELSE EXCLUDED.status
+				END,
+				health_score = CASE WHEN EXCLUDED.status = 'active' THEN 100.0 ELSE nodes.health_score END,
+				last_heartbeat_at = COALESCE(EXCLUDED.last_heartbeat_at, nodes.last_heartbeat_at),
+				task_template = COALESCE(EXCLUDED.task_template, nodes.task_template),
+				desired_runtime = COALESCE(EXCLUDED.desired_runtime, nodes.desired_runtime),
+				standby = nodes.standby OR EXCLUDED.standby,
+				vllm_version = COALESCE(nodes.vllm_version, EXCLUDED.vllm_version),
+				torch_version = COALESCE(nodes.torch_version, EXCLUDED.torch_version),
+				workload_class = COALESCE(NULLIF($25, ''), nodes.workload_class),
+				hardening_profile = COALESCE(EXCLUDED.hardening_profile, nodes.hardening_profile),
+				gpu_count = COALESCE(EXCLUDED.gpu_count, nodes.gpu_count),
+				vllm_gpu_memory_utilization = COALESCE(EXCLUDED.vllm_gpu_memory_utilization, nodes.vllm_gpu_memory_utilization),
+				vllm_max_model_len = COALESCE(EXCLUDED.vllm_max_model_len, nodes.vllm_max_model_len),
+				vllm_max_num_seqs = COALESCE(EXCLUDED.vllm_max_num_seqs, nodes.vllm_max_num_seqs),
+				vllm_tuned_vram_gb = COALESCE(EXCLUDED.vllm_tuned_vram_gb, nodes.vllm_tuned_vram_gb),
+				host_node_id = COALESCE(EXCLUDED.host_node_id, nodes.host_node_id),
+				serving_port = COALESCE(EXCLUDED.serving_port, nodes.serving_port),
+				terminated_at = NULL,
+				status_source = $24,
+				updated_at = NOW()
+			RETURNING id, (xmax = 0) AS created, status
+		), initial AS (
+			INSERT INTO node_status_transitions (node_id, to_status, source)
+			SELECT id, status, $24 FROM upsert WHERE created
+		)
+		SELECT id, created FROM upsert
+	`,
+		id, reg.ClusterName, reg.NodeIDExternal, reg.TenantID, reg.DeploymentID,
+		reg.Provider, reg.RegionID, reg.Region,
+		reg.InstanceType, reg.GPUType, vramTotal,
+		reg.ModelName, reg.ModelID,
+		reg.EndpointURL, reg.InternalIP,
+		reg.SpotInstance, reg.SpotPrice, reg.Status,
+		reg.TaskTemplate, runtime, reg.Standby, reg.VLLMVersion, reg.TorchVersion,
+		reg.Source, reg.WorkloadClass, reg.HardeningProfile, gpuCount,
+		memoryUtilization, maxModelLen, maxNumSeqs, tunedVRAM,
+		reg.HostNodeID, servingPort,
+	).Scan(&nodeID, &created)
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to register node: %w", err)
+	}
+
+	return nodeID, created, nil
+}
+
+// resolveID picks the row a registration applies to. The node ID wins over
+// the cluster name so a relaunch under a reused cluster name keeps its own row.
+func (r *Registry) resolveID(ctx context.Context, reg Registration) (uuid.UUID, error) {
+	if reg.ID != uuid.Nil {
+		return reg.ID, nil
+	}
+
+	var id uuid.UUID
+	err := r.db.Pool.QueryRow(ctx, `SELECT id FROM nodes WHERE cluster_name = $1`, reg.ClusterName).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.New(), nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to look up node: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,65 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRegistrationNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		reg        Registration
+		wantStatus string
+		wantURL    string
+	}{
+		{"launch without endpoint", Registration{ClusterName: "cic-aws-x"}, StatusInitializing, ""},
+		{"agent with endpoint", Registration{EndpointURL: " http://10.0.0.4:8000/ "}, StatusActive, "http://10.0.0.4:8000"},
+		{"explicit status kept", Registration{EndpointURL: "http://10.0.0.4:8000", Status: "draining"}, "draining", "http://10.0.0.4:8000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.reg.Normalize()
+			if got.Status != tt.wantStatus || got.EndpointURL != tt.wantURL {
+				t.Errorf("Normalize() = status %q, endpoint %q; want %q, %q", got.Status, got.EndpointURL, tt.wantStatus, tt.wantURL)
+			}
+		})
+	}
+
+	if got := (Registration{Provider: " AWS "}).Normalize().Provider; got != "aws" {
+		t.Errorf("Normalize() provider = %q, want aws", got)
+	}
+	if got := (Registration{}).Normalize().Source; got != SourceOrchestrator {
+		t.Errorf("Normalize() source = %q, want %s", got, SourceOrchestrator)
+	}
+}
+
+func TestRegistrationValidate(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name    string
+		reg     Registration
+		wantErr bool
+	}{
+		{"node id", Registration{ID: id, Provider: "aws", Status: StatusInitializing}, false},
+		{"cluster name", Registration{ClusterName: "cic-gcp-y", Provider: "gcp", Status: StatusInitializing}, false},
+		{"active with endpoint", Registration{ID: id, Provider: "aws", Status: StatusActive, EndpointURL: "http://n:8000"}, false},
+		{"no identity", Registration{Provider: "aws", EndpointURL: "http://n:8000"}, true},
+		{"no provider", Registration{ID: id}, true},
+		{"active without endpoint", Registration{ID: id, Provider: "aws", Status: StatusActive}, true},
+		{"audio workload", Registration{ID: id, Provider: "aws", WorkloadClass: " Audio "}, false},
+		{"image workload", Registration{ID: id, Provider: "aws", WorkloadClass: "image"}, false},
+		{"unknown workload", Registration{ID: id, Provider: "aws", WorkloadClass: "video"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.reg.Normalize().Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Runtime fields compared for drift
+const (
+	DriftFieldModel       = "model"
+	DriftFieldVLLMVersion = "vllm_version"
+	DriftFieldVLLMArg     = "vllm_arg"
+)
+
+// RuntimeSpec is how a node was launched to serve, recorded at launch
+type RuntimeSpec struct {
+	Model       string `json:"model"`
+	VLLMVersion string `json:"vllm_version,omitempty"`
+	// VLLMArgs are the launch's vLLM flags that must be present on the node
+	VLLMArgs []string `json:"vllm_args,omitempty"`
+}
+
+// RuntimeReport is what the node agent sees running, sent with heartbeats
+type RuntimeReport struct {
+	// ServedModels are the model IDs vLLM lists at /v1/models
+	ServedModels []string `json:"served_models,omitempty"`
+	VLLMVersion  string   `json:"vllm_version,omitempty"`
+	// VLLMArgs is vLLM's command line after the entrypoint
+	VLLMArgs []string `json:"vllm_args,omitempty"`
+}
+
+// Drift is one difference between a node's spec and its reported runtime
+type Drift struct {
+	Field string `json:"field"`
+	// Flag is set for DriftFieldVLLMArg
+	Flag string `json:"flag,omitempty"`
+	Want string `json:"want"`
+	Got  string `json:"got"`
+}
+
+// CompareRuntime lists how a report differs from a spec. Fields the agent
+// couldn't observe are not compared. Models match by exact ID or by path
+// suffix, since vLLM serves R2 models under their s3:// path.
+func CompareRuntime(spec RuntimeSpec, report RuntimeReport) []Drift {
+	var drift []Drift
+
+	if spec.Model != "" && len(report.ServedModels) > 0 && !servesModel(report.ServedModels, spec.Model) {
+		drift = append(drift, Drift{
+			Field: DriftFieldModel,
+			Want:  spec.Model,
+			Got:   strings.Join(report.ServedModels, ","),
+		})
+	}
+
+	if spec.VLLMVersion != "" && report.VLLMVersion != "" && baseVersion(spec.VLLMVersion) != baseVersion(report.VLLMVersion) {
+		drift = append(drift, Drift{
+			Field: DriftFieldVLLMVersion,
+			Want:  spec.VLLMVersion,
+			Got:   report.VLLMVersion,
+		})
+	}
+
+	if len(spec.VLLMArgs) > 0 && len(report.VLLMArgs) > 0 {
+		got := parseFlags(report.VLLMArgs)
+		for _, want := range orderedFlags(spec.VLLMArgs) {
+			value, ok := got[want.name]
+			switch {
+			case !ok:
+				drift = append(drift, Drift{Field: DriftFieldVLLMArg, Flag: want.name, Want: want.display(), Got: ""})
+			case value != want.value:
+				drift = append(drift, Drift{Field: DriftFieldVLLMArg, Flag: want.name, Want: want.display(), Got: flag{want.name, value}.display()})
+			}
+		}
+	}
+
+	return drift
+}
+
+// servesModel reports whether model is one of the served IDs
+func servesModel(served []string, model string) bool {
+	for _, id := range served {
+		if id == model || strings.HasSuffix(id, "/"+model) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseVersion drops a local version label such as "+cu121"
+func baseVersion(v string) string {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	return v
+}
+
+type flag struct {
+	name, value string
+}
+
+func (f flag) display() string {
+	if f.value == "" {
+		return f.name
+	}
+	return f.name + " " + f.value
+}
+
+// orderedFlags parses "--name value", "--name=value" and bare "--name"
+// flags in the order given; other tokens are ignored
+func orderedFlags(args []string) []flag {
+	var flags []flag
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if !hasValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			value = args[i+1]
+			i++
+		}
+		flags = append(flags, flag{name, value})
+	}
+	return flags
+}
+
+// parseFlags indexes flags by name; a repeated flag keeps its last value,
+// as vLLM's argument parser does
+func parseFlags(args []string) map[string]string {
+	flags := make(map[string]string)
+	for _, f := range orderedFlags(args) {
+		flags[f.name] = f.value
+	}
+	return flags
+}
+
+// SplitArgs splits a command line string into arguments, honouring single
+// and double quotes
+func SplitArgs(s string) []string {
+	var args []string
+	var cur strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// NodeRuntime is a deployment node's launch spec and latest runtime report
+type NodeRuntime struct {
+	NodeID      uuid.UUID      `json:"node_id"`
+	ClusterName string         `json:"cluster_name"`
+	Status      string         `json:"status"`
+	Spec        *RuntimeSpec   `json:"spec,omitempty"`
+	Report      *RuntimeReport `json:"report,omitempty"`
+	ReportedAt  *time.Time     `json:"reported_at,omitempty"`
+	// DriftSince is when drift was first detected, nil when in sync
+	DriftSince *time.Time `json:"drift_since,omitempty"`
+	// Standby is set for the deployment's warm standby
+	Standby bool `json:"standby,omitempty"`
+}
+
+// Drift compares the node against its spec with the model replaced by the
+// deployment's, which wins when they disagree. It is nil until the node has
+// reported its runtime.
+func (n NodeRuntime) Drift(deploymentModel string) []Drift {
+	if n.Report == nil {
+		return nil
+	}
+	spec := RuntimeSpec{}
+	if n.Spec != nil {
+		spec = *n.Spec
+	}
+	if deploymentModel != "" {
+		spec.Model = deploymentModel
+	}
+	return CompareRuntime(spec, *n.Report)
+}
+
+// RecordRuntime stores the runtime a node agent reported. The reported vLLM
+// version becomes the node's serving version.
+func (r *Registry) RecordRuntime(ctx context.Context, nodeID uuid.UUID, report RuntimeReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE nodes
+		SET reported_runtime = $2, runtime_reported_at = NOW(),
+		    vllm_version = COALESCE(NULLIF($3, ''), vllm_version)
+		WHERE id = $1
+	`, nodeID, data, report.VLLMVersion)
+	if err != nil {
+		return fmt.Errorf("failed to record node runtime: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("node not found: %s", nodeID)
+	}
+	return nil
+}
+
+// DeploymentRuntimes lists the runtime spec and report of a deployment's
+// live nodes
+func (r *Registry) DeploymentRuntimes(ctx context.Context, deploymentID uuid.UUID) ([]NodeRuntime, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, COALESCE(cluster_name, ''), status, desired_runtime, reported_runtime,
+		       runtime_reported_at, runtime_drift_since, standby
+		FROM nodes
+		WHERE deployment_id = $1 AND status IN ('initializing', 'active', 'ready')
+		ORDER BY created_at
+	`, deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node runtimes: %w", err)
+	}
+	defer rows.Close()
+
+	var list []NodeRuntime
+	for rows.Next() {
+		var n NodeRuntime
+		var spec, report []byte
+		if err := rows.Scan(&n.NodeID, &n.ClusterName, &n.Status, &spec, &report, &n.ReportedAt, &n.DriftSince, &n.Standby); err != nil {
+			return nil, fmt.Errorf("failed to scan node runtime: %w", err)
+		}
+		if len(spec) > 0 {
+			n.Spec = &RuntimeSpec{}
+			if err := json.Unmarshal(spec, n.Spec); err != nil {
+				return nil, fmt.Errorf("invalid runtime spec for node %s: %w", n.NodeID, err)
+			}
+		}
+		if len(report) > 0 {
+			n.Report = &RuntimeReport{}
+			if err := json.Unmarshal(report, n.Report); err != nil {
+				return nil, fmt.Errorf("invalid runtime report for node %s: %w", n.NodeID, err)
+			}
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+// MarkDrift records whether a node has drifted. The first detection time is
+// kept while drift persists and cleared once the node is back in sync. It
+// returns when the current drift was first detected, or nil.
+func (r *Registry) MarkDrift(ctx context.Context, nodeID uuid.UUID, drifted bool) (*time.Time, error) {
+	var since *time.Time
+	err := r.db.Pool.QueryRow(ctx, `
+		UPDATE nodes
+		SET runtime_drift_since = CASE
+			WHEN $2 THEN COALESCE(runtime_drift_since, NOW())
+		END
+		WHERE id = $1
+		RETURNING runtime_drift_since
+	`, nodeID, drifted).Scan(&since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark node drift: %w", err)
+	}
+	return since, nil
+}
@@ -0,0 +1,76 @@
+package nodes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareRuntime(t *testing.T) {
+	spec := RuntimeSpec{
+		Model:       "meta-llama/Llama-3-8B",
+		VLLMVersion: "0.6.2",
+		VLLMArgs:    []string{"--tensor-parallel-size", "2", "--max-model-len=4096", "--enforce-eager"},
+	}
+	inSync := RuntimeReport{
+		ServedModels: []string{"s3://crosslogic-models/meta-llama/Llama-3-8B"},
+		VLLMVersion:  "0.6.2+cu121",
+		VLLMArgs: []string{"--model", "s3://crosslogic-models/meta-llama/Llama-3-8B",
+			"--tensor-parallel-size", "2", "--max-model-len", "4096", "--enforce-eager"},
+	}
+
+	tests := []struct {
+		name   string
+		report func(r *RuntimeReport)
+		want   []Drift
+	}{
+		{"in sync", func(r *RuntimeReport) {}, nil},
+		{"model swapped", func(r *RuntimeReport) {
+			r.ServedModels = []string{"mistralai/Mistral-7B"}
+		}, []Drift{{Field: DriftFieldModel, Want: "meta-llama/Llama-3-8B", Got: "mistralai/Mistral-7B"}}},
+		{"vllm upgraded", func(r *RuntimeReport) {
+			r.VLLMVersion = "0.7.0"
+		}, []Drift{{Field: DriftFieldVLLMVersion, Want: "0.6.2", Got: "0.7.0"}}},
+		{"flag changed and removed", func(r *RuntimeReport) {
+			r.VLLMArgs = []string{"--tensor-parallel-size", "1", "--max-model-len", "4096"}
+		}, []Drift{
+			{Field: DriftFieldVLLMArg, Flag: "--tensor-parallel-size", Want: "--tensor-parallel-size 2", Got: "--tensor-parallel-size 1"},
+			{Field: DriftFieldVLLMArg, Flag: "--enforce-eager", Want: "--enforce-eager", Got: ""},
+		}},
+		{"unobserved fields skipped", func(r *RuntimeReport) {
+			*r = RuntimeReport{}
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := inSync
+			tt.report(&report)
+			if got := CompareRuntime(spec, report); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareRuntime() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeRuntimeDriftUsesDeploymentModel(t *testing.T) {
+	n := NodeRuntime{
+		Spec:   &RuntimeSpec{Model: "old/model"},
+		Report: &RuntimeReport{ServedModels: []string{"old/model"}},
+	}
+	if drift := n.Drift("new/model"); len(drift) != 1 || drift[0].Field != DriftFieldModel {
+		t.Errorf("Drift() = %+v, want a model drift", drift)
+	}
+	if drift := (NodeRuntime{Spec: n.Spec}).Drift("new/model"); drift != nil {
+		t.Errorf("Drift() without a report = %+v, want nil", drift)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	got := SplitArgs(`'--max-model-len' '4096'  --served-model-name "my model"`)
+	want := []string{"--max-model-len", "4096", "--served-model-name", "my model"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitArgs() = %q, want %q", got, want)
+	}
+	if got := SplitArgs("  "); got != nil {
+		t.Errorf("SplitArgs(blank) = %q, want nil", got)
+	}
+}
@@ -0,0 +1,98 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Promotion is a warm standby moved into its deployment's routing set
+type Promotion struct {
+	NodeID       uuid.UUID
+	ClusterName  string
+	DeploymentID uuid.UUID
+}
+
+// NodeDeployment returns the deployment a node belongs to and whether the
+// node is its warm standby. ok is false for nodes outside a deployment.
+func (r *Registry) NodeDeployment(ctx context.Context, nodeID uuid.UUID) (deploymentID uuid.UUID, standby, ok bool, err error) {
+	var id *uuid.UUID
+	err = r.db.Pool.QueryRow(ctx, `
+		SELECT deployment_id, standby FROM nodes WHERE id = $1
+	`, nodeID).Scan(&id, &standby)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, false, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, false, fmt.Errorf("failed to look up node deployment: %w", err)
+	}
+	if id == nil {
+		return uuid.Nil, standby, false, nil
+	}
+	return *id, standby, true, nil
+}
+
+// PromoteStandby moves a deployment's healthy warm standby into routing when
+// the deployment has fewer active primaries than it should. It returns nil
+// when there is nothing to promote: warm standby is off, the deployment is
+// already at strength, or no standby is serving yet. The deployment row is
+// locked so concurrent failure reports promote at most one standby each.
+func (r *Registry) PromoteStandby(ctx context.Context, deploymentID uuid.UUID) (*Promotion, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var warmStandby bool
+	var want int
+	err = tx.QueryRow(ctx, `
+		SELECT warm_standby, GREATEST(min_replicas, current_replicas)
+		FROM deployments WHERE id = $1
+		FOR UPDATE
+	`, deploymentID).Scan(&warmStandby, &want)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !warmStandby) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock deployment: %w", err)
+	}
+
+	var primaries int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM nodes
+		WHERE deployment_id = $1 AND NOT standby AND status = 'active'
+	`, deploymentID).Scan(&primaries); err != nil {
+		return nil, fmt.Errorf("failed to count primaries: %w", err)
+	}
+	if primaries >= want {
+		return nil, nil
+	}
+
+	p := Promotion{DeploymentID: deploymentID}
+	err = tx.QueryRow(ctx, `
+		UPDATE nodes
+		SET standby = false, promoted_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM nodes
+			WHERE deployment_id = $1 AND standby AND status = 'active' AND endpoint_url != ''
+			ORDER BY created_at
+			LIMIT 1
+		)
+		RETURNING id, COALESCE(cluster_name, '')
+	`, deploymentID).Scan(&p.NodeID, &p.ClusterName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to promote standby: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit promotion: %w", err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,105 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sources of node status changes. Writers set nodes.status_source to one of
+// these with the status they write; the status trigger records it in
+// node_status_transitions.
+const (
+	// SourceOrchestrator is launches, registrations and terminations
+	SourceOrchestrator = "orchestrator"
+	// SourceNode is the node agent: registration, heartbeats, drains and
+	// spot termination warnings
+	SourceNode = "node"
+	// SourceMonitor is the triple safety monitor's health evaluation
+	SourceMonitor = "monitor"
+	// SourceReconciler is the state reconciler syncing with the cloud
+	SourceReconciler = "reconciler"
+	// SourceScheduler is the scheduler's node pool
+	SourceScheduler = "scheduler"
+	// SourceAdmin is a platform admin acting through the admin API
+	SourceAdmin = "admin"
+	// SourceHost is a host node's status copied onto the models colocated
+	// on it, by the propagate_host_node_state trigger
+	SourceHost = "host"
+)
+
+// StatusTransition is one change of a node's status
+type StatusTransition struct {
+	FromStatus string    `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status"`
+	Source     string    `json:"source"`
+	Reason     string    `json:"reason,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Transitions returns a node's status changes, oldest first
+func (r *Registry) Transitions(ctx context.Context, nodeID uuid.UUID) ([]StatusTransition, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT COALESCE(from_status, ''), to_status, source, COALESCE(reason, ''), occurred_at
+		FROM node_status_transitions
+		WHERE node_id = $1
+		ORDER BY occurred_at, id
+	`, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status transitions: %w", err)
+	}
+	defer rows.Close()
+
+	var transitions []StatusTransition
+	for rows.Next() {
+		var t StatusTransition
+		if err := rows.Scan(&t.FromStatus, &t.ToStatus, &t.Source, &t.Reason, &t.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan status transition: %w", err)
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, rows.Err()
+}
+
+// billable reports whether the node's instance is running, and paid for, in
+// a status
+func billable(status string) bool {
+	return status != "dead" && status != "terminated"
+}
+
+// BillableDuration returns how long the node's instance ran between launched
+// and until, from its status history. The instance is paid for from launch
+// until the node is terminated or declared dead; a node that comes back
+// after being declared dead is billed again from then. Without history the
+// node is assumed to have run since launched.
+func BillableDuration(transitions []StatusTransition, launched, until time.Time) time.Duration {
+	if until.Before(launched) {
+		return 0
+	}
+
+	var total time.Duration
+	running := true
+	since := launched
+	for _, t := range transitions {
+		at := t.OccurredAt
+		if at.Before(launched) {
+			at = launched
+		}
+		if at.After(until) {
+			break
+		}
+		if running && !billable(t.ToStatus) {
+			total += at.Sub(since)
+			running = false
+		} else if !running && billable(t.ToStatus) {
+			since = at
+			running = true
+		}
+	}
+	if running {
+		total += until.Sub(since)
+	}
+	return total
+}
@@ -0,0 +1,42 @@
+package nodes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBillableDuration(t *testing.T) {
+	launched := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return launched.Add(time.Duration(h) * time.Hour) }
+
+	tests := []struct {
+		name        string
+		transitions []StatusTransition
+		until       time.Time
+		want        time.Duration
+	}{
+		{"no history runs until now", nil, at(5), 5 * time.Hour},
+		{"active then terminated", []StatusTransition{
+			{ToStatus: StatusInitializing, OccurredAt: at(0)},
+			{ToStatus: StatusActive, OccurredAt: at(1)},
+			{ToStatus: "terminated", OccurredAt: at(3)},
+		}, at(10), 3 * time.Hour},
+		{"dead then back", []StatusTransition{
+			{ToStatus: StatusActive, OccurredAt: at(0)},
+			{ToStatus: "dead", OccurredAt: at(2)},
+			{ToStatus: StatusActive, OccurredAt: at(4)},
+		}, at(6), 4 * time.Hour},
+		{"transitions after until ignored", []StatusTransition{
+			{ToStatus: "dead", OccurredAt: at(8)},
+		}, at(6), 6 * time.Hour},
+		{"until before launch", nil, launched.Add(-time.Hour), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BillableDuration(tt.transitions, launched, tt.until); got != tt.want {
+				t.Errorf("BillableDuration() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
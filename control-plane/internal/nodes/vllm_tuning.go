@@ -0,0 +1,166 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// DefaultGPUMemoryUtilization and DefaultMaxModelLen are the vLLM
+	// settings launched with when the model size or GPU memory is unknown
+	DefaultGPUMemoryUtilization = 0.95
+	DefaultMaxModelLen          = 32768
+
+	// maxTunedModelLen caps the context length tuning picks for models
+	// whose context window is unknown or very long
+	maxTunedModelLen = 131072
+	// minTunedModelLen and minTunedNumSeqs are the least tuning settles for
+	minTunedModelLen = 2048
+	minTunedNumSeqs  = 16
+
+	// activationReserveGB is GPU memory per GPU kept for activations and
+	// CUDA graphs, outside the weights and KV cache
+	activationReserveGB = 2.0
+	// fullLengthSequences is how many max-length sequences the KV cache
+	// should hold at once
+	fullLengthSequences = 4
+	// typicalSequenceTokens sizes the batch: how many tokens an average
+	// running sequence holds in the KV cache
+	typicalSequenceTokens = 1024
+)
+
+// VLLMTuning is the memory and batching settings a node's vLLM starts with
+type VLLMTuning struct {
+	GPUMemoryUtilization float64 `json:"gpu_memory_utilization"`
+	MaxModelLen          int     `json:"max_model_len"`
+	MaxNumSeqs           int     `json:"max_num_seqs"`
+	// VRAMPerGPUGB is the GPU memory the settings were chosen for, zero
+	// when it was unknown
+	VRAMPerGPUGB int `json:"vram_per_gpu_gb,omitempty"`
+}
+
+// DefaultVLLMTuning is the tuning used when nothing is known about the model
+// or the GPUs
+func DefaultVLLMTuning() VLLMTuning {
+	return VLLMTuning{
+		GPUMemoryUtilization: DefaultGPUMemoryUtilization,
+		MaxModelLen:          DefaultMaxModelLen,
+		MaxNumSeqs:           DefaultMaxNumSeqs,
+	}
+}
+
+// TuneVLLM chooses vLLM settings for a model of modelGB weights served on
+// gpuCount GPUs of vramPerGPUGB each. contextLength is the model's context
+// window, zero when unknown.
+//
+// Smaller cards keep more memory back from vLLM. What remains after the
+// weights and activations holds the KV cache, whose size per token is
+// estimated from the model size (GQA-era models sit well below the older
+// multi-head ones). The context length is the largest power of two that
+// lets the cache hold a few full-length sequences, and the batch size how
+// many typical sequences it holds.
+func TuneVLLM(modelGB float64, vramPerGPUGB, gpuCount, contextLength int) VLLMTuning {
+	if modelGB <= 0 || vramPerGPUGB <= 0 {
+		tuning := DefaultVLLMTuning()
+		tuning.VRAMPerGPUGB = vramPerGPUGB
+		return tuning
+	}
+	return TuneVLLMShare(modelGB, vramPerGPUGB, gpuCount, contextLength, GPUMemoryBudget(vramPerGPUGB))
+}
+
+// GPUMemoryBudget is the fraction of each GPU's memory vLLM is given on
+// GPUs of vramPerGPUGB; smaller cards keep more back
+func GPUMemoryBudget(vramPerGPUGB int) float64 {
+	switch {
+	case vramPerGPUGB <= 0:
+		return DefaultGPUMemoryUtilization
+	case vramPerGPUGB < 24:
+		return 0.90
+	case vramPerGPUGB < 48:
+		return 0.92
+	}
+	return DefaultGPUMemoryUtilization
+}
+
+// TuneVLLMShare is TuneVLLM for a vLLM instance given utilization of the
+// GPUs' memory, as when several models share a node's GPUs. The context
+// length and batch size are tuned for what the share leaves after the
+// weights.
+func TuneVLLMShare(modelGB float64, vramPerGPUGB, gpuCount, contextLength int, utilization float64) VLLMTuning {
+	tuning := DefaultVLLMTuning()
+	tuning.GPUMemoryUtilization = utilization
+	tuning.VRAMPerGPUGB = vramPerGPUGB
+	if modelGB <= 0 || vramPerGPUGB <= 0 {
+		return tuning
+	}
+	if gpuCount < 1 {
+		gpuCount = 1
+	}
+
+	cacheGB := float64(vramPerGPUGB*gpuCount)*tuning.GPUMemoryUtilization -
+		modelGB - activationReserveGB*float64(gpuCount)
+	kvMBPerToken := math.Max(0.04*math.Sqrt(modelGB), 0.05)
+	cacheTokens := int(cacheGB * 1024 / kvMBPerToken)
+
+	maxLen := DefaultMaxModelLen
+	if contextLength > 0 {
+		maxLen = min(contextLength, maxTunedModelLen)
+	}
+	fits := minTunedModelLen
+	for fits*2 <= cacheTokens/fullLengthSequences {
+		fits *= 2
+	}
+	tuning.MaxModelLen = min(fits, maxLen)
+
+	seqs := cacheTokens / typicalSequenceTokens / 8 * 8
+	tuning.MaxNumSeqs = max(min(seqs, DefaultMaxNumSeqs), minTunedNumSeqs)
+	return tuning
+}
+
+// WithArgs applies vLLM flags that set the same values explicitly, as vLLM
+// would: the last occurrence of a flag wins
+func (t VLLMTuning) WithArgs(args []string) VLLMTuning {
+	flags := parseFlags(args)
+	if v, err := strconv.ParseFloat(flags["--gpu-memory-utilization"], 64); err == nil && v > 0 && v <= 1 {
+		t.GPUMemoryUtilization = v
+	}
+	if v, err := strconv.Atoi(flags["--max-model-len"]); err == nil && v > 0 {
+		t.MaxModelLen = v
+	}
+	if v, err := strconv.Atoi(flags["--max-num-seqs"]); err == nil && v > 0 {
+		t.MaxNumSeqs = v
+	}
+	return t
+}
+
+// ReportedVRAMPerGPU returns the per-GPU memory the most recently
+// registered node of a GPU type reported, or 0 when none has
+func (r *Registry) ReportedVRAMPerGPU(ctx context.Context, gpuType string) (int, error) {
+	var perGPU int
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT vram_total_gb / gpu_count FROM nodes
+		WHERE gpu_type = $1 AND vram_total_gb > 0 AND gpu_count > 0
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`, gpuType).Scan(&perGPU)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return perGPU, err
+}
+
+// TunedVRAMPerGPU returns the per-GPU memory a node's vLLM settings were
+// tuned for, or 0 when unknown
+func (r *Registry) TunedVRAMPerGPU(ctx context.Context, nodeID uuid.UUID) (int, error) {
+	var tuned *int
+	err := r.db.Pool.QueryRow(ctx, `SELECT vllm_tuned_vram_gb FROM nodes WHERE id = $1`, nodeID).Scan(&tuned)
+	if errors.Is(err, pgx.ErrNoRows) || tuned == nil {
+		return 0, nil
+	}
+	return *tuned, err
+}
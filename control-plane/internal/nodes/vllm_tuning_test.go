@@ -0,0 +1,78 @@
+package nodes
+
+import "testing"
+
+func TestTuneVLLM(t *testing.T) {
+	tests := []struct {
+		name          string
+		modelGB       float64
+		vramPerGPUGB  int
+		gpuCount      int
+		contextLength int
+		want          VLLMTuning
+	}{
+		{
+			name:    "unknown VRAM keeps defaults",
+			modelGB: 16,
+			want:    DefaultVLLMTuning(),
+		},
+		{
+			name:         "unknown model size keeps defaults",
+			vramPerGPUGB: 80,
+			gpuCount:     1,
+			want:         VLLMTuning{GPUMemoryUtilization: 0.95, MaxModelLen: 32768, MaxNumSeqs: 256, VRAMPerGPUGB: 80},
+		},
+		{
+			name:         "8B on a 24 GB card leaves little cache",
+			modelGB:      16,
+			vramPerGPUGB: 24,
+			gpuCount:     1,
+			want:         VLLMTuning{GPUMemoryUtilization: 0.92, MaxModelLen: 4096, MaxNumSeqs: 24, VRAMPerGPUGB: 24},
+		},
+		{
+			name:          "8B on an 80 GB card is capped by its context window",
+			modelGB:       16,
+			vramPerGPUGB:  80,
+			gpuCount:      1,
+			contextLength: 8192,
+			want:          VLLMTuning{GPUMemoryUtilization: 0.95, MaxModelLen: 8192, MaxNumSeqs: 256, VRAMPerGPUGB: 80},
+		},
+		{
+			name:         "70B across eight 80 GB cards",
+			modelGB:      140,
+			vramPerGPUGB: 80,
+			gpuCount:     8,
+			want:         VLLMTuning{GPUMemoryUtilization: 0.95, MaxModelLen: 32768, MaxNumSeqs: 256, VRAMPerGPUGB: 80},
+		},
+		{
+			name:         "model barely fitting gets the minimums",
+			modelGB:      16,
+			vramPerGPUGB: 16,
+			gpuCount:     1,
+			want:         VLLMTuning{GPUMemoryUtilization: 0.90, MaxModelLen: minTunedModelLen, MaxNumSeqs: minTunedNumSeqs, VRAMPerGPUGB: 16},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TuneVLLM(tt.modelGB, tt.vramPerGPUGB, tt.gpuCount, tt.contextLength)
+			if got != tt.want {
+				t.Errorf("TuneVLLM() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVLLMTuningWithArgs(t *testing.T) {
+	tuning := VLLMTuning{GPUMemoryUtilization: 0.92, MaxModelLen: 4096, MaxNumSeqs: 24, VRAMPerGPUGB: 24}
+
+	got := tuning.WithArgs([]string{"--max-model-len", "2048", "--max-num-seqs=8", "--max-model-len", "8192", "--gpu-memory-utilization", "1.5"})
+	want := VLLMTuning{GPUMemoryUtilization: 0.92, MaxModelLen: 8192, MaxNumSeqs: 8, VRAMPerGPUGB: 24}
+	if got != want {
+		t.Errorf("WithArgs() = %+v, want %+v", got, want)
+	}
+
+	if got := tuning.WithArgs(nil); got != tuning {
+		t.Errorf("WithArgs(nil) = %+v, want %+v", got, tuning)
+	}
+}
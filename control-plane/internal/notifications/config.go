@@ -43,6 +43,9 @@ type Config struct {
 	// e.g., {"payment.succeeded": ["discord", "slack", "email"]}
 	EventRouting map[string][]string
 
+	// Weekly usage & spend digests (sent by email)
+	DigestEnabled bool
+
 	// General settings
 	Enabled           bool
 	AsyncDelivery     bool
@@ -85,6 +88,9 @@ func LoadConfig() (*Config, error) {
 		// Event routing
 		EventRouting: getEnvEventRouting("NOTIFICATIONS_EVENT_ROUTING"),
 
+		// Weekly digests
+		DigestEnabled: getEnvBool("NOTIFICATIONS_DIGEST_ENABLED", true),
+
 		// General settings
 		Enabled:         getEnvBool("NOTIFICATIONS_ENABLED", true),
 		AsyncDelivery:   getEnvBool("NOTIFICATIONS_ASYNC_DELIVERY", true),
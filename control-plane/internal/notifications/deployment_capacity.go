@@ -0,0 +1,129 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"go.uber.org/zap"
+)
+
+// handleDeploymentCapacity tells a tenant when its dedicated deployment has
+// been serving below its minimum capacity, as capacity changes and when it
+// recovers, by email and through the tenant's webhook. Ops channels receive
+// the same events through handleEvent. Shared deployments carry no tenant
+// and only reach ops.
+func (s *Service) handleDeploymentCapacity(ctx context.Context, event events.Event) error {
+	if event.TenantID == "" {
+		return nil
+	}
+
+	var errs []error
+	if s.email != nil {
+		if err := s.emailTenantCapacity(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := s.postTenantWebhook(ctx, event); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// emailTenantCapacity emails the capacity incident notice to the tenant
+func (s *Service) emailTenantCapacity(ctx context.Context, event events.Event) error {
+	to, err := s.tenantRecipients(ctx, event)
+	if err != nil || len(to) == 0 {
+		return err
+	}
+
+	subject, htmlBody, textBody := formatCapacityNotice(event)
+	start := time.Now()
+	if _, err := s.email.SendMessage(ctx, to, subject, htmlBody, textBody); err != nil {
+		s.metrics.RecordDelivery("tenant_email", string(event.Type), "failed", time.Since(start))
+		return fmt.Errorf("failed to email tenant about deployment capacity: %w", err)
+	}
+	s.metrics.RecordDelivery("tenant_email", string(event.Type), "success", time.Since(start))
+
+	s.logger.Info("notified tenant of deployment capacity",
+		zap.String("tenant_id", event.TenantID),
+		zap.String("event_type", string(event.Type)),
+		zap.String("incident_id", getStringField(event.Payload, "incident_id")),
+	)
+	return nil
+}
+
+// formatCapacityNotice renders the incident, update or recovery notice for a
+// deployment's capacity incident
+func formatCapacityNotice(event events.Event) (string, string, string) {
+	deployment := getStringField(event.Payload, "deployment_name")
+	if model := getStringField(event.Payload, "model"); model != "" {
+		deployment = fmt.Sprintf("%s (%s)", deployment, model)
+	}
+	serving := getIntField(event.Payload, "serving_replicas")
+	minimum := getIntField(event.Payload, "min_replicas")
+	since := getStringField(event.Payload, "started_at")
+
+	title := "⚠️ Deployment Capacity Degraded"
+	detail := fmt.Sprintf("Your deployment %s has been serving with %d of its minimum %d nodes since %s (UTC).",
+		deployment, serving, minimum, since)
+	action := "We are replacing the missing nodes. Requests may queue longer or be rate limited until capacity is restored; retry with backoff. We will email you when it recovers."
+	switch event.Type {
+	case events.EventDeploymentCapacityUpdated:
+		title = "🔄 Deployment Capacity Update"
+		detail = fmt.Sprintf("Your deployment %s is now serving with %d of its minimum %d nodes (previously %d). The incident started at %s (UTC).",
+			deployment, serving, minimum, getIntField(event.Payload, "previous_replicas"), since)
+		if serving < getIntField(event.Payload, "previous_replicas") {
+			action = "Capacity dropped further. We are replacing the missing nodes and will keep you updated."
+		} else {
+			action = "Capacity is recovering. We will email you once the deployment is back at its minimum."
+		}
+	case events.EventDeploymentCapacityRecovered:
+		title = "✅ Deployment Capacity Restored"
+		detail = fmt.Sprintf("Your deployment %s is back to %d serving nodes (minimum %d) after %s below its minimum. At its lowest it served with %d nodes.",
+			deployment, serving, minimum, getStringField(event.Payload, "duration"), getIntField(event.Payload, "lowest_replicas"))
+		action = "No action is needed."
+	}
+	subject := fmt.Sprintf("%s: %s - CrossLogic", title, getStringField(event.Payload, "deployment_name"))
+
+	htmlBody := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<body>
+			<h2>%s</h2>
+			<p>%s</p>
+			<p>%s</p>
+			<p><strong>Incident ID:</strong> %s</p>
+			<p>--<br>CrossLogic Notifications</p>
+		</body>
+		</html>
+	`, title, html.EscapeString(detail), action, html.EscapeString(getStringField(event.Payload, "incident_id")))
+
+	textBody := strings.TrimSpace(fmt.Sprintf(`%s
+
+%s
+
+%s
+
+Incident ID: %s`, title, detail, action, getStringField(event.Payload, "incident_id")))
+
+	return subject, htmlBody, textBody
+}
+
+// getIntField reads a number from the payload, which is a float64 once the
+// event has been through JSON
+func getIntField(payload map[string]interface{}, key string) int {
+	switch v := payload[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
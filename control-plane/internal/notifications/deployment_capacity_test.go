@@ -0,0 +1,45 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+)
+
+func TestFormatCapacityNotice(t *testing.T) {
+	payload := map[string]interface{}{
+		"incident_id":       "inc-1",
+		"deployment_name":   "llama-dedicated",
+		"model":             "llama-3-8b",
+		"min_replicas":      float64(3),
+		"serving_replicas":  float64(1),
+		"previous_replicas": float64(1),
+		"lowest_replicas":   float64(1),
+		"started_at":        "2025-01-10T12:00:00Z",
+		"duration":          "6m0s",
+	}
+
+	subject, htmlBody, textBody := formatCapacityNotice(events.NewEvent(events.EventDeploymentCapacityDegraded, "t-1", payload))
+	if !strings.Contains(subject, "Deployment Capacity Degraded: llama-dedicated") {
+		t.Errorf("subject = %q", subject)
+	}
+	if !strings.Contains(textBody, "llama-dedicated (llama-3-8b) has been serving with 1 of its minimum 3 nodes since 2025-01-10T12:00:00Z") {
+		t.Errorf("text body = %q", textBody)
+	}
+	if !strings.Contains(htmlBody, "inc-1") || !strings.Contains(textBody, "Incident ID: inc-1") {
+		t.Errorf("incident id missing")
+	}
+
+	payload["serving_replicas"] = float64(2)
+	subject, _, textBody = formatCapacityNotice(events.NewEvent(events.EventDeploymentCapacityUpdated, "t-1", payload))
+	if !strings.Contains(subject, "Capacity Update") || !strings.Contains(textBody, "2 of its minimum 3 nodes (previously 1)") || !strings.Contains(textBody, "recovering") {
+		t.Errorf("update = %q / %q", subject, textBody)
+	}
+
+	payload["serving_replicas"] = float64(3)
+	subject, _, textBody = formatCapacityNotice(events.NewEvent(events.EventDeploymentCapacityRecovered, "t-1", payload))
+	if !strings.Contains(subject, "Restored") || !strings.Contains(textBody, "after 6m0s below its minimum") {
+		t.Errorf("recovery = %q / %q", subject, textBody)
+	}
+}
@@ -0,0 +1,192 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/events"
+	"go.uber.org/zap"
+)
+
+// deprecationMilestones are the reminder points before a model's sunset date.
+// Each tenant receives at most one reminder per milestone.
+var deprecationMilestones = []struct {
+	Label  string
+	Before time.Duration
+}{
+	{Label: "30d", Before: 30 * 24 * time.Hour},
+	{Label: "7d", Before: 7 * 24 * time.Hour},
+	{Label: "1d", Before: 24 * time.Hour},
+}
+
+// deprecationUsageWindow is how far back usage is checked to decide whether a
+// tenant is still using a deprecated model
+const deprecationUsageWindow = 7 * 24 * time.Hour
+
+// DeprecationReminder periodically reminds tenants that are still using a
+// deprecated model before its sunset date
+type DeprecationReminder struct {
+	db       *database.Database
+	logger   *zap.Logger
+	bus      *events.Bus
+	interval time.Duration
+}
+
+// NewDeprecationReminder creates a new deprecation reminder job
+func NewDeprecationReminder(db *database.Database, logger *zap.Logger, bus *events.Bus) *DeprecationReminder {
+	return &DeprecationReminder{
+		db:       db,
+		logger:   logger,
+		bus:      bus,
+		interval: 1 * time.Hour, // Milestones are day-granular; hourly keeps latency low
+	}
+}
+
+// Start begins the reminder loop
+func (d *DeprecationReminder) Start(ctx context.Context) {
+	d.logger.Info("starting model deprecation reminder")
+	go d.reminderLoop(ctx)
+}
+
+// reminderLoop periodically sends due reminders
+func (d *DeprecationReminder) reminderLoop(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	d.sendDueReminders(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.sendDueReminders(ctx)
+		}
+	}
+}
+
+type deprecatedModel struct {
+	id               string
+	name             string
+	sunsetAt         time.Time
+	replacementModel *string
+	notice           *string
+}
+
+// dueMilestone returns the most recent milestone that has been reached for a
+// sunset date, or "" if none is due yet or the model is already sunset.
+func dueMilestone(now, sunsetAt time.Time) string {
+	if !now.Before(sunsetAt) {
+		return ""
+	}
+	remaining := sunsetAt.Sub(now)
+	due := ""
+	for _, m := range deprecationMilestones {
+		if remaining <= m.Before {
+			due = m.Label
+		}
+	}
+	return due
+}
+
+// sendDueReminders finds deprecated models with an upcoming sunset and
+// notifies tenants that still use them
+func (d *DeprecationReminder) sendDueReminders(ctx context.Context) {
+	rows, err := d.db.Pool.Query(ctx, `
+		SELECT id, name, sunset_at, replacement_model, deprecation_notice
+		FROM models
+		WHERE status = 'deprecated' AND sunset_at IS NOT NULL AND sunset_at > NOW()
+	`)
+	if err != nil {
+		d.logger.Error("failed to fetch deprecated models", zap.Error(err))
+		return
+	}
+
+	var deprecated []deprecatedModel
+	for rows.Next() {
+		var m deprecatedModel
+		if err := rows.Scan(&m.id, &m.name, &m.sunsetAt, &m.replacementModel, &m.notice); err != nil {
+			continue
+		}
+		deprecated = append(deprecated, m)
+	}
+	rows.Close()
+
+	now := time.Now()
+	for _, m := range deprecated {
+		milestone := dueMilestone(now, m.sunsetAt)
+		if milestone == "" {
+			continue
+		}
+		if err := d.remindTenants(ctx, m, milestone); err != nil {
+			d.logger.Error("failed to send deprecation reminders",
+				zap.String("model", m.name),
+				zap.String("milestone", milestone),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// remindTenants records and publishes a reminder for every tenant that used
+// the model recently and has not yet been reminded at this milestone
+func (d *DeprecationReminder) remindTenants(ctx context.Context, m deprecatedModel, milestone string) error {
+	rows, err := d.db.Pool.Query(ctx, `
+		INSERT INTO model_deprecation_reminders (model_id, tenant_id, milestone)
+		SELECT DISTINCT $1::uuid, ur.tenant_id, $2
+		FROM usage_records ur
+		JOIN tenants t ON t.id = ur.tenant_id
+		WHERE ur.model_id = $1 AND ur.timestamp > $3 AND t.status = 'active'
+		ON CONFLICT (model_id, tenant_id, milestone) DO NOTHING
+		RETURNING tenant_id
+	`, m.id, milestone, time.Now().Add(-deprecationUsageWindow))
+	if err != nil {
+		return fmt.Errorf("failed to record reminders: %w", err)
+	}
+
+	var tenantIDs []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			continue
+		}
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	rows.Close()
+
+	for _, tenantID := range tenantIDs {
+		payload := map[string]interface{}{
+			"model_id":  m.id,
+			"model":     m.name,
+			"milestone": milestone,
+			"sunset_at": m.sunsetAt.UTC().Format(time.RFC3339),
+		}
+		if m.replacementModel != nil {
+			payload["replacement_model"] = *m.replacementModel
+		}
+		if m.notice != nil {
+			payload["notice"] = *m.notice
+		}
+
+		evt := events.NewEvent(events.EventModelDeprecationReminder, tenantID, payload)
+		if err := d.bus.Publish(ctx, evt); err != nil {
+			d.logger.Error("failed to publish deprecation reminder",
+				zap.String("tenant_id", tenantID),
+				zap.String("model", m.name),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if len(tenantIDs) > 0 {
+		d.logger.Info("sent model deprecation reminders",
+			zap.String("model", m.name),
+			zap.String("milestone", milestone),
+			zap.Int("tenants", len(tenantIDs)),
+		)
+	}
+	return nil
+}
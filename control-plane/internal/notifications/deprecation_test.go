@@ -0,0 +1,30 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDueMilestone(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		sunsetAt time.Time
+		want     string
+	}{
+		{"far future", now.Add(60 * 24 * time.Hour), ""},
+		{"within 30 days", now.Add(20 * 24 * time.Hour), "30d"},
+		{"within 7 days", now.Add(3 * 24 * time.Hour), "7d"},
+		{"within 1 day", now.Add(2 * time.Hour), "1d"},
+		{"already sunset", now.Add(-time.Hour), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dueMilestone(now, tt.sunsetAt); got != tt.want {
+				t.Errorf("dueMilestone() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,766 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Weekly digests.
+//
+// Every Monday each active tenant with usage in the previous week gets an
+// email summarising requests, tokens, spend, error rate and top models, plus
+// month-to-date spend against their budget when one is set and, for tenants
+// running their own spot instances, what spot saved them over on-demand. Ops recipients
+// (NOTIFICATIONS_EMAIL_TO) get a fleet-wide variant with top tenants.
+//
+// Tenant preferences live in notification_config (channel 'email'): a
+// disabled row opts the tenant out, event_types can exclude
+// "report.weekly_digest", destination overrides the account email (comma
+// separated), and settings.monthly_budget_usd sets the budget.
+const (
+	weeklyDigestEventType = "report.weekly_digest"
+	fleetDigestEventType  = "report.fleet_digest"
+
+	// digestSendDelay gives late usage records time to land before a week is
+	// reported; digests go out Monday 08:00 UTC
+	digestSendDelay = 8 * time.Hour
+
+	// digestTopN bounds the top models / top tenants tables
+	digestTopN = 5
+)
+
+// DigestReporter sends weekly usage & spend digests by email
+type DigestReporter struct {
+	db              *database.Database
+	email           *EmailAdapter
+	adminRecipients []string
+	metrics         *Metrics
+	logger          *zap.Logger
+	interval        time.Duration
+}
+
+// NewDigestReporter creates a new weekly digest job
+func NewDigestReporter(db *database.Database, email *EmailAdapter, adminRecipients []string, metrics *Metrics, logger *zap.Logger) *DigestReporter {
+	return &DigestReporter{
+		db:              db,
+		email:           email,
+		adminRecipients: adminRecipients,
+		metrics:         metrics,
+		logger:          logger,
+		interval:        1 * time.Hour, // Sends are idempotent per week; hourly retries failures
+	}
+}
+
+// Start begins the digest loop
+func (d *DigestReporter) Start(ctx context.Context) {
+	d.logger.Info("starting weekly digest reporter")
+	go d.digestLoop(ctx)
+}
+
+// digestLoop periodically sends any digests that are due
+func (d *DigestReporter) digestLoop(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	d.sendDueDigests(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.sendDueDigests(ctx)
+		}
+	}
+}
+
+// digestPeriod returns the most recent Monday-to-Monday UTC week whose digest
+// is due at now
+func digestPeriod(now time.Time) (start, end time.Time) {
+	shifted := now.UTC().Add(-digestSendDelay)
+	daysSinceMonday := (int(shifted.Weekday()) + 6) % 7
+	end = time.Date(shifted.Year(), shifted.Month(), shifted.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+	return end.AddDate(0, 0, -7), end
+}
+
+// sendDueDigests sends the fleet digest and every tenant digest for the last
+// completed week that has not been sent yet
+func (d *DigestReporter) sendDueDigests(ctx context.Context) {
+	start, end := digestPeriod(time.Now())
+
+	if len(d.adminRecipients) > 0 {
+		if err := d.sendFleetDigest(ctx, start, end); err != nil {
+			d.logger.Error("failed to send fleet digest", zap.Time("period_start", start), zap.Error(err))
+		}
+	}
+
+	prefs, err := d.loadPreferences(ctx)
+	if err != nil {
+		d.logger.Error("failed to load digest preferences", zap.Error(err))
+		return
+	}
+
+	sent := 0
+	for _, p := range prefs {
+		ok, err := d.sendTenantDigest(ctx, p, start, end)
+		if err != nil {
+			d.logger.Error("failed to send tenant digest",
+				zap.String("tenant_id", p.TenantID),
+				zap.Time("period_start", start),
+				zap.Error(err),
+			)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	if sent > 0 {
+		d.logger.Info("sent weekly tenant digests",
+			zap.Time("period_start", start),
+			zap.Int("tenants", sent),
+		)
+	}
+}
+
+// digestPreferences is a tenant's email preferences for digests
+type digestPreferences struct {
+	TenantID    string
+	TenantName  string
+	TenantEmail string
+	HasConfig   bool
+	Enabled     bool
+	Destination *string
+	EventTypes  []string
+	Settings    []byte
+}
+
+// recipients returns who should receive the tenant's digest, or nil if the
+// tenant opted out
+func (p digestPreferences) recipients() []string {
+	return p.recipientsFor(weeklyDigestEventType)
+}
+
+// recipientsFor returns who should receive tenant email for eventType, or
+// nil if the tenant opted out of it
+func (p digestPreferences) recipientsFor(eventType string) []string {
+	if p.HasConfig {
+		if !p.Enabled {
+			return nil
+		}
+		if p.EventTypes != nil && !containsString(p.EventTypes, eventType) {
+			return nil
+		}
+		if p.Destination != nil {
+			var to []string
+			for _, addr := range strings.Split(*p.Destination, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					to = append(to, addr)
+				}
+			}
+			if len(to) > 0 {
+				return to
+			}
+		}
+	}
+	if p.TenantEmail == "" {
+		return nil
+	}
+	return []string{p.TenantEmail}
+}
+
+// monthlyBudgetMicros returns the tenant's monthly budget, or 0 if none is set
+func (p digestPreferences) monthlyBudgetMicros() int64 {
+	if len(p.Settings) == 0 {
+		return 0
+	}
+	var settings struct {
+		MonthlyBudgetUSD float64 `json:"monthly_budget_usd"`
+	}
+	if err := json.Unmarshal(p.Settings, &settings); err != nil || settings.MonthlyBudgetUSD <= 0 {
+		return 0
+	}
+	return int64(settings.MonthlyBudgetUSD * 1_000_000)
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// loadPreferences returns digest preferences for all active tenants
+func (d *DigestReporter) loadPreferences(ctx context.Context) ([]digestPreferences, error) {
+	rows, err := d.db.Pool.Query(ctx, `
+		SELECT t.id, t.name, t.email,
+		       nc.id IS NOT NULL, COALESCE(nc.enabled, true), nc.destination, nc.event_types, nc.settings
+		FROM tenants t
+		LEFT JOIN notification_config nc ON nc.tenant_id = t.id AND nc.channel = 'email'
+		WHERE t.status = 'active'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prefs []digestPreferences
+	for rows.Next() {
+		var p digestPreferences
+		if err := rows.Scan(&p.TenantID, &p.TenantName, &p.TenantEmail,
+			&p.HasConfig, &p.Enabled, &p.Destination, &p.EventTypes, &p.Settings); err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, rows.Err()
+}
+
+// digestUsage is aggregate usage over a period
+type digestUsage struct {
+	Requests   int64
+	Tokens     int64
+	Errors     int64
+	CostMicros int64
+}
+
+// ErrorRate is the share of requests that returned a 4xx/5xx status
+func (u digestUsage) ErrorRate() float64 {
+	if u.Requests == 0 {
+		return 0
+	}
+	return float64(u.Errors) / float64(u.Requests)
+}
+
+// digestRow is one line of a top models / top tenants table
+type digestRow struct {
+	Name       string
+	Requests   int64
+	Tokens     int64
+	CostMicros int64
+}
+
+// digestReport is the data behind a single digest email
+type digestReport struct {
+	Heading       string
+	PeriodStart   time.Time
+	PeriodEnd     time.Time
+	Usage         digestUsage
+	PreviousUsage digestUsage
+	TopModels     []digestRow
+
+	// Tenant digests only
+	MonthToDateMicros int64
+	BudgetMicros      int64
+	Savings           *billing.SavingsReport
+
+	// Fleet digest only
+	Fleet         bool
+	TopTenants    []digestRow
+	ActiveTenants int64
+	ActiveNodes   int64
+}
+
+// queryUsage aggregates usage over [start, end), for one tenant or (with an
+// empty tenantID) the whole fleet
+func (d *DigestReporter) queryUsage(ctx context.Context, tenantID string, start, end time.Time) (digestUsage, error) {
+	var u digestUsage
+	err := d.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(total_tokens), 0), COUNT(*) FILTER (WHERE status_code >= 400),
+		       COALESCE(SUM(cost_microdollars), 0)
+		FROM usage_records
+		WHERE ($1 = '' OR tenant_id = NULLIF($1, '')::uuid) AND timestamp >= $2 AND timestamp < $3
+	`, tenantID, start, end).Scan(&u.Requests, &u.Tokens, &u.Errors, &u.CostMicros)
+	return u, err
+}
+
+// queryTopModels returns the models with the most tokens over [start, end)
+func (d *DigestReporter) queryTopModels(ctx context.Context, tenantID string, start, end time.Time) ([]digestRow, error) {
+	rows, err := d.db.Pool.Query(ctx, `
+		SELECT COALESCE(m.name, 'unknown'), COUNT(*), COALESCE(SUM(ur.total_tokens), 0), COALESCE(SUM(ur.cost_microdollars), 0)
+		FROM usage_records ur
+		LEFT JOIN models m ON m.id = ur.model_id
+		WHERE ($1 = '' OR ur.tenant_id = NULLIF($1, '')::uuid) AND ur.timestamp >= $2 AND ur.timestamp < $3
+		GROUP BY 1
+		ORDER BY 3 DESC
+		LIMIT $4
+	`, tenantID, start, end, digestTopN)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var top []digestRow
+	for rows.Next() {
+		var r digestRow
+		if err := rows.Scan(&r.Name, &r.Requests, &r.Tokens, &r.CostMicros); err != nil {
+			return nil, err
+		}
+		top = append(top, r)
+	}
+	return top, rows.Err()
+}
+
+// querySavings returns the tenant's spot savings for the week, or nil when
+// it ran no priced spot instances
+func (d *DigestReporter) querySavings(ctx context.Context, tenantID string, start, end time.Time) (*billing.SavingsReport, error) {
+	id, err := uuid.Parse(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	savings, err := billing.GetSavingsReport(ctx, d.db, id, start, end)
+	if err != nil || savings.SpotOnDemandEquivalent <= 0 {
+		return nil, err
+	}
+	return savings, nil
+}
+
+// sendTenantDigest sends one tenant's digest. It reports false when nothing
+// was sent (opted out, no usage, or already sent).
+func (d *DigestReporter) sendTenantDigest(ctx context.Context, p digestPreferences, start, end time.Time) (bool, error) {
+	to := p.recipients()
+	if to == nil {
+		return false, nil
+	}
+
+	usage, err := d.queryUsage(ctx, p.TenantID, start, end)
+	if err != nil {
+		return false, fmt.Errorf("failed to query usage: %w", err)
+	}
+	if usage.Requests == 0 {
+		return false, nil
+	}
+
+	claimed, err := d.claim(ctx, weeklyDigestEventType, p.TenantID, start, end)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	report := &digestReport{
+		Heading:      p.TenantName,
+		PeriodStart:  start,
+		PeriodEnd:    end,
+		Usage:        usage,
+		BudgetMicros: p.monthlyBudgetMicros(),
+	}
+	if report.PreviousUsage, err = d.queryUsage(ctx, p.TenantID, start.AddDate(0, 0, -7), start); err == nil {
+		report.TopModels, err = d.queryTopModels(ctx, p.TenantID, start, end)
+	}
+	if err == nil {
+		var mtd digestUsage
+		mtd, err = d.queryUsage(ctx, p.TenantID, monthStart(end.Add(-time.Nanosecond)), end)
+		report.MonthToDateMicros = mtd.CostMicros
+	}
+	if err == nil {
+		report.Savings, err = d.querySavings(ctx, p.TenantID, start, end)
+	}
+	if err != nil {
+		d.release(ctx, weeklyDigestEventType, p.TenantID, start)
+		return false, fmt.Errorf("failed to build digest: %w", err)
+	}
+
+	if err := d.deliver(ctx, weeklyDigestEventType, p.TenantID, start, to, report); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sendFleetDigest sends the admin fleet-wide digest
+func (d *DigestReporter) sendFleetDigest(ctx context.Context, start, end time.Time) error {
+	claimed, err := d.claim(ctx, fleetDigestEventType, "fleet", start, end)
+	if err != nil || !claimed {
+		return err
+	}
+
+	report, err := d.buildFleetReport(ctx, start, end)
+	if err != nil {
+		d.release(ctx, fleetDigestEventType, "fleet", start)
+		return fmt.Errorf("failed to build fleet digest: %w", err)
+	}
+
+	return d.deliver(ctx, fleetDigestEventType, "fleet", start, d.adminRecipients, report)
+}
+
+func (d *DigestReporter) buildFleetReport(ctx context.Context, start, end time.Time) (*digestReport, error) {
+	report := &digestReport{
+		Heading:     "CrossLogic fleet",
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Fleet:       true,
+	}
+
+	var err error
+	if report.Usage, err = d.queryUsage(ctx, "", start, end); err != nil {
+		return nil, err
+	}
+	if report.PreviousUsage, err = d.queryUsage(ctx, "", start.AddDate(0, 0, -7), start); err != nil {
+		return nil, err
+	}
+	if report.TopModels, err = d.queryTopModels(ctx, "", start, end); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Pool.Query(ctx, `
+		SELECT t.name, COUNT(*), COALESCE(SUM(ur.total_tokens), 0), COALESCE(SUM(ur.cost_microdollars), 0)
+		FROM usage_records ur
+		JOIN tenants t ON t.id = ur.tenant_id
+		WHERE ur.timestamp >= $1 AND ur.timestamp < $2
+		GROUP BY t.id, t.name
+		ORDER BY 4 DESC
+		LIMIT $3
+	`, start, end, digestTopN)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var r digestRow
+		if err := rows.Scan(&r.Name, &r.Requests, &r.Tokens, &r.CostMicros); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.TopTenants = append(report.TopTenants, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = d.db.Pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(DISTINCT tenant_id) FROM usage_records WHERE timestamp >= $1 AND timestamp < $2),
+			(SELECT COUNT(*) FROM nodes WHERE status = 'active')
+	`, start, end).Scan(&report.ActiveTenants, &report.ActiveNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// deliver renders and emails a digest, then marks it sent. Failed sends
+// release the claim so the next run retries.
+func (d *DigestReporter) deliver(ctx context.Context, reportType, scope string, start time.Time, to []string, report *digestReport) error {
+	startTime := time.Now()
+
+	subject := digestSubject(report)
+	htmlBody, textBody, err := renderDigest(report)
+	if err != nil {
+		d.release(ctx, reportType, scope, start)
+		return fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	emailID, err := d.email.SendMessage(ctx, to, subject, htmlBody, textBody)
+	if err != nil {
+		d.metrics.RecordDelivery("email", reportType, "failed", time.Since(startTime))
+		d.release(ctx, reportType, scope, start)
+		return err
+	}
+	d.metrics.RecordDelivery("email", reportType, "success", time.Since(startTime))
+
+	_, err = d.db.Pool.Exec(ctx, `
+		UPDATE report_deliveries
+		SET status = 'sent', recipients = $4, email_id = $5, sent_at = NOW()
+		WHERE report_type = $1 AND scope = $2 AND period_start = $3
+	`, reportType, scope, start, to, emailID)
+	if err != nil {
+		d.logger.Error("failed to mark digest sent",
+			zap.String("report_type", reportType),
+			zap.String("scope", scope),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// claim records that a digest is being sent so it goes out at most once per
+// period across replicas. A claim left in 'sending' for over an hour (crashed
+// sender) can be taken over.
+func (d *DigestReporter) claim(ctx context.Context, reportType, scope string, start, end time.Time) (bool, error) {
+	rows, err := d.db.Pool.Query(ctx, `
+		INSERT INTO report_deliveries (report_type, scope, period_start, period_end)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (report_type, scope, period_start) DO UPDATE SET created_at = NOW()
+		WHERE report_deliveries.status = 'sending' AND report_deliveries.created_at < NOW() - INTERVAL '1 hour'
+		RETURNING id
+	`, reportType, scope, start, end)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim digest: %w", err)
+	}
+	defer rows.Close()
+
+	claimed := rows.Next()
+	return claimed, rows.Err()
+}
+
+// release drops a claim so the digest is retried on the next run
+func (d *DigestReporter) release(ctx context.Context, reportType, scope string, start time.Time) {
+	_, err := d.db.Pool.Exec(ctx, `
+		DELETE FROM report_deliveries
+		WHERE report_type = $1 AND scope = $2 AND period_start = $3 AND status = 'sending'
+	`, reportType, scope, start)
+	if err != nil {
+		d.logger.Error("failed to release digest claim", zap.String("scope", scope), zap.Error(err))
+	}
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Rendering
+
+// digestView is the preformatted data the digest templates render
+type digestView struct {
+	Heading     string
+	Period      string
+	Requests    string
+	Tokens      string
+	Spend       string
+	SpendChange string
+	ErrorRate   string
+	TopModels   []digestRowView
+
+	Budget  *digestBudgetView
+	Savings *digestSavingsView
+
+	Fleet         bool
+	TopTenants    []digestRowView
+	ActiveTenants string
+	ActiveNodes   string
+}
+
+type digestRowView struct {
+	Name     string
+	Requests string
+	Tokens   string
+	Spend    string
+}
+
+type digestSavingsView struct {
+	SpotSpend string
+	OnDemand  string
+	Saved     string
+	Percent   string
+	SpotHours string
+}
+
+type digestBudgetView struct {
+	MonthToDate string
+	Budget      string
+	Used        string
+	Over        bool
+}
+
+func digestSubject(report *digestReport) string {
+	period := report.PeriodStart.Format("Jan 2")
+	if report.Fleet {
+		return fmt.Sprintf("📊 Weekly Fleet Digest (week of %s) - CrossLogic", period)
+	}
+	return fmt.Sprintf("📊 Your weekly usage for %s (week of %s) - CrossLogic", report.Heading, period)
+}
+
+func buildDigestView(report *digestReport) digestView {
+	view := digestView{
+		Heading: report.Heading,
+		Period: fmt.Sprintf("%s – %s",
+			report.PeriodStart.Format("Jan 2, 2006"),
+			report.PeriodEnd.AddDate(0, 0, -1).Format("Jan 2, 2006")),
+		Requests:    formatCount(report.Usage.Requests),
+		Tokens:      formatCount(report.Usage.Tokens),
+		Spend:       formatUSD(report.Usage.CostMicros),
+		SpendChange: percentChange(report.Usage.CostMicros, report.PreviousUsage.CostMicros),
+		ErrorRate:   fmt.Sprintf("%.2f%%", report.Usage.ErrorRate()*100),
+		TopModels:   digestRowViews(report.TopModels),
+		Fleet:       report.Fleet,
+	}
+
+	if report.BudgetMicros > 0 {
+		view.Budget = &digestBudgetView{
+			MonthToDate: formatUSD(report.MonthToDateMicros),
+			Budget:      formatUSD(report.BudgetMicros),
+			Used:        fmt.Sprintf("%.0f%%", float64(report.MonthToDateMicros)/float64(report.BudgetMicros)*100),
+			Over:        report.MonthToDateMicros > report.BudgetMicros,
+		}
+	}
+
+	if s := report.Savings; s != nil {
+		view.Savings = &digestSavingsView{
+			SpotSpend: formatDollars(s.SpotSpend),
+			OnDemand:  formatDollars(s.SpotOnDemandEquivalent),
+			Saved:     formatDollars(s.Savings),
+			Percent:   fmt.Sprintf("%.0f%%", s.SavingsPercent),
+			SpotHours: fmt.Sprintf("%.1f", s.SpotHours),
+		}
+	}
+
+	if report.Fleet {
+		view.TopTenants = digestRowViews(report.TopTenants)
+		view.ActiveTenants = formatCount(report.ActiveTenants)
+		view.ActiveNodes = formatCount(report.ActiveNodes)
+	}
+	return view
+}
+
+func digestRowViews(rows []digestRow) []digestRowView {
+	views := make([]digestRowView, 0, len(rows))
+	for _, r := range rows {
+		views = append(views, digestRowView{
+			Name:     r.Name,
+			Requests: formatCount(r.Requests),
+			Tokens:   formatCount(r.Tokens),
+			Spend:    formatUSD(r.CostMicros),
+		})
+	}
+	return views
+}
+
+// formatUSD formats microdollars as dollars
+func formatUSD(micros int64) string {
+	return fmt.Sprintf("$%.2f", float64(micros)/1_000_000)
+}
+
+// formatDollars formats a dollar amount
+func formatDollars(usd float64) string {
+	return fmt.Sprintf("$%.2f", usd)
+}
+
+// formatCount formats an integer with thousands separators
+func formatCount(n int64) string {
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	s := fmt.Sprintf("%d", n)
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// percentChange describes the week-over-week change, or "" with no baseline
+func percentChange(current, previous int64) string {
+	if previous == 0 {
+		return ""
+	}
+	change := (float64(current) - float64(previous)) / float64(previous) * 100
+	return fmt.Sprintf("%+.1f%% vs previous week", change)
+}
+
+// renderDigest renders the HTML and plain-text bodies of a digest
+func renderDigest(report *digestReport) (htmlBody, textBody string, err error) {
+	view := buildDigestView(report)
+
+	htmlBody, err = renderTemplate(digestHTMLTemplate, view)
+	if err != nil {
+		return "", "", err
+	}
+
+	t, err := texttemplate.New("digest").Parse(digestTextTemplate)
+	if err != nil {
+		return "", "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, view); err != nil {
+		return "", "", err
+	}
+	return htmlBody, buf.String(), nil
+}
+
+const digestHTMLTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.header { background-color: #2196F3; color: white; padding: 20px; text-align: center; }
+		.content { background-color: #f9f9f9; padding: 20px; margin-top: 20px; }
+		.field { margin-bottom: 10px; }
+		.label { font-weight: bold; color: #555; }
+		.value { color: #333; }
+		.warning { background-color: #fff3cd; padding: 15px; border-left: 4px solid #FF9800; margin-bottom: 20px; }
+		table { width: 100%; border-collapse: collapse; margin-top: 10px; }
+		th, td { text-align: left; padding: 6px; border-bottom: 1px solid #ddd; }
+		.footer { text-align: center; margin-top: 30px; color: #888; font-size: 12px; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<div class="header">
+			<h1>📊 Weekly Digest</h1>
+			<p>{{.Heading}} · {{.Period}}</p>
+		</div>
+		<div class="content">
+			{{if .Budget}}{{if .Budget.Over}}<div class="warning"><strong>Over budget:</strong> month-to-date spend of {{.Budget.MonthToDate}} exceeds your {{.Budget.Budget}} monthly budget.</div>{{end}}{{end}}
+			<div class="field"><span class="label">Requests:</span> <span class="value">{{.Requests}}</span></div>
+			<div class="field"><span class="label">Tokens:</span> <span class="value">{{.Tokens}}</span></div>
+			<div class="field"><span class="label">Spend:</span> <span class="value">{{.Spend}}{{if .SpendChange}} ({{.SpendChange}}){{end}}</span></div>
+			<div class="field"><span class="label">Error Rate:</span> <span class="value">{{.ErrorRate}}</span></div>
+			{{if .Budget}}<div class="field"><span class="label">Month-to-date Spend:</span> <span class="value">{{.Budget.MonthToDate}} of {{.Budget.Budget}} budget ({{.Budget.Used}})</span></div>{{end}}
+			{{if .Savings}}<div class="field"><span class="label">Spot Savings:</span> <span class="value">{{.Savings.Saved}} ({{.Savings.Percent}}) — {{.Savings.SpotHours}} spot hours cost {{.Savings.SpotSpend}} vs {{.Savings.OnDemand}} on-demand</span></div>{{end}}
+			{{if .Fleet}}<div class="field"><span class="label">Active Tenants:</span> <span class="value">{{.ActiveTenants}}</span></div>
+			<div class="field"><span class="label">Active Nodes:</span> <span class="value">{{.ActiveNodes}}</span></div>{{end}}
+			{{if .TopModels}}<h3>Top Models</h3>
+			<table>
+				<tr><th>Model</th><th>Requests</th><th>Tokens</th><th>Spend</th></tr>
+				{{range .TopModels}}<tr><td>{{.Name}}</td><td>{{.Requests}}</td><td>{{.Tokens}}</td><td>{{.Spend}}</td></tr>
+				{{end}}
+			</table>{{end}}
+			{{if .TopTenants}}<h3>Top Tenants</h3>
+			<table>
+				<tr><th>Tenant</th><th>Requests</th><th>Tokens</th><th>Spend</th></tr>
+				{{range .TopTenants}}<tr><td>{{.Name}}</td><td>{{.Requests}}</td><td>{{.Tokens}}</td><td>{{.Spend}}</td></tr>
+				{{end}}
+			</table>{{end}}
+		</div>
+		<div class="footer">
+			<p>CrossLogic Notifications</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+const digestTextTemplate = `Weekly Digest - {{.Heading}}
+{{.Period}}
+{{if .Budget}}{{if .Budget.Over}}
+OVER BUDGET: month-to-date spend of {{.Budget.MonthToDate}} exceeds your {{.Budget.Budget}} monthly budget.
+{{end}}{{end}}
+Requests: {{.Requests}}
+Tokens: {{.Tokens}}
+Spend: {{.Spend}}{{if .SpendChange}} ({{.SpendChange}}){{end}}
+Error Rate: {{.ErrorRate}}
+{{- if .Budget}}
+Month-to-date Spend: {{.Budget.MonthToDate}} of {{.Budget.Budget}} budget ({{.Budget.Used}}){{end}}
+{{- if .Savings}}
+Spot Savings: {{.Savings.Saved}} ({{.Savings.Percent}}) - {{.Savings.SpotHours}} spot hours cost {{.Savings.SpotSpend}} vs {{.Savings.OnDemand}} on-demand{{end}}
+{{- if .Fleet}}
+Active Tenants: {{.ActiveTenants}}
+Active Nodes: {{.ActiveNodes}}{{end}}
+{{if .TopModels}}
+Top Models:
+{{range .TopModels}}  {{.Name}}: {{.Requests}} requests, {{.Tokens}} tokens, {{.Spend}}
+{{end}}{{end}}{{if .TopTenants}}
+Top Tenants:
+{{range .TopTenants}}  {{.Name}}: {{.Requests}} requests, {{.Tokens}} tokens, {{.Spend}}
+{{end}}{{end}}
+--
+CrossLogic Notifications`
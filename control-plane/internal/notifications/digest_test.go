@@ -0,0 +1,156 @@
+package notifications
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/billing"
+)
+
+func TestDigestPeriod(t *testing.T) {
+	// Week of Monday 2025-01-13
+	start := time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantStart time.Time
+	}{
+		{"monday after send time", time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC), start},
+		{"later in the week", time.Date(2025, 1, 24, 12, 0, 0, 0, time.UTC), start},
+		{"sunday night", time.Date(2025, 1, 26, 23, 0, 0, 0, time.UTC), start},
+		{"monday before send time", time.Date(2025, 1, 20, 7, 0, 0, 0, time.UTC), start.AddDate(0, 0, -7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStart, gotEnd := digestPeriod(tt.now)
+			if !gotStart.Equal(tt.wantStart) || !gotEnd.Equal(tt.wantStart.AddDate(0, 0, 7)) {
+				t.Errorf("digestPeriod() = %v - %v, want %v - %v", gotStart, gotEnd, tt.wantStart, tt.wantStart.AddDate(0, 0, 7))
+			}
+		})
+	}
+}
+
+func TestDigestPreferencesRecipients(t *testing.T) {
+	dest := "a@example.com, b@example.com"
+
+	tests := []struct {
+		name  string
+		prefs digestPreferences
+		want  []string
+	}{
+		{"no config uses account email", digestPreferences{TenantEmail: "owner@example.com"}, []string{"owner@example.com"}},
+		{"disabled config opts out", digestPreferences{TenantEmail: "owner@example.com", HasConfig: true}, nil},
+		{
+			"event filter without digest opts out",
+			digestPreferences{TenantEmail: "owner@example.com", HasConfig: true, Enabled: true, EventTypes: []string{"payment.succeeded"}},
+			nil,
+		},
+		{
+			"event filter with digest",
+			digestPreferences{TenantEmail: "owner@example.com", HasConfig: true, Enabled: true, EventTypes: []string{weeklyDigestEventType}},
+			[]string{"owner@example.com"},
+		},
+		{
+			"destination overrides account email",
+			digestPreferences{TenantEmail: "owner@example.com", HasConfig: true, Enabled: true, Destination: &dest},
+			[]string{"a@example.com", "b@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.prefs.recipients(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recipients() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestPreferencesMonthlyBudget(t *testing.T) {
+	tests := []struct {
+		settings string
+		want     int64
+	}{
+		{"", 0},
+		{`{}`, 0},
+		{`{"monthly_budget_usd": 250.5}`, 250_500_000},
+		{`{"monthly_budget_usd": -1}`, 0},
+		{`{"monthly_budget_usd": "lots"}`, 0},
+	}
+
+	for _, tt := range tests {
+		p := digestPreferences{Settings: []byte(tt.settings)}
+		if got := p.monthlyBudgetMicros(); got != tt.want {
+			t.Errorf("monthlyBudgetMicros(%q) = %d, want %d", tt.settings, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	tests := map[int64]string{
+		0:       "0",
+		999:     "999",
+		1000:    "1,000",
+		1234567: "1,234,567",
+		-12345:  "-12,345",
+	}
+	for n, want := range tests {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestRenderDigest(t *testing.T) {
+	report := &digestReport{
+		Heading:           "Acme <Corp>",
+		PeriodStart:       time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:         time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+		Usage:             digestUsage{Requests: 2000, Tokens: 1500000, Errors: 20, CostMicros: 12_500_000},
+		PreviousUsage:     digestUsage{CostMicros: 10_000_000},
+		TopModels:         []digestRow{{Name: "llama-3-8b", Requests: 2000, Tokens: 1500000, CostMicros: 12_500_000}},
+		MonthToDateMicros: 60_000_000,
+		BudgetMicros:      50_000_000,
+	}
+
+	htmlBody, textBody, err := renderDigest(report)
+	if err != nil {
+		t.Fatalf("renderDigest() error = %v", err)
+	}
+
+	for _, want := range []string{"Acme &lt;Corp&gt;", "$12.50", "25.0% vs previous week", "1.00%", "llama-3-8b", "Over budget", "120%"} {
+		if !strings.Contains(htmlBody, want) {
+			t.Errorf("html body missing %q", want)
+		}
+	}
+	for _, want := range []string{"Acme <Corp>", "Tokens: 1,500,000", "OVER BUDGET", "Jan 13, 2025 – Jan 19, 2025"} {
+		if !strings.Contains(textBody, want) {
+			t.Errorf("text body missing %q", want)
+		}
+	}
+	if strings.Contains(textBody, "Top Tenants") {
+		t.Error("tenant digest should not include top tenants")
+	}
+	if strings.Contains(textBody, "Spot Savings") {
+		t.Error("digest without spot usage should not include savings")
+	}
+
+	report.Savings = &billing.SavingsReport{
+		SpotHours:              40,
+		SpotSpend:              36,
+		SpotOnDemandEquivalent: 120,
+		Savings:                84,
+		SavingsPercent:         70,
+	}
+	_, textBody, err = renderDigest(report)
+	if err != nil {
+		t.Fatalf("renderDigest() error = %v", err)
+	}
+	if want := "Spot Savings: $84.00 (70%) - 40.0 spot hours cost $36.00 vs $120.00 on-demand"; !strings.Contains(textBody, want) {
+		t.Errorf("text body missing %q:\n%s", want, textBody)
+	}
+}
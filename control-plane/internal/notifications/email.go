@@ -57,9 +57,25 @@ func NewEmailAdapter(from string, to []string, apiKey string, logger *zap.Logger
 func (e *EmailAdapter) Send(ctx context.Context, event events.Event) error {
 	subject, htmlBody, textBody := e.formatEvent(event)
 
+	emailID, err := e.SendMessage(ctx, e.to, subject, htmlBody, textBody)
+	if err != nil {
+		return err
+	}
+
+	e.logger.Info("email sent via resend",
+		zap.String("email_id", emailID),
+		zap.String("event_id", event.ID),
+	)
+
+	return nil
+}
+
+// SendMessage sends a pre-rendered email to the given recipients and returns
+// the Resend email ID
+func (e *EmailAdapter) SendMessage(ctx context.Context, to []string, subject, htmlBody, textBody string) (string, error) {
 	emailReq := ResendEmailRequest{
 		From:    e.from,
-		To:      e.to,
+		To:      to,
 		Subject: subject,
 		HTML:    htmlBody,
 		Text:    textBody,
@@ -67,12 +83,12 @@ func (e *EmailAdapter) Send(ctx context.Context, event events.Event) error {
 
 	jsonData, err := json.Marshal(emailReq)
 	if err != nil {
-		return fmt.Errorf("failed to marshal email request: %w", err)
+		return "", fmt.Errorf("failed to marshal email request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.resend.com/emails", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -80,25 +96,20 @@ func (e *EmailAdapter) Send(ctx context.Context, event events.Event) error {
 
 	resp, err := e.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send email via resend: %w", err)
+		return "", fmt.Errorf("failed to send email via resend: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("resend API returned status %d", resp.StatusCode)
+		return "", fmt.Errorf("resend API returned status %d", resp.StatusCode)
 	}
 
 	var resendResp ResendEmailResponse
 	if err := json.NewDecoder(resp.Body).Decode(&resendResp); err != nil {
-		return fmt.Errorf("failed to decode resend response: %w", err)
+		return "", fmt.Errorf("failed to decode resend response: %w", err)
 	}
 
-	e.logger.Info("email sent via resend",
-		zap.String("email_id", resendResp.ID),
-		zap.String("event_id", event.ID),
-	)
-
-	return nil
+	return resendResp.ID, nil
 }
 
 // formatEvent converts an event into email subject and body
@@ -116,6 +127,12 @@ func (e *EmailAdapter) formatEvent(event events.Event) (subject, htmlBody, textB
 		return e.formatNodeHealthDegraded(event)
 	case events.EventCostAnomalyDetected:
 		return e.formatCostAnomaly(event)
+	case events.EventModelDeprecationReminder:
+		return e.formatModelDeprecationReminder(event)
+	case events.EventModelCircuitOpened, events.EventModelCircuitClosed:
+		return e.formatModelCircuit(event)
+	case events.EventAPIKeyThrottled, events.EventAPIKeyQuarantined:
+		return formatAPIKeyAbuse(event)
 	default:
 		return e.formatGeneric(event)
 	}
@@ -535,6 +552,90 @@ Threshold Exceeded: %s`,
 	return subject, htmlBody, textBody
 }
 
+func (e *EmailAdapter) formatModelDeprecationReminder(event events.Event) (string, string, string) {
+	subject := fmt.Sprintf("⏳ Model %s will be retired - CrossLogic", getStringField(event.Payload, "model"))
+
+	replacement := getStringField(event.Payload, "replacement_model")
+	if replacement == "" {
+		replacement = "N/A"
+	}
+
+	htmlBody := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<body>
+			<h2>⏳ Model Deprecation Reminder</h2>
+			<p><strong>Tenant ID:</strong> %s</p>
+			<p><strong>Model:</strong> %s</p>
+			<p><strong>Sunset Date:</strong> %s</p>
+			<p><strong>Replacement:</strong> %s</p>
+			<p>%s</p>
+			<p>Requests to this model will fail with <code>model_decommissioned</code> after the sunset date.</p>
+			<p>--<br>CrossLogic Notifications</p>
+		</body>
+		</html>
+	`,
+		event.TenantID,
+		getStringField(event.Payload, "model"),
+		getStringField(event.Payload, "sunset_at"),
+		replacement,
+		getStringField(event.Payload, "notice"),
+	)
+
+	textBody := fmt.Sprintf(`Model Deprecation Reminder
+
+Tenant ID: %s
+Model: %s
+Sunset Date: %s
+Replacement: %s
+
+Requests to this model will fail with model_decommissioned after the sunset date.`,
+		event.TenantID,
+		getStringField(event.Payload, "model"),
+		getStringField(event.Payload, "sunset_at"),
+		replacement,
+	)
+
+	return subject, htmlBody, textBody
+}
+
+func (e *EmailAdapter) formatModelCircuit(event events.Event) (string, string, string) {
+	model := getStringField(event.Payload, "model")
+
+	title := "🚨 Model Circuit Breaker Opened"
+	detail := fmt.Sprintf("The fleet-wide error rate for %s exceeded %s over the last %s. %s of its traffic is being rejected with model_overloaded until the error rate recovers.",
+		model,
+		getStringField(event.Payload, "error_rate_threshold"),
+		getStringField(event.Payload, "window"),
+		getStringField(event.Payload, "shed_fraction"),
+	)
+	if event.Type == events.EventModelCircuitClosed {
+		title = "✅ Model Circuit Breaker Closed"
+		detail = fmt.Sprintf("The error rate for %s is back within budget and traffic is no longer being shed.", model)
+	}
+	subject := fmt.Sprintf("%s: %s - CrossLogic", title, model)
+
+	htmlBody := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<body>
+			<h2>%s</h2>
+			<p><strong>Model:</strong> %s</p>
+			<p>%s</p>
+			<p>--<br>CrossLogic Notifications</p>
+		</body>
+		</html>
+	`, title, model, detail)
+
+	textBody := fmt.Sprintf(`%s
+
+Model: %s
+
+%s`, title, model, detail)
+
+	return subject, htmlBody, textBody
+}
+
 func (e *EmailAdapter) formatGeneric(event events.Event) (string, string, string) {
 	subject := fmt.Sprintf("📬 Event: %s - CrossLogic", event.Type)
 
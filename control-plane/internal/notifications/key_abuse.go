@@ -0,0 +1,111 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"go.uber.org/zap"
+)
+
+// abuseSignalDescriptions explain each abuse detection signal to tenants
+var abuseSignalDescriptions = map[string]string{
+	"network_spread": "requests arrived from an unusually large number of networks at the same time",
+	"rate_spike":     "its request rate jumped far above its normal level",
+	"scraping":       "it sent a large volume of near-identical prompts",
+}
+
+// handleAPIKeyAbuse emails the tenant when abuse detection throttles or
+// quarantines one of its keys. Ops channels receive the same event through
+// handleEvent. Security alerts ignore notification event filters but honour
+// the email destination override.
+func (s *Service) handleAPIKeyAbuse(ctx context.Context, event events.Event) error {
+	if s.email == nil || event.TenantID == "" {
+		return nil
+	}
+
+	var accountEmail string
+	var destination *string
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT t.email, CASE WHEN nc.enabled THEN nc.destination END
+		FROM tenants t
+		LEFT JOIN notification_config nc ON nc.tenant_id = t.id AND nc.channel = 'email'
+		WHERE t.id = $1
+	`, event.TenantID).Scan(&accountEmail, &destination)
+	if err != nil {
+		return fmt.Errorf("failed to load tenant email: %w", err)
+	}
+
+	prefs := digestPreferences{TenantEmail: accountEmail, HasConfig: destination != nil, Enabled: true, Destination: destination}
+	to := prefs.recipients()
+	if len(to) == 0 {
+		return nil
+	}
+
+	subject, htmlBody, textBody := formatAPIKeyAbuse(event)
+	start := time.Now()
+	if _, err := s.email.SendMessage(ctx, to, subject, htmlBody, textBody); err != nil {
+		s.metrics.RecordDelivery("tenant_email", string(event.Type), "failed", time.Since(start))
+		return fmt.Errorf("failed to email tenant about API key abuse: %w", err)
+	}
+	s.metrics.RecordDelivery("tenant_email", string(event.Type), "success", time.Since(start))
+
+	s.logger.Info("notified tenant of API key abuse action",
+		zap.String("tenant_id", event.TenantID),
+		zap.String("event_type", string(event.Type)),
+		zap.String("key_id", getStringField(event.Payload, "key_id")),
+	)
+	return nil
+}
+
+// formatAPIKeyAbuse renders the throttle/quarantine notice for a key
+func formatAPIKeyAbuse(event events.Event) (string, string, string) {
+	key := getStringField(event.Payload, "key_prefix")
+	if name := getStringField(event.Payload, "key_name"); name != "" {
+		key = fmt.Sprintf("%s (%s…)", name, key)
+	} else {
+		key += "…"
+	}
+
+	reason, ok := abuseSignalDescriptions[getStringField(event.Payload, "signal")]
+	if !ok {
+		reason = "its traffic looked unusual"
+	}
+
+	title := "⚠️ API Key Throttled"
+	detail := fmt.Sprintf("We reduced the rate limits of API key %s until %s because %s. Requests still succeed at 10%% of the key's normal limits.",
+		key, getStringField(event.Payload, "throttled_until"), reason)
+	action := "If this traffic is expected, no action is needed; the limits restore automatically. If not, rotate the key now."
+	if event.Type == events.EventAPIKeyQuarantined {
+		title = "🚨 API Key Quarantined"
+		detail = fmt.Sprintf("We blocked API key %s because %s. This usually means the key has leaked. Requests using it are rejected with key_quarantined.", key, reason)
+		action = "Create a new key and remove this one from your code and CI secrets. If the traffic was legitimate, contact support to have the key reviewed and released."
+	}
+	subject := fmt.Sprintf("%s - CrossLogic", title)
+
+	htmlBody := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<body>
+			<h2>%s</h2>
+			<p>%s</p>
+			<p>%s</p>
+			<p><strong>Incident ID:</strong> %s</p>
+			<p>--<br>CrossLogic Notifications</p>
+		</body>
+		</html>
+	`, title, html.EscapeString(detail), action, html.EscapeString(getStringField(event.Payload, "incident_id")))
+
+	textBody := strings.TrimSpace(fmt.Sprintf(`%s
+
+%s
+
+%s
+
+Incident ID: %s`, title, detail, action, getStringField(event.Payload, "incident_id")))
+
+	return subject, htmlBody, textBody
+}
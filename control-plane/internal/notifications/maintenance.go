@@ -0,0 +1,161 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// handleTenantMaintenance tells a tenant about maintenance that affects it,
+// by email and through the tenant's own webhook. Unlike security alerts,
+// maintenance notices honour the event filters of each channel.
+func (s *Service) handleTenantMaintenance(ctx context.Context, event events.Event) error {
+	if event.TenantID == "" {
+		return nil
+	}
+
+	var errs []error
+	if s.email != nil {
+		if err := s.emailTenantMaintenance(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := s.postTenantWebhook(ctx, event); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// tenantRecipients returns the addresses to email the tenant about an
+// event, honouring the event filter of its email channel
+func (s *Service) tenantRecipients(ctx context.Context, event events.Event) ([]string, error) {
+	var prefs digestPreferences
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT t.email, nc.id IS NOT NULL, COALESCE(nc.enabled, false), nc.destination, nc.event_types
+		FROM tenants t
+		LEFT JOIN notification_config nc ON nc.tenant_id = t.id AND nc.channel = 'email'
+		WHERE t.id = $1
+	`, event.TenantID).Scan(&prefs.TenantEmail, &prefs.HasConfig, &prefs.Enabled, &prefs.Destination, &prefs.EventTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant email: %w", err)
+	}
+	return prefs.recipientsFor(string(event.Type)), nil
+}
+
+// emailTenantMaintenance emails the maintenance notice to the tenant
+func (s *Service) emailTenantMaintenance(ctx context.Context, event events.Event) error {
+	to, err := s.tenantRecipients(ctx, event)
+	if err != nil || len(to) == 0 {
+		return err
+	}
+
+	subject, htmlBody, textBody := formatMaintenanceNotice(event)
+	start := time.Now()
+	if _, err := s.email.SendMessage(ctx, to, subject, htmlBody, textBody); err != nil {
+		s.metrics.RecordDelivery("tenant_email", string(event.Type), "failed", time.Since(start))
+		return fmt.Errorf("failed to email tenant about maintenance: %w", err)
+	}
+	s.metrics.RecordDelivery("tenant_email", string(event.Type), "success", time.Since(start))
+
+	s.logger.Info("notified tenant of maintenance",
+		zap.String("tenant_id", event.TenantID),
+		zap.String("window_id", getStringField(event.Payload, "window_id")),
+		zap.String("milestone", getStringField(event.Payload, "milestone")),
+	)
+	return nil
+}
+
+// postTenantWebhook delivers the event to the tenant's webhook, if it has
+// one enabled for this event type. Deliveries are signed with the secret
+// in the webhook's settings.
+func (s *Service) postTenantWebhook(ctx context.Context, event events.Event) error {
+	var url, secret string
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT destination, COALESCE(settings->>'secret', '')
+		FROM notification_config
+		WHERE tenant_id = $1 AND channel = 'webhook' AND enabled
+		  AND destination IS NOT NULL AND destination <> ''
+		  AND (event_types IS NULL OR $2 = ANY(event_types))
+	`, event.TenantID, string(event.Type)).Scan(&url, &secret)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to load tenant webhook: %w", err)
+	}
+
+	start := time.Now()
+	if err := NewWebhookAdapter(url, secret, "POST", nil, s.logger).Send(ctx, event); err != nil {
+		s.metrics.RecordDelivery("tenant_webhook", string(event.Type), "failed", time.Since(start))
+		return fmt.Errorf("failed to post tenant webhook %s: %w", maskURL(url), err)
+	}
+	s.metrics.RecordDelivery("tenant_webhook", string(event.Type), "success", time.Since(start))
+	return nil
+}
+
+// maintenanceSubject describes what a maintenance window covers
+func maintenanceSubject(payload map[string]interface{}) string {
+	target, _ := payload["target"].(string)
+	switch getStringField(payload, "scope") {
+	case "model":
+		return fmt.Sprintf("the %s model", target)
+	case "region":
+		return fmt.Sprintf("the %s region", target)
+	default:
+		return "the CrossLogic platform"
+	}
+}
+
+// formatMaintenanceNotice renders the scheduled, reminder or cancellation
+// notice for a maintenance window
+func formatMaintenanceNotice(event events.Event) (string, string, string) {
+	what := maintenanceSubject(event.Payload)
+	window := fmt.Sprintf("%s to %s", getStringField(event.Payload, "starts_at"), getStringField(event.Payload, "ends_at"))
+
+	title := "🛠️ Scheduled Maintenance"
+	detail := fmt.Sprintf("Maintenance on %s is scheduled from %s (UTC).", what, window)
+	action := "Requests may fail or be slower during the window. Retry failed requests with backoff, or route to another model or region until it ends."
+	switch {
+	case event.Type == events.EventMaintenanceCancelled:
+		title = "✅ Maintenance Cancelled"
+		detail = fmt.Sprintf("The maintenance on %s scheduled from %s (UTC) has been cancelled.", what, window)
+		action = "No action is needed."
+	case getStringField(event.Payload, "milestone") == "24h":
+		title = "⏰ Maintenance Starts Within 24 Hours"
+		detail = fmt.Sprintf("Reminder: maintenance on %s runs from %s (UTC).", what, window)
+	}
+	subject := fmt.Sprintf("%s: %s - CrossLogic", title, getStringField(event.Payload, "title"))
+
+	description, _ := event.Payload["description"].(string)
+
+	htmlBody := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<body>
+			<h2>%s</h2>
+			<p><strong>%s</strong></p>
+			<p>%s</p>
+			<p>%s</p>
+			<p>%s</p>
+			<p>--<br>CrossLogic Notifications</p>
+		</body>
+		</html>
+	`, title, html.EscapeString(getStringField(event.Payload, "title")), html.EscapeString(detail),
+		html.EscapeString(description), action)
+
+	text := []string{title, getStringField(event.Payload, "title"), detail}
+	if description != "" {
+		text = append(text, description)
+	}
+	text = append(text, action)
+	textBody := strings.Join(text, "\n\n")
+
+	return subject, htmlBody, textBody
+}
@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+)
+
+func TestFormatMaintenanceNotice(t *testing.T) {
+	payload := map[string]interface{}{
+		"title":     "Network upgrade",
+		"scope":     "region",
+		"target":    "us-east",
+		"milestone": "scheduled",
+		"starts_at": "2025-01-10T02:00:00Z",
+		"ends_at":   "2025-01-10T04:00:00Z",
+	}
+
+	subject, htmlBody, textBody := formatMaintenanceNotice(events.NewEvent(events.EventMaintenanceScheduled, "t-1", payload))
+	if !strings.Contains(subject, "Scheduled Maintenance: Network upgrade") {
+		t.Errorf("subject = %q", subject)
+	}
+	if !strings.Contains(textBody, "the us-east region is scheduled from 2025-01-10T02:00:00Z to 2025-01-10T04:00:00Z") {
+		t.Errorf("text body = %q", textBody)
+	}
+	if !strings.Contains(htmlBody, "Network upgrade") {
+		t.Errorf("html body missing title")
+	}
+
+	payload["milestone"] = "24h"
+	subject, _, _ = formatMaintenanceNotice(events.NewEvent(events.EventMaintenanceScheduled, "t-1", payload))
+	if !strings.Contains(subject, "Within 24 Hours") {
+		t.Errorf("reminder subject = %q", subject)
+	}
+
+	delete(payload, "target")
+	payload["scope"] = "platform"
+	subject, _, textBody = formatMaintenanceNotice(events.NewEvent(events.EventMaintenanceCancelled, "t-1", payload))
+	if !strings.Contains(subject, "Cancelled") || !strings.Contains(textBody, "the CrossLogic platform") {
+		t.Errorf("cancellation = %q / %q", subject, textBody)
+	}
+}
+
+func TestRecipientsForEventType(t *testing.T) {
+	prefs := digestPreferences{TenantEmail: "owner@example.com", HasConfig: true, Enabled: true, EventTypes: []string{"maintenance.scheduled"}}
+	if got := prefs.recipientsFor("maintenance.scheduled"); len(got) != 1 {
+		t.Errorf("recipientsFor(subscribed) = %v", got)
+	}
+	if got := prefs.recipientsFor("maintenance.cancelled"); got != nil {
+		t.Errorf("recipientsFor(filtered) = %v", got)
+	}
+}
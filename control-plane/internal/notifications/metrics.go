@@ -1,6 +1,7 @@
 package notifications
 
 import (
+	"strconv"
 	"sync"
 	"time"
 
@@ -71,7 +72,7 @@ func (m *Metrics) RecordDelivery(channel, eventType, status string, duration tim
 
 // RecordRetry records a retry attempt
 func (m *Metrics) RecordRetry(channel string, retryCount int) {
-	m.retriesTotal.WithLabelValues(channel, string(rune(retryCount))).Inc()
+	m.retriesTotal.WithLabelValues(channel, strconv.Itoa(retryCount)).Inc()
 }
 
 // SetQueueDepth sets the current retry queue depth
@@ -9,6 +9,7 @@ import (
 	"github.com/crosslogic/control-plane/pkg/cache"
 	"github.com/crosslogic/control-plane/pkg/database"
 	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -142,6 +143,11 @@ func (s *Service) Start(ctx context.Context) error {
 		go s.retryWorker(ctx, i)
 	}
 
+	// Start weekly usage & spend digests
+	if s.email != nil && s.config.DigestEnabled {
+		NewDigestReporter(s.db, s.email, s.config.EmailTo, s.metrics, s.logger).Start(ctx)
+	}
+
 	s.logger.Info("notification service started",
 		zap.Int("retry_workers", s.config.RetryWorkers),
 	)
@@ -171,6 +177,7 @@ func (s *Service) Stop(ctx context.Context) error {
 func (s *Service) subscribeToEvents() {
 	// Subscribe to tenant events
 	s.bus.Subscribe(events.EventTenantCreated, s.handleEvent)
+	s.bus.Subscribe(events.EventTenantPlanChanged, s.handleEvent)
 
 	// Subscribe to payment events
 	s.bus.Subscribe(events.EventPaymentSucceeded, s.handleEvent)
@@ -181,22 +188,67 @@ func (s *Service) subscribeToEvents() {
 	s.bus.Subscribe(events.EventNodeTerminated, s.handleEvent)
 	s.bus.Subscribe(events.EventNodeHealthDegraded, s.handleEvent)
 
+	// Subscribe to deployment capacity incidents; owning tenants are
+	// notified directly as well
+	for _, eventType := range []events.EventType{
+		events.EventDeploymentCapacityDegraded,
+		events.EventDeploymentCapacityUpdated,
+		events.EventDeploymentCapacityRecovered,
+	} {
+		s.bus.Subscribe(eventType, s.handleEvent)
+		s.bus.Subscribe(eventType, s.handleDeploymentCapacity)
+	}
+
 	// Subscribe to cost events
 	s.bus.Subscribe(events.EventCostAnomalyDetected, s.handleEvent)
 
+	// Subscribe to tenant usage alerts; these go to the tenant only
+	s.bus.Subscribe(events.EventUsageAlertTriggered, s.handleTenantUsageAlert)
+	s.bus.Subscribe(events.EventUsageAlertResolved, s.handleTenantUsageAlert)
+
+	// Subscribe to model lifecycle events
+	s.bus.Subscribe(events.EventModelDeprecationReminder, s.handleEvent)
+	s.bus.Subscribe(events.EventModelCircuitOpened, s.handleEvent)
+	s.bus.Subscribe(events.EventModelCircuitClosed, s.handleEvent)
+
+	// Subscribe to maintenance events; affected tenants are notified directly
+	s.bus.Subscribe(events.EventMaintenanceScheduled, s.handleEvent)
+	s.bus.Subscribe(events.EventMaintenanceCancelled, s.handleEvent)
+	s.bus.Subscribe(events.EventMaintenanceScheduled, s.handleTenantMaintenance)
+	s.bus.Subscribe(events.EventMaintenanceCancelled, s.handleTenantMaintenance)
+
 	// Subscribe to rate limit events
 	s.bus.Subscribe(events.EventRateLimitThreshold, s.handleEvent)
 
+	// Subscribe to API key abuse events; tenants are emailed directly as well
+	s.bus.Subscribe(events.EventAPIKeyThrottled, s.handleEvent)
+	s.bus.Subscribe(events.EventAPIKeyQuarantined, s.handleEvent)
+	s.bus.Subscribe(events.EventAPIKeyThrottled, s.handleAPIKeyAbuse)
+	s.bus.Subscribe(events.EventAPIKeyQuarantined, s.handleAPIKeyAbuse)
+
 	s.logger.Info("subscribed to event types",
 		zap.Strings("events", []string{
 			string(events.EventTenantCreated),
+			string(events.EventTenantPlanChanged),
 			string(events.EventPaymentSucceeded),
 			string(events.EventPaymentFailed),
 			string(events.EventNodeLaunched),
 			string(events.EventNodeTerminated),
 			string(events.EventNodeHealthDegraded),
+			string(events.EventDeploymentCapacityDegraded),
+			string(events.EventDeploymentCapacityUpdated),
+			string(events.EventDeploymentCapacityRecovered),
 			string(events.EventCostAnomalyDetected),
+			string(events.EventUsageAlertTriggered),
+			string(events.EventUsageAlertResolved),
+			string(events.EventModelDeprecationReminder),
+			string(events.EventModelCircuitOpened),
+			string(events.EventModelCircuitClosed),
+			string(events.EventMaintenanceScheduled),
+			string(events.EventMaintenanceCancelled),
 			string(events.EventRateLimitThreshold),
+			string(events.EventAPIKeyThrottled),
+			string(events.EventAPIKeyQuarantined),
 		}),
 	)
 }
@@ -210,7 +262,7 @@ func (s *Service) handleEvent(ctx context.Context, event events.Event) error {
 	)
 
 	// Check if this event was already processed (idempotency)
-	if s.isDuplicate(ctx, event.ID) {
+	if s.isDuplicate(ctx, event) {
 		s.logger.Debug("duplicate event, skipping",
 			zap.String("event_id", event.ID),
 		)
@@ -252,7 +304,7 @@ func (s *Service) handleEvent(ctx context.Context, event events.Event) error {
 	}
 
 	// Mark event as processed
-	s.markProcessed(ctx, event.ID)
+	s.markProcessed(ctx, event)
 
 	return nil
 }
@@ -414,9 +466,18 @@ func (s *Service) calculateBackoff(retryCount int) time.Duration {
 	return backoff
 }
 
+// processedKey marks an event as processed, in its tenant's namespace when
+// it has one
+func processedKey(event events.Event) string {
+	if tenantID, err := uuid.Parse(event.TenantID); err == nil && tenantID != uuid.Nil {
+		return cache.TenantKey(tenantID, "notification", "processed", event.ID)
+	}
+	return cache.PlatformKey(cache.NamespaceNotification, "processed", event.ID)
+}
+
 // isDuplicate checks if an event was already processed
-func (s *Service) isDuplicate(ctx context.Context, eventID string) bool {
-	key := fmt.Sprintf("notification:processed:%s", eventID)
+func (s *Service) isDuplicate(ctx context.Context, event events.Event) bool {
+	key := processedKey(event)
 	exists, err := s.cache.Exists(ctx, key)
 	if err != nil {
 		s.logger.Error("failed to check duplicate", zap.Error(err))
@@ -426,8 +487,8 @@ func (s *Service) isDuplicate(ctx context.Context, eventID string) bool {
 }
 
 // markProcessed marks an event as processed
-func (s *Service) markProcessed(ctx context.Context, eventID string) {
-	key := fmt.Sprintf("notification:processed:%s", eventID)
+func (s *Service) markProcessed(ctx context.Context, event events.Event) {
+	key := processedKey(event)
 	// Store for 24 hours
 	if err := s.cache.Set(ctx, key, "1", 24*time.Hour); err != nil {
 		s.logger.Error("failed to mark event as processed", zap.Error(err))
@@ -0,0 +1,570 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Usage alerts.
+//
+// Tenants define rules over their own usage through the API, such as "spend
+// over the last 24 hours above $50" or "error rate above 2% over 10 minutes
+// on model X". Every minute each enabled rule's metric is computed from
+// usage_records over its rolling window. A rule fires once when its
+// condition starts holding and resolves once when it stops; both changes are
+// published as events and delivered to the tenant by email and webhook,
+// honouring the tenant's notification_config.
+
+var (
+	// ErrUsageAlertNotFound is returned when the tenant has no such rule
+	ErrUsageAlertNotFound = errors.New("usage alert not found")
+	// ErrInvalidUsageAlert wraps usage alert validation failures
+	ErrInvalidUsageAlert = errors.New("invalid usage alert")
+)
+
+// Usage alert metrics
+const (
+	UsageMetricSpend      = "spend_usd"      // dollars
+	UsageMetricRequests   = "requests"       // count
+	UsageMetricTokens     = "tokens"         // count
+	UsageMetricErrorRate  = "error_rate"     // percent of requests returning 4xx/5xx
+	UsageMetricP95Latency = "p95_latency_ms" // milliseconds
+)
+
+const (
+	minUsageAlertWindow = 5 * time.Minute
+	maxUsageAlertWindow = 7 * 24 * time.Hour
+
+	// maxUsageAlertsPerTenant bounds how many rules each tenant evaluates
+	maxUsageAlertsPerTenant = 50
+
+	// usageAlertMinRequests is how many requests a window needs before error
+	// rate and latency rules are judged, so a single failure doesn't fire
+	usageAlertMinRequests = 20
+
+	// usageAlertPollInterval is how often rules are evaluated
+	usageAlertPollInterval = time.Minute
+)
+
+// UsageAlertRule is a tenant's alert rule and its evaluation state. A nil
+// Model covers all of the tenant's models.
+type UsageAlertRule struct {
+	ID              uuid.UUID  `json:"id"`
+	TenantID        uuid.UUID  `json:"-"`
+	Name            string     `json:"name"`
+	Metric          string     `json:"metric"`
+	Model           *string    `json:"model,omitempty"`
+	Operator        string     `json:"operator"`
+	Threshold       float64    `json:"threshold"`
+	WindowMinutes   int        `json:"window_minutes"`
+	Enabled         bool       `json:"enabled"`
+	State           string     `json:"state"`
+	LastValue       *float64   `json:"last_value,omitempty"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+	TriggeredAt     *time.Time `json:"triggered_at,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Validate normalizes the rule and checks its metric, operator, threshold
+// and window
+func (r *UsageAlertRule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 100 {
+		return fmt.Errorf("%w: name is required and must be at most 100 characters", ErrInvalidUsageAlert)
+	}
+	switch r.Metric {
+	case UsageMetricSpend, UsageMetricRequests, UsageMetricTokens, UsageMetricErrorRate, UsageMetricP95Latency:
+	default:
+		return fmt.Errorf("%w: metric must be one of %s, %s, %s, %s, %s", ErrInvalidUsageAlert,
+			UsageMetricSpend, UsageMetricRequests, UsageMetricTokens, UsageMetricErrorRate, UsageMetricP95Latency)
+	}
+	if r.Model != nil {
+		if model := strings.TrimSpace(*r.Model); model == "" {
+			r.Model = nil
+		} else {
+			r.Model = &model
+		}
+	}
+	if r.Operator == "" {
+		r.Operator = ">"
+	}
+	if r.Operator != ">" && r.Operator != "<" {
+		return fmt.Errorf("%w: operator must be > or <", ErrInvalidUsageAlert)
+	}
+	if r.Threshold < 0 || (r.Metric == UsageMetricErrorRate && r.Threshold > 100) {
+		return fmt.Errorf("%w: threshold out of range", ErrInvalidUsageAlert)
+	}
+	window := time.Duration(r.WindowMinutes) * time.Minute
+	if window < minUsageAlertWindow || window > maxUsageAlertWindow {
+		return fmt.Errorf("%w: window_minutes must be between %d and %d", ErrInvalidUsageAlert,
+			int(minUsageAlertWindow/time.Minute), int(maxUsageAlertWindow/time.Minute))
+	}
+	return nil
+}
+
+// breached reports whether a metric value meets the rule's condition
+func (r *UsageAlertRule) breached(value float64) bool {
+	if r.Operator == "<" {
+		return value < r.Threshold
+	}
+	return value > r.Threshold
+}
+
+// usageWindow is a tenant's usage over a rule's window
+type usageWindow struct {
+	Requests     int64
+	Tokens       int64
+	CostMicros   int64
+	Errors       int64
+	P95LatencyMs float64
+}
+
+// value returns the metric for the window, or false when there are too few
+// requests to judge a rate or latency
+func (u usageWindow) value(metric string) (float64, bool) {
+	switch metric {
+	case UsageMetricSpend:
+		return float64(u.CostMicros) / 1_000_000, true
+	case UsageMetricRequests:
+		return float64(u.Requests), true
+	case UsageMetricTokens:
+		return float64(u.Tokens), true
+	case UsageMetricErrorRate:
+		if u.Requests < usageAlertMinRequests {
+			return 0, false
+		}
+		return float64(u.Errors) / float64(u.Requests) * 100, true
+	case UsageMetricP95Latency:
+		if u.Requests < usageAlertMinRequests {
+			return 0, false
+		}
+		return u.P95LatencyMs, true
+	}
+	return 0, false
+}
+
+// UsageAlerts stores tenant alert rules and evaluates them
+type UsageAlerts struct {
+	db       *database.Database
+	logger   *zap.Logger
+	bus      *events.Bus
+	interval time.Duration
+}
+
+// NewUsageAlerts creates the usage alert rules engine
+func NewUsageAlerts(db *database.Database, logger *zap.Logger, bus *events.Bus) *UsageAlerts {
+	return &UsageAlerts{
+		db:       db,
+		logger:   logger,
+		bus:      bus,
+		interval: usageAlertPollInterval,
+	}
+}
+
+// Start begins evaluating rules in the background
+func (a *UsageAlerts) Start(ctx context.Context) {
+	a.logger.Info("starting usage alert evaluator")
+	go func() {
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.evaluateAll(ctx)
+			}
+		}
+	}()
+}
+
+const usageAlertColumns = `id, tenant_id, name, metric, model, operator, threshold, window_minutes, enabled,
+	state, last_value, last_evaluated_at, triggered_at, resolved_at, created_at, updated_at`
+
+func scanUsageAlert(row pgx.Row) (*UsageAlertRule, error) {
+	var r UsageAlertRule
+	err := row.Scan(&r.ID, &r.TenantID, &r.Name, &r.Metric, &r.Model, &r.Operator, &r.Threshold, &r.WindowMinutes, &r.Enabled,
+		&r.State, &r.LastValue, &r.LastEvaluatedAt, &r.TriggeredAt, &r.ResolvedAt, &r.CreatedAt, &r.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUsageAlertNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// List returns the tenant's rules
+func (a *UsageAlerts) List(ctx context.Context, tenantID uuid.UUID) ([]UsageAlertRule, error) {
+	rows, err := a.db.Pool.Query(ctx, `
+		SELECT `+usageAlertColumns+` FROM usage_alert_rules
+		WHERE tenant_id = $1
+		ORDER BY created_at
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []UsageAlertRule{}
+	for rows.Next() {
+		r, err := scanUsageAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *r)
+	}
+	return rules, rows.Err()
+}
+
+// Get returns one of the tenant's rules
+func (a *UsageAlerts) Get(ctx context.Context, tenantID, id uuid.UUID) (*UsageAlertRule, error) {
+	return scanUsageAlert(a.db.Pool.QueryRow(ctx, `
+		SELECT `+usageAlertColumns+` FROM usage_alert_rules WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID))
+}
+
+// checkModel rejects rules for models that aren't in the catalog
+func (a *UsageAlerts) checkModel(ctx context.Context, model *string) error {
+	if model == nil {
+		return nil
+	}
+	var exists bool
+	if err := a.db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM models WHERE name = $1)`, *model).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: unknown model %q", ErrInvalidUsageAlert, *model)
+	}
+	return nil
+}
+
+// Create adds a rule for the tenant
+func (a *UsageAlerts) Create(ctx context.Context, tenantID uuid.UUID, rule UsageAlertRule) (*UsageAlertRule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	if err := a.checkModel(ctx, rule.Model); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := a.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM usage_alert_rules WHERE tenant_id = $1`, tenantID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= maxUsageAlertsPerTenant {
+		return nil, fmt.Errorf("%w: at most %d rules per tenant", ErrInvalidUsageAlert, maxUsageAlertsPerTenant)
+	}
+
+	return scanUsageAlert(a.db.Pool.QueryRow(ctx, `
+		INSERT INTO usage_alert_rules (tenant_id, name, metric, model, operator, threshold, window_minutes, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+usageAlertColumns,
+		tenantID, rule.Name, rule.Metric, rule.Model, rule.Operator, rule.Threshold, rule.WindowMinutes, rule.Enabled,
+	))
+}
+
+// Update replaces a rule's definition. Its state is reset, so a changed
+// rule that still breaches fires again.
+func (a *UsageAlerts) Update(ctx context.Context, tenantID, id uuid.UUID, rule UsageAlertRule) (*UsageAlertRule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	if err := a.checkModel(ctx, rule.Model); err != nil {
+		return nil, err
+	}
+
+	return scanUsageAlert(a.db.Pool.QueryRow(ctx, `
+		UPDATE usage_alert_rules SET
+			name = $3, metric = $4, model = $5, operator = $6, threshold = $7, window_minutes = $8, enabled = $9,
+			state = 'ok', last_value = NULL, last_evaluated_at = NULL, triggered_at = NULL, resolved_at = NULL,
+			updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING `+usageAlertColumns,
+		id, tenantID, rule.Name, rule.Metric, rule.Model, rule.Operator, rule.Threshold, rule.WindowMinutes, rule.Enabled,
+	))
+}
+
+// Delete removes a rule
+func (a *UsageAlerts) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	tag, err := a.db.Pool.Exec(ctx, `DELETE FROM usage_alert_rules WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUsageAlertNotFound
+	}
+	return nil
+}
+
+// evaluateAll evaluates every enabled rule of active tenants
+func (a *UsageAlerts) evaluateAll(ctx context.Context) {
+	rows, err := a.db.Pool.Query(ctx, `
+		SELECT `+usageAlertColumns+` FROM usage_alert_rules
+		WHERE enabled AND tenant_id IN (SELECT id FROM tenants WHERE status = 'active')
+	`)
+	if err != nil {
+		a.logger.Error("failed to load usage alert rules", zap.Error(err))
+		return
+	}
+
+	var rules []UsageAlertRule
+	for rows.Next() {
+		r, err := scanUsageAlert(rows)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, *r)
+	}
+	rows.Close()
+
+	now := time.Now()
+	for i := range rules {
+		if err := a.evaluate(ctx, &rules[i], now); err != nil {
+			a.logger.Error("failed to evaluate usage alert",
+				zap.String("tenant_id", rules[i].TenantID.String()),
+				zap.String("rule_id", rules[i].ID.String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// loadUsageWindow aggregates the tenant's usage since the start of the
+// rule's window
+func (a *UsageAlerts) loadUsageWindow(ctx context.Context, rule *UsageAlertRule, now time.Time) (usageWindow, error) {
+	var u usageWindow
+	err := a.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(ur.total_tokens), 0), COALESCE(SUM(ur.cost_microdollars), 0),
+		       COUNT(*) FILTER (WHERE ur.status_code >= 400),
+		       COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY ur.latency_ms), 0)
+		FROM usage_records ur
+		LEFT JOIN models m ON m.id = ur.model_id
+		WHERE ur.tenant_id = $1 AND ur.timestamp > $2
+		  AND ($3::text IS NULL OR m.name = $3)
+	`, rule.TenantID, now.Add(-time.Duration(rule.WindowMinutes)*time.Minute), rule.Model).
+		Scan(&u.Requests, &u.Tokens, &u.CostMicros, &u.Errors, &u.P95LatencyMs)
+	return u, err
+}
+
+// evaluate computes the rule's metric and fires or resolves it when its
+// condition changes. Transitions are claimed with a conditional update so
+// only one control plane replica notifies.
+func (a *UsageAlerts) evaluate(ctx context.Context, rule *UsageAlertRule, now time.Time) error {
+	usage, err := a.loadUsageWindow(ctx, rule, now)
+	if err != nil {
+		return fmt.Errorf("failed to load usage: %w", err)
+	}
+	value, ok := usage.value(rule.Metric)
+	if !ok {
+		return nil
+	}
+	breached := rule.breached(value)
+
+	switch {
+	case breached && rule.State == "ok":
+		tag, err := a.db.Pool.Exec(ctx, `
+			UPDATE usage_alert_rules
+			SET state = 'firing', triggered_at = $2, last_value = $3, last_evaluated_at = $2
+			WHERE id = $1 AND state = 'ok'
+		`, rule.ID, now, value)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		a.publish(ctx, events.EventUsageAlertTriggered, rule, value, now)
+
+	case !breached && rule.State == "firing":
+		tag, err := a.db.Pool.Exec(ctx, `
+			UPDATE usage_alert_rules
+			SET state = 'ok', resolved_at = $2, last_value = $3, last_evaluated_at = $2
+			WHERE id = $1 AND state = 'firing'
+		`, rule.ID, now, value)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		a.publish(ctx, events.EventUsageAlertResolved, rule, value, now)
+
+	default:
+		_, err = a.db.Pool.Exec(ctx, `
+			UPDATE usage_alert_rules SET last_value = $2, last_evaluated_at = $3 WHERE id = $1
+		`, rule.ID, value, now)
+		return err
+	}
+	return nil
+}
+
+// publish announces a rule firing or resolving to the tenant
+func (a *UsageAlerts) publish(ctx context.Context, eventType events.EventType, rule *UsageAlertRule, value float64, now time.Time) {
+	a.logger.Info("usage alert changed state",
+		zap.String("tenant_id", rule.TenantID.String()),
+		zap.String("rule_id", rule.ID.String()),
+		zap.String("event_type", string(eventType)),
+		zap.Float64("value", value),
+	)
+	if a.bus == nil {
+		return
+	}
+	evt := events.NewEvent(eventType, rule.TenantID.String(), usageAlertPayload(rule, value, now))
+	if err := a.bus.Publish(ctx, evt); err != nil {
+		a.logger.Error("failed to publish usage alert",
+			zap.String("rule_id", rule.ID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+// usageAlertPayload describes a rule firing or resolving for notifications
+func usageAlertPayload(rule *UsageAlertRule, value float64, now time.Time) map[string]interface{} {
+	payload := map[string]interface{}{
+		"rule_id":        rule.ID.String(),
+		"rule_name":      rule.Name,
+		"metric":         rule.Metric,
+		"operator":       rule.Operator,
+		"threshold":      rule.Threshold,
+		"window_minutes": rule.WindowMinutes,
+		"value":          value,
+		"evaluated_at":   now.UTC().Format(time.RFC3339),
+	}
+	if rule.Model != nil {
+		payload["model"] = *rule.Model
+	}
+	return payload
+}
+
+// handleTenantUsageAlert delivers a tenant's own alert to it by email and
+// webhook. Tenant rules are not sent to ops channels.
+func (s *Service) handleTenantUsageAlert(ctx context.Context, event events.Event) error {
+	if event.TenantID == "" {
+		return nil
+	}
+
+	var errs []error
+	if s.email != nil {
+		if err := s.emailTenantUsageAlert(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := s.postTenantWebhook(ctx, event); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// emailTenantUsageAlert emails the alert to the tenant
+func (s *Service) emailTenantUsageAlert(ctx context.Context, event events.Event) error {
+	to, err := s.tenantRecipients(ctx, event)
+	if err != nil || len(to) == 0 {
+		return err
+	}
+
+	subject, htmlBody, textBody := formatUsageAlert(event)
+	start := time.Now()
+	if _, err := s.email.SendMessage(ctx, to, subject, htmlBody, textBody); err != nil {
+		s.metrics.RecordDelivery("tenant_email", string(event.Type), "failed", time.Since(start))
+		return fmt.Errorf("failed to email tenant usage alert: %w", err)
+	}
+	s.metrics.RecordDelivery("tenant_email", string(event.Type), "success", time.Since(start))
+
+	s.logger.Info("notified tenant of usage alert",
+		zap.String("tenant_id", event.TenantID),
+		zap.String("event_type", string(event.Type)),
+		zap.String("rule_id", getStringField(event.Payload, "rule_id")),
+	)
+	return nil
+}
+
+// formatUsageMetric renders a metric value in its unit
+func formatUsageMetric(metric string, value float64) string {
+	switch metric {
+	case UsageMetricSpend:
+		return formatDollars(value)
+	case UsageMetricErrorRate:
+		return fmt.Sprintf("%.2f%%", value)
+	case UsageMetricP95Latency:
+		return fmt.Sprintf("%.0f ms", value)
+	default:
+		return formatCount(int64(value))
+	}
+}
+
+// usageMetricNames describe each metric in notices
+var usageMetricNames = map[string]string{
+	UsageMetricSpend:      "Spend",
+	UsageMetricRequests:   "Requests",
+	UsageMetricTokens:     "Tokens",
+	UsageMetricErrorRate:  "Error rate",
+	UsageMetricP95Latency: "p95 latency",
+}
+
+// formatUsageWindow renders a rule window as "10 minutes", "1 hour" or "7 days"
+func formatUsageWindow(minutes int) string {
+	n, unit := minutes, "minute"
+	switch {
+	case minutes%(24*60) == 0:
+		n, unit = minutes/(24*60), "day"
+	case minutes%60 == 0:
+		n, unit = minutes/60, "hour"
+	}
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// formatUsageAlert renders the triggered or resolved notice for a rule
+func formatUsageAlert(event events.Event) (string, string, string) {
+	metric := getStringField(event.Payload, "metric")
+	value, _ := event.Payload["value"].(float64)
+	threshold, _ := event.Payload["threshold"].(float64)
+	window := formatUsageWindow(getIntField(event.Payload, "window_minutes"))
+
+	scope := "across all models"
+	if model, _ := event.Payload["model"].(string); model != "" {
+		scope = "on " + model
+	}
+	direction := "above"
+	if getStringField(event.Payload, "operator") == "<" {
+		direction = "below"
+	}
+	condition := fmt.Sprintf("%s over the last %s %s is %s %s",
+		usageMetricNames[metric], window, scope, direction, formatUsageMetric(metric, threshold))
+
+	title := "🔔 Usage Alert Triggered"
+	detail := fmt.Sprintf("Your rule is firing: %s. Current value: %s.", condition, formatUsageMetric(metric, value))
+	if event.Type == events.EventUsageAlertResolved {
+		title = "✅ Usage Alert Resolved"
+		detail = fmt.Sprintf("Your rule is no longer firing (%s). Current value: %s.", condition, formatUsageMetric(metric, value))
+	}
+	name := getStringField(event.Payload, "rule_name")
+	subject := fmt.Sprintf("%s: %s - CrossLogic", title, name)
+
+	htmlBody := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<body>
+			<h2>%s</h2>
+			<p><strong>%s</strong></p>
+			<p>%s</p>
+			<p>Manage your alert rules with the /v1/alerts API.</p>
+			<p>--<br>CrossLogic Notifications</p>
+		</body>
+		</html>
+	`, title, html.EscapeString(name), html.EscapeString(detail))
+
+	textBody := strings.Join([]string{title, name, detail, "Manage your alert rules with the /v1/alerts API."}, "\n\n")
+
+	return subject, htmlBody, textBody
+}
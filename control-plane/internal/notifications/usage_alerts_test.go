@@ -0,0 +1,119 @@
+package notifications
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+)
+
+func TestUsageAlertRuleValidate(t *testing.T) {
+	model := "  llama-3-8b "
+	rule := UsageAlertRule{Name: " Error budget ", Metric: UsageMetricErrorRate, Model: &model, Threshold: 2, WindowMinutes: 10}
+	if err := rule.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if rule.Name != "Error budget" || *rule.Model != "llama-3-8b" || rule.Operator != ">" {
+		t.Errorf("rule = %+v, want trimmed name and model with default operator", rule)
+	}
+
+	blank := " "
+	rule = UsageAlertRule{Name: "Daily spend", Metric: UsageMetricSpend, Model: &blank, Threshold: 50, WindowMinutes: 1440}
+	if err := rule.Validate(); err != nil || rule.Model != nil {
+		t.Errorf("Validate() = %v, model = %v; want blank model to cover all models", err, rule.Model)
+	}
+
+	invalid := []UsageAlertRule{
+		{Metric: UsageMetricSpend, Threshold: 50, WindowMinutes: 60},
+		{Name: "x", Metric: "cpu", Threshold: 50, WindowMinutes: 60},
+		{Name: "x", Metric: UsageMetricSpend, Operator: ">=", Threshold: 50, WindowMinutes: 60},
+		{Name: "x", Metric: UsageMetricSpend, Threshold: -1, WindowMinutes: 60},
+		{Name: "x", Metric: UsageMetricErrorRate, Threshold: 150, WindowMinutes: 60},
+		{Name: "x", Metric: UsageMetricRequests, Threshold: 1, WindowMinutes: 1},
+		{Name: "x", Metric: UsageMetricRequests, Threshold: 1, WindowMinutes: 8 * 24 * 60},
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); !errors.Is(err, ErrInvalidUsageAlert) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalidUsageAlert", r, err)
+		}
+	}
+}
+
+func TestUsageWindowValue(t *testing.T) {
+	u := usageWindow{Requests: 200, Tokens: 50_000, CostMicros: 52_500_000, Errors: 5, P95LatencyMs: 850}
+
+	cases := map[string]float64{
+		UsageMetricSpend:      52.5,
+		UsageMetricRequests:   200,
+		UsageMetricTokens:     50_000,
+		UsageMetricErrorRate:  2.5,
+		UsageMetricP95Latency: 850,
+	}
+	for metric, want := range cases {
+		if got, ok := u.value(metric); !ok || got != want {
+			t.Errorf("value(%s) = %v, %v; want %v", metric, got, ok, want)
+		}
+	}
+
+	quiet := usageWindow{Requests: 3, Errors: 3}
+	if _, ok := quiet.value(UsageMetricErrorRate); ok {
+		t.Error("error rate judged on too few requests")
+	}
+	if v, ok := quiet.value(UsageMetricRequests); !ok || v != 3 {
+		t.Errorf("requests on a quiet window = %v, %v", v, ok)
+	}
+}
+
+func TestUsageAlertRuleBreached(t *testing.T) {
+	above := UsageAlertRule{Operator: ">", Threshold: 50}
+	if !above.breached(50.01) || above.breached(50) {
+		t.Error("> rule breached wrongly")
+	}
+	below := UsageAlertRule{Operator: "<", Threshold: 10}
+	if !below.breached(9) || below.breached(10) {
+		t.Error("< rule breached wrongly")
+	}
+}
+
+func TestFormatUsageWindow(t *testing.T) {
+	cases := map[int]string{10: "10 minutes", 60: "1 hour", 90: "90 minutes", 180: "3 hours", 1440: "1 day", 10080: "7 days"}
+	for minutes, want := range cases {
+		if got := formatUsageWindow(minutes); got != want {
+			t.Errorf("formatUsageWindow(%d) = %q, want %q", minutes, got, want)
+		}
+	}
+}
+
+func TestFormatUsageAlert(t *testing.T) {
+	payload := map[string]interface{}{
+		"rule_id":        "r-1",
+		"rule_name":      "Daily spend",
+		"metric":         UsageMetricSpend,
+		"operator":       ">",
+		"threshold":      50.0,
+		"window_minutes": 1440,
+		"value":          52.5,
+	}
+
+	subject, htmlBody, textBody := formatUsageAlert(events.NewEvent(events.EventUsageAlertTriggered, "t-1", payload))
+	if !strings.Contains(subject, "Usage Alert Triggered: Daily spend") {
+		t.Errorf("subject = %q", subject)
+	}
+	if !strings.Contains(textBody, "Spend over the last 1 day across all models is above $50.00. Current value: $52.50.") {
+		t.Errorf("text body = %q", textBody)
+	}
+	if !strings.Contains(htmlBody, "Daily spend") {
+		t.Error("html body missing rule name")
+	}
+
+	payload["metric"] = UsageMetricErrorRate
+	payload["model"] = "llama-3-8b"
+	payload["window_minutes"] = float64(10)
+	payload["threshold"] = 2.0
+	payload["value"] = 0.5
+	subject, _, textBody = formatUsageAlert(events.NewEvent(events.EventUsageAlertResolved, "t-1", payload))
+	if !strings.Contains(subject, "Resolved") || !strings.Contains(textBody, "Error rate over the last 10 minutes on llama-3-8b is above 2.00%") {
+		t.Errorf("resolved = %q / %q", subject, textBody)
+	}
+}
@@ -0,0 +1,439 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"go.uber.org/zap"
+)
+
+// ErrCatalogSyncInProgress is returned when a sync is requested while one is running
+var ErrCatalogSyncInProgress = errors.New("catalog sync already in progress")
+
+// CatalogInstanceType is one GPU instance type aggregated across regions
+type CatalogInstanceType struct {
+	Provider         string
+	InstanceType     string
+	VCPUCount        int
+	MemoryGB         float64
+	GPUCount         int
+	GPUMemoryGB      float64
+	GPUModel         string
+	PricePerHour     float64
+	SpotPricePerHour float64
+	SupportsSpot     bool
+	Regions          map[string]bool
+}
+
+// CatalogSyncResult summarises a single sync run
+type CatalogSyncResult struct {
+	RunID                 string    `json:"run_id"`
+	Providers             []string  `json:"providers"`
+	InstanceTypesUpserted int       `json:"instance_types_upserted"`
+	AvailabilityUpserted  int       `json:"availability_upserted"`
+	InstanceTypesMissing  int       `json:"instance_types_missing"`
+	StartedAt             time.Time `json:"started_at"`
+	FinishedAt            time.Time `json:"finished_at"`
+}
+
+// CatalogSyncer keeps the instance_types and region_instance_availability
+// tables in sync with the SkyPilot catalog (https://github.com/skypilot-org/skypilot-catalog).
+//
+// The catalog publishes one vms.csv per cloud with a row per
+// (instance type, region, zone) including on-demand and spot prices.
+// GPU rows are aggregated per instance type (cheapest price across regions)
+// and upserted; instance types that vanish from the catalog are flagged via
+// missing_since and, when they were created by the sync, marked unavailable.
+type CatalogSyncer struct {
+	db         *database.Database
+	logger     *zap.Logger
+	httpClient *http.Client
+
+	catalogURL string
+	providers  []string
+	interval   time.Duration
+	enabled    bool
+
+	// running guards against overlapping scheduled and manual syncs
+	running sync.Mutex
+}
+
+// NewCatalogSyncer creates a new catalog syncer.
+func NewCatalogSyncer(db *database.Database, logger *zap.Logger, catalogURL string, interval time.Duration, enabled bool) *CatalogSyncer {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return &CatalogSyncer{
+		db:         db,
+		logger:     logger,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+		catalogURL: strings.TrimRight(catalogURL, "/"),
+		providers:  []string{"aws", "azure", "gcp"},
+		interval:   interval,
+		enabled:    enabled,
+	}
+}
+
+// Start begins the scheduled sync loop (nightly by default).
+func (s *CatalogSyncer) Start(ctx context.Context) {
+	if !s.enabled {
+		s.logger.Info("scheduled catalog sync disabled; manual trigger still available")
+		return
+	}
+
+	s.logger.Info("starting catalog sync",
+		zap.Duration("interval", s.interval),
+		zap.String("catalog_url", s.catalogURL),
+	)
+	go s.syncLoop(ctx)
+}
+
+// syncLoop periodically syncs the catalog
+func (s *CatalogSyncer) syncLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Sync(ctx, "scheduled"); err != nil {
+				s.logger.Error("scheduled catalog sync failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// TriggerSync starts a sync in the background and returns immediately.
+// It returns ErrCatalogSyncInProgress if a sync is already running.
+func (s *CatalogSyncer) TriggerSync(ctx context.Context) error {
+	if !s.running.TryLock() {
+		return ErrCatalogSyncInProgress
+	}
+
+	go func() {
+		defer s.running.Unlock()
+		if _, err := s.sync(context.WithoutCancel(ctx), "manual"); err != nil {
+			s.logger.Error("manual catalog sync failed", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Sync runs a full catalog sync and blocks until it completes.
+func (s *CatalogSyncer) Sync(ctx context.Context, trigger string) (*CatalogSyncResult, error) {
+	if !s.running.TryLock() {
+		return nil, ErrCatalogSyncInProgress
+	}
+	defer s.running.Unlock()
+	return s.sync(ctx, trigger)
+}
+
+func (s *CatalogSyncer) sync(ctx context.Context, trigger string) (*CatalogSyncResult, error) {
+	result := &CatalogSyncResult{
+		Providers: s.providers,
+		StartedAt: time.Now(),
+	}
+
+	providersJSON, _ := json.Marshal(s.providers)
+	if err := s.db.Pool.QueryRow(ctx, `
+		INSERT INTO catalog_sync_runs (trigger, providers) VALUES ($1, $2) RETURNING id
+	`, trigger, providersJSON).Scan(&result.RunID); err != nil {
+		return nil, fmt.Errorf("failed to record sync run: %w", err)
+	}
+
+	s.logger.Info("catalog sync started",
+		zap.String("run_id", result.RunID),
+		zap.String("trigger", trigger),
+	)
+
+	var syncErr error
+	for _, provider := range s.providers {
+		if err := s.syncProvider(ctx, provider, result); err != nil {
+			// Keep going so one broken catalog doesn't block the others
+			s.logger.Error("failed to sync provider catalog",
+				zap.String("provider", provider),
+				zap.Error(err),
+			)
+			syncErr = errors.Join(syncErr, fmt.Errorf("%s: %w", provider, err))
+		}
+	}
+	result.FinishedAt = time.Now()
+
+	status, errText := "completed", (*string)(nil)
+	if syncErr != nil {
+		status = "failed"
+		msg := syncErr.Error()
+		errText = &msg
+	}
+
+	if _, err := s.db.Pool.Exec(ctx, `
+		UPDATE catalog_sync_runs
+		SET status = $2, instance_types_upserted = $3, availability_upserted = $4,
+		    instance_types_missing = $5, error = $6, finished_at = NOW()
+		WHERE id = $1
+	`, result.RunID, status, result.InstanceTypesUpserted, result.AvailabilityUpserted,
+		result.InstanceTypesMissing, errText); err != nil {
+		s.logger.Warn("failed to update sync run", zap.Error(err))
+	}
+
+	s.logger.Info("catalog sync finished",
+		zap.String("run_id", result.RunID),
+		zap.String("status", status),
+		zap.Int("instance_types_upserted", result.InstanceTypesUpserted),
+		zap.Int("availability_upserted", result.AvailabilityUpserted),
+		zap.Int("instance_types_missing", result.InstanceTypesMissing),
+		zap.Duration("duration", result.FinishedAt.Sub(result.StartedAt)),
+	)
+
+	return result, syncErr
+}
+
+// syncProvider fetches one provider's catalog and applies it
+func (s *CatalogSyncer) syncProvider(ctx context.Context, provider string, result *CatalogSyncResult) error {
+	url := fmt.Sprintf("%s/%s/vms.csv", s.catalogURL, provider)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch catalog: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch catalog: unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	instanceTypes, err := ParseCatalogCSV(provider, resp.Body)
+	if err != nil {
+		return fmt.Errorf("parse catalog: %w", err)
+	}
+	if len(instanceTypes) == 0 {
+		// Never treat an empty catalog as "everything disappeared"
+		return fmt.Errorf("catalog for %s contained no GPU instance types", provider)
+	}
+
+	seen := make([]string, 0, len(instanceTypes))
+	for _, it := range instanceTypes {
+		regions, err := s.upsertInstanceType(ctx, it)
+		if err != nil {
+			return fmt.Errorf("upsert %s: %w", it.InstanceType, err)
+		}
+		result.InstanceTypesUpserted++
+		result.AvailabilityUpserted += regions
+		seen = append(seen, it.InstanceType)
+	}
+
+	missing, err := s.flagMissing(ctx, provider, seen)
+	if err != nil {
+		return fmt.Errorf("flag missing instance types: %w", err)
+	}
+	result.InstanceTypesMissing += missing
+
+	return nil
+}
+
+// upsertInstanceType writes an instance type and its region availability,
+// returning the number of availability rows written
+func (s *CatalogSyncer) upsertInstanceType(ctx context.Context, it *CatalogInstanceType) (int, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var spotPrice *float64
+	if it.SupportsSpot {
+		spotPrice = &it.SpotPricePerHour
+	}
+
+	var instanceTypeID int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO instance_types (
+			provider, instance_type, instance_name,
+			vcpu_count, memory_gb, gpu_count, gpu_memory_gb, gpu_model,
+			price_per_hour, spot_price_per_hour, is_available, supports_spot,
+			source, last_synced_at, missing_since
+		) VALUES ($1, $2, $2, $3, $4, $5, $6, $7, $8, $9, true, $10, 'catalog', NOW(), NULL)
+		ON CONFLICT (provider, instance_type) DO UPDATE
+		SET vcpu_count = EXCLUDED.vcpu_count,
+		    memory_gb = EXCLUDED.memory_gb,
+		    gpu_count = EXCLUDED.gpu_count,
+		    gpu_memory_gb = EXCLUDED.gpu_memory_gb,
+		    price_per_hour = EXCLUDED.price_per_hour,
+		    spot_price_per_hour = EXCLUDED.spot_price_per_hour,
+		    supports_spot = EXCLUDED.supports_spot,
+		    is_available = CASE WHEN instance_types.missing_since IS NOT NULL THEN true ELSE instance_types.is_available END,
+		    last_synced_at = NOW(),
+		    missing_since = NULL,
+		    updated_at = NOW()
+		RETURNING id
+	`, it.Provider, it.InstanceType, it.VCPUCount, it.MemoryGB, it.GPUCount,
+		it.GPUMemoryGB, it.GPUModel, it.PricePerHour, spotPrice, it.SupportsSpot,
+	).Scan(&instanceTypeID)
+	if err != nil {
+		return 0, err
+	}
+
+	regions := make([]string, 0, len(it.Regions))
+	for region := range it.Regions {
+		regions = append(regions, region)
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO region_instance_availability (region_code, instance_type_id, is_available, stock_status, last_synced_at)
+			VALUES ($1, $2, true, 'available', NOW())
+			ON CONFLICT (region_code, instance_type_id) DO UPDATE
+			SET is_available = true, last_synced_at = NOW(), updated_at = NOW()
+		`, region, instanceTypeID); err != nil {
+			return 0, err
+		}
+	}
+
+	// Regions no longer offering this instance type
+	if _, err := tx.Exec(ctx, `
+		UPDATE region_instance_availability
+		SET is_available = false, stock_status = 'out_of_stock', updated_at = NOW()
+		WHERE instance_type_id = $1 AND last_synced_at IS NOT NULL AND NOT (region_code = ANY($2))
+	`, instanceTypeID, regions); err != nil {
+		return 0, err
+	}
+
+	return len(regions), tx.Commit(ctx)
+}
+
+// flagMissing marks instance types that are no longer in the provider catalog.
+// Rows created by the sync are also made unavailable; manually created rows
+// are only flagged so an operator can decide.
+func (s *CatalogSyncer) flagMissing(ctx context.Context, provider string, seen []string) (int, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		UPDATE instance_types
+		SET missing_since = COALESCE(missing_since, NOW()),
+		    is_available = CASE WHEN source = 'catalog' THEN false ELSE is_available END,
+		    updated_at = NOW()
+		WHERE provider = $1 AND NOT (instance_type = ANY($2)) AND missing_since IS NULL
+		RETURNING instance_type
+	`, provider, seen)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var flagged []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err == nil {
+			flagged = append(flagged, name)
+		}
+	}
+
+	if len(flagged) > 0 {
+		s.logger.Warn("instance types disappeared from provider catalog",
+			zap.String("provider", provider),
+			zap.Strings("instance_types", flagged),
+		)
+	}
+	return len(flagged), rows.Err()
+}
+
+// gpuMemoryPattern extracts total GPU memory from the catalog's GpuInfo column,
+// which is a Python-literal dict such as
+// {'Gpus': [...], 'TotalGpuMemoryInMiB': 16384}
+var gpuMemoryPattern = regexp.MustCompile(`TotalGpuMemoryInMiB'?"?\s*:\s*([0-9.]+)`)
+
+// ParseCatalogCSV parses a SkyPilot catalog vms.csv and aggregates GPU
+// instance types across regions. Non-GPU rows are skipped.
+func ParseCatalogCSV(provider string, r io.Reader) (map[string]*CatalogInstanceType, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"InstanceType", "AcceleratorName", "AcceleratorCount", "Region"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	number := func(record []string, name string) float64 {
+		v, _ := strconv.ParseFloat(field(record, name), 64)
+		return v
+	}
+
+	instanceTypes := make(map[string]*CatalogInstanceType)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read record: %w", err)
+		}
+
+		name := field(record, "InstanceType")
+		accelerator := field(record, "AcceleratorName")
+		gpuCount := int(number(record, "AcceleratorCount"))
+		if name == "" || accelerator == "" || gpuCount <= 0 {
+			continue
+		}
+
+		it, ok := instanceTypes[name]
+		if !ok {
+			it = &CatalogInstanceType{
+				Provider:     provider,
+				InstanceType: name,
+				VCPUCount:    int(number(record, "vCPUs")),
+				MemoryGB:     number(record, "MemoryGiB"),
+				GPUCount:     gpuCount,
+				GPUModel:     "NVIDIA " + accelerator,
+				Regions:      make(map[string]bool),
+			}
+			if m := gpuMemoryPattern.FindStringSubmatch(field(record, "GpuInfo")); m != nil {
+				mib, _ := strconv.ParseFloat(m[1], 64)
+				it.GPUMemoryGB = mib / 1024
+			}
+			instanceTypes[name] = it
+		}
+
+		if region := field(record, "Region"); region != "" {
+			it.Regions[region] = true
+		}
+
+		// Keep the cheapest price seen across regions
+		if price := number(record, "Price"); price > 0 && (it.PricePerHour == 0 || price < it.PricePerHour) {
+			it.PricePerHour = price
+		}
+		if spot := number(record, "SpotPrice"); spot > 0 && (it.SpotPricePerHour == 0 || spot < it.SpotPricePerHour) {
+			it.SpotPricePerHour = spot
+			it.SupportsSpot = true
+		}
+	}
+
+	return instanceTypes, nil
+}
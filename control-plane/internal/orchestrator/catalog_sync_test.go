@@ -0,0 +1,46 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCatalogCSV(t *testing.T) {
+	csvData := `InstanceType,AcceleratorName,AcceleratorCount,vCPUs,MemoryGiB,GpuInfo,Price,SpotPrice,Region,AvailabilityZone
+g5.xlarge,A10G,1.0,4.0,16.0,"{'Gpus': [{'Name': 'A10G', 'Manufacturer': 'NVIDIA', 'Count': 1.0, 'MemoryInfo': {'SizeInMiB': 24576}}], 'TotalGpuMemoryInMiB': 24576}",1.006,0.45,us-east-1,us-east-1a
+g5.xlarge,A10G,1.0,4.0,16.0,"{'TotalGpuMemoryInMiB': 24576}",1.1,0.30,eu-west-1,eu-west-1a
+m5.large,,,2.0,8.0,,0.096,0.03,us-east-1,us-east-1a
+`
+
+	instanceTypes, err := ParseCatalogCSV("aws", strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(instanceTypes) != 1 {
+		t.Fatalf("expected only GPU instance types, got %d", len(instanceTypes))
+	}
+
+	it := instanceTypes["g5.xlarge"]
+	if it == nil {
+		t.Fatal("expected g5.xlarge to be parsed")
+	}
+	if it.GPUModel != "NVIDIA A10G" || it.GPUCount != 1 {
+		t.Errorf("unexpected GPU info: %s x%d", it.GPUModel, it.GPUCount)
+	}
+	if it.GPUMemoryGB != 24 {
+		t.Errorf("expected 24GB GPU memory, got %v", it.GPUMemoryGB)
+	}
+	if it.PricePerHour != 1.006 || it.SpotPricePerHour != 0.30 || !it.SupportsSpot {
+		t.Errorf("expected cheapest prices across regions, got %v / %v", it.PricePerHour, it.SpotPricePerHour)
+	}
+	if !it.Regions["us-east-1"] || !it.Regions["eu-west-1"] {
+		t.Errorf("expected both regions, got %v", it.Regions)
+	}
+}
+
+func TestParseCatalogCSVMissingColumns(t *testing.T) {
+	if _, err := ParseCatalogCSV("aws", strings.NewReader("Foo,Bar\n1,2\n")); err == nil {
+		t.Error("expected error for catalog without required columns")
+	}
+}
@@ -0,0 +1,324 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// primaryVLLMPort is where a node's primary model is served
+	primaryVLLMPort = 8000
+
+	// firstColocatedPort and lastColocatedPort bound the ports colocated
+	// models are served on
+	firstColocatedPort = 8001
+	lastColocatedPort  = 8099
+
+	// maxColocatedModels caps the models served beside a node's primary
+	// model; every one is a vLLM instance sharing the GPUs
+	maxColocatedModels = 3
+
+	// minColocatedShare is the least GPU memory fraction a vLLM instance
+	// sharing a node is tuned to
+	minColocatedShare = 0.05
+)
+
+// ColocatedModel is a model a node serves beside NodeConfig.Model, from a
+// vLLM instance of its own on its own port. Every colocated model is
+// registered as a node of its own, pointing at the host node, so the load
+// balancer routes it to its port.
+//
+// The instances share the node's GPUs, so their gpu_memory_utilization
+// values add up to at most what a single instance would get. Zero memory
+// settings are tuned like the primary model's, from the model's share of
+// the GPU memory. The launch's vllm_args only apply to the primary model.
+type ColocatedModel struct {
+	Model string `json:"model"`
+
+	// Port is the vLLM instance's port, between 8001 and 8099
+	// Default: the lowest port no other model of the node uses
+	Port int `json:"port,omitempty"`
+
+	GPUMemoryUtilization float64 `json:"gpu_memory_utilization,omitempty"`
+	MaxModelLen          int     `json:"max_model_len,omitempty"`
+	MaxNumSeqs           int     `json:"max_num_seqs,omitempty"`
+}
+
+// catalogModel is what the catalog knows about a model
+type catalogModel struct {
+	Name string
+	// GB estimates the model's weights; zero when unknown
+	GB float64
+	// MinVLLMVersion is the first vLLM release serving the model; empty
+	// when unknown
+	MinVLLMVersion string
+	// ContextLength is the model's context window; zero when unknown
+	ContextLength int
+}
+
+// checkColocatedModels validates the models colocated on the node and
+// assigns the ports left unset
+func checkColocatedModels(config *NodeConfig, errs *ConfigError) {
+	if len(config.ColocatedModels) == 0 {
+		return
+	}
+
+	if config.Runtime != DefaultRuntime {
+		errs.add("colocated_models", "are only served by the %s runtime", DefaultRuntime)
+	}
+	if config.DeploymentID != "" {
+		errs.add("colocated_models", "can't be used by deployment nodes, which serve their deployment's model only")
+	}
+	if len(config.ColocatedModels) > maxColocatedModels {
+		errs.add("colocated_models", "at most %d models can be served beside the node's model", maxColocatedModels)
+	}
+
+	models := map[string]bool{config.Model: true}
+	ports := map[int]bool{primaryVLLMPort: true}
+	budget := config.GPUMemoryUtilization
+	unset := 0
+	if budget == 0 {
+		unset++
+	}
+	for i := range config.ColocatedModels {
+		m := &config.ColocatedModels[i]
+		field := "colocated_models[" + strconv.Itoa(i) + "]"
+
+		m.Model = strings.TrimSpace(m.Model)
+		switch {
+		case m.Model == "":
+			errs.add(field+".model", "is required")
+		case models[m.Model]:
+			errs.add(field+".model", "%s is already served by the node", m.Model)
+		}
+		models[m.Model] = true
+
+		switch {
+		case m.Port == 0:
+		case m.Port < firstColocatedPort || m.Port > lastColocatedPort:
+			errs.add(field+".port", "must be between %d and %d", firstColocatedPort, lastColocatedPort)
+		case ports[m.Port]:
+			errs.add(field+".port", "%d is already used by another model of the node", m.Port)
+		default:
+			ports[m.Port] = true
+		}
+
+		if m.GPUMemoryUtilization < 0 || m.GPUMemoryUtilization > 1 {
+			errs.add(field+".gpu_memory_utilization", "must be between 0 and 1")
+		} else if m.GPUMemoryUtilization == 0 {
+			unset++
+		}
+		budget += m.GPUMemoryUtilization
+		if m.MaxModelLen < 0 {
+			errs.add(field+".max_model_len", "must be positive")
+		}
+		if m.MaxNumSeqs < 0 {
+			errs.add(field+".max_num_seqs", "must be positive")
+		}
+	}
+
+	// Unset ports take the lowest free ones, in order
+	next := firstColocatedPort
+	for i := range config.ColocatedModels {
+		m := &config.ColocatedModels[i]
+		if m.Port != 0 {
+			continue
+		}
+		for ports[next] {
+			next++
+		}
+		m.Port = next
+		ports[next] = true
+	}
+
+	// Models without a memory setting need some of the budget left
+	if budget+minColocatedShare*float64(unset) > nodes.DefaultGPUMemoryUtilization+1e-9 {
+		errs.add("gpu_memory_utilization", "the node's models share the GPUs; their settings add up to %.2f, "+
+			"leaving too little of the %.2f budget for the %d without one", budget, nodes.DefaultGPUMemoryUtilization, unset)
+	}
+}
+
+// describeModels names the models a launch serves, for messages
+func describeModels(config *NodeConfig) string {
+	switch n := len(config.ColocatedModels); n {
+	case 0:
+		return config.Model
+	case 1:
+		return config.Model + " and 1 colocated model"
+	default:
+		return fmt.Sprintf("%s and %d colocated models", config.Model, n)
+	}
+}
+
+// weightsGB estimates the weights of every model the launch serves; models
+// of unknown size are left out
+func (c *launchCatalog) weightsGB() float64 {
+	total := c.ModelGB
+	for _, m := range c.Colocated {
+		total += m.GB
+	}
+	return total
+}
+
+// loadCatalogModel reads a model's size, vLLM requirement and context
+// window. The size is the registry's VRAM requirement, else the parameter
+// count of well-known models at 2 bytes per parameter. The vLLM
+// requirement falls back to well-known architectures the same way.
+func (o *SkyPilotOrchestrator) loadCatalogModel(ctx context.Context, model string) (catalogModel, error) {
+	m := catalogModel{Name: model}
+	var vramGB int
+	var minVLLM string
+	err := o.db.Pool.QueryRow(ctx, `
+		SELECT vram_required_gb, COALESCE(min_vllm_version, ''), COALESCE(context_length, 0)
+		FROM models WHERE name = $1
+	`, model).Scan(&vramGB, &minVLLM, &m.ContextLength)
+	switch {
+	case err == nil:
+		m.GB = float64(vramGB)
+	case errors.Is(err, pgx.ErrNoRows):
+		if params, ok := NewModelConfigGenerator().modelSizes[model]; ok {
+			m.GB = float64(params) * 2 / 1e9
+		}
+	default:
+		return m, err
+	}
+	m.MinVLLMVersion = MinVLLMVersion(model, minVLLM)
+	return m, nil
+}
+
+// splitGPUMemory shares budget, the fraction of each GPU's memory vLLM
+// gets, among the vLLM instances of a node. Instances with a utilization
+// set keep it; what is left goes to the others by weight size, or evenly
+// when a size is unknown. Shares are rounded down to hundredths so they
+// don't add up to more than the budget.
+func splitGPUMemory(budget float64, set, sizesGB []float64) []float64 {
+	shares := make([]float64, len(set))
+	remaining := budget
+	var unset int
+	var unsetGB float64
+	sized := true
+	for i, u := range set {
+		if u > 0 {
+			shares[i] = u
+			remaining -= u
+			continue
+		}
+		unset++
+		unsetGB += sizesGB[i]
+		sized = sized && sizesGB[i] > 0
+	}
+	if unset == 0 {
+		return shares
+	}
+
+	remaining = math.Max(remaining, minColocatedShare*float64(unset))
+	for i, u := range set {
+		if u > 0 {
+			continue
+		}
+		share := remaining / float64(unset)
+		if sized {
+			share = remaining * sizesGB[i] / unsetGB
+		}
+		shares[i] = math.Max(math.Floor(share*100+1e-9)/100, minColocatedShare)
+	}
+	return shares
+}
+
+// tuneColocated fills in the vLLM memory settings of a node serving
+// several models: the GPU memory budget is split across the models and
+// each model's context length and batch size tuned for its share. The
+// catalog may be nil, in which case the budget is split evenly.
+func (c *NodeConfig) tuneColocated(catalog *launchCatalog, vramPerGPU int) {
+	n := 1 + len(c.ColocatedModels)
+	set := make([]float64, n)
+	sizes := make([]float64, n)
+	contexts := make([]int, n)
+
+	set[0] = c.GPUMemoryUtilization
+	for i, m := range c.ColocatedModels {
+		set[i+1] = m.GPUMemoryUtilization
+	}
+	if catalog != nil {
+		sizes[0], contexts[0] = catalog.ModelGB, catalog.ContextLength
+		for i, m := range catalog.Colocated {
+			if i+1 < n {
+				sizes[i+1], contexts[i+1] = m.GB, m.ContextLength
+			}
+		}
+	}
+
+	shares := splitGPUMemory(nodes.GPUMemoryBudget(vramPerGPU), set, sizes)
+	c.applyTuning(nodes.TuneVLLMShare(sizes[0], vramPerGPU, c.GPUCount, contexts[0], shares[0]))
+	for i := range c.ColocatedModels {
+		c.ColocatedModels[i].applyTuning(nodes.TuneVLLMShare(sizes[i+1], vramPerGPU, c.GPUCount, contexts[i+1], shares[i+1]))
+	}
+}
+
+// applyTuning sets the vLLM memory settings that are still zero
+func (m *ColocatedModel) applyTuning(t nodes.VLLMTuning) {
+	if m.GPUMemoryUtilization == 0 {
+		m.GPUMemoryUtilization = t.GPUMemoryUtilization
+	}
+	if m.MaxModelLen == 0 {
+		m.MaxModelLen = t.MaxModelLen
+	}
+	if m.MaxNumSeqs == 0 {
+		m.MaxNumSeqs = t.MaxNumSeqs
+	}
+}
+
+// vllmTuning is the vLLM memory settings the model's instance runs with
+func (m *ColocatedModel) vllmTuning(vramPerGPU int) nodes.VLLMTuning {
+	return nodes.VLLMTuning{
+		GPUMemoryUtilization: m.GPUMemoryUtilization,
+		MaxModelLen:          m.MaxModelLen,
+		MaxNumSeqs:           m.MaxNumSeqs,
+		VRAMPerGPUGB:         vramPerGPU,
+	}
+}
+
+// checkColocatedTemplate rejects launching colocated models with a task
+// template that doesn't start them, such as an admin override written
+// before templates could
+func checkColocatedTemplate(config *NodeConfig, taskTemplate *TaskTemplate) error {
+	if len(config.ColocatedModels) == 0 || taskTemplate.servesColocated() {
+		return nil
+	}
+	return &ConfigError{Fields: []FieldError{{
+		Field:   "colocated_models",
+		Message: fmt.Sprintf("task template %s doesn't serve colocated models", taskTemplate.Ref),
+	}}}
+}
+
+// registerColocated registers each model colocated on a node as a node of
+// its own, on the host's machine and the model's port. The rows stay
+// initializing until the host's agent registers and activates them.
+func (o *SkyPilotOrchestrator) registerColocated(ctx context.Context, config NodeConfig, host nodes.Registration) error {
+	for _, m := range config.ColocatedModels {
+		reg := host
+		reg.ID = nodes.ColocatedNodeID(host.ID, m.Model)
+		reg.ClusterName = ""
+		reg.ModelName = m.Model
+		reg.HostNodeID = &host.ID
+		reg.ServingPort = m.Port
+		reg.Runtime = &nodes.RuntimeSpec{Model: m.Model, VLLMVersion: reg.VLLMVersion}
+		if config.TensorParallel > 0 {
+			reg.Runtime.VLLMArgs = []string{"--tensor-parallel-size", strconv.Itoa(config.TensorParallel)}
+		}
+		tuning := m.vllmTuning(config.tunedVRAMGB)
+		reg.Tuning = &tuning
+
+		if _, _, err := o.registry.Register(ctx, reg); err != nil {
+			return fmt.Errorf("failed to register colocated model %s: %w", m.Model, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crosslogic/control-plane/internal/config"
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+func TestCheckColocatedModels(t *testing.T) {
+	base := NodeConfig{Model: "meta-llama/Llama-3.1-8B-Instruct", Runtime: DefaultRuntime}
+
+	t.Run("ports default to the lowest free ones", func(t *testing.T) {
+		cfg := base
+		cfg.ColocatedModels = []ColocatedModel{
+			{Model: " Qwen/Qwen2.5-0.5B-Instruct "},
+			{Model: "Qwen/Qwen2.5-1.5B-Instruct", Port: 8001},
+			{Model: "HuggingFaceTB/SmolLM2-360M-Instruct"},
+		}
+		var errs ConfigError
+		checkColocatedModels(&cfg, &errs)
+		if err := errs.err(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i, want := range []int{8002, 8001, 8003} {
+			if got := cfg.ColocatedModels[i].Port; got != want {
+				t.Errorf("colocated_models[%d].port = %d, want %d", i, got, want)
+			}
+		}
+		if cfg.ColocatedModels[0].Model != "Qwen/Qwen2.5-0.5B-Instruct" {
+			t.Errorf("model = %q, want it trimmed", cfg.ColocatedModels[0].Model)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		modify func(*NodeConfig)
+		field  string
+	}{
+		{"another runtime", func(c *NodeConfig) {
+			c.Runtime = "diffusion"
+			c.ColocatedModels = []ColocatedModel{{Model: "a"}}
+		}, "colocated_models"},
+		{"deployment node", func(c *NodeConfig) {
+			c.DeploymentID = "00000000-0000-4000-8000-000000000000"
+			c.ColocatedModels = []ColocatedModel{{Model: "a"}}
+		}, "colocated_models"},
+		{"too many", func(c *NodeConfig) {
+			c.ColocatedModels = []ColocatedModel{{Model: "a"}, {Model: "b"}, {Model: "c"}, {Model: "d"}}
+		}, "colocated_models"},
+		{"primary model again", func(c *NodeConfig) {
+			c.ColocatedModels = []ColocatedModel{{Model: c.Model}}
+		}, "colocated_models[0].model"},
+		{"duplicate model", func(c *NodeConfig) {
+			c.ColocatedModels = []ColocatedModel{{Model: "a"}, {Model: "a"}}
+		}, "colocated_models[1].model"},
+		{"primary port", func(c *NodeConfig) {
+			c.ColocatedModels = []ColocatedModel{{Model: "a", Port: 8000}}
+		}, "colocated_models[0].port"},
+		{"duplicate port", func(c *NodeConfig) {
+			c.ColocatedModels = []ColocatedModel{{Model: "a", Port: 8005}, {Model: "b", Port: 8005}}
+		}, "colocated_models[1].port"},
+		{"memory over budget", func(c *NodeConfig) {
+			c.GPUMemoryUtilization = 0.6
+			c.ColocatedModels = []ColocatedModel{{Model: "a", GPUMemoryUtilization: 0.4}}
+		}, "gpu_memory_utilization"},
+		{"nothing left for unset models", func(c *NodeConfig) {
+			c.GPUMemoryUtilization = 0.92
+			c.ColocatedModels = []ColocatedModel{{Model: "a"}}
+		}, "gpu_memory_utilization"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			tt.modify(&cfg)
+			var errs ConfigError
+			checkColocatedModels(&cfg, &errs)
+			fields := fieldMessages(t, errs.err())
+			if _, ok := fields[tt.field]; !ok {
+				t.Errorf("missing %s error in %v", tt.field, fields)
+			}
+		})
+	}
+}
+
+func TestSplitGPUMemory(t *testing.T) {
+	tests := []struct {
+		name  string
+		set   []float64
+		sizes []float64
+		want  []float64
+	}{
+		{"by weight size", []float64{0, 0}, []float64{16, 3}, []float64{0.8, 0.15}},
+		{"evenly when a size is unknown", []float64{0, 0, 0}, []float64{16, 0, 2}, []float64{0.31, 0.31, 0.31}},
+		{"set shares are kept", []float64{0.5, 0, 0}, []float64{16, 1, 3}, []float64{0.5, 0.11, 0.33}},
+		{"at least the minimum share", []float64{0.93, 0}, []float64{16, 1}, []float64{0.93, minColocatedShare}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitGPUMemory(0.95, tt.set, tt.sizes)
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("splitGPUMemory() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTuneColocated(t *testing.T) {
+	cfg := NodeConfig{
+		GPUCount: 1,
+		ColocatedModels: []ColocatedModel{
+			{Model: "small", MaxNumSeqs: 32},
+		},
+	}
+	catalog := &launchCatalog{ModelGB: 16, ContextLength: 8192, Colocated: []catalogModel{{Name: "small", GB: 3, ContextLength: 4096}}}
+	cfg.tuneColocated(catalog, 80)
+
+	m := cfg.ColocatedModels[0]
+	if total := cfg.GPUMemoryUtilization + m.GPUMemoryUtilization; total > 0.95+1e-9 {
+		t.Errorf("gpu_memory_utilization adds up to %.2f, over the 0.95 budget", total)
+	}
+	if cfg.GPUMemoryUtilization <= m.GPUMemoryUtilization {
+		t.Errorf("primary share %.2f, want more than the smaller model's %.2f", cfg.GPUMemoryUtilization, m.GPUMemoryUtilization)
+	}
+	if cfg.MaxModelLen != 8192 || m.MaxModelLen != 4096 {
+		t.Errorf("max_model_len = %d / %d, want each model's context window", cfg.MaxModelLen, m.MaxModelLen)
+	}
+	if m.MaxNumSeqs != 32 {
+		t.Errorf("max_num_seqs = %d, want the value set to be kept", m.MaxNumSeqs)
+	}
+}
+
+func TestGenerateTaskYAMLColocated(t *testing.T) {
+	logger := zap.NewNop()
+	db := &database.Database{Pool: &pgxpool.Pool{}}
+	orch, _ := NewSkyPilotOrchestrator(db, &cache.Cache{}, logger, "https://api.test.com", testVLLMVersion, testTorchVersion, events.NewBus(logger), config.R2Config{}, config.SkyPilotConfig{})
+	if err := orch.SetHardening([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := NodeConfig{
+		NodeID:   "00000000-0000-4000-8000-000000000001",
+		Provider: "aws",
+		Region:   "us-east-1",
+		GPU:      "A100",
+		GPUCount: 1,
+		Model:    "meta-llama/Llama-3.1-8B-Instruct",
+		ColocatedModels: []ColocatedModel{
+			{Model: "Qwen/Qwen2.5-0.5B-Instruct", Port: 8001},
+			{Model: "Qwen/Qwen2.5-1.5B-Instruct", Port: 8002},
+		},
+	}
+	yaml, err := orch.generateTaskYAML(cfg, "cic-test-cluster")
+	if err != nil {
+		t.Fatalf("generateTaskYAML failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"for port in 8000 8001 8002; do",
+		`--model "$(resolve_model_path "Qwen/Qwen2.5-0.5B-Instruct")"`,
+		"--port 8002",
+		"wait_for_vllm 8001 $! /tmp/vllm-8001.log",
+		// Untuned shares are split evenly rather than each taking the default
+		"--gpu-memory-utilization 0.31",
+	} {
+		if !strings.Contains(yaml, want) {
+			t.Errorf("YAML doesn't contain %q", want)
+		}
+	}
+	if cfg.ColocatedModels[0].GPUMemoryUtilization != 0 {
+		t.Error("rendering changed the caller's colocated models")
+	}
+}
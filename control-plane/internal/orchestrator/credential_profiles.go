@@ -0,0 +1,237 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/skypilot"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// SkyPilot credential profiles (API Server mode).
+//
+// Without profiles every launch carries the tenant's decrypted cloud
+// credentials inline. With credential profiles enabled, each tenant
+// credential is registered once with the API server as a named profile and
+// launches reference it by ID. The profile is created lazily on first launch,
+// rotated in place when the credential is updated (skypilot_profile_synced_at
+// older than updated_at marks it stale), and removed when the credential is
+// deleted.
+
+// credentialProfileName is the API server profile name for a credential
+func credentialProfileName(provider string, credentialID uuid.UUID) string {
+	return fmt.Sprintf("cic-%s-%s", provider, credentialID)
+}
+
+// profileCredential is the subset of a cloud_credentials row needed to keep
+// its SkyPilot profile in sync
+type profileCredential struct {
+	ID        uuid.UUID
+	TenantID  uuid.UUID
+	Provider  string
+	Encrypted []byte
+	UpdatedAt time.Time
+	ProfileID *string
+	SyncedAt  *time.Time
+}
+
+// stale reports whether the profile is missing or predates the last update
+func (c *profileCredential) stale() bool {
+	return c.ProfileID == nil || c.SyncedAt == nil || c.SyncedAt.Before(c.UpdatedAt)
+}
+
+// ensureCredentialProfile returns the ID of an up-to-date SkyPilot profile for
+// the tenant's launch credential, creating or rotating it as needed. The row
+// is locked while syncing so concurrent launches don't register duplicates.
+func (o *SkyPilotOrchestrator) ensureCredentialProfile(ctx context.Context, tenantID, provider string) (string, error) {
+	if tenantID == "" {
+		return "", fmt.Errorf("tenant ID is required for API mode")
+	}
+
+	tenantUUID, err := uuid.Parse(tenantID)
+	if err != nil {
+		return "", fmt.Errorf("invalid tenant ID: %w", err)
+	}
+
+	tx, err := o.db.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	// Same selection as getTenantCredentials
+	cred, err := scanProfileCredential(tx.QueryRow(ctx, `
+		SELECT id, tenant_id, provider, credentials_encrypted, updated_at,
+		       skypilot_profile_id, skypilot_profile_synced_at
+		FROM cloud_credentials
+		WHERE tenant_id = $1
+		  AND provider = $2
+		  AND status = 'active'
+		  AND (is_default = true OR environment_id IS NULL)
+		ORDER BY is_default DESC
+		LIMIT 1
+		FOR UPDATE
+	`, tenantUUID, provider))
+	if err != nil {
+		return "", fmt.Errorf("failed to query credentials: %w", err)
+	}
+
+	if !cred.stale() {
+		return *cred.ProfileID, nil
+	}
+
+	profileID, err := o.syncCredentialProfile(ctx, tx, cred)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return profileID, nil
+}
+
+// SyncCredentialProfile pushes an updated credential to its SkyPilot profile
+// so the secret is rotated centrally. Credentials without a profile are left
+// alone; their profile is created on the next launch.
+func (o *SkyPilotOrchestrator) SyncCredentialProfile(ctx context.Context, credentialID uuid.UUID) error {
+	if !o.useAPIServer || !o.credentialProfiles {
+		return nil
+	}
+
+	tx, err := o.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	cred, err := scanProfileCredential(tx.QueryRow(ctx, `
+		SELECT id, tenant_id, provider, credentials_encrypted, updated_at,
+		       skypilot_profile_id, skypilot_profile_synced_at
+		FROM cloud_credentials
+		WHERE id = $1 AND status = 'active'
+		FOR UPDATE
+	`, credentialID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query credential: %w", err)
+	}
+
+	if cred.ProfileID == nil || !cred.stale() {
+		return nil
+	}
+
+	if _, err := o.syncCredentialProfile(ctx, tx, cred); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteCredentialProfile removes a credential's SkyPilot profile. Call it
+// after the credential is deleted so no secret outlives it on the API server.
+func (o *SkyPilotOrchestrator) DeleteCredentialProfile(ctx context.Context, credentialID uuid.UUID) error {
+	if !o.useAPIServer || !o.credentialProfiles {
+		return nil
+	}
+
+	var tenantID uuid.UUID
+	var profileID *string
+	err := o.db.Pool.QueryRow(ctx, `
+		SELECT tenant_id, skypilot_profile_id FROM cloud_credentials WHERE id = $1
+	`, credentialID).Scan(&tenantID, &profileID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query credential: %w", err)
+	}
+	if profileID == nil {
+		return nil
+	}
+
+	wsCtx := o.workspaceContext(ctx, tenantID.String())
+	if err := o.apiClient.DeleteCredentialProfile(wsCtx, *profileID); err != nil {
+		return fmt.Errorf("failed to delete credential profile: %w", err)
+	}
+
+	_, err = o.db.Pool.Exec(ctx, `
+		UPDATE cloud_credentials
+		SET skypilot_profile_id = NULL, skypilot_profile_synced_at = NULL
+		WHERE id = $1
+	`, credentialID)
+	if err != nil {
+		return fmt.Errorf("failed to clear credential profile: %w", err)
+	}
+
+	o.logger.Info("deleted credential profile",
+		zap.String("credential_id", credentialID.String()),
+		zap.String("profile_id", *profileID),
+	)
+
+	return nil
+}
+
+// syncCredentialProfile creates or updates the profile for a locked
+// credential row and records the sync on it
+func (o *SkyPilotOrchestrator) syncCredentialProfile(ctx context.Context, tx pgx.Tx, cred *profileCredential) (string, error) {
+	decryptedJSON, err := o.decryptCredentials(cred.Encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt credentials: %w", err)
+	}
+
+	cloudCreds, err := parseCloudCredentials(cred.Provider, decryptedJSON)
+	if err != nil {
+		return "", err
+	}
+
+	req := skypilot.CredentialProfileRequest{
+		Name:             credentialProfileName(cred.Provider, cred.ID),
+		CloudCredentials: cloudCreds,
+	}
+
+	wsCtx := o.workspaceContext(ctx, cred.TenantID.String())
+
+	var profile *skypilot.CredentialProfile
+	if cred.ProfileID == nil {
+		profile, err = o.apiClient.CreateCredentialProfile(wsCtx, req)
+	} else {
+		profile, err = o.apiClient.UpdateCredentialProfile(wsCtx, *cred.ProfileID, req)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sync credential profile: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE cloud_credentials
+		SET skypilot_profile_id = $2, skypilot_profile_synced_at = NOW()
+		WHERE id = $1
+	`, cred.ID, profile.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to record credential profile: %w", err)
+	}
+
+	o.logger.Info("synced credential profile",
+		zap.String("credential_id", cred.ID.String()),
+		zap.String("tenant_id", cred.TenantID.String()),
+		zap.String("provider", cred.Provider),
+		zap.String("profile_id", profile.ID),
+	)
+
+	return profile.ID, nil
+}
+
+func scanProfileCredential(row pgx.Row) (*profileCredential, error) {
+	var c profileCredential
+	if err := row.Scan(&c.ID, &c.TenantID, &c.Provider, &c.Encrypted, &c.UpdatedAt, &c.ProfileID, &c.SyncedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
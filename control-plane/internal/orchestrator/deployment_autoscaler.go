@@ -0,0 +1,298 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"go.uber.org/zap"
+)
+
+// Deployments with auto scaling enabled follow their load between their
+// minimum and maximum replicas. Load is the larger of two ratios: requests
+// waiting in vLLM's queue per serving node against the target, and the p95
+// latency of the deployment's recent requests against the target. Above 1
+// the deployment scales up in proportion, at most doubling at once; below
+// autoscaleScaleDownLoad it gives back one node. Scale ups wait out the
+// scale up cooldown since the last one, and scale downs the longer scale
+// down cooldown since any scaling, so new nodes get to take traffic before
+// they are judged.
+const (
+	defaultAutoscaleTargetQueueDepth  = 4
+	defaultAutoscaleTargetP95Latency  = 20 * time.Second
+	defaultAutoscaleScaleUpCooldown   = 3 * time.Minute
+	defaultAutoscaleScaleDownCooldown = 10 * time.Minute
+
+	// autoscaleLatencyWindow is how far back request latencies are read,
+	// and autoscaleMinLatencySamples how many requests the p95 needs
+	// before it counts
+	autoscaleLatencyWindow     = 5 * time.Minute
+	autoscaleMinLatencySamples = 50
+
+	// autoscaleScaleDownLoad is the load below which a node is removed; at
+	// half the target, one node fewer keeps the rest under it
+	autoscaleScaleDownLoad = 0.5
+)
+
+// AutoscaleSettings are the targets and cooldowns a deployment autoscales
+// with. Zero fields of a deployment's settings use the controller's.
+type AutoscaleSettings struct {
+	// TargetQueueDepth is the requests waiting per serving node to scale at
+	TargetQueueDepth int
+	// TargetP95Latency is the p95 request latency to scale at
+	TargetP95Latency  time.Duration
+	ScaleUpCooldown   time.Duration
+	ScaleDownCooldown time.Duration
+}
+
+// withDefaults fills the zero fields of s from defaults
+func (s AutoscaleSettings) withDefaults(defaults AutoscaleSettings) AutoscaleSettings {
+	if s.TargetQueueDepth <= 0 {
+		s.TargetQueueDepth = defaults.TargetQueueDepth
+	}
+	if s.TargetP95Latency <= 0 {
+		s.TargetP95Latency = defaults.TargetP95Latency
+	}
+	if s.ScaleUpCooldown <= 0 {
+		s.ScaleUpCooldown = defaults.ScaleUpCooldown
+	}
+	if s.ScaleDownCooldown <= 0 {
+		s.ScaleDownCooldown = defaults.ScaleDownCooldown
+	}
+	return s
+}
+
+// defaultAutoscaleSettings is the platform default autoscaling
+func defaultAutoscaleSettings() AutoscaleSettings {
+	return AutoscaleSettings{
+		TargetQueueDepth:  defaultAutoscaleTargetQueueDepth,
+		TargetP95Latency:  defaultAutoscaleTargetP95Latency,
+		ScaleUpCooldown:   defaultAutoscaleScaleUpCooldown,
+		ScaleDownCooldown: defaultAutoscaleScaleDownCooldown,
+	}
+}
+
+// SetAutoscaling sets the platform default autoscaling targets and
+// cooldowns. Zero values keep the defaults; deployments can override each.
+func (c *DeploymentController) SetAutoscaling(settings AutoscaleSettings) {
+	c.autoscaleDefaults = settings.withDefaults(c.autoscaleDefaults)
+}
+
+// autoscaleMetrics is the load a deployment is scaled on
+type autoscaleMetrics struct {
+	// QueueDepth is the requests waiting across the model's QueueNodes
+	// nodes that reported it
+	QueueDepth int64
+	QueueNodes int
+	// P95Latency is over the deployment's LatencySamples recent requests
+	P95Latency     time.Duration
+	LatencySamples int
+}
+
+// autoscaleDecision is the replica count autoscaling wants and why
+type autoscaleDecision struct {
+	Target       int
+	Reason       string
+	QueueRatio   float64
+	LatencyRatio float64
+}
+
+// Reasons for autoscaling
+const (
+	autoscaleReasonQueueDepth = "queue_depth"
+	autoscaleReasonLatency    = "p95_latency"
+	autoscaleReasonLowLoad    = "low_load"
+)
+
+// decideAutoscale picks the replica count for a deployment running active
+// nodes under the load in m, within minReplicas and maxReplicas
+func decideAutoscale(active, minReplicas, maxReplicas int, m autoscaleMetrics, s AutoscaleSettings) autoscaleDecision {
+	d := autoscaleDecision{Target: active}
+	if m.QueueNodes > 0 && s.TargetQueueDepth > 0 {
+		d.QueueRatio = float64(m.QueueDepth) / float64(m.QueueNodes) / float64(s.TargetQueueDepth)
+	}
+	if m.LatencySamples >= autoscaleMinLatencySamples && s.TargetP95Latency > 0 {
+		d.LatencyRatio = float64(m.P95Latency) / float64(s.TargetP95Latency)
+	}
+	load := math.Max(d.QueueRatio, d.LatencyRatio)
+
+	switch {
+	case load > 1:
+		d.Target = int(math.Ceil(float64(active) * load))
+		d.Target = max(min(d.Target, 2*active), active+1)
+		d.Reason = autoscaleReasonQueueDepth
+		if d.LatencyRatio > d.QueueRatio {
+			d.Reason = autoscaleReasonLatency
+		}
+	case load < autoscaleScaleDownLoad:
+		d.Target = active - 1
+		d.Reason = autoscaleReasonLowLoad
+	}
+
+	d.Target = max(min(d.Target, maxReplicas), minReplicas)
+	if d.Target == active {
+		d.Reason = ""
+	}
+	return d
+}
+
+// autoscaleCooldownUntil returns when a deployment may next scale from
+// active to target nodes, given when it last scaled up and down
+func autoscaleCooldownUntil(active, target int, lastUp, lastDown *time.Time, s AutoscaleSettings) time.Time {
+	var until time.Time
+	if target > active {
+		if lastUp != nil {
+			until = lastUp.Add(s.ScaleUpCooldown)
+		}
+		return until
+	}
+	for _, last := range []*time.Time{lastUp, lastDown} {
+		if last != nil && last.Add(s.ScaleDownCooldown).After(until) {
+			until = last.Add(s.ScaleDownCooldown)
+		}
+	}
+	return until
+}
+
+// autoscale scales a deployment with auto scaling on to its load. It only
+// runs once every node the deployment runs is serving, and each scaling is
+// claimed with a conditional update so only one control plane replica acts.
+func (c *DeploymentController) autoscale(ctx context.Context, d Deployment, activeNodes int, now time.Time) error {
+	if !d.AutoScaling || activeNodes == 0 || c.pendingLaunchCount(d.ID) > 0 {
+		return nil
+	}
+	serving, err := c.countServingNodes(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	if serving < activeNodes {
+		return nil
+	}
+
+	settings := d.Autoscale.withDefaults(c.autoscaleDefaults)
+	metrics, err := c.autoscaleMetrics(ctx, d, now)
+	if err != nil {
+		return err
+	}
+	minReplicas, maxReplicas := d.targetReplicas()
+	decision := decideAutoscale(activeNodes, minReplicas, maxReplicas, metrics, settings)
+	if decision.Target == activeNodes {
+		return nil
+	}
+
+	if until := autoscaleCooldownUntil(activeNodes, decision.Target, d.LastScaledUpAt, d.LastScaledDownAt, settings); now.Before(until) {
+		c.logger.Debug("deployment autoscaling cooling down",
+			zap.String("name", d.Name),
+			zap.Int("target", decision.Target),
+			zap.Time("until", until),
+		)
+		return nil
+	}
+	claimed, err := c.claimAutoscale(ctx, d, decision.Target > activeNodes, now)
+	if err != nil || !claimed {
+		return err
+	}
+
+	c.logger.Info("autoscaling deployment",
+		zap.String("name", d.Name),
+		zap.Int("from", activeNodes),
+		zap.Int("to", decision.Target),
+		zap.String("reason", decision.Reason),
+		zap.Int64("queue_depth", metrics.QueueDepth),
+		zap.Duration("p95_latency", metrics.P95Latency),
+	)
+	eventType := events.EventDeploymentScaledUp
+	if decision.Target > activeNodes {
+		err = c.scaleUp(ctx, d, decision.Target-activeNodes)
+	} else {
+		eventType = events.EventDeploymentScaledDown
+		err = c.scaleDown(ctx, d, activeNodes-decision.Target)
+	}
+	if err != nil {
+		return err
+	}
+	c.publishScaleEvent(ctx, eventType, d, activeNodes, decision, metrics, settings)
+	return nil
+}
+
+// autoscaleMetrics reads the deployment's load: the queue depth of the
+// model's nodes from the load balancer and the p95 latency of requests the
+// deployment's nodes served recently
+func (c *DeploymentController) autoscaleMetrics(ctx context.Context, d Deployment, now time.Time) (autoscaleMetrics, error) {
+	var m autoscaleMetrics
+	var err error
+	if m.QueueDepth, m.QueueNodes, err = c.loadBalancer.GetQueueDepth(ctx, d.ModelName); err != nil {
+		return m, fmt.Errorf("failed to read queue depth: %w", err)
+	}
+
+	var p95Ms float64
+	err = c.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY ur.latency_ms), 0), COUNT(ur.latency_ms)
+		FROM usage_records ur
+		INNER JOIN nodes n ON n.id = ur.node_id
+		WHERE n.deployment_id = $1 AND ur.timestamp > $2 AND ur.latency_ms IS NOT NULL
+	`, d.ID, now.Add(-autoscaleLatencyWindow)).Scan(&p95Ms, &m.LatencySamples)
+	if err != nil {
+		return m, fmt.Errorf("failed to read request latency: %w", err)
+	}
+	m.P95Latency = time.Duration(p95Ms * float64(time.Millisecond))
+	return m, nil
+}
+
+// claimAutoscale records the scaling on the deployment, unless another
+// replica scaled it since this one loaded it
+func (c *DeploymentController) claimAutoscale(ctx context.Context, d Deployment, up bool, now time.Time) (bool, error) {
+	query := `
+		UPDATE deployments SET last_scaled_down_at = $2
+		WHERE id = $1 AND last_scaled_up_at IS NOT DISTINCT FROM $3 AND last_scaled_down_at IS NOT DISTINCT FROM $4
+	`
+	if up {
+		query = `
+			UPDATE deployments SET last_scaled_up_at = $2
+			WHERE id = $1 AND last_scaled_up_at IS NOT DISTINCT FROM $3 AND last_scaled_down_at IS NOT DISTINCT FROM $4
+		`
+	}
+	tag, err := c.db.Pool.Exec(ctx, query, d.ID, now, d.LastScaledUpAt, d.LastScaledDownAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record scaling: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// publishScaleEvent announces an autoscaling of a deployment
+func (c *DeploymentController) publishScaleEvent(ctx context.Context, eventType events.EventType, d Deployment, from int, decision autoscaleDecision, m autoscaleMetrics, s AutoscaleSettings) {
+	if c.eventBus == nil {
+		return
+	}
+	payload := scaleEventPayload(d, from, decision, m, s)
+	if err := c.eventBus.Publish(ctx, events.NewEvent(eventType, d.TenantID, payload)); err != nil {
+		c.logger.Warn("failed to publish scale event",
+			zap.String("deployment_id", d.ID),
+			zap.String("event_type", string(eventType)),
+			zap.Error(err),
+		)
+	}
+}
+
+// scaleEventPayload describes an autoscaling for subscribers
+func scaleEventPayload(d Deployment, from int, decision autoscaleDecision, m autoscaleMetrics, s AutoscaleSettings) map[string]interface{} {
+	minReplicas, maxReplicas := d.targetReplicas()
+	return map[string]interface{}{
+		"deployment_id":      d.ID,
+		"deployment_name":    d.Name,
+		"model":              d.ModelName,
+		"from_replicas":      from,
+		"to_replicas":        decision.Target,
+		"min_replicas":       minReplicas,
+		"max_replicas":       maxReplicas,
+		"reason":             decision.Reason,
+		"queue_depth":        m.QueueDepth,
+		"queue_nodes":        m.QueueNodes,
+		"target_queue_depth": s.TargetQueueDepth,
+		"p95_latency_ms":     m.P95Latency.Milliseconds(),
+		"latency_samples":    m.LatencySamples,
+		"target_p95_ms":      s.TargetP95Latency.Milliseconds(),
+	}
+}
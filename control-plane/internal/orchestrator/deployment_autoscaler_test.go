@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecideAutoscale(t *testing.T) {
+	settings := AutoscaleSettings{TargetQueueDepth: 4, TargetP95Latency: 20 * time.Second}
+
+	cases := []struct {
+		name       string
+		active     int
+		min, max   int
+		metrics    autoscaleMetrics
+		wantTarget int
+		wantReason string
+	}{
+		{"deep queue doubles at most", 2, 1, 10, autoscaleMetrics{QueueDepth: 24, QueueNodes: 2}, 4, autoscaleReasonQueueDepth},
+		{"queue over target", 2, 1, 10, autoscaleMetrics{QueueDepth: 10, QueueNodes: 2}, 3, autoscaleReasonQueueDepth},
+		{"slow requests", 2, 1, 10, autoscaleMetrics{QueueNodes: 2, P95Latency: 30 * time.Second, LatencySamples: 100}, 3, autoscaleReasonLatency},
+		{"too few samples to count", 2, 1, 10, autoscaleMetrics{QueueDepth: 1, QueueNodes: 2, P95Latency: time.Minute, LatencySamples: 10}, 1, autoscaleReasonLowLoad},
+		{"within target", 3, 1, 10, autoscaleMetrics{QueueDepth: 6, QueueNodes: 3}, 3, ""},
+		{"idle gives back one node", 4, 1, 10, autoscaleMetrics{}, 3, autoscaleReasonLowLoad},
+		{"capped at maximum", 4, 1, 4, autoscaleMetrics{QueueDepth: 100, QueueNodes: 4}, 4, ""},
+		{"held at minimum", 2, 2, 10, autoscaleMetrics{}, 2, ""},
+	}
+	for _, tc := range cases {
+		got := decideAutoscale(tc.active, tc.min, tc.max, tc.metrics, settings)
+		if got.Target != tc.wantTarget || got.Reason != tc.wantReason {
+			t.Errorf("%s: got %d (%q), want %d (%q)", tc.name, got.Target, got.Reason, tc.wantTarget, tc.wantReason)
+		}
+	}
+}
+
+func TestAutoscaleCooldownUntil(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	at := func(ago time.Duration) *time.Time {
+		v := now.Add(-ago)
+		return &v
+	}
+	settings := AutoscaleSettings{ScaleUpCooldown: 3 * time.Minute, ScaleDownCooldown: 10 * time.Minute}
+
+	if got := autoscaleCooldownUntil(2, 3, nil, nil, settings); !got.IsZero() {
+		t.Errorf("first scale up waits until %v", got)
+	}
+	if got, want := autoscaleCooldownUntil(2, 3, at(time.Minute), at(time.Minute), settings), now.Add(2*time.Minute); !got.Equal(want) {
+		t.Errorf("scale up after a scale up: got %v, want %v", got, want)
+	}
+	if got, want := autoscaleCooldownUntil(3, 2, at(5*time.Minute), at(12*time.Minute), settings), now.Add(5*time.Minute); !got.Equal(want) {
+		t.Errorf("scale down after a scale up: got %v, want %v", got, want)
+	}
+}
+
+func TestAutoscaleSettingsWithDefaults(t *testing.T) {
+	got := AutoscaleSettings{TargetQueueDepth: 8}.withDefaults(defaultAutoscaleSettings())
+	want := defaultAutoscaleSettings()
+	want.TargetQueueDepth = 8
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
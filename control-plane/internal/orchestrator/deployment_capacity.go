@@ -0,0 +1,251 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// defaultCapacityAlertAfter is how long a deployment may serve below its
+// minimum before the incident is announced, so replacing a single node
+// doesn't notify anyone
+const defaultCapacityAlertAfter = 5 * time.Minute
+
+// SetCapacityAlerts sets how long serving nodes may stay below a
+// deployment's minimum before operators and the owning tenant are notified.
+// Deployments can override it with capacity_alert_after_minutes.
+func (c *DeploymentController) SetCapacityAlerts(after time.Duration) {
+	if after > 0 {
+		c.capacityAlertAfter = after
+	}
+}
+
+// capacityIncident is an open period of a deployment serving fewer nodes
+// than its minimum
+type capacityIncident struct {
+	ID         uuid.UUID
+	Serving    int
+	Lowest     int
+	StartedAt  time.Time
+	NotifiedAt *time.Time
+}
+
+// capacityAction is what a capacity check does with a deployment's incident
+type capacityAction int
+
+const (
+	capacityNone     capacityAction = iota
+	capacityOpen                    // record a new incident, not announced yet
+	capacityTrack                   // record the serving count
+	capacityAnnounce                // announce the incident
+	capacityUpdate                  // announce a change in serving capacity
+	capacityResolve                 // close an incident that was never announced
+	capacityRecover                 // close an announced incident and announce recovery
+)
+
+// nextCapacityAction decides what to do with a deployment's open incident,
+// nil when it has none, given its serving nodes now
+func nextCapacityAction(incident *capacityIncident, serving, minReplicas int, alertAfter time.Duration, now time.Time) capacityAction {
+	below := serving < minReplicas
+	switch {
+	case incident == nil && !below:
+		return capacityNone
+	case incident == nil:
+		return capacityOpen
+	case !below && incident.NotifiedAt == nil:
+		return capacityResolve
+	case !below:
+		return capacityRecover
+	case incident.NotifiedAt == nil && now.Sub(incident.StartedAt) >= alertAfter:
+		return capacityAnnounce
+	case incident.NotifiedAt != nil && serving != incident.Serving:
+		return capacityUpdate
+	default:
+		return capacityTrack
+	}
+}
+
+// checkCapacity compares a deployment's serving nodes against its minimum.
+// Capacity that stays below the minimum for the alert delay raises an
+// incident, announced to operators and, for dedicated deployments, to the
+// owning tenant; changes in capacity and the recovery are announced too.
+// Each announcement is claimed with a conditional update so only one control
+// plane replica sends it.
+func (c *DeploymentController) checkCapacity(ctx context.Context, d Deployment, now time.Time) error {
+	serving, err := c.countServingNodes(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	incident, err := c.openCapacityIncident(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+
+	alertAfter := c.capacityAlertAfter
+	if d.CapacityAlertAfter > 0 {
+		alertAfter = d.CapacityAlertAfter
+	}
+
+	switch nextCapacityAction(incident, serving, d.MinReplicas, alertAfter, now) {
+	case capacityOpen:
+		_, err = c.db.Pool.Exec(ctx, `
+			INSERT INTO deployment_capacity_incidents (
+				deployment_id, tenant_id, min_replicas, serving_replicas, lowest_replicas, started_at
+			) VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $4, $5)
+			ON CONFLICT (deployment_id) WHERE resolved_at IS NULL DO NOTHING
+		`, d.ID, d.TenantID, d.MinReplicas, serving, now)
+		if err == nil {
+			c.logger.Warn("deployment serving below minimum replicas",
+				zap.String("name", d.Name),
+				zap.Int("serving", serving),
+				zap.Int("min", d.MinReplicas),
+			)
+		}
+		return err
+
+	case capacityTrack:
+		_, err = c.db.Pool.Exec(ctx, `
+			UPDATE deployment_capacity_incidents
+			SET serving_replicas = $2, lowest_replicas = LEAST(lowest_replicas, $2), min_replicas = $3
+			WHERE id = $1
+		`, incident.ID, serving, d.MinReplicas)
+		return err
+
+	case capacityAnnounce:
+		claimed, err := c.claimCapacityIncident(ctx, `
+			UPDATE deployment_capacity_incidents
+			SET notified_at = $2, serving_replicas = $3, lowest_replicas = LEAST(lowest_replicas, $3), min_replicas = $4
+			WHERE id = $1 AND notified_at IS NULL
+		`, incident.ID, now, serving, d.MinReplicas)
+		if err != nil || !claimed {
+			return err
+		}
+		c.logger.Error("deployment capacity incident raised",
+			zap.String("name", d.Name),
+			zap.String("incident_id", incident.ID.String()),
+			zap.Int("serving", serving),
+			zap.Int("min", d.MinReplicas),
+			zap.Duration("below_for", now.Sub(incident.StartedAt)),
+		)
+		c.publishCapacityEvent(ctx, events.EventDeploymentCapacityDegraded, d, incident, serving, now)
+		return nil
+
+	case capacityUpdate:
+		claimed, err := c.claimCapacityIncident(ctx, `
+			UPDATE deployment_capacity_incidents
+			SET serving_replicas = $3, lowest_replicas = LEAST(lowest_replicas, $3), min_replicas = $4
+			WHERE id = $1 AND serving_replicas = $2 AND resolved_at IS NULL
+		`, incident.ID, incident.Serving, serving, d.MinReplicas)
+		if err != nil || !claimed {
+			return err
+		}
+		c.publishCapacityEvent(ctx, events.EventDeploymentCapacityUpdated, d, incident, serving, now)
+		return nil
+
+	case capacityResolve:
+		_, err = c.db.Pool.Exec(ctx, `
+			UPDATE deployment_capacity_incidents
+			SET resolved_at = $2, serving_replicas = $3
+			WHERE id = $1 AND resolved_at IS NULL
+		`, incident.ID, now, serving)
+		return err
+
+	case capacityRecover:
+		claimed, err := c.claimCapacityIncident(ctx, `
+			UPDATE deployment_capacity_incidents
+			SET resolved_at = $2, serving_replicas = $3
+			WHERE id = $1 AND resolved_at IS NULL
+		`, incident.ID, now, serving)
+		if err != nil || !claimed {
+			return err
+		}
+		c.logger.Info("deployment capacity recovered",
+			zap.String("name", d.Name),
+			zap.String("incident_id", incident.ID.String()),
+			zap.Int("serving", serving),
+			zap.Duration("duration", now.Sub(incident.StartedAt)),
+		)
+		c.publishCapacityEvent(ctx, events.EventDeploymentCapacityRecovered, d, incident, serving, now)
+		return nil
+	}
+	return nil
+}
+
+// countServingNodes counts the deployment's nodes that are taking traffic
+func (c *DeploymentController) countServingNodes(ctx context.Context, deploymentID string) (int, error) {
+	var count int
+	err := c.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM nodes
+		WHERE deployment_id = $1 AND NOT standby AND status IN ('active', 'ready')
+	`, deploymentID).Scan(&count)
+	return count, err
+}
+
+// openCapacityIncident loads the deployment's open incident, or nil
+func (c *DeploymentController) openCapacityIncident(ctx context.Context, deploymentID string) (*capacityIncident, error) {
+	var incident capacityIncident
+	err := c.db.Pool.QueryRow(ctx, `
+		SELECT id, serving_replicas, lowest_replicas, started_at, notified_at
+		FROM deployment_capacity_incidents
+		WHERE deployment_id = $1 AND resolved_at IS NULL
+	`, deploymentID).Scan(&incident.ID, &incident.Serving, &incident.Lowest, &incident.StartedAt, &incident.NotifiedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load capacity incident: %w", err)
+	}
+	return &incident, nil
+}
+
+// claimCapacityIncident runs a conditional update and reports whether this
+// replica made it
+func (c *DeploymentController) claimCapacityIncident(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	tag, err := c.db.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// publishCapacityEvent announces a capacity incident change. Events of
+// dedicated deployments carry the owning tenant so it is notified too.
+func (c *DeploymentController) publishCapacityEvent(ctx context.Context, eventType events.EventType, d Deployment, incident *capacityIncident, serving int, now time.Time) {
+	if c.eventBus == nil {
+		return
+	}
+	lowest := incident.Lowest
+	if serving < lowest {
+		lowest = serving
+	}
+	payload := capacityEventPayload(d, incident, serving, lowest, now)
+	if err := c.eventBus.Publish(ctx, events.NewEvent(eventType, d.TenantID, payload)); err != nil {
+		c.logger.Warn("failed to publish capacity incident",
+			zap.String("incident_id", incident.ID.String()),
+			zap.String("event_type", string(eventType)),
+			zap.Error(err),
+		)
+	}
+}
+
+// capacityEventPayload describes a capacity incident for notifications
+func capacityEventPayload(d Deployment, incident *capacityIncident, serving, lowest int, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"incident_id":       incident.ID.String(),
+		"deployment_id":     d.ID,
+		"deployment_name":   d.Name,
+		"model":             d.ModelName,
+		"min_replicas":      d.MinReplicas,
+		"serving_replicas":  serving,
+		"previous_replicas": incident.Serving,
+		"lowest_replicas":   lowest,
+		"started_at":        incident.StartedAt.UTC().Format(time.RFC3339),
+		"duration":          now.Sub(incident.StartedAt).Round(time.Second).String(),
+	}
+}
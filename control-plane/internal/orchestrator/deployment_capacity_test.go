@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextCapacityAction(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	notified := now.Add(-time.Minute)
+	pending := &capacityIncident{Serving: 1, StartedAt: now.Add(-2 * time.Minute)}
+	stale := &capacityIncident{Serving: 1, StartedAt: now.Add(-10 * time.Minute)}
+	announced := &capacityIncident{Serving: 1, StartedAt: now.Add(-10 * time.Minute), NotifiedAt: &notified}
+
+	cases := []struct {
+		name     string
+		incident *capacityIncident
+		serving  int
+		want     capacityAction
+	}{
+		{"healthy", nil, 3, capacityNone},
+		{"dip opens incident", nil, 1, capacityOpen},
+		{"dip within delay", pending, 1, capacityTrack},
+		{"dip past delay", stale, 1, capacityAnnounce},
+		{"short dip recovers silently", pending, 3, capacityResolve},
+		{"announced and unchanged", announced, 1, capacityTrack},
+		{"announced and changed", announced, 2, capacityUpdate},
+		{"announced and dropped", announced, 0, capacityUpdate},
+		{"announced and recovered", announced, 3, capacityRecover},
+	}
+	for _, tc := range cases {
+		if got := nextCapacityAction(tc.incident, tc.serving, 3, 5*time.Minute, now); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCapacityEventPayload(t *testing.T) {
+	start := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	d := Deployment{ID: "d-1", Name: "llama-dedicated", ModelName: "llama-3-8b", MinReplicas: 3}
+	incident := &capacityIncident{Serving: 2, Lowest: 1, StartedAt: start}
+
+	payload := capacityEventPayload(d, incident, 3, 1, start.Add(12*time.Minute))
+	if payload["previous_replicas"] != 2 || payload["serving_replicas"] != 3 || payload["lowest_replicas"] != 1 {
+		t.Errorf("replica counts = %v", payload)
+	}
+	if payload["started_at"] != "2025-01-10T12:00:00Z" || payload["duration"] != "12m0s" {
+		t.Errorf("timing = %v / %v", payload["started_at"], payload["duration"])
+	}
+}
+
+func TestSetCapacityAlerts(t *testing.T) {
+	c := NewDeploymentController(nil, nil, nil, nil)
+	c.SetCapacityAlerts(0)
+	if c.capacityAlertAfter != defaultCapacityAlertAfter {
+		t.Errorf("zero delay replaced default: %v", c.capacityAlertAfter)
+	}
+	c.SetCapacityAlerts(15 * time.Minute)
+	if c.capacityAlertAfter != 15*time.Minute {
+		t.Errorf("capacityAlertAfter = %v", c.capacityAlertAfter)
+	}
+}
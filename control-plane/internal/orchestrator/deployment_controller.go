@@ -3,16 +3,21 @@ package orchestrator
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/crosslogic/control-plane/internal/nodes"
 	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/events"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 // LoadBalancer interface to avoid import cycle with gateway
 type LoadBalancer interface {
-	GetAverageLatency(ctx context.Context, modelName string) (time.Duration, error)
+	// GetQueueDepth returns the requests waiting across a model's serving
+	// nodes and how many of them reported their queue
+	GetQueueDepth(ctx context.Context, modelName string) (int64, int, error)
 }
 
 // Deployment represents a managed set of GPU nodes serving a model.
@@ -27,6 +32,30 @@ type Deployment struct {
 	Provider        *string // Nullable
 	Region          *string // Nullable
 	GPUType         *string // Nullable
+	// WarmStandby keeps an unrouted standby node to promote on failure
+	WarmStandby bool
+	// VLLMVersion and TorchVersion pin the nodes' runtime; empty uses the
+	// platform default
+	VLLMVersion  string
+	TorchVersion string
+	// TenantID owns a dedicated deployment; empty for shared deployments
+	TenantID string
+	// CapacityAlertAfter overrides how long capacity may stay below the
+	// minimum before an incident is raised; zero uses the controller's
+	CapacityAlertAfter time.Duration
+	// PrewarmReplicas are extra replicas requested by prewarms that are in
+	// or about to start their window
+	PrewarmReplicas int
+	// KeepStoppedNodes is how many nodes scale down stops rather than
+	// terminates, for scale up to restart
+	KeepStoppedNodes int
+	// AutoScaling scales the deployment between its bounds on load, with
+	// Autoscale overriding the controller's targets and cooldowns
+	AutoScaling bool
+	Autoscale   AutoscaleSettings
+	// LastScaledUpAt and LastScaledDownAt are when autoscaling last acted
+	LastScaledUpAt   *time.Time
+	LastScaledDownAt *time.Time
 }
 
 // DeploymentController manages the lifecycle of deployments and auto-scaling.
@@ -35,8 +64,38 @@ type DeploymentController struct {
 	logger       *zap.Logger
 	orchestrator *SkyPilotOrchestrator
 	loadBalancer LoadBalancer
+	registry     *nodes.Registry
 	ticker       *time.Ticker
 	stopChan     chan struct{}
+
+	// Drifted nodes are only reported unless remediation is turned on
+	driftRemediate      bool
+	driftRemediateAfter time.Duration
+
+	// Retries of failed launches, and the launches still in progress or
+	// cooling down per deployment
+	launchMaxAttempts     int
+	launchRetryBackoff    time.Duration
+	launchRetryMaxBackoff time.Duration
+	launchesMu            sync.Mutex
+	pendingLaunches       map[string]int
+	launchCooldowns       map[string]time.Time
+
+	// eventBus announces standby promotions and capacity incidents, set by
+	// SubscribeFailover
+	eventBus *events.Bus
+
+	// capacityAlertAfter is how long serving nodes may stay below the
+	// minimum before a capacity incident is announced
+	capacityAlertAfter time.Duration
+
+	// cacheWarmer warms model weights once prewarmed capacity is up, set by
+	// SetCacheWarmer
+	cacheWarmer *ModelCacheWarmer
+
+	// autoscaleDefaults are the autoscaling targets and cooldowns of
+	// deployments that don't set their own
+	autoscaleDefaults AutoscaleSettings
 }
 
 // NewDeploymentController creates a new deployment controller.
@@ -46,7 +105,20 @@ func NewDeploymentController(db *database.Database, logger *zap.Logger, orch *Sk
 		logger:       logger,
 		orchestrator: orch,
 		loadBalancer: lb,
+		registry:     nodes.NewRegistry(db),
 		stopChan:     make(chan struct{}),
+
+		driftRemediateAfter: defaultDriftRemediateAfter,
+
+		launchMaxAttempts:     defaultLaunchMaxAttempts,
+		launchRetryBackoff:    defaultLaunchRetryBackoff,
+		launchRetryMaxBackoff: defaultLaunchRetryMaxBackoff,
+		pendingLaunches:       make(map[string]int),
+		launchCooldowns:       make(map[string]time.Time),
+
+		capacityAlertAfter: defaultCapacityAlertAfter,
+
+		autoscaleDefaults: defaultAutoscaleSettings(),
 	}
 }
 
@@ -102,7 +174,12 @@ func (c *DeploymentController) reconcile(ctx context.Context) error {
 
 func (c *DeploymentController) getAllDeployments(ctx context.Context) ([]Deployment, error) {
 	query := `
-		SELECT id, name, model_name, min_replicas, max_replicas, current_replicas, strategy, provider, region, gpu_type
+		SELECT id, name, model_name, min_replicas, max_replicas, current_replicas, strategy, provider, region, gpu_type, warm_standby,
+		       COALESCE(vllm_version, ''), COALESCE(torch_version, ''),
+		       COALESCE(tenant_id::text, ''), COALESCE(capacity_alert_after_minutes, 0), keep_stopped_nodes,
+		       auto_scaling_enabled, COALESCE(autoscale_target_queue_depth, 0), COALESCE(autoscale_target_p95_latency_ms, 0),
+		       COALESCE(autoscale_scale_up_cooldown_seconds, 0), COALESCE(autoscale_scale_down_cooldown_seconds, 0),
+		       last_scaled_up_at, last_scaled_down_at
 		FROM deployments
 		WHERE status = 'active'
 	`
@@ -115,13 +192,22 @@ func (c *DeploymentController) getAllDeployments(ctx context.Context) ([]Deploym
 	var deployments []Deployment
 	for rows.Next() {
 		var d Deployment
+		var alertAfterMinutes, targetP95Ms, upCooldownSecs, downCooldownSecs int
 		if err := rows.Scan(
 			&d.ID, &d.Name, &d.ModelName, &d.MinReplicas, &d.MaxReplicas,
-			&d.CurrentReplicas, &d.Strategy, &d.Provider, &d.Region, &d.GPUType,
+			&d.CurrentReplicas, &d.Strategy, &d.Provider, &d.Region, &d.GPUType, &d.WarmStandby,
+			&d.VLLMVersion, &d.TorchVersion, &d.TenantID, &alertAfterMinutes, &d.KeepStoppedNodes,
+			&d.AutoScaling, &d.Autoscale.TargetQueueDepth, &targetP95Ms,
+			&upCooldownSecs, &downCooldownSecs,
+			&d.LastScaledUpAt, &d.LastScaledDownAt,
 		); err != nil {
 			c.logger.Error("failed to scan deployment", zap.Error(err))
 			continue
 		}
+		d.CapacityAlertAfter = time.Duration(alertAfterMinutes) * time.Minute
+		d.Autoscale.TargetP95Latency = time.Duration(targetP95Ms) * time.Millisecond
+		d.Autoscale.ScaleUpCooldown = time.Duration(upCooldownSecs) * time.Second
+		d.Autoscale.ScaleDownCooldown = time.Duration(downCooldownSecs) * time.Second
 		deployments = append(deployments, d)
 	}
 	return deployments, nil
@@ -152,13 +238,54 @@ func (c *DeploymentController) reconcileDeployment(ctx context.Context, d Deploy
 		return err
 	}
 
+	// Add the extra replicas of current prewarms, and remove those of
+	// prewarms that have ended
+	now := time.Now()
+	if d.PrewarmReplicas, err = c.prewarmReplicas(ctx, d.ID, now); err != nil {
+		c.logger.Warn("failed to load deployment prewarms", zap.String("name", d.Name), zap.Error(err))
+	}
+	if scaledBack, err := c.scaleBackPrewarms(ctx, d, activeNodes, now); err != nil {
+		c.logger.Warn("failed to scale back deployment prewarms", zap.String("name", d.Name), zap.Error(err))
+	} else if scaledBack {
+		return nil
+	}
+	minReplicas, maxReplicas := d.targetReplicas()
+
 	c.logger.Debug("reconciling deployment",
 		zap.String("name", d.Name),
 		zap.Int("active_nodes", activeNodes),
-		zap.Int("min", d.MinReplicas),
-		zap.Int("max", d.MaxReplicas),
+		zap.Int("min", minReplicas),
+		zap.Int("max", maxReplicas),
+		zap.Int("prewarm", d.PrewarmReplicas),
 	)
 
+	// Compare running nodes against the deployment spec
+	if err := c.checkDrift(ctx, d); err != nil {
+		c.logger.Warn("failed to check deployment drift",
+			zap.String("name", d.Name),
+			zap.Error(err),
+		)
+	}
+
+	// Keep the warm standby running
+	c.ensureStandby(ctx, d)
+
+	// Terminate stopped nodes beyond the number the deployment keeps
+	if err := c.pruneStoppedNodes(ctx, d); err != nil {
+		c.logger.Warn("failed to prune stopped nodes",
+			zap.String("name", d.Name),
+			zap.Error(err),
+		)
+	}
+
+	// Raise, update or resolve the deployment's capacity incident
+	if err := c.checkCapacity(ctx, d, now); err != nil {
+		c.logger.Warn("failed to check deployment capacity",
+			zap.String("name", d.Name),
+			zap.Error(err),
+		)
+	}
+
 	// Update current_replicas in DB
 	if activeNodes != d.CurrentReplicas {
 		if err := c.updateCurrentReplicas(ctx, d.ID, activeNodes); err != nil {
@@ -166,19 +293,35 @@ func (c *DeploymentController) reconcileDeployment(ctx context.Context, d Deploy
 		}
 	}
 
-	// Scale Up
-	if activeNodes < d.MinReplicas {
-		needed := d.MinReplicas - activeNodes
+	// Scale Up, counting launches still in progress or awaiting a retry
+	pending := c.pendingLaunchCount(d.ID)
+	if activeNodes+pending < minReplicas {
+		needed := minReplicas - activeNodes - pending
+		if until, ok := c.launchCooldown(d.ID, now); ok {
+			c.logger.Warn("deployment below minimum replicas after failed launches",
+				zap.String("name", d.Name),
+				zap.Int("needed", needed),
+				zap.Time("retry_at", until),
+			)
+			return nil
+		}
 		c.logger.Info("scaling up deployment",
 			zap.String("name", d.Name),
 			zap.Int("needed", needed),
+			zap.Int("pending", pending),
 		)
 		return c.scaleUp(ctx, d, needed)
 	}
+	if activeNodes < minReplicas {
+		return nil
+	}
+
+	// Warm the model cache once prewarmed capacity is running
+	c.warmPrewarmedCache(ctx, d, activeNodes, now)
 
 	// Scale Down
-	if activeNodes > d.MaxReplicas {
-		excess := activeNodes - d.MaxReplicas
+	if activeNodes > maxReplicas {
+		excess := activeNodes - maxReplicas
 		c.logger.Info("scaling down deployment",
 			zap.String("name", d.Name),
 			zap.Int("excess", excess),
@@ -186,9 +329,9 @@ func (c *DeploymentController) reconcileDeployment(ctx context.Context, d Deploy
 		return c.scaleDown(ctx, d, excess)
 	}
 
-	// Scale Up based on metrics (Latency)
-	if err := c.checkScalingMetrics(ctx, d, activeNodes); err != nil {
-		c.logger.Error("failed to check scaling metrics", zap.Error(err))
+	// Follow the deployment's load between its bounds
+	if err := c.autoscale(ctx, d, activeNodes, now); err != nil {
+		c.logger.Error("failed to autoscale deployment", zap.String("name", d.Name), zap.Error(err))
 	}
 
 	return nil
@@ -217,36 +360,10 @@ func (c *DeploymentController) validateDeploymentConfig(d Deployment) error {
 	return nil
 }
 
-func (c *DeploymentController) checkScalingMetrics(ctx context.Context, d Deployment, activeNodes int) error {
-	// Don't scale if we are already at max replicas
-	if activeNodes >= d.MaxReplicas {
-		return nil
-	}
-
-	// Get average latency from load balancer
-	avgLatency, err := c.loadBalancer.GetAverageLatency(ctx, d.ModelName)
-	if err != nil {
-		return err
-	}
-
-	// Threshold: 200ms (from plan)
-	if avgLatency > 200*time.Millisecond {
-		c.logger.Info("high latency detected, scaling up",
-			zap.String("deployment", d.Name),
-			zap.Duration("avg_latency", avgLatency),
-		)
-		// Scale up by 1
-		return c.scaleUp(ctx, d, 1)
-	}
-
-	// TODO: Scale down logic based on low latency (optional for now)
-	return nil
-}
-
 func (c *DeploymentController) countActiveNodes(ctx context.Context, deploymentID string) (int, error) {
 	query := `
 		SELECT COUNT(*) FROM nodes
-		WHERE deployment_id = $1 AND status IN ('initializing', 'active', 'ready')
+		WHERE deployment_id = $1 AND NOT standby AND status IN ('initializing', 'active', 'ready')
 	`
 	var count int
 	err := c.db.Pool.QueryRow(ctx, query, deploymentID).Scan(&count)
@@ -260,6 +377,21 @@ func (c *DeploymentController) updateCurrentReplicas(ctx context.Context, deploy
 }
 
 func (c *DeploymentController) scaleUp(ctx context.Context, d Deployment, count int) error {
+	// Restarting a stopped cluster is faster than provisioning a new one
+	restarted := c.restartStoppedNodes(ctx, d, count)
+
+	for i := restarted; i < count; i++ {
+		config := c.deploymentNodeConfig(d)
+
+		// Launch asynchronously to avoid blocking, retrying transient failures
+		c.startLaunch(launchKey(d.ID, false))
+		go c.launchWithRetry(d, config)
+	}
+	return nil
+}
+
+// deploymentNodeConfig is the launch configuration of a deployment node
+func (c *DeploymentController) deploymentNodeConfig(d Deployment) NodeConfig {
 	// Generate optimal config if GPU type is "auto"
 	gpuType := ""
 	if d.GPUType != nil {
@@ -281,37 +413,25 @@ func (c *DeploymentController) scaleUp(ctx context.Context, d Deployment, count
 		region = *d.Region
 	}
 
-	// Launch nodes
-	for i := 0; i < count; i++ {
-		config := NodeConfig{
-			NodeID:       uuid.New().String(),
-			Provider:     provider,
-			Region:       region,
-			GPU:          gpuType,
-			GPUCount:     gpuCount,
-			Model:        d.ModelName,
-			UseSpot:      true, // Default to spot for cost savings
-			DeploymentID: d.ID,
-		}
-
-		// Launch asynchronously to avoid blocking
-		go func(cfg NodeConfig) {
-			if _, err := c.orchestrator.LaunchNode(context.Background(), cfg); err != nil {
-				c.logger.Error("failed to launch scaled node",
-					zap.String("deployment", d.Name),
-					zap.Error(err),
-				)
-			}
-		}(config)
+	return NodeConfig{
+		NodeID:       uuid.New().String(),
+		Provider:     provider,
+		Region:       region,
+		GPU:          gpuType,
+		GPUCount:     gpuCount,
+		Model:        d.ModelName,
+		UseSpot:      d.KeepStoppedNodes == 0, // Default to spot for cost savings; spot clusters can't be stopped
+		DeploymentID: d.ID,
+		VLLMVersion:  d.VLLMVersion,
+		TorchVersion: d.TorchVersion,
 	}
-	return nil
 }
 
 func (c *DeploymentController) scaleDown(ctx context.Context, d Deployment, count int) error {
 	// Find nodes to terminate (oldest first)
 	query := `
-		SELECT cluster_name FROM nodes
-		WHERE deployment_id = $1 AND status IN ('active', 'ready')
+		SELECT cluster_name, COALESCE(spot_instance, false) FROM nodes
+		WHERE deployment_id = $1 AND NOT standby AND status IN ('active', 'ready')
 		ORDER BY created_at ASC
 		LIMIT $2
 	`
@@ -322,15 +442,39 @@ func (c *DeploymentController) scaleDown(ctx context.Context, d Deployment, coun
 	defer rows.Close()
 
 	var clusters []string
+	var spot []bool
 	for rows.Next() {
 		var name string
-		if err := rows.Scan(&name); err != nil {
+		var isSpot bool
+		if err := rows.Scan(&name, &isSpot); err != nil {
 			continue
 		}
 		clusters = append(clusters, name)
+		spot = append(spot, isSpot)
+	}
+
+	// Stop rather than terminate nodes the deployment keeps for restarts
+	stopped := 0
+	if d.KeepStoppedNodes > 0 {
+		if stopped, err = c.countStoppedNodes(ctx, d.ID); err != nil {
+			c.logger.Warn("failed to count stopped nodes", zap.String("deployment", d.Name), zap.Error(err))
+			stopped = d.KeepStoppedNodes
+		}
 	}
 
-	for _, cluster := range clusters {
+	for i, cluster := range clusters {
+		if scaleDownChoice(d.KeepStoppedNodes, stopped, spot[i]) == scaleDownStop {
+			stopped++
+			go func(name string) {
+				if err := c.orchestrator.StopNode(context.Background(), name); err != nil {
+					c.logger.Error("failed to stop scaled node",
+						zap.String("cluster", name),
+						zap.Error(err),
+					)
+				}
+			}(cluster)
+			continue
+		}
 		go func(name string) {
 			if err := c.orchestrator.TerminateNode(context.Background(), name); err != nil {
 				c.logger.Error("failed to terminate scaled node",
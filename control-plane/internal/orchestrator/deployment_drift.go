@@ -0,0 +1,108 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// defaultDriftRemediateAfter is how long a node must stay drifted before it
+// is replaced, so a node mid-restart isn't replaced for a stale report
+const defaultDriftRemediateAfter = 10 * time.Minute
+
+// SetDriftRemediation turns on replacing nodes whose runtime has drifted
+// from their deployment's spec for longer than after.
+func (c *DeploymentController) SetDriftRemediation(enabled bool, after time.Duration) {
+	c.driftRemediate = enabled
+	if after > 0 {
+		c.driftRemediateAfter = after
+	}
+}
+
+// checkDrift compares each node of a deployment against its spec and marks
+// when drift began. With remediation on, at most one node per pass is
+// replaced so a bad spec can't take a whole deployment down at once.
+func (c *DeploymentController) checkDrift(ctx context.Context, d Deployment) error {
+	deploymentID, err := uuid.Parse(d.ID)
+	if err != nil {
+		return err
+	}
+
+	runtimes, err := c.registry.DeploymentRuntimes(ctx, deploymentID)
+	if err != nil {
+		return err
+	}
+
+	var remediate *nodes.NodeRuntime
+	for i, n := range runtimes {
+		if n.Report == nil {
+			continue
+		}
+		drift := n.Drift(d.ModelName)
+
+		since, err := c.registry.MarkDrift(ctx, n.NodeID, len(drift) > 0)
+		if err != nil {
+			c.logger.Warn("failed to mark node drift", zap.Error(err), zap.String("node_id", n.NodeID.String()))
+			continue
+		}
+		if len(drift) == 0 {
+			continue
+		}
+
+		if n.DriftSince == nil {
+			c.logger.Warn("node runtime drifted from deployment spec",
+				zap.String("deployment", d.Name),
+				zap.String("cluster_name", n.ClusterName),
+				zap.Any("drift", drift),
+			)
+		}
+		if remediate == nil && c.dueForRemediation(since, time.Now()) {
+			remediate = &runtimes[i]
+		}
+	}
+
+	if remediate != nil {
+		c.replaceDriftedNode(ctx, d, *remediate)
+	}
+	return nil
+}
+
+// dueForRemediation reports whether a node drifted since the given time
+// should be replaced now
+func (c *DeploymentController) dueForRemediation(since *time.Time, now time.Time) bool {
+	return c.driftRemediate && since != nil && now.Sub(*since) >= c.driftRemediateAfter
+}
+
+// replaceDriftedNode launches a node with the deployment's spec and
+// terminates the drifted one. A drifted standby is only terminated; the
+// next reconcile launches its replacement.
+func (c *DeploymentController) replaceDriftedNode(ctx context.Context, d Deployment, n nodes.NodeRuntime) {
+	c.logger.Info("replacing drifted node",
+		zap.String("deployment", d.Name),
+		zap.String("cluster_name", n.ClusterName),
+		zap.Bool("standby", n.Standby),
+		zap.Timep("drift_since", n.DriftSince),
+	)
+
+	if !n.Standby {
+		if err := c.scaleUp(ctx, d, 1); err != nil {
+			c.logger.Error("failed to launch replacement for drifted node",
+				zap.String("cluster_name", n.ClusterName),
+				zap.Error(err),
+			)
+			return
+		}
+	}
+
+	go func(name string) {
+		if err := c.orchestrator.TerminateNode(context.Background(), name); err != nil {
+			c.logger.Error("failed to terminate drifted node",
+				zap.String("cluster", name),
+				zap.Error(err),
+			)
+		}
+	}(n.ClusterName)
+}
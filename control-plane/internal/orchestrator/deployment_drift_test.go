@@ -0,0 +1,50 @@
+package orchestrator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRuntimeSpec(t *testing.T) {
+	o := &SkyPilotOrchestrator{vllmVersion: "0.6.2"}
+
+	clean, err := sanitizeVLLMArgs("--max-model-len 4096 --enforce-eager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := o.runtimeSpec(NodeConfig{Model: "meta-llama/Llama-3-8B", TensorParallel: 2, VLLMArgs: clean})
+
+	want := []string{"--tensor-parallel-size", "2", "--max-model-len", "4096", "--enforce-eager"}
+	if spec.Model != "meta-llama/Llama-3-8B" || spec.VLLMVersion != "0.6.2" || !reflect.DeepEqual(spec.VLLMArgs, want) {
+		t.Errorf("runtimeSpec() = %+v", spec)
+	}
+
+	// Other runtimes only pin the model
+	spec = o.runtimeSpec(NodeConfig{Model: "m", Runtime: "sglang", TensorParallel: 2})
+	if spec.VLLMVersion != "" || spec.VLLMArgs != nil {
+		t.Errorf("sglang runtimeSpec() = %+v, want model only", spec)
+	}
+}
+
+func TestDueForRemediation(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	recent := now.Add(-time.Minute)
+
+	c := &DeploymentController{driftRemediateAfter: defaultDriftRemediateAfter}
+	if c.dueForRemediation(&old, now) {
+		t.Error("remediated with remediation off")
+	}
+
+	c.SetDriftRemediation(true, 0)
+	if c.driftRemediateAfter != defaultDriftRemediateAfter {
+		t.Errorf("driftRemediateAfter = %s, want default kept", c.driftRemediateAfter)
+	}
+	if !c.dueForRemediation(&old, now) {
+		t.Error("long drift not remediated")
+	}
+	if c.dueForRemediation(&recent, now) || c.dueForRemediation(nil, now) {
+		t.Error("recent or no drift remediated")
+	}
+}
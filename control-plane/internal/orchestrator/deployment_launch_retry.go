@@ -0,0 +1,295 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/skypilot"
+	"go.uber.org/zap"
+)
+
+// Deployment launches that fail for transient reasons (no capacity, API
+// timeouts, rate limiting) are retried with exponential backoff. Each attempt
+// reuses the node ID, so the node's log and launch timings show every
+// attempt. Capacity errors move the launch down the fallback chain (spot,
+// then on-demand) before it is retried. Once the attempts run out the
+// deployment waits out the maximum backoff before launching again.
+const (
+	defaultLaunchMaxAttempts     = 4
+	defaultLaunchRetryBackoff    = 30 * time.Second
+	defaultLaunchRetryMaxBackoff = 10 * time.Minute
+)
+
+// capacityErrorMarkers identify launches that failed because the cloud had
+// no capacity for the requested resources
+var capacityErrorMarkers = []string{
+	"resourcesunavailableerror",
+	"failed to acquire resources",
+	"insufficientinstancecapacity",
+	"insufficient capacity",
+	"zoneresourcepoolexhausted",
+	"skunotavailable",
+	"out of capacity",
+}
+
+// timeoutErrorMarkers identify launches that failed on a slow or
+// unavailable cloud or SkyPilot API
+var timeoutErrorMarkers = []string{
+	"timeout",
+	"timed out",
+	"connection reset",
+	"connection refused",
+	"temporarily unavailable",
+	"requestlimitexceeded",
+	"throttl",
+}
+
+// SetLaunchRetry configures retries of failed deployment launches. Zero
+// values keep the defaults; maxAttempts of 1 disables retries.
+func (c *DeploymentController) SetLaunchRetry(maxAttempts int, backoff, maxBackoff time.Duration) {
+	if maxAttempts > 0 {
+		c.launchMaxAttempts = maxAttempts
+	}
+	if backoff > 0 {
+		c.launchRetryBackoff = backoff
+	}
+	if maxBackoff > 0 {
+		c.launchRetryMaxBackoff = maxBackoff
+	}
+}
+
+// isCapacityError reports whether a launch failed for lack of cloud capacity
+func isCapacityError(err error) bool {
+	return containsAny(strings.ToLower(err.Error()), capacityErrorMarkers)
+}
+
+// isTransientLaunchError reports whether a failed launch may succeed if
+// retried. Invalid configuration, credentials and cancellation are not.
+func isTransientLaunchError(err error) bool {
+	var configErr *ConfigError
+	if errors.As(err, &configErr) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var apiErr *skypilot.APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode >= 500 || apiErr.IsRateLimited() {
+			return true
+		}
+		if apiErr.StatusCode >= 400 {
+			return isCapacityError(err)
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	return containsAny(msg, capacityErrorMarkers) || containsAny(msg, timeoutErrorMarkers)
+}
+
+// launchFailurePhase is the log phase of a failed launch attempt
+func launchFailurePhase(err error, willRetry bool) NodeLogPhase {
+	if willRetry && isTransientLaunchError(err) {
+		return PhaseRetrying
+	}
+	return PhaseFailed
+}
+
+// launchFallbacks is the order in which a launch tries its configurations:
+// spot launches fall back to on-demand
+func launchFallbacks(config NodeConfig) []NodeConfig {
+	chain := []NodeConfig{config}
+	if config.UseSpot {
+		onDemand := config
+		onDemand.UseSpot = false
+		chain = append(chain, onDemand)
+	}
+	return chain
+}
+
+// retryBackoff is the wait before the given attempt (2 is the first retry)
+func (c *DeploymentController) retryBackoff(attempt int) time.Duration {
+	backoff := c.launchRetryBackoff
+	for i := 2; i < attempt && backoff < c.launchRetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > c.launchRetryMaxBackoff {
+		backoff = c.launchRetryMaxBackoff
+	}
+	return backoff
+}
+
+// launchWithRetry launches a deployment node, retrying transient failures.
+// It runs in its own goroutine and counts as a pending launch of the
+// deployment until it returns.
+func (c *DeploymentController) launchWithRetry(d Deployment, config NodeConfig) {
+	key := launchKey(d.ID, config.Standby)
+	defer c.finishLaunch(key)
+
+	ctx := context.Background()
+	logStore := c.orchestrator.logStore
+	chain := launchFallbacks(config)
+	step := 0
+	if config.RequestedAt.IsZero() {
+		config.RequestedAt = time.Now()
+	}
+
+	for attempt := 1; ; attempt++ {
+		cfg := chain[step]
+		cfg.RequestedAt = config.RequestedAt
+		lastAttempt := attempt >= c.launchMaxAttempts
+
+		if attempt > 1 {
+			logStore.LogInfo(ctx, cfg.NodeID, PhaseQueued,
+				fmt.Sprintf("Launch attempt %d/%d (%s)", attempt, c.launchMaxAttempts, launchMarket(cfg)), 0)
+		}
+
+		_, err := c.orchestrator.launchNode(ctx, cfg, !lastAttempt)
+		if err == nil {
+			c.clearLaunchCooldown(key)
+			return
+		}
+
+		if !isTransientLaunchError(err) {
+			c.logger.Error("failed to launch scaled node",
+				zap.String("deployment", d.Name),
+				zap.String("node_id", cfg.NodeID),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+			return
+		}
+
+		if lastAttempt {
+			c.logger.Error("giving up on scaled node launch",
+				zap.String("deployment", d.Name),
+				zap.String("node_id", cfg.NodeID),
+				zap.Int("attempts", attempt),
+				zap.Error(err),
+			)
+			logStore.LogError(ctx, cfg.NodeID, PhaseFailed,
+				fmt.Sprintf("Launch failed after %d attempts", attempt),
+				fmt.Sprintf("deployment %s launches no %s until %s", d.Name, launchRole(config),
+					c.startLaunchCooldown(key).Format(time.RFC3339)))
+			return
+		}
+
+		// Out of capacity: try the next configuration in the chain
+		if isCapacityError(err) && step+1 < len(chain) {
+			step++
+		}
+
+		backoff := c.retryBackoff(attempt + 1)
+		c.logger.Warn("scaled node launch failed, retrying",
+			zap.String("deployment", d.Name),
+			zap.String("node_id", cfg.NodeID),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		logStore.LogWarn(ctx, cfg.NodeID, PhaseRetrying,
+			fmt.Sprintf("Launch attempt %d/%d failed; retrying %s in %s", attempt, c.launchMaxAttempts,
+				launchMarket(chain[step]), backoff))
+
+		select {
+		case <-time.After(backoff):
+		case <-c.stopChan:
+			logStore.LogError(ctx, cfg.NodeID, PhaseFailed,
+				"Launch retry cancelled", "deployment controller stopped")
+			return
+		}
+	}
+}
+
+// launchMarket describes whether a launch uses spot or on-demand capacity
+func launchMarket(config NodeConfig) string {
+	if config.UseSpot {
+		return "spot"
+	}
+	return "on-demand"
+}
+
+// launchRole describes whether a launch is a primary or the warm standby
+func launchRole(config NodeConfig) string {
+	if config.Standby {
+		return "standby"
+	}
+	return "primaries"
+}
+
+// launchKey identifies a deployment's primary launches, or its standby
+// launches, for pending counts and cooldowns
+func launchKey(deploymentID string, standby bool) string {
+	if standby {
+		return deploymentID + "/standby"
+	}
+	return deploymentID
+}
+
+// startLaunch counts a launch as pending
+func (c *DeploymentController) startLaunch(key string) {
+	c.launchesMu.Lock()
+	defer c.launchesMu.Unlock()
+	c.pendingLaunches[key]++
+}
+
+func (c *DeploymentController) finishLaunch(key string) {
+	c.launchesMu.Lock()
+	defer c.launchesMu.Unlock()
+	if c.pendingLaunches[key] <= 1 {
+		delete(c.pendingLaunches, key)
+		return
+	}
+	c.pendingLaunches[key]--
+}
+
+// pendingLaunchCount is how many launches are in progress or waiting to be
+// retried
+func (c *DeploymentController) pendingLaunchCount(key string) int {
+	c.launchesMu.Lock()
+	defer c.launchesMu.Unlock()
+	return c.pendingLaunches[key]
+}
+
+// startLaunchCooldown holds off new launches after one ran out of attempts,
+// and returns when launches may resume
+func (c *DeploymentController) startLaunchCooldown(key string) time.Time {
+	c.launchesMu.Lock()
+	defer c.launchesMu.Unlock()
+	until := time.Now().Add(c.launchRetryMaxBackoff)
+	c.launchCooldowns[key] = until
+	return until
+}
+
+func (c *DeploymentController) clearLaunchCooldown(key string) {
+	c.launchesMu.Lock()
+	defer c.launchesMu.Unlock()
+	delete(c.launchCooldowns, key)
+}
+
+// launchCooldown returns when launches may resume, if they are cooling down
+// after failed launches
+func (c *DeploymentController) launchCooldown(key string, now time.Time) (time.Time, bool) {
+	c.launchesMu.Lock()
+	defer c.launchesMu.Unlock()
+	until, ok := c.launchCooldowns[key]
+	if ok && !now.Before(until) {
+		delete(c.launchCooldowns, key)
+		return time.Time{}, false
+	}
+	return until, ok
+}
+
+// containsAny reports whether s contains any of the lowercase substrs
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/skypilot"
+	"go.uber.org/zap"
+)
+
+func TestIsTransientLaunchError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("sky launch failed: ResourcesUnavailableError: Failed to acquire resources in all zones"), true},
+		{fmt.Errorf("launch request failed: %w", context.DeadlineExceeded), true},
+		{errors.New("dial tcp 10.0.0.1:46580: connection refused"), true},
+		{&skypilot.APIError{StatusCode: http.StatusServiceUnavailable, Message: "busy"}, true},
+		{&skypilot.APIError{StatusCode: http.StatusTooManyRequests, Message: "slow down"}, true},
+		{&skypilot.APIError{StatusCode: http.StatusBadRequest, Message: "invalid credentials"}, false},
+		{&ConfigError{Fields: []FieldError{{Field: "gpu", Message: "unknown"}}}, false},
+		{fmt.Errorf("launch cancelled while queued: %w", context.Canceled), false},
+		{errors.New("failed to get tenant credentials: no credentials found"), false},
+	}
+	for _, tc := range cases {
+		if got := isTransientLaunchError(tc.err); got != tc.want {
+			t.Errorf("isTransientLaunchError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+
+	if launchFailurePhase(cases[0].err, true) != PhaseRetrying || launchFailurePhase(cases[0].err, false) != PhaseFailed {
+		t.Error("transient failure phases wrong")
+	}
+	if launchFailurePhase(cases[6].err, true) != PhaseFailed {
+		t.Error("permanent failure logged as retrying")
+	}
+}
+
+func TestLaunchFallbacks(t *testing.T) {
+	chain := launchFallbacks(NodeConfig{NodeID: "n1", UseSpot: true})
+	if len(chain) != 2 || !chain[0].UseSpot || chain[1].UseSpot || chain[1].NodeID != "n1" {
+		t.Errorf("spot chain = %+v", chain)
+	}
+	if chain := launchFallbacks(NodeConfig{UseSpot: false}); len(chain) != 1 {
+		t.Errorf("on-demand chain = %+v", chain)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	c := NewDeploymentController(nil, zap.NewNop(), nil, nil)
+	c.SetLaunchRetry(6, 30*time.Second, 3*time.Minute)
+
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}
+	for i, w := range want {
+		if got := c.retryBackoff(i + 2); got != w {
+			t.Errorf("retryBackoff(%d) = %s, want %s", i+2, got, w)
+		}
+	}
+}
+
+func TestPendingLaunchesAndCooldown(t *testing.T) {
+	c := NewDeploymentController(nil, zap.NewNop(), nil, nil)
+	c.SetLaunchRetry(0, 0, time.Minute)
+
+	c.startLaunch("d1")
+	c.startLaunch("d1")
+	c.finishLaunch("d1")
+	if got := c.pendingLaunchCount("d1"); got != 1 {
+		t.Errorf("pending = %d, want 1", got)
+	}
+	c.finishLaunch("d1")
+	if got := c.pendingLaunchCount("d1"); got != 0 {
+		t.Errorf("pending = %d, want 0", got)
+	}
+
+	until := c.startLaunchCooldown("d1")
+	if _, ok := c.launchCooldown("d1", time.Now()); !ok {
+		t.Error("deployment not cooling down")
+	}
+	if _, ok := c.launchCooldown("d1", until); ok {
+		t.Error("cooldown outlived its deadline")
+	}
+	if _, ok := c.launchCooldown("d2", time.Now()); ok {
+		t.Error("unrelated deployment cooling down")
+	}
+}
@@ -0,0 +1,143 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// PrewarmLead is how long before a prewarm window starts its extra replicas
+// are launched; nodes take 5-8 minutes to come up and load weights
+const PrewarmLead = 15 * time.Minute
+
+// SetCacheWarmer sets the cache warmer that warms model weights on a
+// deployment's nodes once prewarmed capacity is up
+func (c *DeploymentController) SetCacheWarmer(w *ModelCacheWarmer) {
+	c.cacheWarmer = w
+}
+
+// targetReplicas returns the replica bounds the controller scales to,
+// including extra replicas requested by prewarms. The maximum grows with
+// the prewarm so the extra nodes are never scaled straight back down.
+func (d Deployment) targetReplicas() (int, int) {
+	minReplicas := d.MinReplicas + d.PrewarmReplicas
+	maxReplicas := d.MaxReplicas
+	if maxReplicas < minReplicas {
+		maxReplicas = minReplicas
+	}
+	return minReplicas, maxReplicas
+}
+
+// prewarmReplicas sums the extra replicas of the deployment's prewarms that
+// are in or within PrewarmLead of their window
+func (c *DeploymentController) prewarmReplicas(ctx context.Context, deploymentID string, now time.Time) (int, error) {
+	var extra int
+	err := c.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(replicas), 0) FROM model_prewarms
+		WHERE deployment_id = $1 AND status = 'scheduled'
+		  AND starts_at <= $2 AND ends_at > $3
+	`, deploymentID, now.Add(PrewarmLead), now).Scan(&extra)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load prewarms: %w", err)
+	}
+	return extra, nil
+}
+
+// warmPrewarmedCache warms the model cache on the deployment's nodes once
+// the prewarmed capacity is running. Each prewarm is claimed so the cache
+// is warmed once, by one replica.
+func (c *DeploymentController) warmPrewarmedCache(ctx context.Context, d Deployment, activeNodes int, now time.Time) {
+	if c.cacheWarmer == nil || d.PrewarmReplicas == 0 {
+		return
+	}
+	if target, _ := d.targetReplicas(); activeNodes < target {
+		return
+	}
+
+	tag, err := c.db.Pool.Exec(ctx, `
+		UPDATE model_prewarms SET warmed_at = $2, updated_at = NOW()
+		WHERE deployment_id = $1 AND status = 'scheduled' AND warmed_at IS NULL
+		  AND starts_at <= $3 AND ends_at > $2
+	`, d.ID, now, now.Add(PrewarmLead))
+	if err != nil {
+		c.logger.Warn("failed to claim prewarm cache warming", zap.String("name", d.Name), zap.Error(err))
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		return
+	}
+
+	c.logger.Info("prewarmed capacity is up, warming model cache",
+		zap.String("name", d.Name),
+		zap.Int("active_nodes", activeNodes),
+	)
+	go func() {
+		if err := c.cacheWarmer.Prewarm(context.Background(), d.ModelName); err != nil {
+			c.logger.Warn("failed to warm cache for prewarm",
+				zap.String("name", d.Name),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+// scaleBackPrewarms removes the extra replicas of prewarms whose window has
+// ended, down to what the deployment still needs. It reports whether nodes
+// were scaled down.
+func (c *DeploymentController) scaleBackPrewarms(ctx context.Context, d Deployment, activeNodes int, now time.Time) (bool, error) {
+	rows, err := c.db.Pool.Query(ctx, `
+		UPDATE model_prewarms SET scaled_back_at = $2, updated_at = NOW()
+		WHERE deployment_id = $1 AND status = 'scheduled' AND ends_at <= $2 AND scaled_back_at IS NULL
+		RETURNING id, replicas
+	`, d.ID, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim ended prewarms: %w", err)
+	}
+	var ids []uuid.UUID
+	var extra int
+	for rows.Next() {
+		var id uuid.UUID
+		var replicas int
+		if err := rows.Scan(&id, &replicas); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+		extra += replicas
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to claim ended prewarms: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return false, nil
+	}
+	target, _ := d.targetReplicas()
+	excess := prewarmExcess(activeNodes, target, extra)
+	c.logger.Info("prewarm window ended, scaling back",
+		zap.String("name", d.Name),
+		zap.Int("prewarms", len(ids)),
+		zap.Int("excess", excess),
+	)
+	if excess == 0 {
+		return false, nil
+	}
+	return true, c.scaleDown(ctx, d, excess)
+}
+
+// prewarmExcess is how many nodes to remove when prewarms adding extra
+// replicas end: never more than they added, and never below the target the
+// deployment still has
+func prewarmExcess(activeNodes, target, extra int) int {
+	excess := activeNodes - target
+	if excess > extra {
+		excess = extra
+	}
+	if excess < 0 {
+		return 0
+	}
+	return excess
+}
@@ -0,0 +1,46 @@
+package orchestrator
+
+import "testing"
+
+func TestTargetReplicas(t *testing.T) {
+	cases := []struct {
+		name             string
+		d                Deployment
+		wantMin, wantMax int
+	}{
+		{"no prewarm", Deployment{MinReplicas: 2, MaxReplicas: 10}, 2, 10},
+		{"prewarm within max", Deployment{MinReplicas: 2, MaxReplicas: 10, PrewarmReplicas: 3}, 5, 10},
+		{"prewarm raises max", Deployment{MinReplicas: 2, MaxReplicas: 4, PrewarmReplicas: 5}, 7, 7},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotMin, gotMax := tc.d.targetReplicas()
+			if gotMin != tc.wantMin || gotMax != tc.wantMax {
+				t.Errorf("targetReplicas() = (%d, %d), want (%d, %d)", gotMin, gotMax, tc.wantMin, tc.wantMax)
+			}
+		})
+	}
+}
+
+func TestPrewarmExcess(t *testing.T) {
+	cases := []struct {
+		name                       string
+		activeNodes, target, extra int
+		want                       int
+	}{
+		{"removes the extra replicas", 5, 2, 3, 3},
+		{"keeps nodes another prewarm still needs", 5, 4, 3, 1},
+		{"never removes more than the prewarm added", 8, 2, 3, 3},
+		{"extra nodes never came up", 2, 2, 3, 0},
+		{"below target", 1, 2, 3, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := prewarmExcess(tc.activeNodes, tc.target, tc.extra); got != tc.want {
+				t.Errorf("prewarmExcess(%d, %d, %d) = %d, want %d", tc.activeNodes, tc.target, tc.extra, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Deployments with warm standby keep one extra node running the deployment's
+// model but out of routing. When the monitor reports a primary unhealthy the
+// standby is promoted into routing at once, and a new standby is launched in
+// the background, so failover takes seconds rather than a full node launch.
+
+// failoverStatuses are the health statuses that take a primary out of
+// routing and trigger a standby promotion
+var failoverStatuses = map[string]bool{
+	"degraded": true,
+	"suspect":  true,
+	"dead":     true,
+}
+
+// ensureStandby launches a warm standby for the deployment when it has none
+// running or launching
+func (c *DeploymentController) ensureStandby(ctx context.Context, d Deployment) {
+	if !d.WarmStandby {
+		return
+	}
+	key := launchKey(d.ID, true)
+	if c.pendingLaunchCount(key) > 0 {
+		return
+	}
+	if until, ok := c.launchCooldown(key, time.Now()); ok {
+		c.logger.Debug("standby launches cooling down after failures",
+			zap.String("deployment", d.Name),
+			zap.Time("until", until),
+		)
+		return
+	}
+
+	var standbys int
+	if err := c.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM nodes
+		WHERE deployment_id = $1 AND standby AND status IN ('initializing', 'active', 'ready')
+	`, d.ID).Scan(&standbys); err != nil {
+		c.logger.Error("failed to count standby nodes", zap.String("deployment", d.Name), zap.Error(err))
+		return
+	}
+	if standbys > 0 {
+		return
+	}
+
+	c.launchStandby(d)
+}
+
+// launchStandby starts launching a new warm standby in the background
+func (c *DeploymentController) launchStandby(d Deployment) {
+	config := c.deploymentNodeConfig(d)
+	config.Standby = true
+
+	c.logger.Info("launching warm standby",
+		zap.String("deployment", d.Name),
+		zap.String("node_id", config.NodeID),
+	)
+	c.startLaunch(launchKey(d.ID, true))
+	go c.launchWithRetry(d, config)
+}
+
+// SubscribeFailover promotes a deployment's warm standby when the monitor
+// reports one of its primaries unhealthy, and announces promotions on bus.
+func (c *DeploymentController) SubscribeFailover(bus *events.Bus) {
+	c.eventBus = bus
+	bus.Subscribe(events.EventNodeHealthChanged, func(ctx context.Context, event events.Event) error {
+		status, _ := event.Payload["status"].(string)
+		nodeID, _ := event.Payload["node_id"].(string)
+		if !failoverStatuses[status] || nodeID == "" {
+			return nil
+		}
+		return c.failover(ctx, nodeID, status)
+	})
+}
+
+// failover promotes the standby of a failed node's deployment and launches
+// the standby's replacement
+func (c *DeploymentController) failover(ctx context.Context, nodeID, status string) error {
+	id, err := uuid.Parse(nodeID)
+	if err != nil {
+		return fmt.Errorf("invalid node ID %q: %w", nodeID, err)
+	}
+	deploymentID, standby, ok, err := c.registry.NodeDeployment(ctx, id)
+	if err != nil || !ok || standby {
+		// A failed standby is replaced by the next reconcile
+		return err
+	}
+
+	promotion, err := c.registry.PromoteStandby(ctx, deploymentID)
+	if err != nil {
+		return err
+	}
+	if promotion == nil {
+		return nil
+	}
+
+	c.logger.Warn("promoted warm standby after primary failure",
+		zap.String("deployment_id", deploymentID.String()),
+		zap.String("failed_node_id", nodeID),
+		zap.String("failed_status", status),
+		zap.String("promoted_node_id", promotion.NodeID.String()),
+		zap.String("promoted_cluster", promotion.ClusterName),
+	)
+	if c.orchestrator != nil {
+		c.orchestrator.logStore.LogInfo(ctx, promotion.NodeID.String(), PhaseActive,
+			fmt.Sprintf("Promoted from warm standby: node %s is %s", nodeID, status), 100)
+	}
+
+	if c.eventBus != nil {
+		if err := c.eventBus.Publish(ctx, events.NewEvent(events.EventNodeStandbyPromoted, "", map[string]interface{}{
+			"node_id":        promotion.NodeID.String(),
+			"cluster_name":   promotion.ClusterName,
+			"deployment_id":  deploymentID.String(),
+			"failed_node_id": nodeID,
+		})); err != nil {
+			c.logger.Warn("failed to publish standby promotion", zap.Error(err))
+		}
+	}
+
+	d, err := c.getDeployment(ctx, deploymentID.String())
+	if err != nil {
+		return err
+	}
+	if d != nil && c.pendingLaunchCount(launchKey(d.ID, true)) == 0 {
+		c.launchStandby(*d)
+	}
+	return nil
+}
+
+// getDeployment loads an active deployment, or nil if there is none
+func (c *DeploymentController) getDeployment(ctx context.Context, id string) (*Deployment, error) {
+	deployments, err := c.getAllDeployments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments {
+		if deployments[i].ID == id {
+			return &deployments[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,44 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestStandbyLaunchesCountedSeparately(t *testing.T) {
+	c := NewDeploymentController(nil, zap.NewNop(), nil, nil)
+
+	c.startLaunch(launchKey("d1", true))
+	if got := c.pendingLaunchCount(launchKey("d1", false)); got != 0 {
+		t.Errorf("primary pending = %d, want 0", got)
+	}
+	if got := c.pendingLaunchCount(launchKey("d1", true)); got != 1 {
+		t.Errorf("standby pending = %d, want 1", got)
+	}
+
+	c.startLaunchCooldown(launchKey("d1", true))
+	if _, ok := c.launchCooldown(launchKey("d1", false), time.Now()); ok {
+		t.Error("failed standby launches held back primaries")
+	}
+}
+
+func TestFailoverStatuses(t *testing.T) {
+	for _, status := range []string{"degraded", "suspect", "dead"} {
+		if !failoverStatuses[status] {
+			t.Errorf("%s does not trigger failover", status)
+		}
+	}
+	for _, status := range []string{"active", "draining"} {
+		if failoverStatuses[status] {
+			t.Errorf("%s triggers failover", status)
+		}
+	}
+
+	c := NewDeploymentController(nil, zap.NewNop(), nil, nil)
+	if err := c.failover(context.Background(), "not-a-uuid", "dead"); err == nil {
+		t.Error("failover accepted an invalid node ID")
+	}
+}
@@ -0,0 +1,151 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Deployments that keep stopped nodes stop their on-demand nodes at scale
+// down instead of terminating them, up to KeepStoppedNodes. The stopped
+// clusters keep their disks, so the next scale up restarts them with the
+// model and setup already in place rather than provisioning from scratch.
+// Spot clusters can't be stopped and are always terminated.
+
+// scaleDownAction is what happens to a node removed at scale down
+type scaleDownAction int
+
+const (
+	scaleDownTerminate scaleDownAction = iota
+	scaleDownStop
+)
+
+// scaleDownChoice stops a node while the deployment keeps fewer stopped
+// nodes than it may, unless the node is a spot instance
+func scaleDownChoice(keep, stopped int, spot bool) scaleDownAction {
+	if spot || stopped >= keep {
+		return scaleDownTerminate
+	}
+	return scaleDownStop
+}
+
+// countStoppedNodes counts the deployment's stopped nodes
+func (c *DeploymentController) countStoppedNodes(ctx context.Context, deploymentID string) (int, error) {
+	var count int
+	err := c.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM nodes
+		WHERE deployment_id = $1 AND NOT standby AND status = 'stopped'
+	`, deploymentID).Scan(&count)
+	return count, err
+}
+
+// claimStoppedNodes marks up to count of the deployment's most recently
+// stopped nodes as initializing and returns their restart configurations
+func (c *DeploymentController) claimStoppedNodes(ctx context.Context, d Deployment, count int) ([]NodeConfig, error) {
+	if d.KeepStoppedNodes == 0 || count <= 0 {
+		return nil, nil
+	}
+
+	rows, err := c.db.Pool.Query(ctx, `
+		UPDATE nodes n
+		SET status = 'initializing', stopped_at = NULL, updated_at = NOW()
+		WHERE n.id IN (
+			SELECT id FROM nodes
+			WHERE deployment_id = $1 AND NOT standby AND status = 'stopped' AND model_name = $2
+			ORDER BY stopped_at DESC NULLS LAST
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING n.id::text, n.cluster_name, n.provider,
+		          COALESCE((SELECT code FROM regions WHERE id = n.region_id), ''),
+		          COALESCE(n.gpu_type, '')
+	`, d.ID, d.ModelName, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim stopped nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var configs []NodeConfig
+	for rows.Next() {
+		config := c.deploymentNodeConfig(d)
+		var gpu string
+		if err := rows.Scan(&config.NodeID, &config.ClusterName, &config.Provider, &config.Region, &gpu); err != nil {
+			return nil, fmt.Errorf("failed to scan stopped node: %w", err)
+		}
+		if gpu != "" {
+			config.GPU = gpu
+		}
+		config.UseSpot = false
+		configs = append(configs, config)
+	}
+	return configs, rows.Err()
+}
+
+// restartStoppedNodes starts relaunching up to count stopped nodes in the
+// background and returns how many it restarts
+func (c *DeploymentController) restartStoppedNodes(ctx context.Context, d Deployment, count int) int {
+	configs, err := c.claimStoppedNodes(ctx, d, count)
+	if err != nil {
+		c.logger.Warn("failed to claim stopped nodes, launching new ones",
+			zap.String("deployment", d.Name),
+			zap.Error(err),
+		)
+		return 0
+	}
+
+	for _, config := range configs {
+		c.logger.Info("restarting stopped node",
+			zap.String("deployment", d.Name),
+			zap.String("node_id", config.NodeID),
+			zap.String("cluster", config.ClusterName),
+		)
+		c.startLaunch(launchKey(d.ID, false))
+		go c.launchWithRetry(d, config)
+	}
+	return len(configs)
+}
+
+// pruneStoppedNodes terminates the deployment's oldest stopped nodes beyond
+// the number it keeps
+func (c *DeploymentController) pruneStoppedNodes(ctx context.Context, d Deployment) error {
+	rows, err := c.db.Pool.Query(ctx, `
+		SELECT cluster_name FROM nodes
+		WHERE deployment_id = $1 AND NOT standby AND status = 'stopped'
+		ORDER BY stopped_at DESC NULLS LAST
+		OFFSET $2
+	`, d.ID, d.KeepStoppedNodes)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var clusters []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		clusters = append(clusters, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, cluster := range clusters {
+		c.logger.Info("terminating stopped node beyond the deployment's keep",
+			zap.String("deployment", d.Name),
+			zap.String("cluster", cluster),
+			zap.Int("keep", d.KeepStoppedNodes),
+		)
+		go func(name string) {
+			if err := c.orchestrator.TerminateNode(context.Background(), name); err != nil {
+				c.logger.Error("failed to terminate stopped node",
+					zap.String("cluster", name),
+					zap.Error(err),
+				)
+			}
+		}(cluster)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package orchestrator
+
+import "testing"
+
+func TestScaleDownChoice(t *testing.T) {
+	tests := []struct {
+		name    string
+		keep    int
+		stopped int
+		spot    bool
+		want    scaleDownAction
+	}{
+		{"not keeping stopped nodes", 0, 0, false, scaleDownTerminate},
+		{"room to keep", 2, 1, false, scaleDownStop},
+		{"keep full", 2, 2, false, scaleDownTerminate},
+		{"spot can't be stopped", 2, 0, true, scaleDownTerminate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scaleDownChoice(tt.keep, tt.stopped, tt.spot); got != tt.want {
+				t.Fatalf("scaleDownChoice(%d, %d, %v) = %v, want %v", tt.keep, tt.stopped, tt.spot, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeploymentNodeConfigOnDemandWhenKeepingStoppedNodes(t *testing.T) {
+	c := &DeploymentController{}
+	gpu := "A10G"
+	d := Deployment{ID: "d1", ModelName: "m", GPUType: &gpu}
+
+	if !c.deploymentNodeConfig(d).UseSpot {
+		t.Fatal("expected spot by default")
+	}
+	d.KeepStoppedNodes = 1
+	if c.deploymentNodeConfig(d).UseSpot {
+		t.Fatal("expected on-demand when keeping stopped nodes")
+	}
+}
@@ -0,0 +1,340 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/skypilot"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// ExecSourceSystem marks commands the control plane runs itself, such as
+	// log tails and cache warming; they skip the allow-list
+	ExecSourceSystem = "system"
+
+	// ExecSourceAdmin marks commands typed by an admin
+	ExecSourceAdmin = "admin_api"
+
+	// defaultExecTimeout applies when the configuration sets none
+	defaultExecTimeout = 5 * time.Minute
+
+	// execReadSize is how much CLI output is read per chunk
+	execReadSize = 4096
+
+	// shellOperators could chain an allowed command with another one
+	shellOperators = ";&|`$<>(){}\\\n"
+)
+
+var (
+	// ErrExecCommandNotAllowed is returned for commands outside the allow-list
+	ErrExecCommandNotAllowed = errors.New("command not allowed")
+
+	// ErrExecTimeoutTooLong is returned for a timeout above the configured
+	// maximum
+	ErrExecTimeoutTooLong = errors.New("timeout exceeds the maximum")
+)
+
+// ExecRequest is a command to run on a node's cluster
+type ExecRequest struct {
+	ClusterName string
+	Command     string
+	// Timeout is zero for the configured default
+	Timeout time.Duration
+	// Source is ExecSourceSystem when empty
+	Source string
+	// Actor is who asked for the command (admin token name), for the audit log
+	Actor string
+}
+
+// ExecOutput is a chunk of a command's output
+type ExecOutput struct {
+	Stream string `json:"stream"` // "stdout" or "stderr"
+	Data   string `json:"data"`
+}
+
+// ExecResult is the outcome of a command
+type ExecResult struct {
+	// Output is the combined output of a buffered run; empty when streamed
+	Output      string        `json:"output,omitempty"`
+	ExitCode    int           `json:"exit_code"`
+	OutputBytes int64         `json:"output_bytes"`
+	Duration    time.Duration `json:"-"`
+	Timeout     time.Duration `json:"-"`
+}
+
+// checkExecCommand enforces the exec allow-list: the command must be an
+// allowed entry or start with one followed by arguments, and must not use
+// shell operators. A "*" entry allows any command.
+func checkExecCommand(command string, allowed []string) error {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return fmt.Errorf("%w: command is empty", ErrExecCommandNotAllowed)
+	}
+
+	for _, entry := range allowed {
+		if entry == "*" {
+			return nil
+		}
+	}
+	if strings.ContainsAny(command, shellOperators) {
+		return fmt.Errorf("%w: shell operators are not allowed", ErrExecCommandNotAllowed)
+	}
+
+	for _, entry := range allowed {
+		switch {
+		case command == entry,
+			strings.HasPrefix(command, entry+" "),
+			strings.HasSuffix(entry, "/") && strings.HasPrefix(command, entry):
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: allowed commands are %s", ErrExecCommandNotAllowed, strings.Join(allowed, ", "))
+}
+
+// execTimeout returns the timeout of a request, the default when unset.
+// Only admin requests are held to the maximum.
+func (o *SkyPilotOrchestrator) execTimeout(requested time.Duration, source string) (time.Duration, error) {
+	if requested <= 0 {
+		if o.execDefaultTimeout > 0 {
+			return o.execDefaultTimeout, nil
+		}
+		return defaultExecTimeout, nil
+	}
+	if source != ExecSourceSystem && o.execMaxTimeout > 0 && requested > o.execMaxTimeout {
+		return 0, fmt.Errorf("%w of %s", ErrExecTimeoutTooLong, o.execMaxTimeout)
+	}
+	return requested, nil
+}
+
+// ExecCommand executes a command on a running node.
+//
+// Routes to API or CLI based on useAPIServer flag. The command runs until
+// ctx's deadline, or for the configured default timeout.
+//
+// Returns:
+// - string: Command output (stdout + stderr)
+// - error: Execution failure
+func (o *SkyPilotOrchestrator) ExecCommand(ctx context.Context, clusterName, command string) (string, error) {
+	req := ExecRequest{ClusterName: clusterName, Command: command}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Timeout = time.Until(deadline)
+	}
+
+	result, err := o.Exec(ctx, req, nil)
+	if result == nil {
+		return "", err
+	}
+	return result.Output, err
+}
+
+// Exec runs a command on a node. With onOutput set the output is passed on
+// as it is produced instead of being buffered in the result; an error from
+// onOutput stops the command. Runs on tenant-owned nodes are audited, and
+// the command is refused if the audit record cannot be written.
+func (o *SkyPilotOrchestrator) Exec(ctx context.Context, req ExecRequest, onOutput func(ExecOutput) error) (*ExecResult, error) {
+	if req.Source == "" {
+		req.Source = ExecSourceSystem
+	}
+	if req.Source != ExecSourceSystem {
+		if err := checkExecCommand(req.Command, o.execAllowed); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout, err := o.execTimeout(req.Timeout, req.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	o.logger.Debug("executing command on node",
+		zap.String("cluster_name", req.ClusterName),
+		zap.String("command", req.Command),
+		zap.String("source", req.Source),
+		zap.Duration("timeout", timeout),
+		zap.Bool("streaming", onOutput != nil),
+		zap.Bool("use_api_server", o.useAPIServer),
+	)
+
+	auditID, err := o.startExecAudit(ctx, req, timeout, onOutput != nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record exec audit: %w", err)
+	}
+
+	result := &ExecResult{Timeout: timeout}
+	var buffered strings.Builder
+	emit := func(out ExecOutput) error {
+		result.OutputBytes += int64(len(out.Data))
+		if onOutput == nil {
+			buffered.WriteString(out.Data)
+			return nil
+		}
+		return onOutput(out)
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	if o.useAPIServer {
+		result.ExitCode, err = o.execCommandViaAPI(execCtx, req.ClusterName, req.Command, timeout, onOutput != nil, emit)
+	} else {
+		result.ExitCode, err = o.execCommandViaCLI(execCtx, req.ClusterName, req.Command, emit)
+	}
+	result.Duration = time.Since(start)
+	result.Output = buffered.String()
+
+	if err != nil && errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("command timed out after %s", timeout)
+	}
+	if err == nil && result.ExitCode != 0 {
+		err = fmt.Errorf("command exited with code %d", result.ExitCode)
+	}
+
+	o.finishExecAudit(auditID, result, err)
+	return result, err
+}
+
+// execCommandViaAPI executes a command using the SkyPilot API Server.
+func (o *SkyPilotOrchestrator) execCommandViaAPI(ctx context.Context, clusterName, command string, timeout time.Duration, stream bool, emit func(ExecOutput) error) (int, error) {
+	ctx = o.clusterWorkspaceContext(ctx, clusterName)
+
+	execReq := skypilot.ExecuteRequest{
+		ClusterName: clusterName,
+		Command:     command,
+		Timeout:     int(timeout.Seconds()),
+	}
+
+	if stream {
+		exitCode, err := o.apiClient.ExecuteStream(ctx, execReq, func(chunk skypilot.ExecuteChunk) error {
+			return emit(ExecOutput{Stream: chunk.Stream, Data: chunk.Data})
+		})
+		if err != nil {
+			return 0, fmt.Errorf("API execute failed: %w", err)
+		}
+		return exitCode, nil
+	}
+
+	execResp, err := o.apiClient.Execute(ctx, execReq)
+	if err != nil {
+		return 0, fmt.Errorf("API execute failed: %w", err)
+	}
+
+	// Combine stdout and stderr
+	if err := emit(ExecOutput{Stream: "stdout", Data: execResp.Stdout}); err != nil {
+		return 0, err
+	}
+	if execResp.Stderr != "" {
+		if err := emit(ExecOutput{Stream: "stderr", Data: "\n" + execResp.Stderr}); err != nil {
+			return 0, err
+		}
+	}
+
+	return execResp.ExitCode, nil
+}
+
+// execCommandViaCLI executes a command using the SkyPilot CLI (legacy mode).
+// The CLI interleaves the command's stdout and stderr.
+func (o *SkyPilotOrchestrator) execCommandViaCLI(ctx context.Context, clusterName, command string, emit func(ExecOutput) error) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sky", "exec",
+		clusterName,
+		command,
+	)
+
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, fmt.Errorf("sky exec failed: %w", err)
+	}
+	cmd.Stderr = cmd.Stdout
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("sky exec failed: %w", err)
+	}
+
+	// Stop the command if the output can't be passed on
+	var emitErr error
+	buf := make([]byte, execReadSize)
+	for {
+		n, readErr := output.Read(buf)
+		if n > 0 && emitErr == nil {
+			if emitErr = emit(ExecOutput{Stream: "stdout", Data: string(buf[:n])}); emitErr != nil {
+				cancel()
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	err = cmd.Wait()
+	if emitErr != nil {
+		return 0, emitErr
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("sky exec failed: %w", err)
+	}
+	return 0, nil
+}
+
+// startExecAudit records a command about to run on a tenant-owned node. It
+// returns uuid.Nil for nodes without a tenant, which are not audited.
+func (o *SkyPilotOrchestrator) startExecAudit(ctx context.Context, req ExecRequest, timeout time.Duration, streamed bool) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := o.db.Pool.QueryRow(ctx, `
+		INSERT INTO node_exec_audit (
+			node_id, tenant_id, cluster_name, command, source, actor, streamed, timeout_seconds
+		)
+		SELECT id, tenant_id, cluster_name, $2, $3, NULLIF($4, ''), $5, $6
+		FROM nodes
+		WHERE cluster_name = $1 AND tenant_id IS NOT NULL
+		LIMIT 1
+		RETURNING id
+	`, req.ClusterName, req.Command, req.Source, req.Actor, streamed, int(timeout.Seconds())).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	return id, err
+}
+
+// finishExecAudit records how an audited command ended. It uses its own
+// context so a disconnected client doesn't leave the record open.
+func (o *SkyPilotOrchestrator) finishExecAudit(id uuid.UUID, result *ExecResult, execErr error) {
+	if id == uuid.Nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var errMsg *string
+	if execErr != nil {
+		msg := execErr.Error()
+		errMsg = &msg
+	}
+
+	_, err := o.db.Pool.Exec(ctx, `
+		UPDATE node_exec_audit
+		SET exit_code = $2, error = $3, output_bytes = $4, finished_at = NOW()
+		WHERE id = $1
+	`, id, result.ExitCode, errMsg, result.OutputBytes)
+	if err != nil {
+		o.logger.Error("failed to finish exec audit record",
+			zap.Error(err),
+			zap.String("audit_id", id.String()),
+		)
+	}
+}
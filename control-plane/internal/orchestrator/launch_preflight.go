@@ -0,0 +1,229 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/crosslogic/control-plane/internal/skypilot"
+)
+
+// Preflight check outcomes. Warnings don't block a launch; failures would
+// make it fail.
+const (
+	PreflightPass = "pass"
+	PreflightWarn = "warn"
+	PreflightFail = "fail"
+	PreflightSkip = "skip"
+)
+
+// Preflight checks, in the order they are reported
+const (
+	preflightConfig       = "config"
+	preflightCredentials  = "credentials"
+	preflightAvailability = "availability"
+	preflightDisk         = "disk"
+	preflightQuota        = "quota"
+	preflightModelStorage = "model_storage"
+)
+
+// ModelStore tells whether a model's weights are in the R2 model bucket;
+// r2.Objects implements it
+type ModelStore interface {
+	HasPrefix(ctx context.Context, prefix string) (bool, error)
+}
+
+// SetModelStore enables the preflight check for model weights in R2
+func (o *SkyPilotOrchestrator) SetModelStore(store ModelStore) {
+	o.modelStore = store
+}
+
+// PreflightCheck is the outcome of one launch preflight check
+type PreflightCheck struct {
+	Name    string       `json:"name"`
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// PreflightReport is the outcome of every preflight check of a launch
+type PreflightReport struct {
+	Passed bool             `json:"passed"`
+	Checks []PreflightCheck `json:"checks"`
+	// Config is the launch configuration with defaults applied
+	Config NodeConfig `json:"config"`
+}
+
+func (r *PreflightReport) add(name, status, format string, args ...interface{}) *PreflightCheck {
+	r.Checks = append(r.Checks, PreflightCheck{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+	if status == PreflightFail {
+		r.Passed = false
+	}
+	return &r.Checks[len(r.Checks)-1]
+}
+
+// addFields reports field errors as a failed check, or a pass when there
+// are none
+func (r *PreflightReport) addFields(name, passMessage string, fields []FieldError) {
+	if len(fields) == 0 {
+		r.add(name, PreflightPass, "%s", passMessage)
+		return
+	}
+	check := r.add(name, PreflightFail, "%s", (&ConfigError{Fields: fields}).Error())
+	check.Fields = fields
+}
+
+// Preflight runs every check a launch of config would go through, plus the
+// provider's quota and the model's presence in R2, without launching
+// anything. Checks that need a valid configuration are skipped when it
+// isn't.
+func (o *SkyPilotOrchestrator) Preflight(ctx context.Context, config NodeConfig) (*PreflightReport, error) {
+	report := &PreflightReport{Passed: true}
+
+	o.resolveModelRuntime(ctx, &config)
+	err := o.validateNodeConfig(&config)
+	report.Config = config
+	if cfgErr, ok := err.(*ConfigError); ok {
+		report.addFields(preflightConfig, "", cfgErr.Fields)
+		for _, name := range []string{preflightCredentials, preflightAvailability, preflightDisk, preflightQuota, preflightModelStorage} {
+			report.add(name, PreflightSkip, "configuration is invalid")
+		}
+		return report, nil
+	}
+	if taskTemplate, err := o.templates.Resolve(ctx, config.Provider, config.Runtime); err != nil {
+		report.add(preflightConfig, PreflightFail, "runtime: %v", err)
+	} else if err := checkColocatedTemplate(&config, taskTemplate); err != nil {
+		report.addFields(preflightConfig, "", err.(*ConfigError).Fields)
+	} else {
+		report.add(preflightConfig, PreflightPass, "configuration is valid")
+	}
+
+	creds := o.preflightCredentials(ctx, &config, report)
+
+	catalog, err := o.loadLaunchCatalog(ctx, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load launch catalog: %w", err)
+	}
+	var errs ConfigError
+	catalog.check(&config, &errs)
+	var diskFields, availabilityFields []FieldError
+	for _, f := range errs.Fields {
+		if f.Field == "disk_size" {
+			diskFields = append(diskFields, f)
+		} else {
+			availabilityFields = append(availabilityFields, f)
+		}
+	}
+	if catalog.empty() {
+		report.add(preflightAvailability, PreflightWarn, "no catalog entries for %s; availability is left to SkyPilot", config.Provider)
+	} else {
+		report.addFields(preflightAvailability,
+			fmt.Sprintf("%s:%d is offered in %s on %s", config.GPU, config.GPUCount, config.Region, config.Provider),
+			availabilityFields)
+	}
+	if catalog.weightsGB() > 0 || len(diskFields) > 0 {
+		report.addFields(preflightDisk,
+			fmt.Sprintf("%d GB fits %s (about %.0f GB of weights)", config.DiskSize, describeModels(&config), catalog.weightsGB()),
+			diskFields)
+	} else {
+		report.add(preflightDisk, PreflightWarn, "size of %s is unknown; %d GB is not checked", config.Model, config.DiskSize)
+	}
+
+	o.preflightQuota(ctx, &config, creds, catalog, report)
+	o.preflightModelStorage(ctx, &config, report)
+
+	return report, nil
+}
+
+// preflightCredentials decrypts the tenant's cloud credentials and checks
+// they reach the region. CLI mode launches with the credentials SkyPilot is
+// configured with, which the control plane can't check.
+func (o *SkyPilotOrchestrator) preflightCredentials(ctx context.Context, config *NodeConfig, report *PreflightReport) *skypilot.CloudCredentials {
+	if !o.useAPIServer {
+		report.add(preflightCredentials, PreflightSkip, "CLI mode uses the credentials SkyPilot is configured with")
+		return nil
+	}
+	creds, err := o.getTenantCredentials(ctx, config.TenantID, config.Provider)
+	if err != nil {
+		report.add(preflightCredentials, PreflightFail, "%v", err)
+		return nil
+	}
+	if err := checkSovereignRegion(creds, config.Region); err != nil {
+		report.add(preflightCredentials, PreflightFail, "%v", err)
+		return nil
+	}
+	report.add(preflightCredentials, PreflightPass, "%s credentials decrypted", config.Provider)
+	return creds
+}
+
+// preflightQuota checks the provider's GPU and vCPU quotas in the region
+// through the API server
+func (o *SkyPilotOrchestrator) preflightQuota(ctx context.Context, config *NodeConfig, creds *skypilot.CloudCredentials, catalog *launchCatalog, report *PreflightReport) {
+	if o.apiClient == nil || creds == nil {
+		report.add(preflightQuota, PreflightSkip, "quotas are read through the SkyPilot API server with the tenant's credentials")
+		return
+	}
+
+	quota, err := o.apiClient.GetResourceQuota(o.workspaceContext(ctx, config.TenantID), skypilot.ResourceQuotaRequest{
+		Provider:         config.Provider,
+		Region:           config.Region,
+		CloudCredentials: creds,
+	})
+	if err != nil {
+		report.add(preflightQuota, PreflightWarn, "failed to read quotas: %v", err)
+		return
+	}
+	checkQuota(config, catalog.minVCPUs(config), quota.Quotas, report)
+}
+
+// checkQuota compares the GPUs and vCPUs the launch needs with what is left
+// of the quotas. Resources the provider reports no quota for are not
+// checked.
+func checkQuota(config *NodeConfig, vcpus int, quotas map[string]skypilot.ResourceQuotaInfo, report *PreflightReport) {
+	var problems, checked []string
+
+	want := normalizeGPU(config.GPU)
+	for _, q := range quotas {
+		resource := strings.ToLower(q.ResourceType)
+		if !strings.HasPrefix(resource, "gpu-") || normalizeGPU(strings.TrimPrefix(resource, "gpu-")) != want {
+			continue
+		}
+		checked = append(checked, "GPU")
+		if q.Available < config.GPUCount {
+			problems = append(problems, fmt.Sprintf("%d %s GPUs needed but %d of %d available", config.GPUCount, config.GPU, q.Available, q.Limit))
+		}
+	}
+	if q, ok := quotas["vcpu"]; ok && vcpus > 0 {
+		checked = append(checked, "vCPU")
+		if q.Available < vcpus {
+			problems = append(problems, fmt.Sprintf("%d vCPUs needed but %d of %d available", vcpus, q.Available, q.Limit))
+		}
+	}
+
+	switch {
+	case len(problems) > 0:
+		report.add(preflightQuota, PreflightFail, "%s quota in %s: %s", config.Provider, config.Region, strings.Join(problems, "; "))
+	case len(checked) == 0:
+		report.add(preflightQuota, PreflightWarn, "%s reported no GPU or vCPU quota in %s", config.Provider, config.Region)
+	default:
+		report.add(preflightQuota, PreflightPass, "%s quota in %s covers the launch", strings.Join(checked, " and "), config.Region)
+	}
+}
+
+// preflightModelStorage checks the model's weights are in R2. Nodes fall
+// back to downloading from HuggingFace, so a missing model only warns.
+func (o *SkyPilotOrchestrator) preflightModelStorage(ctx context.Context, config *NodeConfig, report *PreflightReport) {
+	if o.modelStore == nil {
+		report.add(preflightModelStorage, PreflightSkip, "R2 model storage is not configured")
+		return
+	}
+	found, err := o.modelStore.HasPrefix(ctx, config.Model+"/")
+	switch {
+	case err != nil:
+		report.add(preflightModelStorage, PreflightWarn, "failed to check R2 for %s: %v", config.Model, err)
+	case !found:
+		report.add(preflightModelStorage, PreflightWarn, "%s is not in R2; the node will download it from HuggingFace", config.Model)
+	default:
+		report.add(preflightModelStorage, PreflightPass, "%s is in R2 and will be streamed from it", config.Model)
+	}
+}
@@ -0,0 +1,93 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/crosslogic/control-plane/internal/skypilot"
+)
+
+func TestPreflightInvalidConfigSkipsChecks(t *testing.T) {
+	o := &SkyPilotOrchestrator{vllmVersion: "0.6.2", torchVersion: "2.4.0"}
+
+	report, err := o.Preflight(context.Background(), NodeConfig{Provider: "aws", GPU: "A10G"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Passed {
+		t.Fatal("report passed with an invalid configuration")
+	}
+	if report.Checks[0].Name != preflightConfig || report.Checks[0].Status != PreflightFail {
+		t.Fatalf("first check = %+v, want a failed config check", report.Checks[0])
+	}
+	if len(report.Checks[0].Fields) != 2 {
+		t.Errorf("config fields = %+v, want region and model", report.Checks[0].Fields)
+	}
+	for _, check := range report.Checks[1:] {
+		if check.Status != PreflightSkip {
+			t.Errorf("check %s = %s, want skip", check.Name, check.Status)
+		}
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	config := &NodeConfig{Provider: "aws", Region: "us-east-1", GPU: "A100-80GB", GPUCount: 8}
+
+	tests := []struct {
+		name       string
+		quotas     map[string]skypilot.ResourceQuotaInfo
+		wantStatus string
+		wantText   string
+	}{
+		{"covered", map[string]skypilot.ResourceQuotaInfo{
+			"gpu-a100-80gb": {ResourceType: "gpu-a100-80gb", Limit: 16, Available: 8},
+			"vcpu":          {ResourceType: "vcpu", Limit: 192, Available: 96},
+		}, PreflightPass, "GPU and vCPU quota"},
+		{"gpus short", map[string]skypilot.ResourceQuotaInfo{
+			"gpu-a100-80gb": {ResourceType: "gpu-a100-80gb", Limit: 8, Available: 4},
+		}, PreflightFail, "8 A100-80GB GPUs needed but 4 of 8 available"},
+		{"vcpus short", map[string]skypilot.ResourceQuotaInfo{
+			"vcpu": {ResourceType: "vcpu", Limit: 64, Available: 32},
+		}, PreflightFail, "96 vCPUs needed but 32 of 64 available"},
+		{"other gpus only", map[string]skypilot.ResourceQuotaInfo{
+			"gpu-h100": {ResourceType: "gpu-h100", Limit: 8, Available: 0},
+		}, PreflightWarn, "no GPU or vCPU quota"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &PreflightReport{Passed: true}
+			checkQuota(config, 96, tt.quotas, report)
+			check := report.Checks[0]
+			if check.Status != tt.wantStatus || !strings.Contains(check.Message, tt.wantText) {
+				t.Errorf("check = %+v, want %s containing %q", check, tt.wantStatus, tt.wantText)
+			}
+			if report.Passed != (tt.wantStatus != PreflightFail) {
+				t.Errorf("passed = %v with status %s", report.Passed, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestLaunchCatalogMinVCPUs(t *testing.T) {
+	catalog := &launchCatalog{Offers: []catalogOffer{
+		{InstanceType: "g5.2xlarge", GPUModel: "NVIDIA A10G", GPUCount: 1, VCPUs: 8},
+		{InstanceType: "g5.xlarge", GPUModel: "NVIDIA A10G", GPUCount: 1, VCPUs: 4,
+			Regions: []string{"us-west-2"}, HasAvailability: true},
+		{InstanceType: "g5.12xlarge", GPUModel: "NVIDIA A10G", GPUCount: 4, VCPUs: 48},
+	}}
+
+	config := &NodeConfig{Region: "us-east-1", GPU: "A10G", GPUCount: 1}
+	if got := catalog.minVCPUs(config); got != 8 {
+		t.Errorf("minVCPUs in us-east-1 = %d, want 8", got)
+	}
+	config.Region = "us-west-2"
+	if got := catalog.minVCPUs(config); got != 4 {
+		t.Errorf("minVCPUs in us-west-2 = %d, want 4", got)
+	}
+	config.GPU = "H100"
+	if got := catalog.minVCPUs(config); got != 0 {
+		t.Errorf("minVCPUs of an unknown GPU = %d, want 0", got)
+	}
+}
@@ -0,0 +1,348 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LaunchQueue bounds how many launches run at once against each provider and
+// region, so bursts don't trip cloud API rate limits or overload the SkyPilot
+// API server.
+//
+// A launch holds a slot on its provider (when the provider has a limit) and on
+// its provider/region pair for as long as LaunchNode runs. Waiting launches
+// are started fairly across tenants: the tenant with the fewest launches in
+// flight goes first, then the tenant served least recently, then the launch
+// that has waited longest. A waiting
+// launch that fits never waits behind one that doesn't, so a saturated region
+// doesn't hold up the others.
+//
+// Limits apply to this control-plane process.
+type LaunchQueue struct {
+	// defaultLimit applies to each provider/region pair without its own
+	// limit; zero or less means unlimited
+	defaultLimit int
+	// limits are keyed by provider ("aws") or provider/region ("aws/us-east-1")
+	limits map[string]int
+
+	mu            sync.Mutex
+	running       map[string]int
+	tenantRunning map[string]int
+	// tenantServed is when each tenant with queued or running launches last
+	// had one started, as a count of starts
+	tenantServed map[string]uint64
+	starts       uint64
+	waiting      []*launchTicket
+	seq          uint64
+}
+
+// launchTicket is a launch waiting for, or holding, its slots
+type launchTicket struct {
+	nodeID   string
+	tenantID string
+	provider string
+	region   string
+	seq      uint64
+	since    time.Time
+
+	ready      chan struct{}
+	started    bool
+	position   int
+	onPosition func(position int)
+}
+
+// QueuedLaunch is a launch waiting for a slot
+type QueuedLaunch struct {
+	NodeID       string    `json:"node_id"`
+	TenantID     string    `json:"tenant_id,omitempty"`
+	Provider     string    `json:"provider"`
+	Region       string    `json:"region"`
+	Position     int       `json:"position"`
+	WaitingSince time.Time `json:"waiting_since"`
+}
+
+// LaunchQueueStatus is a snapshot of the launch queue
+type LaunchQueueStatus struct {
+	DefaultLimit int            `json:"default_limit"`
+	Limits       map[string]int `json:"limits"`
+	Running      map[string]int `json:"running"`
+	Waiting      []QueuedLaunch `json:"waiting"`
+}
+
+// NewLaunchQueue creates a launch queue. defaultLimit applies to each
+// provider/region pair without an entry in limits; zero or less means
+// unlimited.
+func NewLaunchQueue(defaultLimit int, limits map[string]int) *LaunchQueue {
+	if limits == nil {
+		limits = make(map[string]int)
+	}
+	return &LaunchQueue{
+		defaultLimit:  defaultLimit,
+		limits:        limits,
+		running:       make(map[string]int),
+		tenantRunning: make(map[string]int),
+		tenantServed:  make(map[string]uint64),
+	}
+}
+
+// ParseLaunchLimits parses "provider=N" and "provider/region=N" entries
+func ParseLaunchLimits(entries []string) (map[string]int, error) {
+	limits := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid launch limit %q: expected provider[/region]=N", entry)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("invalid launch limit %q: limit must be a positive integer", entry)
+		}
+		limits[key] = limit
+	}
+	return limits, nil
+}
+
+// Acquire waits for a launch slot on the provider and region and returns the
+// function that gives it back. onPosition, if set, is called with the
+// launch's 1-based queue position whenever it changes while waiting; it runs
+// outside the queue's lock and must not block for long.
+func (q *LaunchQueue) Acquire(ctx context.Context, nodeID, tenantID, provider, region string, onPosition func(position int)) (func(), error) {
+	q.mu.Lock()
+	q.seq++
+	t := &launchTicket{
+		nodeID:     nodeID,
+		tenantID:   tenantID,
+		provider:   strings.ToLower(provider),
+		region:     strings.ToLower(region),
+		seq:        q.seq,
+		since:      time.Now(),
+		ready:      make(chan struct{}),
+		onPosition: onPosition,
+	}
+	q.waiting = append(q.waiting, t)
+	notify := q.dispatch()
+	q.mu.Unlock()
+	notify()
+
+	select {
+	case <-t.ready:
+		return q.releaseFunc(t), nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	if t.started {
+		// Granted while giving up: hand the slot straight back
+		q.mu.Unlock()
+		q.release(t)
+		return nil, ctx.Err()
+	}
+	q.remove(t)
+	q.forgetTenant(t.tenantID)
+	notify = q.dispatch()
+	q.mu.Unlock()
+	notify()
+	return nil, ctx.Err()
+}
+
+// Position returns a waiting launch's 1-based queue position, or zero if the
+// launch isn't waiting
+func (q *LaunchQueue) Position(nodeID string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, t := range q.waiting {
+		if t.nodeID == nodeID {
+			return t.position
+		}
+	}
+	return 0
+}
+
+// Status returns a snapshot of running and waiting launches
+func (q *LaunchQueue) Status() LaunchQueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	status := LaunchQueueStatus{
+		DefaultLimit: q.defaultLimit,
+		Limits:       make(map[string]int, len(q.limits)),
+		Running:      make(map[string]int, len(q.running)),
+		Waiting:      make([]QueuedLaunch, 0, len(q.waiting)),
+	}
+	for key, limit := range q.limits {
+		status.Limits[key] = limit
+	}
+	for key, n := range q.running {
+		status.Running[key] = n
+	}
+	for _, t := range q.ordered() {
+		status.Waiting = append(status.Waiting, QueuedLaunch{
+			NodeID:       t.nodeID,
+			TenantID:     t.tenantID,
+			Provider:     t.provider,
+			Region:       t.region,
+			Position:     t.position,
+			WaitingSince: t.since,
+		})
+	}
+	return status
+}
+
+func (q *LaunchQueue) releaseFunc(t *launchTicket) func() {
+	var once sync.Once
+	return func() { once.Do(func() { q.release(t) }) }
+}
+
+func (q *LaunchQueue) release(t *launchTicket) {
+	q.mu.Lock()
+	q.decrement(q.running, t.provider)
+	q.decrement(q.running, regionKey(t.provider, t.region))
+	q.decrement(q.tenantRunning, t.tenantID)
+	q.forgetTenant(t.tenantID)
+	notify := q.dispatch()
+	q.mu.Unlock()
+	notify()
+}
+
+// dispatch starts every waiting launch that fits, in fair order, and
+// renumbers the rest. It returns the position callbacks to run once the lock
+// is released. Callers hold q.mu.
+func (q *LaunchQueue) dispatch() func() {
+	for {
+		var next *launchTicket
+		for _, t := range q.ordered() {
+			if q.fits(t) {
+				next = t
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		q.remove(next)
+		q.running[next.provider]++
+		q.running[regionKey(next.provider, next.region)]++
+		q.tenantRunning[next.tenantID]++
+		q.starts++
+		q.tenantServed[next.tenantID] = q.starts
+		next.started = true
+		close(next.ready)
+	}
+
+	// Positions count the launches ahead that compete for the same provider
+	var callbacks []func()
+	ahead := make(map[string]int)
+	for _, t := range q.ordered() {
+		ahead[t.provider]++
+		if position := ahead[t.provider]; position != t.position {
+			t.position = position
+			if t.onPosition != nil {
+				onPosition := t.onPosition
+				callbacks = append(callbacks, func() { onPosition(position) })
+			}
+		}
+	}
+	return func() {
+		for _, callback := range callbacks {
+			callback()
+		}
+	}
+}
+
+// ordered returns the waiting launches in the order they should start.
+// Callers hold q.mu.
+func (q *LaunchQueue) ordered() []*launchTicket {
+	ordered := append([]*launchTicket(nil), q.waiting...)
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if ra, rb := q.tenantRunning[a.tenantID], q.tenantRunning[b.tenantID]; ra != rb {
+			return ra < rb
+		}
+		if sa, sb := q.tenantServed[a.tenantID], q.tenantServed[b.tenantID]; sa != sb {
+			return sa < sb
+		}
+		return a.seq < b.seq
+	})
+	return ordered
+}
+
+// fits reports whether a launch can start without exceeding a limit. Callers
+// hold q.mu.
+func (q *LaunchQueue) fits(t *launchTicket) bool {
+	if limit, ok := q.limits[t.provider]; ok && q.running[t.provider] >= limit {
+		return false
+	}
+	key := regionKey(t.provider, t.region)
+	limit, ok := q.limits[key]
+	if !ok {
+		limit = q.defaultLimit
+	}
+	return limit <= 0 || q.running[key] < limit
+}
+
+func (q *LaunchQueue) remove(t *launchTicket) {
+	for i, w := range q.waiting {
+		if w == t {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// forgetTenant drops a tenant's service history once it has nothing queued or
+// running. Callers hold q.mu.
+func (q *LaunchQueue) forgetTenant(tenantID string) {
+	if q.tenantRunning[tenantID] > 0 {
+		return
+	}
+	for _, t := range q.waiting {
+		if t.tenantID == tenantID {
+			return
+		}
+	}
+	delete(q.tenantServed, tenantID)
+}
+
+func (q *LaunchQueue) decrement(counts map[string]int, key string) {
+	if counts[key] <= 1 {
+		delete(counts, key)
+		return
+	}
+	counts[key]--
+}
+
+func regionKey(provider, region string) string {
+	return provider + "/" + region
+}
+
+// LaunchQueue returns the orchestrator's launch queue
+func (o *SkyPilotOrchestrator) LaunchQueue() *LaunchQueue {
+	return o.launchQueue
+}
+
+// waitForLaunchSlot queues a launch until its provider and region have
+// capacity, reporting its position in the node's launch log
+func (o *SkyPilotOrchestrator) waitForLaunchSlot(ctx context.Context, config NodeConfig) (func(), error) {
+	queuedAt := time.Now()
+	var waited atomic.Bool
+	release, err := o.launchQueue.Acquire(ctx, config.NodeID, config.TenantID, config.Provider, config.Region, func(position int) {
+		waited.Store(true)
+		o.logStore.LogInfo(context.Background(), config.NodeID, PhaseQueued,
+			fmt.Sprintf("Waiting for a %s/%s launch slot: position %d in queue", config.Provider, config.Region, position), 5)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("launch cancelled while queued: %w", err)
+	}
+	if waited.Load() {
+		o.logStore.LogInfo(ctx, config.NodeID, PhaseQueued,
+			fmt.Sprintf("Launch slot acquired after %s", time.Since(queuedAt).Round(time.Second)), 5)
+	}
+	return release, nil
+}
@@ -0,0 +1,168 @@
+package orchestrator
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queueLaunch starts an Acquire in the background and returns a channel that
+// receives its release function once the launch gets a slot
+func queueLaunch(t *testing.T, q *LaunchQueue, nodeID, tenantID, provider, region string) <-chan func() {
+	t.Helper()
+	started := make(chan func(), 1)
+	go func() {
+		release, err := q.Acquire(context.Background(), nodeID, tenantID, provider, region, nil)
+		if err == nil {
+			started <- release
+		}
+	}()
+	waitFor(t, func() bool {
+		for _, w := range q.Status().Waiting {
+			if w.NodeID == nodeID {
+				return true
+			}
+		}
+		return q.Position(nodeID) == 0 && len(started) == 1
+	})
+	return started
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestParseLaunchLimits(t *testing.T) {
+	limits, err := ParseLaunchLimits([]string{"aws=8", " AWS/us-east-1 = 2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"aws": 8, "aws/us-east-1": 2}; !reflect.DeepEqual(limits, want) {
+		t.Errorf("ParseLaunchLimits() = %v, want %v", limits, want)
+	}
+
+	for _, bad := range []string{"aws", "=2", "aws=0", "aws=many"} {
+		if _, err := ParseLaunchLimits([]string{bad}); err == nil {
+			t.Errorf("ParseLaunchLimits(%q) accepted", bad)
+		}
+	}
+}
+
+func TestLaunchQueueLimits(t *testing.T) {
+	q := NewLaunchQueue(1, map[string]int{"aws": 2})
+	ctx := context.Background()
+
+	releaseEast, err := q.Acquire(ctx, "n1", "t1", "aws", "us-east-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another region has its own slot
+	releaseWest, err := q.Acquire(ctx, "n2", "t1", "aws", "us-west-2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The provider limit holds back a third region; the region limit holds
+	// back a second us-east-1 launch
+	central := queueLaunch(t, q, "n3", "t1", "aws", "us-central-1")
+	east := queueLaunch(t, q, "n4", "t1", "aws", "us-east-1")
+	if len(central) != 0 || len(east) != 0 {
+		t.Fatal("launches started beyond the limits")
+	}
+	// Other providers are unaffected
+	if _, err := q.Acquire(ctx, "n5", "t1", "gcp", "us-east1", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	releaseWest()
+	releaseWest() // releasing twice is harmless
+	(<-central)()
+	if len(east) != 0 {
+		t.Fatal("us-east-1 launch started while its region was full")
+	}
+
+	releaseEast()
+	(<-east)()
+	if status := q.Status(); status.Running["aws"] != 0 || len(status.Waiting) != 0 {
+		t.Errorf("status after release = %+v", status)
+	}
+}
+
+func TestLaunchQueueFairAcrossTenants(t *testing.T) {
+	q := NewLaunchQueue(1, nil)
+	release, err := q.Acquire(context.Background(), "a1", "tenant-a", "aws", "us-east-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a2 := queueLaunch(t, q, "a2", "tenant-a", "aws", "us-east-1")
+	a3 := queueLaunch(t, q, "a3", "tenant-a", "aws", "us-east-1")
+	b1 := queueLaunch(t, q, "b1", "tenant-b", "aws", "us-east-1")
+
+	// tenant-b has nothing running, so its launch goes ahead of tenant-a's
+	// earlier ones
+	if got := q.Position("b1"); got != 1 {
+		t.Errorf("b1 position = %d, want 1", got)
+	}
+	if got := q.Position("a3"); got != 3 {
+		t.Errorf("a3 position = %d, want 3", got)
+	}
+
+	release()
+	releaseB := <-b1
+	if len(a2) != 0 || len(a3) != 0 {
+		t.Fatal("tenant-a launch started ahead of tenant-b")
+	}
+	releaseB()
+	(<-a2)()
+	(<-a3)()
+}
+
+func TestLaunchQueueCancel(t *testing.T) {
+	q := NewLaunchQueue(1, nil)
+	release, err := q.Acquire(context.Background(), "n1", "t1", "aws", "us-east-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var positions []int
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := q.Acquire(ctx, "n2", "t1", "aws", "us-east-1", func(position int) {
+			mu.Lock()
+			positions = append(positions, position)
+			mu.Unlock()
+		})
+		done <- err
+	}()
+	waitFor(t, func() bool { return q.Position("n2") == 1 })
+
+	cancel()
+	if err := <-done; err == nil {
+		t.Fatal("cancelled launch acquired a slot")
+	}
+	if q.Position("n2") != 0 {
+		t.Error("cancelled launch still queued")
+	}
+	mu.Lock()
+	if !reflect.DeepEqual(positions, []int{1}) {
+		t.Errorf("positions = %v, want [1]", positions)
+	}
+	mu.Unlock()
+
+	release()
+	if _, err := q.Acquire(context.Background(), "n3", "t1", "aws", "us-east-1", nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/crosslogic/control-plane/internal/nodes"
 	"github.com/crosslogic/control-plane/pkg/database"
 	"github.com/crosslogic/control-plane/pkg/events"
 	"go.uber.org/zap"
@@ -49,6 +50,9 @@ type TripleSafetyMonitor struct {
 	pollInterval       time.Duration
 	cloudCheckInterval time.Duration
 
+	// deregisterAfter is the heartbeat gap after which a node is deregistered
+	deregisterAfter time.Duration
+
 	// Cache for health signals
 	healthSignals sync.Map // nodeID -> map[string]*HealthSignal
 }
@@ -71,6 +75,18 @@ func NewTripleSafetyMonitor(db *database.Database, logger *zap.Logger, orch *Sky
 		heartbeatTimeout:   30 * time.Second,
 		pollInterval:       30 * time.Second, // More frequent polling
 		cloudCheckInterval: 1 * time.Minute,  // More frequent cloud checks
+		deregisterAfter:    5 * time.Minute,
+	}
+}
+
+// SetHeartbeatThresholds configures the heartbeat timeout used in health
+// evaluation and the larger gap after which silent nodes are deregistered.
+func (m *TripleSafetyMonitor) SetHeartbeatThresholds(timeout, deregisterAfter time.Duration) {
+	if timeout > 0 {
+		m.heartbeatTimeout = timeout
+	}
+	if deregisterAfter > 0 {
+		m.deregisterAfter = deregisterAfter
 	}
 }
 
@@ -83,17 +99,20 @@ func (m *TripleSafetyMonitor) Start(ctx context.Context) {
 
 	// Layer 3: Cloud API Verification Loop
 	go m.cloudVerificationLoop(ctx)
+
+	// Stale node deregistration based on heartbeat gaps
+	go m.staleNodeLoop(ctx)
 }
 
 // RecordHeartbeat processes a heartbeat from a node (Layer 1).
 func (m *TripleSafetyMonitor) RecordHeartbeat(ctx context.Context, nodeID string, healthScore float64) error {
-	// Update node status and last_heartbeat in DB
+	// Update node status and last_heartbeat_at in DB
 	query := `
 		UPDATE nodes
-		SET last_heartbeat = NOW(), health_score = $1, status = 'active'
+		SET last_heartbeat_at = NOW(), health_score = $1, status = 'active', status_source = $3
 		WHERE id = $2
 	`
-	result, err := m.db.Pool.Exec(ctx, query, healthScore, nodeID)
+	result, err := m.db.Pool.Exec(ctx, query, healthScore, nodeID, nodes.SourceNode)
 	if err != nil {
 		return fmt.Errorf("failed to record heartbeat: %w", err)
 	}
@@ -132,8 +151,9 @@ func (m *TripleSafetyMonitor) activePollingLoop(ctx context.Context) {
 }
 
 func (m *TripleSafetyMonitor) pollNodes(ctx context.Context) {
-	// Get all active nodes (or suspect nodes that need verification)
-	rows, err := m.db.Pool.Query(ctx, "SELECT id, endpoint FROM nodes WHERE status IN ('active', 'suspect')")
+	// Get all active nodes (or suspect nodes that need verification).
+	// Colocated models follow their host's status.
+	rows, err := m.db.Pool.Query(ctx, "SELECT id, endpoint_url FROM nodes WHERE status IN ('active', 'suspect') AND host_node_id IS NULL")
 	if err != nil {
 		m.logger.Error("failed to fetch nodes for polling", zap.Error(err))
 		return
@@ -364,8 +384,8 @@ func (m *TripleSafetyMonitor) updateNodeStatus(ctx context.Context, nodeID strin
 	}
 
 	// Update database
-	query := `UPDATE nodes SET status = $1, status_message = $2, updated_at = NOW() WHERE id = $3`
-	_, err := m.db.Pool.Exec(ctx, query, dbStatus, statusMessage, nodeID)
+	query := `UPDATE nodes SET status = $1, status_message = $2, status_source = $4, updated_at = NOW() WHERE id = $3`
+	_, err := m.db.Pool.Exec(ctx, query, dbStatus, statusMessage, nodeID, nodes.SourceMonitor)
 	if err != nil {
 		m.logger.Error("failed to update node status",
 			zap.String("node_id", nodeID),
@@ -402,6 +422,112 @@ func (m *TripleSafetyMonitor) markNodeSuspect(ctx context.Context, nodeID, reaso
 		zap.String("reason", reason),
 	)
 
-	query := `UPDATE nodes SET status = 'suspect', status_message = $1 WHERE id = $2`
-	m.db.Pool.Exec(ctx, query, reason, nodeID)
+	query := `UPDATE nodes SET status = 'suspect', status_message = $1, status_source = $3 WHERE id = $2`
+	m.db.Pool.Exec(ctx, query, reason, nodeID, nodes.SourceMonitor)
+}
+
+// staleNodeLoop periodically deregisters nodes whose heartbeats stopped.
+// This catches nodes that died without a termination event.
+func (m *TripleSafetyMonitor) staleNodeLoop(ctx context.Context) {
+	ticker := time.NewTicker(m.heartbeatTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.deregisterStaleNodes(ctx)
+		}
+	}
+}
+
+// staleNode is a live node whose heartbeats may have stopped
+type staleNode struct {
+	id            string
+	clusterName   string
+	lastHeartbeat time.Time
+}
+
+// pastDeregistrationGrace returns the nodes that have gone without a
+// heartbeat for longer than after as of now
+func pastDeregistrationGrace(candidates []staleNode, after time.Duration, now time.Time) []staleNode {
+	var stale []staleNode
+	for _, n := range candidates {
+		if nodes.HeartbeatStale(&n.lastHeartbeat, after, now) {
+			stale = append(stale, n)
+		}
+	}
+	return stale
+}
+
+// deregisterStaleNodes marks nodes dead when their last heartbeat is older
+// than deregisterAfter, and publishes a health change event for each. A
+// node whose heartbeat arrives while it is being deregistered is left alone.
+func (m *TripleSafetyMonitor) deregisterStaleNodes(ctx context.Context) {
+	reason := fmt.Sprintf("deregistered: no heartbeat for over %s", m.deregisterAfter)
+
+	// Heartbeats are stamped by the database, so its clock judges them
+	rows, err := m.db.Pool.Query(ctx, `
+		SELECT id, COALESCE(cluster_name, ''), last_heartbeat_at, NOW()
+		FROM nodes
+		WHERE status IN ('active', 'degraded', 'suspect')
+		  AND last_heartbeat_at IS NOT NULL
+	`)
+	if err != nil {
+		m.logger.Error("failed to deregister stale nodes", zap.Error(err))
+		return
+	}
+
+	var candidates []staleNode
+	var now time.Time
+	for rows.Next() {
+		var n staleNode
+		if err := rows.Scan(&n.id, &n.clusterName, &n.lastHeartbeat, &now); err != nil {
+			continue
+		}
+		candidates = append(candidates, n)
+	}
+	rows.Close()
+
+	var stale []staleNode
+	for _, n := range pastDeregistrationGrace(candidates, m.deregisterAfter, now) {
+		result, err := m.db.Pool.Exec(ctx, `
+			UPDATE nodes
+			SET status = 'dead', status_message = $1, status_source = $2, updated_at = NOW()
+			WHERE id = $3 AND last_heartbeat_at = $4
+			  AND status IN ('active', 'degraded', 'suspect')
+		`, reason, nodes.SourceMonitor, n.id, n.lastHeartbeat)
+		if err != nil {
+			m.logger.Error("failed to deregister stale node", zap.Error(err), zap.String("node_id", n.id))
+			continue
+		}
+		if result.RowsAffected() > 0 {
+			stale = append(stale, n)
+		}
+	}
+
+	for _, n := range stale {
+		m.logger.Warn("deregistered stale node",
+			zap.String("node_id", n.id),
+			zap.String("cluster_name", n.clusterName),
+			zap.Duration("threshold", m.deregisterAfter),
+		)
+
+		// Drop cached signals so a late heartbeat starts from a clean slate
+		m.healthSignals.Delete(n.id)
+
+		if m.eventBus != nil {
+			m.eventBus.Publish(ctx, events.NewEvent(
+				events.EventNodeHealthChanged,
+				"", // System event, no specific tenant
+				map[string]interface{}{
+					"node_id":      n.id,
+					"cluster_name": n.clusterName,
+					"status":       "dead",
+					"message":      reason,
+				},
+			))
+		}
+	}
 }
@@ -0,0 +1,40 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestPastDeregistrationGrace(t *testing.T) {
+	m := NewTripleSafetyMonitor(nil, zap.NewNop(), nil, nil)
+	m.SetHeartbeatThresholds(30*time.Second, 5*time.Minute)
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	candidates := []staleNode{
+		{id: "fresh", lastHeartbeat: now.Add(-10 * time.Second)},
+		// Out of routing, but still within the grace period
+		{id: "quiet", lastHeartbeat: now.Add(-2 * time.Minute)},
+		{id: "gone", lastHeartbeat: now.Add(-6 * time.Minute)},
+	}
+
+	stale := pastDeregistrationGrace(candidates, m.deregisterAfter, now)
+	if len(stale) != 1 || stale[0].id != "gone" {
+		t.Fatalf("deregistered %+v, want only gone", stale)
+	}
+
+	// A fresh heartbeat keeps the node registered
+	candidates[2].lastHeartbeat = now
+	if stale := pastDeregistrationGrace(candidates, m.deregisterAfter, now); len(stale) != 0 {
+		t.Errorf("deregistered %+v after a fresh heartbeat", stale)
+	}
+}
+
+func TestSetHeartbeatThresholdsKeepsDefaults(t *testing.T) {
+	m := NewTripleSafetyMonitor(nil, zap.NewNop(), nil, nil)
+	m.SetHeartbeatThresholds(0, 0)
+	if m.heartbeatTimeout != 30*time.Second || m.deregisterAfter != 5*time.Minute {
+		t.Errorf("thresholds = %s, %s; want the defaults", m.heartbeatTimeout, m.deregisterAfter)
+	}
+}
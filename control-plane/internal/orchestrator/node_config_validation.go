@@ -0,0 +1,385 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// minDiskSizeGB fits the OS image, the vLLM virtualenv and the node agent
+	minDiskSizeGB = 50
+
+	// diskOverheadGB is the disk needed beyond the model weights for the
+	// same, plus the HuggingFace cache's temporary files
+	diskOverheadGB = 50
+
+	// maxListedOptions caps the alternatives listed in an error message
+	maxListedOptions = 10
+)
+
+// FieldError is a problem with one NodeConfig field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ConfigError lists every problem found in a NodeConfig, so a caller can
+// fix them all in one go
+type ConfigError struct {
+	Fields []FieldError
+}
+
+func (e *ConfigError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return "invalid node configuration: " + strings.Join(parts, "; ")
+}
+
+func (e *ConfigError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the ConfigError, or nil when no field had a problem
+func (e *ConfigError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// checkTensorParallel checks that vLLM can shard the model across the GPUs
+func checkTensorParallel(config *NodeConfig, errs *ConfigError) {
+	tp, gpus := config.TensorParallel, config.GPUCount
+	if tp >= 1 && tp <= gpus && gpus%tp == 0 {
+		return
+	}
+
+	var divisors []string
+	for d := 1; d <= gpus; d++ {
+		if gpus%d == 0 {
+			divisors = append(divisors, fmt.Sprint(d))
+		}
+	}
+	errs.add("tensor_parallel", "%d must divide gpu_count %d; use one of %s", tp, gpus, strings.Join(divisors, ", "))
+}
+
+// catalogOffer is an instance type of the provider as seen by the launch
+// validation
+type catalogOffer struct {
+	InstanceType string
+	GPUModel     string
+	GPUCount     int
+	GPUMemoryGB  float64 // across all GPUs of the instance
+	VCPUs        int
+	SupportsSpot bool
+	// Regions lists where the instance is in stock; it is only meaningful
+	// when HasAvailability is set
+	Regions         []string
+	HasAvailability bool
+}
+
+func (o *catalogOffer) availableIn(region string) bool {
+	if !o.HasAvailability {
+		return true
+	}
+	for _, r := range o.Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// launchCatalog is what the catalog knows about a provider and model
+type launchCatalog struct {
+	Offers  []catalogOffer
+	Regions []string
+	// ModelGB estimates the model's weights; zero when unknown
+	ModelGB float64
+	// MinVLLMVersion is the first vLLM release serving the model; empty
+	// when unknown
+	MinVLLMVersion string
+	// ContextLength is the model's context window; zero when unknown
+	ContextLength int
+	// Colocated is the launch's colocated models, in order
+	Colocated []catalogModel
+}
+
+// empty reports whether the catalog has nothing for the provider, in which
+// case the launch is left to SkyPilot
+func (c *launchCatalog) empty() bool {
+	return len(c.Offers) == 0 && len(c.Regions) == 0
+}
+
+// normalizeGPU makes catalog GPU models ("NVIDIA A100 80GB") comparable
+// with SkyPilot accelerator names ("A100-80GB")
+func normalizeGPU(name string) string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "NVIDIA ")
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// check validates the provider/region/GPU combination, GPU memory, disk
+// size and vLLM version against the catalog
+func (c *launchCatalog) check(config *NodeConfig, errs *ConfigError) {
+	if config.Runtime == "" || config.Runtime == DefaultRuntime {
+		checkVLLMCompatibility(config.Model, config.VLLMVersion, c.MinVLLMVersion, errs)
+		for _, m := range c.Colocated {
+			checkVLLMCompatibility(m.Name, config.VLLMVersion, m.MinVLLMVersion, errs)
+		}
+	}
+
+	regionKnown := len(c.Regions) == 0 || containsString(c.Regions, config.Region)
+	if !regionKnown {
+		errs.add("region", "unknown %s region %q; known regions: %s", config.Provider, config.Region, listOptions(c.Regions))
+	}
+
+	weightsGB := c.weightsGB()
+	if weightsGB > 0 {
+		minDisk := int(math.Ceil(weightsGB)) + diskOverheadGB
+		if config.DiskSize < minDisk {
+			errs.add("disk_size", "%d GB is too small for %s (about %.0f GB of weights); use at least %d",
+				config.DiskSize, describeModels(config), weightsGB, minDisk)
+		}
+	}
+
+	if len(c.Offers) == 0 {
+		return
+	}
+
+	// Instance types with the requested GPU (or named directly)
+	want := normalizeGPU(config.GPU)
+	var withGPU []catalogOffer
+	gpuModels := map[string]bool{}
+	for _, o := range c.Offers {
+		gpuModels[strings.TrimPrefix(o.GPUModel, "NVIDIA ")] = true
+		if normalizeGPU(o.GPUModel) == want || o.InstanceType == config.GPU {
+			withGPU = append(withGPU, o)
+		}
+	}
+	if len(withGPU) == 0 {
+		errs.add("gpu", "%s has no instance type with GPU %q; available GPUs: %s", config.Provider, config.GPU, listOptions(mapKeys(gpuModels)))
+		return
+	}
+
+	var candidates []catalogOffer
+	counts := map[string]bool{}
+	for _, o := range withGPU {
+		counts[fmt.Sprint(o.GPUCount)] = true
+		if o.GPUCount == config.GPUCount || o.InstanceType == config.GPU {
+			candidates = append(candidates, o)
+		}
+	}
+	if len(candidates) == 0 {
+		errs.add("gpu_count", "%s offers %s in counts of %s, not %d", config.Provider, config.GPU, listOptions(mapKeys(counts)), config.GPUCount)
+		return
+	}
+
+	var inRegion []catalogOffer
+	elsewhere := map[string]bool{}
+	for _, o := range candidates {
+		if o.availableIn(config.Region) {
+			inRegion = append(inRegion, o)
+		}
+		for _, r := range o.Regions {
+			elsewhere[r] = true
+		}
+	}
+	if len(inRegion) == 0 {
+		if !regionKnown {
+			return
+		}
+		errs.add("region", "%s:%d is not available in %s on %s; available in: %s",
+			config.GPU, config.GPUCount, config.Region, config.Provider, listOptions(mapKeys(elsewhere)))
+		return
+	}
+
+	if config.UseSpot {
+		spot := false
+		for _, o := range inRegion {
+			spot = spot || o.SupportsSpot
+		}
+		if !spot {
+			errs.add("use_spot", "%s:%d has no spot capacity on %s; launch on-demand instead", config.GPU, config.GPUCount, config.Provider)
+		}
+	}
+
+	if weightsGB > 0 {
+		var maxMemory float64
+		for _, o := range inRegion {
+			maxMemory = math.Max(maxMemory, o.GPUMemoryGB)
+		}
+		if maxMemory > 0 && maxMemory < weightsGB {
+			errs.add("gpu", "%s:%d has %.0f GB of GPU memory but %s needs about %.0f GB; use a larger GPU or a higher gpu_count",
+				config.GPU, config.GPUCount, maxMemory, describeModels(config), weightsGB)
+		}
+	}
+}
+
+// minVCPUs returns the fewest vCPUs of an instance type that can serve the
+// launch in its region, or 0 when the catalog doesn't know one
+func (c *launchCatalog) minVCPUs(config *NodeConfig) int {
+	want := normalizeGPU(config.GPU)
+	least := 0
+	for _, o := range c.Offers {
+		matches := o.InstanceType == config.GPU ||
+			(normalizeGPU(o.GPUModel) == want && o.GPUCount == config.GPUCount)
+		if !matches || !o.availableIn(config.Region) || o.VCPUs <= 0 {
+			continue
+		}
+		if least == 0 || o.VCPUs < least {
+			least = o.VCPUs
+		}
+	}
+	return least
+}
+
+// validateAgainstCatalog checks the launch against the instance_types and
+// regions catalog, and returns the catalog. Providers with no catalog
+// entries are not checked.
+func (o *SkyPilotOrchestrator) validateAgainstCatalog(ctx context.Context, config *NodeConfig) (*launchCatalog, error) {
+	catalog, err := o.loadLaunchCatalog(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load launch catalog: %w", err)
+	}
+	if catalog.empty() {
+		o.logStore.LogInfo(ctx, config.NodeID, PhaseQueued,
+			fmt.Sprintf("No catalog entries for %s; skipping instance type validation", config.Provider), 0)
+	}
+
+	var errs ConfigError
+	catalog.check(config, &errs)
+	return catalog, errs.err()
+}
+
+// loadLaunchCatalog reads the provider's available instance types and
+// regions, and the sizes of the models the launch serves
+func (o *SkyPilotOrchestrator) loadLaunchCatalog(ctx context.Context, config *NodeConfig) (*launchCatalog, error) {
+	catalog := &launchCatalog{}
+	provider := config.Provider
+
+	rows, err := o.db.Pool.Query(ctx, `
+		SELECT it.instance_type, it.gpu_model, it.gpu_count, it.gpu_memory_gb::float8,
+		       it.vcpu_count, COALESCE(it.supports_spot, true),
+		       COALESCE(array_agg(ria.region_code) FILTER (
+		           WHERE ria.is_available AND COALESCE(ria.stock_status, 'available') != 'out_of_stock'
+		       ), '{}'),
+		       COUNT(ria.id) > 0
+		FROM instance_types it
+		LEFT JOIN region_instance_availability ria ON ria.instance_type_id = it.id
+		WHERE it.provider = $1 AND COALESCE(it.is_available, true) AND it.missing_since IS NULL
+		GROUP BY it.id
+	`, provider)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var offer catalogOffer
+		if err := rows.Scan(&offer.InstanceType, &offer.GPUModel, &offer.GPUCount, &offer.GPUMemoryGB,
+			&offer.VCPUs, &offer.SupportsSpot, &offer.Regions, &offer.HasAvailability); err != nil {
+			return nil, err
+		}
+		catalog.Offers = append(catalog.Offers, offer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = o.db.Pool.Query(ctx, `
+		SELECT code FROM regions
+		WHERE status != 'offline' AND (provider = $1 OR cloud_providers ? $1)
+		UNION
+		SELECT ria.region_code
+		FROM region_instance_availability ria
+		JOIN instance_types it ON it.id = ria.instance_type_id
+		WHERE it.provider = $1
+	`, provider)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		catalog.Regions = append(catalog.Regions, code)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	model, err := o.loadCatalogModel(ctx, config.Model)
+	if err != nil {
+		return nil, err
+	}
+	catalog.ModelGB, catalog.MinVLLMVersion, catalog.ContextLength = model.GB, model.MinVLLMVersion, model.ContextLength
+
+	for _, m := range config.ColocatedModels {
+		model, err := o.loadCatalogModel(ctx, m.Model)
+		if err != nil {
+			return nil, err
+		}
+		catalog.Colocated = append(catalog.Colocated, model)
+	}
+
+	return catalog, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func mapKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// listOptions formats alternatives for an error message
+func listOptions(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	sorted := append([]string(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool {
+		// Numeric options (GPU counts) sort by value
+		if len(sorted[i]) != len(sorted[j]) && isDigits(sorted[i]) && isDigits(sorted[j]) {
+			return len(sorted[i]) < len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	if len(sorted) > maxListedOptions {
+		return strings.Join(sorted[:maxListedOptions], ", ") + fmt.Sprintf(" and %d more", len(sorted)-maxListedOptions)
+	}
+	return strings.Join(sorted, ", ")
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
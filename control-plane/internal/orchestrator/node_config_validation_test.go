@@ -0,0 +1,151 @@
+package orchestrator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/crosslogic/control-plane/internal/config"
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// fieldMessages indexes a ConfigError's messages by field
+func fieldMessages(t *testing.T, err error) map[string]string {
+	t.Helper()
+	if err == nil {
+		return map[string]string{}
+	}
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("error = %v, want *ConfigError", err)
+	}
+	fields := map[string]string{}
+	for _, f := range cfgErr.Fields {
+		fields[f.Field] = f.Message
+	}
+	return fields
+}
+
+func TestValidateNodeConfigFieldErrors(t *testing.T) {
+	logger := zap.NewNop()
+	db := &database.Database{Pool: &pgxpool.Pool{}}
+	orch, _ := NewSkyPilotOrchestrator(db, &cache.Cache{}, logger, "https://api.test.com", testVLLMVersion, testTorchVersion, events.NewBus(logger), config.R2Config{}, config.SkyPilotConfig{})
+
+	// All problems are reported together
+	cfg := NodeConfig{Provider: "aws", GPU: "A100", GPUCount: 4, TensorParallel: 3, DiskSize: 20}
+	fields := fieldMessages(t, orch.validateNodeConfig(&cfg))
+	for _, field := range []string{"region", "model", "tensor_parallel", "disk_size"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("missing %s error in %v", field, fields)
+		}
+	}
+	if msg := fields["tensor_parallel"]; !strings.Contains(msg, "1, 2, 4") {
+		t.Errorf("tensor_parallel message = %q, want the valid sizes listed", msg)
+	}
+
+	tests := []struct {
+		name           string
+		gpuCount, tp   int
+		wantTPError    bool
+		wantTensorSize int
+	}{
+		{"defaults to gpu count", 4, 0, false, 4},
+		{"divides gpu count", 8, 2, false, 2},
+		{"larger than gpu count", 2, 4, true, 4},
+		{"does not divide gpu count", 8, 3, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NodeConfig{Provider: "aws", Region: "us-east-1", GPU: "A100", Model: "m", GPUCount: tt.gpuCount, TensorParallel: tt.tp}
+			fields := fieldMessages(t, orch.validateNodeConfig(&cfg))
+			if _, got := fields["tensor_parallel"]; got != tt.wantTPError {
+				t.Errorf("tensor_parallel error = %v, want %v (%v)", got, tt.wantTPError, fields)
+			}
+			if cfg.TensorParallel != tt.wantTensorSize {
+				t.Errorf("TensorParallel = %d, want %d", cfg.TensorParallel, tt.wantTensorSize)
+			}
+		})
+	}
+}
+
+func TestLaunchCatalogCheck(t *testing.T) {
+	catalog := &launchCatalog{
+		Regions: []string{"us-east-1", "us-west-2", "eu-west-1"},
+		Offers: []catalogOffer{
+			{InstanceType: "g5.xlarge", GPUModel: "NVIDIA A10G", GPUCount: 1, GPUMemoryGB: 24, SupportsSpot: true,
+				Regions: []string{"us-east-1", "us-west-2"}, HasAvailability: true},
+			{InstanceType: "p4d.24xlarge", GPUModel: "NVIDIA A100", GPUCount: 8, GPUMemoryGB: 320, SupportsSpot: false,
+				Regions: []string{"us-east-1"}, HasAvailability: true},
+			{InstanceType: "p4de.24xlarge", GPUModel: "NVIDIA A100 80GB", GPUCount: 8, GPUMemoryGB: 640, SupportsSpot: true},
+		},
+		ModelGB: 16,
+	}
+
+	valid := NodeConfig{Provider: "aws", Region: "us-east-1", GPU: "A10G", GPUCount: 1, Model: "llama-3-8b", DiskSize: 256, UseSpot: true}
+
+	tests := []struct {
+		name      string
+		modify    func(c *NodeConfig)
+		modelGB   float64
+		wantField string
+		wantText  string
+	}{
+		{"valid", func(c *NodeConfig) {}, 0, "", ""},
+		{"gpu name is normalized", func(c *NodeConfig) { c.GPU = "A100-80GB"; c.GPUCount = 8; c.Region = "eu-west-1" }, 0, "", ""},
+		{"instance type as gpu", func(c *NodeConfig) { c.GPU = "g5.xlarge" }, 0, "", ""},
+		{"unknown region", func(c *NodeConfig) { c.Region = "mars-1" }, 0, "region", "known regions: eu-west-1, us-east-1, us-west-2"},
+		{"unknown gpu", func(c *NodeConfig) { c.GPU = "H100" }, 0, "gpu", "available GPUs: A100, A100 80GB, A10G"},
+		{"unsupported gpu count", func(c *NodeConfig) { c.GPU = "A100"; c.GPUCount = 4; c.UseSpot = false }, 0, "gpu_count", "counts of 8, not 4"},
+		{"not in stock in region", func(c *NodeConfig) { c.Region = "eu-west-1" }, 0, "region", "available in: us-east-1, us-west-2"},
+		{"no spot capacity", func(c *NodeConfig) { c.GPU = "A100"; c.GPUCount = 8 }, 0, "use_spot", "launch on-demand"},
+		{"disk too small for model", func(c *NodeConfig) { c.DiskSize = 100 }, 80, "disk_size", "use at least 130"},
+		{"model does not fit gpu", func(c *NodeConfig) {}, 80, "gpu", "has 24 GB of GPU memory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := *catalog
+			if tt.modelGB > 0 {
+				c.ModelGB = tt.modelGB
+			}
+			cfg := valid
+			tt.modify(&cfg)
+
+			var errs ConfigError
+			c.check(&cfg, &errs)
+			fields := fieldMessages(t, errs.err())
+
+			if tt.wantField == "" {
+				if len(fields) != 0 {
+					t.Errorf("unexpected errors: %v", fields)
+				}
+				return
+			}
+			msg, ok := fields[tt.wantField]
+			if !ok {
+				t.Fatalf("missing %s error in %v", tt.wantField, fields)
+			}
+			if !strings.Contains(msg, tt.wantText) {
+				t.Errorf("%s message = %q, want it to contain %q", tt.wantField, msg, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestLaunchCatalogCheckEmpty(t *testing.T) {
+	catalog := &launchCatalog{}
+	if !catalog.empty() {
+		t.Fatal("catalog without offers or regions should be empty")
+	}
+
+	cfg := NodeConfig{Provider: "lambda", Region: "us-tx-1", GPU: "H100", GPUCount: 1, Model: "m", DiskSize: 256}
+	var errs ConfigError
+	catalog.check(&cfg, &errs)
+	if err := errs.err(); err != nil {
+		t.Errorf("empty catalog should not reject launches: %v", err)
+	}
+}
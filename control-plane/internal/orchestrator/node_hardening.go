@@ -0,0 +1,55 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Hardened nodes are set up with key-only SSH, a firewall that only lets
+// the control plane and mesh networks reach the inference port, and
+// unattended security updates. Their agents report whether that is still
+// the case with every heartbeat.
+
+// SetHardening hardens the nodes launched from now on, letting the given
+// networks reach their inference port. A nil list turns hardening off.
+func (o *SkyPilotOrchestrator) SetHardening(allowedCIDRs []string) error {
+	if allowedCIDRs == nil {
+		o.hardeningCIDRs = nil
+		return nil
+	}
+	cidrs, err := normalizeHardeningCIDRs(allowedCIDRs)
+	if err != nil {
+		return err
+	}
+	o.hardeningCIDRs = cidrs
+	return nil
+}
+
+// normalizeHardeningCIDRs validates the networks allowed through a hardened
+// node's firewall, rewriting each to its network address. A bare IP is
+// allowed as a single-host network.
+func normalizeHardeningCIDRs(cidrs []string) ([]string, error) {
+	if len(cidrs) == 0 {
+		return nil, errors.New("hardening needs at least one network allowed to reach the inference port")
+	}
+
+	normalized := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if ip := net.ParseIP(cidr); ip != nil {
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			cidr = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hardening network %q: %w", cidr, err)
+		}
+		normalized = append(normalized, network.String())
+	}
+	return normalized, nil
+}
@@ -0,0 +1,39 @@
+package orchestrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeHardeningCIDRs(t *testing.T) {
+	got, err := normalizeHardeningCIDRs([]string{"10.0.0.0/8", " 203.0.113.7 ", "100.64.1.9/10", "2001:db8::1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "203.0.113.7/32", "100.64.0.0/10", "2001:db8::1/128"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeHardeningCIDRs = %v, want %v", got, want)
+	}
+
+	if _, err := normalizeHardeningCIDRs(nil); err == nil {
+		t.Error("expected an error without networks")
+	}
+	if _, err := normalizeHardeningCIDRs([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected an error for an invalid network")
+	}
+}
+
+func TestHardeningTaskData(t *testing.T) {
+	o := &SkyPilotOrchestrator{}
+	if data := o.taskData(NodeConfig{}, "cic-test"); data["Hardening"] != false {
+		t.Errorf("Hardening = %v, want false by default", data["Hardening"])
+	}
+
+	if err := o.SetHardening([]string{"10.0.0.0/8", "192.168.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	data := o.taskData(NodeConfig{}, "cic-test")
+	if data["Hardening"] != true || data["HardeningCIDRs"] != "10.0.0.0/8 192.168.1.1/32" {
+		t.Errorf("task data = %v / %v", data["Hardening"], data["HardeningCIDRs"])
+	}
+}
@@ -0,0 +1,360 @@
+package orchestrator
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// NodeLogHotRetention is how long launch logs stay in Redis after their
+// last line
+const NodeLogHotRetention = 24 * time.Hour
+
+const (
+	nodeLogArchivePrefix      = "node-logs"
+	nodeLogArchiveContentType = "application/gzip"
+	// nodeLogPurgeBatch bounds the expired archives deleted per run
+	nodeLogPurgeBatch = 500
+)
+
+// LogObjectStore stores archived logs; r2.Objects implements it
+type LogObjectStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NodeLogRetention is the retention policy for node launch logs
+type NodeLogRetention struct {
+	// ArchiveAfter is how long a log must be idle before it is archived.
+	// It must be shorter than NodeLogHotRetention.
+	ArchiveAfter time.Duration
+	// Retention is how long archives are kept after their last line
+	Retention time.Duration
+	// FailedRetention is Retention for launches that ended in failure
+	FailedRetention time.Duration
+}
+
+// expiresAt is when an archive ending with last, in phase, is deleted
+func (r NodeLogRetention) expiresAt(last time.Time, phase NodeLogPhase) time.Time {
+	if phase == PhaseFailed && r.FailedRetention > 0 {
+		return last.Add(r.FailedRetention)
+	}
+	return last.Add(r.Retention)
+}
+
+// NodeLogArchive moves idle node launch logs from Redis to object storage,
+// deletes archives past their retention, and reads archived logs back for
+// NodeLogStore
+type NodeLogArchive struct {
+	db        *database.Database
+	cache     *cache.Cache
+	objects   LogObjectStore
+	logger    *zap.Logger
+	retention NodeLogRetention
+	interval  time.Duration
+}
+
+// NewNodeLogArchive creates an archive that runs every interval
+func NewNodeLogArchive(db *database.Database, cache *cache.Cache, objects LogObjectStore, logger *zap.Logger,
+	retention NodeLogRetention, interval time.Duration) *NodeLogArchive {
+	// Archive well before Redis drops the log
+	if retention.ArchiveAfter <= 0 || retention.ArchiveAfter > NodeLogHotRetention/2 {
+		retention.ArchiveAfter = NodeLogHotRetention / 2
+	}
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &NodeLogArchive{
+		db:        db,
+		cache:     cache,
+		objects:   objects,
+		logger:    logger,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Start archives and purges logs in the background
+func (a *NodeLogArchive) Start(ctx context.Context) {
+	a.logger.Info("starting node log archival",
+		zap.Duration("archive_after", a.retention.ArchiveAfter),
+		zap.Duration("retention", a.retention.Retention),
+		zap.Duration("failed_retention", a.retention.FailedRetention),
+	)
+	go func() {
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.RunOnce(ctx)
+			}
+		}
+	}()
+}
+
+// RunOnce archives idle logs and deletes expired archives
+func (a *NodeLogArchive) RunOnce(ctx context.Context) {
+	archived, err := a.archiveIdle(ctx)
+	if err != nil {
+		a.logger.Warn("node log archival failed", zap.Error(err))
+	}
+	purged, err := a.purgeExpired(ctx)
+	if err != nil {
+		a.logger.Warn("failed to purge expired node log archives", zap.Error(err))
+	}
+	if archived > 0 || purged > 0 {
+		a.logger.Info("node log archival run", zap.Int("archived", archived), zap.Int("purged", purged))
+	}
+}
+
+// nodeLogKeyPatterns match the Redis keys of platform and tenant node logs
+var nodeLogKeyPatterns = []string{
+	cache.PlatformKey(cache.NamespaceNodeLogs, "*"),
+	"tenant:*:node_logs:*",
+}
+
+// parseNodeLogKey returns the owner and node of a node log key built by
+// NodeLogKey
+func parseNodeLogKey(key string) (tenantID uuid.UUID, nodeID string, ok bool) {
+	parts := strings.Split(key, ":")
+	switch {
+	case len(parts) == 2 && parts[0] == cache.NamespaceNodeLogs:
+		return uuid.Nil, parts[1], parts[1] != ""
+	case len(parts) == 4 && parts[2] == "node_logs":
+		id, err := uuid.Parse(parts[1])
+		if err != nil || NodeLogKey(id, parts[3]) != key {
+			return uuid.Nil, "", false
+		}
+		return id, parts[3], parts[3] != ""
+	}
+	return uuid.Nil, "", false
+}
+
+// archiveIdle archives every node log idle for ArchiveAfter that has lines
+// its archive doesn't
+func (a *NodeLogArchive) archiveIdle(ctx context.Context) (int, error) {
+	archived := 0
+	cutoff := time.Now().Add(-a.retention.ArchiveAfter)
+	for _, pattern := range nodeLogKeyPatterns {
+		err := a.cache.ScanKeys(ctx, pattern, func(key string) error {
+			tenantID, nodeID, ok := parseNodeLogKey(key)
+			if !ok {
+				return nil
+			}
+			done, err := a.archiveIfIdle(ctx, key, tenantID, nodeID, cutoff)
+			if err != nil {
+				a.logger.Warn("failed to archive node logs", zap.String("node_id", nodeID), zap.Error(err))
+				return nil
+			}
+			if done {
+				archived++
+			}
+			return nil
+		})
+		if err != nil {
+			return archived, err
+		}
+	}
+	return archived, nil
+}
+
+func (a *NodeLogArchive) archiveIfIdle(ctx context.Context, key string, tenantID uuid.UUID, nodeID string, cutoff time.Time) (bool, error) {
+	id, err := uuid.Parse(nodeID)
+	if err != nil {
+		return false, nil
+	}
+
+	raw, err := a.cache.Range(ctx, key, 0, -1)
+	if err != nil || len(raw) == 0 {
+		return false, err
+	}
+
+	var archivedCount int
+	err = a.db.Pool.QueryRow(ctx, `SELECT entry_count FROM node_log_archives WHERE node_id = $1`, id).Scan(&archivedCount)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+	if archivedCount >= len(raw) {
+		return false, nil
+	}
+
+	entries := make([]NodeLogEntry, 0, len(raw))
+	for i, line := range raw {
+		var entry NodeLogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		entry.Seq = int64(i)
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return false, nil
+	}
+	last := entries[len(entries)-1]
+	if last.Timestamp.After(cutoff) {
+		return false, nil
+	}
+
+	data, err := encodeNodeLogArchive(entries)
+	if err != nil {
+		return false, err
+	}
+	objectKey := nodeLogArchiveKey(tenantID, nodeID)
+	if err := a.objects.Put(ctx, objectKey, data, nodeLogArchiveContentType); err != nil {
+		return false, fmt.Errorf("failed to upload archive: %w", err)
+	}
+
+	var owner *uuid.UUID
+	if tenantID != uuid.Nil {
+		owner = &tenantID
+	}
+	_, err = a.db.Pool.Exec(ctx, `
+		INSERT INTO node_log_archives (node_id, tenant_id, object_key, entry_count, size_bytes,
+			first_at, last_at, last_phase, archived_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), $9)
+		ON CONFLICT (node_id) DO UPDATE SET
+			object_key = EXCLUDED.object_key, entry_count = EXCLUDED.entry_count,
+			size_bytes = EXCLUDED.size_bytes, first_at = EXCLUDED.first_at, last_at = EXCLUDED.last_at,
+			last_phase = EXCLUDED.last_phase, archived_at = NOW(), expires_at = EXCLUDED.expires_at
+	`, id, owner, objectKey, len(raw), len(data), entries[0].Timestamp, last.Timestamp, string(last.Phase),
+		a.retention.expiresAt(last.Timestamp, last.Phase))
+	if err != nil {
+		return false, fmt.Errorf("failed to index archive: %w", err)
+	}
+	return true, nil
+}
+
+// purgeExpired deletes archives past their retention
+func (a *NodeLogArchive) purgeExpired(ctx context.Context) (int, error) {
+	rows, err := a.db.Pool.Query(ctx, `
+		SELECT node_id, object_key FROM node_log_archives
+		WHERE expires_at < NOW()
+		ORDER BY expires_at
+		LIMIT $1
+	`, nodeLogPurgeBatch)
+	if err != nil {
+		return 0, err
+	}
+	type expired struct {
+		nodeID    uuid.UUID
+		objectKey string
+	}
+	var due []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.nodeID, &e.objectKey); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, e := range due {
+		// The row goes only once the object is gone, so nothing is orphaned
+		if err := a.objects.Delete(ctx, e.objectKey); err != nil {
+			a.logger.Warn("failed to delete node log archive", zap.String("node_id", e.nodeID.String()), zap.Error(err))
+			continue
+		}
+		if _, err := a.db.Pool.Exec(ctx, `DELETE FROM node_log_archives WHERE node_id = $1`, e.nodeID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// Load returns a node's archived log, or nil if it has none
+func (a *NodeLogArchive) Load(ctx context.Context, nodeID string) ([]NodeLogEntry, error) {
+	id, err := uuid.Parse(nodeID)
+	if err != nil {
+		return nil, nil
+	}
+
+	var objectKey string
+	err = a.db.Pool.QueryRow(ctx, `
+		SELECT object_key FROM node_log_archives WHERE node_id = $1 AND expires_at > NOW()
+	`, id).Scan(&objectKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up log archive: %w", err)
+	}
+
+	data, err := a.objects.Get(ctx, objectKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download log archive: %w", err)
+	}
+	return decodeNodeLogArchive(data)
+}
+
+// nodeLogArchiveKey is where a node's archived log is stored
+func nodeLogArchiveKey(tenantID uuid.UUID, nodeID string) string {
+	owner := "platform"
+	if tenantID != uuid.Nil {
+		owner = tenantID.String()
+	}
+	return nodeLogArchivePrefix + "/" + owner + "/" + nodeID + ".jsonl.gz"
+}
+
+// encodeNodeLogArchive writes entries as gzipped JSON lines
+func encodeNodeLogArchive(entries []NodeLogEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeNodeLogArchive reads entries written by encodeNodeLogArchive
+func decodeNodeLogArchive(data []byte) ([]NodeLogEntry, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid log archive: %w", err)
+	}
+	defer zr.Close()
+
+	var entries []NodeLogEntry
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry NodeLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid log archive entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid log archive: %w", err)
+	}
+	return entries, nil
+}
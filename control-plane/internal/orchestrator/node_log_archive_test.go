@@ -0,0 +1,83 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNodeLogArchiveRoundTrip(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	entries := []NodeLogEntry{
+		{Timestamp: now, Level: LogLevelInfo, Phase: PhaseProvisioning, Message: "provisioning", Progress: 10, Seq: 0},
+		{Timestamp: now.Add(time.Minute), Level: LogLevelError, Phase: PhaseFailed, Message: "no capacity", Details: "line one\nline two", Seq: 1},
+	}
+
+	data, err := encodeNodeLogArchive(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeNodeLogArchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("decoded %d entries, want %d", len(got), len(entries))
+	}
+	for i := range entries {
+		if !got[i].Timestamp.Equal(entries[i].Timestamp) || got[i].Message != entries[i].Message ||
+			got[i].Details != entries[i].Details || got[i].Seq != entries[i].Seq || got[i].Phase != entries[i].Phase {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], entries[i])
+		}
+	}
+
+	if _, err := decodeNodeLogArchive([]byte("not gzip")); err == nil {
+		t.Error("expected an error for a corrupt archive")
+	}
+}
+
+func TestParseNodeLogKey(t *testing.T) {
+	nodeID := uuid.NewString()
+	tenantID := uuid.New()
+
+	tenant, node, ok := parseNodeLogKey(NodeLogKey(uuid.Nil, nodeID))
+	if !ok || tenant != uuid.Nil || node != nodeID {
+		t.Errorf("platform key parsed as %v %q %v", tenant, node, ok)
+	}
+	tenant, node, ok = parseNodeLogKey(NodeLogKey(tenantID, nodeID))
+	if !ok || tenant != tenantID || node != nodeID {
+		t.Errorf("tenant key parsed as %v %q %v", tenant, node, ok)
+	}
+	for _, key := range []string{"tenant:not-a-uuid:node_logs:" + nodeID, "tenant:" + tenantID.String() + ":usage:" + nodeID, "node_logs:", "other:" + nodeID} {
+		if _, _, ok := parseNodeLogKey(key); ok {
+			t.Errorf("expected %q not to parse", key)
+		}
+	}
+}
+
+func TestNodeLogRetention(t *testing.T) {
+	last := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	r := NodeLogRetention{Retention: 30 * 24 * time.Hour, FailedRetention: 90 * 24 * time.Hour}
+
+	if got := r.expiresAt(last, PhaseActive); !got.Equal(last.Add(30 * 24 * time.Hour)) {
+		t.Errorf("active launch expires at %v", got)
+	}
+	if got := r.expiresAt(last, PhaseFailed); !got.Equal(last.Add(90 * 24 * time.Hour)) {
+		t.Errorf("failed launch expires at %v", got)
+	}
+	r.FailedRetention = 0
+	if got := r.expiresAt(last, PhaseFailed); !got.Equal(last.Add(30 * 24 * time.Hour)) {
+		t.Errorf("failed launch without a failed retention expires at %v", got)
+	}
+}
+
+func TestNewNodeLogArchiveArchivesBeforeRedisExpiry(t *testing.T) {
+	a := NewNodeLogArchive(nil, nil, nil, nil, NodeLogRetention{ArchiveAfter: 48 * time.Hour}, 0)
+	if a.retention.ArchiveAfter >= NodeLogHotRetention {
+		t.Errorf("ArchiveAfter = %v, want less than %v", a.retention.ArchiveAfter, NodeLogHotRetention)
+	}
+	if a.interval <= 0 {
+		t.Error("expected a default interval")
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/crosslogic/control-plane/pkg/cache"
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -22,6 +25,8 @@ const (
 	PhaseHealthCheck   NodeLogPhase = "health_check"
 	PhaseActive        NodeLogPhase = "active"
 	PhaseFailed        NodeLogPhase = "failed"
+	// PhaseRetrying is a failed attempt that will be retried
+	PhaseRetrying NodeLogPhase = "retrying"
 )
 
 // NodeLogLevel represents log severity
@@ -42,6 +47,9 @@ type NodeLogEntry struct {
 	Phase     NodeLogPhase `json:"phase"`
 	Progress  int          `json:"progress,omitempty"` // 0-100
 	Details   string       `json:"details,omitempty"`  // Additional context
+	// Seq is the entry's position in the node's log. It is assigned on read
+	// and used as the SSE event ID so clients can resume a stream.
+	Seq int64 `json:"seq"`
 }
 
 // NodeStatusEvent represents a status update during node launch
@@ -65,22 +73,108 @@ type NodeDoneEvent struct {
 	Message  string `json:"message"`
 }
 
-// NodeLogStore manages node launch logs in Redis
+// NodeLogStore manages node launch logs in Redis. Logs of tenant-launched
+// nodes are kept in the tenant's key namespace, platform nodes' logs in the
+// node_logs namespace. With an archive set, logs Redis no longer holds are
+// read from the archive.
 type NodeLogStore struct {
-	cache  *cache.Cache
-	logger *zap.Logger
-	ttl    time.Duration // Log retention time
+	cache   *cache.Cache
+	db      *database.Database
+	logger  *zap.Logger
+	ttl     time.Duration // Log retention time
+	archive *NodeLogArchive
+
+	// tenants caches the owning tenant of each node, uuid.Nil for platform
+	// nodes
+	tenants sync.Map
 }
 
-// NewNodeLogStore creates a new log store
-func NewNodeLogStore(cache *cache.Cache, logger *zap.Logger) *NodeLogStore {
+// NewNodeLogStore creates a new log store. Node owners are looked up in db;
+// without one, nodes not bound with BindTenant are treated as platform nodes.
+func NewNodeLogStore(cache *cache.Cache, db *database.Database, logger *zap.Logger) *NodeLogStore {
 	return &NodeLogStore{
 		cache:  cache,
+		db:     db,
 		logger: logger,
-		ttl:    24 * time.Hour, // Retain logs for 24 hours
+		ttl:    NodeLogHotRetention,
 	}
 }
 
+// SetArchive makes the store read logs that have left Redis from archive
+func (s *NodeLogStore) SetArchive(archive *NodeLogArchive) {
+	s.archive = archive
+}
+
+// storedLogs returns a node's log from Redis, or from the archive once Redis
+// no longer has it
+func (s *NodeLogStore) storedLogs(ctx context.Context, nodeID string, start int64) ([]NodeLogEntry, error) {
+	if start < 0 {
+		start = 0
+	}
+	logs, err := s.cache.Range(ctx, s.logKey(ctx, nodeID), start, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve logs: %w", err)
+	}
+
+	if len(logs) == 0 && s.archive != nil {
+		if n, err := s.cache.Len(ctx, s.logKey(ctx, nodeID)); err == nil && n == 0 {
+			archived, err := s.archive.Load(ctx, nodeID)
+			if err != nil {
+				return nil, err
+			}
+			if start >= int64(len(archived)) {
+				return nil, nil
+			}
+			return archived[start:], nil
+		}
+	}
+
+	entries := make([]NodeLogEntry, 0, len(logs))
+	for i, logStr := range logs {
+		if entry, ok := s.decodeEntry(nodeID, logStr, start+int64(i)); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// BindTenant records the tenant owning a node, before it is registered, so
+// its launch logs go to the tenant's namespace from the first line. An empty
+// or invalid tenantID marks a platform node.
+func (s *NodeLogStore) BindTenant(nodeID, tenantID string) {
+	id, err := uuid.Parse(tenantID)
+	if err != nil {
+		id = uuid.Nil
+	}
+	s.tenants.Store(nodeID, id)
+}
+
+// nodeTenant returns the tenant owning a node, uuid.Nil for platform nodes
+// and nodes that aren't registered yet
+func (s *NodeLogStore) nodeTenant(ctx context.Context, nodeID string) uuid.UUID {
+	if id, ok := s.tenants.Load(nodeID); ok {
+		return id.(uuid.UUID)
+	}
+	if s.db == nil {
+		return uuid.Nil
+	}
+
+	var tenantID *uuid.UUID
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT tenant_id FROM nodes WHERE id::text = $1
+	`, nodeID).Scan(&tenantID)
+	if err != nil {
+		// Not registered yet: don't cache, the node may get an owner
+		return uuid.Nil
+	}
+	id := uuid.Nil
+	if tenantID != nil {
+		id = *tenantID
+	}
+	s.tenants.Store(nodeID, id)
+	return id
+}
+
 // AppendLog appends a log entry for a node
 func (s *NodeLogStore) AppendLog(ctx context.Context, nodeID string, entry NodeLogEntry) error {
 	if entry.Timestamp.IsZero() {
@@ -94,10 +188,10 @@ func (s *NodeLogStore) AppendLog(ctx context.Context, nodeID string, entry NodeL
 	}
 
 	// Redis key for this node's logs
-	key := s.logKey(nodeID)
+	key := s.logKey(ctx, nodeID)
 
 	// Append to list (RPUSH adds to tail)
-	if err := s.cache.Client.RPush(ctx, key, string(data)).Err(); err != nil {
+	if err := s.cache.Append(ctx, key, string(data)); err != nil {
 		return fmt.Errorf("failed to append log: %w", err)
 	}
 
@@ -121,25 +215,14 @@ func (s *NodeLogStore) AppendLog(ctx context.Context, nodeID string, entry NodeL
 
 // GetLogs retrieves logs for a node with optional filtering
 func (s *NodeLogStore) GetLogs(ctx context.Context, nodeID string, tail int, since *time.Time) ([]NodeLogEntry, error) {
-	key := s.logKey(nodeID)
-
-	// Get all logs from Redis list
-	logs, err := s.cache.Client.LRange(ctx, key, 0, -1).Result()
+	// Get all logs
+	logs, err := s.storedLogs(ctx, nodeID, 0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve logs: %w", err)
+		return nil, err
 	}
 
 	var entries []NodeLogEntry
-	for _, logStr := range logs {
-		var entry NodeLogEntry
-		if err := json.Unmarshal([]byte(logStr), &entry); err != nil {
-			s.logger.Warn("failed to unmarshal log entry",
-				zap.String("node_id", nodeID),
-				zap.Error(err),
-			)
-			continue
-		}
-
+	for _, entry := range logs {
 		// Filter by timestamp if provided
 		if since != nil && entry.Timestamp.Before(*since) {
 			continue
@@ -156,9 +239,54 @@ func (s *NodeLogStore) GetLogs(ctx context.Context, nodeID string, tail int, sin
 	return entries, nil
 }
 
+// GetLogsAfter retrieves the logs appended after the entry with sequence
+// number seq. Pass -1 to get every entry.
+func (s *NodeLogStore) GetLogsAfter(ctx context.Context, nodeID string, seq int64) ([]NodeLogEntry, error) {
+	return s.storedLogs(ctx, nodeID, seq+1)
+}
+
+// LastLog returns the most recent log entry for a node, or nil if it has none
+func (s *NodeLogStore) LastLog(ctx context.Context, nodeID string) (*NodeLogEntry, error) {
+	length, err := s.cache.Len(ctx, s.logKey(ctx, nodeID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve logs: %w", err)
+	}
+	if length == 0 {
+		if s.archive == nil {
+			return nil, nil
+		}
+		archived, err := s.archive.Load(ctx, nodeID)
+		if err != nil || len(archived) == 0 {
+			return nil, err
+		}
+		return &archived[len(archived)-1], nil
+	}
+
+	entries, err := s.GetLogsAfter(ctx, nodeID, length-2)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[len(entries)-1], nil
+}
+
+// decodeEntry unmarshals a stored log entry and stamps its sequence number
+func (s *NodeLogStore) decodeEntry(nodeID, logStr string, seq int64) (NodeLogEntry, bool) {
+	var entry NodeLogEntry
+	if err := json.Unmarshal([]byte(logStr), &entry); err != nil {
+		s.logger.Warn("failed to unmarshal log entry",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+		return entry, false
+	}
+	entry.Seq = seq
+	return entry, true
+}
+
 // StreamLogs streams logs for a node (blocking until context is canceled)
-// Returns a channel of log entries
-func (s *NodeLogStore) StreamLogs(ctx context.Context, nodeID string, tail int, since *time.Time) (<-chan NodeLogEntry, <-chan error) {
+// Returns a channel of log entries. When after is set the stream resumes
+// behind that sequence number and tail/since are ignored.
+func (s *NodeLogStore) StreamLogs(ctx context.Context, nodeID string, tail int, since *time.Time, after *int64) (<-chan NodeLogEntry, <-chan error) {
 	logChan := make(chan NodeLogEntry, 10)
 	errChan := make(chan error, 1)
 
@@ -167,7 +295,19 @@ func (s *NodeLogStore) StreamLogs(ctx context.Context, nodeID string, tail int,
 		defer close(errChan)
 
 		// First, send existing logs
-		existingLogs, err := s.GetLogs(ctx, nodeID, tail, since)
+		var existingLogs []NodeLogEntry
+		var err error
+		lastSeq := int64(-1)
+		if after != nil {
+			lastSeq = *after
+			existingLogs, err = s.GetLogsAfter(ctx, nodeID, lastSeq)
+		} else {
+			// Follow from the end of the log even when since filters everything
+			if length, lenErr := s.cache.Len(ctx, s.logKey(ctx, nodeID)); lenErr == nil {
+				lastSeq = length - 1
+			}
+			existingLogs, err = s.GetLogs(ctx, nodeID, tail, since)
+		}
 		if err != nil {
 			errChan <- err
 			return
@@ -179,24 +319,22 @@ func (s *NodeLogStore) StreamLogs(ctx context.Context, nodeID string, tail int,
 				return
 			case logChan <- entry:
 			}
+			if entry.Seq > lastSeq {
+				lastSeq = entry.Seq
+			}
 		}
 
 		// Then, poll for new logs every 500ms
 		ticker := time.NewTicker(500 * time.Millisecond)
 		defer ticker.Stop()
 
-		var lastTimestamp time.Time
-		if len(existingLogs) > 0 {
-			lastTimestamp = existingLogs[len(existingLogs)-1].Timestamp
-		}
-
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				// Get new logs since last timestamp
-				newLogs, err := s.GetLogs(ctx, nodeID, 0, &lastTimestamp)
+				// Get logs appended since the last one we sent
+				newLogs, err := s.GetLogsAfter(ctx, nodeID, lastSeq)
 				if err != nil {
 					s.logger.Error("failed to poll for new logs",
 						zap.String("node_id", nodeID),
@@ -206,16 +344,11 @@ func (s *NodeLogStore) StreamLogs(ctx context.Context, nodeID string, tail int,
 				}
 
 				for _, entry := range newLogs {
-					// Skip entries we've already sent
-					if !entry.Timestamp.After(lastTimestamp) {
-						continue
-					}
-
 					select {
 					case <-ctx.Done():
 						return
 					case logChan <- entry:
-						lastTimestamp = entry.Timestamp
+						lastSeq = entry.Seq
 					}
 				}
 			}
@@ -227,13 +360,22 @@ func (s *NodeLogStore) StreamLogs(ctx context.Context, nodeID string, tail int,
 
 // ClearLogs removes all logs for a node
 func (s *NodeLogStore) ClearLogs(ctx context.Context, nodeID string) error {
-	key := s.logKey(nodeID)
+	key := s.logKey(ctx, nodeID)
 	return s.cache.Delete(ctx, key)
 }
 
 // logKey generates the Redis key for a node's logs
-func (s *NodeLogStore) logKey(nodeID string) string {
-	return fmt.Sprintf("node_logs:%s", nodeID)
+func (s *NodeLogStore) logKey(ctx context.Context, nodeID string) string {
+	return NodeLogKey(s.nodeTenant(ctx, nodeID), nodeID)
+}
+
+// NodeLogKey is the Redis key for the logs of a node owned by tenantID, or
+// of a platform node when tenantID is uuid.Nil
+func NodeLogKey(tenantID uuid.UUID, nodeID string) string {
+	if tenantID == uuid.Nil {
+		return cache.PlatformKey(cache.NamespaceNodeLogs, nodeID)
+	}
+	return cache.TenantKey(tenantID, "node_logs", nodeID)
 }
 
 // Helper functions for common log operations
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/crosslogic/control-plane/internal/nodes"
 	"github.com/crosslogic/control-plane/pkg/database"
 	"go.uber.org/zap"
 )
@@ -357,8 +358,9 @@ func (r *StateReconciler) syncStatus(ctx context.Context, skyClusters map[string
 				healthy = false
 			}
 
-			// Update triple safety monitor with cloud API signal
-			if r.monitor != nil {
+			// Update triple safety monitor with cloud API signal. Stopped
+			// clusters are kept for restarts, not failed.
+			if r.monitor != nil && newStatus != "stopped" {
 				go r.updateMonitorSignal(ctx, name, healthy, fmt.Sprintf("cloud_status=%s", skyStatus))
 			}
 
@@ -401,8 +403,8 @@ func (r *StateReconciler) updateMonitorSignal(ctx context.Context, clusterName s
 }
 
 func (r *StateReconciler) updateDBStatus(ctx context.Context, clusterName, status, message string) {
-	query := `UPDATE nodes SET status = $1, status_message = COALESCE(NULLIF($2, ''), status_message), updated_at = NOW() WHERE cluster_name = $3`
-	_, err := r.db.Pool.Exec(ctx, query, status, message, clusterName)
+	query := `UPDATE nodes SET status = $1, status_message = COALESCE(NULLIF($2, ''), status_message), status_source = $4, updated_at = NOW() WHERE cluster_name = $3`
+	_, err := r.db.Pool.Exec(ctx, query, status, message, clusterName, nodes.SourceReconciler)
 	if err != nil {
 		r.logger.Error("failed to update db status", zap.Error(err))
 	}
@@ -0,0 +1,150 @@
+package orchestrator
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// runtimeVersionPattern accepts pip release versions with an optional post
+// release and local label ("0.6.3", "0.6.3.post1", "2.4.0+cu121"). Versions
+// are rendered into the task's install commands, so nothing else is allowed.
+var runtimeVersionPattern = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+){0,3}(\.post[0-9]+)?(\+[A-Za-z0-9.]+)?$`)
+
+// minVLLMVersions are the first vLLM releases supporting well-known model
+// architectures, by model name prefix. The models registry's
+// min_vllm_version wins when set.
+var minVLLMVersions = []struct {
+	prefix  string
+	version string
+}{
+	{"meta-llama/Llama-4", "0.8.3"},
+	{"meta-llama/Llama-3.2", "0.6.2"},
+	{"meta-llama/Llama-3.1", "0.5.3"},
+	{"meta-llama/Meta-Llama-3.1", "0.5.3"},
+	{"google/gemma-3", "0.8.0"},
+	{"google/gemma-2", "0.5.1"},
+	{"Qwen/Qwen3", "0.8.5"},
+	{"Qwen/Qwen2.5-VL", "0.7.2"},
+	{"deepseek-ai/DeepSeek-V3", "0.6.6"},
+	{"deepseek-ai/DeepSeek-R1", "0.7.1"},
+	{"mistralai/Mistral-Small-3.1", "0.8.0"},
+}
+
+// ResolveRuntimeVersions fills in the platform's vLLM and torch versions for
+// the ones a node config doesn't pin
+func (o *SkyPilotOrchestrator) ResolveRuntimeVersions(config *NodeConfig) {
+	if config.VLLMVersion == "" {
+		config.VLLMVersion = o.vllmVersion
+	}
+	if config.TorchVersion == "" {
+		config.TorchVersion = o.torchVersion
+	}
+}
+
+// resolveModelRuntime launches image models with the diffusion runtime
+// when the config doesn't pick a runtime
+func (o *SkyPilotOrchestrator) resolveModelRuntime(ctx context.Context, config *NodeConfig) {
+	if config.Runtime != "" || o.db == nil {
+		return
+	}
+	var modelType string
+	err := o.db.Pool.QueryRow(ctx, `SELECT type FROM models WHERE name = $1`, config.Model).Scan(&modelType)
+	if err == nil && modelType == "image" {
+		config.Runtime = DiffusionRuntime
+	}
+}
+
+// DefaultVLLMVersion is the vLLM version nodes get unless pinned
+func (o *SkyPilotOrchestrator) DefaultVLLMVersion() string {
+	return o.vllmVersion
+}
+
+// ValidRuntimeVersion reports whether v can be pinned as a vLLM or torch
+// version
+func ValidRuntimeVersion(v string) bool {
+	return runtimeVersionPattern.MatchString(v)
+}
+
+// checkRuntimeVersions validates pinned versions' format
+func checkRuntimeVersions(config *NodeConfig, errs *ConfigError) {
+	if config.VLLMVersion != "" && !ValidRuntimeVersion(config.VLLMVersion) {
+		errs.add("vllm_version", "%q is not a release version such as 0.6.3", config.VLLMVersion)
+	}
+	if config.TorchVersion != "" && !ValidRuntimeVersion(config.TorchVersion) {
+		errs.add("torch_version", "%q is not a release version such as 2.4.0", config.TorchVersion)
+	}
+}
+
+// MinVLLMVersion is the first vLLM release able to serve model: registered
+// is the models registry's requirement, else well-known architectures are
+// matched by name. It is empty when unknown.
+func MinVLLMVersion(model, registered string) string {
+	if registered != "" {
+		return registered
+	}
+	for _, m := range minVLLMVersions {
+		if strings.HasPrefix(strings.ToLower(model), strings.ToLower(m.prefix)) {
+			return m.version
+		}
+	}
+	return ""
+}
+
+// CheckVLLMCompatibility returns a ConfigError when vLLM version is older
+// than the model requires
+func CheckVLLMCompatibility(model, version, minVersion string) error {
+	var errs ConfigError
+	checkVLLMCompatibility(model, version, minVersion, &errs)
+	return errs.err()
+}
+
+func checkVLLMCompatibility(model, version, minVersion string, errs *ConfigError) {
+	if version == "" || minVersion == "" || CompareVersions(version, minVersion) >= 0 {
+		return
+	}
+	errs.add("vllm_version", "%s requires vLLM %s or newer, not %s", model, minVersion, version)
+}
+
+// CompareVersions compares dotted release versions numerically, ignoring a
+// leading "v" and local labels. Post releases sort after their release.
+func CompareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts splits "0.6.3.post1+cu121" into [0 6 3 0 1]: the release
+// padded to four parts, then the post release
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	post := 0
+	if i := strings.Index(v, ".post"); i >= 0 {
+		post, _ = strconv.Atoi(v[i+len(".post"):])
+		v = v[:i]
+	}
+
+	parts := make([]int, 4, 5)
+	for i, s := range strings.SplitN(v, ".", 4) {
+		parts[i], _ = strconv.Atoi(s)
+	}
+	return append(parts, post)
+}
@@ -0,0 +1,95 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.6.2", "0.6.2", 0},
+		{"0.6.10", "0.6.9", 1},
+		{"0.6", "0.6.0", 0},
+		{"v0.7.0", "0.6.6", 1},
+		{"0.6.3+cu121", "0.6.3", 0},
+		{"0.6.3.post1", "0.6.3", 1},
+		{"0.6.3.post1", "0.6.4", -1},
+		{"1.0.0", "0.10.0", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestValidRuntimeVersion(t *testing.T) {
+	for _, v := range []string{"0.6.2", "v0.8.5", "0.6.3.post1", "2.4.0+cu121", "1"} {
+		if !ValidRuntimeVersion(v) {
+			t.Errorf("ValidRuntimeVersion(%q) = false", v)
+		}
+	}
+	for _, v := range []string{"", "latest", "0.6.2; rm -rf /", "0.6.2 --pre", ">=0.6"} {
+		if ValidRuntimeVersion(v) {
+			t.Errorf("ValidRuntimeVersion(%q) = true", v)
+		}
+	}
+}
+
+func TestVLLMCompatibility(t *testing.T) {
+	if got := MinVLLMVersion("meta-llama/Llama-3.1-8B-Instruct", ""); got != "0.5.3" {
+		t.Errorf("known architecture minimum = %q", got)
+	}
+	if got := MinVLLMVersion("meta-llama/Llama-3.1-8B-Instruct", "0.6.0"); got != "0.6.0" {
+		t.Errorf("registry minimum not preferred: %q", got)
+	}
+	if got := MinVLLMVersion("acme/custom-model", ""); got != "" {
+		t.Errorf("unknown model minimum = %q", got)
+	}
+
+	err := CheckVLLMCompatibility("Qwen/Qwen3-8B", "0.6.2", MinVLLMVersion("Qwen/Qwen3-8B", ""))
+	fields := fieldMessages(t, err)
+	if !strings.Contains(fields["vllm_version"], "requires vLLM 0.8.5") {
+		t.Errorf("old vLLM for Qwen3 = %v", err)
+	}
+	if err := CheckVLLMCompatibility("Qwen/Qwen3-8B", "0.8.5", "0.8.5"); err != nil {
+		t.Errorf("minimum version refused: %v", err)
+	}
+	if err := CheckVLLMCompatibility("acme/custom-model", "0.6.2", ""); err != nil {
+		t.Errorf("unknown requirement refused: %v", err)
+	}
+}
+
+func TestRuntimeVersionPinning(t *testing.T) {
+	o := &SkyPilotOrchestrator{vllmVersion: "0.6.2", torchVersion: "2.4.0"}
+
+	config := NodeConfig{Model: "Qwen/Qwen3-8B", VLLMVersion: "0.8.5"}
+	o.ResolveRuntimeVersions(&config)
+	if config.VLLMVersion != "0.8.5" || config.TorchVersion != "2.4.0" {
+		t.Errorf("resolved = %s/%s, want pinned vLLM and default torch", config.VLLMVersion, config.TorchVersion)
+	}
+	if spec := o.runtimeSpec(config); spec.VLLMVersion != "0.8.5" {
+		t.Errorf("runtimeSpec() vLLM = %q, want the pin", spec.VLLMVersion)
+	}
+	if data := o.taskData(NodeConfig{Model: "m"}, "c"); data["VLLMVersion"] != "0.6.2" {
+		t.Errorf("task data vLLM = %v, want the default", data["VLLMVersion"])
+	}
+
+	// The catalog check refuses a pin too old for the model
+	var errs ConfigError
+	catalog := &launchCatalog{MinVLLMVersion: "0.8.5"}
+	catalog.check(&NodeConfig{Model: "Qwen/Qwen3-8B", Runtime: DefaultRuntime, VLLMVersion: "0.7.3", DiskSize: 256}, &errs)
+	if _, ok := fieldMessages(t, errs.err())["vllm_version"]; !ok {
+		t.Errorf("catalog check = %v, want a vllm_version error", errs.err())
+	}
+
+	// Other runtimes bring their own engine
+	errs = ConfigError{}
+	catalog.check(&NodeConfig{Model: "Qwen/Qwen3-8B", Runtime: "sglang", VLLMVersion: "0.7.3", DiskSize: 256}, &errs)
+	if errs.err() != nil {
+		t.Errorf("sglang catalog check = %v", errs.err())
+	}
+}
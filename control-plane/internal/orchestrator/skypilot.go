@@ -5,16 +5,19 @@ import (
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
-	"text/template"
 	"time"
 
 	"github.com/crosslogic/control-plane/internal/config"
+	"github.com/crosslogic/control-plane/internal/nodes"
 	"github.com/crosslogic/control-plane/internal/skypilot"
 	"github.com/crosslogic/control-plane/pkg/cache"
 	"github.com/crosslogic/control-plane/pkg/database"
@@ -59,8 +62,8 @@ import (
 // - Node agent uses secure HTTPS communication with control plane
 // - API keys and secrets passed via environment variables
 type SkyPilotOrchestrator struct {
-	// taskTemplate is the parsed Go template for SkyPilot task YAML generation
-	taskTemplate *template.Template
+	// templates resolves the SkyPilot task template for each launch
+	templates *TemplateRegistry
 
 	// db provides access to PostgreSQL for node registry updates
 	db *database.Database
@@ -71,9 +74,20 @@ type SkyPilotOrchestrator struct {
 	// eventBus for publishing node events
 	eventBus *events.Bus
 
+	// registry writes node rows shared with the gateway and scheduler
+	registry *nodes.Registry
+
+	// launches records cold start checkpoints for launch metrics
+	launches *nodes.LaunchTracker
+
 	// controlPlaneURL is the HTTPS endpoint for node agent registration
 	controlPlaneURL string
 
+	// nodeAPIURL and nodeAPIToken point node agents at the internal node
+	// listener when it is enabled (see SetNodeAPI)
+	nodeAPIURL   string
+	nodeAPIToken string
+
 	// runtime versions for dependency pinning
 	vllmVersion  string
 	torchVersion string
@@ -81,17 +95,42 @@ type SkyPilotOrchestrator struct {
 	// r2Config holds Cloudflare R2 configuration
 	r2Config config.R2Config
 
+	// modelStore finds model weights in R2 for launch preflight checks
+	modelStore ModelStore
+
+	// hardeningCIDRs may reach the inference port of hardened nodes; nil
+	// when nodes are not hardened (see SetHardening)
+	hardeningCIDRs []string
+
 	// API client for SkyPilot API Server mode
 	apiClient *skypilot.Client
 
 	// useAPIServer determines whether to use API Server (true) or CLI (false)
 	useAPIServer bool
 
+	// tenantWorkspaces isolates each tenant's clusters in its own SkyPilot workspace (API mode)
+	tenantWorkspaces bool
+	workspacePrefix  string
+	defaultWorkspace string
+
 	// credentialEncryptionKey for decrypting cloud credentials from database
 	credentialEncryptionKey []byte
 
+	// credentialProfiles registers tenant credentials with the API server as
+	// named profiles and references them by ID in launches (API mode)
+	credentialProfiles bool
+
 	// logStore for storing node launch logs in Redis
 	logStore *NodeLogStore
+
+	// launchQueue bounds concurrent launches per provider and region
+	launchQueue *LaunchQueue
+
+	// Remote command execution: default and maximum timeouts, and the
+	// commands admins may run
+	execDefaultTimeout time.Duration
+	execMaxTimeout     time.Duration
+	execAllowed        []string
 }
 
 // NodeConfig defines the configuration for launching a new GPU node.
@@ -103,7 +142,7 @@ type NodeConfig struct {
 	// NodeID is the unique identifier for this node (UUID)
 	NodeID string `json:"node_id"`
 
-	// Provider is the cloud provider (aws, gcp, azure, lambda, oci)
+	// Provider is the cloud provider (aws, gcp, azure, lambda, runpod, oci, nebius)
 	Provider string `json:"provider"`
 
 	// Region is the cloud region for deployment (e.g., us-west-2, us-central1)
@@ -118,6 +157,15 @@ type NodeConfig struct {
 	// Model is the LLM model to serve (e.g., meta-llama/Llama-2-7b-chat-hf)
 	Model string `json:"model"`
 
+	// Runtime selects the inference runtime's task template
+	// Default: vllm
+	Runtime string `json:"runtime,omitempty"`
+
+	// VLLMVersion and TorchVersion pin the runtime's vLLM and torch
+	// Default: the platform's VLLM_VERSION and TORCH_VERSION
+	VLLMVersion  string `json:"vllm_version,omitempty"`
+	TorchVersion string `json:"torch_version,omitempty"`
+
 	// UseSpot enables spot instance provisioning for cost savings
 	// Default: true (80% cost reduction vs on-demand)
 	UseSpot bool `json:"use_spot"`
@@ -136,6 +184,10 @@ type NodeConfig struct {
 	// DeploymentID links this node to a deployment (optional)
 	DeploymentID string `json:"deployment_id,omitempty"`
 
+	// Standby launches the node as its deployment's warm standby, serving
+	// but kept out of routing until promoted
+	Standby bool `json:"standby,omitempty"`
+
 	// TenantID identifies which tenant owns this node (required for API mode)
 	TenantID string `json:"tenant_id,omitempty"`
 
@@ -149,12 +201,32 @@ type NodeConfig struct {
 	StreamerMemoryLimit int64 `json:"streamer_memory_limit"`
 
 	// GPUMemoryUtilization is the fraction of GPU memory to use (0.0-1.0)
-	// Default: 0.95 (Run:ai Streamer is more efficient than standard loading)
+	// Default: tuned to the model size and GPU memory (see nodes.TuneVLLM)
 	GPUMemoryUtilization float64 `json:"gpu_memory_utilization"`
 
+	// MaxModelLen and MaxNumSeqs are vLLM's --max-model-len and
+	// --max-num-seqs. Default: tuned like GPUMemoryUtilization
+	MaxModelLen int `json:"max_model_len,omitempty"`
+	MaxNumSeqs  int `json:"max_num_seqs,omitempty"`
+
+	// ColocatedModels are small models the node serves beside Model, each
+	// from its own vLLM instance on its own port (vllm runtime only)
+	ColocatedModels []ColocatedModel `json:"colocated_models,omitempty"`
+
+	// tunedVRAMGB is the per-GPU memory the vLLM settings were tuned for
+	tunedVRAMGB int
+
 	// UseRunaiStreamer enables Run:ai Model Streamer for 5-10x faster loading
 	// Default: true (reduces load time from 30-60s to 4-23s)
 	UseRunaiStreamer bool `json:"use_runai_streamer"`
+
+	// RequestedAt is when the launch was requested, for queued launches that
+	// start later. Default: the time LaunchNode is called
+	RequestedAt time.Time `json:"requested_at,omitempty"`
+
+	// ClusterName relaunches an existing stopped cluster instead of
+	// provisioning a new one. Default: a name generated from the config
+	ClusterName string `json:"-"`
 }
 
 // GenerateClusterName generates a unique cluster name based on the naming convention.
@@ -183,7 +255,9 @@ func GenerateClusterName(config NodeConfig) string {
 	)
 }
 
-// SkyPilotTaskTemplate is the Go template for generating SkyPilot task YAML.
+// SkyPilotTaskTemplate is the built-in Go template for generating SkyPilot
+// task YAML with the vLLM runtime. It is used for every provider unless a
+// template file or an admin override replaces it (see TemplateRegistry).
 //
 // Template variables:
 // - .NodeID: Unique node identifier
@@ -194,169 +268,20 @@ func GenerateClusterName(config NodeConfig) string {
 // - .UseSpot: Enable spot instances
 // - .DiskSize: Disk size in GB
 // - .VLLMArgs: Additional vLLM arguments
+// - .GPUMemoryUtilization, .MaxModelLen, .MaxNumSeqs: vLLM memory settings
+//   tuned to the model and GPU memory
+// - .ColocatedModels: Models served beside .Model, each with .Model, .Port
+//   and its own vLLM memory settings
 // - .ControlPlaneURL: Control plane HTTPS endpoint
+// - .NodeAPIURL, .NodeAPIToken: Where the node agent calls back and its token
 //
 // The generated YAML defines:
 // 1. Resource requirements (GPU, cloud, region, disk)
 // 2. Setup commands (install dependencies, download node agent)
 // 3. Run commands (start vLLM, wait for health, start node agent)
-const SkyPilotTaskTemplate = `# SkyPilot Task: CrossLogic Inference Node
-# Generated: {{.Timestamp}}
-# Node ID: {{.NodeID}}
-
-name: {{.ClusterName}}
-
-resources:
-  accelerators: {{.GPU}}:{{.GPUCount}}
-  {{if .Provider}}cloud: {{.Provider}}{{end}}
-  {{if .Region}}region: {{.Region}}{{end}}
-  {{if .UseSpot}}use_spot: true{{else}}use_spot: false{{end}}
-  disk_size: {{.DiskSize}}
-  disk_tier: best
-
-# Setup: Install dependencies and configure environment
-setup: |
-  set -e  # Exit on error
-
-  echo "=== Configuring Cloudflare R2 for Model Storage ==="
-  export AWS_ACCESS_KEY_ID="{{.R2AccessKey}}"
-  export AWS_SECRET_ACCESS_KEY="{{.R2SecretKey}}"
-  export AWS_ENDPOINT_URL="{{.R2Endpoint}}"
-  export HF_HUB_ENABLE_HF_TRANSFER=1
-
-  # Create HuggingFace cache directory
-  mkdir -p ~/.cache/huggingface
-
-  if [ -n "$AWS_ACCESS_KEY_ID" ] && [ -n "$AWS_ENDPOINT_URL" ]; then
-    echo "✓ R2 credentials configured"
-    echo "  Endpoint: $AWS_ENDPOINT_URL"
-    echo "  Bucket: {{.R2Bucket}}"
-    echo "  Models will be streamed directly from R2"
-    echo "  Cache directory: ~/.cache/huggingface"
-  else
-    echo "⚠️  R2 not configured - models will be downloaded from HuggingFace"
-  fi
-
-  echo "=== Installing Python and vLLM ==="
-  # Install Python 3.10 if not present
-  if ! command -v python3.10 &> /dev/null; then
-    sudo add-apt-repository -y ppa:deadsnakes/ppa
-    sudo apt-get update
-    sudo apt-get install -y python3.10 python3.10-venv python3-pip
-  fi
-
-  # Create virtual environment
-  python3.10 -m venv /opt/vllm-env
-  source /opt/vllm-env/bin/activate
-
-  # Install vLLM with CUDA 12.1 support and Run:ai Model Streamer
-  pip install --upgrade pip setuptools wheel
-  pip install vllm[runai]=={{.VLLMVersion}} torch=={{.TorchVersion}}
-
-  echo "=== Downloading CrossLogic Node Agent ==="
-  # Download node agent binary
-  wget -q https://{{.ControlPlaneURL}}/downloads/node-agent-linux-amd64 \
-    -O /usr/local/bin/node-agent || \
-    echo "Warning: Failed to download node agent, using fallback"
-  chmod +x /usr/local/bin/node-agent
-
-  echo "=== Setup Complete ==="
-
-# Run: Start vLLM and node agent
-run: |
-  set -e
-  source /opt/vllm-env/bin/activate
-
-  echo "=== Starting vLLM Server ==="
-  # Set up model path - vLLM will handle S3:// URLs natively
-  MODEL_NAME="{{.Model}}"
-
-  # Check if model is in R2
-  if [ -n "$AWS_ENDPOINT_URL" ] && [ -n "{{.R2Bucket}}" ]; then
-    # Use S3 URL for model stored in R2
-    # vLLM natively supports s3:// URLs via HuggingFace Hub
-    R2_MODEL_PATH="s3://{{.R2Bucket}}/$MODEL_NAME"
-
-    echo "✓ Checking if model exists in R2..."
-    # Quick check (optional - vLLM will fail gracefully if not found)
-    if aws s3 ls "$R2_MODEL_PATH/" --endpoint-url "$AWS_ENDPOINT_URL" &> /dev/null; then
-      echo "✓ Model found in R2: $R2_MODEL_PATH"
-      echo "  vLLM will stream directly from Cloudflare R2"
-      echo "  First load: ~30-60s (CDN fetch + cache)"
-      echo "  Subsequent loads: ~5-10s (local HF cache)"
-      MODEL_PATH="$R2_MODEL_PATH"
-    else
-      echo "⚠️  Model not found in R2: $R2_MODEL_PATH"
-      echo "  Falling back to HuggingFace download"
-      echo "  To upload: python scripts/upload-model-to-r2.py $MODEL_NAME"
-      MODEL_PATH="$MODEL_NAME"
-    fi
-  else
-    echo "⚠️  R2 not configured - using HuggingFace download"
-    MODEL_PATH="$MODEL_NAME"
-  fi
-
-  echo "Starting vLLM with Run:ai Model Streamer (ultra-fast loading)"
-  nohup python -m vllm.entrypoints.openai.api_server \
-    --model "$MODEL_PATH" \
-    --load-format runai_streamer \
-    --model-loader-extra-config '{"concurrency": {{.StreamerConcurrency}}, "memory_limit": {{.StreamerMemoryLimit}}}' \
-    --host 0.0.0.0 \
-    --port 8000 \
-    --gpu-memory-utilization {{.GPUMemoryUtilization}} \
-    --max-num-seqs 256 \
-    --max-model-len 32768 \
-    --tensor-parallel-size {{.TensorParallel}} \
-    --dtype bfloat16 \
-    --enable-prefix-caching \
-    --enable-chunked-prefill \
-    --disable-log-requests \
-    --disable-log-stats \
-{{- if .VLLMArgs }}
-    {{.VLLMArgs}} \
-{{- end}}
-    > /tmp/vllm.log 2>&1 &
-
-  VLLM_PID=$!
-  echo "vLLM started with PID: $VLLM_PID"
-
-  echo "=== Waiting for vLLM to be ready ==="
-  # Wait up to 10 minutes for vLLM to load model and start serving
-  for i in {1..600}; do
-    if curl -sf http://localhost:8000/health > /dev/null 2>&1; then
-      echo "✓ vLLM is ready after ${i} seconds"
-      break
-    fi
-
-    # Check if vLLM process crashed
-    if ! kill -0 $VLLM_PID 2>/dev/null; then
-      echo "✗ vLLM process crashed, check /tmp/vllm.log"
-      tail -50 /tmp/vllm.log
-      exit 1
-    fi
-
-    if [ $i -eq 600 ]; then
-      echo "✗ vLLM failed to start after 10 minutes"
-      tail -50 /tmp/vllm.log
-      exit 1
-    fi
-
-    sleep 1
-  done
-
-  echo "=== Starting CrossLogic Node Agent ==="
-  # Set environment variables for node agent
-  export CONTROL_PLANE_URL={{.ControlPlaneURL}}
-  export NODE_ID={{.NodeID}}
-  export MODEL_NAME={{.Model}}
-  export REGION={{.Region}}
-  export PROVIDER={{.Provider}}
-  export VLLM_ENDPOINT=http://localhost:8000
-  export LOG_LEVEL=info
-
-  # Start node agent (blocks until interrupted)
-  /usr/local/bin/node-agent
-`
+//
+//go:embed templates/vllm.yaml.tmpl
+var SkyPilotTaskTemplate string
 
 // NewSkyPilotOrchestrator creates a new SkyPilot orchestrator.
 //
@@ -395,23 +320,35 @@ func NewSkyPilotOrchestrator(
 	r2Config config.R2Config,
 	skyPilotConfig config.SkyPilotConfig,
 ) (*SkyPilotOrchestrator, error) {
-	// Parse template
-	tmpl, err := template.New("skypilot").Parse(SkyPilotTaskTemplate)
+	// Load task templates
+	templates, err := NewTemplateRegistry(db, logger, skyPilotConfig.TemplateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	launchLimits, err := ParseLaunchLimits(skyPilotConfig.LaunchConcurrencyLimits)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse SkyPilot task template: %w", err)
+		return nil, err
 	}
 
 	orchestrator := &SkyPilotOrchestrator{
-		taskTemplate:    tmpl,
+		templates:       templates,
 		db:              db,
 		logger:          logger,
 		eventBus:        eventBus,
+		registry:        nodes.NewRegistry(db),
+		launches:        nodes.NewLaunchTracker(db),
 		controlPlaneURL: controlPlaneURL,
 		vllmVersion:     vllmVersion,
 		torchVersion:    torchVersion,
 		r2Config:        r2Config,
 		useAPIServer:    skyPilotConfig.UseAPIServer,
-		logStore:        NewNodeLogStore(cache, logger),
+		logStore:        NewNodeLogStore(cache, db, logger),
+		launchQueue:     NewLaunchQueue(skyPilotConfig.LaunchConcurrency, launchLimits),
+
+		execDefaultTimeout: skyPilotConfig.ExecTimeout,
+		execMaxTimeout:     skyPilotConfig.ExecMaxTimeout,
+		execAllowed:        skyPilotConfig.ExecAllowedCommands,
 	}
 
 	// Initialize API client if API Server mode is enabled
@@ -429,6 +366,14 @@ func NewSkyPilotOrchestrator(
 		// Store encryption key for credential decryption
 		orchestrator.credentialEncryptionKey = []byte(skyPilotConfig.CredentialEncryptionKey)
 
+		// Per-tenant workspace isolation
+		orchestrator.tenantWorkspaces = skyPilotConfig.TenantWorkspaces
+		orchestrator.workspacePrefix = skyPilotConfig.WorkspacePrefix
+		orchestrator.defaultWorkspace = skyPilotConfig.DefaultWorkspace
+
+		// Reference credentials by profile instead of sending them inline
+		orchestrator.credentialProfiles = skyPilotConfig.CredentialProfiles
+
 		// Initialize API client
 		clientConfig := skypilot.Config{
 			BaseURL:       skyPilotConfig.APIServerURL,
@@ -444,6 +389,7 @@ func NewSkyPilotOrchestrator(
 		logger.Info("SkyPilot orchestrator initialized in API Server mode",
 			zap.String("api_server_url", skyPilotConfig.APIServerURL),
 			zap.Duration("launch_timeout", skyPilotConfig.LaunchTimeout),
+			zap.Bool("tenant_workspaces", skyPilotConfig.TenantWorkspaces),
 		)
 	} else {
 		logger.Info("SkyPilot orchestrator initialized in CLI mode")
@@ -452,14 +398,30 @@ func NewSkyPilotOrchestrator(
 	return orchestrator, nil
 }
 
+// SetNodeAPI points node agents at the internal node listener: launched
+// nodes call back to url with token instead of the public control plane URL
+func (o *SkyPilotOrchestrator) SetNodeAPI(url, token string) {
+	o.nodeAPIURL = url
+	o.nodeAPIToken = token
+}
+
+// nodeAgentURL is the URL node agents register and heartbeat with
+func (o *SkyPilotOrchestrator) nodeAgentURL() string {
+	if o.nodeAPIURL != "" {
+		return o.nodeAPIURL
+	}
+	return o.controlPlaneURL
+}
+
 // LaunchNode provisions a new GPU node using SkyPilot.
 //
 // Process:
 // 1. Validate configuration and set defaults
 // 2. Generate SkyPilot task YAML from template
-// 3. Route to API or CLI based on useAPIServer flag
-// 4. Register node in database
-// 5. Return cluster name for tracking
+// 3. Wait for a launch slot on the provider and region (see LaunchQueue)
+// 4. Route to API or CLI based on useAPIServer flag
+// 5. Register node in database
+// 6. Return cluster name for tracking
 //
 // API Mode:
 // - Retrieves tenant cloud credentials from database
@@ -490,15 +452,55 @@ func NewSkyPilotOrchestrator(
 // - string: Cluster name (format: "cic-{provider}-{region}-{gpu}-{spot|od}-{id}")
 // - error: Validation error, credential error, template error, or SkyPilot launch failure
 func (o *SkyPilotOrchestrator) LaunchNode(ctx context.Context, config NodeConfig) (string, error) {
+	return o.launchNode(ctx, config, false)
+}
+
+// launchNode launches a node. When the caller will retry transient failures,
+// they are logged in the retrying phase so log streams keep following the
+// node.
+func (o *SkyPilotOrchestrator) launchNode(ctx context.Context, config NodeConfig, willRetry bool) (string, error) {
 	startTime := time.Now()
+	o.logStore.BindTenant(config.NodeID, config.TenantID)
+	// Defaults and tuning are filled in on the launch's own copy
+	config.ColocatedModels = append([]ColocatedModel(nil), config.ColocatedModels...)
 
-	// Validate and set defaults
+	// Validate and set defaults, then check the combination against the catalog
+	o.resolveModelRuntime(ctx, &config)
 	if err := o.validateNodeConfig(&config); err != nil {
 		o.logStore.LogError(ctx, config.NodeID, PhaseQueued, "Invalid configuration", err.Error())
-		return "", fmt.Errorf("invalid node configuration: %w", err)
+		return "", err
+	}
+	catalog, err := o.validateAgainstCatalog(ctx, &config)
+	if err != nil {
+		o.logStore.LogError(ctx, config.NodeID, PhaseQueued, "Invalid configuration", err.Error())
+		return "", err
+	}
+	o.tuneVLLM(ctx, &config, catalog)
+
+	taskTemplate, err := o.templates.Resolve(ctx, config.Provider, config.Runtime)
+	if err != nil {
+		if errors.Is(err, ErrTaskTemplateNotFound) {
+			err = &ConfigError{Fields: []FieldError{{Field: "runtime", Message: err.Error()}}}
+		}
+		o.logStore.LogError(ctx, config.NodeID, PhaseQueued, "Invalid configuration", err.Error())
+		return "", err
+	}
+	if err := checkColocatedTemplate(&config, taskTemplate); err != nil {
+		o.logStore.LogError(ctx, config.NodeID, PhaseQueued, "Invalid configuration", err.Error())
+		return "", err
 	}
 
 	clusterName := GenerateClusterName(config)
+	if config.ClusterName != "" {
+		// Launching a stopped cluster restarts its instances with the model
+		// and setup already on disk
+		clusterName = config.ClusterName
+		o.logStore.LogInfo(ctx, config.NodeID, PhaseQueued,
+			fmt.Sprintf("Restarting stopped cluster: %s", clusterName), 0)
+	}
+
+	// Start the launch's cold start timings
+	o.recordLaunchRequested(ctx, config, clusterName, startTime)
 
 	// Log initial queued status
 	o.logStore.LogInfo(ctx, config.NodeID, PhaseQueued,
@@ -506,6 +508,10 @@ func (o *SkyPilotOrchestrator) LaunchNode(ctx context.Context, config NodeConfig
 	o.logStore.LogInfo(ctx, config.NodeID, PhaseQueued,
 		fmt.Sprintf("Provider: %s, Region: %s, GPU: %s:%d, Model: %s",
 			config.Provider, config.Region, config.GPU, config.GPUCount, config.Model), 5)
+	for _, m := range config.ColocatedModels {
+		o.logStore.LogInfo(ctx, config.NodeID, PhaseQueued,
+			fmt.Sprintf("Colocated model: %s on port %d", m.Model, m.Port), 5)
+	}
 
 	o.logger.Info("launching GPU node with SkyPilot",
 		zap.String("node_id", config.NodeID),
@@ -517,26 +523,39 @@ func (o *SkyPilotOrchestrator) LaunchNode(ctx context.Context, config NodeConfig
 		zap.String("model", config.Model),
 		zap.Bool("use_spot", config.UseSpot),
 		zap.Bool("use_api_server", o.useAPIServer),
+		zap.String("task_template", taskTemplate.Ref),
 	)
 
+	// Wait for a launch slot on the provider and region
+	release, err := o.waitForLaunchSlot(ctx, config)
+	if err != nil {
+		o.logStore.LogError(ctx, config.NodeID, launchFailurePhase(err, willRetry),
+			"Node launch failed", err.Error())
+		o.recordLaunchOutcome(ctx, config.NodeID, err)
+		return "", err
+	}
+	defer release()
+
 	// Log provisioning phase
 	o.logStore.LogInfo(ctx, config.NodeID, PhaseProvisioning,
 		"Starting cloud resource provisioning...", 10)
 
 	// Route to API or CLI based on configuration
-	var err error
 	if o.useAPIServer {
-		err = o.launchNodeViaAPI(ctx, config, clusterName)
+		err = o.launchNodeViaAPI(ctx, config, clusterName, taskTemplate)
 	} else {
-		err = o.launchNodeViaCLI(ctx, config, clusterName)
+		err = o.launchNodeViaCLI(ctx, config, clusterName, taskTemplate)
 	}
 
 	if err != nil {
-		o.logStore.LogError(ctx, config.NodeID, PhaseFailed,
+		o.logStore.LogError(ctx, config.NodeID, launchFailurePhase(err, willRetry),
 			"Node launch failed", err.Error())
+		o.recordLaunchOutcome(ctx, config.NodeID, err)
 		return "", err
 	}
 
+	o.recordLaunchOutcome(ctx, config.NodeID, nil)
+
 	launchDuration := time.Since(startTime)
 
 	o.logger.Info("GPU node launched successfully",
@@ -572,7 +591,7 @@ func (o *SkyPilotOrchestrator) LaunchNode(ctx context.Context, config NodeConfig
 	}
 
 	// Register node in database
-	if err := o.registerNode(ctx, config, clusterName); err != nil {
+	if err := o.registerNode(ctx, config, clusterName, taskTemplate.Ref); err != nil {
 		// Node launched but registration failed - log warning but don't fail
 		o.logger.Warn("node launched but database registration failed",
 			zap.Error(err),
@@ -584,7 +603,7 @@ func (o *SkyPilotOrchestrator) LaunchNode(ctx context.Context, config NodeConfig
 }
 
 // launchNodeViaAPI launches a node using the SkyPilot API Server.
-func (o *SkyPilotOrchestrator) launchNodeViaAPI(ctx context.Context, config NodeConfig, clusterName string) error {
+func (o *SkyPilotOrchestrator) launchNodeViaAPI(ctx context.Context, config NodeConfig, clusterName string, taskTemplate *TaskTemplate) error {
 	// Get tenant cloud credentials from database
 	o.logStore.LogInfo(ctx, config.NodeID, PhaseProvisioning,
 		"Retrieving cloud credentials...", 15)
@@ -594,11 +613,28 @@ func (o *SkyPilotOrchestrator) launchNodeViaAPI(ctx context.Context, config Node
 		return fmt.Errorf("failed to get tenant credentials: %w", err)
 	}
 
+	// Sovereign partitions only reach their own regions
+	if err := checkSovereignRegion(cloudCreds, config.Region); err != nil {
+		return err
+	}
+	envs := sovereignEnvs(cloudCreds, config.Region)
+
+	// With credential profiles the launch references a profile registered
+	// with the API server instead of carrying the raw secrets
+	var profileID string
+	if o.credentialProfiles {
+		cloudCreds = nil
+		profileID, err = o.ensureCredentialProfile(ctx, config.TenantID, config.Provider)
+		if err != nil {
+			return fmt.Errorf("failed to get tenant credential profile: %w", err)
+		}
+	}
+
 	// Generate task YAML
 	o.logStore.LogInfo(ctx, config.NodeID, PhaseProvisioning,
 		"Generating SkyPilot task configuration...", 20)
 
-	taskYAML, err := o.generateTaskYAML(config, clusterName)
+	taskYAML, err := taskTemplate.Render(o.taskData(config, clusterName))
 	if err != nil {
 		return fmt.Errorf("failed to generate task YAML: %w", err)
 	}
@@ -608,17 +644,23 @@ func (o *SkyPilotOrchestrator) launchNodeViaAPI(ctx context.Context, config Node
 		fmt.Sprintf("Submitting launch request to SkyPilot API (cluster: %s)...", clusterName), 25)
 
 	launchReq := skypilot.LaunchRequest{
-		ClusterName:      clusterName,
-		TaskYAML:         taskYAML,
-		RetryUntilUp:     true,
-		Detach:           true,
-		CloudCredentials: cloudCreds,
-		Envs: map[string]string{
-			"NODE_ID":          config.NodeID,
-			"CONTROL_PLANE_URL": o.controlPlaneURL,
-		},
+		ClusterName:         clusterName,
+		TaskYAML:            taskYAML,
+		RetryUntilUp:        true,
+		Detach:              true,
+		CloudCredentials:    cloudCreds,
+		CredentialProfileID: profileID,
+		Envs:                envs,
+	}
+	launchReq.Envs["NODE_ID"] = config.NodeID
+	launchReq.Envs["CONTROL_PLANE_URL"] = o.nodeAgentURL()
+	if o.nodeAPIToken != "" {
+		launchReq.Envs["NODE_API_TOKEN"] = o.nodeAPIToken
 	}
 
+	// Scope the launch (and subsequent polling) to the tenant's workspace
+	ctx = o.workspaceContext(ctx, config.TenantID)
+
 	// Call API
 	launchResp, err := o.apiClient.Launch(ctx, launchReq)
 	if err != nil {
@@ -669,12 +711,12 @@ func (o *SkyPilotOrchestrator) launchNodeViaAPI(ctx context.Context, config Node
 }
 
 // launchNodeViaCLI launches a node using the SkyPilot CLI (legacy mode).
-func (o *SkyPilotOrchestrator) launchNodeViaCLI(ctx context.Context, config NodeConfig, clusterName string) error {
+func (o *SkyPilotOrchestrator) launchNodeViaCLI(ctx context.Context, config NodeConfig, clusterName string, taskTemplate *TaskTemplate) error {
 	// Generate task YAML
 	o.logStore.LogInfo(ctx, config.NodeID, PhaseProvisioning,
 		"Generating SkyPilot task configuration...", 15)
 
-	taskYAML, err := o.generateTaskYAML(config, clusterName)
+	taskYAML, err := taskTemplate.Render(o.taskData(config, clusterName))
 	if err != nil {
 		return fmt.Errorf("failed to generate task YAML: %w", err)
 	}
@@ -795,6 +837,8 @@ func (o *SkyPilotOrchestrator) TerminateNode(ctx context.Context, clusterName st
 
 // terminateNodeViaAPI terminates a node using the SkyPilot API Server.
 func (o *SkyPilotOrchestrator) terminateNodeViaAPI(ctx context.Context, clusterName string) error {
+	ctx = o.clusterWorkspaceContext(ctx, clusterName)
+
 	// Call API to terminate cluster
 	terminateResp, err := o.apiClient.Terminate(ctx, clusterName, true)
 	if err != nil {
@@ -861,6 +905,86 @@ func (o *SkyPilotOrchestrator) terminateNodeViaCLI(ctx context.Context, clusterN
 	return nil
 }
 
+// StopNode stops a GPU node's instances without terminating them. The disks
+// are kept (and billed), so launching the same cluster name again restarts
+// the node with the model and setup already in place. Spot clusters can't be
+// stopped.
+func (o *SkyPilotOrchestrator) StopNode(ctx context.Context, clusterName string) error {
+	o.logger.Info("stopping GPU node",
+		zap.String("cluster_name", clusterName),
+		zap.Bool("use_api_server", o.useAPIServer),
+	)
+
+	var err error
+	if o.useAPIServer {
+		err = o.stopNodeViaAPI(ctx, clusterName)
+	} else {
+		err = o.stopNodeViaCLI(ctx, clusterName)
+	}
+	if err != nil {
+		return err
+	}
+
+	o.logger.Info("GPU node stopped", zap.String("cluster_name", clusterName))
+
+	_, err = o.db.Pool.Exec(ctx, `
+		UPDATE nodes
+		SET status = 'stopped', stopped_at = NOW(), status_source = $2, updated_at = NOW()
+		WHERE cluster_name = $1
+	`, clusterName, nodes.SourceOrchestrator)
+	if err != nil {
+		o.logger.Warn("failed to update node status in database",
+			zap.Error(err),
+			zap.String("cluster_name", clusterName),
+		)
+	}
+
+	return nil
+}
+
+// stopNodeViaAPI stops a node using the SkyPilot API Server.
+func (o *SkyPilotOrchestrator) stopNodeViaAPI(ctx context.Context, clusterName string) error {
+	ctx = o.clusterWorkspaceContext(ctx, clusterName)
+
+	stopResp, err := o.apiClient.Stop(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("API stop failed: %w", err)
+	}
+
+	requestStatus, err := o.apiClient.WaitForRequest(ctx, stopResp.RequestID, 3*time.Second)
+	if err != nil {
+		return fmt.Errorf("stop request failed: %w", err)
+	}
+
+	if requestStatus.Status != "completed" {
+		return fmt.Errorf("stop request ended with status: %s, error: %s",
+			requestStatus.Status, requestStatus.Error)
+	}
+
+	return nil
+}
+
+// stopNodeViaCLI stops a node using the SkyPilot CLI (legacy mode).
+func (o *SkyPilotOrchestrator) stopNodeViaCLI(ctx context.Context, clusterName string) error {
+	cmd := exec.CommandContext(ctx, "sky", "stop", clusterName, "-y")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		o.logger.Error("SkyPilot CLI stop failed",
+			zap.Error(err),
+			zap.String("stdout", stdout.String()),
+			zap.String("stderr", stderr.String()),
+		)
+		return fmt.Errorf("sky stop failed: %w\nStdout: %s\nStderr: %s",
+			err, stdout.String(), stderr.String())
+	}
+
+	return nil
+}
+
 // GetNodeStatus retrieves the current status of a GPU node from SkyPilot.
 //
 // Status values:
@@ -883,6 +1007,8 @@ func (o *SkyPilotOrchestrator) GetNodeStatus(ctx context.Context, clusterName st
 
 // getNodeStatusViaAPI retrieves node status using the SkyPilot API Server.
 func (o *SkyPilotOrchestrator) getNodeStatusViaAPI(ctx context.Context, clusterName string) (string, error) {
+	ctx = o.clusterWorkspaceContext(ctx, clusterName)
+
 	status, err := o.apiClient.GetStatus(ctx, clusterName)
 	if err != nil {
 		// Check if cluster not found
@@ -948,16 +1074,25 @@ func (o *SkyPilotOrchestrator) GetAllClusters(ctx context.Context) ([]string, er
 
 // getAllClustersViaAPI retrieves all clusters using the SkyPilot API Server.
 func (o *SkyPilotOrchestrator) getAllClustersViaAPI(ctx context.Context) ([]string, error) {
-	listResp, err := o.apiClient.ListClusters(ctx)
+	workspaces, err := o.activeWorkspaces(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("API list clusters failed: %w", err)
+		return nil, fmt.Errorf("failed to list tenant workspaces: %w", err)
 	}
 
-	// Filter for CIC nodes
 	var nodeNames []string
-	for _, cluster := range listResp.Clusters {
-		if len(cluster.Name) > 4 && cluster.Name[:4] == "cic-" {
-			nodeNames = append(nodeNames, cluster.Name)
+	seen := make(map[string]bool)
+	for _, workspace := range workspaces {
+		listResp, err := o.apiClient.ListClusters(skypilot.WithWorkspace(ctx, workspace))
+		if err != nil {
+			return nil, fmt.Errorf("API list clusters failed (workspace %q): %w", workspace, err)
+		}
+
+		// Filter for CIC nodes
+		for _, cluster := range listResp.Clusters {
+			if len(cluster.Name) > 4 && cluster.Name[:4] == "cic-" && !seen[cluster.Name] {
+				seen[cluster.Name] = true
+				nodeNames = append(nodeNames, cluster.Name)
+			}
 		}
 	}
 
@@ -996,67 +1131,6 @@ func (o *SkyPilotOrchestrator) ListNodes(ctx context.Context) ([]string, error)
 	return o.GetAllClusters(ctx)
 }
 
-// ExecCommand executes a command on a running node.
-//
-// Routes to API or CLI based on useAPIServer flag.
-//
-// Returns:
-// - string: Command output (stdout + stderr)
-// - error: Execution failure
-func (o *SkyPilotOrchestrator) ExecCommand(ctx context.Context, clusterName, command string) (string, error) {
-	o.logger.Debug("executing command on node",
-		zap.String("cluster_name", clusterName),
-		zap.String("command", command),
-		zap.Bool("use_api_server", o.useAPIServer),
-	)
-
-	if o.useAPIServer {
-		return o.execCommandViaAPI(ctx, clusterName, command)
-	}
-	return o.execCommandViaCLI(ctx, clusterName, command)
-}
-
-// execCommandViaAPI executes a command using the SkyPilot API Server.
-func (o *SkyPilotOrchestrator) execCommandViaAPI(ctx context.Context, clusterName, command string) (string, error) {
-	execReq := skypilot.ExecuteRequest{
-		ClusterName: clusterName,
-		Command:     command,
-		Timeout:     300, // 5 minutes
-	}
-
-	execResp, err := o.apiClient.Execute(ctx, execReq)
-	if err != nil {
-		return "", fmt.Errorf("API execute failed: %w", err)
-	}
-
-	// Combine stdout and stderr
-	output := execResp.Stdout
-	if execResp.Stderr != "" {
-		output += "\n" + execResp.Stderr
-	}
-
-	if execResp.ExitCode != 0 {
-		return output, fmt.Errorf("command exited with code %d", execResp.ExitCode)
-	}
-
-	return output, nil
-}
-
-// execCommandViaCLI executes a command using the SkyPilot CLI (legacy mode).
-func (o *SkyPilotOrchestrator) execCommandViaCLI(ctx context.Context, clusterName, command string) (string, error) {
-	cmd := exec.CommandContext(ctx, "sky", "exec",
-		clusterName,
-		command,
-	)
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("sky exec failed: %w\nOutput: %s", err, output)
-	}
-
-	return string(output), nil
-}
-
 // getTenantCredentials retrieves and decrypts cloud credentials for a tenant from the database.
 func (o *SkyPilotOrchestrator) getTenantCredentials(ctx context.Context, tenantID, provider string) (*skypilot.CloudCredentials, error) {
 	if tenantID == "" {
@@ -1094,7 +1168,22 @@ func (o *SkyPilotOrchestrator) getTenantCredentials(ctx context.Context, tenantI
 		return nil, fmt.Errorf("failed to decrypt credentials: %w", err)
 	}
 
-	// Parse decrypted JSON based on provider
+	cloudCreds, err := parseCloudCredentials(provider, decryptedJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	o.logger.Debug("retrieved tenant credentials",
+		zap.String("tenant_id", tenantID),
+		zap.String("provider", provider),
+		zap.String("key_id", keyID),
+	)
+
+	return cloudCreds, nil
+}
+
+// parseCloudCredentials parses decrypted credential JSON based on provider.
+func parseCloudCredentials(provider string, decryptedJSON []byte) (*skypilot.CloudCredentials, error) {
 	cloudCreds := &skypilot.CloudCredentials{}
 
 	switch provider {
@@ -1119,15 +1208,41 @@ func (o *SkyPilotOrchestrator) getTenantCredentials(ctx context.Context, tenantI
 		}
 		cloudCreds.GCP = &gcpCreds
 
+	case "lambda":
+		var lambdaCreds skypilot.LambdaCredentials
+		if err := json.Unmarshal(decryptedJSON, &lambdaCreds); err != nil {
+			return nil, fmt.Errorf("failed to parse Lambda credentials: %w", err)
+		}
+		cloudCreds.Lambda = &lambdaCreds
+
+	case "runpod":
+		var runpodCreds skypilot.RunPodCredentials
+		if err := json.Unmarshal(decryptedJSON, &runpodCreds); err != nil {
+			return nil, fmt.Errorf("failed to parse RunPod credentials: %w", err)
+		}
+		cloudCreds.RunPod = &runpodCreds
+
+	case "oci":
+		var ociCreds skypilot.OCICredentials
+		if err := json.Unmarshal(decryptedJSON, &ociCreds); err != nil {
+			return nil, fmt.Errorf("failed to parse OCI credentials: %w", err)
+		}
+		cloudCreds.OCI = &ociCreds
+
+	case "nebius":
+		var nebiusCreds skypilot.NebiusCredentials
+		if err := json.Unmarshal(decryptedJSON, &nebiusCreds); err != nil {
+			return nil, fmt.Errorf("failed to parse Nebius credentials: %w", err)
+		}
+		cloudCreds.Nebius = &nebiusCreds
+
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
 
-	o.logger.Debug("retrieved tenant credentials",
-		zap.String("tenant_id", tenantID),
-		zap.String("provider", provider),
-		zap.String("key_id", keyID),
-	)
+	if err := normalizeSovereign(cloudCreds); err != nil {
+		return nil, fmt.Errorf("invalid %s credentials: %w", provider, err)
+	}
 
 	return cloudCreds, nil
 }
@@ -1172,31 +1287,34 @@ func (o *SkyPilotOrchestrator) decryptCredentials(encryptedData []byte) ([]byte,
 }
 
 // validateNodeConfig validates and sets defaults for node configuration.
+// Every problem is reported as a field error in one ConfigError.
 func (o *SkyPilotOrchestrator) validateNodeConfig(config *NodeConfig) error {
+	var errs ConfigError
+
 	// Validate required fields
 	if config.NodeID == "" {
 		config.NodeID = uuid.New().String()
 	}
 
 	if config.Provider == "" {
-		return fmt.Errorf("provider is required")
+		errs.add("provider", "is required")
 	}
 
 	if config.Region == "" {
-		return fmt.Errorf("region is required")
+		errs.add("region", "is required")
 	}
 
 	if config.GPU == "" {
-		return fmt.Errorf("GPU type is required")
+		errs.add("gpu", "is required")
 	}
 
 	if config.Model == "" {
-		return fmt.Errorf("model is required")
+		errs.add("model", "is required")
 	}
 
 	// Validate tenant ID in API mode
 	if o.useAPIServer && config.TenantID == "" {
-		return fmt.Errorf("tenant ID is required when using API Server mode")
+		errs.add("tenant_id", "is required when using API Server mode")
 	}
 
 	// Set defaults
@@ -1212,6 +1330,25 @@ func (o *SkyPilotOrchestrator) validateNodeConfig(config *NodeConfig) error {
 		config.TensorParallel = config.GPUCount
 	}
 
+	if config.Runtime == "" {
+		config.Runtime = DefaultRuntime
+	} else if !templateNamePattern.MatchString(config.Runtime) {
+		errs.add("runtime", "must be lowercase letters, digits and dashes")
+	}
+
+	o.ResolveRuntimeVersions(config)
+	checkRuntimeVersions(config, &errs)
+
+	if config.DiskSize < minDiskSizeGB {
+		errs.add("disk_size", "must be at least %d GB", minDiskSizeGB)
+	}
+
+	if config.GPUCount < 0 {
+		errs.add("gpu_count", "must be positive")
+	} else {
+		checkTensorParallel(config, &errs)
+	}
+
 	// Set Run:ai Streamer defaults for ultra-fast model loading
 	if config.StreamerConcurrency == 0 {
 		config.StreamerConcurrency = 32 // Optimal for most models (8-64 range)
@@ -1221,9 +1358,17 @@ func (o *SkyPilotOrchestrator) validateNodeConfig(config *NodeConfig) error {
 		config.StreamerMemoryLimit = 5368709120 // 5GB default (increase for 70B+ models)
 	}
 
-	if config.GPUMemoryUtilization == 0 {
-		config.GPUMemoryUtilization = 0.95 // Run:ai Streamer is more efficient
+	// Zero vLLM memory settings are tuned once the catalog is loaded
+	if config.GPUMemoryUtilization < 0 || config.GPUMemoryUtilization > 1 {
+		errs.add("gpu_memory_utilization", "must be between 0 and 1")
 	}
+	if config.MaxModelLen < 0 {
+		errs.add("max_model_len", "must be positive")
+	}
+	if config.MaxNumSeqs < 0 {
+		errs.add("max_num_seqs", "must be positive")
+	}
+	checkColocatedModels(config, &errs)
 
 	// Enable Run:ai Streamer by default (can be disabled if needed)
 	if !config.UseRunaiStreamer {
@@ -1233,15 +1378,16 @@ func (o *SkyPilotOrchestrator) validateNodeConfig(config *NodeConfig) error {
 	// Sanitize optional VLLM args
 	cleanArgs, err := sanitizeVLLMArgs(config.VLLMArgs)
 	if err != nil {
-		return err
+		errs.add("vllm_args", "%v", err)
+	} else {
+		config.VLLMArgs = cleanArgs
 	}
-	config.VLLMArgs = cleanArgs
 
 	// UseSpot defaults to true (not set in struct, Go zero value is false)
 	// So we need to explicitly check if it was provided
 	// For simplicity, we'll document that UseSpot=false means on-demand
 
-	return nil
+	return errs.err()
 }
 
 var allowedVLLMArgPattern = regexp.MustCompile(`^[a-zA-Z0-9@./_=:-]+$`)
@@ -1264,10 +1410,30 @@ func sanitizeVLLMArgs(args string) (string, error) {
 	return strings.Join(sanitized, " "), nil
 }
 
-// generateTaskYAML generates SkyPilot task YAML from configuration.
+// generateTaskYAML generates SkyPilot task YAML from configuration with the
+// template files, ignoring admin overrides.
 func (o *SkyPilotOrchestrator) generateTaskYAML(config NodeConfig, clusterName string) (string, error) {
-	// Prepare template data
-	data := map[string]interface{}{
+	runtime := config.Runtime
+	if runtime == "" {
+		runtime = DefaultRuntime
+	}
+
+	taskTemplate := o.templates.File(config.Provider, runtime)
+	if taskTemplate == nil {
+		return "", fmt.Errorf("%w for runtime %q on %s", ErrTaskTemplateNotFound, runtime, config.Provider)
+	}
+	return taskTemplate.Render(o.taskData(config, clusterName))
+}
+
+// taskData is the data task templates are rendered with
+func (o *SkyPilotOrchestrator) taskData(config NodeConfig, clusterName string) map[string]interface{} {
+	o.ResolveRuntimeVersions(&config)
+	if len(config.ColocatedModels) > 0 {
+		config.ColocatedModels = append([]ColocatedModel(nil), config.ColocatedModels...)
+		config.tuneColocated(nil, config.tunedVRAMGB)
+	}
+	config.applyTuning(nodes.DefaultVLLMTuning())
+	return map[string]interface{}{
 		"NodeID":           config.NodeID,
 		"ClusterName":      clusterName,
 		"Provider":         config.Provider,
@@ -1275,13 +1441,16 @@ func (o *SkyPilotOrchestrator) generateTaskYAML(config NodeConfig, clusterName s
 		"GPU":              config.GPU,
 		"GPUCount":         config.GPUCount,
 		"Model":            config.Model,
+		"Runtime":          config.Runtime,
 		"UseSpot":          config.UseSpot,
 		"DiskSize":         config.DiskSize,
 		"VLLMArgs":         config.VLLMArgs,
 		"TensorParallel":   config.TensorParallel,
 		"ControlPlaneURL":  o.controlPlaneURL,
-		"VLLMVersion":      o.vllmVersion,
-		"TorchVersion":     o.torchVersion,
+		"NodeAPIURL":       o.nodeAgentURL(),
+		"NodeAPIToken":     o.nodeAPIToken,
+		"VLLMVersion":      config.VLLMVersion,
+		"TorchVersion":     config.TorchVersion,
 		"Timestamp":        time.Now().Format(time.RFC3339),
 		"R2Endpoint":       o.r2Config.Endpoint,
 		"R2Bucket":         o.r2Config.Bucket,
@@ -1291,65 +1460,144 @@ func (o *SkyPilotOrchestrator) generateTaskYAML(config NodeConfig, clusterName s
 		"StreamerConcurrency":    config.StreamerConcurrency,
 		"StreamerMemoryLimit":    config.StreamerMemoryLimit,
 		"GPUMemoryUtilization":   config.GPUMemoryUtilization,
+		"MaxModelLen":            config.MaxModelLen,
+		"MaxNumSeqs":             config.MaxNumSeqs,
 		"UseRunaiStreamer":       config.UseRunaiStreamer,
+		// Models served beside Model, each by its own vLLM
+		"ColocatedModels": config.ColocatedModels,
+		// Security hardening applied during setup
+		"Hardening":      o.hardeningCIDRs != nil,
+		"HardeningCIDRs": strings.Join(o.hardeningCIDRs, " "),
 	}
-
-	// Execute template
-	var buf bytes.Buffer
-	if err := o.taskTemplate.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("template execution failed: %w", err)
-	}
-
-	return buf.String(), nil
 }
 
 // registerNode registers a newly launched node in the database.
-func (o *SkyPilotOrchestrator) registerNode(ctx context.Context, config NodeConfig, clusterName string) error {
-	query := `
-		INSERT INTO nodes (
-			id, cluster_name, provider, region, gpu_type,
-			model_name, status, endpoint, created_at, deployment_id
-		) VALUES ($1, $2, $3, $4, $5, $6, 'initializing', '', NOW(), $7)
-		ON CONFLICT (id) DO UPDATE
-		SET cluster_name = $2, status = 'initializing', updated_at = NOW()
-	`
-
+func (o *SkyPilotOrchestrator) registerNode(ctx context.Context, config NodeConfig, clusterName, taskTemplateRef string) error {
 	nodeID, err := uuid.Parse(config.NodeID)
 	if err != nil {
 		return fmt.Errorf("invalid node ID: %w", err)
 	}
 
-	var deploymentID *uuid.UUID
+	reg := nodes.Registration{
+		ID:           nodeID,
+		ClusterName:  clusterName,
+		Provider:     config.Provider,
+		Region:       config.Region,
+		GPUType:      config.GPU,
+		GPUCount:     config.GPUCount,
+		ModelName:    config.Model,
+		SpotInstance: config.UseSpot,
+		Status:       nodes.StatusInitializing,
+		TaskTemplate: taskTemplateRef,
+		Runtime:      o.runtimeSpec(config),
+		Standby:      config.Standby,
+	}
+	if o.hardeningCIDRs != nil {
+		reg.HardeningProfile = nodes.HardeningBaseline
+	}
+	if config.Runtime == "" || config.Runtime == DefaultRuntime {
+		o.ResolveRuntimeVersions(&config)
+		reg.VLLMVersion = config.VLLMVersion
+		reg.TorchVersion = config.TorchVersion
+		tuning := config.vllmTuning()
+		reg.Tuning = &tuning
+	}
+
 	if config.DeploymentID != "" {
 		id, err := uuid.Parse(config.DeploymentID)
 		if err != nil {
 			return fmt.Errorf("invalid deployment ID: %w", err)
 		}
-		deploymentID = &id
+		reg.DeploymentID = &id
 	}
 
-	_, err = o.db.Pool.Exec(ctx, query,
-		nodeID,
-		clusterName,
-		config.Provider,
-		config.Region,
-		config.GPU,
-		config.Model,
-		deploymentID,
-	)
+	if config.TenantID != "" {
+		id, err := uuid.Parse(config.TenantID)
+		if err != nil {
+			return fmt.Errorf("invalid tenant ID: %w", err)
+		}
+		reg.TenantID = &id
+	}
 
-	return err
+	if _, _, err := o.registry.Register(ctx, reg); err != nil {
+		return err
+	}
+	return o.registerColocated(ctx, config, reg)
+}
+
+// runtimeSpec is what a node's agent should report once it is serving:
+// the model, the pinned vLLM version and the flags the launch controls
+func (o *SkyPilotOrchestrator) runtimeSpec(config NodeConfig) *nodes.RuntimeSpec {
+	spec := &nodes.RuntimeSpec{Model: config.Model}
+	if config.Runtime != "" && config.Runtime != DefaultRuntime {
+		return spec
+	}
+
+	o.ResolveRuntimeVersions(&config)
+	spec.VLLMVersion = config.VLLMVersion
+	if config.TensorParallel > 0 {
+		spec.VLLMArgs = []string{"--tensor-parallel-size", strconv.Itoa(config.TensorParallel)}
+	}
+	spec.VLLMArgs = append(spec.VLLMArgs, nodes.SplitArgs(config.VLLMArgs)...)
+	return spec
+}
+
+// recordLaunchRequested starts the cold start timings of a launch. Launch
+// metrics are best effort and never fail the launch.
+func (o *SkyPilotOrchestrator) recordLaunchRequested(ctx context.Context, config NodeConfig, clusterName string, startTime time.Time) {
+	nodeID, err := uuid.Parse(config.NodeID)
+	if err != nil {
+		return
+	}
+
+	requestedAt := config.RequestedAt
+	if requestedAt.IsZero() || requestedAt.After(startTime) {
+		requestedAt = startTime
+	}
+
+	err = o.launches.Requested(ctx, nodes.LaunchTiming{
+		NodeID:       nodeID,
+		ClusterName:  clusterName,
+		Provider:     config.Provider,
+		Region:       config.Region,
+		GPUType:      config.GPU,
+		GPUCount:     config.GPUCount,
+		ModelName:    config.Model,
+		SpotInstance: config.UseSpot,
+		RequestedAt:  requestedAt,
+	})
+	if err != nil {
+		o.logger.Warn("failed to record launch timing", zap.Error(err), zap.String("node_id", config.NodeID))
+	}
+}
+
+// recordLaunchOutcome records that SkyPilot finished provisioning the node,
+// or that the launch attempt failed
+func (o *SkyPilotOrchestrator) recordLaunchOutcome(ctx context.Context, nodeIDStr string, launchErr error) {
+	nodeID, err := uuid.Parse(nodeIDStr)
+	if err != nil {
+		return
+	}
+
+	if launchErr != nil {
+		err = o.launches.Failed(ctx, nodeID, launchErr)
+	} else {
+		err = o.launches.InstanceReady(ctx, nodeID)
+	}
+	if err != nil {
+		o.logger.Warn("failed to record launch timing", zap.Error(err), zap.String("node_id", nodeIDStr))
+	}
 }
 
 // updateNodeStatus updates the status of a node in the database.
 func (o *SkyPilotOrchestrator) updateNodeStatus(ctx context.Context, clusterName, status string) error {
 	query := `
 		UPDATE nodes
-		SET status = $1, updated_at = NOW()
+		SET status = $1, status_source = $3, updated_at = NOW()
 		WHERE cluster_name = $2
 	`
 
-	_, err := o.db.Pool.Exec(ctx, query, status, clusterName)
+	_, err := o.db.Pool.Exec(ctx, query, status, clusterName, nodes.SourceOrchestrator)
 	return err
 }
 
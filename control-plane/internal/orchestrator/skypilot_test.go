@@ -38,7 +38,7 @@ func TestNewSkyPilotOrchestrator(t *testing.T) {
 		t.Fatal("Orchestrator is nil")
 	}
 
-	if orch.taskTemplate == nil {
+	if orch.templates == nil {
 		t.Error("Task template not initialized")
 	}
 
@@ -346,7 +346,7 @@ func TestMultiCloudConfigurations(t *testing.T) {
 	db := &database.Database{Pool: &pgxpool.Pool{}}
 	orch, _ := NewSkyPilotOrchestrator(db, &cache.Cache{}, logger, "https://api.test.com", testVLLMVersion, testTorchVersion, events.NewBus(logger), config.R2Config{}, config.SkyPilotConfig{})
 
-	providers := []string{"aws", "gcp", "azure", "lambda", "oci"}
+	providers := []string{"aws", "gcp", "azure", "lambda", "runpod", "oci", "nebius"}
 
 	for _, provider := range providers {
 		t.Run(provider, func(t *testing.T) {
@@ -376,6 +376,31 @@ func TestMultiCloudConfigurations(t *testing.T) {
 	}
 }
 
+// TestParseCloudCredentials_GPUClouds verifies tenant credentials for the
+// GPU clouds land on the matching CloudCredentials field
+func TestParseCloudCredentials_GPUClouds(t *testing.T) {
+	lambda, err := parseCloudCredentials("lambda", []byte(`{"api_key":"lk"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "lk", lambda.Lambda.APIKey)
+
+	runpod, err := parseCloudCredentials("runpod", []byte(`{"api_key":"rk"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "rk", runpod.RunPod.APIKey)
+
+	oci, err := parseCloudCredentials("oci", []byte(`{"user_ocid":"u","tenancy_ocid":"t","fingerprint":"f","private_key":"k","region":"us-ashburn-1"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "t", oci.OCI.TenancyOCID)
+	assert.Equal(t, "us-ashburn-1", oci.OCI.Region)
+
+	nebius, err := parseCloudCredentials("nebius", []byte(`{"api_key":"nk","project_id":"p"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "p", nebius.Nebius.ProjectID)
+	assert.Nil(t, nebius.AWS)
+
+	_, err = parseCloudCredentials("vultr", []byte(`{}`))
+	assert.Error(t, err)
+}
+
 // TestGPUTypes tests different GPU configurations
 func TestGPUTypes(t *testing.T) {
 	logger := zap.NewNop()
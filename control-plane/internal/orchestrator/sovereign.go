@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"github.com/crosslogic/control-plane/internal/credentials"
+	"github.com/crosslogic/control-plane/internal/skypilot"
+)
+
+// Sovereign clouds (AWS GovCloud, China and isolated partitions, Azure
+// national clouds) are reached through the partition named on the tenant's
+// credentials. Launches send the resolved partition and endpoints to the API
+// server, refuse regions outside the partition, and pass the endpoints to
+// the node so cloud SDKs on it use them too. CLI mode launches with the
+// host's cloud configuration and is unaffected.
+
+// normalizeSovereign fills in the partition or cloud and its endpoints so
+// the API server doesn't have to infer them
+func normalizeSovereign(creds *skypilot.CloudCredentials) error {
+	if creds.AWS != nil {
+		creds.AWS.Partition = credentials.ResolveAWSPartition(creds.AWS.Partition, creds.AWS.Region)
+		if !credentials.IsValidAWSPartition(creds.AWS.Partition) {
+			return fmt.Errorf("unsupported AWS partition %q", creds.AWS.Partition)
+		}
+	}
+
+	if creds.Azure != nil {
+		cloud, err := credentials.ResolveAzureCloud(creds.Azure.Cloud,
+			optionalString(creds.Azure.AuthorityHost), optionalString(creds.Azure.ResourceManagerEndpoint))
+		if err != nil {
+			return err
+		}
+		creds.Azure.Cloud = cloud.Name
+		creds.Azure.AuthorityHost = cloud.AuthorityHost
+		creds.Azure.ResourceManagerEndpoint = cloud.ResourceManagerEndpoint
+	}
+	return nil
+}
+
+// checkSovereignRegion refuses to launch in a region the credentials can't
+// reach
+func checkSovereignRegion(creds *skypilot.CloudCredentials, region string) error {
+	if creds == nil || creds.AWS == nil {
+		return nil
+	}
+	return credentials.CheckAWSRegion(creds.AWS.Partition, region)
+}
+
+// sovereignEnvs returns the environment that points cloud SDKs on the node at
+// a sovereign partition's endpoints. Standard partitions need none.
+func sovereignEnvs(creds *skypilot.CloudCredentials, region string) map[string]string {
+	envs := make(map[string]string)
+	if creds == nil {
+		return envs
+	}
+
+	if aws := creds.AWS; aws != nil && (aws.Partition != credentials.AWSPartitionStandard || aws.EndpointURL != "" || aws.STSEndpoint != "") {
+		envs["AWS_PARTITION"] = aws.Partition
+		if region != "" {
+			envs["AWS_REGION"] = region
+			envs["AWS_DEFAULT_REGION"] = region
+		}
+		// Partitions outside aws have no global STS endpoint
+		envs["AWS_STS_REGIONAL_ENDPOINTS"] = "regional"
+		if aws.EndpointURL != "" {
+			envs["AWS_ENDPOINT_URL"] = aws.EndpointURL
+		}
+		if aws.STSEndpoint != "" {
+			envs["AWS_ENDPOINT_URL_STS"] = aws.STSEndpoint
+		}
+	}
+
+	if azure := creds.Azure; azure != nil && azure.Cloud != credentials.AzureCloudPublic {
+		envs["AZURE_ENVIRONMENT"] = azure.Cloud
+		envs["AZURE_AUTHORITY_HOST"] = azure.AuthorityHost
+		envs["AZURE_RESOURCE_MANAGER_ENDPOINT"] = azure.ResourceManagerEndpoint
+	}
+	return envs
+}
+
+// optionalString returns nil for an empty string
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,60 @@
+package orchestrator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/crosslogic/control-plane/internal/credentials"
+)
+
+func TestParseCloudCredentialsSovereign(t *testing.T) {
+	creds, err := parseCloudCredentials("aws", []byte(`{"access_key_id":"AKIA","secret_access_key":"s","region":"us-gov-west-1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AWS.Partition != credentials.AWSPartitionGovCloud {
+		t.Errorf("Partition = %q, want %q", creds.AWS.Partition, credentials.AWSPartitionGovCloud)
+	}
+
+	creds, err = parseCloudCredentials("azure", []byte(`{"subscription_id":"s","tenant_id":"t","client_id":"c","client_secret":"x","cloud":"AzureChinaCloud"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Azure.AuthorityHost != "https://login.chinacloudapi.cn" || creds.Azure.ResourceManagerEndpoint != "https://management.chinacloudapi.cn" {
+		t.Errorf("Azure endpoints = %+v", creds.Azure)
+	}
+
+	if _, err := parseCloudCredentials("aws", []byte(`{"access_key_id":"AKIA","secret_access_key":"s","partition":"aws-moon"}`)); err == nil {
+		t.Error("unknown partition accepted")
+	}
+}
+
+func TestSovereignEnvs(t *testing.T) {
+	standard, _ := parseCloudCredentials("aws", []byte(`{"access_key_id":"AKIA","secret_access_key":"s","region":"us-east-1"}`))
+	if envs := sovereignEnvs(standard, "us-east-1"); len(envs) != 0 {
+		t.Errorf("standard partition envs = %v, want none", envs)
+	}
+	if err := checkSovereignRegion(standard, "us-gov-west-1"); err == nil {
+		t.Error("standard credentials allowed a GovCloud region")
+	}
+
+	gov, _ := parseCloudCredentials("aws", []byte(`{"access_key_id":"AKIA","secret_access_key":"s","region":"us-gov-west-1","sts_endpoint":"https://sts.us-gov-west-1.amazonaws.com"}`))
+	if err := checkSovereignRegion(gov, "us-gov-east-1"); err != nil {
+		t.Errorf("checkSovereignRegion() = %v", err)
+	}
+	want := map[string]string{
+		"AWS_PARTITION":              credentials.AWSPartitionGovCloud,
+		"AWS_REGION":                 "us-gov-east-1",
+		"AWS_DEFAULT_REGION":         "us-gov-east-1",
+		"AWS_STS_REGIONAL_ENDPOINTS": "regional",
+		"AWS_ENDPOINT_URL_STS":       "https://sts.us-gov-west-1.amazonaws.com",
+	}
+	if got := sovereignEnvs(gov, "us-gov-east-1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("sovereignEnvs() = %v, want %v", got, want)
+	}
+
+	azure, _ := parseCloudCredentials("azure", []byte(`{"subscription_id":"s","tenant_id":"t","client_id":"c","client_secret":"x","cloud":"AzureUSGovernment"}`))
+	if got := sovereignEnvs(azure, "usgovvirginia"); got["AZURE_ENVIRONMENT"] != credentials.AzureCloudUSGovernment || got["AZURE_AUTHORITY_HOST"] != "https://login.microsoftonline.us" {
+		t.Errorf("Azure envs = %v", got)
+	}
+}
@@ -0,0 +1,607 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultRuntime is the inference runtime launched when a NodeConfig
+	// sets none
+	DefaultRuntime = "vllm"
+
+	// DiffusionRuntime serves image models with diffusers behind an
+	// OpenAI-compatible /v1/images/generations endpoint
+	DiffusionRuntime = "diffusion"
+
+	// taskTemplateExt is the suffix of template files. Files are named
+	// <runtime>.yaml.tmpl for any provider, or <runtime>.<provider>.yaml.tmpl
+	taskTemplateExt = ".yaml.tmpl"
+
+	// maxTaskTemplateSize caps an uploaded template
+	maxTaskTemplateSize = 256 * 1024
+
+	// overrideCacheTTL is how long admin overrides are cached, so overrides
+	// written by another control plane instance are picked up
+	overrideCacheTTL = time.Minute
+)
+
+var (
+	// ErrTaskTemplateNotFound is returned when no template matches a
+	// provider and runtime, or a template version does not exist
+	ErrTaskTemplateNotFound = errors.New("task template not found")
+
+	// ErrInvalidTaskTemplate is returned when a template fails validation
+	ErrInvalidTaskTemplate = errors.New("invalid task template")
+
+	templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+)
+
+//go:embed templates/*.yaml.tmpl
+var builtinTaskTemplates embed.FS
+
+// TaskTemplate is a parsed SkyPilot task template
+type TaskTemplate struct {
+	// Ref identifies the template and its version, and is recorded on the
+	// nodes launched with it, e.g. "override:aws/vllm@v3" or
+	// "file:vllm.yaml.tmpl@1a2b3c4d5e6f"
+	Ref      string `json:"ref"`
+	Source   string `json:"source"`   // "file" or "override"
+	Provider string `json:"provider"` // empty for any provider
+	Runtime  string `json:"runtime"`
+	Version  int    `json:"version,omitempty"` // overrides only
+	SHA256   string `json:"sha256"`
+
+	tmpl *template.Template
+}
+
+// Render executes the template with the task data
+func (t *TaskTemplate) Render(data map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("template execution failed: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// servesColocated reports whether the template starts the launch's
+// colocated models
+func (t *TaskTemplate) servesColocated() bool {
+	return t.tmpl != nil && t.tmpl.Tree != nil &&
+		strings.Contains(t.tmpl.Tree.Root.String(), ".ColocatedModels")
+}
+
+// TemplateVersion is a stored admin override version
+type TemplateVersion struct {
+	ID        uuid.UUID `json:"id"`
+	Provider  string    `json:"provider"`
+	Runtime   string    `json:"runtime"`
+	Version   int       `json:"version"`
+	Ref       string    `json:"ref"`
+	SHA256    string    `json:"sha256"`
+	Active    bool      `json:"active"`
+	Notes     string    `json:"notes,omitempty"`
+	Body      string    `json:"body,omitempty"`
+	Nodes     int       `json:"nodes"` // non-terminated nodes launched with it
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// templateKey indexes templates by provider and runtime
+func templateKey(provider, runtime string) string {
+	return provider + "/" + runtime
+}
+
+// overrideRef is the Ref of an admin override version
+func overrideRef(provider, runtime string, version int) string {
+	if provider == "" {
+		provider = "*"
+	}
+	return fmt.Sprintf("override:%s/%s@v%d", provider, runtime, version)
+}
+
+func templateSHA256(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseTaskTemplate parses a template body. Unknown variables are errors
+// rather than rendering as "<no value>".
+func parseTaskTemplate(name, body string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(body)
+}
+
+// sampleTaskData is the task data templates are validated against
+func sampleTaskData() map[string]interface{} {
+	o := &SkyPilotOrchestrator{
+		controlPlaneURL: "api.example.com",
+		vllmVersion:     "0.6.2",
+		torchVersion:    "2.4.0",
+	}
+	config := NodeConfig{
+		NodeID:               "00000000-0000-4000-8000-000000000000",
+		Provider:             "aws",
+		Region:               "us-east-1",
+		GPU:                  "A100",
+		GPUCount:             1,
+		Model:                "meta-llama/Llama-3.1-8B-Instruct",
+		Runtime:              DefaultRuntime,
+		DiskSize:             256,
+		TensorParallel:       1,
+		StreamerConcurrency:  16,
+		StreamerMemoryLimit:  5368709120,
+		GPUMemoryUtilization: 0.9,
+		UseRunaiStreamer:     true,
+		ColocatedModels: []ColocatedModel{{
+			Model:                "Qwen/Qwen2.5-0.5B-Instruct",
+			Port:                 firstColocatedPort,
+			GPUMemoryUtilization: 0.05,
+		}},
+	}
+	return o.taskData(config, "cic-aws-useast1-a100-od-000000")
+}
+
+// ValidateTaskTemplate parses a template and renders it with sample data,
+// checking that the result is a task SkyPilot can launch and that the node
+// agent gets what it needs to register
+func ValidateTaskTemplate(body string) (*template.Template, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("%w: body is empty", ErrInvalidTaskTemplate)
+	}
+	if len(body) > maxTaskTemplateSize {
+		return nil, fmt.Errorf("%w: body exceeds %d bytes", ErrInvalidTaskTemplate, maxTaskTemplateSize)
+	}
+
+	tmpl, err := parseTaskTemplate("task", body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTaskTemplate, err)
+	}
+
+	data := sampleTaskData()
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTaskTemplate, err)
+	}
+
+	if problems := checkRenderedTask(buf.String(), data); len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTaskTemplate, strings.Join(problems, "; "))
+	}
+	return tmpl, nil
+}
+
+// checkRenderedTask lists what is wrong with a rendered task
+func checkRenderedTask(rendered string, data map[string]interface{}) []string {
+	var problems []string
+
+	keys := map[string]bool{}
+	for i, line := range strings.Split(rendered, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			problems = append(problems, fmt.Sprintf("line %d is indented with a tab; YAML needs spaces", i+1))
+			continue
+		}
+		if line == "" || line[0] == ' ' || line[0] == '#' {
+			continue
+		}
+		if key, _, ok := strings.Cut(line, ":"); ok {
+			keys[key] = true
+		}
+	}
+	for _, key := range []string{"resources", "run"} {
+		if !keys[key] {
+			problems = append(problems, fmt.Sprintf("missing top-level %q section", key))
+		}
+	}
+
+	// The node agent registers with these
+	for _, name := range []string{"NodeID", "ControlPlaneURL"} {
+		if !strings.Contains(rendered, fmt.Sprint(data[name])) {
+			problems = append(problems, fmt.Sprintf("must use .%s so the node agent can register", name))
+		}
+	}
+	return problems
+}
+
+// TemplateRegistry resolves the SkyPilot task template for a launch. Admin
+// overrides stored in the database win over template files, and
+// provider-specific templates win over the runtime's default:
+//
+//  1. override for the provider and runtime
+//  2. override for the runtime on any provider
+//  3. file <runtime>.<provider>.yaml.tmpl
+//  4. file <runtime>.yaml.tmpl
+//
+// Files are built in, and can be replaced or added to from a directory.
+type TemplateRegistry struct {
+	db     *database.Database
+	logger *zap.Logger
+
+	// files holds the templates loaded at startup, by templateKey
+	files map[string]*TaskTemplate
+
+	mu        sync.Mutex
+	overrides map[string]*TaskTemplate
+	loadedAt  time.Time
+}
+
+// NewTemplateRegistry loads the built-in templates and those in dir, which
+// replace built-in files of the same name. dir may be empty.
+func NewTemplateRegistry(db *database.Database, logger *zap.Logger, dir string) (*TemplateRegistry, error) {
+	r := &TemplateRegistry{
+		db:     db,
+		logger: logger,
+		files:  make(map[string]*TaskTemplate),
+	}
+
+	builtin, err := fs.Sub(builtinTaskTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadFiles(builtin); err != nil {
+		return nil, fmt.Errorf("failed to load built-in task templates: %w", err)
+	}
+
+	if dir != "" {
+		if err := r.loadFiles(os.DirFS(dir)); err != nil {
+			return nil, fmt.Errorf("failed to load task templates from %s: %w", dir, err)
+		}
+	}
+
+	return r, nil
+}
+
+// loadFiles validates and adds every template file in fsys
+func (r *TemplateRegistry) loadFiles(fsys fs.FS) error {
+	names, err := fs.Glob(fsys, "*"+taskTemplateExt)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		runtime, provider, err := parseTemplateFileName(name)
+		if err != nil {
+			return err
+		}
+
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		tmpl, err := ValidateTaskTemplate(string(body))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		sum := templateSHA256(string(body))
+		r.files[templateKey(provider, runtime)] = &TaskTemplate{
+			Ref:      "file:" + name + "@" + sum[:12],
+			Source:   "file",
+			Provider: provider,
+			Runtime:  runtime,
+			SHA256:   sum,
+			tmpl:     tmpl,
+		}
+	}
+	return nil
+}
+
+// parseTemplateFileName splits <runtime>[.<provider>].yaml.tmpl
+func parseTemplateFileName(name string) (runtime, provider string, err error) {
+	parts := strings.Split(strings.TrimSuffix(name, taskTemplateExt), ".")
+	if len(parts) > 2 {
+		return "", "", fmt.Errorf("%s: template files are named <runtime>%s or <runtime>.<provider>%s", name, taskTemplateExt, taskTemplateExt)
+	}
+	for _, part := range parts {
+		if !templateNamePattern.MatchString(part) {
+			return "", "", fmt.Errorf("%s: invalid runtime or provider %q", name, part)
+		}
+	}
+	if len(parts) == 2 {
+		provider = parts[1]
+	}
+	return parts[0], provider, nil
+}
+
+// File returns the template file for the provider and runtime, ignoring
+// admin overrides, or nil when there is none
+func (r *TemplateRegistry) File(provider, runtime string) *TaskTemplate {
+	if t, ok := r.files[templateKey(provider, runtime)]; ok {
+		return t
+	}
+	return r.files[templateKey("", runtime)]
+}
+
+// Files lists the template files, by runtime then provider
+func (r *TemplateRegistry) Files() []*TaskTemplate {
+	files := make([]*TaskTemplate, 0, len(r.files))
+	for _, t := range r.files {
+		files = append(files, t)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Runtime != files[j].Runtime {
+			return files[i].Runtime < files[j].Runtime
+		}
+		return files[i].Provider < files[j].Provider
+	})
+	return files
+}
+
+// Resolve returns the template a launch on the provider with the runtime
+// uses
+func (r *TemplateRegistry) Resolve(ctx context.Context, provider, runtime string) (*TaskTemplate, error) {
+	overrides, err := r.activeOverrides(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task template overrides: %w", err)
+	}
+	return r.pick(overrides, provider, runtime)
+}
+
+// pick applies the resolution order to the active overrides and the files
+func (r *TemplateRegistry) pick(overrides map[string]*TaskTemplate, provider, runtime string) (*TaskTemplate, error) {
+	if t, ok := overrides[templateKey(provider, runtime)]; ok {
+		return t, nil
+	}
+	if t, ok := overrides[templateKey("", runtime)]; ok {
+		return t, nil
+	}
+	if t := r.File(provider, runtime); t != nil {
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w for runtime %q on %s", ErrTaskTemplateNotFound, runtime, provider)
+}
+
+// activeOverrides returns the active admin overrides, cached for
+// overrideCacheTTL
+func (r *TemplateRegistry) activeOverrides(ctx context.Context) (map[string]*TaskTemplate, error) {
+	if r.db == nil {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.overrides != nil && time.Since(r.loadedAt) < overrideCacheTTL {
+		return r.overrides, nil
+	}
+
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT provider, runtime, version, body, sha256
+		FROM skypilot_task_templates
+		WHERE active
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]*TaskTemplate)
+	for rows.Next() {
+		var t TaskTemplate
+		var body string
+		if err := rows.Scan(&t.Provider, &t.Runtime, &t.Version, &body, &t.SHA256); err != nil {
+			return nil, err
+		}
+
+		// Bodies were validated on upload, so this only fails if the
+		// template variables changed since
+		tmpl, err := parseTaskTemplate("task", body)
+		if err != nil {
+			r.logger.Error("skipping unparseable task template override",
+				zap.Error(err),
+				zap.String("ref", overrideRef(t.Provider, t.Runtime, t.Version)),
+			)
+			continue
+		}
+
+		t.Ref = overrideRef(t.Provider, t.Runtime, t.Version)
+		t.Source = "override"
+		t.tmpl = tmpl
+		overrides[templateKey(t.Provider, t.Runtime)] = &t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	r.overrides = overrides
+	r.loadedAt = time.Now()
+	return overrides, nil
+}
+
+// invalidate drops the cached overrides after a write
+func (r *TemplateRegistry) invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.overrides = nil
+}
+
+// checkTemplateScope validates the provider ("" for any) and runtime of an
+// override
+func checkTemplateScope(provider, runtime string) error {
+	if !templateNamePattern.MatchString(runtime) {
+		return fmt.Errorf("%w: invalid runtime %q", ErrInvalidTaskTemplate, runtime)
+	}
+	if provider != "" && !templateNamePattern.MatchString(provider) {
+		return fmt.Errorf("%w: invalid provider %q", ErrInvalidTaskTemplate, provider)
+	}
+	return nil
+}
+
+// Upload validates a template and stores it as the next, active override
+// version for the provider ("" for any) and runtime
+func (r *TemplateRegistry) Upload(ctx context.Context, provider, runtime, body, notes string) (*TemplateVersion, error) {
+	if err := checkTemplateScope(provider, runtime); err != nil {
+		return nil, err
+	}
+	if _, err := ValidateTaskTemplate(body); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize uploads for the same scope so versions don't collide
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('skypilot_task_templates:' || $1))`,
+		templateKey(provider, runtime)); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE skypilot_task_templates SET active = false
+		WHERE provider = $1 AND runtime = $2 AND active
+	`, provider, runtime); err != nil {
+		return nil, err
+	}
+
+	v := TemplateVersion{
+		Provider: provider,
+		Runtime:  runtime,
+		SHA256:   templateSHA256(body),
+		Active:   true,
+		Notes:    notes,
+		Body:     body,
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO skypilot_task_templates (provider, runtime, version, body, sha256, active, notes)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, true, NULLIF($5, '')
+		FROM skypilot_task_templates
+		WHERE provider = $1 AND runtime = $2
+		RETURNING id, version, created_at
+	`, provider, runtime, body, v.SHA256, notes).Scan(&v.ID, &v.Version, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	r.invalidate()
+
+	v.Ref = overrideRef(provider, runtime, v.Version)
+	return &v, nil
+}
+
+// Activate makes a stored version the active override, e.g. to roll back
+func (r *TemplateRegistry) Activate(ctx context.Context, provider, runtime string, version int) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Deactivate first: the one-active-version index is checked per row
+	if _, err := tx.Exec(ctx, `
+		UPDATE skypilot_task_templates SET active = false
+		WHERE provider = $1 AND runtime = $2 AND active AND version != $3
+	`, provider, runtime, version); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE skypilot_task_templates SET active = true
+		WHERE provider = $1 AND runtime = $2 AND version = $3
+	`, provider, runtime, version)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTaskTemplateNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	r.invalidate()
+	return nil
+}
+
+// Deactivate drops the active override so launches fall back to the
+// template files. Stored versions are kept.
+func (r *TemplateRegistry) Deactivate(ctx context.Context, provider, runtime string) error {
+	tag, err := r.db.Pool.Exec(ctx, `
+		UPDATE skypilot_task_templates SET active = false
+		WHERE provider = $1 AND runtime = $2 AND active
+	`, provider, runtime)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTaskTemplateNotFound
+	}
+	r.invalidate()
+	return nil
+}
+
+// Versions lists the stored override versions, newest first, with how many
+// nodes each launched. An empty runtime lists every scope.
+func (r *TemplateRegistry) Versions(ctx context.Context, provider, runtime string) ([]TemplateVersion, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT t.id, t.provider, t.runtime, t.version, t.sha256, t.active,
+		       COALESCE(t.notes, ''), t.created_at,
+		       (SELECT COUNT(*) FROM nodes n
+		        WHERE n.task_template = 'override:' || CASE WHEN t.provider = '' THEN '*' ELSE t.provider END
+		                                || '/' || t.runtime || '@v' || t.version
+		          AND n.status != 'terminated')
+		FROM skypilot_task_templates t
+		WHERE ($2 = '' OR (t.provider = $1 AND t.runtime = $2))
+		ORDER BY t.runtime, t.provider, t.version DESC
+	`, provider, runtime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []TemplateVersion{}
+	for rows.Next() {
+		var v TemplateVersion
+		if err := rows.Scan(&v.ID, &v.Provider, &v.Runtime, &v.Version, &v.SHA256, &v.Active,
+			&v.Notes, &v.CreatedAt, &v.Nodes); err != nil {
+			return nil, err
+		}
+		v.Ref = overrideRef(v.Provider, v.Runtime, v.Version)
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// Version returns one stored override version with its body
+func (r *TemplateRegistry) Version(ctx context.Context, provider, runtime string, version int) (*TemplateVersion, error) {
+	v := TemplateVersion{Provider: provider, Runtime: runtime, Version: version}
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT id, sha256, active, COALESCE(notes, ''), body, created_at
+		FROM skypilot_task_templates
+		WHERE provider = $1 AND runtime = $2 AND version = $3
+	`, provider, runtime, version).Scan(&v.ID, &v.SHA256, &v.Active, &v.Notes, &v.Body, &v.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTaskTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v.Ref = overrideRef(provider, runtime, version)
+	return &v, nil
+}
+
+// Templates returns the registry resolving the orchestrator's task templates
+func (o *SkyPilotOrchestrator) Templates() *TemplateRegistry {
+	return o.templates
+}
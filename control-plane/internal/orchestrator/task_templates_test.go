@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const minimalTaskTemplate = `name: {{.ClusterName}}
+
+resources:
+  accelerators: {{.GPU}}:{{.GPUCount}}
+
+run: |
+  export NODE_ID={{.NodeID}}
+  export CONTROL_PLANE_URL={{.ControlPlaneURL}}
+  /usr/local/bin/node-agent
+`
+
+func TestValidateTaskTemplate(t *testing.T) {
+	if _, err := ValidateTaskTemplate(SkyPilotTaskTemplate); err != nil {
+		t.Fatalf("built-in template invalid: %v", err)
+	}
+	if _, err := ValidateTaskTemplate(minimalTaskTemplate); err != nil {
+		t.Fatalf("minimal template invalid: %v", err)
+	}
+
+	tests := []struct {
+		name, body, want string
+	}{
+		{"empty", "  \n", "body is empty"},
+		{"parse error", "run: {{.NodeID", "unclosed action"},
+		{"unknown variable", minimalTaskTemplate + "# {{.Modle}}\n", `"Modle"`},
+		{"missing run", strings.Split(minimalTaskTemplate, "run:")[0] + "# {{.NodeID}} {{.ControlPlaneURL}}\n", `missing top-level "run"`},
+		{"tab indentation", strings.Replace(minimalTaskTemplate, "  accelerators", "\taccelerators", 1), "line 4 is indented with a tab"},
+		{"no node id", strings.Replace(minimalTaskTemplate, "{{.NodeID}}", "fixed", 1), "must use .NodeID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateTaskTemplate(tt.body)
+			if !errors.Is(err, ErrInvalidTaskTemplate) {
+				t.Fatalf("error = %v, want ErrInvalidTaskTemplate", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplateFileName(t *testing.T) {
+	tests := []struct {
+		name, runtime, provider string
+		wantErr                 bool
+	}{
+		{"vllm.yaml.tmpl", "vllm", "", false},
+		{"vllm.lambda.yaml.tmpl", "vllm", "lambda", false},
+		{"sglang.yaml.tmpl", "sglang", "", false},
+		{"vllm.aws.extra.yaml.tmpl", "", "", true},
+		{"vLLM.yaml.tmpl", "", "", true},
+	}
+	for _, tt := range tests {
+		runtime, provider, err := parseTemplateFileName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if runtime != tt.runtime || provider != tt.provider {
+			t.Errorf("%s: got (%q, %q), want (%q, %q)", tt.name, runtime, provider, tt.runtime, tt.provider)
+		}
+	}
+}
+
+func TestTemplateRegistryResolve(t *testing.T) {
+	dir := t.TempDir()
+	lambda := strings.Replace(minimalTaskTemplate, "name:", "# lambda\nname:", 1)
+	if err := os.WriteFile(filepath.Join(dir, "vllm.lambda.yaml.tmpl"), []byte(lambda), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewTemplateRegistry(nil, zap.NewNop(), dir)
+	if err != nil {
+		t.Fatalf("NewTemplateRegistry: %v", err)
+	}
+	ctx := context.Background()
+
+	// Provider file wins over the runtime default
+	got, err := r.Resolve(ctx, "lambda", "vllm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got.Ref, "file:vllm.lambda.yaml.tmpl@") || got.Provider != "lambda" {
+		t.Errorf("lambda resolved to %s", got.Ref)
+	}
+	got, _ = r.Resolve(ctx, "aws", "vllm")
+	if want := "file:vllm.yaml.tmpl@" + templateSHA256(SkyPilotTaskTemplate)[:12]; got.Ref != want {
+		t.Errorf("aws resolved to %s, want %s", got.Ref, want)
+	}
+
+	got, err = r.Resolve(ctx, "aws", DiffusionRuntime)
+	if err != nil || !strings.HasPrefix(got.Ref, "file:diffusion.yaml.tmpl@") {
+		t.Errorf("diffusion resolved to %v, %v", got, err)
+	}
+
+	if _, err := r.Resolve(ctx, "aws", "sglang"); !errors.Is(err, ErrTaskTemplateNotFound) {
+		t.Errorf("unknown runtime error = %v, want ErrTaskTemplateNotFound", err)
+	}
+
+	// Overrides win over files, provider-specific ones first
+	overrides := map[string]*TaskTemplate{
+		templateKey("", "vllm"):    {Ref: overrideRef("", "vllm", 2)},
+		templateKey("aws", "vllm"): {Ref: overrideRef("aws", "vllm", 5)},
+	}
+	for provider, want := range map[string]string{
+		"aws":    "override:aws/vllm@v5",
+		"lambda": "override:*/vllm@v2",
+	} {
+		got, err := r.pick(overrides, provider, "vllm")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Ref != want {
+			t.Errorf("%s resolved to %s, want %s", provider, got.Ref, want)
+		}
+	}
+}
+
+func TestTemplateRegistryRejectsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "vllm.gcp.yaml.tmpl"), []byte("run: echo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTemplateRegistry(nil, zap.NewNop(), dir); err == nil || !strings.Contains(err.Error(), "vllm.gcp.yaml.tmpl") {
+		t.Errorf("error = %v, want the invalid file named", err)
+	}
+}
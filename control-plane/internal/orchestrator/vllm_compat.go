@@ -0,0 +1,710 @@
+package orchestrator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Before the platform moves to a new vLLM release, a compatibility run
+// launches a short-lived canary node of the release for one model of each
+// model family and runs a smoke suite against it: plain and streamed
+// generation, function calling for models with tools, and embeddings for
+// embedding models. The results form a pass/fail matrix of families by
+// check; deployments can only be pinned to a vLLM newer than the platform's
+// once a run of that release has passed for their model's family.
+//
+// Canary nodes are launched as standbys so they never take tenant traffic,
+// and are terminated when their checks finish.
+
+// Compatibility checks
+const (
+	CompatCheckLaunch          = "launch"
+	CompatCheckGeneration      = "generation"
+	CompatCheckStreaming       = "streaming"
+	CompatCheckFunctionCalling = "function_calling"
+	CompatCheckEmbeddings      = "embeddings"
+)
+
+// Compatibility run and check statuses
+const (
+	CompatStatusQueued  = "queued"
+	CompatStatusRunning = "running"
+	CompatStatusPassed  = "passed"
+	CompatStatusFailed  = "failed"
+	CompatStatusSkipped = "skipped"
+)
+
+const (
+	// compatReadyTimeout is how long a canary node may take to register
+	// with vLLM serving
+	compatReadyTimeout = 30 * time.Minute
+	// compatPollInterval is how often the node's status is checked
+	compatPollInterval = 15 * time.Second
+	// compatRequestTimeout bounds each smoke request
+	compatRequestTimeout = 2 * time.Minute
+	// compatConcurrency is how many families are checked at once
+	compatConcurrency = 4
+	// compatMaxTokens keeps smoke generations short
+	compatMaxTokens = 16
+)
+
+// ErrCompatRunNotFound is returned for unknown compatibility runs
+var ErrCompatRunNotFound = errors.New("compatibility run not found")
+
+// CompatRun is a compatibility run of a vLLM release
+type CompatRun struct {
+	ID           uuid.UUID  `json:"id"`
+	VLLMVersion  string     `json:"vllm_version"`
+	TorchVersion string     `json:"torch_version,omitempty"`
+	Families     []string   `json:"families,omitempty"`
+	Provider     string     `json:"provider,omitempty"`
+	Region       string     `json:"region,omitempty"`
+	Status       string     `json:"status"`
+	Error        string     `json:"error,omitempty"`
+	RequestedBy  *string    `json:"requested_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	// Matrix maps each family to its checks' results
+	Matrix map[string]map[string]CompatCheckResult `json:"matrix,omitempty"`
+}
+
+// CompatCheckResult is the outcome of one check of a family's canary
+type CompatCheckResult struct {
+	Model     string `json:"model"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// compatModel is the model a family is checked with
+type compatModel struct {
+	Family        string
+	Name          string
+	Type          string
+	SupportsTools bool
+	MinVLLM       string
+}
+
+// CompatTester runs compatibility runs
+type CompatTester struct {
+	db           *database.Database
+	logger       *zap.Logger
+	orchestrator *SkyPilotOrchestrator
+	httpClient   *http.Client
+
+	readyTimeout time.Duration
+	pollInterval time.Duration
+}
+
+// NewCompatTester creates a compatibility tester launching canaries with orch
+func NewCompatTester(db *database.Database, logger *zap.Logger, orch *SkyPilotOrchestrator) *CompatTester {
+	return &CompatTester{
+		db:           db,
+		logger:       logger,
+		orchestrator: orch,
+		httpClient:   &http.Client{Timeout: compatRequestTimeout},
+		readyTimeout: compatReadyTimeout,
+		pollInterval: compatPollInterval,
+	}
+}
+
+// Create records a queued run of a vLLM release for the given families, all
+// active families when empty
+func (t *CompatTester) Create(ctx context.Context, run CompatRun) (*CompatRun, error) {
+	if !ValidRuntimeVersion(run.VLLMVersion) {
+		return nil, &ConfigError{Fields: []FieldError{{Field: "vllm_version", Message: fmt.Sprintf("%q is not a release version such as 0.6.3", run.VLLMVersion)}}}
+	}
+	if run.TorchVersion != "" && !ValidRuntimeVersion(run.TorchVersion) {
+		return nil, &ConfigError{Fields: []FieldError{{Field: "torch_version", Message: fmt.Sprintf("%q is not a release version such as 2.4.0", run.TorchVersion)}}}
+	}
+
+	if run.Families == nil {
+		run.Families = []string{}
+	}
+	run.ID = uuid.New()
+	run.Status = CompatStatusQueued
+	err := t.db.Pool.QueryRow(ctx, `
+		INSERT INTO vllm_compat_runs (id, vllm_version, torch_version, families, provider, region, status, requested_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+		RETURNING created_at
+	`, run.ID, run.VLLMVersion, run.TorchVersion, run.Families, run.Provider, run.Region, run.Status, run.RequestedBy).Scan(&run.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compatibility run: %w", err)
+	}
+	return &run, nil
+}
+
+// Get loads a run with its matrix
+func (t *CompatTester) Get(ctx context.Context, id uuid.UUID) (*CompatRun, error) {
+	var run CompatRun
+	var torch, provider, region, runErr *string
+	err := t.db.Pool.QueryRow(ctx, `
+		SELECT id, vllm_version, torch_version, families, provider, region, status, error,
+		       requested_by, created_at, started_at, finished_at
+		FROM vllm_compat_runs WHERE id = $1
+	`, id).Scan(&run.ID, &run.VLLMVersion, &torch, &run.Families, &provider, &region, &run.Status, &runErr,
+		&run.RequestedBy, &run.CreatedAt, &run.StartedAt, &run.FinishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCompatRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load compatibility run: %w", err)
+	}
+	run.TorchVersion, run.Provider, run.Region, run.Error = deref(torch), deref(provider), deref(region), deref(runErr)
+
+	if run.Matrix, err = t.matrix(ctx, id); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// List returns the most recent runs, newest first, without their matrices
+func (t *CompatTester) List(ctx context.Context, vllmVersion string, limit int) ([]CompatRun, error) {
+	rows, err := t.db.Pool.Query(ctx, `
+		SELECT id, vllm_version, COALESCE(torch_version, ''), families, COALESCE(provider, ''), COALESCE(region, ''),
+		       status, COALESCE(error, ''), requested_by, created_at, started_at, finished_at
+		FROM vllm_compat_runs
+		WHERE $1 = '' OR vllm_version = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, vllmVersion, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list compatibility runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []CompatRun{}
+	for rows.Next() {
+		var run CompatRun
+		if err := rows.Scan(&run.ID, &run.VLLMVersion, &run.TorchVersion, &run.Families, &run.Provider, &run.Region,
+			&run.Status, &run.Error, &run.RequestedBy, &run.CreatedAt, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan compatibility run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// matrix loads a run's results by family and check
+func (t *CompatTester) matrix(ctx context.Context, runID uuid.UUID) (map[string]map[string]CompatCheckResult, error) {
+	rows, err := t.db.Pool.Query(ctx, `
+		SELECT family, check_name, model_name, status, COALESCE(latency_ms, 0), COALESCE(detail, '')
+		FROM vllm_compat_results WHERE run_id = $1
+	`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load compatibility results: %w", err)
+	}
+	defer rows.Close()
+
+	matrix := make(map[string]map[string]CompatCheckResult)
+	for rows.Next() {
+		var family, check string
+		var result CompatCheckResult
+		if err := rows.Scan(&family, &check, &result.Model, &result.Status, &result.LatencyMs, &result.Detail); err != nil {
+			return nil, fmt.Errorf("failed to scan compatibility result: %w", err)
+		}
+		if matrix[family] == nil {
+			matrix[family] = make(map[string]CompatCheckResult)
+		}
+		matrix[family][check] = result
+	}
+	return matrix, rows.Err()
+}
+
+// Run checks every family of a queued run and records its verdict. A run
+// picked up again after a restart starts over.
+func (t *CompatTester) Run(ctx context.Context, id uuid.UUID) error {
+	run, err := t.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if run.Status != CompatStatusQueued && run.Status != CompatStatusRunning {
+		return nil
+	}
+
+	if _, err := t.db.Pool.Exec(ctx, `DELETE FROM vllm_compat_results WHERE run_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to reset compatibility results: %w", err)
+	}
+	if _, err := t.db.Pool.Exec(ctx, `
+		UPDATE vllm_compat_runs SET status = $2, started_at = NOW(), finished_at = NULL, error = NULL WHERE id = $1
+	`, id, CompatStatusRunning); err != nil {
+		return fmt.Errorf("failed to start compatibility run: %w", err)
+	}
+
+	models, err := t.familyModels(ctx, run.Families)
+	if err == nil && len(models) == 0 {
+		err = errors.New("no active models in the requested families")
+	}
+	if err != nil {
+		t.finish(ctx, id, CompatStatusFailed, err.Error())
+		return nil
+	}
+
+	t.logger.Info("starting vLLM compatibility run",
+		zap.String("run_id", id.String()),
+		zap.String("vllm_version", run.VLLMVersion),
+		zap.Int("families", len(models)),
+	)
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, compatConcurrency)
+	for _, m := range models {
+		wg.Add(1)
+		go func(m compatModel) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			t.checkFamily(ctx, run, m)
+		}(m)
+	}
+	wg.Wait()
+
+	matrix, err := t.matrix(ctx, id)
+	if err != nil {
+		t.finish(ctx, id, CompatStatusFailed, err.Error())
+		return nil
+	}
+	status := CompatVerdict(matrix)
+	t.finish(ctx, id, status, "")
+	t.logger.Info("vLLM compatibility run finished",
+		zap.String("run_id", id.String()),
+		zap.String("vllm_version", run.VLLMVersion),
+		zap.String("status", status),
+	)
+	return nil
+}
+
+// CompatVerdict is a run's outcome: passed when it checked something and
+// no check failed
+func CompatVerdict(matrix map[string]map[string]CompatCheckResult) string {
+	if len(matrix) == 0 {
+		return CompatStatusFailed
+	}
+	for _, checks := range matrix {
+		for _, result := range checks {
+			if result.Status == CompatStatusFailed {
+				return CompatStatusFailed
+			}
+		}
+	}
+	return CompatStatusPassed
+}
+
+// finish records a run's final status
+func (t *CompatTester) finish(ctx context.Context, id uuid.UUID, status, message string) {
+	if _, err := t.db.Pool.Exec(ctx, `
+		UPDATE vllm_compat_runs SET status = $2, error = NULLIF($3, ''), finished_at = NOW() WHERE id = $1
+	`, id, status, message); err != nil {
+		t.logger.Error("failed to finish compatibility run", zap.String("run_id", id.String()), zap.Error(err))
+	}
+}
+
+// familyModels picks the smallest active model of each family to check
+func (t *CompatTester) familyModels(ctx context.Context, families []string) ([]compatModel, error) {
+	rows, err := t.db.Pool.Query(ctx, `
+		SELECT DISTINCT ON (family) family, name, type, supports_tools, COALESCE(min_vllm_version, '')
+		FROM models
+		WHERE status = 'active' AND (cardinality($1::text[]) = 0 OR family = ANY($1))
+		ORDER BY family, vram_required_gb, name
+	`, families)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load models: %w", err)
+	}
+	defer rows.Close()
+
+	var models []compatModel
+	for rows.Next() {
+		var m compatModel
+		if err := rows.Scan(&m.Family, &m.Name, &m.Type, &m.SupportsTools, &m.MinVLLM); err != nil {
+			return nil, fmt.Errorf("failed to scan model: %w", err)
+		}
+		models = append(models, m)
+	}
+	return models, rows.Err()
+}
+
+// checkFamily launches a canary of the family's model, runs the smoke suite
+// against it and terminates it
+func (t *CompatTester) checkFamily(ctx context.Context, run *CompatRun, m compatModel) {
+	nodeID := uuid.New()
+	record := func(check string, result CompatCheckResult) {
+		result.Model = m.Name
+		if _, err := t.db.Pool.Exec(ctx, `
+			INSERT INTO vllm_compat_results (run_id, family, check_name, model_name, node_id, status, latency_ms, detail)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), NULLIF($8, ''))
+			ON CONFLICT (run_id, family, check_name) DO UPDATE
+			SET status = EXCLUDED.status, latency_ms = EXCLUDED.latency_ms, detail = EXCLUDED.detail
+		`, run.ID, m.Family, check, result.Model, nodeID, result.Status, result.LatencyMs, result.Detail); err != nil {
+			t.logger.Error("failed to record compatibility result",
+				zap.String("run_id", run.ID.String()),
+				zap.String("family", m.Family),
+				zap.String("check", check),
+				zap.Error(err),
+			)
+		}
+	}
+
+	// Releases older than the model needs fail without launching anything
+	if minVersion := MinVLLMVersion(m.Name, m.MinVLLM); minVersion != "" && CompareVersions(run.VLLMVersion, minVersion) < 0 {
+		record(CompatCheckLaunch, CompatCheckResult{Status: CompatStatusFailed, Detail: fmt.Sprintf("%s requires vLLM %s or newer", m.Name, minVersion)})
+		return
+	}
+
+	gpu, gpuCount, _ := NewModelConfigGenerator().GetOptimalConfig(m.Name)
+	config := NodeConfig{
+		NodeID:       nodeID.String(),
+		Provider:     run.Provider,
+		Region:       run.Region,
+		GPU:          gpu,
+		GPUCount:     gpuCount,
+		Model:        m.Name,
+		VLLMVersion:  run.VLLMVersion,
+		TorchVersion: run.TorchVersion,
+		UseSpot:      true,
+		Standby:      true,
+		RequestedAt:  time.Now(),
+	}
+
+	start := time.Now()
+	clusterName, err := t.orchestrator.LaunchNode(ctx, config)
+	if err != nil {
+		record(CompatCheckLaunch, CompatCheckResult{Status: CompatStatusFailed, Detail: err.Error()})
+		return
+	}
+	defer func() {
+		// Terminate even when the run's context is done
+		termCtx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		if err := t.orchestrator.TerminateNode(termCtx, clusterName); err != nil {
+			t.logger.Error("failed to terminate compatibility canary",
+				zap.String("run_id", run.ID.String()),
+				zap.String("cluster_name", clusterName),
+				zap.Error(err),
+			)
+		}
+	}()
+
+	endpoint, err := t.waitForEndpoint(ctx, nodeID)
+	if err != nil {
+		record(CompatCheckLaunch, CompatCheckResult{Status: CompatStatusFailed, Detail: err.Error()})
+		return
+	}
+	record(CompatCheckLaunch, CompatCheckResult{Status: CompatStatusPassed, LatencyMs: time.Since(start).Milliseconds()})
+
+	for check, result := range t.smokeSuite(ctx, endpoint, m) {
+		record(check, result)
+	}
+}
+
+// waitForEndpoint waits for a canary to register with vLLM serving and
+// returns its endpoint
+func (t *CompatTester) waitForEndpoint(ctx context.Context, nodeID uuid.UUID) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.readyTimeout)
+	defer cancel()
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		var status, endpoint string
+		err := t.db.Pool.QueryRow(ctx, `
+			SELECT status, COALESCE(endpoint_url, '') FROM nodes WHERE id = $1
+		`, nodeID).Scan(&status, &endpoint)
+		switch {
+		case err == nil && status == "active" && endpoint != "":
+			return strings.TrimRight(endpoint, "/"), nil
+		case err == nil && (status == "failed" || status == "dead" || status == "terminated"):
+			return "", fmt.Errorf("canary node is %s", status)
+		case err != nil && !errors.Is(err, pgx.ErrNoRows) && ctx.Err() == nil:
+			t.logger.Warn("failed to check canary node", zap.String("node_id", nodeID.String()), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("canary node not serving after %s", t.readyTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// smokeSuite runs the checks that apply to the model against a node.
+// Embedding models are only checked for embeddings, and function calling
+// only for models with tools enabled; other checks are skipped.
+func (t *CompatTester) smokeSuite(ctx context.Context, endpoint string, m compatModel) map[string]CompatCheckResult {
+	results := map[string]CompatCheckResult{
+		CompatCheckGeneration:      {Status: CompatStatusSkipped},
+		CompatCheckStreaming:       {Status: CompatStatusSkipped},
+		CompatCheckFunctionCalling: {Status: CompatStatusSkipped},
+		CompatCheckEmbeddings:      {Status: CompatStatusSkipped},
+	}
+
+	if m.Type == "embedding" {
+		results[CompatCheckEmbeddings] = t.timed(func() error { return t.checkEmbeddings(ctx, endpoint, m) })
+		return results
+	}
+	results[CompatCheckGeneration] = t.timed(func() error { return t.checkGeneration(ctx, endpoint, m) })
+	results[CompatCheckStreaming] = t.timed(func() error { return t.checkStreaming(ctx, endpoint, m) })
+	if m.SupportsTools {
+		results[CompatCheckFunctionCalling] = t.timed(func() error { return t.checkFunctionCalling(ctx, endpoint, m) })
+	}
+	return results
+}
+
+// timed runs a check and turns its outcome into a result
+func (t *CompatTester) timed(check func() error) CompatCheckResult {
+	start := time.Now()
+	err := check()
+	result := CompatCheckResult{Status: CompatStatusPassed, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status, result.Detail = CompatStatusFailed, err.Error()
+	}
+	return result
+}
+
+// compatPrompt is the smoke prompt for chat models
+var compatPrompt = []map[string]string{{"role": "user", "content": "Reply with the word ready."}}
+
+// generationRequest is a short generation request for the model's type
+func generationRequest(m compatModel, stream bool) (string, map[string]interface{}) {
+	if m.Type == "completion" {
+		return "/v1/completions", map[string]interface{}{
+			"model": m.Name, "prompt": "The capital of France is", "max_tokens": compatMaxTokens, "stream": stream,
+		}
+	}
+	return "/v1/chat/completions", map[string]interface{}{
+		"model": m.Name, "messages": compatPrompt, "max_tokens": compatMaxTokens, "stream": stream,
+	}
+}
+
+// checkGeneration expects generated text
+func (t *CompatTester) checkGeneration(ctx context.Context, endpoint string, m compatModel) error {
+	path, body := generationRequest(m, false)
+	var resp struct {
+		Choices []struct {
+			Text    string `json:"text"`
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := t.post(ctx, endpoint+path, body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Text+resp.Choices[0].Message.Content == "" {
+		return errors.New("response has no generated text")
+	}
+	return nil
+}
+
+// checkStreaming expects streamed chunks of text ending with [DONE]
+func (t *CompatTester) checkStreaming(ctx context.Context, endpoint string, m compatModel) error {
+	path, body := generationRequest(m, true)
+	resp, err := t.send(ctx, endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var chunks int
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			if chunks == 0 {
+				return errors.New("stream ended without chunks")
+			}
+			return nil
+		}
+		var chunk struct {
+			Choices []json.RawMessage `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("invalid stream chunk: %w", err)
+		}
+		chunks++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream interrupted: %w", err)
+	}
+	return errors.New("stream ended without [DONE]")
+}
+
+// checkFunctionCalling expects a call of the named tool with JSON arguments
+func (t *CompatTester) checkFunctionCalling(ctx context.Context, endpoint string, m compatModel) error {
+	body := map[string]interface{}{
+		"model":      m.Name,
+		"messages":   []map[string]string{{"role": "user", "content": "What is the weather in Paris?"}},
+		"max_tokens": 64,
+		"tools": []map[string]interface{}{{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        "get_weather",
+				"description": "Get the current weather in a city",
+				"parameters": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"city": map[string]string{"type": "string"}},
+					"required":   []string{"city"},
+				},
+			},
+		}},
+		"tool_choice": map[string]interface{}{"type": "function", "function": map[string]string{"name": "get_weather"}},
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				ToolCalls []struct {
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := t.post(ctx, endpoint+"/v1/chat/completions", body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Choices) == 0 || len(resp.Choices[0].Message.ToolCalls) == 0 {
+		return errors.New("response has no tool calls")
+	}
+	call := resp.Choices[0].Message.ToolCalls[0].Function
+	if call.Name != "get_weather" {
+		return fmt.Errorf("called %q instead of get_weather", call.Name)
+	}
+	if !json.Valid([]byte(call.Arguments)) {
+		return errors.New("tool call arguments are not valid JSON")
+	}
+	return nil
+}
+
+// checkEmbeddings expects a non-empty embedding
+func (t *CompatTester) checkEmbeddings(ctx context.Context, endpoint string, m compatModel) error {
+	var resp struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := t.post(ctx, endpoint+"/v1/embeddings", map[string]interface{}{"model": m.Name, "input": "ready"}, &resp); err != nil {
+		return err
+	}
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return errors.New("response has no embedding")
+	}
+	return nil
+}
+
+// post sends a request and decodes the JSON response into out
+func (t *CompatTester) post(ctx context.Context, url string, body, out interface{}) error {
+	resp, err := t.send(ctx, url, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// send posts a JSON request, failing on non-200 responses
+func (t *CompatTester) send(ctx context.Context, url string, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+// CompatGate is whether a vLLM release may be adopted, from its latest
+// finished run
+type CompatGate struct {
+	VLLMVersion string     `json:"vllm_version"`
+	Allowed     bool       `json:"allowed"`
+	Reason      string     `json:"reason,omitempty"`
+	Run         *CompatRun `json:"run,omitempty"`
+}
+
+// Gate reports whether a vLLM release passed its latest finished run. With
+// a family, only that family's checks need to have passed.
+func (t *CompatTester) Gate(ctx context.Context, vllmVersion, family string) (*CompatGate, error) {
+	gate := &CompatGate{VLLMVersion: vllmVersion}
+	var id uuid.UUID
+	err := t.db.Pool.QueryRow(ctx, `
+		SELECT id FROM vllm_compat_runs
+		WHERE vllm_version = $1 AND status IN ('passed', 'failed')
+		ORDER BY finished_at DESC
+		LIMIT 1
+	`, vllmVersion).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		gate.Reason = fmt.Sprintf("vLLM %s has no finished compatibility run", vllmVersion)
+		return gate, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load compatibility run: %w", err)
+	}
+	if gate.Run, err = t.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	gate.Allowed, gate.Reason = gateVerdict(gate.Run, family)
+	return gate, nil
+}
+
+// gateVerdict decides a gate from a finished run
+func gateVerdict(run *CompatRun, family string) (bool, string) {
+	if family == "" {
+		if run.Status != CompatStatusPassed {
+			return false, fmt.Sprintf("vLLM %s failed its latest compatibility run", run.VLLMVersion)
+		}
+		return true, ""
+	}
+
+	checks, ok := run.Matrix[family]
+	if !ok {
+		return false, fmt.Sprintf("the latest compatibility run of vLLM %s did not check the %s family", run.VLLMVersion, family)
+	}
+	if CompatVerdict(map[string]map[string]CompatCheckResult{family: checks}) != CompatStatusPassed {
+		return false, fmt.Sprintf("the %s family failed the latest compatibility run of vLLM %s", family, run.VLLMVersion)
+	}
+	return true, ""
+}
+
+// deref returns the string s points to, or empty
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
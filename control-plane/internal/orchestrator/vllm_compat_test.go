@@ -0,0 +1,154 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompatVerdict(t *testing.T) {
+	passed := CompatCheckResult{Status: CompatStatusPassed}
+	skipped := CompatCheckResult{Status: CompatStatusSkipped}
+	failed := CompatCheckResult{Status: CompatStatusFailed}
+
+	cases := []struct {
+		name   string
+		matrix map[string]map[string]CompatCheckResult
+		want   string
+	}{
+		{"nothing checked", nil, CompatStatusFailed},
+		{"all passed or skipped", map[string]map[string]CompatCheckResult{
+			"llama": {CompatCheckGeneration: passed, CompatCheckEmbeddings: skipped},
+			"bge":   {CompatCheckEmbeddings: passed, CompatCheckGeneration: skipped},
+		}, CompatStatusPassed},
+		{"one failure fails the run", map[string]map[string]CompatCheckResult{
+			"llama": {CompatCheckGeneration: passed},
+			"qwen":  {CompatCheckFunctionCalling: failed},
+		}, CompatStatusFailed},
+	}
+	for _, tc := range cases {
+		if got := CompatVerdict(tc.matrix); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestGateVerdict(t *testing.T) {
+	run := &CompatRun{
+		VLLMVersion: "0.8.5",
+		Status:      CompatStatusFailed,
+		Matrix: map[string]map[string]CompatCheckResult{
+			"llama": {CompatCheckGeneration: {Status: CompatStatusPassed}},
+			"qwen":  {CompatCheckStreaming: {Status: CompatStatusFailed}},
+		},
+	}
+
+	cases := []struct {
+		family string
+		want   bool
+	}{
+		{"", false},
+		{"llama", true},
+		{"qwen", false},
+		{"mistral", false},
+	}
+	for _, tc := range cases {
+		got, reason := gateVerdict(run, tc.family)
+		if got != tc.want {
+			t.Errorf("family %q: got %v (%s), want %v", tc.family, got, reason, tc.want)
+		}
+		if !got && reason == "" {
+			t.Errorf("family %q: refused without a reason", tc.family)
+		}
+	}
+
+	run.Status = CompatStatusPassed
+	if ok, reason := gateVerdict(run, ""); !ok {
+		t.Errorf("passed run refused: %s", reason)
+	}
+}
+
+// fakeVLLM answers the smoke suite's requests like vLLM, without tool calls
+// when toolsBroken is set
+func fakeVLLM(t *testing.T, toolsBroken bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		switch {
+		case r.URL.Path == "/v1/embeddings":
+			fmt.Fprint(w, `{"data":[{"embedding":[0.1,0.2]}]}`)
+		case req["stream"] == true:
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ready\"}}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		case req["tools"] != nil && !toolsBroken:
+			fmt.Fprint(w, `{"choices":[{"message":{"tool_calls":[{"function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]}}]}`)
+		default:
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"ready"}}]}`)
+		}
+	}))
+}
+
+func TestSmokeSuite(t *testing.T) {
+	tester := &CompatTester{httpClient: http.DefaultClient}
+	ctx := context.Background()
+
+	cases := []struct {
+		name        string
+		model       compatModel
+		toolsBroken bool
+		want        map[string]string
+	}{
+		{"chat model with tools", compatModel{Name: "qwen", Type: "chat", SupportsTools: true}, false, map[string]string{
+			CompatCheckGeneration:      CompatStatusPassed,
+			CompatCheckStreaming:       CompatStatusPassed,
+			CompatCheckFunctionCalling: CompatStatusPassed,
+			CompatCheckEmbeddings:      CompatStatusSkipped,
+		}},
+		{"broken tool calling", compatModel{Name: "qwen", Type: "chat", SupportsTools: true}, true, map[string]string{
+			CompatCheckGeneration:      CompatStatusPassed,
+			CompatCheckStreaming:       CompatStatusPassed,
+			CompatCheckFunctionCalling: CompatStatusFailed,
+			CompatCheckEmbeddings:      CompatStatusSkipped,
+		}},
+		{"chat model without tools", compatModel{Name: "llama", Type: "chat"}, true, map[string]string{
+			CompatCheckGeneration:      CompatStatusPassed,
+			CompatCheckStreaming:       CompatStatusPassed,
+			CompatCheckFunctionCalling: CompatStatusSkipped,
+			CompatCheckEmbeddings:      CompatStatusSkipped,
+		}},
+		{"embedding model", compatModel{Name: "bge", Type: "embedding"}, false, map[string]string{
+			CompatCheckGeneration:      CompatStatusSkipped,
+			CompatCheckStreaming:       CompatStatusSkipped,
+			CompatCheckFunctionCalling: CompatStatusSkipped,
+			CompatCheckEmbeddings:      CompatStatusPassed,
+		}},
+	}
+	for _, tc := range cases {
+		server := fakeVLLM(t, tc.toolsBroken)
+		results := tester.smokeSuite(ctx, server.URL, tc.model)
+		server.Close()
+		for check, want := range tc.want {
+			if got := results[check]; got.Status != want {
+				t.Errorf("%s: %s got %s (%s), want %s", tc.name, check, got.Status, got.Detail, want)
+			}
+		}
+	}
+}
+
+func TestSmokeSuiteFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model architecture not supported", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	tester := &CompatTester{httpClient: http.DefaultClient}
+	results := tester.smokeSuite(context.Background(), server.URL, compatModel{Name: "llama", Type: "chat"})
+	if got := results[CompatCheckGeneration]; got.Status != CompatStatusFailed || got.Detail == "" {
+		t.Errorf("generation got %+v, want a failure with detail", got)
+	}
+}
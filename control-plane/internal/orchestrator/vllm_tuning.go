@@ -0,0 +1,112 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+	"go.uber.org/zap"
+)
+
+// knownGPUMemoryGB is the per-GPU memory of common accelerators, keyed by
+// normalizeGPU name. It is the last resort when no node of the type has
+// reported its memory and the catalog doesn't list it.
+var knownGPUMemoryGB = map[string]int{
+	"T4":       16,
+	"V100":     16,
+	"A10":      24,
+	"A10G":     24,
+	"L4":       24,
+	"L40S":     48,
+	"A100":     40,
+	"A10080GB": 80,
+	"H100":     80,
+	"H200":     141,
+}
+
+// gpuMemoryPerGPU returns the memory of one of the launch's GPUs: what
+// nodes of the GPU type last reported, else the catalog's instance types,
+// else the well-known size. It returns 0 when unknown.
+func (o *SkyPilotOrchestrator) gpuMemoryPerGPU(ctx context.Context, config *NodeConfig, catalog *launchCatalog) int {
+	if o.registry != nil {
+		reported, err := o.registry.ReportedVRAMPerGPU(ctx, config.GPU)
+		if err != nil {
+			o.logger.Warn("failed to read reported GPU memory", zap.Error(err), zap.String("gpu", config.GPU))
+		} else if reported > 0 {
+			return reported
+		}
+	}
+
+	if catalog != nil {
+		want := normalizeGPU(config.GPU)
+		for _, offer := range catalog.Offers {
+			matches := offer.InstanceType == config.GPU ||
+				(normalizeGPU(offer.GPUModel) == want && offer.GPUCount == config.GPUCount)
+			if matches && offer.GPUCount > 0 && offer.GPUMemoryGB > 0 {
+				return int(offer.GPUMemoryGB) / offer.GPUCount
+			}
+		}
+	}
+
+	return knownGPUMemoryGB[normalizeGPU(config.GPU)]
+}
+
+// tuneVLLM fills in the vLLM memory settings the launch didn't set, from
+// the model's size and the GPUs' memory
+func (o *SkyPilotOrchestrator) tuneVLLM(ctx context.Context, config *NodeConfig, catalog *launchCatalog) {
+	if config.Runtime != "" && config.Runtime != DefaultRuntime {
+		return
+	}
+
+	var modelGB float64
+	var contextLength int
+	if catalog != nil {
+		modelGB, contextLength = catalog.ModelGB, catalog.ContextLength
+	}
+	vram := o.gpuMemoryPerGPU(ctx, config, catalog)
+	config.tunedVRAMGB = vram
+	if len(config.ColocatedModels) > 0 {
+		config.tuneColocated(catalog, vram)
+	} else {
+		config.applyTuning(nodes.TuneVLLM(modelGB, vram, config.GPUCount, contextLength))
+	}
+
+	tuning := config.vllmTuning()
+	message := "vLLM memory settings: model size or GPU memory unknown, using defaults"
+	if vram > 0 && modelGB > 0 {
+		message = fmt.Sprintf("vLLM memory settings tuned for %.0f GB of weights on %d GB GPUs", modelGB, vram)
+	}
+	o.logStore.LogInfo(ctx, config.NodeID, PhaseQueued,
+		fmt.Sprintf("%s: gpu-memory-utilization %.2f, max-model-len %d, max-num-seqs %d",
+			message, tuning.GPUMemoryUtilization, tuning.MaxModelLen, tuning.MaxNumSeqs), 0)
+	for _, m := range config.ColocatedModels {
+		o.logStore.LogInfo(ctx, config.NodeID, PhaseQueued,
+			fmt.Sprintf("vLLM memory settings for colocated %s: gpu-memory-utilization %.2f, max-model-len %d, max-num-seqs %d",
+				m.Model, m.GPUMemoryUtilization, m.MaxModelLen, m.MaxNumSeqs), 0)
+	}
+}
+
+// applyTuning sets the vLLM memory settings that are still zero
+func (c *NodeConfig) applyTuning(t nodes.VLLMTuning) {
+	if c.GPUMemoryUtilization == 0 {
+		c.GPUMemoryUtilization = t.GPUMemoryUtilization
+	}
+	if c.MaxModelLen == 0 {
+		c.MaxModelLen = t.MaxModelLen
+	}
+	if c.MaxNumSeqs == 0 {
+		c.MaxNumSeqs = t.MaxNumSeqs
+	}
+}
+
+// vllmTuning is the vLLM memory settings the node runs with, including
+// any overridden by the launch's extra vLLM arguments
+func (c *NodeConfig) vllmTuning() nodes.VLLMTuning {
+	t := nodes.VLLMTuning{
+		GPUMemoryUtilization: c.GPUMemoryUtilization,
+		MaxModelLen:          c.MaxModelLen,
+		MaxNumSeqs:           c.MaxNumSeqs,
+		VRAMPerGPUGB:         c.tunedVRAMGB,
+	}
+	return t.WithArgs(nodes.SplitArgs(c.VLLMArgs))
+}
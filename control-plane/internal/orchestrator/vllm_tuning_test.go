@@ -0,0 +1,48 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crosslogic/control-plane/internal/nodes"
+)
+
+func TestGPUMemoryPerGPU(t *testing.T) {
+	o := &SkyPilotOrchestrator{}
+	catalog := &launchCatalog{Offers: []catalogOffer{
+		{InstanceType: "g6e.xlarge", GPUModel: "NVIDIA L40S", GPUCount: 1, GPUMemoryGB: 46},
+		{InstanceType: "p4de.24xlarge", GPUModel: "A100", GPUCount: 8, GPUMemoryGB: 640},
+	}}
+
+	tests := []struct {
+		name    string
+		config  NodeConfig
+		catalog *launchCatalog
+		want    int
+	}{
+		{"catalog by GPU model", NodeConfig{GPU: "L40S", GPUCount: 1}, catalog, 46},
+		{"catalog by instance type", NodeConfig{GPU: "p4de.24xlarge", GPUCount: 8}, catalog, 80},
+		{"known size without catalog", NodeConfig{GPU: "A10G", GPUCount: 1}, nil, 24},
+		{"unknown GPU", NodeConfig{GPU: "MI300X", GPUCount: 1}, catalog, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := o.gpuMemoryPerGPU(context.Background(), &tt.config, tt.catalog); got != tt.want {
+				t.Errorf("gpuMemoryPerGPU() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeConfigVLLMTuning(t *testing.T) {
+	config := NodeConfig{MaxModelLen: 16384, VLLMArgs: "--max-num-seqs 64"}
+	config.applyTuning(nodes.VLLMTuning{GPUMemoryUtilization: 0.92, MaxModelLen: 4096, MaxNumSeqs: 24})
+
+	if config.GPUMemoryUtilization != 0.92 || config.MaxModelLen != 16384 || config.MaxNumSeqs != 24 {
+		t.Errorf("applyTuning() set %.2f/%d/%d, want 0.92/16384/24",
+			config.GPUMemoryUtilization, config.MaxModelLen, config.MaxNumSeqs)
+	}
+	if got := config.vllmTuning(); got.MaxNumSeqs != 64 {
+		t.Errorf("vllmTuning().MaxNumSeqs = %d, want the --max-num-seqs override 64", got.MaxNumSeqs)
+	}
+}
@@ -0,0 +1,89 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/crosslogic/control-plane/internal/skypilot"
+	"go.uber.org/zap"
+)
+
+// Per-tenant SkyPilot workspaces (API Server mode).
+//
+// By default every cluster launched through the API server lives in a single
+// workspace under the platform service account. With tenant workspaces
+// enabled, each tenant's clusters are placed in a workspace named
+// "<prefix><tenant_id>" so they are isolated in SkyPilot's own state and
+// dashboards. Requests are scoped by attaching the workspace to the context
+// (skypilot.WithWorkspace), which the client sends as a header on every call.
+
+// tenantWorkspace returns the SkyPilot workspace for a tenant, or "" when
+// tenant workspaces are disabled (use the API server's default).
+func (o *SkyPilotOrchestrator) tenantWorkspace(tenantID string) string {
+	if !o.tenantWorkspaces {
+		return ""
+	}
+	if tenantID == "" {
+		return o.defaultWorkspace
+	}
+	return o.workspacePrefix + strings.ToLower(tenantID)
+}
+
+// workspaceContext scopes ctx to the tenant's SkyPilot workspace
+func (o *SkyPilotOrchestrator) workspaceContext(ctx context.Context, tenantID string) context.Context {
+	return skypilot.WithWorkspace(ctx, o.tenantWorkspace(tenantID))
+}
+
+// clusterWorkspaceContext scopes ctx to the workspace of the tenant that owns
+// the cluster. Clusters without an owning tenant use the default workspace.
+func (o *SkyPilotOrchestrator) clusterWorkspaceContext(ctx context.Context, clusterName string) context.Context {
+	if !o.tenantWorkspaces {
+		return ctx
+	}
+
+	var tenantID *string
+	err := o.db.Pool.QueryRow(ctx, `
+		SELECT tenant_id::text FROM nodes WHERE cluster_name = $1
+	`, clusterName).Scan(&tenantID)
+	if err != nil {
+		o.logger.Debug("no owning tenant found for cluster, using default workspace",
+			zap.String("cluster_name", clusterName),
+			zap.Error(err),
+		)
+		return skypilot.WithWorkspace(ctx, o.defaultWorkspace)
+	}
+
+	if tenantID == nil {
+		return skypilot.WithWorkspace(ctx, o.defaultWorkspace)
+	}
+	return o.workspaceContext(ctx, *tenantID)
+}
+
+// activeWorkspaces returns every workspace that may hold live clusters: the
+// default workspace plus one per tenant with non-terminated nodes.
+func (o *SkyPilotOrchestrator) activeWorkspaces(ctx context.Context) ([]string, error) {
+	if !o.tenantWorkspaces {
+		return []string{""}, nil
+	}
+
+	rows, err := o.db.Pool.Query(ctx, `
+		SELECT DISTINCT tenant_id::text
+		FROM nodes
+		WHERE tenant_id IS NOT NULL AND status NOT IN ('terminated', 'deleted')
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := []string{o.defaultWorkspace}
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			continue
+		}
+		workspaces = append(workspaces, o.tenantWorkspace(tenantID))
+	}
+
+	return workspaces, rows.Err()
+}
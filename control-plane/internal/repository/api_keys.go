@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/models"
+	"github.com/google/uuid"
+)
+
+// APIKeyRepository reads API keys without their hashes
+type APIKeyRepository struct {
+	q database.Querier
+}
+
+// APIKeyFilter narrows a tenant's key listing
+type APIKeyFilter struct {
+	Status string
+}
+
+func (f APIKeyFilter) where(args *database.Args, tenantID uuid.UUID) *database.Where {
+	where := database.NewWhere(args).Eq("tenant_id", tenantID)
+	if f.Status != "" {
+		where.Eq("status", f.Status)
+	}
+	return where
+}
+
+// ListForTenant returns a page of a tenant's keys, newest first, and the
+// total matching count
+func (r *APIKeyRepository) ListForTenant(ctx context.Context, tenantID uuid.UUID, f APIKeyFilter, page Page) ([]models.APIKey, int, error) {
+	args := database.NewArgs()
+	query := `
+		SELECT
+			id, key_prefix, tenant_id, name, role, status,
+			rate_limit_requests_per_min, concurrency_limit,
+			created_at, last_used_at, expires_at
+		FROM api_keys` + f.where(args, tenantID).String() + `
+		ORDER BY created_at DESC
+		LIMIT ` + args.Add(page.Limit) + ` OFFSET ` + args.Add(page.Offset)
+
+	rows, err := r.q.Query(ctx, query, args.Values()...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(&k.ID, &k.KeyPrefix, &k.TenantID, &k.Name, &k.Role, &k.Status,
+			&k.RateLimitRequestsPerMin, &k.ConcurrencyLimit,
+			&k.CreatedAt, &k.LastUsedAt, &k.ExpiresAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read API keys: %w", err)
+	}
+
+	countArgs := database.NewArgs()
+	var total int
+	if err := r.q.QueryRow(ctx, "SELECT COUNT(*) FROM api_keys"+f.where(countArgs, tenantID).String(), countArgs.Values()...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+
+	return keys, total, nil
+}
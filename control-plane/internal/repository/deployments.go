@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+)
+
+// DeploymentRepository reads managed model deployments
+type DeploymentRepository struct {
+	q database.Querier
+}
+
+// DeploymentFilter narrows a deployment listing
+type DeploymentFilter struct {
+	ModelName string
+	Status    string
+}
+
+// DeploymentSummary is a deployment row with its model name resolved
+type DeploymentSummary struct {
+	ID              uuid.UUID
+	Name            string
+	ModelName       string
+	Status          string
+	CurrentReplicas int
+	MinReplicas     int
+	MaxReplicas     int
+	Strategy        string
+	CreatedAt       time.Time
+}
+
+// List returns deployments, newest first
+func (r *DeploymentRepository) List(ctx context.Context, f DeploymentFilter) ([]DeploymentSummary, error) {
+	args := database.NewArgs()
+	where := database.NewWhere(args)
+	if f.ModelName != "" {
+		where.Eq("m.name", f.ModelName)
+	}
+	if f.Status != "" {
+		where.Eq("d.status", f.Status)
+	}
+
+	rows, err := r.q.Query(ctx, `
+		SELECT d.id, d.name, m.name, d.status,
+		       d.current_replicas, d.min_replicas, d.max_replicas,
+		       d.strategy, d.created_at
+		FROM deployments d
+		INNER JOIN models m ON m.id = d.model_id`+where.String()+`
+		ORDER BY d.created_at DESC`, args.Values()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deployments: %w", err)
+	}
+	defer rows.Close()
+
+	var deployments []DeploymentSummary
+	for rows.Next() {
+		var d DeploymentSummary
+		if err := rows.Scan(&d.ID, &d.Name, &d.ModelName, &d.Status, &d.CurrentReplicas,
+			&d.MinReplicas, &d.MaxReplicas, &d.Strategy, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan deployment: %w", err)
+		}
+		deployments = append(deployments, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read deployments: %w", err)
+	}
+
+	return deployments, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/crosslogic/control-plane/pkg/models"
+)
+
+// ModelSortColumns lists the columns a model listing may be ordered by
+var ModelSortColumns = map[string]bool{
+	"name":             true,
+	"family":           true,
+	"created_at":       true,
+	"updated_at":       true,
+	"vram_required_gb": true,
+}
+
+// ModelRepository reads the model catalog
+type ModelRepository struct {
+	q database.Querier
+}
+
+// ModelFilter narrows and orders a model listing. Zero values are ignored.
+type ModelFilter struct {
+	Family    string
+	Type      string
+	Status    string
+	MinVRAMGB int
+	MaxVRAMGB int
+	// Search matches model names case-insensitively
+	Search string
+	// SortBy must be one of ModelSortColumns; defaults to name
+	SortBy     string
+	Descending bool
+}
+
+func (f ModelFilter) where(args *database.Args) *database.Where {
+	where := database.NewWhere(args)
+	if f.Family != "" {
+		where.Eq("family", f.Family)
+	}
+	if f.Type != "" {
+		where.Eq("type", f.Type)
+	}
+	if f.Status != "" {
+		where.Eq("status", f.Status)
+	}
+	if f.MinVRAMGB > 0 {
+		where.Raw("vram_required_gb >= " + args.Add(f.MinVRAMGB))
+	}
+	if f.MaxVRAMGB > 0 {
+		where.Raw("vram_required_gb <= " + args.Add(f.MaxVRAMGB))
+	}
+	if f.Search != "" {
+		where.Raw("name ILIKE " + args.Add("%"+f.Search+"%"))
+	}
+	return where
+}
+
+func (f ModelFilter) orderBy() (string, error) {
+	column := f.SortBy
+	if column == "" {
+		column = "name"
+	}
+	if !ModelSortColumns[column] {
+		return "", fmt.Errorf("invalid sort column %q", column)
+	}
+	if f.Descending {
+		return column + " DESC", nil
+	}
+	return column + " ASC", nil
+}
+
+// List returns a page of models and the total matching count
+func (r *ModelRepository) List(ctx context.Context, f ModelFilter, page Page) ([]models.Model, int, error) {
+	orderBy, err := f.orderBy()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	countArgs := database.NewArgs()
+	var total int
+	if err := r.q.QueryRow(ctx, "SELECT COUNT(*) FROM models"+f.where(countArgs).String(), countArgs.Values()...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count models: %w", err)
+	}
+
+	args := database.NewArgs()
+	var query strings.Builder
+	query.WriteString(`
+		SELECT id, name, family, size, type, context_length,
+		       vram_required_gb, price_input_per_million, price_output_per_million,
+		       tokens_per_second_capacity, status, supports_tools, supports_vision, supports_json_mode,
+		       supports_guided_decoding, max_output_tokens, price_per_audio_minute::float8, price_per_image::float8, COALESCE(metadata::text, ''), created_at, updated_at
+		FROM models`)
+	query.WriteString(f.where(args).String())
+	query.WriteString(" ORDER BY " + orderBy)
+	query.WriteString(" LIMIT " + args.Add(page.Limit) + " OFFSET " + args.Add(page.Offset))
+
+	rows, err := r.q.Query(ctx, query.String(), args.Values()...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query models: %w", err)
+	}
+	defer rows.Close()
+
+	var list []models.Model
+	for rows.Next() {
+		var m models.Model
+		if err := rows.Scan(
+			&m.ID, &m.Name, &m.Family, &m.Size, &m.Type, &m.ContextLength,
+			&m.VRAMRequiredGB, &m.PriceInputPerMillion, &m.PriceOutputPerMillion,
+			&m.TokensPerSecondCapacity, &m.Status, &m.SupportsTools, &m.SupportsVision, &m.SupportsJSONMode,
+			&m.SupportsGuidedDecoding, &m.MaxOutputTokens, &m.PricePerAudioMinute, &m.PricePerImage, &m.Metadata, &m.CreatedAt, &m.UpdatedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan model: %w", err)
+		}
+		list = append(list, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read models: %w", err)
+	}
+
+	return list, total, nil
+}
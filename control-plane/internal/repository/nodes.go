@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+)
+
+// NodeRepository reads node rows written by the internal/nodes registry
+type NodeRepository struct {
+	q database.Querier
+}
+
+// NodeSummary is the node view shown alongside deployments
+type NodeSummary struct {
+	ClusterName string
+	Status      string
+	HealthScore float64
+}
+
+// ListByModel returns up to limit nodes serving a model, newest first
+func (r *NodeRepository) ListByModel(ctx context.Context, modelName string, limit int) ([]NodeSummary, error) {
+	rows, err := r.q.Query(ctx, `
+		SELECT COALESCE(n.cluster_name, ''), n.status, COALESCE(n.health_score, 0)
+		FROM nodes n
+		WHERE n.model_name = $1
+		ORDER BY n.created_at DESC
+		LIMIT $2
+	`, modelName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []NodeSummary
+	for rows.Next() {
+		var n NodeSummary
+		if err := rows.Scan(&n.ClusterName, &n.Status, &n.HealthScore); err != nil {
+			return nil, fmt.Errorf("failed to scan node: %w", err)
+		}
+		nodes = append(nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read nodes: %w", err)
+	}
+
+	return nodes, nil
+}
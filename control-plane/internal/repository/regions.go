@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+)
+
+// ErrNoChanges is returned when an update carries no fields
+var ErrNoChanges = errors.New("no fields to update")
+
+// RegionRepository writes serving regions
+type RegionRepository struct {
+	q database.Querier
+}
+
+// RegionUpdate holds the region fields to change; nil fields are left alone
+type RegionUpdate struct {
+	Name           *string
+	Status         *string
+	CostMultiplier *float64
+	// Metadata is a JSON document
+	Metadata []byte
+}
+
+func (u RegionUpdate) set(args *database.Args) *database.UpdateSet {
+	set := database.NewUpdateSet(args)
+	if u.Name != nil {
+		set.Set("name", *u.Name)
+	}
+	if u.Status != nil {
+		set.Set("status", *u.Status)
+	}
+	if u.CostMultiplier != nil {
+		set.Set("cost_multiplier", *u.CostMultiplier)
+	}
+	if u.Metadata != nil {
+		set.Set("metadata", u.Metadata)
+	}
+	return set
+}
+
+// Update applies the non-nil fields of u to a region
+func (r *RegionRepository) Update(ctx context.Context, id uuid.UUID, u RegionUpdate) error {
+	args := database.NewArgs()
+	set := u.set(args)
+	if set.Empty() {
+		return ErrNoChanges
+	}
+	set.Raw("updated_at = NOW()")
+
+	tag, err := r.q.Exec(ctx, "UPDATE regions SET "+set.String()+" WHERE id = "+args.Add(id), args.Values()...)
+	if err != nil {
+		return fmt.Errorf("failed to update region: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
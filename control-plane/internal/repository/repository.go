@@ -0,0 +1,44 @@
+// Package repository holds typed data access for the gateway's admin and
+// tenant APIs. Queries with optional filters are assembled with the
+// placeholder-safe builders in pkg/database so handlers never number
+// parameters by hand. Node registration lives in internal/nodes.
+package repository
+
+import (
+	"errors"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+)
+
+// ErrNotFound is returned when a row addressed by ID does not exist
+var ErrNotFound = errors.New("not found")
+
+// Page bounds a list query
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// Store groups the repositories backed by one database handle
+type Store struct {
+	Tenants     *TenantRepository
+	APIKeys     *APIKeyRepository
+	Models      *ModelRepository
+	Deployments *DeploymentRepository
+	Nodes       *NodeRepository
+	Regions     *RegionRepository
+	Usage       *UsageRepository
+}
+
+// NewStore creates repositories sharing q, which may be a pool or a transaction
+func NewStore(q database.Querier) *Store {
+	return &Store{
+		Tenants:     &TenantRepository{q: q},
+		APIKeys:     &APIKeyRepository{q: q},
+		Models:      &ModelRepository{q: q},
+		Deployments: &DeploymentRepository{q: q},
+		Nodes:       &NodeRepository{q: q},
+		Regions:     &RegionRepository{q: q},
+		Usage:       &UsageRepository{q: q},
+	}
+}
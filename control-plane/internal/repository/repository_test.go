@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var errStop = errors.New("stop")
+
+type recordedQuery struct {
+	sql  string
+	args []any
+}
+
+// recordingQuerier captures queries and fails them so tests can inspect the
+// generated SQL without a database
+type recordingQuerier struct {
+	queries []recordedQuery
+	tag     pgconn.CommandTag
+}
+
+func (q *recordingQuerier) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	q.queries = append(q.queries, recordedQuery{sql, args})
+	return q.tag, nil
+}
+
+func (q *recordingQuerier) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	q.queries = append(q.queries, recordedQuery{sql, args})
+	return nil, errStop
+}
+
+func (q *recordingQuerier) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	q.queries = append(q.queries, recordedQuery{sql, args})
+	return errRow{}
+}
+
+type errRow struct{}
+
+func (errRow) Scan(...any) error { return errStop }
+
+func TestUsageBreakdownPlaceholders(t *testing.T) {
+	q := &recordingQuerier{}
+	model, key, region := uuid.New(), uuid.New(), uuid.New()
+	filter := UsageFilter{
+		TenantID: uuid.New(),
+		Start:    time.Unix(0, 0),
+		End:      time.Unix(3600, 0),
+		ModelID:  &model,
+		APIKeyID: &key,
+		RegionID: &region,
+	}
+
+	if _, err := NewStore(q).Usage.Breakdown(context.Background(), UsageByRegion, filter); !errors.Is(err, errStop) {
+		t.Fatalf("Breakdown() error = %v", err)
+	}
+
+	got := q.queries[0]
+	for _, want := range []string{"ur.tenant_id = $1", "ur.timestamp >= $2", "ur.timestamp <= $3", "ur.model_id = $4", "ur.api_key_id = $5", "ur.region_id = $6", "GROUP BY r.id, r.name, r.code"} {
+		if !strings.Contains(got.sql, want) {
+			t.Errorf("query missing %q:\n%s", want, got.sql)
+		}
+	}
+	if len(got.args) != 6 || got.args[5] != region {
+		t.Errorf("args = %v", got.args)
+	}
+
+	if _, err := NewStore(q).Usage.Breakdown(context.Background(), "tenant'; DROP TABLE x", filter); err == nil {
+		t.Error("Breakdown() accepted an unknown group")
+	}
+}
+
+func TestTenantListPlaceholders(t *testing.T) {
+	q := &recordingQuerier{}
+	_, _, err := NewStore(q).Tenants.List(context.Background(), TenantFilter{Status: "active"}, Page{Limit: 50, Offset: 100})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	got := q.queries[0]
+	if !strings.Contains(got.sql, "WHERE t.status = $1") || !strings.Contains(got.sql, "LIMIT $2 OFFSET $3") {
+		t.Errorf("unexpected query:\n%s", got.sql)
+	}
+	if len(got.args) != 3 || got.args[1] != 50 || got.args[2] != 100 {
+		t.Errorf("args = %v", got.args)
+	}
+}
+
+func TestModelListFilters(t *testing.T) {
+	q := &recordingQuerier{}
+	filter := ModelFilter{Family: "llama", Type: "chat", Status: "active", MinVRAMGB: 16, MaxVRAMGB: 80, Search: "instruct", SortBy: "created_at", Descending: true}
+	if _, _, err := NewStore(q).Models.List(context.Background(), filter, Page{Limit: 10}); !errors.Is(err, errStop) {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	count := q.queries[0]
+	if !strings.Contains(count.sql, "name ILIKE $6") || len(count.args) != 6 {
+		t.Errorf("count query = %s, args %v", count.sql, count.args)
+	}
+
+	q = &recordingQuerier{}
+	filter.SortBy = "name; DROP TABLE models"
+	if _, _, err := NewStore(q).Models.List(context.Background(), filter, Page{Limit: 10}); err == nil || errors.Is(err, errStop) {
+		t.Errorf("List() error = %v, want invalid sort column", err)
+	}
+	if len(q.queries) != 0 {
+		t.Error("List() queried with an invalid sort column")
+	}
+}
+
+func TestRegionUpdate(t *testing.T) {
+	q := &recordingQuerier{tag: pgconn.NewCommandTag("UPDATE 1")}
+	regions := NewStore(q).Regions
+	id := uuid.New()
+
+	if err := regions.Update(context.Background(), id, RegionUpdate{}); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("empty Update() error = %v, want ErrNoChanges", err)
+	}
+
+	name, multiplier := "Frankfurt", 1.2
+	if err := regions.Update(context.Background(), id, RegionUpdate{Name: &name, CostMultiplier: &multiplier}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got := q.queries[0]
+	if want := "UPDATE regions SET name = $1, cost_multiplier = $2, updated_at = NOW() WHERE id = $3"; got.sql != want {
+		t.Errorf("query = %q, want %q", got.sql, want)
+	}
+	if got.args[2] != id {
+		t.Errorf("args = %v", got.args)
+	}
+
+	q.tag = pgconn.NewCommandTag("UPDATE 0")
+	if err := regions.Update(context.Background(), id, RegionUpdate{Name: &name}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() of missing region error = %v, want ErrNotFound", err)
+	}
+}
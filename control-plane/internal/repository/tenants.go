@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+)
+
+// TenantRepository reads tenants for the admin console
+type TenantRepository struct {
+	q database.Querier
+}
+
+// TenantFilter narrows a tenant listing
+type TenantFilter struct {
+	Status string
+}
+
+func (f TenantFilter) where(args *database.Args, prefix string) *database.Where {
+	where := database.NewWhere(args)
+	if f.Status != "" {
+		where.Eq(prefix+"status", f.Status)
+	}
+	return where
+}
+
+// TenantSummary is a tenant with its admin list aggregates
+type TenantSummary struct {
+	ID                     uuid.UUID
+	Name                   string
+	Email                  string
+	Status                 string
+	BillingPlan            string
+	CreatedAt              time.Time
+	ActiveAPIKeys          int
+	TotalSpendMicrodollars int64
+}
+
+// List returns a page of tenants, newest first, and the total matching count
+func (r *TenantRepository) List(ctx context.Context, f TenantFilter, page Page) ([]TenantSummary, int, error) {
+	args := database.NewArgs()
+	where := f.where(args, "t.")
+	query := `
+		SELECT
+			t.id, t.name, t.email, t.status, t.billing_plan, t.created_at,
+			COUNT(DISTINCT ak.id),
+			COALESCE((SELECT SUM(cost_microdollars) FROM usage_records ur WHERE ur.tenant_id = t.id), 0)
+		FROM tenants t
+		LEFT JOIN api_keys ak ON ak.tenant_id = t.id AND ak.status = 'active'` + where.String() + `
+		GROUP BY t.id, t.name, t.email, t.status, t.billing_plan, t.created_at
+		ORDER BY t.created_at DESC
+		LIMIT ` + args.Add(page.Limit) + ` OFFSET ` + args.Add(page.Offset)
+
+	rows, err := r.q.Query(ctx, query, args.Values()...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []TenantSummary
+	for rows.Next() {
+		var t TenantSummary
+		if err := rows.Scan(&t.ID, &t.Name, &t.Email, &t.Status, &t.BillingPlan, &t.CreatedAt,
+			&t.ActiveAPIKeys, &t.TotalSpendMicrodollars); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read tenants: %w", err)
+	}
+
+	countArgs := database.NewArgs()
+	var total int
+	if err := r.q.QueryRow(ctx, "SELECT COUNT(*) FROM tenants"+f.where(countArgs, "").String(), countArgs.Values()...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count tenants: %w", err)
+	}
+
+	return tenants, total, nil
+}
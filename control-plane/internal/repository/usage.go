@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/google/uuid"
+)
+
+// UsageGroupBy dimensions supported by Breakdown
+const (
+	UsageByModel  = "model"
+	UsageByAPIKey = "api_key"
+	UsageByRegion = "region"
+	UsageByHour   = "hour"
+	UsageByDay    = "day"
+)
+
+// usageGroups maps each dimension to its select list, join and GROUP BY
+var usageGroups = map[string]struct {
+	selectCols string
+	join       string
+	groupBy    string
+}{
+	UsageByModel:  {"m.id, m.name, m.family", "INNER JOIN models m ON m.id = ur.model_id", "m.id, m.name, m.family"},
+	UsageByAPIKey: {"ak.id, ak.name, ak.key_prefix", "INNER JOIN api_keys ak ON ak.id = ur.api_key_id", "ak.id, ak.name, ak.key_prefix"},
+	UsageByRegion: {"r.id, r.name, r.code", "LEFT JOIN regions r ON r.id = ur.region_id", "r.id, r.name, r.code"},
+	UsageByHour:   {"DATE_TRUNC('hour', ur.timestamp)", "", "DATE_TRUNC('hour', ur.timestamp)"},
+	UsageByDay:    {"DATE_TRUNC('day', ur.timestamp)", "", "DATE_TRUNC('day', ur.timestamp)"},
+}
+
+// ValidUsageGroupBy reports whether Breakdown supports a dimension
+func ValidUsageGroupBy(groupBy string) bool {
+	_, ok := usageGroups[groupBy]
+	return ok
+}
+
+// UsageRepository aggregates usage records
+type UsageRepository struct {
+	q database.Querier
+}
+
+// UsageFilter selects a tenant's usage within a time window
+type UsageFilter struct {
+	TenantID uuid.UUID
+	Start    time.Time
+	End      time.Time
+	ModelID  *uuid.UUID
+	APIKeyID *uuid.UUID
+	RegionID *uuid.UUID
+}
+
+func (f UsageFilter) where(args *database.Args) *database.Where {
+	where := database.NewWhere(args).Eq("ur.tenant_id", f.TenantID)
+	where.Raw("ur.timestamp >= " + args.Add(f.Start))
+	where.Raw("ur.timestamp <= " + args.Add(f.End))
+	if f.ModelID != nil {
+		where.Eq("ur.model_id", *f.ModelID)
+	}
+	if f.APIKeyID != nil {
+		where.Eq("ur.api_key_id", *f.APIKeyID)
+	}
+	if f.RegionID != nil {
+		where.Eq("ur.region_id", *f.RegionID)
+	}
+	return where
+}
+
+// UsageBreakdownRow is one group of aggregated usage. For model, api_key and
+// region groups ID, Name and Detail identify the group (Detail is the model
+// family, key prefix or region code); for hour and day groups Period is set.
+type UsageBreakdownRow struct {
+	ID               *uuid.UUID
+	Name             *string
+	Detail           *string
+	Period           time.Time
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+	CachedTokens     int64
+	Requests         int64
+	AvgLatencyMs     float64
+	P95LatencyMs     float64
+	CostMicrodollars int64
+}
+
+// Breakdown aggregates usage by one dimension, largest token totals first
+func (r *UsageRepository) Breakdown(ctx context.Context, groupBy string, f UsageFilter) ([]UsageBreakdownRow, error) {
+	group, ok := usageGroups[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid usage group %q", groupBy)
+	}
+
+	args := database.NewArgs()
+	query := `
+		SELECT
+			` + group.selectCols + `,
+			COALESCE(SUM(ur.prompt_tokens), 0),
+			COALESCE(SUM(ur.completion_tokens), 0),
+			COALESCE(SUM(ur.total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(ur.cached_tokens), 0),
+			COUNT(*),
+			COALESCE(AVG(ur.latency_ms), 0),
+			COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY ur.latency_ms), 0),
+			COALESCE(SUM(ur.cost_microdollars), 0)
+		FROM usage_records ur
+		` + group.join + f.where(args).String() + `
+		GROUP BY ` + group.groupBy + `
+		ORDER BY total_tokens DESC
+		LIMIT 1000`
+
+	rows, err := r.q.Query(ctx, query, args.Values()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	var result []UsageBreakdownRow
+	for rows.Next() {
+		var row UsageBreakdownRow
+		totals := []any{
+			&row.PromptTokens, &row.CompletionTokens, &row.TotalTokens, &row.CachedTokens,
+			&row.Requests, &row.AvgLatencyMs, &row.P95LatencyMs, &row.CostMicrodollars,
+		}
+		var dest []any
+		switch groupBy {
+		case UsageByHour, UsageByDay:
+			dest = append([]any{&row.Period}, totals...)
+		default:
+			dest = append([]any{&row.ID, &row.Name, &row.Detail}, totals...)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+
+	return result, nil
+}
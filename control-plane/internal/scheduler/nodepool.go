@@ -2,10 +2,10 @@ package scheduler
 
 import (
 	"context"
-	"fmt"
 	"sync"
 	"time"
 
+	"github.com/crosslogic/control-plane/internal/nodes"
 	"github.com/crosslogic/control-plane/pkg/database"
 	"github.com/crosslogic/control-plane/pkg/models"
 	"github.com/google/uuid"
@@ -14,17 +14,19 @@ import (
 
 // NodePool manages the pool of GPU nodes
 type NodePool struct {
-	db     *database.Database
-	logger *zap.Logger
-	nodes  sync.Map // map[uuid.UUID]*models.Node
-	mu     sync.RWMutex
+	db       *database.Database
+	registry *nodes.Registry
+	logger   *zap.Logger
+	nodes    sync.Map // map[uuid.UUID]*models.Node
+	mu       sync.RWMutex
 }
 
 // NewNodePool creates a new node pool
 func NewNodePool(db *database.Database, logger *zap.Logger) *NodePool {
 	np := &NodePool{
-		db:     db,
-		logger: logger,
+		db:       db,
+		registry: nodes.NewRegistry(db),
+		logger:   logger,
 	}
 
 	// Start background refresh
@@ -35,34 +37,35 @@ func NewNodePool(db *database.Database, logger *zap.Logger) *NodePool {
 
 // RegisterNode registers a new node in the pool
 func (np *NodePool) RegisterNode(ctx context.Context, node *models.Node) error {
-	// Insert or update node in database
-	_, err := np.db.Pool.Exec(ctx, `
-		INSERT INTO nodes (
-			id, node_id_external, provider, region_id, instance_type,
-			gpu_type, vram_total_gb, vram_free_gb, model_id,
-			endpoint_url, internal_ip, spot_instance, spot_price,
-			throughput_tokens_per_sec, status, health_score,
-			last_heartbeat_at
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
-			$14, $15, $16, $17
-		)
-		ON CONFLICT (id) DO UPDATE SET
-			status = EXCLUDED.status,
-			health_score = EXCLUDED.health_score,
-			last_heartbeat_at = EXCLUDED.last_heartbeat_at,
-			vram_free_gb = EXCLUDED.vram_free_gb,
-			throughput_tokens_per_sec = EXCLUDED.throughput_tokens_per_sec,
-			updated_at = CURRENT_TIMESTAMP
-	`,
-		node.ID, node.NodeIDExternal, node.Provider, node.RegionID,
-		node.InstanceType, node.GPUType, node.VRAMTotalGB, node.VRAMFreeGB,
-		node.ModelID, node.EndpointURL, node.InternalIP, node.SpotInstance,
-		node.SpotPrice, node.ThroughputTokensPerSec, node.Status,
-		node.HealthScore, node.LastHeartbeatAt,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to register node: %w", err)
+	reg := nodes.Registration{
+		ID:           node.ID,
+		Provider:     node.Provider,
+		RegionID:     node.RegionID,
+		ModelID:      node.ModelID,
+		EndpointURL:  node.EndpointURL,
+		SpotInstance: node.SpotInstance,
+		SpotPrice:    node.SpotPrice,
+		Status:       node.Status,
+		Source:       nodes.SourceScheduler,
+	}
+	if node.NodeIDExternal != nil {
+		reg.NodeIDExternal = *node.NodeIDExternal
+	}
+	if node.InstanceType != nil {
+		reg.InstanceType = *node.InstanceType
+	}
+	if node.GPUType != nil {
+		reg.GPUType = *node.GPUType
+	}
+	if node.VRAMTotalGB != nil {
+		reg.VRAMTotalGB = *node.VRAMTotalGB
+	}
+	if node.InternalIP != nil {
+		reg.InternalIP = *node.InternalIP
+	}
+
+	if _, _, err := np.registry.Register(ctx, reg); err != nil {
+		return err
 	}
 
 	// Add to in-memory map
@@ -144,9 +147,9 @@ func (np *NodePool) GetNodesByModelAndRegion(modelID, regionID uuid.UUID) []*mod
 func (np *NodePool) UpdateNodeStatus(ctx context.Context, nodeID uuid.UUID, status string) error {
 	_, err := np.db.Pool.Exec(ctx, `
 		UPDATE nodes
-		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		SET status = $1, status_source = $3, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $2
-	`, status, nodeID)
+	`, status, nodeID, nodes.SourceScheduler)
 	if err != nil {
 		return err
 	}
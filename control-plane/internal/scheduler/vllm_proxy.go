@@ -9,8 +9,10 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/crosslogic/control-plane/pkg/models"
@@ -448,7 +450,13 @@ func derefInt(value *int) int {
 }
 
 // executeWithRetry performs the HTTP request with exponential backoff retry logic
-// It retries on transient failures (network errors, 502, 503, 504)
+//
+// Inference requests are not idempotent: once vLLM has the request it may start
+// generating, and a retry would generate (and bill) the tokens twice. Retries are
+// therefore limited to failures where the upstream never received the request
+// (connection establishment errors) or explicitly declined it (429, 503).
+// Anything after the request was written, including 502/504 from an
+// intermediary, is returned to the caller without retrying.
 func (p *VLLMProxy) executeWithRetry(ctx context.Context, req *http.Request, nodeEndpoint string) (*http.Response, error) {
 	maxRetries := 3
 	baseDelay := 100 * time.Millisecond
@@ -461,7 +469,7 @@ func (p *VLLMProxy) executeWithRetry(ctx context.Context, req *http.Request, nod
 		}
 
 		// Execute the request
-		resp, err := p.client.Do(req)
+		resp, sent, err := p.attemptRequest(ctx, req)
 
 		// Success case
 		if err == nil && resp.StatusCode < 500 {
@@ -471,7 +479,13 @@ func (p *VLLMProxy) executeWithRetry(ctx context.Context, req *http.Request, nod
 		// Determine if error is retryable
 		if err != nil {
 			lastErr = err
-			if !p.isRetryableError(err) {
+			if sent || !p.isRetryableError(err) {
+				if sent {
+					p.logger.Warn("not retrying request already sent to upstream",
+						zap.String("endpoint", nodeEndpoint),
+						zap.Error(err),
+					)
+				}
 				return nil, err
 			}
 		} else {
@@ -504,7 +518,34 @@ func (p *VLLMProxy) executeWithRetry(ctx context.Context, req *http.Request, nod
 	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
 }
 
-// copyHeaders copies headers from source to destination, filtering out hop-by-hop headers
+// attemptRequest executes a single attempt of req with a fresh copy of its body.
+// sent reports whether the request was fully written to the upstream, after
+// which vLLM may have begun generating and the attempt must not be repeated.
+func (p *VLLMProxy) attemptRequest(ctx context.Context, req *http.Request) (resp *http.Response, sent bool, err error) {
+	var wrote atomic.Bool
+	trace := &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				wrote.Store(true)
+			}
+		},
+	}
+
+	attemptReq := req.Clone(httptrace.WithClientTrace(ctx, trace))
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		attemptReq.Body = body
+	}
+
+	resp, err = p.client.Do(attemptReq)
+	return resp, wrote.Load(), err
+}
+
+// copyHeaders copies headers from source to destination, filtering out
+// hop-by-hop headers and client credentials, which nodes must never see
 func (p *VLLMProxy) copyHeaders(source, dest http.Header) {
 	// List of hop-by-hop headers that should not be forwarded
 	hopByHopHeaders := map[string]bool{
@@ -529,6 +570,11 @@ func (p *VLLMProxy) copyHeaders(source, dest http.Header) {
 			continue
 		}
 
+		// Skip credentials
+		if credentialHeaders[key] {
+			continue
+		}
+
 		// Copy all values for this header
 		for _, value := range values {
 			dest.Add(key, value)
@@ -536,6 +582,15 @@ func (p *VLLMProxy) copyHeaders(source, dest http.Header) {
 	}
 }
 
+// credentialHeaders are the client credentials copyHeaders drops
+var credentialHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"X-Api-Key":     true,
+	"X-Admin-Token": true,
+	"X-Node-Token":  true,
+}
+
 // shouldForwardHeader determines if a header should be forwarded to the client
 func (p *VLLMProxy) shouldForwardHeader(key string) bool {
 	// Headers that should not be forwarded
@@ -563,13 +618,13 @@ func (p *VLLMProxy) isRetryableError(err error) bool {
 	return false
 }
 
-// isRetryableStatus checks if an HTTP status code indicates a retryable error
+// isRetryableStatus checks if an HTTP status code indicates a retryable error.
+// Only statuses where the upstream declined the request before generating are
+// retryable; 502/504 may be returned by an intermediary after vLLM started work.
 func (p *VLLMProxy) isRetryableStatus(statusCode int) bool {
 	switch statusCode {
-	case http.StatusBadGateway, // 502
-		http.StatusServiceUnavailable, // 503
-		http.StatusGatewayTimeout,     // 504
-		http.StatusTooManyRequests:    // 429
+	case http.StatusServiceUnavailable, // 503
+		http.StatusTooManyRequests: // 429
 		return true
 	default:
 		return false
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -24,8 +25,11 @@ func TestVLLMProxy_ForwardRequest(t *testing.T) {
 		if r.URL.Path != "/v1/chat/completions" {
 			t.Errorf("expected path /v1/chat/completions, got %s", r.URL.Path)
 		}
-		if r.Header.Get("Authorization") != "Bearer test-key" {
-			t.Errorf("expected Authorization header, got %s", r.Header.Get("Authorization"))
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("expected Authorization to be stripped, got %s", got)
+		}
+		if r.Header.Get("X-Custom") != "kept" {
+			t.Errorf("expected X-Custom header, got %s", r.Header.Get("X-Custom"))
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -41,6 +45,7 @@ func TestVLLMProxy_ForwardRequest(t *testing.T) {
 	reqBody := []byte(`{"model":"gpt-3.5-turbo","messages":[{"role":"user","content":"Hello"}]}`)
 	req, _ := http.NewRequest("POST", "http://example.com/v1/chat/completions", strings.NewReader(string(reqBody)))
 	req.Header.Set("Authorization", "Bearer test-key")
+	req.Header.Set("X-Custom", "kept")
 
 	resp, err := proxy.ForwardRequest(context.Background(), node, req, reqBody)
 	if err != nil {
@@ -147,3 +152,87 @@ func TestVLLMProxy_CircuitBreaker(t *testing.T) {
 		t.Errorf("expected 'circuit breaker open' error, got %v", err)
 	}
 }
+
+func TestVLLMProxy_RetriesDeclinedRequestWithFullBody(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	proxy := NewVLLMProxy(logger)
+
+	var attempts int32
+	reqBody := []byte(`{"model":"m","messages":[{"role":"user","content":"Hello"}]}`)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != string(reqBody) {
+			t.Errorf("attempt %d received body %q", atomic.LoadInt32(&attempts)+1, body)
+		}
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	node := &models.Node{ID: uuid.New(), EndpointURL: ts.URL}
+	req, _ := http.NewRequest("POST", "http://example.com/v1/chat/completions", nil)
+
+	resp, err := proxy.ForwardRequest(context.Background(), node, req, reqBody)
+	if err != nil {
+		t.Fatalf("ForwardRequest failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestVLLMProxy_DoesNotRetryAfterRequestSent(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	t.Run("bad gateway", func(t *testing.T) {
+		proxy := NewVLLMProxy(logger)
+		var attempts int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer ts.Close()
+
+		node := &models.Node{ID: uuid.New(), EndpointURL: ts.URL}
+		req, _ := http.NewRequest("POST", "http://example.com/v1/completions", nil)
+
+		resp, err := proxy.ForwardRequest(context.Background(), node, req, []byte(`{}`))
+		if err != nil {
+			t.Fatalf("ForwardRequest failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("expected 502 to be returned, got %d", resp.StatusCode)
+		}
+		if got := atomic.LoadInt32(&attempts); got != 1 {
+			t.Errorf("expected 1 attempt, got %d", got)
+		}
+	})
+
+	t.Run("timeout awaiting response", func(t *testing.T) {
+		proxy := NewVLLMProxy(logger)
+		proxy.client.Timeout = 50 * time.Millisecond
+		var attempts int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer ts.Close()
+
+		node := &models.Node{ID: uuid.New(), EndpointURL: ts.URL}
+		req, _ := http.NewRequest("POST", "http://example.com/v1/completions", nil)
+
+		if _, err := proxy.ForwardRequest(context.Background(), node, req, []byte(`{}`)); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if got := atomic.LoadInt32(&attempts); got != 1 {
+			t.Errorf("expected 1 attempt, got %d", got)
+		}
+	})
+}
@@ -9,6 +9,8 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -21,6 +23,10 @@ type Client struct {
 	httpClient *http.Client
 	logger     *zap.Logger
 
+	// streamClient has no overall timeout; streaming calls are bounded by
+	// their context instead
+	streamClient *http.Client
+
 	// Retry configuration
 	maxRetries     int
 	retryDelay     time.Duration
@@ -97,6 +103,7 @@ func NewClient(cfg Config, logger *zap.Logger) *Client {
 		baseURL:       cfg.BaseURL,
 		token:         cfg.Token,
 		httpClient:    httpClient,
+		streamClient:  &http.Client{Transport: transport},
 		logger:        logger,
 		maxRetries:    cfg.MaxRetries,
 		retryDelay:    cfg.RetryDelay,
@@ -109,10 +116,17 @@ func NewClient(cfg Config, logger *zap.Logger) *Client {
 func (c *Client) Launch(ctx context.Context, req LaunchRequest) (*LaunchResponse, error) {
 	c.logger.Info("launching cluster via SkyPilot API",
 		zap.String("cluster_name", req.ClusterName),
+		zap.String("workspace", WorkspaceFromContext(ctx)),
 		zap.Bool("detach", req.Detach),
 		zap.Bool("retry_until_up", req.RetryUntilUp),
 	)
 
+	// Record the workspace in the request body as well so the API server
+	// persists it with the cluster
+	if req.Workspace == "" {
+		req.Workspace = WorkspaceFromContext(ctx)
+	}
+
 	var result LaunchResponse
 	err := c.doRequestWithRetry(ctx, "POST", "/api/v1/clusters/launch", req, &result)
 	if err != nil {
@@ -159,6 +173,31 @@ func (c *Client) Terminate(ctx context.Context, clusterName string, purge bool)
 	return &result, nil
 }
 
+// Stop stops a cluster's instances, keeping their disks so a later launch
+// with the same cluster name restarts them
+func (c *Client) Stop(ctx context.Context, clusterName string) (*StopResponse, error) {
+	c.logger.Info("stopping cluster via SkyPilot API",
+		zap.String("cluster_name", clusterName),
+	)
+
+	var result StopResponse
+	err := c.doRequestWithRetry(ctx, "POST", fmt.Sprintf("/api/v1/clusters/%s/stop", clusterName), nil, &result)
+	if err != nil {
+		c.logger.Error("failed to stop cluster",
+			zap.String("cluster_name", clusterName),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	c.logger.Info("cluster stop initiated",
+		zap.String("cluster_name", clusterName),
+		zap.String("request_id", result.RequestID),
+	)
+
+	return &result, nil
+}
+
 // GetStatus retrieves the current status of a cluster
 func (c *Client) GetStatus(ctx context.Context, clusterName string) (*ClusterStatus, error) {
 	c.logger.Debug("getting cluster status",
@@ -348,6 +387,79 @@ func (c *Client) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteRespo
 	return &result, nil
 }
 
+// ExecuteStream runs a command on a cluster and passes its output to
+// onChunk as it is produced. The API server answers a streaming execute with
+// newline-delimited ExecuteChunks, the last one carrying the exit code.
+// Streaming calls are not retried, since output may already have been
+// delivered.
+func (c *Client) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk func(ExecuteChunk) error) (int, error) {
+	c.logger.Info("executing command on cluster (streaming)",
+		zap.String("cluster_name", req.ClusterName),
+		zap.String("command", req.Command),
+	)
+
+	req.Stream = true
+	bodyBytes, err := json.Marshal(req)
+	if err != nil {
+		return 0, fmt.Errorf("marshal request body: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v1/clusters/execute", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	c.setHeaders(httpReq)
+	httpReq.Header.Set("Accept", "application/x-ndjson")
+
+	resp, err := c.streamClient.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		var apiErr ErrorResponse
+		if err := json.Unmarshal(respBody, &apiErr); err == nil && apiErr.Error != "" {
+			return 0, &APIError{
+				StatusCode: resp.StatusCode,
+				Message:    apiErr.Error,
+				ErrorCode:  apiErr.ErrorCode,
+				Details:    apiErr.Details,
+				RequestID:  apiErr.RequestID,
+			}
+		}
+		return 0, &APIError{StatusCode: resp.StatusCode, Message: string(respBody)}
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk ExecuteChunk
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				return 0, fmt.Errorf("execute stream ended without an exit code")
+			}
+			return 0, fmt.Errorf("read execute stream: %w", err)
+		}
+
+		if chunk.Error != "" {
+			return 0, fmt.Errorf("execute failed: %s", chunk.Error)
+		}
+		if chunk.Data != "" {
+			if err := onChunk(chunk); err != nil {
+				return 0, err
+			}
+		}
+		if chunk.ExitCode != nil {
+			c.logger.Info("command executed",
+				zap.String("cluster_name", req.ClusterName),
+				zap.Int("exit_code", *chunk.ExitCode),
+			)
+			return *chunk.ExitCode, nil
+		}
+	}
+}
+
 // GetLogs retrieves logs from a cluster
 func (c *Client) GetLogs(ctx context.Context, req LogsRequest) (*LogsResponse, error) {
 	c.logger.Debug("getting cluster logs",
@@ -389,6 +501,90 @@ func (c *Client) EstimateCost(ctx context.Context, req EstimateCostRequest) (*Es
 	return &result, nil
 }
 
+// GetResourceQuota returns the cloud's vCPU and GPU quotas in a region,
+// read with the given credentials
+func (c *Client) GetResourceQuota(ctx context.Context, req ResourceQuotaRequest) (*ResourceQuotaResponse, error) {
+	c.logger.Debug("getting resource quota",
+		zap.String("provider", req.Provider),
+		zap.String("region", req.Region),
+	)
+
+	var result ResourceQuotaResponse
+	err := c.doRequestWithRetry(ctx, "POST", "/api/v1/quota", req, &result)
+	if err != nil {
+		c.logger.Error("failed to get resource quota",
+			zap.String("provider", req.Provider),
+			zap.String("region", req.Region),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// credentialProfilesPath is the API server's credential profile collection
+const credentialProfilesPath = "/api/v1/credentials"
+
+// CreateCredentialProfile registers cloud credentials with the API server so
+// launches can reference them by ID instead of sending them inline
+func (c *Client) CreateCredentialProfile(ctx context.Context, req CredentialProfileRequest) (*CredentialProfile, error) {
+	c.logger.Info("creating credential profile",
+		zap.String("name", req.Name),
+		zap.String("workspace", WorkspaceFromContext(ctx)),
+	)
+
+	var result CredentialProfile
+	if err := c.doRequestWithRetry(ctx, "POST", credentialProfilesPath, req, &result); err != nil {
+		c.logger.Error("failed to create credential profile",
+			zap.String("name", req.Name),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// UpdateCredentialProfile replaces the credentials of an existing profile.
+// Clusters launched with the profile pick up the new credentials without
+// being relaunched.
+func (c *Client) UpdateCredentialProfile(ctx context.Context, profileID string, req CredentialProfileRequest) (*CredentialProfile, error) {
+	c.logger.Info("updating credential profile",
+		zap.String("profile_id", profileID),
+		zap.String("workspace", WorkspaceFromContext(ctx)),
+	)
+
+	var result CredentialProfile
+	if err := c.doRequestWithRetry(ctx, "PUT", credentialProfilesPath+"/"+url.PathEscape(profileID), req, &result); err != nil {
+		c.logger.Error("failed to update credential profile",
+			zap.String("profile_id", profileID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// DeleteCredentialProfile removes a credential profile from the API server
+func (c *Client) DeleteCredentialProfile(ctx context.Context, profileID string) error {
+	c.logger.Info("deleting credential profile",
+		zap.String("profile_id", profileID),
+		zap.String("workspace", WorkspaceFromContext(ctx)),
+	)
+
+	if err := c.doRequestWithRetry(ctx, "DELETE", credentialProfilesPath+"/"+url.PathEscape(profileID), nil, nil); err != nil {
+		c.logger.Error("failed to delete credential profile",
+			zap.String("profile_id", profileID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	return nil
+}
+
 // Health checks the API server health
 func (c *Client) Health(ctx context.Context) (*HealthResponse, error) {
 	c.logger.Debug("checking API server health")
@@ -473,8 +669,8 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 		}
 		bodyReader = bytes.NewReader(bodyBytes)
 
-		// Log request body for debugging (be careful with sensitive data)
-		if c.logger.Core().Enabled(zap.DebugLevel) {
+		// Log request body for debugging, except bodies carrying secrets
+		if c.logger.Core().Enabled(zap.DebugLevel) && !containsSecrets(path, body) {
 			c.logger.Debug("request body",
 				zap.String("method", method),
 				zap.String("path", path),
@@ -559,6 +755,15 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 	return nil
 }
 
+// containsSecrets reports whether a request body carries cloud credentials
+func containsSecrets(path string, body interface{}) bool {
+	if strings.HasPrefix(path, credentialProfilesPath) {
+		return true
+	}
+	launch, ok := body.(LaunchRequest)
+	return ok && launch.CloudCredentials != nil
+}
+
 // setHeaders sets common HTTP headers for API requests
 func (c *Client) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
@@ -568,6 +773,32 @@ func (c *Client) setHeaders(req *http.Request) {
 	if c.token != "" {
 		req.Header.Set("Authorization", "Bearer "+c.token)
 	}
+
+	// Scope the request to a SkyPilot workspace when one is set on the context
+	if workspace := WorkspaceFromContext(req.Context()); workspace != "" {
+		req.Header.Set(WorkspaceHeader, workspace)
+	}
+}
+
+// WorkspaceHeader is the HTTP header used to scope a request to a SkyPilot workspace
+const WorkspaceHeader = "X-SkyPilot-Workspace"
+
+type workspaceContextKey struct{}
+
+// WithWorkspace returns a context that scopes every API call made with it to
+// the given SkyPilot workspace. This lets one API server isolate clusters per
+// tenant without a client (and connection pool) per workspace.
+func WithWorkspace(ctx context.Context, workspace string) context.Context {
+	if workspace == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, workspaceContextKey{}, workspace)
+}
+
+// WorkspaceFromContext returns the SkyPilot workspace set on the context, if any
+func WorkspaceFromContext(ctx context.Context) string {
+	workspace, _ := ctx.Value(workspaceContextKey{}).(string)
+	return workspace
 }
 
 // calculateBackoff calculates exponential backoff delay
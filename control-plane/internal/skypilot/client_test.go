@@ -2,6 +2,7 @@ package skypilot
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -506,3 +507,139 @@ func TestEstimateCost(t *testing.T) {
 	assert.Equal(t, "USD", resp.Currency)
 	assert.Equal(t, "high", resp.ConfidenceLevel)
 }
+
+// TestWorkspaceScoping verifies the workspace on the context is sent with requests
+func TestWorkspaceScoping(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	var gotHeader string
+	var gotBody LaunchRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get(WorkspaceHeader)
+		if r.URL.Path == "/api/v1/clusters/launch" {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"request_id": "req-123"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, Token: "test-token", MaxRetries: -1}, logger)
+
+	ctx := WithWorkspace(context.Background(), "tenant-abc")
+	_, err := client.Launch(ctx, LaunchRequest{ClusterName: "cic-test"})
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-abc", gotHeader)
+	assert.Equal(t, "tenant-abc", gotBody.Workspace)
+
+	// Without a workspace no header is sent
+	_, err = client.Launch(context.Background(), LaunchRequest{ClusterName: "cic-test"})
+	require.NoError(t, err)
+	assert.Empty(t, gotHeader)
+}
+
+// TestCredentialProfiles verifies the credential profile lifecycle and that
+// launches can reference a profile instead of inline credentials
+func TestCredentialProfiles(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	var gotMethod, gotPath string
+	var gotProfile CredentialProfileRequest
+	var gotLaunch map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/clusters/launch":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&gotLaunch))
+			w.Write([]byte(`{"request_id": "req-123"}`))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&gotProfile))
+			w.Write([]byte(`{"id": "prof-1", "name": "cic-aws-1", "clouds": ["aws"]}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, Token: "test-token", MaxRetries: -1}, logger)
+	ctx := context.Background()
+
+	req := CredentialProfileRequest{
+		Name: "cic-aws-1",
+		CloudCredentials: &CloudCredentials{
+			AWS: &AWSCredentials{AccessKeyID: "AKIA", SecretAccessKey: "secret"},
+		},
+	}
+
+	profile, err := client.CreateCredentialProfile(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, gotMethod)
+	assert.Equal(t, "/api/v1/credentials", gotPath)
+	assert.Equal(t, "prof-1", profile.ID)
+	assert.Equal(t, "secret", gotProfile.CloudCredentials.AWS.SecretAccessKey)
+
+	_, err = client.UpdateCredentialProfile(ctx, "prof-1", req)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPut, gotMethod)
+	assert.Equal(t, "/api/v1/credentials/prof-1", gotPath)
+
+	require.NoError(t, client.DeleteCredentialProfile(ctx, "prof-1"))
+	assert.Equal(t, http.MethodDelete, gotMethod)
+	assert.Equal(t, "/api/v1/credentials/prof-1", gotPath)
+
+	// A profile-based launch carries no secrets
+	_, err = client.Launch(ctx, LaunchRequest{ClusterName: "cic-test", CredentialProfileID: "prof-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "prof-1", gotLaunch["credential_profile_id"])
+	assert.NotContains(t, gotLaunch, "cloud_credentials")
+}
+
+func TestExecuteStream(t *testing.T) {
+	logger := zap.NewNop()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/clusters/execute", r.URL.Path)
+
+		var req ExecuteRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"stream":"stdout","data":"line 1\n"}` + "\n"))
+		w.Write([]byte(`{"stream":"stderr","data":"warning\n"}` + "\n"))
+		w.Write([]byte(`{"exit_code":3}` + "\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, Token: "test-token"}, logger)
+
+	var chunks []ExecuteChunk
+	exitCode, err := client.ExecuteStream(context.Background(), ExecuteRequest{
+		ClusterName: "test-cluster",
+		Command:     "nvidia-smi",
+	}, func(chunk ExecuteChunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "stderr", chunks[1].Stream)
+	assert.Equal(t, "warning\n", chunks[1].Data)
+}
+
+func TestExecuteStreamWithoutExitCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"stream":"stdout","data":"partial"}` + "\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL}, zap.NewNop())
+	_, err := client.ExecuteStream(context.Background(), ExecuteRequest{ClusterName: "c", Command: "ls"},
+		func(ExecuteChunk) error { return nil })
+	assert.Error(t, err)
+}
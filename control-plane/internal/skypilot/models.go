@@ -10,6 +10,9 @@ type LaunchRequest struct {
 	ClusterName string `json:"cluster_name"`
 	TaskYAML    string `json:"task_yaml"` // Complete SkyPilot task YAML
 
+	// Workspace isolates the cluster in SkyPilot's state (per-tenant workspaces)
+	Workspace string `json:"workspace,omitempty"`
+
 	// Launch options
 	RetryUntilUp      bool `json:"retry_until_up"`
 	IdleMinutesToStop int  `json:"idle_minutes_to_autostop,omitempty"`
@@ -21,6 +24,26 @@ type LaunchRequest struct {
 	// Cloud credentials (for multi-tenant support)
 	// These are dynamically injected per request rather than stored server-side
 	CloudCredentials *CloudCredentials `json:"cloud_credentials,omitempty"`
+
+	// CredentialProfileID references credentials registered with the API
+	// server as a named profile, instead of sending them inline
+	CredentialProfileID string `json:"credential_profile_id,omitempty"`
+}
+
+// CredentialProfileRequest registers or replaces a named credential profile
+type CredentialProfileRequest struct {
+	Name             string            `json:"name"`
+	CloudCredentials *CloudCredentials `json:"cloud_credentials"`
+}
+
+// CredentialProfile is a credential profile stored by the API server. The
+// secrets themselves are never returned.
+type CredentialProfile struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Clouds    []string  `json:"clouds,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // CloudCredentials contains dynamic cloud provider credentials
@@ -34,6 +57,18 @@ type CloudCredentials struct {
 
 	// GCP credentials
 	GCP *GCPCredentials `json:"gcp,omitempty"`
+
+	// Lambda Cloud credentials
+	Lambda *LambdaCredentials `json:"lambda,omitempty"`
+
+	// RunPod credentials
+	RunPod *RunPodCredentials `json:"runpod,omitempty"`
+
+	// Oracle Cloud Infrastructure credentials
+	OCI *OCICredentials `json:"oci,omitempty"`
+
+	// Nebius credentials
+	Nebius *NebiusCredentials `json:"nebius,omitempty"`
 }
 
 // AWSCredentials contains AWS-specific credentials
@@ -42,6 +77,11 @@ type AWSCredentials struct {
 	SecretAccessKey string `json:"secret_access_key"`
 	Region          string `json:"region,omitempty"`
 	SessionToken    string `json:"session_token,omitempty"` // For temporary credentials
+
+	// Sovereign partitions (e.g. aws-us-gov) and their endpoint overrides
+	Partition   string `json:"partition,omitempty"`
+	EndpointURL string `json:"endpoint_url,omitempty"`
+	STSEndpoint string `json:"sts_endpoint,omitempty"`
 }
 
 // AzureCredentials contains Azure-specific credentials
@@ -50,6 +90,11 @@ type AzureCredentials struct {
 	TenantID       string `json:"tenant_id"`
 	ClientID       string `json:"client_id"`
 	ClientSecret   string `json:"client_secret"`
+
+	// National cloud (e.g. AzureUSGovernment) and its login and ARM endpoints
+	Cloud                   string `json:"cloud,omitempty"`
+	AuthorityHost           string `json:"authority_host,omitempty"`
+	ResourceManagerEndpoint string `json:"resource_manager_endpoint,omitempty"`
 }
 
 // GCPCredentials contains GCP-specific credentials
@@ -59,6 +104,34 @@ type GCPCredentials struct {
 	ServiceAccountEmail string `json:"service_account_email,omitempty"`
 }
 
+// LambdaCredentials contains Lambda Cloud-specific credentials
+type LambdaCredentials struct {
+	APIKey   string `json:"api_key"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// RunPodCredentials contains RunPod-specific credentials
+type RunPodCredentials struct {
+	APIKey   string `json:"api_key"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// OCICredentials contains Oracle Cloud Infrastructure credentials
+type OCICredentials struct {
+	UserOCID    string `json:"user_ocid"`
+	TenancyOCID string `json:"tenancy_ocid"`
+	Fingerprint string `json:"fingerprint"`
+	PrivateKey  string `json:"private_key"` // PEM-encoded API signing key
+	Region      string `json:"region,omitempty"`
+}
+
+// NebiusCredentials contains Nebius-specific credentials
+type NebiusCredentials struct {
+	APIKey    string `json:"api_key"`
+	ProjectID string `json:"project_id"`
+	Endpoint  string `json:"endpoint,omitempty"`
+}
+
 // LaunchResponse contains the async request ID for tracking cluster launch
 type LaunchResponse struct {
 	RequestID string `json:"request_id"` // Async request ID to poll for completion
@@ -77,6 +150,12 @@ type TerminateResponse struct {
 	Message   string `json:"message,omitempty"`
 }
 
+// StopResponse contains the async request ID for tracking a cluster stop
+type StopResponse struct {
+	RequestID string `json:"request_id"` // Async request ID to poll for completion
+	Message   string `json:"message,omitempty"`
+}
+
 // ClusterStatus represents the detailed status of a cluster
 type ClusterStatus struct {
 	// Identity
@@ -209,6 +288,16 @@ type ExecuteRequest struct {
 	Envs        map[string]string `json:"envs,omitempty"`   // Environment variables
 	WorkingDir  string            `json:"working_dir,omitempty"` // Working directory
 	Timeout     int               `json:"timeout,omitempty"` // Timeout in seconds
+	Stream      bool              `json:"stream,omitempty"`  // Stream output as ExecuteChunks
+}
+
+// ExecuteChunk is one line of a streaming execute response: output, or the
+// final exit code
+type ExecuteChunk struct {
+	Stream   string `json:"stream,omitempty"` // "stdout" or "stderr"
+	Data     string `json:"data,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"` // Set on the last chunk
+	Error    string `json:"error,omitempty"`     // Set if execution failed
 }
 
 // ExecuteResponse contains the result of command execution
@@ -239,6 +328,10 @@ type LogsResponse struct {
 type ResourceQuotaRequest struct {
 	Provider string `json:"provider"` // aws, azure, gcp
 	Region   string `json:"region"`
+
+	// Credentials to read the quota with, inline or as a registered profile
+	CloudCredentials    *CloudCredentials `json:"cloud_credentials,omitempty"`
+	CredentialProfileID string            `json:"credential_profile_id,omitempty"`
 }
 
 // ResourceQuotaResponse contains quota information
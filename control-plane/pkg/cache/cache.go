@@ -5,41 +5,132 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/crosslogic/control-plane/internal/config"
 	"github.com/go-redis/redis/v8"
 )
 
+// Deployment modes
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+// Features with a configurable policy for Redis outages. Features that fail
+// open are skipped while Redis is down; the rest fail the request.
+const (
+	FeatureRateLimit   = "rate_limit"
+	FeatureIdempotency = "idempotency"
+	FeatureAdminGuard  = "admin_guard"
+)
+
+// ErrUnavailable is returned without contacting Redis while the health check
+// reports it down, so callers don't each wait out a connection timeout
+var ErrUnavailable = errors.New("cache: redis unavailable")
+
 // Cache wraps the Redis client
 type Cache struct {
-	Client *redis.Client
+	// Client is a single-node, Sentinel failover or cluster client. Going
+	// through it directly skips the key namespace checks.
+	Client redis.UniversalClient
+
+	mode     string
+	failOpen map[string]bool
+	down     atomic.Bool
 }
 
-// NewCache creates a new Redis cache client
+// NewCache creates a new Redis client for a standalone server, a Sentinel
+// managed master or a cluster
 func NewCache(cfg config.RedisConfig) (*Cache, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		PoolSize:     cfg.PoolSize,
-		MinIdleConns: cfg.PoolSize / 2,
-		MaxRetries:   3,
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		PoolTimeout:  4 * time.Second,
-	})
+	c := &Cache{
+		Client:   newClient(cfg),
+		mode:     cfg.Mode,
+		failOpen: make(map[string]bool, len(cfg.FailOpen)),
+	}
+	if c.mode == "" {
+		c.mode = ModeStandalone
+	}
+	for _, feature := range cfg.FailOpen {
+		c.failOpen[feature] = true
+	}
 
 	// Test the connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("unable to connect to Redis: %w", err)
+	if err := c.Client.Ping(ctx).Err(); err != nil {
+		c.Client.Close()
+		return nil, fmt.Errorf("unable to connect to Redis (%s): %w", c.mode, err)
 	}
 
-	return &Cache{Client: client}, nil
+	return c, nil
+}
+
+// newClient builds the client for the configured deployment mode
+func newClient(cfg config.RedisConfig) redis.UniversalClient {
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Addrs,
+		DB:               cfg.DB,
+		Password:         cfg.Password,
+		MasterName:       cfg.MasterName,
+		SentinelPassword: cfg.SentinelPassword,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.PoolSize / 2,
+		MaxRetries:       3,
+		DialTimeout:      5 * time.Second,
+		ReadTimeout:      3 * time.Second,
+		WriteTimeout:     3 * time.Second,
+		PoolTimeout:      4 * time.Second,
+	}
+
+	switch cfg.Mode {
+	case ModeSentinel:
+		return redis.NewFailoverClient(opts.Failover())
+	case ModeCluster:
+		return redis.NewClusterClient(opts.Cluster())
+	default:
+		opts.Addrs = []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)}
+		return redis.NewClient(opts.Simple())
+	}
+}
+
+// Mode returns the deployment mode the client was created for
+func (c *Cache) Mode() string {
+	if c.mode == "" {
+		return ModeStandalone
+	}
+	return c.mode
+}
+
+// FailOpen reports whether feature should be skipped, rather than fail the
+// request, when Redis is unavailable
+func (c *Cache) FailOpen(feature string) bool {
+	return c != nil && c.failOpen[feature]
+}
+
+// Available reports whether the last health check reached Redis
+func (c *Cache) Available() bool {
+	return !c.down.Load()
+}
+
+// available returns ErrUnavailable while Redis is known to be down
+func (c *Cache) available() error {
+	if c.down.Load() {
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// ready returns ErrUnavailable while Redis is known to be down and
+// ErrUnscopedKey for keys outside the tenant and platform namespaces
+func (c *Cache) ready(keys ...string) error {
+	if err := checkKeys(keys...); err != nil {
+		return err
+	}
+	return c.available()
 }
 
 // Close closes the Redis connection
@@ -54,21 +145,33 @@ func (c *Cache) Health(ctx context.Context) error {
 
 // Set sets a key-value pair with expiration
 func (c *Cache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if err := c.ready(key); err != nil {
+		return err
+	}
 	return c.Client.Set(ctx, key, value, expiration).Err()
 }
 
 // SetNX sets a key only if it does not already exist
 func (c *Cache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	if err := c.ready(key); err != nil {
+		return false, err
+	}
 	return c.Client.SetNX(ctx, key, value, expiration).Result()
 }
 
 // Get retrieves a value by key
 func (c *Cache) Get(ctx context.Context, key string) (string, error) {
+	if err := c.ready(key); err != nil {
+		return "", err
+	}
 	return c.Client.Get(ctx, key).Result()
 }
 
 // GetInt64 retrieves a key as int64, returning a bool indicating if the key existed
 func (c *Cache) GetInt64(ctx context.Context, key string) (int64, bool, error) {
+	if err := c.ready(key); err != nil {
+		return 0, false, err
+	}
 	value, err := c.Client.Get(ctx, key).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
@@ -85,27 +188,103 @@ func (c *Cache) GetInt64(ctx context.Context, key string) (int64, bool, error) {
 	return parsed, true, nil
 }
 
-// Delete deletes a key
+// Delete deletes keys. In cluster mode the keys may live on different
+// nodes, so each is deleted separately.
 func (c *Cache) Delete(ctx context.Context, keys ...string) error {
-	return c.Client.Del(ctx, keys...).Err()
+	if err := c.ready(keys...); err != nil {
+		return err
+	}
+	if c.Mode() != ModeCluster || len(keys) < 2 {
+		return c.Client.Del(ctx, keys...).Err()
+	}
+
+	_, err := c.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return err
 }
 
 // Incr increments a counter
 func (c *Cache) Incr(ctx context.Context, key string) (int64, error) {
+	if err := c.ready(key); err != nil {
+		return 0, err
+	}
 	return c.Client.Incr(ctx, key).Result()
 }
 
 // IncrBy increments a counter by a specific amount
 func (c *Cache) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	if err := c.ready(key); err != nil {
+		return 0, err
+	}
 	return c.Client.IncrBy(ctx, key, value).Result()
 }
 
 // Expire sets expiration on a key
 func (c *Cache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	if err := c.ready(key); err != nil {
+		return err
+	}
 	return c.Client.Expire(ctx, key, expiration).Err()
 }
 
-// Exists checks if a key exists
+// Exists counts how many of keys exist
 func (c *Cache) Exists(ctx context.Context, keys ...string) (int64, error) {
-	return c.Client.Exists(ctx, keys...).Result()
+	if err := c.ready(keys...); err != nil {
+		return 0, err
+	}
+	if c.Mode() != ModeCluster || len(keys) < 2 {
+		return c.Client.Exists(ctx, keys...).Result()
+	}
+
+	var total int64
+	for _, key := range keys {
+		n, err := c.Client.Exists(ctx, key).Result()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Append appends a value to the tail of a list
+func (c *Cache) Append(ctx context.Context, key string, value interface{}) error {
+	if err := c.ready(key); err != nil {
+		return err
+	}
+	return c.Client.RPush(ctx, key, value).Err()
+}
+
+// Len returns the length of a list, zero when it doesn't exist
+func (c *Cache) Len(ctx context.Context, key string) (int64, error) {
+	if err := c.ready(key); err != nil {
+		return 0, err
+	}
+	return c.Client.LLen(ctx, key).Result()
+}
+
+// PushCapped prepends a value to a list, keeping only the newest maxLen
+// entries, and refreshes the list's expiration
+func (c *Cache) PushCapped(ctx context.Context, key string, value interface{}, maxLen int64, expiration time.Duration) error {
+	if err := c.ready(key); err != nil {
+		return err
+	}
+	pipe := c.Client.TxPipeline()
+	pipe.LPush(ctx, key, value)
+	pipe.LTrim(ctx, key, 0, maxLen-1)
+	pipe.Expire(ctx, key, expiration)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Range returns list elements between start and stop (inclusive)
+func (c *Cache) Range(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	if err := c.ready(key); err != nil {
+		return nil, err
+	}
+	return c.Client.LRange(ctx, key, start, stop).Result()
 }
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/crosslogic/control-plane/internal/config"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func newTestCache(t *testing.T, failOpen ...string) (*Cache, *miniredis.Miniredis) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	port, _ := strconv.Atoi(mr.Port())
+	c, err := NewCache(config.RedisConfig{Host: mr.Host(), Port: port, FailOpen: failOpen})
+	if err != nil {
+		mr.Close()
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+		mr.Close()
+	})
+	return c, mr
+}
+
+func TestHealthCheckMarksUnavailable(t *testing.T) {
+	c, mr := newTestCache(t, FeatureRateLimit)
+	ctx := context.Background()
+
+	if c.Mode() != ModeStandalone || !c.FailOpen(FeatureRateLimit) || c.FailOpen(FeatureIdempotency) {
+		t.Fatalf("mode = %q, fail open = %v", c.Mode(), c.failOpen)
+	}
+
+	mr.SetError("LOADING")
+	c.checkHealth(ctx, zap.NewNop())
+	if c.Available() {
+		t.Fatal("cache still available after failed health check")
+	}
+	if _, err := c.Get(ctx, "feature_flag:k"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Get() error = %v, want ErrUnavailable", err)
+	}
+
+	mr.SetError("")
+	c.checkHealth(ctx, zap.NewNop())
+	if !c.Available() {
+		t.Fatal("cache not available after recovery")
+	}
+	if err := c.Set(ctx, "feature_flag:k", "v", 0); err != nil {
+		t.Errorf("Set() after recovery error = %v", err)
+	}
+}
+
+func TestDeleteAndExistsMultipleKeys(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+
+	a, b := PlatformKey(NamespaceFeatureFlag, "a"), PlatformKey(NamespaceFeatureFlag, "b")
+	c.Set(ctx, a, 1, 0)
+	c.Set(ctx, b, 1, 0)
+	if n, err := c.Exists(ctx, a, b, PlatformKey(NamespaceFeatureFlag, "c")); err != nil || n != 2 {
+		t.Fatalf("Exists() = %d, %v", n, err)
+	}
+	if err := c.Delete(ctx, a, b); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.Exists(ctx, a, b); n != 0 {
+		t.Errorf("Exists() after Delete = %d", n)
+	}
+}
+
+func TestKeyNamespaces(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+	tenantID := uuid.New()
+
+	for _, key := range []string{
+		TenantKey(tenantID, "ratelimit", "key", "k1", "concurrency"),
+		PlatformKey(NamespaceAPIKey, "hash"),
+	} {
+		if err := CheckKey(key); err != nil {
+			t.Errorf("CheckKey(%q) = %v", key, err)
+		}
+	}
+	for _, key := range []string{
+		"ratelimit:key:k1:concurrency",
+		"api_key",
+		"tenant:" + tenantID.String(),
+		"tenant:acme:ratelimit",
+		"tenant:" + strings.ToUpper(tenantID.String()) + ":ratelimit",
+		"tenant:" + uuid.Nil.String() + ":ratelimit",
+	} {
+		if err := CheckKey(key); !errors.Is(err, ErrUnscopedKey) {
+			t.Errorf("CheckKey(%q) = %v, want ErrUnscopedKey", key, err)
+		}
+	}
+
+	if _, err := c.Incr(ctx, "ratelimit:tenant:minute"); !errors.Is(err, ErrUnscopedKey) {
+		t.Errorf("Incr() on unscoped key error = %v", err)
+	}
+	if err := c.Delete(ctx, PlatformKey(NamespaceAPIKey, "hash"), "stray"); !errors.Is(err, ErrUnscopedKey) {
+		t.Errorf("Delete() with an unscoped key error = %v", err)
+	}
+	if id, ok := KeyTenant(TenantKey(tenantID, "tokens")); !ok || id != tenantID {
+		t.Errorf("KeyTenant() = %v, %v", id, ok)
+	}
+}
+
+func TestMoveKeyAndRunOnce(t *testing.T) {
+	c, mr := newTestCache(t)
+	ctx := context.Background()
+	tenantID := uuid.New()
+
+	mr.Set("tokens:key:k1:day:2026-10-16", "42")
+	mr.SetTTL("tokens:key:k1:day:2026-10-16", time.Hour)
+	mr.Set("tokens:key:k2:day:2026-10-16", "7")
+	mr.Set(TenantKey(tenantID, "tokens", "key", "k2"), "9")
+
+	var legacy []string
+	if err := c.ScanKeys(ctx, "tokens:*", func(key string) error {
+		legacy = append(legacy, key)
+		return nil
+	}); err != nil || len(legacy) != 2 {
+		t.Fatalf("ScanKeys() = %v, %v", legacy, err)
+	}
+
+	to := TenantKey(tenantID, "tokens", "key", "k1")
+	if moved, err := c.MoveKey(ctx, "tokens:key:k1:day:2026-10-16", to); err != nil || !moved {
+		t.Fatalf("MoveKey() = %v, %v", moved, err)
+	}
+	if v, _ := c.Get(ctx, to); v != "42" || mr.TTL(to) != time.Hour {
+		t.Errorf("moved key = %q with TTL %s", v, mr.TTL(to))
+	}
+
+	// Data already under the new name wins
+	if moved, err := c.MoveKey(ctx, "tokens:key:k2:day:2026-10-16", TenantKey(tenantID, "tokens", "key", "k2")); err != nil || moved {
+		t.Errorf("MoveKey() onto existing key = %v, %v", moved, err)
+	}
+	if mr.Exists("tokens:key:k2:day:2026-10-16") {
+		t.Error("legacy key kept after losing to the new one")
+	}
+	if moved, err := c.MoveKey(ctx, "tokens:missing", to); err != nil || moved {
+		t.Errorf("MoveKey() of missing key = %v, %v", moved, err)
+	}
+
+	runs := 0
+	migrate := func(ctx context.Context) error {
+		runs++
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.RunOnce(ctx, "test", time.Hour, migrate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if runs != 1 {
+		t.Errorf("migration ran %d times", runs)
+	}
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// healthCheckTimeout bounds a single health check ping
+const healthCheckTimeout = 2 * time.Second
+
+// StartHealthCheck pings Redis every interval, exports connection health
+// metrics and flips Available so callers stop waiting on a dead connection.
+// It returns immediately; checks stop when ctx is canceled.
+func (c *Cache) StartHealthCheck(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			c.checkHealth(ctx, logger)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// checkHealth runs one health check and records the result
+func (c *Cache) checkHealth(ctx context.Context, logger *zap.Logger) {
+	pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Client.Ping(pingCtx).Err()
+	latency := time.Since(start)
+	if ctx.Err() != nil {
+		return
+	}
+
+	up := err == nil
+	if wasDown := c.down.Swap(!up); wasDown == up {
+		if up {
+			logger.Info("redis connection recovered", zap.String("mode", c.Mode()))
+		} else {
+			logger.Error("redis unavailable, degrading Redis-backed features",
+				zap.String("mode", c.Mode()),
+				zap.Strings("fail_open", c.failOpenFeatures()),
+				zap.Error(err),
+			)
+		}
+	}
+
+	stats := c.Client.PoolStats()
+	metrics.UpdateRedisHealth(up, latency, stats.TotalConns, stats.IdleConns, stats.StaleConns, stats.Timeouts)
+}
+
+// failOpenFeatures lists the features configured to fail open
+func (c *Cache) failOpenFeatures() []string {
+	features := make([]string, 0, len(c.failOpen))
+	for feature := range c.failOpen {
+		features = append(features, feature)
+	}
+	return features
+}
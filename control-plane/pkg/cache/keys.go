@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Keys are namespaced so one tenant's counters and records can't collide
+// with another's. Tenant-owned keys live under tenant:{tenant id}:, built
+// with TenantKey; keys shared by the whole platform start with one of the
+// platform namespaces, built with PlatformKey. Every Cache method rejects
+// keys in neither.
+const tenantNamespace = "tenant"
+
+// Platform namespaces, for keys that don't belong to a single tenant or are
+// read before the tenant is known
+const (
+	NamespaceAPIKey       = "api_key"         // API key lookups by hash
+	NamespaceAdminAuth    = "admin_auth"      // admin login rate limits and lockouts
+	NamespaceAdminToken   = "admin_token"     // admin token lookups by hash
+	NamespaceAbuse        = "abuse"           // abuse detection counters per API key
+	NamespaceCatalog      = "catalog"         // shared catalog responses
+	NamespaceFeatureFlag  = "feature_flag"    // feature flag cache
+	NamespaceWebhooks     = "webhooks"        // inbound webhook idempotency, e.g. Stripe events
+	NamespaceNotification = "notification"    // delivery idempotency for system events
+	NamespaceScheduler    = "scheduler"       // per-node load tracking
+	NamespaceNodeRequests = "node_requests"   // recent requests per node endpoint
+	NamespaceNodeLogs     = "node_logs"       // launch logs of platform nodes
+	NamespaceSSEPosition  = "sse_position"    // saved SSE stream positions
+	NamespaceMigration    = "cache_migration" // completed key migrations
+	NamespacePlayground   = "playground"      // anonymous playground rate limits per client IP
+)
+
+var platformNamespaces = map[string]bool{
+	NamespaceAPIKey:       true,
+	NamespaceAdminAuth:    true,
+	NamespaceAdminToken:   true,
+	NamespaceAbuse:        true,
+	NamespaceCatalog:      true,
+	NamespaceFeatureFlag:  true,
+	NamespaceWebhooks:     true,
+	NamespaceNotification: true,
+	NamespaceScheduler:    true,
+	NamespaceNodeRequests: true,
+	NamespaceNodeLogs:     true,
+	NamespaceSSEPosition:  true,
+	NamespaceMigration:    true,
+	NamespacePlayground:   true,
+}
+
+// ErrUnscopedKey is returned for keys outside the tenant and platform
+// namespaces
+var ErrUnscopedKey = errors.New("cache: key is not tenant or platform namespaced")
+
+// TenantKey builds a key in the tenant's namespace:
+// tenant:{tenant id}:{parts joined by ":"}
+func TenantKey(tenantID uuid.UUID, parts ...string) string {
+	return tenantNamespace + ":" + tenantID.String() + ":" + strings.Join(parts, ":")
+}
+
+// PlatformKey builds a key in one of the platform namespaces:
+// {namespace}:{parts joined by ":"}
+func PlatformKey(namespace string, parts ...string) string {
+	return namespace + ":" + strings.Join(parts, ":")
+}
+
+// CheckKey reports whether key is in a tenant namespace, with the tenant ID
+// in canonical form, or in a platform namespace
+func CheckKey(key string) error {
+	namespace, rest, _ := strings.Cut(key, ":")
+	if rest == "" {
+		return fmt.Errorf("%w: %q", ErrUnscopedKey, key)
+	}
+	if namespace != tenantNamespace {
+		if !platformNamespaces[namespace] {
+			return fmt.Errorf("%w: %q", ErrUnscopedKey, key)
+		}
+		return nil
+	}
+
+	tenant, rest, _ := strings.Cut(rest, ":")
+	id, err := uuid.Parse(tenant)
+	if err != nil || id == uuid.Nil || id.String() != tenant || rest == "" {
+		return fmt.Errorf("%w: %q has no valid tenant ID", ErrUnscopedKey, key)
+	}
+	return nil
+}
+
+// KeyTenant returns the tenant a tenant-namespaced key belongs to
+func KeyTenant(key string) (uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(key, tenantNamespace+":")
+	if !ok || CheckKey(key) != nil {
+		return uuid.Nil, false
+	}
+	tenant, _, _ := strings.Cut(rest, ":")
+	return uuid.MustParse(tenant), true
+}
+
+// checkKeys returns an error for the first key outside the namespaces
+func checkKeys(keys ...string) error {
+	for _, key := range keys {
+		if err := CheckKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// scanBatch is how many keys each SCAN call asks for
+const scanBatch = 500
+
+// ScanKeys calls fn for every key matching pattern. In cluster mode every
+// master is scanned. Keys are passed as stored, without namespace checks,
+// so legacy keys can be found and moved.
+func (c *Cache) ScanKeys(ctx context.Context, pattern string, fn func(key string) error) error {
+	if err := c.available(); err != nil {
+		return err
+	}
+
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(ctx, cursor, pattern, scanBatch).Result()
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				if err := fn(key); err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	if cluster, ok := c.Client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+			return scan(ctx, master)
+		})
+	}
+	return scan(ctx, c.Client)
+}
+
+// MoveKey renames a legacy key into a namespaced one, keeping its value and
+// expiration. A destination that already exists is left alone and the
+// legacy key is dropped, so data written under the new name wins. It
+// reports whether the key was moved.
+func (c *Cache) MoveKey(ctx context.Context, from, to string) (bool, error) {
+	if err := c.ready(to); err != nil {
+		return false, err
+	}
+
+	if c.Mode() != ModeCluster {
+		moved, err := c.Client.RenameNX(ctx, from, to).Result()
+		if err != nil {
+			if strings.Contains(err.Error(), "no such key") {
+				return false, nil
+			}
+			return false, err
+		}
+		if !moved {
+			return false, c.Client.Del(ctx, from).Err()
+		}
+		return true, nil
+	}
+
+	// The keys usually hash to different slots, which RENAME can't span
+	dump, err := c.Client.Dump(ctx, from).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	ttl, err := c.Client.PTTL(ctx, from).Result()
+	if err != nil {
+		return false, err
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	if err := c.Client.Restore(ctx, to, ttl, dump).Err(); err != nil {
+		if !isBusyKey(err) {
+			return false, fmt.Errorf("failed to restore %q: %w", to, err)
+		}
+		return false, c.Client.Del(ctx, from).Err()
+	}
+	return true, c.Client.Del(ctx, from).Err()
+}
+
+// isBusyKey reports whether RESTORE failed because the target exists
+func isBusyKey(err error) bool {
+	return strings.HasPrefix(err.Error(), "BUSYKEY")
+}
+
+// RunOnce runs a migration unless one with the same name has completed. The
+// completion marker is kept for retain so the migration isn't repeated on
+// every start.
+func (c *Cache) RunOnce(ctx context.Context, name string, retain time.Duration, migrate func(ctx context.Context) error) (bool, error) {
+	marker := PlatformKey(NamespaceMigration, name)
+	done, err := c.Exists(ctx, marker)
+	if err != nil {
+		return false, err
+	}
+	if done > 0 {
+		return false, nil
+	}
+	if err := migrate(ctx); err != nil {
+		return false, err
+	}
+	return true, c.Set(ctx, marker, time.Now().UTC().Format(time.RFC3339), retain)
+}
@@ -12,6 +12,10 @@ import (
 // Database wraps the PostgreSQL connection pool
 type Database struct {
 	Pool *pgxpool.Pool
+
+	// holds tracks which caller holds each acquired connection
+	holds   *connHolds
+	monitor config.PoolMonitorConfig
 }
 
 // NewDatabase creates a new database connection
@@ -35,9 +39,17 @@ func NewDatabase(cfg config.DatabaseConfig) (*Database, error) {
 	poolConfig.MaxConns = int32(cfg.MaxOpenConns)
 	poolConfig.MinConns = int32(cfg.MaxIdleConns)
 	poolConfig.MaxConnLifetime = cfg.ConnMaxLifetime
-	poolConfig.MaxConnIdleTime = 30 * time.Minute
+	poolConfig.MaxConnIdleTime = cfg.ConnMaxIdleTime
+	if poolConfig.MaxConnIdleTime <= 0 {
+		poolConfig.MaxConnIdleTime = 30 * time.Minute
+	}
 	poolConfig.HealthCheckPeriod = 1 * time.Minute
 
+	holds := newConnHolds()
+	poolConfig.BeforeAcquire = holds.acquired
+	poolConfig.AfterRelease = holds.released
+	poolConfig.BeforeClose = holds.closed
+
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
@@ -51,7 +63,7 @@ func NewDatabase(cfg config.DatabaseConfig) (*Database, error) {
 		return nil, fmt.Errorf("unable to ping database: %w", err)
 	}
 
-	return &Database{Pool: pool}, nil
+	return &Database{Pool: pool, holds: holds, monitor: cfg.PoolMonitor}, nil
 }
 
 // Close closes the database connection pool
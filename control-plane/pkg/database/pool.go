@@ -0,0 +1,273 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/config"
+	"github.com/crosslogic/control-plane/pkg/metrics"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// poolPingTimeout bounds a single pool health check ping
+const poolPingTimeout = 2 * time.Second
+
+type callerKey struct{}
+
+// WithCaller labels ctx with the caller, such as "GET /v1/usage", that
+// connections acquired with it are reported under when held too long
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+func callerFrom(ctx context.Context) string {
+	if caller, ok := ctx.Value(callerKey{}).(string); ok {
+		return caller
+	}
+	return "background"
+}
+
+// connHold is one acquired connection
+type connHold struct {
+	caller   string
+	since    time.Time
+	reported bool
+}
+
+// connHolds tracks acquired connections through the pool's acquire and
+// release hooks, so connections held across slow work such as a proxied
+// inference request show up in metrics and logs
+type connHolds struct {
+	mu        sync.Mutex
+	active    map[*pgx.Conn]*connHold
+	warnAfter atomic.Int64 // nanoseconds; zero disables warnings
+	logger    atomic.Pointer[zap.Logger]
+	now       func() time.Time
+}
+
+func newConnHolds() *connHolds {
+	return &connHolds{active: make(map[*pgx.Conn]*connHold), now: time.Now}
+}
+
+// acquired is the pool's BeforeAcquire hook
+func (h *connHolds) acquired(ctx context.Context, conn *pgx.Conn) bool {
+	h.mu.Lock()
+	h.active[conn] = &connHold{caller: callerFrom(ctx), since: h.now()}
+	h.mu.Unlock()
+	return true
+}
+
+// released is the pool's AfterRelease hook
+func (h *connHolds) released(conn *pgx.Conn) bool {
+	h.mu.Lock()
+	hold, ok := h.active[conn]
+	delete(h.active, conn)
+	h.mu.Unlock()
+	if !ok {
+		return true
+	}
+
+	held := h.now().Sub(hold.since)
+	metrics.DatabaseConnHoldSeconds.Observe(held.Seconds())
+	if warnAfter := time.Duration(h.warnAfter.Load()); warnAfter > 0 && held >= warnAfter {
+		metrics.DatabaseLongConnHolds.Inc()
+		// Holds still open at a monitor tick were logged then
+		if logger := h.logger.Load(); logger != nil && !hold.reported {
+			logger.Warn("database connection held for a long time",
+				zap.String("caller", hold.caller),
+				zap.Duration("held", held),
+			)
+		}
+	}
+	return true
+}
+
+// closed is the pool's BeforeClose hook; connections destroyed on release
+// (expired, or released mid-transaction) never reach AfterRelease
+func (h *connHolds) closed(conn *pgx.Conn) {
+	h.mu.Lock()
+	delete(h.active, conn)
+	h.mu.Unlock()
+}
+
+// overdue returns the holds open longer than warnAfter that haven't been
+// reported yet, marking them reported
+func (h *connHolds) overdue(warnAfter time.Duration) []connHold {
+	now := h.now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var overdue []connHold
+	for _, hold := range h.active {
+		if !hold.reported && now.Sub(hold.since) >= warnAfter {
+			hold.reported = true
+			overdue = append(overdue, *hold)
+		}
+	}
+	return overdue
+}
+
+// poolSample is the pool's state at one monitor tick
+type poolSample struct {
+	Total, Idle, Min, Max int32
+	// Waited is the number of acquisitions since the last tick that found
+	// no idle connection
+	Waited  int64
+	Healthy bool
+}
+
+// poolAdjustment returns how many connections adaptive sizing should open
+// (positive) or idle connections it should close (negative). While callers
+// are waiting on a healthy database, spare idle connections are opened
+// ahead of demand; while the database is struggling, idle connections above
+// the pool minimum are handed back so it has fewer sessions to serve.
+func poolAdjustment(s poolSample, spare int32) int32 {
+	if !s.Healthy {
+		excess := s.Total - s.Min
+		if s.Idle < excess {
+			excess = s.Idle
+		}
+		if excess <= 0 {
+			return 0
+		}
+		return -excess
+	}
+	if s.Waited == 0 || s.Idle >= spare {
+		return 0
+	}
+	open := spare - s.Idle
+	if room := s.Max - s.Total; open > room {
+		open = room
+	}
+	if open <= 0 {
+		return 0
+	}
+	return open
+}
+
+// StartPoolMonitor exports pool statistics, logs connections held past
+// the configured threshold and, when enabled, adapts the pool's open
+// connections to load and database health. It returns immediately; the
+// monitor stops when ctx is canceled.
+func (db *Database) StartPoolMonitor(ctx context.Context, logger *zap.Logger) {
+	cfg := db.monitor
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	db.holds.logger.Store(logger)
+	db.holds.warnAfter.Store(int64(cfg.HoldWarnAfter))
+
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		var last *pgxpool.Stat
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			last = db.checkPool(ctx, cfg, last, logger)
+		}
+	}()
+}
+
+// checkPool runs one monitor tick and returns the statistics it read
+func (db *Database) checkPool(ctx context.Context, cfg config.PoolMonitorConfig, last *pgxpool.Stat, logger *zap.Logger) *pgxpool.Stat {
+	pingCtx, cancel := context.WithTimeout(ctx, poolPingTimeout)
+	start := time.Now()
+	err := db.Pool.Ping(pingCtx)
+	latency := time.Since(start)
+	cancel()
+	if ctx.Err() != nil {
+		return last
+	}
+	metrics.UpdateDatabaseHealth(err == nil, latency)
+
+	stat := db.Pool.Stat()
+	var waited int64
+	var avgWait time.Duration
+	if last != nil {
+		waited = stat.EmptyAcquireCount() - last.EmptyAcquireCount()
+		if acquires := stat.AcquireCount() - last.AcquireCount(); acquires > 0 {
+			avgWait = (stat.AcquireDuration() - last.AcquireDuration()) / time.Duration(acquires)
+		}
+	}
+	metrics.UpdateDatabasePool(stat.TotalConns(), stat.AcquiredConns(), stat.IdleConns(), stat.ConstructingConns(), stat.MaxConns(),
+		stat.AcquireCount(), stat.EmptyAcquireCount(), stat.CanceledAcquireCount(), avgWait)
+
+	if cfg.HoldWarnAfter > 0 {
+		for _, hold := range db.holds.overdue(cfg.HoldWarnAfter) {
+			logger.Warn("database connection still held",
+				zap.String("caller", hold.caller),
+				zap.Duration("held", time.Since(hold.since)),
+			)
+		}
+	}
+
+	if cfg.Adaptive && last != nil {
+		healthy := err == nil && (cfg.SlowPing <= 0 || latency < cfg.SlowPing)
+		adjust := poolAdjustment(poolSample{
+			Total:   stat.TotalConns(),
+			Idle:    stat.IdleConns(),
+			Min:     db.Pool.Config().MinConns,
+			Max:     stat.MaxConns(),
+			Waited:  waited,
+			Healthy: healthy,
+		}, int32(cfg.SpareConns))
+		switch {
+		case adjust > 0:
+			db.openConns(ctx, int(stat.IdleConns()), int(adjust), logger)
+		case adjust < 0:
+			logger.Warn("database is slow, releasing idle pool connections",
+				zap.Duration("ping", latency),
+				zap.Int32("closing", -adjust),
+				zap.Error(err),
+			)
+			db.closeIdleConns(ctx, int(-adjust))
+		}
+	}
+	return stat
+}
+
+// openConns opens n connections and returns them to the pool idle. The
+// idle connections are acquired first, so all of them are held at once
+// for the pool to construct the new ones.
+func (db *Database) openConns(ctx context.Context, idle, n int, logger *zap.Logger) {
+	conns := make([]*pgxpool.Conn, 0, idle+n)
+	for i := 0; i < idle+n; i++ {
+		acquireCtx, cancel := context.WithTimeout(ctx, poolPingTimeout)
+		conn, err := db.Pool.Acquire(acquireCtx)
+		cancel()
+		if err != nil {
+			logger.Debug("failed to open spare database connection", zap.Error(err))
+			break
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		conn.Release()
+	}
+	if opened := len(conns) - idle; opened > 0 {
+		metrics.DatabasePoolAdjustments.WithLabelValues("open").Add(float64(opened))
+	}
+}
+
+// closeIdleConns closes up to n idle connections
+func (db *Database) closeIdleConns(ctx context.Context, n int) {
+	closed := 0
+	for _, conn := range db.Pool.AcquireAllIdle(ctx) {
+		if closed < n {
+			// A closed connection is destroyed rather than returned on release
+			conn.Conn().Close(ctx)
+			closed++
+		}
+		conn.Release()
+	}
+	metrics.DatabasePoolAdjustments.WithLabelValues("close").Add(float64(closed))
+}
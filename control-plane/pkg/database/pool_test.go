@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestPoolAdjustment(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample poolSample
+		spare  int32
+		want   int32
+	}{
+		{"quiet", poolSample{Total: 5, Idle: 1, Min: 5, Max: 25, Healthy: true}, 2, 0},
+		{"waiting opens spares", poolSample{Total: 10, Idle: 0, Min: 5, Max: 25, Waited: 40, Healthy: true}, 2, 2},
+		{"waiting tops up spares", poolSample{Total: 10, Idle: 1, Min: 5, Max: 25, Waited: 3, Healthy: true}, 2, 1},
+		{"enough spares", poolSample{Total: 10, Idle: 3, Min: 5, Max: 25, Waited: 3, Healthy: true}, 2, 0},
+		{"never past max", poolSample{Total: 24, Idle: 0, Min: 5, Max: 25, Waited: 9, Healthy: true}, 2, 1},
+		{"at max", poolSample{Total: 25, Idle: 0, Min: 5, Max: 25, Waited: 9, Healthy: true}, 2, 0},
+		{"unhealthy releases idle", poolSample{Total: 12, Idle: 4, Min: 5, Max: 25, Waited: 9}, 2, -4},
+		{"unhealthy keeps minimum", poolSample{Total: 7, Idle: 4, Min: 5, Max: 25}, 2, -2},
+		{"unhealthy at minimum", poolSample{Total: 5, Idle: 5, Min: 5, Max: 25}, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := poolAdjustment(tt.sample, tt.spare); got != tt.want {
+				t.Errorf("poolAdjustment() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnHolds(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	holds := newConnHolds()
+	holds.now = func() time.Time { return now }
+
+	short, long := &pgx.Conn{}, &pgx.Conn{}
+	holds.acquired(WithCaller(context.Background(), "POST /v1/chat/completions"), long)
+	now = now.Add(20 * time.Second)
+	holds.acquired(context.Background(), short)
+
+	overdue := holds.overdue(10 * time.Second)
+	if len(overdue) != 1 || overdue[0].caller != "POST /v1/chat/completions" {
+		t.Fatalf("overdue() = %+v, want the chat completion hold", overdue)
+	}
+	if again := holds.overdue(10 * time.Second); len(again) != 0 {
+		t.Errorf("overdue() reported a hold twice: %+v", again)
+	}
+
+	if !holds.released(long) || !holds.released(short) {
+		t.Error("released() must return connections to the pool")
+	}
+	holds.acquired(context.Background(), short)
+	holds.closed(short)
+	if len(holds.active) != 0 {
+		t.Errorf("%d holds left after release and close", len(holds.active))
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier is the subset of pgx shared by *pgxpool.Pool and pgx.Tx.
+// Repositories depend on it so they can run inside a transaction or
+// against a fake in tests.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Args collects positional query arguments and hands out their placeholders,
+// so dynamically built queries never have to number parameters by hand.
+type Args struct {
+	values []any
+}
+
+// NewArgs creates an argument list seeded with fixed leading arguments
+func NewArgs(values ...any) *Args {
+	return &Args{values: values}
+}
+
+// Add appends an argument and returns its placeholder, e.g. "$3"
+func (a *Args) Add(value any) string {
+	a.values = append(a.values, value)
+	return "$" + strconv.Itoa(len(a.values))
+}
+
+// Values returns the arguments in placeholder order
+func (a *Args) Values() []any {
+	return a.values
+}
+
+// Len returns the number of arguments added so far
+func (a *Args) Len() int {
+	return len(a.values)
+}
+
+// Where accumulates AND-ed conditions whose values become placeholders
+type Where struct {
+	args       *Args
+	conditions []string
+}
+
+// NewWhere creates a condition list that numbers placeholders through args
+func NewWhere(args *Args) *Where {
+	return &Where{args: args}
+}
+
+// Raw adds a condition that takes no arguments or refers to arguments
+// already added to the shared Args
+func (w *Where) Raw(condition string) *Where {
+	w.conditions = append(w.conditions, condition)
+	return w
+}
+
+// Eq adds "column = $n"
+func (w *Where) Eq(column string, value any) *Where {
+	return w.Raw(column + " = " + w.args.Add(value))
+}
+
+// String renders the conditions as a WHERE clause, or "" when there are none
+func (w *Where) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// UpdateSet builds the SET list of an UPDATE from optional fields
+type UpdateSet struct {
+	args        *Args
+	assignments []string
+}
+
+// NewUpdateSet creates an empty SET list numbering placeholders through args
+func NewUpdateSet(args *Args) *UpdateSet {
+	return &UpdateSet{args: args}
+}
+
+// Set adds "column = $n"
+func (u *UpdateSet) Set(column string, value any) *UpdateSet {
+	u.assignments = append(u.assignments, column+" = "+u.args.Add(value))
+	return u
+}
+
+// Raw adds an assignment that takes no arguments, e.g. "updated_at = NOW()"
+func (u *UpdateSet) Raw(assignment string) *UpdateSet {
+	u.assignments = append(u.assignments, assignment)
+	return u
+}
+
+// Empty reports whether no assignments were added
+func (u *UpdateSet) Empty() bool {
+	return len(u.assignments) == 0
+}
+
+// String renders the comma separated assignments
+func (u *UpdateSet) String() string {
+	return strings.Join(u.assignments, ", ")
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArgsPlaceholdersPastNine(t *testing.T) {
+	args := NewArgs("tenant")
+	var last string
+	for i := 0; i < 11; i++ {
+		last = args.Add(i)
+	}
+	if last != "$12" {
+		t.Errorf("Add() placeholder = %s, want $12", last)
+	}
+	if args.Len() != 12 {
+		t.Errorf("Len() = %d, want 12", args.Len())
+	}
+}
+
+func TestWhere(t *testing.T) {
+	args := NewArgs()
+	if got := NewWhere(args).String(); got != "" {
+		t.Errorf("empty Where = %q, want empty", got)
+	}
+
+	where := NewWhere(args).Eq("tenant_id", "t1").Raw("deleted_at IS NULL")
+	where.Raw("created_at >= " + args.Add("2024-01-01"))
+	if got, want := where.String(), " WHERE tenant_id = $1 AND deleted_at IS NULL AND created_at >= $2"; got != want {
+		t.Errorf("Where = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(args.Values(), []any{"t1", "2024-01-01"}) {
+		t.Errorf("Values() = %v", args.Values())
+	}
+}
+
+func TestUpdateSet(t *testing.T) {
+	args := NewArgs()
+	set := NewUpdateSet(args)
+	if !set.Empty() {
+		t.Error("new UpdateSet should be empty")
+	}
+
+	set.Set("name", "eu-west").Set("status", "active").Raw("updated_at = NOW()")
+	if got, want := set.String(), "name = $1, status = $2, updated_at = NOW()"; got != want {
+		t.Errorf("UpdateSet = %q, want %q", got, want)
+	}
+	if got := args.Add("id"); got != "$3" {
+		t.Errorf("next placeholder = %s, want $3", got)
+	}
+}
@@ -14,14 +14,18 @@ type Handler func(ctx context.Context, event Event) error
 // Bus is an in-memory event bus for pub/sub messaging
 type Bus struct {
 	handlers map[EventType][]Handler
-	mu       sync.RWMutex
-	logger   *zap.Logger
+	// all receive every event, keyed for unsubscribing
+	all     map[int]Handler
+	nextAll int
+	mu      sync.RWMutex
+	logger  *zap.Logger
 }
 
 // NewBus creates a new event bus
 func NewBus(logger *zap.Logger) *Bus {
 	return &Bus{
 		handlers: make(map[EventType][]Handler),
+		all:      make(map[int]Handler),
 		logger:   logger,
 	}
 }
@@ -39,13 +43,45 @@ func (b *Bus) Subscribe(eventType EventType, handler Handler) {
 	)
 }
 
+// SubscribeAll registers a handler for every event type, for consumers such
+// as live admin streams that come and go. Call the returned function to
+// remove the handler.
+func (b *Bus) SubscribeAll(handler Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextAll
+	b.nextAll++
+	b.all[id] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.all, id)
+	}
+}
+
+// handlersFor returns the handlers an event is delivered to
+func (b *Bus) handlersFor(eventType EventType) []Handler {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	typed := b.handlers[eventType]
+	if len(b.all) == 0 {
+		return typed
+	}
+	handlers := make([]Handler, 0, len(typed)+len(b.all))
+	handlers = append(handlers, typed...)
+	for _, handler := range b.all {
+		handlers = append(handlers, handler)
+	}
+	return handlers
+}
+
 // Publish publishes an event to all registered handlers
 // Handlers are called asynchronously in separate goroutines
 // Errors from handlers are logged but don't block the publisher
 func (b *Bus) Publish(ctx context.Context, event Event) error {
-	b.mu.RLock()
-	handlers := b.handlers[event.Type]
-	b.mu.RUnlock()
+	handlers := b.handlersFor(event.Type)
 
 	if len(handlers) == 0 {
 		b.logger.Debug("no handlers registered for event type",
@@ -97,9 +133,7 @@ func (b *Bus) Publish(ctx context.Context, event Event) error {
 // PublishAndWait publishes an event and waits for all handlers to complete
 // Returns the first error encountered from any handler
 func (b *Bus) PublishAndWait(ctx context.Context, event Event) error {
-	b.mu.RLock()
-	handlers := b.handlers[event.Type]
-	b.mu.RUnlock()
+	handlers := b.handlersFor(event.Type)
 
 	if len(handlers) == 0 {
 		return nil
@@ -0,0 +1,31 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSubscribeAll(t *testing.T) {
+	bus := NewBus(zap.NewNop())
+	var typed, all []EventType
+	bus.Subscribe(EventNodeDraining, func(ctx context.Context, event Event) error {
+		typed = append(typed, event.Type)
+		return nil
+	})
+	unsubscribe := bus.SubscribeAll(func(ctx context.Context, event Event) error {
+		all = append(all, event.Type)
+		return nil
+	})
+
+	ctx := context.Background()
+	bus.PublishAndWait(ctx, NewEvent(EventNodeDraining, "", nil))
+	bus.PublishAndWait(ctx, NewEvent(EventPaymentFailed, "", nil))
+	unsubscribe()
+	bus.PublishAndWait(ctx, NewEvent(EventPaymentFailed, "", nil))
+
+	if len(typed) != 1 || len(all) != 2 || all[1] != EventPaymentFailed {
+		t.Errorf("typed = %v, all = %v", typed, all)
+	}
+}
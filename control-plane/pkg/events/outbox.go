@@ -0,0 +1,225 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"go.uber.org/zap"
+)
+
+const (
+	// outboxPollInterval is how often the relay looks for pending events
+	outboxPollInterval = 1 * time.Second
+
+	// outboxBatchSize caps the events claimed per poll
+	outboxBatchSize = 100
+
+	// outboxLease is how long a claimed event is hidden from other relays.
+	// Events whose relay died mid-delivery are retried once it expires.
+	outboxLease = 2 * time.Minute
+
+	// outboxMaxAttempts is how many deliveries are tried before giving up
+	outboxMaxAttempts = 10
+
+	// outboxRetention is how long delivered events are kept for auditing
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// Enqueue records an event in the transactional outbox. Pass the transaction
+// that makes the state change the event describes, so the event is stored if
+// and only if the change commits. The OutboxRelay publishes it afterwards.
+func Enqueue(ctx context.Context, q database.Querier, event Event) error {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO event_outbox (event_id, event_type, tenant_id, payload, occurred_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+	`, event.ID, string(event.Type), event.TenantID, payload, event.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s event: %w", event.Type, err)
+	}
+	return nil
+}
+
+// OutboxRelay publishes outbox events to the bus and marks them delivered.
+// Delivery is at-least-once: an event is retried if any handler fails or the
+// relay stops before recording the delivery, so handlers must tolerate seeing
+// the same event ID twice. Several replicas can relay concurrently.
+type OutboxRelay struct {
+	db       *database.Database
+	bus      *Bus
+	logger   *zap.Logger
+	interval time.Duration
+}
+
+// NewOutboxRelay creates a relay for the event_outbox table
+func NewOutboxRelay(db *database.Database, bus *Bus, logger *zap.Logger) *OutboxRelay {
+	return &OutboxRelay{
+		db:       db,
+		bus:      bus,
+		logger:   logger,
+		interval: outboxPollInterval,
+	}
+}
+
+// Start begins relaying in the background until ctx is cancelled
+func (r *OutboxRelay) Start(ctx context.Context) {
+	r.logger.Info("starting event outbox relay")
+	go r.relayLoop(ctx)
+}
+
+func (r *OutboxRelay) relayLoop(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep draining while full batches come back
+			for {
+				n, err := r.relayBatch(ctx)
+				if err != nil {
+					r.logger.Error("failed to relay outbox events", zap.Error(err))
+					break
+				}
+				if n < outboxBatchSize {
+					break
+				}
+			}
+		case <-cleanup.C:
+			if _, err := r.db.Pool.Exec(ctx, `
+				DELETE FROM event_outbox WHERE delivered_at < NOW() - make_interval(secs => $1)
+			`, outboxRetention.Seconds()); err != nil {
+				r.logger.Warn("failed to prune delivered outbox events", zap.Error(err))
+			}
+		}
+	}
+}
+
+type outboxEntry struct {
+	id       int64
+	attempts int
+	event    Event
+}
+
+// relayBatch claims a batch of pending events, publishes them in the order
+// they were written and records the outcome. It returns the number claimed.
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		UPDATE event_outbox
+		SET locked_until = NOW() + make_interval(secs => $2), attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM event_outbox
+			WHERE delivered_at IS NULL AND failed_at IS NULL
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, attempts, event_id, event_type, COALESCE(tenant_id, ''), payload, occurred_at
+	`, outboxBatchSize, outboxLease.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	var batch []outboxEntry
+	for rows.Next() {
+		var e outboxEntry
+		var eventType string
+		var payload []byte
+		if err := rows.Scan(&e.id, &e.attempts, &e.event.ID, &eventType, &e.event.TenantID, &payload, &e.event.Timestamp); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		e.event.Type = EventType(eventType)
+		if err := json.Unmarshal(payload, &e.event.Payload); err != nil {
+			r.logger.Warn("failed to decode outbox event payload",
+				zap.Int64("outbox_id", e.id),
+				zap.String("event_id", e.event.ID),
+				zap.Error(err),
+			)
+		}
+		batch = append(batch, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read outbox events: %w", err)
+	}
+
+	sort.Slice(batch, func(i, j int) bool { return batch[i].id < batch[j].id })
+
+	for _, e := range batch {
+		if err := r.bus.PublishAndWait(ctx, e.event); err != nil {
+			r.recordFailure(ctx, e, err)
+			continue
+		}
+		if _, err := r.db.Pool.Exec(ctx, `
+			UPDATE event_outbox SET delivered_at = NOW(), locked_until = NULL, last_error = NULL WHERE id = $1
+		`, e.id); err != nil {
+			// The lease expires and the event is delivered again
+			r.logger.Warn("failed to mark outbox event delivered",
+				zap.Int64("outbox_id", e.id),
+				zap.String("event_id", e.event.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return len(batch), nil
+}
+
+// recordFailure schedules a retry with backoff, or gives up after
+// outboxMaxAttempts so a poison event cannot block the relay forever
+func (r *OutboxRelay) recordFailure(ctx context.Context, e outboxEntry, deliveryErr error) {
+	giveUp := e.attempts >= outboxMaxAttempts
+	_, err := r.db.Pool.Exec(ctx, `
+		UPDATE event_outbox
+		SET last_error = $2,
+		    locked_until = NOW() + make_interval(secs => $3),
+		    failed_at = CASE WHEN $4 THEN NOW() END
+		WHERE id = $1
+	`, e.id, deliveryErr.Error(), outboxRetryDelay(e.attempts).Seconds(), giveUp)
+	if err != nil {
+		r.logger.Warn("failed to record outbox delivery failure", zap.Int64("outbox_id", e.id), zap.Error(err))
+	}
+
+	log := r.logger.Warn
+	if giveUp {
+		log = r.logger.Error
+	}
+	log("outbox event delivery failed",
+		zap.Int64("outbox_id", e.id),
+		zap.String("event_id", e.event.ID),
+		zap.String("event_type", string(e.event.Type)),
+		zap.Int("attempts", e.attempts),
+		zap.Bool("gave_up", giveUp),
+		zap.Error(deliveryErr),
+	)
+}
+
+// outboxRetryDelay backs off exponentially from 5s, capped at 30 minutes
+func outboxRetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := 5 * time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= 30*time.Minute {
+			return 30 * time.Minute
+		}
+	}
+	return delay
+}
@@ -12,6 +12,7 @@ const (
 	EventTenantDeleted   EventType = "tenant.deleted"
 	EventTenantSuspended EventType = "tenant.suspended"
 	EventTenantActivated EventType = "tenant.activated"
+	EventTenantPlanChanged EventType = "tenant.plan_changed"
 
 	// Payment events
 	EventPaymentSucceeded EventType = "payment.succeeded"
@@ -20,20 +21,53 @@ const (
 
 	// Node events
 	EventNodeLaunched         EventType = "node.launched"
+	EventNodeRegistered       EventType = "node.registered"
 	EventNodeTerminated       EventType = "node.terminated"
 	EventNodeHealthChanged    EventType = "node.health_changed"
 	EventNodeHealthDegraded   EventType = "node.health_degraded"
 	EventNodeDraining         EventType = "node.draining"
+	EventNodeStandbyPromoted  EventType = "node.standby_promoted"
+
+	// Deployment capacity incidents, raised when serving nodes stay below a
+	// deployment's minimum
+	EventDeploymentCapacityDegraded  EventType = "deployment.capacity_degraded"
+	EventDeploymentCapacityUpdated   EventType = "deployment.capacity_updated"
+	EventDeploymentCapacityRecovered EventType = "deployment.capacity_recovered"
+
+	// Deployment autoscaling on queue depth and latency
+	EventDeploymentScaledUp   EventType = "deployment.scaled_up"
+	EventDeploymentScaledDown EventType = "deployment.scaled_down"
 
 	// Cost events
 	EventCostAnomalyDetected EventType = "cost.anomaly_detected"
+	EventBudgetExceeded      EventType = "cost.budget_exceeded"
+
+	// Tenant-defined usage alert rules
+	EventUsageAlertTriggered EventType = "usage.alert_triggered"
+	EventUsageAlertResolved  EventType = "usage.alert_resolved"
+
+	// Model lifecycle events
+	EventModelDeprecationReminder EventType = "model.deprecation_reminder"
+	EventModelCircuitOpened       EventType = "model.circuit_opened"
+	EventModelCircuitClosed       EventType = "model.circuit_closed"
+	EventModelCatalogChanged      EventType = "model.catalog_changed"
+
+	// Maintenance events
+	EventMaintenanceScheduled EventType = "maintenance.scheduled"
+	EventMaintenanceCancelled EventType = "maintenance.cancelled"
+
+	// Emergency kill switch events
+	EventKillSwitchActivated EventType = "kill_switch.activated"
+	EventKillSwitchReleased  EventType = "kill_switch.released"
 
 	// Rate limit events
 	EventRateLimitThreshold EventType = "ratelimit.threshold_reached"
 
 	// API key events
-	EventAPIKeyCreated EventType = "apikey.created"
-	EventAPIKeyRevoked EventType = "apikey.revoked"
+	EventAPIKeyCreated     EventType = "apikey.created"
+	EventAPIKeyRevoked     EventType = "apikey.revoked"
+	EventAPIKeyThrottled   EventType = "apikey.throttled"
+	EventAPIKeyQuarantined EventType = "apikey.quarantined"
 )
 
 // Event represents a single event in the system
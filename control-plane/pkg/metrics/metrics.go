@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -63,6 +65,106 @@ var (
 		},
 		[]string{"node_id", "model_name"},
 	)
+
+	// Billing reconciliation
+	TokenDiscrepancyPercent = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "billing_token_discrepancy_percent",
+			Help: "Difference between billed and vLLM reported tokens for the last reconciled hour",
+		},
+		[]string{"node_id"},
+	)
+
+	// Redis connection health
+	RedisUp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "redis_up",
+			Help: "Whether the last Redis health check succeeded (1) or failed (0)",
+		},
+	)
+
+	RedisPingSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "redis_ping_seconds",
+			Help: "Latency of the last Redis health check",
+		},
+	)
+
+	RedisPoolConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "redis_pool_connections",
+			Help: "Redis connection pool size by state (total, idle, stale)",
+		},
+		[]string{"state"},
+	)
+
+	RedisPoolTimeouts = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "redis_pool_timeouts",
+			Help: "Times a caller waited too long for a Redis connection since startup",
+		},
+	)
+
+	// Postgres connection pool
+	DatabaseUp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_up",
+			Help: "Whether the last database health check succeeded (1) or failed (0)",
+		},
+	)
+
+	DatabasePingSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_ping_seconds",
+			Help: "Latency of the last database health check",
+		},
+	)
+
+	DatabasePoolConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_pool_connections",
+			Help: "Postgres connection pool size by state (total, acquired, idle, constructing, max)",
+		},
+		[]string{"state"},
+	)
+
+	DatabasePoolAcquires = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_pool_acquires",
+			Help: "Connection acquisitions since startup by result (total, waited, canceled)",
+		},
+		[]string{"result"},
+	)
+
+	DatabasePoolAcquireWaitSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_pool_acquire_wait_seconds",
+			Help: "Average time to acquire a connection over the last monitoring interval",
+		},
+	)
+
+	DatabaseConnHoldSeconds = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "db_pool_conn_hold_seconds",
+			Help:    "How long callers held a pooled connection before releasing it",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+		},
+	)
+
+	DatabaseLongConnHolds = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "db_pool_long_holds_total",
+			Help: "Connections held past the warning threshold",
+		},
+	)
+
+	DatabasePoolAdjustments = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_pool_adjustments_total",
+			Help: "Connections opened or closed by adaptive pool sizing (action: open, close)",
+		},
+		[]string{"action"},
+	)
 )
 
 // UpdateCostMetrics updates cost metrics for a tenant
@@ -79,3 +181,46 @@ func UpdateQueueMetrics(nodeID, modelName string, depth, active int64) {
 	QueueDepth.WithLabelValues(nodeID, modelName).Set(float64(depth))
 	ActiveRequests.WithLabelValues(nodeID, modelName).Set(float64(active))
 }
+
+// UpdateTokenDiscrepancy records the last reconciled token discrepancy for a node
+func UpdateTokenDiscrepancy(nodeID string, percent float64) {
+	TokenDiscrepancyPercent.WithLabelValues(nodeID).Set(percent)
+}
+
+// UpdateRedisHealth records the outcome of a Redis health check and the
+// connection pool state
+func UpdateRedisHealth(up bool, latency time.Duration, totalConns, idleConns, staleConns, timeouts uint32) {
+	if up {
+		RedisUp.Set(1)
+	} else {
+		RedisUp.Set(0)
+	}
+	RedisPingSeconds.Set(latency.Seconds())
+	RedisPoolConnections.WithLabelValues("total").Set(float64(totalConns))
+	RedisPoolConnections.WithLabelValues("idle").Set(float64(idleConns))
+	RedisPoolConnections.WithLabelValues("stale").Set(float64(staleConns))
+	RedisPoolTimeouts.Set(float64(timeouts))
+}
+
+// UpdateDatabaseHealth records the result of a database health check
+func UpdateDatabaseHealth(up bool, latency time.Duration) {
+	if up {
+		DatabaseUp.Set(1)
+	} else {
+		DatabaseUp.Set(0)
+	}
+	DatabasePingSeconds.Set(latency.Seconds())
+}
+
+// UpdateDatabasePool records Postgres connection pool statistics
+func UpdateDatabasePool(total, acquired, idle, constructing, max int32, acquires, waited, canceled int64, avgWait time.Duration) {
+	DatabasePoolConnections.WithLabelValues("total").Set(float64(total))
+	DatabasePoolConnections.WithLabelValues("acquired").Set(float64(acquired))
+	DatabasePoolConnections.WithLabelValues("idle").Set(float64(idle))
+	DatabasePoolConnections.WithLabelValues("constructing").Set(float64(constructing))
+	DatabasePoolConnections.WithLabelValues("max").Set(float64(max))
+	DatabasePoolAcquires.WithLabelValues("total").Set(float64(acquires))
+	DatabasePoolAcquires.WithLabelValues("waited").Set(float64(waited))
+	DatabasePoolAcquires.WithLabelValues("canceled").Set(float64(canceled))
+	DatabasePoolAcquireWaitSeconds.Set(avgWait.Seconds())
+}
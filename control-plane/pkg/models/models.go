@@ -54,6 +54,27 @@ type APIKey struct {
 	LastUsedAt              *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
 	ExpiresAt               *time.Time `json:"expires_at,omitempty" db:"expires_at"`
 	Metadata                string     `json:"metadata" db:"metadata"` // JSON
+	// Budget is the shared budget of the key's environment with the key's
+	// sub-limits, loaded on authentication; nil when the environment has none
+	Budget *EnvironmentBudget `json:"budget,omitempty" db:"-"`
+}
+
+// EnvironmentBudget is a token and/or spend budget shared by every API key
+// of an environment. Keys may have sub-limits within it. Nil limits are
+// unlimited.
+type EnvironmentBudget struct {
+	EnvironmentID     uuid.UUID `json:"environment_id" db:"environment_id"`
+	Period            string    `json:"period" db:"period"` // daily or monthly (UTC)
+	LimitTokens       *int64    `json:"limit_tokens,omitempty" db:"limit_tokens"`
+	LimitMicrodollars *int64    `json:"limit_microdollars,omitempty" db:"limit_microdollars"`
+	// KeyLimitTokens and KeyLimitMicrodollars are the key's sub-limits
+	KeyLimitTokens       *int64 `json:"key_limit_tokens,omitempty" db:"key_limit_tokens"`
+	KeyLimitMicrodollars *int64 `json:"key_limit_microdollars,omitempty" db:"key_limit_microdollars"`
+}
+
+// LimitsSpend reports whether the budget or the key's sub-limit caps spend
+func (b *EnvironmentBudget) LimitsSpend() bool {
+	return b != nil && (b.LimitMicrodollars != nil || b.KeyLimitMicrodollars != nil)
 }
 
 // Region represents a geographical region
@@ -83,6 +104,13 @@ type Model struct {
 	PriceOutputPerMillion   float64   `json:"price_output_per_million" db:"price_output_per_million"`
 	TokensPerSecondCapacity *int      `json:"tokens_per_second_capacity,omitempty" db:"tokens_per_second_capacity"`
 	Status                  string    `json:"status" db:"status"`
+	SupportsTools           bool      `json:"supports_tools" db:"supports_tools"`
+	SupportsVision          bool      `json:"supports_vision" db:"supports_vision"`
+	SupportsJSONMode        bool      `json:"supports_json_mode" db:"supports_json_mode"`
+	SupportsGuidedDecoding  bool      `json:"supports_guided_decoding" db:"supports_guided_decoding"`
+	MaxOutputTokens         *int      `json:"max_output_tokens,omitempty" db:"max_output_tokens"`
+	PricePerAudioMinute     *float64  `json:"price_per_audio_minute,omitempty" db:"price_per_audio_minute"`
+	PricePerImage           *float64  `json:"price_per_image,omitempty" db:"price_per_image"`
 	Metadata                string    `json:"metadata" db:"metadata"` // JSON
 	CreatedAt               time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt               time.Time `json:"updated_at" db:"updated_at"`
@@ -123,6 +151,8 @@ type UsageRecord struct {
 	RegionID         *uuid.UUID `json:"region_id,omitempty" db:"region_id"`
 	ModelID          *uuid.UUID `json:"model_id,omitempty" db:"model_id"`
 	NodeID           *uuid.UUID `json:"node_id,omitempty" db:"node_id"`
+	GPUType          *string    `json:"gpu_type,omitempty" db:"gpu_type"`
+	StatusCode       *int       `json:"status_code,omitempty" db:"status_code"`
 	PromptTokens     int        `json:"prompt_tokens" db:"prompt_tokens"`
 	CompletionTokens int        `json:"completion_tokens" db:"completion_tokens"`
 	TotalTokens      int        `json:"total_tokens" db:"total_tokens"`
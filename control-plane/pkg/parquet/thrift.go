@@ -0,0 +1,104 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol type codes used by the Parquet metadata
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// compactWriter encodes Thrift structs with the compact protocol, which is
+// how Parquet stores page headers and the file footer. It supports only
+// the field types the writer needs.
+type compactWriter struct {
+	buf bytes.Buffer
+	// lastField holds the previous field ID of each open struct; field
+	// headers encode the delta to it
+	lastField []int16
+}
+
+func newCompactWriter() *compactWriter {
+	return &compactWriter{lastField: []int16{0}}
+}
+
+func (c *compactWriter) Bytes() []byte {
+	return c.buf.Bytes()
+}
+
+func (c *compactWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	c.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (c *compactWriter) zigzag(v int64) {
+	c.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (c *compactWriter) fieldHeader(id int16, typ byte) {
+	last := &c.lastField[len(c.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		c.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		c.buf.WriteByte(typ)
+		c.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (c *compactWriter) i32(id int16, v int32) {
+	c.fieldHeader(id, thriftI32)
+	c.zigzag(int64(v))
+}
+
+func (c *compactWriter) i64(id int16, v int64) {
+	c.fieldHeader(id, thriftI64)
+	c.zigzag(v)
+}
+
+func (c *compactWriter) string(id int16, v string) {
+	c.fieldHeader(id, thriftBinary)
+	c.varint(uint64(len(v)))
+	c.buf.WriteString(v)
+}
+
+// list writes a list field header; the caller then writes size elements
+func (c *compactWriter) list(id int16, elemType byte, size int) {
+	c.fieldHeader(id, thriftList)
+	if size < 15 {
+		c.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	c.buf.WriteByte(0xf0 | elemType)
+	c.varint(uint64(size))
+}
+
+// i32Elem and stringElem write list elements
+func (c *compactWriter) i32Elem(v int32) {
+	c.zigzag(int64(v))
+}
+
+func (c *compactWriter) stringElem(v string) {
+	c.varint(uint64(len(v)))
+	c.buf.WriteString(v)
+}
+
+// beginStruct opens a struct field, or a list element when id is 0
+func (c *compactWriter) beginStruct(id int16) {
+	if id != 0 {
+		c.fieldHeader(id, thriftStruct)
+	}
+	c.lastField = append(c.lastField, 0)
+}
+
+// endStruct closes the innermost open struct
+func (c *compactWriter) endStruct() {
+	c.buf.WriteByte(0)
+	c.lastField = c.lastField[:len(c.lastField)-1]
+}
@@ -0,0 +1,421 @@
+// Package parquet writes Apache Parquet files with flat schemas. It covers
+// what the control plane's exports need: required and optional columns of
+// booleans, integers, doubles, strings and timestamps, PLAIN encoded and
+// gzip compressed, with one data page per column in each row group. Rows are
+// buffered one row group at a time, so files of any size stream to the
+// underlying writer.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// magic opens and closes every Parquet file
+const magic = "PAR1"
+
+// DefaultRowGroupRows is how many rows a row group holds unless changed
+const DefaultRowGroupRows = 64 * 1024
+
+// Type is a column's value type
+type Type int
+
+const (
+	Boolean Type = iota
+	Int64
+	Double
+	String
+	// Timestamp is stored as microseconds since the Unix epoch, UTC
+	Timestamp
+)
+
+// Parquet physical types, converted types, encodings and codecs
+const (
+	physicalBoolean   = 0
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecGzip = 2
+
+	pageTypeData = 0
+)
+
+// Column describes one column of a file. Optional columns accept nil.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+func (c Column) physicalType() int32 {
+	switch c.Type {
+	case Boolean:
+		return physicalBoolean
+	case Double:
+		return physicalDouble
+	case String:
+		return physicalByteArray
+	}
+	return physicalInt64
+}
+
+// Writer writes rows to a Parquet file
+type Writer struct {
+	// RowGroupRows is how many rows each row group holds; change it before
+	// the first Write
+	RowGroupRows int
+
+	out     *countingWriter
+	columns []Column
+	chunks  []columnBuffer
+	rows    int
+	numRows int64
+	groups  []rowGroupMeta
+	gz      *gzip.Writer
+	closed  bool
+}
+
+// columnBuffer holds a column's values in the current row group
+type columnBuffer struct {
+	defined []bool // optional columns only
+	bools   []bool // boolean columns only
+	values  bytes.Buffer
+}
+
+// columnChunkMeta locates a written column chunk
+type columnChunkMeta struct {
+	column            Column
+	numValues         int64
+	totalUncompressed int64
+	totalCompressed   int64
+	dataPageOffset    int64
+}
+
+type rowGroupMeta struct {
+	columns       []columnChunkMeta
+	totalByteSize int64
+	numRows       int64
+}
+
+// NewWriter starts a Parquet file with the given columns on w
+func NewWriter(w io.Writer, columns []Column) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("parquet: no columns")
+	}
+	seen := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		if c.Name == "" || seen[c.Name] {
+			return nil, fmt.Errorf("parquet: invalid or duplicate column name %q", c.Name)
+		}
+		seen[c.Name] = true
+	}
+
+	pw := &Writer{
+		RowGroupRows: DefaultRowGroupRows,
+		out:          &countingWriter{w: w},
+		columns:      columns,
+		chunks:       make([]columnBuffer, len(columns)),
+		gz:           gzip.NewWriter(io.Discard),
+	}
+	if _, err := pw.out.Write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Write appends a row holding one value per column: bool, int64 (or int),
+// float64, string or time.Time by column type, or nil in optional columns
+func (w *Writer) Write(values ...interface{}) error {
+	if w.closed {
+		return errors.New("parquet: write to closed writer")
+	}
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values for %d columns", len(values), len(w.columns))
+	}
+	for i, v := range values {
+		if err := checkValue(w.columns[i], v); err != nil {
+			return err
+		}
+	}
+
+	for i, v := range values {
+		col, chunk := w.columns[i], &w.chunks[i]
+		if col.Optional {
+			chunk.defined = append(chunk.defined, v != nil)
+		}
+		if v == nil {
+			continue
+		}
+		switch col.Type {
+		case Boolean:
+			chunk.bools = append(chunk.bools, v.(bool))
+		case Int64:
+			n, ok := v.(int64)
+			if !ok {
+				n = int64(v.(int))
+			}
+			binary.Write(&chunk.values, binary.LittleEndian, n)
+		case Double:
+			binary.Write(&chunk.values, binary.LittleEndian, math.Float64bits(v.(float64)))
+		case String:
+			s := v.(string)
+			binary.Write(&chunk.values, binary.LittleEndian, uint32(len(s)))
+			chunk.values.WriteString(s)
+		case Timestamp:
+			binary.Write(&chunk.values, binary.LittleEndian, v.(time.Time).UnixMicro())
+		}
+	}
+
+	w.rows++
+	if w.rows >= w.RowGroupRows {
+		return w.flush()
+	}
+	return nil
+}
+
+// checkValue reports whether v can be stored in col
+func checkValue(col Column, v interface{}) error {
+	if v == nil {
+		if !col.Optional {
+			return fmt.Errorf("parquet: column %s is required", col.Name)
+		}
+		return nil
+	}
+	ok := false
+	switch col.Type {
+	case Boolean:
+		_, ok = v.(bool)
+	case Int64:
+		switch v.(type) {
+		case int64, int:
+			ok = true
+		}
+	case Double:
+		_, ok = v.(float64)
+	case String:
+		_, ok = v.(string)
+	case Timestamp:
+		_, ok = v.(time.Time)
+	}
+	if !ok {
+		return fmt.Errorf("parquet: column %s cannot hold %T", col.Name, v)
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the file footer. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.rows > 0 {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+
+	footer := w.fileMetadata()
+	if _, err := w.out.Write(footer); err != nil {
+		return err
+	}
+	var trailer [8]byte
+	binary.LittleEndian.PutUint32(trailer[:4], uint32(len(footer)))
+	copy(trailer[4:], magic)
+	_, err := w.out.Write(trailer[:])
+	return err
+}
+
+// Rows is the number of rows written so far
+func (w *Writer) Rows() int64 {
+	return w.numRows + int64(w.rows)
+}
+
+// flush writes the buffered rows as a row group
+func (w *Writer) flush() error {
+	group := rowGroupMeta{numRows: int64(w.rows)}
+	for i, col := range w.columns {
+		chunk := &w.chunks[i]
+
+		var page bytes.Buffer
+		if col.Optional {
+			levels := encodeLevels(chunk.defined)
+			binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+			page.Write(levels)
+		}
+		if col.Type == Boolean {
+			page.Write(packBits(chunk.bools))
+		} else {
+			page.Write(chunk.values.Bytes())
+		}
+
+		var compressed bytes.Buffer
+		w.gz.Reset(&compressed)
+		if _, err := w.gz.Write(page.Bytes()); err != nil {
+			return err
+		}
+		if err := w.gz.Close(); err != nil {
+			return err
+		}
+
+		header := pageHeader(page.Len(), compressed.Len(), w.rows)
+		offset := w.out.n
+		if _, err := w.out.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.out.Write(compressed.Bytes()); err != nil {
+			return err
+		}
+
+		meta := columnChunkMeta{
+			column:            col,
+			numValues:         int64(w.rows),
+			totalUncompressed: int64(len(header) + page.Len()),
+			totalCompressed:   int64(len(header) + compressed.Len()),
+			dataPageOffset:    offset,
+		}
+		group.columns = append(group.columns, meta)
+		group.totalByteSize += meta.totalUncompressed
+
+		chunk.defined = chunk.defined[:0]
+		chunk.bools = chunk.bools[:0]
+		chunk.values.Reset()
+	}
+
+	w.groups = append(w.groups, group)
+	w.numRows += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+// encodeLevels encodes definition levels of bit width 1 with the
+// RLE/bit-packing hybrid, as bit-packed runs of at most 63 groups of 8
+func encodeLevels(defined []bool) []byte {
+	var buf bytes.Buffer
+	var header [binary.MaxVarintLen64]byte
+	for start := 0; start < len(defined); start += 63 * 8 {
+		end := min(start+63*8, len(defined))
+		groups := (end - start + 7) / 8
+		buf.Write(header[:binary.PutUvarint(header[:], uint64(groups<<1|1))])
+		buf.Write(packBits(defined[start:end]))
+	}
+	return buf.Bytes()
+}
+
+// packBits packs values one bit each, least significant bit first
+func packBits(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+// pageHeader encodes the header of a PLAIN encoded data page
+func pageHeader(uncompressed, compressed, numValues int) []byte {
+	c := newCompactWriter()
+	c.i32(1, pageTypeData)
+	c.i32(2, int32(uncompressed))
+	c.i32(3, int32(compressed))
+	c.beginStruct(5)
+	c.i32(1, int32(numValues))
+	c.i32(2, encodingPlain)
+	c.i32(3, encodingRLE)
+	c.i32(4, encodingRLE)
+	c.endStruct()
+	c.endStruct()
+	return c.Bytes()
+}
+
+// fileMetadata encodes the file footer
+func (w *Writer) fileMetadata() []byte {
+	c := newCompactWriter()
+	c.i32(1, 1)
+
+	c.list(2, thriftStruct, len(w.columns)+1)
+	c.beginStruct(0)
+	c.string(4, "schema")
+	c.i32(5, int32(len(w.columns)))
+	c.endStruct()
+	for _, col := range w.columns {
+		c.beginStruct(0)
+		c.i32(1, col.physicalType())
+		if col.Optional {
+			c.i32(3, repetitionOptional)
+		} else {
+			c.i32(3, repetitionRequired)
+		}
+		c.string(4, col.Name)
+		switch col.Type {
+		case String:
+			c.i32(6, convertedUTF8)
+		case Timestamp:
+			c.i32(6, convertedTimestampMicros)
+		}
+		c.endStruct()
+	}
+
+	c.i64(3, w.numRows)
+
+	c.list(4, thriftStruct, len(w.groups))
+	for _, group := range w.groups {
+		c.beginStruct(0)
+		c.list(1, thriftStruct, len(group.columns))
+		for _, chunk := range group.columns {
+			c.beginStruct(0)
+			c.i64(2, chunk.dataPageOffset)
+			c.beginStruct(3)
+			c.i32(1, chunk.column.physicalType())
+			c.list(2, thriftI32, 2)
+			c.i32Elem(encodingPlain)
+			c.i32Elem(encodingRLE)
+			c.list(3, thriftBinary, 1)
+			c.stringElem(chunk.column.Name)
+			c.i32(4, codecGzip)
+			c.i64(5, chunk.numValues)
+			c.i64(6, chunk.totalUncompressed)
+			c.i64(7, chunk.totalCompressed)
+			c.i64(9, chunk.dataPageOffset)
+			c.endStruct()
+			c.endStruct()
+		}
+		c.i64(2, group.totalByteSize)
+		c.i64(3, group.numRows)
+		c.endStruct()
+	}
+
+	c.string(6, "crosslogic control-plane")
+	c.endStruct()
+	return c.Bytes()
+}
+
+// countingWriter tracks the file offset for column chunk metadata
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,187 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// compactReader decodes the Thrift compact structs the writer produces,
+// returning fields by ID
+type compactReader struct {
+	b []byte
+	p int
+}
+
+func (r *compactReader) varint() uint64 {
+	v, n := binary.Uvarint(r.b[r.p:])
+	r.p += n
+	return v
+}
+
+func (r *compactReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *compactReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.varint())
+		r.p += n
+		return string(r.b[r.p-n : r.p])
+	case thriftList:
+		h := r.b[r.p]
+		r.p++
+		size, elem := int(h>>4), h&0x0f
+		if size == 15 {
+			size = int(r.varint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	panic("unexpected thrift type")
+}
+
+func (r *compactReader) structure() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var last int16
+	for {
+		h := r.b[r.p]
+		r.p++
+		if h == 0 {
+			return fields
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		last = id
+		fields[id] = r.value(h & 0x0f)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []Column{
+		{Name: "id", Type: String},
+		{Name: "timestamp", Type: Timestamp},
+		{Name: "tokens", Type: Int64},
+		{Name: "cost", Type: Double, Optional: true},
+		{Name: "billed", Type: Boolean},
+	})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	w.RowGroupRows = 4
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		var cost interface{}
+		if i%2 == 0 {
+			cost = float64(i) / 2
+		}
+		if err := w.Write("req", now, i, cost, i%3 == 0); err != nil {
+			t.Fatalf("Write row %d: %v", i, err)
+		}
+	}
+	if w.Rows() != 10 {
+		t.Errorf("Rows = %d, want 10", w.Rows())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data := buf.Bytes()
+	if string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		t.Fatal("file is not framed by PAR1")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &compactReader{b: data, p: len(data) - 8 - footerLen}
+	meta := r.structure()
+	if r.p != len(data)-8 {
+		t.Fatalf("footer decoded %d bytes, want %d", r.p-(len(data)-8-footerLen), footerLen)
+	}
+
+	if meta[3] != int64(10) {
+		t.Errorf("num_rows = %v, want 10", meta[3])
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != 6 || schema[4].(map[int16]interface{})[4] != "cost" {
+		t.Errorf("schema = %v", schema)
+	}
+	if cost := schema[4].(map[int16]interface{}); cost[3] != int64(repetitionOptional) {
+		t.Errorf("cost repetition = %v, want optional", cost[3])
+	}
+	if ts := schema[2].(map[int16]interface{}); ts[6] != int64(convertedTimestampMicros) {
+		t.Errorf("timestamp converted type = %v", ts[6])
+	}
+
+	groups := meta[4].([]interface{})
+	if len(groups) != 3 {
+		t.Fatalf("row groups = %d, want 3", len(groups))
+	}
+	last := groups[2].(map[int16]interface{})
+	if last[3] != int64(2) {
+		t.Errorf("last row group rows = %v, want 2", last[3])
+	}
+
+	// Every column chunk starts with a data page header of its row count
+	for _, g := range groups {
+		group := g.(map[int16]interface{})
+		for _, c := range group[1].([]interface{}) {
+			chunk := c.(map[int16]interface{})[3].(map[int16]interface{})
+			page := (&compactReader{b: data, p: int(chunk[9].(int64))}).structure()
+			header := page[5].(map[int16]interface{})
+			if header[1] != group[3] {
+				t.Errorf("page values = %v, row group rows = %v", header[1], group[3])
+			}
+		}
+	}
+}
+
+func TestWriterRejectsInvalidRows(t *testing.T) {
+	w, err := NewWriter(&bytes.Buffer{}, []Column{{Name: "n", Type: Int64}, {Name: "s", Type: String, Optional: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][]interface{}{
+		{int64(1)},
+		{nil, "x"},
+		{"1", nil},
+		{int64(1), 2},
+	} {
+		if err := w.Write(row...); err == nil {
+			t.Errorf("Write(%v) accepted", row)
+		}
+	}
+	if w.Rows() != 0 {
+		t.Errorf("rejected rows were buffered: %d", w.Rows())
+	}
+
+	if _, err := NewWriter(&bytes.Buffer{}, []Column{{Name: "a"}, {Name: "a"}}); err == nil {
+		t.Error("duplicate column names accepted")
+	}
+}
+
+func TestEncodeLevels(t *testing.T) {
+	got := encodeLevels([]bool{true, false, true, true, false, false, false, false, true})
+	// One bit-packed run of two groups: header 2<<1|1, then the bits
+	want := []byte{0x05, 0x0d, 0x01}
+	if !bytes.Equal(got, want) {
+		t.Errorf("encodeLevels = %x, want %x", got, want)
+	}
+
+	long := make([]bool, 63*8+1)
+	if got := encodeLevels(long); got[0] != 63<<1|1 || got[64] != 1<<1|1 {
+		t.Errorf("runs are not split at 63 groups: %x", got[:1])
+	}
+}
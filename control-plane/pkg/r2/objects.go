@@ -0,0 +1,134 @@
+package r2
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// objectURLTTL is how long the URLs Objects signs for itself stay valid
+const objectURLTTL = 5 * time.Minute
+
+// Objects reads and writes objects in the presigner's bucket from the
+// control plane, through URLs it presigns for itself
+type Objects struct {
+	presigner *Presigner
+	client    *http.Client
+}
+
+// NewObjects returns an object client for the presigner's bucket
+func NewObjects(presigner *Presigner) *Objects {
+	return &Objects{
+		presigner: presigner,
+		client:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Put uploads data to key, replacing any object already there
+func (o *Objects) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := o.putRequest(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+	if err != nil {
+		return err
+	}
+	_, err = o.do(req)
+	return err
+}
+
+// PutReader uploads size bytes read from body to key without holding them
+// in memory. Large uploads outlast the client timeout, so only ctx bounds
+// the upload.
+func (o *Objects) PutReader(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	req, err := o.putRequest(ctx, key, body, size, contentType)
+	if err != nil {
+		return err
+	}
+	client := *o.client
+	client.Timeout = 0
+	_, err = doRequest(&client, req)
+	return err
+}
+
+func (o *Objects) putRequest(ctx context.Context, key string, body io.Reader, size int64, contentType string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, o.presigner.PresignPut(key, objectURLTTL), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// Get downloads key, returning ErrNotFound if it does not exist
+func (o *Objects) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.presigner.PresignGet(key, objectURLTTL), nil)
+	if err != nil {
+		return nil, err
+	}
+	return o.do(req)
+}
+
+// Delete removes key. Deleting an object that does not exist succeeds.
+func (o *Objects) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, o.presigner.PresignDelete(key, objectURLTTL), nil)
+	if err != nil {
+		return err
+	}
+	_, err = o.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// HasPrefix reports whether any object's key starts with prefix
+func (o *Objects) HasPrefix(ctx context.Context, prefix string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.presigner.PresignList(prefix, 1, objectURLTTL), nil)
+	if err != nil {
+		return false, err
+	}
+	body, err := o.do(req)
+	if err != nil {
+		return false, err
+	}
+	var result struct {
+		KeyCount int `xml:"KeyCount"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return false, fmt.Errorf("invalid R2 list response: %w", err)
+	}
+	return result.KeyCount > 0, nil
+}
+
+func (o *Objects) do(req *http.Request) ([]byte, error) {
+	return doRequest(o.client, req)
+}
+
+func doRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("R2 %s returned %d: %s", req.Method, resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
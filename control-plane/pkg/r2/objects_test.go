@@ -0,0 +1,107 @@
+package r2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeBucket is an in-memory bucket that checks requests are presigned
+func fakeBucket(t *testing.T) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("X-Amz-Signature") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			if r.URL.Query().Get("list-type") == "2" {
+				count := 0
+				for key := range objects {
+					if strings.HasPrefix(key, r.URL.Path+"/"+r.URL.Query().Get("prefix")) {
+						count++
+					}
+				}
+				fmt.Fprintf(w, "<ListBucketResult><KeyCount>%d</KeyCount></ListBucketResult>", count)
+				return
+			}
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+				return
+			}
+			w.Write(body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestObjects(t *testing.T) {
+	srv := fakeBucket(t)
+	p, err := NewPresigner(srv.URL, "logs", "ak", "sk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := NewObjects(p)
+	ctx := context.Background()
+
+	if err := objects.Put(ctx, "node-logs/a.jsonl.gz", []byte("data"), "application/gzip"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, err := objects.Get(ctx, "node-logs/a.jsonl.gz")
+	if err != nil || string(got) != "data" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+	if found, err := objects.HasPrefix(ctx, "node-logs/"); err != nil || !found {
+		t.Errorf("HasPrefix(node-logs/) = %v, %v, want true", found, err)
+	}
+	if found, err := objects.HasPrefix(ctx, "models/"); err != nil || found {
+		t.Errorf("HasPrefix(models/) = %v, %v, want false", found, err)
+	}
+	if err := objects.PutReader(ctx, "exports/b.csv", strings.NewReader("a,b\n"), 4, "text/csv"); err != nil {
+		t.Fatalf("PutReader: %v", err)
+	}
+	if got, err := objects.Get(ctx, "exports/b.csv"); err != nil || string(got) != "a,b\n" {
+		t.Fatalf("Get after PutReader = %q, %v", got, err)
+	}
+	if err := objects.Delete(ctx, "node-logs/a.jsonl.gz"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := objects.Get(ctx, "node-logs/a.jsonl.gz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete err = %v, want ErrNotFound", err)
+	}
+	if err := objects.Delete(ctx, "node-logs/missing"); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+}
+
+func TestObjectsErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	p, _ := NewPresigner(srv.URL, "logs", "ak", "sk")
+
+	err := NewObjects(p).Put(context.Background(), "k", []byte("x"), "")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put err = %v, want a 403 error", err)
+	}
+}
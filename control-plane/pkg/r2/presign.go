@@ -0,0 +1,190 @@
+// Package r2 creates presigned URLs for objects in Cloudflare R2, which
+// speaks the S3 API. URLs are signed with AWS Signature Version 4 so that
+// nodes can upload and operators can download objects without holding R2
+// credentials.
+package r2
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// region is the signing region R2 expects
+const region = "auto"
+
+// MaxExpiry is the longest validity SigV4 allows for a presigned URL
+const MaxExpiry = 7 * 24 * time.Hour
+
+// Presigner creates time-limited URLs for objects in one bucket
+type Presigner struct {
+	endpoint  *url.URL
+	bucket    string
+	accessKey string
+	secretKey string
+	now       func() time.Time
+}
+
+// NewPresigner returns a presigner for bucket, or an error if R2 is not
+// fully configured
+func NewPresigner(endpoint, bucket, accessKey, secretKey string) (*Presigner, error) {
+	if endpoint == "" || bucket == "" || accessKey == "" || secretKey == "" {
+		return nil, errors.New("R2 endpoint, bucket and credentials are required")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid R2 endpoint %q", endpoint)
+	}
+	return &Presigner{
+		endpoint:  u,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		now:       time.Now,
+	}, nil
+}
+
+// Bucket returns the bucket the presigner signs for
+func (p *Presigner) Bucket() string {
+	return p.bucket
+}
+
+// PresignPut returns a URL that uploads key with a PUT request
+func (p *Presigner) PresignPut(key string, expires time.Duration) string {
+	return p.presign("PUT", key, expires)
+}
+
+// PresignGet returns a URL that downloads key
+func (p *Presigner) PresignGet(key string, expires time.Duration) string {
+	return p.presign("GET", key, expires)
+}
+
+// PresignDelete returns a URL that deletes key
+func (p *Presigner) PresignDelete(key string, expires time.Duration) string {
+	return p.presign("DELETE", key, expires)
+}
+
+// PresignList returns a URL that lists up to maxKeys objects under prefix
+// with ListObjectsV2
+func (p *Presigner) PresignList(prefix string, maxKeys int, expires time.Duration) string {
+	u := *p.endpoint
+	u.Path = "/" + p.bucket
+	u.RawQuery = url.Values{
+		"list-type": {"2"},
+		"prefix":    {strings.TrimPrefix(prefix, "/")},
+		"max-keys":  {strconv.Itoa(maxKeys)},
+	}.Encode()
+	return presignURL("GET", &u, region, p.accessKey, p.secretKey, p.now().UTC(), expires)
+}
+
+func (p *Presigner) presign(method, key string, expires time.Duration) string {
+	u := *p.endpoint
+	u.Path = "/" + p.bucket + "/" + strings.TrimPrefix(key, "/")
+	u.RawQuery = ""
+	return presignURL(method, &u, region, p.accessKey, p.secretKey, p.now().UTC(), expires)
+}
+
+// presignURL signs u for method with query-string SigV4 authentication.
+// Only the host header is signed and the payload is left unsigned, so the
+// URL works with any client.
+func presignURL(method string, u *url.URL, region, accessKey, secretKey string, now time.Time, expires time.Duration) string {
+	if expires > MaxExpiry {
+		expires = MaxExpiry
+	}
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	scope := day + "/" + region + "/s3/aws4_request"
+
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", accessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalQuery := canonicalQueryString(query)
+	canonicalRequest := strings.Join([]string{
+		method,
+		encodePath(u.Path),
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	signed := *u
+	signed.RawPath = ""
+	signed.Path = u.Path
+	signed.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+	return signed.Scheme + "://" + signed.Host + encodePath(signed.Path) + "?" + signed.RawQuery
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQueryString sorts and encodes query parameters as SigV4 requires
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, encode(k, true)+"="+encode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// encodePath URI-encodes each segment of an object path
+func encodePath(path string) string {
+	return encode(path, false)
+}
+
+// encode percent-encodes everything except unreserved characters, and
+// slashes too when encodeSlash is set
+func encode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
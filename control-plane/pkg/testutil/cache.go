@@ -0,0 +1,32 @@
+package testutil
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/crosslogic/control-plane/internal/config"
+	"github.com/crosslogic/control-plane/pkg/cache"
+)
+
+// NewCache returns a cache backed by an in-process Redis that is shut down
+// when the test ends. The Redis server is returned for inspecting keys,
+// fast-forwarding TTLs and injecting errors.
+func NewCache(t testing.TB) (*cache.Cache, *miniredis.Miniredis) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start in-process redis: %v", err)
+	}
+	port, _ := strconv.Atoi(mr.Port())
+	c, err := cache.NewCache(config.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		mr.Close()
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+		mr.Close()
+	})
+	return c, mr
+}
@@ -0,0 +1,261 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/crosslogic/control-plane/pkg/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrUnexpectedQuery is returned for queries no expectation matches
+var ErrUnexpectedQuery = errors.New("testutil: unexpected query")
+
+// FakeDB is a database.Querier that answers queries from expectations. A
+// query matches the first expectation whose SQL fragment it contains, after
+// collapsing whitespace, so tests don't need to repeat whole statements.
+type FakeDB struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+}
+
+var _ database.Querier = (*FakeDB)(nil)
+
+// Call is a query made against a FakeDB
+type Call struct {
+	SQL  string
+	Args []any
+}
+
+// Expectation is the scripted result of queries containing a SQL fragment
+type Expectation struct {
+	fragment string
+	columns  []string
+	rows     [][]any
+	affected int64
+	err      error
+	once     bool
+	used     bool
+}
+
+// NewFakeDB returns a FakeDB with no expectations
+func NewFakeDB() *FakeDB {
+	return &FakeDB{}
+}
+
+// Expect adds an expectation for queries containing fragment. Without a
+// result it returns no rows and affects none.
+func (db *FakeDB) Expect(fragment string) *Expectation {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	e := &Expectation{fragment: normalizeSQL(fragment)}
+	db.expectations = append(db.expectations, e)
+	return e
+}
+
+// Returns sets the rows a query returns, with values in column order
+func (e *Expectation) Returns(columns []string, rows ...[]any) *Expectation {
+	e.columns = columns
+	e.rows = rows
+	return e
+}
+
+// Affects sets the rows an Exec reports affected
+func (e *Expectation) Affects(n int64) *Expectation {
+	e.affected = n
+	return e
+}
+
+// Fails makes the query return err
+func (e *Expectation) Fails(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Once limits the expectation to one query, so later queries fall through
+// to the next matching expectation
+func (e *Expectation) Once() *Expectation {
+	e.once = true
+	return e
+}
+
+// Calls returns the queries made so far
+func (db *FakeDB) Calls() []Call {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]Call(nil), db.calls...)
+}
+
+// match records a query and returns the expectation answering it
+func (db *FakeDB) match(sql string, args []any) (*Expectation, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.calls = append(db.calls, Call{SQL: sql, Args: args})
+	normalized := normalizeSQL(sql)
+	for _, e := range db.expectations {
+		if e.once && e.used {
+			continue
+		}
+		if strings.Contains(normalized, e.fragment) {
+			e.used = true
+			return e, e.err
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnexpectedQuery, normalized)
+}
+
+// Exec implements database.Querier
+func (db *FakeDB) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	e, err := db.match(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", e.affected)), nil
+}
+
+// Query implements database.Querier
+func (db *FakeDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	e, err := db.match(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: e.columns, rows: e.rows, index: -1}, nil
+}
+
+// QueryRow implements database.Querier. A query without rows scans
+// pgx.ErrNoRows, as with Postgres.
+func (db *FakeDB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	e, err := db.match(sql, args)
+	if err != nil {
+		return fakeRow{err: err}
+	}
+	if len(e.rows) == 0 {
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+	return fakeRow{values: e.rows[0]}
+}
+
+func normalizeSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+type fakeRow struct {
+	values []any
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return scanValues(r.values, dest)
+}
+
+// fakeRows implements pgx.Rows over scripted values
+type fakeRows struct {
+	columns []string
+	rows    [][]any
+	index   int
+	closed  bool
+	err     error
+}
+
+func (r *fakeRows) Close()                        { r.closed = true }
+func (r *fakeRows) Err() error                    { return r.err }
+func (r *fakeRows) CommandTag() pgconn.CommandTag { return pgconn.NewCommandTag("SELECT") }
+func (r *fakeRows) Conn() *pgx.Conn               { return nil }
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription {
+	fields := make([]pgconn.FieldDescription, len(r.columns))
+	for i, name := range r.columns {
+		fields[i] = pgconn.FieldDescription{Name: name}
+	}
+	return fields
+}
+
+func (r *fakeRows) Next() bool {
+	if r.closed || r.err != nil {
+		return false
+	}
+	r.index++
+	if r.index >= len(r.rows) {
+		r.closed = true
+		return false
+	}
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if r.index < 0 || r.index >= len(r.rows) {
+		return errors.New("testutil: Scan called without a current row")
+	}
+	if err := scanValues(r.rows[r.index], dest); err != nil {
+		r.err = err
+		return err
+	}
+	return nil
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	if r.index < 0 || r.index >= len(r.rows) {
+		return nil, errors.New("testutil: Values called without a current row")
+	}
+	return r.rows[r.index], nil
+}
+
+func (r *fakeRows) RawValues() [][]byte { return nil }
+
+// scanValues assigns values to scan destinations, converting between
+// compatible types and allocating for pointer destinations
+func scanValues(values []any, dest []any) error {
+	if len(values) != len(dest) {
+		return fmt.Errorf("testutil: %d values scanned into %d destinations", len(values), len(dest))
+	}
+	for i, d := range dest {
+		if d == nil {
+			continue
+		}
+		if err := assign(reflect.ValueOf(d), values[i]); err != nil {
+			return fmt.Errorf("testutil: column %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func assign(dest reflect.Value, value any) error {
+	if dest.Kind() != reflect.Pointer || dest.IsNil() {
+		return fmt.Errorf("destination %s is not a non-nil pointer", dest.Type())
+	}
+	target := dest.Elem()
+
+	if value == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(target.Type()):
+		target.Set(v)
+	case target.Kind() == reflect.Pointer:
+		elem := reflect.New(target.Type().Elem())
+		if err := assign(elem, value); err != nil {
+			return err
+		}
+		target.Set(elem)
+	case v.Kind() == reflect.Pointer && !v.IsNil():
+		return assign(dest, v.Elem().Interface())
+	case v.Type().ConvertibleTo(target.Type()) && v.Kind() != reflect.String && target.Kind() != reflect.String:
+		target.Set(v.Convert(target.Type()))
+	default:
+		return fmt.Errorf("cannot scan %T into %s", value, target.Type())
+	}
+	return nil
+}
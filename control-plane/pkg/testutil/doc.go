@@ -0,0 +1,15 @@
+// Package testutil provides fakes and fixtures for testing code built on the
+// control plane without Postgres, Redis, a SkyPilot API server or GPU nodes.
+//
+//   - FakeDB answers database.Querier calls from scripted results
+//   - NewCache runs a cache.Cache against an in-process Redis
+//   - EventRecorder captures what is published on an events.Bus
+//   - FakeSkyPilot serves the SkyPilot API Server endpoints the client uses
+//   - FakeOrchestrator and FakeLoadBalancer stand in for the orchestrator
+//     and the gateway's load balancer behind consumer-defined interfaces
+//   - FakeNode serves vLLM's OpenAI-compatible API with canned responses,
+//     and the SSE helpers build the streams it and the gateway emit
+//
+// testutil imports the orchestrator and skypilot packages, so their own
+// in-package tests can't import it; code built on them can.
+package testutil
@@ -0,0 +1,72 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/pkg/events"
+	"go.uber.org/zap"
+)
+
+// EventRecorder captures the events published on a bus
+type EventRecorder struct {
+	mu      sync.Mutex
+	events  []events.Event
+	arrived chan struct{}
+}
+
+// NewEventBus returns an event bus and a recorder of the given event types
+func NewEventBus(types ...events.EventType) (*events.Bus, *EventRecorder) {
+	bus := events.NewBus(zap.NewNop())
+	return bus, RecordEvents(bus, types...)
+}
+
+// RecordEvents subscribes a recorder to the given event types on bus
+func RecordEvents(bus *events.Bus, types ...events.EventType) *EventRecorder {
+	r := &EventRecorder{arrived: make(chan struct{}, 1)}
+	for _, eventType := range types {
+		bus.Subscribe(eventType, r.record)
+	}
+	return r
+}
+
+func (r *EventRecorder) record(_ context.Context, event events.Event) error {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+
+	select {
+	case r.arrived <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Events returns the events recorded so far, in arrival order
+func (r *EventRecorder) Events() []events.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]events.Event(nil), r.events...)
+}
+
+// WaitFor waits for an event of the given type, which the bus delivers
+// asynchronously, and fails the test if none arrives within timeout
+func (r *EventRecorder) WaitFor(t testing.TB, eventType events.EventType, timeout time.Duration) events.Event {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		for _, event := range r.Events() {
+			if event.Type == eventType {
+				return event
+			}
+		}
+		select {
+		case <-r.arrived:
+		case <-deadline:
+			t.Fatalf("no %s event within %s", eventType, timeout)
+			return events.Event{}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+)
+
+// FakeOrchestrator records node launches and terminations instead of
+// provisioning anything. Its methods match those of
+// *orchestrator.SkyPilotOrchestrator, so it satisfies interfaces that code
+// under test declares over them.
+type FakeOrchestrator struct {
+	mu         sync.Mutex
+	launched   []orchestrator.NodeConfig
+	terminated []string
+	statuses   map[string]string
+	launchErrs []error
+}
+
+// NewFakeOrchestrator returns a FakeOrchestrator with no nodes
+func NewFakeOrchestrator() *FakeOrchestrator {
+	return &FakeOrchestrator{statuses: make(map[string]string)}
+}
+
+// FailLaunches makes the next launches return the given errors, one per
+// launch
+func (o *FakeOrchestrator) FailLaunches(errs ...error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.launchErrs = append(o.launchErrs, errs...)
+}
+
+// LaunchNode records the launch and returns the cluster name the real
+// orchestrator would use. Launched clusters are UP.
+func (o *FakeOrchestrator) LaunchNode(ctx context.Context, config orchestrator.NodeConfig) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.launched = append(o.launched, config)
+	if len(o.launchErrs) > 0 {
+		err := o.launchErrs[0]
+		o.launchErrs = o.launchErrs[1:]
+		return "", err
+	}
+	name := orchestrator.GenerateClusterName(config)
+	o.statuses[name] = "UP"
+	return name, nil
+}
+
+// TerminateNode records the termination
+func (o *FakeOrchestrator) TerminateNode(ctx context.Context, clusterName string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.terminated = append(o.terminated, clusterName)
+	delete(o.statuses, clusterName)
+	return nil
+}
+
+// GetNodeStatus returns the cluster's status, or an error for unknown
+// clusters
+func (o *FakeOrchestrator) GetNodeStatus(ctx context.Context, clusterName string) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	status, ok := o.statuses[clusterName]
+	if !ok {
+		return "", fmt.Errorf("cluster %s not found", clusterName)
+	}
+	return status, nil
+}
+
+// SetNodeStatus changes a cluster's status, e.g. to "STOPPED" to simulate
+// a preemption
+func (o *FakeOrchestrator) SetNodeStatus(clusterName, status string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.statuses[clusterName] = status
+}
+
+// Launched returns the configs of all launches, including failed ones
+func (o *FakeOrchestrator) Launched() []orchestrator.NodeConfig {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]orchestrator.NodeConfig(nil), o.launched...)
+}
+
+// Terminated returns the clusters terminated so far
+func (o *FakeOrchestrator) Terminated() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.terminated...)
+}
+
+// FakeLoadBalancer implements orchestrator.LoadBalancer with fixed
+// per-model queue depths
+type FakeLoadBalancer struct {
+	mu     sync.Mutex
+	queues map[string]fakeQueue
+}
+
+// fakeQueue is a model's waiting requests and the nodes reporting them
+type fakeQueue struct {
+	waiting int64
+	nodes   int
+}
+
+var _ orchestrator.LoadBalancer = (*FakeLoadBalancer)(nil)
+
+// NewFakeLoadBalancer returns a load balancer reporting empty queues
+func NewFakeLoadBalancer() *FakeLoadBalancer {
+	return &FakeLoadBalancer{queues: make(map[string]fakeQueue)}
+}
+
+// SetQueueDepth sets the requests waiting across a model's nodes, e.g. to
+// drive the deployment controller's autoscaling
+func (lb *FakeLoadBalancer) SetQueueDepth(model string, waiting int64, nodes int) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.queues[model] = fakeQueue{waiting: waiting, nodes: nodes}
+}
+
+// GetQueueDepth implements orchestrator.LoadBalancer
+func (lb *FakeLoadBalancer) GetQueueDepth(ctx context.Context, model string) (int64, int, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	q := lb.queues[model]
+	return q.waiting, q.nodes, nil
+}
@@ -0,0 +1,266 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/skypilot"
+	"go.uber.org/zap"
+)
+
+// FakeSkyPilot is an in-process SkyPilot API Server. Launches complete at
+// once and bring their cluster UP unless a failure is scripted with
+// FailLaunches; terminations remove the cluster.
+type FakeSkyPilot struct {
+	server *httptest.Server
+
+	mu           sync.Mutex
+	launches     []skypilot.LaunchRequest
+	terminated   []string
+	clusters     map[string]*skypilot.ClusterStatus
+	requests     map[string]*skypilot.RequestStatus
+	profiles     map[string]skypilot.CredentialProfileRequest
+	launchErrors []string
+	nextID       int
+}
+
+// NewFakeSkyPilot starts a fake SkyPilot API Server that is shut down when
+// the test ends
+func NewFakeSkyPilot(t testing.TB) *FakeSkyPilot {
+	t.Helper()
+	f := &FakeSkyPilot{
+		clusters: make(map[string]*skypilot.ClusterStatus),
+		requests: make(map[string]*skypilot.RequestStatus),
+		profiles: make(map[string]skypilot.CredentialProfileRequest),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/health", f.handleHealth)
+	mux.HandleFunc("/api/v1/clusters/launch", f.handleLaunch)
+	mux.HandleFunc("/api/v1/clusters", f.handleListClusters)
+	mux.HandleFunc("/api/v1/clusters/", f.handleCluster)
+	mux.HandleFunc("/api/v1/requests/", f.handleRequest)
+	mux.HandleFunc("/api/v1/credentials", f.handleCredentials)
+	mux.HandleFunc("/api/v1/credentials/", f.handleCredentials)
+
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// URL is the server's base URL
+func (f *FakeSkyPilot) URL() string {
+	return f.server.URL
+}
+
+// Client returns a SkyPilot client for the fake server, without retries
+func (f *FakeSkyPilot) Client() *skypilot.Client {
+	return skypilot.NewClient(skypilot.Config{
+		BaseURL:    f.server.URL,
+		Token:      "test-token",
+		MaxRetries: -1,
+	}, zap.NewNop())
+}
+
+// FailLaunches makes the next launches fail with the given errors, one per
+// launch, such as "ResourcesUnavailableError: no capacity"
+func (f *FakeSkyPilot) FailLaunches(errs ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.launchErrors = append(f.launchErrors, errs...)
+}
+
+// SetClusterStatus changes a cluster's status, e.g. to simulate preemption
+func (f *FakeSkyPilot) SetClusterStatus(name, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.clusters[name]; ok {
+		c.Status = status
+		return
+	}
+	f.clusters[name] = &skypilot.ClusterStatus{Name: name, Status: status}
+}
+
+// Launches returns the launch requests received so far
+func (f *FakeSkyPilot) Launches() []skypilot.LaunchRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]skypilot.LaunchRequest(nil), f.launches...)
+}
+
+// Terminated returns the names of the clusters terminated so far
+func (f *FakeSkyPilot) Terminated() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.terminated...)
+}
+
+// Profiles returns the credential profiles registered, by ID
+func (f *FakeSkyPilot) Profiles() map[string]skypilot.CredentialProfileRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	profiles := make(map[string]skypilot.CredentialProfileRequest, len(f.profiles))
+	for id, p := range f.profiles {
+		profiles[id] = p
+	}
+	return profiles
+}
+
+// newRequest records a finished async request and returns its ID. Callers
+// hold f.mu.
+func (f *FakeSkyPilot) newRequest(errMsg string) string {
+	f.nextID++
+	id := fmt.Sprintf("req-%d", f.nextID)
+	now := time.Now().UTC()
+	status := &skypilot.RequestStatus{
+		RequestID:   id,
+		Status:      "completed",
+		Progress:    100,
+		CreatedAt:   now,
+		StartedAt:   &now,
+		CompletedAt: &now,
+	}
+	if errMsg != "" {
+		status.Status = "failed"
+		status.Error = errMsg
+	}
+	f.requests[id] = status
+	return id
+}
+
+func (f *FakeSkyPilot) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeFakeJSON(w, http.StatusOK, skypilot.HealthResponse{
+		Status:    "healthy",
+		Version:   "fake",
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+func (f *FakeSkyPilot) handleLaunch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeFakeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req skypilot.LaunchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ClusterName == "" {
+		writeFakeError(w, http.StatusBadRequest, "invalid launch request")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.launches = append(f.launches, req)
+
+	var errMsg string
+	if len(f.launchErrors) > 0 {
+		errMsg, f.launchErrors = f.launchErrors[0], f.launchErrors[1:]
+	} else {
+		now := time.Now().UTC()
+		f.clusters[req.ClusterName] = &skypilot.ClusterStatus{
+			Name:       req.ClusterName,
+			Status:     "UP",
+			LaunchedAt: &now,
+			Endpoints:  skypilot.ClusterEndpoints{HTTP: "http://127.0.0.1:8000"},
+		}
+	}
+	writeFakeJSON(w, http.StatusOK, skypilot.LaunchResponse{RequestID: f.newRequest(errMsg)})
+}
+
+func (f *FakeSkyPilot) handleListClusters(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := skypilot.ClusterListResponse{Clusters: []skypilot.ClusterStatus{}}
+	for _, c := range f.clusters {
+		resp.Clusters = append(resp.Clusters, *c)
+	}
+	resp.Total = len(resp.Clusters)
+	writeFakeJSON(w, http.StatusOK, resp)
+}
+
+func (f *FakeSkyPilot) handleCluster(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/clusters/")
+	if name == "execute" || name == "logs" {
+		writeFakeError(w, http.StatusNotImplemented, "not supported by the fake server")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cluster, ok := f.clusters[name]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "cluster not found")
+			return
+		}
+		writeFakeJSON(w, http.StatusOK, cluster)
+	case http.MethodDelete:
+		delete(f.clusters, name)
+		f.terminated = append(f.terminated, name)
+		writeFakeJSON(w, http.StatusOK, skypilot.TerminateResponse{RequestID: f.newRequest("")})
+	default:
+		writeFakeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (f *FakeSkyPilot) handleRequest(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/requests/")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status, ok := f.requests[id]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "request not found")
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, status)
+}
+
+func (f *FakeSkyPilot) handleCredentials(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/v1/credentials"), "/")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		var req skypilot.CredentialProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeFakeError(w, http.StatusBadRequest, "invalid credential profile")
+			return
+		}
+		if id == "" {
+			f.nextID++
+			id = fmt.Sprintf("profile-%d", f.nextID)
+		}
+		f.profiles[id] = req
+		now := time.Now().UTC()
+		writeFakeJSON(w, http.StatusOK, skypilot.CredentialProfile{ID: id, Name: req.Name, CreatedAt: now, UpdatedAt: now})
+	case http.MethodDelete:
+		if _, ok := f.profiles[id]; !ok {
+			writeFakeError(w, http.StatusNotFound, "credential profile not found")
+			return
+		}
+		delete(f.profiles, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeFakeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeFakeError(w http.ResponseWriter, status int, message string) {
+	writeFakeJSON(w, status, skypilot.ErrorResponse{Error: message, StatusCode: status})
+}
@@ -0,0 +1,291 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/orchestrator"
+)
+
+// SSEEvent is one server-sent event
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// FormatSSE encodes events as an SSE stream
+func FormatSSE(events ...SSEEvent) string {
+	var b strings.Builder
+	for _, e := range events {
+		if e.ID != "" {
+			fmt.Fprintf(&b, "id: %s\n", e.ID)
+		}
+		if e.Event != "" {
+			fmt.Fprintf(&b, "event: %s\n", e.Event)
+		}
+		fmt.Fprintf(&b, "data: %s\n\n", e.Data)
+	}
+	return b.String()
+}
+
+// ParseSSE decodes an SSE stream into its events. Comments and retry
+// fields are skipped.
+func ParseSSE(stream string) []SSEEvent {
+	var events []SSEEvent
+	var cur SSEEvent
+	var data []string
+	for _, line := range strings.Split(stream, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			if data != nil || cur.Event != "" || cur.ID != "" {
+				cur.Data = strings.Join(data, "\n")
+				events = append(events, cur)
+			}
+			cur, data = SSEEvent{}, nil
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			cur.ID = value
+		case "event":
+			cur.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return events
+}
+
+// Usage is the token usage reported by a completion
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// NewUsage returns usage with the total filled in
+func NewUsage(prompt, completion int) Usage {
+	return Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
+
+// ChatCompletionJSON is a non-streaming chat completion response as vLLM
+// returns it
+func ChatCompletionJSON(model, content string, usage Usage) string {
+	return mustJSON(map[string]interface{}{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": time.Unix(1700000000, 0).Unix(),
+		"model":   model,
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": content},
+			"finish_reason": "stop",
+		}},
+		"usage": usage,
+	})
+}
+
+// ChatCompletionStream is a streamed chat completion as vLLM sends it: a
+// role chunk, one chunk per token, a finish chunk, a usage chunk when usage
+// is given, and [DONE]
+func ChatCompletionStream(model string, tokens []string, usage *Usage) string {
+	chunk := func(delta map[string]string, finish interface{}) SSEEvent {
+		return SSEEvent{Data: mustJSON(map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion.chunk",
+			"created": time.Unix(1700000000, 0).Unix(),
+			"model":   model,
+			"choices": []map[string]interface{}{{"index": 0, "delta": delta, "finish_reason": finish}},
+		})}
+	}
+
+	events := []SSEEvent{chunk(map[string]string{"role": "assistant"}, nil)}
+	for _, token := range tokens {
+		events = append(events, chunk(map[string]string{"content": token}, nil))
+	}
+	events = append(events, chunk(map[string]string{}, "stop"))
+	if usage != nil {
+		events = append(events, SSEEvent{Data: mustJSON(map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion.chunk",
+			"created": time.Unix(1700000000, 0).Unix(),
+			"model":   model,
+			"choices": []interface{}{},
+			"usage":   usage,
+		})})
+	}
+	events = append(events, SSEEvent{Data: "[DONE]"})
+	return FormatSSE(events...)
+}
+
+// NodeLogStream is a node log stream as GET /admin/nodes/{id}/logs/stream
+// sends it for a launch that goes from queued to active
+func NodeLogStream(nodeID, endpoint string) string {
+	phases := []struct {
+		phase    orchestrator.NodeLogPhase
+		progress int
+		message  string
+	}{
+		{orchestrator.PhaseQueued, 0, "Launch queued"},
+		{orchestrator.PhaseProvisioning, 20, "Provisioning GPU instance"},
+		{orchestrator.PhaseInstanceReady, 35, "Instance ready"},
+		{orchestrator.PhaseInstalling, 45, "Installing vLLM"},
+		{orchestrator.PhaseModelLoading, 70, "Loading model weights"},
+		{orchestrator.PhaseHealthCheck, 90, "Waiting for vLLM health check"},
+	}
+
+	var events []SSEEvent
+	for i, p := range phases {
+		events = append(events, SSEEvent{
+			ID:    fmt.Sprint(i + 1),
+			Event: "status",
+			Data: mustJSON(map[string]interface{}{
+				"node_id":  nodeID,
+				"phase":    p.phase,
+				"progress": p.progress,
+				"message":  p.message,
+			}),
+		})
+	}
+	events = append(events, SSEEvent{
+		ID:    fmt.Sprint(len(phases) + 1),
+		Event: "done",
+		Data: mustJSON(map[string]interface{}{
+			"status":   "active",
+			"endpoint": endpoint,
+			"message":  "Node ready",
+		}),
+	})
+	return FormatSSE(events...)
+}
+
+// FakeNode is an in-process vLLM node serving the OpenAI-compatible API
+// with canned responses. Streaming requests get the reply one word per
+// chunk, with usage when the request sets stream_options.include_usage.
+type FakeNode struct {
+	server *httptest.Server
+	model  string
+
+	mu       sync.Mutex
+	reply    string
+	usage    Usage
+	status   int
+	requests []map[string]interface{}
+}
+
+// NewFakeNode starts a node serving model that is shut down when the test
+// ends
+func NewFakeNode(t testing.TB, model string) *FakeNode {
+	t.Helper()
+	n := &FakeNode{model: model, reply: "Hello from the fake node.", usage: NewUsage(10, 6), status: http.StatusOK}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"object": "list",
+			"data":   []map[string]string{{"id": n.model, "object": "model", "owned_by": "vllm"}},
+		})
+	})
+	mux.HandleFunc("/v1/chat/completions", n.handleCompletion)
+	mux.HandleFunc("/v1/completions", n.handleCompletion)
+
+	n.server = httptest.NewServer(mux)
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+// URL is the node's endpoint URL
+func (n *FakeNode) URL() string {
+	return n.server.URL
+}
+
+// SetReply sets the completion text and usage the node returns
+func (n *FakeNode) SetReply(reply string, usage Usage) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.reply, n.usage = reply, usage
+}
+
+// FailWith makes the node answer completions with the given status, e.g.
+// 503 to simulate an overloaded or crashed vLLM
+func (n *FakeNode) FailWith(status int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.status = status
+}
+
+// Requests returns the decoded completion request bodies received so far
+func (n *FakeNode) Requests() []map[string]interface{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]map[string]interface{}(nil), n.requests...)
+}
+
+func (n *FakeNode) handleCompletion(w http.ResponseWriter, r *http.Request) {
+	var req map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFakeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	n.mu.Lock()
+	n.requests = append(n.requests, req)
+	reply, usage, status := n.reply, n.usage, n.status
+	n.mu.Unlock()
+
+	if status != http.StatusOK {
+		writeFakeJSON(w, status, map[string]interface{}{
+			"error": map[string]string{"message": http.StatusText(status), "type": "server_error"},
+		})
+		return
+	}
+
+	if stream, _ := req["stream"].(bool); !stream {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, ChatCompletionJSON(n.model, reply, usage))
+		return
+	}
+
+	var withUsage *Usage
+	if opts, ok := req["stream_options"].(map[string]interface{}); ok {
+		if include, _ := opts["include_usage"].(bool); include {
+			withUsage = &usage
+		}
+	}
+	var tokens []string
+	for i, word := range strings.Fields(reply) {
+		if i > 0 {
+			word = " " + word
+		}
+		tokens = append(tokens, word)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	for _, event := range ParseSSE(ChatCompletionStream(n.model, tokens, withUsage)) {
+		fmt.Fprint(w, FormatSSE(event))
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+func mustJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
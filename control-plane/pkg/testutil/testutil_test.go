@@ -0,0 +1,142 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crosslogic/control-plane/internal/skypilot"
+	"github.com/crosslogic/control-plane/pkg/events"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestFakeDB(t *testing.T) {
+	db := NewFakeDB()
+	ctx := context.Background()
+	id := uuid.New()
+
+	db.Expect("SELECT id, name, price FROM models").
+		Returns([]string{"id", "name", "price"},
+			[]any{id, "llama", 0.15},
+			[]any{uuid.New(), "mistral", nil},
+		)
+	db.Expect("UPDATE models").Affects(2)
+	db.Expect("DELETE FROM models").Fails(errors.New("boom"))
+
+	rows, err := db.Query(ctx, "SELECT id, name, price\n\t\tFROM models WHERE status = $1", "active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var prices []*float64
+	for rows.Next() {
+		var gotID uuid.UUID
+		var name string
+		var price *float64
+		if err := rows.Scan(&gotID, &name, &price); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+		prices = append(prices, price)
+	}
+	if len(names) != 2 || names[0] != "llama" || *prices[0] != 0.15 || prices[1] != nil {
+		t.Errorf("scanned %v %v", names, prices)
+	}
+
+	var count int64
+	if err := db.QueryRow(ctx, "SELECT id, name, price FROM models").Scan(&id, new(string), new(float64)); err != nil {
+		t.Errorf("QueryRow scan: %v", err)
+	}
+	tag, err := db.Exec(ctx, "UPDATE models SET status = 'retired'")
+	if err != nil || tag.RowsAffected() != 2 {
+		t.Errorf("Exec = %v, %v", tag, err)
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM models"); err == nil {
+		t.Error("scripted failure not returned")
+	}
+	if err := db.QueryRow(ctx, "SELECT COUNT(*) FROM nodes").Scan(&count); !errors.Is(err, ErrUnexpectedQuery) {
+		t.Errorf("unexpected query error = %v", err)
+	}
+
+	db.Expect("FROM tenants")
+	if err := db.QueryRow(ctx, "SELECT id FROM tenants").Scan(&id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("empty QueryRow error = %v, want pgx.ErrNoRows", err)
+	}
+	if got := len(db.Calls()); got != 6 {
+		t.Errorf("recorded %d calls, want 6", got)
+	}
+}
+
+func TestFakeSkyPilot(t *testing.T) {
+	sky := NewFakeSkyPilot(t)
+	client := sky.Client()
+	ctx := context.Background()
+
+	sky.FailLaunches("ResourcesUnavailableError: no capacity")
+	resp, err := client.Launch(ctx, skypilot.LaunchRequest{ClusterName: "cic-1", TaskYAML: "run: true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.WaitForRequest(ctx, resp.RequestID, time.Millisecond); err == nil {
+		t.Error("scripted launch failure not reported")
+	}
+
+	resp, err = client.Launch(ctx, skypilot.LaunchRequest{ClusterName: "cic-2", TaskYAML: "run: true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.WaitForRequest(ctx, resp.RequestID, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	status, err := client.GetStatus(ctx, "cic-2")
+	if err != nil || status.Status != "UP" {
+		t.Fatalf("GetStatus() = %+v, %v", status, err)
+	}
+
+	if _, err := client.Terminate(ctx, "cic-2", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := sky.Terminated(); len(got) != 1 || got[0] != "cic-2" {
+		t.Errorf("terminated = %v", got)
+	}
+	if got := len(sky.Launches()); got != 2 {
+		t.Errorf("launches = %d, want 2", got)
+	}
+}
+
+func TestFakeNodeStream(t *testing.T) {
+	node := NewFakeNode(t, "llama")
+	node.SetReply("one two three", NewUsage(4, 3))
+
+	resp, err := http.Post(node.URL()+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"model":"llama","stream":true,"stream_options":{"include_usage":true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	events := ParseSSE(string(body))
+	// role, three tokens, finish, usage, [DONE]
+	if len(events) != 7 || events[6].Data != "[DONE]" || !strings.Contains(events[5].Data, `"completion_tokens":3`) {
+		t.Errorf("stream = %q", body)
+	}
+	if got := len(node.Requests()); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestEventRecorder(t *testing.T) {
+	bus, recorder := NewEventBus(events.EventNodeLaunched)
+	bus.Publish(context.Background(), events.NewEvent(events.EventNodeLaunched, "", map[string]interface{}{"node_id": "n1"}))
+
+	event := recorder.WaitFor(t, events.EventNodeLaunched, time.Second)
+	if event.Payload["node_id"] != "n1" {
+		t.Errorf("event = %+v", event)
+	}
+}
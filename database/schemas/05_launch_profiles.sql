@@ -0,0 +1,46 @@
+-- Launch Profiles Schema
+-- Named, reusable defaults for self-service instance launches
+
+-- ============================================================================
+-- LAUNCH PROFILES TABLE
+-- ============================================================================
+-- A profile pre-fills NodeConfig fields for POST /v1/instances.
+-- tenant_id NULL marks a platform-default profile managed by admins; tenant
+-- profiles with the same name take precedence over platform defaults.
+
+CREATE TABLE IF NOT EXISTS launch_profiles (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+
+    -- NodeConfig defaults (NULL = not set by this profile)
+    provider VARCHAR(50),
+    region VARCHAR(100),
+    gpu VARCHAR(50),
+    gpu_count INTEGER CHECK (gpu_count IS NULL OR gpu_count > 0),
+    use_spot BOOLEAN,
+    disk_size INTEGER CHECK (disk_size IS NULL OR disk_size > 0),
+    vllm_args TEXT,
+    idle_minutes_to_autostop INTEGER,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Profile names are unique per tenant, and unique among platform defaults
+CREATE UNIQUE INDEX IF NOT EXISTS idx_launch_profiles_tenant_name
+    ON launch_profiles(tenant_id, name)
+    WHERE tenant_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_launch_profiles_platform_name
+    ON launch_profiles(name)
+    WHERE tenant_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_launch_profiles_tenant_id ON launch_profiles(tenant_id);
+
+CREATE TRIGGER update_launch_profiles_updated_at BEFORE UPDATE ON launch_profiles
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE launch_profiles IS 'Named launch defaults for self-service instances (tenant-owned or platform-wide)';
+COMMENT ON COLUMN launch_profiles.tenant_id IS 'Owning tenant; NULL for admin-managed platform defaults';
@@ -0,0 +1,36 @@
+-- Model Deprecation Workflow
+-- Adds sunset scheduling to the model catalog and tracks tenant reminders
+
+-- ============================================================================
+-- MODEL LIFECYCLE COLUMNS
+-- ============================================================================
+
+ALTER TABLE models ADD COLUMN IF NOT EXISTS deprecated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE models ADD COLUMN IF NOT EXISTS sunset_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE models ADD COLUMN IF NOT EXISTS replacement_model VARCHAR(255);
+ALTER TABLE models ADD COLUMN IF NOT EXISTS deprecation_notice TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_models_sunset_at ON models(sunset_at) WHERE sunset_at IS NOT NULL;
+
+COMMENT ON COLUMN models.deprecated_at IS 'When the model was marked deprecated (sent as the Deprecation response header)';
+COMMENT ON COLUMN models.sunset_at IS 'Enforced cutoff; after this time the gateway returns model_decommissioned';
+COMMENT ON COLUMN models.replacement_model IS 'Suggested replacement model name returned to clients';
+
+-- ============================================================================
+-- DEPRECATION REMINDERS
+-- ============================================================================
+-- One row per (model, tenant, milestone) so reminders are sent exactly once.
+
+CREATE TABLE IF NOT EXISTS model_deprecation_reminders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    model_id UUID NOT NULL REFERENCES models(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    milestone VARCHAR(20) NOT NULL, -- e.g. "30d", "7d", "1d"
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(model_id, tenant_id, milestone)
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_deprecation_reminders_model_id ON model_deprecation_reminders(model_id);
+CREATE INDEX IF NOT EXISTS idx_model_deprecation_reminders_tenant_id ON model_deprecation_reminders(tenant_id);
+
+COMMENT ON TABLE model_deprecation_reminders IS 'Deprecation reminders already sent to tenants still using a model';
@@ -0,0 +1,19 @@
+-- Model Aliases
+-- Stable alias names that the platform repoints to versioned model targets
+
+CREATE TABLE IF NOT EXISTS model_aliases (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    alias VARCHAR(255) UNIQUE NOT NULL,
+    target_model VARCHAR(255) NOT NULL, -- models.name the alias currently resolves to
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_aliases_target_model ON model_aliases(target_model);
+
+CREATE TRIGGER update_model_aliases_updated_at BEFORE UPDATE ON model_aliases
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE model_aliases IS 'Stable model names (e.g. llama-3-small) mapped to versioned model targets';
+COMMENT ON COLUMN model_aliases.target_model IS 'Name of the model in the models table the alias resolves to';
@@ -0,0 +1,39 @@
+-- Catalog Sync
+-- Tracks provenance of instance_types / region availability rows synced from
+-- the SkyPilot catalog, and records each sync run.
+
+-- ============================================================================
+-- INSTANCE TYPE PROVENANCE
+-- ============================================================================
+
+ALTER TABLE instance_types ADD COLUMN IF NOT EXISTS source VARCHAR(20) DEFAULT 'manual'; -- 'manual' or 'catalog'
+ALTER TABLE instance_types ADD COLUMN IF NOT EXISTS last_synced_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE instance_types ADD COLUMN IF NOT EXISTS missing_since TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE region_instance_availability ADD COLUMN IF NOT EXISTS last_synced_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_instance_types_missing_since ON instance_types(missing_since) WHERE missing_since IS NOT NULL;
+
+COMMENT ON COLUMN instance_types.source IS 'Where the row came from: manual (admin API) or catalog (sync job)';
+COMMENT ON COLUMN instance_types.missing_since IS 'Set when the instance type disappeared from the provider catalog';
+
+-- ============================================================================
+-- SYNC RUNS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS catalog_sync_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    trigger VARCHAR(20) NOT NULL DEFAULT 'scheduled', -- 'scheduled' or 'manual'
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    providers JSONB DEFAULT '[]',
+    instance_types_upserted INTEGER DEFAULT 0,
+    availability_upserted INTEGER DEFAULT 0,
+    instance_types_missing INTEGER DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_sync_runs_started_at ON catalog_sync_runs(started_at DESC);
+
+COMMENT ON TABLE catalog_sync_runs IS 'History of instance type / region availability catalog syncs';
@@ -0,0 +1,14 @@
+-- Usage Record Enrichment
+-- Snapshots the serving node's GPU type and the response status code onto each
+-- usage record at write time, so model/region/GPU breakdowns don't need joins
+-- against mutable nodes/models rows.
+
+ALTER TABLE usage_records ADD COLUMN IF NOT EXISTS gpu_type VARCHAR(100);
+ALTER TABLE usage_records ADD COLUMN IF NOT EXISTS status_code INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_usage_records_region_id ON usage_records(region_id);
+CREATE INDEX IF NOT EXISTS idx_usage_records_gpu_type ON usage_records(gpu_type);
+CREATE INDEX IF NOT EXISTS idx_usage_records_status_code ON usage_records(status_code) WHERE status_code >= 400;
+
+COMMENT ON COLUMN usage_records.gpu_type IS 'GPU type of the serving node at request time';
+COMMENT ON COLUMN usage_records.status_code IS 'HTTP status code returned to the client';
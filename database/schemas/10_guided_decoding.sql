@@ -0,0 +1,7 @@
+-- Guided Decoding Capability
+-- Flags models whose vLLM deployment can serve guided_json / guided_regex /
+-- guided_choice constraints; the gateway rejects them for other models.
+
+ALTER TABLE models ADD COLUMN IF NOT EXISTS supports_guided_decoding BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN models.supports_guided_decoding IS 'Whether guided decoding parameters are accepted for this model';
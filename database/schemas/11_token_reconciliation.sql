@@ -0,0 +1,44 @@
+-- Token Reconciliation
+-- Hourly snapshots of vLLM's cumulative token counters per node, and the
+-- result of comparing each hour's counter delta against billed usage records.
+
+-- ============================================================================
+-- VLLM TOKEN COUNTER SNAPSHOTS
+-- ============================================================================
+-- One row per node per hour boundary (vllm:prompt_tokens_total and
+-- vllm:generation_tokens_total summed across label sets).
+
+CREATE TABLE IF NOT EXISTS node_token_snapshots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    node_id UUID NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    hour TIMESTAMP WITH TIME ZONE NOT NULL,
+    prompt_tokens_total BIGINT NOT NULL,
+    generation_tokens_total BIGINT NOT NULL,
+    captured_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(node_id, hour)
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_token_snapshots_hour ON node_token_snapshots(hour DESC);
+
+-- ============================================================================
+-- RECONCILIATION RESULTS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS token_reconciliations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    node_id UUID NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    hour TIMESTAMP WITH TIME ZONE NOT NULL,
+    billed_prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    billed_completion_tokens BIGINT NOT NULL DEFAULT 0,
+    vllm_prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    vllm_generation_tokens BIGINT NOT NULL DEFAULT 0,
+    discrepancy_percent DECIMAL(10, 4) NOT NULL DEFAULT 0,
+    flagged BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(node_id, hour)
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_reconciliations_hour ON token_reconciliations(hour DESC);
+CREATE INDEX IF NOT EXISTS idx_token_reconciliations_flagged ON token_reconciliations(hour DESC) WHERE flagged;
+
+COMMENT ON TABLE token_reconciliations IS 'Billed vs vLLM reported tokens per node per hour; flagged when they differ by more than 1%';
@@ -0,0 +1,14 @@
+-- Model Capability Registry
+-- Structured capability flags and limits the gateway consults to reject
+-- unsupported parameters early, and that /v1/models exposes to clients.
+
+ALTER TABLE models ADD COLUMN IF NOT EXISTS supports_tools BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE models ADD COLUMN IF NOT EXISTS supports_vision BOOLEAN NOT NULL DEFAULT FALSE;
+-- vLLM serves response_format JSON mode without extra flags, so default on
+ALTER TABLE models ADD COLUMN IF NOT EXISTS supports_json_mode BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE models ADD COLUMN IF NOT EXISTS max_output_tokens INTEGER CHECK (max_output_tokens > 0);
+
+COMMENT ON COLUMN models.supports_tools IS 'Whether tools / function calling is enabled (vLLM --enable-auto-tool-choice)';
+COMMENT ON COLUMN models.supports_vision IS 'Whether image content parts are accepted';
+COMMENT ON COLUMN models.supports_json_mode IS 'Whether response_format json_object / json_schema is accepted';
+COMMENT ON COLUMN models.max_output_tokens IS 'Maximum max_tokens a request may ask for; NULL means bounded only by context_length';
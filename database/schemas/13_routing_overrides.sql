@@ -0,0 +1,16 @@
+-- Routing Overrides
+-- Operator overrides the gateway load balancer applies on top of its live
+-- scoring, for emergency traffic steering. Rows are keyed by node endpoint;
+-- a row is deleted once the endpoint is back to the default (unpinned, weight 1).
+
+CREATE TABLE IF NOT EXISTS routing_overrides (
+    endpoint VARCHAR(500) PRIMARY KEY,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    weight DOUBLE PRECISION NOT NULL DEFAULT 1.0 CHECK (weight >= 0 AND weight <= 10),
+    reason TEXT,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE routing_overrides IS 'Operator pins and weights applied by the gateway load balancer';
+COMMENT ON COLUMN routing_overrides.pinned IS 'When any eligible endpoint of a model is pinned, only pinned endpoints receive traffic';
+COMMENT ON COLUMN routing_overrides.weight IS 'Multiplier on the routing score; 0 removes the endpoint from rotation';
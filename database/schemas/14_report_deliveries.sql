@@ -0,0 +1,27 @@
+-- Scheduled Report Deliveries
+-- One row per scheduled report (weekly tenant digest, fleet digest) per
+-- period, so each report is sent at most once across control-plane replicas.
+--
+-- Tenant digest preferences live in notification_config (channel = 'email'):
+-- enabled = false opts out, event_types can omit 'report.weekly_digest',
+-- destination overrides the account email (comma separated), and
+-- settings->'monthly_budget_usd' sets the budget shown in the digest.
+
+CREATE TABLE IF NOT EXISTS report_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    report_type VARCHAR(100) NOT NULL,   -- 'report.weekly_digest', 'report.fleet_digest'
+    scope VARCHAR(255) NOT NULL,         -- tenant ID, or 'fleet'
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'sending' CHECK (status IN ('sending', 'sent')),
+    recipients TEXT[],
+    email_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(report_type, scope, period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_deliveries_period_start ON report_deliveries(period_start DESC);
+
+COMMENT ON TABLE report_deliveries IS 'Scheduled report emails sent per period (claimed before send, at most once)';
+COMMENT ON COLUMN report_deliveries.status IS 'sending while claimed by a sender, sent once delivered';
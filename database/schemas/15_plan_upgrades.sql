@@ -0,0 +1,13 @@
+-- Self-Serve Plan Upgrades
+-- billing_plan now holds the plan names the gateway enforces (free, starter,
+-- pro, enterprise); legacy values stay valid. The Stripe subscription backing
+-- the plan is tracked so upgrades update it in place.
+
+ALTER TABLE tenants DROP CONSTRAINT IF EXISTS tenants_billing_plan_check;
+ALTER TABLE tenants ADD CONSTRAINT tenants_billing_plan_check
+    CHECK (billing_plan IN ('free', 'starter', 'pro', 'enterprise', 'serverless', 'reserved'));
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS stripe_subscription_id VARCHAR(255);
+
+COMMENT ON COLUMN tenants.billing_plan IS 'Subscription plan; drives API key limits and tier-gated features';
+COMMENT ON COLUMN tenants.stripe_subscription_id IS 'Stripe subscription billing the current plan';
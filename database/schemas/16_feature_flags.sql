@@ -0,0 +1,29 @@
+-- Feature Flags
+-- Gate new capabilities per tenant. A flag is on for a tenant when it is
+-- enabled and either the tenant has an override or falls inside the
+-- percentage rollout. Disabling a flag turns it off everywhere.
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+    key VARCHAR(100) PRIMARY KEY,
+    description TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    rollout_percent INTEGER NOT NULL DEFAULT 0 CHECK (rollout_percent >= 0 AND rollout_percent <= 100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS feature_flag_overrides (
+    flag_key VARCHAR(100) NOT NULL REFERENCES feature_flags(key) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (flag_key, tenant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_feature_flag_overrides_tenant ON feature_flag_overrides(tenant_id);
+
+COMMENT ON TABLE feature_flags IS 'Feature flags gating new capabilities per tenant';
+COMMENT ON COLUMN feature_flags.enabled IS 'Kill switch: when false the flag is off for every tenant, overrides included';
+COMMENT ON COLUMN feature_flags.rollout_percent IS 'Share of tenants the flag is on for, bucketed by a hash of flag key and tenant ID';
+COMMENT ON TABLE feature_flag_overrides IS 'Per-tenant flag values that take precedence over the percentage rollout';
@@ -0,0 +1,28 @@
+-- API Key Abuse Detection
+-- The gateway throttles or quarantines keys showing leak patterns (traffic
+-- from many networks, rate spikes, scraping-like prompts). Quarantined keys
+-- are rejected until an admin releases them; every action is recorded as an
+-- incident for review.
+
+ALTER TABLE api_keys DROP CONSTRAINT IF EXISTS api_keys_status_check;
+ALTER TABLE api_keys ADD CONSTRAINT api_keys_status_check
+    CHECK (status IN ('active', 'suspended', 'revoked', 'quarantined'));
+
+CREATE TABLE IF NOT EXISTS api_key_abuse_incidents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    api_key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    signal VARCHAR(50) NOT NULL CHECK (signal IN ('network_spread', 'rate_spike', 'scraping')),
+    action VARCHAR(20) NOT NULL CHECK (action IN ('throttle', 'quarantine')),
+    details JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'released', 'confirmed')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    review_note TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_key_abuse_incidents_status ON api_key_abuse_incidents(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_api_key_abuse_incidents_key ON api_key_abuse_incidents(api_key_id);
+
+COMMENT ON TABLE api_key_abuse_incidents IS 'API keys throttled or quarantined by gateway abuse detection, pending admin review';
+COMMENT ON COLUMN api_key_abuse_incidents.status IS 'open until reviewed; released reactivates the key, confirmed revokes it';
@@ -0,0 +1,20 @@
+-- Request Signing
+-- Optional HMAC-SHA256 request signing for high-security tenants. The signing
+-- secret is separate from API keys and encrypted at rest with
+-- REQUEST_SIGNING_ENCRYPTION_KEY. After a rotation the previous secret stays
+-- valid until previous_expires_at so clients can roll over without downtime.
+
+CREATE TABLE IF NOT EXISTS tenant_request_signing (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    secret_encrypted BYTEA NOT NULL,
+    key_id VARCHAR(50) NOT NULL,
+    previous_secret_encrypted BYTEA,
+    previous_expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    rotated_at TIMESTAMP WITH TIME ZONE
+);
+
+COMMENT ON TABLE tenant_request_signing IS 'Per-tenant HMAC request signing secrets and enforcement';
+COMMENT ON COLUMN tenant_request_signing.required IS 'When true, unsigned requests from the tenant are rejected';
+COMMENT ON COLUMN tenant_request_signing.key_id IS 'Encryption key version used for the secrets';
@@ -0,0 +1,65 @@
+-- Node Registry Consolidation
+-- Orchestrator launches, tenant instances and node agent self-registration
+-- now write nodes through one registry. endpoint_url is the canonical serving
+-- address; the legacy endpoint column is kept as a mirror for older readers.
+
+-- Tenant-owned instances record their owner on the node row
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_nodes_tenant_id ON nodes(tenant_id);
+
+-- Launch registrations happen before the node is serving
+ALTER TABLE nodes ALTER COLUMN endpoint_url SET DEFAULT '';
+
+-- Backfill the endpoint columns from whichever one a registration path wrote
+UPDATE nodes SET endpoint_url = endpoint
+WHERE COALESCE(endpoint_url, '') = '' AND COALESCE(endpoint, '') != '';
+
+UPDATE nodes SET endpoint = endpoint_url
+WHERE COALESCE(endpoint, '') = '' AND COALESCE(endpoint_url, '') != '';
+
+-- Self-registered rows never recorded their deployment or model linkage
+UPDATE nodes n SET deployment_id = dn.deployment_id
+FROM deployment_nodes dn
+WHERE dn.node_id = n.id AND n.deployment_id IS NULL;
+
+UPDATE nodes n SET model_id = m.id
+FROM models m
+WHERE m.name = n.model_name AND n.model_id IS NULL;
+
+UPDATE nodes n SET model_name = m.name
+FROM models m
+WHERE m.id = n.model_id AND n.model_name IS NULL;
+
+-- Merge duplicate rows for the same serving address: keep the row created at
+-- launch (it carries tenant and deployment) and fold in the agent's row.
+WITH ranked AS (
+    SELECT id, endpoint_url,
+           ROW_NUMBER() OVER (
+               PARTITION BY endpoint_url
+               ORDER BY (cluster_name IS NOT NULL) DESC, (deployment_id IS NOT NULL) DESC, created_at
+           ) AS rn
+    FROM nodes
+    WHERE endpoint_url != '' AND status NOT IN ('dead')
+),
+keepers AS (
+    SELECT r.endpoint_url, r.id AS keep_id
+    FROM ranked r
+    WHERE r.rn = 1
+),
+duplicates AS (
+    SELECT r.id AS dup_id, k.keep_id
+    FROM ranked r
+    JOIN keepers k ON k.endpoint_url = r.endpoint_url
+    WHERE r.rn > 1
+)
+UPDATE nodes n SET
+    status = 'dead',
+    status_message = 'merged_into_' || d.keep_id::text,
+    terminated_at = COALESCE(n.terminated_at, NOW()),
+    updated_at = NOW()
+FROM duplicates d
+WHERE n.id = d.dup_id;
+
+COMMENT ON COLUMN nodes.endpoint_url IS 'Canonical vLLM serving address; empty until the node is serving';
+COMMENT ON COLUMN nodes.endpoint IS 'Deprecated mirror of endpoint_url, written by the node registry';
+COMMENT ON COLUMN nodes.tenant_id IS 'Owning tenant for tenant-launched instances (NULL for platform nodes)';
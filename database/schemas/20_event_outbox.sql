@@ -0,0 +1,30 @@
+-- Transactional Event Outbox
+-- State changes write their events to this table in the same transaction,
+-- so an event is never lost when the process dies between commit and
+-- publish. A relay on each control plane replica claims pending rows,
+-- publishes them to the event bus and marks them delivered.
+
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    tenant_id VARCHAR(64),
+    payload JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    failed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Pending events in write order; delivered and abandoned rows drop out
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending ON event_outbox(id)
+    WHERE delivered_at IS NULL AND failed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_event_outbox_delivered_at ON event_outbox(delivered_at)
+    WHERE delivered_at IS NOT NULL;
+
+COMMENT ON TABLE event_outbox IS 'Events written with their state change and relayed to the event bus at least once';
+COMMENT ON COLUMN event_outbox.locked_until IS 'Lease held by the relay delivering the event, or the next retry time after a failure';
+COMMENT ON COLUMN event_outbox.failed_at IS 'Set when delivery is abandoned after repeated handler failures';
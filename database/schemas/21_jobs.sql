@@ -0,0 +1,35 @@
+-- Background Jobs
+-- Durable queue for work that used to run in fire-and-forget goroutines
+-- (usage inserts, API key last-used updates, deployment node launches).
+-- Workers on each control plane replica claim rows with SKIP LOCKED; failed
+-- jobs are retried with backoff and dead-lettered once attempts run out.
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(100) NOT NULL,
+    args JSONB NOT NULL DEFAULT '{}',
+    state VARCHAR(20) NOT NULL DEFAULT 'available'
+        CHECK (state IN ('available', 'running', 'retryable', 'completed', 'dead')),
+    attempt INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    timeout_seconds INTEGER NOT NULL DEFAULT 30,
+    errors JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    scheduled_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempted_at TIMESTAMP WITH TIME ZONE,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    finalized_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Runnable jobs in schedule order
+CREATE INDEX IF NOT EXISTS idx_jobs_runnable ON jobs(scheduled_at, id)
+    WHERE state IN ('available', 'retryable');
+CREATE INDEX IF NOT EXISTS idx_jobs_running ON jobs(locked_until)
+    WHERE state = 'running';
+CREATE INDEX IF NOT EXISTS idx_jobs_kind_state ON jobs(kind, state);
+CREATE INDEX IF NOT EXISTS idx_jobs_finalized_at ON jobs(finalized_at)
+    WHERE state = 'completed';
+
+COMMENT ON TABLE jobs IS 'Postgres-backed background job queue with retries and a dead-letter state';
+COMMENT ON COLUMN jobs.errors IS 'One entry per failed attempt: attempt number, error text and time';
+COMMENT ON COLUMN jobs.locked_until IS 'Lease of the worker running the job; expired leases are rescued';
@@ -0,0 +1,21 @@
+-- Named Admin Tokens
+-- Admin API access beyond the ADMIN_API_TOKEN bootstrap secret. Each token
+-- has a name for audit logs and can be revoked on its own. Only a SHA-256
+-- hash of the token is stored.
+
+CREATE TABLE IF NOT EXISTS admin_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Names identify tokens in audit logs, so they are unique among active tokens
+CREATE UNIQUE INDEX IF NOT EXISTS idx_admin_tokens_active_name ON admin_tokens(name)
+    WHERE revoked_at IS NULL;
+
+COMMENT ON TABLE admin_tokens IS 'Named, individually revocable admin API tokens';
+COMMENT ON COLUMN admin_tokens.token_prefix IS 'Leading characters of the token, shown to identify it';
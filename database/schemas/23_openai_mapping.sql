@@ -0,0 +1,16 @@
+-- OpenAI Organization/Project Mapping
+-- OpenAI SDKs send OpenAI-Organization and OpenAI-Project headers. Tenants
+-- can register the IDs they used with OpenAI so migrated clients work without
+-- code changes: the organization maps to the tenant and each project to one
+-- of its environments, which usage is then attributed to.
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS openai_organization_id VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenants_openai_organization_id ON tenants(openai_organization_id)
+    WHERE openai_organization_id IS NOT NULL;
+
+ALTER TABLE environments ADD COLUMN IF NOT EXISTS openai_project_id VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_environments_openai_project_id ON environments(tenant_id, openai_project_id)
+    WHERE openai_project_id IS NOT NULL;
+
+COMMENT ON COLUMN tenants.openai_organization_id IS 'OpenAI organization ID accepted in the OpenAI-Organization header';
+COMMENT ON COLUMN environments.openai_project_id IS 'OpenAI project ID accepted in the OpenAI-Project header';
@@ -0,0 +1,39 @@
+-- Scheduled Maintenance Windows
+-- Operators declare upcoming maintenance for the whole platform, one model or
+-- one region. Affected tenants are notified ahead of time, the public status
+-- endpoint lists the window, and region maintenance can pre-scale the
+-- deployments serving from that region into other regions.
+
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('platform', 'model', 'region')),
+    target VARCHAR(255),   -- model name or region code; NULL for platform
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    prescale BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'cancelled')),
+    created_by VARCHAR(255),
+    prescaled_at TIMESTAMP WITH TIME ZONE,
+    prescaled_node_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at),
+    CHECK ((scope = 'platform') = (target IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_schedule ON maintenance_windows(ends_at, starts_at) WHERE status = 'scheduled';
+
+COMMENT ON COLUMN maintenance_windows.prescale IS 'Launch replacement capacity in other regions before a region window starts';
+COMMENT ON COLUMN maintenance_windows.prescaled_node_ids IS 'Nodes queued by pre-scaling, kept for operators to reclaim after the window';
+
+-- One row per (window, tenant, milestone) so each notice is sent exactly once
+CREATE TABLE IF NOT EXISTS maintenance_notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    window_id UUID NOT NULL REFERENCES maintenance_windows(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    milestone VARCHAR(20) NOT NULL, -- "scheduled", "24h", "cancelled"
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(window_id, tenant_id, milestone)
+);
@@ -0,0 +1,27 @@
+-- Output Post-Processing Policies
+-- Tenants attach lightweight rules to their inference output: extra stop
+-- sequences, stripping an echoed system prompt, a line limit and regex
+-- redaction. The gateway applies them to streaming and non-streaming
+-- responses. A policy covers the whole tenant, one API key, one model
+-- route, or a key on one model; the most specific match wins.
+
+CREATE TABLE IF NOT EXISTS output_policies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    api_key_id UUID REFERENCES api_keys(id) ON DELETE CASCADE,
+    model VARCHAR(255),
+    rules JSONB NOT NULL DEFAULT '[]',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One policy per selector
+CREATE UNIQUE INDEX IF NOT EXISTS idx_output_policies_selector ON output_policies(
+    tenant_id,
+    COALESCE(api_key_id, '00000000-0000-0000-0000-000000000000'::uuid),
+    COALESCE(model, '')
+);
+
+COMMENT ON TABLE output_policies IS 'Per-tenant output post-processing rules, optionally scoped to an API key and/or model';
+COMMENT ON COLUMN output_policies.rules IS 'Ordered rules: stop_sequences, strip_system_echo, max_lines, redact';
@@ -0,0 +1,30 @@
+-- Node Crash Forensics
+-- When vLLM dies (usually a CUDA OOM) the node agent captures the tail of
+-- vllm.log, an nvidia-smi snapshot and the last vLLM metrics, uploads the
+-- bundle to R2 and files a crash report. The gateway adds the requests the
+-- node was serving at the time from its recent request buffer.
+
+CREATE TABLE IF NOT EXISTS node_crash_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    node_id UUID NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    reason VARCHAR(50) NOT NULL,
+    oom BOOLEAN NOT NULL DEFAULT false,
+    detected_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    summary JSONB NOT NULL DEFAULT '{}',
+    recent_requests JSONB NOT NULL DEFAULT '[]',
+    bundle_key TEXT,
+    bundle_size BIGINT,
+    bundle_sha256 VARCHAR(64),
+    bundle_uploaded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_crash_reports_node ON node_crash_reports(node_id, detected_at DESC);
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS last_crash_report_id UUID REFERENCES node_crash_reports(id) ON DELETE SET NULL;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS last_crash_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON TABLE node_crash_reports IS 'Forensics captured by the node agent when vLLM crashes';
+COMMENT ON COLUMN node_crash_reports.summary IS 'Log tail excerpt, GPU snapshot and last vLLM metrics reported by the agent';
+COMMENT ON COLUMN node_crash_reports.recent_requests IS 'Requests the gateway sent to the node just before the crash';
+COMMENT ON COLUMN node_crash_reports.bundle_key IS 'R2 object key of the tar.gz bundle';
@@ -0,0 +1,43 @@
+-- Model Licensing
+-- Some model families (e.g. Llama) require the user to accept a license
+-- before use. Licenses are versioned: pointing a model at a new version
+-- means tenants must accept again. The gateway rejects requests to a gated
+-- model until the tenant has accepted its license.
+
+CREATE TABLE IF NOT EXISTS model_licenses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(50) NOT NULL DEFAULT '1',
+    url TEXT NOT NULL,
+    summary TEXT,
+    requires_acceptance BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(name, version)
+);
+
+CREATE TRIGGER update_model_licenses_updated_at BEFORE UPDATE ON model_licenses
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE models ADD COLUMN IF NOT EXISTS license_id UUID REFERENCES model_licenses(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_models_license_id ON models(license_id) WHERE license_id IS NOT NULL;
+
+-- One acceptance per tenant and license version, kept as the audit record
+CREATE TABLE IF NOT EXISTS tenant_license_acceptances (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    license_id UUID NOT NULL REFERENCES model_licenses(id) ON DELETE CASCADE,
+    accepted_by_key_id UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    accepted_from VARCHAR(255),
+    accepted_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, license_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_license_acceptances_license ON tenant_license_acceptances(license_id);
+
+COMMENT ON TABLE model_licenses IS 'License terms attached to models, versioned so new terms require re-acceptance';
+COMMENT ON COLUMN model_licenses.requires_acceptance IS 'Gate the models under this license until the tenant accepts it';
+COMMENT ON COLUMN models.license_id IS 'License governing use of the model; gated when the license requires acceptance';
+COMMENT ON TABLE tenant_license_acceptances IS 'Which tenants accepted which license versions, when and with which API key';
+COMMENT ON COLUMN tenant_license_acceptances.accepted_from IS 'Client address the acceptance was made from';
@@ -0,0 +1,12 @@
+-- SkyPilot Credential Profiles
+-- In API Server mode tenant credentials can be registered with the SkyPilot
+-- API server as named profiles. Launches then reference the profile ID
+-- instead of carrying raw secrets, and a credential update rotates the
+-- profile in place. A profile is stale when the credential was updated
+-- after it was last synced.
+
+ALTER TABLE cloud_credentials ADD COLUMN IF NOT EXISTS skypilot_profile_id VARCHAR(255);
+ALTER TABLE cloud_credentials ADD COLUMN IF NOT EXISTS skypilot_profile_synced_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN cloud_credentials.skypilot_profile_id IS 'ID of the credential profile registered with the SkyPilot API server';
+COMMENT ON COLUMN cloud_credentials.skypilot_profile_synced_at IS 'When the profile last received these credentials; older than updated_at means it needs rotating';
@@ -0,0 +1,65 @@
+-- Billing Sandbox
+-- A per-tenant sandbox runs the usage-to-invoice pipeline on accelerated
+-- cycles (e.g. one "month" per hour) against Stripe test mode. It prices
+-- the tenant's recorded usage plus injected synthetic usage, optionally with
+-- proposed model prices, so pricing changes and invoice math can be checked
+-- end to end. Production usage is only read: usage_records.billed, the
+-- tenant's live Stripe customer and billing_events are never touched.
+
+CREATE TABLE IF NOT EXISTS billing_sandboxes (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    cycle_seconds INTEGER NOT NULL CHECK (cycle_seconds >= 60),
+    include_live_usage BOOLEAN NOT NULL DEFAULT true,
+    price_overrides JSONB NOT NULL DEFAULT '{}', -- model name -> {"input_per_million", "output_per_million"}
+    cycle_number INTEGER NOT NULL DEFAULT 1,
+    current_period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    current_period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    stripe_customer_id VARCHAR(255), -- Stripe test-mode customer, never the live one
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (current_period_end > current_period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_billing_sandboxes_due ON billing_sandboxes(current_period_end) WHERE enabled;
+
+CREATE TRIGGER update_billing_sandboxes_updated_at BEFORE UPDATE ON billing_sandboxes
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Synthetic usage injected into a sandbox; never seen by production billing
+CREATE TABLE IF NOT EXISTS billing_sandbox_usage (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES billing_sandboxes(tenant_id) ON DELETE CASCADE,
+    model VARCHAR(255) NOT NULL,
+    requests INTEGER NOT NULL DEFAULT 1 CHECK (requests > 0),
+    prompt_tokens BIGINT NOT NULL DEFAULT 0 CHECK (prompt_tokens >= 0),
+    completion_tokens BIGINT NOT NULL DEFAULT 0 CHECK (completion_tokens >= 0),
+    recorded_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_billing_sandbox_usage_tenant ON billing_sandbox_usage(tenant_id, recorded_at);
+
+-- One invoice per closed sandbox cycle
+CREATE TABLE IF NOT EXISTS billing_sandbox_invoices (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES billing_sandboxes(tenant_id) ON DELETE CASCADE,
+    cycle_number INTEGER NOT NULL,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    line_items JSONB NOT NULL DEFAULT '[]',
+    total_tokens BIGINT NOT NULL DEFAULT 0,
+    total_microdollars BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'computed' CHECK (status IN ('computed', 'issued', 'failed')),
+    stripe_invoice_id VARCHAR(255),
+    stripe_amount_due_cents BIGINT,
+    stripe_invoice_url TEXT,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, cycle_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_billing_sandbox_invoices_tenant ON billing_sandbox_invoices(tenant_id, cycle_number DESC);
+
+COMMENT ON COLUMN billing_sandboxes.cycle_seconds IS 'Length of one accelerated billing cycle (a month in production)';
+COMMENT ON COLUMN billing_sandboxes.include_live_usage IS 'Price the tenant''s recorded usage_records for each cycle window alongside synthetic usage';
+COMMENT ON COLUMN billing_sandbox_invoices.stripe_amount_due_cents IS 'Amount Stripe computed for the test-mode invoice, to compare against total_microdollars';
@@ -0,0 +1,43 @@
+-- Node Launch Timings
+-- Each launch records when it passed through the cold start pipeline so
+-- the minutes between a launch request and a serving node can be split into
+-- phases and compared across providers, regions and GPUs:
+--   requested_at      launch request accepted by the control plane
+--   setup_started_at  SkyPilot setup began on the instance (reported by the node)
+--   instance_ready_at SkyPilot finished provisioning and setup
+--   vllm_started_at   vLLM process started (reported by the node)
+--   vllm_healthy_at   node agent registered after vLLM passed its health check
+--   first_token_at    gateway received the first successful response from the node
+-- The row is written when the launch starts, before the node row exists, so
+-- node_id is not a foreign key.
+
+CREATE TABLE IF NOT EXISTS node_launch_timings (
+    node_id UUID PRIMARY KEY,
+    cluster_name VARCHAR(255),
+    provider VARCHAR(50) NOT NULL,
+    region VARCHAR(100) NOT NULL DEFAULT '',
+    gpu_type VARCHAR(100) NOT NULL DEFAULT '',
+    gpu_count INTEGER NOT NULL DEFAULT 1,
+    model_name VARCHAR(255),
+    spot_instance BOOLEAN NOT NULL DEFAULT false,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    setup_started_at TIMESTAMP WITH TIME ZONE,
+    instance_ready_at TIMESTAMP WITH TIME ZONE,
+    vllm_started_at TIMESTAMP WITH TIME ZONE,
+    vllm_healthy_at TIMESTAMP WITH TIME ZONE,
+    first_token_at TIMESTAMP WITH TIME ZONE,
+    failed_at TIMESTAMP WITH TIME ZONE,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_launch_timings_requested ON node_launch_timings(requested_at DESC);
+CREATE INDEX IF NOT EXISTS idx_node_launch_timings_group ON node_launch_timings(provider, region, gpu_type, requested_at DESC);
+
+CREATE TRIGGER update_node_launch_timings_updated_at BEFORE UPDATE ON node_launch_timings
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE node_launch_timings IS 'Per-launch cold start checkpoints, aggregated by /admin/platform/launch-metrics';
+COMMENT ON COLUMN node_launch_timings.setup_started_at IS 'Node clock; ignored when it falls outside the launch window';
+COMMENT ON COLUMN node_launch_timings.vllm_started_at IS 'Node clock; ignored when it falls outside the launch window';
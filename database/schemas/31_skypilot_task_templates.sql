@@ -0,0 +1,33 @@
+-- SkyPilot Task Templates
+-- Admin overrides of the task template files shipped with the control plane.
+-- Each upload is a new version of the template for a provider and runtime;
+-- at most one version per provider and runtime is active. Launches resolve,
+-- in order: the active override for the provider, the active override for
+-- any provider (provider = ''), the <runtime>.<provider>.yaml.tmpl file and
+-- the <runtime>.yaml.tmpl file.
+
+CREATE TABLE IF NOT EXISTS skypilot_task_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider VARCHAR(50) NOT NULL DEFAULT '',
+    runtime VARCHAR(50) NOT NULL,
+    version INTEGER NOT NULL,
+    body TEXT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT false,
+    notes TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, runtime, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_skypilot_task_templates_active
+    ON skypilot_task_templates(provider, runtime) WHERE active;
+
+-- Which template generated each node, e.g. 'override:aws/vllm@v3' or
+-- 'file:vllm.yaml.tmpl@1a2b3c4d5e6f' (file name and SHA-256 prefix)
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS task_template VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_nodes_task_template ON nodes(task_template) WHERE task_template IS NOT NULL;
+
+COMMENT ON TABLE skypilot_task_templates IS 'Versioned SkyPilot task template overrides, managed via /admin/skypilot/templates';
+COMMENT ON COLUMN skypilot_task_templates.provider IS 'Cloud provider, or empty for any provider';
+COMMENT ON COLUMN nodes.task_template IS 'Ref of the SkyPilot task template that launched the node';
@@ -0,0 +1,27 @@
+-- Node Exec Audit
+-- Every command run on a tenant-owned node (admin exec, log tails, cache
+-- warming) is recorded before it starts, and completed when it ends. A run
+-- whose record cannot be written is refused. finished_at stays NULL for
+-- runs interrupted by a control plane restart.
+
+CREATE TABLE IF NOT EXISTS node_exec_audit (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    node_id UUID REFERENCES nodes(id) ON DELETE SET NULL,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    cluster_name VARCHAR(255) NOT NULL,
+    command TEXT NOT NULL,
+    source VARCHAR(50) NOT NULL, -- 'admin_api' or 'system'
+    actor VARCHAR(255), -- admin token name for admin_api runs
+    streamed BOOLEAN NOT NULL DEFAULT false,
+    timeout_seconds INTEGER NOT NULL,
+    exit_code INTEGER,
+    error TEXT,
+    output_bytes BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_exec_audit_tenant ON node_exec_audit(tenant_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_node_exec_audit_node ON node_exec_audit(node_id, started_at DESC);
+
+COMMENT ON TABLE node_exec_audit IS 'Commands run on tenant-owned nodes, listed by GET /admin/exec-audit';
@@ -0,0 +1,16 @@
+-- Node Runtime Drift
+-- Each node records how it was launched to serve (model, vLLM version and
+-- flags) and its agent reports what vLLM is actually running with every
+-- heartbeat. The deployment controller compares the two and marks when a
+-- node first drifted, e.g. after an operator swapped the model by hand.
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS desired_runtime JSONB;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS reported_runtime JSONB;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS runtime_reported_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS runtime_drift_since TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_nodes_runtime_drift ON nodes(deployment_id) WHERE runtime_drift_since IS NOT NULL;
+
+COMMENT ON COLUMN nodes.desired_runtime IS 'Runtime spec at launch: {model, vllm_version, vllm_args}';
+COMMENT ON COLUMN nodes.reported_runtime IS 'Runtime reported by the node agent: {served_models, vllm_version, vllm_args}';
+COMMENT ON COLUMN nodes.runtime_drift_since IS 'When the node was first seen drifting from its deployment spec; NULL when in sync';
@@ -0,0 +1,46 @@
+-- Quality Samples
+-- A small random fraction (QUALITY_SAMPLE_RATE) of inference requests from
+-- tenants that opted in is stored for offline quality evaluation. Samples
+-- hold no raw identifiers: tenant, API key, end user and request IDs are
+-- HMAC-SHA256 hashed with QUALITY_SAMPLE_HASH_KEY, and emails, phone numbers,
+-- IP addresses and long digit runs are masked in the text. Samples are
+-- deleted after QUALITY_SAMPLE_RETENTION, and when a tenant opts out.
+
+CREATE TABLE IF NOT EXISTS tenant_quality_sampling (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    changed_by_api_key_id UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    opted_in_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS quality_samples (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    sampled_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    tenant_hash VARCHAR(64) NOT NULL,
+    api_key_hash VARCHAR(64),
+    user_hash VARCHAR(64),
+    request_hash VARCHAR(64),
+    endpoint VARCHAR(50) NOT NULL, -- 'chat.completions' or 'completions'
+    model VARCHAR(255) NOT NULL,
+    model_alias VARCHAR(255),
+    vllm_version VARCHAR(50),
+    task_template VARCHAR(255),
+    streamed BOOLEAN NOT NULL DEFAULT false,
+    status INTEGER NOT NULL,
+    latency_ms INTEGER NOT NULL,
+    prompt_tokens INTEGER,
+    completion_tokens INTEGER,
+    prompt JSONB NOT NULL, -- [{role, content}] for chat, a string for completions
+    response TEXT NOT NULL,
+    prompt_truncated BOOLEAN NOT NULL DEFAULT false,
+    response_truncated BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS idx_quality_samples_sampled_at ON quality_samples(sampled_at);
+CREATE INDEX IF NOT EXISTS idx_quality_samples_tenant ON quality_samples(tenant_hash);
+CREATE INDEX IF NOT EXISTS idx_quality_samples_model ON quality_samples(model, sampled_at);
+
+COMMENT ON TABLE tenant_quality_sampling IS 'Tenant opt-in to quality sampling, managed via /v1/privacy/quality-sampling';
+COMMENT ON TABLE quality_samples IS 'Anonymized prompt/response samples, downloaded via GET /admin/quality-samples';
+COMMENT ON COLUMN quality_samples.tenant_hash IS 'HMAC of the tenant ID; samples taken under a previous hash key cannot be matched to their tenant';
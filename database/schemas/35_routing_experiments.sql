@@ -0,0 +1,44 @@
+-- Routing Experiments
+-- A routing experiment compares the node classes (GPU type, spot or on-demand)
+-- serving one model. The gateway load balancer sends a small exploration
+-- fraction of the model's traffic to each class, measures its cost per 1k
+-- tokens and latency, and converges on the cheapest class that meets the
+-- latency SLO. Arm counters are summed across gateway replicas.
+
+CREATE TABLE IF NOT EXISTS routing_experiments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    model_name VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'converged', 'stopped')),
+    latency_slo_ms INTEGER NOT NULL CHECK (latency_slo_ms > 0),
+    slo_target DOUBLE PRECISION NOT NULL DEFAULT 0.95 CHECK (slo_target > 0 AND slo_target <= 1),
+    explore_fraction DOUBLE PRECISION NOT NULL DEFAULT 0.1 CHECK (explore_fraction > 0 AND explore_fraction <= 1),
+    min_samples INTEGER NOT NULL DEFAULT 200 CHECK (min_samples > 0),
+    winner_class VARCHAR(150),
+    reason TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    converged_at TIMESTAMP WITH TIME ZONE,
+    stopped_at TIMESTAMP WITH TIME ZONE
+);
+
+-- One live experiment per model; converged experiments keep routing to their winner
+CREATE UNIQUE INDEX IF NOT EXISTS idx_routing_experiments_live
+    ON routing_experiments(model_name) WHERE status IN ('running', 'converged');
+
+CREATE TABLE IF NOT EXISTS routing_experiment_arms (
+    experiment_id UUID NOT NULL REFERENCES routing_experiments(id) ON DELETE CASCADE,
+    node_class VARCHAR(150) NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    errors BIGINT NOT NULL DEFAULT 0,
+    slo_violations BIGINT NOT NULL DEFAULT 0,
+    latency_ms_sum BIGINT NOT NULL DEFAULT 0,
+    tokens BIGINT NOT NULL DEFAULT 0,
+    cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (experiment_id, node_class)
+);
+
+COMMENT ON TABLE routing_experiments IS 'Bandit experiments over node classes, managed via /admin/routing/experiments';
+COMMENT ON COLUMN routing_experiments.latency_slo_ms IS 'Response latency a request must stay within to count towards the SLO';
+COMMENT ON COLUMN routing_experiments.slo_target IS 'Fraction of requests that must meet latency_slo_ms for a class to qualify';
+COMMENT ON COLUMN routing_experiment_arms.node_class IS 'GPU type and pricing, e.g. A100:spot';
+COMMENT ON COLUMN routing_experiment_arms.cost_usd IS 'Node hourly price times the time each request held the node';
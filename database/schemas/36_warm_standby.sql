@@ -0,0 +1,16 @@
+-- Warm Standby Nodes
+-- Critical deployments can keep one extra node running and serving, but out
+-- of the routing set. When a primary node fails the deployment controller
+-- promotes the standby into routing and launches a new standby, so failover
+-- takes seconds instead of a full node launch.
+
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS warm_standby BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS standby BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS promoted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_nodes_standby ON nodes(deployment_id) WHERE standby;
+
+COMMENT ON COLUMN deployments.warm_standby IS 'Keep a warm standby node that is promoted when a primary node fails';
+COMMENT ON COLUMN nodes.standby IS 'Warm standby: running but excluded from routing until promoted';
+COMMENT ON COLUMN nodes.promoted_at IS 'When the node was promoted from warm standby into the routing set';
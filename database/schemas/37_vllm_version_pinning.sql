@@ -0,0 +1,28 @@
+-- Per-Deployment vLLM Versions
+-- Deployments can pin the vLLM and torch versions their nodes install
+-- instead of the platform's VLLM_VERSION / TORCH_VERSION. Launches are
+-- refused when the vLLM version is older than the model's architecture
+-- needs. Nodes and usage records keep the versions that served, so quality
+-- or latency regressions can be traced to a runtime upgrade.
+
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS vllm_version VARCHAR(50);
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS torch_version VARCHAR(50);
+
+ALTER TABLE models ADD COLUMN IF NOT EXISTS min_vllm_version VARCHAR(50);
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS vllm_version VARCHAR(50);
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS torch_version VARCHAR(50);
+
+ALTER TABLE usage_records ADD COLUMN IF NOT EXISTS vllm_version VARCHAR(50);
+ALTER TABLE usage_records ADD COLUMN IF NOT EXISTS torch_version VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_usage_records_vllm_version ON usage_records(vllm_version, timestamp DESC)
+    WHERE vllm_version IS NOT NULL;
+
+COMMENT ON COLUMN deployments.vllm_version IS 'Pinned vLLM version for new nodes; NULL uses the platform default';
+COMMENT ON COLUMN deployments.torch_version IS 'Pinned torch version for new nodes; NULL uses the platform default';
+COMMENT ON COLUMN models.min_vllm_version IS 'First vLLM release supporting the model architecture; NULL falls back to built-in known architectures';
+COMMENT ON COLUMN nodes.vllm_version IS 'vLLM version serving on the node: launched with, then as reported by the agent';
+COMMENT ON COLUMN nodes.torch_version IS 'torch version the node was launched with';
+COMMENT ON COLUMN usage_records.vllm_version IS 'vLLM version of the serving node at request time';
+COMMENT ON COLUMN usage_records.torch_version IS 'torch version of the serving node at request time';
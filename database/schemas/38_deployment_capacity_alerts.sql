@@ -0,0 +1,39 @@
+-- Deployment Capacity Alerts
+-- A dedicated deployment belongs to one tenant. When its serving nodes stay
+-- below min_replicas for longer than the alert delay, the deployment
+-- controller opens an incident and notifies the tenant (email and webhook)
+-- as well as platform operators, then sends updates as capacity changes and
+-- a final notice on recovery.
+
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id) ON DELETE SET NULL;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS capacity_alert_after_minutes INT
+    CHECK (capacity_alert_after_minutes IS NULL OR capacity_alert_after_minutes > 0);
+
+CREATE INDEX IF NOT EXISTS idx_deployments_tenant_id ON deployments(tenant_id) WHERE tenant_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS deployment_capacity_incidents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    deployment_id UUID NOT NULL REFERENCES deployments(id) ON DELETE CASCADE,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE SET NULL,
+
+    min_replicas INT NOT NULL,
+    serving_replicas INT NOT NULL,
+    lowest_replicas INT NOT NULL,
+
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    notified_at TIMESTAMP WITH TIME ZONE,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+-- At most one open incident per deployment
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deployment_capacity_incidents_open
+    ON deployment_capacity_incidents(deployment_id) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_deployment_capacity_incidents_tenant
+    ON deployment_capacity_incidents(tenant_id, started_at DESC) WHERE tenant_id IS NOT NULL;
+
+COMMENT ON COLUMN deployments.tenant_id IS 'Owning tenant of a dedicated deployment (NULL for shared platform deployments)';
+COMMENT ON COLUMN deployments.capacity_alert_after_minutes IS 'How long serving capacity may stay below min_replicas before an incident is raised (NULL uses the platform default)';
+COMMENT ON TABLE deployment_capacity_incidents IS 'Periods a deployment served with fewer nodes than its minimum';
+COMMENT ON COLUMN deployment_capacity_incidents.serving_replicas IS 'Serving nodes at the last check';
+COMMENT ON COLUMN deployment_capacity_incidents.lowest_replicas IS 'Fewest serving nodes during the incident';
+COMMENT ON COLUMN deployment_capacity_incidents.notified_at IS 'When the incident was announced; dips shorter than the alert delay are never announced';
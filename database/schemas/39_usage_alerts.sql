@@ -0,0 +1,44 @@
+-- Tenant Usage Alerts
+-- Tenants define rules over their own usage, e.g. "spend over the last
+-- 24 hours above $50" or "error rate above 2% over 10 minutes on model X".
+-- The control plane evaluates enabled rules every minute against
+-- usage_records. A rule fires once when its condition starts holding and
+-- resolves when it stops; both are delivered to the tenant by email and
+-- webhook according to notification_config.
+
+CREATE TABLE IF NOT EXISTS usage_alert_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+
+    metric VARCHAR(50) NOT NULL
+        CHECK (metric IN ('spend_usd', 'requests', 'tokens', 'error_rate', 'p95_latency_ms')),
+    model VARCHAR(255),
+    operator VARCHAR(2) NOT NULL DEFAULT '>' CHECK (operator IN ('>', '<')),
+    threshold DOUBLE PRECISION NOT NULL CHECK (threshold >= 0),
+    window_minutes INT NOT NULL CHECK (window_minutes > 0),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+
+    -- Evaluation state
+    state VARCHAR(20) NOT NULL DEFAULT 'ok' CHECK (state IN ('ok', 'firing')),
+    last_value DOUBLE PRECISION,
+    last_evaluated_at TIMESTAMP WITH TIME ZONE,
+    triggered_at TIMESTAMP WITH TIME ZONE,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (tenant_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_alert_rules_enabled ON usage_alert_rules(tenant_id) WHERE enabled;
+
+-- Rules are evaluated over tenant usage windows
+CREATE INDEX IF NOT EXISTS idx_usage_records_tenant_timestamp ON usage_records(tenant_id, timestamp DESC);
+
+COMMENT ON TABLE usage_alert_rules IS 'Tenant-defined alert rules over usage, spend, error rate and latency';
+COMMENT ON COLUMN usage_alert_rules.model IS 'Model name the rule is limited to (NULL covers all models)';
+COMMENT ON COLUMN usage_alert_rules.threshold IS 'Dollars for spend_usd, percent for error_rate, milliseconds for p95_latency_ms, counts otherwise';
+COMMENT ON COLUMN usage_alert_rules.window_minutes IS 'Rolling window the metric is computed over';
+COMMENT ON COLUMN usage_alert_rules.state IS 'firing while the condition holds; changes are notified once';
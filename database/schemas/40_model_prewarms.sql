@@ -0,0 +1,32 @@
+-- Model Prewarms
+-- Tenants (within their plan's quota) and operators ask for extra replicas
+-- of a model's deployment for a window ahead of an anticipated traffic
+-- spike. The deployment controller launches the extra replicas shortly
+-- before the window starts, warms the model cache once they are up and
+-- scales them back when the window ends.
+
+CREATE TABLE IF NOT EXISTS model_prewarms (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    model_name VARCHAR(255) NOT NULL,
+    deployment_id UUID NOT NULL REFERENCES deployments(id) ON DELETE CASCADE,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,  -- NULL when requested by an operator
+    replicas INT NOT NULL CHECK (replicas > 0),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'cancelled')),
+    created_by VARCHAR(255),
+    warmed_at TIMESTAMP WITH TIME ZONE,
+    scaled_back_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_prewarms_deployment ON model_prewarms(deployment_id, ends_at)
+    WHERE status = 'scheduled' AND scaled_back_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_model_prewarms_tenant ON model_prewarms(tenant_id, ends_at)
+    WHERE tenant_id IS NOT NULL AND status = 'scheduled';
+
+COMMENT ON TABLE model_prewarms IS 'Extra deployment replicas requested for a window ahead of anticipated traffic';
+COMMENT ON COLUMN model_prewarms.warmed_at IS 'When the model cache was warmed on the prewarmed nodes';
+COMMENT ON COLUMN model_prewarms.scaled_back_at IS 'When the extra replicas were removed after the window';
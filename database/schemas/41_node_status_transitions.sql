@@ -0,0 +1,54 @@
+-- Node Status Transitions
+-- Every change of nodes.status is kept with when it happened, why and which
+-- component made it, so GET /admin/nodes/{id}/timeline can replay a node's
+-- life for post-incident reviews.
+--
+-- Writers set nodes.status_source alongside the status they write
+-- (orchestrator, node, monitor, reconciler, scheduler, admin). The trigger
+-- copies it into the transition and clears it, so the column is empty at
+-- rest and a writer that doesn't name itself is recorded as 'unknown'
+-- rather than inheriting the previous writer. The reason is the status
+-- message when the same update changed it.
+--
+-- Updates are captured by the trigger; the registry records the first
+-- status of a new node in the statement that inserts it. node_id is not a
+-- foreign key so history outlives the node row.
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS status_source VARCHAR(50);
+
+CREATE TABLE IF NOT EXISTS node_status_transitions (
+    id BIGSERIAL PRIMARY KEY,
+    node_id UUID NOT NULL,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    source VARCHAR(50) NOT NULL DEFAULT 'unknown',
+    reason VARCHAR(500),
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_status_transitions_node ON node_status_transitions(node_id, occurred_at);
+
+CREATE OR REPLACE FUNCTION record_node_status_transition()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.status IS DISTINCT FROM OLD.status THEN
+        INSERT INTO node_status_transitions (node_id, from_status, to_status, source, reason)
+        VALUES (
+            NEW.id, OLD.status, NEW.status,
+            COALESCE(NEW.status_source, 'unknown'),
+            CASE WHEN NEW.status_message IS DISTINCT FROM OLD.status_message THEN NEW.status_message END
+        );
+    END IF;
+    NEW.status_source = NULL;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS record_node_status_transition ON nodes;
+CREATE TRIGGER record_node_status_transition BEFORE UPDATE ON nodes
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status OR NEW.status_source IS NOT NULL)
+    EXECUTE FUNCTION record_node_status_transition();
+
+COMMENT ON TABLE node_status_transitions IS 'Node status history, read by /admin/nodes/{id}/timeline';
+COMMENT ON COLUMN nodes.status_source IS 'Component writing the status in this update; cleared by record_node_status_transition';
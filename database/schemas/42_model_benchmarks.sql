@@ -0,0 +1,23 @@
+-- Model Benchmarks
+-- Throughput measured for a model on a GPU type at increasing numbers of
+-- concurrent requests. The gateway caps the requests in flight on each node
+-- at the concurrency where throughput stops growing (the knee of the
+-- curve): past it extra requests only wait in vLLM's queue, so they are
+-- better sent to another node. Models without benchmarks fall back to
+-- models.tokens_per_second_capacity, and every limit is capped by the
+-- node's vLLM --max-num-seqs.
+
+CREATE TABLE IF NOT EXISTS model_benchmarks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    model_name VARCHAR(255) NOT NULL,
+    gpu_type VARCHAR(100) NOT NULL,
+    concurrency INT NOT NULL CHECK (concurrency > 0),
+    tokens_per_second DECIMAL(12, 2) NOT NULL CHECK (tokens_per_second >= 0),
+    p95_latency_ms INT,
+    recorded_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (model_name, gpu_type, concurrency)
+);
+
+COMMENT ON TABLE model_benchmarks IS 'Aggregate throughput per model and GPU type by concurrency; sets per-node concurrency limits';
+COMMENT ON COLUMN model_benchmarks.tokens_per_second IS 'Generated tokens per second across all concurrent requests';
@@ -0,0 +1,29 @@
+-- Node Log Archives
+-- Node launch logs live in Redis for a day after their last line. Once a
+-- node's log has been idle for NODE_LOG_ARCHIVE_AFTER it is copied to R2 as
+-- gzipped JSON lines and indexed here, so /admin/nodes/{id}/logs keeps
+-- serving it after Redis lets it go. Archives are deleted, object and row,
+-- at expires_at: NODE_LOG_RETENTION after the last line, or
+-- NODE_LOG_FAILED_RETENTION for launches that ended in failure.
+--
+-- A log that grows after it was archived is archived again over the same
+-- object. node_id is not a foreign key so logs outlive the node row.
+
+CREATE TABLE IF NOT EXISTS node_log_archives (
+    node_id UUID PRIMARY KEY,
+    tenant_id UUID,  -- NULL for platform nodes
+    object_key VARCHAR(500) NOT NULL,
+    entry_count INT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    first_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_phase VARCHAR(50),
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_log_archives_expires ON node_log_archives(expires_at);
+CREATE INDEX IF NOT EXISTS idx_node_log_archives_tenant ON node_log_archives(tenant_id) WHERE tenant_id IS NOT NULL;
+
+COMMENT ON TABLE node_log_archives IS 'Index of node launch logs archived to R2';
+COMMENT ON COLUMN node_log_archives.expires_at IS 'When the archive is deleted under the retention policy';
@@ -0,0 +1,22 @@
+-- Audio Transcription
+-- Whisper-class models serve /v1/audio/transcriptions. They are billed per
+-- minute of audio rather than per token, and run on nodes tagged with the
+-- audio workload class (CPU or GPU) so text traffic is never routed to them.
+
+ALTER TABLE models DROP CONSTRAINT IF EXISTS models_type_check;
+ALTER TABLE models ADD CONSTRAINT models_type_check
+    CHECK (type IN ('completion', 'chat', 'embedding', 'audio'));
+
+ALTER TABLE models ADD COLUMN IF NOT EXISTS price_per_audio_minute DECIMAL(10, 6);
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS workload_class VARCHAR(20) NOT NULL DEFAULT 'text'
+    CHECK (workload_class IN ('text', 'audio'));
+
+UPDATE nodes SET workload_class = 'audio'
+FROM models
+WHERE models.name = nodes.model_name AND models.type = 'audio' AND nodes.workload_class <> 'audio';
+
+CREATE INDEX IF NOT EXISTS idx_nodes_model_workload ON nodes(model_name, workload_class) WHERE status = 'active';
+
+COMMENT ON COLUMN models.price_per_audio_minute IS 'USD per minute of transcribed audio, for audio models';
+COMMENT ON COLUMN nodes.workload_class IS 'Kind of traffic the node serves: text (chat, completions, embeddings) or audio (transcription)';
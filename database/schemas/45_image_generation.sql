@@ -0,0 +1,21 @@
+-- Image Generation
+-- Diffusion models (Stable Diffusion, Flux) serve /v1/images/generations.
+-- They run with the diffusion runtime rather than vLLM, on nodes of the
+-- image workload class, and are billed per generated image.
+
+ALTER TABLE models DROP CONSTRAINT IF EXISTS models_type_check;
+ALTER TABLE models ADD CONSTRAINT models_type_check
+    CHECK (type IN ('completion', 'chat', 'embedding', 'audio', 'image'));
+
+ALTER TABLE models ADD COLUMN IF NOT EXISTS price_per_image DECIMAL(10, 6);
+
+ALTER TABLE nodes DROP CONSTRAINT IF EXISTS nodes_workload_class_check;
+ALTER TABLE nodes ADD CONSTRAINT nodes_workload_class_check
+    CHECK (workload_class IN ('text', 'audio', 'image'));
+
+UPDATE nodes SET workload_class = 'image'
+FROM models
+WHERE models.name = nodes.model_name AND models.type = 'image' AND nodes.workload_class <> 'image';
+
+COMMENT ON COLUMN models.price_per_image IS 'USD per generated 1024x1024 image, for image models; other sizes are priced by pixel count';
+COMMENT ON COLUMN nodes.workload_class IS 'Kind of traffic the node serves: text (chat, completions, embeddings), audio (transcription) or image (generation)';
@@ -0,0 +1,23 @@
+-- Model Incidents
+-- Periods a model was unavailable or shedding traffic. Outages are opened
+-- by the health monitor when the last serving node of a model goes
+-- unhealthy and resolved when one recovers; elevated_errors follows the
+-- model's error budget circuit breaker. Tenants see the incidents of models
+-- they used in GET /v1/incidents, together with maintenance windows and
+-- capacity incidents of their dedicated deployments.
+
+CREATE TABLE IF NOT EXISTS model_incidents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    model_name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('outage', 'elevated_errors')),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+-- At most one open incident of each kind per model
+CREATE UNIQUE INDEX IF NOT EXISTS idx_model_incidents_open
+    ON model_incidents(model_name, kind) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_model_incidents_started_at ON model_incidents(started_at DESC);
+
+COMMENT ON TABLE model_incidents IS 'Model outages and error budget breaches shown to the tenants using the model';
+COMMENT ON COLUMN model_incidents.kind IS 'outage: no healthy node served the model; elevated_errors: the error budget breaker was open';
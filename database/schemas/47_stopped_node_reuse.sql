@@ -0,0 +1,16 @@
+-- Stopped Node Reuse
+-- Deployments can stop some of their nodes at scale down instead of
+-- terminating them. A stopped cluster keeps its disks (which are still
+-- billed), so the next scale up restarts it with the model and setup in
+-- place, much faster than provisioning a new node. Spot clusters can't be
+-- stopped, so deployments that keep stopped nodes launch on-demand.
+
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS keep_stopped_nodes INTEGER NOT NULL DEFAULT 0
+    CHECK (keep_stopped_nodes >= 0);
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS stopped_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_nodes_stopped ON nodes(deployment_id, stopped_at DESC) WHERE status = 'stopped';
+
+COMMENT ON COLUMN deployments.keep_stopped_nodes IS 'Nodes scale down stops rather than terminates, for scale up to restart';
+COMMENT ON COLUMN nodes.stopped_at IS 'When the node was stopped at scale down';
@@ -0,0 +1,20 @@
+-- Node Admissions
+-- A node that registers is ramped into routing: it receives a small share
+-- of its model's traffic that grows to a full share over the ramp, while
+-- the gateway compares its error rate and latency against the model's
+-- other nodes. Nodes that pass are admitted; nodes that fail are cordoned
+-- with a zero-weight routing override instead of serving full load.
+
+CREATE TABLE IF NOT EXISTS node_admissions (
+    node_id UUID PRIMARY KEY REFERENCES nodes(id) ON DELETE CASCADE,
+    endpoint VARCHAR(500) NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE,
+    outcome VARCHAR(20) CHECK (outcome IN ('admitted', 'cordoned')),
+    reason TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_admissions_ramping ON node_admissions(node_id) WHERE finished_at IS NULL;
+
+COMMENT ON TABLE node_admissions IS 'Traffic ramp of each node since it last registered';
+COMMENT ON COLUMN node_admissions.outcome IS 'admitted: passed the ramp; cordoned: failed it and was removed from routing';
@@ -0,0 +1,28 @@
+-- Account Exports
+-- Tenants can export their account data (API key and credential metadata,
+-- instances, usage summary and invoices) as a zip archive of JSON files for
+-- procurement reviews and data portability. Exports are built by a
+-- background job, uploaded to R2 and downloadable until they expire. Each
+-- tenant keeps only its latest archive; download links are not handed out
+-- after expires_at.
+
+CREATE TABLE IF NOT EXISTS account_exports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed', 'expired')),
+    object_key VARCHAR(500),
+    size_bytes BIGINT,
+    error TEXT,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_exports_tenant ON account_exports(tenant_id, requested_at DESC);
+
+-- One export in progress per tenant
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_exports_in_progress
+    ON account_exports(tenant_id) WHERE status IN ('pending', 'running');
+
+COMMENT ON TABLE account_exports IS 'Tenant account data exports and their archives in R2';
+COMMENT ON COLUMN account_exports.status IS 'expired: the archive was deleted when a newer export completed';
@@ -0,0 +1,12 @@
+-- Node Compliance
+-- Nodes can be hardened during setup (key-only SSH, a firewall limiting
+-- the inference port to the control plane and mesh networks, unattended
+-- security updates). Node agents check that hardening and report it with
+-- their heartbeats for the admin compliance view.
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS hardening_profile VARCHAR(50);
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS compliance_report JSONB;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS compliance_reported_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN nodes.hardening_profile IS 'Security hardening applied at setup (baseline); NULL when the node was not hardened';
+COMMENT ON COLUMN nodes.compliance_report IS 'Latest hardening checks reported by the node agent';
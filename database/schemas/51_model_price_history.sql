@@ -0,0 +1,24 @@
+-- Model Price History
+-- Token price changes are recorded with the time they take effect. Changes
+-- effective now are applied to models right away; later ones are applied by
+-- the gateway when they come due. previous_* keep the prices replaced.
+
+CREATE TABLE IF NOT EXISTS model_price_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    model_id UUID NOT NULL REFERENCES models(id) ON DELETE CASCADE,
+    price_input_per_million DECIMAL(10, 6) NOT NULL CHECK (price_input_per_million >= 0),
+    price_output_per_million DECIMAL(10, 6) NOT NULL CHECK (price_output_per_million >= 0),
+    previous_input_per_million DECIMAL(10, 6),
+    previous_output_per_million DECIMAL(10, 6),
+    effective_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    applied_at TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_price_changes_model ON model_price_changes(model_id, effective_at DESC);
+CREATE INDEX IF NOT EXISTS idx_model_price_changes_pending
+    ON model_price_changes(effective_at) WHERE applied_at IS NULL;
+
+COMMENT ON TABLE model_price_changes IS 'Token price changes per model and when they take effect';
+COMMENT ON COLUMN model_price_changes.applied_at IS 'When the prices were written to models; NULL while scheduled';
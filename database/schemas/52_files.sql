@@ -0,0 +1,50 @@
+-- Files
+-- OpenAI-compatible Files API: tenants upload JSONL inputs for batch and
+-- fine-tuning jobs. File contents live in R2; rows hold their metadata and
+-- count against the tenant plan's storage quota until deleted. Large files
+-- can be sent in parts through the Uploads API, which assembles them into
+-- a file on completion.
+
+CREATE TABLE IF NOT EXISTS files (
+    id VARCHAR(64) PRIMARY KEY, -- file-...
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('batch', 'fine-tune')),
+    bytes BIGINT NOT NULL,
+    line_count INTEGER NOT NULL DEFAULT 0,
+    object_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_files_tenant ON files(tenant_id, created_at DESC) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS uploads (
+    id VARCHAR(64) PRIMARY KEY, -- upload_...
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('batch', 'fine-tune')),
+    bytes BIGINT NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'cancelled', 'expired')),
+    file_id VARCHAR(64) REFERENCES files(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_tenant ON uploads(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_uploads_pending ON uploads(expires_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS upload_parts (
+    id VARCHAR(64) PRIMARY KEY, -- part_...
+    upload_id VARCHAR(64) NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    bytes BIGINT NOT NULL,
+    object_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_parts_upload ON upload_parts(upload_id);
+
+COMMENT ON TABLE files IS 'Tenant files for batch and fine-tuning inputs; contents are stored in R2';
+COMMENT ON COLUMN files.deleted_at IS 'Set when the tenant deletes the file; its object is removed and it no longer counts against the quota';
+COMMENT ON COLUMN uploads.bytes IS 'Size the tenant declared; the completed parts must add up to it';
@@ -0,0 +1,33 @@
+-- Platform configuration snapshots
+-- Admins snapshot platform configuration (regions, instance catalog, models,
+-- aliases, deployment specs, launch profiles, routing overrides, feature
+-- flags) into a versioned JSON artifact in R2 and restore it into this or
+-- another environment, for disaster recovery drills and staging parity.
+
+CREATE TABLE IF NOT EXISTS config_snapshots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    label VARCHAR(255),
+    format_version INTEGER NOT NULL,
+    object_key TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    row_counts JSONB NOT NULL DEFAULT '{}', -- table -> rows captured
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_config_snapshots_created ON config_snapshots(created_at DESC);
+
+CREATE TABLE IF NOT EXISTS config_snapshot_restores (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    snapshot_id UUID NOT NULL, -- may come from another environment
+    snapshot_label VARCHAR(255),
+    summary JSONB NOT NULL DEFAULT '{}', -- table -> inserted/updated/skipped
+    restored_by VARCHAR(255),
+    restored_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_config_snapshot_restores_restored ON config_snapshot_restores(restored_at DESC);
+
+COMMENT ON TABLE config_snapshots IS 'Platform configuration snapshots; the artifact lives in R2 at object_key';
+COMMENT ON TABLE config_snapshot_restores IS 'Applied configuration restores; snapshot_id has no foreign key since snapshots can come from another environment';
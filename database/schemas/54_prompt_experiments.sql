@@ -0,0 +1,41 @@
+-- Prompt experiments
+-- Tenants register system prompt variants for a model and the gateway
+-- assigns each chat request to a variant by percentage, so prompts can be
+-- A/B tested without client changes. Per-variant counters hold the
+-- aggregate latency and length of the responses each variant produced.
+
+CREATE TABLE IF NOT EXISTS prompt_experiments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    model VARCHAR(255), -- NULL applies to every model without its own experiment
+    status VARCHAR(20) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'stopped')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    stopped_at TIMESTAMP WITH TIME ZONE
+);
+
+-- One running experiment per tenant and model
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompt_experiments_running
+    ON prompt_experiments(tenant_id, COALESCE(model, ''))
+    WHERE status = 'running';
+
+CREATE INDEX IF NOT EXISTS idx_prompt_experiments_tenant ON prompt_experiments(tenant_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS prompt_variants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    experiment_id UUID NOT NULL REFERENCES prompt_experiments(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    system_prompt TEXT NOT NULL DEFAULT '', -- empty leaves requests unchanged (control)
+    percent INTEGER NOT NULL CHECK (percent BETWEEN 0 AND 100),
+    requests BIGINT NOT NULL DEFAULT 0,
+    errors BIGINT NOT NULL DEFAULT 0,
+    latency_ms_sum BIGINT NOT NULL DEFAULT 0,
+    usage_requests BIGINT NOT NULL DEFAULT 0, -- responses that reported token usage
+    prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    completion_tokens BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (experiment_id, name)
+);
+
+COMMENT ON TABLE prompt_experiments IS 'Tenant A/B experiments over system prompt variants';
+COMMENT ON TABLE prompt_variants IS 'System prompt variants with aggregate outcome counters, flushed by each gateway replica';
@@ -0,0 +1,35 @@
+-- Egress-aware routing
+-- When several regions serve a model, endpoint selection also weighs the
+-- network egress cost and latency between the node's cloud region and the
+-- client's region (the environment's region). How much each counts is set
+-- per tenant plan.
+
+CREATE TABLE IF NOT EXISTS region_egress_routes (
+    provider VARCHAR(50) NOT NULL DEFAULT '*', -- node cloud provider, '*' for any
+    source_region VARCHAR(50) NOT NULL, -- node region code, '*' for any
+    dest_region VARCHAR(50) NOT NULL, -- client region code, '*' for any
+    egress_cost_per_gb DECIMAL(10, 4) NOT NULL DEFAULT 0 CHECK (egress_cost_per_gb >= 0),
+    latency_ms INTEGER NOT NULL DEFAULT 0 CHECK (latency_ms >= 0),
+    updated_by VARCHAR(255),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, source_region, dest_region)
+);
+
+CREATE TABLE IF NOT EXISTS routing_egress_weights (
+    plan VARCHAR(50) PRIMARY KEY, -- tenants.billing_plan
+    cost_weight DECIMAL(6, 3) NOT NULL DEFAULT 0 CHECK (cost_weight >= 0),
+    latency_weight DECIMAL(6, 3) NOT NULL DEFAULT 0 CHECK (latency_weight >= 0),
+    updated_by VARCHAR(255),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Cheaper plans lean on egress cost, higher plans on network latency
+INSERT INTO routing_egress_weights (plan, cost_weight, latency_weight) VALUES
+('free', 1.0, 0.25),
+('starter', 0.75, 0.5),
+('pro', 0.5, 1.0),
+('enterprise', 0.25, 1.5)
+ON CONFLICT (plan) DO NOTHING;
+
+COMMENT ON TABLE region_egress_routes IS 'Estimated egress cost and network latency from node regions to client regions; the most specific row applies';
+COMMENT ON TABLE routing_egress_weights IS 'Per plan weight of egress cost and latency in endpoint selection; plans without a row ignore egress';
@@ -0,0 +1,42 @@
+-- Emergency kill switches
+-- An admin can stop routing inference for a tenant, a model, a provider or
+-- the whole fleet, and optionally terminate the nodes in scope, for
+-- incidents such as compromised keys or runaway costs. Unless two-person
+-- confirmation is turned off, a switch takes effect only once a second
+-- admin confirms it. Every step is recorded in kill_switch_audit.
+
+CREATE TABLE IF NOT EXISTS kill_switches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('tenant', 'model', 'provider', 'global')),
+    target VARCHAR(255), -- tenant ID, model name or provider; NULL for global
+    terminate_nodes BOOLEAN NOT NULL DEFAULT false,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'active', 'cancelled', 'expired', 'released')),
+    requested_by VARCHAR(255) NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    confirm_by TIMESTAMP WITH TIME ZONE NOT NULL, -- pending switches expire after this
+    confirmed_by VARCHAR(255),
+    activated_at TIMESTAMP WITH TIME ZONE,
+    released_by VARCHAR(255),
+    released_at TIMESTAMP WITH TIME ZONE,
+    CHECK ((scope = 'global') = (target IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_kill_switches_status ON kill_switches(status) WHERE status IN ('pending', 'active');
+CREATE INDEX IF NOT EXISTS idx_kill_switches_requested ON kill_switches(requested_at DESC);
+
+CREATE TABLE IF NOT EXISTS kill_switch_audit (
+    id BIGSERIAL PRIMARY KEY, -- orders steps recorded in the same transaction
+    kill_switch_id UUID NOT NULL REFERENCES kill_switches(id) ON DELETE CASCADE,
+    action VARCHAR(50) NOT NULL, -- requested, confirmed, activated, cancelled, expired, released, nodes_terminated, ...
+    actor VARCHAR(255), -- admin token name; NULL for the control plane itself
+    remote_addr VARCHAR(255),
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_kill_switch_audit_switch ON kill_switch_audit(kill_switch_id, created_at);
+
+COMMENT ON TABLE kill_switches IS 'Emergency routing halts by tenant, model, provider or fleet-wide';
+COMMENT ON TABLE kill_switch_audit IS 'Every request, confirmation, release and node termination of a kill switch';
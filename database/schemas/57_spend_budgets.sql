@@ -0,0 +1,31 @@
+-- Tenant Spend Budgets
+-- Admins cap what a tenant may spend per day or per month, either across
+-- the whole tenant or for one environment. Spend is the cost recorded in
+-- usage_records since the start of the current period (UTC). Gateways keep
+-- the state of every enabled budget in memory, refreshed every few seconds,
+-- and reject inference with 402 once a budget's spend reaches its limit.
+-- Budgets reset at the start of each period.
+
+CREATE TABLE IF NOT EXISTS spend_budgets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    environment_id UUID REFERENCES environments(id) ON DELETE CASCADE, -- NULL covers every environment
+    period VARCHAR(10) NOT NULL CHECK (period IN ('daily', 'monthly')),
+    limit_microdollars BIGINT NOT NULL CHECK (limit_microdollars > 0),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+
+    -- Set when spend first reaches the limit in a period; an exceeded_at
+    -- before the current period start means the budget has since reset
+    exceeded_at TIMESTAMP WITH TIME ZONE,
+
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One budget per tenant, environment and period
+CREATE UNIQUE INDEX IF NOT EXISTS idx_spend_budgets_scope
+    ON spend_budgets(tenant_id, COALESCE(environment_id, '00000000-0000-0000-0000-000000000000'::uuid), period);
+
+COMMENT ON TABLE spend_budgets IS 'Daily and monthly spend caps per tenant or environment, enforced by the gateway';
+COMMENT ON COLUMN spend_budgets.limit_microdollars IS 'Spend cap for the period in microdollars';
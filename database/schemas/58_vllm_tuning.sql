@@ -0,0 +1,19 @@
+-- vLLM Tuning
+-- Node agents report the GPU memory they find at registration. The
+-- orchestrator sizes vLLM's gpu-memory-utilization, max-model-len and
+-- max-num-seqs from the model size and the memory reported for the GPU
+-- type, and records the settings a node was launched with.
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS gpu_count INTEGER;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS vllm_gpu_memory_utilization DOUBLE PRECISION;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS vllm_max_model_len INTEGER;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS vllm_max_num_seqs INTEGER;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS vllm_tuned_vram_gb INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_nodes_reported_vram ON nodes(gpu_type, updated_at DESC) WHERE vram_total_gb IS NOT NULL;
+
+COMMENT ON COLUMN nodes.gpu_count IS 'GPUs on the node; vram_total_gb is their combined memory';
+COMMENT ON COLUMN nodes.vllm_gpu_memory_utilization IS 'vLLM --gpu-memory-utilization the node was launched with';
+COMMENT ON COLUMN nodes.vllm_max_model_len IS 'vLLM --max-model-len the node was launched with';
+COMMENT ON COLUMN nodes.vllm_max_num_seqs IS 'vLLM --max-num-seqs the node was launched with';
+COMMENT ON COLUMN nodes.vllm_tuned_vram_gb IS 'Per-GPU memory the vLLM settings were chosen for; NULL when it was unknown';
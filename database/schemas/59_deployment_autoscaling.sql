@@ -0,0 +1,30 @@
+-- Deployment Autoscaling
+-- Deployments with auto scaling enabled scale between min_replicas and
+-- max_replicas on load: the requests waiting in vLLM's queue per serving
+-- node, and the p95 latency of the requests their nodes served recently.
+-- Targets and cooldowns left NULL use the platform defaults. The last
+-- scaling times drive the cooldowns and let one control plane replica
+-- claim each scaling.
+
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS auto_scaling_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS autoscale_target_queue_depth INTEGER
+    CHECK (autoscale_target_queue_depth > 0);
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS autoscale_target_p95_latency_ms INTEGER
+    CHECK (autoscale_target_p95_latency_ms > 0);
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS autoscale_scale_up_cooldown_seconds INTEGER
+    CHECK (autoscale_scale_up_cooldown_seconds > 0);
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS autoscale_scale_down_cooldown_seconds INTEGER
+    CHECK (autoscale_scale_down_cooldown_seconds > 0);
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS last_scaled_up_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS last_scaled_down_at TIMESTAMP WITH TIME ZONE;
+
+-- Recent latencies of a deployment's nodes
+CREATE INDEX IF NOT EXISTS idx_usage_records_node_timestamp ON usage_records(node_id, timestamp DESC);
+
+COMMENT ON COLUMN deployments.auto_scaling_enabled IS 'Scale between min_replicas and max_replicas on queue depth and latency';
+COMMENT ON COLUMN deployments.autoscale_target_queue_depth IS 'Requests waiting per serving node to scale up at (NULL uses the platform default)';
+COMMENT ON COLUMN deployments.autoscale_target_p95_latency_ms IS 'p95 request latency to scale up at (NULL uses the platform default)';
+COMMENT ON COLUMN deployments.autoscale_scale_up_cooldown_seconds IS 'Minimum time between scale ups (NULL uses the platform default)';
+COMMENT ON COLUMN deployments.autoscale_scale_down_cooldown_seconds IS 'Minimum time from any scaling to a scale down (NULL uses the platform default)';
+COMMENT ON COLUMN deployments.last_scaled_up_at IS 'When autoscaling last added nodes';
+COMMENT ON COLUMN deployments.last_scaled_down_at IS 'When autoscaling last removed nodes';
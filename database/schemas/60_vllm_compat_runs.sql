@@ -0,0 +1,43 @@
+-- vLLM Compatibility Runs
+-- Before the platform adopts a new vLLM release, a compatibility run
+-- launches a short-lived canary node of the release for the smallest model
+-- of each family and runs a smoke suite against it. The results form a
+-- pass/fail matrix of families by check. Deployments can only be pinned to
+-- a release newer than the platform's once its latest finished run passed
+-- for their model's family.
+
+CREATE TABLE IF NOT EXISTS vllm_compat_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    vllm_version VARCHAR(50) NOT NULL,
+    torch_version VARCHAR(50),
+    families TEXT[] NOT NULL DEFAULT '{}', -- empty checks every active family
+    provider VARCHAR(50),
+    region VARCHAR(50),
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'passed', 'failed')),
+    error TEXT,
+    requested_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_vllm_compat_runs_version ON vllm_compat_runs(vllm_version, finished_at DESC);
+
+CREATE TABLE IF NOT EXISTS vllm_compat_results (
+    run_id UUID NOT NULL REFERENCES vllm_compat_runs(id) ON DELETE CASCADE,
+    family VARCHAR(100) NOT NULL,
+    check_name VARCHAR(50) NOT NULL
+        CHECK (check_name IN ('launch', 'generation', 'streaming', 'function_calling', 'embeddings')),
+    model_name VARCHAR(255) NOT NULL,
+    node_id UUID,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('passed', 'failed', 'skipped')),
+    latency_ms BIGINT,
+    detail TEXT,
+    checked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (run_id, family, check_name)
+);
+
+COMMENT ON TABLE vllm_compat_runs IS 'Smoke test runs of a vLLM release on canary nodes, gating pins to newer releases';
+COMMENT ON TABLE vllm_compat_results IS 'Pass/fail matrix of a compatibility run by model family and check';
+COMMENT ON COLUMN vllm_compat_results.node_id IS 'Canary node the family was checked on; terminated once its checks finish';
@@ -0,0 +1,39 @@
+-- Rate Limit Templates
+-- A template holds the rate limits and quotas for environments with a given
+-- name (dev, staging, production, ...). A new environment takes the template
+-- matching its name, and API keys created in it get the template's limits.
+-- A key limit left NULL follows the tenant's plan, and a limit set on the
+-- template never raises a key above the plan.
+
+CREATE TABLE IF NOT EXISTS rate_limit_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    requests_per_min INTEGER CHECK (requests_per_min > 0),
+    tokens_per_min INTEGER CHECK (tokens_per_min > 0),
+    concurrency_limit INTEGER CHECK (concurrency_limit > 0),
+    tokens_per_day BIGINT CHECK (tokens_per_day > 0),
+    created_by VARCHAR(255),
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE environments ADD COLUMN IF NOT EXISTS rate_limit_template_id UUID
+    REFERENCES rate_limit_templates(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_environments_rate_limit_template ON environments(rate_limit_template_id);
+
+INSERT INTO rate_limit_templates (name, description, requests_per_min) VALUES
+('dev', 'Development environments', 60),
+('staging', 'Staging environments', 300),
+('production', 'Production environments follow the plan', NULL)
+ON CONFLICT (name) DO NOTHING;
+
+COMMENT ON TABLE rate_limit_templates IS 'Rate limits and quotas applied to environments by name and to the API keys created in them';
+COMMENT ON COLUMN rate_limit_templates.name IS 'Environment name the template applies to';
+COMMENT ON COLUMN rate_limit_templates.requests_per_min IS 'Per-key request limit, capped by the plan; NULL follows the plan';
+COMMENT ON COLUMN rate_limit_templates.tokens_per_min IS 'Per-key token limit, capped by the plan; NULL follows the plan';
+COMMENT ON COLUMN rate_limit_templates.concurrency_limit IS 'Per-key concurrency limit, capped by the plan; NULL follows the plan';
+COMMENT ON COLUMN rate_limit_templates.tokens_per_day IS 'Daily token quota of the environment; NULL keeps the default';
+COMMENT ON COLUMN environments.rate_limit_template_id IS 'Template the environment''s limits came from';
@@ -0,0 +1,7 @@
+-- GPU Cloud Providers
+-- Tenants with Lambda, RunPod or Nebius credentials launch nodes on those
+-- clouds, so nodes accept the same providers as cloud_credentials.
+
+ALTER TABLE nodes DROP CONSTRAINT IF EXISTS nodes_provider_check;
+ALTER TABLE nodes ADD CONSTRAINT nodes_provider_check
+    CHECK (provider IN ('aws', 'gcp', 'azure', 'oci', 'lambda', 'runpod', 'nebius', 'on-prem'));
@@ -0,0 +1,40 @@
+-- Environment Budgets
+-- A tenant-defined token and/or spend budget shared by all API keys of an
+-- environment, with optional per-key sub-limits within it. The rate limiter
+-- keeps the running totals per UTC day or month and rejects inference
+-- requests once the environment or the key is over its limit.
+
+CREATE TABLE IF NOT EXISTS environment_budgets (
+    environment_id UUID PRIMARY KEY REFERENCES environments(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    period VARCHAR(20) NOT NULL DEFAULT 'monthly' CHECK (period IN ('daily', 'monthly')),
+    limit_tokens BIGINT CHECK (limit_tokens > 0),
+    limit_microdollars BIGINT CHECK (limit_microdollars > 0),
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT environment_budgets_limit_check CHECK (limit_tokens IS NOT NULL OR limit_microdollars IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_environment_budgets_tenant ON environment_budgets(tenant_id);
+
+CREATE TABLE IF NOT EXISTS environment_budget_key_limits (
+    api_key_id UUID PRIMARY KEY REFERENCES api_keys(id) ON DELETE CASCADE,
+    environment_id UUID NOT NULL REFERENCES environment_budgets(environment_id) ON DELETE CASCADE,
+    limit_tokens BIGINT CHECK (limit_tokens > 0),
+    limit_microdollars BIGINT CHECK (limit_microdollars > 0),
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT environment_budget_key_limits_limit_check CHECK (limit_tokens IS NOT NULL OR limit_microdollars IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_environment_budget_key_limits_environment ON environment_budget_key_limits(environment_id);
+
+COMMENT ON TABLE environment_budgets IS 'Token and spend budgets shared by all API keys of an environment';
+COMMENT ON COLUMN environment_budgets.period IS 'UTC day or calendar month the budget covers; it resets at the period end';
+COMMENT ON COLUMN environment_budgets.limit_tokens IS 'Tokens the environment may use per period; NULL is unlimited';
+COMMENT ON COLUMN environment_budgets.limit_microdollars IS 'Spend the environment may incur per period; NULL is unlimited';
+COMMENT ON TABLE environment_budget_key_limits IS 'Per-key sub-limits within an environment budget';
+COMMENT ON COLUMN environment_budget_key_limits.limit_tokens IS 'Tokens the key may use per period of the environment budget; NULL draws on the shared budget only';
+COMMENT ON COLUMN environment_budget_key_limits.limit_microdollars IS 'Spend the key may incur per period of the environment budget; NULL draws on the shared budget only';
@@ -0,0 +1,36 @@
+-- Usage Exports
+-- Tenants and admins extract usage_records rows as CSV or Parquet for bill
+-- reconciliation, filtered by date range, model and API key. Extracts are
+-- written by a background job, uploaded to R2 and downloadable until they
+-- expire; the artifacts of expired exports are deleted when the tenant's
+-- next export completes.
+
+CREATE TABLE IF NOT EXISTS usage_exports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'parquet')),
+    start_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    model VARCHAR(255),
+    api_key_id UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    requested_by VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed', 'expired')),
+    object_key VARCHAR(500),
+    size_bytes BIGINT,
+    row_count BIGINT,
+    error TEXT,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT usage_exports_range_check CHECK (end_date > start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_exports_tenant ON usage_exports(tenant_id, requested_at DESC);
+CREATE INDEX IF NOT EXISTS idx_usage_exports_in_progress
+    ON usage_exports(tenant_id) WHERE status IN ('pending', 'running');
+
+COMMENT ON TABLE usage_exports IS 'Usage record extracts (CSV or Parquet) and their artifacts in R2';
+COMMENT ON COLUMN usage_exports.model IS 'Model name filter; NULL exports all models';
+COMMENT ON COLUMN usage_exports.api_key_id IS 'API key filter; NULL exports all keys';
+COMMENT ON COLUMN usage_exports.requested_by IS 'Admin token name, or api_key:<id> for tenant requests';
+COMMENT ON COLUMN usage_exports.status IS 'expired: the artifact was deleted after expires_at';
@@ -0,0 +1,59 @@
+-- Stripe Reconciliation
+-- Tenants are cross-checked against Stripe customers and subscriptions to
+-- catch what lost or out-of-order webhooks leave behind: customers no tenant
+-- points at, tenants without a customer, and tenants whose status,
+-- subscription or plan disagree with their Stripe subscription. Each run
+-- records its discrepancy report; safe discrepancies are fixed when the run
+-- has auto_fix set.
+
+-- The subscription webhook writes 'canceled' for canceled subscriptions
+ALTER TABLE tenants DROP CONSTRAINT IF EXISTS tenants_status_check;
+ALTER TABLE tenants ADD CONSTRAINT tenants_status_check
+    CHECK (status IN ('active', 'suspended', 'canceled', 'deleted'));
+
+CREATE TABLE IF NOT EXISTS stripe_reconciliation_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    auto_fix BOOLEAN NOT NULL DEFAULT false,
+    triggered_by VARCHAR(255) NOT NULL,
+    tenants_checked INTEGER NOT NULL DEFAULT 0,
+    customers_checked INTEGER NOT NULL DEFAULT 0,
+    subscriptions_checked INTEGER NOT NULL DEFAULT 0,
+    discrepancy_count INTEGER NOT NULL DEFAULT 0,
+    fixed_count INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_stripe_reconciliation_runs_started ON stripe_reconciliation_runs(started_at DESC);
+-- One run at a time across replicas
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stripe_reconciliation_runs_in_progress
+    ON stripe_reconciliation_runs((true)) WHERE status IN ('pending', 'running');
+
+CREATE TABLE IF NOT EXISTS stripe_reconciliation_discrepancies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    run_id UUID NOT NULL REFERENCES stripe_reconciliation_runs(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL CHECK (type IN ('orphaned_customer', 'missing_customer', 'status_mismatch', 'subscription_mismatch', 'plan_mismatch')),
+    tenant_id UUID REFERENCES tenants(id) ON DELETE SET NULL,
+    stripe_customer_id VARCHAR(255),
+    stripe_subscription_id VARCHAR(255),
+    expected VARCHAR(255),
+    actual VARCHAR(255),
+    detail TEXT NOT NULL,
+    fixable BOOLEAN NOT NULL DEFAULT false,
+    fixed BOOLEAN NOT NULL DEFAULT false,
+    fix_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stripe_reconciliation_discrepancies_run ON stripe_reconciliation_discrepancies(run_id);
+CREATE INDEX IF NOT EXISTS idx_stripe_reconciliation_discrepancies_tenant ON stripe_reconciliation_discrepancies(tenant_id);
+
+COMMENT ON TABLE stripe_reconciliation_runs IS 'Cross-checks of tenants against Stripe customers and subscriptions';
+COMMENT ON COLUMN stripe_reconciliation_runs.triggered_by IS 'Admin token name, or scheduled';
+COMMENT ON COLUMN stripe_reconciliation_runs.auto_fix IS 'Whether fixable discrepancies were corrected';
+COMMENT ON TABLE stripe_reconciliation_discrepancies IS 'Discrepancy report of a Stripe reconciliation run';
+COMMENT ON COLUMN stripe_reconciliation_discrepancies.expected IS 'Value Stripe implies for the tenant';
+COMMENT ON COLUMN stripe_reconciliation_discrepancies.actual IS 'Value the tenant had when the run compared it';
+COMMENT ON COLUMN stripe_reconciliation_discrepancies.fixable IS 'Safe to correct automatically; others need an operator';
@@ -0,0 +1,45 @@
+-- Colocated Models
+-- A node can serve a few small models beside its primary one, each from its
+-- own vLLM instance on its own port. Every colocated model gets a nodes row
+-- of its own, pointing at the host node's row, so routing by model and
+-- per-endpoint stats work unchanged: the row's endpoint_url carries the
+-- model's port.
+--
+-- Colocated rows have no cluster and no agent of their own. The host's
+-- agent activates them when it registers, and from then on they follow the
+-- host: its status, heartbeats and termination are copied onto them by
+-- the trigger below. Rows not yet serving don't go active with the host.
+
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS host_node_id UUID REFERENCES nodes(id) ON DELETE CASCADE;
+ALTER TABLE nodes ADD COLUMN IF NOT EXISTS serving_port INTEGER CHECK (serving_port BETWEEN 1 AND 65535);
+
+CREATE INDEX IF NOT EXISTS idx_nodes_host ON nodes(host_node_id) WHERE host_node_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION propagate_host_node_state()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE nodes SET
+        status = NEW.status,
+        status_message = NEW.status_message,
+        status_source = 'host',
+        last_heartbeat_at = NEW.last_heartbeat_at,
+        health_score = NEW.health_score,
+        terminated_at = NEW.terminated_at,
+        updated_at = NOW()
+    WHERE host_node_id = NEW.id
+      AND (COALESCE(endpoint_url, '') != '' OR NEW.status != 'active');
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS propagate_host_node_state ON nodes;
+CREATE TRIGGER propagate_host_node_state AFTER UPDATE ON nodes
+    FOR EACH ROW
+    WHEN (NEW.host_node_id IS NULL AND (
+        OLD.status IS DISTINCT FROM NEW.status
+        OR OLD.last_heartbeat_at IS DISTINCT FROM NEW.last_heartbeat_at
+        OR OLD.terminated_at IS DISTINCT FROM NEW.terminated_at))
+    EXECUTE FUNCTION propagate_host_node_state();
+
+COMMENT ON COLUMN nodes.host_node_id IS 'Node whose machine serves this model beside its own; NULL for nodes with their own cluster';
+COMMENT ON COLUMN nodes.serving_port IS 'Port of the colocated model''s vLLM instance on the host';
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -34,6 +35,11 @@ func main() {
 		InstanceType:    getEnv("INSTANCE_TYPE", "unknown"),
 		SpotInstance:    getEnv("SPOT_INSTANCE", "false") == "true",
 		HeartbeatInterval: 10 * time.Second,
+		NodeToken:         getEnv("NODE_API_TOKEN", ""),
+		DiscoveryFile:     getEnv("NODE_DISCOVERY_FILE", ""),
+		VLLMLogPath:       getEnv("VLLM_LOG_PATH", "/tmp/vllm.log"),
+		SetupStartedAt:    getEnvUnixTime("SETUP_STARTED_AT"),
+		VLLMStartedAt:     getEnvUnixTime("VLLM_STARTED_AT"),
 	}
 
 	// Create and start agent
@@ -74,3 +80,13 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvUnixTime reads a Unix timestamp in seconds, or the zero time when
+// it is unset or invalid
+func getEnvUnixTime(key string) time.Time {
+	secs, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || secs <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(secs, 0).UTC()
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -25,6 +27,17 @@ type Config struct {
 	InstanceType      string
 	SpotInstance      bool
 	HeartbeatInterval time.Duration
+	// NodeToken authenticates callbacks to the control plane's node listener
+	NodeToken string
+	// DiscoveryFile, when set, receives the node registry lookup and a
+	// discovery token for serving workers on the node
+	DiscoveryFile string
+	// VLLMLogPath is vLLM's log file, captured when it crashes
+	VLLMLogPath string
+	// SetupStartedAt and VLLMStartedAt are cold start checkpoints recorded
+	// by the launch script, reported at registration for launch metrics
+	SetupStartedAt time.Time
+	VLLMStartedAt  time.Time
 }
 
 // Agent represents a node agent
@@ -34,18 +47,54 @@ type Agent struct {
 	httpClient *http.Client
 	nodeID     string
 	stopChan   chan struct{}
+	crash      crashState
+
+	// runtime is the last vLLM runtime read, owned by the heartbeat loop
+	runtime       *runtimeReport
+	runtimeReadAt time.Time
+	// compliance is the last hardening check, owned by the heartbeat loop
+	compliance *complianceReport
+
+	// controlPlane is the URL callbacks go to, which node discovery moves
+	// when the control plane changes domains
+	controlPlaneMu sync.RWMutex
+	controlPlane   string
+	transport      *controlPlaneTransport
+	// discovery is the last registry lookup, owned by the discovery loop
+	discovery *discoveryResult
 }
 
 // NewAgent creates a new node agent
 func NewAgent(config *Config, logger *zap.Logger) (*Agent, error) {
-	return &Agent{
-		config: config,
-		logger: logger,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		stopChan: make(chan struct{}),
-	}, nil
+	a := &Agent{
+		config:       config,
+		logger:       logger,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		stopChan:     make(chan struct{}),
+		controlPlane: strings.TrimRight(config.ControlPlaneURL, "/"),
+	}
+	if config.NodeToken != "" {
+		a.transport = newControlPlaneTransport(config.ControlPlaneURL, config.NodeToken)
+		a.httpClient.Transport = a.transport
+	}
+	return a, nil
+}
+
+// controlPlaneURL returns the base URL for control plane callbacks
+func (a *Agent) controlPlaneURL() string {
+	a.controlPlaneMu.RLock()
+	defer a.controlPlaneMu.RUnlock()
+	return a.controlPlane
+}
+
+// setControlPlaneURL moves control plane callbacks to a new base URL
+func (a *Agent) setControlPlaneURL(controlPlaneURL string) {
+	if a.transport != nil {
+		a.transport.trust(controlPlaneURL)
+	}
+	a.controlPlaneMu.Lock()
+	a.controlPlane = strings.TrimRight(controlPlaneURL, "/")
+	a.controlPlaneMu.Unlock()
 }
 
 // Start starts the agent
@@ -64,6 +113,11 @@ func (a *Agent) Start(ctx context.Context) error {
 	// Start heartbeat loop
 	go a.heartbeatLoop(ctx)
 
+	// Keep control plane URLs and sibling nodes current
+	if a.config.NodeToken != "" {
+		go a.discoveryLoop(ctx)
+	}
+
 	// Start health monitoring
 	go a.healthMonitorLoop(ctx)
 
@@ -98,13 +152,31 @@ func (a *Agent) register(ctx context.Context) error {
 		"spot_instance": a.config.SpotInstance,
 		"status":        "active",
 	}
+	// Lets the control plane update the row created at launch
+	if a.config.NodeID != "" {
+		payload["node_id"] = a.config.NodeID
+	}
+	if !a.config.SetupStartedAt.IsZero() {
+		payload["setup_started_at"] = a.config.SetupStartedAt
+	}
+	if !a.config.VLLMStartedAt.IsZero() {
+		payload["vllm_started_at"] = a.config.VLLMStartedAt
+	}
+	// GPU memory as the node actually has it, which may differ from what
+	// the launch assumed for the GPU type
+	if gpus, err := detectGPUs(ctx); err != nil {
+		a.logger.Warn("failed to detect GPU memory", zap.Error(err))
+	} else {
+		payload["gpu_count"] = gpus.Count
+		payload["vram_total_gb"] = gpus.VRAMTotalGB
+	}
 
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	url := fmt.Sprintf("%s/admin/nodes/register", a.config.ControlPlaneURL)
+	url := fmt.Sprintf("%s/admin/nodes/register", a.controlPlaneURL())
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
 		return err
@@ -141,7 +213,7 @@ func (a *Agent) deregister(ctx context.Context) error {
 		return nil
 	}
 
-	url := fmt.Sprintf("%s/admin/nodes/%s/deregister", a.config.ControlPlaneURL, a.nodeID)
+	url := fmt.Sprintf("%s/admin/nodes/%s/deregister", a.controlPlaneURL(), a.nodeID)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
 		return err
@@ -190,13 +262,17 @@ func (a *Agent) sendHeartbeat(ctx context.Context) error {
 		"health_score": healthScore,
 		"timestamp":    time.Now().Unix(),
 	}
+	if runtime := a.currentRuntime(ctx); runtime != nil {
+		payload["runtime"] = runtime
+	}
+	payload["compliance"] = a.currentCompliance(ctx)
 
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	url := fmt.Sprintf("%s/admin/nodes/%s/heartbeat", a.config.ControlPlaneURL, a.nodeID)
+	url := fmt.Sprintf("%s/admin/nodes/%s/heartbeat", a.controlPlaneURL(), a.nodeID)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
 		return err
@@ -256,7 +332,8 @@ func (a *Agent) checkVLLMHealth(ctx context.Context) bool {
 
 // healthMonitorLoop monitors node health
 func (a *Agent) healthMonitorLoop(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
+	// Frequent enough that the vLLM metrics kept for crash reports are fresh
+	ticker := time.NewTicker(15 * time.Second)
 	defer ticker.Stop()
 
 	for {
@@ -271,12 +348,14 @@ func (a *Agent) healthMonitorLoop(ctx context.Context) {
 	}
 }
 
-// monitorHealth monitors node health and reports issues
+// monitorHealth monitors node health and reports vLLM crashes
 func (a *Agent) monitorHealth(ctx context.Context) {
-	if !a.checkVLLMHealth(ctx) {
-		a.logger.Warn("vLLM health check failed")
-		// TODO: Report to control plane
+	if a.checkVLLMHealth(ctx) {
+		a.recordHealthy(ctx)
+		return
 	}
+	a.logger.Warn("vLLM health check failed")
+	a.recordUnhealthy(ctx)
 }
 
 // terminationMonitorLoop polls for spot instance termination warnings
@@ -358,7 +437,7 @@ func (a *Agent) markNodeDraining(ctx context.Context) {
 		return
 	}
 
-	url := fmt.Sprintf("%s/admin/nodes/%s/drain", a.config.ControlPlaneURL, a.nodeID)
+	url := fmt.Sprintf("%s/admin/nodes/%s/drain", a.controlPlaneURL(), a.nodeID)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
 		a.logger.Error("failed to mark node as draining", zap.Error(err))
@@ -479,7 +558,7 @@ func (a *Agent) checkAzureTermination(ctx context.Context) bool {
 
 // reportTerminationWarning sends a warning to the control plane
 func (a *Agent) reportTerminationWarning(ctx context.Context) error {
-	url := fmt.Sprintf("%s/admin/nodes/%s/termination-warning", a.config.ControlPlaneURL, a.nodeID)
+	url := fmt.Sprintf("%s/admin/nodes/%s/termination-warning", a.controlPlaneURL(), a.nodeID)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
 		return err
@@ -496,3 +575,43 @@ func (a *Agent) reportTerminationWarning(ctx context.Context) error {
 	}
 	return nil
 }
+
+// controlPlaneTransport adds the node token to requests sent to the control
+// plane. The agent's client also reaches vLLM, which never sees the token.
+// Requests that already carry credentials, such as discovery tokens, are
+// sent as they are.
+type controlPlaneTransport struct {
+	mu    sync.RWMutex
+	hosts map[string]bool
+	token string
+	base  http.RoundTripper
+}
+
+func newControlPlaneTransport(controlPlaneURL, token string) *controlPlaneTransport {
+	t := &controlPlaneTransport{hosts: map[string]bool{}, token: token, base: http.DefaultTransport}
+	t.trust(controlPlaneURL)
+	return t
+}
+
+// trust sends the node token to another control plane URL's host
+func (t *controlPlaneTransport) trust(controlPlaneURL string) {
+	u, err := url.Parse(controlPlaneURL)
+	if err != nil || u.Host == "" {
+		return
+	}
+	t.mu.Lock()
+	t.hosts[u.Host] = true
+	t.mu.Unlock()
+}
+
+func (t *controlPlaneTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.RLock()
+	trusted := t.hosts[req.URL.Host]
+	t.mu.RUnlock()
+	if !trusted || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
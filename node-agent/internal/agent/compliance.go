@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// complianceRefreshInterval is how often the node's hardening is re-checked;
+// heartbeats in between resend the last report
+const complianceRefreshInterval = 5 * time.Minute
+
+// inferencePort is the port vLLM serves on, which hardening firewalls
+const inferencePort = "8000"
+
+// complianceReport is the security hardening state of the node, sent with
+// heartbeats for the control plane's compliance view
+type complianceReport struct {
+	PasswordSSHDisabled     bool      `json:"password_ssh_disabled"`
+	FirewallActive          bool      `json:"firewall_active"`
+	InferencePortRestricted bool      `json:"inference_port_restricted"`
+	AutoUpdatesEnabled      bool      `json:"auto_updates_enabled"`
+	CheckedAt               time.Time `json:"checked_at"`
+}
+
+// currentCompliance returns the compliance report to send, re-checking the
+// node every few minutes
+func (a *Agent) currentCompliance(ctx context.Context) *complianceReport {
+	if a.compliance != nil && time.Since(a.compliance.CheckedAt) < complianceRefreshInterval {
+		return a.compliance
+	}
+
+	firewallActive, portRestricted := checkFirewall(ctx)
+	a.compliance = &complianceReport{
+		PasswordSSHDisabled:     checkPasswordSSHDisabled(),
+		FirewallActive:          firewallActive,
+		InferencePortRestricted: portRestricted,
+		AutoUpdatesEnabled:      checkAutoUpdates(),
+		CheckedAt:               time.Now().UTC(),
+	}
+	return a.compliance
+}
+
+// checkPasswordSSHDisabled reads sshd's configuration the way sshd does:
+// drop-in files first, since sshd_config includes them at the top, and the
+// first PasswordAuthentication setting wins. Password login is on by
+// default.
+func checkPasswordSSHDisabled() bool {
+	dropIns, _ := filepath.Glob("/etc/ssh/sshd_config.d/*.conf")
+	sort.Strings(dropIns)
+	for _, path := range append(dropIns, "/etc/ssh/sshd_config") {
+		if value, ok := sshdSetting(path, "passwordauthentication"); ok {
+			return strings.EqualFold(value, "no")
+		}
+	}
+	return false
+}
+
+// sshdSetting returns the first value of a keyword in an sshd config file
+// before its first Match block
+func sshdSetting(path, keyword string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if strings.EqualFold(fields[0], "match") {
+			return "", false
+		}
+		if strings.EqualFold(fields[0], keyword) {
+			return fields[1], true
+		}
+	}
+	return "", false
+}
+
+// checkFirewall reports whether ufw is active and whether it only lets
+// specific networks reach the inference port. The agent runs as the
+// cluster user, which has passwordless sudo.
+func checkFirewall(ctx context.Context) (active, portRestricted bool) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "sudo", "-n", "ufw", "status").Output()
+	if err != nil {
+		return false, false
+	}
+	return parseUFWStatus(string(out))
+}
+
+// parseUFWStatus reads `ufw status` output. The inference port is
+// restricted when some network is allowed to reach it and no rule covering
+// it allows Anywhere; other traffic falls to ufw's default incoming deny.
+func parseUFWStatus(out string) (active, portRestricted bool) {
+	var allowed bool
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Status:") {
+			active = strings.TrimSpace(strings.TrimPrefix(line, "Status:")) == "active"
+			continue
+		}
+
+		// Rules read "<to> ALLOW [IN] <from>"
+		fields := strings.Fields(line)
+		action := -1
+		for i, field := range fields {
+			if field == "ALLOW" {
+				action = i
+				break
+			}
+		}
+		if action < 1 || action == len(fields)-1 {
+			continue
+		}
+		to := strings.SplitN(fields[0], "/", 2)[0]
+		if to != inferencePort && to != "Anywhere" {
+			continue
+		}
+		from := fields[action+1:]
+		if from[0] == "IN" && len(from) > 1 {
+			from = from[1:]
+		}
+		if from[0] == "Anywhere" {
+			return active, false
+		}
+		allowed = true
+	}
+	return active, active && allowed
+}
+
+// checkAutoUpdates reports whether unattended security upgrades run
+func checkAutoUpdates() bool {
+	data, err := os.ReadFile("/etc/apt/apt.conf.d/20auto-upgrades")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "APT::Periodic::Unattended-Upgrade") {
+			return strings.Contains(line, `"1"`)
+		}
+	}
+	return false
+}
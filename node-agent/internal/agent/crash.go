@@ -0,0 +1,414 @@
+package agent
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// crashUnhealthyChecks is how many failed health checks in a row count
+	// as a crash when the log shows no OOM
+	crashUnhealthyChecks = 3
+
+	// crashBundleLogSize is how much of vllm.log goes into the bundle;
+	// crashReportLogSize is the excerpt sent with the report itself
+	crashBundleLogSize = 4 * 1024 * 1024
+	crashReportLogSize = 64 * 1024
+
+	// nvidiaSMITimeout bounds nvidia-smi, which hangs when the driver is wedged
+	nvidiaSMITimeout = 15 * time.Second
+)
+
+// oomMarkers are log lines vLLM and PyTorch write when GPU memory runs out
+var oomMarkers = []string{
+	"CUDA out of memory",
+	"OutOfMemoryError",
+	"CUBLAS_STATUS_ALLOC_FAILED",
+	"cudaErrorMemoryAllocation",
+	"No available memory for the cache blocks",
+}
+
+// crashMetrics are the vLLM metrics kept from the last healthy scrape. They
+// show what the engine was doing just before it died.
+var crashMetrics = map[string]bool{
+	"vllm:num_requests_running":    true,
+	"vllm:num_requests_waiting":    true,
+	"vllm:num_requests_swapped":    true,
+	"vllm:gpu_cache_usage_perc":    true,
+	"vllm:kv_cache_usage_perc":     true,
+	"vllm:num_preemptions_total":   true,
+	"vllm:cpu_cache_usage_perc":    true,
+	"vllm:request_success_total":   true,
+	"vllm:prompt_tokens_total":     true,
+	"vllm:generation_tokens_total": true,
+}
+
+// crashState tracks vLLM health for crash detection. It is only touched by
+// the health monitor loop.
+type crashState struct {
+	// served is set once vLLM has been healthy, so a slow model load isn't
+	// mistaken for a crash
+	served          bool
+	unhealthyChecks int
+	reported        bool
+	lastMetrics     string
+	lastMetricsAt   time.Time
+}
+
+// recordHealthy resets crash detection and keeps the latest vLLM metrics
+func (a *Agent) recordHealthy(ctx context.Context) {
+	a.crash.served = true
+	a.crash.unhealthyChecks = 0
+	a.crash.reported = false
+
+	if metrics, err := a.scrapeVLLMMetrics(ctx); err == nil {
+		a.crash.lastMetrics = metrics
+		a.crash.lastMetricsAt = time.Now()
+	}
+}
+
+// recordUnhealthy captures a crash report once vLLM has clearly died: the
+// log shows a GPU OOM, or health checks keep failing after it had served
+func (a *Agent) recordUnhealthy(ctx context.Context) {
+	a.crash.unhealthyChecks++
+	if a.crash.reported {
+		return
+	}
+
+	logTail := readTail(a.config.VLLMLogPath, crashBundleLogSize)
+	oom := detectOOM(logTail)
+	if !oom && (!a.crash.served || a.crash.unhealthyChecks < crashUnhealthyChecks) {
+		return
+	}
+	a.crash.reported = true
+
+	reason := "unhealthy"
+	switch {
+	case oom:
+		reason = "oom"
+	case !a.vllmReachable(ctx):
+		reason = "process_exited"
+	}
+
+	a.logger.Error("vLLM crash detected, capturing forensics",
+		zap.String("reason", reason),
+		zap.Int("failed_checks", a.crash.unhealthyChecks),
+	)
+	if err := a.reportCrash(ctx, reason, oom, logTail); err != nil {
+		a.logger.Error("failed to report crash", zap.Error(err))
+	}
+}
+
+// reportCrash bundles the forensics, files a crash report with the control
+// plane and uploads the bundle to the URL it returns
+func (a *Agent) reportCrash(ctx context.Context, reason string, oom bool, logTail []byte) error {
+	if a.nodeID == "" {
+		return fmt.Errorf("node not registered")
+	}
+
+	detectedAt := time.Now().UTC()
+	gpu := a.gpuSnapshot(ctx)
+	metrics := a.crash.lastMetrics
+
+	meta, _ := json.MarshalIndent(map[string]interface{}{
+		"node_id":            a.nodeID,
+		"model":              a.config.ModelName,
+		"gpu_type":           a.config.GPUType,
+		"instance_type":      a.config.InstanceType,
+		"reason":             reason,
+		"oom":                oom,
+		"detected_at":        detectedAt,
+		"failed_checks":      a.crash.unhealthyChecks,
+		"metrics_scraped_at": a.crash.lastMetricsAt,
+	}, "", "  ")
+
+	bundle, err := buildCrashBundle(detectedAt, []bundleFile{
+		{Name: "crash.json", Data: meta},
+		{Name: "vllm.log", Data: logTail},
+		{Name: "nvidia-smi.txt", Data: []byte(gpu)},
+		{Name: "vllm-metrics.txt", Data: []byte(metrics)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build crash bundle: %w", err)
+	}
+	sum := sha256.Sum256(bundle)
+
+	excerpt := logTail
+	if len(excerpt) > crashReportLogSize {
+		excerpt = excerpt[len(excerpt)-crashReportLogSize:]
+	}
+	payload := map[string]interface{}{
+		"reason":        reason,
+		"oom":           oom,
+		"detected_at":   detectedAt,
+		"log_tail":      string(bytes.ToValidUTF8(excerpt, nil)),
+		"gpu_snapshot":  gpu,
+		"vllm_metrics":  parseVLLMMetrics(metrics),
+		"bundle_size":   len(bundle),
+		"bundle_sha256": hex.EncodeToString(sum[:]),
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/admin/nodes/%s/crash-reports", a.controlPlaneURL(), a.nodeID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("crash report failed with status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		ID        string `json:"id"`
+		UploadURL string `json:"upload_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	a.logger.Info("crash report filed", zap.String("report_id", result.ID))
+
+	// Without R2 the report still carries the log excerpt and GPU snapshot
+	if result.UploadURL == "" {
+		return nil
+	}
+	if err := a.uploadCrashBundle(ctx, result.UploadURL, bundle); err != nil {
+		return fmt.Errorf("failed to upload crash bundle: %w", err)
+	}
+
+	url = fmt.Sprintf("%s/admin/nodes/%s/crash-reports/%s/uploaded", a.controlPlaneURL(), a.nodeID, result.ID)
+	req, err = http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return err
+	}
+	done, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer done.Body.Close()
+
+	if done.StatusCode != http.StatusOK {
+		return fmt.Errorf("marking crash bundle uploaded failed with status %d", done.StatusCode)
+	}
+
+	a.logger.Info("crash bundle uploaded",
+		zap.String("report_id", result.ID),
+		zap.Int("size", len(bundle)),
+	)
+	return nil
+}
+
+// uploadCrashBundle PUTs the bundle to its presigned R2 URL
+func (a *Agent) uploadCrashBundle(ctx context.Context, url string, bundle []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(bundle))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(bundle))
+	req.Header.Set("Content-Type", "application/gzip")
+
+	// The presigned URL does its own auth, and large bundles outlast the
+	// control plane client's timeout
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// vllmReachable reports whether anything answers on the vLLM port
+func (a *Agent) vllmReachable(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.config.VLLMEndpoint+"/health", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// scrapeVLLMMetrics returns the vllm: lines of the Prometheus endpoint
+func (a *Agent) scrapeVLLMMetrics(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.config.VLLMEndpoint+"/metrics", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metrics returned status %d", resp.StatusCode)
+	}
+
+	var b strings.Builder
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, 4*1024*1024))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "vllm:") {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String(), scanner.Err()
+}
+
+// parseVLLMMetrics sums the crash metrics across labels, e.g. per model.
+// Lines look like `name{labels} value [timestamp]`.
+func parseVLLMMetrics(text string) map[string]float64 {
+	values := map[string]float64{}
+	for _, line := range strings.Split(text, "\n") {
+		name, rest, ok := strings.Cut(line, " ")
+		if i := strings.IndexByte(line, '{'); i >= 0 {
+			j := strings.LastIndexByte(line, '}')
+			if j < i {
+				continue
+			}
+			name, rest, ok = line[:i], line[j+1:], true
+		}
+		if !ok || !crashMetrics[name] {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		values[name] += value
+	}
+	return values
+}
+
+// gpuSnapshot captures nvidia-smi's summary and per-process memory use
+func (a *Agent) gpuSnapshot(ctx context.Context) string {
+	var b strings.Builder
+	for _, args := range [][]string{
+		{},
+		{"--query-compute-apps=pid,process_name,used_memory", "--format=csv"},
+		{"--query-gpu=index,name,memory.used,memory.total,utilization.gpu,temperature.gpu", "--format=csv"},
+	} {
+		cmdCtx, cancel := context.WithTimeout(ctx, nvidiaSMITimeout)
+		out, err := exec.CommandContext(cmdCtx, "nvidia-smi", args...).CombinedOutput()
+		cancel()
+
+		fmt.Fprintf(&b, "$ nvidia-smi %s\n", strings.Join(args, " "))
+		b.Write(out)
+		if err != nil {
+			fmt.Fprintf(&b, "error: %v\n", err)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// detectOOM reports whether a vLLM log tail shows GPU memory exhaustion
+func detectOOM(log []byte) bool {
+	for _, marker := range oomMarkers {
+		if bytes.Contains(log, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readTail returns up to max bytes from the end of a file, or nothing if it
+// can't be read
+func readTail(path string, max int64) []byte {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	offset := info.Size() - max
+	if offset < 0 {
+		offset = 0
+	}
+	data, err := io.ReadAll(io.NewSectionReader(f, offset, info.Size()-offset))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// bundleFile is one file in a crash bundle
+type bundleFile struct {
+	Name string
+	Data []byte
+}
+
+// buildCrashBundle packs the forensics files into a tar.gz
+func buildCrashBundle(modTime time.Time, files []bundleFile) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, file := range files {
+		hdr := &tar.Header{
+			Name:    file.Name,
+			Mode:    0o644,
+			Size:    int64(len(file.Data)),
+			ModTime: modTime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(file.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Node discovery keeps the agent pointed at the control plane after it
+// moves domains. The agent trades its node token for a short-lived discovery
+// token, looks itself up in the control plane's node registry and switches
+// callbacks to the node API URL it is given, falling back to the alternate
+// URLs when its current URL stops answering.
+const (
+	// defaultDiscoveryInterval applies until the registry says otherwise
+	defaultDiscoveryInterval = 5 * time.Minute
+	// discoveryRetryInterval is the wait after a failed lookup
+	discoveryRetryInterval = time.Minute
+)
+
+// discoveryToken is a short-lived node registry token
+type discoveryToken struct {
+	Token               string    `json:"token"`
+	ExpiresAt           time.Time `json:"expires_at"`
+	RefreshAfterSeconds int       `json:"refresh_after_seconds"`
+}
+
+// discoveryResult is the node registry's view of this node
+type discoveryResult struct {
+	NodeID       string  `json:"node_id"`
+	DeploymentID *string `json:"deployment_id"`
+	ControlPlane struct {
+		NodeAPIURL    string   `json:"node_api_url"`
+		PublicURL     string   `json:"public_url"`
+		AlternateURLs []string `json:"alternate_urls"`
+	} `json:"control_plane"`
+	Siblings []struct {
+		NodeID          string     `json:"node_id"`
+		EndpointURL     string     `json:"endpoint_url"`
+		InternalIP      string     `json:"internal_ip,omitempty"`
+		Status          string     `json:"status"`
+		LastHeartbeatAt *time.Time `json:"last_heartbeat_at,omitempty"`
+	} `json:"siblings"`
+	RefreshAfterSeconds int `json:"refresh_after_seconds"`
+}
+
+// discoveryLoop refreshes the registry lookup until the agent stops
+func (a *Agent) discoveryLoop(ctx context.Context) {
+	for {
+		wait := discoveryRetryInterval
+		if err := a.refreshDiscovery(ctx); err != nil {
+			a.logger.Warn("node discovery failed", zap.Error(err))
+		} else if a.discovery.RefreshAfterSeconds > 0 {
+			wait = time.Duration(a.discovery.RefreshAfterSeconds) * time.Second
+		} else {
+			wait = defaultDiscoveryInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-a.stopChan:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// refreshDiscovery looks the node up through the current control plane URL,
+// then through the alternates, and follows the node API URL it returns
+func (a *Agent) refreshDiscovery(ctx context.Context) error {
+	if a.nodeID == "" {
+		return fmt.Errorf("node not registered")
+	}
+
+	candidates := []string{a.controlPlaneURL()}
+	if a.discovery != nil {
+		candidates = append(candidates, a.discovery.ControlPlane.NodeAPIURL)
+		candidates = append(candidates, a.discovery.ControlPlane.AlternateURLs...)
+	}
+
+	var lastErr error
+	tried := map[string]bool{}
+	for _, candidate := range candidates {
+		candidate = strings.TrimRight(candidate, "/")
+		if candidate == "" || tried[candidate] {
+			continue
+		}
+		tried[candidate] = true
+
+		if candidate != a.controlPlaneURL() && a.transport != nil {
+			a.transport.trust(candidate)
+		}
+		token, result, err := a.lookupDiscovery(ctx, candidate)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		next := strings.TrimRight(result.ControlPlane.NodeAPIURL, "/")
+		if next == "" {
+			next = candidate
+		}
+		if next != a.controlPlaneURL() {
+			a.logger.Info("moving control plane callbacks",
+				zap.String("from", a.controlPlaneURL()),
+				zap.String("to", next),
+			)
+			a.setControlPlaneURL(next)
+		}
+		a.discovery = result
+
+		if a.config.DiscoveryFile != "" {
+			if err := writeDiscoveryFile(a.config.DiscoveryFile, token, result); err != nil {
+				a.logger.Warn("failed to write discovery file", zap.Error(err))
+			}
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// lookupDiscovery fetches a discovery token and the registry lookup from one
+// control plane URL
+func (a *Agent) lookupDiscovery(ctx context.Context, baseURL string) (*discoveryToken, *discoveryResult, error) {
+	url := fmt.Sprintf("%s/admin/nodes/%s/discovery-token", baseURL, a.nodeID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var token discoveryToken
+	if err := a.doDiscoveryRequest(req, &token); err != nil {
+		return nil, nil, fmt.Errorf("discovery token from %s: %w", baseURL, err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "GET", baseURL+"/registry/discovery", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	var result discoveryResult
+	if err := a.doDiscoveryRequest(req, &result); err != nil {
+		return nil, nil, fmt.Errorf("registry lookup at %s: %w", baseURL, err)
+	}
+	return &token, &result, nil
+}
+
+func (a *Agent) doDiscoveryRequest(req *http.Request, out interface{}) error {
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// writeDiscoveryFile atomically replaces the file serving workers read
+// sibling nodes and the discovery token from. It is readable only by the
+// agent's user since it holds a token.
+func writeDiscoveryFile(path string, token *discoveryToken, result *discoveryResult) error {
+	data, err := json.MarshalIndent(struct {
+		*discoveryResult
+		Token          string    `json:"token"`
+		TokenExpiresAt time.Time `json:"token_expires_at"`
+	}{result, token.Token, token.ExpiresAt}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".discovery-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// gpuInventory is the GPUs nvidia-smi sees on the node
+type gpuInventory struct {
+	Count       int
+	VRAMTotalGB int // across all GPUs
+}
+
+// detectGPUs reads the node's GPU count and total memory. The control plane
+// tunes later launches' vLLM memory settings from what nodes report.
+func detectGPUs(ctx context.Context) (*gpuInventory, error) {
+	cmdCtx, cancel := context.WithTimeout(ctx, nvidiaSMITimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(cmdCtx, "nvidia-smi",
+		"--query-gpu=memory.total", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi: %w", err)
+	}
+	return parseGPUMemory(string(out))
+}
+
+// parseGPUMemory parses one memory.total value in MiB per GPU
+func parseGPUMemory(out string) (*gpuInventory, error) {
+	inv := &gpuInventory{}
+	var totalMiB int
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		mib, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected nvidia-smi memory value %q", line)
+		}
+		inv.Count++
+		totalMiB += mib
+	}
+	if inv.Count == 0 {
+		return nil, fmt.Errorf("nvidia-smi reported no GPUs")
+	}
+	// Cards report slightly under their marketed size (81559 MiB for 80 GB)
+	inv.VRAMTotalGB = (totalMiB + 512) / 1024
+	return inv, nil
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runtimeRefreshInterval is how often the reported vLLM runtime is re-read;
+// heartbeats in between resend the last one
+const runtimeRefreshInterval = time.Minute
+
+// vllmServerModule is vLLM's OpenAI-compatible server module
+const vllmServerModule = "vllm.entrypoints.openai.api_server"
+
+// runtimeReport is the configuration vLLM is actually serving with, sent
+// with heartbeats so the control plane can detect drift from the spec
+type runtimeReport struct {
+	ServedModels []string `json:"served_models,omitempty"`
+	VLLMVersion  string   `json:"vllm_version,omitempty"`
+	VLLMArgs     []string `json:"vllm_args,omitempty"`
+}
+
+// currentRuntime returns the vLLM runtime to report, re-reading it once a
+// minute. It is nil while vLLM can't be reached.
+func (a *Agent) currentRuntime(ctx context.Context) *runtimeReport {
+	if a.runtime != nil && time.Since(a.runtimeReadAt) < runtimeRefreshInterval {
+		return a.runtime
+	}
+
+	models, err := a.servedModels(ctx)
+	if err != nil {
+		a.runtime = nil
+		return nil
+	}
+
+	a.runtime = &runtimeReport{
+		ServedModels: models,
+		VLLMVersion:  a.vllmVersion(ctx),
+		VLLMArgs:     vllmCommandLine(),
+	}
+	a.runtimeReadAt = time.Now()
+	return a.runtime
+}
+
+// servedModels lists the model IDs vLLM serves
+func (a *Agent) servedModels(ctx context.Context) ([]string, error) {
+	var body struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := a.getVLLMJSON(ctx, "/v1/models", &body); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(body.Data))
+	for _, m := range body.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+// vllmVersion returns vLLM's version, or "" when it can't be read
+func (a *Agent) vllmVersion(ctx context.Context) string {
+	var body struct {
+		Version string `json:"version"`
+	}
+	if err := a.getVLLMJSON(ctx, "/version", &body); err != nil {
+		return ""
+	}
+	return body.Version
+}
+
+// getVLLMJSON decodes a JSON response from the vLLM server
+func (a *Agent) getVLLMJSON(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.config.VLLMEndpoint+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// vllmCommandLine finds the vLLM server process and returns its arguments
+// after the entrypoint, or nil when it isn't found (e.g. outside Linux)
+func vllmCommandLine() []string {
+	cmdlines, err := filepath.Glob("/proc/[0-9]*/cmdline")
+	if err != nil {
+		return nil
+	}
+
+	for _, path := range cmdlines {
+		raw, err := os.ReadFile(path)
+		if err != nil || len(raw) == 0 {
+			continue
+		}
+		args := strings.Split(string(bytes.TrimRight(raw, "\x00")), "\x00")
+		if rest, ok := vllmArgs(args); ok {
+			return rest
+		}
+	}
+	return nil
+}
+
+// vllmArgs returns the arguments after vLLM's entrypoint: the module for
+// "python -m vllm.entrypoints.openai.api_server", or "serve" for the vllm
+// CLI
+func vllmArgs(args []string) ([]string, bool) {
+	for i, arg := range args {
+		if arg == vllmServerModule {
+			return args[i+1:], true
+		}
+		if arg == "serve" && i > 0 && filepath.Base(args[i-1]) == "vllm" {
+			return args[i+1:], true
+		}
+	}
+	return nil, false
+}